@@ -0,0 +1,172 @@
+// Command agentctl runs the agents of a workflow file for other programs and
+// checks policy files.
+//
+//	agentctl serve --stdio --workflow code-review.yaml
+//	agentctl policy lint policies.yaml
+//
+// serve speaks JSON-RPC 2.0 over standard input and output, framed like the
+// Language Server Protocol, so editor extensions and desktop apps can embed the
+// agents without HTTP. See server.RPCServer for the methods and event schema.
+//
+// The model provider is picked with --provider, or from the environment:
+// OpenAI when OPENAI_API_KEY is set, Anthropic when ANTHROPIC_API_KEY is set and
+// LM Studio otherwise. Standard output carries only protocol messages; logs go to
+// standard error.
+//
+// policy lint checks policy files against the schema of package policy and
+// reports every problem found, exiting with status 1 if there are any, so CI can
+// check policies before they are deployed.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/anthropic"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/lmstudio"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/openai"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/policy"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/server"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/workflow"
+)
+
+const usage = `usage: agentctl serve --stdio --workflow <file> [--provider openai|anthropic|lmstudio] [--base-url <url>]
+       agentctl policy lint <file>...`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch os.Args[1] {
+	case "serve":
+		err = serve(os.Args[2:])
+	case "policy":
+		if len(os.Args) < 4 || os.Args[2] != "lint" {
+			fmt.Fprintln(os.Stderr, usage)
+			os.Exit(2)
+		}
+		err = lintPolicies(os.Args[3:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "agentctl:", err)
+		os.Exit(1)
+	}
+}
+
+// lintPolicies runs the policy lint command, printing the problems of each file
+func lintPolicies(files []string) error {
+	failed := 0
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		problems := policy.Lint(data)
+		for _, problem := range problems {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", file, problem)
+		}
+		if len(problems) > 0 {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d policy files have problems", failed, len(files))
+	}
+	return nil
+}
+
+// serve runs the serve command
+func serve(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	stdio := flags.Bool("stdio", false, "serve JSON-RPC over standard input and output")
+	workflowFile := flags.String("workflow", "", "workflow file defining the agents")
+	providerName := flags.String("provider", "", "model provider: openai, anthropic or lmstudio")
+	baseURL := flags.String("base-url", "", "base URL of the model provider")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if !*stdio {
+		return fmt.Errorf("only --stdio is supported\n%s", usage)
+	}
+	if *workflowFile == "" {
+		return fmt.Errorf("--workflow is required\n%s", usage)
+	}
+
+	provider, err := newProvider(*providerName, *baseURL)
+	if err != nil {
+		return err
+	}
+	wf, err := workflow.Load(*workflowFile, workflow.NewRegistry().WithProvider(provider))
+	if err != nil {
+		return err
+	}
+
+	// The entry agent comes first, so requests without an agent start there
+	agents := []*agent.Agent{wf.Entry}
+	names := make([]string, 0, len(wf.Agents))
+	for name := range wf.Agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if wf.Agents[name] != wf.Entry {
+			agents = append(agents, wf.Agents[name])
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	rpc := server.NewRPCServer(wf.Runner, agents...).
+		WithRunOptions(&runner.RunOptions{WorkflowConfig: wf.Config})
+	return rpc.ServeStdio(ctx)
+}
+
+// newProvider creates the named model provider, or picks one from the environment
+func newProvider(name, baseURL string) (model.Provider, error) {
+	if name == "" {
+		switch {
+		case os.Getenv("OPENAI_API_KEY") != "":
+			name = "openai"
+		case os.Getenv("ANTHROPIC_API_KEY") != "":
+			name = "anthropic"
+		default:
+			name = "lmstudio"
+		}
+	}
+
+	switch name {
+	case "openai":
+		p := openai.NewProvider(os.Getenv("OPENAI_API_KEY"))
+		if baseURL != "" {
+			p.SetBaseURL(baseURL)
+		}
+		return p, nil
+	case "anthropic":
+		p := anthropic.NewProvider(os.Getenv("ANTHROPIC_API_KEY"))
+		if baseURL != "" {
+			p.SetBaseURL(baseURL)
+		}
+		return p, nil
+	case "lmstudio":
+		p := lmstudio.NewProvider()
+		if baseURL != "" {
+			p.SetBaseURL(baseURL)
+		}
+		return p, nil
+	}
+	return nil, fmt.Errorf("unknown provider %q", name)
+}
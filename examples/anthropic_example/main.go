@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/anthropic"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
@@ -37,6 +38,45 @@ func main() {
 	fmt.Println("- Rate limit:", "40 requests/min, 80,000 tokens/min")
 	fmt.Println("- Max retries:", 3)
 
+	// Create a simple agent that can use the calculator
+	calcAssistant := newCalculatorAssistant(provider)
+
+	// Create a runner
+	r := runner.NewRunner()
+	r.WithDefaultProvider(provider)
+
+	// Run the agent with a calculation request
+	fmt.Println("\nSending a calculation request to the agent...")
+	result, err := r.RunSync(calcAssistant, &runner.RunOptions{
+		Input:    "What is 42 * 23?",
+		MaxTurns: 5,
+	})
+	if err != nil {
+		log.Fatalf("Error running agent: %v", err)
+	}
+
+	// Print the result
+	fmt.Println("\nAgent response:")
+	fmt.Println(result.FinalOutput)
+
+	// Run with another calculation
+	fmt.Println("\nSending another calculation request...")
+	result, err = r.RunSync(calcAssistant, &runner.RunOptions{
+		Input:    "If I have 5 items that cost $12.50 each, and 3 items that cost $8.75 each, what's the total?",
+		MaxTurns: 5,
+	})
+	if err != nil {
+		log.Fatalf("Error running agent: %v", err)
+	}
+
+	// Print the result
+	fmt.Println("\nAgent response:")
+	fmt.Println(result.FinalOutput)
+}
+
+// newCalculatorAssistant creates an assistant that uses a calculator tool for
+// every calculation
+func newCalculatorAssistant(provider model.Provider) *agent.Agent {
 	// Create a simple calculator tool
 	calculatorTool := tool.NewFunctionTool(
 		"calculator",
@@ -111,35 +151,5 @@ func main() {
 	calcAssistant.SetSystemInstructions("You are an AI assistant that can perform calculations. You MUST use the calculator tool when asked to perform any mathematical operation. Never try to do the calculation yourself - always use the tool.")
 	calcAssistant.WithTools(calculatorTool)
 
-	// Create a runner
-	r := runner.NewRunner()
-	r.WithDefaultProvider(provider)
-
-	// Run the agent with a calculation request
-	fmt.Println("\nSending a calculation request to the agent...")
-	result, err := r.RunSync(calcAssistant, &runner.RunOptions{
-		Input:    "What is 42 * 23?",
-		MaxTurns: 5,
-	})
-	if err != nil {
-		log.Fatalf("Error running agent: %v", err)
-	}
-
-	// Print the result
-	fmt.Println("\nAgent response:")
-	fmt.Println(result.FinalOutput)
-
-	// Run with another calculation
-	fmt.Println("\nSending another calculation request...")
-	result, err = r.RunSync(calcAssistant, &runner.RunOptions{
-		Input:    "If I have 5 items that cost $12.50 each, and 3 items that cost $8.75 each, what's the total?",
-		MaxTurns: 5,
-	})
-	if err != nil {
-		log.Fatalf("Error running agent: %v", err)
-	}
-
-	// Print the result
-	fmt.Println("\nAgent response:")
-	fmt.Println(result.FinalOutput)
+	return calcAssistant
 }
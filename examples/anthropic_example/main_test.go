@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/harness"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalculatorAssistantUsesTheCalculator(t *testing.T) {
+	assistant := newCalculatorAssistant(&mocks.MockModelProvider{})
+
+	run := harness.Execute(t, runner.NewRunner(), assistant, harness.Script{
+		"Anthropic Calculator Assistant": {
+			harness.CallTool("calculator", map[string]interface{}{"operation": "multiply", "num1": 42.0, "num2": 23.0}),
+			harness.Answer("42 * 23 = 966"),
+		},
+	}, &runner.RunOptions{Input: "What is 42 * 23?", MaxTurns: 5})
+	require.NoError(t, run.Err)
+
+	assert.Equal(t, []string{"calculator"}, run.ToolCalls())
+	assert.Equal(t, []interface{}{map[string]interface{}{"result": 966.0}}, run.ToolResults("calculator"))
+	assert.Equal(t, "42 * 23 = 966", run.Output())
+	assert.Empty(t, run.Unused())
+}
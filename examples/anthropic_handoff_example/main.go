@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/anthropic"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
@@ -52,6 +53,46 @@ func main() {
 	fmt.Println("- Max retries:", 3)
 	fmt.Println("- Working directory:", "./code-review")
 
+	// Create the orchestrator and the specialized agents
+	orchestratorAgent := newCodeReviewWorkflow(provider)
+
+	// Create runner
+	r := runner.NewRunner()
+	r.WithDefaultProvider(provider)
+
+	// Enable debug output if needed
+	if err := os.Setenv("ANTHROPIC_DEBUG", "1"); err != nil {
+		log.Printf("Warning: Failed to set ANTHROPIC_DEBUG environment variable: %v", err)
+	}
+	if err := os.Setenv("DEBUG", "1"); err != nil {
+		log.Printf("Warning: Failed to set DEBUG environment variable: %v", err)
+	}
+
+	// Run the workflow
+	fmt.Println("\nStarting the code review workflow with Anthropic Claude...")
+	result, err := r.RunSync(orchestratorAgent, &runner.RunOptions{
+		Input:    "Please review this code for me:\n\n" + sampleCode,
+		MaxTurns: 10,
+	})
+
+	if err != nil {
+		log.Fatalf("Error running agent: %v", err)
+	}
+
+	fmt.Println("\nWorkflow complete! Final result:")
+	fmt.Println(result.FinalOutput)
+
+	// Print detailed items if desired
+	fmt.Println("\nItems generated:", len(result.NewItems))
+	fmt.Println("\nDetailed items:")
+	for i, item := range result.NewItems {
+		fmt.Printf("Item %d: Type=%s\n", i, item.GetType())
+	}
+}
+
+// newCodeReviewWorkflow creates the orchestrator of the code review and the
+// agents it hands off to
+func newCodeReviewWorkflow(provider model.Provider) *agent.Agent {
 	// Create a simple code analysis tool
 	getCodeInfo := tool.NewFunctionTool(
 		"get_code_info",
@@ -98,42 +139,18 @@ Always use the handoff_to_[Agent] tools to delegate tasks to the specialized age
 		optimizerAgent,
 	)
 
-	// Create runner
-	r := runner.NewRunner()
-	r.WithDefaultProvider(provider)
-
-	// Enable debug output if needed
-	if err := os.Setenv("ANTHROPIC_DEBUG", "1"); err != nil {
-		log.Printf("Warning: Failed to set ANTHROPIC_DEBUG environment variable: %v", err)
-	}
-	if err := os.Setenv("DEBUG", "1"); err != nil {
-		log.Printf("Warning: Failed to set DEBUG environment variable: %v", err)
-	}
-
-	// Run the workflow
-	fmt.Println("\nStarting the code review workflow with Anthropic Claude...")
-	result, err := r.RunSync(orchestratorAgent, &runner.RunOptions{
-		Input:    "Please review this code for me:\n\n" + sampleCode,
-		MaxTurns: 10,
-	})
-
-	if err != nil {
-		log.Fatalf("Error running agent: %v", err)
+	// Let the specialized agents hand their results back, so that the
+	// orchestrator can continue with the next step
+	for _, specialist := range []*agent.Agent{analyzerAgent, optimizerAgent} {
+		specialist.AsTaskExecutor()
+		specialist.WithHandoffs(orchestratorAgent)
 	}
 
-	fmt.Println("\nWorkflow complete! Final result:")
-	fmt.Println(result.FinalOutput)
-
-	// Print detailed items if desired
-	fmt.Println("\nItems generated:", len(result.NewItems))
-	fmt.Println("\nDetailed items:")
-	for i, item := range result.NewItems {
-		fmt.Printf("Item %d: Type=%s\n", i, item.GetType())
-	}
+	return orchestratorAgent
 }
 
 // Create the analyzer agent
-func createAnalyzerAgent(provider *anthropic.Provider, getCodeInfo tool.Tool) *agent.Agent {
+func createAnalyzerAgent(provider model.Provider, getCodeInfo tool.Tool) *agent.Agent {
 	analyzerAgent := agent.NewAgent("Analyzer")
 	analyzerAgent.SetModelProvider(provider)
 	analyzerAgent.WithModel("claude-3-haiku-20240307")
@@ -159,7 +176,7 @@ Then provide a comprehensive analysis with clear, actionable feedback.`)
 }
 
 // Create the optimizer agent
-func createOptimizerAgent(provider *anthropic.Provider, getCodeInfo tool.Tool) *agent.Agent {
+func createOptimizerAgent(provider model.Provider, getCodeInfo tool.Tool) *agent.Agent {
 	optimizerAgent := agent.NewAgent("Optimizer")
 	optimizerAgent.SetModelProvider(provider)
 	optimizerAgent.WithModel("claude-3-haiku-20240307")
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/harness"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeReviewGoesThroughAnalyzerAndOptimizer(t *testing.T) {
+	orchestrator := newCodeReviewWorkflow(&mocks.MockModelProvider{})
+
+	run := harness.Execute(t, runner.NewRunner(), orchestrator, harness.Script{
+		"Orchestrator": {
+			harness.CallTool("get_code_info", nil),
+			harness.Handoff("Analyzer", "Analyze processItems"),
+			harness.Handoff("Optimizer", "Optimize processItems: preallocate the results"),
+			harness.Answer("processItems now preallocates its results."),
+		},
+		"Analyzer": {
+			harness.CallTool("get_code_info", nil),
+			harness.Handoff("Orchestrator", "The results slice is not preallocated."),
+		},
+		"Optimizer": {harness.Handoff("Orchestrator", "Preallocated the results slice.")},
+	}, &runner.RunOptions{Input: "Please review this code for me:\n\n" + sampleCode, MaxTurns: 10})
+	require.NoError(t, run.Err)
+
+	assert.Equal(t, []string{"Analyzer", "Orchestrator", "Optimizer", "Orchestrator"}, run.Handoffs())
+	assert.Equal(t, []string{"get_code_info", "get_code_info"}, run.ToolCalls())
+	assert.Equal(t, "processItems now preallocates its results.", run.Output())
+	assert.Equal(t, "Orchestrator", run.LastAgent())
+	assert.Empty(t, run.Unused())
+	assert.Contains(t, fmt.Sprint(run.Models["Orchestrator"].Requests[2].Input), "The results slice is not preallocated.",
+		"the analysis reaches the orchestrator")
+}
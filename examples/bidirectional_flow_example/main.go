@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/openai"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
@@ -16,6 +17,10 @@ import (
 // Sample research topic
 const researchTopic = "Quantum Computing Advancements in 2023"
 
+// maxTurns is enough turns for the orchestrator and the agents to go through
+// every step of the workflow
+const maxTurns = 15
+
 func main() {
 	// Get API key from environment
 	apiKey := os.Getenv("OPENAI_API_KEY")
@@ -36,6 +41,76 @@ func main() {
 	fmt.Println("- Model:", "gpt-3.5-turbo")
 	fmt.Println("- Max retries:", 3)
 
+	// Create the orchestrator and the specialized agents
+	orchestratorAgent := newResearchWorkflow(provider)
+
+	// Create runner
+	r := runner.NewRunner()
+	r.WithDefaultProvider(provider)
+
+	// Run the workflow
+	fmt.Println("\nStarting the research workflow with bidirectional flow...")
+
+	// Enable all debugging
+	if err := os.Setenv("DEBUG", "1"); err != nil {
+		log.Printf("Warning: Failed to set DEBUG environment variable: %v", err)
+	}
+	if err := os.Setenv("OPENAI_DEBUG", "1"); err != nil {
+		log.Printf("Warning: Failed to set OPENAI_DEBUG environment variable: %v", err)
+	}
+
+	// Print debug info about the agents
+	fmt.Printf("DEBUG: Orchestrator agent has %d handoffs configured\n", len(orchestratorAgent.Handoffs))
+	for i, h := range orchestratorAgent.Handoffs {
+		fmt.Printf("DEBUG: Handoff #%d: %s\n", i+1, h.Name)
+	}
+
+	// Run the workflow with a simpler approach that just tracks handoffs
+	result, err := r.RunSync(orchestratorAgent, &runner.RunOptions{
+		Input:    fmt.Sprintf("I need comprehensive research on %s. Please coordinate the research process.", researchTopic),
+		MaxTurns: maxTurns,
+	})
+
+	if err != nil {
+		log.Fatalf("Error running agent: %v", err)
+	}
+
+	// Print a summary of what happened
+	fmt.Println("\nWorkflow complete! Summary:")
+
+	// Handle nil FinalOutput by providing a fallback message
+	if result.FinalOutput == nil {
+		fmt.Println("- Final output: (No final output generated)")
+	} else {
+		fmt.Printf("- Final output: %v\n", result.FinalOutput)
+	}
+
+	fmt.Printf("- Last agent: %s\n", result.LastAgent.Name)
+	fmt.Printf("- Items generated: %d\n", len(result.NewItems))
+
+	// Print details of any handoff items
+	fmt.Println("\nHandoffs:")
+	handoffCount := 0
+	for _, item := range result.NewItems {
+		if item.GetType() == "handoff" {
+			handoffCount++
+			handoffItem, ok := item.(interface{ GetAgentName() string })
+			if ok {
+				fmt.Printf("- Handoff #%d: %s\n", handoffCount, handoffItem.GetAgentName())
+			} else {
+				fmt.Printf("- Handoff #%d: (agent name not available)\n", handoffCount)
+			}
+		}
+	}
+
+	if handoffCount == 0 {
+		fmt.Println("- No handoffs occurred")
+	}
+}
+
+// newResearchWorkflow creates the orchestrator of the research workflow and the
+// agents it delegates to, which return to it
+func newResearchWorkflow(provider model.Provider) *agent.Agent {
 	// Create tools
 	getCurrentTime := tool.NewFunctionTool(
 		"get_current_time",
@@ -109,7 +184,6 @@ IMPORTANT:
 - ALWAYS end with a clear, complete summary of the research results when the workflow is finished`)
 
 	// Configure as task delegator with explicit delegator name
-	fmt.Println("Configuring orchestrator as task delegator...")
 	orchestratorAgent.AsTaskDelegator()
 
 	// Set up bidirectional handoffs
@@ -129,72 +203,11 @@ IMPORTANT:
 		childAgent.WithHandoffs(orchestratorAgent)
 	}
 
-	// Create runner
-	r := runner.NewRunner()
-	r.WithDefaultProvider(provider)
-
-	// Run the workflow
-	fmt.Println("\nStarting the research workflow with bidirectional flow...")
-
-	// Enable all debugging
-	if err := os.Setenv("DEBUG", "1"); err != nil {
-		log.Printf("Warning: Failed to set DEBUG environment variable: %v", err)
-	}
-	if err := os.Setenv("OPENAI_DEBUG", "1"); err != nil {
-		log.Printf("Warning: Failed to set OPENAI_DEBUG environment variable: %v", err)
-	}
-
-	// Print debug info about the agents
-	fmt.Printf("DEBUG: Orchestrator agent has %d handoffs configured\n", len(orchestratorAgent.Handoffs))
-	for i, h := range orchestratorAgent.Handoffs {
-		fmt.Printf("DEBUG: Handoff #%d: %s\n", i+1, h.Name)
-	}
-
-	// Run the workflow with a simpler approach that just tracks handoffs
-	result, err := r.RunSync(orchestratorAgent, &runner.RunOptions{
-		Input:    fmt.Sprintf("I need comprehensive research on %s. Please coordinate the research process.", researchTopic),
-		MaxTurns: 5, // Use fewer turns for debugging
-	})
-
-	if err != nil {
-		log.Fatalf("Error running agent: %v", err)
-	}
-
-	// Print a summary of what happened
-	fmt.Println("\nWorkflow complete! Summary:")
-
-	// Handle nil FinalOutput by providing a fallback message
-	if result.FinalOutput == nil {
-		fmt.Println("- Final output: (No final output generated)")
-	} else {
-		fmt.Printf("- Final output: %v\n", result.FinalOutput)
-	}
-
-	fmt.Printf("- Last agent: %s\n", result.LastAgent.Name)
-	fmt.Printf("- Items generated: %d\n", len(result.NewItems))
-
-	// Print details of any handoff items
-	fmt.Println("\nHandoffs:")
-	handoffCount := 0
-	for _, item := range result.NewItems {
-		if item.GetType() == "handoff" {
-			handoffCount++
-			handoffItem, ok := item.(interface{ GetAgentName() string })
-			if ok {
-				fmt.Printf("- Handoff #%d: %s\n", handoffCount, handoffItem.GetAgentName())
-			} else {
-				fmt.Printf("- Handoff #%d: (agent name not available)\n", handoffCount)
-			}
-		}
-	}
-
-	if handoffCount == 0 {
-		fmt.Println("- No handoffs occurred")
-	}
+	return orchestratorAgent
 }
 
 // Create the research agent
-func createResearchAgent(provider *openai.Provider, searchTool, timeTool tool.Tool) *agent.Agent {
+func createResearchAgent(provider model.Provider, searchTool, timeTool tool.Tool) *agent.Agent {
 	researchAgent := agent.NewAgent("ResearchAgent")
 	researchAgent.SetModelProvider(provider)
 	researchAgent.WithModel("gpt-3.5-turbo")
@@ -222,7 +235,7 @@ When you complete your task, return to the delegating agent with your research f
 }
 
 // Create the summary agent
-func createSummaryAgent(provider *openai.Provider, timeTool tool.Tool) *agent.Agent {
+func createSummaryAgent(provider model.Provider, timeTool tool.Tool) *agent.Agent {
 	summaryAgent := agent.NewAgent("SummaryAgent")
 	summaryAgent.SetModelProvider(provider)
 	summaryAgent.WithModel("gpt-3.5-turbo")
@@ -252,7 +265,7 @@ When you complete your task, return to the delegating agent with your summary th
 }
 
 // Create the fact check agent
-func createFactCheckAgent(provider *openai.Provider, searchTool, timeTool tool.Tool) *agent.Agent {
+func createFactCheckAgent(provider model.Provider, searchTool, timeTool tool.Tool) *agent.Agent {
 	factCheckAgent := agent.NewAgent("FactCheckAgent")
 	factCheckAgent.SetModelProvider(provider)
 	factCheckAgent.WithModel("gpt-3.5-turbo")
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/harness"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResearchWorkflowDelegatesInOrder(t *testing.T) {
+	orchestrator := newResearchWorkflow(&mocks.MockModelProvider{})
+
+	run := harness.Execute(t, runner.NewRunner(), orchestrator, harness.Script{
+		"Orchestrator": {
+			harness.Handoff("ResearchAgent", "Research "+researchTopic),
+			harness.Handoff("FactCheckAgent", "Verify the findings"),
+			harness.Handoff("SummaryAgent", "Summarize the verified findings"),
+			harness.Answer("Quantum computing advanced in error correction."),
+		},
+		"ResearchAgent": {
+			harness.CallTool("search_information", map[string]interface{}{"topic": researchTopic}),
+			harness.Return("Findings: error correction improved"),
+		},
+		"FactCheckAgent": {harness.Return("Verified")},
+		"SummaryAgent":   {harness.Return("Summary: error correction improved")},
+	}, &runner.RunOptions{
+		Input:    fmt.Sprintf("I need comprehensive research on %s. Please coordinate the research process.", researchTopic),
+		MaxTurns: maxTurns,
+	})
+	require.NoError(t, run.Err)
+
+	assert.Equal(t, []string{
+		"ResearchAgent", "Orchestrator",
+		"FactCheckAgent", "Orchestrator",
+		"SummaryAgent", "Orchestrator",
+	}, run.Handoffs())
+	assert.Equal(t, []string{"search_information"}, run.ToolCalls())
+	assert.Equal(t, "Quantum computing advanced in error correction.", run.Output())
+	assert.Equal(t, "Orchestrator", run.LastAgent())
+	assert.Empty(t, run.Unused())
+	assert.Contains(t, fmt.Sprint(run.Models["Orchestrator"].Requests[1].Input), "Findings: error correction improved",
+		"the research reaches the orchestrator")
+}
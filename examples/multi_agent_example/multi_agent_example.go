@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/lmstudio"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
@@ -33,6 +34,80 @@ func main() {
 	fmt.Println("- Base URL:", "http://127.0.0.1:1234/v1")
 	fmt.Println("- Model:", "gemma-3-4b-it")
 
+	// Create the frontend agent and the specialized agents
+	frontendAgent := newFrontendAgent(provider)
+
+	// Create a runner
+	r := runner.NewRunner()
+	r.WithDefaultProvider(provider)
+
+	// Run example with a math query
+	fmt.Println("Running with a math query...")
+	result, err := r.RunSync(frontendAgent, &runner.RunOptions{
+		Input:    "What is 42 divided by 6?",
+		MaxTurns: 20,
+	})
+	if err != nil {
+		log.Fatalf("Error running agent: %v", err)
+	}
+
+	// Print the result
+	fmt.Println("\nAgent response:")
+	fmt.Println(result.FinalOutput)
+	fmt.Println("\nItems generated:", len(result.NewItems))
+
+	// Print detailed items for debugging
+	fmt.Println("\nDetailed items:")
+	for i, item := range result.NewItems {
+		fmt.Printf("Item %d: Type=%s\n", i, item.GetType())
+	}
+
+	// Run example with a weather query
+	fmt.Println("\nRunning with a weather query...")
+	result, err = r.RunSync(frontendAgent, &runner.RunOptions{
+		Input:    "What's the current weather in Paris?",
+		MaxTurns: 20,
+	})
+	if err != nil {
+		log.Fatalf("Error running agent: %v", err)
+	}
+
+	// Print the result
+	fmt.Println("\nAgent response:")
+	fmt.Println(result.FinalOutput)
+	fmt.Println("\nItems generated:", len(result.NewItems))
+
+	// Print detailed items for debugging
+	fmt.Println("\nDetailed items:")
+	for i, item := range result.NewItems {
+		fmt.Printf("Item %d: Type=%s\n", i, item.GetType())
+	}
+
+	// Run example with a mixed query
+	fmt.Println("\nRunning with a mixed query...")
+	result, err = r.RunSync(frontendAgent, &runner.RunOptions{
+		Input:    "What is 15 × 4 and what's the current time?",
+		MaxTurns: 20,
+	})
+	if err != nil {
+		log.Fatalf("Error running agent: %v", err)
+	}
+
+	// Print the result
+	fmt.Println("\nAgent response:")
+	fmt.Println(result.FinalOutput)
+	fmt.Println("\nItems generated:", len(result.NewItems))
+
+	// Print detailed items for debugging
+	fmt.Println("\nDetailed items:")
+	for i, item := range result.NewItems {
+		fmt.Printf("Item %d: Type=%s\n", i, item.GetType())
+	}
+}
+
+// newFrontendAgent creates the frontend agent and the math and weather agents it
+// hands off to
+func newFrontendAgent(provider model.Provider) *agent.Agent {
 	// Create the primary agent (Frontend)
 	frontendAgent := agent.NewAgent("Frontend Agent")
 	frontendAgent.SetModelProvider(provider)
@@ -297,70 +372,5 @@ IMPORTANT: Never end with a tool call. Always provide a final human-readable res
 	// Set up agent handoffs
 	frontendAgent.WithHandoffs(mathAgent, weatherAgent)
 
-	// Create a runner
-	r := runner.NewRunner()
-	r.WithDefaultProvider(provider)
-
-	// Run example with a math query
-	fmt.Println("Running with a math query...")
-	result, err := r.RunSync(frontendAgent, &runner.RunOptions{
-		Input:    "What is 42 divided by 6?",
-		MaxTurns: 20,
-	})
-	if err != nil {
-		log.Fatalf("Error running agent: %v", err)
-	}
-
-	// Print the result
-	fmt.Println("\nAgent response:")
-	fmt.Println(result.FinalOutput)
-	fmt.Println("\nItems generated:", len(result.NewItems))
-
-	// Print detailed items for debugging
-	fmt.Println("\nDetailed items:")
-	for i, item := range result.NewItems {
-		fmt.Printf("Item %d: Type=%s\n", i, item.GetType())
-	}
-
-	// Run example with a weather query
-	fmt.Println("\nRunning with a weather query...")
-	result, err = r.RunSync(frontendAgent, &runner.RunOptions{
-		Input:    "What's the current weather in Paris?",
-		MaxTurns: 20,
-	})
-	if err != nil {
-		log.Fatalf("Error running agent: %v", err)
-	}
-
-	// Print the result
-	fmt.Println("\nAgent response:")
-	fmt.Println(result.FinalOutput)
-	fmt.Println("\nItems generated:", len(result.NewItems))
-
-	// Print detailed items for debugging
-	fmt.Println("\nDetailed items:")
-	for i, item := range result.NewItems {
-		fmt.Printf("Item %d: Type=%s\n", i, item.GetType())
-	}
-
-	// Run example with a mixed query
-	fmt.Println("\nRunning with a mixed query...")
-	result, err = r.RunSync(frontendAgent, &runner.RunOptions{
-		Input:    "What is 15 × 4 and what's the current time?",
-		MaxTurns: 20,
-	})
-	if err != nil {
-		log.Fatalf("Error running agent: %v", err)
-	}
-
-	// Print the result
-	fmt.Println("\nAgent response:")
-	fmt.Println(result.FinalOutput)
-	fmt.Println("\nItems generated:", len(result.NewItems))
-
-	// Print detailed items for debugging
-	fmt.Println("\nDetailed items:")
-	for i, item := range result.NewItems {
-		fmt.Printf("Item %d: Type=%s\n", i, item.GetType())
-	}
+	return frontendAgent
 }
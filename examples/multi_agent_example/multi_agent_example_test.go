@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/harness"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrontendHandsMathToMathAgent(t *testing.T) {
+	frontend := newFrontendAgent(&mocks.MockModelProvider{})
+
+	run := harness.Execute(t, runner.NewRunner(), frontend, harness.Script{
+		"Frontend Agent": {harness.Handoff("Math Agent", "Calculate 42 divided by 6")},
+		"Math Agent": {
+			harness.CallTool("calculate", map[string]interface{}{"operation": "divide", "a": 42.0, "b": 6.0}),
+			harness.Answer("The calculation of 42 divided by 6 equals 7."),
+		},
+	}, &runner.RunOptions{Input: "What is 42 divided by 6?", MaxTurns: 20})
+	require.NoError(t, run.Err)
+
+	assert.Equal(t, []string{"Math Agent"}, run.Handoffs())
+	assert.Equal(t, []string{"calculate"}, run.ToolCalls())
+	assert.Equal(t, []interface{}{7.0}, run.ToolResults("calculate"))
+	assert.Equal(t, "The calculation of 42 divided by 6 equals 7.", run.Output())
+	assert.Equal(t, "Math Agent", run.LastAgent())
+	assert.Empty(t, run.Unused())
+}
+
+func TestFrontendHandsWeatherToWeatherAgent(t *testing.T) {
+	frontend := newFrontendAgent(&mocks.MockModelProvider{})
+
+	run := harness.Execute(t, runner.NewRunner(), frontend, harness.Script{
+		"Frontend Agent": {harness.Handoff("Weather Agent", "Get the weather in Paris")},
+		"Weather Agent": {
+			harness.CallTool("get_weather", map[string]interface{}{"location": "Paris"}),
+			harness.Answer("Currently in Paris, it's pleasant."),
+		},
+	}, &runner.RunOptions{Input: "What's the current weather in Paris?", MaxTurns: 20})
+	require.NoError(t, run.Err)
+
+	assert.Equal(t, []string{"Weather Agent"}, run.Handoffs())
+	assert.Equal(t, []string{"get_weather"}, run.ToolCalls())
+	require.Len(t, run.ToolResults("get_weather"), 1)
+	assert.Equal(t, "Paris", run.ToolResults("get_weather")[0].(map[string]interface{})["location"])
+	assert.Equal(t, "Weather Agent", run.LastAgent())
+	assert.Empty(t, run.Unused())
+}
+
+func TestFrontendTellsTheTimeItself(t *testing.T) {
+	frontend := newFrontendAgent(&mocks.MockModelProvider{})
+
+	run := harness.Execute(t, runner.NewRunner(), frontend, harness.Script{
+		"Frontend Agent": {
+			harness.CallTool("get_current_time", map[string]interface{}{"format": "kitchen"}),
+			harness.Answer("It is 3:04PM."),
+		},
+	}, &runner.RunOptions{Input: "What's the current time?", MaxTurns: 20})
+	require.NoError(t, run.Err)
+
+	assert.Empty(t, run.Handoffs())
+	assert.Equal(t, []string{"get_current_time"}, run.ToolCalls())
+	assert.Equal(t, "It is 3:04PM.", run.Output())
+	assert.Empty(t, run.Unused())
+}
@@ -8,8 +8,10 @@ import (
 	"time"
 
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/openai"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/scratchpad"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
 )
 
@@ -51,16 +53,49 @@ func main() {
 	fmt.Printf("- Max retries: %d\n", provider.MaxRetries)
 	fmt.Println()
 
+	// Create the orchestrator and the specialized agents
+	orchestratorAgent := newCodeReviewWorkflow(provider, modelName)
+
+	// Create runner
+	r := runner.NewRunner()
+	r.WithDefaultProvider(provider)
+
+	// Run the workflow with the orchestrator agent
+	fmt.Println("Starting the code review workflow...")
+	// The workflow state lives in the run's scratchpad, shared by all agents
+	pad := scratchpad.New()
+	result, err := r.RunSync(orchestratorAgent, &runner.RunOptions{
+		Input:     "Please review and improve this code:\n\n" + sampleCode,
+		MaxTurns:  maxTurns,
+		RunConfig: &runner.RunConfig{Scratchpad: pad},
+	})
+
+	if err != nil {
+		log.Fatalf("Error running agent: %v", err)
+	}
+
+	fmt.Println("\nWorkflow complete! Final report:")
+	fmt.Println(result.FinalOutput)
+
+	completedPhases, _ := pad.Get("completed_phases")
+	fmt.Println("\nWorkflow phases completed:", completedPhases)
+}
+
+// newCodeReviewWorkflow creates the orchestrator of the code review workflow and
+// the agents it hands off to
+func newCodeReviewWorkflow(provider model.Provider, modelName string) *agent.Agent {
 	// Create shared tools for all agents
 	getWorkflowStateInfo := tool.NewFunctionTool(
 		"get_workflow_state",
 		"Get information about the current state of the workflow",
 		func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			pad := scratchpad.FromContext(ctx)
+			completedPhases, _ := pad.Get("completed_phases")
 			return map[string]interface{}{
 				"workflow_id":       "code-review-1234",
-				"current_phase":     currentPhase,
+				"current_phase":     pad.GetString("current_phase"),
 				"completed_phases":  completedPhases,
-				"remaining_phases":  []string{"analyze", "optimize", "test", "document", "summarize"}[len(completedPhases):],
+				"remaining_phases":  remainingPhases(pad),
 				"timestamp":         time.Now().Format(time.RFC3339),
 				"code_under_review": sampleCode,
 			}, nil
@@ -77,16 +112,21 @@ func main() {
 			}
 
 			// Handle special cases
+			pad := scratchpad.FromContext(ctx)
 			if phase == "complete_current" {
+				currentPhase := pad.GetString("current_phase")
 				if currentPhase != "" && currentPhase != "complete" {
-					completedPhases = append(completedPhases, currentPhase)
+					pad.Update("completed_phases", func(value interface{}, ok bool) interface{} {
+						completed, _ := value.([]string)
+						return append(completed, currentPhase)
+					})
 					return fmt.Sprintf("Completed phase: %s", currentPhase), nil
 				}
 				return "No current phase to complete", nil
 			}
 
 			// Set the current phase
-			currentPhase = phase
+			pad.Set("current_phase", phase)
 			return fmt.Sprintf("Updated current phase to: %s", phase), nil
 		},
 	).WithSchema(map[string]interface{}{
@@ -118,33 +158,35 @@ func main() {
 		summarizerAgent,
 	)
 
-	// Create runner
-	r := runner.NewRunner()
-	r.WithDefaultProvider(provider)
+	// Let the specialized agents hand their results back, so that the
+	// orchestrator can continue with the next step
+	for _, specialist := range []*agent.Agent{analyzerAgent, optimizerAgent, testerAgent, documentorAgent, summarizerAgent} {
+		specialist.AsTaskExecutor()
+		specialist.WithHandoffs(orchestratorAgent)
+	}
 
-	// Run the workflow with the orchestrator agent
-	fmt.Println("Starting the code review workflow...")
-	result, err := r.RunSync(orchestratorAgent, &runner.RunOptions{
-		Input:    "Please review and improve this code:\n\n" + sampleCode,
-		MaxTurns: 25, // Set a higher limit for complex workflows
-	})
+	return orchestratorAgent
+}
 
-	if err != nil {
-		log.Fatalf("Error running agent: %v", err)
-	}
+// maxTurns is enough turns for the orchestrator and the agents to go through
+// every phase of the workflow
+const maxTurns = 40
 
-	fmt.Println("\nWorkflow complete! Final report:")
-	fmt.Println(result.FinalOutput)
+// workflowPhases are the phases of the workflow in order
+var workflowPhases = []string{"analyze", "optimize", "test", "document", "summarize"}
 
-	fmt.Println("\nWorkflow phases completed:", completedPhases)
+// remainingPhases returns the phases of the workflow that are not completed yet
+func remainingPhases(pad *scratchpad.Scratchpad) []string {
+	value, _ := pad.Get("completed_phases")
+	completed, _ := value.([]string)
+	if len(completed) >= len(workflowPhases) {
+		return nil
+	}
+	return workflowPhases[len(completed):]
 }
 
-// Track workflow state
-var currentPhase string
-var completedPhases []string
-
 // Create the orchestrator agent
-func createOrchestratorAgent(provider *openai.Provider, getWorkflowStateInfo, updateWorkflowState tool.Tool, modelName string) *agent.Agent {
+func createOrchestratorAgent(provider model.Provider, getWorkflowStateInfo, updateWorkflowState tool.Tool, modelName string) *agent.Agent {
 	orchestratorAgent := agent.NewAgent("Orchestrator")
 	orchestratorAgent.SetModelProvider(provider)
 	orchestratorAgent.WithModel(modelName)
@@ -181,7 +223,7 @@ IMPORTANT: Always use the handoff_to_[Agent] tools to delegate work. Never try t
 }
 
 // Create the analyzer agent
-func createAnalyzerAgent(provider *openai.Provider, getWorkflowStateInfo tool.Tool, modelName string) *agent.Agent {
+func createAnalyzerAgent(provider model.Provider, getWorkflowStateInfo tool.Tool, modelName string) *agent.Agent {
 	analyzerAgent := agent.NewAgent("Analyzer")
 	analyzerAgent.SetModelProvider(provider)
 	analyzerAgent.WithModel(modelName)
@@ -207,7 +249,7 @@ Then provide a comprehensive analysis with clear, actionable feedback.`)
 }
 
 // Create the optimizer agent
-func createOptimizerAgent(provider *openai.Provider, getWorkflowStateInfo tool.Tool, modelName string) *agent.Agent {
+func createOptimizerAgent(provider model.Provider, getWorkflowStateInfo tool.Tool, modelName string) *agent.Agent {
 	optimizerAgent := agent.NewAgent("Optimizer")
 	optimizerAgent.SetModelProvider(provider)
 	optimizerAgent.WithModel(modelName)
@@ -233,7 +275,7 @@ Then provide the optimized code along with explanations of your changes.`)
 }
 
 // Create the tester agent
-func createTesterAgent(provider *openai.Provider, getWorkflowStateInfo tool.Tool, modelName string) *agent.Agent {
+func createTesterAgent(provider model.Provider, getWorkflowStateInfo tool.Tool, modelName string) *agent.Agent {
 	testerAgent := agent.NewAgent("Tester")
 	testerAgent.SetModelProvider(provider)
 	testerAgent.WithModel(modelName)
@@ -259,7 +301,7 @@ Then provide complete test code that would properly test the functionality.`)
 }
 
 // Create the documentor agent
-func createDocumentorAgent(provider *openai.Provider, getWorkflowStateInfo tool.Tool, modelName string) *agent.Agent {
+func createDocumentorAgent(provider model.Provider, getWorkflowStateInfo tool.Tool, modelName string) *agent.Agent {
 	documentorAgent := agent.NewAgent("Documentor")
 	documentorAgent.SetModelProvider(provider)
 	documentorAgent.WithModel(modelName)
@@ -287,7 +329,7 @@ Then provide complete documentation for the code including properly formatted co
 }
 
 // Create the summarizer agent
-func createSummarizerAgent(provider *openai.Provider, getWorkflowStateInfo tool.Tool, modelName string) *agent.Agent {
+func createSummarizerAgent(provider model.Provider, getWorkflowStateInfo tool.Tool, modelName string) *agent.Agent {
 	summarizerAgent := agent.NewAgent("Summarizer")
 	summarizerAgent.SetModelProvider(provider)
 	summarizerAgent.WithModel(modelName)
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/scratchpad"
+	"github.com/pontus-devoteam/agent-sdk-go/test/harness"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodeReviewWorkflowCompletesEveryPhase(t *testing.T) {
+	orchestrator := newCodeReviewWorkflow(&mocks.MockModelProvider{}, "gpt-4o-mini")
+
+	// The orchestrator runs each phase the way its instructions describe
+	specialists := map[string]string{
+		"analyze":   "Analyzer",
+		"optimize":  "Optimizer",
+		"test":      "Tester",
+		"document":  "Documentor",
+		"summarize": "Summarizer",
+	}
+	script := harness.Script{"Orchestrator": {harness.CallTool("get_workflow_state", nil)}}
+	var handoffs, toolCalls []string
+	toolCalls = append(toolCalls, "get_workflow_state")
+	for _, phase := range workflowPhases {
+		specialist := specialists[phase]
+		script["Orchestrator"] = append(script["Orchestrator"],
+			harness.CallTool("update_workflow_phase", map[string]interface{}{"phase": phase}),
+			harness.Handoff(specialist, "Work on the "+phase+" phase"),
+			harness.CallTool("update_workflow_phase", map[string]interface{}{"phase": "complete_current"}),
+		)
+		script[specialist] = []*model.Response{
+			harness.CallTool("get_workflow_state", nil),
+			harness.Handoff("Orchestrator", specialist+" is done"),
+		}
+		handoffs = append(handoffs, specialist, "Orchestrator")
+		toolCalls = append(toolCalls, "update_workflow_phase", "get_workflow_state", "update_workflow_phase")
+	}
+	script["Orchestrator"] = append(script["Orchestrator"],
+		harness.CallTool("update_workflow_phase", map[string]interface{}{"phase": "complete"}),
+		harness.Answer("Final report"),
+	)
+	toolCalls = append(toolCalls, "update_workflow_phase")
+
+	pad := scratchpad.New()
+	run := harness.Execute(t, runner.NewRunner(), orchestrator, script, &runner.RunOptions{
+		Input:     "Please review and improve this code:\n\n" + sampleCode,
+		MaxTurns:  maxTurns,
+		RunConfig: &runner.RunConfig{Scratchpad: pad},
+	})
+	require.NoError(t, run.Err)
+
+	assert.Equal(t, handoffs, run.Handoffs())
+	assert.Equal(t, toolCalls, run.ToolCalls())
+	assert.Equal(t, "Final report", run.Output())
+	assert.Empty(t, run.Unused())
+
+	completed, _ := pad.Get("completed_phases")
+	assert.Equal(t, workflowPhases, completed)
+	assert.Empty(t, remainingPhases(pad))
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/harness"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssistantTellsTheTime(t *testing.T) {
+	assistant := newAssistant(&mocks.MockModelProvider{})
+
+	run := harness.Execute(t, runner.NewRunner(), assistant, harness.Script{
+		"OpenAI Assistant": {
+			harness.CallTool("get_current_time", map[string]interface{}{"format": "unix"}),
+			harness.Answer("It is 12:00."),
+		},
+	}, &runner.RunOptions{Input: "What's the current time?", MaxTurns: 10})
+	require.NoError(t, run.Err)
+
+	assert.Equal(t, []string{"get_current_time"}, run.ToolCalls())
+	require.Len(t, run.ToolResults("get_current_time"), 1)
+	assert.IsType(t, int64(0), run.ToolResults("get_current_time")[0])
+	assert.Equal(t, "It is 12:00.", run.Output())
+	assert.Empty(t, run.Unused())
+}
+
+func TestAssistantStreams(t *testing.T) {
+	assistant := newAssistant(&mocks.MockModelProvider{})
+
+	run := harness.Stream(t, runner.NewRunner(), assistant, harness.Script{
+		"OpenAI Assistant": {harness.Answer("1, 2, 3, 4, 5")},
+	}, &runner.RunOptions{Input: "Count from 1 to 5 slowly, with a brief pause between each number."})
+	require.NoError(t, run.Err)
+
+	assert.Equal(t, "1, 2, 3, 4, 5", run.Content)
+	assert.Empty(t, run.Unused())
+}
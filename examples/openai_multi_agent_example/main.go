@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/openai"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
@@ -42,51 +43,8 @@ func main() {
 	fmt.Println("- Rate limit:", "60 requests/min, 150,000 tokens/min")
 	fmt.Println("- Max retries:", 3)
 
-	// Create a simple agent
-	assistant := agent.NewAgent("Assistant")
-	assistant.SetModelProvider(provider)
-	assistant.WithModel("gpt-4o-mini")
-	assistant.SetSystemInstructions(`You are a helpful assistant that can provide information and answer questions.
-You can use tools to get information that you might not know, like the current time.`)
-
-	// Time Tool
-	timeTool := tool.NewFunctionTool(
-		"get_current_time",
-		"Get the current time in a specified format. This tool will return the current system time, not the time in a specific location.",
-		func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
-			format := time.RFC3339
-
-			if formatParam, ok := params["format"].(string); ok && formatParam != "" {
-				switch formatParam {
-				case "rfc3339":
-					format = time.RFC3339
-				case "kitchen":
-					format = time.Kitchen
-				case "date":
-					format = "2006-01-02"
-				case "datetime":
-					format = "2006-01-02 15:04:05"
-				case "unix":
-					return time.Now().Unix(), nil
-				}
-			}
-
-			return time.Now().Format(format), nil
-		},
-	).WithSchema(map[string]interface{}{
-		"type": "object",
-		"properties": map[string]interface{}{
-			"format": map[string]interface{}{
-				"type":        "string",
-				"enum":        []string{"rfc3339", "kitchen", "date", "datetime", "unix"},
-				"description": "The format to return the time in. Options: rfc3339, kitchen, date, datetime, unix",
-			},
-		},
-		"required": []string{},
-	})
-
-	// Add tools to the agent
-	assistant.WithTools(timeTool)
+	// Create the assistant
+	assistant := newAssistant(provider)
 
 	// Create a runner
 	r := runner.NewRunner()
@@ -137,3 +95,54 @@ You can use tools to get information that you might not know, like the current t
 		}
 	}
 }
+
+// newAssistant creates the assistant, which can tell the time
+func newAssistant(provider model.Provider) *agent.Agent {
+	// Create a simple agent
+	assistant := agent.NewAgent("Assistant")
+	assistant.SetModelProvider(provider)
+	assistant.WithModel("gpt-4o-mini")
+	assistant.SetSystemInstructions(`You are a helpful assistant that can provide information and answer questions.
+You can use tools to get information that you might not know, like the current time.`)
+
+	// Time Tool
+	timeTool := tool.NewFunctionTool(
+		"get_current_time",
+		"Get the current time in a specified format. This tool will return the current system time, not the time in a specific location.",
+		func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			format := time.RFC3339
+
+			if formatParam, ok := params["format"].(string); ok && formatParam != "" {
+				switch formatParam {
+				case "rfc3339":
+					format = time.RFC3339
+				case "kitchen":
+					format = time.Kitchen
+				case "date":
+					format = "2006-01-02"
+				case "datetime":
+					format = "2006-01-02 15:04:05"
+				case "unix":
+					return time.Now().Unix(), nil
+				}
+			}
+
+			return time.Now().Format(format), nil
+		},
+	).WithSchema(map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"format": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"rfc3339", "kitchen", "date", "datetime", "unix"},
+				"description": "The format to return the time in. Options: rfc3339, kitchen, date, datetime, unix",
+			},
+		},
+		"required": []string{},
+	})
+
+	// Add tools to the agent
+	assistant.WithTools(timeTool)
+
+	return assistant
+}
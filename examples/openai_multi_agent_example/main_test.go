@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/harness"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssistantTellsTheTimeInTwoFormats(t *testing.T) {
+	assistant := newAssistant(&mocks.MockModelProvider{})
+
+	run := harness.Execute(t, runner.NewRunner(), assistant, harness.Script{
+		"Assistant": {
+			harness.CallTool("get_current_time", map[string]interface{}{"format": "rfc3339"}),
+			harness.CallTool("get_current_time", map[string]interface{}{"format": "kitchen"}),
+			harness.Answer("It is 2025-01-01T12:00:00Z, or 12:00PM."),
+		},
+	}, &runner.RunOptions{Input: "Tell me the current time in both RFC3339 format and kitchen format.", MaxTurns: 10})
+	require.NoError(t, run.Err)
+
+	assert.Equal(t, []string{"get_current_time", "get_current_time"}, run.ToolCalls())
+	assert.Equal(t, "It is 2025-01-01T12:00:00Z, or 12:00PM.", run.Output())
+	assert.Empty(t, run.Unused())
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/harness"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// filterCode is the code the scripted coder writes
+const filterCode = `interface Criteria { field: string; value: unknown }
+export function filterBy<T>(items: T[], criteria: Criteria[]): T[] {
+  return items.filter(item => criteria.every(c => (item as any)[c.field] === c.value));
+}
+test("filters", () => expect(filterBy([], [])).toEqual([]));`
+
+func TestDevelopmentWorkflowWritesAndReviewsCode(t *testing.T) {
+	require.NoError(t, prompts.LoadFS(promptFiles, "prompts"))
+
+	r := runner.NewRunner()
+	orchestrator := newDevelopmentWorkflow(r, &mocks.MockModelProvider{})
+	hooks := &TaskTrackingHooks{WorkContext: make(map[string]interface{})}
+
+	run := harness.Execute(t, r, orchestrator, harness.Script{
+		"Orchestrator": {
+			harness.Handoff("CoderAgent", "Write the filter function"),
+			harness.Handoff("ReviewerAgent", "Review the filter function:\n"+filterCode),
+			harness.Answer("The filter function is written and approved."),
+		},
+		"CoderAgent": {
+			harness.CallTool("validate_ts_code", map[string]interface{}{"code": filterCode}),
+			harness.Handoff("Orchestrator", filterCode),
+		},
+		"ReviewerAgent": {harness.Handoff("Orchestrator", "Approved")},
+	}, &runner.RunOptions{
+		Input:     "I need a TypeScript function with the following requirements: " + functionRequirement,
+		MaxTurns:  15,
+		Hooks:     hooks,
+		RunConfig: &runner.RunConfig{ReviewDiffs: true},
+	})
+	require.NoError(t, run.Err)
+
+	assert.Equal(t, []string{"CoderAgent", "Orchestrator", "ReviewerAgent", "Orchestrator"}, run.Handoffs())
+	assert.Equal(t, []string{"validate_ts_code"}, run.ToolCalls())
+	require.Len(t, run.ToolResults("validate_ts_code"), 1)
+	assert.Equal(t, true, run.ToolResults("validate_ts_code")[0].(map[string]interface{})["valid"])
+	assert.Equal(t, "The filter function is written and approved.", run.Output())
+	assert.Empty(t, run.Unused())
+
+	assert.Equal(t, "Orchestrator", hooks.WorkContext["initial_agent"])
+	assert.Equal(t, "Orchestrator", hooks.WorkContext["last_agent"])
+}
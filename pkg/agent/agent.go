@@ -1,11 +1,16 @@
 package agent
 
 import (
+	"context"
+	"fmt"
 	"reflect"
 	"strings"
 	"sync"
 
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/guardrail"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/output"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
 )
 
@@ -16,22 +21,78 @@ type Agent struct {
 	Instructions string
 	Description  string
 
+	// InstructionsFunc generates the instructions for each turn, in place of
+	// Instructions
+	InstructionsFunc InstructionsFunc
+
 	// Model configuration
 	Model         interface{} // Can be a string (model name) or a Model instance
 	ModelSettings *model.Settings
+	PromptCaching bool
 
 	// Capabilities
-	Tools    []tool.Tool
-	Handoffs []*Agent
+	Tools             []tool.Tool
+	Handoffs          []*Agent
+	BroadcastHandoffs []*BroadcastHandoff
+	MCPServers        []MCPServer
+
+	// HandoffDeclarations name and describe the tools of handoffs, by target name
+	HandoffDeclarations map[string]*Handoff
+
+	// HandoffFilters shape what handoffs pass to their targets, by target name
+	HandoffFilters map[string]HandoffFilter
+
+	// HandoffInputType is the type of the input the agent takes when handed off
+	// to, in place of a single input string
+	HandoffInputType reflect.Type
 
 	// Output configuration
-	OutputType reflect.Type
+	OutputType       reflect.Type
+	OutputProcessors []output.Processor
+
+	// Guardrails
+	InputGuardrails  []guardrail.InputGuardrail
+	OutputGuardrails []guardrail.OutputGuardrail
+
+	// MaxTurnsPerDelegation is the number of turns the agent may take on a task
+	// delegated to it before the task fails and returns to the delegating agent,
+	// or zero for no limit
+	MaxTurnsPerDelegation int
 
 	// Lifecycle hooks
 	Hooks Hooks
 
+	// Logger logs what the agent does, in place of the runner's logger
+	Logger logging.Logger
+
 	// Internal state
-	mu sync.RWMutex
+	mu         sync.RWMutex
+	mcpLoaded  int
+	schemas    *ToolSchemas
+	schemasKey schemaKey
+}
+
+// RunContext describes the run and turn an agent's instructions are generated for
+type RunContext struct {
+	// RunID identifies the run
+	RunID string
+
+	// Turn is the turn of the run, starting at 1
+	Turn int
+
+	// Input is the input the run started with
+	Input interface{}
+}
+
+// InstructionsFunc generates the system instructions of an agent for a turn, so
+// they can include runtime data such as the user's profile, the date or
+// retrieved facts
+type InstructionsFunc func(ctx context.Context, run RunContext) string
+
+// MCPServer provides tools from a Model Context Protocol server
+type MCPServer interface {
+	// ListTools returns the tools offered by the server
+	ListTools(ctx context.Context) ([]tool.Tool, error)
 }
 
 // NewAgent creates a new agent with the given name and instructions
@@ -68,11 +129,21 @@ func (a *Agent) WithModelSettings(settings *model.Settings) *Agent {
 	return a
 }
 
+// WithPromptCaching caches the agent's system instructions and tool schemas with
+// the model provider, so that turns after the first pay less for a long prompt
+func (a *Agent) WithPromptCaching() *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.PromptCaching = true
+	return a
+}
+
 // WithTools adds tools to the agent
 func (a *Agent) WithTools(tools ...tool.Tool) *Agent {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	a.Tools = append(a.Tools, tools...)
+	a.schemas = nil
 	return a
 }
 
@@ -81,14 +152,80 @@ func (a *Agent) WithHandoffs(handoffs ...*Agent) *Agent {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	a.Handoffs = append(a.Handoffs, handoffs...)
+	a.schemas = nil
+	return a
+}
+
+// WithInputGuardrails adds guardrails that check the input when a run starts with this agent
+func (a *Agent) WithInputGuardrails(guardrails ...guardrail.InputGuardrail) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.InputGuardrails = append(a.InputGuardrails, guardrails...)
+	return a
+}
+
+// WithOutputGuardrails adds guardrails that check the final output when this agent produces it
+func (a *Agent) WithOutputGuardrails(guardrails ...guardrail.OutputGuardrail) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.OutputGuardrails = append(a.OutputGuardrails, guardrails...)
+	return a
+}
+
+// WithOutputProcessors adds processors that clean up the final text output of this
+// agent, such as stripping code fences, before guardrails and callers see it
+func (a *Agent) WithOutputProcessors(processors ...output.Processor) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.OutputProcessors = append(a.OutputProcessors, processors...)
 	return a
 }
 
+// WithBroadcastHandoffs adds handoffs that dispatch one task to several agents at once
+func (a *Agent) WithBroadcastHandoffs(broadcasts ...*BroadcastHandoff) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.BroadcastHandoffs = append(a.BroadcastHandoffs, broadcasts...)
+	a.schemas = nil
+	return a
+}
+
+// WithMCPServer adds an MCP server whose tools are registered with the agent before its first turn
+func (a *Agent) WithMCPServer(server MCPServer) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.MCPServers = append(a.MCPServers, server)
+	return a
+}
+
+// LoadMCPTools registers the tools of MCP servers that have not been loaded yet
+func (a *Agent) LoadMCPTools(ctx context.Context) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for a.mcpLoaded < len(a.MCPServers) {
+		tools, err := a.MCPServers[a.mcpLoaded].ListTools(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to load MCP tools for agent %s: %w", a.Name, err)
+		}
+		a.Tools = append(a.Tools, tools...)
+		a.schemas = nil
+		a.mcpLoaded++
+	}
+	return nil
+}
+
 // WithOutputType sets the output type for the agent
 func (a *Agent) WithOutputType(outputType interface{}) *Agent {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
+	a.OutputType = outputTypeOf(outputType)
+	return a
+}
+
+// outputTypeOf returns the type of an output value, dereferencing pointers
+func outputTypeOf(outputType interface{}) reflect.Type {
 	// Get the type of the output type
 	t := reflect.TypeOf(outputType)
 
@@ -96,9 +233,7 @@ func (a *Agent) WithOutputType(outputType interface{}) *Agent {
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
-
-	a.OutputType = t
-	return a
+	return t
 }
 
 // WithHooks sets the lifecycle hooks for the agent
@@ -109,31 +244,33 @@ func (a *Agent) WithHooks(hooks Hooks) *Agent {
 	return a
 }
 
-// Clone creates a copy of the agent with optional overrides
-func (a *Agent) Clone(overrides map[string]interface{}) *Agent {
-	a.mu.RLock()
-	defer a.mu.RUnlock()
-
-	// Create a new agent with the same properties
-	clone := &Agent{
-		Name:          a.Name,
-		Instructions:  a.Instructions,
-		Description:   a.Description,
-		Model:         a.Model,
-		ModelSettings: a.ModelSettings,
-		Tools:         make([]tool.Tool, len(a.Tools)),
-		Handoffs:      make([]*Agent, len(a.Handoffs)),
-		OutputType:    a.OutputType,
-		Hooks:         a.Hooks,
-	}
+// WithLogger sets the logger of the agent's runs, in place of the runner's logger
+func (a *Agent) WithLogger(logger logging.Logger) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.Logger = logger
+	return a
+}
 
-	// Copy tools
-	copy(clone.Tools, a.Tools)
+// WithMaxTurnsPerDelegation limits the turns the agent takes on a task delegated
+// to it, so that a runaway delegate cannot use up the turns of the whole run
+func (a *Agent) WithMaxTurnsPerDelegation(turns int) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.MaxTurnsPerDelegation = turns
+	return a
+}
 
-	// Copy handoffs
-	copy(clone.Handoffs, a.Handoffs)
+// log returns the logger of the agent
+func (a *Agent) log() logging.Logger {
+	return logging.For(a.Logger, "agent")
+}
 
-	// Apply overrides
+// Clone creates a copy of the agent with optional overrides of its Name,
+// Instructions, Description, Model, ModelSettings, OutputType and Hooks. Prefer
+// CloneWith, which takes the same options as New.
+func (a *Agent) Clone(overrides map[string]interface{}) *Agent {
+	clone := a.CloneWith()
 	for key, value := range overrides {
 		switch key {
 		case "Name":
@@ -152,10 +289,115 @@ func (a *Agent) Clone(overrides map[string]interface{}) *Agent {
 			clone.Hooks = value.(Hooks)
 		}
 	}
+	return clone
+}
+
+// CloneWith returns a deep copy of the agent with options applied to the copy.
+// The copy shares tools, handoff targets and hooks with the agent but none of
+// its slices or settings, so configuring either one leaves the other untouched.
+// Use it to derive a variant of an agent that concurrent runs already use.
+func (a *Agent) CloneWith(opts ...Option) *Agent {
+	a.mu.RLock()
+	clone := &Agent{
+		Name:          a.Name,
+		Instructions:  a.Instructions,
+		Description:   a.Description,
+		Model:         a.Model,
+		ModelSettings: cloneSettings(a.ModelSettings),
+		PromptCaching: a.PromptCaching,
+		Logger:        a.Logger,
+		OutputType:    a.OutputType,
+		Hooks:         a.Hooks,
+		mcpLoaded:     a.mcpLoaded,
+
+		InstructionsFunc: a.InstructionsFunc,
+		HandoffInputType: a.HandoffInputType,
+
+		MaxTurnsPerDelegation: a.MaxTurnsPerDelegation,
 
+		Tools:             append(make([]tool.Tool, 0, len(a.Tools)), a.Tools...),
+		Handoffs:          append(make([]*Agent, 0, len(a.Handoffs)), a.Handoffs...),
+		BroadcastHandoffs: append([]*BroadcastHandoff(nil), a.BroadcastHandoffs...),
+		MCPServers:        append([]MCPServer(nil), a.MCPServers...),
+		OutputProcessors:  append([]output.Processor(nil), a.OutputProcessors...),
+		InputGuardrails:   append([]guardrail.InputGuardrail(nil), a.InputGuardrails...),
+		OutputGuardrails:  append([]guardrail.OutputGuardrail(nil), a.OutputGuardrails...),
+	}
+	for target, filter := range a.HandoffFilters {
+		clone.setHandoffFilter(target, filter)
+	}
+	for target, h := range a.HandoffDeclarations {
+		if clone.HandoffDeclarations == nil {
+			clone.HandoffDeclarations = make(map[string]*Handoff)
+		}
+		clone.HandoffDeclarations[target] = h
+	}
+	a.mu.RUnlock()
+
+	for _, opt := range opts {
+		opt(clone)
+	}
 	return clone
 }
 
+// cloneSettings returns a deep copy of model settings
+func cloneSettings(settings *model.Settings) *model.Settings {
+	if settings == nil {
+		return nil
+	}
+	copied := *settings
+	copied.Temperature = clonePointer(settings.Temperature)
+	copied.TopP = clonePointer(settings.TopP)
+	copied.FrequencyPenalty = clonePointer(settings.FrequencyPenalty)
+	copied.PresencePenalty = clonePointer(settings.PresencePenalty)
+	copied.ToolChoice = clonePointer(settings.ToolChoice)
+	copied.ParallelToolCalls = clonePointer(settings.ParallelToolCalls)
+	copied.MaxTokens = clonePointer(settings.MaxTokens)
+	if settings.ResponseFormat != nil {
+		format := *settings.ResponseFormat
+		format.Schema, _ = cloneSchemaValue(settings.ResponseFormat.Schema).(map[string]interface{})
+		copied.ResponseFormat = &format
+	}
+	return &copied
+}
+
+// clonePointer returns a pointer to a copy of the value p points to
+func clonePointer[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	copied := *p
+	return &copied
+}
+
+// cloneSchemaValue returns a deep copy of a value of a JSON schema
+func cloneSchemaValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if v == nil {
+			return v
+		}
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = cloneSchemaValue(item)
+		}
+		return copied
+	case []interface{}:
+		if v == nil {
+			return v
+		}
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = cloneSchemaValue(item)
+		}
+		return copied
+	case []string:
+		return append([]string(nil), v...)
+	default:
+		return value
+	}
+}
+
 // AddFunctionTool adds a function as a tool to the agent
 func (a *Agent) AddFunctionTool(name, description string, fn interface{}) *Agent {
 	functionTool := tool.NewFunctionTool(name, description, fn)
@@ -184,6 +426,27 @@ func (a *Agent) SetSystemInstructions(instructions string) *Agent {
 	return a
 }
 
+// SetInstructionsFunc generates the system instructions for each turn with a
+// function, in place of the static instructions
+func (a *Agent) SetInstructionsFunc(fn InstructionsFunc) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.InstructionsFunc = fn
+	return a
+}
+
+// ResolveInstructions returns the system instructions of the agent for a turn of
+// a run
+func (a *Agent) ResolveInstructions(ctx context.Context, run RunContext) string {
+	a.mu.RLock()
+	fn, instructions := a.InstructionsFunc, a.Instructions
+	a.mu.RUnlock()
+	if fn == nil {
+		return instructions
+	}
+	return fn(ctx, run)
+}
+
 // AddToolFromDefinition adds a tool from an OpenAI-compatible tool definition
 func (a *Agent) AddToolFromDefinition(definition map[string]interface{}, executeFn func(map[string]interface{}) (interface{}, error)) *Agent {
 	// Create a tool from the definition
@@ -248,6 +511,7 @@ func (a *Agent) WithBidirectionalHandoffs(agents ...*Agent) *Agent {
 		returnAgent := NewAgent("return_to_delegator", "Special agent used to return to the delegating agent")
 		a.Handoffs = append(a.Handoffs, returnAgent)
 	}
+	a.schemas = nil
 
 	return a
 }
@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"context"
+	"sync"
+)
+
+// BroadcastResult is the outcome of one executor of a broadcast handoff
+type BroadcastResult struct {
+	// AgentName is the name of the executor agent
+	AgentName string
+
+	// TaskID is the ID of the subtask the executor worked on
+	TaskID string
+
+	// Output is the final output of the executor
+	Output interface{}
+
+	// Error is set if the executor failed
+	Error error
+}
+
+// ReconcileFunc merges the results of a broadcast handoff into a single output
+type ReconcileFunc func(ctx context.Context, input interface{}, results []BroadcastResult) (interface{}, error)
+
+// BroadcastHandoff dispatches the same task to several executor agents at once.
+// Once every executor has returned, the results are merged by a reconciler agent
+// or function before the delegator resumes.
+type BroadcastHandoff struct {
+	// Name is the name the model uses to invoke the broadcast
+	Name string
+
+	// Description tells the model when to use the broadcast
+	Description string
+
+	// Executors are the agents that receive the task
+	Executors []*Agent
+
+	// Reconciler is an agent that merges the executor results
+	Reconciler *Agent
+
+	// ReconcileFunc merges the executor results, it takes precedence over Reconciler
+	ReconcileFunc ReconcileFunc
+
+	mu sync.RWMutex
+}
+
+// NewBroadcastHandoff creates a new broadcast handoff to the given executors
+func NewBroadcastHandoff(name string, executors ...*Agent) *BroadcastHandoff {
+	return &BroadcastHandoff{
+		Name:      name,
+		Executors: executors,
+	}
+}
+
+// WithDescription sets the description shown to the model
+func (b *BroadcastHandoff) WithDescription(description string) *BroadcastHandoff {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.Description = description
+	return b
+}
+
+// WithReconciler sets an agent that merges the executor results
+func (b *BroadcastHandoff) WithReconciler(reconciler *Agent) *BroadcastHandoff {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.Reconciler = reconciler
+	return b
+}
+
+// WithReconcileFunc sets a function that merges the executor results
+func (b *BroadcastHandoff) WithReconcileFunc(fn ReconcileFunc) *BroadcastHandoff {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.ReconcileFunc = fn
+	return b
+}
@@ -0,0 +1,202 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// HandoffToolPrefix is the prefix of the default names of handoff tools
+const HandoffToolPrefix = "handoff_to_"
+
+// maxToolNameLength is the longest tool name providers accept
+const maxToolNameLength = 64
+
+// Handoff declares the tool a model calls to hand off to an agent. Without a
+// declaration the tool is named after the agent, as handoff_to_<name>, and has a
+// generic description.
+type Handoff struct {
+	// Target is the agent handed off to
+	Target *Agent
+
+	// ToolName is the name of the tool; it is sanitized for providers
+	ToolName string
+
+	// Description tells the model when to hand off
+	Description string
+
+	mu sync.RWMutex
+}
+
+// NewHandoff creates a declaration of the handoff to an agent
+func NewHandoff(target *Agent) *Handoff {
+	return &Handoff{Target: target}
+}
+
+// WithToolName sets the name of the tool, such as "transfer_to_reviewer"
+func (h *Handoff) WithToolName(name string) *Handoff {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ToolName = name
+	return h
+}
+
+// WithDescription sets the description shown to the model
+func (h *Handoff) WithDescription(description string) *Handoff {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.Description = description
+	return h
+}
+
+// Name returns the sanitized name of the tool
+func (h *Handoff) Name() string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.ToolName != "" {
+		return SanitizeToolName(h.ToolName)
+	}
+	return HandoffToolName(h.Target.Name)
+}
+
+// Schema returns the definition of the tool. Its parameters are the schema of the
+// target's handoff input type, or a single input string.
+func (h *Handoff) Schema() map[string]interface{} {
+	name := h.Name()
+	h.mu.RLock()
+	description := h.Description
+	h.mu.RUnlock()
+
+	target := h.Target
+	if description == "" {
+		description = fmt.Sprintf("Handoff the conversation to the %s. Use this when a query requires expertise from %s.", target.Name, target.Name)
+	}
+	parameters := handoffInputSchema(target.HandoffInputType)
+	if parameters == nil {
+		parameters = map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"input": map[string]interface{}{
+					"type":        "string",
+					"description": "The specific request to send to the agent. Be clear about what you're asking the agent to do.",
+				},
+			},
+			"required": []string{"input"},
+		}
+	}
+	return map[string]interface{}{
+		"type": "function",
+		"function": map[string]interface{}{
+			"name":        name,
+			"description": description,
+			"parameters":  parameters,
+		},
+	}
+}
+
+// HandoffToolName returns the default name of the tool handing off to an agent
+func HandoffToolName(agentName string) string {
+	return SanitizeToolName(HandoffToolPrefix + agentName)
+}
+
+// SanitizeToolName returns a name providers accept as a tool name: letters,
+// digits, underscores and dashes, at most 64 characters. Other characters, such
+// as the spaces of "Design Agent", become underscores.
+func SanitizeToolName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+		if b.Len() >= maxToolNameLength {
+			break
+		}
+	}
+	return b.String()
+}
+
+// WithHandoff adds a declared handoff, adding its target to the handoffs
+func WithHandoff(h *Handoff) Option {
+	return func(a *Agent) {
+		a.addHandoff(h)
+	}
+}
+
+// WithHandoff adds a declared handoff, adding its target to the handoffs. A
+// declaration for a target that is already a handoff replaces its tool name and
+// description.
+func (a *Agent) WithHandoff(h *Handoff) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.addHandoff(h)
+	return a
+}
+
+// addHandoff stores a handoff declaration; the caller holds the lock if needed
+func (a *Agent) addHandoff(h *Handoff) {
+	if a.HandoffDeclarations == nil {
+		a.HandoffDeclarations = make(map[string]*Handoff)
+	}
+	a.HandoffDeclarations[h.Target.Name] = h
+
+	for _, existing := range a.Handoffs {
+		if existing == h.Target {
+			a.schemas = nil
+			return
+		}
+	}
+	a.Handoffs = append(a.Handoffs, h.Target)
+	a.schemas = nil
+}
+
+// HandoffFor returns the declaration of the handoff to a target, or the default
+// declaration if it has none
+func (a *Agent) HandoffFor(target *Agent) *Handoff {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.handoffFor(target)
+}
+
+// handoffFor returns the declaration of a handoff; the caller holds the lock
+func (a *Agent) handoffFor(target *Agent) *Handoff {
+	if h, ok := a.HandoffDeclarations[target.Name]; ok && h.Target == target {
+		return h
+	}
+	return NewHandoff(target)
+}
+
+// HandoffTarget returns the handoff target a model named in a handoff call. The
+// name may be the agent's name, its sanitized name as recovered from a
+// handoff_to_ tool name, or the name of a declared handoff tool.
+func (a *Agent) HandoffTarget(name string) (*Agent, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, target := range a.Handoffs {
+		if target.Name == name {
+			return target, true
+		}
+	}
+	for _, target := range a.Handoffs {
+		tool := a.handoffFor(target).Name()
+		if tool == name || tool == HandoffToolName(name) || SanitizeToolName(target.Name) == name {
+			return target, true
+		}
+	}
+	return nil, false
+}
+
+// HandoffToolTarget returns the target of the handoff whose tool has the given
+// name, for tool calls providers did not recognize as handoffs
+func (a *Agent) HandoffToolTarget(toolName string) (*Agent, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, target := range a.Handoffs {
+		if a.handoffFor(target).Name() == toolName {
+			return target, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+)
+
+// HandoffContext is what a handoff passes to the agent it delegates to. Handoff
+// filters shape it before the target agent sees it.
+type HandoffContext struct {
+	// Context is the context of the run
+	Context context.Context
+
+	// From and To are the names of the delegating and the target agent
+	From string
+	To   string
+
+	// Input is the task the delegating agent gave the target
+	Input interface{}
+
+	// Conversation is the history of the delegating agent at the handoff. It is
+	// for filters to read; the target only sees History.
+	Conversation []interface{}
+
+	// History is the history the target agent starts with, before Input. It is
+	// empty unless a filter fills it.
+	History []interface{}
+
+	// Artifact is the work product passed along, such as code, with its type.
+	// It is the delegating task's artifact unless a filter changes it.
+	Artifact     interface{}
+	ArtifactType string
+
+	// Metadata is added to the target's input: as a "metadata" field of map
+	// inputs, or as lines after text inputs
+	Metadata map[string]interface{}
+}
+
+// HandoffFilter shapes what a handoff passes to its target agent
+type HandoffFilter func(HandoffContext) HandoffContext
+
+// HistorySummarizer condenses the conversation of a delegating agent into text
+type HistorySummarizer func(ctx context.Context, history []interface{}) (string, error)
+
+// handoffSummaryPrefix introduces the summary of the delegating agent's conversation
+const handoffSummaryPrefix = "Summary of the conversation before this task was handed to you:\n\n"
+
+// WithHandoffFilter shapes what the handoff to the agent named target passes to it
+func WithHandoffFilter(target string, filter HandoffFilter) Option {
+	return func(a *Agent) {
+		a.setHandoffFilter(target, filter)
+	}
+}
+
+// WithHandoffFilter shapes what the handoff to the agent named target passes to it.
+// Filters apply to delegations; the filter of a target replaces any earlier one.
+func (a *Agent) WithHandoffFilter(target string, filter HandoffFilter) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.setHandoffFilter(target, filter)
+	return a
+}
+
+// setHandoffFilter stores a handoff filter; the caller holds the lock if needed
+func (a *Agent) setHandoffFilter(target string, filter HandoffFilter) {
+	if a.HandoffFilters == nil {
+		a.HandoffFilters = make(map[string]HandoffFilter)
+	}
+	a.HandoffFilters[target] = filter
+}
+
+// HandoffFilterFor returns the filter of the handoff to the agent named target
+func (a *Agent) HandoffFilterFor(target string) (HandoffFilter, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	filter, ok := a.HandoffFilters[target]
+	return filter, ok
+}
+
+// ChainHandoffFilters returns a filter applying filters in order
+func ChainHandoffFilters(filters ...HandoffFilter) HandoffFilter {
+	return func(hc HandoffContext) HandoffContext {
+		for _, filter := range filters {
+			hc = filter(hc)
+		}
+		return hc
+	}
+}
+
+// CleanSlate passes the target only the task: no history, artifact or metadata
+func CleanSlate() HandoffFilter {
+	return func(hc HandoffContext) HandoffContext {
+		hc.History = nil
+		hc.Artifact = nil
+		hc.ArtifactType = ""
+		hc.Metadata = nil
+		return hc
+	}
+}
+
+// FullHistory passes the target the most recent items of the delegating agent's
+// conversation, all of them if last is zero
+func FullHistory(last int) HandoffFilter {
+	return func(hc HandoffContext) HandoffContext {
+		history := hc.Conversation
+		if last > 0 && len(history) > last {
+			history = history[len(history)-last:]
+		}
+		hc.History = append([]interface{}(nil), history...)
+		return hc
+	}
+}
+
+// SummarizedHistory passes the target a summary of the delegating agent's
+// conversation. If summarizing fails, the target starts without history.
+func SummarizedHistory(summarize HistorySummarizer) HandoffFilter {
+	return func(hc HandoffContext) HandoffContext {
+		hc.History = nil
+		if len(hc.Conversation) == 0 {
+			return hc
+		}
+		ctx := hc.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		summary, err := summarize(ctx, hc.Conversation)
+		if err != nil {
+			logging.For(nil, "agent").Warn("Failed to summarize history for handoff", "from", hc.From, "to", hc.To, "error", err)
+			return hc
+		}
+		if summary = strings.TrimSpace(summary); summary != "" {
+			hc.History = []interface{}{model.DefaultMessageFormatter{}.FormatUserMessage(handoffSummaryPrefix + summary)}
+		}
+		return hc
+	}
+}
+
+// AddHandoffMetadata adds metadata to what the handoff passes to its target
+func AddHandoffMetadata(metadata map[string]interface{}) HandoffFilter {
+	return func(hc HandoffContext) HandoffContext {
+		merged := make(map[string]interface{}, len(hc.Metadata)+len(metadata))
+		for key, value := range hc.Metadata {
+			merged[key] = value
+		}
+		for key, value := range metadata {
+			merged[key] = value
+		}
+		hc.Metadata = merged
+		return hc
+	}
+}
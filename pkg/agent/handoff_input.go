@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+)
+
+// HandoffInputError is returned when the input of a handoff does not match the
+// input type of its target
+type HandoffInputError struct {
+	Agent    string
+	Problems []string
+}
+
+// Error implements the error interface
+func (e *HandoffInputError) Error() string {
+	return fmt.Sprintf("invalid handoff input for %s: %s", e.Agent, strings.Join(e.Problems, "; "))
+}
+
+// WithHandoffInputType sets the struct the agent takes as input when handed off
+// to, such as ReviewRequest{}. The handoff tool then exposes the struct's schema,
+// using json tags for field names and doc tags for descriptions, in place of a
+// single input string.
+func WithHandoffInputType(inputType interface{}) Option {
+	return func(a *Agent) {
+		a.HandoffInputType = outputTypeOf(inputType)
+	}
+}
+
+// WithHandoffInputType sets the struct the agent takes as input when handed off to.
+// Set it before adding the agent to the handoffs of others, whose tool schemas
+// are built once.
+func (a *Agent) WithHandoffInputType(inputType interface{}) *Agent {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.HandoffInputType = outputTypeOf(inputType)
+	return a
+}
+
+// HandoffInputSchema returns the JSON schema of the agent's handoff input, or nil
+// if the agent takes a single input string
+func (a *Agent) HandoffInputSchema() map[string]interface{} {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return handoffInputSchema(a.HandoffInputType)
+}
+
+// handoffInputSchema returns the schema of a handoff input type, or nil
+func handoffInputSchema(inputType reflect.Type) map[string]interface{} {
+	if inputType == nil {
+		return nil
+	}
+	return tool.TypeSchema(inputType)
+}
+
+// DecodeHandoffInput validates the input of a handoff to the agent against its
+// input type and returns it as a value of that type. The input may be the
+// arguments of the handoff call or their JSON text. An agent without an input
+// type returns the input as it is.
+func (a *Agent) DecodeHandoffInput(input interface{}) (interface{}, error) {
+	a.mu.RLock()
+	inputType := a.HandoffInputType
+	a.mu.RUnlock()
+	if inputType == nil {
+		return input, nil
+	}
+
+	if text, ok := input.(string); ok {
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &decoded); err != nil {
+			return nil, &HandoffInputError{Agent: a.Name, Problems: []string{"input must be a JSON object"}}
+		}
+		input = decoded
+	}
+	if input == nil {
+		return nil, &HandoffInputError{Agent: a.Name, Problems: []string{"input is missing"}}
+	}
+	if problems := tool.ValidateValue("input", input, handoffInputSchema(inputType)); len(problems) > 0 {
+		return nil, &HandoffInputError{Agent: a.Name, Problems: problems}
+	}
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode handoff input for %s: %w", a.Name, err)
+	}
+	value := reflect.New(inputType)
+	if err := json.Unmarshal(data, value.Interface()); err != nil {
+		return nil, &HandoffInputError{Agent: a.Name, Problems: []string{err.Error()}}
+	}
+	return value.Elem().Interface(), nil
+}
@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/guardrail"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/output"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+)
+
+// Option configures an agent created with New or CloneWith
+type Option func(*Agent)
+
+// New creates an agent configured by options. Unlike the With methods, which
+// change an agent in place, options only apply while the agent is built: an
+// agent that is not changed afterwards can be shared by concurrent runs, and
+// CloneWith derives variants of it without touching the original.
+//
+//	a := agent.New("Assistant",
+//		agent.WithInstructions("You are a helpful assistant."),
+//		agent.WithModel("gpt-4o"),
+//		agent.WithTools(searchTool),
+//	)
+func New(name string, opts ...Option) *Agent {
+	a := NewAgent(name)
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// WithName sets the name of the agent
+func WithName(name string) Option {
+	return func(a *Agent) {
+		a.Name = name
+	}
+}
+
+// WithInstructions sets the system instructions of the agent
+func WithInstructions(instructions string) Option {
+	return func(a *Agent) {
+		a.Instructions = instructions
+	}
+}
+
+// WithInstructionsFunc generates the system instructions of the agent for each turn
+func WithInstructionsFunc(fn InstructionsFunc) Option {
+	return func(a *Agent) {
+		a.InstructionsFunc = fn
+	}
+}
+
+// WithDescription sets the description other agents see when handing off to the agent
+func WithDescription(description string) Option {
+	return func(a *Agent) {
+		a.Description = description
+	}
+}
+
+// WithModel sets the model of the agent, a model name or a model.Model
+func WithModel(m interface{}) Option {
+	return func(a *Agent) {
+		a.Model = m
+	}
+}
+
+// WithModelSettings sets the model settings of the agent. The agent keeps a copy
+// of the settings.
+func WithModelSettings(settings *model.Settings) Option {
+	return func(a *Agent) {
+		a.ModelSettings = cloneSettings(settings)
+	}
+}
+
+// WithPromptCaching caches the agent's system instructions and tool schemas with
+// the model provider
+func WithPromptCaching() Option {
+	return func(a *Agent) {
+		a.PromptCaching = true
+	}
+}
+
+// WithTools adds tools to the agent
+func WithTools(tools ...tool.Tool) Option {
+	return func(a *Agent) {
+		a.Tools = append(a.Tools, tools...)
+	}
+}
+
+// WithHandoffs adds agents the agent can hand off to
+func WithHandoffs(handoffs ...*Agent) Option {
+	return func(a *Agent) {
+		a.Handoffs = append(a.Handoffs, handoffs...)
+	}
+}
+
+// WithBroadcastHandoffs adds handoffs that dispatch one task to several agents at once
+func WithBroadcastHandoffs(broadcasts ...*BroadcastHandoff) Option {
+	return func(a *Agent) {
+		a.BroadcastHandoffs = append(a.BroadcastHandoffs, broadcasts...)
+	}
+}
+
+// WithMCPServers adds MCP servers whose tools are registered before the agent's first turn
+func WithMCPServers(servers ...MCPServer) Option {
+	return func(a *Agent) {
+		a.MCPServers = append(a.MCPServers, servers...)
+	}
+}
+
+// WithInputGuardrails adds guardrails that check the input when a run starts with the agent
+func WithInputGuardrails(guardrails ...guardrail.InputGuardrail) Option {
+	return func(a *Agent) {
+		a.InputGuardrails = append(a.InputGuardrails, guardrails...)
+	}
+}
+
+// WithOutputGuardrails adds guardrails that check the final output of the agent
+func WithOutputGuardrails(guardrails ...guardrail.OutputGuardrail) Option {
+	return func(a *Agent) {
+		a.OutputGuardrails = append(a.OutputGuardrails, guardrails...)
+	}
+}
+
+// WithOutputProcessors adds processors that clean up the final text output of the agent
+func WithOutputProcessors(processors ...output.Processor) Option {
+	return func(a *Agent) {
+		a.OutputProcessors = append(a.OutputProcessors, processors...)
+	}
+}
+
+// WithOutputType sets the type of the agent's structured output
+func WithOutputType(outputType interface{}) Option {
+	return func(a *Agent) {
+		a.OutputType = outputTypeOf(outputType)
+	}
+}
+
+// WithHooks sets the lifecycle hooks of the agent
+func WithHooks(hooks Hooks) Option {
+	return func(a *Agent) {
+		a.Hooks = hooks
+	}
+}
+
+// WithMaxTurnsPerDelegation limits the turns the agent takes on a task delegated to it
+func WithMaxTurnsPerDelegation(turns int) Option {
+	return func(a *Agent) {
+		a.MaxTurnsPerDelegation = turns
+	}
+}
+
+// WithLogger sets the logger of the agent's runs, in place of the runner's logger
+func WithLogger(logger logging.Logger) Option {
+	return func(a *Agent) {
+		a.Logger = logger
+	}
+}
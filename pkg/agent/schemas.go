@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+)
+
+// ToolSchemas are the wire-format definitions of an agent's tools and handoffs, in
+// the OpenAI function format that every provider accepts. They are built once and
+// shared between requests, so they must not be modified.
+type ToolSchemas struct {
+	// Tools has one definition per tool, in the order of Agent.Tools
+	Tools []map[string]interface{}
+
+	// Handoffs has the definitions of the handoffs followed by the broadcast handoffs
+	Handoffs []map[string]interface{}
+}
+
+// schemaKey identifies the tool and handoff slices a ToolSchemas was built from,
+// so that direct assignments to the exported fields are noticed
+type schemaKey struct {
+	tools, handoffs, broadcasts int
+	firstTool                   *tool.Tool
+	firstHandoff                **Agent
+	firstBroadcast              **BroadcastHandoff
+}
+
+// ToolSchemas returns the definitions of the agent's tools and handoffs, building
+// them on first use and after the tools or handoffs change
+func (a *Agent) ToolSchemas() *ToolSchemas {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := a.schemaKey()
+	if a.schemas != nil && a.schemasKey == key {
+		return a.schemas
+	}
+
+	schemas := &ToolSchemas{
+		Tools:    make([]map[string]interface{}, len(a.Tools)),
+		Handoffs: make([]map[string]interface{}, 0, len(a.Handoffs)+len(a.BroadcastHandoffs)),
+	}
+	for i, t := range a.Tools {
+		schemas.Tools[i] = tool.ToOpenAITool(t)
+	}
+	for _, h := range a.Handoffs {
+		schemas.Handoffs = append(schemas.Handoffs, a.handoffFor(h).Schema())
+	}
+	for _, b := range a.BroadcastHandoffs {
+		schemas.Handoffs = append(schemas.Handoffs, BroadcastHandoffSchema(b))
+	}
+
+	a.log().Debug("Built tool schemas", "agent", a.Name, "tools", len(schemas.Tools), "handoffs", len(schemas.Handoffs))
+
+	a.schemas = schemas
+	a.schemasKey = key
+	return schemas
+}
+
+// InvalidateToolSchemas discards the cached schemas. Call it after changing a tool
+// or handoff in place, for example its description.
+func (a *Agent) InvalidateToolSchemas() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.schemas = nil
+}
+
+// schemaKey returns the key of the current tools and handoffs. The caller must hold a.mu.
+func (a *Agent) schemaKey() schemaKey {
+	key := schemaKey{tools: len(a.Tools), handoffs: len(a.Handoffs), broadcasts: len(a.BroadcastHandoffs)}
+	if len(a.Tools) > 0 {
+		key.firstTool = &a.Tools[0]
+	}
+	if len(a.Handoffs) > 0 {
+		key.firstHandoff = &a.Handoffs[0]
+	}
+	if len(a.BroadcastHandoffs) > 0 {
+		key.firstBroadcast = &a.BroadcastHandoffs[0]
+	}
+	return key
+}
+
+// HandoffSchema returns the definition of the tool the model calls to hand off
+// to an agent without a declared handoff
+func HandoffSchema(target *Agent) map[string]interface{} {
+	return NewHandoff(target).Schema()
+}
+
+// BroadcastHandoffSchema returns the definition of the tool the model calls to
+// start a broadcast handoff
+func BroadcastHandoffSchema(b *BroadcastHandoff) map[string]interface{} {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	names := make([]string, 0, len(b.Executors))
+	for _, executor := range b.Executors {
+		names = append(names, executor.Name)
+	}
+
+	description := b.Description
+	if description == "" {
+		description = fmt.Sprintf("Send the same request to %s at once and receive their reconciled answer.", strings.Join(names, ", "))
+	}
+
+	return map[string]interface{}{
+		"type": "function",
+		"function": map[string]interface{}{
+			"name":        HandoffToolName(b.Name),
+			"description": description,
+			"parameters": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"input": map[string]interface{}{
+						"type":        "string",
+						"description": "The request to send to every agent. Be clear about what you're asking them to do.",
+					},
+				},
+				"required": []string{"input"},
+			},
+		},
+	}
+}
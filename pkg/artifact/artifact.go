@@ -0,0 +1,117 @@
+// Package artifact stores binary payloads produced during a run, such as images
+// returned by a model, so that run results and conversation history can refer to
+// them by ID instead of carrying the data.
+package artifact
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when an artifact does not exist
+var ErrNotFound = errors.New("artifact not found")
+
+// Artifact describes a stored payload
+type Artifact struct {
+	// ID identifies the artifact in its store
+	ID string `json:"id"`
+
+	// Name is a human readable name, such as a file name
+	Name string `json:"name,omitempty"`
+
+	// MediaType is the MIME type of the payload
+	MediaType string `json:"media_type,omitempty"`
+
+	// Size is the size of the payload in bytes
+	Size int `json:"size"`
+
+	// CreatedAt is when the artifact was stored
+	CreatedAt time.Time `json:"created_at"`
+
+	// Metadata holds additional information, such as the agent that produced it
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Store keeps artifacts and their payloads
+type Store interface {
+	// Put stores a payload and returns its artifact with ID, Size and CreatedAt set.
+	// An empty ID is replaced by a generated one.
+	Put(ctx context.Context, artifact Artifact, data []byte) (Artifact, error)
+
+	// Get returns an artifact and its payload, or ErrNotFound
+	Get(ctx context.Context, id string) (Artifact, []byte, error)
+
+	// Delete removes an artifact. Deleting a missing artifact is not an error.
+	Delete(ctx context.Context, id string) error
+}
+
+// NewID generates a unique artifact ID
+func NewID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		// Fall back to a timestamp-based ID if crypto/rand fails
+		return fmt.Sprintf("artifact-%d", time.Now().UnixNano())
+	}
+	return fmt.Sprintf("artifact-%x", b)
+}
+
+// prepare fills in the generated fields of an artifact
+func prepare(artifact Artifact, data []byte) Artifact {
+	if artifact.ID == "" {
+		artifact.ID = NewID()
+	}
+	artifact.Size = len(data)
+	artifact.CreatedAt = time.Now().UTC()
+	return artifact
+}
+
+// MemoryStore is a Store that keeps artifacts in process memory
+type MemoryStore struct {
+	artifacts map[string]Artifact
+	data      map[string][]byte
+	mu        sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		artifacts: make(map[string]Artifact),
+		data:      make(map[string][]byte),
+	}
+}
+
+// Put stores a payload
+func (s *MemoryStore) Put(ctx context.Context, artifact Artifact, data []byte) (Artifact, error) {
+	artifact = prepare(artifact, data)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.artifacts[artifact.ID] = artifact
+	s.data[artifact.ID] = append([]byte(nil), data...)
+	return artifact, nil
+}
+
+// Get returns an artifact and its payload
+func (s *MemoryStore) Get(ctx context.Context, id string) (Artifact, []byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	artifact, ok := s.artifacts[id]
+	if !ok {
+		return Artifact{}, nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return artifact, append([]byte(nil), s.data[id]...), nil
+}
+
+// Delete removes an artifact
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.artifacts, id)
+	delete(s.data, id)
+	return nil
+}
@@ -0,0 +1,95 @@
+package artifact
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileStore is a Store that keeps each artifact as a payload file and a JSON
+// metadata file in a directory
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a store in the given directory, creating it if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Put stores a payload
+func (s *FileStore) Put(ctx context.Context, artifact Artifact, data []byte) (Artifact, error) {
+	artifact = prepare(artifact, data)
+	dataPath, metaPath, err := s.paths(artifact.ID)
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	meta, err := json.Marshal(artifact)
+	if err != nil {
+		return Artifact{}, fmt.Errorf("failed to encode artifact: %w", err)
+	}
+	if err := os.WriteFile(dataPath, data, 0o644); err != nil {
+		return Artifact{}, fmt.Errorf("failed to write artifact: %w", err)
+	}
+	if err := os.WriteFile(metaPath, meta, 0o644); err != nil {
+		return Artifact{}, fmt.Errorf("failed to write artifact metadata: %w", err)
+	}
+	return artifact, nil
+}
+
+// Get returns an artifact and its payload
+func (s *FileStore) Get(ctx context.Context, id string) (Artifact, []byte, error) {
+	dataPath, metaPath, err := s.paths(id)
+	if err != nil {
+		return Artifact{}, nil, err
+	}
+
+	meta, err := os.ReadFile(metaPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return Artifact{}, nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return Artifact{}, nil, fmt.Errorf("failed to read artifact metadata: %w", err)
+	}
+
+	var artifact Artifact
+	if err := json.Unmarshal(meta, &artifact); err != nil {
+		return Artifact{}, nil, fmt.Errorf("failed to decode artifact metadata: %w", err)
+	}
+	data, err := os.ReadFile(dataPath)
+	if err != nil {
+		return Artifact{}, nil, fmt.Errorf("failed to read artifact: %w", err)
+	}
+	return artifact, data, nil
+}
+
+// Delete removes an artifact
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	dataPath, metaPath, err := s.paths(id)
+	if err != nil {
+		return err
+	}
+	for _, path := range []string{metaPath, dataPath} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to delete artifact: %w", err)
+		}
+	}
+	return nil
+}
+
+// paths returns the payload and metadata paths of an artifact
+func (s *FileStore) paths(id string) (string, string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return "", "", fmt.Errorf("invalid artifact ID %q", id)
+	}
+	base := filepath.Join(s.dir, id)
+	return base + ".bin", base + ".json", nil
+}
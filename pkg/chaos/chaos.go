@@ -0,0 +1,212 @@
+// Package chaos injects faults into model providers, HTTP transports and tools,
+// so retry, fallback and resume logic can be exercised in integration tests.
+//
+// An Injector decides which fault, if any, each call receives. Faults are drawn
+// from a seeded random source, so a failing test can be replayed with the same seed:
+//
+//	injector := chaos.NewInjector(42).
+//		WithFault(chaos.FaultRateLimit, 0.2).
+//		WithFault(chaos.FaultTruncatedStream, 0.05)
+//	provider := chaos.WrapProvider(openai.NewProvider(apiKey), injector)
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+)
+
+// Fault is a kind of injected failure
+type Fault string
+
+// Faults
+const (
+	// FaultRateLimit fails the call with a 429 Too Many Requests error
+	FaultRateLimit Fault = "rate_limit"
+
+	// FaultServerError fails the call with a 500 Internal Server Error
+	FaultServerError Fault = "server_error"
+
+	// FaultTimeout blocks the call until its context ends or the hang duration passes
+	FaultTimeout Fault = "timeout"
+
+	// FaultMalformedJSON returns a response body that is not valid JSON
+	FaultMalformedJSON Fault = "malformed_json"
+
+	// FaultTruncatedStream ends a streamed response before it completes
+	FaultTruncatedStream Fault = "truncated_stream"
+
+	// FaultSlow delays the call by the slow delay and then lets it proceed
+	FaultSlow Fault = "slow"
+
+	// FaultToolError fails a tool call
+	FaultToolError Fault = "tool_error"
+)
+
+const (
+	// DefaultSlowDelay is how long FaultSlow delays a call by default
+	DefaultSlowDelay = 2 * time.Second
+
+	// DefaultHang is how long FaultTimeout blocks a call without a deadline by default
+	DefaultHang = 30 * time.Second
+)
+
+// FaultError is the error returned for an injected fault
+type FaultError struct {
+	Fault      Fault
+	StatusCode int
+}
+
+// Error implements the error interface
+func (e *FaultError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("chaos: injected %s fault: API error: %s (%d)", e.Fault, http.StatusText(e.StatusCode), e.StatusCode)
+	}
+	return fmt.Sprintf("chaos: injected %s fault", e.Fault)
+}
+
+// Unwrap returns the provider error the fault imitates: an *model.ErrRateLimited
+// for rate limit faults and an *model.ErrProvider for other faults with a status
+func (e *FaultError) Unwrap() error {
+	if e.StatusCode == 0 {
+		return nil
+	}
+	providerErr := &model.ErrProvider{Provider: "chaos", Status: e.StatusCode, Code: string(e.Fault)}
+	if e.StatusCode == http.StatusTooManyRequests {
+		return &model.ErrRateLimited{Err: providerErr}
+	}
+	return providerErr
+}
+
+// rule is a fault with its probability
+type rule struct {
+	fault       Fault
+	probability float64
+}
+
+// Injector decides which faults to inject
+type Injector struct {
+	rules     []rule
+	slowDelay time.Duration
+	hang      time.Duration
+	rng       *rand.Rand
+	counts    map[Fault]int
+	mu        sync.Mutex
+}
+
+// NewInjector creates an injector drawing faults from a random source with the given seed
+func NewInjector(seed int64) *Injector {
+	return &Injector{
+		slowDelay: DefaultSlowDelay,
+		hang:      DefaultHang,
+		rng:       rand.New(rand.NewSource(seed)),
+		counts:    make(map[Fault]int),
+	}
+}
+
+// WithFault injects a fault into the given fraction of calls, between 0 and 1.
+// The probabilities of the faults that apply to a call are added up, so they
+// should not exceed 1 together.
+func (i *Injector) WithFault(fault Fault, probability float64) *Injector {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.rules = append(i.rules, rule{fault: fault, probability: probability})
+	return i
+}
+
+// WithSlowDelay sets how long FaultSlow delays a call
+func (i *Injector) WithSlowDelay(delay time.Duration) *Injector {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.slowDelay = delay
+	return i
+}
+
+// WithHang sets how long FaultTimeout blocks a call whose context has no deadline
+func (i *Injector) WithHang(hang time.Duration) *Injector {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.hang = hang
+	return i
+}
+
+// Injected returns how many times each fault was injected
+func (i *Injector) Injected() map[Fault]int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	counts := make(map[Fault]int, len(i.counts))
+	for fault, n := range i.counts {
+		counts[fault] = n
+	}
+	return counts
+}
+
+// roll draws the fault for a call, considering only the faults that apply to it
+func (i *Injector) roll(applicable ...Fault) (Fault, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	draw := i.rng.Float64()
+	cumulative := 0.0
+	for _, r := range i.rules {
+		if !contains(applicable, r.fault) {
+			continue
+		}
+		cumulative += r.probability
+		if draw < cumulative {
+			i.counts[r.fault]++
+			logging.For(nil, "chaos").Debug("Injecting fault", "fault", r.fault)
+			return r.fault, true
+		}
+	}
+	return "", false
+}
+
+// sleep waits for the slow delay or until the context ends
+func (i *Injector) sleep(ctx context.Context) error {
+	i.mu.Lock()
+	delay := i.slowDelay
+	i.mu.Unlock()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// hangUp blocks until the context ends or the hang duration passes and returns a
+// deadline error
+func (i *Injector) hangUp(ctx context.Context) error {
+	i.mu.Lock()
+	hang := i.hang
+	i.mu.Unlock()
+
+	timer := time.NewTimer(hang)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+	return fmt.Errorf("chaos: injected timeout: %w", context.DeadlineExceeded)
+}
+
+// contains reports whether a fault is in the list
+func contains(faults []Fault, fault Fault) bool {
+	for _, f := range faults {
+		if f == fault {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package chaos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+)
+
+// Provider wraps a model provider so its models receive injected faults
+type Provider struct {
+	provider model.Provider
+	injector *Injector
+}
+
+// WrapProvider returns a provider whose models fail according to the injector
+func WrapProvider(provider model.Provider, injector *Injector) *Provider {
+	return &Provider{provider: provider, injector: injector}
+}
+
+// GetModel returns the wrapped model
+func (p *Provider) GetModel(name string) (model.Model, error) {
+	m, err := p.provider.GetModel(name)
+	if err != nil {
+		return nil, err
+	}
+	return WrapModel(m, p.injector), nil
+}
+
+// Model wraps a model with injected faults
+type Model struct {
+	model    model.Model
+	injector *Injector
+}
+
+// WrapModel returns a model that fails according to the injector
+func WrapModel(m model.Model, injector *Injector) *Model {
+	return &Model{model: m, injector: injector}
+}
+
+// GetResponse returns the response of the wrapped model unless a fault is injected
+func (m *Model) GetResponse(ctx context.Context, request *model.Request) (*model.Response, error) {
+	fault, ok := m.injector.roll(FaultRateLimit, FaultServerError, FaultTimeout, FaultMalformedJSON, FaultSlow)
+	if ok {
+		if err := m.inject(ctx, fault); err != nil {
+			return nil, err
+		}
+	}
+	return m.model.GetResponse(ctx, request)
+}
+
+// StreamResponse streams the response of the wrapped model unless a fault is
+// injected. A truncated stream forwards the first event and then reports an error.
+func (m *Model) StreamResponse(ctx context.Context, request *model.Request) (<-chan model.StreamEvent, error) {
+	fault, ok := m.injector.roll(FaultRateLimit, FaultServerError, FaultTimeout, FaultMalformedJSON, FaultSlow, FaultTruncatedStream)
+	if ok && fault != FaultTruncatedStream {
+		if err := m.inject(ctx, fault); err != nil {
+			return nil, err
+		}
+	}
+
+	events, err := m.model.StreamResponse(ctx, request)
+	if err != nil || !ok || fault != FaultTruncatedStream {
+		return events, err
+	}
+
+	truncated := make(chan model.StreamEvent)
+	go func() {
+		defer close(truncated)
+		forwarded := false
+		for event := range events {
+			if forwarded {
+				continue
+			}
+			if event.Type == model.StreamEventTypeDone {
+				break
+			}
+			truncated <- event
+			forwarded = true
+		}
+		truncated <- model.StreamEvent{
+			Type:  model.StreamEventTypeError,
+			Error: &FaultError{Fault: FaultTruncatedStream},
+		}
+		// Drain the rest of the stream so the wrapped model can finish
+		for range events {
+		}
+	}()
+	return truncated, nil
+}
+
+// inject applies a fault to a model call. It returns nil for faults that let the call proceed.
+func (m *Model) inject(ctx context.Context, fault Fault) error {
+	switch fault {
+	case FaultRateLimit:
+		return &FaultError{Fault: fault, StatusCode: http.StatusTooManyRequests}
+	case FaultServerError:
+		return &FaultError{Fault: fault, StatusCode: http.StatusInternalServerError}
+	case FaultTimeout:
+		return m.injector.hangUp(ctx)
+	case FaultMalformedJSON:
+		var v interface{}
+		err := json.Unmarshal([]byte(malformedBody), &v)
+		return fmt.Errorf("failed to unmarshal response: %w (%s)", err, &FaultError{Fault: fault})
+	case FaultSlow:
+		return m.injector.sleep(ctx)
+	}
+	return nil
+}
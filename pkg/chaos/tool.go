@@ -0,0 +1,41 @@
+package chaos
+
+import (
+	"context"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+)
+
+// ToolFaults returns tool middleware that injects FaultToolError, FaultTimeout and
+// FaultSlow into tool calls:
+//
+//	search := tool.Wrap(searchTool, tool.WithRetry(3, time.Second), chaos.ToolFaults(injector))
+func ToolFaults(injector *Injector) tool.Middleware {
+	return func(next tool.Tool) tool.Tool {
+		return &faultyTool{Tool: next, injector: injector}
+	}
+}
+
+// faultyTool is a tool with injected faults
+type faultyTool struct {
+	tool.Tool
+	injector *Injector
+}
+
+// Execute runs the tool unless a fault is injected
+func (t *faultyTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	fault, ok := t.injector.roll(FaultToolError, FaultTimeout, FaultSlow)
+	if ok {
+		switch fault {
+		case FaultToolError:
+			return nil, &FaultError{Fault: fault}
+		case FaultTimeout:
+			return nil, t.injector.hangUp(ctx)
+		case FaultSlow:
+			if err := t.injector.sleep(ctx); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return t.Tool.Execute(ctx, params)
+}
@@ -0,0 +1,105 @@
+package chaos
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// malformedBody is returned by FaultMalformedJSON
+const malformedBody = `{"id": "chatcmpl-chaos", "choices": [{"message": {"content": "`
+
+// truncateAfter is the number of body bytes a truncated stream delivers
+const truncateAfter = 64
+
+// Transport is an http.RoundTripper that injects faults into HTTP responses. It
+// exercises the retry logic of providers that accept an HTTP client:
+//
+//	client := &http.Client{Transport: chaos.NewTransport(nil, injector)}
+//	provider := openai.NewProvider(apiKey).WithHTTPClient(client)
+type Transport struct {
+	base     http.RoundTripper
+	injector *Injector
+}
+
+// NewTransport wraps a transport, http.DefaultTransport if nil
+func NewTransport(base http.RoundTripper, injector *Injector) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{base: base, injector: injector}
+}
+
+// RoundTrip sends the request unless a fault replaces or alters the response
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	fault, ok := t.injector.roll(FaultRateLimit, FaultServerError, FaultTimeout, FaultMalformedJSON, FaultTruncatedStream, FaultSlow)
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+
+	switch fault {
+	case FaultRateLimit:
+		resp := fakeResponse(req, http.StatusTooManyRequests, `{"error": {"message": "Rate limit exceeded (chaos)", "type": "rate_limit_error"}}`)
+		resp.Header.Set("Retry-After", "1")
+		return resp, nil
+	case FaultServerError:
+		return fakeResponse(req, http.StatusInternalServerError, `{"error": {"message": "Internal server error (chaos)", "type": "server_error"}}`), nil
+	case FaultTimeout:
+		return nil, t.injector.hangUp(req.Context())
+	case FaultMalformedJSON:
+		return fakeResponse(req, http.StatusOK, malformedBody), nil
+	case FaultSlow:
+		if err := t.injector.sleep(req.Context()); err != nil {
+			return nil, err
+		}
+		return t.base.RoundTrip(req)
+	case FaultTruncatedStream:
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = &truncatedBody{body: resp.Body, remaining: truncateAfter}
+		resp.ContentLength = -1
+		return resp, nil
+	}
+	return t.base.RoundTrip(req)
+}
+
+// fakeResponse creates a JSON response without contacting the server
+func fakeResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		Status:        http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader([]byte(body))),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// truncatedBody ends a response body with an unexpected EOF after a number of bytes
+type truncatedBody struct {
+	body      io.ReadCloser
+	remaining int
+}
+
+// Read reads from the body until the truncation point
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if len(p) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.body.Read(p)
+	b.remaining -= n
+	return n, err
+}
+
+// Close closes the underlying body
+func (b *truncatedBody) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,443 @@
+// Package config builds agents and runner options from YAML or JSON documents,
+// so that orchestration can change without recompiling.
+//
+// A document declares the agents with their instructions, models, tools,
+// guardrails and handoffs, and the options of the runs that use them:
+//
+//	version: 1
+//	provider: openai
+//	model: gpt-4o
+//	entry: triage
+//	agents:
+//	  - name: triage
+//	    instructions: Route the request to the right specialist.
+//	    handoffs: [billing, support]
+//	  - name: billing
+//	    prompt:
+//	      template: billing
+//	      variables: {company: Acme}
+//	    tools: [lookup_invoice]
+//	    handoffs: [triage]
+//	  - name: support
+//	    instructions: Solve technical problems.
+//	    model: gpt-4o-mini
+//	    settings: {temperature: 0.2}
+//	    output_guardrails: [no_secrets]
+//	runner:
+//	  max_turns: 12
+//	  require_approval: [lookup_invoice]
+//
+// Names of tools, guardrails, providers and prompt templates are resolved through
+// a Registry. Documents are checked against Schema and all of their references
+// before anything is built, and every problem found is reported together. Use
+// package workflow for documents that also declare phases and validation points.
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+)
+
+// Version is the version of the document format
+const Version = 1
+
+// ErrInvalidConfig is returned for documents that do not match the schema or
+// refer to names the registry does not know
+var ErrInvalidConfig = errors.New("invalid agent configuration")
+
+// Document is the content of a config document
+type Document struct {
+	Version  int           `yaml:"version"`
+	Provider string        `yaml:"provider"`
+	Model    string        `yaml:"model"`
+	Entry    string        `yaml:"entry"`
+	Agents   []AgentConfig `yaml:"agents"`
+	Runner   *RunnerConfig `yaml:"runner"`
+}
+
+// AgentConfig declares an agent
+type AgentConfig struct {
+	Name             string          `yaml:"name"`
+	Description      string          `yaml:"description"`
+	Instructions     string          `yaml:"instructions"`
+	Prompt           *PromptConfig   `yaml:"prompt"`
+	Provider         string          `yaml:"provider"`
+	Model            string          `yaml:"model"`
+	Settings         *SettingsConfig `yaml:"settings"`
+	PromptCaching    bool            `yaml:"prompt_caching"`
+	Tools            []string        `yaml:"tools"`
+	Handoffs         []string        `yaml:"handoffs"`
+	InputGuardrails  []string        `yaml:"input_guardrails"`
+	OutputGuardrails []string        `yaml:"output_guardrails"`
+	Policy           string          `yaml:"policy"`
+}
+
+// PromptConfig renders the instructions of an agent from a template of the
+// registry's prompt library
+type PromptConfig struct {
+	Template  string                 `yaml:"template"`
+	Variables map[string]interface{} `yaml:"variables"`
+}
+
+// SettingsConfig declares model settings
+type SettingsConfig struct {
+	Temperature       *float64 `yaml:"temperature"`
+	TopP              *float64 `yaml:"top_p"`
+	FrequencyPenalty  *float64 `yaml:"frequency_penalty"`
+	PresencePenalty   *float64 `yaml:"presence_penalty"`
+	ToolChoice        *string  `yaml:"tool_choice"`
+	ParallelToolCalls *bool    `yaml:"parallel_tool_calls"`
+	MaxTokens         *int     `yaml:"max_tokens"`
+}
+
+// RunnerConfig declares the options of runs
+type RunnerConfig struct {
+	MaxTurns         int             `yaml:"max_turns"`
+	MaxTotalTokens   int             `yaml:"max_total_tokens"`
+	MaxCostUSD       float64         `yaml:"max_cost_usd"`
+	Settings         *SettingsConfig `yaml:"settings"`
+	ModelFallbacks   []string        `yaml:"model_fallbacks"`
+	InputGuardrails  []string        `yaml:"input_guardrails"`
+	OutputGuardrails []string        `yaml:"output_guardrails"`
+	RequireApproval  []string        `yaml:"require_approval"`
+	TracingDisabled  bool            `yaml:"tracing_disabled"`
+	WorkflowName     string          `yaml:"workflow_name"`
+	Policy           string          `yaml:"policy"`
+}
+
+// Config is a loaded document, ready to run
+type Config struct {
+	// Document is the parsed document
+	Document *Document
+
+	// Agents are the agents of the document by name
+	Agents map[string]*agent.Agent
+
+	// Entry is the agent runs start with
+	Entry *agent.Agent
+
+	// MaxTurns is the turn limit of runs, or 0 for the runner's default
+	MaxTurns int
+
+	// RunConfig holds the run options of the document
+	RunConfig *runner.RunConfig
+}
+
+// Load reads a config file. YAML and JSON files are both accepted.
+func Load(path string, registry *Registry) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	c, err := Parse(data, registry)
+	if err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Parse builds agents and run options from a YAML or JSON document
+func Parse(data []byte, registry *Registry) (*Config, error) {
+	if registry == nil {
+		registry = NewRegistry()
+	}
+
+	// Check the document against the schema first, for messages that name the
+	// offending field. YAML is a superset of JSON, so one decoder handles both.
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("%w: the document is empty", ErrInvalidConfig)
+	}
+	if problems := tool.ValidateValue("config", raw, validationSchema()); len(problems) > 0 {
+		return nil, invalid(problems)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var doc Document
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	if problems := doc.validate(registry); len(problems) > 0 {
+		return nil, invalid(problems)
+	}
+	return build(&doc, registry)
+}
+
+// invalid returns an ErrInvalidConfig listing problems
+func invalid(problems []string) error {
+	errs := make([]error, 0, len(problems)+1)
+	errs = append(errs, ErrInvalidConfig)
+	for _, problem := range problems {
+		errs = append(errs, errors.New(problem))
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks the values and references of the document
+func (d *Document) validate(registry *Registry) []string {
+	var problems []string
+
+	if d.Version != 0 && d.Version != Version {
+		problems = append(problems, fmt.Sprintf("unsupported version %d", d.Version))
+	}
+	if len(d.Agents) == 0 {
+		problems = append(problems, "no agents defined")
+	}
+
+	names := make(map[string]bool, len(d.Agents))
+	for i, a := range d.Agents {
+		if a.Name == "" {
+			problems = append(problems, fmt.Sprintf("agent %d has no name", i+1))
+			continue
+		}
+		if names[a.Name] {
+			problems = append(problems, fmt.Sprintf("agent %q is defined more than once", a.Name))
+		}
+		names[a.Name] = true
+	}
+	if d.Entry != "" && !names[d.Entry] {
+		problems = append(problems, fmt.Sprintf("entry agent %q is not defined", d.Entry))
+	}
+
+	checkProvider := func(owner, name string) {
+		if _, ok := registry.Provider(name); name != "" && !ok {
+			problems = append(problems, fmt.Sprintf("%s uses unregistered provider %q", owner, name))
+		}
+	}
+	checkPolicy := func(owner, name string) {
+		if _, err := registry.Policy(name); name != "" && err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", owner, err))
+		}
+	}
+	checkGuardrails := func(owner string, input, output []string) {
+		for _, name := range input {
+			if _, ok := registry.InputGuardrail(name); !ok {
+				problems = append(problems, fmt.Sprintf("%s uses unregistered input guardrail %q", owner, name))
+			}
+		}
+		for _, name := range output {
+			if _, ok := registry.OutputGuardrail(name); !ok {
+				problems = append(problems, fmt.Sprintf("%s uses unregistered output guardrail %q", owner, name))
+			}
+		}
+	}
+	checkProvider("the document", d.Provider)
+
+	for _, a := range d.Agents {
+		owner := fmt.Sprintf("agent %q", a.Name)
+		checkProvider(owner, a.Provider)
+		checkGuardrails(owner, a.InputGuardrails, a.OutputGuardrails)
+		checkPolicy(owner, a.Policy)
+		problems = append(problems, a.Settings.validate(owner)...)
+
+		if a.Prompt != nil {
+			if a.Instructions != "" {
+				problems = append(problems, fmt.Sprintf("%s sets both instructions and prompt", owner))
+			}
+			if tmpl, err := registry.Prompt(a.Prompt.Template); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", owner, err))
+			} else if _, err := tmpl.Render(a.Prompt.Variables); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", owner, err))
+			}
+		}
+		for _, t := range a.Tools {
+			if _, ok := registry.Tool(t); !ok {
+				problems = append(problems, fmt.Sprintf("%s uses unregistered tool %q", owner, t))
+			}
+		}
+		for _, h := range a.Handoffs {
+			if !names[h] {
+				problems = append(problems, fmt.Sprintf("%s hands off to undefined agent %q", owner, h))
+			}
+		}
+	}
+
+	if r := d.Runner; r != nil {
+		if r.MaxTurns < 0 || r.MaxTotalTokens < 0 || r.MaxCostUSD < 0 {
+			problems = append(problems, "runner limits must not be negative")
+		}
+		checkGuardrails("the runner", r.InputGuardrails, r.OutputGuardrails)
+		checkPolicy("the runner", r.Policy)
+		problems = append(problems, r.Settings.validate("the runner")...)
+	}
+	return problems
+}
+
+// validate checks the ranges of the settings
+func (s *SettingsConfig) validate(owner string) []string {
+	if s == nil {
+		return nil
+	}
+	var problems []string
+	if s.Temperature != nil && (*s.Temperature < 0 || *s.Temperature > 2) {
+		problems = append(problems, fmt.Sprintf("%s has temperature %v outside of 0 to 2", owner, *s.Temperature))
+	}
+	if s.TopP != nil && (*s.TopP < 0 || *s.TopP > 1) {
+		problems = append(problems, fmt.Sprintf("%s has top_p %v outside of 0 to 1", owner, *s.TopP))
+	}
+	if s.MaxTokens != nil && *s.MaxTokens <= 0 {
+		problems = append(problems, fmt.Sprintf("%s has max_tokens %d, which must be positive", owner, *s.MaxTokens))
+	}
+	return problems
+}
+
+// toModelSettings converts the settings to model settings
+func (s *SettingsConfig) toModelSettings() *model.Settings {
+	return &model.Settings{
+		Temperature:       s.Temperature,
+		TopP:              s.TopP,
+		FrequencyPenalty:  s.FrequencyPenalty,
+		PresencePenalty:   s.PresencePenalty,
+		ToolChoice:        s.ToolChoice,
+		ParallelToolCalls: s.ParallelToolCalls,
+		MaxTokens:         s.MaxTokens,
+	}
+}
+
+// build creates the agents and run options of a validated document
+func build(doc *Document, registry *Registry) (*Config, error) {
+	agents := make(map[string]*agent.Agent, len(doc.Agents))
+	for _, a := range doc.Agents {
+		ag := agent.NewAgent(a.Name, a.Instructions)
+		ag.Description = a.Description
+		ag.PromptCaching = a.PromptCaching
+
+		if a.Prompt != nil {
+			tmpl, _ := registry.Prompt(a.Prompt.Template)
+			instructions, err := tmpl.Instructions(a.Prompt.Variables)
+			if err != nil {
+				return nil, fmt.Errorf("agent %q: %w", a.Name, err)
+			}
+			ag.SetInstructionsFunc(instructions)
+		}
+
+		m, err := resolveModel(doc, a, registry)
+		if err != nil {
+			return nil, fmt.Errorf("agent %q: %w", a.Name, err)
+		}
+		if m != nil {
+			ag.WithModel(m)
+		}
+		if a.Settings != nil {
+			ag.WithModelSettings(a.Settings.toModelSettings())
+		}
+		for _, name := range a.Tools {
+			t, _ := registry.Tool(name)
+			ag.WithTools(t)
+		}
+		for _, name := range a.InputGuardrails {
+			g, _ := registry.InputGuardrail(name)
+			ag.WithInputGuardrails(g)
+		}
+		for _, name := range a.OutputGuardrails {
+			g, _ := registry.OutputGuardrail(name)
+			ag.WithOutputGuardrails(g)
+		}
+		if a.Policy != "" {
+			// Applied before the handoffs are wired, so handoffs reach the copy
+			p, _ := registry.Policy(a.Policy)
+			ag = p.Agent(ag)
+		}
+		agents[a.Name] = ag
+	}
+
+	// Wire the handoff graph once every agent exists, so cycles are allowed
+	for _, a := range doc.Agents {
+		for _, h := range a.Handoffs {
+			agents[a.Name].WithHandoffs(agents[h])
+		}
+	}
+
+	entry := doc.Entry
+	if entry == "" {
+		entry = doc.Agents[0].Name
+	}
+
+	runConfig := &runner.RunConfig{}
+	if provider, ok := registry.Provider(doc.Provider); ok {
+		runConfig.ModelProvider = provider
+	}
+	c := &Config{Document: doc, Agents: agents, Entry: agents[entry], RunConfig: runConfig}
+	if r := doc.Runner; r != nil {
+		c.MaxTurns = r.MaxTurns
+		runConfig.MaxTotalTokens = r.MaxTotalTokens
+		runConfig.MaxCostUSD = r.MaxCostUSD
+		runConfig.TracingDisabled = r.TracingDisabled
+		if r.Settings != nil {
+			runConfig.ModelSettings = r.Settings.toModelSettings()
+		}
+		for _, name := range r.ModelFallbacks {
+			runConfig.ModelFallbacks = append(runConfig.ModelFallbacks, name)
+		}
+		for _, name := range r.InputGuardrails {
+			g, _ := registry.InputGuardrail(name)
+			runConfig.InputGuardrails = append(runConfig.InputGuardrails, g)
+		}
+		for _, name := range r.OutputGuardrails {
+			g, _ := registry.OutputGuardrail(name)
+			runConfig.OutputGuardrails = append(runConfig.OutputGuardrails, g)
+		}
+		if len(r.RequireApproval) > 0 {
+			runConfig.ApprovalPolicy = runner.RequireApproval(r.RequireApproval...)
+		}
+		if r.WorkflowName != "" {
+			runConfig.TracingConfig = &runner.TracingConfig{WorkflowName: r.WorkflowName}
+		}
+		if r.Policy != "" {
+			p, _ := registry.Policy(r.Policy)
+			opts := p.RunOptions(&runner.RunOptions{MaxTurns: c.MaxTurns, RunConfig: runConfig})
+			c.MaxTurns, c.RunConfig = opts.MaxTurns, opts.RunConfig
+		}
+	}
+	return c, nil
+}
+
+// resolveModel returns the model of an agent: a model of the agent's provider if
+// it names one, otherwise the model name, which the run's provider resolves
+func resolveModel(doc *Document, a AgentConfig, registry *Registry) (interface{}, error) {
+	name := a.Model
+	if name == "" {
+		name = doc.Model
+	}
+	if a.Provider == "" {
+		if name == "" {
+			return nil, nil
+		}
+		return name, nil
+	}
+
+	provider, _ := registry.Provider(a.Provider)
+	m, err := provider.GetModel(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get model %q from provider %q: %w", name, a.Provider, err)
+	}
+	return m, nil
+}
+
+// RunOptions returns the options of a run of the document with the given input.
+// The run config is shared by all runs of the document.
+func (c *Config) RunOptions(input interface{}) *runner.RunOptions {
+	return &runner.RunOptions{Input: input, MaxTurns: c.MaxTurns, RunConfig: c.RunConfig}
+}
+
+// Run runs the document's entry agent with the given input
+func (c *Config) Run(ctx context.Context, input interface{}) (*result.RunResult, error) {
+	return runner.NewRunner().Run(ctx, c.Entry, c.RunOptions(input))
+}
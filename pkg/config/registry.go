@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/guardrail"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/policy"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/prompt"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+)
+
+// Registry maps the names used in config documents to Go implementations
+type Registry struct {
+	tools            map[string]tool.Tool
+	inputGuardrails  map[string]guardrail.InputGuardrail
+	outputGuardrails map[string]guardrail.OutputGuardrail
+	providers        map[string]model.Provider
+	prompts          *prompt.Library
+	policies         *policy.Set
+	mu               sync.RWMutex
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		tools:            make(map[string]tool.Tool),
+		inputGuardrails:  make(map[string]guardrail.InputGuardrail),
+		outputGuardrails: make(map[string]guardrail.OutputGuardrail),
+		providers:        make(map[string]model.Provider),
+	}
+}
+
+// WithTools registers tools under their names
+func (r *Registry) WithTools(tools ...tool.Tool) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range tools {
+		r.tools[t.GetName()] = t
+	}
+	return r
+}
+
+// WithInputGuardrails registers input guardrails under their names
+func (r *Registry) WithInputGuardrails(guardrails ...guardrail.InputGuardrail) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, g := range guardrails {
+		r.inputGuardrails[g.Name()] = g
+	}
+	return r
+}
+
+// WithOutputGuardrails registers output guardrails under their names
+func (r *Registry) WithOutputGuardrails(guardrails ...guardrail.OutputGuardrail) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, g := range guardrails {
+		r.outputGuardrails[g.Name()] = g
+	}
+	return r
+}
+
+// WithProvider registers a model provider under a name, such as "openai". The
+// provider named by a document's provider field resolves the model names of
+// its agents.
+func (r *Registry) WithProvider(name string, provider model.Provider) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[name] = provider
+	return r
+}
+
+// WithPrompts sets the library of the instruction templates agents refer to
+// with their prompt field
+func (r *Registry) WithPrompts(library *prompt.Library) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.prompts = library
+	return r
+}
+
+// WithPolicies sets the policies agents and the runner refer to with their policy
+// field
+func (r *Registry) WithPolicies(policies *policy.Set) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policies = policies
+	return r
+}
+
+// Tool returns the tool registered under a name
+func (r *Registry) Tool(name string) (tool.Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.tools[name]
+	return t, ok
+}
+
+// InputGuardrail returns the input guardrail registered under a name
+func (r *Registry) InputGuardrail(name string) (guardrail.InputGuardrail, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	g, ok := r.inputGuardrails[name]
+	return g, ok
+}
+
+// OutputGuardrail returns the output guardrail registered under a name
+func (r *Registry) OutputGuardrail(name string) (guardrail.OutputGuardrail, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	g, ok := r.outputGuardrails[name]
+	return g, ok
+}
+
+// Provider returns the model provider registered under a name
+func (r *Registry) Provider(name string) (model.Provider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.providers[name]
+	return p, ok
+}
+
+// Prompt returns an instruction template of the registry's prompt library
+func (r *Registry) Prompt(name string) (*prompt.Template, error) {
+	r.mu.RLock()
+	library := r.prompts
+	r.mu.RUnlock()
+	if library == nil {
+		return nil, fmt.Errorf("%w: %s (no prompt library registered)", prompt.ErrTemplateNotFound, name)
+	}
+	return library.Get(name)
+}
+
+// Policy returns a policy of the registry's policy set
+func (r *Registry) Policy(name string) (*policy.Policy, error) {
+	r.mu.RLock()
+	policies := r.policies
+	r.mu.RUnlock()
+	if policies == nil {
+		return nil, fmt.Errorf("%w: %s (no policies registered)", policy.ErrPolicyNotFound, name)
+	}
+	return policies.Policy(name)
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// schemaJSON is the JSON schema of config documents
+const schemaJSON = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "Agent configuration",
+  "type": "object",
+  "required": ["agents"],
+  "additionalProperties": false,
+  "properties": {
+    "version": {"type": "integer", "description": "Version of the document format, 1"},
+    "provider": {"type": "string", "description": "Registered provider resolving the model names of the agents"},
+    "model": {"type": "string", "description": "Model of agents that do not set one"},
+    "entry": {"type": "string", "description": "Agent runs start with, the first agent by default"},
+    "agents": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["name"],
+        "additionalProperties": false,
+        "properties": {
+          "name": {"type": "string"},
+          "description": {"type": "string"},
+          "instructions": {"type": "string"},
+          "prompt": {
+            "type": "object",
+            "required": ["template"],
+            "additionalProperties": false,
+            "description": "Instructions rendered from a template of the registry's prompt library",
+            "properties": {
+              "template": {"type": "string"},
+              "variables": {"type": "object"}
+            }
+          },
+          "provider": {"type": "string"},
+          "model": {"type": "string"},
+          "settings": {"$ref": "#/$defs/settings"},
+          "prompt_caching": {"type": "boolean"},
+          "tools": {"type": "array", "items": {"type": "string"}},
+          "handoffs": {"type": "array", "items": {"type": "string"}},
+          "input_guardrails": {"type": "array", "items": {"type": "string"}},
+          "output_guardrails": {"type": "array", "items": {"type": "string"}},
+          "policy": {"type": "string", "description": "Policy of the registry's policy set applied to the agent"}
+        }
+      }
+    },
+    "runner": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "max_turns": {"type": "integer"},
+        "max_total_tokens": {"type": "integer"},
+        "max_cost_usd": {"type": "number"},
+        "settings": {"$ref": "#/$defs/settings"},
+        "model_fallbacks": {"type": "array", "items": {"type": "string"}},
+        "input_guardrails": {"type": "array", "items": {"type": "string"}},
+        "output_guardrails": {"type": "array", "items": {"type": "string"}},
+        "require_approval": {"type": "array", "items": {"type": "string"}, "description": "Tools and handoffs that need approval before they run"},
+        "tracing_disabled": {"type": "boolean"},
+        "workflow_name": {"type": "string"},
+        "policy": {"type": "string", "description": "Policy of the registry's policy set applied to runs"}
+      }
+    }
+  },
+  "$defs": {
+    "settings": {
+      "type": "object",
+      "additionalProperties": false,
+      "properties": {
+        "temperature": {"type": "number"},
+        "top_p": {"type": "number"},
+        "frequency_penalty": {"type": "number"},
+        "presence_penalty": {"type": "number"},
+        "tool_choice": {"type": "string"},
+        "parallel_tool_calls": {"type": "boolean"},
+        "max_tokens": {"type": "integer"}
+      }
+    }
+  }
+}`
+
+// Schema returns the JSON schema of config documents, which editors can use to
+// complete and check them
+func Schema() map[string]interface{} {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(schemaJSON), &schema); err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+// validationSchema returns the schema with its references resolved and its
+// required lists typed as tool.ValidateValue expects them
+func validationSchema() map[string]interface{} {
+	schema := Schema()
+	defs, _ := schema["$defs"].(map[string]interface{})
+	return resolve(schema, defs).(map[string]interface{})
+}
+
+// resolve replaces the $ref nodes of a schema with their definitions
+func resolve(node interface{}, defs map[string]interface{}) interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if ref, ok := n["$ref"].(string); ok && strings.HasPrefix(ref, "#/$defs/") {
+			return resolve(defs[strings.TrimPrefix(ref, "#/$defs/")], defs)
+		}
+		out := make(map[string]interface{}, len(n))
+		for key, value := range n {
+			if required, ok := value.([]interface{}); ok && key == "required" {
+				names := make([]string, 0, len(required))
+				for _, name := range required {
+					names = append(names, name.(string))
+				}
+				out[key] = names
+				continue
+			}
+			out[key] = resolve(value, defs)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(n))
+		for i, value := range n {
+			out[i] = resolve(value, defs)
+		}
+		return out
+	default:
+		return node
+	}
+}
@@ -0,0 +1,344 @@
+// Package drift watches the final outputs of agents for semantic drift. For inputs
+// that recur over time, the monitor embeds each output and compares it with the
+// baseline output recorded the first time the input was seen. Outputs that move
+// too far from their baseline, typically after a model or prompt update, are
+// reported, counted in the drift metrics and optionally fail the run.
+//
+//	monitor := drift.NewMonitor(embedder).WithThreshold(0.8).WithOnDrift(alert)
+//	r := runner.NewRunner().WithHooks(monitor)
+//	http.Handle("/metrics/drift", monitor.MetricsHandler())
+package drift
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/guardrail"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/retrieval"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+)
+
+// DefaultThreshold is the cosine similarity to the baseline below which an output
+// has drifted
+const DefaultThreshold = 0.85
+
+// GuardrailName is the guardrail name of the error returned by blocking monitors
+const GuardrailName = "drift"
+
+// Baseline is the reference output for a recurring input
+type Baseline struct {
+	// Key identifies the input, see Monitor.WithKeyFunc
+	Key string `json:"key"`
+
+	// Agent is the name of the agent that produced the output
+	Agent string `json:"agent"`
+
+	// Input and Output are the texts of the run the baseline was recorded from
+	Input  string `json:"input"`
+	Output string `json:"output"`
+
+	// Vector is the embedding of the output
+	Vector []float32 `json:"vector"`
+
+	// Previous is the embedding of the most recent output for the input
+	Previous []float32 `json:"previous,omitempty"`
+
+	// RecordedAt is when the baseline was recorded
+	RecordedAt time.Time `json:"recorded_at"`
+
+	// Observations is the number of outputs compared with the baseline
+	Observations int `json:"observations"`
+}
+
+// Store keeps the baselines of a monitor
+type Store interface {
+	// GetBaseline returns the baseline of a key, or nil if there is none
+	GetBaseline(ctx context.Context, key string) (*Baseline, error)
+
+	// SaveBaseline creates or replaces a baseline
+	SaveBaseline(ctx context.Context, baseline *Baseline) error
+
+	// DeleteBaseline removes a baseline
+	DeleteBaseline(ctx context.Context, key string) error
+}
+
+// Measurement is the comparison of an output with its baseline
+type Measurement struct {
+	Key    string
+	Agent  string
+	Input  string
+	Output string
+
+	// Similarity is the cosine similarity of the output to the baseline output
+	Similarity float64
+
+	// ChangeSimilarity is the cosine similarity of the output to the previous
+	// output for the same input, which shows how fast outputs are changing
+	ChangeSimilarity float64
+
+	// Drifted is set when Similarity is below the monitor's threshold
+	Drifted bool
+
+	// Baseline is the baseline the output was compared with. It is nil when the
+	// output became the baseline.
+	Baseline *Baseline
+
+	// ObservedAt is when the output was compared
+	ObservedAt time.Time
+}
+
+// KeyFunc identifies recurring inputs. It returns false for inputs that should not
+// be monitored.
+type KeyFunc func(agentName string, input interface{}) (string, bool)
+
+// Monitor compares the final outputs of runs with baselines. It implements
+// runner.RunHooks, so it can be added to a runner with WithHooks.
+type Monitor struct {
+	runner.DefaultRunHooks
+
+	embedder  retrieval.Embedder
+	store     Store
+	threshold float64
+	keyFunc   KeyFunc
+	onDrift   func(ctx context.Context, m *Measurement)
+	blocking  bool
+	metrics   *metrics
+	mu        sync.RWMutex
+}
+
+// Ensure Monitor implements runner.RunHooks
+var _ runner.RunHooks = (*Monitor)(nil)
+
+// NewMonitor creates a monitor that embeds outputs with the given embedder and
+// keeps baselines in memory
+func NewMonitor(embedder retrieval.Embedder) *Monitor {
+	return &Monitor{
+		embedder:  embedder,
+		store:     NewMemoryStore(),
+		threshold: DefaultThreshold,
+		keyFunc:   DefaultKey,
+		metrics:   newMetrics(),
+	}
+}
+
+// WithStore sets the store of the baselines
+func (m *Monitor) WithStore(store Store) *Monitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.store = store
+	return m
+}
+
+// WithThreshold sets the similarity to the baseline below which an output has drifted
+func (m *Monitor) WithThreshold(threshold float64) *Monitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.threshold = threshold
+	return m
+}
+
+// WithKeyFunc sets how recurring inputs are identified
+func (m *Monitor) WithKeyFunc(keyFunc KeyFunc) *Monitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keyFunc = keyFunc
+	return m
+}
+
+// WithOnDrift sets a function called for every drifted output
+func (m *Monitor) WithOnDrift(fn func(ctx context.Context, m *Measurement)) *Monitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onDrift = fn
+	return m
+}
+
+// WithBlocking makes runs with drifted outputs fail with a *guardrail.GuardrailTripped
+// error instead of only reporting them
+func (m *Monitor) WithBlocking(blocking bool) *Monitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.blocking = blocking
+	return m
+}
+
+// DefaultKey identifies an input by the agent name and the input text, ignoring
+// case and whitespace
+func DefaultKey(agentName string, input interface{}) (string, bool) {
+	text := strings.Join(strings.Fields(strings.ToLower(guardrail.Text(input))), " ")
+	if text == "" {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(agentName + "\x00" + text))
+	return hex.EncodeToString(sum[:]), true
+}
+
+// OnRunEnd compares the final output of the run with its baseline
+func (m *Monitor) OnRunEnd(ctx context.Context, res *result.RunResult) error {
+	if res == nil || res.FinalOutput == nil {
+		return nil
+	}
+	agentName := ""
+	if res.LastAgent != nil {
+		agentName = res.LastAgent.Name
+	}
+
+	measurement, err := m.Observe(ctx, agentName, res.Input, res.FinalOutput)
+	if err != nil {
+		// A monitoring failure does not fail the run
+		m.metrics.recordError(agentName)
+		return nil
+	}
+
+	m.mu.RLock()
+	blocking, threshold := m.blocking, m.threshold
+	m.mu.RUnlock()
+	if blocking && measurement != nil && measurement.Drifted {
+		return &guardrail.GuardrailTripped{
+			Guardrail: GuardrailName,
+			Stage:     guardrail.StageOutput,
+			Result: &guardrail.Result{
+				TripwireTriggered: true,
+				Message:           fmt.Sprintf("output similarity %.2f to baseline is below %.2f", measurement.Similarity, threshold),
+				Info:              map[string]interface{}{"key": measurement.Key, "similarity": measurement.Similarity},
+			},
+		}
+	}
+	return nil
+}
+
+// Observe compares an output with the baseline of its input, recording it as the
+// baseline if there is none. It returns nil for inputs the key function skips.
+func (m *Monitor) Observe(ctx context.Context, agentName string, input, output interface{}) (*Measurement, error) {
+	m.mu.RLock()
+	store, threshold, keyFunc, onDrift := m.store, m.threshold, m.keyFunc, m.onDrift
+	m.mu.RUnlock()
+
+	key, ok := keyFunc(agentName, input)
+	if !ok {
+		return nil, nil
+	}
+
+	outputText := guardrail.Text(output)
+	vectors, err := m.embedder.Embed(ctx, []string{outputText})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed output: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embedder returned %d vectors for 1 output", len(vectors))
+	}
+	vector := vectors[0]
+
+	baseline, err := store.GetBaseline(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load baseline %s: %w", key, err)
+	}
+
+	measurement := &Measurement{
+		Key:              key,
+		Agent:            agentName,
+		Input:            guardrail.Text(input),
+		Output:           outputText,
+		Similarity:       1,
+		ChangeSimilarity: 1,
+		ObservedAt:       time.Now(),
+	}
+
+	if baseline == nil {
+		baseline = &Baseline{
+			Key:        key,
+			Agent:      agentName,
+			Input:      measurement.Input,
+			Output:     outputText,
+			Vector:     vector,
+			Previous:   vector,
+			RecordedAt: measurement.ObservedAt,
+		}
+		if err := store.SaveBaseline(ctx, baseline); err != nil {
+			return nil, fmt.Errorf("failed to save baseline %s: %w", key, err)
+		}
+		m.metrics.recordBaseline(agentName)
+		return measurement, nil
+	}
+
+	if len(baseline.Vector) != len(vector) {
+		return nil, fmt.Errorf("output has %d dimensions, baseline %s has %d: %w", len(vector), key, len(baseline.Vector), retrieval.ErrDimensionMismatch)
+	}
+	measurement.Similarity = retrieval.CosineSimilarity(baseline.Vector, vector)
+	if len(baseline.Previous) == len(vector) {
+		measurement.ChangeSimilarity = retrieval.CosineSimilarity(baseline.Previous, vector)
+	}
+	measurement.Drifted = measurement.Similarity < threshold
+	measurement.Baseline = baseline
+
+	updated := *baseline
+	updated.Previous = vector
+	updated.Observations++
+	if err := store.SaveBaseline(ctx, &updated); err != nil {
+		return nil, fmt.Errorf("failed to save baseline %s: %w", key, err)
+	}
+
+	m.metrics.record(measurement)
+	if measurement.Drifted && onDrift != nil {
+		onDrift(ctx, measurement)
+	}
+	return measurement, nil
+}
+
+// ResetBaseline forgets the baseline of a key, so that the next output becomes the
+// new baseline. Use it after an intentional change of behaviour.
+func (m *Monitor) ResetBaseline(ctx context.Context, key string) error {
+	m.mu.RLock()
+	store := m.store
+	m.mu.RUnlock()
+	if err := store.DeleteBaseline(ctx, key); err != nil {
+		return fmt.Errorf("failed to delete baseline %s: %w", key, err)
+	}
+	return nil
+}
+
+// MemoryStore is a Store that keeps baselines in memory
+type MemoryStore struct {
+	baselines map[string]*Baseline
+	mu        sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{baselines: make(map[string]*Baseline)}
+}
+
+// GetBaseline returns the baseline of a key, or nil if there is none
+func (s *MemoryStore) GetBaseline(ctx context.Context, key string) (*Baseline, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	baseline, ok := s.baselines[key]
+	if !ok {
+		return nil, nil
+	}
+	copied := *baseline
+	return &copied, nil
+}
+
+// SaveBaseline creates or replaces a baseline
+func (s *MemoryStore) SaveBaseline(ctx context.Context, baseline *Baseline) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *baseline
+	s.baselines[baseline.Key] = &copied
+	return nil
+}
+
+// DeleteBaseline removes a baseline
+func (s *MemoryStore) DeleteBaseline(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.baselines, key)
+	return nil
+}
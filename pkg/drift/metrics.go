@@ -0,0 +1,133 @@
+package drift
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// AgentMetrics are the drift metrics of one agent
+type AgentMetrics struct {
+	Agent string `json:"agent"`
+
+	// Baselines is the number of baselines recorded
+	Baselines int `json:"baselines"`
+
+	// Comparisons is the number of outputs compared with a baseline
+	Comparisons int `json:"comparisons"`
+
+	// Drifted is the number of outputs below the similarity threshold
+	Drifted int `json:"drifted"`
+
+	// Errors is the number of outputs that could not be compared
+	Errors int `json:"errors"`
+
+	// MeanSimilarity is the mean similarity of the compared outputs to their baselines
+	MeanSimilarity float64 `json:"mean_similarity"`
+
+	// LastSimilarity is the similarity of the most recent compared output
+	LastSimilarity float64 `json:"last_similarity"`
+
+	// LastChangeSimilarity is the similarity of the most recent compared output
+	// to the output before it
+	LastChangeSimilarity float64 `json:"last_change_similarity"`
+
+	similaritySum float64
+}
+
+// metrics accumulates drift metrics by agent
+type metrics struct {
+	agents map[string]*AgentMetrics
+	mu     sync.Mutex
+}
+
+func newMetrics() *metrics {
+	return &metrics{agents: make(map[string]*AgentMetrics)}
+}
+
+// agent returns the metrics of an agent. The caller holds the lock.
+func (m *metrics) agent(name string) *AgentMetrics {
+	agentMetrics, ok := m.agents[name]
+	if !ok {
+		agentMetrics = &AgentMetrics{Agent: name}
+		m.agents[name] = agentMetrics
+	}
+	return agentMetrics
+}
+
+func (m *metrics) record(measurement *Measurement) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	agentMetrics := m.agent(measurement.Agent)
+	agentMetrics.Comparisons++
+	if measurement.Drifted {
+		agentMetrics.Drifted++
+	}
+	agentMetrics.similaritySum += measurement.Similarity
+	agentMetrics.MeanSimilarity = agentMetrics.similaritySum / float64(agentMetrics.Comparisons)
+	agentMetrics.LastSimilarity = measurement.Similarity
+	agentMetrics.LastChangeSimilarity = measurement.ChangeSimilarity
+}
+
+func (m *metrics) recordBaseline(agentName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.agent(agentName).Baselines++
+}
+
+func (m *metrics) recordError(agentName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.agent(agentName).Errors++
+}
+
+// Metrics returns the drift metrics of every monitored agent, by agent name
+func (m *Monitor) Metrics() []AgentMetrics {
+	m.metrics.mu.Lock()
+	defer m.metrics.mu.Unlock()
+
+	result := make([]AgentMetrics, 0, len(m.metrics.agents))
+	for _, agentMetrics := range m.metrics.agents {
+		result = append(result, *agentMetrics)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Agent < result[j].Agent })
+	return result
+}
+
+// MetricsHandler serves the drift metrics in the Prometheus text format
+func (m *Monitor) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		fmt.Fprint(w, m.prometheusText())
+	})
+}
+
+// prometheusText renders the metrics in the Prometheus text format
+func (m *Monitor) prometheusText() string {
+	all := m.Metrics()
+
+	var b strings.Builder
+	write := func(name, kind, help string, value func(AgentMetrics) float64) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		for _, agentMetrics := range all {
+			fmt.Fprintf(&b, "%s{agent=%q} %g\n", name, agentMetrics.Agent, value(agentMetrics))
+		}
+	}
+	write("agent_drift_baselines_total", "counter", "Baselines recorded for recurring inputs.",
+		func(a AgentMetrics) float64 { return float64(a.Baselines) })
+	write("agent_drift_comparisons_total", "counter", "Outputs compared with their baseline.",
+		func(a AgentMetrics) float64 { return float64(a.Comparisons) })
+	write("agent_drift_drifted_total", "counter", "Outputs below the similarity threshold.",
+		func(a AgentMetrics) float64 { return float64(a.Drifted) })
+	write("agent_drift_errors_total", "counter", "Outputs that could not be compared.",
+		func(a AgentMetrics) float64 { return float64(a.Errors) })
+	write("agent_drift_similarity_mean", "gauge", "Mean similarity of outputs to their baseline.",
+		func(a AgentMetrics) float64 { return a.MeanSimilarity })
+	write("agent_drift_similarity_last", "gauge", "Similarity of the latest output to its baseline.",
+		func(a AgentMetrics) float64 { return a.LastSimilarity })
+	write("agent_drift_change_similarity_last", "gauge", "Similarity of the latest output to the output before it.",
+		func(a AgentMetrics) float64 { return a.LastChangeSimilarity })
+	return b.String()
+}
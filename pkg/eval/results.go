@@ -0,0 +1,131 @@
+package eval
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Row is the result of one run of a sweep: one combination of parameters on one
+// example
+type Row struct {
+	Params
+
+	ExampleID string
+	Output    string
+	Score     float64
+	Latency   time.Duration
+
+	PromptTokens     int
+	CompletionTokens int
+
+	// Error is the error of a failed run
+	Error string
+}
+
+// Results are the rows of a sweep, ordered by parameter combination and example
+type Results struct {
+	Rows []Row
+}
+
+// Summary aggregates the rows of one combination of parameters
+type Summary struct {
+	Params
+
+	Runs   int
+	Errors int
+
+	// MeanScore is the mean score of the runs that did not fail
+	MeanScore float64
+
+	// MeanLatency is the mean latency of the runs that did not fail
+	MeanLatency time.Duration
+
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// Summaries aggregates the rows by combination of parameters, in grid order
+func (r *Results) Summaries() []Summary {
+	var summaries []Summary
+	index := make(map[string]int)
+	for _, row := range r.Rows {
+		key := row.Params.String()
+		i, ok := index[key]
+		if !ok {
+			i = len(summaries)
+			index[key] = i
+			summaries = append(summaries, Summary{Params: row.Params})
+		}
+
+		summary := &summaries[i]
+		summary.Runs++
+		if row.Error != "" {
+			summary.Errors++
+			continue
+		}
+		succeeded := float64(summary.Runs - summary.Errors)
+		summary.MeanScore += (row.Score - summary.MeanScore) / succeeded
+		summary.MeanLatency += time.Duration((float64(row.Latency) - float64(summary.MeanLatency)) / succeeded)
+		summary.PromptTokens += row.PromptTokens
+		summary.CompletionTokens += row.CompletionTokens
+	}
+	return summaries
+}
+
+// Best returns the summary of the combination of parameters with the highest mean
+// score, preferring fewer errors and then lower latency, or nil if there are no rows
+func (r *Results) Best() *Summary {
+	var best *Summary
+	summaries := r.Summaries()
+	for i := range summaries {
+		s := &summaries[i]
+		switch {
+		case best == nil,
+			s.MeanScore > best.MeanScore,
+			s.MeanScore == best.MeanScore && s.Errors < best.Errors,
+			s.MeanScore == best.MeanScore && s.Errors == best.Errors && s.MeanLatency < best.MeanLatency:
+			best = s
+		}
+	}
+	return best
+}
+
+// csvHeader are the columns of the CSV results table
+var csvHeader = []string{"model", "temperature", "prompt", "example", "score", "latency_ms", "prompt_tokens", "completion_tokens", "error", "output"}
+
+// WriteCSV writes the rows as a CSV table with one row per run
+func (r *Results) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+	for _, row := range r.Rows {
+		temperature := ""
+		if row.Temperature != nil {
+			temperature = strconv.FormatFloat(*row.Temperature, 'g', -1, 64)
+		}
+		record := []string{
+			row.Model,
+			temperature,
+			row.Prompt,
+			row.ExampleID,
+			strconv.FormatFloat(row.Score, 'g', -1, 64),
+			strconv.FormatInt(row.Latency.Milliseconds(), 10),
+			strconv.Itoa(row.PromptTokens),
+			strconv.Itoa(row.CompletionTokens),
+			row.Error,
+			row.Output,
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write results: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
+	return nil
+}
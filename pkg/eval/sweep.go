@@ -0,0 +1,394 @@
+// Package eval runs experiments over agents. A sweep runs every combination of a
+// parameter grid of model names, temperatures and prompt variants against a
+// dataset, in parallel within the rate limits of the providers, and collects
+// the outputs, scores, latencies and token usage in a table:
+//
+//	results, err := eval.Sweep(newAgent, eval.ParamGrid{
+//		Models:       []string{"gpt-4o", "gpt-4o-mini"},
+//		Temperatures: []float64{0, 0.7},
+//		Prompts:      []eval.PromptVariant{{Name: "terse", Instructions: terse}},
+//	}, dataset).WithRunConfig(config).WithConcurrency(8).Run(ctx)
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/guardrail"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/ratelimit"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+)
+
+// DefaultConcurrency is the number of runs a sweep executes at the same time
+const DefaultConcurrency = 4
+
+// DefaultRateLimitRetries is the number of times a rate limited run is retried
+const DefaultRateLimitRetries = 3
+
+// defaultRetryDelay is the wait before retrying a rate limited run when the
+// provider does not say how long to wait
+const defaultRetryDelay = time.Second
+
+// PromptVariant is a named version of an agent's instructions
+type PromptVariant struct {
+	Name         string
+	Instructions string
+}
+
+// ParamGrid lists the values a sweep combines. A dimension without values is
+// left to the agent factory.
+type ParamGrid struct {
+	Models       []string
+	Temperatures []float64
+	Prompts      []PromptVariant
+}
+
+// Params are one combination of the values of a parameter grid
+type Params struct {
+	// Model is the model name, or empty if the grid has no models
+	Model string
+
+	// Temperature is the temperature, or nil if the grid has no temperatures
+	Temperature *float64
+
+	// Prompt is the name of the prompt variant and Instructions its text, or
+	// empty if the grid has no prompts
+	Prompt       string
+	Instructions string
+}
+
+// String returns a short description of the parameters
+func (p Params) String() string {
+	var parts []string
+	if p.Model != "" {
+		parts = append(parts, "model="+p.Model)
+	}
+	if p.Temperature != nil {
+		parts = append(parts, fmt.Sprintf("temperature=%g", *p.Temperature))
+	}
+	if p.Prompt != "" {
+		parts = append(parts, "prompt="+p.Prompt)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Combinations returns every combination of the grid's values, models first
+func (g ParamGrid) Combinations() []Params {
+	models := g.Models
+	if len(models) == 0 {
+		models = []string{""}
+	}
+	temperatures := make([]*float64, 0, len(g.Temperatures))
+	for i := range g.Temperatures {
+		temperatures = append(temperatures, &g.Temperatures[i])
+	}
+	if len(temperatures) == 0 {
+		temperatures = []*float64{nil}
+	}
+	prompts := g.Prompts
+	if len(prompts) == 0 {
+		prompts = []PromptVariant{{}}
+	}
+
+	combinations := make([]Params, 0, len(models)*len(temperatures)*len(prompts))
+	for _, m := range models {
+		for _, t := range temperatures {
+			for _, p := range prompts {
+				combinations = append(combinations, Params{Model: m, Temperature: t, Prompt: p.Name, Instructions: p.Instructions})
+			}
+		}
+	}
+	return combinations
+}
+
+// Apply sets the parameters on an agent: the model name, the temperature and the
+// instructions of the prompt variant, where the grid has them
+func (p Params) Apply(a *agent.Agent) *agent.Agent {
+	if p.Model != "" {
+		a.WithModel(p.Model)
+	}
+	if p.Temperature != nil {
+		settings := runner.ModelSettingsType{}
+		if a.ModelSettings != nil {
+			settings = *a.ModelSettings
+		}
+		temperature := *p.Temperature
+		settings.Temperature = &temperature
+		a.ModelSettings = &settings
+	}
+	if p.Instructions != "" {
+		a.Instructions = p.Instructions
+	}
+	return a
+}
+
+// AgentFactory creates the agent to run with a combination of parameters. It is
+// called once per run, so runs do not share agents.
+type AgentFactory func(params Params) (*agent.Agent, error)
+
+// Example is an input of a dataset
+type Example struct {
+	// ID identifies the example in the results, its index if empty
+	ID string
+
+	// Input is the input of the run
+	Input interface{}
+
+	// Expected is the expected output, for scorers that compare with it
+	Expected string
+}
+
+// Scorer scores the output of a run for an example, typically between 0 and 1
+type Scorer func(ctx context.Context, example Example, output interface{}) (float64, error)
+
+// ExactMatch scores 1 for outputs equal to the expected output, ignoring case and
+// surrounding whitespace, and 0 otherwise
+func ExactMatch(ctx context.Context, example Example, output interface{}) (float64, error) {
+	if strings.EqualFold(strings.TrimSpace(guardrail.Text(output)), strings.TrimSpace(example.Expected)) {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Contains scores 1 for outputs that contain the expected output, ignoring case,
+// and 0 otherwise
+func Contains(ctx context.Context, example Example, output interface{}) (float64, error) {
+	if strings.Contains(strings.ToLower(guardrail.Text(output)), strings.ToLower(strings.TrimSpace(example.Expected))) {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// Experiment is a sweep over a parameter grid and a dataset
+type Experiment struct {
+	factory AgentFactory
+	grid    ParamGrid
+	dataset []Example
+
+	runner           *runner.Runner
+	runConfig        *runner.RunConfig
+	maxTurns         int
+	scorer           Scorer
+	concurrency      int
+	modelConcurrency map[string]int
+	retries          int
+}
+
+// Sweep creates an experiment that runs the agents of every combination of the
+// grid's parameters against every example of the dataset
+func Sweep(factory AgentFactory, grid ParamGrid, dataset []Example) *Experiment {
+	return &Experiment{
+		factory:          factory,
+		grid:             grid,
+		dataset:          dataset,
+		scorer:           ExactMatch,
+		concurrency:      DefaultConcurrency,
+		modelConcurrency: make(map[string]int),
+		retries:          DefaultRateLimitRetries,
+	}
+}
+
+// WithRunner sets the runner that executes the runs
+func (e *Experiment) WithRunner(r *runner.Runner) *Experiment {
+	e.runner = r
+	return e
+}
+
+// WithRunConfig sets the run configuration of the runs, which resolves model names
+// with its model provider
+func (e *Experiment) WithRunConfig(config *runner.RunConfig) *Experiment {
+	e.runConfig = config
+	return e
+}
+
+// WithMaxTurns sets the maximum number of turns of each run
+func (e *Experiment) WithMaxTurns(maxTurns int) *Experiment {
+	e.maxTurns = maxTurns
+	return e
+}
+
+// WithScorer sets how outputs are scored, ExactMatch by default
+func (e *Experiment) WithScorer(scorer Scorer) *Experiment {
+	e.scorer = scorer
+	return e
+}
+
+// WithConcurrency sets the number of runs executed at the same time
+func (e *Experiment) WithConcurrency(n int) *Experiment {
+	if n > 0 {
+		e.concurrency = n
+	}
+	return e
+}
+
+// WithModelConcurrency limits the number of runs of a model executed at the same
+// time, to stay within the rate limits of its provider
+func (e *Experiment) WithModelConcurrency(modelName string, n int) *Experiment {
+	if n > 0 {
+		e.modelConcurrency[modelName] = n
+	}
+	return e
+}
+
+// WithRateLimitRetries sets how often a run that fails with a rate limit error is
+// retried, after waiting as long as the provider asked
+func (e *Experiment) WithRateLimitRetries(retries int) *Experiment {
+	e.retries = retries
+	return e
+}
+
+// job is one run of a sweep
+type job struct {
+	index   int
+	params  Params
+	example Example
+}
+
+// Run executes the sweep. Runs that fail are recorded in the results with their
+// error; only a cancelled context stops the sweep.
+func (e *Experiment) Run(ctx context.Context) (*Results, error) {
+	if e.factory == nil {
+		return nil, fmt.Errorf("sweep has no agent factory")
+	}
+	r := e.runner
+	if r == nil {
+		r = runner.NewRunner()
+	}
+
+	combinations := e.grid.Combinations()
+	results := &Results{Rows: make([]Row, len(combinations)*len(e.dataset))}
+
+	// Limit the runs per model with a semaphore per model
+	modelSlots := make(map[string]chan struct{}, len(e.modelConcurrency))
+	for name, n := range e.modelConcurrency {
+		modelSlots[name] = make(chan struct{}, n)
+	}
+
+	jobs := make(chan job)
+	var wg sync.WaitGroup
+	for i := 0; i < e.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				slots := modelSlots[j.params.Model]
+				if slots != nil {
+					select {
+					case slots <- struct{}{}:
+					case <-ctx.Done():
+						results.Rows[j.index] = e.row(j, ctx.Err())
+						continue
+					}
+				}
+				results.Rows[j.index] = e.runJob(ctx, r, j)
+				if slots != nil {
+					<-slots
+				}
+			}
+		}()
+	}
+
+	index := 0
+	for _, params := range combinations {
+		for i, example := range e.dataset {
+			if example.ID == "" {
+				example.ID = fmt.Sprintf("%d", i)
+			}
+			jobs <- job{index: index, params: params, example: example}
+			index++
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+	return results, nil
+}
+
+// row returns the row of a job that did not complete
+func (e *Experiment) row(j job, err error) Row {
+	return Row{Params: j.params, ExampleID: j.example.ID, Error: err.Error()}
+}
+
+// runJob runs the agent of a job's parameters on its example, retrying it while it
+// is rate limited
+func (e *Experiment) runJob(ctx context.Context, r *runner.Runner, j job) Row {
+	for attempt := 0; ; attempt++ {
+		row, err := e.runOnce(ctx, r, j)
+		if err == nil {
+			return row
+		}
+		var rateErr *model.ErrRateLimited
+		rateLimited := errors.As(err, &rateErr) || ratelimit.IsRateLimited(err)
+		if !rateLimited || attempt >= e.retries {
+			return e.row(j, err)
+		}
+
+		wait, ok := ratelimit.RetryAfter(err)
+		if !ok && rateErr != nil && rateErr.RetryAfter > 0 {
+			wait, ok = rateErr.RetryAfter, true
+		}
+		if !ok {
+			wait = defaultRetryDelay << attempt
+		}
+		logging.For(nil, "eval").Debug("Sweep run rate limited, retrying", "params", j.params, "example", j.example.ID, "wait", wait)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return e.row(j, ctx.Err())
+		}
+	}
+}
+
+// runOnce runs the agent of a job's parameters on its example and scores the output
+func (e *Experiment) runOnce(ctx context.Context, r *runner.Runner, j job) (Row, error) {
+	a, err := e.factory(j.params)
+	if err != nil {
+		return Row{}, fmt.Errorf("failed to create agent: %w", err)
+	}
+
+	// Each run gets its own copy of the run configuration, as the runner fills in
+	// its defaults
+	opts := &runner.RunOptions{Input: j.example.Input, MaxTurns: e.maxTurns}
+	if e.runConfig != nil {
+		config := *e.runConfig
+		opts.RunConfig = &config
+	}
+
+	start := time.Now()
+	res, err := r.Run(ctx, a, opts)
+	latency := time.Since(start)
+	if err != nil {
+		return Row{}, err
+	}
+
+	row := Row{
+		Params:    j.params,
+		ExampleID: j.example.ID,
+		Output:    guardrail.Text(res.FinalOutput),
+		Latency:   latency,
+	}
+	for _, response := range res.RawResponses {
+		if response.Usage != nil {
+			row.PromptTokens += response.Usage.PromptTokens
+			row.CompletionTokens += response.Usage.CompletionTokens
+		}
+	}
+	if e.scorer != nil {
+		score, err := e.scorer(ctx, j.example, res.FinalOutput)
+		if err != nil {
+			row.Error = fmt.Sprintf("failed to score output: %v", err)
+		}
+		row.Score = score
+	}
+	return row, nil
+}
@@ -0,0 +1,137 @@
+// Package flags connects agent runs to a feature-flag service, so model choice,
+// guardrail strictness and new tools can be rolled out gradually per tenant or
+// percentage of traffic.
+//
+// The runner puts the provider of RunConfig.Flags in the run's context, where
+// instructions, guardrails and tools read flags with Bool, String and Float. The
+// evaluation context, usually identifying the tenant, is attached to the context
+// passed to Run:
+//
+//	ctx = flags.WithEvaluationContext(ctx, flags.EvaluationContext{Key: tenantID})
+//	strict := flags.Bool(ctx, "strict-pii-guardrail", false)
+package flags
+
+import (
+	"context"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+)
+
+// EvaluationContext identifies who a flag is evaluated for
+type EvaluationContext struct {
+	// Key identifies the tenant or user; percentage rollouts bucket by it
+	Key string
+
+	// Attributes are additional targeting attributes
+	Attributes map[string]interface{}
+}
+
+// Provider evaluates feature flags. Implementations return the default value when
+// the flag is unknown, has a different type or cannot be evaluated.
+type Provider interface {
+	// BoolValue evaluates a boolean flag
+	BoolValue(ctx context.Context, key string, defaultValue bool, ec EvaluationContext) bool
+
+	// StringValue evaluates a string flag
+	StringValue(ctx context.Context, key string, defaultValue string, ec EvaluationContext) string
+
+	// FloatValue evaluates a numeric flag
+	FloatValue(ctx context.Context, key string, defaultValue float64, ec EvaluationContext) float64
+}
+
+type providerKey struct{}
+
+type evaluationContextKey struct{}
+
+// WithProvider returns a context carrying the flag provider
+func WithProvider(ctx context.Context, provider Provider) context.Context {
+	return context.WithValue(ctx, providerKey{}, provider)
+}
+
+// ProviderFromContext returns the flag provider of the context
+func ProviderFromContext(ctx context.Context) (Provider, bool) {
+	provider, ok := ctx.Value(providerKey{}).(Provider)
+	return provider, ok && provider != nil
+}
+
+// WithEvaluationContext returns a context carrying the evaluation context
+func WithEvaluationContext(ctx context.Context, ec EvaluationContext) context.Context {
+	return context.WithValue(ctx, evaluationContextKey{}, ec)
+}
+
+// EvaluationContextFromContext returns the evaluation context of the context
+func EvaluationContextFromContext(ctx context.Context) EvaluationContext {
+	ec, _ := ctx.Value(evaluationContextKey{}).(EvaluationContext)
+	return ec
+}
+
+// WithAttribute returns a context whose evaluation context has an additional attribute
+func WithAttribute(ctx context.Context, name string, value interface{}) context.Context {
+	ec := EvaluationContextFromContext(ctx)
+	attributes := make(map[string]interface{}, len(ec.Attributes)+1)
+	for k, v := range ec.Attributes {
+		attributes[k] = v
+	}
+	attributes[name] = value
+	ec.Attributes = attributes
+	return WithEvaluationContext(ctx, ec)
+}
+
+// Bool evaluates a boolean flag with the provider and evaluation context of ctx
+func Bool(ctx context.Context, key string, defaultValue bool) bool {
+	provider, ok := ProviderFromContext(ctx)
+	if !ok {
+		return defaultValue
+	}
+	return provider.BoolValue(ctx, key, defaultValue, EvaluationContextFromContext(ctx))
+}
+
+// String evaluates a string flag with the provider and evaluation context of ctx
+func String(ctx context.Context, key string, defaultValue string) string {
+	provider, ok := ProviderFromContext(ctx)
+	if !ok {
+		return defaultValue
+	}
+	return provider.StringValue(ctx, key, defaultValue, EvaluationContextFromContext(ctx))
+}
+
+// Float evaluates a numeric flag with the provider and evaluation context of ctx
+func Float(ctx context.Context, key string, defaultValue float64) float64 {
+	provider, ok := ProviderFromContext(ctx)
+	if !ok {
+		return defaultValue
+	}
+	return provider.FloatValue(ctx, key, defaultValue, EvaluationContextFromContext(ctx))
+}
+
+// Gated is implemented by tools that are only offered while a flag is on
+type Gated interface {
+	// FlagKey returns the boolean flag that enables the tool
+	FlagKey() string
+}
+
+// gatedTool is a tool enabled by a flag
+type gatedTool struct {
+	tool.Tool
+	flagKey string
+}
+
+// FlagKey returns the boolean flag that enables the tool
+func (t *gatedTool) FlagKey() string {
+	return t.flagKey
+}
+
+// Gate makes a tool available to agents only while the boolean flag is on. Gated
+// tools are off when the run has no flag provider.
+func Gate(t tool.Tool, flagKey string) tool.Tool {
+	return &gatedTool{Tool: t, flagKey: flagKey}
+}
+
+// Enabled reports whether a tool is available in the context
+func Enabled(ctx context.Context, t tool.Tool) bool {
+	gated, ok := t.(Gated)
+	if !ok {
+		return true
+	}
+	return Bool(ctx, gated.FlagKey(), false)
+}
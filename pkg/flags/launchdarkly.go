@@ -0,0 +1,58 @@
+package flags
+
+import (
+	"context"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+)
+
+// LaunchDarklyClient is the subset of the LaunchDarkly server SDK client used by
+// the adapter. *ldclient.LDClient satisfies it with C = ldcontext.Context.
+type LaunchDarklyClient[C any] interface {
+	BoolVariation(key string, context C, defaultVal bool) (bool, error)
+	StringVariation(key string, context C, defaultVal string) (string, error)
+	Float64Variation(key string, context C, defaultVal float64) (float64, error)
+}
+
+// LaunchDarkly evaluates flags with a LaunchDarkly client
+type LaunchDarkly[C any] struct {
+	client     LaunchDarklyClient[C]
+	newContext func(EvaluationContext) C
+}
+
+// NewLaunchDarkly creates a provider from a LaunchDarkly client and a function
+// building LaunchDarkly contexts, for example:
+//
+//	flags.NewLaunchDarkly(client, func(ec flags.EvaluationContext) ldcontext.Context {
+//		return ldcontext.NewBuilder(ec.Key).Kind("tenant").Build()
+//	})
+func NewLaunchDarkly[C any](client LaunchDarklyClient[C], newContext func(EvaluationContext) C) *LaunchDarkly[C] {
+	return &LaunchDarkly[C]{client: client, newContext: newContext}
+}
+
+// BoolValue evaluates a boolean flag
+func (p *LaunchDarkly[C]) BoolValue(ctx context.Context, key string, defaultValue bool, ec EvaluationContext) bool {
+	value, err := p.client.BoolVariation(key, p.newContext(ec), defaultValue)
+	return checked(key, value, defaultValue, err)
+}
+
+// StringValue evaluates a string flag
+func (p *LaunchDarkly[C]) StringValue(ctx context.Context, key string, defaultValue string, ec EvaluationContext) string {
+	value, err := p.client.StringVariation(key, p.newContext(ec), defaultValue)
+	return checked(key, value, defaultValue, err)
+}
+
+// FloatValue evaluates a numeric flag
+func (p *LaunchDarkly[C]) FloatValue(ctx context.Context, key string, defaultValue float64, ec EvaluationContext) float64 {
+	value, err := p.client.Float64Variation(key, p.newContext(ec), defaultValue)
+	return checked(key, value, defaultValue, err)
+}
+
+// checked returns the default value when the evaluation failed
+func checked[T any](key string, value, defaultValue T, err error) T {
+	if err != nil {
+		logging.For(nil, "flags").Warn("Failed to evaluate flag, using its default", "flag", key, "error", err)
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,127 @@
+package flags
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+)
+
+// InMemory is a Provider holding flags in memory. Each flag has a default value,
+// optional values for individual keys and an optional percentage rollout.
+type InMemory struct {
+	flags map[string]*flag
+	mu    sync.RWMutex
+}
+
+// flag is the configuration of a flag
+type flag struct {
+	value        interface{}
+	targets      map[string]interface{}
+	percent      float64
+	rolloutValue interface{}
+}
+
+// NewInMemory creates an empty in-memory provider
+func NewInMemory() *InMemory {
+	return &InMemory{flags: make(map[string]*flag)}
+}
+
+// Set sets the value of a flag for everyone without a more specific rule
+func (m *InMemory) Set(key string, value interface{}) *InMemory {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.flag(key).value = value
+	return m
+}
+
+// SetTarget sets the value of a flag for one evaluation context key, such as a tenant
+func (m *InMemory) SetTarget(key, target string, value interface{}) *InMemory {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f := m.flag(key)
+	if f.targets == nil {
+		f.targets = make(map[string]interface{})
+	}
+	f.targets[target] = value
+	return m
+}
+
+// SetRollout serves value to the given percentage of evaluation context keys. A
+// key stays in or out of the rollout for as long as the percentage is unchanged
+// or grows.
+func (m *InMemory) SetRollout(key string, percent float64, value interface{}) *InMemory {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f := m.flag(key)
+	f.percent = percent
+	f.rolloutValue = value
+	return m
+}
+
+// flag returns the configuration of a flag, creating it. Must be called with m.mu held.
+func (m *InMemory) flag(key string) *flag {
+	f, ok := m.flags[key]
+	if !ok {
+		f = &flag{}
+		m.flags[key] = f
+	}
+	return f
+}
+
+// evaluate returns the value of a flag for the evaluation context
+func (m *InMemory) evaluate(key string, ec EvaluationContext) (interface{}, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	f, ok := m.flags[key]
+	if !ok {
+		return nil, false
+	}
+	if value, ok := f.targets[ec.Key]; ok {
+		return value, true
+	}
+	if f.rolloutValue != nil && ec.Key != "" && bucket(key, ec.Key) < f.percent {
+		return f.rolloutValue, true
+	}
+	return f.value, f.value != nil
+}
+
+// bucket maps a flag and key to a stable percentage in [0, 100)
+func bucket(flagKey, key string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(flagKey + "/" + key))
+	return float64(h.Sum32()%10000) / 100
+}
+
+// BoolValue evaluates a boolean flag
+func (m *InMemory) BoolValue(ctx context.Context, key string, defaultValue bool, ec EvaluationContext) bool {
+	if value, ok := m.evaluate(key, ec); ok {
+		if b, ok := value.(bool); ok {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+// StringValue evaluates a string flag
+func (m *InMemory) StringValue(ctx context.Context, key string, defaultValue string, ec EvaluationContext) string {
+	if value, ok := m.evaluate(key, ec); ok {
+		if s, ok := value.(string); ok {
+			return s
+		}
+	}
+	return defaultValue
+}
+
+// FloatValue evaluates a numeric flag
+func (m *InMemory) FloatValue(ctx context.Context, key string, defaultValue float64, ec EvaluationContext) float64 {
+	if value, ok := m.evaluate(key, ec); ok {
+		switch v := value.(type) {
+		case float64:
+			return v
+		case int:
+			return float64(v)
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,49 @@
+package flags
+
+import (
+	"context"
+)
+
+// OpenFeatureClient is the subset of the OpenFeature Go SDK client used by the
+// adapter. *openfeature.Client satisfies it with E = openfeature.EvaluationContext
+// and O = openfeature.Option.
+type OpenFeatureClient[E any, O any] interface {
+	BooleanValue(ctx context.Context, flag string, defaultValue bool, evalCtx E, options ...O) (bool, error)
+	StringValue(ctx context.Context, flag string, defaultValue string, evalCtx E, options ...O) (string, error)
+	FloatValue(ctx context.Context, flag string, defaultValue float64, evalCtx E, options ...O) (float64, error)
+}
+
+// OpenFeature evaluates flags with an OpenFeature client
+type OpenFeature[E any, O any] struct {
+	client     OpenFeatureClient[E, O]
+	newContext func(EvaluationContext) E
+}
+
+// NewOpenFeature creates a provider from an OpenFeature client and a function
+// building OpenFeature evaluation contexts, for example:
+//
+//	flags.NewOpenFeature[openfeature.EvaluationContext, openfeature.Option](client,
+//		func(ec flags.EvaluationContext) openfeature.EvaluationContext {
+//			return openfeature.NewEvaluationContext(ec.Key, ec.Attributes)
+//		})
+func NewOpenFeature[E any, O any](client OpenFeatureClient[E, O], newContext func(EvaluationContext) E) *OpenFeature[E, O] {
+	return &OpenFeature[E, O]{client: client, newContext: newContext}
+}
+
+// BoolValue evaluates a boolean flag
+func (p *OpenFeature[E, O]) BoolValue(ctx context.Context, key string, defaultValue bool, ec EvaluationContext) bool {
+	value, err := p.client.BooleanValue(ctx, key, defaultValue, p.newContext(ec))
+	return checked(key, value, defaultValue, err)
+}
+
+// StringValue evaluates a string flag
+func (p *OpenFeature[E, O]) StringValue(ctx context.Context, key string, defaultValue string, ec EvaluationContext) string {
+	value, err := p.client.StringValue(ctx, key, defaultValue, p.newContext(ec))
+	return checked(key, value, defaultValue, err)
+}
+
+// FloatValue evaluates a numeric flag
+func (p *OpenFeature[E, O]) FloatValue(ctx context.Context, key string, defaultValue float64, ec EvaluationContext) float64 {
+	value, err := p.client.FloatValue(ctx, key, defaultValue, p.newContext(ec))
+	return checked(key, value, defaultValue, err)
+}
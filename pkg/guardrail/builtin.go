@@ -0,0 +1,197 @@
+package guardrail
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+// RegexGuardrail trips when the text matches any of its patterns. It can be
+// used both as an input and as an output guardrail.
+type RegexGuardrail struct {
+	name     string
+	patterns []*regexp.Regexp
+}
+
+// NewRegexGuardrail creates a guardrail that trips when any pattern matches
+func NewRegexGuardrail(name string, patterns ...string) (*RegexGuardrail, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return &RegexGuardrail{name: name, patterns: compiled}, nil
+}
+
+// Name returns the name of the guardrail
+func (g *RegexGuardrail) Name() string {
+	return g.name
+}
+
+// CheckInput checks the input
+func (g *RegexGuardrail) CheckInput(ctx context.Context, input interface{}) (*Result, error) {
+	return g.check(Text(input)), nil
+}
+
+// CheckOutput checks the output
+func (g *RegexGuardrail) CheckOutput(ctx context.Context, output interface{}) (*Result, error) {
+	return g.check(Text(output)), nil
+}
+
+func (g *RegexGuardrail) check(text string) *Result {
+	for _, re := range g.patterns {
+		if re.MatchString(text) {
+			result := Trip("text matches pattern %s", re.String())
+			result.Info = map[string]interface{}{"pattern": re.String()}
+			return result
+		}
+	}
+	return Pass()
+}
+
+// PIIType is a category of personally identifiable information
+type PIIType string
+
+const (
+	// PIIEmail matches email addresses
+	PIIEmail PIIType = "email"
+
+	// PIIPhone matches phone numbers
+	PIIPhone PIIType = "phone"
+
+	// PIICreditCard matches credit card numbers that pass the Luhn check
+	PIICreditCard PIIType = "credit_card"
+
+	// PIISSN matches US social security numbers
+	PIISSN PIIType = "ssn"
+
+	// PIIIPAddress matches IPv4 addresses
+	PIIIPAddress PIIType = "ip_address"
+)
+
+var piiPatterns = map[PIIType]*regexp.Regexp{
+	PIIEmail:      regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+	PIIPhone:      regexp.MustCompile(`(?:\+\d{1,3}[\s.\-]?)?(?:\(\d{3}\)|\b\d{3})[\s.\-]\d{3}[\s.\-]\d{4}\b`),
+	PIICreditCard: regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`),
+	PIISSN:        regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+	PIIIPAddress:  regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`),
+}
+
+// PIIGuardrail trips when the text contains personally identifiable information
+type PIIGuardrail struct {
+	types []PIIType
+}
+
+// NewPIIGuardrail creates a guardrail detecting the given PII types, or all known types if none are given
+func NewPIIGuardrail(types ...PIIType) *PIIGuardrail {
+	if len(types) == 0 {
+		types = []PIIType{PIIEmail, PIIPhone, PIICreditCard, PIISSN, PIIIPAddress}
+	}
+	return &PIIGuardrail{types: types}
+}
+
+// Name returns the name of the guardrail
+func (g *PIIGuardrail) Name() string {
+	return "pii"
+}
+
+// CheckInput checks the input
+func (g *PIIGuardrail) CheckInput(ctx context.Context, input interface{}) (*Result, error) {
+	return g.check(Text(input)), nil
+}
+
+// CheckOutput checks the output
+func (g *PIIGuardrail) CheckOutput(ctx context.Context, output interface{}) (*Result, error) {
+	return g.check(Text(output)), nil
+}
+
+func (g *PIIGuardrail) check(text string) *Result {
+	found := make([]string, 0)
+	for _, piiType := range g.types {
+		re, ok := piiPatterns[piiType]
+		if !ok {
+			continue
+		}
+
+		for _, match := range re.FindAllString(text, -1) {
+			if piiType == PIICreditCard && !luhnValid(match) {
+				continue
+			}
+			found = append(found, string(piiType))
+			break
+		}
+	}
+
+	if len(found) == 0 {
+		return Pass()
+	}
+
+	result := Trip("text contains personally identifiable information (%v)", found)
+	result.Info = map[string]interface{}{"types": found}
+	return result
+}
+
+// luhnValid checks the Luhn checksum of the digits in s
+func luhnValid(s string) bool {
+	sum := 0
+	double := false
+	digits := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := rune(s[i])
+		if !unicode.IsDigit(c) {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
+
+// MaxLengthGuardrail trips when the text is longer than a number of characters
+type MaxLengthGuardrail struct {
+	maxLength int
+}
+
+// NewMaxLengthGuardrail creates a guardrail limiting the text to maxLength characters
+func NewMaxLengthGuardrail(maxLength int) *MaxLengthGuardrail {
+	return &MaxLengthGuardrail{maxLength: maxLength}
+}
+
+// Name returns the name of the guardrail
+func (g *MaxLengthGuardrail) Name() string {
+	return "max_length"
+}
+
+// CheckInput checks the input
+func (g *MaxLengthGuardrail) CheckInput(ctx context.Context, input interface{}) (*Result, error) {
+	return g.check(Text(input)), nil
+}
+
+// CheckOutput checks the output
+func (g *MaxLengthGuardrail) CheckOutput(ctx context.Context, output interface{}) (*Result, error) {
+	return g.check(Text(output)), nil
+}
+
+func (g *MaxLengthGuardrail) check(text string) *Result {
+	length := utf8.RuneCountInString(text)
+	if length <= g.maxLength {
+		return Pass()
+	}
+
+	result := Trip("text is %d characters long, the maximum is %d", length, g.maxLength)
+	result.Info = map[string]interface{}{"length": length, "max_length": g.maxLength}
+	return result
+}
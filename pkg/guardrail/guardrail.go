@@ -0,0 +1,161 @@
+// Package guardrail provides checks that the runner applies to the input of a
+// run before the first model call and to the final output before it is
+// returned. A guardrail whose tripwire is triggered aborts the run with a
+// *GuardrailTripped error.
+package guardrail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Stage identifies whether a guardrail checked the input or the output of a run
+type Stage string
+
+const (
+	// StageInput is used for guardrails checking the run input
+	StageInput Stage = "input"
+
+	// StageOutput is used for guardrails checking the final output
+	StageOutput Stage = "output"
+)
+
+// Result is the outcome of a guardrail check
+type Result struct {
+	// TripwireTriggered aborts the run when set
+	TripwireTriggered bool
+
+	// Message explains why the tripwire was triggered
+	Message string
+
+	// Info contains additional details about the check
+	Info map[string]interface{}
+}
+
+// Pass returns a result that lets the run continue
+func Pass() *Result {
+	return &Result{}
+}
+
+// Trip returns a result that aborts the run
+func Trip(format string, args ...interface{}) *Result {
+	return &Result{
+		TripwireTriggered: true,
+		Message:           fmt.Sprintf(format, args...),
+	}
+}
+
+// InputGuardrail checks the input of a run before the first model call
+type InputGuardrail interface {
+	// Name returns the name of the guardrail
+	Name() string
+
+	// CheckInput checks the input
+	CheckInput(ctx context.Context, input interface{}) (*Result, error)
+}
+
+// OutputGuardrail checks the final output of a run before it is returned
+type OutputGuardrail interface {
+	// Name returns the name of the guardrail
+	Name() string
+
+	// CheckOutput checks the output
+	CheckOutput(ctx context.Context, output interface{}) (*Result, error)
+}
+
+// GuardrailTripped is returned by the runner when a guardrail's tripwire is triggered
+type GuardrailTripped struct {
+	// Guardrail is the name of the guardrail that tripped
+	Guardrail string
+
+	// Stage is the stage at which the guardrail tripped
+	Stage Stage
+
+	// Result is the result returned by the guardrail
+	Result *Result
+}
+
+func (e *GuardrailTripped) Error() string {
+	if e.Result != nil && e.Result.Message != "" {
+		return fmt.Sprintf("%s guardrail %s tripped: %s", e.Stage, e.Guardrail, e.Result.Message)
+	}
+	return fmt.Sprintf("%s guardrail %s tripped", e.Stage, e.Guardrail)
+}
+
+// InputFunc is the signature of a function based input guardrail
+type InputFunc func(ctx context.Context, input interface{}) (*Result, error)
+
+// OutputFunc is the signature of a function based output guardrail
+type OutputFunc func(ctx context.Context, output interface{}) (*Result, error)
+
+type inputFuncGuardrail struct {
+	name string
+	fn   InputFunc
+}
+
+func (g *inputFuncGuardrail) Name() string {
+	return g.name
+}
+
+func (g *inputFuncGuardrail) CheckInput(ctx context.Context, input interface{}) (*Result, error) {
+	return g.fn(ctx, input)
+}
+
+type outputFuncGuardrail struct {
+	name string
+	fn   OutputFunc
+}
+
+func (g *outputFuncGuardrail) Name() string {
+	return g.name
+}
+
+func (g *outputFuncGuardrail) CheckOutput(ctx context.Context, output interface{}) (*Result, error) {
+	return g.fn(ctx, output)
+}
+
+// NewInputGuardrail creates an input guardrail from a function
+func NewInputGuardrail(name string, fn InputFunc) InputGuardrail {
+	return &inputFuncGuardrail{name: name, fn: fn}
+}
+
+// NewOutputGuardrail creates an output guardrail from a function
+func NewOutputGuardrail(name string, fn OutputFunc) OutputGuardrail {
+	return &outputFuncGuardrail{name: name, fn: fn}
+}
+
+// Text extracts the text of a run input or output. Strings are returned as is,
+// message lists are flattened to their contents and other values are encoded as JSON.
+func Text(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			if text := Text(item); text != "" {
+				parts = append(parts, text)
+			}
+		}
+		return strings.Join(parts, "\n")
+	case map[string]interface{}:
+		if content, ok := v["content"]; ok {
+			return Text(content)
+		}
+		if text, ok := v["text"]; ok {
+			return Text(text)
+		}
+	case fmt.Stringer:
+		return v.String()
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
@@ -0,0 +1,19 @@
+// Package handoff declares handoffs whose tools have their own names and
+// descriptions, in place of the generated handoff_to_<agent> tools:
+//
+//	orchestrator := agent.New("Orchestrator", agent.WithHandoff(
+//		handoff.To(reviewer).
+//			WithToolName("transfer_to_reviewer").
+//			WithDescription("Hand off finished code for a security review."),
+//	))
+package handoff
+
+import "github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+
+// Handoff is a declared handoff
+type Handoff = agent.Handoff
+
+// To declares the handoff to an agent
+func To(target *agent.Agent) *Handoff {
+	return agent.NewHandoff(target)
+}
@@ -0,0 +1,215 @@
+// Package logging is the logging abstraction of the SDK. Runners, agents and
+// providers log through a Logger, which *slog.Logger implements, scoped to a
+// component and with secrets such as API keys redacted:
+//
+//	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
+//	r := runner.NewRunner().WithLogger(logger)
+//	provider := anthropic.NewProvider(key).WithLogger(logger)
+//
+// Without a logger, warnings and errors are written to stderr. Debug messages
+// are included when the DEBUG environment variable is 1, or the variable of the
+// component, such as ANTHROPIC_DEBUG for the anthropic provider.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Logger logs messages with key-value attributes. *slog.Logger implements it.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// Ensure *slog.Logger implements Logger
+var _ Logger = (*slog.Logger)(nil)
+
+// ComponentKey is the attribute key of the component a message is logged by
+const ComponentKey = "component"
+
+// Discard is a Logger that drops every message
+var Discard Logger = discard{}
+
+type discard struct{}
+
+func (discard) Debug(msg string, args ...any) {}
+func (discard) Info(msg string, args ...any)  {}
+func (discard) Warn(msg string, args ...any)  {}
+func (discard) Error(msg string, args ...any) {}
+
+// Enabled reports that no level is enabled
+func (discard) Enabled(ctx context.Context, level slog.Level) bool { return false }
+
+var (
+	defaultLogger Logger
+	envLoggers    = make(map[string]Logger)
+	mu            sync.RWMutex
+)
+
+// SetDefault sets the logger of components that have no logger of their own.
+// Passing nil restores the stderr logger controlled by the DEBUG variables.
+func SetDefault(l Logger) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultLogger = l
+}
+
+// Default returns the logger of a component that has no logger of its own
+func Default(component string) Logger {
+	mu.RLock()
+	l := defaultLogger
+	envLogger := envLoggers[component]
+	mu.RUnlock()
+	if l != nil {
+		return l
+	}
+	if envLogger != nil {
+		return envLogger
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if envLogger = envLoggers[component]; envLogger == nil {
+		envLogger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: envLevel(component)}))
+		envLoggers[component] = envLogger
+	}
+	return envLogger
+}
+
+// envLevel is the level of the stderr logger of a component: debug when DEBUG or
+// the component's variable is 1, and warn otherwise. It is read on every message,
+// so the variables can be changed at runtime.
+type envLevel string
+
+func (component envLevel) Level() slog.Level {
+	if os.Getenv("DEBUG") == "1" {
+		return slog.LevelDebug
+	}
+	if component != "" && os.Getenv(strings.ToUpper(string(component))+"_DEBUG") == "1" {
+		return slog.LevelDebug
+	}
+	return slog.LevelWarn
+}
+
+// Enabled reports whether a logger logs messages of a level, so that attributes
+// that are expensive to build are only built when they are logged. Loggers that
+// cannot tell log every level.
+func Enabled(l Logger, level slog.Level) bool {
+	if leveled, ok := l.(interface {
+		Enabled(ctx context.Context, level slog.Level) bool
+	}); ok {
+		return leveled.Enabled(context.Background(), level)
+	}
+	return true
+}
+
+// For returns the logger of a component: l, or the default logger when l is nil,
+// adding the component attribute to every message and redacting secrets from
+// messages and attribute values, including the given secrets
+func For(l Logger, component string, secrets ...string) Logger {
+	if l == nil {
+		l = Default(component)
+	}
+	return &scoped{logger: l, component: component, secrets: secrets}
+}
+
+// scoped is the logger of a component
+type scoped struct {
+	logger    Logger
+	component string
+	secrets   []string
+}
+
+func (s *scoped) Debug(msg string, args ...any) {
+	if s.enabled(slog.LevelDebug) {
+		s.logger.Debug(s.redact(msg), s.args(args)...)
+	}
+}
+
+func (s *scoped) Info(msg string, args ...any) {
+	if s.enabled(slog.LevelInfo) {
+		s.logger.Info(s.redact(msg), s.args(args)...)
+	}
+}
+
+func (s *scoped) Warn(msg string, args ...any) {
+	if s.enabled(slog.LevelWarn) {
+		s.logger.Warn(s.redact(msg), s.args(args)...)
+	}
+}
+
+func (s *scoped) Error(msg string, args ...any) {
+	if s.enabled(slog.LevelError) {
+		s.logger.Error(s.redact(msg), s.args(args)...)
+	}
+}
+
+// Enabled reports whether the underlying logger logs messages of a level
+func (s *scoped) Enabled(ctx context.Context, level slog.Level) bool {
+	return s.enabled(level)
+}
+
+// enabled reports whether a level is logged, so that disabled messages are not
+// redacted for nothing
+func (s *scoped) enabled(level slog.Level) bool {
+	return Enabled(s.logger, level)
+}
+
+// args returns the attributes of a message with the component added and their
+// values redacted
+func (s *scoped) args(args []any) []any {
+	redacted := make([]any, 0, len(args)+2)
+	redacted = append(redacted, ComponentKey, s.component)
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i].(type) {
+		case string:
+			// A key followed by its value
+			redacted = append(redacted, arg)
+			if i+1 < len(args) {
+				i++
+				redacted = append(redacted, s.redactValue(args[i]))
+			}
+		case slog.Attr:
+			redacted = append(redacted, slog.Any(arg.Key, s.redactValue(arg.Value.Any())))
+		default:
+			redacted = append(redacted, s.redactValue(arg))
+		}
+	}
+	return redacted
+}
+
+// redactValue redacts an attribute value. Values that may contain text are
+// logged as redacted text.
+func (s *scoped) redactValue(value any) any {
+	switch v := value.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	case string:
+		return s.redact(v)
+	case []byte:
+		return s.redact(string(v))
+	case error:
+		return s.redact(v.Error())
+	case fmt.Stringer:
+		return s.redact(v.String())
+	default:
+		return s.redact(fmt.Sprintf("%+v", v))
+	}
+}
+
+// redact removes secrets from text
+func (s *scoped) redact(text string) string {
+	for _, secret := range s.secrets {
+		if secret != "" {
+			text = strings.ReplaceAll(text, secret, Redacted)
+		}
+	}
+	return Redact(text)
+}
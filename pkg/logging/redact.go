@@ -0,0 +1,27 @@
+package logging
+
+import "regexp"
+
+// Redacted replaces secrets in logged text
+const Redacted = "[REDACTED]"
+
+// secretPatterns match API keys and credentials in logged text. The first group
+// of a pattern is kept, the rest of the match is redacted.
+var secretPatterns = []*regexp.Regexp{
+	// Credentials in JSON, headers and query strings
+	regexp.MustCompile(`(?i)("?(?:api[_-]?key|x-api-key|access[_-]?token|refresh[_-]?token|client[_-]?secret|secret|password)"?\s*[:=]\s*"?)[^"\s,&}]+`),
+	regexp.MustCompile(`(?i)((?:authorization|proxy-authorization)"?\s*[:=]\s*"?(?:bearer|basic)?\s*)[^"\s,}]+`),
+	regexp.MustCompile(`(?i)(bearer\s+)[a-z0-9._~+/=-]+`),
+	// Provider API keys
+	regexp.MustCompile(`()\bsk-[A-Za-z0-9_-]{8,}`),
+	regexp.MustCompile(`()\bgh[pousr]_[A-Za-z0-9]{20,}`),
+	regexp.MustCompile(`()\bAKIA[0-9A-Z]{16}\b`),
+}
+
+// Redact replaces API keys and credentials in text with Redacted
+func Redact(text string) string {
+	for _, pattern := range secretPatterns {
+		text = pattern.ReplaceAllString(text, "${1}"+Redacted)
+	}
+	return text
+}
@@ -0,0 +1,22 @@
+-- Conversation history of memory sessions
+CREATE TABLE IF NOT EXISTS {{prefix}}sessions (
+    session_id TEXT PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS {{prefix}}sessions_updated_idx ON {{prefix}}sessions (updated_at);
+
+CREATE TABLE IF NOT EXISTS {{prefix}}session_items (
+    id BIGSERIAL PRIMARY KEY,
+    session_id TEXT NOT NULL REFERENCES {{prefix}}sessions (session_id) ON DELETE CASCADE,
+    role TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL DEFAULT '',
+    item JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS {{prefix}}session_items_session_idx ON {{prefix}}session_items (session_id, id);
+
+-- Full text search over the history
+CREATE INDEX IF NOT EXISTS {{prefix}}session_items_search_idx ON {{prefix}}session_items USING gin (to_tsvector('simple', content));
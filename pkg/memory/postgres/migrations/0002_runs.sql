@@ -0,0 +1,44 @@
+-- Runs, the items they produced and the tokens they used
+CREATE TABLE IF NOT EXISTS {{prefix}}runs (
+    run_id TEXT PRIMARY KEY,
+    session_id TEXT,
+    agent_name TEXT NOT NULL,
+    model TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL,
+    input JSONB,
+    final_output JSONB,
+    error TEXT NOT NULL DEFAULT '',
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS {{prefix}}runs_session_idx ON {{prefix}}runs (session_id, started_at);
+CREATE INDEX IF NOT EXISTS {{prefix}}runs_agent_idx ON {{prefix}}runs (agent_name, started_at);
+CREATE INDEX IF NOT EXISTS {{prefix}}runs_status_idx ON {{prefix}}runs (status, started_at);
+
+CREATE TABLE IF NOT EXISTS {{prefix}}run_items (
+    run_id TEXT NOT NULL REFERENCES {{prefix}}runs (run_id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    item_type TEXT NOT NULL,
+    content TEXT NOT NULL DEFAULT '',
+    item JSONB NOT NULL,
+    PRIMARY KEY (run_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS {{prefix}}run_items_type_idx ON {{prefix}}run_items (item_type);
+CREATE INDEX IF NOT EXISTS {{prefix}}run_items_search_idx ON {{prefix}}run_items USING gin (to_tsvector('simple', content));
+
+CREATE TABLE IF NOT EXISTS {{prefix}}usage (
+    id BIGSERIAL PRIMARY KEY,
+    run_id TEXT NOT NULL REFERENCES {{prefix}}runs (run_id) ON DELETE CASCADE,
+    model TEXT NOT NULL,
+    prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    completion_tokens INTEGER NOT NULL DEFAULT 0,
+    total_tokens INTEGER NOT NULL DEFAULT 0,
+    cost_usd DOUBLE PRECISION NOT NULL DEFAULT 0,
+    recorded_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS {{prefix}}usage_run_idx ON {{prefix}}usage (run_id);
+CREATE INDEX IF NOT EXISTS {{prefix}}usage_model_idx ON {{prefix}}usage (model, recorded_at);
+CREATE INDEX IF NOT EXISTS {{prefix}}usage_recorded_idx ON {{prefix}}usage (recorded_at);
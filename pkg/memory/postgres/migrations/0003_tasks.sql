@@ -0,0 +1,18 @@
+-- Task registry and delegation chains, in the layout of runner.SQLTaskStore
+CREATE TABLE IF NOT EXISTS {{prefix}}tasks (
+    task_id TEXT PRIMARY KEY,
+    parent_agent TEXT NOT NULL,
+    child_agent TEXT NOT NULL,
+    status TEXT NOT NULL,
+    data TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS {{prefix}}tasks_status_idx ON {{prefix}}tasks (status);
+CREATE INDEX IF NOT EXISTS {{prefix}}tasks_agents_idx ON {{prefix}}tasks (parent_agent, child_agent);
+
+CREATE TABLE IF NOT EXISTS {{prefix}}delegation_chains (
+    agent_name TEXT PRIMARY KEY,
+    chain TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
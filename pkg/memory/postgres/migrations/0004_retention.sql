@@ -0,0 +1,8 @@
+-- End users of sessions and runs, so their data can be forgotten on request
+ALTER TABLE {{prefix}}sessions ADD COLUMN IF NOT EXISTS user_id TEXT;
+ALTER TABLE {{prefix}}runs ADD COLUMN IF NOT EXISTS user_id TEXT;
+
+CREATE INDEX IF NOT EXISTS {{prefix}}sessions_user_idx ON {{prefix}}sessions (user_id);
+CREATE INDEX IF NOT EXISTS {{prefix}}runs_user_idx ON {{prefix}}runs (user_id);
+CREATE INDEX IF NOT EXISTS {{prefix}}runs_started_idx ON {{prefix}}runs (started_at);
+CREATE INDEX IF NOT EXISTS {{prefix}}session_items_created_idx ON {{prefix}}session_items (created_at);
@@ -0,0 +1,110 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/retention"
+)
+
+// Ensure Store implements retention.Store
+var _ retention.Store = (*Store)(nil)
+
+// Purge removes the content stored before cutoff: session items, sessions left
+// empty, and the items, input, output and error of runs. Runs keep their agent,
+// model, status and times, and the usage table is untouched, so usage can still
+// be aggregated.
+func (s *Store) Purge(ctx context.Context, cutoff time.Time) (retention.Report, error) {
+	var report retention.Report
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return report, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	deleted, err := exec(ctx, tx, fmt.Sprintf(`DELETE FROM %s WHERE created_at < $1`, s.Table("session_items")), cutoff)
+	if err != nil {
+		return report, fmt.Errorf("failed to purge session items: %w", err)
+	}
+	report.Deleted += deleted
+
+	deleted, err = exec(ctx, tx, fmt.Sprintf(`DELETE FROM %s s WHERE updated_at < $1
+AND NOT EXISTS (SELECT 1 FROM %s i WHERE i.session_id = s.session_id)`, s.Table("sessions"), s.Table("session_items")), cutoff)
+	if err != nil {
+		return report, fmt.Errorf("failed to purge sessions: %w", err)
+	}
+	report.Deleted += deleted
+
+	redacted, err := s.anonymizeRuns(ctx, tx, `started_at < $1`, cutoff)
+	if err != nil {
+		return report, err
+	}
+	report.Redacted += redacted
+
+	if err := tx.Commit(); err != nil {
+		return report, fmt.Errorf("failed to commit purge: %w", err)
+	}
+	return report, nil
+}
+
+// Forget deletes the sessions of a user and removes the content and user of
+// their runs, keeping the runs' usage
+func (s *Store) Forget(ctx context.Context, userID string) (retention.Report, error) {
+	var report retention.Report
+	if userID == "" {
+		return report, errors.New("user ID is required")
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return report, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	deleted, err := exec(ctx, tx, fmt.Sprintf(`DELETE FROM %s WHERE user_id = $1`, s.Table("sessions")), userID)
+	if err != nil {
+		return report, fmt.Errorf("failed to delete sessions of user: %w", err)
+	}
+	report.Deleted += deleted
+
+	redacted, err := s.anonymizeRuns(ctx, tx, `user_id = $1`, userID)
+	if err != nil {
+		return report, err
+	}
+	report.Redacted += redacted
+
+	if err := tx.Commit(); err != nil {
+		return report, fmt.Errorf("failed to commit forget: %w", err)
+	}
+	return report, nil
+}
+
+// anonymizeRuns deletes the items of the runs matching a condition and clears
+// their content, session and user. It returns the number of runs changed.
+func (s *Store) anonymizeRuns(ctx context.Context, db execer, condition string, arg interface{}) (int, error) {
+	if _, err := exec(ctx, db, fmt.Sprintf(`DELETE FROM %s WHERE run_id IN (SELECT run_id FROM %s WHERE %s)`,
+		s.Table("run_items"), s.Table("runs"), condition), arg); err != nil {
+		return 0, fmt.Errorf("failed to delete run items: %w", err)
+	}
+	redacted, err := exec(ctx, db, fmt.Sprintf(`UPDATE %s SET input = NULL, final_output = NULL, error = '', session_id = NULL, user_id = NULL
+WHERE %s AND (input IS NOT NULL OR final_output IS NOT NULL OR error <> '' OR session_id IS NOT NULL OR user_id IS NOT NULL)`,
+		s.Table("runs"), condition), arg)
+	if err != nil {
+		return 0, fmt.Errorf("failed to anonymize runs: %w", err)
+	}
+	return redacted, nil
+}
+
+// exec runs a statement and returns the number of rows it affected
+func exec(ctx context.Context, db execer, query string, args ...interface{}) (int, error) {
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
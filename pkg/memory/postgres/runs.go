@@ -0,0 +1,370 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+)
+
+// Run statuses
+const (
+	RunStatusRunning   = "running"
+	RunStatusCompleted = "completed"
+	RunStatusFailed    = "failed"
+)
+
+// ErrRunNotFound is returned when a run does not exist
+var ErrRunNotFound = errors.New("postgres: run not found")
+
+// RunRecord is a stored run
+type RunRecord struct {
+	RunID       string
+	SessionID   string
+	UserID      string
+	AgentName   string
+	Model       string
+	Status      string
+	Input       interface{}
+	FinalOutput interface{}
+	Error       string
+	StartedAt   time.Time
+	FinishedAt  time.Time
+}
+
+// RunFilter selects runs in ListRuns. Empty fields match all runs.
+type RunFilter struct {
+	SessionID string
+	UserID    string
+	AgentName string
+	Status    string
+	Since     time.Time
+	Limit     int
+}
+
+// UsageRecord is the token usage of one model response
+type UsageRecord struct {
+	RunID            string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+	CostUSD          float64
+	RecordedAt       time.Time
+}
+
+// UsageSummary is the usage of one model over a period
+type UsageSummary struct {
+	Model            string
+	Runs             int
+	PromptTokens     int64
+	CompletionTokens int64
+	TotalTokens      int64
+	CostUSD          float64
+}
+
+// SearchResult is a stored item that matched a search
+type SearchResult struct {
+	// SessionID is set for session items, RunID for run items
+	SessionID string
+	RunID     string
+	Role      string
+	Content   string
+	CreatedAt time.Time
+}
+
+// SaveRun creates or updates a run. When res is not nil its items replace the
+// stored items of the run and the usage of its responses is recorded, so call it
+// with the result once the run has finished.
+func (s *Store) SaveRun(ctx context.Context, run *RunRecord, res *result.RunResult) error {
+	if run.RunID == "" {
+		return errors.New("run ID is required")
+	}
+	if run.Status == "" {
+		run.Status = RunStatusCompleted
+	}
+	if run.StartedAt.IsZero() {
+		run.StartedAt = time.Now().UTC()
+	}
+	if res != nil {
+		if run.Input == nil {
+			run.Input = res.Input
+		}
+		if run.FinalOutput == nil {
+			run.FinalOutput = res.FinalOutput
+		}
+	}
+
+	input, err := nullableJSON(run.Input)
+	if err != nil {
+		return fmt.Errorf("failed to serialize input of run %s: %w", run.RunID, err)
+	}
+	output, err := nullableJSON(run.FinalOutput)
+	if err != nil {
+		return fmt.Errorf("failed to serialize output of run %s: %w", run.RunID, err)
+	}
+	var finishedAt interface{}
+	if !run.FinishedAt.IsZero() {
+		finishedAt = run.FinishedAt
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (run_id, session_id, agent_name, model, status, input, final_output, error, started_at, finished_at, user_id)
+VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6::jsonb, $7::jsonb, $8, $9, $10, NULLIF($11, ''))
+ON CONFLICT (run_id) DO UPDATE SET status = excluded.status, final_output = excluded.final_output,
+	error = excluded.error, finished_at = excluded.finished_at`, s.Table("runs")),
+		run.RunID, run.SessionID, run.AgentName, run.Model, run.Status, input, output, run.Error, run.StartedAt, finishedAt, run.UserID,
+	); err != nil {
+		return fmt.Errorf("failed to save run %s: %w", run.RunID, err)
+	}
+
+	if res != nil {
+		if err := s.saveRunItems(ctx, tx, run.RunID, res.NewItems); err != nil {
+			return err
+		}
+		for _, response := range res.RawResponses {
+			if response.Usage == nil {
+				continue
+			}
+			if err := s.insertUsage(ctx, tx, usageRecord(run, response.Usage)); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit run %s: %w", run.RunID, err)
+	}
+	return nil
+}
+
+// saveRunItems replaces the items of a run
+func (s *Store) saveRunItems(ctx context.Context, tx *sql.Tx, runID string, items []result.RunItem) error {
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE run_id = $1`, s.Table("run_items")), runID); err != nil {
+		return fmt.Errorf("failed to replace items of run %s: %w", runID, err)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (run_id, position, item_type, content, item) VALUES ($1, $2, $3, $4, $5::jsonb)`, s.Table("run_items"))
+	for i, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return fmt.Errorf("failed to serialize item %d of run %s: %w", i, runID, err)
+		}
+		_, content := itemText(item)
+		if _, err := tx.ExecContext(ctx, query, runID, i, item.GetType(), content, string(data)); err != nil {
+			return fmt.Errorf("failed to save item %d of run %s: %w", i, runID, err)
+		}
+	}
+	return nil
+}
+
+// usageRecord converts the usage of a response of a run
+func usageRecord(run *RunRecord, usage *model.Usage) *UsageRecord {
+	return &UsageRecord{
+		RunID:            run.RunID,
+		Model:            run.Model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+}
+
+// RecordUsage stores the usage of a model response. The run must exist.
+func (s *Store) RecordUsage(ctx context.Context, usage *UsageRecord) error {
+	return s.insertUsage(ctx, s.db, usage)
+}
+
+// execer is implemented by *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertUsage inserts a usage row
+func (s *Store) insertUsage(ctx context.Context, db execer, usage *UsageRecord) error {
+	recordedAt := usage.RecordedAt
+	if recordedAt.IsZero() {
+		recordedAt = time.Now().UTC()
+	}
+	totalTokens := usage.TotalTokens
+	if totalTokens == 0 {
+		totalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (run_id, model, prompt_tokens, completion_tokens, total_tokens, cost_usd, recorded_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)`, s.Table("usage")),
+		usage.RunID, usage.Model, usage.PromptTokens, usage.CompletionTokens, totalTokens, usage.CostUSD, recordedAt,
+	); err != nil {
+		return fmt.Errorf("failed to record usage of run %s: %w", usage.RunID, err)
+	}
+	return nil
+}
+
+// runColumns are the columns read by scanRun
+const runColumns = `run_id, COALESCE(session_id, ''), COALESCE(user_id, ''), agent_name, model, status, input, final_output, error, started_at, finished_at`
+
+// GetRun returns a stored run
+func (s *Store) GetRun(ctx context.Context, runID string) (*RunRecord, error) {
+	row := s.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT %s FROM %s WHERE run_id = $1`, runColumns, s.Table("runs")), runID)
+	run, err := scanRun(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("run %s: %w", runID, ErrRunNotFound)
+	}
+	return run, err
+}
+
+// ListRuns returns the runs matching the filter, newest first
+func (s *Store) ListRuns(ctx context.Context, filter RunFilter) ([]*RunRecord, error) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+	if filter.SessionID != "" {
+		where("session_id = $%d", filter.SessionID)
+	}
+	if filter.UserID != "" {
+		where("user_id = $%d", filter.UserID)
+	}
+	if filter.AgentName != "" {
+		where("agent_name = $%d", filter.AgentName)
+	}
+	if filter.Status != "" {
+		where("status = $%d", filter.Status)
+	}
+	if !filter.Since.IsZero() {
+		where("started_at >= $%d", filter.Since)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM %s`, runColumns, s.Table("runs"))
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY started_at DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []*RunRecord
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanRun reads a run selected with runColumns
+func scanRun(row scanner) (*RunRecord, error) {
+	run := &RunRecord{}
+	var input, output []byte
+	var finishedAt sql.NullTime
+	if err := row.Scan(&run.RunID, &run.SessionID, &run.UserID, &run.AgentName, &run.Model, &run.Status,
+		&input, &output, &run.Error, &run.StartedAt, &finishedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan run: %w", err)
+	}
+	if finishedAt.Valid {
+		run.FinishedAt = finishedAt.Time
+	}
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &run.Input); err != nil {
+			return nil, fmt.Errorf("failed to deserialize input of run %s: %w", run.RunID, err)
+		}
+	}
+	if len(output) > 0 {
+		if err := json.Unmarshal(output, &run.FinalOutput); err != nil {
+			return nil, fmt.Errorf("failed to deserialize output of run %s: %w", run.RunID, err)
+		}
+	}
+	return run, nil
+}
+
+// SearchHistory returns session and run items whose text matches the query, newest
+// first. An empty sessionID searches all sessions and runs.
+func (s *Store) SearchHistory(ctx context.Context, query string, sessionID string, limit int) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT session_id, '' AS run_id, role, content, created_at FROM %s
+WHERE to_tsvector('simple', content) @@ plainto_tsquery('simple', $1) AND ($2 = '' OR session_id = $2)
+UNION ALL
+SELECT COALESCE(r.session_id, ''), i.run_id, i.item_type, i.content, r.started_at FROM %s i JOIN %s r ON r.run_id = i.run_id
+WHERE to_tsvector('simple', i.content) @@ plainto_tsquery('simple', $1) AND ($2 = '' OR r.session_id = $2)
+ORDER BY created_at DESC LIMIT $3`, s.Table("session_items"), s.Table("run_items"), s.Table("runs")),
+		query, sessionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search history: %w", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		if err := rows.Scan(&r.SessionID, &r.RunID, &r.Role, &r.Content, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// UsageByModel sums the usage of each model since the given time, most expensive first
+func (s *Store) UsageByModel(ctx context.Context, since time.Time) ([]UsageSummary, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT model, COUNT(DISTINCT run_id), SUM(prompt_tokens), SUM(completion_tokens), SUM(total_tokens), SUM(cost_usd)
+FROM %s WHERE recorded_at >= $1 GROUP BY model ORDER BY SUM(cost_usd) DESC, SUM(total_tokens) DESC`, s.Table("usage")), since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query usage: %w", err)
+	}
+	defer rows.Close()
+
+	var summaries []UsageSummary
+	for rows.Next() {
+		var u UsageSummary
+		if err := rows.Scan(&u.Model, &u.Runs, &u.PromptTokens, &u.CompletionTokens, &u.TotalTokens, &u.CostUSD); err != nil {
+			return nil, fmt.Errorf("failed to scan usage: %w", err)
+		}
+		summaries = append(summaries, u)
+	}
+	return summaries, rows.Err()
+}
+
+// nullableJSON encodes a value as JSON text, or nil for a nil value
+func nullableJSON(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
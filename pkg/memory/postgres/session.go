@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/memory"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/message"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+)
+
+// Session is a memory.Session stored in the session tables
+type Session struct {
+	store  *Store
+	id     string
+	userID string
+}
+
+// Ensure Session implements memory.Session
+var _ memory.Session = (*Session)(nil)
+
+// Session returns the session with the given ID. It is created on first write.
+func (s *Store) Session(id string) *Session {
+	return &Session{store: s, id: id}
+}
+
+// WithUserID records the end user the session belongs to, so Store.Forget can
+// delete it
+func (s *Session) WithUserID(userID string) *Session {
+	s.userID = userID
+	return s
+}
+
+// ID returns the identifier of the session
+func (s *Session) ID() string {
+	return s.id
+}
+
+// GetItems returns the stored items
+func (s *Session) GetItems(ctx context.Context, limit int) ([]interface{}, error) {
+	query := fmt.Sprintf(`SELECT item FROM %s WHERE session_id = $1 ORDER BY id`, s.store.Table("session_items"))
+	args := []interface{}{s.id}
+	if limit > 0 {
+		query = fmt.Sprintf(`SELECT item FROM (
+	SELECT id, item FROM %s WHERE session_id = $1 ORDER BY id DESC LIMIT $2
+) recent ORDER BY id`, s.store.Table("session_items"))
+		args = append(args, limit)
+	}
+
+	rows, err := s.store.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session %s: %w", s.id, err)
+	}
+	defer rows.Close()
+
+	items := make([]interface{}, 0)
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan session item: %w", err)
+		}
+		var item interface{}
+		if err := json.Unmarshal(data, &item); err != nil {
+			return nil, fmt.Errorf("failed to deserialize session item: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// AddItems appends items to the session
+func (s *Session) AddItems(ctx context.Context, items []interface{}) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	tx, err := s.store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	sessions := s.store.Table("sessions")
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (session_id, user_id, created_at, updated_at) VALUES ($1, NULLIF($2, ''), $3, $3)
+ON CONFLICT (session_id) DO UPDATE SET updated_at = excluded.updated_at, user_id = COALESCE(excluded.user_id, %s.user_id)`, sessions, sessions),
+		s.id, s.userID, now); err != nil {
+		return fmt.Errorf("failed to update session %s: %w", s.id, err)
+	}
+
+	query := fmt.Sprintf(`INSERT INTO %s (session_id, role, content, item, created_at) VALUES ($1, $2, $3, $4::jsonb, $5)`, s.store.Table("session_items"))
+	for _, item := range items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return fmt.Errorf("failed to serialize session item: %w", err)
+		}
+		role, content := itemText(item)
+		if _, err := tx.ExecContext(ctx, query, s.id, role, content, string(data), now); err != nil {
+			return fmt.Errorf("failed to append to session %s: %w", s.id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit session %s: %w", s.id, err)
+	}
+	return nil
+}
+
+// PopItem removes and returns the most recent item
+func (s *Session) PopItem(ctx context.Context) (interface{}, error) {
+	table := s.store.Table("session_items")
+	var data []byte
+	err := s.store.db.QueryRowContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id = (
+	SELECT id FROM %s WHERE session_id = $1 ORDER BY id DESC LIMIT 1
+) RETURNING item`, table, table), s.id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to pop from session %s: %w", s.id, err)
+	}
+
+	var item interface{}
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, fmt.Errorf("failed to deserialize session item: %w", err)
+	}
+	return item, nil
+}
+
+// Clear removes the session and all its items
+func (s *Session) Clear(ctx context.Context) error {
+	if _, err := s.store.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE session_id = $1`, s.store.Table("sessions")), s.id); err != nil {
+		return fmt.Errorf("failed to clear session %s: %w", s.id, err)
+	}
+	return nil
+}
+
+// itemText returns the role and searchable text of a session or run item
+func itemText(item interface{}) (string, string) {
+	switch v := item.(type) {
+	case string:
+		return "user", v
+	case *result.MessageItem:
+		return v.Role, v.Content
+	case *result.ToolResultItem:
+		return "tool", fmt.Sprint(v.Result)
+	}
+
+	parsed, _ := message.Parse(item)
+	switch v := parsed.(type) {
+	case message.Message:
+		if parts, ok := model.ContentParts(v.Content); ok {
+			return v.Role, model.ContentText(parts)
+		}
+		return v.Role, v.Text()
+	case message.ToolResult:
+		return message.RoleTool, model.ToolResultText(v.Content)
+	}
+	return "", ""
+}
@@ -0,0 +1,178 @@
+// Package postgres stores sessions, runs, run items, token usage and tasks in
+// Postgres. The schema ships as embedded migrations, applied with Store.Migrate,
+// and is indexed for searching conversation history and aggregating usage. The
+// caller opens the *sql.DB with a driver of their choice (e.g. pgx or lib/pq).
+//
+//	store := postgres.NewStore(db)
+//	if err := store.Migrate(ctx); err != nil {
+//		log.Fatal(err)
+//	}
+//	session := store.Session("user-42")
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+)
+
+// DefaultTablePrefix is the prefix of the tables created by the store. It matches
+// the default of runner.SQLTaskStore so both can share the task tables.
+const DefaultTablePrefix = runner.DefaultTaskTablePrefix
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is a versioned schema change
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// Store is the entry point to the Postgres storage
+type Store struct {
+	db     *sql.DB
+	prefix string
+}
+
+// NewStore creates a new store. Call Migrate once to create or upgrade the schema.
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db, prefix: DefaultTablePrefix}
+}
+
+// WithTablePrefix sets the prefix of all tables
+func (s *Store) WithTablePrefix(prefix string) *Store {
+	s.prefix = prefix
+	return s
+}
+
+// DB returns the database handle, for queries the store does not cover
+func (s *Store) DB() *sql.DB {
+	return s.db
+}
+
+// Table returns the name of a table with the store's prefix, such as "runs"
+func (s *Store) Table(name string) string {
+	return s.prefix + name
+}
+
+// TaskStore returns a runner.TaskStore that keeps tasks in the store's task tables
+func (s *Store) TaskStore() *runner.SQLTaskStore {
+	return runner.NewPostgresTaskStore(s.db).WithTablePrefix(s.prefix)
+}
+
+// Migrations returns the migrations of the schema in order, with the table prefix applied
+func (s *Store) Migrations() ([]Migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	migrations := make([]Migration, 0, len(entries))
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".sql")
+		versionText, description, ok := strings.Cut(name, "_")
+		version, err := strconv.Atoi(versionText)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+
+		data, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, Migration{
+			Version: version,
+			Name:    description,
+			SQL:     strings.ReplaceAll(string(data), "{{prefix}}", s.prefix),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrate applies the migrations that have not been applied yet. It holds an
+// advisory lock while migrating, so replicas can call it concurrently at startup.
+func (s *Store) Migrate(ctx context.Context) error {
+	migrations, err := s.Migrations()
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, s.lockID()); err != nil {
+		return fmt.Errorf("failed to lock schema: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+)`, s.Table("schema_migrations"))); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	var current int
+	if err := tx.QueryRowContext(ctx, fmt.Sprintf(`SELECT COALESCE(MAX(version), 0) FROM %s`, s.Table("schema_migrations"))).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for _, migration := range migrations {
+		if migration.Version <= current {
+			continue
+		}
+		for _, stmt := range splitStatements(migration.SQL) {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (version, name) VALUES ($1, $2)`, s.Table("schema_migrations")),
+			migration.Version, migration.Name); err != nil {
+			return fmt.Errorf("failed to record migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration: %w", err)
+	}
+	return nil
+}
+
+// lockID returns the advisory lock key of the store's schema
+func (s *Store) lockID() int64 {
+	h := fnv.New64a()
+	h.Write([]byte("agentsdk-schema:" + s.prefix))
+	return int64(h.Sum64())
+}
+
+// splitStatements splits a migration into statements, dropping comments
+func splitStatements(script string) []string {
+	var lines []string
+	for _, line := range strings.Split(script, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	var statements []string
+	for _, stmt := range strings.Split(strings.Join(lines, "\n"), ";") {
+		if stmt = strings.TrimSpace(stmt); stmt != "" {
+			statements = append(statements, stmt)
+		}
+	}
+	return statements
+}
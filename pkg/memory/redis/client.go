@@ -0,0 +1,85 @@
+// Package redis provides Redis-backed implementations of the session and
+// workflow state stores, and of the counters of rate limiters. The package does not depend on a specific Redis
+// driver; instead it talks to Redis through the small Client interface, which
+// can be satisfied by a thin adapter around go-redis, rueidis or similar.
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultKeyPrefix is the prefix used for all keys unless configured otherwise
+const DefaultKeyPrefix = "agentsdk:"
+
+var (
+	// ErrNil must be returned by Client.Get when the key does not exist
+	ErrNil = errors.New("redis: nil")
+
+	// ErrVersionConflict is returned when a write is rejected because another
+	// writer has updated the record since it was last read
+	ErrVersionConflict = errors.New("redis: version conflict")
+)
+
+// Client is the subset of Redis commands used by this package
+type Client interface {
+	// Get returns the value stored at key, or ErrNil if the key does not exist
+	Get(ctx context.Context, key string) (string, error)
+
+	// Set stores value at key. A ttl of zero means the key does not expire.
+	Set(ctx context.Context, key string, value string, ttl time.Duration) error
+
+	// Del removes the given keys
+	Del(ctx context.Context, keys ...string) error
+
+	// Eval executes a Lua script server side
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// toInt64 converts a reply from Eval to an int64
+func toInt64(reply interface{}) (int64, bool) {
+	switch v := reply.(type) {
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	case float64:
+		return int64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// toStrings converts a multi-bulk reply from Eval to a slice of strings
+func toStrings(reply interface{}) ([]string, bool) {
+	switch v := reply.(type) {
+	case nil:
+		return []string{}, true
+	case []string:
+		return v, true
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			switch s := item.(type) {
+			case string:
+				result = append(result, s)
+			case []byte:
+				result = append(result, string(s))
+			default:
+				return nil, false
+			}
+		}
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
+// ttlMillis converts a ttl to the millisecond argument used by the scripts
+func ttlMillis(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	return ttl.Milliseconds()
+}
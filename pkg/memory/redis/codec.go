@@ -0,0 +1,371 @@
+package redis
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Codec serializes session items
+type Codec interface {
+	// Marshal encodes an item
+	Marshal(item interface{}) ([]byte, error)
+
+	// Unmarshal decodes an item encoded by Marshal
+	Unmarshal(data []byte) (interface{}, error)
+}
+
+// JSONCodec stores items as JSON. Numbers are decoded as float64.
+type JSONCodec struct{}
+
+// Marshal encodes an item as JSON
+func (JSONCodec) Marshal(item interface{}) ([]byte, error) {
+	return json.Marshal(item)
+}
+
+// Unmarshal decodes a JSON item
+func (JSONCodec) Unmarshal(data []byte) (interface{}, error) {
+	var item interface{}
+	if err := json.Unmarshal(data, &item); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// MsgpackCodec stores items as MessagePack, which is more compact than JSON and
+// keeps integers and binary data intact. Maps, slices and scalars are encoded
+// directly; other values, such as structs, are converted through their JSON
+// representation first so their json tags apply.
+type MsgpackCodec struct{}
+
+// Marshal encodes an item as MessagePack
+func (MsgpackCodec) Marshal(item interface{}) ([]byte, error) {
+	return appendMsgpack(nil, item)
+}
+
+// Unmarshal decodes a MessagePack item. Maps decode to map[string]interface{},
+// arrays to []interface{}, integers to int64 or uint64 and binary data to []byte.
+func (MsgpackCodec) Unmarshal(data []byte) (interface{}, error) {
+	decoder := &msgpackDecoder{data: data}
+	item, err := decoder.decode()
+	if err != nil {
+		return nil, err
+	}
+	if decoder.pos != len(data) {
+		return nil, errors.New("msgpack: trailing data")
+	}
+	return item, nil
+}
+
+// appendMsgpack appends the MessagePack encoding of v to buf
+func appendMsgpack(buf []byte, v interface{}) ([]byte, error) {
+	switch value := v.(type) {
+	case nil:
+		return append(buf, 0xc0), nil
+	case bool:
+		if value {
+			return append(buf, 0xc3), nil
+		}
+		return append(buf, 0xc2), nil
+	case string:
+		return appendMsgpackString(buf, value), nil
+	case []byte:
+		return appendMsgpackBinary(buf, value), nil
+	case json.Number:
+		if n, err := value.Int64(); err == nil {
+			return appendMsgpackInt(buf, n), nil
+		}
+		f, err := value.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("msgpack: invalid number %q", value)
+		}
+		return appendMsgpackFloat(buf, f), nil
+	case []interface{}:
+		buf = appendMsgpackLength(buf, len(value), 0x90, 0xdc)
+		for _, item := range value {
+			var err error
+			if buf, err = appendMsgpack(buf, item); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		buf = appendMsgpackLength(buf, len(value), 0x80, 0xde)
+		for _, key := range keys {
+			buf = appendMsgpackString(buf, key)
+			var err error
+			if buf, err = appendMsgpack(buf, value[key]); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return appendMsgpackInt(buf, rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if u := rv.Uint(); u > math.MaxInt64 {
+			return binary.BigEndian.AppendUint64(append(buf, 0xcf), u), nil
+		}
+		return appendMsgpackInt(buf, int64(rv.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return appendMsgpackFloat(buf, rv.Float()), nil
+	}
+
+	// Convert everything else through JSON so struct tags and marshalers apply
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return appendMsgpack(buf, generic)
+}
+
+// appendMsgpackInt appends an integer in its smallest encoding
+func appendMsgpackInt(buf []byte, n int64) []byte {
+	switch {
+	case n >= 0 && n <= 0x7f:
+		return append(buf, byte(n))
+	case n < 0 && n >= -32:
+		return append(buf, byte(n))
+	case n >= math.MinInt8 && n <= math.MaxInt8:
+		return append(buf, 0xd0, byte(n))
+	case n >= math.MinInt16 && n <= math.MaxInt16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xd1), uint16(n))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		return binary.BigEndian.AppendUint32(append(buf, 0xd2), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, 0xd3), uint64(n))
+	}
+}
+
+// appendMsgpackFloat appends a float64
+func appendMsgpackFloat(buf []byte, f float64) []byte {
+	return binary.BigEndian.AppendUint64(append(buf, 0xcb), math.Float64bits(f))
+}
+
+// appendMsgpackString appends a string
+func appendMsgpackString(buf []byte, s string) []byte {
+	switch n := len(s); {
+	case n <= 31:
+		buf = append(buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		buf = append(buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		buf = binary.BigEndian.AppendUint16(append(buf, 0xda), uint16(n))
+	default:
+		buf = binary.BigEndian.AppendUint32(append(buf, 0xdb), uint32(n))
+	}
+	return append(buf, s...)
+}
+
+// appendMsgpackBinary appends binary data
+func appendMsgpackBinary(buf []byte, data []byte) []byte {
+	switch n := len(data); {
+	case n <= math.MaxUint8:
+		buf = append(buf, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		buf = binary.BigEndian.AppendUint16(append(buf, 0xc5), uint16(n))
+	default:
+		buf = binary.BigEndian.AppendUint32(append(buf, 0xc6), uint32(n))
+	}
+	return append(buf, data...)
+}
+
+// appendMsgpackLength appends the header of an array or map
+func appendMsgpackLength(buf []byte, n int, fix byte, code16 byte) []byte {
+	switch {
+	case n <= 15:
+		return append(buf, fix|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, code16), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(buf, code16+1), uint32(n))
+	}
+}
+
+// errMsgpackShort is returned when the data ends in the middle of a value
+var errMsgpackShort = errors.New("msgpack: unexpected end of data")
+
+// msgpackDecoder decodes MessagePack data into generic values
+type msgpackDecoder struct {
+	data []byte
+	pos  int
+}
+
+// next returns the next n bytes
+func (d *msgpackDecoder) next(n int) ([]byte, error) {
+	if n < 0 || d.pos+n > len(d.data) {
+		return nil, errMsgpackShort
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+// uint reads a big endian unsigned integer of n bytes
+func (d *msgpackDecoder) uint(n int) (uint64, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+// decode decodes the next value
+func (d *msgpackDecoder) decode() (interface{}, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	code := b[0]
+
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xe0 == 0xa0:
+		return d.string(int(code & 0x1f))
+	case code&0xf0 == 0x90:
+		return d.array(int(code & 0x0f))
+	case code&0xf0 == 0x80:
+		return d.mapValue(int(code & 0x0f))
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xc5, 0xc6:
+		n, err := d.uint(1 << (code - 0xc4))
+		if err != nil {
+			return nil, err
+		}
+		data, err := d.next(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(nil), data...), nil
+	case 0xca:
+		bits, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(bits))), err
+	case 0xcb:
+		bits, err := d.uint(8)
+		return math.Float64frombits(bits), err
+	case 0xcc, 0xcd, 0xce:
+		n, err := d.uint(1 << (code - 0xcc))
+		return int64(n), err
+	case 0xcf:
+		n, err := d.uint(8)
+		if n <= math.MaxInt64 {
+			return int64(n), err
+		}
+		return n, err
+	case 0xd0:
+		n, err := d.uint(1)
+		return int64(int8(n)), err
+	case 0xd1:
+		n, err := d.uint(2)
+		return int64(int16(n)), err
+	case 0xd2:
+		n, err := d.uint(4)
+		return int64(int32(n)), err
+	case 0xd3:
+		n, err := d.uint(8)
+		return int64(n), err
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (code - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+		return d.string(int(n))
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (code - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.array(int(n))
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (code - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.mapValue(int(n))
+	}
+	return nil, fmt.Errorf("msgpack: unsupported type code 0x%x", code)
+}
+
+// string decodes a string of n bytes
+func (d *msgpackDecoder) string(n int) (interface{}, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// array decodes an array of n items
+func (d *msgpackDecoder) array(n int) (interface{}, error) {
+	if n > len(d.data)-d.pos {
+		return nil, errMsgpackShort
+	}
+	items := make([]interface{}, n)
+	for i := range items {
+		item, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+// mapValue decodes a map of n entries with string keys
+func (d *msgpackDecoder) mapValue(n int) (interface{}, error) {
+	if n > len(d.data)-d.pos {
+		return nil, errMsgpackShort
+	}
+	m := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		keyString, ok := key.(string)
+		if !ok {
+			keyString = fmt.Sprint(key)
+		}
+		value, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+		m[keyString] = value
+	}
+	return m, nil
+}
@@ -0,0 +1,94 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/ratelimit"
+)
+
+// incrScript adds ARGV[1] to a counter and sets it to expire after ARGV[2]
+// milliseconds unless it already expires
+const incrScript = `
+local value = redis.call('INCRBY', KEYS[1], ARGV[1])
+if tonumber(ARGV[2]) > 0 and redis.call('PTTL', KEYS[1]) < 0 then
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return value
+`
+
+// Counter is a ratelimit.Counter kept in Redis, so that the CounterLimiters of
+// several processes share the limits of an API key:
+//
+//	limiter := ratelimit.NewCounterLimiter(redis.NewCounter(client), "anthropic:org-key", 50, 80000)
+type Counter struct {
+	client Client
+	prefix string
+}
+
+// Ensure Counter implements ratelimit.Counter
+var _ ratelimit.Counter = (*Counter)(nil)
+
+// NewCounter creates a counter store using the given Redis client
+func NewCounter(client Client) *Counter {
+	return &Counter{client: client, prefix: DefaultKeyPrefix}
+}
+
+// WithKeyPrefix sets the prefix used for all keys written by the counter store
+func (c *Counter) WithKeyPrefix(prefix string) *Counter {
+	c.prefix = prefix
+	return c
+}
+
+// Add adds n to a counter, setting it to expire after ttl when it is created,
+// and returns the new value
+func (c *Counter) Add(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error) {
+	reply, err := c.client.Eval(ctx, incrScript, []string{c.key(key)}, n, ttlMillis(ttl))
+	if err != nil {
+		return 0, fmt.Errorf("failed to add to counter %s: %w", key, err)
+	}
+	value, ok := toInt64(reply)
+	if !ok {
+		return 0, fmt.Errorf("unexpected reply adding to counter %s: %v", key, reply)
+	}
+	return value, nil
+}
+
+// Get returns the value of a counter, zero if it does not exist
+func (c *Counter) Get(ctx context.Context, key string) (int64, error) {
+	raw, err := c.client.Get(ctx, c.key(key))
+	if errors.Is(err, ErrNil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read counter %s: %w", key, err)
+	}
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value of counter %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// Delete removes counters
+func (c *Counter) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = c.key(key)
+	}
+	if err := c.client.Del(ctx, prefixed...); err != nil {
+		return fmt.Errorf("failed to delete counters: %w", err)
+	}
+	return nil
+}
+
+// key returns the Redis key of a counter
+func (c *Counter) key(key string) string {
+	return c.prefix + "ratelimit:" + key
+}
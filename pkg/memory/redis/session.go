@@ -0,0 +1,259 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/memory"
+)
+
+// appendScript appends items to a session list if the session is at the expected
+// version, trims it to the maximum length and refreshes its expiry. KEYS[1] is the
+// list and KEYS[2] the version counter. ARGV holds the ttl, the expected version
+// (negative for any), the maximum length and the items. It returns the new version,
+// or -1 on a version conflict.
+const appendScript = `
+local version = tonumber(redis.call('GET', KEYS[2]) or '0')
+local expected = tonumber(ARGV[2])
+if expected >= 0 and expected ~= version then
+  return -1
+end
+for i = 4, #ARGV do
+  redis.call('RPUSH', KEYS[1], ARGV[i])
+end
+local maxItems = tonumber(ARGV[3])
+if maxItems > 0 then
+  redis.call('LTRIM', KEYS[1], -maxItems, -1)
+end
+version = redis.call('INCR', KEYS[2])
+local ttl = tonumber(ARGV[1])
+if ttl > 0 then
+  redis.call('PEXPIRE', KEYS[1], ttl)
+  redis.call('PEXPIRE', KEYS[2], ttl)
+end
+return version
+`
+
+// rangeScript returns the version of a session followed by its last ARGV[1] items,
+// or all items if ARGV[1] <= 0, and refreshes the expiry of the session
+const rangeScript = `
+local limit = tonumber(ARGV[1])
+local items
+if limit > 0 then
+  items = redis.call('LRANGE', KEYS[1], -limit, -1)
+else
+  items = redis.call('LRANGE', KEYS[1], 0, -1)
+end
+local ttl = tonumber(ARGV[2])
+if ttl > 0 then
+  redis.call('PEXPIRE', KEYS[1], ttl)
+  redis.call('PEXPIRE', KEYS[2], ttl)
+end
+table.insert(items, 1, redis.call('GET', KEYS[2]) or '0')
+return items
+`
+
+// popScript removes and returns the last item of a session list
+const popScript = `
+local item = redis.call('RPOP', KEYS[1])
+if not item then
+  return {}
+end
+redis.call('INCR', KEYS[2])
+local ttl = tonumber(ARGV[1])
+if ttl > 0 then
+  redis.call('PEXPIRE', KEYS[2], ttl)
+end
+return {item}
+`
+
+// Session is a memory.Session stored as a Redis list of encoded items. Every write
+// increments the version of the session, so concurrent writers can detect each
+// other with GetItemsWithVersion and AddItemsIfVersion.
+type Session struct {
+	client   Client
+	id       string
+	prefix   string
+	ttl      time.Duration
+	maxItems int
+	codec    Codec
+	mu       sync.RWMutex
+}
+
+// Ensure Session implements memory.Session
+var _ memory.Session = (*Session)(nil)
+
+// NewSession creates a new Redis-backed session
+func NewSession(client Client, id string) *Session {
+	return &Session{
+		client: client,
+		id:     id,
+		prefix: DefaultKeyPrefix,
+		codec:  JSONCodec{},
+	}
+}
+
+// WithKeyPrefix sets the prefix used for the session key
+func (s *Session) WithKeyPrefix(prefix string) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prefix = prefix
+	return s
+}
+
+// WithTTL sets the time to live of the session, refreshed on every read and write
+// so that active sessions stay alive. Zero disables expiry.
+func (s *Session) WithTTL(ttl time.Duration) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ttl = ttl
+	return s
+}
+
+// WithMaxItems bounds the history to the most recent maxItems items; older items
+// are dropped on write. Zero keeps all items.
+func (s *Session) WithMaxItems(maxItems int) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxItems = maxItems
+	return s
+}
+
+// WithCodec sets the serialization of items, JSONCodec by default. Sessions must
+// be read with the codec they were written with.
+func (s *Session) WithCodec(codec Codec) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codec = codec
+	return s
+}
+
+// ID returns the identifier of the session
+func (s *Session) ID() string {
+	return s.id
+}
+
+// GetItems returns the stored items
+func (s *Session) GetItems(ctx context.Context, limit int) ([]interface{}, error) {
+	items, _, err := s.GetItemsWithVersion(ctx, limit)
+	return items, err
+}
+
+// GetItemsWithVersion returns the stored items and the version of the session, to
+// pass to AddItemsIfVersion
+func (s *Session) GetItemsWithVersion(ctx context.Context, limit int) ([]interface{}, int64, error) {
+	s.mu.RLock()
+	ttl, codec := s.ttl, s.codec
+	s.mu.RUnlock()
+
+	reply, err := s.client.Eval(ctx, rangeScript, s.keys(), limit, ttlMillis(ttl))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read session %s: %w", s.id, err)
+	}
+
+	encoded, ok := toStrings(reply)
+	if !ok || len(encoded) == 0 {
+		return nil, 0, fmt.Errorf("unexpected reply reading session %s: %v", s.id, reply)
+	}
+	version, err := strconv.ParseInt(encoded[0], 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid version of session %s: %w", s.id, err)
+	}
+
+	items := make([]interface{}, 0, len(encoded)-1)
+	for _, raw := range encoded[1:] {
+		item, err := codec.Unmarshal([]byte(raw))
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to deserialize session item: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, version, nil
+}
+
+// AddItems appends items to the session
+func (s *Session) AddItems(ctx context.Context, items []interface{}) error {
+	if len(items) == 0 {
+		return nil
+	}
+	_, err := s.AddItemsIfVersion(ctx, AnyVersion, items)
+	return err
+}
+
+// AddItemsIfVersion appends items only if the session is still at the expected
+// version, returning the new version. It returns ErrVersionConflict if another
+// writer changed the session since it was read; pass AnyVersion to append
+// unconditionally.
+func (s *Session) AddItemsIfVersion(ctx context.Context, expected int64, items []interface{}) (int64, error) {
+	s.mu.RLock()
+	args := []interface{}{ttlMillis(s.ttl), expected, s.maxItems}
+	codec := s.codec
+	s.mu.RUnlock()
+
+	for _, item := range items {
+		data, err := codec.Marshal(item)
+		if err != nil {
+			return 0, fmt.Errorf("failed to serialize session item: %w", err)
+		}
+		args = append(args, string(data))
+	}
+
+	reply, err := s.client.Eval(ctx, appendScript, s.keys(), args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to append to session %s: %w", s.id, err)
+	}
+	version, ok := toInt64(reply)
+	if !ok {
+		return 0, fmt.Errorf("unexpected reply appending to session %s: %v", s.id, reply)
+	}
+	if version < 0 {
+		return 0, fmt.Errorf("failed to append to session %s: %w", s.id, ErrVersionConflict)
+	}
+	return version, nil
+}
+
+// PopItem removes and returns the most recent item
+func (s *Session) PopItem(ctx context.Context) (interface{}, error) {
+	s.mu.RLock()
+	ttl, codec := s.ttl, s.codec
+	s.mu.RUnlock()
+
+	reply, err := s.client.Eval(ctx, popScript, s.keys(), ttlMillis(ttl))
+	if err != nil && !errors.Is(err, ErrNil) {
+		return nil, fmt.Errorf("failed to pop from session %s: %w", s.id, err)
+	}
+
+	encoded, ok := toStrings(reply)
+	if !ok {
+		return nil, fmt.Errorf("unexpected reply popping from session %s: %v", s.id, reply)
+	}
+	if len(encoded) == 0 {
+		return nil, nil
+	}
+
+	item, err := codec.Unmarshal([]byte(encoded[0]))
+	if err != nil {
+		return nil, fmt.Errorf("failed to deserialize session item: %w", err)
+	}
+	return item, nil
+}
+
+// Clear removes all items from the session
+func (s *Session) Clear(ctx context.Context) error {
+	if err := s.client.Del(ctx, s.keys()...); err != nil {
+		return fmt.Errorf("failed to clear session %s: %w", s.id, err)
+	}
+	return nil
+}
+
+// keys returns the key of the item list and of the version counter
+func (s *Session) keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key := s.prefix + "session:" + s.id
+	return []string{key, key + ":version"}
+}
@@ -0,0 +1,247 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AnyVersion can be passed to Store.Save to skip the optimistic locking check
+const AnyVersion int64 = -1
+
+// ErrNotFound is returned when a record or checkpoint does not exist
+var ErrNotFound = errors.New("redis: record not found")
+
+// saveScript writes a versioned record and records a checkpoint of it, named
+// after ARGV[4] or the version. Records are stored as "<version>\n<json>" so
+// the version can be checked without decoding the payload.
+const saveScript = `
+local current = redis.call('GET', KEYS[1])
+local version = 0
+if current then
+  version = tonumber(string.match(current, '^(%d+)\n')) or 0
+end
+local expected = tonumber(ARGV[1])
+if expected >= 0 and expected ~= version then
+  return -1
+end
+version = version + 1
+local record = version .. '\n' .. ARGV[2]
+local ttl = tonumber(ARGV[3])
+local checkpointID = ARGV[4]
+if checkpointID == '' then
+  checkpointID = tostring(version)
+end
+local checkpoint = KEYS[3] .. checkpointID
+if ttl > 0 then
+  redis.call('SET', KEYS[1], record, 'PX', ttl)
+  redis.call('SET', checkpoint, record, 'PX', ttl)
+else
+  redis.call('SET', KEYS[1], record)
+  redis.call('SET', checkpoint, record)
+end
+redis.call('ZADD', KEYS[2], version, checkpointID)
+if ttl > 0 then
+  redis.call('PEXPIRE', KEYS[2], ttl)
+end
+return version
+`
+
+// listCheckpointsScript returns the checkpoint IDs of a record, oldest first
+const listCheckpointsScript = `
+return redis.call('ZRANGE', KEYS[1], 0, -1)
+`
+
+// deleteCheckpointScript removes a single checkpoint
+const deleteCheckpointScript = `
+redis.call('ZREM', KEYS[1], ARGV[1])
+return redis.call('DEL', KEYS[2])
+`
+
+// Store is a versioned JSON document store on top of Redis. Every write
+// increments the version of the record and keeps the written value as a
+// checkpoint, and writes can be made conditional on the version last seen by
+// the caller.
+type Store struct {
+	client Client
+	prefix string
+	ttl    time.Duration
+	mu     sync.RWMutex
+}
+
+// NewStore creates a new Store using the given client
+func NewStore(client Client) *Store {
+	return &Store{
+		client: client,
+		prefix: DefaultKeyPrefix,
+	}
+}
+
+// WithKeyPrefix sets the prefix used for all keys written by the store
+func (s *Store) WithKeyPrefix(prefix string) *Store {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prefix = prefix
+	return s
+}
+
+// WithTTL sets the time to live of records and checkpoints. Zero disables expiry.
+func (s *Store) WithTTL(ttl time.Duration) *Store {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ttl = ttl
+	return s
+}
+
+// Save stores value under id and returns the new version. If expectedVersion is
+// not AnyVersion the write only succeeds when the stored version matches it,
+// otherwise ErrVersionConflict is returned. A record that does not exist yet has
+// version 0.
+func (s *Store) Save(ctx context.Context, id string, value interface{}, expectedVersion int64) (int64, error) {
+	return s.SaveCheckpoint(ctx, id, "", value, expectedVersion)
+}
+
+// SaveCheckpoint is Save with the ID of the checkpoint it records. An empty ID
+// names the checkpoint after the new version.
+func (s *Store) SaveCheckpoint(ctx context.Context, id string, checkpointID string, value interface{}, expectedVersion int64) (int64, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to serialize record %s: %w", id, err)
+	}
+
+	s.mu.RLock()
+	keys := []string{s.recordKey(id), s.checkpointSetKey(id), s.checkpointKey(id, "")}
+	ttl := ttlMillis(s.ttl)
+	s.mu.RUnlock()
+
+	reply, err := s.client.Eval(ctx, saveScript, keys, expectedVersion, string(data), ttl, checkpointID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to save record %s: %w", id, err)
+	}
+
+	version, ok := toInt64(reply)
+	if !ok {
+		return 0, fmt.Errorf("unexpected reply saving record %s: %v", id, reply)
+	}
+	if version < 0 {
+		return 0, fmt.Errorf("record %s (expected version %d): %w", id, expectedVersion, ErrVersionConflict)
+	}
+
+	return version, nil
+}
+
+// Load decodes the latest value stored under id into out and returns its version
+func (s *Store) Load(ctx context.Context, id string, out interface{}) (int64, error) {
+	s.mu.RLock()
+	key := s.recordKey(id)
+	s.mu.RUnlock()
+
+	return s.get(ctx, key, out)
+}
+
+// ListCheckpoints returns the checkpoint IDs stored for id, oldest first
+func (s *Store) ListCheckpoints(ctx context.Context, id string) ([]string, error) {
+	s.mu.RLock()
+	key := s.checkpointSetKey(id)
+	s.mu.RUnlock()
+
+	reply, err := s.client.Eval(ctx, listCheckpointsScript, []string{key})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints of %s: %w", id, err)
+	}
+
+	checkpoints, ok := toStrings(reply)
+	if !ok {
+		return nil, fmt.Errorf("unexpected reply listing checkpoints of %s: %v", id, reply)
+	}
+	return checkpoints, nil
+}
+
+// LoadCheckpoint decodes the value stored at a checkpoint into out
+func (s *Store) LoadCheckpoint(ctx context.Context, id string, checkpointID string, out interface{}) error {
+	s.mu.RLock()
+	key := s.checkpointKey(id, checkpointID)
+	s.mu.RUnlock()
+
+	_, err := s.get(ctx, key, out)
+	return err
+}
+
+// DeleteCheckpoint removes a checkpoint
+func (s *Store) DeleteCheckpoint(ctx context.Context, id string, checkpointID string) error {
+	s.mu.RLock()
+	keys := []string{s.checkpointSetKey(id), s.checkpointKey(id, checkpointID)}
+	s.mu.RUnlock()
+
+	if _, err := s.client.Eval(ctx, deleteCheckpointScript, keys, checkpointID); err != nil {
+		return fmt.Errorf("failed to delete checkpoint %s of %s: %w", checkpointID, id, err)
+	}
+	return nil
+}
+
+// Delete removes a record together with all of its checkpoints
+func (s *Store) Delete(ctx context.Context, id string) error {
+	checkpoints, err := s.ListCheckpoints(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	keys := []string{s.recordKey(id), s.checkpointSetKey(id)}
+	for _, checkpointID := range checkpoints {
+		keys = append(keys, s.checkpointKey(id, checkpointID))
+	}
+	s.mu.RUnlock()
+
+	if err := s.client.Del(ctx, keys...); err != nil {
+		return fmt.Errorf("failed to delete record %s: %w", id, err)
+	}
+	return nil
+}
+
+// get reads and decodes a versioned record
+func (s *Store) get(ctx context.Context, key string, out interface{}) (int64, error) {
+	raw, err := s.client.Get(ctx, key)
+	if errors.Is(err, ErrNil) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+
+	header, payload, found := strings.Cut(raw, "\n")
+	if !found {
+		return 0, fmt.Errorf("malformed record at %s", key)
+	}
+
+	version, err := strconv.ParseInt(header, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("malformed record version at %s: %w", key, err)
+	}
+
+	if out != nil {
+		if err := json.Unmarshal([]byte(payload), out); err != nil {
+			return 0, fmt.Errorf("failed to deserialize %s: %w", key, err)
+		}
+	}
+
+	return version, nil
+}
+
+// Keys of a record share a hash tag so the scripts also work on Redis Cluster
+func (s *Store) recordKey(id string) string {
+	return s.prefix + "state:{" + id + "}"
+}
+
+func (s *Store) checkpointSetKey(id string) string {
+	return s.prefix + "state:{" + id + "}:checkpoints"
+}
+
+func (s *Store) checkpointKey(id string, checkpointID string) string {
+	return s.prefix + "state:{" + id + "}:checkpoint:" + checkpointID
+}
@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"sync"
+)
+
+// Session stores the conversation history of an agent so that it can be
+// continued across runs, processes or replicas
+type Session interface {
+	// ID returns the identifier of the session
+	ID() string
+
+	// GetItems returns the stored items in insertion order. A limit <= 0 returns all items,
+	// otherwise only the most recent limit items are returned.
+	GetItems(ctx context.Context, limit int) ([]interface{}, error)
+
+	// AddItems appends items to the session
+	AddItems(ctx context.Context, items []interface{}) error
+
+	// PopItem removes and returns the most recent item, or nil if the session is empty
+	PopItem(ctx context.Context) (interface{}, error)
+
+	// Clear removes all items from the session
+	Clear(ctx context.Context) error
+}
+
+// InMemorySession is a Session that keeps its items in process memory
+type InMemorySession struct {
+	id    string
+	items []interface{}
+	mu    sync.RWMutex
+}
+
+// NewInMemorySession creates a new in-memory session
+func NewInMemorySession(id string) *InMemorySession {
+	return &InMemorySession{
+		id:    id,
+		items: make([]interface{}, 0),
+	}
+}
+
+// ID returns the identifier of the session
+func (s *InMemorySession) ID() string {
+	return s.id
+}
+
+// GetItems returns the stored items
+func (s *InMemorySession) GetItems(ctx context.Context, limit int) ([]interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start := 0
+	if limit > 0 && limit < len(s.items) {
+		start = len(s.items) - limit
+	}
+
+	items := make([]interface{}, len(s.items)-start)
+	copy(items, s.items[start:])
+	return items, nil
+}
+
+// AddItems appends items to the session
+func (s *InMemorySession) AddItems(ctx context.Context, items []interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = append(s.items, items...)
+	return nil
+}
+
+// PopItem removes and returns the most recent item
+func (s *InMemorySession) PopItem(ctx context.Context) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.items) == 0 {
+		return nil, nil
+	}
+
+	item := s.items[len(s.items)-1]
+	s.items = s.items[:len(s.items)-1]
+	return item, nil
+}
+
+// Clear removes all items from the session
+func (s *InMemorySession) Clear(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = make([]interface{}, 0)
+	return nil
+}
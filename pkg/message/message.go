@@ -0,0 +1,221 @@
+// Package message defines the conversation history the runner sends to models,
+// independent of any provider. Providers translate it to the messages of their
+// API.
+package message
+
+import (
+	"encoding/json"
+)
+
+// Roles of the authors of messages
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleTool      = "tool"
+)
+
+// Item is an item of a conversation history: a Message or a ToolResult
+type Item interface {
+	isItem()
+}
+
+// ToolCall is a call of a tool requested by a model
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments map[string]interface{}
+}
+
+// ArgumentsJSON returns the arguments of the call encoded as JSON, "{}" if they
+// cannot be encoded
+func (c ToolCall) ArgumentsJSON() string {
+	if c.Arguments == nil {
+		return "{}"
+	}
+	data, err := json.Marshal(c.Arguments)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
+
+// Message is a chat message of a conversation history
+type Message struct {
+	// Role is the role of the author, such as RoleUser or RoleAssistant
+	Role string
+
+	// Content is a string or a []model.ContentPart
+	Content interface{}
+
+	// Name is the optional name of the author
+	Name string
+
+	// ToolCalls are the tool calls of an assistant message
+	ToolCalls []ToolCall
+}
+
+func (Message) isItem() {}
+
+// Text returns the content of a text message, or "" if the content is not a string
+func (m Message) Text() string {
+	text, _ := m.Content.(string)
+	return text
+}
+
+// Map returns the message as a generic input item with "type":"message" and
+// OpenAI style tool calls
+func (m Message) Map() map[string]interface{} {
+	item := map[string]interface{}{
+		"type":    "message",
+		"role":    m.Role,
+		"content": m.Content,
+	}
+	if m.Name != "" {
+		item["name"] = m.Name
+	}
+	if len(m.ToolCalls) == 0 {
+		return item
+	}
+
+	toolCalls := make([]map[string]interface{}, len(m.ToolCalls))
+	for i, tc := range m.ToolCalls {
+		toolCalls[i] = map[string]interface{}{
+			"id":   tc.ID,
+			"type": "function",
+			"function": map[string]interface{}{
+				"name":      tc.Name,
+				"arguments": tc.ArgumentsJSON(),
+			},
+		}
+	}
+	item["tool_calls"] = toolCalls
+	return item
+}
+
+// MarshalJSON encodes the message in the form of Map, so that stored histories
+// keep their format
+func (m Message) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Map())
+}
+
+// ToolResult is the result of a tool call
+type ToolResult struct {
+	// Call is the call the result answers
+	Call ToolCall
+
+	// Content is the result of the tool
+	Content interface{}
+}
+
+func (ToolResult) isItem() {}
+
+// Map returns the result as a generic input item with "type":"tool_result"
+func (r ToolResult) Map() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "tool_result",
+		"tool_call": map[string]interface{}{
+			"name":       r.Call.Name,
+			"id":         r.Call.ID,
+			"parameters": r.Call.Arguments,
+		},
+		"tool_result": map[string]interface{}{
+			"content": r.Content,
+		},
+	}
+}
+
+// MarshalJSON encodes the result in the form of Map, so that stored histories
+// keep their format
+func (r ToolResult) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Map())
+}
+
+// Parse converts a history item to a Message or a ToolResult. It accepts the
+// typed items, pointers to them and generic maps such as those decoded from
+// JSON, including OpenAI style messages with role "tool". ok is false for items
+// it does not recognize.
+func Parse(item interface{}) (Item, bool) {
+	switch v := item.(type) {
+	case Message:
+		return v, true
+	case *Message:
+		if v != nil {
+			return *v, true
+		}
+	case ToolResult:
+		return v, true
+	case *ToolResult:
+		if v != nil {
+			return *v, true
+		}
+	case map[string]interface{}:
+		return parseMap(v)
+	}
+	return nil, false
+}
+
+// parseMap converts a generic input item to a Message or a ToolResult
+func parseMap(m map[string]interface{}) (Item, bool) {
+	role, _ := m["role"].(string)
+
+	if m["type"] == "tool_result" || (m["tool_call"] != nil && m["tool_result"] != nil) {
+		result := ToolResult{}
+		if call, ok := m["tool_call"].(map[string]interface{}); ok {
+			result.Call.ID, _ = call["id"].(string)
+			result.Call.Name, _ = call["name"].(string)
+			result.Call.Arguments, _ = call["parameters"].(map[string]interface{})
+		}
+		if content, ok := m["tool_result"].(map[string]interface{}); ok {
+			result.Content = content["content"]
+		}
+		return result, true
+	}
+
+	if role == RoleTool {
+		result := ToolResult{Content: m["content"]}
+		result.Call.ID, _ = m["tool_call_id"].(string)
+		result.Call.Name, _ = m["name"].(string)
+		return result, true
+	}
+
+	if m["type"] != nil && m["type"] != "message" {
+		return nil, false
+	}
+	if role == "" && m["type"] == nil {
+		return nil, false
+	}
+
+	msg := Message{Role: role, Content: m["content"]}
+	msg.Name, _ = m["name"].(string)
+	switch calls := m["tool_calls"].(type) {
+	case []map[string]interface{}:
+		for _, call := range calls {
+			msg.ToolCalls = append(msg.ToolCalls, parseToolCall(call))
+		}
+	case []interface{}:
+		for _, raw := range calls {
+			if call, ok := raw.(map[string]interface{}); ok {
+				msg.ToolCalls = append(msg.ToolCalls, parseToolCall(call))
+			}
+		}
+	}
+	return msg, true
+}
+
+// parseToolCall converts an OpenAI style tool call to a ToolCall
+func parseToolCall(m map[string]interface{}) ToolCall {
+	call := ToolCall{}
+	call.ID, _ = m["id"].(string)
+	function, _ := m["function"].(map[string]interface{})
+	call.Name, _ = function["name"].(string)
+	switch args := function["arguments"].(type) {
+	case string:
+		if err := json.Unmarshal([]byte(args), &call.Arguments); err != nil {
+			call.Arguments = nil
+		}
+	case map[string]interface{}:
+		call.Arguments = args
+	}
+	return call
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+// TranscriptionRequest describes audio to transcribe
+type TranscriptionRequest struct {
+	// Audio is the audio data
+	Audio []byte
+
+	// Filename is the name of the audio file; its extension tells the provider the format
+	Filename string
+
+	// Model is the transcription model; providers use their default when empty
+	Model string
+
+	// Language is the ISO-639-1 language of the audio, detected when empty
+	Language string
+
+	// Prompt guides the transcription, for example with the spelling of names
+	Prompt string
+}
+
+// Transcription is the text of transcribed audio
+type Transcription struct {
+	Text     string        `json:"text"`
+	Language string        `json:"language,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+}
+
+// SpeechRequest describes text to synthesize
+type SpeechRequest struct {
+	// Text is the text to speak
+	Text string
+
+	// Model is the speech model; providers use their default when empty
+	Model string
+
+	// Voice is the provider's voice name
+	Voice string
+
+	// Format is the audio format, such as mp3 or wav
+	Format string
+
+	// Speed is the speaking speed, 1 when zero
+	Speed float64
+
+	// Instructions describe the tone and style of the voice, for models that support it
+	Instructions string
+}
+
+// Speech is synthesized audio
+type Speech struct {
+	Audio     []byte
+	MediaType string
+}
+
+// AudioProvider is implemented by providers that can transcribe and synthesize speech
+type AudioProvider interface {
+	// Transcribe converts speech to text
+	Transcribe(ctx context.Context, request *TranscriptionRequest) (*Transcription, error)
+
+	// Synthesize converts text to speech
+	Synthesize(ctx context.Context, request *SpeechRequest) (*Speech, error)
+}
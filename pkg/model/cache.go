@@ -0,0 +1,509 @@
+package model
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+)
+
+// ResponseCache stores model responses by request key. Implementations must be
+// safe for concurrent use.
+type ResponseCache interface {
+	// Get returns the response stored under key, and false if there is none or it
+	// has expired
+	Get(ctx context.Context, key string) (*Response, bool, error)
+
+	// Set stores a response under key. A ttl of zero keeps it until it is deleted.
+	Set(ctx context.Context, key string, response *Response, ttl time.Duration) error
+
+	// Delete removes the response stored under key
+	Delete(ctx context.Context, key string) error
+
+	// Clear removes all responses
+	Clear(ctx context.Context) error
+}
+
+// CacheStats counts the lookups of a CachingModel
+type CacheStats struct {
+	Hits   int
+	Misses int
+
+	// Skipped counts requests that were not cacheable, such as requests with a
+	// temperature above zero
+	Skipped int
+}
+
+// CachingModel wraps a model and serves repeated requests from a cache, so that
+// test runs and batch jobs at temperature 0 do not call the API again for a
+// request it has already answered:
+//
+//	cache := model.NewMemoryResponseCache()
+//	m := model.NewCachingModel(provider.GetModel("gpt-4o"), cache).WithNamespace("gpt-4o")
+//
+// Only requests with a temperature of exactly zero are cached unless
+// WithAnyTemperature is set. Cached responses report no usage, as they cost nothing.
+type CachingModel struct {
+	model     Model
+	cache     ResponseCache
+	namespace string
+	ttl       time.Duration
+	anyTemp   bool
+
+	stats CacheStats
+	mu    sync.Mutex
+}
+
+// Ensure CachingModel implements Model
+var _ Model = (*CachingModel)(nil)
+
+// NewCachingModel creates a model that caches the responses of m
+func NewCachingModel(m Model, cache ResponseCache) *CachingModel {
+	return &CachingModel{model: m, cache: cache}
+}
+
+// WithNamespace sets a prefix of the cache keys, such as the model name, so that
+// several models can share a cache
+func (m *CachingModel) WithNamespace(namespace string) *CachingModel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.namespace = namespace
+	return m
+}
+
+// WithTTL sets how long responses stay cached. Zero, the default, keeps them
+// until they are invalidated.
+func (m *CachingModel) WithTTL(ttl time.Duration) *CachingModel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ttl = ttl
+	return m
+}
+
+// WithAnyTemperature caches requests regardless of their temperature, for tests
+// against models whose answers are fixed anyway
+func (m *CachingModel) WithAnyTemperature() *CachingModel {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.anyTemp = true
+	return m
+}
+
+// Stats returns the number of cache hits, misses and skipped requests
+func (m *CachingModel) Stats() CacheStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
+
+// Invalidate removes the cached response to a request
+func (m *CachingModel) Invalidate(ctx context.Context, request *Request) error {
+	key, err := m.Key(request)
+	if err != nil {
+		return err
+	}
+	return m.cache.Delete(ctx, key)
+}
+
+// Clear removes all cached responses
+func (m *CachingModel) Clear(ctx context.Context) error {
+	return m.cache.Clear(ctx)
+}
+
+// cacheBypassKey is the context key of WithCacheRefresh
+type cacheBypassKey struct{}
+
+// WithCacheRefresh returns a context in which caching models call the wrapped
+// model instead of reading the cache, and store the fresh responses
+func WithCacheRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// cacheRefresh reports whether the context asks for fresh responses
+func cacheRefresh(ctx context.Context) bool {
+	refresh, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return refresh
+}
+
+// Key returns the cache key of a request: a hash of its namespace, instructions,
+// input, tools, handoffs, output schema and settings
+func (m *CachingModel) Key(request *Request) (string, error) {
+	m.mu.Lock()
+	namespace := m.namespace
+	m.mu.Unlock()
+
+	// Maps are encoded with sorted keys, so equal requests encode the same
+	normalized := struct {
+		Namespace    string      `json:"namespace"`
+		System       string      `json:"system"`
+		Input        interface{} `json:"input"`
+		Tools        interface{} `json:"tools,omitempty"`
+		OutputSchema interface{} `json:"output_schema,omitempty"`
+		Handoffs     interface{} `json:"handoffs,omitempty"`
+		Settings     *Settings   `json:"settings,omitempty"`
+	}{
+		Namespace:    namespace,
+		System:       strings.TrimSpace(request.SystemInstructions),
+		Input:        request.Input,
+		Tools:        request.Tools,
+		OutputSchema: request.OutputSchema,
+		Handoffs:     request.Handoffs,
+		Settings:     request.Settings,
+	}
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request for cache key: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// lookup returns the cache key of a request and its cached response, if any. An
+// empty key means the request is not cacheable.
+func (m *CachingModel) lookup(ctx context.Context, request *Request) (string, *Response) {
+	if !m.cacheable(request) {
+		m.count(func(s *CacheStats) { s.Skipped++ })
+		return "", nil
+	}
+	key, err := m.Key(request)
+	if err != nil {
+		logging.For(nil, "model").Debug("Not caching request", "error", err)
+		m.count(func(s *CacheStats) { s.Skipped++ })
+		return "", nil
+	}
+	if cacheRefresh(ctx) {
+		m.count(func(s *CacheStats) { s.Misses++ })
+		return key, nil
+	}
+
+	cached, ok, err := m.cache.Get(ctx, key)
+	if err != nil {
+		logging.For(nil, "model").Warn("Response cache lookup failed", "error", err)
+	}
+	if err != nil || !ok {
+		m.count(func(s *CacheStats) { s.Misses++ })
+		return key, nil
+	}
+	m.count(func(s *CacheStats) { s.Hits++ })
+
+	response := cloneResponse(cached)
+	response.Usage = nil
+	return key, response
+}
+
+// store caches the response to a request. Failures are ignored, as the response
+// is still valid.
+func (m *CachingModel) store(ctx context.Context, key string, response *Response) {
+	m.mu.Lock()
+	ttl := m.ttl
+	m.mu.Unlock()
+	if err := m.cache.Set(ctx, key, cloneResponse(response), ttl); err != nil {
+		logging.For(nil, "model").Warn("Failed to cache response", "error", err)
+	}
+}
+
+// cacheable reports whether a request's response may be cached
+func (m *CachingModel) cacheable(request *Request) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.anyTemp {
+		return true
+	}
+	return request.Settings != nil && request.Settings.Temperature != nil && *request.Settings.Temperature == 0
+}
+
+// count updates the lookup counters
+func (m *CachingModel) count(update func(*CacheStats)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	update(&m.stats)
+}
+
+// GetResponse returns the cached response to the request, or the response of the
+// wrapped model, which is then cached
+func (m *CachingModel) GetResponse(ctx context.Context, request *Request) (*Response, error) {
+	key, cached := m.lookup(ctx, request)
+	if cached != nil {
+		return cached, nil
+	}
+
+	response, err := m.model.GetResponse(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if key != "" {
+		m.store(ctx, key, response)
+	}
+	return response, nil
+}
+
+// StreamResponse replays a cached response as a stream, starting with a cache hit
+// event, or streams the response of the wrapped model and caches it once done
+func (m *CachingModel) StreamResponse(ctx context.Context, request *Request) (<-chan StreamEvent, error) {
+	key, cached := m.lookup(ctx, request)
+	if cached != nil {
+		return replayResponse(cached), nil
+	}
+
+	events, err := m.model.StreamResponse(ctx, request)
+	if err != nil || key == "" {
+		return events, err
+	}
+
+	forwarded := make(chan StreamEvent)
+	go func() {
+		defer close(forwarded)
+		failed := false
+		for event := range events {
+			if event.Error != nil || event.Type == StreamEventTypeError {
+				failed = true
+			}
+			if event.Type == StreamEventTypeDone && event.Response != nil && !failed {
+				m.store(ctx, key, event.Response)
+			}
+			forwarded <- event
+		}
+	}()
+	return forwarded, nil
+}
+
+// replayResponse streams a cached response
+func replayResponse(response *Response) <-chan StreamEvent {
+	replayed := ResponseEvents(response)
+	events := make(chan StreamEvent, len(replayed)+1)
+	events <- StreamEvent{Type: StreamEventTypeCacheHit}
+	for _, event := range replayed {
+		events <- event
+	}
+	close(events)
+	return events
+}
+
+// ResponseEvents returns the stream events that deliver a complete response: its
+// content, media, tool calls and handoff, followed by a done event
+func ResponseEvents(response *Response) []StreamEvent {
+	events := make([]StreamEvent, 0, 3+len(response.ToolCalls)+len(response.Media))
+	if response.Content != "" {
+		events = append(events, StreamEvent{Type: StreamEventTypeContent, Content: response.Content})
+	}
+	for i := range response.Media {
+		events = append(events, StreamEvent{Type: StreamEventTypeMedia, Media: &response.Media[i]})
+	}
+	for i := range response.ToolCalls {
+		events = append(events, StreamEvent{Type: StreamEventTypeToolCall, ToolCall: &response.ToolCalls[i]})
+	}
+	if response.HandoffCall != nil {
+		events = append(events, StreamEvent{Type: StreamEventTypeHandoff, HandoffCall: response.HandoffCall})
+	}
+	return append(events, StreamEvent{Type: StreamEventTypeDone, Response: response, Done: true})
+}
+
+// cloneResponse copies a response, so that callers cannot change cached responses
+func cloneResponse(response *Response) *Response {
+	clone := *response
+	if response.ToolCalls != nil {
+		clone.ToolCalls = make([]ToolCall, len(response.ToolCalls))
+		for i, call := range response.ToolCalls {
+			clone.ToolCalls[i] = ToolCall{ID: call.ID, Name: call.Name, Parameters: copyMap(call.Parameters)}
+			clone.ToolCalls[i].RawParameter.WriteString(call.RawParameter.String())
+		}
+	}
+	if response.HandoffCall != nil {
+		handoff := *response.HandoffCall
+		handoff.Parameters = copyMap(response.HandoffCall.Parameters)
+		clone.HandoffCall = &handoff
+	}
+	if response.Usage != nil {
+		usage := *response.Usage
+		clone.Usage = &usage
+	}
+	clone.Media = append([]MediaPart(nil), response.Media...)
+	return &clone
+}
+
+// copyMap makes a shallow copy of a map
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	copied := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
+}
+
+// cachedResponse is a cache entry with its expiry
+type cachedResponse struct {
+	Response  *Response `json:"response"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// expired reports whether the entry has expired
+func (c cachedResponse) expired() bool {
+	return !c.ExpiresAt.IsZero() && !time.Now().Before(c.ExpiresAt)
+}
+
+// newCachedResponse creates a cache entry expiring after ttl
+func newCachedResponse(response *Response, ttl time.Duration) cachedResponse {
+	entry := cachedResponse{Response: response}
+	if ttl > 0 {
+		entry.ExpiresAt = time.Now().Add(ttl)
+	}
+	return entry
+}
+
+// MemoryResponseCache is a ResponseCache kept in process memory
+type MemoryResponseCache struct {
+	entries map[string]cachedResponse
+	mu      sync.Mutex
+}
+
+// Ensure MemoryResponseCache implements ResponseCache
+var _ ResponseCache = (*MemoryResponseCache)(nil)
+
+// NewMemoryResponseCache creates an empty in-memory response cache
+func NewMemoryResponseCache() *MemoryResponseCache {
+	return &MemoryResponseCache{entries: make(map[string]cachedResponse)}
+}
+
+// Get returns the response stored under key
+func (c *MemoryResponseCache) Get(ctx context.Context, key string) (*Response, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if entry.expired() {
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+	return entry.Response, true, nil
+}
+
+// Set stores a response under key
+func (c *MemoryResponseCache) Set(ctx context.Context, key string, response *Response, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = newCachedResponse(response, ttl)
+	return nil
+}
+
+// Delete removes the response stored under key
+func (c *MemoryResponseCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+	return nil
+}
+
+// Clear removes all responses
+func (c *MemoryResponseCache) Clear(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]cachedResponse)
+	return nil
+}
+
+// FileResponseCache is a ResponseCache that keeps each response in a JSON file of
+// a directory, so that cached responses survive between test runs
+type FileResponseCache struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// Ensure FileResponseCache implements ResponseCache
+var _ ResponseCache = (*FileResponseCache)(nil)
+
+// NewFileResponseCache creates a response cache in dir, creating the directory
+// if needed
+func NewFileResponseCache(dir string) (*FileResponseCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create response cache directory: %w", err)
+	}
+	return &FileResponseCache{dir: dir}, nil
+}
+
+// path returns the file of a key
+func (c *FileResponseCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// Get returns the response stored under key
+func (c *FileResponseCache) Get(ctx context.Context, key string) (*Response, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, err := os.ReadFile(c.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read cached response: %w", err)
+	}
+
+	var entry cachedResponse
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false, fmt.Errorf("failed to decode cached response: %w", err)
+	}
+	if entry.expired() || entry.Response == nil {
+		_ = os.Remove(c.path(key))
+		return nil, false, nil
+	}
+	return entry.Response, true, nil
+}
+
+// Set stores a response under key
+func (c *FileResponseCache) Set(ctx context.Context, key string, response *Response, ttl time.Duration) error {
+	data, err := json.MarshalIndent(newCachedResponse(response, ttl), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode response for cache: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Write to a temporary file first, so that readers never see a partial entry
+	tmp := c.path(key) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write cached response: %w", err)
+	}
+	if err := os.Rename(tmp, c.path(key)); err != nil {
+		return fmt.Errorf("failed to write cached response: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the response stored under key
+func (c *FileResponseCache) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.Remove(c.path(key)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete cached response: %w", err)
+	}
+	return nil
+}
+
+// Clear removes all responses
+func (c *FileResponseCache) Clear(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	files, err := filepath.Glob(filepath.Join(c.dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list cached responses: %w", err)
+	}
+	for _, file := range files {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to delete cached response: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultModelListTTL is how long a ModelValidator trusts a fetched model list
+const DefaultModelListTTL = 10 * time.Minute
+
+// ModelInfo describes a model offered by a provider
+type ModelInfo struct {
+	// ID is the name used to request the model
+	ID string
+
+	// DisplayName is a human readable name, if the provider has one
+	DisplayName string
+
+	// OwnedBy is the organization that owns the model, if the provider reports it
+	OwnedBy string
+
+	// CreatedAt is when the model was released, if the provider reports it
+	CreatedAt time.Time
+}
+
+// ModelLister is implemented by providers that can list their models
+type ModelLister interface {
+	// ListModels returns the models available to the caller
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+}
+
+// UnknownModelError is returned when a model name is not offered by a provider
+type UnknownModelError struct {
+	// Name is the requested model name
+	Name string
+
+	// Suggestion is the closest available model name, if any is close
+	Suggestion string
+}
+
+// Error implements the error interface
+func (e *UnknownModelError) Error() string {
+	if e.Suggestion != "" {
+		return fmt.Sprintf("unknown model %q, did you mean %q?", e.Name, e.Suggestion)
+	}
+	return fmt.Sprintf("unknown model %q", e.Name)
+}
+
+// ModelValidator checks model names against a provider's model list. The list is
+// fetched on first use and cached for the TTL.
+type ModelValidator struct {
+	lister    ModelLister
+	ttl       time.Duration
+	ids       []string
+	fetchedAt time.Time
+	mu        sync.Mutex
+}
+
+// NewModelValidator creates a validator for the models of a provider
+func NewModelValidator(lister ModelLister) *ModelValidator {
+	return &ModelValidator{
+		lister: lister,
+		ttl:    DefaultModelListTTL,
+	}
+}
+
+// WithTTL sets how long a fetched model list is cached
+func (v *ModelValidator) WithTTL(ttl time.Duration) *ModelValidator {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.ttl = ttl
+	return v
+}
+
+// Models returns the IDs of the provider's models, sorted, fetching them if the
+// cached list is missing or expired
+func (v *ModelValidator) Models(ctx context.Context) ([]string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.ids == nil || time.Since(v.fetchedAt) > v.ttl {
+		models, err := v.lister.ListModels(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list models: %w", err)
+		}
+		v.ids = make([]string, 0, len(models))
+		for _, m := range models {
+			v.ids = append(v.ids, m.ID)
+		}
+		sort.Strings(v.ids)
+		v.fetchedAt = time.Now()
+	}
+	return v.ids, nil
+}
+
+// Validate returns an *UnknownModelError if the provider doesn't offer the model
+func (v *ModelValidator) Validate(ctx context.Context, name string) error {
+	ids, err := v.Models(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if id == name {
+			return nil
+		}
+	}
+	return &UnknownModelError{Name: name, Suggestion: closestName(name, ids)}
+}
+
+// closestName returns the candidate with the smallest edit distance to name, if it
+// is close enough to be a likely typo
+func closestName(name string, candidates []string) string {
+	best := ""
+	bestDistance := 0
+	for _, candidate := range candidates {
+		d := editDistance(name, candidate)
+		if best == "" || d < bestDistance {
+			best = candidate
+			bestDistance = d
+		}
+	}
+
+	limit := len(name) / 4
+	if limit < 2 {
+		limit = 2
+	}
+	if best == "" || bestDistance > limit {
+		return ""
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,33 @@
+package model
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"", "gpt-4o", 6},
+		{"gpt-4o", "", 6},
+		{"gpt-4o", "gpt-4o", 0},
+		{"gpt-4o-minii", "gpt-4o-mini", 1},
+		{"gpt-4o", "gpt-o4", 2},
+		{"kitten", "sitting", 3},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.distance {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.distance)
+		}
+	}
+}
+
+func TestClosestName(t *testing.T) {
+	if got := closestName("gpt-4o", nil); got != "" {
+		t.Errorf("closestName with no candidates = %q, want none", got)
+	}
+	if got := closestName("gpt-4o", []string{"gpt-4o"}); got != "gpt-4o" {
+		t.Errorf("closestName of an exact match = %q, want gpt-4o", got)
+	}
+}
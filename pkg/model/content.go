@@ -0,0 +1,199 @@
+package model
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// Content part types
+const (
+	ContentTypeText     = "text"
+	ContentTypeImageURL = "image_url"
+	ContentTypeImage    = "image"
+	ContentTypeFile     = "file"
+	ContentTypeAudio    = "audio"
+)
+
+// audioMediaTypes maps audio formats to their MIME types
+var audioMediaTypes = map[string]string{
+	"wav":  "audio/wav",
+	"mp3":  "audio/mpeg",
+	"flac": "audio/flac",
+	"ogg":  "audio/ogg",
+	"webm": "audio/webm",
+	"m4a":  "audio/mp4",
+}
+
+// ContentPart is one part of a multimodal message. A request input may be a
+// []ContentPart, which is sent as a single user message, or a list of message
+// maps whose "content" is a []ContentPart.
+type ContentPart struct {
+	// Type is one of the ContentType constants
+	Type string `json:"type"`
+
+	// Text is the text of a text part
+	Text string `json:"text,omitempty"`
+
+	// URL is the address of an image_url part
+	URL string `json:"url,omitempty"`
+
+	// Data is the raw content of an image or file part
+	Data []byte `json:"data,omitempty"`
+
+	// MediaType is the MIME type of Data, such as image/png or application/pdf
+	MediaType string `json:"media_type,omitempty"`
+
+	// Detail is the image detail level for providers that support it (low, high or auto)
+	Detail string `json:"detail,omitempty"`
+
+	// Filename is the name of a file part
+	Filename string `json:"filename,omitempty"`
+}
+
+// TextPart creates a text content part
+func TextPart(text string) ContentPart {
+	return ContentPart{Type: ContentTypeText, Text: text}
+}
+
+// ImageURLPart creates an image part referencing a URL
+func ImageURLPart(url string) ContentPart {
+	return ContentPart{Type: ContentTypeImageURL, URL: url}
+}
+
+// ImagePart creates an image part from raw image data
+func ImagePart(data []byte, mediaType string) ContentPart {
+	return ContentPart{Type: ContentTypeImage, Data: data, MediaType: mediaType}
+}
+
+// FilePart creates a file part, such as a PDF document
+func FilePart(filename string, data []byte, mediaType string) ContentPart {
+	return ContentPart{Type: ContentTypeFile, Filename: filename, Data: data, MediaType: mediaType}
+}
+
+// AudioPart creates an audio part from raw audio data in the given format, such
+// as wav or mp3
+func AudioPart(data []byte, format string) ContentPart {
+	format = strings.ToLower(strings.TrimPrefix(format, "."))
+	mediaType, ok := audioMediaTypes[format]
+	if !ok {
+		mediaType = "audio/" + format
+	}
+	return ContentPart{Type: ContentTypeAudio, Data: data, MediaType: mediaType}
+}
+
+// AudioFormat returns the format of an audio part, such as wav or mp3, derived
+// from its media type
+func (p ContentPart) AudioFormat() string {
+	for format, mediaType := range audioMediaTypes {
+		if mediaType == p.MediaType {
+			return format
+		}
+	}
+	switch p.MediaType {
+	case "audio/mp3":
+		return "mp3"
+	case "audio/x-wav", "audio/wave":
+		return "wav"
+	}
+	return strings.TrimPrefix(p.MediaType, "audio/")
+}
+
+// WithDetail returns a copy of the part with the given image detail level
+func (p ContentPart) WithDetail(detail string) ContentPart {
+	p.Detail = detail
+	return p
+}
+
+// Base64 returns Data encoded as standard base64
+func (p ContentPart) Base64() string {
+	return base64.StdEncoding.EncodeToString(p.Data)
+}
+
+// DataURL returns Data as a data: URL
+func (p ContentPart) DataURL() string {
+	return fmt.Sprintf("data:%s;base64,%s", p.MediaType, p.Base64())
+}
+
+// UserMessage creates a user message map with multimodal content, for use in
+// a list input
+func UserMessage(parts ...ContentPart) map[string]interface{} {
+	return map[string]interface{}{
+		"type":    "message",
+		"role":    "user",
+		"content": parts,
+	}
+}
+
+// ContentParts returns the parts of a message content. It accepts a
+// []ContentPart, or a []interface{} of ContentPart values or maps such as those
+// decoded from JSON. ok is false if the content is not a list of parts.
+func ContentParts(content interface{}) ([]ContentPart, bool) {
+	switch v := content.(type) {
+	case []ContentPart:
+		return v, true
+	case []interface{}:
+		parts := make([]ContentPart, 0, len(v))
+		for _, item := range v {
+			part, ok := contentPart(item)
+			if !ok {
+				return nil, false
+			}
+			parts = append(parts, part)
+		}
+		return parts, true
+	default:
+		return nil, false
+	}
+}
+
+// ContentText returns the text parts of a content joined by newlines
+func ContentText(parts []ContentPart) string {
+	var texts []string
+	for _, part := range parts {
+		if part.Type == ContentTypeText && part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// contentPart converts a single item of a content list to a ContentPart
+func contentPart(item interface{}) (ContentPart, bool) {
+	switch v := item.(type) {
+	case ContentPart:
+		return v, true
+	case *ContentPart:
+		if v == nil {
+			return ContentPart{}, false
+		}
+		return *v, true
+	case map[string]interface{}:
+		partType, _ := v["type"].(string)
+		switch partType {
+		case ContentTypeText, ContentTypeImageURL, ContentTypeImage, ContentTypeFile, ContentTypeAudio:
+		default:
+			return ContentPart{}, false
+		}
+		part := ContentPart{Type: partType}
+		part.Text, _ = v["text"].(string)
+		part.URL, _ = v["url"].(string)
+		part.MediaType, _ = v["media_type"].(string)
+		part.Detail, _ = v["detail"].(string)
+		part.Filename, _ = v["filename"].(string)
+		switch data := v["data"].(type) {
+		case []byte:
+			part.Data = data
+		case string:
+			// JSON encodes []byte as base64
+			decoded, err := base64.StdEncoding.DecodeString(data)
+			if err != nil {
+				return ContentPart{}, false
+			}
+			part.Data = decoded
+		}
+		return part, true
+	default:
+		return ContentPart{}, false
+	}
+}
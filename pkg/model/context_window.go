@@ -0,0 +1,202 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// charsPerToken is the average number of characters per token used for estimates
+const charsPerToken = 4
+
+// messageOverheadTokens is the estimated number of tokens each message adds for
+// its role and formatting
+const messageOverheadTokens = 4
+
+// attachmentTokens is the estimated number of tokens of an image or file part
+const attachmentTokens = 1000
+
+// ErrContextWindowExceeded is returned when a request is estimated not to fit the
+// model's context window
+var ErrContextWindowExceeded = errors.New("context window exceeded")
+
+// ContextWindowError reports a request that is estimated not to fit the context window
+type ContextWindowError struct {
+	// Model is the name of the model
+	Model string
+
+	// EstimatedTokens is the estimated size of the request, including the tokens
+	// reserved for the response
+	EstimatedTokens int
+
+	// ContextWindow is the model's context window in tokens
+	ContextWindow int
+}
+
+// Error implements the error interface
+func (e *ContextWindowError) Error() string {
+	return fmt.Sprintf("request for %s needs about %d tokens but the context window is %d tokens", e.Model, e.EstimatedTokens, e.ContextWindow)
+}
+
+// Unwrap returns ErrContextWindowExceeded
+func (e *ContextWindowError) Unwrap() error {
+	return ErrContextWindowExceeded
+}
+
+var (
+	// contextWindows maps model name prefixes to context windows in tokens
+	contextWindows = map[string]int{
+		"gpt-4.1":         1047576,
+		"gpt-4o":          128000,
+		"gpt-4-turbo":     128000,
+		"gpt-4":           8192,
+		"gpt-3.5-turbo":   16385,
+		"o1":              200000,
+		"o3":              200000,
+		"o4-mini":         200000,
+		"claude-3":        200000,
+		"claude-sonnet-4": 200000,
+		"claude-opus-4":   200000,
+	}
+	contextWindowsMu sync.RWMutex
+)
+
+// RegisterContextWindow sets the context window of the models whose names start
+// with the prefix, such as a model served by LM Studio
+func RegisterContextWindow(prefix string, tokens int) {
+	contextWindowsMu.Lock()
+	defer contextWindowsMu.Unlock()
+	contextWindows[prefix] = tokens
+}
+
+// ContextWindow returns the context window of a model in tokens, using the longest
+// registered prefix of its name
+func ContextWindow(modelName string) (int, bool) {
+	contextWindowsMu.RLock()
+	defer contextWindowsMu.RUnlock()
+
+	best, tokens := "", 0
+	for prefix, window := range contextWindows {
+		if strings.HasPrefix(modelName, prefix) && len(prefix) > len(best) {
+			best, tokens = prefix, window
+		}
+	}
+	return tokens, best != ""
+}
+
+// EstimateTokens estimates the number of prompt tokens of a request from the size of
+// its instructions, input, tools and output schema. It is a rough estimate meant to
+// catch requests that are far too large, not an exact count.
+func EstimateTokens(request *Request) int {
+	if request == nil {
+		return 0
+	}
+
+	chars := len(request.SystemInstructions)
+	messages := 1
+	inputChars, attachments := inputSize(request.Input)
+	chars += inputChars
+
+	switch input := request.Input.(type) {
+	case nil:
+	case []interface{}:
+		messages += len(input)
+	default:
+		messages++
+	}
+
+	if len(request.Tools) > 0 {
+		chars += encodedLength(request.Tools)
+	}
+	if len(request.Handoffs) > 0 {
+		chars += encodedLength(request.Handoffs)
+	}
+	if request.OutputSchema != nil {
+		chars += encodedLength(request.OutputSchema)
+	}
+
+	return chars/charsPerToken + messages*messageOverheadTokens + attachments*attachmentTokens
+}
+
+// EstimateMessageTokens estimates the number of tokens of a history item, such as
+// a message or a tool result, in the same way as EstimateTokens
+func EstimateMessageTokens(message interface{}) int {
+	chars, attachments := inputSize(message)
+	return chars/charsPerToken + messageOverheadTokens + attachments*attachmentTokens
+}
+
+// CheckContextWindow returns an *ErrContextLengthExceeded wrapping a
+// *ContextWindowError if the request, plus the tokens reserved for the response by
+// Settings.MaxTokens, is estimated not to fit the model's context window. Models
+// with an unknown context window are not checked.
+func CheckContextWindow(modelName string, request *Request) error {
+	window, ok := ContextWindow(modelName)
+	if !ok {
+		return nil
+	}
+
+	estimated := EstimateTokens(request)
+	if request.Settings != nil && request.Settings.MaxTokens != nil {
+		estimated += *request.Settings.MaxTokens
+	}
+	if estimated > window {
+		return &ErrContextLengthExceeded{
+			Model: modelName,
+			Err:   &ContextWindowError{Model: modelName, EstimatedTokens: estimated, ContextWindow: window},
+		}
+	}
+	return nil
+}
+
+// inputSize returns the number of characters of an input and the number of image
+// and file parts it contains, which are estimated separately from their encoded size
+func inputSize(v interface{}) (int, int) {
+	switch v := v.(type) {
+	case nil:
+		return 0, 0
+	case string:
+		return len(v), 0
+	case []ContentPart:
+		chars, attachments := 0, 0
+		for _, part := range v {
+			if part.Type == ContentTypeText {
+				chars += len(part.Text)
+			} else {
+				attachments++
+			}
+		}
+		return chars, attachments
+	case []interface{}:
+		if parts, ok := ContentParts(v); ok && len(parts) > 0 {
+			return inputSize(parts)
+		}
+		chars, attachments := 0, 0
+		for _, item := range v {
+			itemChars, itemAttachments := inputSize(item)
+			chars += itemChars
+			attachments += itemAttachments
+		}
+		return chars, attachments
+	case map[string]interface{}:
+		chars, attachments := 0, 0
+		for key, value := range v {
+			valueChars, valueAttachments := inputSize(value)
+			chars += len(key) + valueChars
+			attachments += valueAttachments
+		}
+		return chars, attachments
+	default:
+		return encodedLength(v), 0
+	}
+}
+
+// encodedLength returns the length of a value encoded as JSON
+func encodedLength(v interface{}) int {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return len(fmt.Sprint(v))
+	}
+	return len(data)
+}
@@ -0,0 +1,150 @@
+package model
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/ratelimit"
+)
+
+// ErrProvider is an error response of a provider's API. The errors of rate
+// limited requests and of requests too long for the context window wrap it, so
+// errors.As finds it for every failed request.
+type ErrProvider struct {
+	// Provider is the name of the provider, such as "openai"
+	Provider string
+
+	// Status is the HTTP status of the response
+	Status int
+
+	// Code is the error code or type reported by the provider, such as
+	// "invalid_request_error"
+	Code string
+
+	// Message is the error message reported by the provider
+	Message string
+
+	// RequestID is the ID the provider assigned to the request, if it sent one
+	RequestID string
+}
+
+// Error implements the error interface
+func (e *ErrProvider) Error() string {
+	message := e.Message
+	if message == "" {
+		message = fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status))
+	}
+	text := "API error"
+	if e.Code != "" {
+		text += " (" + e.Code + ")"
+	}
+	text += ": " + message
+	if e.RequestID != "" {
+		text += fmt.Sprintf(" (request ID: %s)", e.RequestID)
+	}
+	return text
+}
+
+// ErrRateLimited is returned for requests a provider rejected because of its rate
+// limits, after the provider's own retries
+type ErrRateLimited struct {
+	// RetryAfter is how long the provider asked to wait before retrying, or zero
+	// if it did not say
+	RetryAfter time.Duration
+
+	// Err is the *ratelimit.Error of the response, which wraps the *ErrProvider
+	Err error
+}
+
+// Error implements the error interface
+func (e *ErrRateLimited) Error() string {
+	if e.Err == nil {
+		return "rate limited"
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the response
+func (e *ErrRateLimited) Unwrap() error {
+	return e.Err
+}
+
+// ErrContextLengthExceeded is returned for requests too long for the model's
+// context window, whether the provider rejected them or the context window check
+// estimated they would not fit. errors.Is matches it with ErrContextWindowExceeded.
+type ErrContextLengthExceeded struct {
+	// Model is the name of the model
+	Model string
+
+	// Err is the *ErrProvider of the response, or the *ContextWindowError of the
+	// estimate
+	Err error
+}
+
+// Error implements the error interface
+func (e *ErrContextLengthExceeded) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("request for %s exceeds the context window", e.Model)
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the response or estimate
+func (e *ErrContextLengthExceeded) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is ErrContextWindowExceeded
+func (e *ErrContextLengthExceeded) Is(target error) bool {
+	return target == ErrContextWindowExceeded
+}
+
+// contextLengthCodes are the error codes providers report for requests too long
+// for the context window
+var contextLengthCodes = []string{"context_length_exceeded", "string_above_max_length"}
+
+// contextLengthMessages are parts of the error messages of providers that report
+// requests too long for the context window without a specific code
+var contextLengthMessages = []string{
+	"context length",
+	"context window",
+	"context_length_exceeded",
+	"maximum context",
+	"prompt is too long",
+	"too many tokens",
+}
+
+// ProviderError returns the typed error of a provider's error response: an
+// *ErrRateLimited for 429 Too Many Requests, an *ErrContextLengthExceeded for
+// requests too long for the model's context window, and the *ErrProvider
+// otherwise
+func ProviderError(err *ErrProvider, modelName string, response *http.Response) error {
+	if err.Status == http.StatusTooManyRequests && response != nil {
+		rateErr := ratelimit.NewError(response, err)
+		retryAfter, _ := ratelimit.RetryAfter(rateErr)
+		return &ErrRateLimited{RetryAfter: retryAfter, Err: rateErr}
+	}
+	if isContextLengthError(err) {
+		return &ErrContextLengthExceeded{Model: modelName, Err: err}
+	}
+	return err
+}
+
+// isContextLengthError reports whether an error response is about a request too
+// long for the context window
+func isContextLengthError(err *ErrProvider) bool {
+	for _, code := range contextLengthCodes {
+		if err.Code == code {
+			return true
+		}
+	}
+	message := strings.ToLower(err.Message)
+	for _, part := range contextLengthMessages {
+		if strings.Contains(message, part) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package model
+
+import "context"
+
+// Image response formats
+const (
+	// ImageFormatURL returns a hosted URL for each image
+	ImageFormatURL = "url"
+
+	// ImageFormatBase64 returns the image data inline
+	ImageFormatBase64 = "b64_json"
+)
+
+// ImageRequest describes the images to generate
+type ImageRequest struct {
+	// Prompt describes the image
+	Prompt string
+
+	// Model is the image model; providers use their default when empty
+	Model string
+
+	// N is the number of images to generate, 1 when zero
+	N int
+
+	// Size is the image size, such as 1024x1024
+	Size string
+
+	// Quality is a provider-specific quality level, such as standard or hd
+	Quality string
+
+	// Style is a provider-specific style, such as vivid or natural
+	Style string
+
+	// Format is ImageFormatURL or ImageFormatBase64
+	Format string
+}
+
+// GeneratedImage is a single generated image. Depending on the requested format
+// either URL or Data is set.
+type GeneratedImage struct {
+	URL           string `json:"url,omitempty"`
+	Data          []byte `json:"data,omitempty"`
+	MediaType     string `json:"media_type,omitempty"`
+	RevisedPrompt string `json:"revised_prompt,omitempty"`
+}
+
+// ImageResponse is the result of an image generation request
+type ImageResponse struct {
+	Images []GeneratedImage
+	Usage  *Usage
+}
+
+// ImageGenerator is implemented by providers that can generate images
+type ImageGenerator interface {
+	// GenerateImage generates images for the request
+	GenerateImage(ctx context.Context, request *ImageRequest) (*ImageResponse, error)
+}
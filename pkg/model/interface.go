@@ -3,6 +3,9 @@ package model
 import (
 	"context"
 	"strings"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/ratelimit"
 )
 
 // Request represents a request to a model
@@ -21,6 +24,36 @@ type Response struct {
 	ToolCalls   []ToolCall
 	HandoffCall *HandoffCall
 	Usage       *Usage
+
+	// RequestID is the provider's identifier for the request, for correlating a
+	// turn with the provider's logs and support tickets
+	RequestID string
+
+	// StopReason is the provider's reason for ending the response, such as
+	// "end_turn", "tool_use" or "max_tokens", when the provider reports one
+	StopReason string
+
+	// Media holds images and other non-text output of the model
+	Media []MediaPart
+}
+
+// MediaPart is a non-text output of a model, such as a generated image. The runner
+// moves Data into the artifact store when one is configured and sets ArtifactID.
+type MediaPart struct {
+	// Type is ContentTypeImage, ContentTypeFile or another content type
+	Type string `json:"type"`
+
+	// MediaType is the MIME type of the payload
+	MediaType string `json:"media_type,omitempty"`
+
+	// Data is the payload, if the model returned it inline
+	Data []byte `json:"data,omitempty"`
+
+	// URL is the address of the payload, if the model returned a link
+	URL string `json:"url,omitempty"`
+
+	// ArtifactID is the ID of the payload in the artifact store
+	ArtifactID string `json:"artifact_id,omitempty"`
 }
 
 // ToolCall represents a tool call from a model
@@ -41,11 +74,38 @@ type HandoffCall struct {
 	IsTaskComplete bool           `json:"is_task_complete,omitempty"` // Whether the task is complete
 }
 
+// handoffFields are the arguments of a handoff tool call that configure the
+// handoff rather than form its input
+var handoffFields = map[string]bool{"task_id": true, "return_to_agent": true, "is_task_complete": true}
+
+// HandoffInput returns the input of a handoff from the arguments of the handoff
+// tool call: the "input" argument when it is a string, or else the other
+// arguments, for targets that take typed input
+func HandoffInput(args map[string]interface{}) interface{} {
+	if input, ok := args["input"].(string); ok {
+		return input
+	}
+	input := make(map[string]interface{}, len(args))
+	for key, value := range args {
+		if !handoffFields[key] {
+			input[key] = value
+		}
+	}
+	return input
+}
+
 // Usage represents token usage information
 type Usage struct {
 	PromptTokens     int
 	CompletionTokens int
 	TotalTokens      int
+
+	// CachedTokens is the part of PromptTokens read from the provider's prompt cache
+	CachedTokens int
+
+	// CacheCreationTokens is the part of PromptTokens written to the provider's
+	// prompt cache
+	CacheCreationTokens int
 }
 
 // StreamEvent represents an event in a streaming response
@@ -57,15 +117,113 @@ type StreamEvent struct {
 	Done        bool
 	Error       error
 	Response    *Response
+
+	// RateLimit is the limiter state for throttled events
+	RateLimit *ratelimit.Status
+
+	// Media is the payload of media events
+	Media *MediaPart
+
+	// Artifact is the new version of artifact events
+	Artifact *ArtifactUpdate
+
+	// Sequence numbers the events of a streamed run in the order they were sent,
+	// starting at 1
+	Sequence int64
+
+	// RunID, Agent and Turn are the run, agent and turn the event of a streamed
+	// run belongs to
+	RunID string
+	Agent string
+	Turn  int
+
+	// FromAgent is the agent the run switched away from, for agent_switched events
+	FromAgent string
+
+	// Usage is the token usage of a model response, for usage events
+	Usage *Usage
+
+	// Output is the result of a tool call for tool_call_finished events, and the
+	// final output of the run for run_completed events
+	Output interface{}
+}
+
+// ArtifactUpdate is a new version of the artifact of a delegated task
+type ArtifactUpdate struct {
+	TaskID       string
+	Version      int
+	ArtifactType string
+	Author       string
+	Timestamp    time.Time
+	Hash         string
+	Artifact     interface{}
 }
 
 // StreamEvent types
 const (
-	StreamEventTypeContent  = "content"
-	StreamEventTypeToolCall = "tool_call"
-	StreamEventTypeHandoff  = "handoff"
-	StreamEventTypeDone     = "done"
-	StreamEventTypeError    = "error"
+	StreamEventTypeContent          = "content"
+	StreamEventTypeToolCall         = "tool_call"
+	StreamEventTypeHandoff          = "handoff"
+	StreamEventTypeDone             = "done"
+	StreamEventTypeError            = "error"
+	StreamEventTypeGuardrailTripped = "guardrail_tripped"
+
+	// StreamEventTypeThrottled is sent while a request waits for rate limit capacity
+	StreamEventTypeThrottled = "throttled"
+
+	// StreamEventTypeMedia is sent for each image or other media part of a response
+	StreamEventTypeMedia = "media"
+
+	// StreamEventTypeApprovalRequired is sent when the run pauses for a tool call or
+	// handoff to be approved. The event's Error holds the state to resume from.
+	StreamEventTypeApprovalRequired = "approval_required"
+
+	// StreamEventTypeAudio is sent for each chunk of audio output in a realtime
+	// session. The event's Media holds the chunk.
+	StreamEventTypeAudio = "audio"
+
+	// StreamEventTypeTranscript is sent with the transcript of spoken user input in
+	// a realtime session
+	StreamEventTypeTranscript = "transcript"
+
+	// StreamEventTypeCacheHit is sent before a response replayed from a CachingModel
+	StreamEventTypeCacheHit = "cache_hit"
+
+	// StreamEventTypeArtifact is sent when a handoff records a new version of a
+	// task's artifact. The event's Artifact holds the version.
+	StreamEventTypeArtifact = "artifact"
+
+	// StreamEventTypeToolOutput is sent for output a tool produces while it runs,
+	// such as the logs of a Kubernetes job. The event's ToolCall is the call and
+	// its Content holds the output.
+	StreamEventTypeToolOutput = "tool_output"
+
+	// StreamEventTypeRunStarted is the first event of a streamed run
+	StreamEventTypeRunStarted = "run_started"
+
+	// StreamEventTypeTurnStarted is sent at the start of every turn of a run
+	StreamEventTypeTurnStarted = "turn_started"
+
+	// StreamEventTypeToolCallStarted and StreamEventTypeToolCallFinished are sent
+	// around the execution of a tool call. The event's ToolCall is the call, and
+	// the Output of the finished event is its result.
+	StreamEventTypeToolCallStarted  = "tool_call_started"
+	StreamEventTypeToolCallFinished = "tool_call_finished"
+
+	// StreamEventTypeAgentSwitched is sent when another agent takes over the run,
+	// after a handoff or a fallback. The event's FromAgent is the previous agent.
+	StreamEventTypeAgentSwitched = "agent_switched"
+
+	// StreamEventTypeUsage is sent with the token usage of every model response
+	StreamEventTypeUsage = "usage"
+
+	// StreamEventTypeRunCompleted is sent when a run finishes with a final output,
+	// which the event's Output holds
+	StreamEventTypeRunCompleted = "run_completed"
+
+	// StreamEventTypeRunCancelled is the last event of a cancelled run. The
+	// event's Error is the cause, and the run's result holds what was streamed.
+	StreamEventTypeRunCancelled = "run_cancelled"
 )
 
 // Handoff types
@@ -86,6 +244,17 @@ type Settings struct {
 	ToolChoice        *string
 	ParallelToolCalls *bool
 	MaxTokens         *int
+
+	// ResponseFormat asks for JSON output, optionally matching a schema
+	ResponseFormat *ResponseFormat
+
+	// PromptCaching marks the system instructions and tool schemas as cacheable.
+	// Anthropic caches them only when asked; OpenAI caches long prompts
+	// automatically and uses PromptCacheKey to route requests to the same cache.
+	PromptCaching bool
+
+	// PromptCacheKey groups requests that share a prompt prefix for OpenAI
+	PromptCacheKey string
 }
 
 // Model defines the interface for interacting with LLMs
@@ -102,3 +271,10 @@ type Provider interface {
 	// GetModel returns a model by name
 	GetModel(modelName string) (Model, error)
 }
+
+// WarmUpProvider is implemented by providers that can load a model ahead of the
+// first request. Currently only the LM Studio provider implements it.
+type WarmUpProvider interface {
+	// WarmUp loads the model into memory
+	WarmUp(ctx context.Context, modelName string) error
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/message"
+)
+
+// MessageFormatter builds the conversation history the runner sends back to a model
+// after a turn. Providers implement it on their models so that each model receives
+// tool results and assistant messages in the form its request conversion expects.
+type MessageFormatter interface {
+	// FormatUserMessage formats user content, a string or []ContentPart, as a history item
+	FormatUserMessage(content interface{}) interface{}
+
+	// FormatAssistantMessage formats a response of the model, including its tool
+	// calls, as a history item. Tool calls always have an ID.
+	FormatAssistantMessage(response *Response) interface{}
+
+	// FormatToolResult formats the result of a tool call as a history item
+	FormatToolResult(call ToolCall, result interface{}) interface{}
+}
+
+// DefaultMessageFormatter formats messages as the provider-agnostic history items
+// of the message package: message.Message and message.ToolResult. All providers
+// of this module translate them to the messages of their API.
+type DefaultMessageFormatter struct{}
+
+// Ensure DefaultMessageFormatter implements MessageFormatter
+var _ MessageFormatter = DefaultMessageFormatter{}
+
+// FormatUserMessage formats user content as a user message
+func (DefaultMessageFormatter) FormatUserMessage(content interface{}) interface{} {
+	return message.Message{Role: message.RoleUser, Content: content}
+}
+
+// FormatAssistantMessage formats a response as an assistant message with its
+// tool calls
+func (DefaultMessageFormatter) FormatAssistantMessage(response *Response) interface{} {
+	// Ensure the content field is never null
+	content := response.Content
+	if content == "" {
+		content = " "
+	}
+
+	msg := message.Message{Role: message.RoleAssistant, Content: content}
+	for _, tc := range response.ToolCalls {
+		msg.ToolCalls = append(msg.ToolCalls, message.ToolCall{ID: tc.ID, Name: tc.Name, Arguments: tc.Parameters})
+	}
+	return msg
+}
+
+// FormatToolResult formats the result of a tool call
+func (DefaultMessageFormatter) FormatToolResult(call ToolCall, result interface{}) interface{} {
+	return message.ToolResult{
+		Call:    message.ToolCall{ID: call.ID, Name: call.Name, Arguments: call.Parameters},
+		Content: result,
+	}
+}
+
+// FormatterFor returns the message formatter of a model, or DefaultMessageFormatter
+// if the model does not implement MessageFormatter
+func FormatterFor(m Model) MessageFormatter {
+	if formatter, ok := m.(MessageFormatter); ok {
+		return formatter
+	}
+	return DefaultMessageFormatter{}
+}
+
+// FormatHistory converts run input to a history list. Strings and content parts
+// become a user message, lists are copied and other input gives an empty history.
+func FormatHistory(formatter MessageFormatter, input interface{}) []interface{} {
+	switch v := input.(type) {
+	case string, []ContentPart:
+		return []interface{}{formatter.FormatUserMessage(v)}
+	case []interface{}:
+		return append(make([]interface{}, 0, len(v)+2), v...)
+	}
+	return []interface{}{}
+}
+
+// ToolResultText converts a tool result to text, encoding non-string results as JSON
+func ToolResultText(result interface{}) string {
+	switch v := result.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	}
+	if data, err := json.Marshal(result); err == nil {
+		return string(data)
+	}
+	return fmt.Sprintf("%v", result)
+}
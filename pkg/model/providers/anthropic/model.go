@@ -2,20 +2,23 @@ package anthropic
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"math"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/message"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/ratelimit"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tracing"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
@@ -29,17 +32,77 @@ type Model struct {
 	IncludeToolMessages bool // Whether to include tool result messages in the history limit
 }
 
-// AnthropicMessage represents a message in a conversation
+// log returns the logger of the model's provider
+func (m *Model) log() logging.Logger {
+	return m.Provider.log()
+}
+
+// AnthropicMessage represents a message in a conversation. Its content is a list
+// of content blocks: text, images and documents, the tool_use blocks of assistant
+// messages and the tool_result blocks answering them in user messages.
 type AnthropicMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string                  `json:"role"`
+	Content []AnthropicContentBlock `json:"content"`
+}
+
+// AnthropicContentBlock represents a content block of a message, in requests and
+// responses alike
+type AnthropicContentBlock struct {
+	Type   string                `json:"type"`
+	Text   string                `json:"text,omitempty"`
+	Source *AnthropicBlockSource `json:"source,omitempty"`
+
+	// ID, Name and Input describe a tool_use block
+	ID    string                 `json:"id,omitempty"`
+	Name  string                 `json:"name,omitempty"`
+	Input map[string]interface{} `json:"input,omitempty"`
+
+	// ToolUseID and Content describe a tool_result block
+	ToolUseID string                  `json:"tool_use_id,omitempty"`
+	Content   []AnthropicContentBlock `json:"content,omitempty"`
+
+	CacheControl *AnthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+// MarshalJSON encodes the block, always sending the input of tool_use blocks,
+// which Anthropic requires even when it is empty
+func (b AnthropicContentBlock) MarshalJSON() ([]byte, error) {
+	type anthropicContentBlock AnthropicContentBlock
+	if b.Type != "tool_use" {
+		return json.Marshal(anthropicContentBlock(b))
+	}
+	input := b.Input
+	if input == nil {
+		input = map[string]interface{}{}
+	}
+	return json.Marshal(struct {
+		anthropicContentBlock
+		Input map[string]interface{} `json:"input"`
+	}{anthropicContentBlock(b), input})
+}
+
+// AnthropicCacheControl marks the end of a prompt prefix to cache
+type AnthropicCacheControl struct {
+	Type string `json:"type"`
+}
+
+// ephemeralCache is the cache control of prompt caching
+var ephemeralCache = &AnthropicCacheControl{Type: "ephemeral"}
+
+// AnthropicBlockSource represents the source of an image or document block
+type AnthropicBlockSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
 }
 
 // AnthropicTool represents a tool in Anthropic's API
 type AnthropicTool struct {
-	Name        string                 `json:"name"`
-	Description string                 `json:"description"`
-	InputSchema map[string]interface{} `json:"input_schema"`
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description"`
+	InputSchema  map[string]interface{} `json:"input_schema"`
+	CacheControl *AnthropicCacheControl `json:"cache_control,omitempty"`
 }
 
 // AnthropicToolUse represents a tool use in Anthropic's API
@@ -64,6 +127,21 @@ type AnthropicMessageRequest struct {
 	Tools         []AnthropicTool    `json:"tools,omitempty"`
 	ToolChoice    interface{}        `json:"tool_choice,omitempty"`
 	StopSequences []string           `json:"stop_sequences,omitempty"`
+
+	// SystemBlocks replaces System with a list of content blocks when set
+	SystemBlocks []AnthropicContentBlock `json:"-"`
+}
+
+// MarshalJSON encodes the request, sending SystemBlocks as the system prompt when set
+func (r AnthropicMessageRequest) MarshalJSON() ([]byte, error) {
+	type anthropicMessageRequest AnthropicMessageRequest
+	if len(r.SystemBlocks) == 0 {
+		return json.Marshal(anthropicMessageRequest(r))
+	}
+	return json.Marshal(struct {
+		anthropicMessageRequest
+		System []AnthropicContentBlock `json:"system"`
+	}{anthropicMessageRequest(r), r.SystemBlocks})
 }
 
 // AnthropicMessageResponse represents a response from the messages API
@@ -87,6 +165,7 @@ type AnthropicStreamResponse struct {
 	Index        int                      `json:"index,omitempty"`
 	ContentBlock *AnthropicContent        `json:"content_block,omitempty"`
 	Delta        *AnthropicDelta          `json:"delta,omitempty"`
+	Usage        *AnthropicUsage          `json:"usage,omitempty"`
 	Error        map[string]interface{}   `json:"error,omitempty"`
 }
 
@@ -99,19 +178,28 @@ type AnthropicDelta struct {
 	StopSequence string `json:"stop_sequence,omitempty"`
 }
 
-// AnthropicContent represents content in a message
-type AnthropicContent struct {
-	Type  string                 `json:"type"`
-	Text  string                 `json:"text,omitempty"`
-	ID    string                 `json:"id,omitempty"`
-	Name  string                 `json:"name,omitempty"`
-	Input map[string]interface{} `json:"input,omitempty"`
-}
+// AnthropicContent represents a content block of a response
+type AnthropicContent = AnthropicContentBlock
 
 // AnthropicUsage represents token usage in a response
 type AnthropicUsage struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// modelUsage converts the usage to a model.Usage. Anthropic counts cached input
+// separately from input_tokens, so the prompt tokens are the sum of all three.
+func (u AnthropicUsage) modelUsage() *model.Usage {
+	promptTokens := u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+	return &model.Usage{
+		PromptTokens:        promptTokens,
+		CompletionTokens:    u.OutputTokens,
+		TotalTokens:         promptTokens + u.OutputTokens,
+		CachedTokens:        u.CacheReadInputTokens,
+		CacheCreationTokens: u.CacheCreationInputTokens,
+	}
 }
 
 // ErrorResponse represents an error response from the API
@@ -124,16 +212,24 @@ type ErrorResponse struct {
 
 // GetResponse gets a single response from the model with retry logic
 func (m *Model) GetResponse(ctx context.Context, request *model.Request) (*model.Response, error) {
+	// Fail early if the request cannot fit the context window
+	if err := m.Provider.checkContextWindow(m.ModelName, request); err != nil {
+		return nil, err
+	}
+
 	var response *model.Response
 	var lastErr error
 
 	// Try with exponential backoff
 	for attempt := 0; attempt <= m.Provider.MaxRetries; attempt++ {
 		// Wait for rate limit
-		m.Provider.WaitForRateLimit()
+		if err := m.Provider.WaitForRateLimitContext(ctx, nil); err != nil {
+			return nil, fmt.Errorf("context cancelled while waiting for rate limit: %w", err)
+		}
 
-		// If this is not the first attempt, wait with exponential backoff
-		if attempt > 0 {
+		// If this is not the first attempt, wait with exponential backoff unless the
+		// limiter already waited as long as the API asked
+		if attempt > 0 && !retryAfterKnown(lastErr) {
 			backoffDuration := calculateBackoff(attempt, m.Provider.RetryAfter)
 			select {
 			case <-ctx.Done():
@@ -174,32 +270,24 @@ func (m *Model) getResponseOnce(ctx context.Context, request *model.Request) (*m
 		return nil, fmt.Errorf("failed to construct request: %w", err)
 	}
 
-	// Marshal the request to JSON
-	requestBody, err := json.Marshal(anthropicRequest)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	// Print the request for debugging
-	if os.Getenv("ANTHROPIC_DEBUG") == "1" {
-		fmt.Println("DEBUG - Anthropic Request:", string(requestBody))
+	// Log the request for debugging
+	if log := m.log(); logging.Enabled(log, slog.LevelDebug) {
+		if requestBody, err := json.Marshal(anthropicRequest); err == nil {
+			log.Debug("Anthropic request", "body", requestBody)
+		}
 	}
 
 	// Create the HTTP request
-	httpRequest, err := http.NewRequestWithContext(
-		ctx,
-		http.MethodPost,
-		fmt.Sprintf("%s/messages", m.Provider.BaseURL),
-		bytes.NewReader(requestBody),
-	)
+	httpRequest, err := model.NewJSONRequest(ctx, http.MethodPost, fmt.Sprintf("%s/messages", m.Provider.BaseURL), anthropicRequest, m.Provider.uploadOptions())
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return nil, err
 	}
 
 	// Set headers
 	httpRequest.Header.Set("Content-Type", "application/json")
 	httpRequest.Header.Set("x-api-key", m.Provider.APIKey)
 	httpRequest.Header.Set("anthropic-version", "2023-06-01")
+	tracing.Inject(ctx, httpRequest.Header)
 
 	// Send the request
 	httpResponse, err := m.Provider.HTTPClient.Do(httpRequest)
@@ -215,6 +303,9 @@ func (m *Model) getResponseOnce(ctx context.Context, request *model.Request) (*m
 		}
 	}()
 
+	// Pace later requests by the rate limit headers
+	m.Provider.observeRateLimit(httpResponse)
+
 	// Check for errors
 	if httpResponse.StatusCode != http.StatusOK {
 		return nil, m.handleError(httpResponse)
@@ -226,10 +317,8 @@ func (m *Model) getResponseOnce(ctx context.Context, request *model.Request) (*m
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	// Print the response for debugging
-	if os.Getenv("ANTHROPIC_DEBUG") == "1" {
-		fmt.Println("DEBUG - Anthropic Response:", string(responseBody))
-	}
+	// Log the response for debugging
+	m.log().Debug("Anthropic response", "body", responseBody)
 
 	// Unmarshal the response
 	var anthropicResponse AnthropicMessageResponse
@@ -243,11 +332,26 @@ func (m *Model) getResponseOnce(ctx context.Context, request *model.Request) (*m
 	}
 
 	// Parse the response
-	return m.parseResponse(&anthropicResponse)
+	response, err := m.parseResponse(&anthropicResponse)
+	if err != nil {
+		return nil, err
+	}
+	if wantsStructuredOutput(request) {
+		if err := extractStructuredOutput(response); err != nil {
+			return nil, err
+		}
+	}
+	response.RequestID = httpResponse.Header.Get("request-id")
+	return response, nil
 }
 
 // StreamResponse streams a response from the model with retry logic
 func (m *Model) StreamResponse(ctx context.Context, request *model.Request) (<-chan model.StreamEvent, error) {
+	// Fail early if the request cannot fit the context window
+	if err := m.Provider.checkContextWindow(m.ModelName, request); err != nil {
+		return nil, err
+	}
+
 	// Create a channel for stream events
 	eventChan := make(chan model.StreamEvent)
 
@@ -258,11 +362,24 @@ func (m *Model) StreamResponse(ctx context.Context, request *model.Request) (<-c
 
 		// Try with exponential backoff
 		for attempt := 0; attempt <= m.Provider.MaxRetries; attempt++ {
-			// Wait for rate limit
-			m.Provider.WaitForRateLimit()
+			// Wait for rate limit, letting the caller know while the request is throttled
+			err := m.Provider.WaitForRateLimitContext(ctx, func(status ratelimit.Status) {
+				eventChan <- model.StreamEvent{
+					Type:      model.StreamEventTypeThrottled,
+					RateLimit: &status,
+				}
+			})
+			if err != nil {
+				eventChan <- model.StreamEvent{
+					Type:  model.StreamEventTypeError,
+					Error: fmt.Errorf("context cancelled while waiting for rate limit: %w", err),
+				}
+				return
+			}
 
-			// If this is not the first attempt, wait with exponential backoff
-			if attempt > 0 {
+			// If this is not the first attempt, wait with exponential backoff unless the
+			// limiter already waited as long as the API asked
+			if attempt > 0 && !retryAfterKnown(lastErr) {
 				backoffDuration := calculateBackoff(attempt, m.Provider.RetryAfter)
 				select {
 				case <-ctx.Done():
@@ -277,7 +394,7 @@ func (m *Model) StreamResponse(ctx context.Context, request *model.Request) (<-c
 			}
 
 			// Try to stream a response
-			err := m.streamResponseOnce(ctx, request, eventChan)
+			err = m.streamResponseOnce(ctx, request, eventChan)
 
 			// If successful or context cancelled, return
 			if err == nil || ctx.Err() != nil {
@@ -306,9 +423,13 @@ func (m *Model) StreamResponse(ctx context.Context, request *model.Request) (<-c
 			}
 
 			// Inform the client that we're retrying
+			delay, ok := ratelimit.RetryAfter(err)
+			if !ok {
+				delay = calculateBackoff(attempt+1, m.Provider.RetryAfter)
+			}
 			eventChan <- model.StreamEvent{
 				Type:    model.StreamEventTypeContent,
-				Content: fmt.Sprintf("Rate limit exceeded, retrying in %v...", calculateBackoff(attempt+1, m.Provider.RetryAfter)),
+				Content: fmt.Sprintf("Rate limit exceeded, retrying in %v...", delay.Round(time.Millisecond)),
 			}
 		}
 	}()
@@ -327,21 +448,10 @@ func (m *Model) streamResponseOnce(ctx context.Context, request *model.Request,
 	// Enable streaming
 	anthropicRequest.Stream = true
 
-	// Marshal the request to JSON
-	requestBody, err := json.Marshal(anthropicRequest)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
-	}
-
 	// Create the HTTP request
-	httpRequest, err := http.NewRequestWithContext(
-		ctx,
-		http.MethodPost,
-		fmt.Sprintf("%s/messages", m.Provider.BaseURL),
-		bytes.NewReader(requestBody),
-	)
+	httpRequest, err := model.NewJSONRequest(ctx, http.MethodPost, fmt.Sprintf("%s/messages", m.Provider.BaseURL), anthropicRequest, m.Provider.uploadOptions())
 	if err != nil {
-		return fmt.Errorf("failed to create HTTP request: %w", err)
+		return err
 	}
 
 	// Set headers
@@ -349,6 +459,7 @@ func (m *Model) streamResponseOnce(ctx context.Context, request *model.Request,
 	httpRequest.Header.Set("x-api-key", m.Provider.APIKey)
 	httpRequest.Header.Set("anthropic-version", "2023-06-01")
 	httpRequest.Header.Set("Accept", "text/event-stream")
+	tracing.Inject(ctx, httpRequest.Header)
 
 	// Send the request
 	httpResponse, err := m.Provider.HTTPClient.Do(httpRequest)
@@ -357,6 +468,9 @@ func (m *Model) streamResponseOnce(ctx context.Context, request *model.Request,
 	}
 	defer httpResponse.Body.Close()
 
+	// Pace later requests by the rate limit headers
+	m.Provider.observeRateLimit(httpResponse)
+
 	// Check for errors
 	if httpResponse.StatusCode != http.StatusOK {
 		return m.handleError(httpResponse)
@@ -366,13 +480,16 @@ func (m *Model) streamResponseOnce(ctx context.Context, request *model.Request,
 	reader := bufio.NewReader(httpResponse.Body)
 	tokenCount := 0
 	var (
-		content         strings.Builder
-		currentToolCall *model.ToolCall
-		toolCalls       []model.ToolCall
-		handoffCall     *model.HandoffCall
-		done            bool
+		content     strings.Builder
+		toolUses    = make(map[int]*model.ToolCall) // tool_use blocks in progress, by block index
+		toolCalls   []model.ToolCall
+		handoffCall *model.HandoffCall
+		usage       *AnthropicUsage
+		stopReason  string
+		done        bool
 	)
 
+stream:
 	for {
 		// Check if the context is cancelled
 		select {
@@ -423,23 +540,18 @@ func (m *Model) streamResponseOnce(ctx context.Context, request *model.Request,
 		// Handle different event types
 		switch streamResp.Type {
 		case "message_start":
-			// Message start event
+			// Message start event, carrying the input token counts
+			startUsage := streamResp.Message.Usage
+			usage = &startUsage
 			continue
 
 		case "content_block_start":
-			// Content block start event
-			if streamResp.ContentBlock != nil {
-				switch streamResp.ContentBlock.Type {
-				case "text":
-					// Reset the content builder
-					content.Reset()
-				case "tool_use":
-					currentToolCall = &model.ToolCall{
-						ID:           streamResp.ContentBlock.ID,
-						Name:         streamResp.ContentBlock.Name,
-						Parameters:   map[string]interface{}{},
-						RawParameter: strings.Builder{},
-					}
+			// A tool_use block streams its input as input_json_delta events
+			if streamResp.ContentBlock != nil && streamResp.ContentBlock.Type == "tool_use" {
+				toolUses[streamResp.Index] = &model.ToolCall{
+					ID:         streamResp.ContentBlock.ID,
+					Name:       streamResp.ContentBlock.Name,
+					Parameters: map[string]interface{}{},
 				}
 			}
 			continue
@@ -459,55 +571,69 @@ func (m *Model) streamResponseOnce(ctx context.Context, request *model.Request,
 						Content: streamResp.Delta.Text,
 					}
 				case "input_json_delta":
-					currentToolCall.RawParameter.WriteString(streamResp.Delta.PartialJson)
+					if toolCall, ok := toolUses[streamResp.Index]; ok {
+						toolCall.RawParameter.WriteString(streamResp.Delta.PartialJson)
+					}
 				}
 			}
 			continue
 
 		case "content_block_stop":
-			// Content block stop event
+			// A tool_use block is complete once its input is
+			toolCall, ok := toolUses[streamResp.Index]
+			if !ok {
+				continue
+			}
+			delete(toolUses, streamResp.Index)
+
+			rawInput := toolCall.RawParameter.String()
+			if rawInput != "" {
+				if err := json.Unmarshal([]byte(rawInput), &toolCall.Parameters); err != nil {
+					return fmt.Errorf("invalid input of tool %s: %w", toolCall.Name, err)
+				}
+			}
+
+			// Check if this is a handoff call
+			if call, isHandoff := m.checkIfHandoffCall(toolCall); isHandoff {
+				handoffCall = call
+				eventChan <- model.StreamEvent{
+					Type:        model.StreamEventTypeHandoff,
+					HandoffCall: handoffCall,
+				}
+			} else if toolCall.Name == OutputToolName && wantsStructuredOutput(request) {
+				// The input of the output tool is the structured response
+				output := rawInput
+				if output == "" {
+					output = "{}"
+				}
+				content.WriteString(output)
+				eventChan <- model.StreamEvent{
+					Type:    model.StreamEventTypeContent,
+					Content: output,
+				}
+			} else {
+				toolCalls = append(toolCalls, *toolCall)
+				eventChan <- model.StreamEvent{
+					Type:     model.StreamEventTypeToolCall,
+					ToolCall: toolCall,
+				}
+			}
 			continue
 
 		case "message_delta":
-			// Message delta event
-			if streamResp.Delta != nil {
-				switch streamResp.Delta.StopReason {
-				case "end_turn":
-					done = true
-				case "tool_use":
-					rawInput := currentToolCall.RawParameter.String()
-					if rawInput != "" {
-						if err := json.Unmarshal([]byte(rawInput), &currentToolCall.Parameters); err != nil {
-							return err
-						}
-					}
-					// Check if this is a handoff call
-					var isHandoff bool
-					handoffCall, isHandoff = m.checkIfHandoffCall(currentToolCall)
-					if isHandoff {
-						eventChan <- model.StreamEvent{
-							Type:        model.StreamEventTypeHandoff,
-							HandoffCall: handoffCall,
-						}
-						continue
-					} else {
-						toolCalls = append(toolCalls, *currentToolCall)
-						eventChan <- model.StreamEvent{
-							Type:     model.StreamEventTypeToolCall,
-							ToolCall: currentToolCall,
-						}
-					}
-					break
-				default:
-					// End of message
-					break
-				}
+			// Message delta event, carrying the stop reason and output token count
+			if streamResp.Usage != nil && usage != nil {
+				usage.OutputTokens = streamResp.Usage.OutputTokens
+			}
+			if streamResp.Delta != nil && streamResp.Delta.StopReason != "" {
+				stopReason = streamResp.Delta.StopReason
 			}
 			continue
 
 		case "message_stop":
-			// Message stop event
-			break
+			// The message is complete
+			done = true
+			break stream
 
 		case "error":
 			// Error event
@@ -527,14 +653,20 @@ func (m *Model) streamResponseOnce(ctx context.Context, request *model.Request,
 	}
 
 	// Final done event
+	response := &model.Response{
+		Content:     content.String(),
+		ToolCalls:   toolCalls,
+		HandoffCall: handoffCall,
+		RequestID:   httpResponse.Header.Get("request-id"),
+		StopReason:  stopReason,
+	}
+	if usage != nil {
+		response.Usage = usage.modelUsage()
+	}
 	eventChan <- model.StreamEvent{
-		Type: model.StreamEventTypeDone,
-		Response: &model.Response{
-			Content:     content.String(),
-			ToolCalls:   toolCalls,
-			HandoffCall: handoffCall,
-		},
-		Done: done,
+		Type:     model.StreamEventTypeDone,
+		Response: response,
+		Done:     done,
 	}
 
 	return nil
@@ -550,13 +682,11 @@ func (m *Model) constructRequest(request *model.Request) (*AnthropicMessageReque
 
 	// Apply history limit if configured
 	if m.MaxHistoryMessages > 0 && len(messages) > m.MaxHistoryMessages {
-		if os.Getenv("ANTHROPIC_DEBUG") == "1" {
-			fmt.Printf("DEBUG - Limiting message history from %d to %d messages\n",
-				len(messages), m.MaxHistoryMessages)
-		}
+		m.log().Debug("Limiting message history", "messages", len(messages), "limit", m.MaxHistoryMessages)
 
-		// Keep only the most recent messages based on configured limit
-		messages = messages[len(messages)-m.MaxHistoryMessages:]
+		// Keep only the most recent messages based on configured limit, without
+		// tool results whose tool_use blocks were cut off
+		messages = dropOrphanedToolResults(messages[len(messages)-m.MaxHistoryMessages:])
 	}
 
 	// Create the Anthropic request
@@ -609,50 +739,141 @@ func (m *Model) constructRequest(request *model.Request) (*AnthropicMessageReque
 				anthropicRequest.ToolChoice = map[string]interface{}{
 					"type": "auto",
 				}
-				if os.Getenv("ANTHROPIC_DEBUG") == "1" {
-					fmt.Println("DEBUG - Setting tool_choice to type: auto")
-				}
+				m.log().Debug("Setting tool_choice", "type", "auto")
 			case "none":
 				// For "none", use type format
 				anthropicRequest.ToolChoice = map[string]interface{}{
 					"type": "none",
 				}
-				if os.Getenv("ANTHROPIC_DEBUG") == "1" {
-					fmt.Println("DEBUG - Setting tool_choice to type: none")
-				}
+				m.log().Debug("Setting tool_choice", "type", "none")
 			case "any":
 				// For "any", use the object format with "any" type
 				anthropicRequest.ToolChoice = map[string]interface{}{
 					"type": "any",
 				}
-				if os.Getenv("ANTHROPIC_DEBUG") == "1" {
-					fmt.Println("DEBUG - Setting tool_choice to type: any")
-				}
+				m.log().Debug("Setting tool_choice", "type", "any")
 			default:
 				// For specific tool name (including handoffs), use the correct format
 				anthropicRequest.ToolChoice = map[string]interface{}{
 					"type": "tool",
 					"name": toolChoice,
 				}
-				if os.Getenv("ANTHROPIC_DEBUG") == "1" {
-					fmt.Printf("DEBUG - Setting tool_choice to specific tool: %s\n", toolChoice)
-				}
+				m.log().Debug("Setting tool_choice", "tool", toolChoice)
 			}
 		}
 	}
 
+	// Apply the response format if requested
+	if request.Settings != nil && request.Settings.ResponseFormat != nil {
+		applyResponseFormat(anthropicRequest, request.Settings)
+	}
+
+	// Cache the tools and system prompt, which are the same every turn
+	if request.Settings != nil && request.Settings.PromptCaching {
+		applyPromptCaching(anthropicRequest)
+	}
+
 	return anthropicRequest, nil
 }
 
+// applyPromptCaching marks the end of the tools and of the system prompt as cache
+// breakpoints. Anthropic builds the prompt from tools, then system, then messages,
+// so the first breakpoint caches the tools and the second both.
+func applyPromptCaching(anthropicRequest *AnthropicMessageRequest) {
+	if n := len(anthropicRequest.Tools); n > 0 {
+		anthropicRequest.Tools[n-1].CacheControl = ephemeralCache
+	}
+	if anthropicRequest.System != "" {
+		anthropicRequest.SystemBlocks = []AnthropicContentBlock{{
+			Type:         "text",
+			Text:         anthropicRequest.System,
+			CacheControl: ephemeralCache,
+		}}
+	}
+}
+
+// OutputToolName is the name of the tool through which schema output is requested.
+// Anthropic has no response_format, so a json_schema format becomes a tool whose
+// input schema is the output schema, and the tool's input becomes the response content.
+const OutputToolName = "structured_output"
+
+// jsonObjectInstruction is appended to the system prompt for json_object formats
+const jsonObjectInstruction = "Respond only with a single valid JSON object, without any other text or code fences."
+
+// applyResponseFormat translates a response format to the request
+func applyResponseFormat(anthropicRequest *AnthropicMessageRequest, settings *model.Settings) {
+	format := settings.ResponseFormat
+	switch format.Type {
+	case model.ResponseFormatJSONObject:
+		if anthropicRequest.System != "" {
+			anthropicRequest.System += "\n\n"
+		}
+		anthropicRequest.System += jsonObjectInstruction
+
+	case model.ResponseFormatJSONSchema:
+		description := "Respond with the final output"
+		if format.Name != "" {
+			description += fmt.Sprintf(" (%s)", format.Name)
+		}
+		schema := format.Schema
+		if schema == nil {
+			schema = map[string]interface{}{"type": "object"}
+		}
+		anthropicRequest.Tools = append(anthropicRequest.Tools, AnthropicTool{
+			Name:        OutputToolName,
+			Description: description + ". Call this tool instead of answering in text once you have the answer.",
+			InputSchema: schema,
+		})
+
+		// Force the output tool, unless the agent has other tools it may need to call
+		// first, in which case any tool call is required
+		if settings.ToolChoice == nil {
+			if len(anthropicRequest.Tools) == 1 {
+				anthropicRequest.ToolChoice = map[string]interface{}{"type": "tool", "name": OutputToolName}
+			} else {
+				anthropicRequest.ToolChoice = map[string]interface{}{"type": "any"}
+			}
+		}
+	}
+}
+
+// wantsStructuredOutput reports whether the request asks for output through the output tool
+func wantsStructuredOutput(request *model.Request) bool {
+	return request.Settings != nil && request.Settings.ResponseFormat != nil &&
+		request.Settings.ResponseFormat.Type == model.ResponseFormatJSONSchema
+}
+
+// extractStructuredOutput moves the input of the output tool call into the
+// response content
+func extractStructuredOutput(response *model.Response) error {
+	for i, toolCall := range response.ToolCalls {
+		if toolCall.Name != OutputToolName {
+			continue
+		}
+		output, err := json.Marshal(toolCall.Parameters)
+		if err != nil {
+			return fmt.Errorf("failed to serialize structured output: %w", err)
+		}
+		response.Content = string(output)
+		response.ToolCalls = append(response.ToolCalls[:i], response.ToolCalls[i+1:]...)
+		return nil
+	}
+	return nil
+}
+
+// SupportsJSONSchema reports whether the model accepts json_schema response
+// formats. All Claude models do, through a forced output tool.
+func (m *Model) SupportsJSONSchema() bool {
+	return true
+}
+
 // addHandoffToolsToRequest adds handoff tools to the request
 func (m *Model) addHandoffToolsToRequest(request *model.Request, tools *[]AnthropicTool) error {
 	if request.Handoffs == nil || len(request.Handoffs) == 0 {
 		return nil
 	}
 
-	if os.Getenv("ANTHROPIC_DEBUG") == "1" {
-		fmt.Printf("DEBUG - Adding %d handoff tools to request\n", len(request.Handoffs))
-	}
+	m.log().Debug("Adding handoff tools to request", "handoffs", len(request.Handoffs))
 
 	for _, handoff := range request.Handoffs {
 		handoffMap, ok := handoff.(map[string]interface{})
@@ -677,28 +898,7 @@ func (m *Model) addHandoffToolsToRequest(request *model.Request, tools *[]Anthro
 		handoffTool := AnthropicTool{
 			Name:        agentName,
 			Description: description,
-			InputSchema: map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"input": map[string]interface{}{
-						"type":        "string",
-						"description": "Input for the handoff",
-					},
-					"task_id": map[string]interface{}{
-						"type":        "string",
-						"description": "Unique identifier for the task",
-					},
-					"return_to_agent": map[string]interface{}{
-						"type":        "string",
-						"description": "Agent to return to after task completion",
-					},
-					"is_task_complete": map[string]interface{}{
-						"type":        "boolean",
-						"description": "Whether the task is complete",
-					},
-				},
-				"required": []string{"input"},
-			},
+			InputSchema: handoffInputSchema(function["parameters"]),
 		}
 
 		*tools = append(*tools, handoffTool)
@@ -707,166 +907,253 @@ func (m *Model) addHandoffToolsToRequest(request *model.Request, tools *[]Anthro
 	return nil
 }
 
+// handoffInputSchema returns the input schema of a handoff tool: the parameters
+// the handoff declares, or a single input string, plus the arguments that
+// configure the handoff. The declared schema is copied, as it is shared by all
+// agents handing off to the target.
+func handoffInputSchema(parameters interface{}) map[string]interface{} {
+	schema := map[string]interface{}{
+		"type":     "object",
+		"required": []string{"input"},
+	}
+	properties := map[string]interface{}{}
+	if declared, ok := parameters.(map[string]interface{}); ok {
+		for key, value := range declared {
+			schema[key] = value
+		}
+		if declaredProperties, ok := declared["properties"].(map[string]interface{}); ok {
+			for name, property := range declaredProperties {
+				properties[name] = property
+			}
+		}
+	} else {
+		properties["input"] = map[string]interface{}{
+			"type":        "string",
+			"description": "Input for the handoff",
+		}
+	}
+
+	properties["task_id"] = map[string]interface{}{
+		"type":        "string",
+		"description": "Unique identifier for the task",
+	}
+	properties["return_to_agent"] = map[string]interface{}{
+		"type":        "string",
+		"description": "Agent to return to after task completion",
+	}
+	properties["is_task_complete"] = map[string]interface{}{
+		"type":        "boolean",
+		"description": "Whether the task is complete",
+	}
+	schema["properties"] = properties
+	return schema
+}
+
 // createMessages creates AnthropicMessages from a model.Request.Input
 func (m *Model) createMessages(input interface{}) ([]AnthropicMessage, error) {
 	// Debug the input
-	if os.Getenv("ANTHROPIC_DEBUG") == "1" {
-		fmt.Println("DEBUG - Creating Anthropic messages from input:", input)
-	}
+	m.log().Debug("Creating Anthropic messages", "input", input)
 
 	var messages []AnthropicMessage
 
 	// Convert input to messages based on type
 	switch v := input.(type) {
 	case string:
-		// Single string input becomes a user message
-		// Skip empty content messages to avoid Anthropic API errors
-		if v != "" && strings.TrimSpace(v) != "" {
-			messages = []AnthropicMessage{
-				{
-					Role:    "user",
-					Content: v,
-				},
-			}
-		}
+		// Single string input becomes a user message, empty content is skipped to
+		// avoid Anthropic API errors
+		messages = appendBlocks(messages, "user", textBlock(v)...)
+	case []model.ContentPart:
+		// Multimodal content becomes a single user message
+		messages = appendBlocks(messages, "user", convertContentParts(v)...)
 	case []interface{}:
-		// Array of messages
-		for _, msgInterface := range v {
-			msg, ok := msgInterface.(map[string]interface{})
+		// Array of messages. Tool results answer the tool_use blocks of the
+		// preceding assistant message.
+		toolUses := make(map[string]bool)
+		for _, item := range v {
+			parsed, ok := message.Parse(item)
 			if !ok {
-				return nil, fmt.Errorf("message must be a map, got %T", msgInterface)
+				return nil, fmt.Errorf("unsupported history item %T", item)
 			}
 
 			// Process the message based on type
-			if os.Getenv("ANTHROPIC_DEBUG") == "1" {
-				fmt.Printf("DEBUG - Processing message: %+v\n", msg)
-			}
-
-			// Check if it's a tool result message
-			if toolCall, ok := msg["tool_call"].(map[string]interface{}); ok && msg["tool_result"] != nil {
-				if os.Getenv("ANTHROPIC_DEBUG") == "1" {
-					fmt.Println("DEBUG - Found tool result message")
+			m.log().Debug("Processing message", "message", parsed)
+
+			switch msg := parsed.(type) {
+			case message.ToolResult:
+				if !toolUses[msg.Call.ID] {
+					// A result without its tool_use block is sent as text, as Anthropic
+					// rejects unmatched tool_result blocks
+					text := fmt.Sprintf("Result of tool %s: %s", msg.Call.Name, model.ToolResultText(msg.Content))
+					messages = appendBlocks(messages, "user", textBlock(text)...)
+					continue
 				}
+				messages = appendBlocks(messages, "user", toolResultBlock(msg))
 
-				// Extract the tool call ID and result content
-				var toolCallID string
-				if id, ok := toolCall["id"].(string); ok {
-					toolCallID = id
+			case message.Message:
+				if msg.Role == "" {
+					return nil, fmt.Errorf("message must have a role")
 				}
 
-				// Get the result content
-				var resultContent interface{}
-				if result, ok := msg["tool_result"].(map[string]interface{}); ok {
-					if content, ok := result["content"]; ok {
-						resultContent = content
-					}
+				// System messages are handled separately
+				if msg.Role == message.RoleSystem {
+					continue
 				}
 
-				// We need to convert our tool result format to Anthropic's format
-				// The AnthropicMessage takes a string content, but Anthropic's API
-				// actually expects the "content" field to contain an array of content blocks
-				// when sending tool results. This is a quirk of their API.
-				toolResultContent := map[string]interface{}{
-					"type":        "tool_result",
-					"tool_use_id": toolCallID,
-					"content":     resultContent,
+				var blocks []AnthropicContentBlock
+				if parts, ok := model.ContentParts(msg.Content); ok {
+					// Multimodal content is sent as content blocks
+					blocks = convertContentParts(parts)
+				} else if content, ok := msg.Content.(string); ok {
+					blocks = textBlock(content)
+				} else if len(msg.ToolCalls) == 0 {
+					return nil, fmt.Errorf("message must have content")
 				}
 
-				// For a tool result, we need to wrap this in a Content array
-				contentBlocks := []interface{}{toolResultContent}
-
-				// Marshal the content blocks to JSON
-				contentJSON, err := json.Marshal(contentBlocks)
-				if err != nil {
-					if os.Getenv("ANTHROPIC_DEBUG") == "1" {
-						fmt.Printf("DEBUG - Error marshaling tool result: %v\n", err)
+				// Assistant messages echo their tool calls as tool_use blocks
+				if msg.Role == message.RoleAssistant {
+					toolUses = make(map[string]bool, len(msg.ToolCalls))
+					for _, call := range msg.ToolCalls {
+						blocks = append(blocks, AnthropicContentBlock{Type: "tool_use", ID: call.ID, Name: call.Name, Input: call.Arguments})
+						toolUses[call.ID] = true
 					}
-					continue
 				}
-
-				// Add the message with the raw JSON as content
-				messages = append(messages, AnthropicMessage{
-					Role:    "user",
-					Content: string(contentJSON),
-				})
-
-				if os.Getenv("ANTHROPIC_DEBUG") == "1" {
-					fmt.Printf("DEBUG - Added tool result message for tool ID %s with content: %s\n",
-						toolCallID, string(contentJSON))
-				}
-				continue
+				messages = appendBlocks(messages, msg.Role, blocks...)
 			}
+		}
+	default:
+		return nil, fmt.Errorf("unexpected input type: %T", input)
+	}
 
-			// Check for tool message type
-			if role, ok := msg["role"].(string); ok && role == "tool" {
-				if os.Getenv("ANTHROPIC_DEBUG") == "1" {
-					fmt.Println("DEBUG - Found tool message with role=tool")
-				}
-				// Skip tool messages, we handle them differently
-				continue
-			}
+	// Log the final messages for debugging
+	m.log().Debug("Created Anthropic messages", "count", len(messages), "messages", messages)
 
-			// Extract the role and content
-			role, roleOk := msg["role"].(string)
-			content, contentOk := msg["content"].(string)
+	return messages, nil
+}
 
-			// Skip messages with empty content
-			if contentOk && (content == "" || strings.TrimSpace(content) == "") {
-				continue
-			}
+// appendBlocks adds content blocks as a message of the given role, merging them
+// into the last message if it has the same role. Messages without blocks are
+// dropped.
+func appendBlocks(messages []AnthropicMessage, role string, blocks ...AnthropicContentBlock) []AnthropicMessage {
+	if len(blocks) == 0 {
+		return messages
+	}
+	if n := len(messages); n > 0 && messages[n-1].Role == role {
+		messages[n-1].Content = toolResultsFirst(append(messages[n-1].Content, blocks...))
+		return messages
+	}
+	return append(messages, AnthropicMessage{Role: role, Content: blocks})
+}
 
-			// Error if missing required fields
-			if !roleOk || !contentOk {
-				if !roleOk {
-					return nil, fmt.Errorf("message must have a role")
-				}
-				if !contentOk {
-					// If content is missing but tool_calls is present, create a placeholder content
-					if _, hasToolCalls := msg["tool_calls"]; hasToolCalls {
-						content = "I'll use a tool to help with this request."
-					} else {
-						return nil, fmt.Errorf("message must have content")
-					}
-				}
-			}
+// toolResultsFirst moves the tool_result blocks of a message ahead of its other
+// blocks, keeping their order, as Anthropic requires them to come first
+func toolResultsFirst(blocks []AnthropicContentBlock) []AnthropicContentBlock {
+	ordered := make([]AnthropicContentBlock, 0, len(blocks))
+	for _, block := range blocks {
+		if block.Type == "tool_result" {
+			ordered = append(ordered, block)
+		}
+	}
+	for _, block := range blocks {
+		if block.Type != "tool_result" {
+			ordered = append(ordered, block)
+		}
+	}
+	return ordered
+}
 
-			// Map role to Anthropic format
-			anthropicRole := role
-			if role == "assistant" {
-				anthropicRole = "assistant"
-			} else if role == "user" {
-				anthropicRole = "user"
-			} else if role == "system" {
-				// Skip system messages, handled separately
-				continue
-			}
+// textBlock returns a text block, or no block for blank text, which Anthropic rejects
+func textBlock(text string) []AnthropicContentBlock {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	return []AnthropicContentBlock{{Type: "text", Text: text}}
+}
 
-			// Add the message
-			messages = append(messages, AnthropicMessage{
-				Role:    anthropicRole,
-				Content: content,
-			})
+// toolResultBlock converts a tool result to a tool_result block. Text and images
+// are sent as content blocks; other results are encoded as JSON text.
+func toolResultBlock(result message.ToolResult) AnthropicContentBlock {
+	block := AnthropicContentBlock{Type: "tool_result", ToolUseID: result.Call.ID}
+	if parts, ok := model.ContentParts(result.Content); ok {
+		block.Content = convertContentParts(parts)
+	} else {
+		block.Content = textBlock(model.ToolResultText(result.Content))
+	}
+	return block
+}
+
+// hasToolResult reports whether a message answers tool calls of a message before it
+func hasToolResult(msg AnthropicMessage) bool {
+	for _, block := range msg.Content {
+		if block.Type == "tool_result" {
+			return true
 		}
-	default:
-		return nil, fmt.Errorf("unexpected input type: %T", input)
 	}
+	return false
+}
 
-	// Debug the final messages
-	if os.Getenv("ANTHROPIC_DEBUG") == "1" {
-		fmt.Println("DEBUG - Final Anthropic messages:")
-		for i, msg := range messages {
-			fmt.Printf("DEBUG - Message %d: {Role: %s, Content: %s}\n", i, msg.Role, msg.Content)
+// dropOrphanedToolResults removes the tool_result blocks of the leading message of
+// a trimmed history, whose tool_use blocks were trimmed with the messages before
+// it. The rest of the message is kept; a message left without blocks is dropped.
+func dropOrphanedToolResults(messages []AnthropicMessage) []AnthropicMessage {
+	for len(messages) > 0 && hasToolResult(messages[0]) {
+		kept := make([]AnthropicContentBlock, 0, len(messages[0].Content))
+		for _, block := range messages[0].Content {
+			if block.Type != "tool_result" {
+				kept = append(kept, block)
+			}
 		}
+		if len(kept) > 0 {
+			messages[0] = AnthropicMessage{Role: messages[0].Role, Content: kept}
+			return messages
+		}
+		messages = messages[1:]
 	}
+	return messages
+}
 
-	return messages, nil
+// convertContentParts converts content parts to Anthropic content blocks. Images
+// become image blocks and files become document blocks, with text files sent as
+// plain text sources. Audio is not supported and is dropped.
+func convertContentParts(parts []model.ContentPart) []AnthropicContentBlock {
+	blocks := make([]AnthropicContentBlock, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case model.ContentTypeText:
+			if strings.TrimSpace(part.Text) != "" {
+				blocks = append(blocks, AnthropicContentBlock{Type: "text", Text: part.Text})
+			}
+		case model.ContentTypeImageURL:
+			blocks = append(blocks, AnthropicContentBlock{
+				Type:   "image",
+				Source: &AnthropicBlockSource{Type: "url", URL: part.URL},
+			})
+		case model.ContentTypeImage:
+			blocks = append(blocks, AnthropicContentBlock{
+				Type:   "image",
+				Source: &AnthropicBlockSource{Type: "base64", MediaType: part.MediaType, Data: part.Base64()},
+			})
+		case model.ContentTypeFile:
+			if strings.HasPrefix(part.MediaType, "text/") {
+				blocks = append(blocks, AnthropicContentBlock{
+					Type:   "document",
+					Source: &AnthropicBlockSource{Type: "text", MediaType: "text/plain", Data: string(part.Data)},
+				})
+			} else {
+				blocks = append(blocks, AnthropicContentBlock{
+					Type:   "document",
+					Source: &AnthropicBlockSource{Type: "base64", MediaType: part.MediaType, Data: part.Base64()},
+				})
+			}
+		default:
+			logging.For(nil, "anthropic").Debug("Anthropic does not support content part, dropping it", "type", part.Type)
+		}
+	}
+	return blocks
 }
 
 // createTools creates AnthropicTools from model.Tools
 func (m *Model) createTools(tools []interface{}) ([]AnthropicTool, error) {
-	if os.Getenv("ANTHROPIC_DEBUG") == "1" {
-		fmt.Println("DEBUG - Creating tools from:", tools)
-	}
+	m.log().Debug("Creating tools", "tools", tools)
 
 	anthropicTools := make([]AnthropicTool, 0, len(tools))
 
@@ -908,9 +1195,7 @@ func (m *Model) createTools(tools []interface{}) ([]AnthropicTool, error) {
 			}
 
 			anthropicTools = append(anthropicTools, tool)
-			if os.Getenv("ANTHROPIC_DEBUG") == "1" {
-				fmt.Printf("DEBUG - Created Anthropic tool: %+v\n", tool)
-			}
+			m.log().Debug("Created Anthropic tool", "tool", tool)
 		}
 	}
 
@@ -921,13 +1206,10 @@ func (m *Model) createTools(tools []interface{}) ([]AnthropicTool, error) {
 func (m *Model) parseResponse(anthropicResponse *AnthropicMessageResponse) (*model.Response, error) {
 	// Create the model response
 	response := &model.Response{
-		Content:   "",
-		ToolCalls: make([]model.ToolCall, 0),
-		Usage: &model.Usage{
-			PromptTokens:     anthropicResponse.Usage.InputTokens,
-			CompletionTokens: anthropicResponse.Usage.OutputTokens,
-			TotalTokens:      anthropicResponse.Usage.InputTokens + anthropicResponse.Usage.OutputTokens,
-		},
+		Content:    "",
+		ToolCalls:  make([]model.ToolCall, 0),
+		Usage:      anthropicResponse.Usage.modelUsage(),
+		StopReason: anthropicResponse.StopReason,
 	}
 
 	// Extract text content
@@ -967,9 +1249,7 @@ func (m *Model) parseResponse(anthropicResponse *AnthropicMessageResponse) (*mod
 		}
 
 		if !isDuplicate && tool.Type == "tool_use" && tool.Submitted {
-			if os.Getenv("ANTHROPIC_DEBUG") == "1" {
-				fmt.Printf("DEBUG - Found tool call: %+v\n", tool)
-			}
+			m.log().Debug("Found tool call", "tool", tool)
 
 			toolCall := model.ToolCall{
 				ID:         tool.ID,
@@ -1003,24 +1283,12 @@ func (m *Model) checkIfHandoffCall(toolCall *model.ToolCall) (*model.HandoffCall
 		// Extract the agent name from the tool name
 		agentName := strings.TrimPrefix(toolCall.Name, "handoff_to_")
 
-		if os.Getenv("ANTHROPIC_DEBUG") == "1" {
-			fmt.Printf("DEBUG - Detected handoff call to agent: %s\n", agentName)
-		}
-
-		// Extract input from parameters
-		var input string
-		if inputVal, ok := toolCall.Parameters["input"].(string); ok {
-			input = inputVal
-		} else {
-			// If not a string or not found, convert parameters to JSON
-			inputBytes, _ := json.Marshal(toolCall.Parameters)
-			input = string(inputBytes)
-		}
+		m.log().Debug("Detected handoff call", "agent", agentName)
 
 		// Create a handoff call
 		handoffCall := &model.HandoffCall{
 			AgentName:      agentName,
-			Parameters:     map[string]any{"input": input},
+			Parameters:     map[string]any{"input": model.HandoffInput(toolCall.Parameters)},
 			Type:           model.HandoffTypeDelegate,
 			ReturnToAgent:  "", // Will be set by the runner
 			TaskID:         "", // Will be generated by the runner if not provided
@@ -1067,24 +1335,36 @@ func (m *Model) handleError(response *http.Response) error {
 		return fmt.Errorf("error reading error response: %w (status code: %d)", err, response.StatusCode)
 	}
 
-	// Try to parse the error response
+	// Try to parse the error response, falling back to the status code
+	providerErr := &model.ErrProvider{Provider: "anthropic", Status: response.StatusCode, RequestID: response.Header.Get("request-id")}
 	var errorResponse ErrorResponse
-	if err := json.Unmarshal(body, &errorResponse); err != nil {
-		// If we can't parse the error response, return a generic error
-		return fmt.Errorf("API error: %s (%d)", http.StatusText(response.StatusCode), response.StatusCode)
+	if jsonErr := json.Unmarshal(body, &errorResponse); jsonErr == nil {
+		providerErr.Code = errorResponse.Error.Type
+		providerErr.Message = errorResponse.Error.Message
 	}
 
-	// Return a formatted error
-	return fmt.Errorf("API error: %s (%s)", errorResponse.Error.Message, errorResponse.Error.Type)
+	// Rate limited requests carry the limit state for the retry
+	return model.ProviderError(providerErr, m.ModelName, response)
 }
 
-// isRateLimitError checks if an error is a rate limit error
+// isRateLimitError checks if an error is a rate limit error. Errors of rate limited
+// responses are typed; errors reported in a stream are recognized by their message.
 func isRateLimitError(err error) bool {
+	if ratelimit.IsRateLimited(err) {
+		return true
+	}
 	return strings.Contains(err.Error(), "rate limit") ||
 		strings.Contains(err.Error(), "Too Many Requests") ||
 		strings.Contains(err.Error(), "429")
 }
 
+// retryAfterKnown reports whether the API said when a rate limited request can be
+// retried, in which case the rate limiter paces the retry
+func retryAfterKnown(err error) bool {
+	_, ok := ratelimit.RetryAfter(err)
+	return ok
+}
+
 // calculateBackoff calculates the backoff duration based on the attempt number
 func calculateBackoff(attempt int, baseDelay time.Duration) time.Duration {
 	// Use exponential backoff with jitter
@@ -1116,26 +1396,25 @@ func generateID() string {
 	return fmt.Sprintf("id_%x", b)
 }
 
-// formatToolResultContent formats the tool result content as a JSON string
-func formatToolResultContent(content interface{}) string {
-	if content == nil {
-		return "null"
-	}
+// Ensure Model implements model.MessageFormatter
+var _ model.MessageFormatter = (*Model)(nil)
 
-	switch v := content.(type) {
-	case string:
-		// For string content, quote it properly
-		bytes, err := json.Marshal(v)
-		if err != nil {
-			return fmt.Sprintf(`"%v"`, v)
-		}
-		return string(bytes)
-	default:
-		// For other types, convert to JSON
-		bytes, err := json.Marshal(v)
-		if err != nil {
-			return fmt.Sprintf(`"%v"`, v)
-		}
-		return string(bytes)
+// FormatUserMessage formats user content as a history item
+func (m *Model) FormatUserMessage(content interface{}) interface{} {
+	return model.DefaultMessageFormatter{}.FormatUserMessage(content)
+}
+
+// FormatAssistantMessage formats a response as an assistant message
+func (m *Model) FormatAssistantMessage(response *model.Response) interface{} {
+	return model.DefaultMessageFormatter{}.FormatAssistantMessage(response)
+}
+
+// FormatToolResult formats a tool result. It becomes a tool_result block in a user
+// message, whose content Anthropic requires to be text or content blocks, so
+// results other than content parts are encoded as JSON.
+func (m *Model) FormatToolResult(call model.ToolCall, result interface{}) interface{} {
+	if parts, ok := model.ContentParts(result); ok {
+		return model.DefaultMessageFormatter{}.FormatToolResult(call, parts)
 	}
+	return model.DefaultMessageFormatter{}.FormatToolResult(call, model.ToolResultText(result))
 }
@@ -0,0 +1,123 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tracing"
+)
+
+// modelListResponse is a page of the models endpoint
+type modelListResponse struct {
+	Data []struct {
+		ID          string    `json:"id"`
+		DisplayName string    `json:"display_name"`
+		CreatedAt   time.Time `json:"created_at"`
+	} `json:"data"`
+	HasMore bool   `json:"has_more"`
+	LastID  string `json:"last_id"`
+}
+
+// ListModels returns the models available to the API key
+func (p *Provider) ListModels(ctx context.Context) ([]model.ModelInfo, error) {
+	p.mu.RLock()
+	baseURL := p.BaseURL
+	apiKey := p.APIKey
+	client := p.HTTPClient
+	p.mu.RUnlock()
+
+	var models []model.ModelInfo
+	afterID := ""
+	for {
+		query := url.Values{"limit": {"100"}}
+		if afterID != "" {
+			query.Set("after_id", afterID)
+		}
+
+		httpRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/models?%s", baseURL, query.Encode()), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		}
+		httpRequest.Header.Set("x-api-key", apiKey)
+		httpRequest.Header.Set("anthropic-version", "2023-06-01")
+		tracing.Inject(ctx, httpRequest.Header)
+
+		page, err := p.fetchModelPage(client, httpRequest)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range page.Data {
+			models = append(models, model.ModelInfo{
+				ID:          entry.ID,
+				DisplayName: entry.DisplayName,
+				CreatedAt:   entry.CreatedAt,
+			})
+		}
+
+		if !page.HasMore || page.LastID == "" {
+			return models, nil
+		}
+		afterID = page.LastID
+	}
+}
+
+// fetchModelPage sends a models request and decodes the page
+func (p *Provider) fetchModelPage(client *http.Client, httpRequest *http.Request) (*modelListResponse, error) {
+	httpResponse, err := client.Do(httpRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return nil, (&Model{Provider: p}).handleError(httpResponse)
+	}
+
+	var page modelListResponse
+	if err := json.NewDecoder(httpResponse.Body).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to decode model list: %w", err)
+	}
+	return &page, nil
+}
+
+// ValidateModel returns a *model.UnknownModelError if the model is not available.
+// Aliases such as "claude-3-5-sonnet-latest" are accepted when a dated version of
+// the model is available. The model list is fetched once and cached.
+func (p *Provider) ValidateModel(ctx context.Context, modelName string) error {
+	p.mu.Lock()
+	if p.validator == nil {
+		p.validator = model.NewModelValidator(p)
+	}
+	validator := p.validator
+	p.mu.Unlock()
+
+	if family, ok := strings.CutSuffix(modelName, "-latest"); ok {
+		ids, err := validator.Models(ctx)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if strings.HasPrefix(id, family+"-") {
+				return nil
+			}
+		}
+	}
+
+	return validator.Validate(ctx, modelName)
+}
+
+// WithModelValidation makes GetModel check model names against the model list, so
+// typos fail before the first request instead of as a 404 mid-run
+func (p *Provider) WithModelValidation(enabled bool) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.validateModels = enabled
+	return p
+}
@@ -1,12 +1,15 @@
 package anthropic
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/ratelimit"
 )
 
 const (
@@ -48,10 +51,19 @@ type Provider struct {
 
 	// Internal state
 	mu            sync.RWMutex
-	requestCount  int
-	tokenCount    int
-	lastResetTime time.Time
-	rateLimiter   *time.Ticker
+	limiter       ratelimit.Limiter
+	sharedLimiter bool
+
+	// Model name validation
+	validateModels bool
+	validator      *model.ModelValidator
+
+	// Large request handling
+	upload             model.UploadOptions
+	contextWindowCheck bool
+
+	// Logger of the provider's requests, see WithLogger
+	logger logging.Logger
 }
 
 // NewAnthropicProvider creates a new Provider with default settings
@@ -62,12 +74,11 @@ func NewAnthropicProvider(apiKey string) *Provider {
 		HTTPClient: &http.Client{
 			Timeout: 120 * time.Second,
 		},
-		RPM:           DefaultRPM,
-		TPM:           DefaultTPM,
-		MaxRetries:    DefaultMaxRetries,
-		RetryAfter:    DefaultRetryAfter,
-		lastResetTime: time.Now(),
-		rateLimiter:   time.NewTicker(time.Minute / time.Duration(DefaultRPM)),
+		RPM:        DefaultRPM,
+		TPM:        DefaultTPM,
+		MaxRetries: DefaultMaxRetries,
+		RetryAfter: DefaultRetryAfter,
+		limiter:    ratelimit.NewSlidingWindow(DefaultRPM, DefaultTPM),
 	}
 }
 
@@ -87,6 +98,25 @@ func (p *Provider) WithHTTPClient(client *http.Client) *Provider {
 	return p
 }
 
+// WithLogger sets the logger of the provider. API keys are redacted from what it
+// logs, including request and response bodies at debug level.
+func (p *Provider) WithLogger(logger logging.Logger) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.logger = logger
+	return p
+}
+
+// log returns the logger of the provider
+func (p *Provider) log() logging.Logger {
+	if p == nil {
+		return logging.For(nil, "anthropic")
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return logging.For(p.logger, "anthropic", p.APIKey)
+}
+
 // WithDefaultModel sets the default model for the provider
 func (p *Provider) WithDefaultModel(modelName string) *Provider {
 	p.mu.Lock()
@@ -101,10 +131,37 @@ func (p *Provider) WithRateLimit(rpm, tpm int) *Provider {
 	defer p.mu.Unlock()
 	p.RPM = rpm
 	p.TPM = tpm
-	p.rateLimiter = time.NewTicker(time.Minute / time.Duration(rpm))
+	p.syncLimits()
 	return p
 }
 
+// WithLimiter makes the provider pace its requests with a limiter shared with
+// other providers, instead of its own RPM and TPM limits. Providers using the same
+// API key should share one limiter.
+func (p *Provider) WithLimiter(limiter ratelimit.Limiter) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.limiter = limiter
+	p.sharedLimiter = true
+	return p
+}
+
+// syncLimits applies RPM and TPM to the provider's own limiter. A shared limiter
+// keeps its own limits. The caller holds the lock.
+func (p *Provider) syncLimits() {
+	if window, ok := p.limiter.(*ratelimit.SlidingWindow); ok && !p.sharedLimiter {
+		window.SetLimits(p.RPM, p.TPM)
+	}
+}
+
+// rateLimiter returns the limiter of the provider
+func (p *Provider) rateLimiter() ratelimit.Limiter {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	p.syncLimits()
+	return p.limiter
+}
+
 // WithRetryConfig sets the retry configuration for the provider
 func (p *Provider) WithRetryConfig(maxRetries int, retryAfter time.Duration) *Provider {
 	p.mu.Lock()
@@ -146,11 +203,11 @@ func (p *Provider) WithToolMessagesInHistory(include bool) *Provider {
 // GetModel returns a model by name
 func (p *Provider) GetModel(name string) (model.Model, error) {
 	p.mu.RLock()
-	defer p.mu.RUnlock()
 
 	// If no name is provided, use the default model
 	if name == "" {
 		if p.DefaultModel == "" {
+			p.mu.RUnlock()
 			return nil, fmt.Errorf("no model name provided and no default model set")
 		}
 		name = p.DefaultModel
@@ -158,60 +215,104 @@ func (p *Provider) GetModel(name string) (model.Model, error) {
 
 	// Check if API key is set
 	if p.APIKey == "" {
+		p.mu.RUnlock()
 		return nil, fmt.Errorf("no API key provided")
 	}
 
 	// Create a new model
-	return &Model{
+	m := &Model{
 		ModelName:           name,
 		Provider:            p,
 		MaxHistoryMessages:  p.MaxHistoryMessages,
 		IncludeToolMessages: p.IncludeToolMessages,
-	}, nil
+	}
+	validate := p.validateModels
+	p.mu.RUnlock()
+
+	// Check the name against the available models
+	if validate {
+		if err := p.ValidateModel(context.Background(), name); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
 }
 
 // WaitForRateLimit waits for the rate limiter to allow a new request
 func (p *Provider) WaitForRateLimit() {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+	_ = p.WaitForRateLimitContext(context.Background(), nil)
+}
 
-	// Check if we need to reset the rate limiter
-	now := time.Now()
-	if now.Sub(p.lastResetTime) > time.Minute {
-		p.resetRateLimiter()
-	}
+// WaitForRateLimitContext waits for the rate limiter to allow a new request, calling
+// onThrottle while the request is waiting for capacity
+func (p *Provider) WaitForRateLimitContext(ctx context.Context, onThrottle ratelimit.ThrottleFunc) error {
+	return p.rateLimiter().Wait(ctx, onThrottle)
+}
+
+// RateLimitStatus returns the remaining request and token capacity of the provider
+func (p *Provider) RateLimitStatus() ratelimit.Status {
+	return p.rateLimiter().Status()
+}
 
-	// Wait for the rate limiter
-	<-p.rateLimiter.C
+// RateLimitQuota returns the rate limit state last reported in the API's response
+// headers, and false if none has been reported
+func (p *Provider) RateLimitQuota() (ratelimit.Quota, bool) {
+	return p.rateLimiter().Quota()
+}
 
-	// Increment the request count
-	p.requestCount++
+// observeRateLimit paces later requests by the rate limit headers of a response
+func (p *Provider) observeRateLimit(response *http.Response) {
+	if quota, ok := ratelimit.ParseHeaders(response.Header, time.Now()); ok {
+		p.rateLimiter().Observe(quota)
+	}
 }
 
 // UpdateTokenCount updates the token count for rate limiting
 func (p *Provider) UpdateTokenCount(tokens int) {
+	p.rateLimiter().AddTokens(tokens)
+}
+
+// NewProvider creates a new provider with default settings
+func NewProvider(apiKey string) *Provider {
+	return NewAnthropicProvider(apiKey)
+}
+
+// WithStreamingUpload encodes request bodies while they are sent instead of
+// buffering them, which keeps memory flat for very large prompts
+func (p *Provider) WithStreamingUpload(enabled bool) *Provider {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	p.upload.Stream = enabled
+	return p
+}
 
-	// Check if we need to reset the rate limiter
-	now := time.Now()
-	if now.Sub(p.lastResetTime) > time.Minute {
-		p.resetRateLimiter()
-	}
-
-	// Update the token count
-	p.tokenCount += tokens
+// WithContextWindowCheck makes requests fail with a *model.ContextWindowError before
+// they are sent if their estimated size exceeds the model's context window. Models
+// with an unknown window can be registered with model.RegisterContextWindow.
+func (p *Provider) WithContextWindowCheck(enabled bool) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.contextWindowCheck = enabled
+	return p
 }
 
-// resetRateLimiter resets the rate limiter
-func (p *Provider) resetRateLimiter() {
-	p.requestCount = 0
-	p.tokenCount = 0
-	p.lastResetTime = time.Now()
-	p.rateLimiter.Reset(time.Minute / time.Duration(p.RPM))
+// uploadOptions returns how request bodies are sent
+func (p *Provider) uploadOptions() model.UploadOptions {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.upload
 }
 
-// NewProvider creates a new provider with default settings
-func NewProvider(apiKey string) *Provider {
-	return NewAnthropicProvider(apiKey)
+// checkContextWindow fails if the context window check is enabled and the request
+// is estimated not to fit the model's context window
+func (p *Provider) checkContextWindow(modelName string, request *model.Request) error {
+	p.mu.RLock()
+	enabled := p.contextWindowCheck
+	p.mu.RUnlock()
+
+	if !enabled {
+		return nil
+	}
+	return model.CheckContextWindow(modelName, request)
 }
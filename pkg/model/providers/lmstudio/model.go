@@ -2,19 +2,19 @@ package lmstudio
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strings"
 
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/message"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tracing"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
-
-	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
 )
 
 // Model implements the model.Model interface for LM Studio
@@ -26,12 +26,44 @@ type Model struct {
 	// Internal state
 }
 
+// log returns the logger of the model's provider
+func (m *Model) log() logging.Logger {
+	return m.Provider.log()
+}
+
 // ChatMessage represents a message in a chat
 type ChatMessage struct {
 	Role      string                `json:"role"`
 	Content   string                `json:"content,omitempty"`
 	Name      string                `json:"name,omitempty"`
 	ToolCalls []ChatMessageToolCall `json:"tool_calls,omitempty"`
+
+	// Parts replaces Content with a list of content parts when set
+	Parts []ChatContentPart `json:"-"`
+}
+
+// MarshalJSON encodes the message, sending Parts as the content when set
+func (m ChatMessage) MarshalJSON() ([]byte, error) {
+	type chatMessage ChatMessage
+	if len(m.Parts) == 0 {
+		return json.Marshal(chatMessage(m))
+	}
+	return json.Marshal(struct {
+		chatMessage
+		Content []ChatContentPart `json:"content"`
+	}{chatMessage(m), m.Parts})
+}
+
+// ChatContentPart represents a part of a multimodal message
+type ChatContentPart struct {
+	Type     string        `json:"type"`
+	Text     string        `json:"text,omitempty"`
+	ImageURL *ChatImageURL `json:"image_url,omitempty"`
+}
+
+// ChatImageURL represents an image in a content part
+type ChatImageURL struct {
+	URL string `json:"url"`
 }
 
 // ChatMessageToolCall represents a tool call in a chat message
@@ -72,6 +104,9 @@ type ChatCompletionRequest struct {
 	PresencePenalty  float64       `json:"presence_penalty,omitempty"`
 	MaxTokens        int           `json:"max_tokens,omitempty"`
 	Stream           bool          `json:"stream,omitempty"`
+
+	// ResponseFormat asks for JSON output
+	ResponseFormat map[string]interface{} `json:"response_format,omitempty"`
 }
 
 // ChatCompletionResponse represents a response from the chat completions API
@@ -100,27 +135,21 @@ type ChatCompletionUsage struct {
 
 // GetResponse gets a single response from the model
 func (m *Model) GetResponse(ctx context.Context, request *model.Request) (*model.Response, error) {
+	// Fail early if the request cannot fit the context window
+	if err := m.Provider.checkContextWindow(m.ModelName, request); err != nil {
+		return nil, err
+	}
+
 	// Construct the request
 	chatRequest, err := m.constructRequest(request)
 	if err != nil {
 		return nil, fmt.Errorf("failed to construct request: %w", err)
 	}
 
-	// Marshal the request to JSON
-	requestBody, err := json.Marshal(chatRequest)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
 	// Create the HTTP request
-	httpRequest, err := http.NewRequestWithContext(
-		ctx,
-		http.MethodPost,
-		fmt.Sprintf("%s/chat/completions", m.Provider.BaseURL),
-		bytes.NewReader(requestBody),
-	)
+	httpRequest, err := model.NewJSONRequest(ctx, http.MethodPost, fmt.Sprintf("%s/chat/completions", m.Provider.BaseURL), chatRequest, m.Provider.uploadOptions())
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return nil, err
 	}
 
 	// Set headers
@@ -128,6 +157,7 @@ func (m *Model) GetResponse(ctx context.Context, request *model.Request) (*model
 	if m.Provider.APIKey != "" {
 		httpRequest.Header.Set("Authorization", fmt.Sprintf("Bearer %s", m.Provider.APIKey))
 	}
+	tracing.Inject(ctx, httpRequest.Header)
 
 	// Send the request
 	httpResponse, err := m.Provider.HTTPClient.Do(httpRequest)
@@ -163,11 +193,21 @@ func (m *Model) GetResponse(ctx context.Context, request *model.Request) (*model
 	}
 
 	// Parse the response
-	return m.parseResponse(&chatResponse)
+	response, err := m.parseResponse(&chatResponse)
+	if err != nil {
+		return nil, err
+	}
+	response.RequestID = httpResponse.Header.Get("x-request-id")
+	return response, nil
 }
 
 // StreamResponse streams a response from the model
 func (m *Model) StreamResponse(ctx context.Context, request *model.Request) (<-chan model.StreamEvent, error) {
+	// Fail early if the request cannot fit the context window
+	if err := m.Provider.checkContextWindow(m.ModelName, request); err != nil {
+		return nil, err
+	}
+
 	// Create a channel for stream events
 	eventChan := make(chan model.StreamEvent)
 
@@ -180,21 +220,10 @@ func (m *Model) StreamResponse(ctx context.Context, request *model.Request) (<-c
 	// Set streaming to true
 	chatRequest.Stream = true
 
-	// Marshal the request to JSON
-	requestBody, err := json.Marshal(chatRequest)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
 	// Create the HTTP request
-	httpRequest, err := http.NewRequestWithContext(
-		ctx,
-		http.MethodPost,
-		fmt.Sprintf("%s/chat/completions", m.Provider.BaseURL),
-		bytes.NewReader(requestBody),
-	)
+	httpRequest, err := model.NewJSONRequest(ctx, http.MethodPost, fmt.Sprintf("%s/chat/completions", m.Provider.BaseURL), chatRequest, m.Provider.uploadOptions())
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return nil, err
 	}
 
 	// Set headers
@@ -202,29 +231,21 @@ func (m *Model) StreamResponse(ctx context.Context, request *model.Request) (<-c
 	if m.Provider.APIKey != "" {
 		httpRequest.Header.Set("Authorization", fmt.Sprintf("Bearer %s", m.Provider.APIKey))
 	}
+	tracing.Inject(ctx, httpRequest.Header)
 
 	// Send the request
 	httpResponse, err := m.Provider.HTTPClient.Do(httpRequest)
 	if err != nil {
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
-	defer func() {
-		if closeErr := httpResponse.Body.Close(); closeErr != nil {
-			// Log the error or handle it appropriately within the goroutine
-			// log.Printf("Warning: error closing response body in goroutine: %v", closeErr)
-		}
-	}()
 
 	// Check for errors
 	if httpResponse.StatusCode != http.StatusOK {
-		err := httpResponse.Body.Close()
-		if err != nil {
-			return nil, fmt.Errorf("failed to close response body: %w (original status code: %d)", err, httpResponse.StatusCode)
-		}
+		defer httpResponse.Body.Close()
 		return nil, m.handleError(httpResponse)
 	}
 
-	// Start a goroutine to process the stream
+	// Start a goroutine to process the stream; it closes the body when done
 	go func() {
 		defer func() {
 			if closeErr := httpResponse.Body.Close(); closeErr != nil {
@@ -348,6 +369,7 @@ func (m *Model) StreamResponse(ctx context.Context, request *model.Request) (<-c
 						Response: &model.Response{
 							Content:   content,
 							ToolCalls: toolCalls,
+							RequestID: httpResponse.Header.Get("x-request-id"),
 						},
 					}
 					break
@@ -388,71 +410,70 @@ func addUserInputMessages(chatRequest *ChatCompletionRequest, input interface{})
 			Role:    "user",
 			Content: inputStr,
 		})
+	} else if parts, ok := input.([]model.ContentPart); ok {
+		// If input is multimodal content, add it as a single user message
+		chatRequest.Messages = append(chatRequest.Messages, ChatMessage{
+			Role:  "user",
+			Parts: convertContentParts(parts),
+		})
 	} else if inputList, ok := input.([]interface{}); ok {
 		// If input is a list, process each item
 		processInputList(chatRequest, inputList)
 	}
 }
 
+// convertContentParts converts content parts to the OpenAI content part format.
+// LM Studio only accepts text and images, so file parts are dropped.
+func convertContentParts(parts []model.ContentPart) []ChatContentPart {
+	converted := make([]ChatContentPart, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case model.ContentTypeText:
+			converted = append(converted, ChatContentPart{Type: "text", Text: part.Text})
+		case model.ContentTypeImageURL:
+			converted = append(converted, ChatContentPart{Type: "image_url", ImageURL: &ChatImageURL{URL: part.URL}})
+		case model.ContentTypeImage:
+			converted = append(converted, ChatContentPart{Type: "image_url", ImageURL: &ChatImageURL{URL: part.DataURL()}})
+		default:
+			logging.For(nil, "lmstudio").Debug("LM Studio does not support content part, dropping it", "type", part.Type)
+		}
+	}
+	return converted
+}
+
 // processInputList processes a list of input items and adds them as messages
 func processInputList(chatRequest *ChatCompletionRequest, inputList []interface{}) {
 	for _, item := range inputList {
-		if message, ok := item.(map[string]interface{}); ok {
-			// Handle different message types
-			if message["type"] == "message" {
-				// Add a regular message
-				chatMessage := createChatMessageFromMap(message)
-				chatRequest.Messages = append(chatRequest.Messages, chatMessage)
-			} else if message["type"] == "tool_result" {
-				// Add a tool result message
-				toolResultMessage := createToolResultMessage(message)
-				if toolResultMessage != nil {
-					chatRequest.Messages = append(chatRequest.Messages, *toolResultMessage)
-				}
-			}
+		parsed, ok := message.Parse(item)
+		if !ok {
+			continue
+		}
+		switch msg := parsed.(type) {
+		case message.Message:
+			chatRequest.Messages = append(chatRequest.Messages, createChatMessage(msg))
+		case message.ToolResult:
+			chatRequest.Messages = append(chatRequest.Messages, createToolResultMessage(msg))
 		}
 	}
 }
 
-// createChatMessageFromMap creates a ChatMessage from a map representation
-func createChatMessageFromMap(message map[string]interface{}) ChatMessage {
-	chatMessage := ChatMessage{
-		Role:    message["role"].(string),
-		Content: message["content"].(string),
-	}
-
-	// Add name if provided
-	if name, ok := message["name"].(string); ok && name != "" {
-		chatMessage.Name = name
+// createChatMessage creates a ChatMessage from a history message
+func createChatMessage(msg message.Message) ChatMessage {
+	chatMessage := ChatMessage{Role: msg.Role, Name: msg.Name}
+	if content, ok := msg.Content.(string); ok {
+		chatMessage.Content = content
+	} else if parts, ok := model.ContentParts(msg.Content); ok {
+		chatMessage.Parts = convertContentParts(parts)
 	}
-
 	return chatMessage
 }
 
-// createToolResultMessage creates a tool result message from a map representation
-func createToolResultMessage(message map[string]interface{}) *ChatMessage {
-	// Extract tool result and tool call
-	toolResult, ok := message["tool_result"].(map[string]interface{})
-	if !ok || toolResult == nil {
-		// Skip invalid tool results
-		return nil
-	}
-
-	toolCall, ok := message["tool_call"].(map[string]interface{})
-	if !ok || toolCall == nil {
-		// Skip invalid tool calls
-		return nil
-	}
-
-	// Create a tool result message
-	content := fmt.Sprintf("Tool '%s' returned: %v",
-		toolCall["name"].(string),
-		toolResult["content"])
-
-	return &ChatMessage{
+// createToolResultMessage creates a tool result message from a history tool result
+func createToolResultMessage(result message.ToolResult) ChatMessage {
+	return ChatMessage{
 		Role:    "tool",
-		Content: content,
-		Name:    toolCall["name"].(string),
+		Content: fmt.Sprintf("Tool '%s' returned: %v", result.Call.Name, result.Content),
+		Name:    result.Call.Name,
 	}
 }
 
@@ -543,9 +564,7 @@ func addHandoffToolsToRequest(chatRequest *ChatCompletionRequest, handoffs []int
 				}
 
 				chatRequest.Tools = append(chatRequest.Tools, chatTool)
-				if os.Getenv("LMSTUDIO_DEBUG") == "1" {
-					fmt.Printf("Added handoff tool to request: %s\n", function["name"].(string))
-				}
+				logging.For(nil, "lmstudio").Debug("Added handoff tool to request", "tool", function["name"])
 			}
 		}
 	}
@@ -586,6 +605,20 @@ func applyModelSettings(chatRequest *ChatCompletionRequest, settings *model.Sett
 			}
 		}
 	}
+	if format := settings.ResponseFormat; format != nil {
+		chatRequest.ResponseFormat = map[string]interface{}{"type": format.Type}
+		if format.Type == model.ResponseFormatJSONSchema {
+			name := format.Name
+			if name == "" {
+				name = "output"
+			}
+			chatRequest.ResponseFormat["json_schema"] = map[string]interface{}{
+				"name":   name,
+				"schema": format.Schema,
+				"strict": format.Strict,
+			}
+		}
+	}
 	// Note: parallel_tool_calls is not directly supported in the OpenAI API request
 	// It's a client-side setting that affects how tool calls are processed
 }
@@ -663,10 +696,10 @@ func (m *Model) parseResponse(chatResponse *ChatCompletionResponse) (*model.Resp
 			// Check if this is a handoff call
 			if strings.HasPrefix(strings.ToLower(toolCall.Function.Name), "handoff_to_") {
 				// Extract the agent name from the tool name
-				agentName := strings.TrimPrefix(toolCall.Function.Name, "handoff_to_")
+				agentName := toolCall.Function.Name[len("handoff_to_"):]
 				response.HandoffCall = &model.HandoffCall{
 					AgentName:      agentName,
-					Parameters:     map[string]interface{}{"input": args["input"].(string)},
+					Parameters:     map[string]interface{}{"input": model.HandoffInput(args)},
 					Type:           model.HandoffTypeDelegate,
 					ReturnToAgent:  "", // Will be set by the runner
 					TaskID:         "", // Will be generated by the runner if not provided
@@ -791,10 +824,30 @@ func (m *Model) handleError(response *http.Response) error {
 			Type    string `json:"type"`
 		} `json:"error"`
 	}
+	providerErr := &model.ErrProvider{Provider: "lmstudio", Status: response.StatusCode}
 	if err := json.Unmarshal(body, &errorResponse); err == nil && errorResponse.Error.Message != "" {
-		return fmt.Errorf("API error (%s): %s", errorResponse.Error.Type, errorResponse.Error.Message)
+		providerErr.Code = errorResponse.Error.Type
+		providerErr.Message = errorResponse.Error.Message
 	}
+	return model.ProviderError(providerErr, m.ModelName, response)
+}
+
+// Ensure Model implements model.MessageFormatter
+var _ model.MessageFormatter = (*Model)(nil)
+
+// FormatUserMessage formats user content as a history item
+func (m *Model) FormatUserMessage(content interface{}) interface{} {
+	return model.DefaultMessageFormatter{}.FormatUserMessage(content)
+}
+
+// FormatAssistantMessage formats a response as an assistant message with tool_calls,
+// which must precede the tool messages answering them
+func (m *Model) FormatAssistantMessage(response *model.Response) interface{} {
+	return model.DefaultMessageFormatter{}.FormatAssistantMessage(response)
+}
 
-	// Fallback to status code
-	return fmt.Errorf("API error: %s", response.Status)
+// FormatToolResult formats a tool result. It becomes a message with role "tool"
+// and the result encoded as text.
+func (m *Model) FormatToolResult(call model.ToolCall, result interface{}) interface{} {
+	return model.DefaultMessageFormatter{}.FormatToolResult(call, result)
 }
@@ -0,0 +1,80 @@
+package lmstudio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tracing"
+)
+
+// modelListResponse is the response of the models endpoint
+type modelListResponse struct {
+	Data []struct {
+		ID      string `json:"id"`
+		OwnedBy string `json:"owned_by"`
+	} `json:"data"`
+}
+
+// ListModels returns the models the server can load
+func (p *Provider) ListModels(ctx context.Context) ([]model.ModelInfo, error) {
+	p.mu.RLock()
+	baseURL := p.BaseURL
+	apiKey := p.APIKey
+	client := p.HTTPClient
+	p.mu.RUnlock()
+
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/models", baseURL), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	if apiKey != "" {
+		httpRequest.Header.Set("Authorization", fmt.Sprintf("Bearer %s", apiKey))
+	}
+	tracing.Inject(ctx, httpRequest.Header)
+
+	httpResponse, err := client.Do(httpRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return nil, (&Model{Provider: p}).handleError(httpResponse)
+	}
+
+	var list modelListResponse
+	if err := json.NewDecoder(httpResponse.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode model list: %w", err)
+	}
+
+	models := make([]model.ModelInfo, 0, len(list.Data))
+	for _, entry := range list.Data {
+		models = append(models, model.ModelInfo{ID: entry.ID, OwnedBy: entry.OwnedBy})
+	}
+	return models, nil
+}
+
+// ValidateModel returns a *model.UnknownModelError if the server doesn't have the
+// model. The model list is fetched once and cached.
+func (p *Provider) ValidateModel(ctx context.Context, modelName string) error {
+	p.mu.Lock()
+	if p.validator == nil {
+		p.validator = model.NewModelValidator(p)
+	}
+	validator := p.validator
+	p.mu.Unlock()
+
+	return validator.Validate(ctx, modelName)
+}
+
+// WithModelValidation makes GetModel check model names against the model list, so
+// typos fail before the first request instead of as a 404 mid-run
+func (p *Provider) WithModelValidation(enabled bool) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.validateModels = enabled
+	return p
+}
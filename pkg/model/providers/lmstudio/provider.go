@@ -1,11 +1,13 @@
 package lmstudio
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
 )
 
@@ -26,6 +28,17 @@ type Provider struct {
 
 	// Internal state
 	mu sync.RWMutex
+
+	// Model name validation
+	validateModels bool
+	validator      *model.ModelValidator
+
+	// Large request handling
+	upload             model.UploadOptions
+	contextWindowCheck bool
+
+	// Logger of the provider's requests, see WithLogger
+	logger logging.Logger
 }
 
 // NewLMStudioProvider creates a new Provider with default settings
@@ -58,6 +71,25 @@ func (p *Provider) WithHTTPClient(client *http.Client) *Provider {
 	return p
 }
 
+// WithLogger sets the logger of the provider. API keys are redacted from what it
+// logs, including request and response bodies at debug level.
+func (p *Provider) WithLogger(logger logging.Logger) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.logger = logger
+	return p
+}
+
+// log returns the logger of the provider
+func (p *Provider) log() logging.Logger {
+	if p == nil {
+		return logging.For(nil, "lmstudio")
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return logging.For(p.logger, "lmstudio", p.APIKey)
+}
+
 // WithDefaultModel sets the default model for the provider
 func (p *Provider) WithDefaultModel(modelName string) *Provider {
 	p.mu.Lock()
@@ -82,16 +114,26 @@ func (p *Provider) SetDefaultModel(modelName string) *Provider {
 // GetModel returns a model by name
 func (p *Provider) GetModel(name string) (model.Model, error) {
 	p.mu.RLock()
-	defer p.mu.RUnlock()
 
 	// If no name is provided, use the default model
 	if name == "" {
 		if p.DefaultModel == "" {
+			p.mu.RUnlock()
 			return nil, fmt.Errorf("no model name provided and no default model set")
 		}
 		name = p.DefaultModel
 	}
 
+	validate := p.validateModels
+	p.mu.RUnlock()
+
+	// Check the name against the available models
+	if validate {
+		if err := p.ValidateModel(context.Background(), name); err != nil {
+			return nil, err
+		}
+	}
+
 	// Create a new model
 	return &Model{
 		ModelName: name,
@@ -103,3 +145,51 @@ func (p *Provider) GetModel(name string) (model.Model, error) {
 func NewProvider() *Provider {
 	return NewLMStudioProvider(DefaultBaseURL)
 }
+
+// WithGzipRequests compresses request bodies, for servers that accept
+// Content-Encoding: gzip, such as proxies in front of OpenAI-compatible APIs
+func (p *Provider) WithGzipRequests(enabled bool) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.upload.Gzip = enabled
+	return p
+}
+
+// WithStreamingUpload encodes request bodies while they are sent instead of
+// buffering them, which keeps memory flat for very large prompts
+func (p *Provider) WithStreamingUpload(enabled bool) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.upload.Stream = enabled
+	return p
+}
+
+// WithContextWindowCheck makes requests fail with a *model.ContextWindowError before
+// they are sent if their estimated size exceeds the model's context window. Models
+// with an unknown window can be registered with model.RegisterContextWindow.
+func (p *Provider) WithContextWindowCheck(enabled bool) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.contextWindowCheck = enabled
+	return p
+}
+
+// uploadOptions returns how request bodies are sent
+func (p *Provider) uploadOptions() model.UploadOptions {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.upload
+}
+
+// checkContextWindow fails if the context window check is enabled and the request
+// is estimated not to fit the model's context window
+func (p *Provider) checkContextWindow(modelName string, request *model.Request) error {
+	p.mu.RLock()
+	enabled := p.contextWindowCheck
+	p.mu.RUnlock()
+
+	if !enabled {
+		return nil
+	}
+	return model.CheckContextWindow(modelName, request)
+}
@@ -0,0 +1,78 @@
+package lmstudio
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+)
+
+// DefaultKeepAliveInterval is how often StartKeepAlive pings a model by default.
+// Local servers commonly unload idle models after five minutes or more.
+const DefaultKeepAliveInterval = 4 * time.Minute
+
+// WarmUp loads a model into memory by sending it a one-token request, so the first
+// agent turn doesn't absorb the server's cold start. Only the LM Studio provider
+// offers warm-up; Ollama and vLLM serve the same OpenAI-compatible API, so they can
+// be warmed up through a provider created with NewLMStudioProvider and pointed at
+// their /v1 endpoint.
+func (p *Provider) WarmUp(ctx context.Context, modelName string) error {
+	m, err := p.GetModel(modelName)
+	if err != nil {
+		return fmt.Errorf("failed to warm up model: %w", err)
+	}
+
+	maxTokens := 1
+	start := time.Now()
+	if _, err := m.GetResponse(ctx, &model.Request{
+		Input:    "ping",
+		Settings: &model.Settings{MaxTokens: &maxTokens},
+	}); err != nil {
+		return fmt.Errorf("failed to warm up model %s: %w", m.(*Model).ModelName, err)
+	}
+
+	p.log().Debug("Warmed up model", "model", m.(*Model).ModelName, "duration", time.Since(start))
+	return nil
+}
+
+// StartKeepAlive warms up a model and then pings it at the given interval so the
+// server keeps it loaded. It returns a function that stops the keepalive; the
+// keepalive also stops when the context is cancelled. Ping failures are logged
+// in debug mode and otherwise ignored.
+func (p *Provider) StartKeepAlive(ctx context.Context, modelName string, interval time.Duration) (stop func()) {
+	if interval <= 0 {
+		interval = DefaultKeepAliveInterval
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := p.WarmUp(ctx, modelName); err != nil && ctx.Err() == nil {
+				p.log().Warn("Keepalive failed", "model", modelName, "error", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cancel()
+			<-done
+		})
+	}
+}
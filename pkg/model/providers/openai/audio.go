@@ -0,0 +1,196 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+)
+
+const (
+	// DefaultTranscriptionModel is the model used for transcription when none is requested
+	DefaultTranscriptionModel = "whisper-1"
+
+	// DefaultSpeechModel is the model used for speech when none is requested
+	DefaultSpeechModel = "tts-1"
+
+	// DefaultVoice is the voice used for speech when none is requested
+	DefaultVoice = "alloy"
+)
+
+// speechMediaTypes maps speech formats to their MIME types
+var speechMediaTypes = map[string]string{
+	"mp3":  "audio/mpeg",
+	"opus": "audio/opus",
+	"aac":  "audio/aac",
+	"flac": "audio/flac",
+	"wav":  "audio/wav",
+	"pcm":  "audio/pcm",
+}
+
+// speechRequest is the body of the speech endpoint
+type speechRequest struct {
+	Model          string  `json:"model"`
+	Input          string  `json:"input"`
+	Voice          string  `json:"voice"`
+	ResponseFormat string  `json:"response_format,omitempty"`
+	Speed          float64 `json:"speed,omitempty"`
+	Instructions   string  `json:"instructions,omitempty"`
+}
+
+// transcriptionResponse is the response of the transcriptions endpoint
+type transcriptionResponse struct {
+	Text     string  `json:"text"`
+	Language string  `json:"language"`
+	Duration float64 `json:"duration"`
+}
+
+// Transcribe converts speech to text with the audio transcriptions endpoint
+func (p *Provider) Transcribe(ctx context.Context, request *model.TranscriptionRequest) (*model.Transcription, error) {
+	if request == nil || len(request.Audio) == 0 {
+		return nil, fmt.Errorf("audio is required")
+	}
+
+	modelName := request.Model
+	if modelName == "" {
+		modelName = DefaultTranscriptionModel
+	}
+	filename := request.Filename
+	if filename == "" {
+		filename = "audio.wav"
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := file.Write(request.Audio); err != nil {
+		return nil, fmt.Errorf("failed to write audio: %w", err)
+	}
+	fields := map[string]string{
+		"model":    modelName,
+		"language": request.Language,
+		"prompt":   request.Prompt,
+	}
+	// Only whisper returns the detected language and duration
+	if strings.HasPrefix(modelName, "whisper") {
+		fields["response_format"] = "verbose_json"
+	} else {
+		fields["response_format"] = "json"
+	}
+	for name, value := range fields {
+		if value == "" {
+			continue
+		}
+		if err := form.WriteField(name, value); err != nil {
+			return nil, fmt.Errorf("failed to write form field %s: %w", name, err)
+		}
+	}
+	if err := form.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close form: %w", err)
+	}
+
+	httpResponse, err := p.sendAudioRequest(ctx, modelName, "/audio/transcriptions", &body, form.FormDataContentType())
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+
+	var transcribed transcriptionResponse
+	if err := json.NewDecoder(httpResponse.Body).Decode(&transcribed); err != nil {
+		return nil, fmt.Errorf("failed to decode transcription: %w", err)
+	}
+
+	return &model.Transcription{
+		Text:     transcribed.Text,
+		Language: transcribed.Language,
+		Duration: time.Duration(transcribed.Duration * float64(time.Second)),
+	}, nil
+}
+
+// Synthesize converts text to speech with the audio speech endpoint
+func (p *Provider) Synthesize(ctx context.Context, request *model.SpeechRequest) (*model.Speech, error) {
+	if request == nil || strings.TrimSpace(request.Text) == "" {
+		return nil, fmt.Errorf("text is required")
+	}
+
+	body := speechRequest{
+		Model:          request.Model,
+		Input:          request.Text,
+		Voice:          request.Voice,
+		ResponseFormat: request.Format,
+		Speed:          request.Speed,
+		Instructions:   request.Instructions,
+	}
+	if body.Model == "" {
+		body.Model = DefaultSpeechModel
+	}
+	if body.Voice == "" {
+		body.Voice = DefaultVoice
+	}
+	if body.ResponseFormat == "" {
+		body.ResponseFormat = "mp3"
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	httpResponse, err := p.sendAudioRequest(ctx, body.Model, "/audio/speech", bytes.NewReader(data), "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer httpResponse.Body.Close()
+
+	audio, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read speech: %w", err)
+	}
+
+	mediaType := speechMediaTypes[body.ResponseFormat]
+	if contentType := httpResponse.Header.Get("Content-Type"); strings.HasPrefix(contentType, "audio/") {
+		mediaType = contentType
+	}
+	return &model.Speech{Audio: audio, MediaType: mediaType}, nil
+}
+
+// sendAudioRequest sends a request to an audio endpoint and returns the response
+// if it succeeded
+func (p *Provider) sendAudioRequest(ctx context.Context, modelName, suffix string, body io.Reader, contentType string) (*http.Response, error) {
+	if err := p.WaitForRateLimitContext(ctx, nil); err != nil {
+		return nil, err
+	}
+
+	p.mu.RLock()
+	url := p.buildURL(suffix, modelName)
+	client := p.HTTPClient
+	p.mu.RUnlock()
+
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	m := &Model{ModelName: modelName, Provider: p}
+	m.setHeader(httpRequest)
+	httpRequest.Header.Set("Content-Type", contentType)
+
+	httpResponse, err := client.Do(httpRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	if httpResponse.StatusCode != http.StatusOK {
+		defer httpResponse.Body.Close()
+		return nil, m.handleError(httpResponse)
+	}
+	return httpResponse, nil
+}
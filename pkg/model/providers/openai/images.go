@@ -0,0 +1,124 @@
+package openai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+)
+
+// DefaultImageModel is the model used for image generation when none is requested
+const DefaultImageModel = "dall-e-3"
+
+// imageGenerationRequest is the body of the image generations endpoint
+type imageGenerationRequest struct {
+	Model          string `json:"model"`
+	Prompt         string `json:"prompt"`
+	N              int    `json:"n,omitempty"`
+	Size           string `json:"size,omitempty"`
+	Quality        string `json:"quality,omitempty"`
+	Style          string `json:"style,omitempty"`
+	ResponseFormat string `json:"response_format,omitempty"`
+}
+
+// imageGenerationResponse is the response of the image generations endpoint
+type imageGenerationResponse struct {
+	Data []struct {
+		URL           string `json:"url"`
+		B64JSON       string `json:"b64_json"`
+		RevisedPrompt string `json:"revised_prompt"`
+	} `json:"data"`
+	OutputFormat string `json:"output_format"`
+	Usage        *struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// GenerateImage generates images with the Images API
+func (p *Provider) GenerateImage(ctx context.Context, request *model.ImageRequest) (*model.ImageResponse, error) {
+	if request == nil || strings.TrimSpace(request.Prompt) == "" {
+		return nil, fmt.Errorf("image prompt is required")
+	}
+
+	modelName := request.Model
+	if modelName == "" {
+		modelName = DefaultImageModel
+	}
+	body := imageGenerationRequest{
+		Model:   modelName,
+		Prompt:  request.Prompt,
+		N:       request.N,
+		Size:    request.Size,
+		Quality: request.Quality,
+		Style:   request.Style,
+	}
+	// gpt-image models always return base64 data and reject response_format
+	if !strings.HasPrefix(modelName, "gpt-image") {
+		body.ResponseFormat = request.Format
+	}
+
+	if err := p.WaitForRateLimitContext(ctx, nil); err != nil {
+		return nil, err
+	}
+
+	p.mu.RLock()
+	url := p.buildURL("/images/generations", modelName)
+	client := p.HTTPClient
+	p.mu.RUnlock()
+
+	httpRequest, err := model.NewJSONRequest(ctx, http.MethodPost, url, body, p.uploadOptions())
+	if err != nil {
+		return nil, err
+	}
+	m := &Model{ModelName: modelName, Provider: p}
+	m.setHeader(httpRequest)
+
+	httpResponse, err := client.Do(httpRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return nil, m.handleError(httpResponse)
+	}
+
+	var generated imageGenerationResponse
+	if err := json.NewDecoder(httpResponse.Body).Decode(&generated); err != nil {
+		return nil, fmt.Errorf("failed to decode image response: %w", err)
+	}
+
+	mediaType := "image/png"
+	if generated.OutputFormat != "" {
+		mediaType = "image/" + generated.OutputFormat
+	}
+
+	response := &model.ImageResponse{Images: make([]model.GeneratedImage, 0, len(generated.Data))}
+	for _, image := range generated.Data {
+		generatedImage := model.GeneratedImage{URL: image.URL, RevisedPrompt: image.RevisedPrompt}
+		if image.B64JSON != "" {
+			data, err := base64.StdEncoding.DecodeString(image.B64JSON)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode image data: %w", err)
+			}
+			generatedImage.Data = data
+			generatedImage.MediaType = mediaType
+		}
+		response.Images = append(response.Images, generatedImage)
+	}
+	if generated.Usage != nil {
+		response.Usage = &model.Usage{
+			PromptTokens:     generated.Usage.InputTokens,
+			CompletionTokens: generated.Usage.OutputTokens,
+			TotalTokens:      generated.Usage.TotalTokens,
+		}
+	}
+
+	return response, nil
+}
@@ -2,7 +2,6 @@ package openai
 
 import (
 	"bufio"
-	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/json"
@@ -11,14 +10,16 @@ import (
 	"maps"
 	"math"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/message"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/ratelimit"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tracing"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
-
-	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
 )
 
 // Model implements the model.Model interface for OpenAI
@@ -28,6 +29,11 @@ type Model struct {
 	Provider  *Provider
 }
 
+// log returns the logger of the model's provider
+func (m *Model) log() logging.Logger {
+	return m.Provider.log()
+}
+
 // ChatMessage represents a message in a chat
 type ChatMessage struct {
 	Role       string                `json:"role"`
@@ -35,6 +41,49 @@ type ChatMessage struct {
 	Name       string                `json:"name,omitempty"`
 	ToolCalls  []ChatMessageToolCall `json:"tool_calls,omitempty"`
 	ToolCallID string                `json:"tool_call_id,omitempty"`
+
+	// Parts replaces Content with a list of content parts when set
+	Parts []ChatContentPart `json:"-"`
+}
+
+// MarshalJSON encodes the message, sending Parts as the content when set
+func (m ChatMessage) MarshalJSON() ([]byte, error) {
+	type chatMessage ChatMessage
+	if len(m.Parts) == 0 {
+		return json.Marshal(chatMessage(m))
+	}
+	return json.Marshal(struct {
+		chatMessage
+		Content []ChatContentPart `json:"content"`
+	}{chatMessage(m), m.Parts})
+}
+
+// ChatContentPart represents a part of a multimodal message
+type ChatContentPart struct {
+	Type       string           `json:"type"`
+	Text       string           `json:"text,omitempty"`
+	ImageURL   *ChatImageURL    `json:"image_url,omitempty"`
+	File       *ChatFileContent `json:"file,omitempty"`
+	InputAudio *ChatInputAudio  `json:"input_audio,omitempty"`
+}
+
+// ChatImageURL represents an image in a content part
+type ChatImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// ChatInputAudio represents audio in a content part
+type ChatInputAudio struct {
+	Data   string `json:"data"`
+	Format string `json:"format"`
+}
+
+// ChatFileContent represents a file in a content part
+type ChatFileContent struct {
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data,omitempty"`
+	FileID   string `json:"file_id,omitempty"`
 }
 
 // ChatMessageToolCall represents a tool call in a chat message
@@ -75,6 +124,34 @@ type ChatCompletionRequest struct {
 	PresencePenalty  float64       `json:"presence_penalty,omitempty"`
 	MaxTokens        int           `json:"max_tokens,omitempty"`
 	Stream           bool          `json:"stream,omitempty"`
+
+	// StreamOptions configures a streamed response
+	StreamOptions *ChatStreamOptions `json:"stream_options,omitempty"`
+
+	// ResponseFormat asks for JSON output
+	ResponseFormat *ChatResponseFormat `json:"response_format,omitempty"`
+
+	// PromptCacheKey routes requests sharing a prompt prefix to the same cache
+	PromptCacheKey string `json:"prompt_cache_key,omitempty"`
+}
+
+// ChatStreamOptions are the stream_options of a streaming chat completion request
+type ChatStreamOptions struct {
+	// IncludeUsage adds a last chunk with the usage of the whole response
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// ChatResponseFormat is the response_format of a chat completion request
+type ChatResponseFormat struct {
+	Type       string          `json:"type"`
+	JSONSchema *ChatJSONSchema `json:"json_schema,omitempty"`
+}
+
+// ChatJSONSchema is the schema of a json_schema response format
+type ChatJSONSchema struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema,omitempty"`
+	Strict bool                   `json:"strict,omitempty"`
 }
 
 // ChatCompletionResponse represents a response from the chat completions API
@@ -96,9 +173,15 @@ type ChatCompletionChoice struct {
 
 // ChatCompletionUsage represents usage information in a chat completion response
 type ChatCompletionUsage struct {
-	PromptTokens     int `json:"prompt_tokens"`
-	CompletionTokens int `json:"completion_tokens"`
-	TotalTokens      int `json:"total_tokens"`
+	PromptTokens        int                     `json:"prompt_tokens"`
+	CompletionTokens    int                     `json:"completion_tokens"`
+	TotalTokens         int                     `json:"total_tokens"`
+	PromptTokensDetails *ChatPromptTokenDetails `json:"prompt_tokens_details,omitempty"`
+}
+
+// ChatPromptTokenDetails breaks down the prompt tokens of a chat completion
+type ChatPromptTokenDetails struct {
+	CachedTokens int `json:"cached_tokens"`
 }
 
 // ErrorResponse represents an error response from the API
@@ -113,16 +196,24 @@ type ErrorResponse struct {
 
 // GetResponse gets a single response from the model with retry logic
 func (m *Model) GetResponse(ctx context.Context, request *model.Request) (*model.Response, error) {
+	// Fail early if the request cannot fit the context window
+	if err := m.Provider.checkContextWindow(m.ModelName, request); err != nil {
+		return nil, err
+	}
+
 	var response *model.Response
 	var lastErr error
 
 	// Try with exponential backoff
 	for attempt := 0; attempt <= m.Provider.MaxRetries; attempt++ {
 		// Wait for rate limit
-		m.Provider.WaitForRateLimit()
+		if err := m.Provider.WaitForRateLimitContext(ctx, nil); err != nil {
+			return nil, fmt.Errorf("context cancelled while waiting for rate limit: %w", err)
+		}
 
-		// If this is not the first attempt, wait with exponential backoff
-		if attempt > 0 {
+		// If this is not the first attempt, wait with exponential backoff unless the
+		// limiter already waited as long as the API asked
+		if attempt > 0 && !retryAfterKnown(lastErr) {
 			backoffDuration := calculateBackoff(attempt, m.Provider.RetryAfter)
 			select {
 			case <-ctx.Done():
@@ -163,21 +254,10 @@ func (m *Model) getResponseOnce(ctx context.Context, request *model.Request) (*m
 		return nil, fmt.Errorf("failed to construct request: %w", err)
 	}
 
-	// Marshal the request to JSON
-	requestBody, err := json.Marshal(chatRequest)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
 	// Create the HTTP request
-	httpRequest, err := http.NewRequestWithContext(
-		ctx,
-		http.MethodPost,
-		m.Provider.buildURL("/chat/completions", m.ModelName),
-		bytes.NewReader(requestBody),
-	)
+	httpRequest, err := model.NewJSONRequest(ctx, http.MethodPost, m.Provider.buildURL("/chat/completions", m.ModelName), chatRequest, m.Provider.uploadOptions())
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return nil, err
 	}
 
 	// Set headers
@@ -197,6 +277,9 @@ func (m *Model) getResponseOnce(ctx context.Context, request *model.Request) (*m
 		}
 	}()
 
+	// Pace later requests by the rate limit headers
+	m.Provider.observeRateLimit(httpResponse)
+
 	// Check for errors
 	if httpResponse.StatusCode != http.StatusOK {
 		return nil, m.handleError(httpResponse)
@@ -220,11 +303,21 @@ func (m *Model) getResponseOnce(ctx context.Context, request *model.Request) (*m
 	}
 
 	// Parse the response
-	return m.parseResponse(&chatResponse)
+	response, err := m.parseResponse(&chatResponse)
+	if err != nil {
+		return nil, err
+	}
+	response.RequestID = requestID(httpResponse)
+	return response, nil
 }
 
 // StreamResponse streams a response from the model with retry logic
 func (m *Model) StreamResponse(ctx context.Context, request *model.Request) (<-chan model.StreamEvent, error) {
+	// Fail early if the request cannot fit the context window
+	if err := m.Provider.checkContextWindow(m.ModelName, request); err != nil {
+		return nil, err
+	}
+
 	// Create a channel for stream events
 	eventChan := make(chan model.StreamEvent)
 
@@ -235,11 +328,24 @@ func (m *Model) StreamResponse(ctx context.Context, request *model.Request) (<-c
 
 		// Try with exponential backoff
 		for attempt := 0; attempt <= m.Provider.MaxRetries; attempt++ {
-			// Wait for rate limit
-			m.Provider.WaitForRateLimit()
+			// Wait for rate limit, letting the caller know while the request is throttled
+			err := m.Provider.WaitForRateLimitContext(ctx, func(status ratelimit.Status) {
+				eventChan <- model.StreamEvent{
+					Type:      model.StreamEventTypeThrottled,
+					RateLimit: &status,
+				}
+			})
+			if err != nil {
+				eventChan <- model.StreamEvent{
+					Type:  model.StreamEventTypeError,
+					Error: fmt.Errorf("context cancelled while waiting for rate limit: %w", err),
+				}
+				return
+			}
 
-			// If this is not the first attempt, wait with exponential backoff
-			if attempt > 0 {
+			// If this is not the first attempt, wait with exponential backoff unless the
+			// limiter already waited as long as the API asked
+			if attempt > 0 && !retryAfterKnown(lastErr) {
 				backoffDuration := calculateBackoff(attempt, m.Provider.RetryAfter)
 				select {
 				case <-ctx.Done():
@@ -254,7 +360,7 @@ func (m *Model) StreamResponse(ctx context.Context, request *model.Request) (<-c
 			}
 
 			// Try to stream a response
-			err := m.streamResponseOnce(ctx, request, eventChan)
+			err = m.streamResponseOnce(ctx, request, eventChan)
 
 			// If successful, return
 			if err == nil {
@@ -300,24 +406,16 @@ func (m *Model) streamResponseOnce(ctx context.Context, request *model.Request,
 		return fmt.Errorf("failed to construct request: %w", err)
 	}
 
-	// Set streaming to true
+	// Set streaming to true, asking for the usage in a last chunk
 	chatRequest.Stream = true
-
-	// Marshal the request to JSON
-	requestBody, err := json.Marshal(chatRequest)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
+	if m.Provider.streamUsage() {
+		chatRequest.StreamOptions = &ChatStreamOptions{IncludeUsage: true}
 	}
 
 	// Create the HTTP request
-	httpRequest, err := http.NewRequestWithContext(
-		ctx,
-		http.MethodPost,
-		m.Provider.buildURL("/chat/completions", m.ModelName),
-		bytes.NewReader(requestBody),
-	)
+	httpRequest, err := model.NewJSONRequest(ctx, http.MethodPost, m.Provider.buildURL("/chat/completions", m.ModelName), chatRequest, m.Provider.uploadOptions())
 	if err != nil {
-		return fmt.Errorf("failed to create HTTP request: %w", err)
+		return err
 	}
 
 	// Set headers
@@ -330,6 +428,9 @@ func (m *Model) streamResponseOnce(ctx context.Context, request *model.Request,
 	}
 	defer httpResponse.Body.Close()
 
+	// Pace later requests by the rate limit headers
+	m.Provider.observeRateLimit(httpResponse)
+
 	// Check for errors
 	if httpResponse.StatusCode != http.StatusOK {
 		return m.handleError(httpResponse)
@@ -340,10 +441,11 @@ func (m *Model) streamResponseOnce(ctx context.Context, request *model.Request,
 
 	// Variables to accumulate the response
 	var (
-		totalTokens int
+		usage       *ChatCompletionUsage
 		content     string
 		toolCalls   []model.ToolCall
 		handoffCall *model.HandoffCall
+		finished    bool
 	)
 
 	// Process each line
@@ -385,9 +487,7 @@ func (m *Model) streamResponseOnce(ctx context.Context, request *model.Request,
 				} `json:"delta"`
 				FinishReason string `json:"finish_reason"`
 			} `json:"choices"`
-			Usage struct {
-				TotalTokens int `json:"total_tokens"`
-			} `json:"usage"`
+			Usage *ChatCompletionUsage `json:"usage"`
 		}
 
 		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
@@ -397,15 +497,15 @@ func (m *Model) streamResponseOnce(ctx context.Context, request *model.Request,
 			return err
 		}
 
+		// The usage comes in a last chunk without choices
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+
 		// Process the chunk
 		if len(chunk.Choices) > 0 {
 			choice := chunk.Choices[0]
 
-			// Update total tokens if available
-			if chunk.Usage.TotalTokens > 0 {
-				totalTokens = chunk.Usage.TotalTokens
-			}
-
 			// Process content
 			if choice.Delta.Content != "" {
 				content += choice.Delta.Content
@@ -460,10 +560,10 @@ func (m *Model) streamResponseOnce(ctx context.Context, request *model.Request,
 						// or by using a tool name that matches an agent name
 						if strings.HasPrefix(strings.ToLower(toolCall.Name), "handoff_to_") {
 							// Extract the agent name from the tool name
-							agentName := strings.TrimPrefix(toolCall.Name, "handoff_to_")
+							agentName := toolCall.Name[len("handoff_to_"):]
 							handoffCall = &model.HandoffCall{
 								AgentName:      agentName,
-								Parameters:     map[string]interface{}{"input": args["input"].(string)},
+								Parameters:     map[string]interface{}{"input": model.HandoffInput(args)},
 								Type:           model.HandoffTypeDelegate,
 								ReturnToAgent:  "", // Will be set by the runner
 								TaskID:         "", // Will be generated by the runner if not provided
@@ -567,23 +667,8 @@ func (m *Model) streamResponseOnce(ctx context.Context, request *model.Request,
 						}
 					}
 				}
-				// Update token count for rate limiting
-				if totalTokens > 0 {
-					m.Provider.UpdateTokenCount(totalTokens)
-				}
-
-				eventChan <- model.StreamEvent{
-					Type: model.StreamEventTypeDone,
-					Response: &model.Response{
-						Content:   content,
-						ToolCalls: toolCalls,
-						Usage: &model.Usage{
-							TotalTokens: totalTokens,
-						},
-						HandoffCall: handoffCall,
-					},
-				}
-				break
+				// The response is done once the usage chunk that may follow has arrived
+				finished = true
 			}
 		}
 	}
@@ -592,7 +677,28 @@ func (m *Model) streamResponseOnce(ctx context.Context, request *model.Request,
 	if err := scanner.Err(); err != nil {
 		return fmt.Errorf("error reading stream: %w", err)
 	}
+	if !finished {
+		return nil
+	}
 
+	response := &model.Response{
+		Content:     content,
+		ToolCalls:   toolCalls,
+		HandoffCall: handoffCall,
+		RequestID:   requestID(httpResponse),
+	}
+	if usage != nil {
+		response.Usage = convertUsage(*usage)
+
+		// Update token count for rate limiting
+		if usage.TotalTokens > 0 {
+			m.Provider.UpdateTokenCount(usage.TotalTokens)
+		}
+	}
+	eventChan <- model.StreamEvent{
+		Type:     model.StreamEventTypeDone,
+		Response: response,
+	}
 	return nil
 }
 
@@ -642,6 +748,7 @@ func (m *Model) setHeader(req *http.Request) {
 	if m.Provider.Organization != "" {
 		req.Header.Set("OpenAI-Organization", m.Provider.Organization)
 	}
+	tracing.Inject(req.Context(), req.Header)
 }
 
 // addSystemMessage adds a system message to the chat request if provided
@@ -666,114 +773,97 @@ func addUserInputMessages(chatRequest *ChatCompletionRequest, input interface{})
 			Role:    "user",
 			Content: inputStr,
 		})
+	} else if parts, ok := input.([]model.ContentPart); ok {
+		// If input is multimodal content, add it as a single user message
+		chatRequest.Messages = append(chatRequest.Messages, ChatMessage{
+			Role:  "user",
+			Parts: convertContentParts(parts),
+		})
 	} else if inputList, ok := input.([]interface{}); ok {
 		// If input is a list, process each item
 		processInputList(chatRequest, inputList)
 	}
 }
 
+// convertContentParts converts content parts to OpenAI's content part format
+func convertContentParts(parts []model.ContentPart) []ChatContentPart {
+	converted := make([]ChatContentPart, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case model.ContentTypeText:
+			converted = append(converted, ChatContentPart{Type: "text", Text: part.Text})
+		case model.ContentTypeImageURL:
+			converted = append(converted, ChatContentPart{
+				Type:     "image_url",
+				ImageURL: &ChatImageURL{URL: part.URL, Detail: part.Detail},
+			})
+		case model.ContentTypeImage:
+			converted = append(converted, ChatContentPart{
+				Type:     "image_url",
+				ImageURL: &ChatImageURL{URL: part.DataURL(), Detail: part.Detail},
+			})
+		case model.ContentTypeFile:
+			converted = append(converted, ChatContentPart{
+				Type: "file",
+				File: &ChatFileContent{Filename: part.Filename, FileData: part.DataURL()},
+			})
+		case model.ContentTypeAudio:
+			converted = append(converted, ChatContentPart{
+				Type:       "input_audio",
+				InputAudio: &ChatInputAudio{Data: part.Base64(), Format: part.AudioFormat()},
+			})
+		}
+	}
+	return converted
+}
+
 // processInputList processes a list of input items and adds them as messages
 func processInputList(chatRequest *ChatCompletionRequest, inputList []interface{}) {
 	for _, item := range inputList {
-		if message, ok := item.(map[string]interface{}); ok {
-			// Handle different message types
-			if message["type"] == "message" {
-				// Add a regular message
-				chatMessage := createChatMessageFromMap(message)
-				chatRequest.Messages = append(chatRequest.Messages, chatMessage)
-			} else if message["type"] == "tool_result" {
-				// Add a tool result message
-				toolResultMessage := createToolResultMessage(message)
-				if toolResultMessage != nil {
-					chatRequest.Messages = append(chatRequest.Messages, *toolResultMessage)
-				}
+		parsed, ok := message.Parse(item)
+		if !ok {
+			continue
+		}
+		switch msg := parsed.(type) {
+		case message.Message:
+			chatRequest.Messages = append(chatRequest.Messages, createChatMessage(msg))
+		case message.ToolResult:
+			if toolResultMessage := createToolResultMessage(msg); toolResultMessage != nil {
+				chatRequest.Messages = append(chatRequest.Messages, *toolResultMessage)
 			}
 		}
 	}
 }
 
-// createChatMessageFromMap creates a ChatMessage from a map representation
-func createChatMessageFromMap(message map[string]interface{}) ChatMessage {
-	chatMessage := ChatMessage{
-		Role:    message["role"].(string),
-		Content: message["content"].(string),
-	}
-
-	// Add name if provided
-	if name, ok := message["name"].(string); ok && name != "" {
-		chatMessage.Name = name
+// createChatMessage creates a ChatMessage from a history message
+func createChatMessage(msg message.Message) ChatMessage {
+	chatMessage := ChatMessage{Role: msg.Role, Name: msg.Name}
+	if content, ok := msg.Content.(string); ok {
+		chatMessage.Content = content
+	} else if parts, ok := model.ContentParts(msg.Content); ok {
+		chatMessage.Parts = convertContentParts(parts)
 	}
 
 	// Add tool_calls if provided (critical for OpenAI's message ordering requirements)
-	if toolCalls, ok := message["tool_calls"].([]map[string]interface{}); ok && len(toolCalls) > 0 {
-		chatMessage.ToolCalls = make([]ChatMessageToolCall, 0, len(toolCalls))
-		for _, tc := range toolCalls {
-			// Extract function details
-			function, ok := tc["function"].(map[string]interface{})
-			if !ok {
-				continue
-			}
-
-			// Create a tool call
-			toolCall := ChatMessageToolCall{
-				ID:   tc["id"].(string),
-				Type: tc["type"].(string),
-				Function: ChatMessageToolCallFunction{
-					Name:      function["name"].(string),
-					Arguments: function["arguments"].(string),
-				},
-			}
-			chatMessage.ToolCalls = append(chatMessage.ToolCalls, toolCall)
-		}
-	} else if toolCallsRaw, ok := message["tool_calls"].([]interface{}); ok && len(toolCallsRaw) > 0 {
-		// Handle case where tool_calls is a []interface{}
-		chatMessage.ToolCalls = make([]ChatMessageToolCall, 0, len(toolCallsRaw))
-		for _, tcRaw := range toolCallsRaw {
-			tc, ok := tcRaw.(map[string]interface{})
-			if !ok {
-				continue
-			}
-
-			// Extract function details
-			function, ok := tc["function"].(map[string]interface{})
-			if !ok {
-				continue
-			}
-
-			// Create a tool call
-			toolCall := ChatMessageToolCall{
-				ID:   tc["id"].(string),
-				Type: tc["type"].(string),
-				Function: ChatMessageToolCallFunction{
-					Name:      function["name"].(string),
-					Arguments: function["arguments"].(string),
-				},
-			}
-			chatMessage.ToolCalls = append(chatMessage.ToolCalls, toolCall)
-		}
+	for _, tc := range msg.ToolCalls {
+		chatMessage.ToolCalls = append(chatMessage.ToolCalls, ChatMessageToolCall{
+			ID:   tc.ID,
+			Type: "function",
+			Function: ChatMessageToolCallFunction{
+				Name:      tc.Name,
+				Arguments: tc.ArgumentsJSON(),
+			},
+		})
 	}
 
 	return chatMessage
 }
 
-// createToolResultMessage creates a tool result message from a map representation
-func createToolResultMessage(message map[string]interface{}) *ChatMessage {
-	// Extract tool result and tool call
-	toolResult, ok := message["tool_result"].(map[string]interface{})
-	if !ok || toolResult == nil {
-		// Skip invalid tool results
-		return nil
-	}
-
-	toolCall, ok := message["tool_call"].(map[string]interface{})
-	if !ok || toolCall == nil {
-		// Skip invalid tool calls
-		return nil
-	}
-
+// createToolResultMessage creates a tool result message from a history tool result
+func createToolResultMessage(result message.ToolResult) *ChatMessage {
 	// Get the tool call ID - this is critical for proper tool response handling
-	toolCallID, ok := toolCall["id"].(string)
-	if !ok || toolCallID == "" {
+	toolCallID := result.Call.ID
+	if toolCallID == "" {
 		// OpenAI requires tool_call_id for tool responses
 		// Generate a random ID if not provided
 		randomBytes := make([]byte, 16)
@@ -784,32 +874,11 @@ func createToolResultMessage(message map[string]interface{}) *ChatMessage {
 		toolCallID = fmt.Sprintf("call_%x", randomBytes)
 	}
 
-	// Extract content from the tool result
-	resultContent, ok := toolResult["content"]
-	if !ok {
-		resultContent = ""
-	}
-
-	// Convert result content to string if needed
-	var contentStr string
-	switch v := resultContent.(type) {
-	case string:
-		contentStr = v
-	default:
-		// For non-string content, try to convert to JSON
-		// If that fails, use fmt.Sprintf
-		if jsonBytes, err := json.Marshal(resultContent); err == nil {
-			contentStr = string(jsonBytes)
-		} else {
-			contentStr = fmt.Sprintf("%v", resultContent)
-		}
-	}
-
 	// Create a proper tool response message according to OpenAI's spec
 	// For OpenAI, a tool result message must have role="tool", tool_call_id matching the assistant's tool call ID
 	return &ChatMessage{
 		Role:       "tool",
-		Content:    contentStr,
+		Content:    model.ToolResultText(result.Content),
 		ToolCallID: toolCallID,
 	}
 }
@@ -901,9 +970,7 @@ func addHandoffToolsToRequest(chatRequest *ChatCompletionRequest, handoffs []int
 				}
 
 				chatRequest.Tools = append(chatRequest.Tools, chatTool)
-				if os.Getenv("OPENAI_DEBUG") == "1" {
-					fmt.Printf("Added handoff tool to request: %s\n", function["name"].(string))
-				}
+				logging.For(nil, "openai").Debug("Added handoff tool to request", "tool", function["name"])
 			}
 		}
 	}
@@ -944,10 +1011,57 @@ func applyModelSettings(chatRequest *ChatCompletionRequest, settings *model.Sett
 			}
 		}
 	}
+	if settings.ResponseFormat != nil {
+		chatRequest.ResponseFormat = convertResponseFormat(settings.ResponseFormat)
+	}
+	if settings.PromptCaching {
+		// Prompts are cached automatically; the key keeps an agent's requests together
+		chatRequest.PromptCacheKey = settings.PromptCacheKey
+	}
 	// Note: parallel_tool_calls is not directly supported in the OpenAI API request
 	// It's a client-side setting that affects how tool calls are processed
 }
 
+// convertResponseFormat converts a response format to its chat completions form
+func convertResponseFormat(format *model.ResponseFormat) *ChatResponseFormat {
+	if format.Type != model.ResponseFormatJSONSchema {
+		return &ChatResponseFormat{Type: format.Type}
+	}
+
+	name := format.Name
+	if name == "" {
+		name = "output"
+	}
+	return &ChatResponseFormat{
+		Type: model.ResponseFormatJSONSchema,
+		JSONSchema: &ChatJSONSchema{
+			Name:   name,
+			Schema: format.Schema,
+			Strict: format.Strict,
+		},
+	}
+}
+
+// SupportsJSONSchema reports whether the model accepts json_schema response
+// formats. The original GPT-4 and GPT-3.5 models only support json_object.
+func (m *Model) SupportsJSONSchema() bool {
+	name := strings.ToLower(m.ModelName)
+	return !strings.HasPrefix(name, "gpt-3.5") && name != "gpt-4" && !strings.HasPrefix(name, "gpt-4-")
+}
+
+// convertUsage converts the usage of a chat completion
+func convertUsage(u ChatCompletionUsage) *model.Usage {
+	usage := &model.Usage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+		TotalTokens:      u.TotalTokens,
+	}
+	if u.PromptTokensDetails != nil {
+		usage.CachedTokens = u.PromptTokensDetails.CachedTokens
+	}
+	return usage
+}
+
 // parseResponse parses a chat completion response into a model response
 func (m *Model) parseResponse(chatResponse *ChatCompletionResponse) (*model.Response, error) {
 	// Check if we have any choices
@@ -963,11 +1077,7 @@ func (m *Model) parseResponse(chatResponse *ChatCompletionResponse) (*model.Resp
 		Content:     choice.Message.Content,
 		ToolCalls:   make([]model.ToolCall, 0),
 		HandoffCall: nil,
-		Usage: &model.Usage{
-			PromptTokens:     chatResponse.Usage.PromptTokens,
-			CompletionTokens: chatResponse.Usage.CompletionTokens,
-			TotalTokens:      chatResponse.Usage.TotalTokens,
-		},
+		Usage:       convertUsage(chatResponse.Usage),
 	}
 
 	// Parse tool calls if any
@@ -987,10 +1097,10 @@ func (m *Model) parseResponse(chatResponse *ChatCompletionResponse) (*model.Resp
 			// or by using a tool name that matches an agent name
 			if strings.HasPrefix(strings.ToLower(toolCall.Function.Name), "handoff_to_") {
 				// Extract the agent name from the tool name
-				agentName := strings.TrimPrefix(toolCall.Function.Name, "handoff_to_")
+				agentName := toolCall.Function.Name[len("handoff_to_"):]
 				response.HandoffCall = &model.HandoffCall{
 					AgentName:      agentName,
-					Parameters:     map[string]interface{}{"input": args["input"].(string)},
+					Parameters:     map[string]interface{}{"input": model.HandoffInput(args)},
 					Type:           model.HandoffTypeDelegate,
 					ReturnToAgent:  "", // Will be set by the runner
 					TaskID:         "", // Will be generated by the runner if not provided
@@ -1105,18 +1215,35 @@ func (m *Model) handleError(response *http.Response) error {
 		return fmt.Errorf("failed to read error response: %w", err)
 	}
 
-	// Try to parse the error
+	// Try to parse the error, falling back to the status code
+	providerErr := &model.ErrProvider{Provider: "openai", Status: response.StatusCode, RequestID: requestID(response)}
 	var errorResponse ErrorResponse
-	if err := json.Unmarshal(body, &errorResponse); err == nil && errorResponse.Error.Message != "" {
-		return fmt.Errorf("API error (%s): %s", errorResponse.Error.Type, errorResponse.Error.Message)
+	if jsonErr := json.Unmarshal(body, &errorResponse); jsonErr == nil && errorResponse.Error.Message != "" {
+		providerErr.Code = errorResponse.Error.Code
+		if providerErr.Code == "" {
+			providerErr.Code = errorResponse.Error.Type
+		}
+		providerErr.Message = errorResponse.Error.Message
 	}
 
-	// Fallback to status code
-	return fmt.Errorf("API error: %s", response.Status)
+	// Rate limited requests carry the limit state for the retry
+	return model.ProviderError(providerErr, m.ModelName, response)
 }
 
-// isRateLimitError checks if an error is a rate limit error
+// requestID returns the request ID assigned by the API, checking the Azure header as a fallback
+func requestID(response *http.Response) string {
+	if id := response.Header.Get("x-request-id"); id != "" {
+		return id
+	}
+	return response.Header.Get("apim-request-id")
+}
+
+// isRateLimitError checks if an error is a rate limit error. Errors of rate limited
+// responses are typed; errors reported in a stream are recognized by their message.
 func isRateLimitError(err error) bool {
+	if ratelimit.IsRateLimited(err) {
+		return true
+	}
 	errStr := err.Error()
 	return strings.Contains(errStr, "rate limit") ||
 		strings.Contains(errStr, "Rate limit") ||
@@ -1125,6 +1252,13 @@ func isRateLimitError(err error) bool {
 		strings.Contains(errStr, "usage cap")
 }
 
+// retryAfterKnown reports whether the API said when a rate limited request can be
+// retried, in which case the rate limiter paces the retry
+func retryAfterKnown(err error) bool {
+	_, ok := ratelimit.RetryAfter(err)
+	return ok
+}
+
 // calculateBackoff calculates the backoff duration for retries
 func calculateBackoff(attempt int, baseDelay time.Duration) time.Duration {
 	// Calculate exponential backoff: baseDelay * 2^attempt
@@ -1151,3 +1285,23 @@ func removeArray[T any](arr []T, index int) []T {
 	}
 	return result
 }
+
+// Ensure Model implements model.MessageFormatter
+var _ model.MessageFormatter = (*Model)(nil)
+
+// FormatUserMessage formats user content as a history item
+func (m *Model) FormatUserMessage(content interface{}) interface{} {
+	return model.DefaultMessageFormatter{}.FormatUserMessage(content)
+}
+
+// FormatAssistantMessage formats a response as an assistant message with tool_calls,
+// which must precede the tool messages answering them
+func (m *Model) FormatAssistantMessage(response *model.Response) interface{} {
+	return model.DefaultMessageFormatter{}.FormatAssistantMessage(response)
+}
+
+// FormatToolResult formats a tool result. It becomes a message with role "tool"
+// and the result encoded as text.
+func (m *Model) FormatToolResult(call model.ToolCall, result interface{}) interface{} {
+	return model.DefaultMessageFormatter{}.FormatToolResult(call, result)
+}
@@ -0,0 +1,87 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+)
+
+// modelListResponse is the response of the models endpoint
+type modelListResponse struct {
+	Data []struct {
+		ID      string `json:"id"`
+		Created int64  `json:"created"`
+		OwnedBy string `json:"owned_by"`
+	} `json:"data"`
+}
+
+// ListModels returns the models available to the API key
+func (p *Provider) ListModels(ctx context.Context) ([]model.ModelInfo, error) {
+	p.mu.RLock()
+	url := fmt.Sprintf("%s/models", p.baseURL)
+	if isAzure(p.apiType) {
+		url = fmt.Sprintf("%s/openai/models?api-version=%s", strings.TrimRight(p.baseURL, "/"), p.apiVersion)
+	}
+	client := p.HTTPClient
+	p.mu.RUnlock()
+
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	m := &Model{Provider: p}
+	m.setHeader(httpRequest)
+
+	httpResponse, err := client.Do(httpRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer httpResponse.Body.Close()
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return nil, m.handleError(httpResponse)
+	}
+
+	var list modelListResponse
+	if err := json.NewDecoder(httpResponse.Body).Decode(&list); err != nil {
+		return nil, fmt.Errorf("failed to decode model list: %w", err)
+	}
+
+	models := make([]model.ModelInfo, 0, len(list.Data))
+	for _, entry := range list.Data {
+		info := model.ModelInfo{ID: entry.ID, OwnedBy: entry.OwnedBy}
+		if entry.Created > 0 {
+			info.CreatedAt = time.Unix(entry.Created, 0)
+		}
+		models = append(models, info)
+	}
+	return models, nil
+}
+
+// ValidateModel returns a *model.UnknownModelError if the model is not available.
+// The model list is fetched once and cached.
+func (p *Provider) ValidateModel(ctx context.Context, modelName string) error {
+	p.mu.Lock()
+	if p.validator == nil {
+		p.validator = model.NewModelValidator(p)
+	}
+	validator := p.validator
+	p.mu.Unlock()
+
+	return validator.Validate(ctx, modelName)
+}
+
+// WithModelValidation makes GetModel check model names against the model list, so
+// typos fail before the first request instead of as a 404 mid-run. Azure lists base
+// models rather than deployments, so leave validation off for Azure deployment names.
+func (p *Provider) WithModelValidation(enabled bool) *Provider {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.validateModels = enabled
+	return p
+}
@@ -0,0 +1,383 @@
+package openai
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/websocket"
+)
+
+// DefaultRealtimeModel is the model used for realtime sessions when none is requested
+const DefaultRealtimeModel = "gpt-4o-realtime-preview"
+
+// realtimeAudioMediaTypes maps realtime audio formats to their MIME types
+var realtimeAudioMediaTypes = map[string]string{
+	"pcm16":     "audio/pcm",
+	"g711_ulaw": "audio/basic",
+	"g711_alaw": "audio/x-alaw-basic",
+}
+
+// realtimeServerEvent is an event sent by the realtime API. Only the fields used
+// by the session are decoded.
+type realtimeServerEvent struct {
+	Type       string `json:"type"`
+	Delta      string `json:"delta"`
+	Transcript string `json:"transcript"`
+	CallID     string `json:"call_id"`
+	Name       string `json:"name"`
+	Arguments  string `json:"arguments"`
+	Response   *struct {
+		Status string `json:"status"`
+		Usage  *struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+			TotalTokens  int `json:"total_tokens"`
+		} `json:"usage"`
+	} `json:"response"`
+	Error *struct {
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// realtimeSession is a session with the OpenAI Realtime API
+type realtimeSession struct {
+	conn              *websocket.Conn
+	events            chan model.StreamEvent
+	done              chan struct{}
+	closeOnce         sync.Once
+	outputAudioFormat string
+	log               logging.Logger
+
+	// State of the current response, only used by the read loop
+	text      strings.Builder
+	toolCalls []model.ToolCall
+}
+
+// ConnectRealtime opens a session with the Realtime API over a WebSocket
+func (p *Provider) ConnectRealtime(ctx context.Context, config *model.RealtimeConfig) (model.RealtimeSession, error) {
+	if config == nil {
+		config = &model.RealtimeConfig{}
+	}
+	modelName := config.Model
+	if modelName == "" {
+		modelName = DefaultRealtimeModel
+	}
+
+	p.mu.RLock()
+	endpoint, err := p.realtimeURL(modelName)
+	p.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	// Reuse the authentication headers of regular requests
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create realtime request: %w", err)
+	}
+	m := &Model{ModelName: modelName, Provider: p}
+	m.setHeader(httpRequest)
+	httpRequest.Header.Del("Content-Type")
+	httpRequest.Header.Set("OpenAI-Beta", "realtime=v1")
+
+	conn, _, err := websocket.Dial(ctx, endpoint, httpRequest.Header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to realtime API: %w", err)
+	}
+
+	session := &realtimeSession{
+		conn:              conn,
+		events:            make(chan model.StreamEvent, 100),
+		done:              make(chan struct{}),
+		outputAudioFormat: config.OutputAudioFormat,
+		log:               p.log(),
+	}
+	if session.outputAudioFormat == "" {
+		session.outputAudioFormat = "pcm16"
+	}
+
+	if err := session.send(ctx, map[string]interface{}{
+		"type":    "session.update",
+		"session": realtimeSessionConfig(config),
+	}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to configure realtime session: %w", err)
+	}
+
+	go session.readLoop()
+	return session, nil
+}
+
+// realtimeURL returns the WebSocket URL of the Realtime API. The caller must hold p.mu.
+func (p *Provider) realtimeURL(modelName string) (string, error) {
+	var endpoint string
+	if isAzure(p.apiType) {
+		endpoint = fmt.Sprintf("%s/openai/realtime?api-version=%s&deployment=%s",
+			strings.TrimRight(p.baseURL, "/"), p.apiVersion, url.QueryEscape(modelName))
+	} else {
+		endpoint = fmt.Sprintf("%s/realtime?model=%s", strings.TrimRight(p.baseURL, "/"), url.QueryEscape(modelName))
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid realtime URL: %w", err)
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	case "http":
+		u.Scheme = "ws"
+	}
+	return u.String(), nil
+}
+
+// realtimeSessionConfig converts the session config to a session.update payload
+func realtimeSessionConfig(config *model.RealtimeConfig) map[string]interface{} {
+	session := map[string]interface{}{}
+	if config.Instructions != "" {
+		session["instructions"] = config.Instructions
+	}
+	if config.Voice != "" {
+		session["voice"] = config.Voice
+	}
+	if len(config.Modalities) > 0 {
+		session["modalities"] = config.Modalities
+	}
+	if config.InputAudioFormat != "" {
+		session["input_audio_format"] = config.InputAudioFormat
+	}
+	if config.OutputAudioFormat != "" {
+		session["output_audio_format"] = config.OutputAudioFormat
+	}
+	switch config.TurnDetection {
+	case "":
+	case "none":
+		session["turn_detection"] = nil
+	default:
+		session["turn_detection"] = map[string]interface{}{"type": config.TurnDetection}
+	}
+	if config.Settings != nil && config.Settings.Temperature != nil {
+		session["temperature"] = *config.Settings.Temperature
+	}
+
+	if len(config.Tools) > 0 {
+		tools := make([]map[string]interface{}, 0, len(config.Tools))
+		for _, t := range config.Tools {
+			if realtimeTool := convertRealtimeTool(t); realtimeTool != nil {
+				tools = append(tools, realtimeTool)
+			}
+		}
+		session["tools"] = tools
+		session["tool_choice"] = "auto"
+	}
+	return session
+}
+
+// convertRealtimeTool flattens a chat completions tool definition into the
+// format of the Realtime API
+func convertRealtimeTool(t interface{}) map[string]interface{} {
+	definition, ok := t.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	function, ok := definition["function"].(map[string]interface{})
+	if !ok {
+		// Already in the realtime format
+		return definition
+	}
+
+	realtimeTool := map[string]interface{}{
+		"type":        "function",
+		"name":        function["name"],
+		"description": function["description"],
+	}
+	if parameters, ok := function["parameters"]; ok {
+		realtimeTool["parameters"] = parameters
+	}
+	return realtimeTool
+}
+
+// SendText adds a user text message to the conversation
+func (s *realtimeSession) SendText(ctx context.Context, text string) error {
+	return s.send(ctx, map[string]interface{}{
+		"type": "conversation.item.create",
+		"item": map[string]interface{}{
+			"type": "message",
+			"role": "user",
+			"content": []map[string]interface{}{
+				{"type": "input_text", "text": text},
+			},
+		},
+	})
+}
+
+// SendAudio appends audio to the input buffer
+func (s *realtimeSession) SendAudio(ctx context.Context, audio []byte) error {
+	return s.send(ctx, map[string]interface{}{
+		"type":  "input_audio_buffer.append",
+		"audio": base64.StdEncoding.EncodeToString(audio),
+	})
+}
+
+// CommitAudio ends the current spoken turn
+func (s *realtimeSession) CommitAudio(ctx context.Context) error {
+	return s.send(ctx, map[string]interface{}{"type": "input_audio_buffer.commit"})
+}
+
+// SendToolResult adds the output of a tool call to the conversation
+func (s *realtimeSession) SendToolResult(ctx context.Context, callID string, output string) error {
+	return s.send(ctx, map[string]interface{}{
+		"type": "conversation.item.create",
+		"item": map[string]interface{}{
+			"type":    "function_call_output",
+			"call_id": callID,
+			"output":  output,
+		},
+	})
+}
+
+// CreateResponse asks the model to respond to the conversation so far
+func (s *realtimeSession) CreateResponse(ctx context.Context) error {
+	return s.send(ctx, map[string]interface{}{"type": "response.create"})
+}
+
+// Events returns the events of the session
+func (s *realtimeSession) Events() <-chan model.StreamEvent {
+	return s.events
+}
+
+// Close ends the session
+func (s *realtimeSession) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.done)
+		err = s.conn.Close()
+	})
+	return err
+}
+
+// send writes a client event to the connection
+func (s *realtimeSession) send(ctx context.Context, event map[string]interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case <-s.done:
+		return errors.New("realtime session is closed")
+	default:
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode realtime event: %w", err)
+	}
+	if err := s.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		return fmt.Errorf("failed to send realtime event: %w", err)
+	}
+	return nil
+}
+
+// readLoop converts server events to stream events until the connection closes
+func (s *realtimeSession) readLoop() {
+	defer close(s.events)
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			var closeErr *websocket.CloseError
+			select {
+			case <-s.done:
+			default:
+				if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseNormal {
+					s.emit(model.StreamEvent{Type: model.StreamEventTypeError, Error: fmt.Errorf("realtime connection failed: %w", err)})
+				}
+				s.Close()
+			}
+			return
+		}
+
+		var event realtimeServerEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			s.log.Debug("Skipping undecodable realtime event", "error", err)
+			continue
+		}
+		s.handleEvent(&event)
+	}
+}
+
+// handleEvent converts a server event to stream events
+func (s *realtimeSession) handleEvent(event *realtimeServerEvent) {
+	switch event.Type {
+	case "response.text.delta", "response.audio_transcript.delta",
+		"response.output_text.delta", "response.output_audio_transcript.delta":
+		s.text.WriteString(event.Delta)
+		s.emit(model.StreamEvent{Type: model.StreamEventTypeContent, Content: event.Delta})
+
+	case "response.audio.delta", "response.output_audio.delta":
+		audio, err := base64.StdEncoding.DecodeString(event.Delta)
+		if err != nil {
+			s.emit(model.StreamEvent{Type: model.StreamEventTypeError, Error: fmt.Errorf("invalid realtime audio: %w", err)})
+			return
+		}
+		s.emit(model.StreamEvent{
+			Type: model.StreamEventTypeAudio,
+			Media: &model.MediaPart{
+				Type:      model.ContentTypeAudio,
+				MediaType: realtimeAudioMediaTypes[s.outputAudioFormat],
+				Data:      audio,
+			},
+		})
+
+	case "conversation.item.input_audio_transcription.completed":
+		s.emit(model.StreamEvent{Type: model.StreamEventTypeTranscript, Content: event.Transcript})
+
+	case "response.function_call_arguments.done":
+		toolCall := model.ToolCall{ID: event.CallID, Name: event.Name, Parameters: map[string]interface{}{}}
+		if event.Arguments != "" {
+			if err := json.Unmarshal([]byte(event.Arguments), &toolCall.Parameters); err != nil {
+				s.emit(model.StreamEvent{Type: model.StreamEventTypeError, Error: fmt.Errorf("invalid arguments for tool %s: %w", event.Name, err)})
+				return
+			}
+		}
+		s.toolCalls = append(s.toolCalls, toolCall)
+		s.emit(model.StreamEvent{Type: model.StreamEventTypeToolCall, ToolCall: &toolCall})
+
+	case "response.done":
+		response := &model.Response{Content: s.text.String(), ToolCalls: s.toolCalls}
+		if event.Response != nil && event.Response.Usage != nil {
+			response.Usage = &model.Usage{
+				PromptTokens:     event.Response.Usage.InputTokens,
+				CompletionTokens: event.Response.Usage.OutputTokens,
+				TotalTokens:      event.Response.Usage.TotalTokens,
+			}
+		}
+		s.text.Reset()
+		s.toolCalls = nil
+		s.emit(model.StreamEvent{Type: model.StreamEventTypeDone, Done: true, Response: response})
+
+	case "error":
+		message := "unknown error"
+		if event.Error != nil {
+			message = event.Error.Message
+		}
+		s.emit(model.StreamEvent{Type: model.StreamEventTypeError, Error: fmt.Errorf("realtime API error: %s", message)})
+	}
+}
+
+// emit sends a stream event unless the session was closed
+func (s *realtimeSession) emit(event model.StreamEvent) {
+	select {
+	case s.events <- event:
+	case <-s.done:
+	}
+}
@@ -0,0 +1,65 @@
+package model
+
+import "context"
+
+// RealtimeConfig configures a realtime session
+type RealtimeConfig struct {
+	// Model is the realtime model; providers use their default when empty
+	Model string
+
+	// Instructions are the system instructions of the session
+	Instructions string
+
+	// Tools are the tool definitions, in the same format as Request.Tools
+	Tools []interface{}
+
+	// Voice is the provider's voice name for audio output
+	Voice string
+
+	// Modalities are the output modalities, such as text and audio
+	Modalities []string
+
+	// InputAudioFormat and OutputAudioFormat are the audio encodings, such as pcm16
+	InputAudioFormat  string
+	OutputAudioFormat string
+
+	// TurnDetection selects how the end of a spoken turn is detected. Empty keeps the
+	// provider default and "none" disables it, so audio must be committed explicitly.
+	TurnDetection string
+
+	// Settings holds the sampling settings of the session
+	Settings *Settings
+}
+
+// RealtimeSession is a live, bidirectional conversation with a realtime model.
+// Output arrives on Events as content, audio, tool call and done events; a done
+// event ends each model response.
+type RealtimeSession interface {
+	// SendText adds a user text message to the conversation
+	SendText(ctx context.Context, text string) error
+
+	// SendAudio appends audio to the input buffer
+	SendAudio(ctx context.Context, audio []byte) error
+
+	// CommitAudio ends the current spoken turn
+	CommitAudio(ctx context.Context) error
+
+	// SendToolResult adds the output of a tool call to the conversation
+	SendToolResult(ctx context.Context, callID string, output string) error
+
+	// CreateResponse asks the model to respond to the conversation so far
+	CreateResponse(ctx context.Context) error
+
+	// Events returns the events of the session. The channel is closed when the
+	// session ends.
+	Events() <-chan StreamEvent
+
+	// Close ends the session
+	Close() error
+}
+
+// RealtimeProvider is implemented by providers that support realtime sessions
+type RealtimeProvider interface {
+	// ConnectRealtime opens a realtime session
+	ConnectRealtime(ctx context.Context, config *RealtimeConfig) (RealtimeSession, error)
+}
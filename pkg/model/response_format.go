@@ -0,0 +1,141 @@
+package model
+
+import "sort"
+
+// Response format types
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// ResponseFormat constrains the output of a model to JSON
+type ResponseFormat struct {
+	// Type is ResponseFormatText, ResponseFormatJSONObject or ResponseFormatJSONSchema
+	Type string
+
+	// Name identifies the schema of a json_schema format
+	Name string
+
+	// Schema is the JSON schema of a json_schema format
+	Schema map[string]interface{}
+
+	// Strict asks the provider to guarantee that the output matches the schema
+	Strict bool
+}
+
+// JSONObjectFormat asks for any valid JSON object
+func JSONObjectFormat() *ResponseFormat {
+	return &ResponseFormat{Type: ResponseFormatJSONObject}
+}
+
+// JSONSchemaFormat asks for output matching a JSON schema. With strict set the
+// schema is converted with StrictJSONSchema; if it cannot be made strict, the
+// format falls back to best-effort schema output.
+func JSONSchemaFormat(name string, schema map[string]interface{}, strict bool) *ResponseFormat {
+	format := &ResponseFormat{Type: ResponseFormatJSONSchema, Name: name, Schema: schema}
+	if strict {
+		if strictSchema, ok := StrictJSONSchema(schema); ok {
+			format.Schema = strictSchema
+			format.Strict = true
+		}
+	}
+	return format
+}
+
+// SchemaOutputModel is implemented by models that can constrain their output to a
+// JSON schema. The runner requests schema output for agents with an output type
+// when the model supports it.
+type SchemaOutputModel interface {
+	// SupportsJSONSchema reports whether the model accepts json_schema response formats
+	SupportsJSONSchema() bool
+}
+
+// StrictJSONSchema converts a schema to the subset accepted by strict structured
+// output: every object lists all its properties as required and forbids others,
+// and properties that were optional become nullable. It returns false if the
+// schema has objects with free-form keys, which strict mode cannot express.
+func StrictJSONSchema(schema map[string]interface{}) (map[string]interface{}, bool) {
+	if schema == nil {
+		return nil, false
+	}
+	return strictSchema(schema)
+}
+
+// strictSchema converts one level of a schema
+func strictSchema(schema map[string]interface{}) (map[string]interface{}, bool) {
+	converted := make(map[string]interface{}, len(schema)+1)
+	for key, value := range schema {
+		converted[key] = value
+	}
+
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		strictItems, ok := strictSchema(items)
+		if !ok {
+			return nil, false
+		}
+		converted["items"] = strictItems
+	}
+
+	if schema["type"] != "object" {
+		return converted, true
+	}
+	if additional, ok := schema["additionalProperties"]; ok && additional != false {
+		return nil, false
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	required := make(map[string]bool)
+	switch names := schema["required"].(type) {
+	case []string:
+		for _, name := range names {
+			required[name] = true
+		}
+	case []interface{}:
+		for _, name := range names {
+			if s, ok := name.(string); ok {
+				required[s] = true
+			}
+		}
+	}
+
+	strictProperties := make(map[string]interface{}, len(properties))
+	allRequired := make([]string, 0, len(properties))
+	for name, property := range properties {
+		propertySchema, ok := property.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		strictProperty, ok := strictSchema(propertySchema)
+		if !ok {
+			return nil, false
+		}
+		if !required[name] {
+			strictProperty = nullable(strictProperty)
+		}
+		strictProperties[name] = strictProperty
+		allRequired = append(allRequired, name)
+	}
+	sort.Strings(allRequired)
+
+	converted["properties"] = strictProperties
+	converted["required"] = allRequired
+	converted["additionalProperties"] = false
+	return converted, true
+}
+
+// nullable allows null in addition to the type of a schema
+func nullable(schema map[string]interface{}) map[string]interface{} {
+	switch t := schema["type"].(type) {
+	case string:
+		schema["type"] = []interface{}{t, "null"}
+	case []interface{}:
+		for _, existing := range t {
+			if existing == "null" {
+				return schema
+			}
+		}
+		schema["type"] = append(append([]interface{}(nil), t...), "null")
+	}
+	return schema
+}
@@ -0,0 +1,387 @@
+// Package testutil records the HTTP exchanges of model providers to golden files
+// and replays them, so tests can check how providers build requests without
+// live API keys.
+//
+// Record a cassette once against the real API:
+//
+//	AGENT_SDK_RECORD=1 OPENAI_API_KEY=... go test ./...
+//
+// and commit it; later runs replay it and fail if the provider sends a request
+// that does not match the recording:
+//
+//	recorder := testutil.Start(t, "testdata/openai_tool_call.json")
+//	provider := openai.NewProvider(os.Getenv("OPENAI_API_KEY")).WithHTTPClient(recorder.Client())
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// RecordEnvVar is the environment variable that switches ModeFromEnv to recording
+const RecordEnvVar = "AGENT_SDK_RECORD"
+
+// Redacted replaces scrubbed secrets in recordings
+const Redacted = "REDACTED"
+
+// Mode controls whether a Recorder records or replays
+type Mode int
+
+const (
+	// ModeReplay serves responses from the cassette and never touches the network
+	ModeReplay Mode = iota
+
+	// ModeRecord sends requests to the network and saves the exchanges
+	ModeRecord
+)
+
+// ErrNoInteraction is returned when a replayed request matches no recording
+var ErrNoInteraction = errors.New("no recorded interaction matches request")
+
+// SecretHeaders are the headers scrubbed from recordings by default
+var SecretHeaders = []string{
+	"Authorization",
+	"Api-Key",
+	"X-Api-Key",
+	"X-Goog-Api-Key",
+	"OpenAI-Organization",
+	"Cookie",
+	"Set-Cookie",
+}
+
+// SecretQueryParams are the query parameters scrubbed from recordings by default
+var SecretQueryParams = []string{"key", "api_key", "api-key", "access_token"}
+
+// ModeFromEnv returns ModeRecord if RecordEnvVar is set to 1, and ModeReplay otherwise
+func ModeFromEnv() Mode {
+	if os.Getenv(RecordEnvVar) == "1" {
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+// Cassette is the golden file of a recording
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and its response
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a recorded HTTP request
+type RecordedRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is a recorded HTTP response
+type RecordedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body"`
+}
+
+// MatcherFunc reports whether a request matches a recorded request. The request
+// has already been scrubbed.
+type MatcherFunc func(request RecordedRequest, recorded RecordedRequest) bool
+
+// ScrubberFunc removes secrets from an interaction before it is saved
+type ScrubberFunc func(*Interaction)
+
+// Recorder is an http.RoundTripper that records or replays a cassette
+type Recorder struct {
+	path      string
+	mode      Mode
+	transport http.RoundTripper
+	matcher   MatcherFunc
+	scrubbers []ScrubberFunc
+
+	cassette Cassette
+	used     []bool
+	mu       sync.Mutex
+}
+
+// NewRecorder creates a recorder for the cassette at path. In replay mode the
+// cassette must exist.
+func NewRecorder(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{
+		path:      path,
+		mode:      mode,
+		transport: http.DefaultTransport,
+		matcher:   DefaultMatcher,
+		scrubbers: []ScrubberFunc{scrubSecrets},
+	}
+
+	if mode == ModeReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cassette (record it with %s=1): %w", RecordEnvVar, err)
+		}
+		if err := json.Unmarshal(data, &r.cassette); err != nil {
+			return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	}
+
+	return r, nil
+}
+
+// Start creates a recorder for a test in the mode given by ModeFromEnv and saves the
+// cassette when the test finishes
+func Start(t testing.TB, path string) *Recorder {
+	t.Helper()
+
+	r, err := NewRecorder(path, ModeFromEnv())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := r.Stop(); err != nil {
+			t.Error(err)
+		}
+	})
+	return r
+}
+
+// WithTransport sets the transport used to reach the network when recording
+func (r *Recorder) WithTransport(transport http.RoundTripper) *Recorder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.transport = transport
+	return r
+}
+
+// WithMatcher sets how replayed requests are matched to recordings
+func (r *Recorder) WithMatcher(matcher MatcherFunc) *Recorder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.matcher = matcher
+	return r
+}
+
+// WithScrubber adds a function that removes secrets from interactions, in addition
+// to the default scrubbing of SecretHeaders and SecretQueryParams
+func (r *Recorder) WithScrubber(scrubber ScrubberFunc) *Recorder {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.scrubbers = append(r.scrubbers, scrubber)
+	return r
+}
+
+// Mode returns the recorder's mode
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Client returns an HTTP client using the recorder
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Interactions returns the interactions recorded or loaded so far
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.cassette.Interactions...)
+}
+
+// RoundTrip records or replays a request
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+	}
+
+	interaction := Interaction{Request: RecordedRequest{
+		Method:  req.Method,
+		URL:     req.URL.String(),
+		Headers: req.Header.Clone(),
+		Body:    string(body),
+	}}
+
+	if r.mode == ModeReplay {
+		return r.replay(req, interaction)
+	}
+	return r.record(req, body, interaction)
+}
+
+// replay serves the first unused recording matching the request
+func (r *Recorder) replay(req *http.Request, interaction Interaction) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.scrub(&interaction)
+	for i, recorded := range r.cassette.Interactions {
+		if r.used[i] || !r.matcher(interaction.Request, recorded.Request) {
+			continue
+		}
+		r.used[i] = true
+		return recorded.Response.toHTTP(req), nil
+	}
+	return nil, fmt.Errorf("%w: %s %s\n%s", ErrNoInteraction, interaction.Request.Method, interaction.Request.URL, interaction.Request.Body)
+}
+
+// record forwards the request and saves the exchange
+func (r *Recorder) record(req *http.Request, body []byte, interaction Interaction) (*http.Response, error) {
+	r.mu.Lock()
+	transport := r.transport
+	r.mu.Unlock()
+
+	forwarded := req.Clone(req.Context())
+	forwarded.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := transport.RoundTrip(forwarded)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	interaction.Response = RecordedResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Header.Clone(),
+		Body:       string(respBody),
+	}
+
+	r.mu.Lock()
+	saved := interaction
+	saved.Request.Headers = interaction.Request.Headers.Clone()
+	saved.Response.Headers = interaction.Response.Headers.Clone()
+	r.scrub(&saved)
+	r.cassette.Interactions = append(r.cassette.Interactions, saved)
+	r.mu.Unlock()
+
+	return interaction.Response.toHTTP(req), nil
+}
+
+// Stop saves the cassette when recording. In replay mode it fails if recorded
+// interactions were not used, which means the provider sent fewer requests than
+// when the cassette was recorded.
+func (r *Recorder) Stop() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.mode == ModeReplay {
+		unused := 0
+		for _, used := range r.used {
+			if !used {
+				unused++
+			}
+		}
+		if unused > 0 {
+			return fmt.Errorf("%d recorded interactions of %s were not replayed", unused, r.path)
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(r.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+// scrub runs the scrubbers on an interaction
+func (r *Recorder) scrub(interaction *Interaction) {
+	for _, scrubber := range r.scrubbers {
+		scrubber(interaction)
+	}
+}
+
+// DefaultMatcher matches requests by method, URL and body, comparing JSON bodies
+// independently of key order and formatting
+func DefaultMatcher(request RecordedRequest, recorded RecordedRequest) bool {
+	return request.Method == recorded.Method &&
+		request.URL == recorded.URL &&
+		normalizeBody(request.Body) == normalizeBody(recorded.Body)
+}
+
+// normalizeBody re-encodes JSON bodies so equivalent documents compare equal
+func normalizeBody(body string) string {
+	var decoded interface{}
+	if err := json.Unmarshal([]byte(body), &decoded); err != nil {
+		return body
+	}
+	normalized, err := json.Marshal(decoded)
+	if err != nil {
+		return body
+	}
+	return string(normalized)
+}
+
+// scrubSecrets redacts SecretHeaders and SecretQueryParams
+func scrubSecrets(interaction *Interaction) {
+	for _, headers := range []http.Header{interaction.Request.Headers, interaction.Response.Headers} {
+		for _, name := range SecretHeaders {
+			if headers.Get(name) != "" {
+				headers.Set(name, Redacted)
+			}
+		}
+	}
+
+	parsed, err := url.Parse(interaction.Request.URL)
+	if err != nil {
+		return
+	}
+	query := parsed.Query()
+	changed := false
+	for key := range query {
+		for _, secret := range SecretQueryParams {
+			if strings.EqualFold(key, secret) {
+				query.Set(key, Redacted)
+				changed = true
+			}
+		}
+	}
+	if changed {
+		parsed.RawQuery = query.Encode()
+		interaction.Request.URL = parsed.String()
+	}
+}
+
+// toHTTP builds a response for a request from a recording
+func (r RecordedResponse) toHTTP(req *http.Request) *http.Response {
+	header := r.Headers.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
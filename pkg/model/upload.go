@@ -0,0 +1,99 @@
+package model
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// UploadOptions control how a provider sends request bodies. Both options help
+// with very large prompts: compression shrinks the upload, and streaming avoids
+// holding the encoded body in memory.
+type UploadOptions struct {
+	// Gzip compresses the body and sets Content-Encoding: gzip. Only enable it for
+	// servers that accept compressed requests.
+	Gzip bool
+
+	// Stream encodes the body while it is sent, using chunked transfer encoding,
+	// instead of encoding it into a buffer first
+	Stream bool
+}
+
+// NewJSONRequest creates an HTTP request whose body is the payload encoded as JSON
+func NewJSONRequest(ctx context.Context, method, url string, payload interface{}, opts UploadOptions) (*http.Request, error) {
+	if !opts.Stream {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		if opts.Gzip {
+			var compressed bytes.Buffer
+			zw := gzip.NewWriter(&compressed)
+			if _, err := zw.Write(body); err != nil {
+				return nil, fmt.Errorf("failed to compress request: %w", err)
+			}
+			if err := zw.Close(); err != nil {
+				return nil, fmt.Errorf("failed to compress request: %w", err)
+			}
+			body = compressed.Bytes()
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		}
+		setUploadHeaders(req, opts)
+		return req, nil
+	}
+
+	// Make sure the payload can be encoded before the request is sent, since
+	// errors in the encoding goroutine only surface as a broken upload
+	if err := json.NewEncoder(io.Discard).Encode(payload); err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	getBody := func() (io.ReadCloser, error) {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.CloseWithError(encodeJSON(pw, payload, opts.Gzip))
+		}()
+		return pr, nil
+	}
+
+	body, _ := getBody()
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		body.Close()
+		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.ContentLength = -1
+	req.GetBody = getBody
+	setUploadHeaders(req, opts)
+	return req, nil
+}
+
+// encodeJSON writes the payload as JSON, compressed if requested
+func encodeJSON(w io.Writer, payload interface{}, compress bool) error {
+	if !compress {
+		return json.NewEncoder(w).Encode(payload)
+	}
+
+	zw := gzip.NewWriter(w)
+	if err := json.NewEncoder(zw).Encode(payload); err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
+
+// setUploadHeaders sets the content headers of a JSON request
+func setUploadHeaders(req *http.Request, opts UploadOptions) {
+	req.Header.Set("Content-Type", "application/json")
+	if opts.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+}
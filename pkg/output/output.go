@@ -0,0 +1,225 @@
+// Package output post-processes the final text output of agents. Models wrap
+// answers in Markdown code fences, XML tags and commentary inconsistently; the
+// processors of this package normalize the output once, in the runner, so that
+// consumers receive what they asked for.
+//
+//	a := agent.NewAgent("Coder").WithOutputProcessors(
+//		output.TrimXMLWrapper("answer"),
+//		output.ExtractCodeBlock("typescript"),
+//	)
+package output
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrNoCodeBlock is returned by ExtractCodeBlock when the output has fenced blocks
+// but none in the expected language
+var ErrNoCodeBlock = errors.New("no matching code block in output")
+
+// Processor transforms the final text output of an agent
+type Processor func(ctx context.Context, text string) (string, error)
+
+// Apply runs processors in order, each on the result of the previous one
+func Apply(ctx context.Context, text string, processors ...Processor) (string, error) {
+	for _, process := range processors {
+		var err error
+		if text, err = process(ctx, text); err != nil {
+			return "", err
+		}
+	}
+	return text, nil
+}
+
+// CodeBlock is a fenced code block of a Markdown text
+type CodeBlock struct {
+	// Language is the info string of the opening fence in lower case, such as "go"
+	Language string
+
+	// Code is the content between the fences
+	Code string
+
+	// Closed is false for a block the model did not close before the output ended
+	Closed bool
+
+	// Start and End are the byte offsets of the block, including its fences
+	Start, End int
+}
+
+// languageAliases maps common spellings of fence languages to one name
+var languageAliases = map[string]string{
+	"ts":     "typescript",
+	"js":     "javascript",
+	"py":     "python",
+	"golang": "go",
+	"sh":     "bash",
+	"shell":  "bash",
+	"yml":    "yaml",
+	"c++":    "cpp",
+	"cs":     "csharp",
+	"c#":     "csharp",
+	"rb":     "ruby",
+	"rs":     "rust",
+	"md":     "markdown",
+	"jsonc":  "json",
+}
+
+// NormalizeLanguage lower-cases a fence language and resolves common aliases
+func NormalizeLanguage(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if alias, ok := languageAliases[language]; ok {
+		return alias
+	}
+	return language
+}
+
+// fenceLine matches an opening or closing fence of backticks or tildes
+var fenceLine = regexp.MustCompile("^[ \t]{0,3}(`{3,}|~{3,})[ \t]*([^`\\s]*)[^\n]*$")
+
+// CodeBlocks returns the fenced code blocks of a text in order
+func CodeBlocks(text string) []CodeBlock {
+	var blocks []CodeBlock
+	var current *CodeBlock
+	var fence string
+	var code strings.Builder
+
+	offset := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimRight(line, "\r\n")
+		match := fenceLine.FindStringSubmatch(trimmed)
+
+		switch {
+		case current == nil && match != nil:
+			current = &CodeBlock{Language: NormalizeLanguage(match[2]), Start: offset}
+			fence = match[1]
+			code.Reset()
+		case current != nil && match != nil && match[2] == "" &&
+			match[1][0] == fence[0] && len(match[1]) >= len(fence):
+			current.Code = strings.TrimSuffix(code.String(), "\n")
+			current.Closed = true
+			current.End = offset + len(strings.TrimRight(line, "\n"))
+			blocks = append(blocks, *current)
+			current = nil
+		case current != nil:
+			code.WriteString(strings.TrimRight(line, "\r\n"))
+			code.WriteString("\n")
+		}
+		offset += len(line)
+	}
+
+	// A block the model did not close runs to the end of the output
+	if current != nil {
+		current.Code = strings.TrimSuffix(code.String(), "\n")
+		current.End = len(text)
+		blocks = append(blocks, *current)
+	}
+	return blocks
+}
+
+// rewriteBlocks replaces every fenced block of a text with the result of fn
+func rewriteBlocks(text string, fn func(block CodeBlock) string) string {
+	blocks := CodeBlocks(text)
+	if len(blocks) == 0 {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, block := range blocks {
+		b.WriteString(text[last:block.Start])
+		b.WriteString(fn(block))
+		last = block.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// StripFences removes the fences of code blocks, keeping their code
+func StripFences() Processor {
+	return func(ctx context.Context, text string) (string, error) {
+		return strings.TrimSpace(rewriteBlocks(text, func(block CodeBlock) string {
+			return block.Code
+		})), nil
+	}
+}
+
+// NormalizeFences rewrites code blocks with three backticks and a normalized
+// language, and closes blocks the model left open
+func NormalizeFences() Processor {
+	return func(ctx context.Context, text string) (string, error) {
+		return rewriteBlocks(text, func(block CodeBlock) string {
+			fence := "```"
+			// Code that contains a backtick fence needs a longer one
+			for strings.Contains(block.Code, fence) {
+				fence += "`"
+			}
+			return fence + block.Language + "\n" + block.Code + "\n" + fence
+		}), nil
+	}
+}
+
+// ExtractCodeBlock replaces the output with the code of its first fenced block in
+// one of the given languages, or in any language when none are given. Output
+// without any fenced block is taken to be the code itself. Output with blocks in
+// other languages only fails with ErrNoCodeBlock.
+func ExtractCodeBlock(languages ...string) Processor {
+	wanted := make(map[string]bool, len(languages))
+	for _, language := range languages {
+		wanted[NormalizeLanguage(language)] = true
+	}
+
+	return func(ctx context.Context, text string) (string, error) {
+		blocks := CodeBlocks(text)
+		if len(blocks) == 0 {
+			return strings.TrimSpace(text), nil
+		}
+		for _, block := range blocks {
+			if len(wanted) == 0 || wanted[block.Language] {
+				return block.Code, nil
+			}
+		}
+		return "", fmt.Errorf("%w: expected %s, found %d other blocks", ErrNoCodeBlock, strings.Join(languages, " or "), len(blocks))
+	}
+}
+
+// xmlWrapper matches output that is entirely wrapped in one XML element
+var xmlWrapper = regexp.MustCompile(`(?s)^<([A-Za-z][\w.-]*)(\s[^>]*)?>(.*)</([A-Za-z][\w.-]*)\s*>$`)
+
+// TrimXMLWrapper removes an XML element wrapping the whole output, such as
+// <answer>...</answer>, keeping its content. Only the given tags are removed, or
+// any tag when none are given. Nested wrappers are removed one after another.
+func TrimXMLWrapper(tags ...string) Processor {
+	allowed := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		allowed[strings.ToLower(tag)] = true
+	}
+
+	return func(ctx context.Context, text string) (string, error) {
+		text = strings.TrimSpace(text)
+		for {
+			match := xmlWrapper.FindStringSubmatch(text)
+			if match == nil || !strings.EqualFold(match[1], match[4]) {
+				return text, nil
+			}
+			// <a>x</a> <a>y</a> is two elements, not one wrapper
+			if strings.Contains(strings.ToLower(match[3]), "</"+strings.ToLower(match[1])) {
+				return text, nil
+			}
+			if len(allowed) > 0 && !allowed[strings.ToLower(match[1])] {
+				return text, nil
+			}
+			text = strings.TrimSpace(match[3])
+		}
+	}
+}
+
+// TrimSpace removes leading and trailing white space from the output
+func TrimSpace() Processor {
+	return func(ctx context.Context, text string) (string, error) {
+		return strings.TrimSpace(text), nil
+	}
+}
@@ -0,0 +1,117 @@
+// Package patterns contains reusable multi-agent orchestration patterns built on top of the runner
+package patterns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+)
+
+// Answer is the answer of a single agent taking part in a consensus
+type Answer struct {
+	// AgentName is the name of the agent that answered
+	AgentName string
+
+	// Output is the final output of the agent as text
+	Output string
+
+	// Error is set if the agent failed to answer
+	Error error
+}
+
+// ConsensusResult is the outcome of a consensus
+type ConsensusResult struct {
+	// Winner is the winning answer
+	Winner string
+
+	// WinningAgents are the agents that gave the winning answer
+	WinningAgents []string
+
+	// Distribution maps each distinct answer to its share of votes or score
+	Distribution map[string]float64
+
+	// Answers are the individual answers in agent order
+	Answers []Answer
+}
+
+// Strategy aggregates the answers of several agents into a consensus
+type Strategy interface {
+	// Aggregate picks the winning answer. Failed answers are included with their error set.
+	Aggregate(ctx context.Context, r *runner.Runner, opts *runner.RunOptions, answers []Answer) (*ConsensusResult, error)
+}
+
+// ConsensusGroup runs the same input through several agents and aggregates their answers
+type ConsensusGroup struct {
+	agents   []*agent.Agent
+	strategy Strategy
+}
+
+// Consensus creates a group that aggregates the answers of the given agents with a strategy
+func Consensus(agents []*agent.Agent, strategy Strategy) *ConsensusGroup {
+	if strategy == nil {
+		strategy = MajorityVote(nil)
+	}
+	return &ConsensusGroup{
+		agents:   agents,
+		strategy: strategy,
+	}
+}
+
+// Run runs every agent concurrently with the given options and aggregates their answers
+func (g *ConsensusGroup) Run(ctx context.Context, r *runner.Runner, opts *runner.RunOptions) (*ConsensusResult, error) {
+	if len(g.agents) == 0 {
+		return nil, errors.New("consensus requires at least one agent")
+	}
+	if opts == nil {
+		opts = &runner.RunOptions{}
+	}
+
+	answers := make([]Answer, len(g.agents))
+	var wg sync.WaitGroup
+	for i, a := range g.agents {
+		wg.Add(1)
+		go func(i int, a *agent.Agent) {
+			defer wg.Done()
+
+			answers[i] = Answer{AgentName: a.Name}
+			runResult, err := r.Run(ctx, a, copyRunOptions(opts))
+			if err != nil {
+				answers[i].Error = err
+				return
+			}
+			answers[i].Output = fmt.Sprintf("%v", runResult.FinalOutput)
+		}(i, a)
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, answer := range answers {
+		if answer.Error != nil {
+			failed++
+		}
+	}
+	if failed == len(answers) {
+		return nil, fmt.Errorf("all agents failed: %w", answers[0].Error)
+	}
+
+	consensus, err := g.strategy.Aggregate(ctx, r, opts, answers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate answers: %w", err)
+	}
+	consensus.Answers = answers
+	return consensus, nil
+}
+
+// copyRunOptions copies run options so concurrent runs don't share mutable state
+func copyRunOptions(opts *runner.RunOptions) *runner.RunOptions {
+	copied := *opts
+	if opts.RunConfig != nil {
+		runConfig := *opts.RunConfig
+		copied.RunConfig = &runConfig
+	}
+	return &copied
+}
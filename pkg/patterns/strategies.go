@@ -0,0 +1,225 @@
+package patterns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+)
+
+// NormalizeFunc maps an answer to the key used to compare it with other answers
+type NormalizeFunc func(answer string) string
+
+// DefaultNormalize compares answers case-insensitively and ignores surrounding whitespace and punctuation
+func DefaultNormalize(answer string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(answer), ".!?\"'"))
+}
+
+type majorityVote struct {
+	normalize NormalizeFunc
+}
+
+// MajorityVote picks the answer given by the most agents. Ties go to the answer
+// of the earliest agent. A nil normalize function uses DefaultNormalize.
+func MajorityVote(normalize NormalizeFunc) Strategy {
+	if normalize == nil {
+		normalize = DefaultNormalize
+	}
+	return &majorityVote{normalize: normalize}
+}
+
+func (s *majorityVote) Aggregate(ctx context.Context, r *runner.Runner, opts *runner.RunOptions, answers []Answer) (*ConsensusResult, error) {
+	votes := make(map[string]int)
+	first := make(map[string]int)
+	order := make([]string, 0)
+	total := 0
+
+	for i, answer := range answers {
+		if answer.Error != nil {
+			continue
+		}
+		key := s.normalize(answer.Output)
+		if _, seen := votes[key]; !seen {
+			first[key] = i
+			order = append(order, key)
+		}
+		votes[key]++
+		total++
+	}
+
+	winner := order[0]
+	for _, key := range order[1:] {
+		if votes[key] > votes[winner] {
+			winner = key
+		}
+	}
+
+	result := &ConsensusResult{
+		Winner:       answers[first[winner]].Output,
+		Distribution: make(map[string]float64, len(votes)),
+	}
+	for key, count := range votes {
+		result.Distribution[key] = float64(count) / float64(total)
+	}
+	for _, answer := range answers {
+		if answer.Error == nil && s.normalize(answer.Output) == winner {
+			result.WinningAgents = append(result.WinningAgents, answer.AgentName)
+		}
+	}
+
+	return result, nil
+}
+
+type judgeRanking struct {
+	judge *agent.Agent
+}
+
+// JudgeRanking asks a judge agent to rank the answers from best to worst. The
+// judge must reply with the numbers of the answers in order of preference, for
+// example "2, 1, 3". Each answer's distribution value is its Borda score
+// normalized to the range [0, 1].
+func JudgeRanking(judge *agent.Agent) Strategy {
+	return &judgeRanking{judge: judge}
+}
+
+var (
+	numberPattern = regexp.MustCompile(`\d+`)
+	scorePattern  = regexp.MustCompile(`\d+(\.\d+)?`)
+)
+
+func (s *judgeRanking) Aggregate(ctx context.Context, r *runner.Runner, opts *runner.RunOptions, answers []Answer) (*ConsensusResult, error) {
+	candidates := make([]Answer, 0, len(answers))
+	for _, answer := range answers {
+		if answer.Error == nil {
+			candidates = append(candidates, answer)
+		}
+	}
+
+	var prompt strings.Builder
+	prompt.WriteString(fmt.Sprintf("Question:\n%v\n\nCandidate answers:\n", opts.Input))
+	for i, answer := range candidates {
+		prompt.WriteString(fmt.Sprintf("\n[%d]\n%s\n", i+1, answer.Output))
+	}
+	prompt.WriteString("\nRank the candidate answers from best to worst. Reply only with their numbers separated by commas.")
+
+	judgeOpts := copyRunOptions(opts)
+	judgeOpts.Input = prompt.String()
+	judgeResult, err := r.Run(ctx, s.judge, judgeOpts)
+	if err != nil {
+		return nil, fmt.Errorf("judge failed: %w", err)
+	}
+
+	// Parse the ranking, ignoring numbers that are out of range or repeated
+	ranking := make([]int, 0, len(candidates))
+	seen := make(map[int]bool)
+	for _, match := range numberPattern.FindAllString(fmt.Sprintf("%v", judgeResult.FinalOutput), -1) {
+		n, err := strconv.Atoi(match)
+		if err != nil || n < 1 || n > len(candidates) || seen[n] {
+			continue
+		}
+		seen[n] = true
+		ranking = append(ranking, n-1)
+	}
+	if len(ranking) == 0 {
+		return nil, errors.New("judge did not return a ranking")
+	}
+
+	result := &ConsensusResult{
+		Winner:        candidates[ranking[0]].Output,
+		WinningAgents: []string{candidates[ranking[0]].AgentName},
+		Distribution:  make(map[string]float64, len(candidates)),
+	}
+	for _, answer := range candidates {
+		result.Distribution[answer.Output] = 0
+	}
+	if len(candidates) == 1 {
+		result.Distribution[candidates[0].Output] = 1
+		return result, nil
+	}
+	for position, idx := range ranking {
+		score := float64(len(candidates)-1-position) / float64(len(candidates)-1)
+		if score > result.Distribution[candidates[idx].Output] {
+			result.Distribution[candidates[idx].Output] = score
+		}
+	}
+
+	return result, nil
+}
+
+// Scorer scores an answer, higher is better
+type Scorer func(ctx context.Context, input interface{}, answer string) (float64, error)
+
+type scoreAveraging struct {
+	scorers []Scorer
+}
+
+// ScoreAveraging scores every answer with all scorers and picks the answer with
+// the highest average score. The distribution contains the average scores.
+func ScoreAveraging(scorers ...Scorer) Strategy {
+	return &scoreAveraging{scorers: scorers}
+}
+
+func (s *scoreAveraging) Aggregate(ctx context.Context, r *runner.Runner, opts *runner.RunOptions, answers []Answer) (*ConsensusResult, error) {
+	if len(s.scorers) == 0 {
+		return nil, errors.New("score averaging requires at least one scorer")
+	}
+
+	result := &ConsensusResult{Distribution: make(map[string]float64)}
+	best := -1.0
+	found := false
+
+	for _, answer := range answers {
+		if answer.Error != nil {
+			continue
+		}
+
+		total := 0.0
+		for _, scorer := range s.scorers {
+			score, err := scorer(ctx, opts.Input, answer.Output)
+			if err != nil {
+				return nil, fmt.Errorf("failed to score answer of %s: %w", answer.AgentName, err)
+			}
+			total += score
+		}
+		average := total / float64(len(s.scorers))
+		result.Distribution[answer.Output] = average
+
+		if !found || average > best {
+			best = average
+			found = true
+			result.Winner = answer.Output
+			result.WinningAgents = []string{answer.AgentName}
+		} else if average == best && answer.Output == result.Winner {
+			result.WinningAgents = append(result.WinningAgents, answer.AgentName)
+		}
+	}
+
+	return result, nil
+}
+
+// AgentScorer uses a judge agent to score answers from 0 to 10. The judge runs
+// with a copy of opts, which may be nil.
+func AgentScorer(r *runner.Runner, judge *agent.Agent, opts *runner.RunOptions) Scorer {
+	if opts == nil {
+		opts = &runner.RunOptions{}
+	}
+	return func(ctx context.Context, input interface{}, answer string) (float64, error) {
+		judgeOpts := copyRunOptions(opts)
+		judgeOpts.Input = fmt.Sprintf("Question:\n%v\n\nAnswer:\n%s\n\nScore the answer from 0 to 10. Reply only with the number.", input, answer)
+		judgeResult, err := r.Run(ctx, judge, judgeOpts)
+		if err != nil {
+			return 0, err
+		}
+
+		match := scorePattern.FindString(fmt.Sprintf("%v", judgeResult.FinalOutput))
+		if match == "" {
+			return 0, fmt.Errorf("judge did not return a score")
+		}
+		return strconv.ParseFloat(match, 64)
+	}
+}
@@ -0,0 +1,286 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/guardrail"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+)
+
+// DefaultRetryBackoff is the wait before the first retry of tools with retries
+const DefaultRetryBackoff = 500 * time.Millisecond
+
+// Policy is a built policy with the rules it inherits
+type Policy struct {
+	name             string
+	inputGuardrails  []guardrail.InputGuardrail
+	outputGuardrails []guardrail.OutputGuardrail
+	allow            [][]string
+	deny             []string
+	requireApproval  []string
+	limits           []*toolLimit
+	budget           BudgetDefinition
+}
+
+// toolLimit is a tool limit with the rate limiters of the tools it matches
+type toolLimit struct {
+	ToolLimitDefinition
+	limiters map[string]tool.Middleware
+	mu       sync.Mutex
+}
+
+// newPolicy builds a policy from its definition and the policy it extends
+func newPolicy(def *PolicyDefinition, parent *Policy, guardrails Guardrails) (*Policy, error) {
+	p := &Policy{name: def.Name}
+	if parent != nil {
+		p.inputGuardrails = append(p.inputGuardrails, parent.inputGuardrails...)
+		p.outputGuardrails = append(p.outputGuardrails, parent.outputGuardrails...)
+		p.allow = append(p.allow, parent.allow...)
+		p.deny = append(p.deny, parent.deny...)
+		p.requireApproval = append(p.requireApproval, parent.requireApproval...)
+		p.limits = append(p.limits, parent.limits...)
+		p.budget = parent.budget
+	}
+
+	if g := def.Guardrails; g != nil {
+		for _, d := range g.Input {
+			input, _, err := d.build(guardrails, true)
+			if err != nil {
+				return nil, err
+			}
+			p.inputGuardrails = append(p.inputGuardrails, input)
+		}
+		for _, d := range g.Output {
+			_, output, err := d.build(guardrails, false)
+			if err != nil {
+				return nil, err
+			}
+			p.outputGuardrails = append(p.outputGuardrails, output)
+		}
+	}
+
+	if t := def.Tools; t != nil {
+		// Allow lists narrow what the parent allows instead of widening it
+		if len(t.Allow) > 0 {
+			p.allow = append(p.allow, t.Allow)
+		}
+		p.deny = append(p.deny, t.Deny...)
+		p.requireApproval = append(p.requireApproval, t.RequireApproval...)
+		for _, limit := range t.Limits {
+			p.limits = append(p.limits, &toolLimit{ToolLimitDefinition: limit, limiters: make(map[string]tool.Middleware)})
+		}
+	}
+
+	if b := def.Budget; b != nil {
+		p.budget.MaxTurns = stricter(p.budget.MaxTurns, b.MaxTurns)
+		p.budget.MaxTotalTokens = stricter(p.budget.MaxTotalTokens, b.MaxTotalTokens)
+		p.budget.MaxCostUSD = stricter(p.budget.MaxCostUSD, b.MaxCostUSD)
+	}
+	return p, nil
+}
+
+// build creates the guardrail a definition declares
+func (g GuardrailDefinition) build(guardrails Guardrails, input bool) (guardrail.InputGuardrail, guardrail.OutputGuardrail, error) {
+	switch g.Type {
+	case GuardrailPII:
+		types := make([]guardrail.PIIType, 0, len(g.PIITypes))
+		for _, t := range g.PIITypes {
+			types = append(types, guardrail.PIIType(t))
+		}
+		pii := guardrail.NewPIIGuardrail(types...)
+		return pii, pii, nil
+	case GuardrailRegex:
+		name := g.Name
+		if name == "" {
+			name = "regex"
+		}
+		regex, err := guardrail.NewRegexGuardrail(name, g.Patterns...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return regex, regex, nil
+	case GuardrailMaxLength:
+		maxLength := guardrail.NewMaxLengthGuardrail(g.MaxLength)
+		return maxLength, maxLength, nil
+	case GuardrailRegistered:
+		if guardrails != nil && input {
+			if registered, ok := guardrails.InputGuardrail(g.Name); ok {
+				return registered, nil, nil
+			}
+		} else if guardrails != nil {
+			if registered, ok := guardrails.OutputGuardrail(g.Name); ok {
+				return nil, registered, nil
+			}
+		}
+		return nil, nil, fmt.Errorf("guardrail %q is not registered", g.Name)
+	}
+	return nil, nil, fmt.Errorf("unknown guardrail type %q", g.Type)
+}
+
+// Name returns the name of the policy
+func (p *Policy) Name() string {
+	return p.name
+}
+
+// Budget returns the budget limits of the policy, with zero meaning unlimited
+func (p *Policy) Budget() BudgetDefinition {
+	return p.budget
+}
+
+// Allows reports whether agents under the policy may use a tool
+func (p *Policy) Allows(toolName string) bool {
+	for _, allow := range p.allow {
+		if !matchAny(allow, toolName) {
+			return false
+		}
+	}
+	return !matchAny(p.deny, toolName)
+}
+
+// RequiresApproval reports whether calls to a tool or handoffs to an agent need
+// approval under the policy
+func (p *Policy) RequiresApproval(ctx context.Context, request *runner.ApprovalRequest) bool {
+	if request.Kind == runner.ApprovalKindHandoff {
+		return matchAny(p.requireApproval, request.TargetAgent)
+	}
+	return matchAny(p.requireApproval, request.ToolName)
+}
+
+// Agent returns a copy of an agent with the policy's guardrails, without the tools
+// the policy does not allow, and with the policy's limits applied to its tools.
+// The agent itself is left untouched. Handoff targets keep their own policies.
+func (p *Policy) Agent(a *agent.Agent) *agent.Agent {
+	clone := a.CloneWith()
+	tools := make([]tool.Tool, 0, len(clone.Tools))
+	for _, t := range clone.Tools {
+		if !p.Allows(t.GetName()) {
+			continue
+		}
+		tools = append(tools, p.limit(t))
+	}
+	clone.Tools = tools
+	clone.InputGuardrails = append(clone.InputGuardrails, p.inputGuardrails...)
+	clone.OutputGuardrails = append(clone.OutputGuardrails, p.outputGuardrails...)
+	return clone
+}
+
+// limit wraps a tool with the limits matching its name
+func (p *Policy) limit(t tool.Tool) tool.Tool {
+	name := t.GetName()
+	var middleware []tool.Middleware
+	for _, limit := range p.limits {
+		if !match(limit.Tool, name) {
+			continue
+		}
+		if limit.RateLimit != nil {
+			middleware = append(middleware, limit.rateLimiter(name))
+		}
+		if limit.Retries > 0 {
+			backoff := limit.RetryBackoff
+			if backoff == 0 {
+				backoff = DefaultRetryBackoff
+			}
+			middleware = append(middleware, tool.WithRetry(limit.Retries+1, backoff))
+		}
+		if limit.Timeout > 0 {
+			middleware = append(middleware, tool.WithTimeout(limit.Timeout))
+		}
+	}
+	if len(middleware) == 0 {
+		return t
+	}
+	return tool.Wrap(t, middleware...)
+}
+
+// rateLimiter returns the rate limit middleware of a tool. Every agent the policy
+// is applied to shares it, so the limit holds across agents and runs.
+func (l *toolLimit) rateLimiter(toolName string) tool.Middleware {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	mw, ok := l.limiters[toolName]
+	if !ok {
+		mw = tool.WithRateLimit(l.RateLimit.Requests, l.RateLimit.Per)
+		l.limiters[toolName] = mw
+	}
+	return mw
+}
+
+// RunOptions returns a copy of run options with the policy's run guardrails and
+// approvals added and the policy's budget applied where it is stricter than the
+// limits already set. opts may be nil.
+func (p *Policy) RunOptions(opts *runner.RunOptions) *runner.RunOptions {
+	applied := &runner.RunOptions{}
+	if opts != nil {
+		*applied = *opts
+	}
+	runConfig := &runner.RunConfig{}
+	if applied.RunConfig != nil {
+		*runConfig = *applied.RunConfig
+	}
+	applied.RunConfig = runConfig
+
+	runConfig.InputGuardrails = append(append([]runner.InputGuardrail(nil), runConfig.InputGuardrails...), p.inputGuardrails...)
+	runConfig.OutputGuardrails = append(append([]runner.OutputGuardrail(nil), runConfig.OutputGuardrails...), p.outputGuardrails...)
+	applied.MaxTurns = stricter(applied.MaxTurns, p.budget.MaxTurns)
+	runConfig.MaxTotalTokens = stricter(runConfig.MaxTotalTokens, p.budget.MaxTotalTokens)
+	runConfig.MaxCostUSD = stricter(runConfig.MaxCostUSD, p.budget.MaxCostUSD)
+
+	if len(p.requireApproval) > 0 {
+		if existing := runConfig.ApprovalPolicy; existing != nil {
+			runConfig.ApprovalPolicy = runner.ApprovalFunc(func(ctx context.Context, request *runner.ApprovalRequest) bool {
+				return existing.RequiresApproval(ctx, request) || p.RequiresApproval(ctx, request)
+			})
+		} else {
+			runConfig.ApprovalPolicy = p
+		}
+	}
+	return applied
+}
+
+// Agent returns a copy of an agent with a policy of the set applied
+func (s *Set) Agent(policyName string, a *agent.Agent) (*agent.Agent, error) {
+	p, err := s.Policy(policyName)
+	if err != nil {
+		return nil, err
+	}
+	return p.Agent(a), nil
+}
+
+// RunOptions returns a copy of run options with a policy of the set applied
+func (s *Set) RunOptions(policyName string, opts *runner.RunOptions) (*runner.RunOptions, error) {
+	p, err := s.Policy(policyName)
+	if err != nil {
+		return nil, err
+	}
+	return p.RunOptions(opts), nil
+}
+
+// stricter returns the smaller of two limits, where zero means unlimited
+func stricter[T int | float64](current, limit T) T {
+	if limit > 0 && (current <= 0 || limit < current) {
+		return limit
+	}
+	return current
+}
+
+// match reports whether a name matches a name or path.Match pattern
+func match(pattern, name string) bool {
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
+
+// matchAny reports whether a name matches any of the patterns
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if match(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,424 @@
+// Package policy loads guardrails, tool policies and budget limits from YAML or
+// JSON policy files, so that the constraints agents run under can be managed
+// without touching Go code.
+//
+// A policy file declares named policies. A policy can extend another and adds
+// its guardrails and tool rules to the ones it inherits:
+//
+//	version: 1
+//	policies:
+//	  - name: default
+//	    guardrails:
+//	      input:
+//	        - type: pii
+//	          pii_types: [email, credit_card]
+//	        - type: max_length
+//	          max_length: 8000
+//	      output:
+//	        - type: regex
+//	          name: no_secrets
+//	          patterns: ["(?i)api[_-]?key\\s*[:=]"]
+//	    tools:
+//	      deny: ["delete_*"]
+//	      require_approval: [deploy]
+//	      limits:
+//	        - tool: web_search
+//	          timeout: 30s
+//	          retries: 2
+//	          rate_limit: {requests: 10, per: 1m}
+//	    budget:
+//	      max_turns: 20
+//	      max_total_tokens: 200000
+//	      max_cost_usd: 5
+//	  - name: untrusted
+//	    extends: default
+//	    tools:
+//	      allow: [web_search, read_file]
+//
+// Policies are attached by name: Set.Agent returns a copy of an agent with the
+// policy's guardrails and tool rules, and Set.RunOptions returns run options
+// with its budget limits, approvals and run guardrails.
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/guardrail"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+)
+
+// Version is the version of the policy file format
+const Version = 1
+
+// Guardrail types of policy files
+const (
+	GuardrailPII        = "pii"
+	GuardrailRegex      = "regex"
+	GuardrailMaxLength  = "max_length"
+	GuardrailRegistered = "registered"
+)
+
+var (
+	// ErrInvalidPolicy is returned for policy files that do not match the schema
+	// or are inconsistent
+	ErrInvalidPolicy = errors.New("invalid policy")
+
+	// ErrPolicyNotFound is returned for policies a set does not have
+	ErrPolicyNotFound = errors.New("policy not found")
+)
+
+// Guardrails resolves the names of guardrails implemented in Go, which policies
+// use with the registered type. config.Registry implements it.
+type Guardrails interface {
+	InputGuardrail(name string) (guardrail.InputGuardrail, bool)
+	OutputGuardrail(name string) (guardrail.OutputGuardrail, bool)
+}
+
+// File is the content of a policy file
+type File struct {
+	Version  int                `yaml:"version"`
+	Policies []PolicyDefinition `yaml:"policies"`
+}
+
+// PolicyDefinition declares a policy
+type PolicyDefinition struct {
+	Name       string                `yaml:"name"`
+	Extends    string                `yaml:"extends"`
+	Guardrails *GuardrailsDefinition `yaml:"guardrails"`
+	Tools      *ToolsDefinition      `yaml:"tools"`
+	Budget     *BudgetDefinition     `yaml:"budget"`
+}
+
+// GuardrailsDefinition declares the guardrails checking the input and the
+// final output of runs
+type GuardrailsDefinition struct {
+	Input  []GuardrailDefinition `yaml:"input"`
+	Output []GuardrailDefinition `yaml:"output"`
+}
+
+// GuardrailDefinition declares a guardrail
+type GuardrailDefinition struct {
+	// Type is pii, regex, max_length or registered
+	Type string `yaml:"type"`
+
+	// Name names a regex guardrail, or the Go guardrail of the registered type
+	Name string `yaml:"name"`
+
+	// Patterns are the regular expressions of a regex guardrail
+	Patterns []string `yaml:"patterns"`
+
+	// PIITypes are the kinds of personal data a pii guardrail detects, all of
+	// them if empty
+	PIITypes []string `yaml:"pii_types"`
+
+	// MaxLength is the most characters a max_length guardrail allows
+	MaxLength int `yaml:"max_length"`
+}
+
+// ToolsDefinition declares which tools agents may use and how
+type ToolsDefinition struct {
+	// Allow lists the tools agents keep, as names or path.Match patterns. All
+	// tools are allowed if it is empty.
+	Allow []string `yaml:"allow"`
+
+	// Deny lists tools removed from agents, as names or patterns
+	Deny []string `yaml:"deny"`
+
+	// RequireApproval lists tools and handoff targets that pause runs for approval
+	RequireApproval []string `yaml:"require_approval"`
+
+	// Limits constrain the execution of tools
+	Limits []ToolLimitDefinition `yaml:"limits"`
+}
+
+// ToolLimitDefinition constrains the execution of the tools matching a pattern.
+// The timeout applies to each attempt, and retries wait for the rate limit.
+type ToolLimitDefinition struct {
+	Tool         string               `yaml:"tool"`
+	Timeout      time.Duration        `yaml:"timeout"`
+	Retries      int                  `yaml:"retries"`
+	RetryBackoff time.Duration        `yaml:"retry_backoff"`
+	RateLimit    *RateLimitDefinition `yaml:"rate_limit"`
+}
+
+// RateLimitDefinition allows a number of executions per period
+type RateLimitDefinition struct {
+	Requests int           `yaml:"requests"`
+	Per      time.Duration `yaml:"per"`
+}
+
+// BudgetDefinition limits the resources of a run
+type BudgetDefinition struct {
+	MaxTurns       int     `yaml:"max_turns"`
+	MaxTotalTokens int     `yaml:"max_total_tokens"`
+	MaxCostUSD     float64 `yaml:"max_cost_usd"`
+}
+
+// Load reads a policy file. YAML and JSON files are both accepted.
+func Load(path string, guardrails Guardrails) (*Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	set, err := Parse(data, guardrails)
+	if err != nil {
+		return nil, fmt.Errorf("policy file %s: %w", path, err)
+	}
+	return set, nil
+}
+
+// Parse builds the policies of a YAML or JSON document. Guardrails of the
+// registered type are resolved with guardrails, which may be nil if none are used.
+func Parse(data []byte, guardrails Guardrails) (*Set, error) {
+	file, problems := parse(data)
+	if len(problems) == 0 {
+		problems = file.validate(guardrails, true)
+	}
+	if len(problems) > 0 {
+		return nil, invalid(problems)
+	}
+	return build(file, guardrails)
+}
+
+// Lint checks a policy document without building it and returns every problem
+// found. Guardrails of the registered type are not checked, since they are only
+// known to the program that loads the policies.
+func Lint(data []byte) []string {
+	file, problems := parse(data)
+	if len(problems) > 0 {
+		return problems
+	}
+	return file.validate(nil, false)
+}
+
+// parse decodes a document after checking it against the schema
+func parse(data []byte) (*File, []string) {
+	// YAML is a superset of JSON, so one decoder handles both formats
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, []string{fmt.Sprintf("failed to parse policy file: %v", err)}
+	}
+	if raw == nil {
+		return nil, []string{"the document is empty"}
+	}
+	if problems := tool.ValidateValue("policy", raw, Schema()); len(problems) > 0 {
+		return nil, problems
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	var file File
+	if err := decoder.Decode(&file); err != nil {
+		return nil, []string{err.Error()}
+	}
+	return &file, nil
+}
+
+// invalid returns an ErrInvalidPolicy listing problems
+func invalid(problems []string) error {
+	errs := make([]error, 0, len(problems)+1)
+	errs = append(errs, ErrInvalidPolicy)
+	for _, problem := range problems {
+		errs = append(errs, errors.New(problem))
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks the values and references of the file. Registered guardrails
+// are looked up when resolve is set.
+func (f *File) validate(guardrails Guardrails, resolve bool) []string {
+	var problems []string
+	if f.Version != 0 && f.Version != Version {
+		problems = append(problems, fmt.Sprintf("unsupported version %d", f.Version))
+	}
+	if len(f.Policies) == 0 {
+		problems = append(problems, "no policies defined")
+	}
+
+	byName := make(map[string]*PolicyDefinition, len(f.Policies))
+	for i := range f.Policies {
+		p := &f.Policies[i]
+		if p.Name == "" {
+			problems = append(problems, fmt.Sprintf("policy %d has no name", i+1))
+			continue
+		}
+		if byName[p.Name] != nil {
+			problems = append(problems, fmt.Sprintf("policy %q is defined more than once", p.Name))
+		}
+		byName[p.Name] = p
+	}
+
+	for _, p := range f.Policies {
+		owner := fmt.Sprintf("policy %q", p.Name)
+		if p.Extends != "" {
+			if byName[p.Extends] == nil {
+				problems = append(problems, fmt.Sprintf("%s extends undefined policy %q", owner, p.Extends))
+			} else if cycle := extendsCycle(byName, p.Name); cycle {
+				problems = append(problems, fmt.Sprintf("%s extends itself", owner))
+			}
+		}
+		if p.Guardrails != nil {
+			for _, g := range p.Guardrails.Input {
+				problems = append(problems, g.validate(owner+" input guardrail", guardrails, resolve, true)...)
+			}
+			for _, g := range p.Guardrails.Output {
+				problems = append(problems, g.validate(owner+" output guardrail", guardrails, resolve, false)...)
+			}
+		}
+		if t := p.Tools; t != nil {
+			for _, pattern := range append(append(append([]string(nil), t.Allow...), t.Deny...), t.RequireApproval...) {
+				if _, err := path.Match(pattern, ""); err != nil {
+					problems = append(problems, fmt.Sprintf("%s has invalid tool pattern %q", owner, pattern))
+				}
+			}
+			for _, limit := range t.Limits {
+				if _, err := path.Match(limit.Tool, ""); err != nil || limit.Tool == "" {
+					problems = append(problems, fmt.Sprintf("%s has a tool limit with invalid tool pattern %q", owner, limit.Tool))
+				}
+				if limit.Timeout < 0 || limit.Retries < 0 || limit.RetryBackoff < 0 {
+					problems = append(problems, fmt.Sprintf("%s has negative limits for tool %q", owner, limit.Tool))
+				}
+				if r := limit.RateLimit; r != nil && (r.Requests <= 0 || r.Per <= 0) {
+					problems = append(problems, fmt.Sprintf("%s has a rate limit for tool %q without positive requests and per", owner, limit.Tool))
+				}
+			}
+		}
+		if b := p.Budget; b != nil && (b.MaxTurns < 0 || b.MaxTotalTokens < 0 || b.MaxCostUSD < 0) {
+			problems = append(problems, fmt.Sprintf("%s has a negative budget", owner))
+		}
+	}
+	return problems
+}
+
+// extendsCycle reports whether following the extends chain of a policy returns to it
+func extendsCycle(byName map[string]*PolicyDefinition, name string) bool {
+	seen := map[string]bool{}
+	for p := byName[name]; p != nil && p.Extends != ""; p = byName[p.Extends] {
+		if seen[p.Name] {
+			return true
+		}
+		seen[p.Name] = true
+		if p.Extends == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks a guardrail definition
+func (g GuardrailDefinition) validate(owner string, guardrails Guardrails, resolve, input bool) []string {
+	switch g.Type {
+	case GuardrailPII:
+		var problems []string
+		for _, t := range g.PIITypes {
+			switch guardrail.PIIType(t) {
+			case guardrail.PIIEmail, guardrail.PIIPhone, guardrail.PIICreditCard, guardrail.PIISSN, guardrail.PIIIPAddress:
+			default:
+				problems = append(problems, fmt.Sprintf("%s has unknown pii type %q", owner, t))
+			}
+		}
+		return problems
+	case GuardrailRegex:
+		if len(g.Patterns) == 0 {
+			return []string{fmt.Sprintf("%s of type regex has no patterns", owner)}
+		}
+		for _, pattern := range g.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return []string{fmt.Sprintf("%s has invalid pattern %q: %v", owner, pattern, err)}
+			}
+		}
+	case GuardrailMaxLength:
+		if g.MaxLength <= 0 {
+			return []string{fmt.Sprintf("%s of type max_length needs a positive max_length", owner)}
+		}
+	case GuardrailRegistered:
+		if g.Name == "" {
+			return []string{fmt.Sprintf("%s of type registered has no name", owner)}
+		}
+		if !resolve {
+			return nil
+		}
+		found := false
+		if guardrails != nil && input {
+			_, found = guardrails.InputGuardrail(g.Name)
+		} else if guardrails != nil {
+			_, found = guardrails.OutputGuardrail(g.Name)
+		}
+		if !found {
+			return []string{fmt.Sprintf("%s %q is not registered", owner, g.Name)}
+		}
+	default:
+		return []string{fmt.Sprintf("%s has unknown type %q", owner, g.Type)}
+	}
+	return nil
+}
+
+// Set holds the policies of a file by name
+type Set struct {
+	policies map[string]*Policy
+}
+
+// Policy returns a policy of the set
+func (s *Set) Policy(name string) (*Policy, error) {
+	p, ok := s.policies[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPolicyNotFound, name)
+	}
+	return p, nil
+}
+
+// Names returns the names of the set's policies in order
+func (s *Set) Names() []string {
+	names := make([]string, 0, len(s.policies))
+	for name := range s.policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// build creates the policies of a validated file
+func build(file *File, guardrails Guardrails) (*Set, error) {
+	byName := make(map[string]*PolicyDefinition, len(file.Policies))
+	for i := range file.Policies {
+		byName[file.Policies[i].Name] = &file.Policies[i]
+	}
+
+	set := &Set{policies: make(map[string]*Policy, len(file.Policies))}
+	var get func(name string) (*Policy, error)
+	get = func(name string) (*Policy, error) {
+		if p, ok := set.policies[name]; ok {
+			return p, nil
+		}
+		def := byName[name]
+		var parent *Policy
+		if def.Extends != "" {
+			var err error
+			if parent, err = get(def.Extends); err != nil {
+				return nil, err
+			}
+		}
+		p, err := newPolicy(def, parent, guardrails)
+		if err != nil {
+			return nil, fmt.Errorf("policy %q: %w", name, err)
+		}
+		set.policies[name] = p
+		return p, nil
+	}
+
+	for _, def := range file.Policies {
+		if _, err := get(def.Name); err != nil {
+			return nil, err
+		}
+	}
+	return set, nil
+}
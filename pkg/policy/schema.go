@@ -0,0 +1,104 @@
+package policy
+
+// Schema returns the JSON schema of policy files, which editors and CI checks
+// can use to validate them
+func Schema() map[string]interface{} {
+	stringList := func() map[string]interface{} {
+		return map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}
+	}
+	duration := func(description string) map[string]interface{} {
+		return map[string]interface{}{"type": "string", "description": description}
+	}
+	guardrails := func() map[string]interface{} {
+		return map[string]interface{}{
+			"type": "array",
+			"items": map[string]interface{}{
+				"type":                 "object",
+				"required":             []string{"type"},
+				"additionalProperties": false,
+				"properties": map[string]interface{}{
+					"type": map[string]interface{}{
+						"type": "string",
+						"enum": []interface{}{GuardrailPII, GuardrailRegex, GuardrailMaxLength, GuardrailRegistered},
+					},
+					"name":       map[string]interface{}{"type": "string", "description": "Name of a regex guardrail, or of the registered Go guardrail"},
+					"patterns":   stringList(),
+					"pii_types":  stringList(),
+					"max_length": map[string]interface{}{"type": "integer"},
+				},
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                "Agent policies",
+		"type":                 "object",
+		"required":             []string{"policies"},
+		"additionalProperties": false,
+		"properties": map[string]interface{}{
+			"version": map[string]interface{}{"type": "integer", "description": "Version of the file format, 1"},
+			"policies": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type":                 "object",
+					"required":             []string{"name"},
+					"additionalProperties": false,
+					"properties": map[string]interface{}{
+						"name":    map[string]interface{}{"type": "string"},
+						"extends": map[string]interface{}{"type": "string", "description": "Policy whose rules this policy inherits"},
+						"guardrails": map[string]interface{}{
+							"type":                 "object",
+							"additionalProperties": false,
+							"properties": map[string]interface{}{
+								"input":  guardrails(),
+								"output": guardrails(),
+							},
+						},
+						"tools": map[string]interface{}{
+							"type":                 "object",
+							"additionalProperties": false,
+							"properties": map[string]interface{}{
+								"allow":            stringList(),
+								"deny":             stringList(),
+								"require_approval": stringList(),
+								"limits": map[string]interface{}{
+									"type": "array",
+									"items": map[string]interface{}{
+										"type":                 "object",
+										"required":             []string{"tool"},
+										"additionalProperties": false,
+										"properties": map[string]interface{}{
+											"tool":          map[string]interface{}{"type": "string"},
+											"timeout":       duration("Longest execution, such as 30s"),
+											"retries":       map[string]interface{}{"type": "integer"},
+											"retry_backoff": duration("Wait before the first retry, doubling after each failure"),
+											"rate_limit": map[string]interface{}{
+												"type":                 "object",
+												"required":             []string{"requests", "per"},
+												"additionalProperties": false,
+												"properties": map[string]interface{}{
+													"requests": map[string]interface{}{"type": "integer"},
+													"per":      duration("Period of the limit, such as 1m"),
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+						"budget": map[string]interface{}{
+							"type":                 "object",
+							"additionalProperties": false,
+							"properties": map[string]interface{}{
+								"max_turns":        map[string]interface{}{"type": "integer"},
+								"max_total_tokens": map[string]interface{}{"type": "integer"},
+								"max_cost_usd":     map[string]interface{}{"type": "number"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
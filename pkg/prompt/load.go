@@ -0,0 +1,103 @@
+package prompt
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Extension is the file extension of templates loaded with LoadFS
+const Extension = ".tmpl"
+
+// frontMatter is the YAML header of a template file
+type frontMatter struct {
+	Description string     `yaml:"description"`
+	Variables   []Variable `yaml:"variables"`
+}
+
+// LoadFS loads the templates under dir of a file system, such as an embed.FS.
+// Every file with the .tmpl extension is a template named by its path relative
+// to dir without the extension, so dir/agents/support.tmpl is "agents/support".
+// Files whose name starts with an underscore are partials named by their file
+// name without the underscore, so _safety.tmpl is the partial "safety".
+//
+//	//go:embed prompts
+//	var prompts embed.FS
+//
+//	lib := prompt.NewLibrary()
+//	err := lib.LoadFS(prompts, "prompts")
+func (l *Library) LoadFS(fsys fs.FS, dir string) error {
+	var templates []string
+	err := fs.WalkDir(fsys, dir, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || path.Ext(p) != Extension {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+
+		base := path.Base(p)
+		if strings.HasPrefix(base, "_") {
+			return l.AddPartial(strings.TrimSuffix(base[1:], Extension), string(data))
+		}
+		templates = append(templates, p)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load templates: %w", err)
+	}
+
+	// Templates are added after all partials, so that they parse with them
+	for _, p := range templates {
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return fmt.Errorf("failed to load templates: %w", err)
+		}
+		meta, text, err := splitFrontMatter(data)
+		if err != nil {
+			return fmt.Errorf("failed to load template %s: %w", p, err)
+		}
+		name := strings.TrimSuffix(p, Extension)
+		if dir != "." {
+			name = strings.TrimPrefix(name, strings.TrimSuffix(dir, "/")+"/")
+		}
+		if _, err := l.add(name, meta.Description, text, meta.Variables); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadDir loads the templates of a directory, as LoadFS does
+func (l *Library) LoadDir(dir string) error {
+	return l.LoadFS(os.DirFS(dir), ".")
+}
+
+// splitFrontMatter separates the YAML front matter of a template file from its text
+func splitFrontMatter(data []byte) (frontMatter, string, error) {
+	var meta frontMatter
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	if !bytes.HasPrefix(data, []byte("---\n")) {
+		return meta, string(data), nil
+	}
+
+	rest := data[len("---\n"):]
+	end := bytes.Index(rest, []byte("\n---\n"))
+	if end < 0 {
+		if !bytes.HasSuffix(rest, []byte("\n---")) {
+			return meta, "", fmt.Errorf("front matter is not closed")
+		}
+		end = len(rest) - len("\n---")
+	}
+	if err := yaml.Unmarshal(rest[:end], &meta); err != nil {
+		return meta, "", fmt.Errorf("invalid front matter: %w", err)
+	}
+	text := rest[min(end+len("\n---\n"), len(rest)):]
+	return meta, string(text), nil
+}
@@ -0,0 +1,343 @@
+// Package prompt renders agent instructions from templates, so that long system
+// prompts can live in files instead of inline strings.
+//
+// Templates use Go's text/template syntax. They declare the variables they take,
+// and rendering fails with ErrMissingVariable when a required one is not given.
+// Partials are templates shared by every template of a Library and included with
+// {{template "name" .}}:
+//
+//	lib := prompt.NewLibrary()
+//	lib.AddPartial("safety", "Never share credentials or personal data.")
+//	support, err := lib.Add("support",
+//		"You are the support agent of {{.company}}. Answer in a {{.tone}} tone.\n{{template \"safety\" .}}",
+//		prompt.Variable{Name: "company", Required: true},
+//		prompt.Variable{Name: "tone", Default: "friendly"},
+//	)
+//	instructions, err := support.Render(map[string]interface{}{"company": "Acme"})
+//
+// Templates and partials can also be loaded from a directory or an embed.FS with
+// LoadFS. Each file declares its variables in YAML front matter:
+//
+//	---
+//	description: Instructions of the support agent
+//	variables:
+//	  - name: company
+//	    required: true
+//	  - name: tone
+//	    default: friendly
+//	---
+//	You are the support agent of {{.company}}. Answer in a {{.tone}} tone.
+//	{{template "safety" .}}
+package prompt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+)
+
+var (
+	// ErrMissingVariable is returned when a template is rendered without a
+	// variable it requires
+	ErrMissingVariable = errors.New("missing template variable")
+
+	// ErrTemplateNotFound is returned for templates a library does not have
+	ErrTemplateNotFound = errors.New("template not found")
+)
+
+// RunVariable is the variable holding the agent.RunContext of the turn when a
+// template generates instructions with Template.Instructions
+const RunVariable = "run"
+
+// Variable declares a variable of a template
+type Variable struct {
+	// Name is the name the template uses for the variable, as in {{.name}}
+	Name string `yaml:"name" json:"name"`
+
+	// Description documents the variable
+	Description string `yaml:"description" json:"description,omitempty"`
+
+	// Required fails rendering when the variable is not given
+	Required bool `yaml:"required" json:"required,omitempty"`
+
+	// Default is the value of the variable when it is not given
+	Default interface{} `yaml:"default" json:"default,omitempty"`
+}
+
+// Template is a parsed instructions template
+type Template struct {
+	name        string
+	description string
+	text        string
+	variables   []Variable
+	library     *Library
+
+	// compiled is the template with the library's partials, as of version
+	compiled *template.Template
+	version  int
+	mu       sync.Mutex
+}
+
+// New parses a standalone template, which can only use the partials it defines
+// itself
+func New(name, text string, variables ...Variable) (*Template, error) {
+	return NewLibrary().Add(name, text, variables...)
+}
+
+// Name returns the name of the template
+func (t *Template) Name() string {
+	return t.name
+}
+
+// Description returns the description declared in the template's front matter
+func (t *Template) Description() string {
+	return t.description
+}
+
+// Variables returns the variables the template declares
+func (t *Template) Variables() []Variable {
+	return append([]Variable(nil), t.variables...)
+}
+
+// Render renders the template with the given variables. Declared variables that
+// are not given take their default; referring to a variable that is neither
+// given nor defaulted is an error.
+func (t *Template) Render(vars map[string]interface{}) (string, error) {
+	data := make(map[string]interface{}, len(vars)+len(t.variables))
+	for name, value := range vars {
+		data[name] = value
+	}
+
+	var missing []string
+	for _, v := range t.variables {
+		if _, ok := data[v.Name]; ok {
+			continue
+		}
+		switch {
+		case v.Default != nil:
+			data[v.Name] = v.Default
+		case v.Required:
+			missing = append(missing, v.Name)
+		default:
+			data[v.Name] = ""
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w in template %s: %s", ErrMissingVariable, t.name, strings.Join(missing, ", "))
+	}
+
+	compiled, err := t.compile()
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := compiled.Execute(&buf, data); err != nil {
+		if strings.Contains(err.Error(), "map has no entry for key") {
+			return "", fmt.Errorf("%w in template %s: %v", ErrMissingVariable, t.name, err)
+		}
+		return "", fmt.Errorf("failed to render template %s: %w", t.name, err)
+	}
+	return buf.String(), nil
+}
+
+// MustRender renders the template and panics if it fails. It suits templates
+// rendered once at startup.
+func (t *Template) MustRender(vars map[string]interface{}) string {
+	text, err := t.Render(vars)
+	if err != nil {
+		panic(err)
+	}
+	return text
+}
+
+// Instructions returns an agent.InstructionsFunc rendering the template for each
+// turn with the given variables and the turn's agent.RunContext as {{.run}}. The
+// template is rendered once to check the variables; a turn whose rendering still
+// fails gets empty instructions and logs the error.
+//
+//	a := agent.New("Support", agent.WithInstructionsFunc(instructions))
+func (t *Template) Instructions(vars map[string]interface{}) (agent.InstructionsFunc, error) {
+	withRun := func(run agent.RunContext) map[string]interface{} {
+		data := make(map[string]interface{}, len(vars)+1)
+		for name, value := range vars {
+			data[name] = value
+		}
+		data[RunVariable] = run
+		return data
+	}
+	if _, err := t.Render(withRun(agent.RunContext{})); err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, run agent.RunContext) string {
+		text, err := t.Render(withRun(run))
+		if err != nil {
+			logging.For(nil, "prompt").Error("Failed to render instructions", "template", t.name, "error", err)
+			return ""
+		}
+		return text
+	}, nil
+}
+
+// compile returns the template combined with the current partials of its library
+func (t *Template) compile() (*template.Template, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	l := t.library
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if t.compiled != nil && t.version == l.version {
+		return t.compiled, nil
+	}
+
+	compiled := template.New(t.name).Funcs(l.funcs).Option("missingkey=error")
+	names := make([]string, 0, len(l.partials))
+	for name := range l.partials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := compiled.New(name).Parse(l.partials[name]); err != nil {
+			return nil, fmt.Errorf("failed to parse partial %s: %w", name, err)
+		}
+	}
+	if _, err := compiled.Parse(t.text); err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", t.name, err)
+	}
+
+	t.compiled, t.version = compiled, l.version
+	return compiled, nil
+}
+
+// Library holds templates and the partials they share
+type Library struct {
+	templates map[string]*Template
+	partials  map[string]string
+	funcs     template.FuncMap
+
+	// version changes whenever partials or functions change, so that templates
+	// compile again
+	version int
+	mu      sync.RWMutex
+}
+
+// NewLibrary creates an empty library
+func NewLibrary() *Library {
+	return &Library{
+		templates: make(map[string]*Template),
+		partials:  make(map[string]string),
+		funcs: template.FuncMap{
+			"join":  strings.Join,
+			"upper": strings.ToUpper,
+			"lower": strings.ToLower,
+			"trim":  strings.TrimSpace,
+		},
+	}
+}
+
+// WithFuncs adds functions templates can call, next to the built-in join, upper,
+// lower and trim
+func (l *Library) WithFuncs(funcs template.FuncMap) *Library {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for name, fn := range funcs {
+		l.funcs[name] = fn
+	}
+	l.version++
+	return l
+}
+
+// AddPartial adds a partial that every template of the library can include with
+// {{template "name" .}}. Templates see partials added after they were.
+func (l *Library) AddPartial(name, text string) error {
+	if _, err := template.New(name).Funcs(l.funcsCopy()).Parse(text); err != nil {
+		return fmt.Errorf("failed to parse partial %s: %w", name, err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.partials[name] = text
+	l.version++
+	return nil
+}
+
+// Add parses a template and adds it to the library, replacing any template of
+// the same name
+func (l *Library) Add(name, text string, variables ...Variable) (*Template, error) {
+	return l.add(name, "", text, variables)
+}
+
+// add parses a template with a description and adds it to the library
+func (l *Library) add(name, description, text string, variables []Variable) (*Template, error) {
+	if _, err := template.New(name).Funcs(l.funcsCopy()).Parse(text); err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+	}
+	for _, v := range variables {
+		if v.Name == "" {
+			return nil, fmt.Errorf("template %s declares a variable without a name", name)
+		}
+	}
+
+	t := &Template{
+		name:        name,
+		description: description,
+		text:        text,
+		variables:   append([]Variable(nil), variables...),
+		library:     l,
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.templates[name] = t
+	return t, nil
+}
+
+// Get returns a template of the library
+func (l *Library) Get(name string) (*Template, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	t, ok := l.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return t, nil
+}
+
+// Render renders a template of the library with the given variables
+func (l *Library) Render(name string, vars map[string]interface{}) (string, error) {
+	t, err := l.Get(name)
+	if err != nil {
+		return "", err
+	}
+	return t.Render(vars)
+}
+
+// Names returns the names of the library's templates in order
+func (l *Library) Names() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	names := make([]string, 0, len(l.templates))
+	for name := range l.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// funcsCopy returns the library's functions, for parsing outside of the lock
+func (l *Library) funcsCopy() template.FuncMap {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	funcs := make(template.FuncMap, len(l.funcs))
+	for name, fn := range l.funcs {
+		funcs[name] = fn
+	}
+	return funcs
+}
@@ -0,0 +1,192 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Quota is the rate limit state a provider reports in its response headers
+type Quota struct {
+	// RequestsLimit and RequestsRemaining are the request limit of the current
+	// period and the requests left in it, or -1 when not reported
+	RequestsLimit     int
+	RequestsRemaining int
+
+	// RequestsReset is when the request limit resets, or zero when not reported
+	RequestsReset time.Time
+
+	// TokensLimit and TokensRemaining are the token limit of the current period and
+	// the tokens left in it, or -1 when not reported
+	TokensLimit     int
+	TokensRemaining int
+
+	// TokensReset is when the token limit resets, or zero when not reported
+	TokensReset time.Time
+
+	// RetryAfter is how long the provider asked to wait before the next request
+	RetryAfter time.Duration
+
+	// ReportedAt is when the headers were received
+	ReportedAt time.Time
+}
+
+// ResumeAt returns when the provider accepts requests again: after RetryAfter,
+// or when an exhausted request or token limit resets. It returns the zero time
+// when requests are not limited.
+func (q Quota) ResumeAt() time.Time {
+	var resume time.Time
+	later := func(t time.Time) {
+		if t.After(resume) {
+			resume = t
+		}
+	}
+	if q.RetryAfter > 0 {
+		later(q.ReportedAt.Add(q.RetryAfter))
+	}
+	if q.RequestsRemaining == 0 {
+		later(q.RequestsReset)
+	}
+	if q.TokensRemaining == 0 {
+		later(q.TokensReset)
+	}
+	return resume
+}
+
+// ParseHeaders reads the rate limit headers of a response: Retry-After and
+// retry-after-ms, the OpenAI x-ratelimit-* headers and the Anthropic
+// anthropic-ratelimit-* headers. It returns false when none are present.
+func ParseHeaders(header http.Header, now time.Time) (Quota, bool) {
+	q := Quota{
+		RequestsLimit:     -1,
+		RequestsRemaining: -1,
+		TokensLimit:       -1,
+		TokensRemaining:   -1,
+		ReportedAt:        now,
+	}
+	found := false
+
+	intHeader := func(target *int, names ...string) {
+		for _, name := range names {
+			if n, err := strconv.Atoi(strings.TrimSpace(header.Get(name))); err == nil {
+				*target = n
+				found = true
+				return
+			}
+		}
+	}
+	resetHeader := func(target *time.Time, names ...string) {
+		for _, name := range names {
+			if t, ok := parseReset(header.Get(name), now); ok {
+				*target = t
+				found = true
+				return
+			}
+		}
+	}
+
+	intHeader(&q.RequestsLimit, "x-ratelimit-limit-requests", "anthropic-ratelimit-requests-limit")
+	intHeader(&q.RequestsRemaining, "x-ratelimit-remaining-requests", "anthropic-ratelimit-requests-remaining")
+	resetHeader(&q.RequestsReset, "x-ratelimit-reset-requests", "anthropic-ratelimit-requests-reset")
+	intHeader(&q.TokensLimit, "x-ratelimit-limit-tokens", "anthropic-ratelimit-tokens-limit")
+	intHeader(&q.TokensRemaining, "x-ratelimit-remaining-tokens", "anthropic-ratelimit-tokens-remaining")
+	resetHeader(&q.TokensReset, "x-ratelimit-reset-tokens", "anthropic-ratelimit-tokens-reset")
+
+	if ms, err := strconv.ParseFloat(header.Get("retry-after-ms"), 64); err == nil && ms >= 0 {
+		q.RetryAfter = time.Duration(ms * float64(time.Millisecond))
+		found = true
+	} else if d, ok := parseRetryAfter(header.Get("Retry-After"), now); ok {
+		q.RetryAfter = d
+		found = true
+	}
+
+	return q, found
+}
+
+// parseRetryAfter parses a Retry-After value in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// parseReset parses a reset time given as an RFC 3339 time (Anthropic), a duration
+// such as "6m0s" or "20ms" (OpenAI), or a number of seconds
+func parseReset(value string, now time.Time) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(d), true
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return now.Add(time.Duration(seconds * float64(time.Second))), true
+	}
+	return time.Time{}, false
+}
+
+// Error is returned for requests a provider rejected with 429 Too Many Requests
+type Error struct {
+	// StatusCode is the HTTP status of the response
+	StatusCode int
+
+	// Quota is the rate limit state reported with the response
+	Quota Quota
+
+	// Err is the error reported by the provider
+	Err error
+}
+
+// NewError creates the error for a rate limited response
+func NewError(response *http.Response, err error) *Error {
+	quota, _ := ParseHeaders(response.Header, time.Now())
+	return &Error{StatusCode: response.StatusCode, Quota: quota, Err: err}
+}
+
+// Error returns the error message
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("rate limited (%d)", e.StatusCode)
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the error reported by the provider
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfter returns how long the provider asked to wait before retrying a request
+// that failed with err, and whether it said so
+func RetryAfter(err error) (time.Duration, bool) {
+	var rateErr *Error
+	if !errors.As(err, &rateErr) {
+		return 0, false
+	}
+	resume := rateErr.Quota.ResumeAt()
+	if resume.IsZero() {
+		return 0, false
+	}
+	return max(time.Until(resume), 0), true
+}
+
+// IsRateLimited reports whether err is an *Error
+func IsRateLimited(err error) bool {
+	var rateErr *Error
+	return errors.As(err, &rateErr)
+}
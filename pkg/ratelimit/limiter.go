@@ -0,0 +1,303 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Limiter paces the requests of one or more providers. Providers sharing an API
+// key should share a limiter, so that their requests count against the same limits:
+//
+//	limiter := ratelimit.NewSlidingWindow(500, 200000)
+//	openaiA := openai.NewProvider(key).WithLimiter(limiter)
+//	openaiB := openai.NewProvider(key).WithLimiter(limiter)
+type Limiter interface {
+	// Wait blocks until a request can be made and records it. onThrottle, if not
+	// nil, is called each time the request has to wait.
+	Wait(ctx context.Context, onThrottle ThrottleFunc) error
+
+	// AddTokens records tokens used by a request
+	AddTokens(tokens int)
+
+	// Observe applies the rate limit state reported by a provider
+	Observe(quota Quota)
+
+	// Quota returns the rate limit state last reported by a provider, and false if
+	// none has been reported
+	Quota() (Quota, bool)
+
+	// Status returns the current state of the limiter
+	Status() Status
+
+	// Reset forgets all recorded requests and tokens
+	Reset()
+}
+
+// Ensure SlidingWindow implements Limiter
+var _ Limiter = (*SlidingWindow)(nil)
+
+// Counter is a store of expiring counters shared between processes. redis.Counter
+// in pkg/memory/redis keeps them in Redis. A counter that does not exist has the
+// value zero.
+type Counter interface {
+	// Add adds n to a counter, setting it to expire after ttl when it is created,
+	// and returns the new value
+	Add(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error)
+
+	// Get returns the value of a counter
+	Get(ctx context.Context, key string) (int64, error)
+
+	// Delete removes counters
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// CounterLimiter limits requests and tokens per fixed window with counters in a
+// shared store, so that several processes using one API key stay within its
+// limits together. The provider's Retry-After and reset times are applied in
+// the process that received them.
+type CounterLimiter struct {
+	counter Counter
+	prefix  string
+	rpm     int
+	tpm     int
+	window  time.Duration
+
+	quota    *Quota
+	resumeAt time.Time
+	mu       sync.Mutex
+}
+
+// Ensure CounterLimiter implements Limiter
+var _ Limiter = (*CounterLimiter)(nil)
+
+// NewCounterLimiter creates a limiter for the given requests and tokens per minute
+// that keeps its counts under the key prefix in a counter store. A limit of zero
+// disables that limit.
+func NewCounterLimiter(counter Counter, prefix string, rpm, tpm int) *CounterLimiter {
+	return &CounterLimiter{
+		counter: counter,
+		prefix:  prefix,
+		rpm:     rpm,
+		tpm:     tpm,
+		window:  DefaultWindow,
+	}
+}
+
+// WithWindow sets the length of the window the limits apply to
+func (l *CounterLimiter) WithWindow(window time.Duration) *CounterLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.window = window
+	return l
+}
+
+// keys returns the request and token counter keys of the window containing t, and
+// the end of the window
+func (l *CounterLimiter) keys(t time.Time) (string, string, time.Time) {
+	start := t.Truncate(l.window)
+	suffix := strconv.FormatInt(start.UnixMilli(), 10)
+	return l.prefix + ":requests:" + suffix, l.prefix + ":tokens:" + suffix, start.Add(l.window)
+}
+
+// Wait blocks until a request can be made and records it
+func (l *CounterLimiter) Wait(ctx context.Context, onThrottle ThrottleFunc) error {
+	for {
+		status, err := l.status(ctx)
+		if err != nil {
+			return err
+		}
+
+		if !status.Throttled && l.rpm > 0 {
+			// Claim a request, giving it back if another process took the last one
+			l.mu.Lock()
+			requestsKey, _, windowEnd := l.keys(time.Now())
+			ttl := windowEnd.Sub(time.Now()) + time.Second
+			l.mu.Unlock()
+
+			count, err := l.counter.Add(ctx, requestsKey, 1, ttl)
+			if err != nil {
+				return fmt.Errorf("failed to count request: %w", err)
+			}
+			if count <= int64(l.rpm) {
+				return nil
+			}
+			if _, err := l.counter.Add(ctx, requestsKey, -1, ttl); err != nil {
+				return fmt.Errorf("failed to release request: %w", err)
+			}
+			status.Throttled = true
+			status.RemainingRequests = 0
+			status.NextAvailable = windowEnd
+		}
+		if !status.Throttled {
+			return nil
+		}
+
+		if onThrottle != nil {
+			onThrottle(status)
+		}
+
+		timer := time.NewTimer(status.WaitDuration())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// AddTokens records tokens used by a request. Failures of the counter store are
+// ignored; the next request is then paced by the provider's own limits.
+func (l *CounterLimiter) AddTokens(tokens int) {
+	if tokens <= 0 || l.tpm <= 0 {
+		return
+	}
+	l.mu.Lock()
+	_, tokensKey, windowEnd := l.keys(time.Now())
+	ttl := windowEnd.Sub(time.Now()) + time.Second
+	l.mu.Unlock()
+	_, _ = l.counter.Add(context.Background(), tokensKey, int64(tokens), ttl)
+}
+
+// Observe applies the rate limit state reported by the provider
+func (l *CounterLimiter) Observe(quota Quota) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.quota = &quota
+	if resume := quota.ResumeAt(); resume.After(l.resumeAt) {
+		l.resumeAt = resume
+	}
+}
+
+// Quota returns the rate limit state last reported to this process
+func (l *CounterLimiter) Quota() (Quota, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.quota == nil {
+		return Quota{}, false
+	}
+	return *l.quota, true
+}
+
+// Status returns the current state of the limiter. Counter store failures report
+// an unthrottled status.
+func (l *CounterLimiter) Status() Status {
+	status, err := l.status(context.Background())
+	if err != nil {
+		return Status{RPM: l.rpm, TPM: l.tpm, RemainingRequests: -1, RemainingTokens: -1, NextAvailable: time.Now()}
+	}
+	return status
+}
+
+// Reset deletes the counters of the current window and the reported state
+func (l *CounterLimiter) Reset() {
+	l.mu.Lock()
+	requestsKey, tokensKey, _ := l.keys(time.Now())
+	l.quota = nil
+	l.resumeAt = time.Time{}
+	l.mu.Unlock()
+	_ = l.counter.Delete(context.Background(), requestsKey, tokensKey)
+}
+
+// status reads the counters of the current window
+func (l *CounterLimiter) status(ctx context.Context) (Status, error) {
+	l.mu.Lock()
+	now := time.Now()
+	requestsKey, tokensKey, windowEnd := l.keys(now)
+	resumeAt := l.resumeAt
+	l.mu.Unlock()
+
+	status := Status{
+		RPM:               l.rpm,
+		TPM:               l.tpm,
+		RemainingRequests: -1,
+		RemainingTokens:   -1,
+		NextAvailable:     now,
+	}
+
+	if l.rpm > 0 {
+		requests, err := l.counter.Get(ctx, requestsKey)
+		if err != nil {
+			return status, fmt.Errorf("failed to read request count: %w", err)
+		}
+		status.RemainingRequests = max(l.rpm-int(requests), 0)
+		if status.RemainingRequests == 0 {
+			status.NextAvailable = windowEnd
+		}
+	}
+
+	if l.tpm > 0 {
+		tokens, err := l.counter.Get(ctx, tokensKey)
+		if err != nil {
+			return status, fmt.Errorf("failed to read token count: %w", err)
+		}
+		status.RemainingTokens = max(l.tpm-int(tokens), 0)
+		if status.RemainingTokens == 0 {
+			status.NextAvailable = windowEnd
+		}
+	}
+
+	if resumeAt.After(status.NextAvailable) {
+		status.NextAvailable = resumeAt
+	}
+	status.Throttled = status.NextAvailable.After(now)
+	return status, nil
+}
+
+// MemoryCounter is a Counter kept in process memory, for tests and for sharing a
+// CounterLimiter within one process
+type MemoryCounter struct {
+	values  map[string]int64
+	expires map[string]time.Time
+	mu      sync.Mutex
+}
+
+// Ensure MemoryCounter implements Counter
+var _ Counter = (*MemoryCounter)(nil)
+
+// NewMemoryCounter creates an empty in-memory counter store
+func NewMemoryCounter() *MemoryCounter {
+	return &MemoryCounter{values: make(map[string]int64), expires: make(map[string]time.Time)}
+}
+
+// Add adds n to a counter and returns the new value
+func (c *MemoryCounter) Add(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expire(key)
+	if _, ok := c.values[key]; !ok {
+		c.expires[key] = time.Now().Add(ttl)
+	}
+	c.values[key] += n
+	return c.values[key], nil
+}
+
+// Get returns the value of a counter
+func (c *MemoryCounter) Get(ctx context.Context, key string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expire(key)
+	return c.values[key], nil
+}
+
+// Delete removes counters
+func (c *MemoryCounter) Delete(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range keys {
+		delete(c.values, key)
+		delete(c.expires, key)
+	}
+	return nil
+}
+
+// expire removes a counter past its expiry. The caller holds the lock.
+func (c *MemoryCounter) expire(key string) {
+	if expires, ok := c.expires[key]; ok && !time.Now().Before(expires) {
+		delete(c.values, key)
+		delete(c.expires, key)
+	}
+}
@@ -0,0 +1,243 @@
+// Package ratelimit paces requests to model providers within their request and token limits.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultWindow is the length of the sliding window that limits apply to
+const DefaultWindow = time.Minute
+
+// Status is a snapshot of a limiter's state
+type Status struct {
+	// RPM is the request limit per window. Zero means unlimited.
+	RPM int
+
+	// TPM is the token limit per window. Zero means unlimited.
+	TPM int
+
+	// RemainingRequests is the number of requests that can be made right now, or -1
+	// when requests are unlimited
+	RemainingRequests int
+
+	// RemainingTokens is the number of tokens that can be used right now, or -1
+	// when tokens are unlimited
+	RemainingTokens int
+
+	// NextAvailable is when the next request can be made. It is the current time
+	// when capacity is available.
+	NextAvailable time.Time
+
+	// Throttled indicates that requests currently have to wait for capacity
+	Throttled bool
+}
+
+// WaitDuration returns how long a request has to wait from now
+func (s Status) WaitDuration() time.Duration {
+	if !s.Throttled {
+		return 0
+	}
+	if d := time.Until(s.NextAvailable); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// ThrottleFunc is called when a request has to wait for capacity
+type ThrottleFunc func(status Status)
+
+// tokenUsage is a number of tokens used at a point in time
+type tokenUsage struct {
+	at     time.Time
+	tokens int
+}
+
+// SlidingWindow limits requests and tokens over a sliding window. Unlike counters
+// that reset every minute, capacity frees up gradually as old requests leave the
+// window, which avoids bursts at the start of each minute.
+type SlidingWindow struct {
+	rpm      int
+	tpm      int
+	window   time.Duration
+	requests []time.Time
+	tokens   []tokenUsage
+	quota    *Quota
+	resumeAt time.Time
+	mu       sync.Mutex
+}
+
+// NewSlidingWindow creates a limiter for the given requests and tokens per minute.
+// A limit of zero disables that limit.
+func NewSlidingWindow(rpm, tpm int) *SlidingWindow {
+	return &SlidingWindow{
+		rpm:    rpm,
+		tpm:    tpm,
+		window: DefaultWindow,
+	}
+}
+
+// WithWindow sets the length of the window the limits apply to
+func (w *SlidingWindow) WithWindow(window time.Duration) *SlidingWindow {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.window = window
+	return w
+}
+
+// SetLimits updates the request and token limits
+func (w *SlidingWindow) SetLimits(rpm, tpm int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.rpm = rpm
+	w.tpm = tpm
+}
+
+// Status returns the current state of the limiter
+func (w *SlidingWindow) Status() Status {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status(time.Now())
+}
+
+// Wait blocks until a request can be made and records it. onThrottle, if not nil,
+// is called each time the request has to wait.
+func (w *SlidingWindow) Wait(ctx context.Context, onThrottle ThrottleFunc) error {
+	for {
+		w.mu.Lock()
+		now := time.Now()
+		status := w.status(now)
+		if !status.Throttled {
+			w.requests = append(w.requests, now)
+			w.mu.Unlock()
+			return nil
+		}
+		w.mu.Unlock()
+
+		if onThrottle != nil {
+			onThrottle(status)
+		}
+
+		timer := time.NewTimer(status.WaitDuration())
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// AddTokens records tokens used by a request
+func (w *SlidingWindow) AddTokens(tokens int) {
+	if tokens <= 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.tokens = append(w.tokens, tokenUsage{at: time.Now(), tokens: tokens})
+}
+
+// Observe applies the rate limit state reported by the provider. Requests wait
+// until the provider's Retry-After has passed and, when the reported requests or
+// tokens are exhausted, until they reset, instead of being sent and rejected.
+func (w *SlidingWindow) Observe(quota Quota) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.quota = &quota
+	if resume := quota.ResumeAt(); resume.After(w.resumeAt) {
+		w.resumeAt = resume
+	}
+}
+
+// Quota returns the rate limit state last reported by the provider, and false if
+// none has been reported
+func (w *SlidingWindow) Quota() (Quota, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.quota == nil {
+		return Quota{}, false
+	}
+	return *w.quota, true
+}
+
+// Reset forgets all recorded requests and tokens and the reported state
+func (w *SlidingWindow) Reset() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.requests = nil
+	w.tokens = nil
+	w.quota = nil
+	w.resumeAt = time.Time{}
+}
+
+// status computes the limiter state at the given time. Must be called with the lock held.
+func (w *SlidingWindow) status(now time.Time) Status {
+	w.prune(now)
+
+	status := Status{
+		RPM:               w.rpm,
+		TPM:               w.tpm,
+		RemainingRequests: -1,
+		RemainingTokens:   -1,
+		NextAvailable:     now,
+	}
+
+	if w.rpm > 0 {
+		status.RemainingRequests = max(w.rpm-len(w.requests), 0)
+		if status.RemainingRequests == 0 {
+			// Wait until enough requests leave the window
+			available := w.requests[len(w.requests)-w.rpm].Add(w.window)
+			if available.After(status.NextAvailable) {
+				status.NextAvailable = available
+			}
+		}
+	}
+
+	if w.tpm > 0 {
+		used := 0
+		for _, usage := range w.tokens {
+			used += usage.tokens
+		}
+		status.RemainingTokens = max(w.tpm-used, 0)
+		if status.RemainingTokens == 0 {
+			// Wait until enough tokens leave the window to get back under the limit
+			for _, usage := range w.tokens {
+				used -= usage.tokens
+				if used < w.tpm {
+					available := usage.at.Add(w.window)
+					if available.After(status.NextAvailable) {
+						status.NextAvailable = available
+					}
+					break
+				}
+			}
+		}
+	}
+
+	// Wait for the provider's limits to reset
+	if w.resumeAt.After(status.NextAvailable) {
+		status.NextAvailable = w.resumeAt
+	}
+
+	status.Throttled = status.NextAvailable.After(now)
+	return status
+}
+
+// prune drops requests and tokens that have left the window
+func (w *SlidingWindow) prune(now time.Time) {
+	cutoff := now.Add(-w.window)
+
+	i := 0
+	for i < len(w.requests) && !w.requests[i].After(cutoff) {
+		i++
+	}
+	w.requests = w.requests[i:]
+
+	j := 0
+	for j < len(w.tokens) && !w.tokens[j].at.After(cutoff) {
+		j++
+	}
+	w.tokens = w.tokens[j:]
+}
@@ -0,0 +1,391 @@
+// Package remote runs agents that live in another service. A remote agent is a
+// regular agent whose model forwards each turn to the chat completions endpoint
+// of the service, such as one served by server.APIHandler, so it can be a
+// handoff target like any local agent:
+//
+//	coder := remote.NewAgent("CoderAgent", "https://coder.internal",
+//		remote.WithBearerToken(os.Getenv("CODER_TOKEN")),
+//		remote.WithTimeout(5*time.Minute),
+//	)
+//	manager.WithHandoffs(coder)
+//
+// The service runs the agent with its own model, tools and instructions and
+// answers with the agent's final output, which ends the remote agent's turn.
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/message"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+)
+
+// DefaultTimeout bounds each call to the remote service
+const DefaultTimeout = 5 * time.Minute
+
+// chatCompletionsPath is the path of the endpoint remote agents are called on
+const chatCompletionsPath = "/v1/chat/completions"
+
+// Option configures a remote agent
+type Option func(*RemoteAgent)
+
+// WithRemoteName sets the name of the agent in the remote service, when it differs
+// from the local name
+func WithRemoteName(name string) Option {
+	return func(r *RemoteAgent) {
+		r.remoteName = name
+	}
+}
+
+// WithBearerToken authenticates calls with a bearer token
+func WithBearerToken(token string) Option {
+	return WithHeader("Authorization", "Bearer "+token)
+}
+
+// WithHeader sets a header on every call
+func WithHeader(key, value string) Option {
+	return func(r *RemoteAgent) {
+		r.headers.Set(key, value)
+	}
+}
+
+// WithRequestEditor changes every request before it is sent, e.g. to sign it
+// or add credentials that expire. An error fails the call.
+func WithRequestEditor(edit func(*http.Request) error) Option {
+	return func(r *RemoteAgent) {
+		r.editors = append(r.editors, edit)
+	}
+}
+
+// WithTimeout bounds each call, streamed or not. Zero or less leaves calls
+// bounded by their context only.
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *RemoteAgent) {
+		r.timeout = timeout
+	}
+}
+
+// WithHTTPClient sets the HTTP client of the calls
+func WithHTTPClient(client *http.Client) Option {
+	return func(r *RemoteAgent) {
+		r.client = client
+	}
+}
+
+// RemoteAgent is the model of an agent running in another service. It sends the
+// user and assistant messages of the conversation to the service and returns
+// the remote agent's output as the response.
+type RemoteAgent struct {
+	endpoint   string
+	remoteName string
+	headers    http.Header
+	editors    []func(*http.Request) error
+	timeout    time.Duration
+	client     *http.Client
+}
+
+// Ensure RemoteAgent implements model.Model
+var _ model.Model = (*RemoteAgent)(nil)
+
+// NewRemoteAgent creates the model of an agent served at endpoint, the base URL
+// of the remote service. The agent is called by its remote name.
+func NewRemoteAgent(endpoint, remoteName string, opts ...Option) *RemoteAgent {
+	r := &RemoteAgent{
+		endpoint:   strings.TrimSuffix(endpoint, "/"),
+		remoteName: remoteName,
+		headers:    make(http.Header),
+		timeout:    DefaultTimeout,
+		client:     http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// NewAgent creates a local agent proxying the agent with the same name in the
+// service at endpoint
+func NewAgent(name, endpoint string, opts ...Option) *agent.Agent {
+	return agent.NewAgent(name).WithModel(NewRemoteAgent(endpoint, name, opts...))
+}
+
+// String returns the remote agent's address, for logs and traces
+func (r *RemoteAgent) String() string {
+	return fmt.Sprintf("remote:%s@%s", r.remoteName, r.endpoint)
+}
+
+// chatMessage is a message of a chat completion request
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// chatRequest is a chat completion request
+type chatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream,omitempty"`
+}
+
+// chatResponse is a chat completion or a chunk of a streamed one
+type chatResponse struct {
+	ID      string `json:"id"`
+	Choices []struct {
+		Message *chatMessage `json:"message"`
+		Delta   *chatMessage `json:"delta"`
+	} `json:"choices"`
+	Usage *chatUsage `json:"usage"`
+	Error *chatError `json:"error"`
+}
+
+// chatUsage is the token usage of a chat completion
+type chatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// usage converts the usage of a chat completion
+func (u *chatUsage) usage() *model.Usage {
+	if u == nil {
+		return nil
+	}
+	return &model.Usage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens, TotalTokens: u.TotalTokens}
+}
+
+// chatError is an error of the remote service
+type chatError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+// GetResponse runs the remote agent on the conversation of a request
+func (r *RemoteAgent) GetResponse(ctx context.Context, request *model.Request) (*model.Response, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := r.call(ctx, request, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var completion chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return nil, fmt.Errorf("failed to decode response of %s: %w", r, err)
+	}
+	response := &model.Response{RequestID: completion.ID, Usage: completion.Usage.usage()}
+	if len(completion.Choices) > 0 && completion.Choices[0].Message != nil {
+		response.Content = completion.Choices[0].Message.Content
+	}
+	return response, nil
+}
+
+// StreamResponse runs the remote agent on the conversation of a request,
+// streaming its output as it arrives
+func (r *RemoteAgent) StreamResponse(ctx context.Context, request *model.Request) (<-chan model.StreamEvent, error) {
+	ctx, cancel := r.withTimeout(ctx)
+	resp, err := r.call(ctx, request, true)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	events := make(chan model.StreamEvent)
+	go func() {
+		defer close(events)
+		defer cancel()
+		defer resp.Body.Close()
+
+		send := func(event model.StreamEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		response := &model.Response{}
+		var content strings.Builder
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+		for scanner.Scan() {
+			data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			if data == "[DONE]" {
+				response.Content = content.String()
+				send(model.StreamEvent{Type: model.StreamEventTypeDone, Response: response})
+				return
+			}
+
+			var chunk chatResponse
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				send(model.StreamEvent{Type: model.StreamEventTypeError, Error: fmt.Errorf("failed to decode chunk of %s: %w", r, err)})
+				return
+			}
+			if chunk.Error != nil {
+				send(model.StreamEvent{Type: model.StreamEventTypeError, Error: &model.ErrProvider{Provider: "remote", Status: http.StatusOK, Code: chunk.Error.Type, Message: chunk.Error.Message}})
+				return
+			}
+			response.RequestID = chunk.ID
+			if chunk.Usage != nil {
+				response.Usage = chunk.Usage.usage()
+			}
+			if len(chunk.Choices) > 0 && chunk.Choices[0].Delta != nil && chunk.Choices[0].Delta.Content != "" {
+				content.WriteString(chunk.Choices[0].Delta.Content)
+				if !send(model.StreamEvent{Type: model.StreamEventTypeContent, Content: chunk.Choices[0].Delta.Content}) {
+					return
+				}
+			}
+		}
+
+		err := scanner.Err()
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		send(model.StreamEvent{Type: model.StreamEventTypeError, Error: fmt.Errorf("stream of %s ended early: %w", r, err)})
+	}()
+	return events, nil
+}
+
+// withTimeout bounds a call by the timeout of the remote agent
+func (r *RemoteAgent) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if r.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, r.timeout)
+}
+
+// call sends the conversation of a request to the remote service
+func (r *RemoteAgent) call(ctx context.Context, request *model.Request, stream bool) (*http.Response, error) {
+	messages, err := chatMessages(request.Input)
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(chatRequest{Model: r.remoteName, Messages: messages, Stream: stream})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint+chatCompletionsPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range r.headers {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	for _, edit := range r.editors {
+		if err := edit(req); err != nil {
+			return nil, fmt.Errorf("failed to prepare request: %w", err)
+		}
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %w", r, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, remoteError(resp)
+	}
+	return resp, nil
+}
+
+// remoteError converts an error response of the remote service
+func remoteError(resp *http.Response) error {
+	providerErr := &model.ErrProvider{Provider: "remote", Status: resp.StatusCode, RequestID: resp.Header.Get("x-request-id")}
+	var body struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err == nil && len(body.Error) > 0 {
+		// The OpenAI endpoints report errors as objects, the others as strings
+		var structured chatError
+		if json.Unmarshal(body.Error, &structured) == nil {
+			providerErr.Code = structured.Type
+			providerErr.Message = structured.Message
+		} else {
+			json.Unmarshal(body.Error, &providerErr.Message)
+		}
+	}
+	return providerErr
+}
+
+// chatMessages converts the input of a request to the user and assistant
+// messages of a chat. Tool calls, tool results and system messages stay local.
+func chatMessages(input interface{}) ([]chatMessage, error) {
+	switch v := input.(type) {
+	case string:
+		return []chatMessage{{Role: "user", Content: v}}, nil
+	case []model.ContentPart:
+		return []chatMessage{{Role: "user", Content: contentText(v)}}, nil
+	case []interface{}:
+		var messages []chatMessage
+		for _, item := range v {
+			parsed, _ := message.Parse(item)
+			msg, ok := parsed.(message.Message)
+			if !ok || (msg.Role != message.RoleUser && msg.Role != message.RoleAssistant) {
+				continue
+			}
+			if text := contentText(msg.Content); strings.TrimSpace(text) != "" {
+				messages = append(messages, chatMessage{Role: msg.Role, Content: text})
+			}
+		}
+		if len(messages) > 0 {
+			return messages, nil
+		}
+	}
+	return nil, errors.New("remote agents need input with a user message")
+}
+
+// contentText returns the text of message content: a string, content parts or
+// provider-formatted content blocks
+func contentText(content interface{}) string {
+	switch v := content.(type) {
+	case string:
+		return v
+	case []model.ContentPart:
+		var texts []string
+		for _, part := range v {
+			if part.Type == model.ContentTypeText {
+				texts = append(texts, part.Text)
+			}
+		}
+		return strings.Join(texts, "\n")
+	case []interface{}:
+		var texts []string
+		for _, block := range v {
+			if m, ok := block.(map[string]interface{}); ok {
+				if text, ok := m["text"].(string); ok {
+					texts = append(texts, text)
+				}
+			}
+		}
+		return strings.Join(texts, "\n")
+	case []map[string]interface{}:
+		var texts []string
+		for _, block := range v {
+			if text, ok := block["text"].(string); ok {
+				texts = append(texts, text)
+			}
+		}
+		return strings.Join(texts, "\n")
+	}
+	return ""
+}
@@ -3,6 +3,7 @@ package result
 import (
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
 )
 
 // RunItem represents an item generated during a run
@@ -58,6 +59,14 @@ func (i *ToolCallItem) ToInputItem() interface{} {
 type ToolResultItem struct {
 	Name   string
 	Result interface{}
+
+	// Execution describes what tool middleware did, such as retries and cache
+	// hits. It is nil when the tool has no middleware.
+	Execution *tool.ExecutionInfo
+
+	// Error is the error of a failed call, such as one wrapping
+	// runner.ErrToolNotFound. The model receives it as the result text.
+	Error error `json:"-"`
 }
 
 // GetType returns the type of the item
@@ -94,8 +103,31 @@ func (i *HandoffItem) ToInputItem() interface{} {
 	}
 }
 
+// MediaItem represents an image or other media produced by a model
+type MediaItem struct {
+	AgentName string
+	Media     model.MediaPart
+}
+
+// GetType returns the type of the item
+func (i *MediaItem) GetType() string {
+	return "media"
+}
+
+// ToInputItem converts the item to an input item
+func (i *MediaItem) ToInputItem() interface{} {
+	return map[string]interface{}{
+		"type":       "media",
+		"agent_name": i.AgentName,
+		"media":      i.Media,
+	}
+}
+
 // RunResult contains the result of an agent run
 type RunResult struct {
+	// RunID identifies the run, see runner.RunOptions.RunID
+	RunID string
+
 	// Input is the original input to the run
 	Input interface{}
 
@@ -105,6 +137,10 @@ type RunResult struct {
 	// RawResponses are the raw LLM responses generated during the run
 	RawResponses []model.Response
 
+	// Usage is the token usage and estimated cost of the run, including model
+	// calls made to compact the history and the runs of broadcast handoffs
+	Usage Usage
+
 	// FinalOutput is the output of the last agent
 	FinalOutput interface{}
 
@@ -116,6 +152,35 @@ type RunResult struct {
 
 	// LastAgent is the last agent that was run
 	LastAgent *agent.Agent
+
+	// ModelFallbacks are the model calls that failed and were retried on a
+	// fallback model, in order
+	ModelFallbacks []ModelFallback
+
+	// WorkspaceArchive is the ID of the artifact the run's workspace was archived
+	// to, if the workspace manager archives workspaces
+	WorkspaceArchive string
+
+	// Cancelled is true for runs that were cancelled. Their result holds the items
+	// generated before the run stopped.
+	Cancelled bool
+}
+
+// ModelFallback records a model call that was retried on a fallback model
+type ModelFallback struct {
+	// Agent is the name of the agent whose model call failed
+	Agent string
+
+	// Turn is the turn of the model call
+	Turn int
+
+	// From is the model that failed and To the fallback model the call was
+	// retried on
+	From string
+	To   string
+
+	// Error is the error the failed model returned
+	Error error
 }
 
 // GuardrailResult represents the result of a guardrail check
@@ -144,6 +209,8 @@ func (r *RunResult) ToInputList() []interface{} {
 			Content: input,
 		}
 		result = append(result, messageItem.ToInputItem())
+	} else if parts, ok := r.Input.([]model.ContentPart); ok {
+		result = append(result, model.UserMessage(parts...))
 	} else if inputList, ok := r.Input.([]interface{}); ok {
 		result = append(result, inputList...)
 	}
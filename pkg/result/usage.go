@@ -0,0 +1,108 @@
+package result
+
+import "github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+
+// Usage is the token usage and estimated cost of a run, in total and split by
+// agent and model
+type Usage struct {
+	// ModelCalls is the number of model responses
+	ModelCalls int
+
+	// Total is the usage of all model responses
+	Total model.Usage
+
+	// CostUSD is the estimated cost of the run. Responses of models without
+	// pricing add no cost.
+	CostUSD float64
+
+	// ByAgent and ByModel split the usage by agent name and model name. Model
+	// instances without a name are counted under "".
+	ByAgent map[string]*UsageEntry
+	ByModel map[string]*UsageEntry
+}
+
+// UsageEntry is the usage of one agent or model
+type UsageEntry struct {
+	// ModelCalls is the number of model responses
+	ModelCalls int
+
+	// Usage is the token usage of the responses
+	Usage model.Usage
+
+	// CostUSD is the estimated cost of the responses
+	CostUSD float64
+}
+
+// Add adds a model response of an agent to the usage
+func (u *Usage) Add(agentName, modelName string, usage *model.Usage, costUSD float64) {
+	if u.ByAgent == nil {
+		u.ByAgent = make(map[string]*UsageEntry)
+	}
+	if u.ByModel == nil {
+		u.ByModel = make(map[string]*UsageEntry)
+	}
+	u.ModelCalls++
+	u.CostUSD += costUSD
+	addUsage(&u.Total, usage)
+	for _, entry := range []*UsageEntry{usageEntry(u.ByAgent, agentName), usageEntry(u.ByModel, modelName)} {
+		entry.ModelCalls++
+		entry.CostUSD += costUSD
+		addUsage(&entry.Usage, usage)
+	}
+}
+
+// Merge adds the usage of another run, such as a sub-run
+func (u *Usage) Merge(other Usage) {
+	if other.ModelCalls == 0 {
+		return
+	}
+	if u.ByAgent == nil {
+		u.ByAgent = make(map[string]*UsageEntry)
+	}
+	if u.ByModel == nil {
+		u.ByModel = make(map[string]*UsageEntry)
+	}
+	u.ModelCalls += other.ModelCalls
+	u.CostUSD += other.CostUSD
+	addUsage(&u.Total, &other.Total)
+	for name, e := range other.ByAgent {
+		mergeEntry(usageEntry(u.ByAgent, name), e)
+	}
+	for name, e := range other.ByModel {
+		mergeEntry(usageEntry(u.ByModel, name), e)
+	}
+}
+
+// usageEntry returns the entry of a name, creating it if needed
+func usageEntry(entries map[string]*UsageEntry, name string) *UsageEntry {
+	entry, ok := entries[name]
+	if !ok {
+		entry = &UsageEntry{}
+		entries[name] = entry
+	}
+	return entry
+}
+
+// mergeEntry adds one entry to another
+func mergeEntry(entry, other *UsageEntry) {
+	entry.ModelCalls += other.ModelCalls
+	entry.CostUSD += other.CostUSD
+	addUsage(&entry.Usage, &other.Usage)
+}
+
+// addUsage adds a usage to a total. Responses that only report prompt and
+// completion tokens count their sum as total tokens.
+func addUsage(total *model.Usage, usage *model.Usage) {
+	if usage == nil {
+		return
+	}
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	if usage.TotalTokens == 0 {
+		total.TotalTokens += usage.PromptTokens + usage.CompletionTokens
+	} else {
+		total.TotalTokens += usage.TotalTokens
+	}
+	total.CachedTokens += usage.CachedTokens
+	total.CacheCreationTokens += usage.CacheCreationTokens
+}
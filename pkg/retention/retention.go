@@ -0,0 +1,198 @@
+// Package retention purges the prompts, outputs and personal data that runs
+// leave in storage once they are older than a retention period, and deletes the
+// data of a user on request, as SOC 2 controls and GDPR deletion requests need.
+//
+// Every storage backend that keeps run content implements Store. Purging redacts
+// content rather than dropping whole records where it can, so token usage and
+// cost stay available as anonymized metrics:
+//
+//	manager := retention.New(30 * 24 * time.Hour).
+//		WithStore("events", eventStore).
+//		WithStore("postgres", pgStore).
+//		WithStore("traces", tracing.NewFileRetention("."))
+//	go manager.Start(ctx)
+//
+//	// Handle a GDPR deletion request
+//	reports, err := manager.Forget(ctx, "user-42")
+//
+// Runs are attributed to users with runner.RunOptions.UserID.
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/memory"
+)
+
+// DefaultInterval is how often a started manager purges expired content
+const DefaultInterval = time.Hour
+
+// Report counts what a store purged or deleted
+type Report struct {
+	// Store is the name the store was added to the manager with
+	Store string
+
+	// Redacted is the number of records whose content was removed while their
+	// anonymized metrics were kept
+	Redacted int
+
+	// Deleted is the number of records removed entirely
+	Deleted int
+}
+
+// Store is a storage backend holding run content
+type Store interface {
+	// Purge removes the content stored before cutoff
+	Purge(ctx context.Context, cutoff time.Time) (Report, error)
+
+	// Forget removes the content stored for a user
+	Forget(ctx context.Context, userID string) (Report, error)
+}
+
+// namedStore is a store with the name it was added with
+type namedStore struct {
+	name  string
+	store Store
+}
+
+// Manager applies a retention period to stores
+type Manager struct {
+	maxAge   time.Duration
+	interval time.Duration
+	stores   []namedStore
+	now      func() time.Time
+	mu       sync.RWMutex
+}
+
+// New creates a manager purging content older than maxAge
+func New(maxAge time.Duration) *Manager {
+	return &Manager{maxAge: maxAge, interval: DefaultInterval, now: time.Now}
+}
+
+// WithStore adds a store under a name used in reports and logs
+func (m *Manager) WithStore(name string, store Store) *Manager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stores = append(m.stores, namedStore{name: name, store: store})
+	return m
+}
+
+// WithInterval sets how often Start purges expired content
+func (m *Manager) WithInterval(interval time.Duration) *Manager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.interval = interval
+	return m
+}
+
+// WithClock sets the function returning the current time, for tests
+func (m *Manager) WithClock(now func() time.Time) *Manager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = now
+	return m
+}
+
+// MaxAge returns the retention period
+func (m *Manager) MaxAge() time.Duration {
+	return m.maxAge
+}
+
+// Purge removes the content older than the retention period from every store.
+// A failing store does not stop the others; their errors are joined.
+func (m *Manager) Purge(ctx context.Context) ([]Report, error) {
+	m.mu.RLock()
+	cutoff := m.now().Add(-m.maxAge)
+	m.mu.RUnlock()
+	return m.each(func(s Store) (Report, error) { return s.Purge(ctx, cutoff) }, "purge")
+}
+
+// Forget removes the content of a user from every store. A failing store does
+// not stop the others; their errors are joined, and the request should be
+// retried until it succeeds.
+func (m *Manager) Forget(ctx context.Context, userID string) ([]Report, error) {
+	if userID == "" {
+		return nil, errors.New("user ID is required")
+	}
+	return m.each(func(s Store) (Report, error) { return s.Forget(ctx, userID) }, "forget")
+}
+
+// each runs an operation on every store
+func (m *Manager) each(op func(Store) (Report, error), action string) ([]Report, error) {
+	m.mu.RLock()
+	stores := append([]namedStore(nil), m.stores...)
+	m.mu.RUnlock()
+
+	reports := make([]Report, 0, len(stores))
+	var errs []error
+	for _, s := range stores {
+		report, err := op(s.store)
+		report.Store = s.name
+		reports = append(reports, report)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", action, s.name, err))
+			continue
+		}
+		logging.For(nil, "retention").Info("Applied retention", "action", action, "store", s.name, "redacted", report.Redacted, "deleted", report.Deleted)
+	}
+	return reports, errors.Join(errs...)
+}
+
+// Start purges expired content right away and then at every interval until the
+// context is done
+func (m *Manager) Start(ctx context.Context) {
+	m.mu.RLock()
+	interval := m.interval
+	m.mu.RUnlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := m.Purge(ctx); err != nil {
+			logging.For(nil, "retention").Error("Failed to purge expired content", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SessionStore adapts memory sessions, which have no timestamps of their own, to
+// Store. Forget clears the sessions that lookup returns for a user. Purge does
+// nothing: give sessions that support it a TTL, such as redis.Session.WithTTL.
+type SessionStore struct {
+	lookup func(ctx context.Context, userID string) ([]memory.Session, error)
+}
+
+// Sessions returns a store forgetting the sessions lookup finds for a user
+func Sessions(lookup func(ctx context.Context, userID string) ([]memory.Session, error)) *SessionStore {
+	return &SessionStore{lookup: lookup}
+}
+
+// Purge does nothing, since sessions do not record when their items were added
+func (s *SessionStore) Purge(ctx context.Context, cutoff time.Time) (Report, error) {
+	return Report{}, nil
+}
+
+// Forget clears the sessions of a user
+func (s *SessionStore) Forget(ctx context.Context, userID string) (Report, error) {
+	sessions, err := s.lookup(ctx, userID)
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to look up sessions of user: %w", err)
+	}
+	var report Report
+	for _, session := range sessions {
+		if err := session.Clear(ctx); err != nil {
+			return report, fmt.Errorf("failed to clear session %s: %w", session.ID(), err)
+		}
+		report.Deleted++
+	}
+	return report, nil
+}
@@ -0,0 +1,86 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// MemoryStore is a VectorStore that keeps records in memory and compares them by
+// cosine similarity. It suits tests and small, static knowledge bases.
+type MemoryStore struct {
+	records   map[string]Record
+	dimension int
+	mu        sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]Record)}
+}
+
+// Len returns the number of stored records
+func (s *MemoryStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.records)
+}
+
+// Upsert adds records, replacing records with the same ID
+func (s *MemoryStore) Upsert(ctx context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, record := range records {
+		if s.dimension == 0 {
+			s.dimension = len(record.Vector)
+		}
+		if len(record.Vector) != s.dimension {
+			return fmt.Errorf("record %s has %d dimensions, expected %d: %w", record.ID, len(record.Vector), s.dimension, ErrDimensionMismatch)
+		}
+	}
+	for _, record := range records {
+		s.records[record.ID] = record
+	}
+	return nil
+}
+
+// Query returns the topK records most similar to the vector
+func (s *MemoryStore) Query(ctx context.Context, vector []float32, topK int, filter Filter) ([]SearchResult, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.dimension != 0 && len(vector) != s.dimension {
+		return nil, fmt.Errorf("query has %d dimensions, expected %d: %w", len(vector), s.dimension, ErrDimensionMismatch)
+	}
+
+	results := make([]SearchResult, 0, len(s.records))
+	for _, record := range s.records {
+		if !matchesFilter(record.Metadata, filter) {
+			continue
+		}
+		results = append(results, SearchResult{Document: record.Document, Score: CosineSimilarity(vector, record.Vector)})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score == results[j].Score {
+			return results[i].ID < results[j].ID
+		}
+		return results[i].Score > results[j].Score
+	})
+	if topK = clampTopK(topK); len(results) > topK {
+		results = results[:topK]
+	}
+	return results, nil
+}
+
+// Delete removes records by ID
+func (s *MemoryStore) Delete(ctx context.Context, ids ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		delete(s.records, id)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package retrieval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultOpenAIEmbeddingModel is the embedding model used by default
+	DefaultOpenAIEmbeddingModel = "text-embedding-3-small"
+
+	// openAIEmbeddingBatchSize is the number of texts sent in one request
+	openAIEmbeddingBatchSize = 512
+)
+
+// OpenAIEmbedder embeds texts with the OpenAI embeddings API
+type OpenAIEmbedder struct {
+	apiKey     string
+	model      string
+	dimensions int
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewOpenAIEmbedder creates an embedder for the OpenAI API
+func NewOpenAIEmbedder(apiKey string) *OpenAIEmbedder {
+	return &OpenAIEmbedder{
+		apiKey:     apiKey,
+		model:      DefaultOpenAIEmbeddingModel,
+		baseURL:    "https://api.openai.com/v1",
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// WithModel sets the embedding model
+func (e *OpenAIEmbedder) WithModel(model string) *OpenAIEmbedder {
+	e.model = model
+	return e
+}
+
+// WithDimensions shortens the embeddings to the given number of dimensions
+func (e *OpenAIEmbedder) WithDimensions(dimensions int) *OpenAIEmbedder {
+	e.dimensions = dimensions
+	return e
+}
+
+// WithBaseURL sets the API base URL, for OpenAI-compatible servers
+func (e *OpenAIEmbedder) WithBaseURL(baseURL string) *OpenAIEmbedder {
+	e.baseURL = strings.TrimSuffix(baseURL, "/")
+	return e
+}
+
+// WithHTTPClient sets the HTTP client
+func (e *OpenAIEmbedder) WithHTTPClient(client *http.Client) *OpenAIEmbedder {
+	e.httpClient = client
+	return e
+}
+
+// embeddingRequest is the body of an embeddings request
+type embeddingRequest struct {
+	Model      string   `json:"model"`
+	Input      []string `json:"input"`
+	Dimensions int      `json:"dimensions,omitempty"`
+}
+
+// embeddingResponse is the body of an embeddings response
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed returns one vector per text
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += openAIEmbeddingBatchSize {
+		end := min(start+openAIEmbeddingBatchSize, len(texts))
+		batch, err := e.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+// embedBatch embeds texts with a single request
+func (e *OpenAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(embeddingRequest{Model: e.model, Input: texts, Dimensions: e.dimensions})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+e.apiKey)
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var decoded embeddingResponse
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(decoded.Data) != len(texts) {
+		return nil, fmt.Errorf("received %d embeddings for %d texts", len(decoded.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, item := range decoded.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, nil
+}
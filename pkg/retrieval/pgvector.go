@@ -0,0 +1,163 @@
+package retrieval
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultPGVectorTable is the table used by PGVectorStore
+const DefaultPGVectorTable = "agent_documents"
+
+// PGVectorStore is a VectorStore backed by Postgres with the pgvector extension.
+// The caller opens the *sql.DB with a driver of their choice (e.g. pgx or lib/pq).
+type PGVectorStore struct {
+	db        *sql.DB
+	table     string
+	dimension int
+}
+
+// NewPGVectorStore creates a store for vectors of the given dimension. Call Migrate
+// once to create the table.
+func NewPGVectorStore(db *sql.DB, dimension int) *PGVectorStore {
+	return &PGVectorStore{db: db, table: DefaultPGVectorTable, dimension: dimension}
+}
+
+// WithTable sets the table name
+func (s *PGVectorStore) WithTable(table string) *PGVectorStore {
+	s.table = table
+	return s
+}
+
+// Migrate creates the pgvector extension, the table and its HNSW index if they don't exist
+func (s *PGVectorStore) Migrate(ctx context.Context) error {
+	statements := []string{
+		`CREATE EXTENSION IF NOT EXISTS vector`,
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id TEXT PRIMARY KEY,
+	content TEXT NOT NULL,
+	metadata JSONB NOT NULL DEFAULT '{}',
+	embedding vector(%d) NOT NULL
+)`, s.table, s.dimension),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_embedding_idx ON %s USING hnsw (embedding vector_cosine_ops)`, s.table, s.table),
+	}
+
+	for _, stmt := range statements {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to migrate vector store: %w", err)
+		}
+	}
+	return nil
+}
+
+// Upsert adds records, replacing records with the same ID
+func (s *PGVectorStore) Upsert(ctx context.Context, records []Record) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`INSERT INTO %s (id, content, metadata, embedding)
+VALUES ($1, $2, $3::jsonb, $4::vector)
+ON CONFLICT (id) DO UPDATE SET content = excluded.content, metadata = excluded.metadata, embedding = excluded.embedding`, s.table)
+
+	for _, record := range records {
+		if len(record.Vector) != s.dimension {
+			return fmt.Errorf("record %s has %d dimensions, expected %d: %w", record.ID, len(record.Vector), s.dimension, ErrDimensionMismatch)
+		}
+		metadata, err := json.Marshal(record.Metadata)
+		if err != nil {
+			return fmt.Errorf("failed to encode metadata of %s: %w", record.ID, err)
+		}
+		if record.Metadata == nil {
+			metadata = []byte("{}")
+		}
+		if _, err := tx.ExecContext(ctx, query, record.ID, record.Content, string(metadata), vectorLiteral(record.Vector)); err != nil {
+			return fmt.Errorf("failed to store %s: %w", record.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit documents: %w", err)
+	}
+	return nil
+}
+
+// Query returns the topK records most similar to the vector
+func (s *PGVectorStore) Query(ctx context.Context, vector []float32, topK int, filter Filter) ([]SearchResult, error) {
+	if len(vector) != s.dimension {
+		return nil, fmt.Errorf("query has %d dimensions, expected %d: %w", len(vector), s.dimension, ErrDimensionMismatch)
+	}
+
+	filterJSON := []byte("{}")
+	if len(filter) > 0 {
+		var err error
+		if filterJSON, err = json.Marshal(filter); err != nil {
+			return nil, fmt.Errorf("failed to encode filter: %w", err)
+		}
+	}
+
+	query := fmt.Sprintf(`SELECT id, content, metadata, 1 - (embedding <=> $1::vector) AS score
+FROM %s
+WHERE metadata @> $2::jsonb
+ORDER BY embedding <=> $1::vector
+LIMIT $3`, s.table)
+
+	rows, err := s.db.QueryContext(ctx, query, vectorLiteral(vector), string(filterJSON), clampTopK(topK))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	var results []SearchResult
+	for rows.Next() {
+		var result SearchResult
+		var metadata []byte
+		if err := rows.Scan(&result.ID, &result.Content, &metadata, &result.Score); err != nil {
+			return nil, fmt.Errorf("failed to scan document: %w", err)
+		}
+		if err := json.Unmarshal(metadata, &result.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to decode metadata of %s: %w", result.ID, err)
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// Delete removes records by ID
+func (s *PGVectorStore) Delete(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for n, id := range ids {
+		placeholders[n] = "$" + strconv.Itoa(n+1)
+		args[n] = id
+	}
+
+	query := fmt.Sprintf(`DELETE FROM %s WHERE id IN (%s)`, s.table, strings.Join(placeholders, ", "))
+	if _, err := s.db.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to delete documents: %w", err)
+	}
+	return nil
+}
+
+// vectorLiteral formats a vector in pgvector's text representation
+func vectorLiteral(vector []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for n, value := range vector {
+		if n > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(value), 'f', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
@@ -0,0 +1,172 @@
+package retrieval
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// qdrantIDKey is the payload key holding the document ID, since Qdrant only
+// accepts integers and UUIDs as point IDs
+const qdrantIDKey = "_id"
+
+// QdrantStore is a VectorStore backed by a Qdrant collection, using its REST API
+type QdrantStore struct {
+	baseURL    string
+	collection string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewQdrantStore creates a store for a collection of the Qdrant server at baseURL,
+// such as http://localhost:6333. Call CreateCollection if the collection does not exist.
+func NewQdrantStore(baseURL, collection string) *QdrantStore {
+	return &QdrantStore{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		collection: collection,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// WithAPIKey sets the API key for Qdrant Cloud
+func (s *QdrantStore) WithAPIKey(apiKey string) *QdrantStore {
+	s.apiKey = apiKey
+	return s
+}
+
+// WithHTTPClient sets the HTTP client
+func (s *QdrantStore) WithHTTPClient(client *http.Client) *QdrantStore {
+	s.httpClient = client
+	return s
+}
+
+// CreateCollection creates the collection for vectors of the given dimension,
+// compared by cosine similarity
+func (s *QdrantStore) CreateCollection(ctx context.Context, dimension int) error {
+	body := map[string]interface{}{
+		"vectors": map[string]interface{}{"size": dimension, "distance": "Cosine"},
+	}
+	return s.do(ctx, http.MethodPut, "", body, nil)
+}
+
+// Upsert adds records, replacing records with the same ID
+func (s *QdrantStore) Upsert(ctx context.Context, records []Record) error {
+	points := make([]map[string]interface{}, len(records))
+	for n, record := range records {
+		payload := map[string]interface{}{"content": record.Content, qdrantIDKey: record.ID}
+		for key, value := range record.Metadata {
+			payload[key] = value
+		}
+		points[n] = map[string]interface{}{
+			"id":      qdrantPointID(record.ID),
+			"vector":  record.Vector,
+			"payload": payload,
+		}
+	}
+	return s.do(ctx, http.MethodPut, "/points?wait=true", map[string]interface{}{"points": points}, nil)
+}
+
+// Query returns the topK records most similar to the vector
+func (s *QdrantStore) Query(ctx context.Context, vector []float32, topK int, filter Filter) ([]SearchResult, error) {
+	body := map[string]interface{}{
+		"vector":       vector,
+		"limit":        clampTopK(topK),
+		"with_payload": true,
+	}
+	if len(filter) > 0 {
+		must := make([]map[string]interface{}, 0, len(filter))
+		for key, value := range filter {
+			must = append(must, map[string]interface{}{"key": key, "match": map[string]interface{}{"value": value}})
+		}
+		body["filter"] = map[string]interface{}{"must": must}
+	}
+
+	var decoded struct {
+		Result []struct {
+			Score   float64                `json:"score"`
+			Payload map[string]interface{} `json:"payload"`
+		} `json:"result"`
+	}
+	if err := s.do(ctx, http.MethodPost, "/points/search", body, &decoded); err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(decoded.Result))
+	for _, point := range decoded.Result {
+		document := Document{Metadata: make(map[string]interface{})}
+		for key, value := range point.Payload {
+			switch key {
+			case qdrantIDKey:
+				document.ID, _ = value.(string)
+			case "content":
+				document.Content, _ = value.(string)
+			default:
+				document.Metadata[key] = value
+			}
+		}
+		results = append(results, SearchResult{Document: document, Score: point.Score})
+	}
+	return results, nil
+}
+
+// Delete removes records by ID
+func (s *QdrantStore) Delete(ctx context.Context, ids ...string) error {
+	points := make([]string, len(ids))
+	for n, id := range ids {
+		points[n] = qdrantPointID(id)
+	}
+	return s.do(ctx, http.MethodPost, "/points/delete?wait=true", map[string]interface{}{"points": points}, nil)
+}
+
+// do sends a request to the collection and decodes the JSON response into out
+func (s *QdrantStore) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	endpoint := s.baseURL + "/collections/" + url.PathEscape(s.collection) + path
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("api-key", s.apiKey)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("qdrant request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	if out != nil {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+// qdrantPointID derives a stable UUID from a document ID
+func qdrantPointID(id string) string {
+	sum := sha1.Sum([]byte(id))
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
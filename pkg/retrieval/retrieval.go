@@ -0,0 +1,196 @@
+// Package retrieval gives agents knowledge-grounded answers: documents are embedded
+// with an Embedder, kept in a VectorStore, and found again by the search tool.
+//
+//	index := retrieval.NewIndex(retrieval.NewOpenAIEmbedder(apiKey), retrieval.NewMemoryStore())
+//	index.Add(ctx, retrieval.Document{ID: "refunds", Content: "Refunds are issued within 14 days."})
+//	support := agent.NewAgent("Support").WithTools(retrieval.NewSearchTool(index))
+package retrieval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+)
+
+// DefaultTopK is the number of documents returned when the model does not ask for a number
+const DefaultTopK = 5
+
+// MaxTopK is the largest number of documents a single search returns
+const MaxTopK = 50
+
+// ErrDimensionMismatch is returned for vectors of the wrong length
+var ErrDimensionMismatch = errors.New("vector dimension mismatch")
+
+// Document is a piece of knowledge that can be retrieved
+type Document struct {
+	ID       string                 `json:"id"`
+	Content  string                 `json:"content"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Record is a document with its embedding
+type Record struct {
+	Document
+	Vector []float32 `json:"-"`
+}
+
+// SearchResult is a document found by a search, with its similarity to the query
+type SearchResult struct {
+	Document
+	Score float64 `json:"score"`
+}
+
+// Filter restricts a search to documents whose metadata has the given values
+type Filter map[string]interface{}
+
+// Embedder turns texts into vectors
+type Embedder interface {
+	// Embed returns one vector per text, in order
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// VectorStore stores embedded documents and finds the ones closest to a vector
+type VectorStore interface {
+	// Upsert adds records, replacing records with the same ID
+	Upsert(ctx context.Context, records []Record) error
+
+	// Query returns the topK records most similar to the vector, best first
+	Query(ctx context.Context, vector []float32, topK int, filter Filter) ([]SearchResult, error)
+
+	// Delete removes records by ID
+	Delete(ctx context.Context, ids ...string) error
+}
+
+// Searcher finds documents for a text query
+type Searcher interface {
+	// Search returns the topK documents most relevant to the query
+	Search(ctx context.Context, query string, topK int, filter Filter) ([]SearchResult, error)
+}
+
+// Index combines an embedder and a vector store
+type Index struct {
+	embedder Embedder
+	store    VectorStore
+	minScore float64
+}
+
+// NewIndex creates an index that embeds documents and queries with the embedder
+func NewIndex(embedder Embedder, store VectorStore) *Index {
+	return &Index{embedder: embedder, store: store}
+}
+
+// WithMinScore drops search results less similar to the query than the score
+func (i *Index) WithMinScore(score float64) *Index {
+	i.minScore = score
+	return i
+}
+
+// Store returns the vector store of the index
+func (i *Index) Store() VectorStore {
+	return i.store
+}
+
+// Add embeds and stores documents
+func (i *Index) Add(ctx context.Context, documents ...Document) error {
+	if len(documents) == 0 {
+		return nil
+	}
+
+	texts := make([]string, len(documents))
+	for n, document := range documents {
+		if document.ID == "" {
+			return fmt.Errorf("document %d has no ID", n)
+		}
+		texts[n] = document.Content
+	}
+
+	vectors, err := i.embedder.Embed(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed documents: %w", err)
+	}
+	if len(vectors) != len(documents) {
+		return fmt.Errorf("embedder returned %d vectors for %d documents", len(vectors), len(documents))
+	}
+
+	records := make([]Record, len(documents))
+	for n, document := range documents {
+		records[n] = Record{Document: document, Vector: vectors[n]}
+	}
+	if err := i.store.Upsert(ctx, records); err != nil {
+		return fmt.Errorf("failed to store documents: %w", err)
+	}
+
+	logging.For(nil, "retrieval").Debug("Indexed documents", "documents", len(documents))
+	return nil
+}
+
+// Delete removes documents by ID
+func (i *Index) Delete(ctx context.Context, ids ...string) error {
+	return i.store.Delete(ctx, ids...)
+}
+
+// Search embeds the query and returns the most similar documents
+func (i *Index) Search(ctx context.Context, query string, topK int, filter Filter) ([]SearchResult, error) {
+	vectors, err := i.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embedder returned %d vectors for the query", len(vectors))
+	}
+
+	results, err := i.store.Query(ctx, vectors[0], topK, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vector store: %w", err)
+	}
+
+	if i.minScore > 0 {
+		kept := results[:0]
+		for _, result := range results {
+			if result.Score >= i.minScore {
+				kept = append(kept, result)
+			}
+		}
+		results = kept
+	}
+	return results, nil
+}
+
+// CosineSimilarity returns the cosine similarity of two vectors of equal length
+func CosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for n := range a {
+		dot += float64(a[n]) * float64(b[n])
+		normA += float64(a[n]) * float64(a[n])
+		normB += float64(b[n]) * float64(b[n])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// matchesFilter reports whether metadata has every value of the filter
+func matchesFilter(metadata map[string]interface{}, filter Filter) bool {
+	for key, want := range filter {
+		got, ok := metadata[key]
+		if !ok || fmt.Sprint(got) != fmt.Sprint(want) {
+			return false
+		}
+	}
+	return true
+}
+
+// clampTopK keeps a result count between 1 and MaxTopK
+func clampTopK(n int) int {
+	if n < 1 {
+		return DefaultTopK
+	}
+	if n > MaxTopK {
+		return MaxTopK
+	}
+	return n
+}
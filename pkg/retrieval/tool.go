@@ -0,0 +1,109 @@
+package retrieval
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SearchTool is a tool that searches a knowledge base
+type SearchTool struct {
+	name        string
+	description string
+	searcher    Searcher
+	topK        int
+	filter      Filter
+}
+
+// SearchResponse is the result of the search tool
+type SearchResponse struct {
+	Query     string         `json:"query"`
+	Documents []SearchResult `json:"documents"`
+}
+
+// NewSearchTool creates a search_knowledge_base tool. The searcher is usually an Index.
+func NewSearchTool(store Searcher) *SearchTool {
+	return &SearchTool{
+		name:        "search_knowledge_base",
+		description: "Search the knowledge base for documents relevant to a question. Base your answer on the returned documents.",
+		searcher:    store,
+		topK:        DefaultTopK,
+	}
+}
+
+// WithName sets the name of the tool
+func (t *SearchTool) WithName(name string) *SearchTool {
+	t.name = name
+	return t
+}
+
+// WithDescription sets the description of the tool, such as what the knowledge base contains
+func (t *SearchTool) WithDescription(description string) *SearchTool {
+	t.description = description
+	return t
+}
+
+// WithTopK sets the number of documents returned when the model does not ask for a number
+func (t *SearchTool) WithTopK(topK int) *SearchTool {
+	t.topK = clampTopK(topK)
+	return t
+}
+
+// WithFilter restricts every search to documents with the given metadata
+func (t *SearchTool) WithFilter(filter Filter) *SearchTool {
+	t.filter = filter
+	return t
+}
+
+// GetName returns the name of the tool
+func (t *SearchTool) GetName() string {
+	return t.name
+}
+
+// GetDescription returns the description of the tool
+func (t *SearchTool) GetDescription() string {
+	return t.description
+}
+
+// GetParametersSchema returns the JSON schema for the tool parameters
+func (t *SearchTool) GetParametersSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "What to search for",
+			},
+			"top_k": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Number of documents to return, at most %d", MaxTopK),
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+// Execute runs the search
+func (t *SearchTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	query, _ := params["query"].(string)
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("query parameter is required")
+	}
+
+	topK := t.topK
+	switch n := params["top_k"].(type) {
+	case float64:
+		topK = clampTopK(int(n))
+	case int:
+		topK = clampTopK(n)
+	}
+
+	results, err := t.searcher.Search(ctx, query, topK, t.filter)
+	if err != nil {
+		return nil, err
+	}
+	if results == nil {
+		results = []SearchResult{}
+	}
+	return &SearchResponse{Query: query, Documents: results}, nil
+}
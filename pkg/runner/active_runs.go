@@ -0,0 +1,313 @@
+package runner
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tracing"
+)
+
+var (
+	// ErrRunNotActive is returned for run IDs the runner is not currently running
+	ErrRunNotActive = errors.New("run is not active")
+
+	// ErrRunCancelled is returned by runs stopped with Cancel
+	ErrRunCancelled = errors.New("run cancelled")
+
+	// errRunCompleted stops runs completed with CompleteRun
+	errRunCompleted = errors.New("run completed by operator")
+)
+
+// ActiveRun describes a run in progress
+type ActiveRun struct {
+	// ID identifies the run, see RunOptions.RunID
+	ID string `json:"id"`
+
+	// StartingAgent is the name of the agent the run started with
+	StartingAgent string `json:"starting_agent"`
+
+	// CurrentAgent is the name of the agent whose turn is in progress
+	CurrentAgent string `json:"current_agent"`
+
+	// Turn is the turn in progress
+	Turn int `json:"turn"`
+
+	// Streaming is true for runs started with RunStreaming
+	Streaming bool `json:"streaming"`
+
+	// UserID is the end user the run acts for
+	UserID string `json:"user_id,omitempty"`
+
+	// StartedAt is when the run started
+	StartedAt time.Time `json:"started_at"`
+}
+
+// RunSnapshot is the progress of an active run
+type RunSnapshot struct {
+	ActiveRun
+
+	// Input is the input the run started with
+	Input interface{} `json:"input"`
+
+	// Items are the items generated before the current turn started
+	Items []result.RunItem `json:"-"`
+}
+
+// activeRun is the runner's record of a run in progress
+type activeRun struct {
+	mu       sync.Mutex
+	info     ActiveRun
+	input    interface{}
+	items    []result.RunItem
+	cancel   context.CancelCauseFunc
+	stop     context.CancelFunc
+	complete bool
+	output   interface{}
+
+	// child is true for runs started by another active run, such as the
+	// executors of a broadcast handoff
+	child bool
+
+	// done is closed when the run has returned
+	done chan struct{}
+
+	// status and err record how the run ended
+	status string
+	err    string
+}
+
+// trackRun registers a run as active under id, or under a generated ID if id is
+// empty. The returned context is cancelled when an operator cancels or completes
+// the run.
+func (r *Runner) trackRun(ctx context.Context, id string, agent AgentType, input interface{}, opts *RunOptions, streaming bool) (context.Context, *activeRun, error) {
+	if id == "" {
+		id = generateRunID()
+	}
+
+	ctx, stop := withRunDeadline(ctx, opts)
+	ctx, cancel := context.WithCancelCause(ctx)
+	run := &activeRun{
+		info: ActiveRun{
+			ID:            id,
+			StartingAgent: agent.Name,
+			CurrentAgent:  agent.Name,
+			Streaming:     streaming,
+			UserID:        opts.UserID,
+			StartedAt:     time.Now(),
+		},
+		input:  input,
+		cancel: cancel,
+		stop:   stop,
+		done:   make(chan struct{}),
+	}
+
+	r.mu.RLock()
+	limit := r.maxConcurrentRuns
+	r.mu.RUnlock()
+
+	r.activeMu.Lock()
+	defer r.activeMu.Unlock()
+
+	// Runs started by an active run use the slot of their parent
+	if parentID := RunIDFromContext(ctx); parentID != "" {
+		_, run.child = r.activeRuns[parentID]
+	}
+
+	if r.shutdown && !run.child {
+		cancel(nil)
+		stop()
+		return nil, nil, ErrRunnerShutdown
+	}
+	if _, exists := r.activeRuns[id]; exists {
+		cancel(nil)
+		stop()
+		return nil, nil, fmt.Errorf("run %s is already active", id)
+	}
+	if active := r.topLevelRuns(); limit > 0 && !run.child && active >= limit {
+		cancel(nil)
+		stop()
+		return nil, nil, fmt.Errorf("%w: %d runs are active", ErrTooManyRuns, active)
+	}
+	if r.activeRuns == nil {
+		r.activeRuns = make(map[string]*activeRun)
+	}
+	r.activeRuns[id] = run
+
+	ctx = context.WithValue(ctx, runIDKey{}, id)
+	ctx = withTaskRun(ctx, id)
+
+	// Runs started within a streamed run, such as the executors of a broadcast
+	// handoff, do not stream into it
+	ctx = context.WithValue(ctx, streamListenerKey{}, nil)
+	if opts.UserID != "" {
+		ctx = tracing.WithUserID(ctx, opts.UserID)
+	}
+	ctx = r.withEventLog(ctx, id, opts.UserID)
+	r.recordEvent(ctx, RunEvent{Type: EventRunStarted, Agent: agent.Name, Input: input})
+	return ctx, run, nil
+}
+
+// topLevelRuns counts the active runs that were not started by another run. The
+// caller must hold activeMu.
+func (r *Runner) topLevelRuns() int {
+	n := 0
+	for _, run := range r.activeRuns {
+		if !run.child {
+			n++
+		}
+	}
+	return n
+}
+
+// untrackRun removes a finished run, keeping its status in the run history
+func (r *Runner) untrackRun(run *activeRun) {
+	status := run.finishedStatus()
+	r.activeMu.Lock()
+	delete(r.activeRuns, run.info.ID)
+	r.history.add(status)
+	r.activeMu.Unlock()
+	r.releaseTaskRun(run.info.ID)
+	run.cancel(nil)
+	run.stop()
+	close(run.done)
+}
+
+// update records the start of a turn. Items appended after the call are not
+// visible to InspectRun until the next turn.
+func (run *activeRun) update(agent AgentType, turn int, items []result.RunItem) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	run.info.CurrentAgent = agent.Name
+	run.info.Turn = turn
+	run.items = items[:len(items):len(items)]
+}
+
+// completedOutput returns the output of a run completed with CompleteRun
+func (run *activeRun) completedOutput() (interface{}, bool) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	return run.output, run.complete
+}
+
+// cancellationError returns the error a stopped run ends with
+func cancellationError(ctx context.Context, err error) error {
+	if errors.Is(context.Cause(ctx), ErrRunCancelled) && !errors.Is(err, ErrRunCancelled) {
+		return fmt.Errorf("%w: %v", ErrRunCancelled, err)
+	}
+	var deadlineErr *DeadlineExceededError
+	if errors.As(context.Cause(ctx), &deadlineErr) && !errors.Is(err, ErrDeadlineExceeded) {
+		return fmt.Errorf("%w: %v", deadlineErr, err)
+	}
+	return err
+}
+
+// ActiveRuns returns the runs in progress, oldest first
+func (r *Runner) ActiveRuns() []ActiveRun {
+	r.activeMu.Lock()
+	runs := make([]*activeRun, 0, len(r.activeRuns))
+	for _, run := range r.activeRuns {
+		runs = append(runs, run)
+	}
+	r.activeMu.Unlock()
+
+	infos := make([]ActiveRun, 0, len(runs))
+	for _, run := range runs {
+		run.mu.Lock()
+		infos = append(infos, run.info)
+		run.mu.Unlock()
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].StartedAt.Equal(infos[j].StartedAt) {
+			return infos[i].ID < infos[j].ID
+		}
+		return infos[i].StartedAt.Before(infos[j].StartedAt)
+	})
+	return infos
+}
+
+// InspectRun returns the progress of an active run
+func (r *Runner) InspectRun(id string) (*RunSnapshot, error) {
+	run, err := r.activeRun(id)
+	if err != nil {
+		return nil, err
+	}
+
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	return &RunSnapshot{
+		ActiveRun: run.info,
+		Input:     run.input,
+		Items:     append([]result.RunItem(nil), run.items...),
+	}, nil
+}
+
+// CancelRun stops an active run, see Cancel.
+//
+// Deprecated: use Cancel.
+func (r *Runner) CancelRun(id string) error {
+	return r.Cancel(id)
+}
+
+// CompleteRun stops an active run and makes it succeed with the given final output.
+// Streaming runs end with a done event carrying the output, which may follow an
+// error event for the interrupted model call.
+func (r *Runner) CompleteRun(id string, output interface{}) error {
+	run, err := r.activeRun(id)
+	if err != nil {
+		return err
+	}
+
+	run.mu.Lock()
+	run.complete = true
+	run.output = output
+	run.mu.Unlock()
+	run.cancel(errRunCompleted)
+	return nil
+}
+
+// activeRun returns the record of an active run
+func (r *Runner) activeRun(id string) (*activeRun, error) {
+	r.activeMu.Lock()
+	defer r.activeMu.Unlock()
+	run, ok := r.activeRuns[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotActive, id)
+	}
+	return run, nil
+}
+
+type runIDKey struct{}
+
+// RunIDFromContext returns the ID of the run a context belongs to, such as the
+// context passed to tools and instruction functions. It returns "" outside of a
+// run.
+func RunIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(runIDKey{}).(string)
+	return id
+}
+
+// runInstructions returns the system instructions of an agent for a turn of the
+// run of the context
+func runInstructions(ctx context.Context, a AgentType, opts *RunOptions, input interface{}, turn int) string {
+	runID := RunIDFromContext(ctx)
+	if runID == "" {
+		runID = opts.RunID
+	}
+	return a.ResolveInstructions(ctx, agent.RunContext{RunID: runID, Turn: turn, Input: input})
+}
+
+// generateRunID generates a unique run ID
+func generateRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("run-%d", time.Now().UnixNano())
+	}
+	return fmt.Sprintf("run-%x", b)
+}
@@ -0,0 +1,217 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+)
+
+// Approval request kinds
+const (
+	ApprovalKindToolCall = "tool_call"
+	ApprovalKindHandoff  = "handoff"
+)
+
+// ApprovalRequest describes a tool call or handoff that is waiting for approval
+type ApprovalRequest struct {
+	// ID identifies the request within the run
+	ID string
+
+	// Kind is ApprovalKindToolCall or ApprovalKindHandoff
+	Kind string
+
+	// AgentName is the agent that wants to perform the action
+	AgentName string
+
+	// ToolName is the tool to call, for tool calls
+	ToolName string
+
+	// TargetAgent is the agent to hand off to, for handoffs
+	TargetAgent string
+
+	// Parameters are the tool call or handoff parameters
+	Parameters map[string]interface{}
+
+	// Turn is the turn the action was requested in
+	Turn int
+}
+
+// Approval decides which tool calls and handoffs pause the run for approval
+type Approval interface {
+	// RequiresApproval reports whether the action must be approved before it runs
+	RequiresApproval(ctx context.Context, request *ApprovalRequest) bool
+}
+
+// ApprovalFunc is a function that implements Approval
+type ApprovalFunc func(ctx context.Context, request *ApprovalRequest) bool
+
+// RequiresApproval calls the function
+func (f ApprovalFunc) RequiresApproval(ctx context.Context, request *ApprovalRequest) bool {
+	return f(ctx, request)
+}
+
+// RequireApproval returns a policy that requires approval for calls to the named
+// tools and for handoffs to the named agents
+func RequireApproval(names ...string) Approval {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return ApprovalFunc(func(ctx context.Context, request *ApprovalRequest) bool {
+		if request.Kind == ApprovalKindHandoff {
+			return set[request.TargetAgent]
+		}
+		return set[request.ToolName]
+	})
+}
+
+// ApprovalDecision is the answer to an approval request
+type ApprovalDecision struct {
+	// Approved indicates whether the action may run
+	Approved bool
+
+	// Reason is passed to the model when the action is rejected
+	Reason string
+}
+
+// Approve returns a decision approving the action
+func Approve() ApprovalDecision {
+	return ApprovalDecision{Approved: true}
+}
+
+// Reject returns a decision rejecting the action for the given reason
+func Reject(reason string) ApprovalDecision {
+	return ApprovalDecision{Reason: reason}
+}
+
+// ApprovalRequiredError is returned when a run pauses for approval. Pass State to
+// Runner.Resume together with a decision to continue the run.
+type ApprovalRequiredError struct {
+	Request *ApprovalRequest
+	State   *RunState
+}
+
+// Error implements the error interface
+func (e *ApprovalRequiredError) Error() string {
+	if e.Request.Kind == ApprovalKindHandoff {
+		return fmt.Sprintf("approval required for handoff from %s to %s", e.Request.AgentName, e.Request.TargetAgent)
+	}
+	return fmt.Sprintf("approval required for tool %s called by %s", e.Request.ToolName, e.Request.AgentName)
+}
+
+// Resume continues a run paused for approval, applying the decision to its pending
+// request. The run may pause again for other actions of the same turn. States that
+// were deserialized must be resumed with ResumeFromState.
+func (r *Runner) Resume(ctx context.Context, state *RunState, decision ApprovalDecision) (*result.RunResult, error) {
+	if err := state.Decide(decision); err != nil {
+		return nil, err
+	}
+	if state.opts == nil {
+		return nil, errors.New("run state has no run options")
+	}
+	return r.resumeState(ctx, state, state.opts)
+}
+
+// nextApproval returns the first action of the response that requires approval and
+// has no decision yet
+func (r *Runner) nextApproval(ctx context.Context, agent AgentType, response *model.Response, turn int, decisions map[string]ApprovalDecision, opts *RunOptions) *ApprovalRequest {
+	if opts.RunConfig == nil || opts.RunConfig.ApprovalPolicy == nil {
+		return nil
+	}
+	policy := opts.RunConfig.ApprovalPolicy
+
+	if response.HandoffCall != nil {
+		request := &ApprovalRequest{
+			ID:          handoffApprovalID(turn),
+			Kind:        ApprovalKindHandoff,
+			AgentName:   agent.Name,
+			TargetAgent: response.HandoffCall.AgentName,
+			Parameters:  response.HandoffCall.Parameters,
+			Turn:        turn,
+		}
+		if _, decided := decisions[request.ID]; !decided && policy.RequiresApproval(ctx, request) {
+			return request
+		}
+		return nil
+	}
+
+	for i, tc := range response.ToolCalls {
+		request := &ApprovalRequest{
+			ID:         toolApprovalID(turn, i, tc),
+			Kind:       ApprovalKindToolCall,
+			AgentName:  agent.Name,
+			ToolName:   tc.Name,
+			Parameters: tc.Parameters,
+			Turn:       turn,
+		}
+		if _, decided := decisions[request.ID]; !decided && policy.RequiresApproval(ctx, request) {
+			return request
+		}
+	}
+	return nil
+}
+
+// toolApprovalID identifies a tool call for approval
+func toolApprovalID(turn, idx int, tc model.ToolCall) string {
+	if tc.ID != "" {
+		return tc.ID
+	}
+	return fmt.Sprintf("turn_%d_call_%d", turn, idx)
+}
+
+// handoffApprovalID identifies a handoff for approval
+func handoffApprovalID(turn int) string {
+	return fmt.Sprintf("turn_%d_handoff", turn)
+}
+
+// appendUserMessage adds a user message to the input, converting a string input to a list
+func appendUserMessage(formatter model.MessageFormatter, input interface{}, content string) interface{} {
+	return append(model.FormatHistory(formatter, input), formatter.FormatUserMessage(content))
+}
+
+// rejectionMessage describes a rejected action to the model
+func rejectionMessage(action string, decision ApprovalDecision) string {
+	if decision.Reason == "" {
+		return fmt.Sprintf("%s was rejected by a human reviewer.", action)
+	}
+	return fmt.Sprintf("%s was rejected by a human reviewer: %s", action, decision.Reason)
+}
+
+// rejectedToolCall creates the results of a tool call rejected by a reviewer
+func rejectedToolCall(tc model.ToolCall, decision ApprovalDecision) (interface{}, *result.ToolCallItem, *result.ToolResultItem) {
+	err := errors.New(rejectionMessage(fmt.Sprintf("The call to %s", tc.Name), decision))
+	return fmt.Sprintf("Error: %v", err),
+		&result.ToolCallItem{
+			Name:       tc.Name,
+			Parameters: tc.Parameters,
+		},
+		&result.ToolResultItem{
+			Name:   tc.Name,
+			Result: fmt.Sprintf("Error: %v", err),
+		}
+}
+
+// approvalEvent creates the stream event announcing a paused run
+func approvalEvent(err *ApprovalRequiredError) model.StreamEvent {
+	event := model.StreamEvent{
+		Type:    model.StreamEventTypeApprovalRequired,
+		Content: err.Error(),
+		Error:   err,
+	}
+
+	response := err.State.Response
+	if err.Request.Kind == ApprovalKindHandoff {
+		event.HandoffCall = response.HandoffCall
+		return event
+	}
+	for i := range response.ToolCalls {
+		if toolApprovalID(err.Request.Turn, i, response.ToolCalls[i]) == err.Request.ID {
+			event.ToolCall = &response.ToolCalls[i]
+			break
+		}
+	}
+	return event
+}
@@ -0,0 +1,144 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/artifact"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+)
+
+// DiffArtifactToolName is the name of the tool returned by DiffArtifactTool
+const DiffArtifactToolName = "diff_artifact"
+
+// diffArtifactParams are the parameters of the diff_artifact tool
+type diffArtifactParams struct {
+	TaskID      string `json:"task_id" doc:"ID of the task whose artifact versions to compare"`
+	FromVersion int    `json:"from_version,omitempty" doc:"Version to compare from, the one before to_version by default"`
+	ToVersion   int    `json:"to_version,omitempty" doc:"Version to compare to, the latest by default"`
+}
+
+// DiffArtifactTool returns a tool that computes the unified diff between two
+// versions of a task's artifact, for agents that review code across handoffs
+func (r *Runner) DiffArtifactTool() tool.Tool {
+	return tool.NewTypedTool(DiffArtifactToolName,
+		"Show the unified diff between two versions of the artifact of a task",
+		func(ctx context.Context, params diffArtifactParams) (string, error) {
+			diff, err := r.DiffArtifact(params.TaskID, params.FromVersion, params.ToVersion)
+			if err != nil {
+				return "", err
+			}
+			if diff == "" {
+				return "The versions are identical.", nil
+			}
+			return diff, nil
+		})
+}
+
+// DiffArtifact returns the unified diff between two versions of a task's
+// artifact. A zero to version is the latest version and a zero from version the
+// one before it.
+func (r *Runner) DiffArtifact(taskID string, from, to int) (string, error) {
+	task := r.getTask(taskID)
+	if task == nil {
+		return "", fmt.Errorf("task %s not found", taskID)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if to == 0 {
+		to = task.ArtifactVersionCount()
+	}
+	if from == 0 {
+		from = to - 1
+	}
+	newVersion, ok := task.GetArtifactVersion(to)
+	if !ok {
+		return "", fmt.Errorf("task %s has no artifact version %d", taskID, to)
+	}
+	oldVersion, ok := task.GetArtifactVersion(from)
+	if !ok {
+		return "", fmt.Errorf("task %s has no artifact version %d", taskID, from)
+	}
+
+	return artifact.UnifiedDiff(
+		fmt.Sprintf("%s@v%d", taskID, from),
+		fmt.Sprintf("%s@v%d", taskID, to),
+		artifactText(oldVersion.Artifact),
+		artifactText(newVersion.Artifact),
+	), nil
+}
+
+// artifactText returns the text of an artifact to diff, encoding artifacts that
+// are not text as indented JSON
+func artifactText(a interface{}) string {
+	switch v := a.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%v", a)
+	}
+	return string(data)
+}
+
+// changesSinceReview returns the diff between the code artifact an agent was last
+// handed and the one it is handed now, and remembers the new one. It returns an
+// empty string the first time an agent is handed code, or when it did not change.
+func (r *Runner) changesSinceReview(agentName string, a interface{}, artifactType string) string {
+	code, ok := a.(string)
+	if !ok || artifactType != "code" {
+		return ""
+	}
+
+	r.mu.Lock()
+	if r.reviewed == nil {
+		r.reviewed = make(map[string]string)
+	}
+	previous, seen := r.reviewed[agentName]
+	r.reviewed[agentName] = code
+	r.mu.Unlock()
+
+	if !seen {
+		return ""
+	}
+	return artifact.UnifiedDiff("last review", "current", previous, code)
+}
+
+// addReviewDiff adds what changed in the code artifact of a task since the agent
+// last saw it to the agent's handoff input, when the run asks for review diffs
+func (r *Runner) addReviewDiff(opts *RunOptions, agentName, taskID string, input interface{}) interface{} {
+	if opts == nil || opts.RunConfig == nil || !opts.RunConfig.ReviewDiffs {
+		return input
+	}
+	task := r.getTask(taskID)
+	if task == nil {
+		return input
+	}
+
+	r.mu.RLock()
+	a, artifactType, version := task.WorkingContext.Artifact, task.WorkingContext.ArtifactType, task.ArtifactVersionCount()
+	r.mu.RUnlock()
+
+	diff := r.changesSinceReview(agentName, a, artifactType)
+	if diff == "" {
+		return input
+	}
+	r.log(nil).Debug("Adding review diff to handoff", "agent", agentName, "task", taskID, "version", version)
+
+	switch in := input.(type) {
+	case string:
+		return fmt.Sprintf("%s\n\nWhat changed since your last review (task %s, artifact version %d):\n```diff\n%s```\n", in, taskID, version, diff)
+	case map[string]interface{}:
+		in["changes_since_last_review"] = diff
+		return in
+	}
+	return input
+}
@@ -0,0 +1,146 @@
+package runner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+)
+
+// Artifact types recognized in handoff inputs
+const (
+	ArtifactTypeCode = "code"
+	ArtifactTypeText = "text"
+)
+
+// ArtifactVersion is an artifact as a task had it at one point
+type ArtifactVersion struct {
+	// Version numbers the versions of a task from 1
+	Version int `json:"version"`
+
+	// Artifact is the artifact of the version
+	Artifact interface{} `json:"artifact,omitempty"`
+
+	// ArtifactType is the type of the artifact
+	ArtifactType string `json:"artifact_type,omitempty"`
+
+	// Author is the name of the agent that produced the version, if known
+	Author string `json:"author,omitempty"`
+
+	// Timestamp is when the version was recorded
+	Timestamp time.Time `json:"timestamp"`
+
+	// Hash is the SHA-256 of the artifact's text, which identifies equal versions
+	Hash string `json:"hash"`
+}
+
+// UpdateArtifact sets the working artifact for the task and records it as a new
+// version by the author, unless it equals the latest version. It returns the
+// latest version and whether it was added.
+func (t *TaskContext) UpdateArtifact(artifact interface{}, artifactType, author string) (ArtifactVersion, bool) {
+	t.WorkingContext.Artifact = artifact
+	t.WorkingContext.ArtifactType = artifactType
+
+	hash := artifactHash(artifact)
+	versions := t.WorkingContext.Versions
+	if len(versions) > 0 && versions[len(versions)-1].Hash == hash {
+		return versions[len(versions)-1], false
+	}
+	version := ArtifactVersion{
+		Version:      len(versions) + 1,
+		Artifact:     artifact,
+		ArtifactType: artifactType,
+		Author:       author,
+		Timestamp:    time.Now(),
+		Hash:         hash,
+	}
+	t.WorkingContext.Versions = append(versions, version)
+	return version, true
+}
+
+// ArtifactHistory returns the versions of the task's artifact, oldest first
+func (t *TaskContext) ArtifactHistory() []ArtifactVersion {
+	return append([]ArtifactVersion(nil), t.WorkingContext.Versions...)
+}
+
+// GetArtifactVersion returns a version of the task's artifact
+func (t *TaskContext) GetArtifactVersion(version int) (ArtifactVersion, bool) {
+	versions := t.WorkingContext.Versions
+	if version < 1 || version > len(versions) {
+		return ArtifactVersion{}, false
+	}
+	return versions[version-1], true
+}
+
+// ArtifactVersionCount returns the number of versions of the task's artifact
+func (t *TaskContext) ArtifactVersionCount() int {
+	return len(t.WorkingContext.Versions)
+}
+
+// artifactHash returns the SHA-256 of an artifact's text
+func artifactHash(artifact interface{}) string {
+	sum := sha256.Sum256([]byte(artifactText(artifact)))
+	return hex.EncodeToString(sum[:])
+}
+
+// codeBlockPattern matches a fenced code block of a message
+var codeBlockPattern = regexp.MustCompile("(?s)```[a-zA-Z0-9_+-]*\\n(.*?)```")
+
+// codeMarkers are words whose presence makes an unfenced message count as code
+var codeMarkers = []string{"function ", "class "}
+
+// extractArtifact returns the artifact of a handoff input: the code or text field
+// of a map, the first fenced code block of a message, or the message itself,
+// typed as code when it looks like code
+func extractArtifact(input interface{}) (interface{}, string, bool) {
+	switch in := input.(type) {
+	case map[string]interface{}:
+		if code, ok := in["code"]; ok {
+			return code, ArtifactTypeCode, true
+		}
+		if text, ok := in["text"]; ok {
+			return text, ArtifactTypeText, true
+		}
+	case string:
+		if match := codeBlockPattern.FindStringSubmatch(in); match != nil {
+			return match[1], ArtifactTypeCode, true
+		}
+		for _, marker := range codeMarkers {
+			if strings.Contains(in, marker) {
+				return in, ArtifactTypeCode, true
+			}
+		}
+		return in, ArtifactTypeText, true
+	}
+	return nil, "", false
+}
+
+// artifactListenerKey is the context key of the function that is told about new
+// artifact versions
+type artifactListenerKey struct{}
+
+// withArtifactListener returns a context whose artifact updates are passed to fn
+func withArtifactListener(ctx context.Context, fn func(update *model.ArtifactUpdate)) context.Context {
+	return context.WithValue(ctx, artifactListenerKey{}, fn)
+}
+
+// notifyArtifact tells the listener of the context about a new artifact version
+func notifyArtifact(ctx context.Context, taskID string, version ArtifactVersion) {
+	fn, ok := ctx.Value(artifactListenerKey{}).(func(update *model.ArtifactUpdate))
+	if !ok {
+		return
+	}
+	fn(&model.ArtifactUpdate{
+		TaskID:       taskID,
+		Version:      version.Version,
+		ArtifactType: version.ArtifactType,
+		Author:       version.Author,
+		Timestamp:    version.Timestamp,
+		Hash:         version.Hash,
+		Artifact:     version.Artifact,
+	})
+}
@@ -0,0 +1,220 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tracing"
+)
+
+// findBroadcastHandoff returns the broadcast handoff of an agent with the given name
+func findBroadcastHandoff(currentAgent AgentType, name string) *agent.BroadcastHandoff {
+	for _, b := range currentAgent.BroadcastHandoffs {
+		if b.Name == name || agent.SanitizeToolName(b.Name) == name {
+			return b
+		}
+	}
+	return nil
+}
+
+// prepareAgentHandoffs returns the cached definitions of all handoffs of an agent
+// for the model request
+func (r *Runner) prepareAgentHandoffs(currentAgent AgentType) []interface{} {
+	schemas := currentAgent.ToolSchemas().Handoffs
+	if len(schemas) == 0 {
+		return nil
+	}
+
+	handoffs := make([]interface{}, len(schemas))
+	for i, schema := range schemas {
+		handoffs[i] = schema
+	}
+	return handoffs
+}
+
+// processBroadcastHandoff runs every executor of a broadcast handoff concurrently as a
+// subtask of the delegator's task, reconciles their results and hands control back to
+// the delegator with the reconciled output as its next input
+func (r *Runner) processBroadcastHandoff(
+	ctx context.Context,
+	currentAgent AgentType,
+	broadcast *agent.BroadcastHandoff,
+	handoffInput interface{},
+	runResult *result.RunResult,
+	opts *RunOptions,
+) (AgentType, interface{}, error) {
+	if len(broadcast.Executors) == 0 {
+		return currentAgent, handoffInput, fmt.Errorf("broadcast handoff %s has no executors", broadcast.Name)
+	}
+
+	// Create the parent task that gathers the results of all executors
+	var broadcastTaskID string
+	if currentTask := r.getTaskContextForAgent(ctx, currentAgent.Name); currentTask != nil && !currentTask.IsFinished() {
+		broadcastTaskID = r.createRelatedTask(ctx, currentTask.TaskID, currentAgent.Name, broadcast.Name)
+	} else {
+		broadcastTaskID = r.createTask(ctx, currentAgent.Name, broadcast.Name)
+	}
+	r.transitionTask(ctx, broadcastTaskID, TaskStatusInProgress, "broadcast by "+currentAgent.Name)
+	r.addTaskInteraction(ctx, broadcastTaskID, currentAgent.Name, handoffInput)
+
+	// Create one subtask per executor before starting any of them
+	subtaskIDs := make([]string, len(broadcast.Executors))
+	for i, executor := range broadcast.Executors {
+		// Call agent hooks if provided
+		if currentAgent.Hooks != nil {
+			if err := currentAgent.Hooks.OnBeforeHandoff(ctx, currentAgent, executor); err != nil {
+				return nil, nil, fmt.Errorf("before handoff hook error: %w", err)
+			}
+		}
+
+		subtaskIDs[i] = r.createRelatedTask(ctx, broadcastTaskID, currentAgent.Name, executor.Name)
+		r.transitionTask(ctx, subtaskIDs[i], TaskStatusInProgress, "broadcast by "+currentAgent.Name)
+
+		tracing.Handoff(ctx, currentAgent.Name, executor.Name, handoffInput)
+		runResult.NewItems = append(runResult.NewItems, &result.HandoffItem{
+			AgentName: executor.Name,
+			Input:     handoffInput,
+		})
+	}
+	r.transitionTask(ctx, broadcastTaskID, TaskStatusWaitingOnSubtask, "waiting on "+strings.Join(subtaskIDs, ", "))
+
+	// Run all executors concurrently
+	results := make([]agent.BroadcastResult, len(broadcast.Executors))
+	usages := make([]result.Usage, len(broadcast.Executors))
+	var wg sync.WaitGroup
+	for i, executor := range broadcast.Executors {
+		wg.Add(1)
+		go func(i int, executor AgentType) {
+			defer wg.Done()
+
+			results[i] = agent.BroadcastResult{AgentName: executor.Name, TaskID: subtaskIDs[i]}
+			subResult, err := r.Run(ctx, executor, &RunOptions{
+				Input:     handoffInput,
+				MaxTurns:  opts.MaxTurns,
+				RunConfig: opts.RunConfig,
+			})
+			if err != nil {
+				results[i].Error = err
+				r.failTask(ctx, subtaskIDs[i], err)
+				return
+			}
+
+			usages[i] = subResult.Usage
+			results[i].Output = subResult.FinalOutput
+			r.completeTask(ctx, subtaskIDs[i], subResult.FinalOutput)
+		}(i, executor)
+	}
+	wg.Wait()
+	for _, usage := range usages {
+		runResult.Usage.Merge(usage)
+	}
+
+	// Gather all returns under the broadcast task
+	failures := 0
+	for _, res := range results {
+		if res.Error != nil {
+			failures++
+			r.addTaskMetadata(ctx, broadcastTaskID, "child_error_"+res.TaskID, res.Error.Error())
+			continue
+		}
+		r.addTaskMetadata(ctx, broadcastTaskID, "child_result_"+res.TaskID, res.Output)
+		r.addTaskInteraction(ctx, broadcastTaskID, res.AgentName, res.Output)
+		tracing.HandoffComplete(ctx, res.AgentName, currentAgent.Name, res.Output)
+
+		// Call agent hooks if provided
+		if currentAgent.Hooks != nil {
+			if err := currentAgent.Hooks.OnAfterHandoff(ctx, currentAgent, findExecutor(broadcast, res.AgentName), res.Output); err != nil {
+				return nil, nil, fmt.Errorf("after handoff hook error: %w", err)
+			}
+		}
+	}
+	r.transitionTask(ctx, broadcastTaskID, TaskStatusInProgress, "all executors returned")
+
+	if failures == len(results) {
+		err := fmt.Errorf("all executors of broadcast handoff %s failed: %w", broadcast.Name, results[0].Error)
+		r.failTask(ctx, broadcastTaskID, err)
+		return nil, nil, err
+	}
+
+	// Reconcile the results before the delegator resumes
+	reconciled, err := r.reconcileBroadcast(ctx, broadcast, handoffInput, results, opts, &runResult.Usage)
+	if err != nil {
+		r.failTask(ctx, broadcastTaskID, err)
+		return nil, nil, fmt.Errorf("failed to reconcile broadcast handoff %s: %w", broadcast.Name, err)
+	}
+	r.completeTask(ctx, broadcastTaskID, reconciled)
+
+	runResult.NewItems = append(runResult.NewItems, &result.HandoffItem{
+		AgentName: currentAgent.Name,
+		Input:     reconciled,
+	})
+
+	return currentAgent, reconciled, nil
+}
+
+// reconcileBroadcast merges the results of a broadcast handoff, adding the usage
+// of a reconciler agent to usage
+func (r *Runner) reconcileBroadcast(ctx context.Context, broadcast *agent.BroadcastHandoff, input interface{}, results []agent.BroadcastResult, opts *RunOptions, usage *result.Usage) (interface{}, error) {
+	summary := formatBroadcastResults(broadcast.Name, results)
+
+	// A reconcile function takes precedence over a reconciler agent
+	if broadcast.ReconcileFunc != nil {
+		output, err := broadcast.ReconcileFunc(ctx, input, results)
+		if err != nil {
+			return nil, err
+		}
+		if s, ok := output.(string); ok {
+			return s, nil
+		}
+		data, err := json.Marshal(output)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize reconciled output: %w", err)
+		}
+		return string(data), nil
+	}
+
+	if broadcast.Reconciler != nil {
+		reconcileResult, err := r.Run(ctx, broadcast.Reconciler, &RunOptions{
+			Input:     fmt.Sprintf("Original request:\n%v\n\n%s", input, summary),
+			MaxTurns:  opts.MaxTurns,
+			RunConfig: opts.RunConfig,
+		})
+		if err != nil {
+			return nil, err
+		}
+		usage.Merge(reconcileResult.Usage)
+		return fmt.Sprintf("%v", reconcileResult.FinalOutput), nil
+	}
+
+	// Without a reconciler the delegator receives all results side by side
+	return summary, nil
+}
+
+// formatBroadcastResults renders the results of all executors as text
+func formatBroadcastResults(name string, results []agent.BroadcastResult) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Results of broadcast %s:\n", name))
+	for _, res := range results {
+		if res.Error != nil {
+			sb.WriteString(fmt.Sprintf("\n[%s] failed: %v\n", res.AgentName, res.Error))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("\n[%s]\n%v\n", res.AgentName, res.Output))
+	}
+	return sb.String()
+}
+
+// findExecutor returns the executor of a broadcast handoff with the given name
+func findExecutor(broadcast *agent.BroadcastHandoff, name string) AgentType {
+	for _, executor := range broadcast.Executors {
+		if executor.Name == name {
+			return executor
+		}
+	}
+	return nil
+}
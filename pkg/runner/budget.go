@@ -0,0 +1,235 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+)
+
+// ModelPricing is the price of a model in USD per million tokens
+type ModelPricing struct {
+	// PromptPerMillion is the price of one million prompt tokens
+	PromptPerMillion float64
+
+	// CompletionPerMillion is the price of one million completion tokens
+	CompletionPerMillion float64
+
+	// CachedPromptPerMillion is the price of one million prompt tokens read from
+	// the provider's prompt cache. Zero prices them like other prompt tokens.
+	CachedPromptPerMillion float64
+}
+
+// PricingTable maps model names to their pricing. A model name that is not in the
+// table uses the entry with the longest matching prefix, so dated model versions
+// such as "gpt-4o-2024-08-06" are priced like "gpt-4o".
+type PricingTable map[string]ModelPricing
+
+// DefaultPricing contains list prices for common models
+var DefaultPricing = PricingTable{
+	"gpt-4o":            {PromptPerMillion: 2.50, CompletionPerMillion: 10.00, CachedPromptPerMillion: 1.25},
+	"gpt-4o-mini":       {PromptPerMillion: 0.15, CompletionPerMillion: 0.60, CachedPromptPerMillion: 0.075},
+	"gpt-4.1":           {PromptPerMillion: 2.00, CompletionPerMillion: 8.00, CachedPromptPerMillion: 0.50},
+	"gpt-4.1-mini":      {PromptPerMillion: 0.40, CompletionPerMillion: 1.60, CachedPromptPerMillion: 0.10},
+	"gpt-4.1-nano":      {PromptPerMillion: 0.10, CompletionPerMillion: 0.40, CachedPromptPerMillion: 0.025},
+	"gpt-3.5-turbo":     {PromptPerMillion: 0.50, CompletionPerMillion: 1.50},
+	"o1":                {PromptPerMillion: 15.00, CompletionPerMillion: 60.00, CachedPromptPerMillion: 7.50},
+	"o3-mini":           {PromptPerMillion: 1.10, CompletionPerMillion: 4.40, CachedPromptPerMillion: 0.55},
+	"claude-3-7-sonnet": {PromptPerMillion: 3.00, CompletionPerMillion: 15.00, CachedPromptPerMillion: 0.30},
+	"claude-3-5-sonnet": {PromptPerMillion: 3.00, CompletionPerMillion: 15.00, CachedPromptPerMillion: 0.30},
+	"claude-3-5-haiku":  {PromptPerMillion: 0.80, CompletionPerMillion: 4.00, CachedPromptPerMillion: 0.08},
+	"claude-3-opus":     {PromptPerMillion: 15.00, CompletionPerMillion: 75.00, CachedPromptPerMillion: 1.50},
+	"claude-3-haiku":    {PromptPerMillion: 0.25, CompletionPerMillion: 1.25, CachedPromptPerMillion: 0.03},
+}
+
+// Lookup returns the pricing for a model
+func (p PricingTable) Lookup(modelName string) (ModelPricing, bool) {
+	if pricing, ok := p[modelName]; ok {
+		return pricing, true
+	}
+
+	best := ""
+	for name := range p {
+		if strings.HasPrefix(modelName, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return ModelPricing{}, false
+	}
+	return p[best], true
+}
+
+// Cost returns the cost in USD of the given usage
+func (p PricingTable) Cost(modelName string, usage *model.Usage) (float64, bool) {
+	pricing, ok := p.Lookup(modelName)
+	if !ok || usage == nil {
+		return 0, ok
+	}
+	promptTokens, cachedTokens := usage.PromptTokens, 0
+	if pricing.CachedPromptPerMillion > 0 {
+		promptTokens, cachedTokens = usage.PromptTokens-usage.CachedTokens, usage.CachedTokens
+	}
+	return float64(promptTokens)/1e6*pricing.PromptPerMillion +
+		float64(cachedTokens)/1e6*pricing.CachedPromptPerMillion +
+		float64(usage.CompletionTokens)/1e6*pricing.CompletionPerMillion, true
+}
+
+// Budget limits
+const (
+	BudgetLimitTokens = "tokens"
+	BudgetLimitCost   = "cost"
+)
+
+// BudgetExceededError is returned when a run exceeds its token or cost budget
+type BudgetExceededError struct {
+	// Limit is the limit that was exceeded: BudgetLimitTokens or BudgetLimitCost
+	Limit string
+
+	// TotalTokens is the number of tokens used so far
+	TotalTokens int
+
+	// CostUSD is the cost of the run so far
+	CostUSD float64
+
+	// MaxTotalTokens is the configured token budget
+	MaxTotalTokens int
+
+	// MaxCostUSD is the configured cost budget
+	MaxCostUSD float64
+}
+
+// Error implements the error interface
+func (e *BudgetExceededError) Error() string {
+	if e.Limit == BudgetLimitCost {
+		return fmt.Sprintf("run budget exceeded: cost $%.4f exceeds limit of $%.4f", e.CostUSD, e.MaxCostUSD)
+	}
+	return fmt.Sprintf("run budget exceeded: %d tokens exceed limit of %d", e.TotalTokens, e.MaxTotalTokens)
+}
+
+// BudgetExceededHook is called when a run exceeds its budget. Returning nil lets the
+// run continue; returning an error aborts the run with that error.
+type BudgetExceededHook func(ctx context.Context, err *BudgetExceededError) error
+
+// budgetTracker accumulates the usage of a run and enforces its budget
+type budgetTracker struct {
+	config      *RunConfig
+	totalTokens int
+	costUSD     float64
+	notified    bool
+
+	// The run reported to the cost monitor, set by attach
+	runID     string
+	agentName string
+	anomalous bool
+
+	// usage is the usage of the run result, set by track
+	usage *result.Usage
+}
+
+// newBudgetTracker creates a tracker for the budget in the run config
+func newBudgetTracker(config *RunConfig) *budgetTracker {
+	return &budgetTracker{config: config}
+}
+
+// attach reports the usage of the tracker to the cost monitor of the run config as
+// the usage of a run of an agent
+func (b *budgetTracker) attach(runID, agentName string) {
+	b.runID = runID
+	b.agentName = agentName
+}
+
+// track adds the usage the tracker records to the usage of a run result
+func (b *budgetTracker) track(usage *result.Usage) {
+	b.usage = usage
+}
+
+// record adds the usage of a model response of an agent and checks it against
+// the budget
+func (b *budgetTracker) record(ctx context.Context, agentName, modelName string, usage *model.Usage) error {
+	if b.config == nil {
+		return nil
+	}
+	cost, priced := b.config.pricing().Cost(modelName, usage)
+	if b.usage != nil {
+		b.usage.Add(agentName, modelName, usage, cost)
+	}
+	if usage == nil {
+		return nil
+	}
+
+	tokens := usage.TotalTokens
+	if tokens == 0 {
+		tokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	b.totalTokens += tokens
+
+	if priced {
+		b.costUSD += cost
+	} else if b.config.MaxCostUSD > 0 {
+		logging.For(nil, "runner").Debug("No pricing for model, cost budget not applied to this response", "model", modelName)
+	}
+
+	if b.config.CostMonitor != nil && b.agentName != "" {
+		b.anomalous = b.config.CostMonitor.observe(ctx, b.agentName, b.runID, cost, tokens, b.costUSD, b.totalTokens, b.anomalous)
+	}
+
+	return b.check(ctx)
+}
+
+// finish adds the usage of a completed run to the baselines of the cost monitor
+func (b *budgetTracker) finish() {
+	if b.config == nil || b.config.CostMonitor == nil || b.agentName == "" {
+		return
+	}
+	b.config.CostMonitor.finishRun(b.agentName, b.costUSD, b.totalTokens, b.anomalous)
+}
+
+// check reports a budget violation once, through the hook if one is configured
+func (b *budgetTracker) check(ctx context.Context) error {
+	if b.notified {
+		return nil
+	}
+
+	exceeded := &BudgetExceededError{
+		TotalTokens:    b.totalTokens,
+		CostUSD:        b.costUSD,
+		MaxTotalTokens: b.config.MaxTotalTokens,
+		MaxCostUSD:     b.config.MaxCostUSD,
+	}
+	switch {
+	case b.config.MaxTotalTokens > 0 && b.totalTokens > b.config.MaxTotalTokens:
+		exceeded.Limit = BudgetLimitTokens
+	case b.config.MaxCostUSD > 0 && b.costUSD > b.config.MaxCostUSD:
+		exceeded.Limit = BudgetLimitCost
+	default:
+		return nil
+	}
+
+	b.notified = true
+	if b.config.OnBudgetExceeded != nil {
+		return b.config.OnBudgetExceeded(ctx, exceeded)
+	}
+	return exceeded
+}
+
+// pricing returns the pricing the run's costs are estimated with
+func (c *RunConfig) pricing() PricingTable {
+	if c.Pricing == nil {
+		return DefaultPricing
+	}
+	return c.Pricing
+}
+
+// modelName returns the name of the model used by the agent, or "" for model instances
+func modelName(agent AgentType, runConfig *RunConfig) string {
+	if runConfig != nil && runConfig.Model != nil {
+		name, _ := runConfig.Model.(string)
+		return name
+	}
+	name, _ := agent.Model.(string)
+	return name
+}
@@ -0,0 +1,117 @@
+package runner
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+)
+
+// ErrRunnerShutdown is returned by runs started after Shutdown was called
+var ErrRunnerShutdown = errors.New("runner is shut down")
+
+// Cancel stops an active run. Model calls, tool calls and model streams in
+// progress are abandoned, a run_cancelled event is recorded, and the run returns
+// the result generated so far, marked as cancelled, with an error wrapping
+// ErrRunCancelled. Streaming runs end with an error event followed by a
+// run_cancelled event.
+func (r *Runner) Cancel(runID string) error {
+	run, err := r.activeRun(runID)
+	if err != nil {
+		return err
+	}
+	run.cancel(ErrRunCancelled)
+	return nil
+}
+
+// Shutdown stops the runner gracefully. Runs started from now on fail with
+// ErrRunnerShutdown, while the active runs, and the runs they start, are given
+// until ctx is done to finish.
+// Runs still active then are cancelled, and Shutdown waits for them to return
+// before it returns the error of ctx.
+func (r *Runner) Shutdown(ctx context.Context) error {
+	r.activeMu.Lock()
+	r.shutdown = true
+	runs := make([]*activeRun, 0, len(r.activeRuns))
+	for _, run := range r.activeRuns {
+		runs = append(runs, run)
+	}
+	r.activeMu.Unlock()
+
+	for i, run := range runs {
+		select {
+		case <-run.done:
+		case <-ctx.Done():
+			for _, remaining := range runs[i:] {
+				remaining.cancel(ErrRunCancelled)
+			}
+			for _, remaining := range runs[i:] {
+				<-remaining.done
+			}
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// isCancellation reports whether a run ended because it was cancelled, with
+// Cancel or through the context it was started with
+func isCancellation(err error) bool {
+	return errors.Is(err, ErrRunCancelled) || errors.Is(err, context.Canceled)
+}
+
+// awaitCancellable waits for fn unless ctx is done first, so tools and models
+// that ignore their context do not hold up a cancelled run
+func awaitCancellable[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	type outcome struct {
+		value T
+		err   error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		value, err := fn()
+		done <- outcome{value, err}
+	}()
+
+	select {
+	case o := <-done:
+		return o.value, o.err
+	case <-ctx.Done():
+		var zero T
+		return zero, context.Cause(ctx)
+	}
+}
+
+// streamUntilDone forwards the events of a model stream until it ends or ctx is
+// done. The rest of an abandoned stream is drained, so the model is not blocked
+// sending it.
+func streamUntilDone(ctx context.Context, events <-chan model.StreamEvent) <-chan model.StreamEvent {
+	out := make(chan model.StreamEvent)
+	drain := func() {
+		go func() {
+			for range events {
+			}
+		}()
+	}
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				select {
+				case out <- event:
+				case <-ctx.Done():
+					drain()
+					return
+				}
+			case <-ctx.Done():
+				drain()
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,290 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCheckpointNotFound is returned when restoring a checkpoint that does not exist
+var ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+// CheckpointStore is a WorkflowStateStore that keeps the state saved at each
+// checkpoint, so workflows can be restored to an earlier point
+type CheckpointStore interface {
+	WorkflowStateStore
+
+	// LoadCheckpoint loads the workflow state saved at a checkpoint
+	LoadCheckpoint(workflowID string, checkpointID string) (*WorkflowState, error)
+}
+
+// CheckpointRetention decides which checkpoints of a workflow are pruned after
+// each save. The checkpoint just saved is never pruned.
+type CheckpointRetention struct {
+	// MaxCheckpoints keeps at most this many checkpoints, pruning the oldest.
+	// Zero keeps any number.
+	MaxCheckpoints int
+
+	// MaxAge prunes checkpoints older than this. Zero keeps them regardless of
+	// their age. It requires a CheckpointStore.
+	MaxAge time.Duration
+
+	// KeepPhaseStarts exempts the checkpoints taken when a phase started, so a
+	// workflow can always be restored to the start of any of its phases
+	KeepPhaseStarts bool
+}
+
+// Points of a workflow at which checkpoints are taken
+const (
+	checkpointStart    = "start"
+	checkpointEnd      = "end"
+	checkpointRecovery = "recovery"
+)
+
+// checkpointID returns the ID of the next checkpoint of a state, such as
+// "3-review-turn2". The sequence number keeps IDs unique and ordered.
+func checkpointID(sequence int, phase, point string) string {
+	if phase == "" {
+		phase = "workflow"
+	}
+	return fmt.Sprintf("%d-%s-%s", sequence, phase, point)
+}
+
+// checkpointSequence returns the sequence number of a checkpoint ID, or zero
+// for IDs that were not assigned by the runner
+func checkpointSequence(id string) int {
+	prefix, _, _ := strings.Cut(id, "-")
+	n, _ := strconv.Atoi(prefix)
+	return n
+}
+
+// isPhaseStart reports whether a checkpoint was taken when a phase started
+func isPhaseStart(id string) bool {
+	return strings.HasSuffix(id, "-"+checkpointStart)
+}
+
+// ListCheckpoints lists the checkpoints of a workflow, oldest first
+func (r *WorkflowRunner) ListCheckpoints(workflowID string) ([]string, error) {
+	sm := r.workflowConfig.StateManagement
+	if sm == nil || sm.StateStore == nil {
+		return nil, fmt.Errorf("workflow has no state store")
+	}
+	return sm.StateStore.ListCheckpoints(workflowID)
+}
+
+// RestoreFromCheckpoint makes the state saved at a checkpoint the current state
+// of a workflow, so its next run resumes from that point. Later checkpoints are
+// kept, and new checkpoints are numbered after them.
+func (r *WorkflowRunner) RestoreFromCheckpoint(workflowID string, checkpointID string) (*WorkflowState, error) {
+	sm := r.workflowConfig.StateManagement
+	if sm == nil || sm.StateStore == nil {
+		return nil, fmt.Errorf("workflow has no state store")
+	}
+	store, ok := sm.StateStore.(CheckpointStore)
+	if !ok {
+		return nil, fmt.Errorf("state store %T cannot load checkpoints", sm.StateStore)
+	}
+
+	state, err := store.LoadCheckpoint(workflowID, checkpointID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkpoint %s of workflow %s: %w", checkpointID, workflowID, err)
+	}
+	if state == nil {
+		return nil, fmt.Errorf("%w: %s of workflow %s", ErrCheckpointNotFound, checkpointID, workflowID)
+	}
+
+	ids, err := store.ListCheckpoints(workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checkpoints of workflow %s: %w", workflowID, err)
+	}
+	for _, id := range ids {
+		state.CheckpointSequence = max(state.CheckpointSequence, checkpointSequence(id))
+	}
+
+	// Saved without a checkpoint ID, the restored state only becomes current
+	state.CheckpointID = ""
+	state.PhaseStartedAt = time.Now()
+	if err := store.SaveState(workflowID, state); err != nil {
+		return nil, fmt.Errorf("failed to restore checkpoint %s of workflow %s: %w", checkpointID, workflowID, err)
+	}
+	return state, nil
+}
+
+// pruneCheckpoints deletes the checkpoints the retention policy no longer keeps
+func (r *WorkflowRunner) pruneCheckpoints(workflowID, current string) error {
+	sm := r.workflowConfig.StateManagement
+	retention := sm.Retention
+	if retention == nil || (retention.MaxCheckpoints <= 0 && retention.MaxAge <= 0) {
+		return nil
+	}
+
+	ids, err := sm.StateStore.ListCheckpoints(workflowID)
+	if err != nil {
+		return fmt.Errorf("failed to list checkpoints: %w", err)
+	}
+	prunable := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id != current && !(retention.KeepPhaseStarts && isPhaseStart(id)) {
+			prunable = append(prunable, id)
+		}
+	}
+
+	// Checkpoints are listed oldest first
+	excess := 0
+	if retention.MaxCheckpoints > 0 {
+		excess = len(ids) - retention.MaxCheckpoints
+	}
+	store, canLoad := sm.StateStore.(CheckpointStore)
+	cutoff := time.Now().Add(-retention.MaxAge)
+	for i, id := range prunable {
+		if i >= excess {
+			if retention.MaxAge <= 0 || !canLoad {
+				break
+			}
+			state, err := store.LoadCheckpoint(workflowID, id)
+			if err != nil {
+				return fmt.Errorf("failed to load checkpoint %s: %w", id, err)
+			}
+			if state != nil && state.LastCheckpoint.After(cutoff) {
+				break
+			}
+		}
+		if err := sm.StateStore.DeleteCheckpoint(workflowID, id); err != nil {
+			return fmt.Errorf("failed to delete checkpoint %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// MemoryStateStore is a CheckpointStore that keeps workflow states in memory.
+// Every save of a *WorkflowState is kept as a copy under its checkpoint ID.
+type MemoryStateStore struct {
+	states      map[string]interface{}
+	checkpoints map[string][]memoryCheckpoint
+	saves       int
+	mu          sync.Mutex
+}
+
+// memoryCheckpoint is a checkpoint kept by a MemoryStateStore
+type memoryCheckpoint struct {
+	id    string
+	state *WorkflowState
+}
+
+// NewMemoryStateStore creates an empty in-memory state store
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{
+		states:      make(map[string]interface{}),
+		checkpoints: make(map[string][]memoryCheckpoint),
+	}
+}
+
+// SaveState saves the current workflow state and, for a *WorkflowState, a
+// checkpoint of it
+func (s *MemoryStateStore) SaveState(workflowID string, state interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.saves++
+	ws, ok := state.(*WorkflowState)
+	if !ok || ws == nil {
+		s.states[workflowID] = state
+		return nil
+	}
+
+	saved := copyWorkflowState(ws)
+	s.states[workflowID] = saved
+	id := ws.CheckpointID
+	if id == "" {
+		id = strconv.Itoa(s.saves)
+	}
+	checkpoints := s.checkpoints[workflowID]
+	for i, c := range checkpoints {
+		if c.id == id {
+			checkpoints = append(checkpoints[:i:i], checkpoints[i+1:]...)
+			break
+		}
+	}
+	s.checkpoints[workflowID] = append(checkpoints, memoryCheckpoint{id: id, state: saved})
+	return nil
+}
+
+// LoadState loads a copy of the latest workflow state, or nil if none has been saved
+func (s *MemoryStateStore) LoadState(workflowID string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.states[workflowID]
+	if !ok {
+		return nil, nil
+	}
+	if ws, ok := state.(*WorkflowState); ok {
+		return copyWorkflowState(ws), nil
+	}
+	return state, nil
+}
+
+// LoadCheckpoint loads a copy of the workflow state saved at a checkpoint, or
+// nil if the checkpoint does not exist
+func (s *MemoryStateStore) LoadCheckpoint(workflowID string, checkpointID string) (*WorkflowState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, c := range s.checkpoints[workflowID] {
+		if c.id == checkpointID {
+			return copyWorkflowState(c.state), nil
+		}
+	}
+	return nil, nil
+}
+
+// ListCheckpoints lists the checkpoints of a workflow, oldest first
+func (s *MemoryStateStore) ListCheckpoints(workflowID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.checkpoints[workflowID]))
+	for _, c := range s.checkpoints[workflowID] {
+		ids = append(ids, c.id)
+	}
+	return ids, nil
+}
+
+// DeleteCheckpoint deletes a checkpoint. The current state is kept.
+func (s *MemoryStateStore) DeleteCheckpoint(workflowID string, checkpointID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	checkpoints := s.checkpoints[workflowID]
+	for i, c := range checkpoints {
+		if c.id == checkpointID {
+			s.checkpoints[workflowID] = append(checkpoints[:i:i], checkpoints[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// copyWorkflowState copies a state, so later changes to it do not alter a checkpoint
+func copyWorkflowState(state *WorkflowState) *WorkflowState {
+	c := *state
+	c.CompletedPhases = append([]string(nil), state.CompletedPhases...)
+	c.SLABreaches = append([]SLABreach(nil), state.SLABreaches...)
+	c.Compensations = append([]CompensationResult(nil), state.Compensations...)
+	if state.Artifacts != nil {
+		c.Artifacts = make(map[string]interface{}, len(state.Artifacts))
+		for k, v := range state.Artifacts {
+			c.Artifacts[k] = v
+		}
+	}
+	if state.Metadata != nil {
+		c.Metadata = make(map[string]interface{}, len(state.Metadata))
+		for k, v := range state.Metadata {
+			c.Metadata[k] = v
+		}
+	}
+	return &c
+}
@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CompensationFunc undoes the side effects of a completed phase, such as closing
+// the tickets it created or removing the files it wrote. It receives the state
+// of the workflow and the error the workflow failed with.
+type CompensationFunc func(ctx context.Context, state *WorkflowState, cause error) error
+
+// CompensationResult records the compensation of a phase
+type CompensationResult struct {
+	Phase string
+	// Error is the error of a failed compensation, empty when it succeeded
+	Error string
+	At    time.Time
+}
+
+// CompensationError is returned when a workflow failed and some of its phases
+// could not be compensated
+type CompensationError struct {
+	// Err is the error the workflow failed with
+	Err error
+
+	// Failed are the compensations that failed
+	Failed []CompensationResult
+}
+
+// Error implements the error interface
+func (e *CompensationError) Error() string {
+	phases := make([]string, len(e.Failed))
+	for i, f := range e.Failed {
+		phases[i] = f.Phase
+	}
+	return fmt.Sprintf("%v; compensation of phases %s failed", e.Err, strings.Join(phases, ", "))
+}
+
+// Unwrap returns the error the workflow failed with
+func (e *CompensationError) Unwrap() error {
+	return e.Err
+}
+
+// checkpointCompensation is the point at which a compensated workflow is checkpointed
+const checkpointCompensation = "compensation"
+
+// compensate rolls back the completed phases of a failed workflow in reverse
+// order and records the results in the workflow state. Phases compensated by an
+// earlier run are skipped. It returns the error the workflow failed with, or a
+// CompensationError when a compensation failed.
+func (wh *workflowHooks) compensate(ctx context.Context, cause error) error {
+	// Paused runs are continued later rather than rolled back
+	var approvalErr *ApprovalRequiredError
+	if errors.Is(cause, ErrPauseRun) || errors.As(cause, &approvalErr) {
+		return cause
+	}
+
+	// Compensation runs even when the workflow failed because ctx was cancelled
+	ctx = context.WithoutCancel(ctx)
+
+	var failed []CompensationResult
+	compensated := false
+	for i := len(wh.state.CompletedPhases) - 1; i >= 0; i-- {
+		name := wh.state.CompletedPhases[i]
+		phase := wh.phase(name)
+		if phase == nil || phase.Compensate == nil || wh.compensated(name) {
+			continue
+		}
+
+		compensated = true
+		res := CompensationResult{Phase: name, At: time.Now()}
+		if err := phase.Compensate(ctx, wh.state, cause); err != nil {
+			res.Error = err.Error()
+			failed = append(failed, res)
+			wh.log.Error("Failed to compensate phase", "phase", name, "error", err)
+		} else {
+			wh.log.Info("Compensated phase", "phase", name)
+		}
+		wh.state.Compensations = append(wh.state.Compensations, res)
+	}
+	if !compensated {
+		return cause
+	}
+
+	if wh.persists() {
+		if err := wh.saveState(wh.state, checkpointCompensation); err != nil {
+			wh.log.Warn("Failed to save compensated workflow state", "error", err)
+		}
+	}
+	if len(failed) > 0 {
+		return &CompensationError{Err: cause, Failed: failed}
+	}
+	return cause
+}
+
+// compensated reports whether a phase has already been compensated successfully
+func (wh *workflowHooks) compensated(phase string) bool {
+	for _, c := range wh.state.Compensations {
+		if c.Phase == phase && c.Error == "" {
+			return true
+		}
+	}
+	return false
+}
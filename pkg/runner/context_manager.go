@@ -0,0 +1,233 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/message"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+)
+
+// DefaultContextThreshold is the fraction of the context window at which a
+// context manager compacts the history
+const DefaultContextThreshold = 0.8
+
+// DefaultKeepRecentMessages is the number of most recent history items a context
+// manager keeps as they are
+const DefaultKeepRecentMessages = 6
+
+// summaryInstructions are the instructions of the model that summarizes compacted
+// history
+const summaryInstructions = `You summarize the earlier part of a conversation between a user, an assistant and its tools, so that the assistant can continue the task without it.
+Keep the user's task and requirements, the decisions made, the facts and results learned from tool calls, and what is still open.
+Be concise and answer with the summary only.`
+
+// summaryPrefix introduces the summary that replaces compacted history
+const summaryPrefix = "Summary of the earlier conversation, which was compacted to fit the context window:\n\n"
+
+// ContextManager keeps the history of long runs within the model's context
+// window. Before each model call it estimates the tokens of the instructions and
+// history, and once they pass the threshold it replaces the older history items
+// with a summary written by the summary model, or drops them when there is none.
+// The most recent items are kept as they are, and tool results are never
+// separated from the tool calls they answer.
+type ContextManager struct {
+	threshold     float64
+	keepRecent    int
+	summaryModel  interface{}
+	contextWindow int
+	mu            sync.RWMutex
+}
+
+// NewContextManager creates a context manager that drops older history items at
+// DefaultContextThreshold of the context window
+func NewContextManager() *ContextManager {
+	return &ContextManager{
+		threshold:  DefaultContextThreshold,
+		keepRecent: DefaultKeepRecentMessages,
+	}
+}
+
+// WithThreshold sets the fraction of the context window at which the history is
+// compacted
+func (m *ContextManager) WithThreshold(threshold float64) *ContextManager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.threshold = threshold
+	return m
+}
+
+// WithKeepRecent sets the number of most recent history items kept as they are
+func (m *ContextManager) WithKeepRecent(n int) *ContextManager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keepRecent = n
+	return m
+}
+
+// WithSummaryModel sets the model that summarizes compacted history, a model name
+// resolved with the run's model provider or a model.Model. A small, cheap model is
+// usually enough.
+func (m *ContextManager) WithSummaryModel(summaryModel interface{}) *ContextManager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.summaryModel = summaryModel
+	return m
+}
+
+// WithContextWindow sets the context window in tokens, for models whose context
+// window is not known by name
+func (m *ContextManager) WithContextWindow(tokens int) *ContextManager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.contextWindow = tokens
+	return m
+}
+
+// compaction is a compacted history and, when it was summarized, the summary
+// model and the usage of its call
+type compaction struct {
+	input      interface{}
+	summarized bool
+	usage      *model.Usage
+	modelName  string
+}
+
+// compact returns the history compacted to fit the context window of the agent's
+// model, or nil if it fits
+func (m *ContextManager) compact(ctx context.Context, agent AgentType, instructions string, input interface{}, runConfig *RunConfig) (*compaction, error) {
+	m.mu.RLock()
+	threshold, keepRecent, summaryModel, window := m.threshold, m.keepRecent, m.summaryModel, m.contextWindow
+	m.mu.RUnlock()
+
+	if window == 0 {
+		known, ok := model.ContextWindow(modelName(agent, runConfig))
+		if !ok {
+			return nil, nil
+		}
+		window = known
+	}
+	history, ok := input.([]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	budget := int(float64(window) * threshold)
+	instructionTokens := model.EstimateTokens(&model.Request{SystemInstructions: instructions})
+	tokens := instructionTokens
+	for _, item := range history {
+		tokens += model.EstimateMessageTokens(item)
+	}
+	if tokens <= budget {
+		return nil, nil
+	}
+
+	// Keep the most recent items that fit half the budget, leaving room for the
+	// summary and the turns to come
+	split, kept := len(history), instructionTokens
+	for split > 0 && len(history)-split < keepRecent {
+		itemTokens := model.EstimateMessageTokens(history[split-1])
+		if len(history)-split > 0 && kept+itemTokens > budget/2 {
+			break
+		}
+		kept += itemTokens
+		split--
+	}
+	// Keep tool results with the assistant message of their tool calls
+	for split > 0 && split < len(history) && isToolResult(history[split]) {
+		split--
+	}
+	if split == 0 {
+		return nil, nil
+	}
+
+	if summaryModel == nil {
+		// Keep the input the run started with and drop the items after it
+		if split == 1 {
+			return nil, nil
+		}
+		compacted := append([]interface{}{history[0]}, history[split:]...)
+		return &compaction{input: compacted}, nil
+	}
+
+	summarizer, err := resolveConfiguredModel(runConfig, summaryModel, "summary")
+	if err != nil {
+		return nil, err
+	}
+	response, err := summarizer.GetResponse(ctx, &model.Request{
+		SystemInstructions: summaryInstructions,
+		Input:              transcript(history[:split]),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize history: %w", err)
+	}
+
+	compacted := make([]interface{}, 0, len(history)-split+1)
+	compacted = append(compacted, model.DefaultMessageFormatter{}.FormatUserMessage(summaryPrefix+strings.TrimSpace(response.Content)))
+	compacted = append(compacted, history[split:]...)
+	return &compaction{input: compacted, summarized: true, usage: response.Usage, modelName: describeModel(summaryModel)}, nil
+}
+
+// compactHistory compacts the history of a turn when the run has a context
+// manager, recording the summary model's usage in the run's budget
+func (r *Runner) compactHistory(ctx context.Context, agent AgentType, instructions string, input interface{}, opts *RunOptions, budget *budgetTracker) (interface{}, error) {
+	if opts.RunConfig == nil || opts.RunConfig.ContextManager == nil {
+		return input, nil
+	}
+	c, err := opts.RunConfig.ContextManager.compact(ctx, agent, instructions, input, opts.RunConfig)
+	if err != nil {
+		return nil, fmt.Errorf("context management failed: %w", err)
+	}
+	if c == nil {
+		return input, nil
+	}
+
+	before, _ := input.([]interface{})
+	after, _ := c.input.([]interface{})
+	r.log(agent).Info("Compacted history to fit the context window", "agent", agent.Name, "items_before", len(before), "items_after", len(after), "summarized", c.summarized)
+	if c.summarized && budget != nil {
+		if err := budget.record(ctx, agent.Name, c.modelName, c.usage); err != nil {
+			return nil, err
+		}
+	}
+	return c.input, nil
+}
+
+// isToolResult reports whether a history item is the result of a tool call
+func isToolResult(item interface{}) bool {
+	parsed, ok := message.Parse(item)
+	if !ok {
+		return false
+	}
+	_, ok = parsed.(message.ToolResult)
+	return ok
+}
+
+// transcript renders history items as text for the summary model
+func transcript(history []interface{}) string {
+	var b strings.Builder
+	for _, item := range history {
+		parsed, ok := message.Parse(item)
+		if !ok {
+			fmt.Fprintf(&b, "%s\n\n", model.ToolResultText(item))
+			continue
+		}
+		switch msg := parsed.(type) {
+		case message.ToolResult:
+			fmt.Fprintf(&b, "tool %s: %s\n\n", msg.Call.Name, model.ToolResultText(msg.Content))
+		case message.Message:
+			role := msg.Role
+			if role == "" {
+				role = "message"
+			}
+			fmt.Fprintf(&b, "%s: %s\n", role, strings.TrimSpace(model.ToolResultText(msg.Content)))
+			for _, call := range msg.ToolCalls {
+				fmt.Fprintf(&b, "tool call: %s %s\n", call.Name, call.ArgumentsJSON())
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
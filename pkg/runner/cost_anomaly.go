@@ -0,0 +1,337 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+)
+
+// Cost anomaly kinds
+const (
+	// CostAnomalyRun is reported when the usage of a single run exceeds the
+	// multiple of the agent's typical run
+	CostAnomalyRun = "run"
+
+	// CostAnomalyWindow is reported when the usage of an agent within a time window
+	// exceeds the multiple of its typical window
+	CostAnomalyWindow = "window"
+)
+
+// Cost monitor defaults
+const (
+	DefaultCostAnomalyMultiple = 3.0
+	DefaultCostWindow          = time.Hour
+	DefaultCostMinSamples      = 10
+	DefaultCostSmoothing       = 0.1
+)
+
+// CostAnomaly describes usage well above an agent's baseline
+type CostAnomaly struct {
+	// Kind is CostAnomalyRun or CostAnomalyWindow
+	Kind string `json:"kind"`
+
+	// Agent is the name of the agent the run started with
+	Agent string `json:"agent"`
+
+	// RunID is the run that exceeded the baseline, for run anomalies, or the run
+	// whose usage pushed the window over it
+	RunID string `json:"run_id,omitempty"`
+
+	// CostUSD and Tokens are the usage of the run or window so far
+	CostUSD float64 `json:"cost_usd"`
+	Tokens  int     `json:"tokens"`
+
+	// BaselineCostUSD and BaselineTokens are the typical usage of a run or window
+	BaselineCostUSD float64 `json:"baseline_cost_usd"`
+	BaselineTokens  float64 `json:"baseline_tokens"`
+
+	// Multiple is the usage divided by the baseline
+	Multiple float64 `json:"multiple"`
+
+	// WindowStart is the start of the window, for window anomalies
+	WindowStart time.Time `json:"window_start,omitempty"`
+
+	// DetectedAt is when the anomaly was detected
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// CostAnomalyHandler is called for every detected anomaly
+type CostAnomalyHandler func(ctx context.Context, anomaly *CostAnomaly)
+
+// AgentCostStats are the usage baselines and anomaly counts of an agent
+type AgentCostStats struct {
+	Agent string `json:"agent"`
+
+	// Runs is the number of completed runs in the baseline
+	Runs int `json:"runs"`
+
+	// RunCostUSD and RunTokens are the smoothed usage of a run
+	RunCostUSD float64 `json:"run_cost_usd"`
+	RunTokens  float64 `json:"run_tokens"`
+
+	// Windows is the number of completed windows in the baseline
+	Windows int `json:"windows"`
+
+	// WindowCostUSD and WindowTokens are the smoothed usage of a window
+	WindowCostUSD float64 `json:"window_cost_usd"`
+	WindowTokens  float64 `json:"window_tokens"`
+
+	// RunAnomalies and WindowAnomalies count the detected anomalies
+	RunAnomalies    int `json:"run_anomalies"`
+	WindowAnomalies int `json:"window_anomalies"`
+}
+
+// agentCost is the state the monitor keeps for an agent
+type agentCost struct {
+	stats AgentCostStats
+
+	// The current window
+	windowStart   time.Time
+	windowCost    float64
+	windowTokens  int
+	windowAlerted bool
+}
+
+// CostMonitor learns rolling per-agent baselines of run and hourly usage and alerts
+// when a run or window exceeds a multiple of them, catching runaway tool loops and
+// prompt explosions while they happen. Set it as RunConfig.CostMonitor and share it
+// between runs. Baselines are exponentially smoothed and only used once they have
+// enough samples; runs that trigger an anomaly do not update them.
+type CostMonitor struct {
+	runMultiple    float64
+	windowMultiple float64
+	window         time.Duration
+	minSamples     int
+	smoothing      float64
+	handlers       []CostAnomalyHandler
+	now            func() time.Time
+
+	agents map[string]*agentCost
+	mu     sync.Mutex
+}
+
+// NewCostMonitor creates a cost monitor with the default multiples and an hourly window
+func NewCostMonitor() *CostMonitor {
+	return &CostMonitor{
+		runMultiple:    DefaultCostAnomalyMultiple,
+		windowMultiple: DefaultCostAnomalyMultiple,
+		window:         DefaultCostWindow,
+		minSamples:     DefaultCostMinSamples,
+		smoothing:      DefaultCostSmoothing,
+		now:            time.Now,
+		agents:         make(map[string]*agentCost),
+	}
+}
+
+// WithRunMultiple sets how many times the typical run a run may use
+func (m *CostMonitor) WithRunMultiple(multiple float64) *CostMonitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runMultiple = multiple
+	return m
+}
+
+// WithWindow sets the length of the usage window and how many times the typical
+// window an agent may use within one
+func (m *CostMonitor) WithWindow(window time.Duration, multiple float64) *CostMonitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.window = window
+	m.windowMultiple = multiple
+	return m
+}
+
+// WithMinSamples sets the number of runs or windows a baseline needs before it is used
+func (m *CostMonitor) WithMinSamples(samples int) *CostMonitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.minSamples = samples
+	return m
+}
+
+// WithSmoothing sets the weight of each new sample in the baselines, between 0 and 1
+func (m *CostMonitor) WithSmoothing(smoothing float64) *CostMonitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.smoothing = smoothing
+	return m
+}
+
+// WithClock sets the clock of the monitor, for tests
+func (m *CostMonitor) WithClock(now func() time.Time) *CostMonitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = now
+	return m
+}
+
+// WithAlertHandler adds a function called for every anomaly
+func (m *CostMonitor) WithAlertHandler(handler CostAnomalyHandler) *CostMonitor {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers = append(m.handlers, handler)
+	return m
+}
+
+// WebhookAlert returns an alert handler that posts each anomaly as JSON to a URL.
+// Delivery failures are logged in debug mode and otherwise ignored.
+func WebhookAlert(url string, client *http.Client) CostAnomalyHandler {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return func(ctx context.Context, anomaly *CostAnomaly) {
+		body, err := json.Marshal(anomaly)
+		if err != nil {
+			return
+		}
+		req, err := http.NewRequestWithContext(context.WithoutCancel(ctx), http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			logging.For(nil, "runner").Warn("Failed to deliver cost anomaly alert", "url", url, "error", err)
+			return
+		}
+		resp.Body.Close()
+	}
+}
+
+// Stats returns the baselines and anomaly counts of every agent, by agent name
+func (m *CostMonitor) Stats() []AgentCostStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make([]AgentCostStats, 0, len(m.agents))
+	for _, agent := range m.agents {
+		stats = append(stats, agent.stats)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Agent < stats[j].Agent })
+	return stats
+}
+
+// agent returns the state of an agent. The caller holds the lock.
+func (m *CostMonitor) agent(name string) *agentCost {
+	agent, ok := m.agents[name]
+	if !ok {
+		agent = &agentCost{stats: AgentCostStats{Agent: name}}
+		m.agents[name] = agent
+	}
+	return agent
+}
+
+// observe records the usage of a response of a run and checks the run and the
+// window against their baselines. It returns true once the run is anomalous.
+func (m *CostMonitor) observe(ctx context.Context, agentName, runID string, costUSD float64, tokens int, runCostUSD float64, runTokens int, runAlerted bool) bool {
+	m.mu.Lock()
+	now := m.now()
+	agent := m.agent(agentName)
+	var anomalies []*CostAnomaly
+
+	// Roll the window over, adding the completed window to the baseline
+	windowStart := now.Truncate(m.window)
+	if !agent.windowStart.Equal(windowStart) {
+		if !agent.windowStart.IsZero() {
+			agent.stats.Windows++
+			agent.stats.WindowCostUSD = m.smooth(agent.stats.WindowCostUSD, agent.windowCost, agent.stats.Windows)
+			agent.stats.WindowTokens = m.smooth(agent.stats.WindowTokens, float64(agent.windowTokens), agent.stats.Windows)
+		}
+		agent.windowStart = windowStart
+		agent.windowCost, agent.windowTokens, agent.windowAlerted = 0, 0, false
+	}
+	agent.windowCost += costUSD
+	agent.windowTokens += tokens
+
+	if !agent.windowAlerted && agent.stats.Windows >= m.minSamples {
+		if multiple := exceeds(agent.windowCost, agent.windowTokens, agent.stats.WindowCostUSD, agent.stats.WindowTokens, m.windowMultiple); multiple > 0 {
+			agent.windowAlerted = true
+			agent.stats.WindowAnomalies++
+			anomalies = append(anomalies, &CostAnomaly{
+				Kind:            CostAnomalyWindow,
+				Agent:           agentName,
+				RunID:           runID,
+				CostUSD:         agent.windowCost,
+				Tokens:          agent.windowTokens,
+				BaselineCostUSD: agent.stats.WindowCostUSD,
+				BaselineTokens:  agent.stats.WindowTokens,
+				Multiple:        multiple,
+				WindowStart:     windowStart,
+				DetectedAt:      now,
+			})
+		}
+	}
+
+	if !runAlerted && agent.stats.Runs >= m.minSamples {
+		if multiple := exceeds(runCostUSD, runTokens, agent.stats.RunCostUSD, agent.stats.RunTokens, m.runMultiple); multiple > 0 {
+			runAlerted = true
+			agent.stats.RunAnomalies++
+			anomalies = append(anomalies, &CostAnomaly{
+				Kind:            CostAnomalyRun,
+				Agent:           agentName,
+				RunID:           runID,
+				CostUSD:         runCostUSD,
+				Tokens:          runTokens,
+				BaselineCostUSD: agent.stats.RunCostUSD,
+				BaselineTokens:  agent.stats.RunTokens,
+				Multiple:        multiple,
+				DetectedAt:      now,
+			})
+		}
+	}
+	handlers := append([]CostAnomalyHandler(nil), m.handlers...)
+	m.mu.Unlock()
+
+	for _, anomaly := range anomalies {
+		for _, handler := range handlers {
+			handler(ctx, anomaly)
+		}
+	}
+	return runAlerted
+}
+
+// finishRun adds a completed run to the agent's baseline, unless it was anomalous
+func (m *CostMonitor) finishRun(agentName string, costUSD float64, tokens int, anomalous bool) {
+	if anomalous {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	agent := m.agent(agentName)
+	agent.stats.Runs++
+	agent.stats.RunCostUSD = m.smooth(agent.stats.RunCostUSD, costUSD, agent.stats.Runs)
+	agent.stats.RunTokens = m.smooth(agent.stats.RunTokens, float64(tokens), agent.stats.Runs)
+}
+
+// smooth adds a sample to an exponentially smoothed baseline. Until the baseline
+// has enough samples it is a plain average, so early samples are not overweighted.
+func (m *CostMonitor) smooth(baseline, sample float64, samples int) float64 {
+	weight := m.smoothing
+	if 1/float64(samples) > weight {
+		weight = 1 / float64(samples)
+	}
+	return baseline + weight*(sample-baseline)
+}
+
+// exceeds returns how many times the baseline the usage is, or 0 if it is within
+// the multiple. Cost is compared when there is a cost baseline, tokens otherwise.
+func exceeds(costUSD float64, tokens int, baselineCost, baselineTokens, multiple float64) float64 {
+	if baselineCost > 0 && costUSD > 0 {
+		if ratio := costUSD / baselineCost; ratio > multiple {
+			return ratio
+		}
+		return 0
+	}
+	if baselineTokens > 0 {
+		if ratio := float64(tokens) / baselineTokens; ratio > multiple {
+			return ratio
+		}
+	}
+	return 0
+}
@@ -0,0 +1,143 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tracing"
+)
+
+// Kinds of deadlines
+const (
+	// DeadlineRun is the deadline of a whole run, set with RunOptions.Deadline or
+	// RunOptions.MaxWallClock
+	DeadlineRun = "run"
+
+	// DeadlineDelegation is the turn limit of an agent on a delegated task, set
+	// with the agent's MaxTurnsPerDelegation
+	DeadlineDelegation = "delegation"
+)
+
+// ErrDeadlineExceeded is wrapped by the errors of runs and tasks that ran past
+// their deadline
+var ErrDeadlineExceeded = errors.New("deadline exceeded")
+
+// DeadlineExceededError is returned when a run passes its deadline, or when a
+// delegate uses up its turns on a task it cannot return to a delegating agent.
+// A run deadline also matches context.DeadlineExceeded with errors.Is.
+type DeadlineExceededError struct {
+	// Kind is DeadlineRun or DeadlineDelegation
+	Kind string
+
+	// Deadline is the time a run had to finish by
+	Deadline time.Time
+
+	// MaxWallClock is the time a run had to finish in, if it was set
+	MaxWallClock time.Duration
+
+	// Agent and TaskID are the delegate and the task it did not finish
+	Agent  string
+	TaskID string
+
+	// MaxTurns is the delegate's MaxTurnsPerDelegation
+	MaxTurns int
+}
+
+// Error implements the error interface
+func (e *DeadlineExceededError) Error() string {
+	switch {
+	case e.Kind == DeadlineDelegation:
+		return fmt.Sprintf("%v: %s did not finish task %s within %d turns", ErrDeadlineExceeded, e.Agent, e.TaskID, e.MaxTurns)
+	case e.MaxWallClock > 0:
+		return fmt.Sprintf("%v: the run did not finish within %s", ErrDeadlineExceeded, e.MaxWallClock)
+	default:
+		return fmt.Sprintf("%v: the run did not finish by %s", ErrDeadlineExceeded, e.Deadline.Format(time.RFC3339))
+	}
+}
+
+// Unwrap returns ErrDeadlineExceeded
+func (e *DeadlineExceededError) Unwrap() error {
+	return ErrDeadlineExceeded
+}
+
+// Is reports a run deadline as context.DeadlineExceeded
+func (e *DeadlineExceededError) Is(target error) bool {
+	return e.Kind == DeadlineRun && target == context.DeadlineExceeded
+}
+
+// withRunDeadline returns a context that ends at the earlier of the run's
+// Deadline and the end of its MaxWallClock, with a DeadlineExceededError as cause
+func withRunDeadline(ctx context.Context, opts *RunOptions) (context.Context, context.CancelFunc) {
+	deadline, maxWallClock := opts.Deadline, time.Duration(0)
+	if opts.MaxWallClock > 0 {
+		if end := time.Now().Add(opts.MaxWallClock); deadline.IsZero() || end.Before(deadline) {
+			deadline, maxWallClock = end, opts.MaxWallClock
+		}
+	}
+	if deadline.IsZero() {
+		return ctx, func() {}
+	}
+	return context.WithDeadlineCause(ctx, deadline, &DeadlineExceededError{Kind: DeadlineRun, Deadline: deadline, MaxWallClock: maxWallClock})
+}
+
+// reportDeadline logs, records and traces an exceeded deadline
+func (r *Runner) reportDeadline(ctx context.Context, agentName string, err *DeadlineExceededError) {
+	r.log(nil).Warn("Deadline exceeded", "kind", err.Kind, "agent", agentName, "task", err.TaskID, "error", err)
+	r.recordEvent(ctx, RunEvent{Type: EventDeadlineExceeded, Agent: agentName, TaskID: err.TaskID, Reason: err.Kind, Error: err.Error()})
+	tracing.DeadlineExceeded(ctx, agentName, err.Kind, err.Error())
+}
+
+// countDelegationTurn counts a turn of an agent on the task delegated to it and
+// returns the error of a delegate that used up its MaxTurnsPerDelegation
+func (r *Runner) countDelegationTurn(ctx context.Context, turns map[string]int, agent AgentType) *DeadlineExceededError {
+	if agent.MaxTurnsPerDelegation <= 0 {
+		return nil
+	}
+	task := r.getTaskContextForAgent(ctx, agent.Name)
+	if task == nil {
+		return nil
+	}
+	r.mu.RLock()
+	inProgress := task.IsInProgress()
+	r.mu.RUnlock()
+	if !inProgress {
+		return nil
+	}
+
+	turns[task.TaskID]++
+	if turns[task.TaskID] <= agent.MaxTurnsPerDelegation {
+		return nil
+	}
+	return &DeadlineExceededError{Kind: DeadlineDelegation, Agent: agent.Name, TaskID: task.TaskID, MaxTurns: agent.MaxTurnsPerDelegation}
+}
+
+// enforceDelegationTurns fails the task of a delegate that used up its turns and
+// returns the conversation to the delegating agent, which continues the turn. A
+// delegate that cannot return stops the run with a DeadlineExceededError.
+func (r *Runner) enforceDelegationTurns(ctx context.Context, turns map[string]int, agent AgentType, input interface{}, runResult *result.RunResult, opts *RunOptions) (AgentType, interface{}, error) {
+	for {
+		exceeded := r.countDelegationTurn(ctx, turns, agent)
+		if exceeded == nil {
+			return agent, input, nil
+		}
+		r.reportDeadline(ctx, agent.Name, exceeded)
+		r.failTask(ctx, exceeded.TaskID, exceeded)
+
+		message := fmt.Sprintf("%s did not complete the task within %d turns and was stopped. Continue without its result, or delegate the task again with clearer instructions.", agent.Name, exceeded.MaxTurns)
+		next, nextInput, err := r.processHandoff(ctx, agent, input, &model.HandoffCall{
+			AgentName:  "return_to_delegator",
+			Parameters: map[string]interface{}{"input": message},
+		}, runResult, opts)
+		if errors.Is(err, ErrHandoffTargetNotFound) {
+			return nil, nil, exceeded
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		agent, input = next, nextInput
+	}
+}
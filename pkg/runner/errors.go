@@ -0,0 +1,36 @@
+package runner
+
+import (
+	"errors"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+)
+
+// Errors of runs, which the errors returned by the runner wrap so that callers
+// can tell them apart with errors.Is
+var (
+	// ErrMaxTurnsExceeded is returned when a streamed run ends without a final
+	// output after its maximum number of turns
+	ErrMaxTurnsExceeded = errors.New("exceeded maximum number of turns")
+
+	// ErrToolNotFound is returned when a model calls a tool the agent does not have
+	ErrToolNotFound = errors.New("tool not found")
+
+	// ErrHandoffTargetNotFound is returned when a model hands off to an agent that
+	// is not among the handoffs of the current agent, or returns to a delegator
+	// that is not
+	ErrHandoffTargetNotFound = errors.New("handoff target not found")
+)
+
+// Errors of model providers, for use with errors.As
+type (
+	// ErrProvider is an error response of a provider's API
+	ErrProvider = model.ErrProvider
+
+	// ErrRateLimited is returned for requests rejected because of rate limits
+	ErrRateLimited = model.ErrRateLimited
+
+	// ErrContextLengthExceeded is returned for requests too long for the context
+	// window of the model
+	ErrContextLengthExceeded = model.ErrContextLengthExceeded
+)
@@ -0,0 +1,366 @@
+package runner
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+)
+
+// Types of run events
+const (
+	EventRunStarted       = "run_started"
+	EventTurnStarted      = "turn_started"
+	EventModelResponse    = "model_response"
+	EventToolCall         = "tool_call"
+	EventToolResult       = "tool_result"
+	EventHandoff          = "handoff"
+	EventTaskCreated      = "task_created"
+	EventTaskTransition   = "task_transition"
+	EventArtifactUpdated  = "artifact_updated"
+	EventLoopDetected     = "loop_detected"
+	EventDeadlineExceeded = "deadline_exceeded"
+	EventRunPaused        = "run_paused"
+	EventRunCompleted     = "run_completed"
+	EventRunFailed        = "run_failed"
+	EventRunCancelled     = "run_cancelled"
+)
+
+// RunEvent is a state change of a run. The events of a run, in sequence order,
+// are the source the projections compute its state, task board and usage from.
+type RunEvent struct {
+	// RunID identifies the run
+	RunID string `json:"run_id"`
+
+	// UserID is the end user the run acts for
+	UserID string `json:"user_id,omitempty"`
+
+	// Sequence orders the events of the run, starting at 1
+	Sequence int64 `json:"sequence"`
+
+	// Type is the type of the event, such as EventToolCall
+	Type string `json:"type"`
+
+	// Time is when the event happened
+	Time time.Time `json:"time"`
+
+	// Agent is the agent the event happened to
+	Agent string `json:"agent,omitempty"`
+
+	// Turn is the turn the event happened in
+	Turn int `json:"turn,omitempty"`
+
+	// Target is the agent handed off to, or the executor of a created task
+	Target string `json:"target,omitempty"`
+
+	// Tool and Arguments are the tool and arguments of tool events
+	Tool      string                 `json:"tool,omitempty"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+
+	// Model and Usage are the model and token usage of model responses
+	Model string       `json:"model,omitempty"`
+	Usage *model.Usage `json:"usage,omitempty"`
+
+	// TaskID, From, To and Reason are the task, statuses and reason of task
+	// events
+	TaskID string     `json:"task_id,omitempty"`
+	From   TaskStatus `json:"from,omitempty"`
+	To     TaskStatus `json:"to,omitempty"`
+	Reason string     `json:"reason,omitempty"`
+
+	// Version is the artifact version of artifact events
+	Version int `json:"version,omitempty"`
+
+	// Input is the input of the run or handoff, and Output the tool result or
+	// final output
+	Input  interface{} `json:"input,omitempty"`
+	Output interface{} `json:"output,omitempty"`
+
+	// Error is the error of a failed tool call or run
+	Error string `json:"error,omitempty"`
+}
+
+// EventStore stores the event logs of runs
+type EventStore interface {
+	// AppendEvents appends events to the logs of their runs
+	AppendEvents(ctx context.Context, events ...RunEvent) error
+
+	// LoadEvents returns the events of a run in sequence order
+	LoadEvents(ctx context.Context, runID string) ([]RunEvent, error)
+}
+
+// MemoryEventStore keeps event logs in memory
+type MemoryEventStore struct {
+	events map[string][]RunEvent
+	mu     sync.RWMutex
+}
+
+// Ensure MemoryEventStore implements EventStore
+var _ EventStore = (*MemoryEventStore)(nil)
+
+// NewMemoryEventStore creates an empty in-memory event store
+func NewMemoryEventStore() *MemoryEventStore {
+	return &MemoryEventStore{events: make(map[string][]RunEvent)}
+}
+
+// AppendEvents appends events to the logs of their runs
+func (s *MemoryEventStore) AppendEvents(ctx context.Context, events ...RunEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, event := range events {
+		s.events[event.RunID] = append(s.events[event.RunID], event)
+	}
+	return nil
+}
+
+// LoadEvents returns the events of a run in sequence order
+func (s *MemoryEventStore) LoadEvents(ctx context.Context, runID string) ([]RunEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]RunEvent(nil), s.events[runID]...), nil
+}
+
+// FileEventStore keeps the event log of each run in a JSON Lines file named
+// after the run in a directory
+type FileEventStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// Ensure FileEventStore implements EventStore
+var _ EventStore = (*FileEventStore)(nil)
+
+// NewFileEventStore creates an event store writing to a directory, creating it
+// if needed
+func NewFileEventStore(dir string) (*FileEventStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create event log directory: %w", err)
+	}
+	return &FileEventStore{dir: dir}, nil
+}
+
+// path returns the file of a run's event log
+func (s *FileEventStore) path(runID string) (string, error) {
+	if runID == "" || strings.ContainsAny(runID, `/\`) || runID == "." || runID == ".." {
+		return "", fmt.Errorf("invalid run ID %q", runID)
+	}
+	return filepath.Join(s.dir, runID+".jsonl"), nil
+}
+
+// AppendEvents appends events to the logs of their runs
+func (s *FileEventStore) AppendEvents(ctx context.Context, events ...RunEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, event := range events {
+		path, err := s.path(event.RunID)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open event log: %w", err)
+		}
+		_, err = f.Write(append(data, '\n'))
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to append event: %w", err)
+		}
+	}
+	return nil
+}
+
+// LoadEvents returns the events of a run in sequence order
+func (s *FileEventStore) LoadEvents(ctx context.Context, runID string) ([]RunEvent, error) {
+	path, err := s.path(runID)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loadEvents(path, runID)
+}
+
+// loadEvents reads an event log; the caller holds the lock
+func (s *FileEventStore) loadEvents(path, runID string) ([]RunEvent, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log: %w", err)
+	}
+	defer f.Close()
+
+	var events []RunEvent
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var event RunEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("failed to decode event %d of %s: %w", len(events)+1, runID, err)
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read event log: %w", err)
+	}
+	return events, nil
+}
+
+// WithEventStore records every state change of the runner's runs as an ordered
+// event log in the store
+func (r *Runner) WithEventStore(store EventStore) *Runner {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.eventStore = store
+	return r
+}
+
+// RunEvents returns the event log of a run
+func (r *Runner) RunEvents(ctx context.Context, runID string) ([]RunEvent, error) {
+	r.mu.RLock()
+	store := r.eventStore
+	r.mu.RUnlock()
+	if store == nil {
+		return nil, errors.New("runner has no event store")
+	}
+	return store.LoadEvents(ctx, runID)
+}
+
+// eventLog appends the events of a run to the event store
+type eventLog struct {
+	store    EventStore
+	runID    string
+	userID   string
+	sequence int64
+	runner   *Runner
+	mu       sync.Mutex
+}
+
+type eventLogKey struct{}
+
+// withEventLog returns a context carrying the event log of a run, continuing the
+// sequence of a run resumed with the same ID
+func (r *Runner) withEventLog(ctx context.Context, runID, userID string) context.Context {
+	r.mu.RLock()
+	store := r.eventStore
+	r.mu.RUnlock()
+	if store == nil {
+		return ctx
+	}
+
+	log := &eventLog{store: store, runID: runID, userID: userID, runner: r}
+	if events, err := store.LoadEvents(ctx, runID); err != nil {
+		r.log(nil).Warn("Failed to load event log", "run", runID, "error", err)
+	} else if len(events) > 0 {
+		log.sequence = events[len(events)-1].Sequence
+	}
+	return context.WithValue(ctx, eventLogKey{}, log)
+}
+
+// recordEvent appends an event to the log of the run of the context. Events that
+// cannot be stored are logged and do not fail the run.
+func (r *Runner) recordEvent(ctx context.Context, event RunEvent) {
+	r.recordOutcome(ctx, event)
+
+	log, ok := ctx.Value(eventLogKey{}).(*eventLog)
+	if !ok {
+		return
+	}
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	log.sequence++
+	event.RunID = log.runID
+	event.UserID = log.userID
+	event.Sequence = log.sequence
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if err := log.store.AppendEvents(context.WithoutCancel(ctx), event); err != nil {
+		log.runner.log(nil).Warn("Failed to append run event", "run", log.runID, "type", event.Type, "error", err)
+	}
+}
+
+// recordTaskTransition appends the last status transition of a task to the event
+// log
+func (r *Runner) recordTaskTransition(ctx context.Context, task *TaskContext, agentName string) {
+	if len(task.StatusHistory) == 0 {
+		return
+	}
+	transition := task.StatusHistory[len(task.StatusHistory)-1]
+	r.recordEvent(ctx, RunEvent{
+		Type:   EventTaskTransition,
+		Agent:  agentName,
+		TaskID: task.TaskID,
+		From:   transition.From,
+		To:     transition.To,
+		Reason: transition.Reason,
+	})
+}
+
+// recordRunEnd appends the outcome of a run to the event log
+func (r *Runner) recordRunEnd(ctx context.Context, agentName string, output interface{}, err error) {
+	event := RunEvent{Type: EventRunCompleted, Agent: agentName, Output: output}
+	var approvalErr *ApprovalRequiredError
+	var pausedErr *RunPausedError
+	switch {
+	case errors.As(err, &approvalErr) || errors.As(err, &pausedErr):
+		event = RunEvent{Type: EventRunPaused, Agent: agentName}
+	case isCancellation(err):
+		event = RunEvent{Type: EventRunCancelled, Agent: agentName, Error: err.Error()}
+	case err != nil:
+		event = RunEvent{Type: EventRunFailed, Agent: agentName, Error: err.Error()}
+		var deadlineErr *DeadlineExceededError
+		if errors.As(err, &deadlineErr) && deadlineErr.Kind == DeadlineRun {
+			r.reportDeadline(ctx, agentName, deadlineErr)
+		}
+	}
+	r.recordEvent(ctx, event)
+}
+
+// recordStreamEnd appends the outcome of a streamed run to the event log. The
+// errors of streamed runs are sent as stream events, so failed runs are recorded
+// with the cause of their cancellation only.
+func (r *Runner) recordStreamEnd(ctx context.Context, startingAgent AgentType, streamedResult *result.StreamedRunResult, paused bool) {
+	agentName := startingAgent.Name
+	if streamedResult.CurrentAgent != nil {
+		agentName = streamedResult.CurrentAgent.Name
+	}
+	switch {
+	case paused:
+		r.recordEvent(ctx, RunEvent{Type: EventRunPaused, Agent: agentName})
+	case streamedResult.IsComplete:
+		r.recordRunEnd(ctx, agentName, streamedResult.FinalOutput, nil)
+	case isCancellation(context.Cause(ctx)):
+		r.recordEvent(ctx, RunEvent{Type: EventRunCancelled, Agent: agentName, Error: context.Cause(ctx).Error()})
+	default:
+		event := RunEvent{Type: EventRunFailed, Agent: agentName}
+		if ctx.Err() != nil {
+			event.Error = context.Cause(ctx).Error()
+			var deadlineErr *DeadlineExceededError
+			if errors.As(context.Cause(ctx), &deadlineErr) {
+				r.reportDeadline(ctx, agentName, deadlineErr)
+			}
+		}
+		r.recordEvent(ctx, event)
+	}
+}
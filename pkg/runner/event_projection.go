@@ -0,0 +1,185 @@
+package runner
+
+import (
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+)
+
+// Project computes a view of a run by applying its events in order to an
+// initial state. The projections of this package are built with it.
+func Project[S any](events []RunEvent, initial S, apply func(state S, event RunEvent) S) S {
+	state := initial
+	for _, event := range events {
+		state = apply(state, event)
+	}
+	return state
+}
+
+// Statuses of a projected run
+const (
+	RunStatusRunning   = "running"
+	RunStatusPaused    = "paused"
+	RunStatusCompleted = "completed"
+	RunStatusFailed    = "failed"
+	RunStatusCancelled = "cancelled"
+)
+
+// RunView is the current state of a run, projected from its events
+type RunView struct {
+	RunID         string
+	Status        string
+	StartingAgent string
+	CurrentAgent  string
+	Turn          int
+	Input         interface{}
+	FinalOutput   interface{}
+	Error         string
+	StartedAt     time.Time
+	EndedAt       time.Time
+
+	// ToolCalls and Handoffs count the tool calls and handoffs of the run
+	ToolCalls int
+	Handoffs  int
+}
+
+// ProjectRun returns the current state of a run
+func ProjectRun(events []RunEvent) RunView {
+	return Project(events, RunView{}, func(view RunView, event RunEvent) RunView {
+		view.RunID = event.RunID
+		switch event.Type {
+		case EventRunStarted:
+			if view.StartedAt.IsZero() {
+				view.StartedAt = event.Time
+				view.StartingAgent = event.Agent
+				view.Input = event.Input
+			}
+			view.Status = RunStatusRunning
+			view.CurrentAgent = event.Agent
+			view.EndedAt = time.Time{}
+		case EventTurnStarted:
+			view.CurrentAgent = event.Agent
+			view.Turn = event.Turn
+		case EventToolCall:
+			view.ToolCalls++
+		case EventHandoff:
+			view.Handoffs++
+		case EventRunPaused:
+			view.Status = RunStatusPaused
+		case EventRunCompleted:
+			view.Status = RunStatusCompleted
+			view.FinalOutput = event.Output
+			view.EndedAt = event.Time
+		case EventRunFailed:
+			view.Status = RunStatusFailed
+			view.Error = event.Error
+			view.EndedAt = event.Time
+		case EventRunCancelled:
+			view.Status = RunStatusCancelled
+			view.Error = event.Error
+			view.EndedAt = event.Time
+		}
+		return view
+	})
+}
+
+// TaskCard is a delegated task on the task board of a run
+type TaskCard struct {
+	TaskID    string
+	Delegator string
+	Executor  string
+	Status    TaskStatus
+
+	// ArtifactVersion is the latest version of the task's artifact
+	ArtifactVersion int
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// TaskBoard is the delegated tasks of a run, in the order they were created
+type TaskBoard []TaskCard
+
+// ByStatus returns the cards of the tasks in a status
+func (b TaskBoard) ByStatus(status TaskStatus) []TaskCard {
+	var cards []TaskCard
+	for _, card := range b {
+		if card.Status == status {
+			cards = append(cards, card)
+		}
+	}
+	return cards
+}
+
+// ProjectTaskBoard returns the delegated tasks of a run and their current status
+func ProjectTaskBoard(events []RunEvent) TaskBoard {
+	index := make(map[string]int)
+	return Project(events, TaskBoard{}, func(board TaskBoard, event RunEvent) TaskBoard {
+		if event.TaskID == "" {
+			return board
+		}
+		i, exists := index[event.TaskID]
+		switch {
+		case event.Type == EventTaskCreated && !exists:
+			index[event.TaskID] = len(board)
+			return append(board, TaskCard{
+				TaskID:    event.TaskID,
+				Delegator: event.Agent,
+				Executor:  event.Target,
+				Status:    TaskStatusPending,
+				CreatedAt: event.Time,
+				UpdatedAt: event.Time,
+			})
+		case !exists:
+			return board
+		case event.Type == EventTaskTransition:
+			board[i].Status = event.To
+			board[i].UpdatedAt = event.Time
+		case event.Type == EventArtifactUpdated:
+			board[i].ArtifactVersion = event.Version
+			board[i].UpdatedAt = event.Time
+		}
+		return board
+	})
+}
+
+// UsageView is the token usage of a run, projected from its model responses
+type UsageView struct {
+	// ModelCalls is the number of model responses
+	ModelCalls int
+
+	// Total is the usage of the run
+	Total model.Usage
+
+	// ByAgent and ByModel split the usage by agent name and model name
+	ByAgent map[string]model.Usage
+	ByModel map[string]model.Usage
+}
+
+// ProjectUsage returns the token usage of a run
+func ProjectUsage(events []RunEvent) UsageView {
+	initial := UsageView{ByAgent: make(map[string]model.Usage), ByModel: make(map[string]model.Usage)}
+	return Project(events, initial, func(view UsageView, event RunEvent) UsageView {
+		if event.Type != EventModelResponse {
+			return view
+		}
+		view.ModelCalls++
+		if event.Usage == nil {
+			return view
+		}
+		view.Total = addUsage(view.Total, event.Usage)
+		view.ByAgent[event.Agent] = addUsage(view.ByAgent[event.Agent], event.Usage)
+		view.ByModel[event.Model] = addUsage(view.ByModel[event.Model], event.Usage)
+		return view
+	})
+}
+
+// addUsage returns the sum of two usages
+func addUsage(total model.Usage, usage *model.Usage) model.Usage {
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.TotalTokens += usage.TotalTokens
+	total.CachedTokens += usage.CachedTokens
+	total.CacheCreationTokens += usage.CacheCreationTokens
+	return total
+}
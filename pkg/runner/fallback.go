@@ -0,0 +1,151 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+)
+
+// statusOverloaded is the status providers such as Anthropic answer with when
+// they are overloaded
+const statusOverloaded = 529
+
+// ModelFallbackHooks is implemented by run hooks that want to know when a model
+// call is retried on a fallback model. An error aborts the run.
+type ModelFallbackHooks interface {
+	OnModelFallback(ctx context.Context, agent AgentType, fallback result.ModelFallback) error
+}
+
+// OnModelFallback is called when a model call is retried on a fallback model
+func (m MultiRunHooks) OnModelFallback(ctx context.Context, agent AgentType, fallback result.ModelFallback) error {
+	for _, h := range m {
+		if fallbackHooks, ok := h.(ModelFallbackHooks); ok {
+			if err := fallbackHooks.OnModelFallback(ctx, agent, fallback); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ShouldFallback reports whether a failed model call is worth retrying on
+// another model: the provider rate limited it, was overloaded or failed, or the
+// request did not fit the model's context window
+func ShouldFallback(err error) bool {
+	var rateErr *model.ErrRateLimited
+	if errors.As(err, &rateErr) {
+		return true
+	}
+	if errors.Is(err, model.ErrContextWindowExceeded) {
+		return true
+	}
+	var providerErr *model.ErrProvider
+	if errors.As(err, &providerErr) {
+		return providerErr.Status == statusOverloaded || providerErr.Status >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// withFallbacks calls the model and, while the call fails with an error the run
+// falls back on, calls the run's fallback models in order. Each fallback is
+// recorded in the run result and reported to the run hooks.
+func (r *Runner) withFallbacks(ctx context.Context, agent AgentType, modelInstance model.Model, opts *RunOptions, runResult *result.RunResult, turn int, call func(m model.Model) error) error {
+	err := call(modelInstance)
+	if err == nil || opts.RunConfig == nil || len(opts.RunConfig.ModelFallbacks) == 0 {
+		return err
+	}
+	shouldFallback := opts.RunConfig.FallbackOn
+	if shouldFallback == nil {
+		shouldFallback = ShouldFallback
+	}
+
+	from := describeModel(opts.RunConfig.Model)
+	if from == "" {
+		from = describeModel(agent.Model)
+	}
+	for _, fallback := range opts.RunConfig.ModelFallbacks {
+		if ctx.Err() != nil || !shouldFallback(err) {
+			return err
+		}
+		fallbackModel, resolveErr := resolveConfiguredModel(opts.RunConfig, fallback, "fallback")
+		if resolveErr != nil {
+			r.log(agent).Warn("Skipping fallback model", "agent", agent.Name, "model", describeModel(fallback), "error", resolveErr)
+			continue
+		}
+
+		record := result.ModelFallback{Agent: agent.Name, Turn: turn, From: from, To: describeModel(fallback), Error: err}
+		r.log(agent).Warn("Model call failed, retrying on fallback model", "agent", agent.Name, "from", record.From, "to", record.To, "error", err)
+		if runResult != nil {
+			runResult.ModelFallbacks = append(runResult.ModelFallbacks, record)
+		}
+		if hooks, ok := r.runHooks(opts).(ModelFallbackHooks); ok {
+			if hookErr := hooks.OnModelFallback(ctx, agent, record); hookErr != nil {
+				return fmt.Errorf("model fallback hook error: %w", hookErr)
+			}
+		}
+
+		err = call(fallbackModel)
+		if err == nil {
+			return nil
+		}
+		from = record.To
+	}
+	return err
+}
+
+// resolveConfiguredModel returns a model of the run configuration, such as a
+// fallback model, resolving model names with the run's model provider
+func resolveConfiguredModel(runConfig *RunConfig, m interface{}, purpose string) (model.Model, error) {
+	switch v := m.(type) {
+	case model.Model:
+		return v, nil
+	case string:
+		if runConfig == nil || runConfig.ModelProvider == nil {
+			return nil, fmt.Errorf("no model provider to resolve %s model %s", purpose, v)
+		}
+		return runConfig.ModelProvider.GetModel(v)
+	default:
+		return nil, fmt.Errorf("invalid %s model type: %T", purpose, v)
+	}
+}
+
+// describeModel returns the name of a model, or its type for model values
+func describeModel(m interface{}) string {
+	switch v := m.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// turnModelName returns the name of the model that answered a turn, for pricing:
+// the fallback model the turn was last retried on, or the agent's model
+func turnModelName(agent AgentType, runConfig *RunConfig, runResult *result.RunResult, turn int) string {
+	if runResult != nil && len(runResult.ModelFallbacks) > 0 {
+		last := runResult.ModelFallbacks[len(runResult.ModelFallbacks)-1]
+		if last.Agent == agent.Name && last.Turn == turn {
+			return last.To
+		}
+	}
+	return modelName(agent, runConfig)
+}
+
+// traceModelName returns the model of a turn for traces: its name, or the type of
+// a model instance. It never formats the instance itself, which parallel runs may
+// be using.
+func traceModelName(agent AgentType, runConfig *RunConfig, runResult *result.RunResult, turn int) string {
+	if name := turnModelName(agent, runConfig, runResult, turn); name != "" {
+		return name
+	}
+	if runConfig != nil && runConfig.Model != nil {
+		return describeModel(runConfig.Model)
+	}
+	return describeModel(agent.Model)
+}
@@ -0,0 +1,98 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/guardrail"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tracing"
+)
+
+// runInputGuardrails checks the run input against the guardrails of the run
+// config and the starting agent. The results are recorded on the run result and
+// a *guardrail.GuardrailTripped error is returned if any tripwire is triggered.
+func (r *Runner) runInputGuardrails(ctx context.Context, agent AgentType, input interface{}, opts *RunOptions, runResult *result.RunResult) error {
+	var guardrails []guardrail.InputGuardrail
+	if opts.RunConfig != nil {
+		guardrails = append(guardrails, opts.RunConfig.InputGuardrails...)
+	}
+	guardrails = append(guardrails, agent.InputGuardrails...)
+
+	for _, g := range guardrails {
+		res, err := g.CheckInput(ctx, input)
+		gr, tripped := r.recordGuardrailResult(ctx, agent, g.Name(), guardrail.StageInput, res, err)
+		runResult.InputGuardrailResults = append(runResult.InputGuardrailResults, gr)
+		if err != nil {
+			return fmt.Errorf("input guardrail %s error: %w", g.Name(), err)
+		}
+		if tripped != nil {
+			return tripped
+		}
+	}
+
+	return nil
+}
+
+// runOutputGuardrails checks the final output against the guardrails of the run
+// config and the agent that produced it
+func (r *Runner) runOutputGuardrails(ctx context.Context, agent AgentType, output interface{}, opts *RunOptions, runResult *result.RunResult) error {
+	var guardrails []guardrail.OutputGuardrail
+	if opts.RunConfig != nil {
+		guardrails = append(guardrails, opts.RunConfig.OutputGuardrails...)
+	}
+	guardrails = append(guardrails, agent.OutputGuardrails...)
+
+	for _, g := range guardrails {
+		res, err := g.CheckOutput(ctx, output)
+		gr, tripped := r.recordGuardrailResult(ctx, agent, g.Name(), guardrail.StageOutput, res, err)
+		runResult.OutputGuardrailResults = append(runResult.OutputGuardrailResults, gr)
+		if err != nil {
+			return fmt.Errorf("output guardrail %s error: %w", g.Name(), err)
+		}
+		if tripped != nil {
+			return tripped
+		}
+	}
+
+	return nil
+}
+
+// recordGuardrailResult converts a guardrail check to a result item and traces tripped guardrails
+func (r *Runner) recordGuardrailResult(ctx context.Context, agent AgentType, name string, stage guardrail.Stage, res *guardrail.Result, err error) (result.GuardrailResult, *guardrail.GuardrailTripped) {
+	if res == nil {
+		res = guardrail.Pass()
+	}
+
+	gr := result.GuardrailResult{
+		Name:    name,
+		Passed:  err == nil && !res.TripwireTriggered,
+		Message: res.Message,
+		Error:   err,
+	}
+	if err != nil || !res.TripwireTriggered {
+		return gr, nil
+	}
+
+	tripped := &guardrail.GuardrailTripped{Guardrail: name, Stage: stage, Result: res}
+	tracing.Error(ctx, agent.Name, "guardrail tripped", tripped)
+	return gr, tripped
+}
+
+// guardrailErrorEvent creates the stream event for an error returned by the guardrails
+func guardrailErrorEvent(err error) model.StreamEvent {
+	var tripped *guardrail.GuardrailTripped
+	if errors.As(err, &tripped) {
+		return model.StreamEvent{
+			Type:    model.StreamEventTypeGuardrailTripped,
+			Content: tripped.Result.Message,
+			Error:   tripped,
+		}
+	}
+	return model.StreamEvent{
+		Type:  model.StreamEventTypeError,
+		Error: err,
+	}
+}
@@ -0,0 +1,45 @@
+package runner
+
+import (
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+)
+
+// resolveHandoffCall makes the handoff call of a response name its target by the
+// agent's name. Providers recover agent names from handoff_to_ tool names, which
+// are sanitized, and report calls of declared handoff tools with other names as
+// tool calls; both are mapped back to the target here.
+func resolveHandoffCall(a AgentType, response *model.Response) {
+	if response == nil {
+		return
+	}
+	if call := response.HandoffCall; call != nil {
+		if target, ok := a.HandoffTarget(call.AgentName); ok {
+			call.AgentName = target.Name
+		}
+		return
+	}
+
+	for i, tc := range response.ToolCalls {
+		target, ok := a.HandoffToolTarget(tc.Name)
+		if !ok {
+			continue
+		}
+		call := &model.HandoffCall{
+			AgentName:  target.Name,
+			Parameters: map[string]interface{}{"input": model.HandoffInput(tc.Parameters)},
+			Type:       model.HandoffTypeDelegate,
+		}
+		if taskID, ok := tc.Parameters["task_id"].(string); ok {
+			call.TaskID = taskID
+		}
+		if returnTo, ok := tc.Parameters["return_to_agent"].(string); ok {
+			call.ReturnToAgent = returnTo
+		}
+		if isComplete, ok := tc.Parameters["is_task_complete"].(bool); ok {
+			call.IsTaskComplete = isComplete
+		}
+		response.HandoffCall = call
+		response.ToolCalls = append(response.ToolCalls[:i:i], response.ToolCalls[i+1:]...)
+		return
+	}
+}
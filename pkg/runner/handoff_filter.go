@@ -0,0 +1,127 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/guardrail"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+)
+
+// ModelHistorySummarizer returns a summarizer for agent.SummarizedHistory that
+// has a model summarize the delegating agent's conversation
+func ModelHistorySummarizer(m model.Model) agent.HistorySummarizer {
+	return func(ctx context.Context, history []interface{}) (string, error) {
+		response, err := m.GetResponse(ctx, &model.Request{
+			SystemInstructions: summaryInstructions,
+			Input:              transcript(history),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to summarize history: %w", err)
+		}
+		return response.Content, nil
+	}
+}
+
+// shapeHandoff builds the context of a delegation and applies the delegating
+// agent's filter for the target to it
+func shapeHandoff(ctx context.Context, from, to AgentType, input, conversation interface{}, currentTask *TaskContext) agent.HandoffContext {
+	hc := agent.HandoffContext{
+		Context:      ctx,
+		From:         from.Name,
+		To:           to.Name,
+		Input:        input,
+		Conversation: historyItems(conversation),
+	}
+	if currentTask != nil && currentTask.WorkingContext != nil && currentTask.WorkingContext.Artifact != nil {
+		hc.Artifact = currentTask.WorkingContext.Artifact
+		hc.ArtifactType = currentTask.WorkingContext.ArtifactType
+	}
+	if filter, ok := from.HandoffFilterFor(to.Name); ok {
+		hc = filter(hc)
+	}
+	return hc
+}
+
+// historyItems returns an input as history items
+func historyItems(input interface{}) []interface{} {
+	switch in := input.(type) {
+	case []interface{}:
+		return in
+	case string:
+		if in == "" {
+			return nil
+		}
+		return []interface{}{model.DefaultMessageFormatter{}.FormatUserMessage(in)}
+	}
+	return nil
+}
+
+// targetInput returns the input of the handoff's target: its task with the
+// artifact and metadata added
+func targetInput(hc agent.HandoffContext) interface{} {
+	switch in := hc.Input.(type) {
+	case string:
+		text := in
+		if code, ok := hc.Artifact.(string); ok && hc.ArtifactType == ArtifactTypeCode {
+			text += fmt.Sprintf("\n\nHere is the code that was previously worked on:\n```\n%s\n```\n", code)
+		}
+		if len(hc.Metadata) > 0 {
+			text += "\n\nContext:\n" + metadataLines(hc.Metadata)
+		}
+		return text
+	case map[string]interface{}:
+		shaped := make(map[string]interface{}, len(in)+2)
+		for key, value := range in {
+			shaped[key] = value
+		}
+		if hc.Artifact != nil {
+			if hc.ArtifactType == ArtifactTypeCode {
+				shaped["code_context"] = hc.Artifact
+			} else {
+				shaped["context"] = hc.Artifact
+			}
+		}
+		if len(hc.Metadata) > 0 {
+			shaped["metadata"] = hc.Metadata
+		}
+		return shaped
+	}
+	return hc.Input
+}
+
+// metadataLines renders metadata as sorted "key: value" lines
+func metadataLines(metadata map[string]interface{}) string {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s: %s\n", key, guardrail.Text(metadata[key]))
+	}
+	return b.String()
+}
+
+// withHandoffHistory puts the history a filter chose before the target's input
+func withHandoffHistory(formatter model.MessageFormatter, history []interface{}, input interface{}) interface{} {
+	if len(history) == 0 {
+		return input
+	}
+	text, ok := input.(string)
+	if !ok {
+		data, err := json.Marshal(input)
+		if err != nil {
+			text = guardrail.Text(input)
+		} else {
+			text = string(data)
+		}
+	}
+	items := append(make([]interface{}, 0, len(history)+1), history...)
+	return append(items, formatter.FormatUserMessage(text))
+}
@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+)
+
+// handoffInputKey is the task metadata key of the typed input of a delegated task
+const handoffInputKey = "handoff_input"
+
+// handoffInputCtxKey is the context key of the typed input of the current task
+type handoffInputCtxKey struct{}
+
+// HandoffInput returns the typed input the agent of a context was handed off with,
+// for agents with a handoff input type (see agent.WithHandoffInputType). Tools of
+// the agent receive it in their context:
+//
+//	request, ok := runner.HandoffInput[ReviewRequest](ctx)
+func HandoffInput[T any](ctx context.Context) (T, bool) {
+	input, ok := ctx.Value(handoffInputCtxKey{}).(T)
+	return input, ok
+}
+
+// decodeHandoffInput validates the input of a delegation against the target's
+// handoff input type. Invalid input is returned to the delegating agent as
+// feedback, so that it can correct the call; ok is false then.
+func (r *Runner) decodeHandoffInput(ctx context.Context, from, to AgentType, input, currentInput interface{}, opts *RunOptions) (typed, feedback interface{}, ok bool, err error) {
+	typed, err = to.DecodeHandoffInput(input)
+	var inputErr *agent.HandoffInputError
+	if errors.As(err, &inputErr) {
+		r.log(from).Warn("Rejected handoff input", "from", from.Name, "to", to.Name, "problems", inputErr.Problems)
+		message := fmt.Sprintf("The handoff to %s was not made: %s. Call %s again with arguments matching its schema.", to.Name, inputErr.Error(), from.HandoffFor(to).Name())
+		return nil, appendUserMessage(r.messageFormatter(ctx, from, opts), currentInput, message), false, nil
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return typed, nil, true, nil
+}
+
+// typedFields returns typed handoff input as the object its model receives, so
+// input given as JSON text and input with extra fields look the same
+func typedFields(typed interface{}) (map[string]interface{}, bool) {
+	data, err := json.Marshal(typed)
+	if err != nil {
+		return nil, false
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, false
+	}
+	return fields, true
+}
+
+// typedInputText renders the validated input of a target with a handoff input
+// type as the JSON text its model receives
+func typedInputText(input interface{}) interface{} {
+	if _, ok := input.(map[string]interface{}); !ok {
+		return input
+	}
+	data, err := json.MarshalIndent(input, "", "  ")
+	if err != nil {
+		return input
+	}
+	return string(data)
+}
+
+// withHandoffInput returns a context carrying the typed input of the task the
+// agent is working on, if it has one
+func (r *Runner) withHandoffInput(ctx context.Context, agentName string) context.Context {
+	task := r.getTaskContextForAgent(ctx, agentName)
+	if task == nil {
+		return ctx
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if task.WorkingContext == nil {
+		return ctx
+	}
+	if input, ok := task.WorkingContext.Metadata[handoffInputKey]; ok {
+		return context.WithValue(ctx, handoffInputCtxKey{}, input)
+	}
+	return ctx
+}
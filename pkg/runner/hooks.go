@@ -67,3 +67,77 @@ func (h *DefaultRunHooks) OnBeforeHandoff(ctx context.Context, agent AgentType,
 func (h *DefaultRunHooks) OnAfterHandoff(ctx context.Context, agent AgentType, handoffAgent AgentType, result interface{}) error {
 	return nil
 }
+
+// MultiRunHooks calls several RunHooks in order, stopping at the first error
+type MultiRunHooks []RunHooks
+
+// NewMultiRunHooks combines hooks into a single RunHooks, skipping nil entries
+func NewMultiRunHooks(hooks ...RunHooks) MultiRunHooks {
+	multi := make(MultiRunHooks, 0, len(hooks))
+	for _, h := range hooks {
+		if h != nil {
+			multi = append(multi, h)
+		}
+	}
+	return multi
+}
+
+// OnRunStart is called when the run starts
+func (m MultiRunHooks) OnRunStart(ctx context.Context, agent *agent.Agent, input interface{}) error {
+	for _, h := range m {
+		if err := h.OnRunStart(ctx, agent, input); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OnTurnStart is called when a turn starts
+func (m MultiRunHooks) OnTurnStart(ctx context.Context, agent *agent.Agent, turn int) error {
+	for _, h := range m {
+		if err := h.OnTurnStart(ctx, agent, turn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OnTurnEnd is called when a turn ends
+func (m MultiRunHooks) OnTurnEnd(ctx context.Context, agent *agent.Agent, turn int, result *SingleTurnResult) error {
+	for _, h := range m {
+		if err := h.OnTurnEnd(ctx, agent, turn, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OnRunEnd is called when the run ends
+func (m MultiRunHooks) OnRunEnd(ctx context.Context, result *result.RunResult) error {
+	for _, h := range m {
+		if err := h.OnRunEnd(ctx, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OnBeforeHandoff is called before a handoff occurs
+func (m MultiRunHooks) OnBeforeHandoff(ctx context.Context, agent AgentType, handoffAgent AgentType) error {
+	for _, h := range m {
+		if err := h.OnBeforeHandoff(ctx, agent, handoffAgent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OnAfterHandoff is called after a handoff completes
+func (m MultiRunHooks) OnAfterHandoff(ctx context.Context, agent AgentType, handoffAgent AgentType, result interface{}) error {
+	for _, h := range m {
+		if err := h.OnAfterHandoff(ctx, agent, handoffAgent, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tracing"
+)
+
+// Kinds of loops
+const (
+	// LoopPingPong is two agents handing the conversation back and forth
+	LoopPingPong = "ping_pong"
+
+	// LoopRepeatedToolCall is an agent calling a tool again with the same arguments
+	LoopRepeatedToolCall = "repeated_tool_call"
+)
+
+// Actions taken when a loop is detected
+const (
+	// LoopActionNudge tells the agent it is looping and lets the run continue.
+	// Repeated tool calls are not made again; the agent is asked to use the
+	// results it has.
+	LoopActionNudge = "nudge"
+
+	// LoopActionAbort stops the run with a LoopDetectedError
+	LoopActionAbort = "abort"
+
+	// LoopActionHook only calls OnLoop, which lets the run continue by returning
+	// nil or stops it by returning an error
+	LoopActionHook = "hook"
+)
+
+// Defaults of loop detection
+const (
+	DefaultMaxHandoffCycles     = 3
+	DefaultMaxRepeatedToolCalls = 3
+	DefaultMaxLoopNudges        = 2
+)
+
+// ErrLoopDetected is wrapped by the errors of runs stopped because of a loop
+var ErrLoopDetected = errors.New("loop detected")
+
+// LoopDetection detects agents that loop instead of making progress: pairs of
+// agents handing off back and forth (A→B→A→B) and agents calling a tool again
+// with identical arguments. Without it such runs go on until MaxTurns.
+type LoopDetection struct {
+	// MaxHandoffCycles is the number of round trips two agents may make between
+	// them, with no other agent in between, before it is a loop.
+	// DefaultMaxHandoffCycles if zero; negative disables the check.
+	MaxHandoffCycles int
+
+	// MaxRepeatedToolCalls is the number of times an agent may call a tool with
+	// the same arguments in a run before it is a loop.
+	// DefaultMaxRepeatedToolCalls if zero; negative disables the check.
+	MaxRepeatedToolCalls int
+
+	// Action is what happens when a loop is detected: LoopActionNudge (the
+	// default), LoopActionAbort or LoopActionHook
+	Action string
+
+	// MaxNudges is the number of nudges after which the next loop aborts the run.
+	// DefaultMaxLoopNudges if zero.
+	MaxNudges int
+
+	// Nudge is the message telling an agent it is looping. A message describing
+	// the loop is used if it is empty.
+	Nudge string
+
+	// OnLoop is called with every loop detected, before the action. Returning an
+	// error stops the run with that error.
+	OnLoop func(ctx context.Context, loop *Loop) error
+}
+
+// Loop describes a loop detected in a run
+type Loop struct {
+	// Kind is LoopPingPong or LoopRepeatedToolCall
+	Kind string
+
+	// Agent is the agent whose response closed the loop
+	Agent string
+
+	// Agents are the two agents of a ping-pong loop
+	Agents []string
+
+	// Tool and Parameters are the tool call an agent repeated
+	Tool       string
+	Parameters map[string]interface{}
+
+	// Count is the number of round trips or identical tool calls
+	Count int
+
+	// Turn is the turn the loop was detected in
+	Turn int
+}
+
+// String describes the loop
+func (l *Loop) String() string {
+	if l.Kind == LoopPingPong {
+		return fmt.Sprintf("%s and %s handed the conversation back and forth %d times", l.Agents[0], l.Agents[1], l.Count)
+	}
+	return fmt.Sprintf("%s called %s with the same arguments %d times", l.Agent, l.Tool, l.Count)
+}
+
+// LoopDetectedError is returned when a run is stopped because of a loop
+type LoopDetectedError struct {
+	Loop *Loop
+}
+
+// Error implements the error interface
+func (e *LoopDetectedError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrLoopDetected, e.Loop)
+}
+
+// Unwrap returns ErrLoopDetected
+func (e *LoopDetectedError) Unwrap() error {
+	return ErrLoopDetected
+}
+
+// loopDetector tracks the handoffs and tool calls of a run
+type loopDetector struct {
+	config *LoopDetection
+
+	// path is the agents the conversation moved between since a third agent
+	// was last involved
+	path []string
+
+	// toolCalls counts the calls of each agent, tool and arguments
+	toolCalls map[string]int
+
+	nudges int
+}
+
+// newLoopDetector creates a detector for the run config, or nil if loop detection
+// is off
+func newLoopDetector(config *RunConfig) *loopDetector {
+	if config == nil || config.LoopDetection == nil {
+		return nil
+	}
+	return &loopDetector{config: config.LoopDetection, toolCalls: make(map[string]int)}
+}
+
+// handoff records a handoff and returns the ping-pong loop it closes, if any
+func (d *loopDetector) handoff(from, to string, turn int) *Loop {
+	if d == nil || from == to {
+		return nil
+	}
+	limit := d.config.MaxHandoffCycles
+	if limit == 0 {
+		limit = DefaultMaxHandoffCycles
+	}
+	if limit < 0 {
+		return nil
+	}
+
+	// Keep the path only while it alternates between the same two agents
+	n := len(d.path)
+	switch {
+	case n == 0 || d.path[n-1] != from:
+		d.path = []string{from, to}
+	case n >= 2 && d.path[n-2] != to:
+		d.path = []string{from, to}
+	default:
+		d.path = append(d.path, to)
+	}
+
+	// A round trip is two handoffs
+	cycles := (len(d.path) - 1) / 2
+	if (len(d.path)-1)%2 != 0 || cycles < limit {
+		return nil
+	}
+	return &Loop{Kind: LoopPingPong, Agent: from, Agents: []string{d.path[0], d.path[1]}, Count: cycles, Turn: turn}
+}
+
+// toolCall records the tool calls of a response and returns the first loop they
+// close, if any
+func (d *loopDetector) toolCall(agentName string, calls []model.ToolCall, turn int) *Loop {
+	if d == nil {
+		return nil
+	}
+	limit := d.config.MaxRepeatedToolCalls
+	if limit == 0 {
+		limit = DefaultMaxRepeatedToolCalls
+	}
+	if limit < 0 {
+		return nil
+	}
+
+	var loop *Loop
+	for _, tc := range calls {
+		arguments, err := json.Marshal(tc.Parameters)
+		if err != nil {
+			continue
+		}
+		key := agentName + "\x00" + tc.Name + "\x00" + string(arguments)
+		d.toolCalls[key]++
+		if count := d.toolCalls[key]; count >= limit && loop == nil {
+			loop = &Loop{Kind: LoopRepeatedToolCall, Agent: agentName, Tool: tc.Name, Parameters: tc.Parameters, Count: count, Turn: turn}
+		}
+	}
+	return loop
+}
+
+// nudgeMessage returns the message telling the agent about a loop
+func (d *loopDetector) nudgeMessage(loop *Loop) string {
+	if d.config.Nudge != "" {
+		return d.config.Nudge
+	}
+	if loop.Kind == LoopPingPong {
+		return fmt.Sprintf("You are going in circles: %s without making progress. Complete the task with what you have, or give a final answer, instead of handing it back again.", loop)
+	}
+	return fmt.Sprintf("You are going in circles: %s. The call was not made again; use the result you already have or try a different approach.", loop)
+}
+
+// handleLoop reports a detected loop and applies the configured action. It
+// returns the message to nudge the agent with, or the error stopping the run.
+func (r *Runner) handleLoop(ctx context.Context, d *loopDetector, agent AgentType, loop *Loop) (string, error) {
+	if loop == nil {
+		return "", nil
+	}
+
+	action := d.config.Action
+	if action == "" {
+		action = LoopActionNudge
+	}
+	maxNudges := d.config.MaxNudges
+	if maxNudges == 0 {
+		maxNudges = DefaultMaxLoopNudges
+	}
+	if action == LoopActionNudge && d.nudges >= maxNudges {
+		action = LoopActionAbort
+	}
+
+	r.log(agent).Warn("Detected a loop", "kind", loop.Kind, "agent", loop.Agent, "count", loop.Count, "turn", loop.Turn, "action", action)
+	r.recordEvent(ctx, RunEvent{Type: EventLoopDetected, Agent: loop.Agent, Turn: loop.Turn, Tool: loop.Tool,
+		Arguments: loop.Parameters, Reason: loop.Kind})
+	tracing.LoopDetected(ctx, loop.Agent, loop.Kind, loop.Count, action)
+
+	if d.config.OnLoop != nil {
+		if err := d.config.OnLoop(ctx, loop); err != nil {
+			return "", fmt.Errorf("loop hook error: %w", err)
+		}
+	}
+
+	switch action {
+	case LoopActionAbort:
+		return "", &LoopDetectedError{Loop: loop}
+	case LoopActionNudge:
+		d.nudges++
+		return d.nudgeMessage(loop), nil
+	default:
+		return "", nil
+	}
+}
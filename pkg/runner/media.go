@@ -0,0 +1,48 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/artifact"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+)
+
+// recordMedia stores the media of a response in the artifact store and adds a media
+// item for each part to the run result
+func (r *Runner) recordMedia(ctx context.Context, agent AgentType, response *model.Response, runResult *result.RunResult, opts *RunOptions) error {
+	// Copy the parts so the model's own response is not modified
+	response.Media = append([]model.MediaPart(nil), response.Media...)
+	for i, part := range response.Media {
+		stored, err := storeMediaPart(ctx, agent, part, opts)
+		if err != nil {
+			return err
+		}
+		response.Media[i] = stored
+		runResult.NewItems = append(runResult.NewItems, &result.MediaItem{AgentName: agent.Name, Media: stored})
+	}
+	return nil
+}
+
+// storeMediaPart moves the payload of a media part into the artifact store of the
+// run, returning the part with its artifact ID set. Parts without inline data, or
+// runs without an artifact store, are returned unchanged.
+func storeMediaPart(ctx context.Context, agent AgentType, part model.MediaPart, opts *RunOptions) (model.MediaPart, error) {
+	if opts.RunConfig == nil || opts.RunConfig.ArtifactStore == nil || len(part.Data) == 0 || part.ArtifactID != "" {
+		return part, nil
+	}
+
+	stored, err := opts.RunConfig.ArtifactStore.Put(ctx, artifact.Artifact{
+		Name:      fmt.Sprintf("%s-%s", agent.Name, part.Type),
+		MediaType: part.MediaType,
+		Metadata:  map[string]string{"agent": agent.Name, "type": part.Type},
+	}, part.Data)
+	if err != nil {
+		return part, fmt.Errorf("failed to store %s from %s: %w", part.Type, agent.Name, err)
+	}
+
+	part.ArtifactID = stored.ID
+	part.Data = nil
+	return part, nil
+}
@@ -0,0 +1,152 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+)
+
+// LabeledEvent is a stream event tagged with the stream that produced it
+type LabeledEvent struct {
+	// Label identifies the source stream, usually the agent name
+	Label string
+
+	// Sequence is the position of the event in the merged stream, starting at 1
+	Sequence uint64
+
+	// SourceSequence is the position of the event within its source stream, starting at 1
+	SourceSequence uint64
+
+	// Event is the original stream event. It is zero when Closed is set.
+	Event model.StreamEvent
+
+	// Closed marks the last event of a source, sent after its stream has ended
+	Closed bool
+}
+
+// StreamMultiplexer merges several stream event channels into one labeled channel.
+// Events from the same source are delivered in the order the source produced them,
+// and Sequence numbers reflect the order in which events were delivered.
+type StreamMultiplexer struct {
+	ctx    context.Context
+	events chan LabeledEvent
+	labels map[string]bool
+	seq    uint64
+	closed bool
+	wg     sync.WaitGroup
+	mu     sync.Mutex
+	sendMu sync.Mutex
+}
+
+// NewStreamMultiplexer creates a multiplexer. When ctx is cancelled, remaining source
+// events are drained and discarded so producers are never blocked.
+func NewStreamMultiplexer(ctx context.Context) *StreamMultiplexer {
+	return &StreamMultiplexer{
+		ctx:    ctx,
+		events: make(chan LabeledEvent, 100),
+		labels: make(map[string]bool),
+	}
+}
+
+// Add registers a source stream under the given label
+func (m *StreamMultiplexer) Add(label string, stream <-chan model.StreamEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return fmt.Errorf("stream multiplexer is closed")
+	}
+	if m.labels[label] {
+		return fmt.Errorf("stream label %q is already in use", label)
+	}
+	m.labels[label] = true
+
+	m.wg.Add(1)
+	go m.forward(label, stream)
+	return nil
+}
+
+// Events returns the merged stream. It is closed once Close has been called and
+// every source has ended.
+func (m *StreamMultiplexer) Events() <-chan LabeledEvent {
+	return m.events
+}
+
+// Close signals that no more sources will be added
+func (m *StreamMultiplexer) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return
+	}
+	m.closed = true
+
+	go func() {
+		m.wg.Wait()
+		close(m.events)
+	}()
+}
+
+// forward copies events from a source to the merged stream
+func (m *StreamMultiplexer) forward(label string, stream <-chan model.StreamEvent) {
+	defer m.wg.Done()
+
+	var sourceSeq uint64
+	for event := range stream {
+		sourceSeq++
+		if !m.send(LabeledEvent{Label: label, SourceSequence: sourceSeq, Event: event}) {
+			// The consumer is gone; drain the source so its producer can finish
+			for range stream {
+			}
+			return
+		}
+	}
+	m.send(LabeledEvent{Label: label, SourceSequence: sourceSeq + 1, Closed: true})
+}
+
+// send assigns the next sequence number and delivers the event
+func (m *StreamMultiplexer) send(event LabeledEvent) bool {
+	m.sendMu.Lock()
+	defer m.sendMu.Unlock()
+
+	m.seq++
+	event.Sequence = m.seq
+	select {
+	case m.events <- event:
+		return true
+	case <-m.ctx.Done():
+		return false
+	}
+}
+
+// RunStreamingMultiplexed streams several agents concurrently on the same options and
+// merges their events into one multiplexer labeled by agent name
+func (r *Runner) RunStreamingMultiplexed(ctx context.Context, opts *RunOptions, agents ...AgentType) (*StreamMultiplexer, error) {
+	mux := NewStreamMultiplexer(ctx)
+	defer mux.Close()
+
+	counts := make(map[string]int)
+	for _, a := range agents {
+		runOpts := *opts
+		streamed, err := r.RunStreaming(ctx, a, &runOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start stream for agent %s: %w", a.Name, err)
+		}
+
+		// Disambiguate agents that share a name
+		counts[a.Name]++
+		label := a.Name
+		if counts[a.Name] > 1 {
+			label = fmt.Sprintf("%s#%d", a.Name, counts[a.Name])
+		}
+
+		if err := mux.Add(label, streamed.Stream); err != nil {
+			return nil, err
+		}
+	}
+
+	return mux, nil
+}
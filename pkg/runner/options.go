@@ -3,7 +3,12 @@ package runner
 import (
 	"time"
 
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/artifact"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/flags"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/guardrail"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/scratchpad"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/workspace"
 )
 
 // RunOptions configures a run
@@ -17,6 +22,14 @@ type RunOptions struct {
 	// MaxTurns is the maximum number of turns
 	MaxTurns int
 
+	// Deadline is the time the run must finish by. A run past its deadline fails
+	// with a DeadlineExceededError.
+	Deadline time.Time
+
+	// MaxWallClock is the time the run may take, or zero for no limit. It is
+	// measured from the start of Run, RunStreaming or ResumeRun.
+	MaxWallClock time.Duration
+
 	// Hooks are lifecycle hooks for the run
 	Hooks RunHooks
 
@@ -25,6 +38,13 @@ type RunOptions struct {
 
 	// WorkflowConfig configures workflow-specific behavior
 	WorkflowConfig *WorkflowConfig
+
+	// RunID identifies the run while it is active. A unique ID is generated when empty.
+	RunID string
+
+	// UserID identifies the end user the run acts for. It is recorded with the
+	// run's events and traces, so retention controls can find and forget them.
+	UserID string
 }
 
 // WorkflowConfig configures workflow behavior
@@ -40,6 +60,37 @@ type WorkflowConfig struct {
 
 	// RecoveryConfig configures how to handle and recover from failures
 	RecoveryConfig *RecoveryConfig
+
+	// Phases map agents to workflow phases. The workflow state moves to an agent's
+	// phase when the agent starts a turn.
+	Phases []WorkflowPhase
+}
+
+// WorkflowPhase is a named stage of a workflow handled by one agent
+type WorkflowPhase struct {
+	// Name is the name of the phase
+	Name string
+
+	// Agent is the name of the agent that works in this phase
+	Agent string
+
+	// ModelSettings override the model settings of every agent's turns while the
+	// workflow is in this phase. Only the fields that are set are overridden.
+	ModelSettings *model.Settings
+
+	// AgentSettings override the model settings of specific agents in this phase,
+	// on top of ModelSettings
+	AgentSettings map[string]*model.Settings
+
+	// Budget is the time the workflow may spend in this phase, or zero for no limit
+	Budget time.Duration
+
+	// Escalation is what happens when the phase exceeds its budget
+	Escalation *SLAEscalation
+
+	// Compensate undoes the side effects of the phase once it has completed, when
+	// a later phase fails. Completed phases are compensated in reverse order.
+	Compensate CompensationFunc
 }
 
 // RetryConfig configures retry behavior
@@ -68,11 +119,18 @@ type StateManagementConfig struct {
 	// StateStore is the interface for storing workflow state
 	StateStore WorkflowStateStore
 
+	// WorkflowID identifies the workflow in the state store. Runs sharing an ID
+	// resume from the last saved state. Defaults to "default".
+	WorkflowID string
+
 	// CheckpointFrequency determines how often to save state
 	CheckpointFrequency time.Duration
 
 	// RestoreOnFailure indicates whether to restore state on failure
 	RestoreOnFailure bool
+
+	// Retention prunes old checkpoints after each save. Nil keeps them all.
+	Retention *CheckpointRetention
 }
 
 // ValidationConfig configures validation behavior
@@ -85,6 +143,15 @@ type ValidationConfig struct {
 
 	// PhaseTransitionValidation validates phase transitions
 	PhaseTransitionValidation []ValidationRule
+
+	// PostStepValidation validates the final output of an agent, passed to the
+	// rules as a *StepOutput. An agent whose output fails a blocking rule is asked
+	// to fix it, up to MaxStepRetries times before the workflow fails.
+	PostStepValidation []ValidationRule
+
+	// MaxStepRetries is the number of times an agent is asked to fix rejected
+	// output, DefaultStepRetries if zero. Negative fails on the first rejection.
+	MaxStepRetries int
 }
 
 // ValidationRule defines a validation rule
@@ -154,7 +221,21 @@ type RunConfig struct {
 	// ModelSettings are global model settings
 	ModelSettings *model.Settings
 
-	// HandoffInputFilter is a global handoff input filter
+	// ModelFallbacks are the models a turn is retried on, in order, when the
+	// model call fails with an error FallbackOn accepts. They are model names
+	// resolved with the model provider, or model.Model values.
+	ModelFallbacks []interface{}
+
+	// FallbackOn reports whether a failed model call is retried on the next
+	// fallback model, ShouldFallback by default
+	FallbackOn func(err error) bool
+
+	// ContextManager compacts the history of the run's turns when it approaches
+	// the model's context window
+	ContextManager *ContextManager
+
+	// HandoffInputFilter rewrites the input of every delegation handoff, after the
+	// delegating agent's handoff filter has shaped it
 	HandoffInputFilter HandoffInputFilter
 
 	// InputGuardrails are global input guardrails
@@ -163,6 +244,76 @@ type RunConfig struct {
 	// OutputGuardrails are global output guardrails
 	OutputGuardrails []OutputGuardrail
 
+	// MaxTotalTokens aborts the run once the tokens used across all turns exceed it.
+	// Zero means no limit.
+	MaxTotalTokens int
+
+	// MaxCostUSD aborts the run once its cost, computed with Pricing, exceeds it.
+	// Zero means no limit.
+	MaxCostUSD float64
+
+	// Pricing is the pricing table used for cost budgets. Defaults to DefaultPricing.
+	Pricing PricingTable
+
+	// OnBudgetExceeded is called instead of aborting the run when a budget is exceeded
+	OnBudgetExceeded BudgetExceededHook
+
+	// LoopDetection detects agents handing off back and forth and agents repeating
+	// identical tool calls, and nudges them or stops the run. Nil disables it.
+	LoopDetection *LoopDetection
+
+	// ResultValidation validates the results executors return to their delegator,
+	// delegating the task again with feedback when a result is rejected
+	ResultValidation []*ResultValidation
+
+	// ReviewDiffs adds a unified diff of what changed in a task's code artifact
+	// since an agent was last handed it to the agent's handoff input, so reviewers
+	// see what changed since their last review
+	ReviewDiffs bool
+
+	// TerminalOutput declares the delegated task whose result becomes FinalOutput
+	// when an orchestrated run ends without one
+	TerminalOutput *TerminalOutput
+
+	// CostMonitor alerts when the usage of a run or time window is far above the
+	// agent's baseline. Share one monitor between runs so it can learn baselines.
+	CostMonitor *CostMonitor
+
+	// ApprovalPolicy selects tool calls and handoffs that pause the run until a
+	// decision is passed to Runner.Resume
+	ApprovalPolicy Approval
+
+	// Checkpoint receives the state of the run between turns, for persisting it or
+	// pausing the run with ErrPauseRun
+	Checkpoint CheckpointFunc
+
+	// Scratchpad is the key/value store shared by the agents of the run, available
+	// to tools through scratchpad.FromContext. Runs without one get a new
+	// scratchpad; set it to share state across runs or to read it afterwards.
+	Scratchpad *scratchpad.Scratchpad
+
+	// Workspace provisions a temporary directory for the run, available to tools
+	// through workspace.FromContext and removed, or archived, when the run ends
+	Workspace *workspace.Manager
+
+	// Flags is the feature-flag provider of the run. It is available to instructions,
+	// guardrails and tools through the flags package, and enables tools wrapped with
+	// flags.Gate.
+	Flags flags.Provider
+
+	// ModelFlag is a string flag whose value, when not empty, overrides the model
+	// name. The agent name is added to the evaluation context as "agent".
+	ModelFlag string
+
+	// ArtifactStore receives the images and other media returned by models. Media
+	// parts in responses and run items then refer to the payload by artifact ID.
+	ArtifactStore artifact.Store
+
+	// Realtime holds the session settings of RunRealtime, such as the voice and
+	// audio formats. The model, instructions and tools come from the agent when
+	// they are not set.
+	Realtime *model.RealtimeConfig
+
 	// TracingDisabled indicates whether tracing is disabled
 	TracingDisabled bool
 
@@ -174,16 +325,10 @@ type RunConfig struct {
 type HandoffInputFilter func(input interface{}) (interface{}, error)
 
 // InputGuardrail is an interface for input guardrails
-type InputGuardrail interface {
-	// Check checks the input
-	Check(input interface{}) (bool, string, error)
-}
+type InputGuardrail = guardrail.InputGuardrail
 
 // OutputGuardrail is an interface for output guardrails
-type OutputGuardrail interface {
-	// Check checks the output
-	Check(output interface{}) (bool, string, error)
-}
+type OutputGuardrail = guardrail.OutputGuardrail
 
 // TracingConfig configures tracing
 type TracingConfig struct {
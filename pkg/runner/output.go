@@ -0,0 +1,22 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/output"
+)
+
+// processOutput runs the output processors of an agent on a text final output
+func (r *Runner) processOutput(ctx context.Context, agent AgentType, finalOutput interface{}) (interface{}, error) {
+	text, ok := finalOutput.(string)
+	if !ok || len(agent.OutputProcessors) == 0 {
+		return finalOutput, nil
+	}
+
+	processed, err := output.Apply(ctx, text, agent.OutputProcessors...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process output of agent %s: %w", agent.Name, err)
+	}
+	return processed, nil
+}
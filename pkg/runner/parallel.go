@@ -0,0 +1,117 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+)
+
+// AgentRun is one of the runs of RunParallel
+type AgentRun struct {
+	// Agent is the agent to run
+	Agent AgentType
+
+	// Options are the options of the run, including its input. Each run gets a
+	// copy, so runs may share options.
+	Options *RunOptions
+}
+
+// ParallelError is returned by RunParallel when some of the runs fail
+type ParallelError struct {
+	// Errors are the errors of the runs, in the order of the runs, with nil for
+	// runs that succeeded
+	Errors []error
+}
+
+// Error implements the error interface
+func (e *ParallelError) Error() string {
+	failed := 0
+	var first error
+	for _, err := range e.Errors {
+		if err != nil {
+			failed++
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return fmt.Sprintf("%d of %d parallel runs failed: %v", failed, len(e.Errors), first)
+}
+
+// Unwrap returns the errors of the failed runs
+func (e *ParallelError) Unwrap() []error {
+	var errs []error
+	for _, err := range e.Errors {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// WithMaxParallelRuns limits how many runs of RunParallel run at once. Zero or
+// less runs them all at once.
+func (r *Runner) WithMaxParallelRuns(n int) *Runner {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxParallelRuns = n
+	return r
+}
+
+// RunParallel runs agents concurrently and returns their results in the order of
+// the runs. If any run fails, the results of the others are still returned, with
+// nil for the failed runs, along with a *ParallelError. Runs that have not
+// started when ctx ends fail with its error.
+func (r *Runner) RunParallel(ctx context.Context, runs []AgentRun) ([]*result.RunResult, error) {
+	r.mu.RLock()
+	limit := r.maxParallelRuns
+	r.mu.RUnlock()
+	if limit <= 0 || limit > len(runs) {
+		limit = len(runs)
+	}
+
+	results := make([]*result.RunResult, len(runs))
+	errs := make([]error, len(runs))
+	slots := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, run := range runs {
+		if run.Agent == nil {
+			errs[i] = errors.New("parallel run has no agent")
+			continue
+		}
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(i int, run AgentRun) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			opts := &RunOptions{}
+			if run.Options != nil {
+				*opts = *run.Options
+			}
+			res, err := r.Run(ctx, run.Agent, opts)
+			if err != nil {
+				errs[i] = fmt.Errorf("run of %s failed: %w", run.Agent.Name, err)
+				return
+			}
+			results[i] = res
+		}(i, run)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return results, &ParallelError{Errors: errs}
+		}
+	}
+	return results, nil
+}
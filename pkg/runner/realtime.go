@@ -0,0 +1,349 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tracing"
+)
+
+// RealtimeRun is a live conversation between a user and an agent over a realtime
+// session. Events carries the model's text, audio, tool calls and a done event per
+// response. Tool calls are executed by the run and their results sent back to the
+// model, which then continues its response.
+type RealtimeRun struct {
+	runner  *Runner
+	agent   AgentType
+	opts    *RunOptions
+	session model.RealtimeSession
+	ctx     context.Context
+	cancel  context.CancelFunc
+	events  chan model.StreamEvent
+	done    chan struct{}
+	budget  *budgetTracker
+
+	// Guards result, which guardrails checked by SendText also update
+	mu     sync.Mutex
+	result *result.RunResult
+}
+
+// RunRealtime starts a realtime conversation with an agent. The model provider of
+// the run must implement model.RealtimeProvider. A string input is sent as the
+// first user message. Input guardrails check text and transcribed speech from the
+// user and output guardrails check each final response; a tripped guardrail ends
+// the conversation. Handoffs are not supported in realtime mode.
+func (r *Runner) RunRealtime(ctx context.Context, agent AgentType, opts *RunOptions) (*RealtimeRun, error) {
+	opts, err := r.prepareOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	provider, ok := opts.RunConfig.ModelProvider.(model.RealtimeProvider)
+	if !ok {
+		return nil, fmt.Errorf("model provider %T does not support realtime sessions", opts.RunConfig.ModelProvider)
+	}
+
+	ctx = r.withTraceContext(ctx, opts)
+	ctx = r.withFlags(ctx, opts)
+	ctx = r.withScratchpad(ctx, opts)
+	ctx, cancel := context.WithCancel(ctx)
+
+	run := &RealtimeRun{
+		runner: r,
+		agent:  agent,
+		opts:   opts,
+		ctx:    ctx,
+		cancel: cancel,
+		events: make(chan model.StreamEvent, 100),
+		done:   make(chan struct{}),
+		budget: newBudgetTracker(opts.RunConfig),
+		result: &result.RunResult{
+			Input:     opts.Input,
+			NewItems:  make([]result.RunItem, 0),
+			LastAgent: agent,
+		},
+	}
+	runID := opts.RunID
+	if runID == "" {
+		runID = generateRunID()
+	}
+	run.budget.attach(runID, agent.Name)
+
+	if err := r.callStartHooks(ctx, agent, opts.Input, opts); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	session, err := provider.ConnectRealtime(ctx, r.realtimeConfig(ctx, agent, opts))
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to start realtime session: %w", err)
+	}
+	run.session = session
+	go run.loop()
+
+	if text, ok := opts.Input.(string); ok && text != "" {
+		if err := run.SendText(ctx, text); err != nil {
+			run.Close()
+			<-run.done
+			return nil, err
+		}
+	}
+	return run, nil
+}
+
+// realtimeConfig builds the session config from the run config and the agent
+func (r *Runner) realtimeConfig(ctx context.Context, agent AgentType, opts *RunOptions) *model.RealtimeConfig {
+	config := &model.RealtimeConfig{}
+	if opts.RunConfig.Realtime != nil {
+		*config = *opts.RunConfig.Realtime
+	}
+	if config.Model == "" {
+		modelToUse := agent.Model
+		if opts.RunConfig.Model != nil {
+			modelToUse = opts.RunConfig.Model
+		}
+		if modelName, ok := modelToUse.(string); ok {
+			config.Model = modelName
+		}
+	}
+	if config.Instructions == "" {
+		config.Instructions = runInstructions(ctx, agent, opts, opts.Input, 1)
+	}
+	if config.Tools == nil {
+		config.Tools = r.prepareTools(ctx, agent)
+	}
+	if config.Settings == nil {
+		config.Settings = r.prepareModelSettings(agent, opts, 0)
+	}
+	return config
+}
+
+// Events returns the events of the conversation. The channel is closed when the
+// conversation ends.
+func (run *RealtimeRun) Events() <-chan model.StreamEvent {
+	return run.events
+}
+
+// SendText checks a user message against the input guardrails and sends it to the
+// model, which then responds
+func (run *RealtimeRun) SendText(ctx context.Context, text string) error {
+	if err := run.checkInput(text); err != nil {
+		return err
+	}
+	run.addItem(&result.MessageItem{Role: "user", Content: text})
+
+	if err := run.session.SendText(ctx, text); err != nil {
+		return fmt.Errorf("failed to send text: %w", err)
+	}
+	if err := run.session.CreateResponse(ctx); err != nil {
+		return fmt.Errorf("failed to request response: %w", err)
+	}
+	return nil
+}
+
+// SendAudio streams user audio to the model. With turn detection the model
+// responds when the user stops speaking; otherwise call CommitAudio.
+func (run *RealtimeRun) SendAudio(ctx context.Context, audio []byte) error {
+	if err := run.session.SendAudio(ctx, audio); err != nil {
+		return fmt.Errorf("failed to send audio: %w", err)
+	}
+	return nil
+}
+
+// CommitAudio ends the user's spoken turn and asks the model to respond
+func (run *RealtimeRun) CommitAudio(ctx context.Context) error {
+	if err := run.session.CommitAudio(ctx); err != nil {
+		return fmt.Errorf("failed to commit audio: %w", err)
+	}
+	if err := run.session.CreateResponse(ctx); err != nil {
+		return fmt.Errorf("failed to request response: %w", err)
+	}
+	return nil
+}
+
+// Close ends the conversation
+func (run *RealtimeRun) Close() error {
+	return run.session.Close()
+}
+
+// Result waits for the conversation to end and returns its result. FinalOutput is
+// the text of the last response.
+func (run *RealtimeRun) Result() *result.RunResult {
+	<-run.done
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	return run.result
+}
+
+// loop forwards session events, executing tool calls and checking guardrails,
+// until the session ends
+func (run *RealtimeRun) loop() {
+	defer close(run.events)
+	defer close(run.done)
+	defer run.cancel()
+
+	r, ctx, agent := run.runner, run.ctx, run.agent
+	turn := 0
+	turnStarted := false
+
+events:
+	for event := range run.session.Events() {
+		if !turnStarted && event.Type != model.StreamEventTypeTranscript && event.Type != model.StreamEventTypeError {
+			turn++
+			turnStarted = true
+			if err := r.callTurnStartHooks(ctx, agent, turn, run.opts); err != nil {
+				run.fail(err)
+				break events
+			}
+		}
+
+		switch event.Type {
+		case model.StreamEventTypeTranscript:
+			run.events <- event
+			run.addItem(&result.MessageItem{Role: "user", Content: event.Content})
+			if err := run.checkInput(event.Content); err != nil {
+				run.fail(err)
+				break events
+			}
+
+		case model.StreamEventTypeDone:
+			turnStarted = false
+			if err := run.finishResponse(event, turn); err != nil {
+				run.fail(err)
+				break events
+			}
+
+		default:
+			run.events <- event
+		}
+	}
+
+	// Drain the session if the loop stopped early
+	for range run.session.Events() {
+	}
+
+	run.mu.Lock()
+	runResult := run.result
+	run.mu.Unlock()
+	run.budget.finish()
+	if err := r.callEndHooks(ctx, agent, runResult, run.opts); err != nil {
+		run.events <- model.StreamEvent{Type: model.StreamEventTypeError, Error: err}
+	}
+}
+
+// finishResponse handles the end of a model response. Tool calls are executed and
+// their results sent back; a final response is checked by the output guardrails.
+func (run *RealtimeRun) finishResponse(event model.StreamEvent, turn int) error {
+	r, ctx, agent := run.runner, run.ctx, run.agent
+	response := event.Response
+	if response == nil {
+		response = &model.Response{}
+	}
+
+	usedModel := modelName(agent, run.opts.RunConfig)
+	tracing.ModelResponse(ctx, agent.Name, usedModel, response, nil)
+
+	cost, _ := run.opts.RunConfig.pricing().Cost(usedModel, response.Usage)
+	run.mu.Lock()
+	run.result.RawResponses = append(run.result.RawResponses, *response)
+	run.result.Usage.Add(agent.Name, usedModel, response.Usage, cost)
+	run.mu.Unlock()
+	if err := run.budget.record(ctx, agent.Name, usedModel, response.Usage); err != nil {
+		return err
+	}
+
+	if len(response.ToolCalls) > 0 {
+		for _, tc := range response.ToolCalls {
+			_, callItem, resultItem, err := r.executeToolCall(ctx, agent, tc)
+			if err != nil {
+				return err
+			}
+			run.addItem(callItem, resultItem)
+
+			output, err := realtimeToolOutput(resultItem.Result)
+			if err != nil {
+				return fmt.Errorf("failed to encode result of tool %s: %w", tc.Name, err)
+			}
+			if err := run.session.SendToolResult(ctx, tc.ID, output); err != nil {
+				return fmt.Errorf("failed to send result of tool %s: %w", tc.Name, err)
+			}
+		}
+		if err := r.callTurnEndHooks(ctx, agent, turn, response, nil, run.opts); err != nil {
+			return err
+		}
+		run.events <- event
+
+		// Let the model continue with the tool results
+		if err := run.session.CreateResponse(ctx); err != nil {
+			return fmt.Errorf("failed to request response: %w", err)
+		}
+		return nil
+	}
+
+	if response.Content != "" {
+		outputResult := &result.RunResult{}
+		err := r.runOutputGuardrails(ctx, agent, response.Content, run.opts, outputResult)
+		run.mu.Lock()
+		run.result.OutputGuardrailResults = append(run.result.OutputGuardrailResults, outputResult.OutputGuardrailResults...)
+		run.mu.Unlock()
+		if err != nil {
+			return err
+		}
+
+		run.addItem(&result.MessageItem{Role: "assistant", Content: response.Content})
+		run.mu.Lock()
+		run.result.FinalOutput = response.Content
+		run.mu.Unlock()
+	}
+
+	if err := r.callTurnEndHooks(ctx, agent, turn, response, response.Content, run.opts); err != nil {
+		return err
+	}
+	run.events <- event
+	return nil
+}
+
+// checkInput runs the input guardrails on user input
+func (run *RealtimeRun) checkInput(input string) error {
+	inputResult := &result.RunResult{}
+	err := run.runner.runInputGuardrails(run.ctx, run.agent, input, run.opts, inputResult)
+
+	run.mu.Lock()
+	run.result.InputGuardrailResults = append(run.result.InputGuardrailResults, inputResult.InputGuardrailResults...)
+	run.mu.Unlock()
+	return err
+}
+
+// addItem appends items to the run result
+func (run *RealtimeRun) addItem(items ...result.RunItem) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	run.result.NewItems = append(run.result.NewItems, items...)
+}
+
+// fail sends the event for an error and ends the session
+func (run *RealtimeRun) fail(err error) {
+	if errors.Is(err, context.Canceled) {
+		run.session.Close()
+		return
+	}
+	run.events <- guardrailErrorEvent(err)
+	run.session.Close()
+}
+
+// realtimeToolOutput converts a tool result to the string sent to the model
+func realtimeToolOutput(toolResult interface{}) (string, error) {
+	if text, ok := toolResult.(string); ok {
+		return text, nil
+	}
+	data, err := json.Marshal(toolResult)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,292 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+)
+
+// RecordingVersion is the version of the recording file format
+const RecordingVersion = 1
+
+// Kinds of recorded calls
+const (
+	RecordedModelCall = "model"
+	RecordedToolCall  = "tool"
+)
+
+// ErrReplayExhausted is returned when a replayed run makes a call that the
+// recording has no more results for, which means the run diverged from it
+var ErrReplayExhausted = errors.New("recording has no result for call")
+
+// RecordedCall is a model response or tool result of a recorded run
+type RecordedCall struct {
+	// Kind is RecordedModelCall or RecordedToolCall
+	Kind string `json:"kind"`
+
+	// Agent is the name of the agent that made the call
+	Agent string `json:"agent"`
+
+	// Tool is the name of the tool of a tool call
+	Tool string `json:"tool,omitempty"`
+
+	// Arguments are the arguments of a tool call
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+
+	// Response is the response of a model call
+	Response *model.Response `json:"response,omitempty"`
+
+	// Result is the result of a tool call
+	Result interface{} `json:"result,omitempty"`
+
+	// Error is the error the call returned
+	Error string `json:"error,omitempty"`
+}
+
+// key returns the queue a call is replayed from. Calls are replayed in order per
+// agent and tool, so tool calls executed concurrently replay correctly.
+func (c *RecordedCall) key() string {
+	return c.Kind + "/" + c.Agent + "/" + c.Tool
+}
+
+// Recording is the file a run is recorded to
+type Recording struct {
+	Version int             `json:"version"`
+	Calls   []*RecordedCall `json:"calls"`
+}
+
+// LoadRecording reads a recording file
+func LoadRecording(path string) (*Recording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+	var recording Recording
+	if err := json.Unmarshal(data, &recording); err != nil {
+		return nil, fmt.Errorf("failed to decode recording %s: %w", path, err)
+	}
+	if recording.Version != RecordingVersion {
+		return nil, fmt.Errorf("unsupported recording version %d in %s", recording.Version, path)
+	}
+	return &recording, nil
+}
+
+// recorder records the model responses and tool results of runs to a file, or
+// replays them from one
+type recorder struct {
+	path   string
+	replay bool
+
+	recording *Recording
+	loadErr   error
+	next      map[string]int
+	mu        sync.Mutex
+}
+
+// WithRecorder records every model response and tool result of the runner's runs
+// to a file, which WithReplay can replay later. The file is rewritten after each
+// call, so it is complete even if the process dies.
+func (r *Runner) WithRecorder(path string) *Runner {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recorder = &recorder{path: path, recording: &Recording{Version: RecordingVersion}}
+	return r
+}
+
+// WithReplay replays the model responses and tool results of a recording instead
+// of calling models and executing tools, so a multi-agent run can be reproduced
+// without network access. A run that makes a call the recording has no result
+// for fails with ErrReplayExhausted.
+func (r *Runner) WithReplay(path string) *Runner {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec := &recorder{path: path, replay: true, next: make(map[string]int)}
+	rec.recording, rec.loadErr = LoadRecording(path)
+	r.recorder = rec
+	return r
+}
+
+// getRecorder returns the recorder of the runner, if any
+func (r *Runner) getRecorder() *recorder {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.recorder
+}
+
+// record appends a call to the recording and saves it
+func (rec *recorder) record(call *RecordedCall) error {
+	// Keep a copy, as the runner goes on to use the response. Results that cannot
+	// be encoded are recorded as text.
+	data, err := json.Marshal(call)
+	if err != nil {
+		call.Result = fmt.Sprintf("%v", call.Result)
+		data, err = json.Marshal(call)
+	}
+	var recorded RecordedCall
+	if err == nil {
+		err = json.Unmarshal(data, &recorded)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record call: %w", err)
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.recording.Calls = append(rec.recording.Calls, &recorded)
+
+	data, err = json.MarshalIndent(rec.recording, "", "  ")
+	if err == nil {
+		tmp := rec.path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0o644); err == nil {
+			err = os.Rename(tmp, rec.path)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save recording: %w", err)
+	}
+	return nil
+}
+
+// recordCall records a call, logging a call that cannot be recorded, as the run
+// goes on without it
+func (r *Runner) recordCall(rec *recorder, agent AgentType, call *RecordedCall) {
+	if err := rec.record(call); err != nil {
+		r.log(agent).Warn("Recording incomplete", "path", rec.path, "error", err)
+	}
+}
+
+// take returns the next recorded call with the same kind, agent and tool
+func (rec *recorder) take(kind, agentName, toolName string) (*RecordedCall, error) {
+	if rec.loadErr != nil {
+		return nil, rec.loadErr
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	key := (&RecordedCall{Kind: kind, Agent: agentName, Tool: toolName}).key()
+	seen := 0
+	for _, call := range rec.recording.Calls {
+		if call.key() != key {
+			continue
+		}
+		if seen == rec.next[key] {
+			rec.next[key]++
+			return call, nil
+		}
+		seen++
+	}
+	if toolName != "" {
+		return nil, fmt.Errorf("%w: tool %s of agent %s", ErrReplayExhausted, toolName, agentName)
+	}
+	return nil, fmt.Errorf("%w: model of agent %s", ErrReplayExhausted, agentName)
+}
+
+// callError returns the recorded error of a call
+func (c *RecordedCall) callError() error {
+	if c.Error == "" {
+		return nil
+	}
+	return errors.New(c.Error)
+}
+
+// getResponse gets a model response, recording or replaying it
+func (r *Runner) getResponse(ctx context.Context, agent AgentType, modelInstance model.Model, request *model.Request) (*model.Response, error) {
+	rec := r.getRecorder()
+	if rec != nil && rec.replay {
+		call, err := rec.take(RecordedModelCall, agent.Name, "")
+		if err != nil {
+			return nil, err
+		}
+		if err := call.callError(); err != nil {
+			return nil, err
+		}
+		return call.Response, nil
+	}
+
+	response, err := awaitCancellable(ctx, func() (*model.Response, error) {
+		return modelInstance.GetResponse(ctx, request)
+	})
+	if rec != nil {
+		call := &RecordedCall{Kind: RecordedModelCall, Agent: agent.Name, Response: response}
+		if err != nil {
+			call.Error = err.Error()
+		}
+		r.recordCall(rec, agent, call)
+	}
+	return response, err
+}
+
+// streamResponse streams a model response, recording or replaying it
+func (r *Runner) streamResponse(ctx context.Context, agent AgentType, modelInstance model.Model, request *model.Request) (<-chan model.StreamEvent, error) {
+	rec := r.getRecorder()
+	if rec != nil && rec.replay {
+		response, err := r.getResponse(ctx, agent, modelInstance, request)
+		if err != nil {
+			return nil, err
+		}
+		replayed := model.ResponseEvents(response)
+		events := make(chan model.StreamEvent, len(replayed))
+		for _, event := range replayed {
+			events <- event
+		}
+		close(events)
+		return events, nil
+	}
+
+	events, err := modelInstance.StreamResponse(ctx, request)
+	if rec == nil {
+		return events, err
+	}
+	if err != nil {
+		r.recordCall(rec, agent, &RecordedCall{Kind: RecordedModelCall, Agent: agent.Name, Error: err.Error()})
+		return nil, err
+	}
+
+	// Record the response once the stream is done, or the error it ends with
+	forwarded := make(chan model.StreamEvent)
+	go func() {
+		defer close(forwarded)
+		recorded := false
+		for event := range events {
+			if !recorded && event.Error != nil {
+				r.recordCall(rec, agent, &RecordedCall{Kind: RecordedModelCall, Agent: agent.Name, Error: event.Error.Error()})
+				recorded = true
+			} else if !recorded && event.Type == model.StreamEventTypeDone {
+				r.recordCall(rec, agent, &RecordedCall{Kind: RecordedModelCall, Agent: agent.Name, Response: event.Response})
+				recorded = true
+			}
+			forwarded <- event
+		}
+	}()
+	return forwarded, nil
+}
+
+// executeTool executes a tool, recording or replaying its result
+func (r *Runner) executeTool(ctx context.Context, agent AgentType, t tool.Tool, params map[string]interface{}) (interface{}, error) {
+	rec := r.getRecorder()
+	if rec != nil && rec.replay {
+		call, err := rec.take(RecordedToolCall, agent.Name, t.GetName())
+		if err != nil {
+			return nil, err
+		}
+		return call.Result, call.callError()
+	}
+
+	toolResult, err := awaitCancellable(ctx, func() (interface{}, error) {
+		return t.Execute(r.withHandoffInput(ctx, agent.Name), params)
+	})
+	if rec != nil {
+		call := &RecordedCall{Kind: RecordedToolCall, Agent: agent.Name, Tool: t.GetName(), Arguments: params, Result: toolResult}
+		if err != nil {
+			call.Error = err.Error()
+		}
+		r.recordCall(rec, agent, call)
+	}
+	return toolResult, err
+}
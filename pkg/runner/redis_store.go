@@ -0,0 +1,110 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/memory/redis"
+)
+
+// RedisStateStore is a WorkflowStateStore backed by Redis. States are stored as
+// JSON with an optional TTL, and every save creates a checkpoint, named after
+// the CheckpointID of a *WorkflowState or else the version. Saves use
+// optimistic locking: once a store has seen a workflow's version through
+// LoadState or SaveState, a later SaveState fails with redis.ErrVersionConflict
+// if another replica has written the workflow in the meantime, and keeps
+// failing until the workflow is reloaded.
+type RedisStateStore struct {
+	store    *redis.Store
+	versions map[string]int64
+	mu       sync.Mutex
+}
+
+// NewRedisStateStore creates a new Redis-backed workflow state store
+func NewRedisStateStore(client redis.Client) *RedisStateStore {
+	return &RedisStateStore{
+		store:    redis.NewStore(client),
+		versions: make(map[string]int64),
+	}
+}
+
+// WithKeyPrefix sets the prefix used for all keys written by the store
+func (s *RedisStateStore) WithKeyPrefix(prefix string) *RedisStateStore {
+	s.store.WithKeyPrefix(prefix)
+	return s
+}
+
+// WithTTL sets how long workflow states and checkpoints are kept
+func (s *RedisStateStore) WithTTL(ttl time.Duration) *RedisStateStore {
+	s.store.WithTTL(ttl)
+	return s
+}
+
+// SaveState saves the current workflow state
+func (s *RedisStateStore) SaveState(workflowID string, state interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expected, seen := s.versions[workflowID]
+	if !seen {
+		expected = redis.AnyVersion
+	}
+
+	var checkpointID string
+	if ws, ok := state.(*WorkflowState); ok && ws != nil {
+		checkpointID = ws.CheckpointID
+	}
+	version, err := s.store.SaveCheckpoint(context.Background(), workflowID, checkpointID, state, expected)
+	if err != nil {
+		// The stale version is kept, so saves keep failing until LoadState
+		// has picked up the other replica's write
+		return err
+	}
+
+	s.versions[workflowID] = version
+	return nil
+}
+
+// LoadState loads the latest workflow state, or nil if none has been saved
+func (s *RedisStateStore) LoadState(workflowID string) (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := &WorkflowState{}
+	version, err := s.store.Load(context.Background(), workflowID, state)
+	if errors.Is(err, redis.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.versions[workflowID] = version
+	return state, nil
+}
+
+// LoadCheckpoint loads the workflow state stored at a specific checkpoint, or
+// nil if the checkpoint does not exist
+func (s *RedisStateStore) LoadCheckpoint(workflowID string, checkpointID string) (*WorkflowState, error) {
+	state := &WorkflowState{}
+	err := s.store.LoadCheckpoint(context.Background(), workflowID, checkpointID, state)
+	if errors.Is(err, redis.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// ListCheckpoints lists available checkpoints for a workflow, oldest first
+func (s *RedisStateStore) ListCheckpoints(workflowID string) ([]string, error) {
+	return s.store.ListCheckpoints(context.Background(), workflowID)
+}
+
+// DeleteCheckpoint deletes a checkpoint
+func (s *RedisStateStore) DeleteCheckpoint(workflowID string, checkpointID string) error {
+	return s.store.DeleteCheckpoint(context.Background(), workflowID, checkpointID)
+}
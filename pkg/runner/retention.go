@@ -0,0 +1,120 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/retention"
+)
+
+// Ensure the event stores implement retention.Store
+var (
+	_ retention.Store = (*MemoryEventStore)(nil)
+	_ retention.Store = (*FileEventStore)(nil)
+)
+
+// redactEvent removes the content of an event: its input, output, arguments,
+// error, reason and user. The type, agents, tool, model, usage and task
+// transitions stay, so projections still compute usage and task boards. It
+// reports whether the event changed.
+func redactEvent(event *RunEvent) bool {
+	if event.Input == nil && event.Output == nil && event.Arguments == nil &&
+		event.Error == "" && event.Reason == "" && event.UserID == "" {
+		return false
+	}
+	event.Input = nil
+	event.Output = nil
+	event.Arguments = nil
+	event.Error = ""
+	event.Reason = ""
+	event.UserID = ""
+	return true
+}
+
+// Purge redacts the events that happened before cutoff
+func (s *MemoryEventStore) Purge(ctx context.Context, cutoff time.Time) (retention.Report, error) {
+	return s.redact(func(event RunEvent) bool { return event.Time.Before(cutoff) }), nil
+}
+
+// Forget redacts the events of a user's runs
+func (s *MemoryEventStore) Forget(ctx context.Context, userID string) (retention.Report, error) {
+	return s.redact(func(event RunEvent) bool { return event.UserID == userID }), nil
+}
+
+// redact redacts the events matching a predicate
+func (s *MemoryEventStore) redact(match func(RunEvent) bool) retention.Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var report retention.Report
+	for _, events := range s.events {
+		for i := range events {
+			if match(events[i]) && redactEvent(&events[i]) {
+				report.Redacted++
+			}
+		}
+	}
+	return report
+}
+
+// Purge redacts the events that happened before cutoff
+func (s *FileEventStore) Purge(ctx context.Context, cutoff time.Time) (retention.Report, error) {
+	return s.redact(ctx, func(event RunEvent) bool { return event.Time.Before(cutoff) })
+}
+
+// Forget redacts the events of a user's runs
+func (s *FileEventStore) Forget(ctx context.Context, userID string) (retention.Report, error) {
+	return s.redact(ctx, func(event RunEvent) bool { return event.UserID == userID })
+}
+
+// redact rewrites the event logs whose events match a predicate with those
+// events redacted
+func (s *FileEventStore) redact(ctx context.Context, match func(RunEvent) bool) (retention.Report, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*.jsonl"))
+	if err != nil {
+		return retention.Report{}, fmt.Errorf("failed to list event logs: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var report retention.Report
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		runID := strings.TrimSuffix(filepath.Base(path), ".jsonl")
+		events, err := s.loadEvents(path, runID)
+		if err != nil {
+			return report, err
+		}
+
+		redacted := 0
+		for i := range events {
+			if match(events[i]) && redactEvent(&events[i]) {
+				redacted++
+			}
+		}
+		if redacted == 0 {
+			continue
+		}
+
+		var buf bytes.Buffer
+		for _, event := range events {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return report, fmt.Errorf("failed to encode event: %w", err)
+			}
+			buf.Write(append(data, '\n'))
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			return report, fmt.Errorf("failed to rewrite event log of %s: %w", runID, err)
+		}
+		report.Redacted += redacted
+	}
+	return report, nil
+}
@@ -0,0 +1,166 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultRunHistory is the number of finished runs whose status the runner keeps
+const DefaultRunHistory = 100
+
+var (
+	// ErrRunNotFound is returned for run IDs the runner knows nothing about
+	ErrRunNotFound = errors.New("run not found")
+
+	// ErrTooManyRuns is returned by runs started while the runner is running as
+	// many runs as WithMaxConcurrentRuns allows
+	ErrTooManyRuns = errors.New("too many concurrent runs")
+)
+
+// RunStatus is the status of a run started by the runner
+type RunStatus struct {
+	ActiveRun
+
+	// Status is RunStatusRunning, RunStatusPaused, RunStatusCompleted,
+	// RunStatusFailed or RunStatusCancelled
+	Status string `json:"status"`
+
+	// Error is the error a failed or cancelled run ended with
+	Error string `json:"error,omitempty"`
+
+	// EndedAt is when a run that is no longer running stopped
+	EndedAt time.Time `json:"ended_at,omitempty"`
+}
+
+// runHistory keeps the status of the most recently finished runs
+type runHistory struct {
+	limit    int
+	statuses map[string]*RunStatus
+	order    []string
+}
+
+// add records the status of a finished run, forgetting the oldest runs beyond the limit
+func (h *runHistory) add(status *RunStatus) {
+	if h.statuses == nil {
+		h.statuses = make(map[string]*RunStatus)
+	}
+	if _, exists := h.statuses[status.ID]; !exists {
+		h.order = append(h.order, status.ID)
+	}
+	h.statuses[status.ID] = status
+
+	limit := h.limit
+	if limit == 0 {
+		limit = DefaultRunHistory
+	}
+	for len(h.order) > limit {
+		delete(h.statuses, h.order[0])
+		h.order = h.order[1:]
+	}
+}
+
+// WithMaxConcurrentRuns limits how many runs the runner runs at once. Runs started
+// beyond the limit fail with ErrTooManyRuns. Runs started by other runs, such as
+// the executors of broadcast handoffs, share the slot of their parent and don't
+// count towards the limit. Zero or less allows any number.
+func (r *Runner) WithMaxConcurrentRuns(n int) *Runner {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxConcurrentRuns = n
+	return r
+}
+
+// WithRunHistory sets how many finished runs GetRunStatus remembers,
+// DefaultRunHistory by default. Runs of the event store are found regardless.
+func (r *Runner) WithRunHistory(n int) *Runner {
+	r.activeMu.Lock()
+	defer r.activeMu.Unlock()
+	r.history.limit = n
+	return r
+}
+
+// GetRunStatus returns the status of a run: a run in progress, a recently
+// finished run, or a run recorded in the event store
+func (r *Runner) GetRunStatus(id string) (*RunStatus, error) {
+	r.activeMu.Lock()
+	run, active := r.activeRuns[id]
+	finished, known := r.history.statuses[id]
+	r.activeMu.Unlock()
+
+	if active {
+		run.mu.Lock()
+		defer run.mu.Unlock()
+		return &RunStatus{ActiveRun: run.info, Status: RunStatusRunning}, nil
+	}
+	if known {
+		status := *finished
+		return &status, nil
+	}
+
+	r.mu.RLock()
+	store := r.eventStore
+	r.mu.RUnlock()
+	if store != nil {
+		events, err := store.LoadEvents(context.Background(), id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load events of run %s: %w", id, err)
+		}
+		if len(events) > 0 {
+			view := ProjectRun(events)
+			return &RunStatus{
+				ActiveRun: ActiveRun{
+					ID:            id,
+					StartingAgent: view.StartingAgent,
+					CurrentAgent:  view.CurrentAgent,
+					Turn:          view.Turn,
+					UserID:        events[0].UserID,
+					StartedAt:     view.StartedAt,
+				},
+				Status:  view.Status,
+				Error:   view.Error,
+				EndedAt: view.EndedAt,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+}
+
+// recordOutcome records how the run of the context ended, for its status once it
+// is no longer active
+func (r *Runner) recordOutcome(ctx context.Context, event RunEvent) {
+	var status string
+	switch event.Type {
+	case EventRunCompleted:
+		status = RunStatusCompleted
+	case EventRunFailed:
+		status = RunStatusFailed
+	case EventRunCancelled:
+		status = RunStatusCancelled
+	case EventRunPaused:
+		status = RunStatusPaused
+	default:
+		return
+	}
+
+	run, err := r.activeRun(RunIDFromContext(ctx))
+	if err != nil {
+		return
+	}
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	run.status = status
+	run.err = event.Error
+}
+
+// finishedStatus returns the status of a run that is no longer active
+func (run *activeRun) finishedStatus() *RunStatus {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	status := &RunStatus{ActiveRun: run.info, Status: run.status, Error: run.err, EndedAt: time.Now()}
+	if status.Status == "" {
+		status.Status = RunStatusFailed
+	}
+	return status
+}
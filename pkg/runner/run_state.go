@@ -0,0 +1,377 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tracing"
+)
+
+// ErrPauseRun is returned by a Checkpoint function to pause the run after saving its state
+var ErrPauseRun = errors.New("run paused")
+
+// CheckpointFunc receives the state of a run between turns. Returning ErrPauseRun
+// stops the run with a RunPausedError; other errors abort the run.
+type CheckpointFunc func(ctx context.Context, state *RunState) error
+
+// RunPausedError is returned when a Checkpoint function pauses a run. Pass State to
+// Runner.ResumeFromState to continue the run.
+type RunPausedError struct {
+	State *RunState
+}
+
+// Error implements the error interface
+func (e *RunPausedError) Error() string {
+	return fmt.Sprintf("run paused before turn %d", e.State.Turn)
+}
+
+// Unwrap returns ErrPauseRun
+func (e *RunPausedError) Unwrap() error {
+	return ErrPauseRun
+}
+
+// RunState is a snapshot of a paused run. It can be encoded as JSON and resumed by
+// another process with ResumeFromState; agents are stored by name and must be
+// resolved with Bind after decoding.
+type RunState struct {
+	// StartingAgent is the agent the run started with
+	StartingAgent AgentType `json:"-"`
+
+	// CurrentAgent is the agent whose turn was paused
+	CurrentAgent AgentType `json:"-"`
+
+	// OriginalInput is the input the run started with
+	OriginalInput interface{} `json:"original_input"`
+
+	// Input is the input of the paused turn
+	Input interface{} `json:"input"`
+
+	// Turn is the paused turn
+	Turn int `json:"turn"`
+
+	// ConsecutiveToolCalls is the number of consecutive turns with tool calls
+	ConsecutiveToolCalls int `json:"consecutive_tool_calls"`
+
+	// Items are the items generated before the pause
+	Items []result.RunItem `json:"-"`
+
+	// RawResponses are the model responses received before the pause
+	RawResponses []model.Response `json:"raw_responses,omitempty"`
+
+	// InputGuardrailResults are the results of the input guardrails
+	InputGuardrailResults []result.GuardrailResult `json:"input_guardrail_results,omitempty"`
+
+	// Response is the model response containing the actions awaiting approval
+	Response *model.Response `json:"response,omitempty"`
+
+	// Pending is the request awaiting a decision
+	Pending *ApprovalRequest `json:"pending,omitempty"`
+
+	// Decisions are the decisions made so far, by request ID
+	Decisions map[string]ApprovalDecision `json:"decisions,omitempty"`
+
+	// Tasks is the runner's task registry at the time of the pause
+	Tasks []*TaskContext `json:"tasks,omitempty"`
+
+	// DelegationChains are the runner's delegation chains at the time of the pause
+	DelegationChains map[string][]string `json:"delegation_chains,omitempty"`
+
+	// TotalTokens is the number of tokens used before the pause
+	TotalTokens int `json:"total_tokens"`
+
+	// CostUSD is the cost of the run before the pause
+	CostUSD float64 `json:"cost_usd"`
+
+	// Usage is the usage of the run before the pause
+	Usage result.Usage `json:"usage"`
+
+	startingAgentName string
+	currentAgentName  string
+	opts              *RunOptions
+	budget            *budgetTracker
+	loops             *loopDetector
+	delegationTurns   map[string]int
+}
+
+// runStateJSON is the JSON encoding of a RunState
+type runStateJSON struct {
+	StartingAgent string        `json:"starting_agent"`
+	CurrentAgent  string        `json:"current_agent"`
+	Items         []runItemJSON `json:"items,omitempty"`
+	*runStateFields
+}
+
+// runStateFields has the fields of RunState without its JSON methods
+type runStateFields RunState
+
+// runItemJSON is the JSON encoding of a run item
+type runItemJSON struct {
+	Type string          `json:"type"`
+	Item json.RawMessage `json:"item"`
+}
+
+// MarshalJSON encodes the state, replacing agents by their names
+func (s *RunState) MarshalJSON() ([]byte, error) {
+	encoded := runStateJSON{
+		StartingAgent:  s.startingAgentName,
+		CurrentAgent:   s.currentAgentName,
+		runStateFields: (*runStateFields)(s),
+	}
+	if s.StartingAgent != nil {
+		encoded.StartingAgent = s.StartingAgent.Name
+	}
+	if s.CurrentAgent != nil {
+		encoded.CurrentAgent = s.CurrentAgent.Name
+	}
+
+	for _, item := range s.Items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s item: %w", item.GetType(), err)
+		}
+		encoded.Items = append(encoded.Items, runItemJSON{Type: item.GetType(), Item: data})
+	}
+
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON decodes a state encoded with MarshalJSON
+func (s *RunState) UnmarshalJSON(data []byte) error {
+	decoded := runStateJSON{runStateFields: (*runStateFields)(s)}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+
+	s.startingAgentName = decoded.StartingAgent
+	s.currentAgentName = decoded.CurrentAgent
+	s.Items = make([]result.RunItem, 0, len(decoded.Items))
+	for _, encoded := range decoded.Items {
+		var item result.RunItem
+		switch encoded.Type {
+		case "message":
+			item = &result.MessageItem{}
+		case "tool_call":
+			item = &result.ToolCallItem{}
+		case "tool_result":
+			item = &result.ToolResultItem{}
+		case "handoff":
+			item = &result.HandoffItem{}
+		case "media":
+			item = &result.MediaItem{}
+		default:
+			return fmt.Errorf("unknown run item type %q", encoded.Type)
+		}
+		if err := json.Unmarshal(encoded.Item, item); err != nil {
+			return fmt.Errorf("failed to decode %s item: %w", encoded.Type, err)
+		}
+		s.Items = append(s.Items, item)
+	}
+
+	return nil
+}
+
+// Bind resolves the agents of a decoded state by name, searching the handoff graph
+// of the starting agent
+func (s *RunState) Bind(agent AgentType) error {
+	if agent == nil {
+		return errors.New("agent is required")
+	}
+
+	startingName, currentName := s.startingAgentName, s.currentAgentName
+	if s.StartingAgent != nil {
+		startingName = s.StartingAgent.Name
+	}
+	if s.CurrentAgent != nil {
+		currentName = s.CurrentAgent.Name
+	}
+	if startingName != "" && startingName != agent.Name {
+		return fmt.Errorf("run state was started by agent %s, not %s", startingName, agent.Name)
+	}
+
+	// Walk the handoff graph breadth first to find the current agent
+	seen := map[AgentType]bool{agent: true}
+	queue := []AgentType{agent}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		if next.Name == currentName || currentName == "" {
+			s.StartingAgent = agent
+			s.CurrentAgent = next
+			return nil
+		}
+		for _, handoff := range next.Handoffs {
+			if handoff != nil && !seen[handoff] {
+				seen[handoff] = true
+				queue = append(queue, handoff)
+			}
+		}
+	}
+
+	return fmt.Errorf("agent %s is not reachable from %s", currentName, agent.Name)
+}
+
+// Decide records the decision for the pending approval request of the state
+func (s *RunState) Decide(decision ApprovalDecision) error {
+	if s == nil || s.Pending == nil || s.Response == nil {
+		return errors.New("run state has no pending approval")
+	}
+
+	if s.Decisions == nil {
+		s.Decisions = make(map[string]ApprovalDecision)
+	}
+	s.Decisions[s.Pending.ID] = decision
+	s.Pending = nil
+	return nil
+}
+
+// ResumeFromState continues a paused run with a new runner
+func ResumeFromState(ctx context.Context, state *RunState, opts *RunOptions) (*result.RunResult, error) {
+	return NewRunner().ResumeFromState(ctx, state, opts)
+}
+
+// ResumeFromState continues a paused run. The state may come from another process;
+// its agents must be bound with Bind and its pending approval, if any, decided with
+// Decide. The input of opts is ignored in favour of the input of the state.
+func (r *Runner) ResumeFromState(ctx context.Context, state *RunState, opts *RunOptions) (*result.RunResult, error) {
+	if state == nil {
+		return nil, errors.New("run state is required")
+	}
+
+	opts, err := r.prepareOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return r.resumeState(ctx, state, opts)
+}
+
+// resumeState continues the run of a state with the given options
+func (r *Runner) resumeState(ctx context.Context, state *RunState, opts *RunOptions) (*result.RunResult, error) {
+	if state.Pending != nil {
+		return nil, fmt.Errorf("run state is waiting for approval of %s", state.Pending.ID)
+	}
+	if state.StartingAgent == nil || state.CurrentAgent == nil {
+		return nil, errors.New("run state has no agents, bind it to its starting agent first")
+	}
+
+	runResult := &result.RunResult{
+		Input:                 state.OriginalInput,
+		NewItems:              state.Items,
+		RawResponses:          state.RawResponses,
+		InputGuardrailResults: state.InputGuardrailResults,
+		LastAgent:             state.CurrentAgent,
+	}
+	if runResult.NewItems == nil {
+		runResult.NewItems = make([]result.RunItem, 0)
+	}
+	runResult.Usage.Merge(state.Usage)
+	if state.budget == nil {
+		state.budget = newBudgetTracker(opts.RunConfig)
+		state.budget.totalTokens = state.TotalTokens
+		state.budget.costUSD = state.CostUSD
+	}
+	if state.loops == nil {
+		state.loops = newLoopDetector(opts.RunConfig)
+	}
+	if state.delegationTurns == nil {
+		state.delegationTurns = make(map[string]int)
+	}
+	state.opts = opts
+
+	// Join the caller's distributed trace, or start a new one
+	ctx = r.withTraceContext(ctx, opts)
+	ctx = r.withFlags(ctx, opts)
+	ctx = r.withScratchpad(ctx, opts)
+
+	// Set up tracing if not disabled
+	ctx, tracingCleanup, _ := r.setupTracing(ctx, state.StartingAgent, state.OriginalInput, opts)
+	defer func() {
+		tracing.AgentEnd(ctx, state.StartingAgent.Name, runResult.FinalOutput)
+		tracingCleanup()
+	}()
+
+	return r.runTurns(ctx, state, runResult, opts)
+}
+
+// snapshotState copies the progress of the run of the context into the state
+func (r *Runner) snapshotState(ctx context.Context, state *RunState, runResult *result.RunResult) {
+	state.Items = runResult.NewItems
+	state.RawResponses = runResult.RawResponses
+	state.InputGuardrailResults = runResult.InputGuardrailResults
+	state.Usage = result.Usage{}
+	state.Usage.Merge(runResult.Usage)
+	if state.budget != nil {
+		state.TotalTokens = state.budget.totalTokens
+		state.CostUSD = state.budget.costUSD
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	runID := taskRunID(ctx)
+	state.Tasks = make([]*TaskContext, 0)
+	for _, task := range r.taskRegistry {
+		if task.RunID != runID {
+			continue
+		}
+		snapshot := *task
+		state.Tasks = append(state.Tasks, &snapshot)
+	}
+	sort.Slice(state.Tasks, func(i, j int) bool {
+		return state.Tasks[i].TaskID < state.Tasks[j].TaskID
+	})
+
+	// Chains are stored by agent name, so that the run can be resumed as another run
+	state.DelegationChains = make(map[string][]string)
+	prefix := delegationKey(runID, "")
+	for key, chain := range r.delegationChains {
+		agentName, ok := strings.CutPrefix(key, prefix)
+		if !ok || (runID == "" && strings.Contains(key, "/")) {
+			continue
+		}
+		state.DelegationChains[agentName] = append([]string(nil), chain...)
+	}
+}
+
+// restoreStateTasks adds the tasks and delegation chains of the state to the run
+// of the context, which takes over the tasks the runner already knows
+func (r *Runner) restoreStateTasks(ctx context.Context, state *RunState) {
+	r.mu.Lock()
+	runID := taskRunID(ctx)
+	var taskIDs, chainKeys []string
+	for _, task := range state.Tasks {
+		taskIDs = append(taskIDs, task.TaskID)
+		if existing, exists := r.taskRegistry[task.TaskID]; exists {
+			existing.RunID = runID
+			continue
+		}
+		task.RunID = runID
+		if task.WorkingContext == nil {
+			task.WorkingContext = &WorkingContext{}
+		}
+		if task.WorkingContext.Metadata == nil {
+			task.WorkingContext.Metadata = make(map[string]interface{})
+		}
+		r.taskRegistry[task.TaskID] = task
+	}
+	for agentName, chain := range state.DelegationChains {
+		key := delegationKey(runID, agentName)
+		if _, exists := r.delegationChains[key]; !exists {
+			r.delegationChains[key] = chain
+			chainKeys = append(chainKeys, key)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, taskID := range taskIDs {
+		r.persistTask(ctx, taskID)
+	}
+	for _, key := range chainKeys {
+		r.persistDelegationChain(ctx, key)
+	}
+}
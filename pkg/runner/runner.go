@@ -3,19 +3,23 @@ package runner
 import (
 	"context"
 	"crypto/rand"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/flags"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/guardrail"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/scratchpad"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/tracing"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/workspace"
 )
 
 const (
@@ -31,7 +35,39 @@ type Runner struct {
 
 	// Task management
 	taskRegistry     map[string]*TaskContext // Maps taskID to TaskContext
-	delegationChains map[string][]string     // Maps agent name to stack of delegators
+	delegationChains map[string][]string     // Maps run and agent name to stack of delegators
+	taskStore        TaskStore               // Optional persistence for tasks and delegation chains
+	storeLocks       [16]sync.Mutex          // Serialize the writes of each task and chain to taskStore
+
+	// Hooks applied to every run
+	hooks []RunHooks
+
+	// Runs in progress, by run ID
+	activeRuns map[string]*activeRun
+	activeMu   sync.Mutex
+	shutdown   bool
+
+	// Statuses of finished runs, guarded by activeMu
+	history runHistory
+
+	// Limits how many runs are active at once
+	maxConcurrentRuns int
+
+	// Records or replays model responses and tool results
+	recorder *recorder
+
+	// Stores the event logs of runs
+	eventStore EventStore
+
+	// Code artifacts last handed to each agent, for review diffs
+	reviewed map[string]string
+
+	// Limits how many runs of RunParallel run at once
+	maxParallelRuns int
+
+	// Logger of the runner, guarded by its own lock as it is used while mu is held
+	logger logging.Logger
+	logMu  sync.RWMutex
 
 	// Internal state
 	mu sync.RWMutex
@@ -62,8 +98,62 @@ func (r *Runner) WithDefaultProvider(provider model.Provider) *Runner {
 	return r
 }
 
+// WithHooks registers hooks that apply to every run, in addition to the hooks in RunOptions
+func (r *Runner) WithHooks(hooks ...RunHooks) *Runner {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = append(r.hooks, hooks...)
+	return r
+}
+
+// WithLogger sets the logger of the runner. Agents with a logger of their own log
+// with theirs.
+func (r *Runner) WithLogger(logger logging.Logger) *Runner {
+	r.logMu.Lock()
+	defer r.logMu.Unlock()
+	r.logger = logger
+	return r
+}
+
+// log returns the logger for messages about an agent: the agent's logger, the
+// runner's logger or the default logger. The agent may be nil.
+func (r *Runner) log(agent AgentType) logging.Logger {
+	if agent != nil && agent.Logger != nil {
+		return logging.For(agent.Logger, "runner")
+	}
+	r.logMu.RLock()
+	defer r.logMu.RUnlock()
+	return logging.For(r.logger, "runner")
+}
+
+// runHooks returns the runner's hooks merged with the hooks of the run
+func (r *Runner) runHooks(opts *RunOptions) RunHooks {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.hooks) == 0 {
+		return opts.Hooks
+	}
+	merged := NewMultiRunHooks(r.hooks...)
+	if opts.Hooks != nil {
+		merged = append(merged, opts.Hooks)
+	}
+	return merged
+}
+
 // Run executes an agent with the given input and options
 func (r *Runner) Run(ctx context.Context, agent AgentType, opts *RunOptions) (*result.RunResult, error) {
+	opts, err := r.prepareOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Run the agent loop
+	return r.runAgentLoop(ctx, agent, opts.Input, opts)
+}
+
+// prepareOptions applies the runner's defaults to the run options
+func (r *Runner) prepareOptions(opts *RunOptions) (*RunOptions, error) {
 	// Apply default options if not provided
 	if opts == nil {
 		opts = &RunOptions{}
@@ -93,8 +183,7 @@ func (r *Runner) Run(ctx context.Context, agent AgentType, opts *RunOptions) (*r
 		return nil, errors.New("no model provider available")
 	}
 
-	// Run the agent loop
-	return r.runAgentLoop(ctx, agent, opts.Input, opts)
+	return opts, nil
 }
 
 // RunSync is a synchronous version of Run
@@ -112,15 +201,23 @@ func (r *Runner) RunStreaming(ctx context.Context, agent AgentType, opts *RunOpt
 		return nil, err
 	}
 
+	// The run ID is assigned before returning, so callers can read it while the
+	// run streams
+	runID := opts.RunID
+	if runID == "" {
+		runID = generateRunID()
+	}
+
 	// Create a streamed run result
 	streamedResult := &result.StreamedRunResult{
 		RunResult: &result.RunResult{
+			RunID:       runID,
 			Input:       opts.Input,
 			NewItems:    make([]result.RunItem, 0),
 			LastAgent:   agent,
 			FinalOutput: nil,
 		},
-		Stream:            eventCh,
+		Stream:            sequenceEvents(eventCh),
 		IsComplete:        false,
 		CurrentAgent:      agent,
 		ActiveTasks:       make(map[string]*result.TaskContext),
@@ -131,28 +228,107 @@ func (r *Runner) RunStreaming(ctx context.Context, agent AgentType, opts *RunOpt
 	go func() {
 		defer close(eventCh)
 
-		// Call run start hooks
-		if err := r.callRunStartHooks(ctx, agent, opts.Input, opts, eventCh); err != nil {
+		// Join the caller's distributed trace, or start a new one
+		ctx := r.withTraceContext(ctx, opts)
+		ctx = r.withFlags(ctx, opts)
+		ctx = r.withScratchpad(ctx, opts)
+
+		// Register the run as active
+		ctx, run, err := r.trackRun(ctx, runID, agent, opts.Input, opts, true)
+		if err != nil {
+			eventCh <- model.StreamEvent{
+				Type:  model.StreamEventTypeError,
+				Error: err,
+			}
 			return
 		}
+		paused := false
+		var ws *workspace.Workspace
+		defer func() {
+			// A run completed by an operator ends with the operator's output
+			if output, ok := run.completedOutput(); ok {
+				streamedResult.RunResult.FinalOutput = output
+				streamedResult.IsComplete = true
+				eventCh <- model.StreamEvent{
+					Type:    model.StreamEventTypeDone,
+					Content: fmt.Sprint(output),
+					Done:    true,
+				}
+				eventCh <- model.StreamEvent{Type: model.StreamEventTypeRunCompleted, Output: output}
+			}
+			// A cancelled run ends with what it streamed before it stopped
+			if _, completed := run.completedOutput(); !completed && isCancellation(context.Cause(ctx)) && !streamedResult.IsComplete {
+				streamedResult.RunResult.Cancelled = true
+				eventCh <- model.StreamEvent{Type: model.StreamEventTypeRunCancelled, Agent: streamedResult.CurrentAgent.Name, Error: context.Cause(ctx)}
+			}
+			// Paused runs keep their workspace until they are resumed
+			if !paused {
+				r.releaseWorkspace(ctx, ws, opts, streamedResult.RunResult)
+			}
+			r.recordStreamEnd(ctx, agent, streamedResult, paused)
+			r.untrackRun(run)
+		}()
+
+		// Handoffs and tool calls deeper in the run are streamed as they happen
+		eventCh <- model.StreamEvent{Type: model.StreamEventTypeRunStarted, RunID: run.info.ID, Agent: agent.Name}
+		ctx = withStreamListener(ctx, func(event model.StreamEvent) {
+			eventCh <- event
+		})
 
-		// Resolve the model
-		modelInstance, err := r.resolveModel(agent, opts.RunConfig)
+		// Provision the run's workspace
+		ctx, ws, err = r.acquireWorkspace(ctx, run.info.ID, opts)
 		if err != nil {
 			eventCh <- model.StreamEvent{
 				Type:  model.StreamEventTypeError,
-				Error: fmt.Errorf("failed to resolve model: %w", err),
+				Error: err,
 			}
 			return
 		}
 
+		// Call run start hooks
+		if err := r.callRunStartHooks(ctx, agent, opts.Input, opts, eventCh); err != nil {
+			return
+		}
+
+		// Check the input against the input guardrails before the first model call
+		if err := r.runInputGuardrails(ctx, agent, opts.Input, opts, streamedResult.RunResult); err != nil {
+			eventCh <- guardrailErrorEvent(err)
+			return
+		}
+
 		// Variables to track consecutive tool calls
 		consecutiveToolCalls := 0
 
+		// Track usage against the budget of the run
+		budget := newBudgetTracker(opts.RunConfig)
+		budget.attach(run.info.ID, agent.Name)
+		budget.track(&streamedResult.RunResult.Usage)
+		loops := newLoopDetector(opts.RunConfig)
+		delegationTurns := make(map[string]int)
+		defer func() {
+			if !paused {
+				budget.finish()
+			}
+		}()
+
 		// Run the agent loop
 		currentAgent := agent
 		currentInput := opts.Input
 		for turn := 1; turn <= opts.MaxTurns; turn++ {
+			// Stop if the run was cancelled or completed between turns
+			if ctx.Err() != nil {
+				if _, ok := run.completedOutput(); !ok {
+					eventCh <- model.StreamEvent{
+						Type:  model.StreamEventTypeError,
+						Error: context.Cause(ctx),
+					}
+				}
+				return
+			}
+			run.update(currentAgent, turn, streamedResult.RunResult.NewItems)
+			r.recordEvent(ctx, RunEvent{Type: EventTurnStarted, Agent: currentAgent.Name, Turn: turn})
+			eventCh <- model.StreamEvent{Type: model.StreamEventTypeTurnStarted, Agent: currentAgent.Name, Turn: turn}
+
 			// Update the current turn and agent
 			streamedResult.CurrentTurn = turn
 			streamedResult.CurrentAgent = currentAgent
@@ -160,8 +336,8 @@ func (r *Runner) RunStreaming(ctx context.Context, agent AgentType, opts *RunOpt
 			streamedResult.ContinueLoop = false
 
 			// Call turn start hooks
-			if opts.Hooks != nil {
-				if err := opts.Hooks.OnTurnStart(ctx, currentAgent, turn); err != nil {
+			if hooks := r.runHooks(opts); hooks != nil {
+				if err := hooks.OnTurnStart(ctx, currentAgent, turn); err != nil {
 					eventCh <- model.StreamEvent{
 						Type:  model.StreamEventTypeError,
 						Error: fmt.Errorf("turn start hook error: %w", err),
@@ -170,16 +346,90 @@ func (r *Runner) RunStreaming(ctx context.Context, agent AgentType, opts *RunOpt
 				}
 			}
 
+			// Hand the conversation to the fallback phase of a phase over its budget
+			fallback, err := r.slaFallback(opts, currentAgent, agent)
+			if err != nil {
+				eventCh <- model.StreamEvent{
+					Type:  model.StreamEventTypeError,
+					Error: err,
+				}
+				return
+			}
+			if fallback != nil {
+				eventCh <- agentSwitchedEvent(currentAgent, fallback, turn)
+				currentAgent = fallback
+				streamedResult.CurrentAgent = fallback
+				consecutiveToolCalls = 0
+			}
+
+			// Return the task of a delegate that used up its turns to its delegator
+			next, nextInput, err := r.enforceDelegationTurns(ctx, delegationTurns, currentAgent, currentInput, streamedResult.RunResult, opts)
+			if err != nil {
+				eventCh <- model.StreamEvent{
+					Type:  model.StreamEventTypeError,
+					Error: err,
+				}
+				return
+			}
+			if next != currentAgent {
+				eventCh <- agentSwitchedEvent(currentAgent, next, turn)
+				currentAgent = next
+				currentInput = nextInput
+				streamedResult.CurrentAgent = next
+				streamedResult.CurrentInput = nextInput
+				consecutiveToolCalls = 0
+			}
+
+			// Register tools from the agent's MCP servers
+			if err := currentAgent.LoadMCPTools(ctx); err != nil {
+				eventCh <- model.StreamEvent{
+					Type:  model.StreamEventTypeError,
+					Error: err,
+				}
+				return
+			}
+
+			// Resolve the model of the current agent, which changes with handoffs
+			modelInstance, err := r.resolveModel(ctx, currentAgent, opts.RunConfig)
+			if err != nil {
+				eventCh <- model.StreamEvent{
+					Type:  model.StreamEventTypeError,
+					Error: fmt.Errorf("failed to resolve model: %w", err),
+				}
+				return
+			}
+
+			// Stop a run whose workspace outgrew its quota
+			if err := checkWorkspaceQuota(ctx); err != nil {
+				eventCh <- model.StreamEvent{
+					Type:  model.StreamEventTypeError,
+					Error: err,
+				}
+				return
+			}
+
+			// Compact the history when it approaches the context window
+			instructions := runInstructions(ctx, currentAgent, opts, streamedResult.RunResult.Input, turn)
+			currentInput, err = r.compactHistory(ctx, currentAgent, instructions, currentInput, opts, budget)
+			if err != nil {
+				eventCh <- model.StreamEvent{
+					Type:  model.StreamEventTypeError,
+					Error: err,
+				}
+				return
+			}
+			streamedResult.CurrentInput = currentInput
+
 			// Prepare model settings
-			modelSettings := r.prepareModelSettings(currentAgent, opts.RunConfig, consecutiveToolCalls)
+			modelSettings := r.prepareModelSettings(currentAgent, opts, consecutiveToolCalls)
 
 			// Prepare model request
 			request := &ModelRequestType{
-				SystemInstructions: currentAgent.Instructions,
+				SystemInstructions: instructions,
 				Input:              currentInput,
-				Tools:              r.prepareTools(currentAgent.Tools),
+				Tools:              r.prepareTools(ctx, currentAgent),
 				OutputSchema:       r.prepareOutputSchema(currentAgent.OutputType),
-				Handoffs:           r.prepareHandoffs(currentAgent.Handoffs),
+				Handoffs:           r.prepareAgentHandoffs(currentAgent),
 				Settings:           modelSettings,
 			}
 
@@ -195,14 +445,30 @@ func (r *Runner) RunStreaming(ctx context.Context, agent AgentType, opts *RunOpt
 			}
 
 			// Record model request event
-			tracing.ModelRequest(ctx, currentAgent.Name, fmt.Sprintf("%v", agent.Model), request.Input, request.Tools)
+			tracing.ModelRequest(ctx, currentAgent.Name, traceModelName(currentAgent, opts.RunConfig, streamedResult.RunResult, turn), request.Input, request.Tools)
+
+			// Use the faster model of a phase over its budget
+			turnModel, err := r.escalateModel(opts, modelInstance)
+			if err != nil {
+				eventCh <- model.StreamEvent{
+					Type:  model.StreamEventTypeError,
+					Error: fmt.Errorf("failed to resolve model: %w", err),
+				}
+				return
+			}
 
-			// Stream the model response
-			modelStream, err := modelInstance.StreamResponse(ctx, request)
+			// Stream the model response, retrying on the fallback models
+			var modelStream <-chan model.StreamEvent
+			err = r.withFallbacks(ctx, currentAgent, turnModel, opts, streamedResult.RunResult, turn, func(m model.Model) error {
+				applyOutputFormat(request, currentAgent, m)
+				var streamErr error
+				modelStream, streamErr = r.streamResponse(ctx, currentAgent, m, request)
+				return streamErr
+			})
 			if err != nil {
 				eventCh <- model.StreamEvent{
 					Type:  model.StreamEventTypeError,
-					Error: fmt.Errorf("model call error: %w", err),
+					Error: cancellationError(ctx, fmt.Errorf("model call error: %w", err)),
 				}
 				return
 			}
@@ -217,8 +483,19 @@ func (r *Runner) RunStreaming(ctx context.Context, agent AgentType, opts *RunOpt
 				turn,
 				eventCh,
 				&consecutiveToolCalls,
+				budget,
+				loops,
 			)
 
+			// Hand the paused run to the caller, who resumes it with a decision
+			var approvalErr *ApprovalRequiredError
+			if errors.As(err, &approvalErr) {
+				paused = true
+				approvalErr.State.StartingAgent = agent
+				eventCh <- approvalEvent(approvalErr)
+				return
+			}
+
 			if err == nil && streamedResult.ContinueLoop {
 				currentInput = streamedResult.CurrentInput
 				continue
@@ -227,16 +504,39 @@ func (r *Runner) RunStreaming(ctx context.Context, agent AgentType, opts *RunOpt
 			// If the error is nil, we may need to update currentAgent and currentInput
 			// Typically this happens after a handoff
 			if err == nil && streamedResult.CurrentAgent != currentAgent {
+				previousAgent := currentAgent
 				currentAgent = streamedResult.CurrentAgent
+				eventCh <- agentSwitchedEvent(previousAgent, currentAgent, turn)
 				// If there was a handoff, find the corresponding item to get its input
 				if handoffItem := findHandoffItem(streamedResult.RunResult.NewItems); handoffItem != nil {
 					currentInput = handoffItem.Input
 				}
+
+				// Tell agents handing the conversation back and forth that they are looping
+				nudge, err := r.handleLoop(ctx, loops, previousAgent, loops.handoff(previousAgent.Name, currentAgent.Name, turn))
+				if err != nil {
+					eventCh <- model.StreamEvent{
+						Type:  model.StreamEventTypeError,
+						Error: err,
+					}
+					return
+				}
+				if nudge != "" {
+					currentInput = appendUserMessage(r.messageFormatter(ctx, currentAgent, opts), currentInput, nudge)
+				}
 				continue
 			}
 
 			// If there was an error or we're done, exit the loop
 			if err != nil || streamedResult.IsComplete {
+				if err == nil {
+					r.promoteTerminalOutput(ctx, streamedResult.RunResult, opts, run.info.StartedAt)
+					eventCh <- model.StreamEvent{
+						Type:   model.StreamEventTypeRunCompleted,
+						Agent:  streamedResult.RunResult.LastAgent.Name,
+						Output: streamedResult.RunResult.FinalOutput,
+					}
+				}
 				return
 			}
 		}
@@ -244,7 +544,7 @@ func (r *Runner) RunStreaming(ctx context.Context, agent AgentType, opts *RunOpt
 		// If we get here, we've exceeded the maximum number of turns
 		eventCh <- model.StreamEvent{
 			Type:  model.StreamEventTypeError,
-			Error: fmt.Errorf("exceeded maximum number of turns (%d)", opts.MaxTurns),
+			Error: fmt.Errorf("%w (%d)", ErrMaxTurnsExceeded, opts.MaxTurns),
 		}
 	}()
 
@@ -261,6 +561,45 @@ func findHandoffItem(items []result.RunItem) *result.HandoffItem {
 	return nil
 }
 
+// withTraceContext returns a context carrying the W3C trace context of the run. Runs join
+// the trace carried by ctx, otherwise a new trace is started unless tracing is disabled.
+func (r *Runner) withTraceContext(ctx context.Context, opts *RunOptions) context.Context {
+	if sc, ok := tracing.SpanContextFromContext(ctx); ok {
+		return tracing.ContextWithSpanContext(ctx, sc.Child())
+	}
+	if opts.RunConfig != nil && opts.RunConfig.TracingDisabled {
+		return ctx
+	}
+
+	// Use the configured trace ID when it is a valid W3C trace ID
+	if opts.RunConfig != nil && opts.RunConfig.TracingConfig != nil && opts.RunConfig.TracingConfig.TraceID != "" {
+		if sc, err := tracing.NewSpanContextWithTraceID(opts.RunConfig.TracingConfig.TraceID); err == nil {
+			return tracing.ContextWithSpanContext(ctx, sc)
+		}
+	}
+	return tracing.ContextWithSpanContext(ctx, tracing.NewSpanContext())
+}
+
+// withFlags returns a context carrying the flag provider of the run
+func (r *Runner) withFlags(ctx context.Context, opts *RunOptions) context.Context {
+	if opts.RunConfig == nil || opts.RunConfig.Flags == nil {
+		return ctx
+	}
+	return flags.WithProvider(ctx, opts.RunConfig.Flags)
+}
+
+// withScratchpad returns a context carrying the scratchpad of the run: the one
+// of the run configuration, the one of a run the run is part of, or a new one
+func (r *Runner) withScratchpad(ctx context.Context, opts *RunOptions) context.Context {
+	if opts.RunConfig != nil && opts.RunConfig.Scratchpad != nil {
+		return scratchpad.WithScratchpad(ctx, opts.RunConfig.Scratchpad)
+	}
+	if scratchpad.FromContext(ctx) != nil {
+		return ctx
+	}
+	return scratchpad.WithScratchpad(ctx, scratchpad.New())
+}
+
 // setupTracing sets up tracing for an agent if not disabled in the options
 func (r *Runner) setupTracing(ctx context.Context, agent AgentType, input interface{}, opts *RunOptions) (context.Context, func(), error) {
 	// Skip if tracing is disabled
@@ -273,7 +612,7 @@ func (r *Runner) setupTracing(ctx context.Context, agent AgentType, input interf
 	tracer, err := tracing.TraceForAgent(agent.Name)
 	if err != nil {
 		// Log error but continue without tracing
-		fmt.Fprintf(os.Stderr, "Failed to create tracer: %v\n", err)
+		r.log(agent).Warn("Failed to create tracer, continuing without tracing", "agent", agent.Name, "error", err)
 		return ctx, func() {}, nil
 	}
 
@@ -289,10 +628,10 @@ func (r *Runner) setupTracing(ctx context.Context, agent AgentType, input interf
 		tracing.AgentEnd(tracingCtx, agent.Name, nil) // FinalOutput will be added later
 
 		if err := tracer.Flush(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error flushing tracer: %v\n", err)
+			r.log(agent).Error("Failed to flush tracer", "agent", agent.Name, "error", err)
 		}
 		if err := tracer.Close(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error closing tracer: %v\n", err)
+			r.log(agent).Error("Failed to close tracer", "agent", agent.Name, "error", err)
 		}
 	}
 
@@ -300,9 +639,15 @@ func (r *Runner) setupTracing(ctx context.Context, agent AgentType, input interf
 }
 
 // prepareModelSettings creates model settings for a request based on agent and run configuration
-func (r *Runner) prepareModelSettings(agent AgentType, runConfig *RunConfig, consecutiveToolCalls int) *ModelSettingsType {
+func (r *Runner) prepareModelSettings(agent AgentType, opts *RunOptions, consecutiveToolCalls int) *ModelSettingsType {
 	// Clone the model settings to avoid modifying the original
 	var modelSettings *ModelSettingsType
+	var runConfig *RunConfig
+	var phaseHooks *workflowHooks
+	if opts != nil {
+		runConfig = opts.RunConfig
+		phaseHooks, _ = opts.Hooks.(*workflowHooks)
+	}
 
 	// Try to use agent settings first
 	if agent.ModelSettings != nil {
@@ -318,6 +663,11 @@ func (r *Runner) prepareModelSettings(agent AgentType, runConfig *RunConfig, con
 		modelSettings = &ModelSettingsType{}
 	}
 
+	// Apply the overrides of the current workflow phase
+	if phaseHooks != nil {
+		phaseHooks.applyPhaseSettings(agent.Name, modelSettings)
+	}
+
 	// Adjust tool_choice if we've had many consecutive calls to the same tool
 	if consecutiveToolCalls >= 3 {
 		// Suggest the model to provide a text response after multiple tool calls
@@ -326,6 +676,13 @@ func (r *Runner) prepareModelSettings(agent AgentType, runConfig *RunConfig, con
 		modelSettings.ToolChoice = &autoChoice
 	}
 
+	if agent.PromptCaching {
+		modelSettings.PromptCaching = true
+		if modelSettings.PromptCacheKey == "" {
+			modelSettings.PromptCacheKey = agent.Name
+		}
+	}
+
 	return modelSettings
 }
 
@@ -340,6 +697,11 @@ func (r *Runner) runAgentLoop(ctx context.Context, agent AgentType, input interf
 		RawResponses: make([]model.Response, 0), // Initialize the raw responses slice
 	}
 
+	// Join the caller's distributed trace, or start a new one
+	ctx = r.withTraceContext(ctx, opts)
+	ctx = r.withFlags(ctx, opts)
+	ctx = r.withScratchpad(ctx, opts)
+
 	// Set up tracing if not disabled
 	var tracingCleanup func()
 	ctx, tracingCleanup, _ = r.setupTracing(ctx, agent, input, opts)
@@ -356,69 +718,279 @@ func (r *Runner) runAgentLoop(ctx context.Context, agent AgentType, input interf
 		return nil, err
 	}
 
-	// Variables to track consecutive tool calls
-	consecutiveToolCalls := 0
+	// Check the input against the input guardrails before the first model call
+	if err := r.runInputGuardrails(ctx, agent, input, opts, runResult); err != nil {
+		return nil, err
+	}
+
+	state := &RunState{
+		StartingAgent: agent,
+		CurrentAgent:  agent,
+		OriginalInput: input,
+		Input:         input,
+		Turn:          1,
+		opts:          opts,
+		budget:        newBudgetTracker(opts.RunConfig),
+		loops:         newLoopDetector(opts.RunConfig),
 
-	// Run the agent loop
-	currentAgent := agent
-	currentInput := input
-	for turn := 1; turn <= opts.MaxTurns; turn++ {
-		// Call turn start hooks
-		if err := r.callTurnStartHooks(ctx, currentAgent, turn, opts); err != nil {
+		delegationTurns: make(map[string]int),
+	}
+	return r.runTurns(ctx, state, runResult, opts)
+}
+
+// runTurns runs the agent loop as an active run, which operators can list, inspect,
+// cancel and complete
+func (r *Runner) runTurns(ctx context.Context, state *RunState, runResult *result.RunResult, opts *RunOptions) (*result.RunResult, error) {
+	ctx, run, err := r.trackRun(ctx, opts.RunID, state.StartingAgent, state.OriginalInput, opts, false)
+	if err != nil {
+		return nil, err
+	}
+	defer r.untrackRun(run)
+	runResult.RunID = run.info.ID
+
+	// A resumed run takes over the tasks of the run it continues
+	if len(state.Tasks) > 0 || len(state.DelegationChains) > 0 {
+		r.restoreStateTasks(ctx, state)
+	}
+	state.budget.attach(run.info.ID, state.StartingAgent.Name)
+	state.budget.track(&runResult.Usage)
+
+	ctx, ws, err := r.acquireWorkspace(ctx, run.info.ID, opts)
+	if err != nil {
+		r.recordRunEnd(ctx, state.CurrentAgent.Name, nil, err)
+		return nil, err
+	}
+
+	res, err := r.runTurnLoop(ctx, state, runResult, opts, run)
+
+	// Add the run to the cost baselines and remove its workspace unless it is
+	// paused and will be resumed
+	var approvalErr *ApprovalRequiredError
+	var pausedErr *RunPausedError
+	if !errors.As(err, &approvalErr) && !errors.As(err, &pausedErr) {
+		state.budget.finish()
+		r.releaseWorkspace(ctx, ws, opts, runResult)
+	}
+
+	// A run completed by an operator succeeds with the operator's output
+	if output, ok := run.completedOutput(); ok {
+		runResult.FinalOutput = output
+		runResult.LastAgent = state.CurrentAgent
+		r.recordRunEnd(ctx, state.CurrentAgent.Name, output, nil)
+		if err := r.callEndHooks(context.WithoutCancel(ctx), state.StartingAgent, runResult, opts); err != nil {
 			return nil, err
 		}
+		return runResult, nil
+	}
+	if err != nil {
+		err = cancellationError(ctx, err)
+		r.recordRunEnd(ctx, state.CurrentAgent.Name, nil, err)
+
+		// A cancelled run returns what it generated before it stopped
+		if isCancellation(err) {
+			runResult.Cancelled = true
+			runResult.LastAgent = state.CurrentAgent
+			return runResult, err
+		}
+		return nil, err
+	}
+	r.recordRunEnd(ctx, state.CurrentAgent.Name, res.FinalOutput, nil)
+	return res, nil
+}
 
-		// Prepare and execute model request
-		response, err := r.executeModelRequest(ctx, currentAgent, currentInput, consecutiveToolCalls, opts, turn)
-		if err != nil {
-			return nil, err
+// runTurnLoop runs the agent loop from the turn in the state until the run completes
+// or pauses for approval
+func (r *Runner) runTurnLoop(ctx context.Context, state *RunState, runResult *result.RunResult, opts *RunOptions, run *activeRun) (*result.RunResult, error) {
+	for firstTurn := state.Turn; state.Turn <= opts.MaxTurns; state.Turn++ {
+		turn := state.Turn
+		currentAgent := state.CurrentAgent
+
+		// Stop if the run was cancelled or completed between turns
+		if ctx.Err() != nil {
+			return nil, context.Cause(ctx)
+		}
+		run.update(currentAgent, turn, runResult.NewItems)
+		r.recordEvent(ctx, RunEvent{Type: EventTurnStarted, Agent: currentAgent.Name, Turn: turn})
+
+		// Offer the state between turns to the checkpoint function
+		if turn > firstTurn && opts.RunConfig.Checkpoint != nil {
+			r.snapshotState(ctx, state, runResult)
+			if err := opts.RunConfig.Checkpoint(ctx, state); err != nil {
+				if errors.Is(err, ErrPauseRun) {
+					return nil, &RunPausedError{State: state}
+				}
+				return nil, fmt.Errorf("checkpoint failed: %w", err)
+			}
 		}
 
-		// Store the raw response in the result
-		runResult.RawResponses = append(runResult.RawResponses, *response)
+		// A resumed run continues with the response that was waiting for approval
+		response := state.Response
+		state.Response = nil
+		if response == nil {
+			// Call turn start hooks
+			if err := r.callTurnStartHooks(ctx, currentAgent, turn, opts); err != nil {
+				return nil, err
+			}
 
-		// Process the response
-		// Check if we have a final output (structured output)
-		if currentAgent.OutputType != nil {
-			// TODO: Implement structured output parsing
-			runResult.FinalOutput = response.Content
+			// Hand the conversation to the fallback phase of a phase over its budget
+			fallback, err := r.slaFallback(opts, currentAgent, state.StartingAgent)
+			if err != nil {
+				return nil, err
+			}
+			if fallback != nil {
+				currentAgent = fallback
+				state.CurrentAgent = fallback
+				state.ConsecutiveToolCalls = 0
+			}
 
-			// Call hooks if provided
-			if err := r.callTurnEndHooks(ctx, currentAgent, turn, response, runResult.FinalOutput, opts); err != nil {
+			// Return the task of a delegate that used up its turns to its delegator
+			next, nextInput, err := r.enforceDelegationTurns(ctx, state.delegationTurns, currentAgent, state.Input, runResult, opts)
+			if err != nil {
 				return nil, err
 			}
+			if next != currentAgent {
+				currentAgent = next
+				state.CurrentAgent = next
+				state.Input = nextInput
+				state.ConsecutiveToolCalls = 0
+			}
 
-			break
+			// Register tools from the agent's MCP servers
+			if err := currentAgent.LoadMCPTools(ctx); err != nil {
+				return nil, err
+			}
+
+			// Stop a run whose workspace outgrew its quota
+			if err := checkWorkspaceQuota(ctx); err != nil {
+				return nil, err
+			}
+
+			// Compact the history when it approaches the context window
+			instructions := runInstructions(ctx, currentAgent, opts, runResult.Input, turn)
+			state.Input, err = r.compactHistory(ctx, currentAgent, instructions, state.Input, opts, state.budget)
+			if err != nil {
+				return nil, err
+			}
+
+			// Prepare and execute model request
+			response, err = r.executeModelRequest(ctx, currentAgent, instructions, state.Input, state.ConsecutiveToolCalls, opts, runResult, turn)
+			if err != nil {
+				return nil, err
+			}
+			resolveHandoffCall(currentAgent, response)
+
+			// Enforce the token and cost budget of the run
+			usedModel := turnModelName(currentAgent, opts.RunConfig, runResult, turn)
+			r.recordEvent(ctx, RunEvent{Type: EventModelResponse, Agent: currentAgent.Name, Turn: turn, Model: usedModel, Usage: response.Usage})
+			if err := state.budget.record(ctx, currentAgent.Name, usedModel, response.Usage); err != nil {
+				return nil, err
+			}
+
+			// Store images and other media of the response
+			if err := r.recordMedia(ctx, currentAgent, response, runResult, opts); err != nil {
+				return nil, err
+			}
+
+			// Store the raw response in the result
+			runResult.RawResponses = append(runResult.RawResponses, *response)
+
+			// Process the response
+			// Check if we have a final output (structured output)
+			if currentAgent.OutputType != nil {
+				// Ask the agent to fix output rejected by post-step validation
+				retryInput, err := r.validateStepOutput(ctx, currentAgent, state.Input, response, opts)
+				if err != nil {
+					return nil, err
+				}
+				if retryInput != nil {
+					state.Input = retryInput
+					continue
+				}
+
+				// TODO: Implement structured output parsing
+				runResult.FinalOutput = response.Content
+
+				// Call hooks if provided
+				if err := r.callTurnEndHooks(ctx, currentAgent, turn, response, runResult.FinalOutput, opts); err != nil {
+					return nil, err
+				}
+
+				break
+			}
+		}
+
+		// Pause the run if an action of the response needs approval
+		if request := r.nextApproval(ctx, currentAgent, response, turn, state.Decisions, opts); request != nil {
+			state.Response = response
+			state.Pending = request
+			r.snapshotState(ctx, state, runResult)
+			return nil, &ApprovalRequiredError{Request: request, State: state}
 		}
 
 		// Check if we have a handoff
 		if response.HandoffCall != nil {
-			nextAgent, nextInput, err := r.processHandoff(ctx, currentAgent, currentInput, response.HandoffCall, runResult, opts)
+			// A rejected handoff is reported to the same agent
+			if decision, ok := state.Decisions[handoffApprovalID(turn)]; ok && !decision.Approved {
+				action := fmt.Sprintf("The handoff to %s", response.HandoffCall.AgentName)
+				state.Input = appendUserMessage(r.messageFormatter(ctx, currentAgent, opts), state.Input, rejectionMessage(action, decision))
+				continue
+			}
+
+			nextAgent, nextInput, err := r.processHandoff(ctx, currentAgent, state.Input, response.HandoffCall, runResult, opts)
 			if err != nil {
 				return nil, err
 			}
 
 			if nextAgent != nil {
+				// Tell agents handing the conversation back and forth that they are looping
+				nudge, err := r.handleLoop(ctx, state.loops, currentAgent, state.loops.handoff(currentAgent.Name, nextAgent.Name, turn))
+				if err != nil {
+					return nil, err
+				}
+				if nudge != "" {
+					nextInput = appendUserMessage(r.messageFormatter(ctx, nextAgent, opts), nextInput, nudge)
+				}
+
 				// Reset consecutive tool calls counter on handoff
-				consecutiveToolCalls = 0
-				currentAgent = nextAgent
-				currentInput = nextInput
+				state.ConsecutiveToolCalls = 0
+				state.CurrentAgent = nextAgent
+				state.Input = nextInput
 				continue
 			}
 		}
 
 		// Check if we have tool calls
 		if len(response.ToolCalls) > 0 {
+			// Ask an agent repeating a tool call to use the result it has instead
+			nudge, err := r.handleLoop(ctx, state.loops, currentAgent, state.loops.toolCall(currentAgent.Name, response.ToolCalls, turn))
+			if err != nil {
+				return nil, err
+			}
+			if nudge != "" {
+				state.Input = appendUserMessage(r.messageFormatter(ctx, currentAgent, opts), state.Input, nudge)
+				continue
+			}
+
 			// Process tool calls and update input
-			nextInput, continueLoop, toolCallCount := r.processToolCalls(ctx, currentAgent, response, currentInput, consecutiveToolCalls, runResult, turn, opts)
+			nextInput, continueLoop, toolCallCount := r.processToolCalls(ctx, currentAgent, response, state.Input, state.ConsecutiveToolCalls, runResult, turn, opts, state.Decisions)
 			if continueLoop {
-				currentInput = nextInput
-				consecutiveToolCalls = toolCallCount
+				state.Input = nextInput
+				state.ConsecutiveToolCalls = toolCallCount
 				continue
 			}
-		} else if response.Content != "" {
-			// If we get here with content, we have a final output
-			runResult.FinalOutput = response.Content
+		} else if response.Content != "" || len(response.Media) > 0 {
+			// Ask the agent to fix output rejected by post-step validation
+			retryInput, err := r.validateStepOutput(ctx, currentAgent, state.Input, response, opts)
+			if err != nil {
+				return nil, err
+			}
+			if retryInput != nil {
+				state.Input = retryInput
+				continue
+			}
+
+			// If we get here with content or media, we have a final output
+			runResult.FinalOutput = response.Content
 
 			// Call hooks if provided
 			if err := r.callTurnEndHooks(ctx, currentAgent, turn, response, runResult.FinalOutput, opts); err != nil {
@@ -434,8 +1006,25 @@ func (r *Runner) runAgentLoop(ctx context.Context, agent AgentType, input interf
 		}
 	}
 
+	runResult.LastAgent = state.CurrentAgent
+
+	// Use the deliverable of the terminal task when the orchestrator ended without one
+	r.promoteTerminalOutput(ctx, runResult, opts, run.info.StartedAt)
+
+	// Clean up the final output and check it against the output guardrails before returning it
+	if runResult.FinalOutput != nil {
+		output, err := r.processOutput(ctx, state.CurrentAgent, runResult.FinalOutput)
+		if err != nil {
+			return nil, err
+		}
+		runResult.FinalOutput = output
+		if err := r.runOutputGuardrails(ctx, state.CurrentAgent, runResult.FinalOutput, opts, runResult); err != nil {
+			return nil, err
+		}
+	}
+
 	// Call end hooks
-	if err := r.callEndHooks(ctx, agent, runResult, opts); err != nil {
+	if err := r.callEndHooks(ctx, state.StartingAgent, runResult, opts); err != nil {
 		return nil, err
 	}
 
@@ -445,8 +1034,8 @@ func (r *Runner) runAgentLoop(ctx context.Context, agent AgentType, input interf
 // callStartHooks calls the hooks at the start of a run
 func (r *Runner) callStartHooks(ctx context.Context, agent AgentType, input interface{}, opts *RunOptions) error {
 	// Call hooks if provided
-	if opts.Hooks != nil {
-		if err := opts.Hooks.OnRunStart(ctx, agent, input); err != nil {
+	if hooks := r.runHooks(opts); hooks != nil {
+		if err := hooks.OnRunStart(ctx, agent, input); err != nil {
 			return fmt.Errorf("run start hook error: %w", err)
 		}
 	}
@@ -464,8 +1053,8 @@ func (r *Runner) callStartHooks(ctx context.Context, agent AgentType, input inte
 // callTurnStartHooks calls the hooks at the start of a turn
 func (r *Runner) callTurnStartHooks(ctx context.Context, agent AgentType, turn int, opts *RunOptions) error {
 	// Call hooks if provided
-	if opts.Hooks != nil {
-		if err := opts.Hooks.OnTurnStart(ctx, agent, turn); err != nil {
+	if hooks := r.runHooks(opts); hooks != nil {
+		if err := hooks.OnTurnStart(ctx, agent, turn); err != nil {
 			return fmt.Errorf("turn start hook error: %w", err)
 		}
 	}
@@ -476,13 +1065,13 @@ func (r *Runner) callTurnStartHooks(ctx context.Context, agent AgentType, turn i
 // callTurnEndHooks calls the hooks at the end of a turn
 func (r *Runner) callTurnEndHooks(ctx context.Context, agent AgentType, turn int, response *model.Response, output interface{}, opts *RunOptions) error {
 	// Call hooks if provided
-	if opts.Hooks != nil {
+	if hooks := r.runHooks(opts); hooks != nil {
 		turnResult := &SingleTurnResult{
 			Agent:    agent,
 			Response: response,
 			Output:   output,
 		}
-		if err := opts.Hooks.OnTurnEnd(ctx, agent, turn, turnResult); err != nil {
+		if err := hooks.OnTurnEnd(ctx, agent, turn, turnResult); err != nil {
 			return fmt.Errorf("turn end hook error: %w", err)
 		}
 	}
@@ -500,8 +1089,8 @@ func (r *Runner) callEndHooks(ctx context.Context, agent AgentType, runResult *r
 	}
 
 	// Call hooks if provided
-	if opts.Hooks != nil {
-		if err := opts.Hooks.OnRunEnd(ctx, runResult); err != nil {
+	if hooks := r.runHooks(opts); hooks != nil {
+		if err := hooks.OnRunEnd(ctx, runResult); err != nil {
 			return fmt.Errorf("run end hook error: %w", err)
 		}
 	}
@@ -510,17 +1099,17 @@ func (r *Runner) callEndHooks(ctx context.Context, agent AgentType, runResult *r
 }
 
 // executeModelRequest prepares and executes a model request
-func (r *Runner) executeModelRequest(ctx context.Context, agent AgentType, input interface{}, consecutiveToolCalls int, opts *RunOptions, turn int) (*model.Response, error) {
+func (r *Runner) executeModelRequest(ctx context.Context, agent AgentType, instructions string, input interface{}, consecutiveToolCalls int, opts *RunOptions, runResult *result.RunResult, turn int) (*model.Response, error) {
 	// Prepare model settings
-	modelSettings := r.prepareModelSettings(agent, opts.RunConfig, consecutiveToolCalls)
+	modelSettings := r.prepareModelSettings(agent, opts, consecutiveToolCalls)
 
 	// Prepare model request
 	request := &ModelRequestType{
-		SystemInstructions: agent.Instructions,
+		SystemInstructions: instructions,
 		Input:              input,
-		Tools:              r.prepareTools(agent.Tools),
+		Tools:              r.prepareTools(ctx, agent),
 		OutputSchema:       r.prepareOutputSchema(agent.OutputType),
-		Handoffs:           r.prepareHandoffs(agent.Handoffs),
+		Handoffs:           r.prepareAgentHandoffs(agent),
 		Settings:           modelSettings,
 	}
 
@@ -532,22 +1121,31 @@ func (r *Runner) executeModelRequest(ctx context.Context, agent AgentType, input
 	}
 
 	// Record model request event
-	tracing.ModelRequest(ctx, agent.Name, fmt.Sprintf("%v", agent.Model), request.Input, request.Tools)
+	tracing.ModelRequest(ctx, agent.Name, traceModelName(agent, opts.RunConfig, runResult, turn), request.Input, request.Tools)
 
-	// Resolve model
-	modelInstance, err := r.resolveModel(agent, opts.RunConfig)
+	// Resolve model, using the faster model of a workflow phase over its budget
+	modelInstance, err := r.resolveModel(ctx, agent, opts.RunConfig)
+	if err == nil {
+		modelInstance, err = r.escalateModel(opts, modelInstance)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to resolve model: %w", err)
 	}
 
-	// Call the model
-	response, err := modelInstance.GetResponse(ctx, request)
+	// Call the model, retrying on the fallback models
+	var response *model.Response
+	err = r.withFallbacks(ctx, agent, modelInstance, opts, runResult, turn, func(m model.Model) error {
+		applyOutputFormat(request, agent, m)
+		var callErr error
+		response, callErr = r.getResponse(ctx, agent, m, request)
+		return callErr
+	})
 	if err != nil {
 		return nil, fmt.Errorf("model call error: %w", err)
 	}
 
 	// Record model response event
-	tracing.ModelResponse(ctx, agent.Name, fmt.Sprintf("%v", agent.Model), response, err)
+	tracing.ModelResponse(ctx, agent.Name, traceModelName(agent, opts.RunConfig, runResult, turn), response, err)
 
 	// Call agent hooks if provided
 	if agent.Hooks != nil {
@@ -575,6 +1173,7 @@ func (r *Runner) processHandoff(ctx context.Context, currentAgent AgentType, cur
 	if taskID == "" {
 		taskID = generateTaskID()
 	}
+	r.recordEvent(ctx, RunEvent{Type: EventHandoff, Agent: currentAgent.Name, Target: handoffCall.AgentName, TaskID: handoffCall.TaskID, Input: handoffInput})
 
 	// Record the current task's context
 	// Just comment out the response variables since they are undefined
@@ -582,12 +1181,12 @@ func (r *Runner) processHandoff(ctx context.Context, currentAgent AgentType, cur
 		if response != nil && response.Content != "" {
 			// Get or create task context for current agent
 			var currentTaskID string
-			currentTask := r.getTaskContextForAgent(currentAgent.Name)
+			currentTask := r.getTaskContextForAgent(ctx, currentAgent.Name)
 
 			if currentTask != nil {
 				currentTaskID = currentTask.TaskID
 				// Update the interaction history
-				r.addTaskInteraction(currentTaskID, "agent", response.Content)
+				r.addTaskInteraction(ctx, currentTaskID, "agent", response.Content)
 			}
 		}
 	*/
@@ -598,10 +1197,10 @@ func (r *Runner) processHandoff(ctx context.Context, currentAgent AgentType, cur
 		handoffCall.Type = model.HandoffTypeReturn
 
 		// Get the parent agent name
-		parentAgentName := r.getDelegator(currentAgent.Name)
+		parentAgentName := r.getDelegator(ctx, currentAgent.Name)
 		if parentAgentName == "" {
 			// No delegator found, can't return
-			return currentAgent, handoffInput, fmt.Errorf("no delegator found for agent %s", currentAgent.Name)
+			return currentAgent, handoffInput, fmt.Errorf("%w: no delegator found for agent %s", ErrHandoffTargetNotFound, currentAgent.Name)
 		}
 
 		// Find the parent agent
@@ -615,18 +1214,37 @@ func (r *Runner) processHandoff(ctx context.Context, currentAgent AgentType, cur
 
 		if parentAgent == nil {
 			// Parent agent not found in handoffs
-			return currentAgent, handoffInput, fmt.Errorf("delegator %s not found in handoffs", parentAgentName)
+			return currentAgent, handoffInput, fmt.Errorf("%w: delegator %s is not among the handoffs of %s", ErrHandoffTargetNotFound, parentAgentName, currentAgent.Name)
 		}
 
 		// Get the current task context to find the parent task
-		currentTask := r.getTaskContextForAgent(currentAgent.Name)
+		currentTask := r.getTaskContextForAgent(ctx, currentAgent.Name)
 		parentTaskID := ""
 
 		// If we have task context, get the parent task ID
 		if currentTask != nil {
 			// Mark the task as complete before returning
 			if handoffCall.IsTaskComplete {
-				r.completeTask(currentTask.TaskID, handoffInput)
+				// Validate the result on behalf of the delegator, delegating the task
+				// again with the feedback if it is rejected
+				retry, failure, err := r.validateResult(ctx, currentAgent, currentTask, handoffInput, opts)
+				if err != nil {
+					return nil, nil, err
+				}
+				if retry != nil {
+					runResult.NewItems = append(runResult.NewItems, &result.HandoffItem{
+						AgentName: currentAgent.Name,
+						Input:     retry,
+					})
+					return currentAgent, retry, nil
+				}
+
+				if failure != nil {
+					r.failTask(ctx, currentTask.TaskID, failure)
+					handoffInput = fmt.Sprintf("%s\n\nLast result:\n%s", failure.Error(), guardrail.Text(handoffInput))
+				} else {
+					r.completeTask(ctx, currentTask.TaskID, handoffInput)
+				}
 			}
 
 			// Find parent task in related tasks
@@ -634,27 +1252,23 @@ func (r *Runner) processHandoff(ctx context.Context, currentAgent AgentType, cur
 			if parentTask != nil {
 				parentTaskID = parentTask.TaskID
 
+				// The delegator resumes work on its own task
+				r.transitionTask(ctx, parentTaskID, TaskStatusInProgress, "returned from "+currentTask.TaskID)
+
 				// Record the current result in the parent task
-				r.addTaskMetadata(parentTaskID, "child_result_"+currentTask.TaskID, handoffInput)
-
-				// If the input is a map or can be converted to a string, try to extract artifacts
-				if inputMap, ok := handoffInput.(map[string]interface{}); ok {
-					if code, hasCode := inputMap["code"]; hasCode {
-						r.updateTaskContext(parentTaskID, code, "code")
-					} else if text, hasText := inputMap["text"]; hasText {
-						r.updateTaskContext(parentTaskID, text, "text")
-					}
-				} else if inputStr, ok := handoffInput.(string); ok {
-					// Check if it looks like code (simplistic check)
-					if strings.Contains(inputStr, "function ") || strings.Contains(inputStr, "class ") {
-						r.updateTaskContext(parentTaskID, inputStr, "code")
-					} else {
-						r.updateTaskContext(parentTaskID, inputStr, "text")
-					}
+				r.addTaskMetadata(ctx, parentTaskID, "child_result_"+currentTask.TaskID, handoffInput)
+
+				// The result becomes a new version of the delegator's artifact
+				if artifact, artifactType, ok := extractArtifact(handoffInput); ok {
+					r.updateTaskContext(ctx, parentTaskID, artifact, artifactType, currentAgent.Name)
 				}
 
 				// Update the interaction history
-				r.addTaskInteraction(parentTaskID, currentAgent.Name, handoffInput)
+				r.addTaskInteraction(ctx, parentTaskID, currentAgent.Name, handoffInput)
+			} else if artifact, artifactType, ok := extractArtifact(handoffInput); ok {
+				// A delegator without a task of its own keeps the result as a new
+				// version of the returned task's artifact
+				r.updateTaskContext(ctx, currentTask.TaskID, artifact, artifactType, currentAgent.Name)
 			}
 		}
 
@@ -682,6 +1296,11 @@ func (r *Runner) processHandoff(ctx context.Context, currentAgent AgentType, cur
 			}
 		}
 
+		// Show the delegator what changed in its code since it last saw it
+		if parentTaskID != "" {
+			enhancedInput = r.addReviewDiff(opts, parentAgent.Name, parentTaskID, enhancedInput)
+		}
+
 		// Record handoff event
 		tracing.Handoff(ctx, currentAgent.Name, parentAgent.Name, enhancedInput)
 
@@ -692,53 +1311,81 @@ func (r *Runner) processHandoff(ctx context.Context, currentAgent AgentType, cur
 		}
 		runResult.NewItems = append(runResult.NewItems, handoffItem)
 
-		// Update the streamed result with the handoff event
-		// Similarly, comment out the eventCh references
-		/*
-			eventCh <- model.StreamEvent{
-				Type:    model.StreamEventTypeHandoff,
-				Content: fmt.Sprintf("Returning to %s...", parentAgentName),
-				HandoffCall: &model.HandoffCall{
-					AgentName:      parentAgentName,
-					Parameters:     map[string]any{"input": enhancedInput},
-					ReturnToAgent:  "",
-					TaskID:         parentTaskID, // Use parent task ID if available
-					IsTaskComplete: handoffCall.IsTaskComplete,
-					Type:           model.HandoffTypeReturn,
-				},
-			}
-		*/
+		// Stream the handoff to the caller of a streamed run
+		emitStreamEvent(ctx, model.StreamEvent{
+			Type:    model.StreamEventTypeHandoff,
+			Agent:   currentAgent.Name,
+			Content: fmt.Sprintf("Returning to %s...", parentAgentName),
+			HandoffCall: &model.HandoffCall{
+				AgentName:      parentAgentName,
+				Parameters:     map[string]any{"input": enhancedInput},
+				TaskID:         parentTaskID, // Use parent task ID if available
+				IsTaskComplete: handoffCall.IsTaskComplete,
+				Type:           model.HandoffTypeReturn,
+			},
+		})
 
 		// Return the parent agent and enhanced input
 		return parentAgent, enhancedInput, nil
 	}
 
-	// Regular handoff logic for delegation
-	var handoffAgent AgentType
-	for _, h := range currentAgent.Handoffs {
-		if h.Name == handoffCall.AgentName {
-			handoffAgent = h
-			break
-		}
+	// Broadcast handoffs fan the task out to several executors
+	if broadcast := findBroadcastHandoff(currentAgent, handoffCall.AgentName); broadcast != nil {
+		handoffCall.Type = model.HandoffTypeDelegate
+		emitStreamEvent(ctx, model.StreamEvent{
+			Type:    model.StreamEventTypeHandoff,
+			Agent:   currentAgent.Name,
+			Content: fmt.Sprintf("Broadcasting to %s...", broadcast.Name),
+			HandoffCall: &model.HandoffCall{
+				AgentName:  broadcast.Name,
+				Parameters: map[string]any{"input": handoffInput},
+				Type:       model.HandoffTypeDelegate,
+			},
+		})
+		return r.processBroadcastHandoff(ctx, currentAgent, broadcast, handoffInput, runResult, opts)
 	}
 
+	// Regular handoff logic for delegation
+	handoffAgent, _ := currentAgent.HandoffTarget(handoffCall.AgentName)
+
 	// If we found the handoff agent, update the current agent and input
 	if handoffAgent != nil {
 		// Mark this as a delegation handoff
 		handoffCall.Type = model.HandoffTypeDelegate
 
+		// A target with a handoff input type only takes input matching it; other
+		// input goes back to the delegating agent to correct
+		typedInput, feedback, valid, err := r.decodeHandoffInput(ctx, currentAgent, handoffAgent, handoffInput, currentInput, opts)
+		if err != nil {
+			return currentAgent, handoffInput, err
+		}
+		if !valid {
+			return currentAgent, feedback, nil
+		}
+		if handoffAgent.HandoffInputType != nil {
+			if fields, ok := typedFields(typedInput); ok {
+				handoffInput = fields
+			}
+		}
+
 		// Register the delegation in our registry
-		r.registerDelegation(currentAgent.Name, handoffAgent.Name)
+		r.registerDelegation(ctx, currentAgent.Name, handoffAgent.Name)
 
 		// Get current task context
-		currentTask := r.getTaskContextForAgent(currentAgent.Name)
+		currentTask := r.getTaskContextForAgent(ctx, currentAgent.Name)
 
 		// Create a new related task or use existing task ID
 		var newTaskID string
 		if currentTask != nil {
-			newTaskID = r.createRelatedTask(currentTask.TaskID, currentAgent.Name, handoffAgent.Name)
+			newTaskID = r.createRelatedTask(ctx, currentTask.TaskID, currentAgent.Name, handoffAgent.Name)
 		} else {
-			newTaskID = r.createTask(currentAgent.Name, handoffAgent.Name)
+			newTaskID = r.createTask(ctx, currentAgent.Name, handoffAgent.Name)
+		}
+
+		// The delegate starts working right away while the delegator waits on it
+		r.transitionTask(ctx, newTaskID, TaskStatusInProgress, "delegated by "+currentAgent.Name)
+		if currentTask != nil {
+			r.transitionTask(ctx, currentTask.TaskID, TaskStatusWaitingOnSubtask, "waiting on "+newTaskID)
 		}
 
 		// Set task description if input is a string
@@ -751,38 +1398,37 @@ func (r *Runner) processHandoff(ctx context.Context, currentAgent AgentType, cur
 		}
 
 		// Add initial interaction
-		r.addTaskInteraction(newTaskID, currentAgent.Name, handoffInput)
-
-		// Enhance input with context from current work if available
-		enhancedInput := handoffInput
-		if currentTask != nil && currentTask.WorkingContext != nil && currentTask.WorkingContext.Artifact != nil {
-			// Extract the artifact and its type
-			artifact := currentTask.WorkingContext.Artifact
-			artifactType := currentTask.WorkingContext.ArtifactType
+		r.addTaskInteraction(ctx, newTaskID, currentAgent.Name, handoffInput)
+		if handoffAgent.HandoffInputType != nil {
+			r.addTaskMetadata(ctx, newTaskID, handoffInputKey, typedInput)
+		}
 
-			// Create an enhanced input that includes the artifact
-			if inputStr, ok := handoffInput.(string); ok {
-				// For string inputs, we can include artifact info in the input
-				artifactInfo := ""
-				if artifactType == "code" {
-					if codeStr, ok := artifact.(string); ok {
-						artifactInfo = fmt.Sprintf("\n\nHere is the code that was previously worked on:\n```\n%s\n```\n", codeStr)
-					}
-				}
+		// The delegator's filter for the target decides which history, artifact and
+		// metadata the target receives
+		shaped := shapeHandoff(ctx, currentAgent, handoffAgent, handoffInput, currentInput, currentTask)
+		if shaped.Artifact != nil {
+			r.updateTaskContext(ctx, newTaskID, shaped.Artifact, shaped.ArtifactType, "")
+		}
+		enhancedInput := targetInput(shaped)
 
-				enhancedInput = inputStr + artifactInfo
-			} else if inputMap, ok := handoffInput.(map[string]interface{}); ok {
-				// For map inputs, we can add the artifact as a field
-				if artifactType == "code" {
-					inputMap["code_context"] = artifact
-				} else {
-					inputMap["context"] = artifact
-				}
-				enhancedInput = inputMap
+		// Code passed with the delegation becomes the new task's artifact, and the
+		// delegate is shown what changed in it since it last saw it
+		if inputMap, ok := shaped.Input.(map[string]interface{}); ok {
+			if code, hasCode := inputMap["code"]; hasCode {
+				r.updateTaskContext(ctx, newTaskID, code, ArtifactTypeCode, currentAgent.Name)
 			}
-
-			// Also set the artifact in the new task
-			r.updateTaskContext(newTaskID, artifact, artifactType)
+		}
+		enhancedInput = r.addReviewDiff(opts, handoffAgent.Name, newTaskID, enhancedInput)
+		if handoffAgent.HandoffInputType != nil {
+			enhancedInput = typedInputText(enhancedInput)
+		}
+		enhancedInput = withHandoffHistory(r.messageFormatter(ctx, handoffAgent, opts), shaped.History, enhancedInput)
+		if opts != nil && opts.RunConfig != nil && opts.RunConfig.HandoffInputFilter != nil {
+			filtered, err := opts.RunConfig.HandoffInputFilter(enhancedInput)
+			if err != nil {
+				return currentAgent, handoffInput, fmt.Errorf("handoff input filter for %s: %w", handoffAgent.Name, err)
+			}
+			enhancedInput = filtered
 		}
 
 		// Record handoff event
@@ -795,22 +1441,19 @@ func (r *Runner) processHandoff(ctx context.Context, currentAgent AgentType, cur
 		}
 		runResult.NewItems = append(runResult.NewItems, handoffItem)
 
-		// Update the streamed result with the handoff event
-		// Similarly, comment out the eventCh references
-		/*
-			eventCh <- model.StreamEvent{
-				Type:    model.StreamEventTypeHandoff,
-				Content: fmt.Sprintf("Handing off to %s...", handoffAgent.Name),
-				HandoffCall: &model.HandoffCall{
-					AgentName:      handoffAgent.Name,
-					Parameters:     map[string]any{"input": enhancedInput},
-					TaskID:         newTaskID, // Use the new task ID
-					Type:           model.HandoffTypeDelegate,
-					ReturnToAgent:  currentAgent.Name,
-					IsTaskComplete: false,
-				},
-			}
-		*/
+		// Stream the handoff to the caller of a streamed run
+		emitStreamEvent(ctx, model.StreamEvent{
+			Type:    model.StreamEventTypeHandoff,
+			Agent:   currentAgent.Name,
+			Content: fmt.Sprintf("Handing off to %s...", handoffAgent.Name),
+			HandoffCall: &model.HandoffCall{
+				AgentName:     handoffAgent.Name,
+				Parameters:    map[string]any{"input": enhancedInput},
+				TaskID:        newTaskID, // Use the new task ID
+				Type:          model.HandoffTypeDelegate,
+				ReturnToAgent: currentAgent.Name,
+			},
+		})
 
 		// Call agent hooks if provided
 		if currentAgent.Hooks != nil {
@@ -825,11 +1468,11 @@ func (r *Runner) processHandoff(ctx context.Context, currentAgent AgentType, cur
 	}
 
 	// Handoff agent not found
-	return currentAgent, handoffInput, fmt.Errorf("handoff agent %s not found", handoffCall.AgentName)
+	return currentAgent, handoffInput, fmt.Errorf("%w: %s is not among the handoffs of %s", ErrHandoffTargetNotFound, handoffCall.AgentName, currentAgent.Name)
 }
 
 // processToolCalls processes tool calls and updates the input
-func (r *Runner) processToolCalls(ctx context.Context, agent AgentType, response *model.Response, currentInput interface{}, currentConsecutiveCalls int, runResult *result.RunResult, turn int, opts *RunOptions) (interface{}, bool, int) {
+func (r *Runner) processToolCalls(ctx context.Context, agent AgentType, response *model.Response, currentInput interface{}, currentConsecutiveCalls int, runResult *result.RunResult, turn int, opts *RunOptions, decisions map[string]ApprovalDecision) (interface{}, bool, int) {
 	// Track consecutive tool calls to the same tool
 	toolCallCount := currentConsecutiveCalls
 	if len(response.ToolCalls) == 1 {
@@ -839,18 +1482,37 @@ func (r *Runner) processToolCalls(ctx context.Context, agent AgentType, response
 		toolCallCount = 0
 	}
 
+	// Format the history the way the agent's model expects it
+	formatter := r.messageFormatter(ctx, agent, opts)
+	calls := withToolCallIDs(response.ToolCalls, turn)
+
 	// Execute the tool calls
 	toolResults := make([]interface{}, 0, len(response.ToolCalls))
 	for i, tc := range response.ToolCalls {
-		// Execute the tool call with our helper function
-		modelToolResult, toolCallItem, toolResultItem, err := r.executeToolCall(ctx, agent, tc, turn, i)
+		// The remaining tool calls of a cancelled run are not started
+		if ctx.Err() != nil {
+			break
+		}
+
+		// Execute the tool call with our helper function, unless it was rejected
+		var toolOutput interface{}
+		var toolCallItem *result.ToolCallItem
+		var toolResultItem *result.ToolResultItem
+		var err error
+		emitStreamEvent(ctx, model.StreamEvent{Type: model.StreamEventTypeToolCallStarted, Agent: agent.Name, Turn: turn, ToolCall: &calls[i]})
+		if decision, ok := decisions[toolApprovalID(turn, i, tc)]; ok && !decision.Approved {
+			toolOutput, toolCallItem, toolResultItem = rejectedToolCall(tc, decision)
+		} else {
+			toolOutput, toolCallItem, toolResultItem, err = r.executeToolCall(ctx, agent, tc)
+		}
+		emitStreamEvent(ctx, model.StreamEvent{Type: model.StreamEventTypeToolCallFinished, Agent: agent.Name, Turn: turn, ToolCall: &calls[i], Output: toolOutput})
 
 		// Add the items to the result
 		runResult.NewItems = append(runResult.NewItems, toolCallItem)
 		runResult.NewItems = append(runResult.NewItems, toolResultItem)
 
 		// Add the tool result to the list for model input
-		toolResults = append(toolResults, modelToolResult)
+		toolResults = append(toolResults, formatter.FormatToolResult(calls[i], toolOutput))
 
 		// If we had a critical error that wasn't handled in executeToolCall, return it
 		if err != nil && (toolCallItem == nil || toolResultItem == nil) {
@@ -861,7 +1523,7 @@ func (r *Runner) processToolCalls(ctx context.Context, agent AgentType, response
 
 	// Update the input with the tool results
 	if len(toolResults) > 0 {
-		nextInput := r.updateInputWithToolResults(currentInput, response, toolResults, toolCallCount)
+		nextInput := r.updateInputWithToolResults(currentInput, response, calls, toolResults, toolCallCount, formatter)
 		return nextInput, true, toolCallCount
 	}
 
@@ -869,124 +1531,74 @@ func (r *Runner) processToolCalls(ctx context.Context, agent AgentType, response
 	return currentInput, false, toolCallCount
 }
 
-// updateInputWithToolResults updates the input with the tool results
-func (r *Runner) updateInputWithToolResults(currentInput interface{}, response *model.Response, toolResults []interface{}, consecutiveToolCalls int) interface{} {
-	// Debug output
-	if os.Getenv("DEBUG") == "1" {
-		fmt.Println("DEBUG - Updating input with tool results")
-		fmt.Printf("DEBUG - Current input type: %T\n", currentInput)
-		fmt.Printf("DEBUG - Tool results: %+v\n", toolResults)
-	}
+// updateInputWithToolResults appends the assistant message of the response and the
+// results of its tool calls to the input
+func (r *Runner) updateInputWithToolResults(currentInput interface{}, response *model.Response, calls []model.ToolCall, toolResults []interface{}, consecutiveToolCalls int, formatter model.MessageFormatter) interface{} {
+	r.log(nil).Debug("Updating input with tool results", "input_type", fmt.Sprintf("%T", currentInput), "results", toolResults)
 
-	// If the input is a string, convert it to a list
-	if _, ok := currentInput.(string); ok {
-		currentInput = []interface{}{
-			map[string]interface{}{
-				"type":    "message",
-				"role":    "user",
-				"content": currentInput,
-			},
-		}
-	}
+	inputList := model.FormatHistory(formatter, currentInput)
 
-	// If the input is not a list, create a new list
-	inputList, ok := currentInput.([]interface{})
-	if !ok {
-		inputList = []interface{}{}
+	if len(calls) > 0 {
+		// The assistant message with the tool calls must precede their results
+		assistant := *response
+		assistant.ToolCalls = calls
+		inputList = append(inputList, formatter.FormatAssistantMessage(&assistant))
+		inputList = append(inputList, toolResults...)
+	} else if response.Content != "" {
+		// Regular text response without tool calls
+		inputList = append(inputList, formatter.FormatAssistantMessage(response))
 	}
 
-	// Ensure the content field is never null
-	content := response.Content
-	if content == "" {
-		content = " " // Use a space character if content is empty
+	// If we've had several consecutive calls to the same tool, add a prompt
+	if consecutiveToolCalls >= 3 {
+		inputList = append(inputList, formatter.FormatUserMessage(
+			"Now that you have the information from the tool(s), please provide a complete response to my original question."))
 	}
 
-	// If we have tool calls, we need to do special handling for OpenAI
-	if len(response.ToolCalls) > 0 {
-		// Create properly formatted tool calls for OpenAI
-		toolCalls := make([]map[string]interface{}, len(response.ToolCalls))
-		for i, tc := range response.ToolCalls {
-			// Ensure we have a valid ID
-			toolCallID := tc.ID
-			if toolCallID == "" {
-				randomBytes := make([]byte, 8)
-				if _, err := rand.Read(randomBytes); err == nil {
-					toolCallID = fmt.Sprintf("call_%x", randomBytes)
-				} else {
-					toolCallID = fmt.Sprintf("call_%d", i)
-				}
-			}
+	r.log(nil).Debug("Updated input with tool results", "items", len(inputList), "input", inputList)
 
-			toolCalls[i] = map[string]interface{}{
-				"id":   toolCallID,
-				"type": "function",
-				"function": map[string]interface{}{
-					"name": tc.Name,
-					"arguments": func() string {
-						args, err := json.Marshal(tc.Parameters)
-						if err != nil {
-							return "{}"
-						}
-						return string(args)
-					}(),
-				},
-			}
-		}
+	return inputList
+}
 
-		// Add the assistant message with tool_calls
-		assistantMessage := map[string]interface{}{
-			"type":       "message",
-			"role":       "assistant",
-			"content":    content,
-			"tool_calls": toolCalls,
+// withToolCallIDs returns copies of the tool calls, generating IDs for calls the
+// model did not give one so that results can be matched to their calls
+func withToolCallIDs(toolCalls []model.ToolCall, turn int) []model.ToolCall {
+	calls := make([]model.ToolCall, len(toolCalls))
+	for i, tc := range toolCalls {
+		calls[i] = model.ToolCall{ID: tc.ID, Name: tc.Name, Parameters: tc.Parameters}
+		if calls[i].ID != "" {
+			continue
 		}
-		inputList = append(inputList, assistantMessage)
-
-		// Now add each tool result after the assistant message
-		for _, result := range toolResults {
-			if toolResult, ok := result.(map[string]interface{}); ok {
-				// Debug the tool result before adding
-				if os.Getenv("DEBUG") == "1" {
-					fmt.Printf("DEBUG - Adding tool result: %+v\n", toolResult)
-				}
-				inputList = append(inputList, toolResult)
-			}
+		// Generate a tool call ID in the same format as OpenAI's: "call_<random_string>"
+		randomBytes := make([]byte, 8)
+		if _, err := rand.Read(randomBytes); err != nil {
+			calls[i].ID = fmt.Sprintf("call_%d_%d", turn, i)
+		} else {
+			calls[i].ID = fmt.Sprintf("call_%x", randomBytes)
 		}
-	} else if response.Content != "" {
-		// Regular text response without tool calls
-		inputList = append(inputList, map[string]interface{}{
-			"type":    "message",
-			"role":    "assistant",
-			"content": response.Content,
-		})
 	}
+	return calls
+}
 
-	// If we've had several consecutive calls to the same tool, add a prompt
-	if consecutiveToolCalls >= 3 {
-		inputList = append(inputList, map[string]interface{}{
-			"type":    "message",
-			"role":    "user",
-			"content": "Now that you have the information from the tool(s), please provide a complete response to my original question.",
-		})
+// messageFormatter returns the message formatter of the agent's model
+func (r *Runner) messageFormatter(ctx context.Context, agent AgentType, opts *RunOptions) model.MessageFormatter {
+	modelInstance, err := r.resolveModel(ctx, agent, opts.RunConfig)
+	if err == nil {
+		modelInstance, err = r.escalateModel(opts, modelInstance)
 	}
-
-	// Debug the final input list
-	if os.Getenv("DEBUG") == "1" {
-		fmt.Println("DEBUG - Final input list:")
-		for i, item := range inputList {
-			fmt.Printf("DEBUG - Item %d: %+v\n", i, item)
-		}
+	if err != nil {
+		return model.DefaultMessageFormatter{}
 	}
-
-	return inputList
+	return model.FormatterFor(modelInstance)
 }
 
-// executeToolCall executes a tool call and returns the result
-func (r *Runner) executeToolCall(ctx context.Context, agent AgentType, tc model.ToolCall, turn int, idx int) (interface{}, *result.ToolCallItem, *result.ToolResultItem, error) {
+// executeToolCall executes a tool call and returns the output to send to the model
+// with the items of the call
+func (r *Runner) executeToolCall(ctx context.Context, agent AgentType, tc model.ToolCall) (interface{}, *result.ToolCallItem, *result.ToolResultItem, error) {
 	// Find the tool
 	var toolToCall tool.Tool
 	for _, t := range agent.Tools {
-		if t.GetName() == tc.Name {
+		if t.GetName() == tc.Name && flags.Enabled(ctx, t) {
 			toolToCall = t
 			break
 		}
@@ -994,8 +1606,9 @@ func (r *Runner) executeToolCall(ctx context.Context, agent AgentType, tc model.
 
 	// If we didn't find the tool, return an error result
 	if toolToCall == nil {
-		err := fmt.Errorf("tool not found: %s", tc.Name)
-		return createToolResultForError(tc, err, turn, idx),
+		err := fmt.Errorf("%w: %s", ErrToolNotFound, tc.Name)
+		r.recordEvent(ctx, RunEvent{Type: EventToolResult, Agent: agent.Name, Tool: tc.Name, Arguments: tc.Parameters, Error: err.Error()})
+		return fmt.Sprintf("Error: %v", err),
 			&result.ToolCallItem{
 				Name:       tc.Name,
 				Parameters: tc.Parameters,
@@ -1003,17 +1616,19 @@ func (r *Runner) executeToolCall(ctx context.Context, agent AgentType, tc model.
 			&result.ToolResultItem{
 				Name:   tc.Name,
 				Result: fmt.Sprintf("Error: %v", err),
+				Error:  err,
 			},
 			err
 	}
 
 	// Record tool call event
 	tracing.ToolCall(ctx, agent.Name, tc.Name, tc.Parameters)
+	r.recordEvent(ctx, RunEvent{Type: EventToolCall, Agent: agent.Name, Tool: tc.Name, Arguments: tc.Parameters})
 
 	// Call agent hooks if provided
 	if agent.Hooks != nil {
 		if err := agent.Hooks.OnBeforeToolCall(ctx, agent, toolToCall, tc.Parameters); err != nil {
-			return createToolResultForError(tc, err, turn, idx),
+			return fmt.Sprintf("Error: %v", err),
 				&result.ToolCallItem{
 					Name:       tc.Name,
 					Parameters: tc.Parameters,
@@ -1026,23 +1641,30 @@ func (r *Runner) executeToolCall(ctx context.Context, agent AgentType, tc model.
 		}
 	}
 
-	// Execute the tool
-	toolResult, err := toolToCall.Execute(ctx, tc.Parameters)
+	// Execute the tool, tracking what its middleware does
+	toolCtx, executionInfo := tool.TrackExecution(withToolCallOutput(ctx, tc))
+	toolResult, err := r.executeTool(toolCtx, agent, toolToCall, tc.Parameters)
 
 	// Record tool result event
 	tracing.ToolResult(ctx, agent.Name, tc.Name, toolResult, err)
+	resultEvent := RunEvent{Type: EventToolResult, Agent: agent.Name, Tool: tc.Name, Output: toolResult}
+	if err != nil {
+		resultEvent.Error = err.Error()
+	}
+	r.recordEvent(ctx, resultEvent)
 
 	// Call agent hooks if provided
 	if agent.Hooks != nil {
 		if hookErr := agent.Hooks.OnAfterToolCall(ctx, agent, toolToCall, toolResult, err); hookErr != nil {
-			return createToolResultForError(tc, hookErr, turn, idx),
+			return fmt.Sprintf("Error: %v", hookErr),
 				&result.ToolCallItem{
 					Name:       tc.Name,
 					Parameters: tc.Parameters,
 				},
 				&result.ToolResultItem{
-					Name:   tc.Name,
-					Result: fmt.Sprintf("Error: %v", hookErr),
+					Name:      tc.Name,
+					Result:    fmt.Sprintf("Error: %v", hookErr),
+					Execution: executionInfo(),
 				},
 				fmt.Errorf("after tool call hook error: %w", hookErr)
 		}
@@ -1061,93 +1683,30 @@ func (r *Runner) executeToolCall(ctx context.Context, agent AgentType, tc model.
 
 	// Create the tool result item
 	toolResultItem := &result.ToolResultItem{
-		Name:   tc.Name,
-		Result: toolResult,
-	}
-
-	// Use the actual ID from the tool call if available, otherwise generate one
-	// For OpenAI, the tool call ID format is important and should be consistent
-	toolCallID := tc.ID
-	if toolCallID == "" {
-		// Generate a tool call ID in the same format as OpenAI's: "call_<random_string>"
-		randomBytes := make([]byte, 8)
-		if _, err := rand.Read(randomBytes); err != nil {
-			toolCallID = fmt.Sprintf("call_%d_%d", turn, idx)
-		} else {
-			toolCallID = fmt.Sprintf("call_%x", randomBytes)
-		}
-	}
-
-	// Detect if the provider is Anthropic based on model provider name
-	isAnthropic := false
-	if agent.Model != nil {
-		providerName := reflect.TypeOf(agent.Model).String()
-		if strings.Contains(strings.ToLower(providerName), "anthropic") {
-			isAnthropic = true
-		}
-	}
-
-	var modelToolResult interface{}
-
-	if isAnthropic {
-		// Anthropic uses a different format for tool results
-		modelToolResult = map[string]interface{}{
-			"role":         "tool",
-			"tool_call_id": toolCallID,
-			"content":      fmt.Sprintf("%v", toolResult),
-		}
-	} else {
-		// Standard OpenAI format
-		modelToolResult = map[string]interface{}{
-			"type": "tool_result",
-			"tool_call": map[string]interface{}{
-				"name":       tc.Name,
-				"id":         toolCallID,
-				"parameters": tc.Parameters,
-			},
-			"tool_result": map[string]interface{}{
-				"content": toolResult,
-			},
-		}
-	}
-
-	return modelToolResult, toolCallItem, toolResultItem, nil
-}
-
-// createToolResultForError creates a structured tool result for an error
-func createToolResultForError(tc model.ToolCall, err error, turn int, idx int) interface{} {
-	// Generate a tool call ID
-	toolCallID := tc.ID
-	if toolCallID == "" {
-		randomBytes := make([]byte, 8)
-		if _, err := rand.Read(randomBytes); err != nil {
-			toolCallID = fmt.Sprintf("call_%d_%d", turn, idx)
-		} else {
-			toolCallID = fmt.Sprintf("call_%x", randomBytes)
-		}
+		Name:      tc.Name,
+		Result:    toolResult,
+		Execution: executionInfo(),
+		Error:     err,
 	}
 
-	return map[string]interface{}{
-		"type": "tool_result",
-		"tool_call": map[string]interface{}{
-			"name":       tc.Name,
-			"id":         toolCallID,
-			"parameters": tc.Parameters,
-		},
-		"tool_result": map[string]interface{}{
-			"content": fmt.Sprintf("Error: %v", err),
-		},
-	}
+	return toolResult, toolCallItem, toolResultItem, nil
 }
 
 // resolveModel resolves the model for the agent
-func (r *Runner) resolveModel(agent AgentType, runConfig *RunConfig) (model.Model, error) {
+func (r *Runner) resolveModel(ctx context.Context, agent AgentType, runConfig *RunConfig) (model.Model, error) {
 	// If runConfig.Model is set, it overrides agent.Model
 	modelToUse := agent.Model
 	if runConfig.Model != nil {
 		modelToUse = runConfig.Model
 	}
 
+	// A model name from the model flag overrides both
+	if runConfig.ModelFlag != "" {
+		if modelName := flags.String(flags.WithAttribute(ctx, "agent", agent.Name), runConfig.ModelFlag, ""); modelName != "" {
+			modelToUse = modelName
+		}
+	}
+
 	// If model is a string, use the provider to resolve it
 	if modelName, ok := modelToUse.(string); ok {
 		return runConfig.ModelProvider.GetModel(modelName)
@@ -1161,23 +1720,41 @@ func (r *Runner) resolveModel(agent AgentType, runConfig *RunConfig) (model.Mode
 	return nil, fmt.Errorf("invalid model type: %T", modelToUse)
 }
 
-// prepareTools prepares tools for the model request
-func (r *Runner) prepareTools(tools []tool.Tool) []interface{} {
-	// If no tools, return nil
-	if len(tools) == 0 {
-		return nil
-	}
+// prepareTools returns the cached definitions of the agent's tools for the model request
+func (r *Runner) prepareTools(ctx context.Context, currentAgent AgentType) []interface{} {
+	schemas := currentAgent.ToolSchemas()
 
-	// Convert tools to the OpenAI format using the utility function
-	openAITools := tool.ToOpenAITools(tools)
+	// Leave out tools whose feature flag is off
+	var result []interface{}
+	for i, t := range currentAgent.Tools {
+		if i < len(schemas.Tools) && flags.Enabled(ctx, t) {
+			result = append(result, schemas.Tools[i])
+		}
+	}
+	return result
+}
 
-	// Convert to []interface{} for compatibility with the ModelRequest
-	result := make([]interface{}, len(openAITools))
-	for i, t := range openAITools {
-		result[i] = t
+// applyOutputFormat asks for strict schema output when the agent has an output
+// type and the model supports it. A response format set in the model settings
+// takes precedence.
+func applyOutputFormat(request *ModelRequestType, agent AgentType, modelInstance model.Model) {
+	if agent.OutputType == nil || request.Settings == nil || request.Settings.ResponseFormat != nil {
+		return
+	}
+	schemaModel, ok := modelInstance.(model.SchemaOutputModel)
+	if !ok || !schemaModel.SupportsJSONSchema() {
+		return
+	}
+	schema, ok := request.OutputSchema.(map[string]interface{})
+	if !ok {
+		return
 	}
 
-	return result
+	name := agent.OutputType.Name()
+	if name == "" {
+		name = "output"
+	}
+	request.Settings.ResponseFormat = model.JSONSchemaFormat(name, schema, true)
 }
 
 // prepareOutputSchema prepares the output schema for the model request
@@ -1295,46 +1872,6 @@ func (r *Runner) getFieldSchema(fieldType reflect.Type) map[string]interface{} {
 	return schema
 }
 
-// prepareHandoffs prepares handoffs for the model request
-func (r *Runner) prepareHandoffs(handoffs []AgentType) []interface{} {
-	// If no handoffs, return nil
-	if len(handoffs) == 0 {
-		return nil
-	}
-
-	// Convert handoffs to the format expected by the model
-	// Format them as tools so the model can call them directly
-	result := make([]interface{}, len(handoffs))
-	for i, h := range handoffs {
-		handoffToolName := fmt.Sprintf("handoff_to_%s", h.Name)
-
-		result[i] = map[string]interface{}{
-			"type": "function",
-			"function": map[string]interface{}{
-				"name":        handoffToolName,
-				"description": fmt.Sprintf("Handoff the conversation to the %s. Use this when a query requires expertise from %s.", h.Name, h.Name),
-				"parameters": map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"input": map[string]interface{}{
-							"type":        "string",
-							"description": "The specific request to send to the agent. Be clear about what you're asking the agent to do.",
-						},
-					},
-					"required": []string{"input"},
-				},
-			},
-		}
-
-		// Debug log
-		if os.Getenv("DEBUG") == "1" {
-			fmt.Printf("Added handoff tool for agent: %s with name: %s\n", h.Name, handoffToolName)
-		}
-	}
-
-	return result
-}
-
 // initializeStreamingRun initializes the streaming run with default options and event channel
 func (r *Runner) initializeStreamingRun(ctx context.Context, agent AgentType, opts *RunOptions) (*RunOptions, chan model.StreamEvent, error) {
 	// Apply default options if not provided
@@ -1375,8 +1912,8 @@ func (r *Runner) initializeStreamingRun(ctx context.Context, agent AgentType, op
 // callRunStartHooks calls the start hooks for the run and agent
 func (r *Runner) callRunStartHooks(ctx context.Context, agent AgentType, input interface{}, opts *RunOptions, eventCh chan model.StreamEvent) error {
 	// Call hooks if provided
-	if opts.Hooks != nil {
-		if err := opts.Hooks.OnRunStart(ctx, agent, input); err != nil {
+	if hooks := r.runHooks(opts); hooks != nil {
+		if err := hooks.OnRunStart(ctx, agent, input); err != nil {
 			eventCh <- model.StreamEvent{
 				Type:  model.StreamEventTypeError,
 				Error: fmt.Errorf("run start hook error: %w", err),
@@ -1409,8 +1946,18 @@ func (r *Runner) processModelStream(
 	turn int,
 	eventCh chan model.StreamEvent,
 	consecutiveToolCalls *int,
+	budget *budgetTracker,
+	loops *loopDetector,
 ) error {
-	for event := range modelStream {
+	// Parts of the response streamed before it is done
+	var (
+		content     strings.Builder
+		toolCalls   []model.ToolCall
+		handoffCall *model.HandoffCall
+		media       []model.MediaPart
+	)
+
+	for event := range streamUntilDone(ctx, modelStream) {
 		// Check for errors
 		if event.Error != nil {
 			eventCh <- model.StreamEvent{
@@ -1424,131 +1971,295 @@ func (r *Runner) processModelStream(
 		switch event.Type {
 		case model.StreamEventTypeContent:
 			// Forward the event
+			content.WriteString(event.Content)
 			eventCh <- event
 
 		case model.StreamEventTypeToolCall:
 			// Forward the event
+			if event.ToolCall != nil {
+				toolCalls = append(toolCalls, *event.ToolCall)
+			}
 			eventCh <- event
 
 		case model.StreamEventTypeHandoff:
-			// Forward the event
+			// The handoff is streamed once it has been processed, see processHandoff
+			if event.HandoffCall != nil {
+				handoffCall = event.HandoffCall
+			}
+
+		case model.StreamEventTypeThrottled:
+			// Let the caller know the run is waiting for rate limit capacity
 			eventCh <- event
 
-		case model.StreamEventTypeDone:
-			// Create the final response
-			response := &model.Response{
-				Content:     "",
-				ToolCalls:   []model.ToolCall{},
-				HandoffCall: nil,
-			}
-			if event.Response != nil {
-				response.Content = event.Response.Content
-				response.ToolCalls = event.Response.ToolCalls
-				response.HandoffCall = event.Response.HandoffCall
-			}
+		case model.StreamEventTypeCacheHit:
+			// Let the caller know the response comes from the response cache
+			eventCh <- event
 
-			// Call agent hooks if provided
-			if currentAgent.Hooks != nil {
-				if err := currentAgent.Hooks.OnAfterModelCall(ctx, currentAgent, response); err != nil {
+		case model.StreamEventTypeMedia:
+			// Store the payload before forwarding the event, so it carries the artifact ID
+			if event.Media != nil {
+				stored, err := storeMediaPart(ctx, currentAgent, *event.Media, opts)
+				if err != nil {
 					eventCh <- model.StreamEvent{
 						Type:  model.StreamEventTypeError,
-						Error: fmt.Errorf("after model call hook error: %w", err),
+						Error: err,
 					}
 					return err
 				}
+				media = append(media, stored)
+				event.Media = &stored
 			}
+			eventCh <- event
 
-			// Handle structured output if applicable
-			if currentAgent.OutputType != nil {
-				return r.handleFinalOutput(ctx, currentAgent, response, opts, streamedResult, turn, eventCh)
-			}
-
-			// Handle handoff if applicable
-			if response.HandoffCall != nil {
-				// Process handoff and prepare for next turn
-				nextAgent, _, err := r.handleHandoff(
-					ctx,
-					currentAgent,
-					response.HandoffCall,
-					response,
-					opts,
-					streamedResult,
-					turn,
-					eventCh,
-				)
-				if err != nil {
-					return err
-				}
+		case model.StreamEventTypeDone:
+			response := streamedResponse(event.Response, content.String(), toolCalls, handoffCall, media)
+			return r.handleStreamedResponse(ctx, response, currentAgent, opts, streamedResult, turn, eventCh, consecutiveToolCalls, budget, loops)
+		}
+	}
 
-				// Update the current agent for the next turn
-				streamedResult.CurrentAgent = nextAgent
-				*consecutiveToolCalls = 0
-				return nil // Exit the event loop to start the next turn
-			}
-
-			// Check if we have tool calls
-			if len(response.ToolCalls) > 0 {
-				streamedResult.CurrentInput, streamedResult.ContinueLoop, *consecutiveToolCalls = r.processToolCalls(
-					ctx,
-					currentAgent,
-					response,
-					streamedResult.CurrentInput,
-					*consecutiveToolCalls,
-					streamedResult.RunResult,
-					turn,
-					opts,
-				)
-				if streamedResult.ContinueLoop {
-					return nil
-				}
-			} else if response.Content != "" {
-				return r.handleTextResponse(ctx, currentAgent, response, opts, streamedResult, turn, eventCh)
-			}
+	// A stream cut off by the end of the run is reported by the turn loop; other
+	// streams that end without a done event end with what they streamed
+	if ctx.Err() != nil {
+		return nil
+	}
+	response := streamedResponse(nil, content.String(), toolCalls, handoffCall, media)
+	return r.handleStreamedResponse(ctx, response, currentAgent, opts, streamedResult, turn, eventCh, consecutiveToolCalls, budget, loops)
+}
 
-			// If we reached max turns without a final output, use the last response content
-			if turn == opts.MaxTurns && streamedResult.RunResult.FinalOutput == nil {
-				streamedResult.RunResult.FinalOutput = response.Content
-				streamedResult.IsComplete = true
-				return nil
-			}
+// streamedResponse returns the response of a model stream. The content, tool
+// calls and handoff of models that leave them out of their done event are taken
+// from the events streamed before it.
+func streamedResponse(done *model.Response, content string, toolCalls []model.ToolCall, handoffCall *model.HandoffCall, media []model.MediaPart) *model.Response {
+	response := &model.Response{
+		Content:     content,
+		ToolCalls:   toolCalls,
+		HandoffCall: handoffCall,
+	}
+	if done != nil {
+		if done.Content != "" {
+			response.Content = done.Content
+		}
+		if len(done.ToolCalls) > 0 {
+			response.ToolCalls = done.ToolCalls
 		}
+		if done.HandoffCall != nil {
+			response.HandoffCall = done.HandoffCall
+		}
+		response.Usage = done.Usage
+		response.RequestID = done.RequestID
+		response.Media = done.Media
 	}
-
-	return nil
+	if len(media) > 0 {
+		response.Media = media
+	}
+	if response.ToolCalls == nil {
+		response.ToolCalls = []model.ToolCall{}
+	}
+	return response
 }
 
-// handleFinalOutput processes final output (structured or text)
-func (r *Runner) handleFinalOutput(
+// handleStreamedResponse acts on the response of a model stream like the turn
+// loop of Run does: it ends the run with a final output, hands off, or executes
+// the tool calls and feeds their results into the next turn
+func (r *Runner) handleStreamedResponse(
 	ctx context.Context,
-	currentAgent AgentType,
 	response *model.Response,
+	currentAgent AgentType,
 	opts *RunOptions,
 	streamedResult *result.StreamedRunResult,
 	turn int,
 	eventCh chan model.StreamEvent,
+	consecutiveToolCalls *int,
+	budget *budgetTracker,
+	loops *loopDetector,
 ) error {
-	// If the agent has an output type defined, treat this as structured output
-	// TODO: Implement structured output parsing
-	streamedResult.RunResult.FinalOutput = response.Content
+	resolveHandoffCall(currentAgent, response)
+	tracing.ModelResponse(ctx, currentAgent.Name, traceModelName(currentAgent, opts.RunConfig, streamedResult.RunResult, turn), response, nil)
 
-	// Call hooks if provided
-	if opts.Hooks != nil {
-		turnResult := &SingleTurnResult{
-			Agent:    currentAgent,
-			Response: response,
-			Output:   streamedResult.RunResult.FinalOutput,
-		}
-		if err := opts.Hooks.OnTurnEnd(ctx, currentAgent, turn, turnResult); err != nil {
-			eventCh <- model.StreamEvent{
-				Type:  model.StreamEventTypeError,
-				Error: fmt.Errorf("turn end hook error: %w", err),
-			}
-			return err
+	// Enforce the token and cost budget of the run
+	usedModel := turnModelName(currentAgent, opts.RunConfig, streamedResult.RunResult, turn)
+	r.recordEvent(ctx, RunEvent{Type: EventModelResponse, Agent: currentAgent.Name, Turn: turn, Model: usedModel, Usage: response.Usage})
+	if err := budget.record(ctx, currentAgent.Name, usedModel, response.Usage); err != nil {
+		eventCh <- model.StreamEvent{
+			Type:  model.StreamEventTypeError,
+			Error: err,
 		}
+		return err
+	}
+	if response.Usage != nil {
+		eventCh <- model.StreamEvent{Type: model.StreamEventTypeUsage, Agent: currentAgent.Name, Turn: turn, Usage: response.Usage}
 	}
 
-	// Send done event
-	eventCh <- model.StreamEvent{
+	// Store images and other media of the response
+	if err := r.recordMedia(ctx, currentAgent, response, streamedResult.RunResult, opts); err != nil {
+		eventCh <- model.StreamEvent{
+			Type:  model.StreamEventTypeError,
+			Error: err,
+		}
+		return err
+	}
+
+	// Store the raw response in the result
+	streamedResult.RunResult.RawResponses = append(streamedResult.RunResult.RawResponses, *response)
+
+	// Call agent hooks if provided
+	if currentAgent.Hooks != nil {
+		if err := currentAgent.Hooks.OnAfterModelCall(ctx, currentAgent, response); err != nil {
+			eventCh <- model.StreamEvent{
+				Type:  model.StreamEventTypeError,
+				Error: fmt.Errorf("after model call hook error: %w", err),
+			}
+			return err
+		}
+	}
+
+	// Handle structured output if applicable
+	if currentAgent.OutputType != nil {
+		return r.handleFinalOutput(ctx, currentAgent, response, opts, streamedResult, turn, eventCh)
+	}
+
+	// Pause the run if an action of the response needs approval
+	if request := r.nextApproval(ctx, currentAgent, response, turn, nil, opts); request != nil {
+		state := &RunState{
+			CurrentAgent:         currentAgent,
+			OriginalInput:        streamedResult.RunResult.Input,
+			Input:                streamedResult.CurrentInput,
+			Turn:                 turn,
+			ConsecutiveToolCalls: *consecutiveToolCalls,
+			Response:             response,
+			Pending:              request,
+			opts:                 opts,
+			budget:               budget,
+			loops:                loops,
+		}
+		r.snapshotState(ctx, state, streamedResult.RunResult)
+		return &ApprovalRequiredError{Request: request, State: state}
+	}
+
+	// Handle handoff if applicable
+	if response.HandoffCall != nil {
+		// Process handoff and prepare for next turn
+		nextAgent, nextInput, err := r.handleHandoff(
+			ctx,
+			currentAgent,
+			response.HandoffCall,
+			response,
+			opts,
+			streamedResult,
+			turn,
+			eventCh,
+		)
+		if err != nil {
+			return err
+		}
+
+		// Update the current agent for the next turn
+		streamedResult.CurrentAgent = nextAgent
+		*consecutiveToolCalls = 0
+
+		// A broadcast handoff hands control back to the same agent with new input
+		if nextAgent == currentAgent {
+			streamedResult.CurrentInput = nextInput
+			streamedResult.ContinueLoop = true
+		}
+		return nil // Exit the event loop to start the next turn
+	}
+
+	// Check if we have tool calls
+	if len(response.ToolCalls) > 0 {
+		// Ask an agent repeating a tool call to use the result it has instead
+		nudge, err := r.handleLoop(ctx, loops, currentAgent, loops.toolCall(currentAgent.Name, response.ToolCalls, turn))
+		if err != nil {
+			eventCh <- model.StreamEvent{
+				Type:  model.StreamEventTypeError,
+				Error: err,
+			}
+			return err
+		}
+		if nudge != "" {
+			streamedResult.CurrentInput = appendUserMessage(r.messageFormatter(ctx, currentAgent, opts), streamedResult.CurrentInput, nudge)
+			streamedResult.ContinueLoop = true
+			return nil
+		}
+
+		// Output tools produce while they run is streamed as it arrives
+		toolCtx := withToolOutputListener(ctx, func(tc model.ToolCall, chunk string) {
+			eventCh <- model.StreamEvent{Type: model.StreamEventTypeToolOutput, ToolCall: &tc, Content: chunk}
+		})
+		streamedResult.CurrentInput, streamedResult.ContinueLoop, *consecutiveToolCalls = r.processToolCalls(
+			toolCtx,
+			currentAgent,
+			response,
+			streamedResult.CurrentInput,
+			*consecutiveToolCalls,
+			streamedResult.RunResult,
+			turn,
+			opts,
+			nil,
+		)
+		if streamedResult.ContinueLoop {
+			return nil
+		}
+	} else if response.Content != "" || len(response.Media) > 0 {
+		return r.handleTextResponse(ctx, currentAgent, response, opts, streamedResult, turn, eventCh)
+	}
+
+	// If we reached max turns without a final output, use the last response content
+	if turn == opts.MaxTurns && streamedResult.RunResult.FinalOutput == nil {
+		streamedResult.RunResult.FinalOutput = response.Content
+		streamedResult.IsComplete = true
+		return nil
+	}
+
+	return nil
+}
+
+// handleFinalOutput processes final output (structured or text)
+func (r *Runner) handleFinalOutput(
+	ctx context.Context,
+	currentAgent AgentType,
+	response *model.Response,
+	opts *RunOptions,
+	streamedResult *result.StreamedRunResult,
+	turn int,
+	eventCh chan model.StreamEvent,
+) error {
+	// If the agent has an output type defined, treat this as structured output
+	// TODO: Implement structured output parsing
+	streamedResult.RunResult.FinalOutput = response.Content
+
+	// Clean up the final output with the agent's output processors
+	output, err := r.processOutput(ctx, currentAgent, streamedResult.RunResult.FinalOutput)
+	if err != nil {
+		eventCh <- model.StreamEvent{Type: model.StreamEventTypeError, Error: err}
+		return err
+	}
+	streamedResult.RunResult.FinalOutput = output
+
+	// Check the final output against the output guardrails before returning it
+	if err := r.runOutputGuardrails(ctx, currentAgent, streamedResult.RunResult.FinalOutput, opts, streamedResult.RunResult); err != nil {
+		eventCh <- guardrailErrorEvent(err)
+		return err
+	}
+
+	// Call hooks if provided
+	if hooks := r.runHooks(opts); hooks != nil {
+		turnResult := &SingleTurnResult{
+			Agent:    currentAgent,
+			Response: response,
+			Output:   streamedResult.RunResult.FinalOutput,
+		}
+		if err := hooks.OnTurnEnd(ctx, currentAgent, turn, turnResult); err != nil {
+			eventCh <- model.StreamEvent{
+				Type:  model.StreamEventTypeError,
+				Error: fmt.Errorf("turn end hook error: %w", err),
+			}
+			return err
+		}
+	}
+
+	// Send done event
+	eventCh <- model.StreamEvent{
 		Type:     model.StreamEventTypeDone,
 		Response: response,
 	}
@@ -1569,8 +2280,8 @@ func (r *Runner) handleFinalOutput(
 	}
 
 	// Call hooks if provided
-	if opts.Hooks != nil {
-		if err := opts.Hooks.OnRunEnd(ctx, streamedResult.RunResult); err != nil {
+	if hooks := r.runHooks(opts); hooks != nil {
+		if err := hooks.OnRunEnd(ctx, streamedResult.RunResult); err != nil {
 			eventCh <- model.StreamEvent{
 				Type:  model.StreamEventTypeError,
 				Error: fmt.Errorf("run end hook error: %w", err),
@@ -1595,14 +2306,28 @@ func (r *Runner) handleTextResponse(
 	// Use the response content as the final output
 	streamedResult.RunResult.FinalOutput = response.Content
 
+	// Clean up the final output with the agent's output processors
+	output, err := r.processOutput(ctx, currentAgent, streamedResult.RunResult.FinalOutput)
+	if err != nil {
+		eventCh <- model.StreamEvent{Type: model.StreamEventTypeError, Error: err}
+		return err
+	}
+	streamedResult.RunResult.FinalOutput = output
+
+	// Check the final output against the output guardrails before returning it
+	if err := r.runOutputGuardrails(ctx, currentAgent, streamedResult.RunResult.FinalOutput, opts, streamedResult.RunResult); err != nil {
+		eventCh <- guardrailErrorEvent(err)
+		return err
+	}
+
 	// Call hooks if provided
-	if opts.Hooks != nil {
+	if hooks := r.runHooks(opts); hooks != nil {
 		turnResult := &SingleTurnResult{
 			Agent:    currentAgent,
 			Response: response,
 			Output:   streamedResult.RunResult.FinalOutput,
 		}
-		if err := opts.Hooks.OnTurnEnd(ctx, currentAgent, turn, turnResult); err != nil {
+		if err := hooks.OnTurnEnd(ctx, currentAgent, turn, turnResult); err != nil {
 			eventCh <- model.StreamEvent{
 				Type:  model.StreamEventTypeError,
 				Error: fmt.Errorf("turn end hook error: %w", err),
@@ -1627,8 +2352,8 @@ func (r *Runner) handleTextResponse(
 	}
 
 	// Call hooks if provided
-	if opts.Hooks != nil {
-		if err := opts.Hooks.OnRunEnd(ctx, streamedResult.RunResult); err != nil {
+	if hooks := r.runHooks(opts); hooks != nil {
+		if err := hooks.OnRunEnd(ctx, streamedResult.RunResult); err != nil {
 			eventCh <- model.StreamEvent{
 				Type:  model.StreamEventTypeError,
 				Error: fmt.Errorf("run end hook error: %w", err),
@@ -1642,27 +2367,25 @@ func (r *Runner) handleTextResponse(
 
 // Task and Delegation Management Functions
 
-// registerDelegation registers a delegation from parent agent to child agent
-func (r *Runner) registerDelegation(parentName, childName string) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
-	// Initialize the delegation chain for the child if it doesn't exist
-	if _, exists := r.delegationChains[childName]; !exists {
-		r.delegationChains[childName] = make([]string, 0)
-	}
+// registerDelegation registers a delegation from parent agent to child agent in
+// the run of the context
+func (r *Runner) registerDelegation(ctx context.Context, parentName, childName string) {
+	key := delegationKey(taskRunID(ctx), childName)
 
-	// Add the parent to the delegation chain
-	r.delegationChains[childName] = append(r.delegationChains[childName], parentName)
+	// Add the parent to the delegation chain of the child
+	r.mu.Lock()
+	r.delegationChains[key] = append(r.delegationChains[key], parentName)
+	r.mu.Unlock()
+	r.persistDelegationChain(ctx, key)
 }
 
-// getDelegator returns the immediate delegator of an agent
-func (r *Runner) getDelegator(agentName string) string {
+// getDelegator returns the immediate delegator of an agent in the run of the context
+func (r *Runner) getDelegator(ctx context.Context, agentName string) string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	// Get the delegation chain for the agent
-	chain, exists := r.delegationChains[agentName]
+	chain, exists := r.delegationChains[delegationKey(taskRunID(ctx), agentName)]
 	if !exists || len(chain) == 0 {
 		// No delegator found
 		return ""
@@ -1672,15 +2395,17 @@ func (r *Runner) getDelegator(agentName string) string {
 	return chain[len(chain)-1]
 }
 
-// completeDelegation removes the parent from the child's delegation chain
-func (r *Runner) completeDelegation(parentName, childName string) {
+// completeDelegation removes the parent from the child's delegation chain in the
+// run of the context
+func (r *Runner) completeDelegation(ctx context.Context, parentName, childName string) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
 
 	// Get the delegation chain for the child
-	chain, exists := r.delegationChains[childName]
+	key := delegationKey(taskRunID(ctx), childName)
+	chain, exists := r.delegationChains[key]
 	if !exists || len(chain) == 0 {
 		// No delegation chain exists
+		r.mu.Unlock()
 		return
 	}
 
@@ -1688,24 +2413,27 @@ func (r *Runner) completeDelegation(parentName, childName string) {
 	for i, name := range chain {
 		if name == parentName {
 			// Remove this delegator by preserving order
-			r.delegationChains[childName] = append(chain[:i], chain[i+1:]...)
+			r.delegationChains[key] = append(chain[:i], chain[i+1:]...)
 			break
 		}
 	}
 
 	// If the chain is now empty, remove it
-	if len(r.delegationChains[childName]) == 0 {
-		delete(r.delegationChains, childName)
+	if len(r.delegationChains[key]) == 0 {
+		delete(r.delegationChains, key)
 	}
+	r.mu.Unlock()
+	r.persistDelegationChain(ctx, key)
 }
 
-// getDelegationChain returns the full delegation chain for an agent
-func (r *Runner) getDelegationChain(agentName string) []string {
+// getDelegationChain returns the full delegation chain for an agent in the run of
+// the context
+func (r *Runner) getDelegationChain(ctx context.Context, agentName string) []string {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	// Get the delegation chain for the agent
-	chain, exists := r.delegationChains[agentName]
+	chain, exists := r.delegationChains[delegationKey(taskRunID(ctx), agentName)]
 	if !exists {
 		// No delegation chain exists
 		return []string{}
@@ -1718,15 +2446,18 @@ func (r *Runner) getDelegationChain(agentName string) []string {
 }
 
 // createTask creates a new task in the task registry
-func (r *Runner) createTask(parentName, childName string) string {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-
+func (r *Runner) createTask(ctx context.Context, parentName, childName string) string {
 	// Generate a unique task ID
 	taskID := generateTaskID()
 
 	// Create and store the task context
-	r.taskRegistry[taskID] = NewTaskContext(taskID, parentName, childName)
+	task := NewTaskContext(taskID, parentName, childName)
+	task.RunID = taskRunID(ctx)
+	r.mu.Lock()
+	r.taskRegistry[taskID] = task
+	r.mu.Unlock()
+	r.persistTask(ctx, taskID)
+	r.recordEvent(ctx, RunEvent{Type: EventTaskCreated, Agent: parentName, Target: childName, TaskID: taskID, To: TaskStatusPending})
 
 	return taskID
 }
@@ -1740,35 +2471,110 @@ func (r *Runner) getTask(taskID string) *TaskContext {
 }
 
 // completeTask marks a task as complete
-func (r *Runner) completeTask(taskID string, result interface{}) {
+func (r *Runner) completeTask(ctx context.Context, taskID string, result interface{}) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
 
 	// Get the task
 	task, exists := r.taskRegistry[taskID]
 	if !exists {
 		// Task doesn't exist
+		r.mu.Unlock()
 		return
 	}
 
 	// Mark the task as complete
-	task.Complete(result)
+	if err := task.MarkCompleted(result); err != nil {
+		r.log(nil).Debug("Failed to complete task", "task", taskID, "error", err)
+	} else {
+		r.recordTaskTransition(ctx, task, task.ChildAgentName)
+	}
+	r.mu.Unlock()
+	r.persistTask(ctx, taskID)
 }
 
 // failTask marks a task as failed
-func (r *Runner) failTask(taskID string, err error) {
+func (r *Runner) failTask(ctx context.Context, taskID string, err error) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
 
 	// Get the task
 	task, exists := r.taskRegistry[taskID]
 	if !exists {
 		// Task doesn't exist
+		r.mu.Unlock()
 		return
 	}
 
 	// Mark the task as failed
-	task.Fail(err)
+	if terr := task.MarkFailed(err); terr != nil {
+		r.log(nil).Debug("Failed to mark task as failed", "task", taskID, "error", terr)
+	} else {
+		r.recordTaskTransition(ctx, task, task.ChildAgentName)
+	}
+	r.mu.Unlock()
+	r.persistTask(ctx, taskID)
+}
+
+// transitionTask moves a task to a new status, ignoring illegal transitions
+func (r *Runner) transitionTask(ctx context.Context, taskID string, status TaskStatus, reason string) {
+	r.mu.Lock()
+	task, exists := r.taskRegistry[taskID]
+	if !exists || task.Status == status {
+		r.mu.Unlock()
+		return
+	}
+
+	if err := task.Transition(status, reason); err != nil {
+		r.mu.Unlock()
+		r.log(nil).Debug("Ignored task transition", "task", taskID, "error", err)
+		return
+	}
+	r.recordTaskTransition(ctx, task, task.ChildAgentName)
+	r.mu.Unlock()
+	r.persistTask(ctx, taskID)
+}
+
+// TaskHistory returns the status transitions of a task, oldest first. Tasks are
+// held while their run is in progress; use a TaskStore to keep them afterwards.
+func (r *Runner) TaskHistory(taskID string) ([]TaskTransition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	task, exists := r.taskRegistry[taskID]
+	if !exists {
+		return nil, fmt.Errorf("task %s not found", taskID)
+	}
+
+	history := make([]TaskTransition, len(task.StatusHistory))
+	copy(history, task.StatusHistory)
+	return history, nil
+}
+
+// GetTaskStatus returns the current status of a task of a run in progress
+func (r *Runner) GetTaskStatus(taskID string) (TaskStatus, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	task, exists := r.taskRegistry[taskID]
+	if !exists {
+		return "", fmt.Errorf("task %s not found", taskID)
+	}
+	return task.Status, nil
+}
+
+// TasksByStatus returns the IDs of the tasks of runs in progress that are in the
+// given status
+func (r *Runner) TasksByStatus(status TaskStatus) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var taskIDs []string
+	for taskID, task := range r.taskRegistry {
+		if task.Status == status {
+			taskIDs = append(taskIDs, taskID)
+		}
+	}
+	sort.Strings(taskIDs)
+	return taskIDs
 }
 
 // generateTaskID generates a unique task ID
@@ -1783,15 +2589,15 @@ func generateTaskID() string {
 }
 
 // createRelatedTask creates a new task that's related to an existing task
-func (r *Runner) createRelatedTask(parentTaskID, parentName, childName string) string {
+func (r *Runner) createRelatedTask(ctx context.Context, parentTaskID, parentName, childName string) string {
 	r.mu.Lock()
-	defer r.mu.Unlock()
 
 	// Generate a unique task ID
 	taskID := generateTaskID()
 
 	// Create and store the task context
 	task := NewTaskContext(taskID, parentName, childName)
+	task.RunID = taskRunID(ctx)
 	r.taskRegistry[taskID] = task
 
 	// Associate with parent task
@@ -1802,8 +2608,10 @@ func (r *Runner) createRelatedTask(parentTaskID, parentName, childName string) s
 		if parentTask, exists := r.taskRegistry[parentTaskID]; exists {
 			parentTask.AddRelatedTask(taskID)
 
-			// Copy working context from parent task to maintain context
+			// Copy working context from parent task to maintain context, keeping the
+			// version numbers of its artifact
 			if parentTask.WorkingContext != nil && parentTask.WorkingContext.Artifact != nil {
+				task.WorkingContext.Versions = append([]ArtifactVersion(nil), parentTask.WorkingContext.Versions...)
 				task.SetArtifact(
 					parentTask.WorkingContext.Artifact,
 					parentTask.WorkingContext.ArtifactType,
@@ -1816,18 +2624,26 @@ func (r *Runner) createRelatedTask(parentTaskID, parentName, childName string) s
 			}
 		}
 	}
+	r.mu.Unlock()
+
+	if parentTaskID != "" {
+		r.persistTask(ctx, parentTaskID)
+	}
+	r.persistTask(ctx, taskID)
+	r.recordEvent(ctx, RunEvent{Type: EventTaskCreated, Agent: parentName, Target: childName, TaskID: taskID, To: TaskStatusPending})
 
 	return taskID
 }
 
-// getTasksForAgent returns all tasks for a specific agent
-func (r *Runner) getTasksForAgent(agentName string) []*TaskContext {
+// getTasksForAgent returns all tasks for a specific agent in the run of the context
+func (r *Runner) getTasksForAgent(ctx context.Context, agentName string) []*TaskContext {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
+	runID := taskRunID(ctx)
 	var tasks []*TaskContext
 	for _, task := range r.taskRegistry {
-		if task.ChildAgentName == agentName {
+		if task.ChildAgentName == agentName && task.RunID == runID {
 			tasks = append(tasks, task)
 		}
 	}
@@ -1855,43 +2671,52 @@ func (r *Runner) getTasksByRelationship(taskID string) []*TaskContext {
 	return relatedTasks
 }
 
-// updateTaskContext updates the working context of a task
-func (r *Runner) updateTaskContext(taskID string, artifact interface{}, artifactType string) {
+// updateTaskContext updates the working context of a task, telling the listener
+// of the context about a new version of its artifact
+func (r *Runner) updateTaskContext(ctx context.Context, taskID string, artifact interface{}, artifactType, author string) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
-
 	task, exists := r.taskRegistry[taskID]
 	if !exists {
+		r.mu.Unlock()
 		return
 	}
+	version, added := task.UpdateArtifact(artifact, artifactType, author)
+	r.mu.Unlock()
+	r.persistTask(ctx, taskID)
 
-	task.SetArtifact(artifact, artifactType)
+	if added {
+		r.log(nil).Debug("Artifact updated", "task", taskID, "version", version.Version, "author", author, "type", artifactType)
+		notifyArtifact(ctx, taskID, version)
+		r.recordEvent(ctx, RunEvent{Type: EventArtifactUpdated, Agent: author, TaskID: taskID, Version: version.Version})
+	}
 }
 
 // addTaskMetadata adds metadata to a task
-func (r *Runner) addTaskMetadata(taskID string, key string, value interface{}) {
+func (r *Runner) addTaskMetadata(ctx context.Context, taskID string, key string, value interface{}) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
-
 	task, exists := r.taskRegistry[taskID]
 	if !exists {
+		r.mu.Unlock()
 		return
 	}
 
 	task.AddMetadata(key, value)
+	r.mu.Unlock()
+	r.persistTask(ctx, taskID)
 }
 
 // addTaskInteraction adds an interaction to a task's history
-func (r *Runner) addTaskInteraction(taskID string, role string, content interface{}) {
+func (r *Runner) addTaskInteraction(ctx context.Context, taskID string, role string, content interface{}) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
-
 	task, exists := r.taskRegistry[taskID]
 	if !exists {
+		r.mu.Unlock()
 		return
 	}
 
 	task.AddInteraction(role, content)
+	r.mu.Unlock()
+	r.persistTask(ctx, taskID)
 }
 
 // getTaskArtifact retrieves the working artifact for a task
@@ -1907,16 +2732,18 @@ func (r *Runner) getTaskArtifact(taskID string) interface{} {
 	return task.GetArtifact()
 }
 
-// getTaskContextForAgent retrieves task context for the most recent task assigned to an agent
-func (r *Runner) getTaskContextForAgent(agentName string) *TaskContext {
+// getTaskContextForAgent retrieves task context for the most recent task assigned
+// to an agent in the run of the context
+func (r *Runner) getTaskContextForAgent(ctx context.Context, agentName string) *TaskContext {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
+	runID := taskRunID(ctx)
 	var latestTask *TaskContext
 	var latestTime time.Time
 
 	for _, task := range r.taskRegistry {
-		if task.ChildAgentName == agentName && (latestTask == nil || task.CreatedAt.After(latestTime)) {
+		if task.ChildAgentName == agentName && task.RunID == runID && (latestTask == nil || task.CreatedAt.After(latestTime)) {
 			latestTask = task
 			latestTime = task.CreatedAt
 		}
@@ -1956,271 +2783,13 @@ func (r *Runner) handleHandoff(
 	turn int,
 	eventCh chan model.StreamEvent,
 ) (AgentType, interface{}, error) {
-	// Get the input from Parameters
-	var handoffInput interface{}
-	if inputVal, ok := handoffCall.Parameters["input"]; ok {
-		handoffInput = inputVal
-	} else {
-		// Default to empty string if no input provided
-		handoffInput = ""
-	}
-
-	// Generate a task ID if one doesn't exist
-	taskID := handoffCall.TaskID
-	if taskID == "" {
-		taskID = generateTaskID()
-	}
-
-	// Record the current task's context
-	// Just comment out the response variables since they are undefined
-	/*
-		if response != nil && response.Content != "" {
-			// Get or create task context for current agent
-			var currentTaskID string
-			currentTask := r.getTaskContextForAgent(currentAgent.Name)
-
-			if currentTask != nil {
-				currentTaskID = currentTask.TaskID
-				// Update the interaction history
-				r.addTaskInteraction(currentTaskID, "agent", response.Content)
-			}
-		}
-	*/
-
-	// Check if this is a return handoff
-	if handoffCall.AgentName == "return_to_delegator" {
-		// Mark this as a return handoff
-		handoffCall.Type = model.HandoffTypeReturn
-
-		// Get the parent agent name
-		parentAgentName := r.getDelegator(currentAgent.Name)
-		if parentAgentName == "" {
-			// No delegator found, can't return
-			return currentAgent, handoffInput, fmt.Errorf("no delegator found for agent %s", currentAgent.Name)
-		}
-
-		// Find the parent agent
-		var parentAgent AgentType
-		for _, h := range currentAgent.Handoffs {
-			if h.Name == parentAgentName {
-				parentAgent = h
-				break
-			}
-		}
-
-		if parentAgent == nil {
-			// Parent agent not found in handoffs
-			return currentAgent, handoffInput, fmt.Errorf("delegator %s not found in handoffs", parentAgentName)
-		}
-
-		// Get the current task context to find the parent task
-		currentTask := r.getTaskContextForAgent(currentAgent.Name)
-		parentTaskID := ""
-
-		// If we have task context, get the parent task ID
-		if currentTask != nil {
-			// Mark the task as complete before returning
-			if handoffCall.IsTaskComplete {
-				r.completeTask(currentTask.TaskID, handoffInput)
-			}
-
-			// Find parent task in related tasks
-			parentTask := r.getParentTask(currentTask.TaskID)
-			if parentTask != nil {
-				parentTaskID = parentTask.TaskID
-
-				// Record the current result in the parent task
-				r.addTaskMetadata(parentTaskID, "child_result_"+currentTask.TaskID, handoffInput)
-
-				// If the input is a map or can be converted to a string, try to extract artifacts
-				if inputMap, ok := handoffInput.(map[string]interface{}); ok {
-					if code, hasCode := inputMap["code"]; hasCode {
-						r.updateTaskContext(parentTaskID, code, "code")
-					} else if text, hasText := inputMap["text"]; hasText {
-						r.updateTaskContext(parentTaskID, text, "text")
-					}
-				} else if inputStr, ok := handoffInput.(string); ok {
-					// Check if it looks like code (simplistic check)
-					if strings.Contains(inputStr, "function ") || strings.Contains(inputStr, "class ") {
-						r.updateTaskContext(parentTaskID, inputStr, "code")
-					} else {
-						r.updateTaskContext(parentTaskID, inputStr, "text")
-					}
-				}
-
-				// Update the interaction history
-				r.addTaskInteraction(parentTaskID, currentAgent.Name, handoffInput)
-			}
-		}
-
-		// Enhance handoff input with task context if available
-		enhancedInput := handoffInput
-		if currentTask != nil && currentTask.WorkingContext != nil && currentTask.WorkingContext.Artifact != nil {
-			// If the input is a string, we can append context information
-			if inputStr, ok := handoffInput.(string); ok {
-				contextInfo := fmt.Sprintf("\n\nTask Context:\n- Task ID: %s\n", currentTask.TaskID)
-
-				if currentTask.TaskDescription != "" {
-					contextInfo += fmt.Sprintf("- Description: %s\n", currentTask.TaskDescription)
-				}
-
-				if currentTask.WorkingContext.ArtifactType != "" {
-					contextInfo += fmt.Sprintf("- Artifact Type: %s\n", currentTask.WorkingContext.ArtifactType)
-				}
-
-				enhancedInput = inputStr + contextInfo
-			} else if inputMap, ok := handoffInput.(map[string]interface{}); ok {
-				// If the input is a map, we can add context as additional fields
-				inputMap["task_id"] = currentTask.TaskID
-				inputMap["task_context"] = currentTask.WorkingContext
-				enhancedInput = inputMap
-			}
-		}
-
-		// Record handoff event
-		tracing.Handoff(ctx, currentAgent.Name, parentAgent.Name, enhancedInput)
-
-		// Create a handoff item for the result
-		handoffItem := &result.HandoffItem{
-			AgentName: parentAgent.Name,
-			Input:     enhancedInput,
-		}
-		streamedResult.RunResult.NewItems = append(streamedResult.RunResult.NewItems, handoffItem)
-
-		// Update the streamed result with the handoff event
-		// Similarly, comment out the eventCh references
-		/*
-			eventCh <- model.StreamEvent{
-				Type:    model.StreamEventTypeHandoff,
-				Content: fmt.Sprintf("Returning to %s...", parentAgentName),
-				HandoffCall: &model.HandoffCall{
-					AgentName:      parentAgentName,
-					Parameters:     map[string]any{"input": enhancedInput},
-					ReturnToAgent:  "",
-					TaskID:         parentTaskID, // Use parent task ID if available
-					IsTaskComplete: handoffCall.IsTaskComplete,
-					Type:           model.HandoffTypeReturn,
-				},
-			}
-		*/
-
-		// Return the parent agent and enhanced input
-		return parentAgent, enhancedInput, nil
-	}
-
-	// Regular handoff logic for delegation
-	var handoffAgent AgentType
-	for _, h := range currentAgent.Handoffs {
-		if h.Name == handoffCall.AgentName {
-			handoffAgent = h
-			break
-		}
-	}
-
-	// If we found the handoff agent, update the current agent and input
-	if handoffAgent != nil {
-		// Mark this as a delegation handoff
-		handoffCall.Type = model.HandoffTypeDelegate
-
-		// Register the delegation in our registry
-		r.registerDelegation(currentAgent.Name, handoffAgent.Name)
-
-		// Get current task context
-		currentTask := r.getTaskContextForAgent(currentAgent.Name)
-
-		// Create a new related task or use existing task ID
-		var newTaskID string
-		if currentTask != nil {
-			newTaskID = r.createRelatedTask(currentTask.TaskID, currentAgent.Name, handoffAgent.Name)
-		} else {
-			newTaskID = r.createTask(currentAgent.Name, handoffAgent.Name)
-		}
-
-		// Set task description if input is a string
-		if inputStr, ok := handoffInput.(string); ok {
-			if len(inputStr) > 100 {
-				r.getTask(newTaskID).SetDescription(inputStr[:100] + "...")
-			} else {
-				r.getTask(newTaskID).SetDescription(inputStr)
-			}
-		}
-
-		// Add initial interaction
-		r.addTaskInteraction(newTaskID, currentAgent.Name, handoffInput)
-
-		// Enhance input with context from current work if available
-		enhancedInput := handoffInput
-		if currentTask != nil && currentTask.WorkingContext != nil && currentTask.WorkingContext.Artifact != nil {
-			// Extract the artifact and its type
-			artifact := currentTask.WorkingContext.Artifact
-			artifactType := currentTask.WorkingContext.ArtifactType
-
-			// Create an enhanced input that includes the artifact
-			if inputStr, ok := handoffInput.(string); ok {
-				// For string inputs, we can include artifact info in the input
-				artifactInfo := ""
-				if artifactType == "code" {
-					if codeStr, ok := artifact.(string); ok {
-						artifactInfo = fmt.Sprintf("\n\nHere is the code that was previously worked on:\n```\n%s\n```\n", codeStr)
-					}
-				}
-
-				enhancedInput = inputStr + artifactInfo
-			} else if inputMap, ok := handoffInput.(map[string]interface{}); ok {
-				// For map inputs, we can add the artifact as a field
-				if artifactType == "code" {
-					inputMap["code_context"] = artifact
-				} else {
-					inputMap["context"] = artifact
-				}
-				enhancedInput = inputMap
-			}
-
-			// Also set the artifact in the new task
-			r.updateTaskContext(newTaskID, artifact, artifactType)
-		}
-
-		// Record handoff event
-		tracing.Handoff(ctx, currentAgent.Name, handoffAgent.Name, enhancedInput)
-
-		// Create a handoff item for the result
-		handoffItem := &result.HandoffItem{
-			AgentName: handoffAgent.Name,
-			Input:     enhancedInput,
-		}
-		streamedResult.RunResult.NewItems = append(streamedResult.RunResult.NewItems, handoffItem)
-
-		// Update the streamed result with the handoff event
-		// Similarly, comment out the eventCh references
-		/*
-			eventCh <- model.StreamEvent{
-				Type:    model.StreamEventTypeHandoff,
-				Content: fmt.Sprintf("Handing off to %s...", handoffAgent.Name),
-				HandoffCall: &model.HandoffCall{
-					AgentName:      handoffAgent.Name,
-					Parameters:     map[string]any{"input": enhancedInput},
-					TaskID:         newTaskID, // Use the new task ID
-					Type:           model.HandoffTypeDelegate,
-					ReturnToAgent:  currentAgent.Name,
-					IsTaskComplete: false,
-				},
-			}
-		*/
-
-		// Call agent hooks if provided
-		if currentAgent.Hooks != nil {
-			if err := currentAgent.Hooks.OnBeforeHandoff(ctx, currentAgent, handoffAgent); err != nil {
-				return nil, nil, fmt.Errorf("before handoff hook error: %w", err)
-			}
-		}
-
-		// For streaming mode, we don't run the sub-agent to completion here
-		// Instead, we let the streaming loop handle the new agent in the next turn
-		return handoffAgent, enhancedInput, nil
-	}
-
-	// Handoff agent not found
-	return currentAgent, handoffInput, fmt.Errorf("handoff agent %s not found", handoffCall.AgentName)
+	// For streaming mode, we don't run the sub-agent to completion here
+	// Instead, we let the streaming loop handle the new agent in the next turn.
+	// New versions of task artifacts are streamed as they are recorded.
+	ctx = withArtifactListener(ctx, func(update *model.ArtifactUpdate) {
+		eventCh <- model.StreamEvent{Type: model.StreamEventTypeArtifact, Artifact: update}
+	})
+	return r.processHandoff(ctx, currentAgent, streamedResult.CurrentInput, handoffCall, streamedResult.RunResult, opts)
 }
 
 // generateHandoffTools creates a list of handoff tool definitions from agent list
@@ -0,0 +1,193 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tracing"
+)
+
+// Actions taken when a phase exceeds its time budget
+const (
+	SLAActionNotify   = "notify"
+	SLAActionModel    = "switch_model"
+	SLAActionFallback = "fallback_phase"
+)
+
+// SLAEscalation configures what happens when a workflow phase exceeds its time
+// budget. The breach is checked at the start of each turn, so a phase is never
+// interrupted in the middle of a model call or tool call.
+type SLAEscalation struct {
+	// OnBreach is called with the breach, for example to notify someone. An error
+	// stops the workflow.
+	OnBreach func(ctx context.Context, breach *SLABreach) error
+
+	// Model replaces the model of the phase's remaining turns, as a model name or
+	// a model.Model, typically with a faster model
+	Model interface{}
+
+	// FallbackPhase is the phase the workflow skips to. Its agent takes over the
+	// conversation and must be reachable through the handoffs of the run's agents.
+	FallbackPhase string
+}
+
+// SLABreach records a phase that exceeded its time budget
+type SLABreach struct {
+	Phase   string
+	Agent   string
+	Budget  time.Duration
+	Elapsed time.Duration
+	Action  string
+	At      time.Time
+}
+
+// checkPhaseBudget records a breach of the current phase's budget and escalates it
+func (wh *workflowHooks) checkPhaseBudget(ctx context.Context, agentName string) error {
+	phase := wh.phase(wh.state.CurrentPhase)
+	if phase == nil || phase.Budget <= 0 || wh.breached() {
+		return nil
+	}
+	elapsed := time.Since(wh.state.PhaseStartedAt)
+	if elapsed <= phase.Budget {
+		return nil
+	}
+
+	breach := SLABreach{
+		Phase:   phase.Name,
+		Agent:   agentName,
+		Budget:  phase.Budget,
+		Elapsed: elapsed,
+		Action:  SLAActionNotify,
+		At:      time.Now(),
+	}
+	escalation := phase.Escalation
+	if escalation != nil && escalation.FallbackPhase != "" {
+		breach.Action = SLAActionFallback
+	} else if escalation != nil && escalation.Model != nil {
+		breach.Action = SLAActionModel
+	}
+	wh.state.SLABreaches = append(wh.state.SLABreaches, breach)
+
+	wh.log.Warn("Workflow phase exceeded its time budget", "phase", phase.Name, "agent", agentName,
+		"budget", phase.Budget, "elapsed", elapsed, "action", breach.Action)
+	tracing.SLABreach(ctx, agentName, phase.Name, phase.Budget, elapsed, breach.Action)
+
+	if escalation == nil {
+		return nil
+	}
+	if escalation.OnBreach != nil {
+		if err := escalation.OnBreach(ctx, &breach); err != nil {
+			return fmt.Errorf("escalation of phase %s failed: %w", phase.Name, err)
+		}
+	}
+	if breach.Action == SLAActionFallback {
+		fallback := wh.phase(escalation.FallbackPhase)
+		if fallback == nil {
+			return fmt.Errorf("fallback phase %s of phase %s is not declared", escalation.FallbackPhase, phase.Name)
+		}
+		wh.moveToPhase(fallback.Name)
+		wh.fallbackAgent = fallback.Agent
+	}
+	return nil
+}
+
+// phase returns the declared phase with a name
+func (wh *workflowHooks) phase(name string) *WorkflowPhase {
+	if name == "" {
+		return nil
+	}
+	for i := range wh.workflowConfig.Phases {
+		if wh.workflowConfig.Phases[i].Name == name {
+			return &wh.workflowConfig.Phases[i]
+		}
+	}
+	return nil
+}
+
+// breached reports whether the current phase exceeded its budget since the
+// workflow entered it
+func (wh *workflowHooks) breached() bool {
+	breaches := wh.state.SLABreaches
+	if len(breaches) == 0 {
+		return false
+	}
+	last := breaches[len(breaches)-1]
+	return last.Phase == wh.state.CurrentPhase && !last.At.Before(wh.state.PhaseStartedAt)
+}
+
+// escalatedModel returns the model that replaces the model of the current phase
+// after it exceeded its budget, if any
+func (wh *workflowHooks) escalatedModel() interface{} {
+	phase := wh.phase(wh.state.CurrentPhase)
+	if phase == nil || phase.Escalation == nil || phase.Escalation.Model == nil || !wh.breached() {
+		return nil
+	}
+	return phase.Escalation.Model
+}
+
+// takeFallbackAgent returns the agent of the phase the workflow skipped to, once
+func (wh *workflowHooks) takeFallbackAgent() string {
+	name := wh.fallbackAgent
+	wh.fallbackAgent = ""
+	return name
+}
+
+// escalateModel returns the model that replaces an agent's model in a workflow
+// phase that exceeded its budget, or the agent's model
+func (r *Runner) escalateModel(opts *RunOptions, modelInstance model.Model) (model.Model, error) {
+	hooks, ok := opts.Hooks.(*workflowHooks)
+	if !ok {
+		return modelInstance, nil
+	}
+	switch m := hooks.escalatedModel().(type) {
+	case nil:
+		return modelInstance, nil
+	case model.Model:
+		return m, nil
+	case string:
+		if opts.RunConfig == nil || opts.RunConfig.ModelProvider == nil {
+			return nil, fmt.Errorf("no model provider to resolve escalation model %s", m)
+		}
+		return opts.RunConfig.ModelProvider.GetModel(m)
+	default:
+		return nil, fmt.Errorf("invalid escalation model type: %T", m)
+	}
+}
+
+// slaFallback returns the agent that takes over when a workflow phase that
+// exceeded its budget skips to a fallback phase, or nil
+func (r *Runner) slaFallback(opts *RunOptions, agents ...AgentType) (AgentType, error) {
+	hooks, ok := opts.Hooks.(*workflowHooks)
+	if !ok {
+		return nil, nil
+	}
+	name := hooks.takeFallbackAgent()
+	if name == "" {
+		return nil, nil
+	}
+	if fallback := findAgent(name, agents...); fallback != nil {
+		return fallback, nil
+	}
+	return nil, fmt.Errorf("fallback agent %s is not reachable through handoffs", name)
+}
+
+// findAgent searches the handoffs reachable from the agents for an agent by name
+func findAgent(name string, agents ...AgentType) AgentType {
+	seen := make(map[AgentType]bool)
+	queue := append([]AgentType(nil), agents...)
+	for len(queue) > 0 {
+		a := queue[0]
+		queue = queue[1:]
+		if a == nil || seen[a] {
+			continue
+		}
+		seen[a] = true
+		if a.Name == name {
+			return a
+		}
+		queue = append(queue, a.Handoffs...)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SQLDialect selects the SQL flavour used by SQLTaskStore
+type SQLDialect string
+
+const (
+	// SQLDialectPostgres uses $n placeholders
+	SQLDialectPostgres SQLDialect = "postgres"
+
+	// SQLDialectSQLite uses ? placeholders
+	SQLDialectSQLite SQLDialect = "sqlite"
+)
+
+// DefaultTaskTablePrefix is the prefix of the tables created by SQLTaskStore
+const DefaultTaskTablePrefix = "agent_"
+
+// SQLTaskStore is a TaskStore backed by Postgres or SQLite through database/sql.
+// The caller opens the *sql.DB with a driver of their choice (e.g. pgx, lib/pq,
+// modernc.org/sqlite or mattn/go-sqlite3).
+type SQLTaskStore struct {
+	db          *sql.DB
+	dialect     SQLDialect
+	tablePrefix string
+}
+
+// NewSQLTaskStore creates a new SQL task store. Call Migrate once to create the tables.
+func NewSQLTaskStore(db *sql.DB, dialect SQLDialect) *SQLTaskStore {
+	return &SQLTaskStore{
+		db:          db,
+		dialect:     dialect,
+		tablePrefix: DefaultTaskTablePrefix,
+	}
+}
+
+// NewPostgresTaskStore creates a new task store for Postgres
+func NewPostgresTaskStore(db *sql.DB) *SQLTaskStore {
+	return NewSQLTaskStore(db, SQLDialectPostgres)
+}
+
+// NewSQLiteTaskStore creates a new task store for SQLite
+func NewSQLiteTaskStore(db *sql.DB) *SQLTaskStore {
+	return NewSQLTaskStore(db, SQLDialectSQLite)
+}
+
+// WithTablePrefix sets the prefix of the task tables
+func (s *SQLTaskStore) WithTablePrefix(prefix string) *SQLTaskStore {
+	s.tablePrefix = prefix
+	return s
+}
+
+// Migrate creates the task tables if they don't exist
+func (s *SQLTaskStore) Migrate(ctx context.Context) error {
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %stasks (
+	task_id TEXT PRIMARY KEY,
+	parent_agent TEXT NOT NULL,
+	child_agent TEXT NOT NULL,
+	status TEXT NOT NULL,
+	data TEXT NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+)`, s.tablePrefix),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %stasks_status_idx ON %stasks (status)`, s.tablePrefix, s.tablePrefix),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %sdelegation_chains (
+	agent_name TEXT PRIMARY KEY,
+	chain TEXT NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+)`, s.tablePrefix),
+	}
+
+	for _, stmt := range statements {
+		if _, err := s.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to migrate task store: %w", err)
+		}
+	}
+	return nil
+}
+
+// SaveTask creates or updates a task
+func (s *SQLTaskStore) SaveTask(ctx context.Context, task *TaskContext) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return fmt.Errorf("failed to serialize task %s: %w", task.TaskID, err)
+	}
+
+	query := s.rebind(fmt.Sprintf(`INSERT INTO %stasks (task_id, parent_agent, child_agent, status, data, updated_at)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT (task_id) DO UPDATE SET status = excluded.status, data = excluded.data, updated_at = excluded.updated_at`, s.tablePrefix))
+
+	if _, err := s.db.ExecContext(ctx, query,
+		task.TaskID, task.ParentAgentName, task.ChildAgentName, string(task.Status), string(data), time.Now().UTC(),
+	); err != nil {
+		return fmt.Errorf("failed to save task %s: %w", task.TaskID, err)
+	}
+	return nil
+}
+
+// LoadTasks returns all stored tasks
+func (s *SQLTaskStore) LoadTasks(ctx context.Context) ([]*TaskContext, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT data FROM %stasks`, s.tablePrefix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tasks: %w", err)
+	}
+	defer rows.Close()
+
+	var tasks []*TaskContext
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+
+		task := &TaskContext{}
+		if err := json.Unmarshal([]byte(data), task); err != nil {
+			return nil, fmt.Errorf("failed to deserialize task: %w", err)
+		}
+		tasks = append(tasks, task)
+	}
+
+	return tasks, rows.Err()
+}
+
+// DeleteTask removes a task
+func (s *SQLTaskStore) DeleteTask(ctx context.Context, taskID string) error {
+	query := s.rebind(fmt.Sprintf(`DELETE FROM %stasks WHERE task_id = ?`, s.tablePrefix))
+	if _, err := s.db.ExecContext(ctx, query, taskID); err != nil {
+		return fmt.Errorf("failed to delete task %s: %w", taskID, err)
+	}
+	return nil
+}
+
+// SaveDelegationChain stores the stack of delegators of an agent
+func (s *SQLTaskStore) SaveDelegationChain(ctx context.Context, agentName string, chain []string) error {
+	if len(chain) == 0 {
+		query := s.rebind(fmt.Sprintf(`DELETE FROM %sdelegation_chains WHERE agent_name = ?`, s.tablePrefix))
+		if _, err := s.db.ExecContext(ctx, query, agentName); err != nil {
+			return fmt.Errorf("failed to delete delegation chain of %s: %w", agentName, err)
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(chain)
+	if err != nil {
+		return fmt.Errorf("failed to serialize delegation chain: %w", err)
+	}
+
+	query := s.rebind(fmt.Sprintf(`INSERT INTO %sdelegation_chains (agent_name, chain, updated_at)
+VALUES (?, ?, ?)
+ON CONFLICT (agent_name) DO UPDATE SET chain = excluded.chain, updated_at = excluded.updated_at`, s.tablePrefix))
+
+	if _, err := s.db.ExecContext(ctx, query, agentName, string(data), time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to save delegation chain of %s: %w", agentName, err)
+	}
+	return nil
+}
+
+// LoadDelegationChains returns all stored delegation chains
+func (s *SQLTaskStore) LoadDelegationChains(ctx context.Context) (map[string][]string, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT agent_name, chain FROM %sdelegation_chains`, s.tablePrefix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query delegation chains: %w", err)
+	}
+	defer rows.Close()
+
+	chains := make(map[string][]string)
+	for rows.Next() {
+		var agentName, data string
+		if err := rows.Scan(&agentName, &data); err != nil {
+			return nil, fmt.Errorf("failed to scan delegation chain: %w", err)
+		}
+
+		var chain []string
+		if err := json.Unmarshal([]byte(data), &chain); err != nil {
+			return nil, fmt.Errorf("failed to deserialize delegation chain of %s: %w", agentName, err)
+		}
+		chains[agentName] = chain
+	}
+
+	return chains, rows.Err()
+}
+
+// rebind converts ? placeholders to the placeholder style of the dialect
+func (s *SQLTaskStore) rebind(query string) string {
+	if s.dialect != SQLDialectPostgres {
+		return query
+	}
+
+	var sb strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			sb.WriteString(fmt.Sprintf("$%d", n))
+			continue
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
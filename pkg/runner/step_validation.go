@@ -0,0 +1,151 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/guardrail"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+)
+
+// DefaultStepRetries is the number of times an agent is asked to fix rejected
+// output when the validation config sets no limit
+const DefaultStepRetries = 2
+
+// StepOutput is the output an agent finished its step with. Post-step
+// validation rules receive it as their data.
+type StepOutput struct {
+	// Agent is the name of the agent
+	Agent string
+
+	// Phase is the workflow phase the agent worked in
+	Phase string
+
+	// Output is the final output of the agent
+	Output interface{}
+
+	// Attempt is the number of the attempt, starting at 1
+	Attempt int
+}
+
+// Text returns the output as text
+func (s *StepOutput) Text() string {
+	return guardrail.Text(s.Output)
+}
+
+// StepValidationError is returned when an agent's output is still rejected
+// after it was asked to fix it
+type StepValidationError struct {
+	Agent    string
+	Phase    string
+	Attempts int
+	Err      error
+}
+
+// Error implements the error interface
+func (e *StepValidationError) Error() string {
+	return fmt.Sprintf("output of %s rejected after %d attempts: %v", e.Agent, e.Attempts, e.Err)
+}
+
+// Unwrap returns the error of the failed validation rule
+func (e *StepValidationError) Unwrap() error {
+	return e.Err
+}
+
+// RequireCodeBlock is a post-step validation rule accepting output that contains
+// a fenced code block
+func RequireCodeBlock() ValidationRule {
+	return ValidationRule{
+		Name: "code_block",
+		Validate: func(data interface{}) (bool, error) {
+			step, ok := data.(*StepOutput)
+			return ok && strings.Count(step.Text(), "```") >= 2, nil
+		},
+		ErrorMessage: "the output must contain a fenced code block",
+		Severity:     ValidationError,
+	}
+}
+
+// RequireSchema is a post-step validation rule accepting output that is JSON
+// matching a schema
+func RequireSchema(schema map[string]interface{}) ValidationRule {
+	return ValidationRule{
+		Name: "schema",
+		Validate: func(data interface{}) (bool, error) {
+			step, ok := data.(*StepOutput)
+			if !ok {
+				return false, nil
+			}
+			var value interface{}
+			if err := json.Unmarshal([]byte(step.Text()), &value); err != nil {
+				return false, fmt.Errorf("the output must be valid JSON: %v", err)
+			}
+			if problems := tool.ValidateValue("output", value, schema); len(problems) > 0 {
+				return false, fmt.Errorf("the output does not match the expected schema: %s", strings.Join(problems, "; "))
+			}
+			return true, nil
+		},
+		Severity: ValidationError,
+	}
+}
+
+// validateStep checks the final output of an agent against the post-step
+// validation rules. It returns feedback asking the agent to fix rejected output,
+// or a StepValidationError once the agent has used up its retries.
+func (wh *workflowHooks) validateStep(agentName string, output interface{}) (string, error) {
+	vc := wh.workflowConfig.ValidationConfig
+	if vc == nil || len(vc.PostStepValidation) == 0 {
+		return "", nil
+	}
+	if wh.stepAttempts == nil {
+		wh.stepAttempts = make(map[string]int)
+	}
+	wh.stepAttempts[agentName]++
+
+	step := &StepOutput{
+		Agent:   agentName,
+		Phase:   wh.state.CurrentPhase,
+		Output:  output,
+		Attempt: wh.stepAttempts[agentName],
+	}
+	for _, rule := range vc.PostStepValidation {
+		err := applyValidationRule(wh.log, rule, step)
+		if err == nil {
+			continue
+		}
+
+		retries := vc.MaxStepRetries
+		if retries == 0 {
+			retries = DefaultStepRetries
+		}
+		if step.Attempt > retries {
+			delete(wh.stepAttempts, agentName)
+			return "", &StepValidationError{Agent: agentName, Phase: step.Phase, Attempts: step.Attempt, Err: err}
+		}
+		wh.log.Warn("Output rejected, asking the agent to fix it", "agent", agentName, "attempt", step.Attempt, "error", err)
+		return fmt.Sprintf("Your output was rejected: %v. Fix the problem and give your complete answer again.", err), nil
+	}
+
+	delete(wh.stepAttempts, agentName)
+	return "", nil
+}
+
+// validateStepOutput checks the final output of an agent in a workflow run. When
+// the output is rejected it returns the input asking the agent to fix it.
+func (r *Runner) validateStepOutput(ctx context.Context, agent AgentType, input interface{}, response *model.Response, opts *RunOptions) (interface{}, error) {
+	hooks, ok := opts.Hooks.(*workflowHooks)
+	if !ok {
+		return nil, nil
+	}
+	feedback, err := hooks.validateStep(agent.Name, response.Content)
+	if err != nil || feedback == "" {
+		return nil, err
+	}
+
+	formatter := r.messageFormatter(ctx, agent, opts)
+	history := append(model.FormatHistory(formatter, input), formatter.FormatAssistantMessage(response))
+	return append(history, formatter.FormatUserMessage(feedback)), nil
+}
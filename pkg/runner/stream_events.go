@@ -0,0 +1,74 @@
+package runner
+
+import (
+	"context"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+)
+
+// streamListenerKey is the context key of the listener of a streamed run's events
+type streamListenerKey struct{}
+
+// withStreamListener returns a context that sends the events of a streamed run,
+// such as handoffs and tool calls, to fn
+func withStreamListener(ctx context.Context, fn func(event model.StreamEvent)) context.Context {
+	return context.WithValue(ctx, streamListenerKey{}, fn)
+}
+
+// emitStreamEvent sends an event to the listener of the context. Runs that are
+// not streamed have no listener and drop the event.
+func emitStreamEvent(ctx context.Context, event model.StreamEvent) {
+	if fn, ok := ctx.Value(streamListenerKey{}).(func(event model.StreamEvent)); ok {
+		fn(event)
+	}
+}
+
+// sequenceEvents numbers the events of a streamed run in the order they are
+// sent. Events that do not name their run, agent or turn get those of the last
+// run_started, turn_started or agent_switched event before them.
+func sequenceEvents(events <-chan model.StreamEvent) <-chan model.StreamEvent {
+	sequenced := make(chan model.StreamEvent, cap(events))
+	go func() {
+		defer close(sequenced)
+		var (
+			seq   int64
+			runID string
+			agent string
+			turn  int
+		)
+		for event := range events {
+			switch event.Type {
+			case model.StreamEventTypeRunStarted:
+				runID, agent = event.RunID, event.Agent
+			case model.StreamEventTypeTurnStarted:
+				agent, turn = event.Agent, event.Turn
+			case model.StreamEventTypeAgentSwitched:
+				agent = event.Agent
+			}
+
+			seq++
+			event.Sequence = seq
+			if event.RunID == "" {
+				event.RunID = runID
+			}
+			if event.Agent == "" {
+				event.Agent = agent
+			}
+			if event.Turn == 0 {
+				event.Turn = turn
+			}
+			sequenced <- event
+		}
+	}()
+	return sequenced
+}
+
+// agentSwitchedEvent returns the event of another agent taking over a run
+func agentSwitchedEvent(from, to AgentType, turn int) model.StreamEvent {
+	return model.StreamEvent{
+		Type:      model.StreamEventTypeAgentSwitched,
+		Agent:     to.Name,
+		FromAgent: from.Name,
+		Turn:      turn,
+	}
+}
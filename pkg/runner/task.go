@@ -11,16 +11,95 @@ import (
 type TaskStatus string
 
 const (
-	// TaskStatusPending indicates the task is in progress
+	// TaskStatusPending indicates the task has been created but not started
 	TaskStatusPending TaskStatus = "pending"
 
-	// TaskStatusComplete indicates the task is complete
-	TaskStatusComplete TaskStatus = "complete"
+	// TaskStatusInProgress indicates an agent is working on the task
+	TaskStatusInProgress TaskStatus = "in_progress"
+
+	// TaskStatusWaitingOnSubtask indicates the task is blocked on a delegated subtask
+	TaskStatusWaitingOnSubtask TaskStatus = "waiting_on_subtask"
+
+	// TaskStatusCompleted indicates the task is complete
+	TaskStatusCompleted TaskStatus = "completed"
 
 	// TaskStatusFailed indicates the task failed
 	TaskStatusFailed TaskStatus = "failed"
+
+	// TaskStatusCancelled indicates the task was cancelled
+	TaskStatusCancelled TaskStatus = "cancelled"
+
+	// TaskStatusComplete is kept for backwards compatibility, use TaskStatusCompleted
+	TaskStatusComplete = TaskStatusCompleted
+
+	// legacyTaskStatusComplete is the status completed tasks were stored with
+	// before TaskStatusCompleted
+	legacyTaskStatusComplete TaskStatus = "complete"
 )
 
+// UnmarshalJSON decodes a task status, reading the "complete" status of tasks
+// stored by earlier versions as TaskStatusCompleted
+func (s *TaskStatus) UnmarshalJSON(data []byte) error {
+	var status string
+	if err := json.Unmarshal(data, &status); err != nil {
+		return err
+	}
+	*s = TaskStatus(status)
+	if *s == legacyTaskStatusComplete {
+		*s = TaskStatusCompleted
+	}
+	return nil
+}
+
+// taskTransitions lists the legal transitions out of each task status.
+// Completed, failed and cancelled are terminal.
+var taskTransitions = map[TaskStatus][]TaskStatus{
+	TaskStatusPending:          {TaskStatusInProgress, TaskStatusCompleted, TaskStatusFailed, TaskStatusCancelled},
+	TaskStatusInProgress:       {TaskStatusWaitingOnSubtask, TaskStatusCompleted, TaskStatusFailed, TaskStatusCancelled},
+	TaskStatusWaitingOnSubtask: {TaskStatusInProgress, TaskStatusFailed, TaskStatusCancelled},
+}
+
+// CanTransition reports whether a task may move from one status to another
+func CanTransition(from, to TaskStatus) bool {
+	for _, allowed := range taskTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminal reports whether no further transitions are possible from the status
+func (s TaskStatus) IsTerminal() bool {
+	return len(taskTransitions[s]) == 0
+}
+
+// TaskTransition records a single status change of a task
+type TaskTransition struct {
+	// From is the status before the transition
+	From TaskStatus `json:"from"`
+
+	// To is the status after the transition
+	To TaskStatus `json:"to"`
+
+	// Timestamp is when the transition happened
+	Timestamp time.Time `json:"timestamp"`
+
+	// Reason optionally explains the transition
+	Reason string `json:"reason,omitempty"`
+}
+
+// InvalidTransitionError is returned when a task is moved to a status that is not reachable from its current one
+type InvalidTransitionError struct {
+	TaskID string
+	From   TaskStatus
+	To     TaskStatus
+}
+
+func (e *InvalidTransitionError) Error() string {
+	return fmt.Sprintf("task %s: invalid transition from %s to %s", e.TaskID, e.From, e.To)
+}
+
 // Interaction represents a single interaction in a task's history
 type Interaction struct {
 	// Role is the role of the interaction (e.g., "user", "agent")
@@ -43,6 +122,9 @@ type WorkingContext struct {
 
 	// Metadata contains additional metadata about the task
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
+
+	// Versions are the artifacts the task had, oldest first
+	Versions []ArtifactVersion `json:"versions,omitempty"`
 }
 
 // TaskContext tracks information about a delegated task
@@ -50,6 +132,10 @@ type TaskContext struct {
 	// TaskID is a unique identifier for the task
 	TaskID string
 
+	// RunID is the ID of the run the task was created in. Runs sharing a runner
+	// only see their own tasks.
+	RunID string
+
 	// ParentAgentName is the name of the agent that delegated the task
 	ParentAgentName string
 
@@ -79,6 +165,9 @@ type TaskContext struct {
 
 	// InteractionHistory contains the history of interactions for this task
 	InteractionHistory []Interaction
+
+	// StatusHistory contains every status transition of the task, oldest first
+	StatusHistory []TaskTransition
 }
 
 // NewTaskContext creates a new task context
@@ -92,23 +181,81 @@ func NewTaskContext(taskID, parentName, childName string) *TaskContext {
 		RelatedTaskIDs:     []string{},
 		WorkingContext:     &WorkingContext{Metadata: make(map[string]interface{})},
 		InteractionHistory: []Interaction{},
+		StatusHistory:      []TaskTransition{},
 	}
 }
 
-// Complete marks the task as complete with a result
+// Transition moves the task to a new status, recording the transition in the
+// status history. Illegal transitions return an *InvalidTransitionError.
+func (t *TaskContext) Transition(to TaskStatus, reason string) error {
+	if !CanTransition(t.Status, to) {
+		return &InvalidTransitionError{TaskID: t.TaskID, From: t.Status, To: to}
+	}
+
+	now := time.Now()
+	t.StatusHistory = append(t.StatusHistory, TaskTransition{
+		From:      t.Status,
+		To:        to,
+		Timestamp: now,
+		Reason:    reason,
+	})
+	t.Status = to
+
+	if to.IsTerminal() {
+		t.CompletedAt = &now
+	}
+	return nil
+}
+
+// Start marks the task as in progress
+func (t *TaskContext) Start() error {
+	return t.Transition(TaskStatusInProgress, "")
+}
+
+// WaitOnSubtask marks the task as blocked on a delegated subtask
+func (t *TaskContext) WaitOnSubtask(subtaskID string) error {
+	return t.Transition(TaskStatusWaitingOnSubtask, "waiting on "+subtaskID)
+}
+
+// Complete marks the task as complete with a result. Tasks that cannot be
+// completed from their status are left unchanged; use MarkCompleted to find out.
 func (t *TaskContext) Complete(result interface{}) {
-	t.Status = TaskStatusComplete
+	_ = t.MarkCompleted(result)
+}
+
+// MarkCompleted marks the task as complete with a result. Illegal transitions
+// return an *InvalidTransitionError.
+func (t *TaskContext) MarkCompleted(result interface{}) error {
+	if err := t.Transition(TaskStatusCompleted, ""); err != nil {
+		return err
+	}
 	t.Result = result
-	now := time.Now()
-	t.CompletedAt = &now
+	return nil
 }
 
-// Fail marks the task as failed with an error
+// Fail marks the task as failed with an error. Tasks that cannot fail from their
+// status are left unchanged; use MarkFailed to find out.
 func (t *TaskContext) Fail(err error) {
-	t.Status = TaskStatusFailed
+	_ = t.MarkFailed(err)
+}
+
+// MarkFailed marks the task as failed with an error. Illegal transitions return
+// an *InvalidTransitionError.
+func (t *TaskContext) MarkFailed(err error) error {
+	reason := ""
+	if err != nil {
+		reason = err.Error()
+	}
+	if terr := t.Transition(TaskStatusFailed, reason); terr != nil {
+		return terr
+	}
 	t.Result = err
-	now := time.Now()
-	t.CompletedAt = &now
+	return nil
+}
+
+// Cancel marks the task as cancelled
+func (t *TaskContext) Cancel(reason string) error {
+	return t.Transition(TaskStatusCancelled, reason)
 }
 
 // IsPending checks if the task is still pending
@@ -116,9 +263,19 @@ func (t *TaskContext) IsPending() bool {
 	return t.Status == TaskStatusPending
 }
 
+// IsInProgress checks if the task is being worked on
+func (t *TaskContext) IsInProgress() bool {
+	return t.Status == TaskStatusInProgress
+}
+
+// IsWaitingOnSubtask checks if the task is blocked on a subtask
+func (t *TaskContext) IsWaitingOnSubtask() bool {
+	return t.Status == TaskStatusWaitingOnSubtask
+}
+
 // IsComplete checks if the task is complete
 func (t *TaskContext) IsComplete() bool {
-	return t.Status == TaskStatusComplete
+	return t.Status == TaskStatusCompleted
 }
 
 // IsFailed checks if the task has failed
@@ -126,9 +283,14 @@ func (t *TaskContext) IsFailed() bool {
 	return t.Status == TaskStatusFailed
 }
 
-// IsFinished checks if the task is either complete or failed
+// IsCancelled checks if the task was cancelled
+func (t *TaskContext) IsCancelled() bool {
+	return t.Status == TaskStatusCancelled
+}
+
+// IsFinished checks if the task has reached a terminal status
 func (t *TaskContext) IsFinished() bool {
-	return t.IsComplete() || t.IsFailed()
+	return t.Status.IsTerminal()
 }
 
 // GetResult returns the task result
@@ -151,10 +313,10 @@ func (t *TaskContext) AddRelatedTask(taskID string) {
 	t.RelatedTaskIDs = append(t.RelatedTaskIDs, taskID)
 }
 
-// SetArtifact sets the working artifact for the task
+// SetArtifact sets the working artifact for the task, adding a version when it
+// changed
 func (t *TaskContext) SetArtifact(artifact interface{}, artifactType string) {
-	t.WorkingContext.Artifact = artifact
-	t.WorkingContext.ArtifactType = artifactType
+	t.UpdateArtifact(artifact, artifactType, "")
 }
 
 // GetArtifact returns the working artifact for the task
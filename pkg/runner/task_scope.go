@@ -0,0 +1,54 @@
+package runner
+
+import (
+	"context"
+	"strings"
+)
+
+type taskRunKey struct{}
+
+// withTaskRun returns a context whose tasks and delegation chains belong to the
+// run with the given ID. Runs started by another run, such as the executors of a
+// broadcast handoff, keep working on the tasks of the run that started them.
+func withTaskRun(ctx context.Context, runID string) context.Context {
+	if taskRunID(ctx) != "" {
+		return ctx
+	}
+	return context.WithValue(ctx, taskRunKey{}, runID)
+}
+
+// taskRunID returns the ID of the run whose tasks and delegation chains the
+// context works on, or "" outside of a run
+func taskRunID(ctx context.Context) string {
+	id, _ := ctx.Value(taskRunKey{}).(string)
+	return id
+}
+
+// delegationKey returns the key of the delegation chain of an agent in a run.
+// Chains recorded outside of a run are keyed by the agent name alone.
+func delegationKey(runID, agentName string) string {
+	if runID == "" {
+		return agentName
+	}
+	return runID + "/" + agentName
+}
+
+// releaseTaskRun drops the tasks and delegation chains of a finished run from
+// memory, so that a long-lived runner does not keep every run's tasks. A task
+// store still holds them.
+func (r *Runner) releaseTaskRun(runID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for taskID, task := range r.taskRegistry {
+		if task.RunID == runID {
+			delete(r.taskRegistry, taskID)
+		}
+	}
+	prefix := delegationKey(runID, "")
+	for key := range r.delegationChains {
+		if strings.HasPrefix(key, prefix) {
+			delete(r.delegationChains, key)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+// TaskStore persists the runner's task registry and delegation chains so that
+// delegation workflows survive process restarts
+type TaskStore interface {
+	// SaveTask creates or updates a task
+	SaveTask(ctx context.Context, task *TaskContext) error
+
+	// LoadTasks returns all stored tasks
+	LoadTasks(ctx context.Context) ([]*TaskContext, error)
+
+	// DeleteTask removes a task
+	DeleteTask(ctx context.Context, taskID string) error
+
+	// SaveDelegationChain stores the stack of delegators of an agent. Chains are
+	// keyed by the ID of their run and the agent name, joined by a slash. An
+	// empty chain removes the entry.
+	SaveDelegationChain(ctx context.Context, agentName string, chain []string) error
+
+	// LoadDelegationChains returns all stored delegation chains by key
+	LoadDelegationChains(ctx context.Context) (map[string][]string, error)
+}
+
+// WithTaskStore sets the store used to persist tasks and delegation chains.
+// Tasks and chains already in the store are loaded into the runner.
+func (r *Runner) WithTaskStore(store TaskStore) *Runner {
+	r.mu.Lock()
+	r.taskStore = store
+	r.mu.Unlock()
+
+	if err := r.RestoreTasks(context.Background()); err != nil {
+		r.log(nil).Error("Failed to restore tasks", "error", err)
+	}
+	return r
+}
+
+// RestoreTasks loads the tasks and delegation chains from the task store,
+// replacing any entries with the same key held in memory
+func (r *Runner) RestoreTasks(ctx context.Context) error {
+	r.mu.RLock()
+	store := r.taskStore
+	r.mu.RUnlock()
+	if store == nil {
+		return nil
+	}
+
+	tasks, err := store.LoadTasks(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load tasks: %w", err)
+	}
+	chains, err := store.LoadDelegationChains(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load delegation chains: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, task := range tasks {
+		if task.WorkingContext == nil {
+			task.WorkingContext = &WorkingContext{}
+		}
+		if task.WorkingContext.Metadata == nil {
+			task.WorkingContext.Metadata = make(map[string]interface{})
+		}
+		r.taskRegistry[task.TaskID] = task
+	}
+	for key, chain := range chains {
+		r.delegationChains[key] = chain
+	}
+
+	return nil
+}
+
+// persistTask writes the current state of a task to the task store. It must be
+// called without r.mu held: the task is copied under the lock and written once
+// it is released, so that a slow store does not hold up other runs.
+func (r *Runner) persistTask(ctx context.Context, taskID string) {
+	lock := r.storeLock("task:" + taskID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	r.mu.RLock()
+	store := r.taskStore
+	var task *TaskContext
+	if current, exists := r.taskRegistry[taskID]; exists && store != nil {
+		task = copyTask(current)
+	}
+	r.mu.RUnlock()
+	if task == nil {
+		return
+	}
+
+	if err := store.SaveTask(ctx, task); err != nil {
+		r.log(nil).Error("Failed to persist task", "task", taskID, "error", err)
+	}
+}
+
+// persistDelegationChain writes the delegation chain with the given key to the
+// task store. Like persistTask, it must be called without r.mu held.
+func (r *Runner) persistDelegationChain(ctx context.Context, key string) {
+	lock := r.storeLock("chain:" + key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	r.mu.RLock()
+	store := r.taskStore
+	chain := append([]string(nil), r.delegationChains[key]...)
+	r.mu.RUnlock()
+	if store == nil {
+		return
+	}
+
+	if err := store.SaveDelegationChain(ctx, key, chain); err != nil {
+		r.log(nil).Error("Failed to persist delegation chain", "key", key, "error", err)
+	}
+}
+
+// storeLock returns the lock serializing the writes of a task or chain to the
+// task store. As each write copies the latest state under it, the store is
+// never left with an older state than the runner's.
+func (r *Runner) storeLock(key string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return &r.storeLocks[h.Sum32()%uint32(len(r.storeLocks))]
+}
+
+// copyTask returns a copy of a task sharing none of its slices or maps, so that
+// it can be written while the runner goes on updating the task
+func copyTask(task *TaskContext) *TaskContext {
+	snapshot := *task
+	snapshot.RelatedTaskIDs = append([]string(nil), task.RelatedTaskIDs...)
+	snapshot.InteractionHistory = append([]Interaction(nil), task.InteractionHistory...)
+	snapshot.StatusHistory = append([]TaskTransition(nil), task.StatusHistory...)
+	if task.WorkingContext != nil {
+		working := *task.WorkingContext
+		working.Versions = append([]ArtifactVersion(nil), task.WorkingContext.Versions...)
+		working.Metadata = make(map[string]interface{}, len(task.WorkingContext.Metadata))
+		for key, value := range task.WorkingContext.Metadata {
+			working.Metadata[key] = value
+		}
+		snapshot.WorkingContext = &working
+	}
+	return &snapshot
+}
@@ -0,0 +1,88 @@
+package runner
+
+import (
+	"context"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+)
+
+// TerminalOutput declares which delegated task produces the deliverable of an
+// orchestrated run. Orchestrators often end their loop with a handoff or a tool
+// call instead of a message, leaving FinalOutput empty; when they do, the runner
+// uses the result of the declared task, or its artifact if it has no result.
+type TerminalOutput struct {
+	// Agent is the name of the agent whose most recently completed task is the
+	// deliverable
+	Agent string
+
+	// TaskID selects a specific task instead of the latest one of Agent
+	TaskID string
+
+	// Override replaces a FinalOutput the orchestrator did produce
+	Override bool
+}
+
+// promoteTerminalOutput sets the final output of a run from its terminal task.
+// Only tasks of the run completed since it started are considered.
+func (r *Runner) promoteTerminalOutput(ctx context.Context, runResult *result.RunResult, opts *RunOptions, since time.Time) {
+	if opts.RunConfig == nil || opts.RunConfig.TerminalOutput == nil {
+		return
+	}
+	terminal := opts.RunConfig.TerminalOutput
+	if !terminal.Override && !emptyOutput(runResult.FinalOutput) {
+		return
+	}
+
+	task := r.terminalTask(ctx, terminal, since)
+	if task == nil {
+		r.log(nil).Debug("No completed terminal task, final output unchanged", "agent", terminal.Agent)
+		return
+	}
+
+	deliverable := task.Result
+	if emptyOutput(deliverable) && task.WorkingContext != nil {
+		deliverable = task.WorkingContext.Artifact
+	}
+	if !emptyOutput(deliverable) {
+		runResult.FinalOutput = deliverable
+	}
+}
+
+// terminalTask returns the completed task declared by a terminal output
+func (r *Runner) terminalTask(ctx context.Context, terminal *TerminalOutput, since time.Time) *TaskContext {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if terminal.TaskID != "" {
+		task, ok := r.taskRegistry[terminal.TaskID]
+		if !ok || task.Status != TaskStatusCompleted {
+			return nil
+		}
+		return task
+	}
+
+	runID := taskRunID(ctx)
+	var latest *TaskContext
+	for _, task := range r.taskRegistry {
+		if task.ChildAgentName != terminal.Agent || task.RunID != runID || task.Status != TaskStatusCompleted || task.CompletedAt == nil {
+			continue
+		}
+		if task.CompletedAt.Before(since) {
+			continue
+		}
+		if latest == nil || task.CompletedAt.After(*latest.CompletedAt) {
+			latest = task
+		}
+	}
+	return latest
+}
+
+// emptyOutput reports whether an output is missing or an empty string
+func emptyOutput(output interface{}) bool {
+	if output == nil {
+		return true
+	}
+	text, ok := output.(string)
+	return ok && text == ""
+}
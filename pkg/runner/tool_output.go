@@ -0,0 +1,30 @@
+package runner
+
+import (
+	"context"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+)
+
+// toolOutputListenerKey is the context key of the function that is told about
+// output tools produce while they run
+type toolOutputListenerKey struct{}
+
+// withToolOutputListener returns a context whose tool output is passed to fn
+// with the call that produced it
+func withToolOutputListener(ctx context.Context, fn func(tc model.ToolCall, chunk string)) context.Context {
+	return context.WithValue(ctx, toolOutputListenerKey{}, fn)
+}
+
+// withToolCallOutput returns the context to execute a tool call in, passing the
+// output of the tool to the listener of the context, if there is one
+func withToolCallOutput(ctx context.Context, tc model.ToolCall) context.Context {
+	fn, ok := ctx.Value(toolOutputListenerKey{}).(func(tc model.ToolCall, chunk string))
+	if !ok {
+		return ctx
+	}
+	return tool.WithOutputListener(ctx, func(chunk string) {
+		fn(tc, chunk)
+	})
+}
@@ -26,4 +26,15 @@ type WorkflowState struct {
 	LastCheckpoint time.Time
 	// Metadata is additional workflow metadata
 	Metadata map[string]interface{}
+	// PhaseStartedAt is when the workflow entered the current phase
+	PhaseStartedAt time.Time
+	// SLABreaches are the phases that exceeded their time budget
+	SLABreaches []SLABreach
+	// CheckpointID identifies the checkpoint the state was saved as, such as
+	// "3-review-turn2"
+	CheckpointID string
+	// CheckpointSequence counts the checkpoints of the workflow
+	CheckpointSequence int
+	// Compensations record the phases rolled back after the workflow failed
+	Compensations []CompensationResult
 }
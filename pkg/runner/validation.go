@@ -0,0 +1,249 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/guardrail"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+)
+
+// DefaultValidationAttempts is the number of times a task is delegated when its
+// result validation sets no limit
+const DefaultValidationAttempts = 3
+
+// validationAttemptsKey is the task metadata key counting rejected results
+const validationAttemptsKey = "validation_attempts"
+
+// ResultCheck is the result of a delegated task, validated before it is returned
+// to the delegator
+type ResultCheck struct {
+	// TaskID is the ID of the delegated task
+	TaskID string
+
+	// Delegator and Executor are the names of the delegating and executing agents
+	Delegator string
+	Executor  string
+
+	// Task describes what was delegated
+	Task string
+
+	// Result is the result the executor returned
+	Result interface{}
+
+	// Attempt is the number of the attempt, starting at 1
+	Attempt int
+
+	runner *Runner
+	opts   *RunOptions
+}
+
+// ResultValidator checks the result of a delegated task. It returns feedback
+// explaining what is wrong with the result, or an empty string to accept it.
+type ResultValidator func(ctx context.Context, check *ResultCheck) (string, error)
+
+// ResultValidation validates the results executors return to their delegator, so
+// that the orchestrator's prompt does not have to. A rejected result is sent back
+// to the executor with the validation feedback until MaxAttempts is reached:
+//
+//	config.ResultValidation = []*runner.ResultValidation{
+//		runner.ValidateResults("Coder", runner.JudgeValidator(reviewer)).WithMaxAttempts(2),
+//	}
+type ResultValidation struct {
+	// Executor is the name of the agent whose results are validated, or empty for
+	// all executors
+	Executor string
+
+	// Validators check the result in order; the first rejection counts
+	Validators []ResultValidator
+
+	// MaxAttempts is the number of times the task is worked on before it fails,
+	// DefaultValidationAttempts if zero
+	MaxAttempts int
+}
+
+// ValidateResults creates a validation of the results of an executor
+func ValidateResults(executor string, validators ...ResultValidator) *ResultValidation {
+	return &ResultValidation{Executor: executor, Validators: validators}
+}
+
+// WithMaxAttempts sets the number of times the task is worked on before it fails
+func (v *ResultValidation) WithMaxAttempts(attempts int) *ResultValidation {
+	v.MaxAttempts = attempts
+	return v
+}
+
+// ResultValidationError is the error of a task whose results were rejected on
+// every attempt
+type ResultValidationError struct {
+	TaskID   string
+	Executor string
+	Attempts int
+	Feedback string
+}
+
+// Error implements the error interface
+func (e *ResultValidationError) Error() string {
+	return fmt.Sprintf("result of task %s by %s rejected after %d attempts: %s", e.TaskID, e.Executor, e.Attempts, e.Feedback)
+}
+
+// SchemaValidator accepts results that match a JSON schema. String results are
+// decoded as JSON first.
+func SchemaValidator(schema map[string]interface{}) ResultValidator {
+	return func(ctx context.Context, check *ResultCheck) (string, error) {
+		value := check.Result
+		if text, ok := value.(string); ok {
+			if err := json.Unmarshal([]byte(text), &value); err != nil {
+				return fmt.Sprintf("the result must be valid JSON: %v", err), nil
+			}
+		}
+		if problems := tool.ValidateValue("result", value, schema); len(problems) > 0 {
+			return "the result does not match the expected schema: " + strings.Join(problems, "; "), nil
+		}
+		return "", nil
+	}
+}
+
+// GuardrailValidator accepts results that do not trip an output guardrail
+func GuardrailValidator(g guardrail.OutputGuardrail) ResultValidator {
+	return func(ctx context.Context, check *ResultCheck) (string, error) {
+		res, err := g.CheckOutput(ctx, check.Result)
+		if err != nil {
+			return "", fmt.Errorf("guardrail %s failed: %w", g.Name(), err)
+		}
+		if res == nil || !res.TripwireTriggered {
+			return "", nil
+		}
+		if res.Message == "" {
+			return fmt.Sprintf("the result was rejected by %s", g.Name()), nil
+		}
+		return res.Message, nil
+	}
+}
+
+// JudgeValidator asks a judge agent whether the result completes the task. The
+// judge must reply PASS to accept it, or FAIL followed by its feedback.
+func JudgeValidator(judge *agent.Agent) ResultValidator {
+	return func(ctx context.Context, check *ResultCheck) (string, error) {
+		prompt := fmt.Sprintf("Task given to %s:\n%s\n\nResult:\n%s\n\nReply PASS if the result completes the task. "+
+			"Otherwise reply FAIL: followed by what must be fixed.", check.Executor, check.Task, guardrail.Text(check.Result))
+
+		judgeOpts := &RunOptions{Input: prompt, MaxTurns: DefaultMaxTurns}
+		if check.opts != nil {
+			judgeOpts.RunConfig = check.opts.RunConfig
+		}
+		verdict, err := check.runner.Run(ctx, judge, judgeOpts)
+		if err != nil {
+			return "", fmt.Errorf("judge %s failed: %w", judge.Name, err)
+		}
+
+		text := strings.TrimSpace(guardrail.Text(verdict.FinalOutput))
+		if strings.HasPrefix(strings.ToUpper(text), "PASS") {
+			return "", nil
+		}
+		feedback := strings.TrimSpace(strings.TrimLeft(strings.TrimPrefix(strings.TrimPrefix(text, "FAIL"), "fail"), ":"))
+		if feedback == "" {
+			feedback = "the judge rejected the result without feedback"
+		}
+		return feedback, nil
+	}
+}
+
+// resultValidation returns the validation of an executor's results
+func resultValidation(opts *RunOptions, executor string) *ResultValidation {
+	if opts == nil || opts.RunConfig == nil {
+		return nil
+	}
+	for _, validation := range opts.RunConfig.ResultValidation {
+		if validation.Executor == "" || validation.Executor == executor {
+			return validation
+		}
+	}
+	return nil
+}
+
+// validateResult checks the result an executor returns for a task. It returns
+// the input to send the executor when the task is delegated again, or nil when
+// the result is accepted. A task rejected too often fails with a
+// *ResultValidationError, which is returned to the delegator in place of the result.
+func (r *Runner) validateResult(ctx context.Context, executor AgentType, task *TaskContext, result interface{}, opts *RunOptions) (retry interface{}, failure *ResultValidationError, err error) {
+	validation := resultValidation(opts, executor.Name)
+	if validation == nil || len(validation.Validators) == 0 {
+		return nil, nil, nil
+	}
+
+	attempt := r.validationAttempts(task.TaskID) + 1
+	r.mu.RLock()
+	description := taskDescription(task)
+	r.mu.RUnlock()
+
+	check := &ResultCheck{
+		TaskID:    task.TaskID,
+		Delegator: task.ParentAgentName,
+		Executor:  executor.Name,
+		Task:      description,
+		Result:    result,
+		Attempt:   attempt,
+		runner:    r,
+		opts:      opts,
+	}
+
+	for _, validate := range validation.Validators {
+		feedback, err := validate(ctx, check)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to validate result of task %s: %w", task.TaskID, err)
+		}
+		if feedback == "" {
+			continue
+		}
+
+		r.log(executor).Debug("Result rejected", "task", task.TaskID, "executor", executor.Name, "attempt", attempt, "feedback", feedback)
+		r.addTaskMetadata(ctx, task.TaskID, validationAttemptsKey, attempt)
+		r.addTaskInteraction(ctx, task.TaskID, "validator", feedback)
+
+		maxAttempts := validation.MaxAttempts
+		if maxAttempts <= 0 {
+			maxAttempts = DefaultValidationAttempts
+		}
+		if attempt >= maxAttempts {
+			return nil, &ResultValidationError{TaskID: task.TaskID, Executor: executor.Name, Attempts: attempt, Feedback: feedback}, nil
+		}
+
+		return fmt.Sprintf("Your result for this task was rejected and the task is delegated to you again.\n\n"+
+			"Task:\n%s\n\nYour result:\n%s\n\nFeedback:\n%s\n\nFix the result and return it to the delegator.",
+			check.Task, guardrail.Text(result), feedback), nil, nil
+	}
+	return nil, nil, nil
+}
+
+// validationAttempts returns the number of rejected results of a task
+func (r *Runner) validationAttempts(taskID string) int {
+	task := r.getTask(taskID)
+	if task == nil {
+		return 0
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	// Tasks loaded from a store decode numbers as float64
+	switch n := task.GetMetadata(validationAttemptsKey).(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	}
+	return 0
+}
+
+// taskDescription returns what was delegated in a task: the first message of the
+// delegator, or the description
+func taskDescription(task *TaskContext) string {
+	for _, interaction := range task.InteractionHistory {
+		if interaction.Role == task.ParentAgentName {
+			return guardrail.Text(interaction.Content)
+		}
+	}
+	return task.TaskDescription
+}
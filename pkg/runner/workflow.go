@@ -3,10 +3,11 @@ package runner
 import (
 	"context"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
 )
 
@@ -24,11 +25,23 @@ func NewWorkflowRunner(baseRunner *Runner, config *WorkflowConfig) *WorkflowRunn
 	}
 }
 
+// defaultWorkflowID is used when the state management config does not name the workflow
+const defaultWorkflowID = "default"
+
 // workflowHooks implements RunHooks with workflow-specific behavior
 type workflowHooks struct {
 	baseHooks      RunHooks
 	workflowConfig *WorkflowConfig
 	state          *WorkflowState
+	saveState      func(state *WorkflowState, point string) error
+
+	// fallbackAgent is the agent of the phase an SLA breach skipped to
+	fallbackAgent string
+
+	// stepAttempts counts the rejected outputs of each agent
+	stepAttempts map[string]int
+
+	log logging.Logger
 }
 
 func (wh *workflowHooks) OnRunStart(ctx context.Context, agent *agent.Agent, input interface{}) error {
@@ -39,6 +52,20 @@ func (wh *workflowHooks) OnRunStart(ctx context.Context, agent *agent.Agent, inp
 }
 
 func (wh *workflowHooks) OnTurnStart(ctx context.Context, agent *agent.Agent, turn int) error {
+	entered, err := wh.enterPhase(agent.Name)
+	if err != nil {
+		return err
+	}
+	// Checkpoint the start of every phase, so the workflow can be restored to it
+	if entered && wh.persists() {
+		if err := wh.saveState(wh.state, checkpointStart); err != nil {
+			return fmt.Errorf("failed to checkpoint workflow state: %w", err)
+		}
+	}
+	if err := wh.checkPhaseBudget(ctx, agent.Name); err != nil {
+		return err
+	}
+
 	if wh.baseHooks != nil {
 		return wh.baseHooks.OnTurnStart(ctx, agent, turn)
 	}
@@ -46,6 +73,15 @@ func (wh *workflowHooks) OnTurnStart(ctx context.Context, agent *agent.Agent, tu
 }
 
 func (wh *workflowHooks) OnTurnEnd(ctx context.Context, agent *agent.Agent, turn int, result *SingleTurnResult) error {
+	// Checkpoint the workflow state once the checkpoint frequency has elapsed
+	if wh.persists() {
+		if time.Since(wh.state.LastCheckpoint) >= wh.workflowConfig.StateManagement.CheckpointFrequency {
+			if err := wh.saveState(wh.state, fmt.Sprintf("turn%d", turn)); err != nil {
+				return fmt.Errorf("failed to checkpoint workflow state: %w", err)
+			}
+		}
+	}
+
 	if wh.baseHooks != nil {
 		return wh.baseHooks.OnTurnEnd(ctx, agent, turn, result)
 	}
@@ -53,6 +89,13 @@ func (wh *workflowHooks) OnTurnEnd(ctx context.Context, agent *agent.Agent, turn
 }
 
 func (wh *workflowHooks) OnRunEnd(ctx context.Context, result *result.RunResult) error {
+	// Save the final workflow state
+	if wh.saveState != nil {
+		if err := wh.saveState(wh.state, checkpointEnd); err != nil {
+			return fmt.Errorf("failed to save workflow state: %w", err)
+		}
+	}
+
 	if wh.baseHooks != nil {
 		return wh.baseHooks.OnRunEnd(ctx, result)
 	}
@@ -73,6 +116,118 @@ func (wh *workflowHooks) OnAfterHandoff(ctx context.Context, agent AgentType, ha
 	return nil
 }
 
+// persists reports whether the workflow state is checkpointed during the run
+func (wh *workflowHooks) persists() bool {
+	sm := wh.workflowConfig.StateManagement
+	return sm != nil && sm.PersistState && wh.saveState != nil
+}
+
+// enterPhase moves the workflow state to the phase of the agent, checking the phase
+// transition validation rules first. It reports whether a new phase was entered.
+func (wh *workflowHooks) enterPhase(agentName string) (bool, error) {
+	phase := ""
+	for _, p := range wh.workflowConfig.Phases {
+		if p.Agent == agentName {
+			phase = p.Name
+			break
+		}
+	}
+	if phase == "" || phase == wh.state.CurrentPhase {
+		return false, nil
+	}
+
+	if vc := wh.workflowConfig.ValidationConfig; vc != nil {
+		for _, rule := range vc.PhaseTransitionValidation {
+			if err := applyValidationRule(wh.log, rule, wh.state); err != nil {
+				return false, fmt.Errorf("transition to phase %s: %w", phase, err)
+			}
+		}
+	}
+
+	wh.moveToPhase(phase)
+	return true, nil
+}
+
+// moveToPhase completes the current phase and starts the next one
+func (wh *workflowHooks) moveToPhase(phase string) {
+	if wh.state.CurrentPhase != "" {
+		wh.state.CompletedPhases = append(wh.state.CompletedPhases, wh.state.CurrentPhase)
+	}
+	wh.state.CurrentPhase = phase
+	wh.state.PhaseStartedAt = time.Now()
+}
+
+// applyPhaseSettings overrides model settings with the settings of the current
+// phase for an agent
+func (wh *workflowHooks) applyPhaseSettings(agentName string, settings *model.Settings) {
+	for _, p := range wh.workflowConfig.Phases {
+		if p.Name != wh.state.CurrentPhase {
+			continue
+		}
+		overrideSettings(settings, p.ModelSettings)
+		overrideSettings(settings, p.AgentSettings[agentName])
+	}
+}
+
+// overrideSettings copies the fields that are set in overrides to settings
+func overrideSettings(settings, overrides *model.Settings) {
+	if overrides == nil {
+		return
+	}
+	if overrides.Temperature != nil {
+		settings.Temperature = overrides.Temperature
+	}
+	if overrides.TopP != nil {
+		settings.TopP = overrides.TopP
+	}
+	if overrides.FrequencyPenalty != nil {
+		settings.FrequencyPenalty = overrides.FrequencyPenalty
+	}
+	if overrides.PresencePenalty != nil {
+		settings.PresencePenalty = overrides.PresencePenalty
+	}
+	if overrides.ToolChoice != nil {
+		settings.ToolChoice = overrides.ToolChoice
+	}
+	if overrides.ParallelToolCalls != nil {
+		settings.ParallelToolCalls = overrides.ParallelToolCalls
+	}
+	if overrides.MaxTokens != nil {
+		settings.MaxTokens = overrides.MaxTokens
+	}
+	if overrides.ResponseFormat != nil {
+		settings.ResponseFormat = overrides.ResponseFormat
+	}
+	if overrides.PromptCaching {
+		settings.PromptCaching = true
+	}
+	if overrides.PromptCacheKey != "" {
+		settings.PromptCacheKey = overrides.PromptCacheKey
+	}
+}
+
+// applyValidationRule runs a validation rule, returning an error for blocking failures
+func applyValidationRule(log logging.Logger, rule ValidationRule, data interface{}) error {
+	if rule.Validate == nil {
+		return nil
+	}
+
+	ok, err := rule.Validate(data)
+	if ok && err == nil {
+		return nil
+	}
+
+	message := rule.ErrorMessage
+	if message == "" && err != nil {
+		message = err.Error()
+	}
+	if rule.Severity == ValidationWarning {
+		log.Warn("Validation rule failed", "rule", rule.Name, "message", message)
+		return nil
+	}
+	return fmt.Errorf("validation rule %s failed: %s", rule.Name, message)
+}
+
 // RunWorkflow executes a workflow with the given options
 func (wr *WorkflowRunner) RunWorkflow(ctx context.Context, agent AgentType, opts *RunOptions) (*result.RunResult, error) {
 	if opts.WorkflowConfig == nil {
@@ -87,15 +242,30 @@ func (wr *WorkflowRunner) RunWorkflow(ctx context.Context, agent AgentType, opts
 		Metadata:        make(map[string]interface{}),
 	}
 
+	// Resume from a previously saved state if there is one
+	restored, err := wr.loadWorkflowState()
+	if err != nil {
+		return nil, err
+	}
+	if restored != nil {
+		state = restored
+	}
+
 	// Initialize workflow hooks
 	hooks := &workflowHooks{
 		baseHooks:      opts.Hooks,
 		workflowConfig: opts.WorkflowConfig,
 		state:          state,
+		saveState:      wr.saveWorkflowState,
+		log:            wr.log(agent),
 	}
 	opts.Hooks = hooks
 
-	return wr.runWorkflowWithRecovery(ctx, agent, opts)
+	runResult, err := wr.runWorkflowWithRecovery(ctx, agent, opts)
+	if err != nil {
+		return runResult, hooks.compensate(ctx, err)
+	}
+	return runResult, nil
 }
 
 // runWorkflowWithRecovery executes the workflow with recovery capabilities
@@ -144,13 +314,63 @@ func (wr *WorkflowRunner) isRetryableError(err error, retryableErrors []string)
 	return false
 }
 
-// saveWorkflowState saves the current workflow state
-func (r *WorkflowRunner) saveWorkflowState(state *WorkflowState) error {
-	if r.workflowConfig.StateManagement == nil || !r.workflowConfig.StateManagement.PersistState {
+// saveWorkflowState saves the current workflow state as a checkpoint of the
+// given point of the current phase, then prunes old checkpoints
+func (r *WorkflowRunner) saveWorkflowState(state *WorkflowState, point string) error {
+	if r.workflowConfig.StateManagement == nil || !r.workflowConfig.StateManagement.PersistState ||
+		r.workflowConfig.StateManagement.StateStore == nil {
 		return nil
 	}
 
-	return r.workflowConfig.StateManagement.StateStore.SaveState("default", state)
+	state.CheckpointSequence++
+	state.CheckpointID = checkpointID(state.CheckpointSequence, state.CurrentPhase, point)
+	state.LastCheckpoint = time.Now()
+	if err := r.workflowConfig.StateManagement.StateStore.SaveState(r.workflowID(), state); err != nil {
+		return err
+	}
+
+	// Pruning is housekeeping; the checkpoint itself was saved
+	if err := r.pruneCheckpoints(r.workflowID(), state.CheckpointID); err != nil {
+		r.log(nil).Warn("Failed to prune checkpoints", "workflow", r.workflowID(), "error", err)
+	}
+	return nil
+}
+
+// loadWorkflowState loads the last saved workflow state, if any
+func (r *WorkflowRunner) loadWorkflowState() (*WorkflowState, error) {
+	sm := r.workflowConfig.StateManagement
+	if sm == nil || !sm.PersistState || sm.StateStore == nil {
+		return nil, nil
+	}
+
+	saved, err := sm.StateStore.LoadState(r.workflowID())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load workflow state: %w", err)
+	}
+
+	state, ok := saved.(*WorkflowState)
+	if !ok || state == nil {
+		return nil, nil
+	}
+	if state.Artifacts == nil {
+		state.Artifacts = make(map[string]interface{})
+	}
+	if state.Metadata == nil {
+		state.Metadata = make(map[string]interface{})
+	}
+	// States saved before phases were timed restart the clock of their phase
+	if state.PhaseStartedAt.IsZero() {
+		state.PhaseStartedAt = time.Now()
+	}
+	return state, nil
+}
+
+// workflowID returns the ID under which the workflow state is stored
+func (r *WorkflowRunner) workflowID() string {
+	if sm := r.workflowConfig.StateManagement; sm != nil && sm.WorkflowID != "" {
+		return sm.WorkflowID
+	}
+	return defaultWorkflowID
 }
 
 // attemptRecovery attempts to recover from a panic
@@ -159,13 +379,10 @@ func (r *WorkflowRunner) attemptRecovery(ctx context.Context, agent *agent.Agent
 		return fmt.Errorf("recovery not configured")
 	}
 
-	// Log recovery attempt
-	if os.Getenv("DEBUG") == "1" {
-		fmt.Printf("Attempting recovery from panic: %v\n", rec)
-	}
+	r.log(agent).Warn("Attempting recovery from panic", "panic", rec)
 
 	// Save state before recovery attempt
-	if err := r.saveWorkflowState(state); err != nil {
+	if err := r.saveWorkflowState(state, checkpointRecovery); err != nil {
 		return fmt.Errorf("failed to save state before recovery: %w", err)
 	}
 
@@ -0,0 +1,48 @@
+package runner
+
+import (
+	"context"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/workspace"
+)
+
+// acquireWorkspace provisions the workspace of a run when the run config has a
+// workspace manager, returning a context carrying it
+func (r *Runner) acquireWorkspace(ctx context.Context, runID string, opts *RunOptions) (context.Context, *workspace.Workspace, error) {
+	if opts.RunConfig == nil || opts.RunConfig.Workspace == nil {
+		return ctx, nil, nil
+	}
+	ws, err := opts.RunConfig.Workspace.Acquire(runID)
+	if err != nil {
+		return ctx, nil, err
+	}
+	r.log(nil).Debug("Provisioned run workspace", "run", runID, "path", ws.Path())
+	return workspace.WithWorkspace(ctx, ws), ws, nil
+}
+
+// releaseWorkspace removes the workspace of a finished run, recording its archive
+// in the result. Workspaces that cannot be released are logged and do not fail
+// the run.
+func (r *Runner) releaseWorkspace(ctx context.Context, ws *workspace.Workspace, opts *RunOptions, runResult *result.RunResult) {
+	if ws == nil {
+		return
+	}
+	archived, err := opts.RunConfig.Workspace.Release(context.WithoutCancel(ctx), ws)
+	if archived != nil && runResult != nil {
+		runResult.WorkspaceArchive = archived.ID
+	}
+	if err != nil {
+		r.log(nil).Warn("Failed to release run workspace", "run", ws.RunID(), "path", ws.Path(), "error", err)
+	}
+}
+
+// checkWorkspaceQuota returns an error if the workspace of the run exceeds its
+// quota
+func checkWorkspaceQuota(ctx context.Context) error {
+	ws := workspace.FromContext(ctx)
+	if ws == nil {
+		return nil
+	}
+	return ws.CheckQuota(0)
+}
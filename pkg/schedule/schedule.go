@@ -0,0 +1,596 @@
+// Package schedule runs agents and workflows on intervals or cron expressions,
+// for agents that monitor systems or generate reports.
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/workflow"
+)
+
+var (
+	// ErrJobNotFound is returned for jobs that are not registered
+	ErrJobNotFound = errors.New("scheduled job not found")
+
+	// ErrJobExists is returned when registering a job under a taken name
+	ErrJobExists = errors.New("scheduled job already exists")
+
+	// ErrJobRunning is returned by RunNow when the job is running and its
+	// overlap policy does not allow another run
+	ErrJobRunning = errors.New("scheduled job is already running")
+)
+
+// Task is a job that runs Go code
+type Task func(ctx context.Context) error
+
+// OverlapPolicy decides what happens when a job is due while it is still running
+type OverlapPolicy int
+
+const (
+	// OverlapSkip skips the run that is due
+	OverlapSkip OverlapPolicy = iota
+
+	// OverlapQueue runs the job once more after the current run. Runs due
+	// while one is already queued are skipped.
+	OverlapQueue
+
+	// OverlapAllow starts the run concurrently
+	OverlapAllow
+)
+
+// JobState is the run history of a job
+type JobState struct {
+	// Name is the name of the job
+	Name string
+
+	// LastRun is when the job last started
+	LastRun time.Time
+
+	// LastSuccess is when a run of the job last succeeded
+	LastSuccess time.Time
+
+	// LastError is the error of the last run, if it failed
+	LastError string
+
+	// Runs is how many times the job ran
+	Runs int
+
+	// Failures is how many runs failed
+	Failures int
+
+	// Skipped is how many due runs were skipped because the job was running
+	Skipped int
+
+	// NextRun is when the job is due next, while the scheduler is started
+	NextRun time.Time
+}
+
+// Failure describes a failed run, for failure hooks
+type Failure struct {
+	// Job is the name of the job
+	Job string
+
+	// StartedAt is when the run started
+	StartedAt time.Time
+
+	// Err is the error of the run
+	Err error
+
+	// State is the state of the job after the run
+	State JobState
+}
+
+// JobOption configures a job
+type JobOption func(*job)
+
+// WithInput sets the input of an agent or workflow job
+func WithInput(input interface{}) JobOption {
+	return func(j *job) {
+		j.input = func(time.Time) interface{} { return input }
+	}
+}
+
+// WithInputFunc builds the input of an agent or workflow job from the time of
+// the run, such as a report period
+func WithInputFunc(fn func(at time.Time) interface{}) JobOption {
+	return func(j *job) {
+		j.input = fn
+	}
+}
+
+// WithOverlap sets what happens when the job is due while it is still running.
+// The default is OverlapSkip.
+func WithOverlap(policy OverlapPolicy) JobOption {
+	return func(j *job) {
+		j.overlap = policy
+	}
+}
+
+// WithTimeout limits how long a run of the job may take
+func WithTimeout(timeout time.Duration) JobOption {
+	return func(j *job) {
+		j.timeout = timeout
+	}
+}
+
+// WithCatchUp runs the job as soon as the scheduler starts if a run was missed
+// while it was stopped, according to the persisted state
+func WithCatchUp() JobOption {
+	return func(j *job) {
+		j.catchUp = true
+	}
+}
+
+// job is a registered job
+type job struct {
+	name     string
+	schedule Schedule
+	task     Task
+	input    func(time.Time) interface{}
+	overlap  OverlapPolicy
+	timeout  time.Duration
+	catchUp  bool
+
+	state   JobState
+	running int
+	queued  bool
+}
+
+// Scheduler runs jobs on their schedules
+type Scheduler struct {
+	runner     *runner.Runner
+	runOptions *runner.RunOptions
+	store      runner.WorkflowStateStore
+	location   *time.Location
+	onFailure  func(Failure)
+
+	jobs map[string]*job
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+}
+
+// NewScheduler creates a scheduler without jobs
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		runner:   runner.NewRunner(),
+		location: time.Local,
+		jobs:     make(map[string]*job),
+	}
+}
+
+// WithRunner sets the runner of agent jobs
+func (s *Scheduler) WithRunner(r *runner.Runner) *Scheduler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runner = r
+	return s
+}
+
+// WithRunOptions sets the options agent jobs run with. Their input is replaced
+// by the input of the job.
+func (s *Scheduler) WithRunOptions(opts *runner.RunOptions) *Scheduler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runOptions = opts
+	return s
+}
+
+// WithStateStore persists the state of the jobs, so their history survives
+// restarts. Add loads the saved state of each job.
+func (s *Scheduler) WithStateStore(store runner.WorkflowStateStore) *Scheduler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store = store
+	return s
+}
+
+// WithLocation sets the time zone cron expressions are evaluated in. The
+// default is the local time zone.
+func (s *Scheduler) WithLocation(loc *time.Location) *Scheduler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.location = loc
+	return s
+}
+
+// OnFailure sets a function called after each failed run
+func (s *Scheduler) OnFailure(fn func(Failure)) *Scheduler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onFailure = fn
+	return s
+}
+
+// Add registers a job. The target is an *agent.Agent, a *workflow.Workflow, a
+// *workflow.Graph, a *workflow.Parallel or a Task.
+func (s *Scheduler) Add(name string, schedule Schedule, target interface{}, opts ...JobOption) error {
+	if schedule == nil {
+		return fmt.Errorf("job %q has no schedule", name)
+	}
+	j := &job{name: name, schedule: schedule, state: JobState{Name: name}}
+	for _, opt := range opts {
+		opt(j)
+	}
+
+	input := func(at time.Time) interface{} {
+		if j.input == nil {
+			return nil
+		}
+		return j.input(at)
+	}
+	switch t := target.(type) {
+	case Task:
+		j.task = t
+	case func(ctx context.Context) error:
+		j.task = t
+	case *agent.Agent:
+		j.task = func(ctx context.Context) error {
+			s.mu.Lock()
+			r, runOptions := s.runner, s.runOptions
+			s.mu.Unlock()
+			opts := &runner.RunOptions{}
+			if runOptions != nil {
+				*opts = *runOptions
+			}
+			opts.Input = input(time.Now())
+			_, err := r.Run(ctx, t, opts)
+			return err
+		}
+	case *workflow.Workflow:
+		j.task = func(ctx context.Context) error {
+			_, err := t.Run(ctx, input(time.Now()))
+			return err
+		}
+	case *workflow.Graph:
+		j.task = func(ctx context.Context) error {
+			_, err := t.Run(ctx, input(time.Now()))
+			return err
+		}
+	case *workflow.Parallel:
+		j.task = func(ctx context.Context) error {
+			_, err := t.Run(ctx, input(time.Now()))
+			return err
+		}
+	default:
+		return fmt.Errorf("job %q must be an *agent.Agent, a workflow or a Task, not %T", name, target)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.jobs[name]; exists {
+		return fmt.Errorf("%w: %s", ErrJobExists, name)
+	}
+	if s.store != nil {
+		if err := j.restore(s.store); err != nil {
+			return err
+		}
+	}
+	s.jobs[name] = j
+	return nil
+}
+
+// Remove unregisters a job. Runs in progress finish.
+func (s *Scheduler) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.jobs[name]; !exists {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	delete(s.jobs, name)
+	return nil
+}
+
+// State returns the state of a job
+func (s *Scheduler) State(name string) (JobState, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return JobState{}, false
+	}
+	return j.state, true
+}
+
+// States returns the state of every job, sorted by name
+func (s *Scheduler) States() []JobState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	states := make([]JobState, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		states = append(states, j.state)
+	}
+	sort.Slice(states, func(i, k int) bool { return states[i].Name < states[k].Name })
+	return states
+}
+
+// RunNow runs a job right away and waits for it, outside of its schedule. It
+// follows the job's overlap policy, except that it never queues.
+func (s *Scheduler) RunNow(ctx context.Context, name string) error {
+	s.mu.Lock()
+	j, ok := s.jobs[name]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	if j.running > 0 && j.overlap != OverlapAllow {
+		s.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrJobRunning, name)
+	}
+	j.running++
+	s.wg.Add(1)
+	s.mu.Unlock()
+	defer s.wg.Done()
+
+	return s.execute(ctx, j)
+}
+
+// Start runs the jobs on their schedules until the returned function is called
+// or the context is cancelled. Jobs added later are picked up within a second.
+// Stopping cancels runs in progress and waits for them to return.
+func (s *Scheduler) Start(ctx context.Context) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		loops := make(map[*job]struct{})
+		var wg sync.WaitGroup
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for {
+			s.mu.Lock()
+			for _, j := range s.jobs {
+				if _, started := loops[j]; !started {
+					loops[j] = struct{}{}
+					wg.Add(1)
+					go func(j *job) {
+						defer wg.Done()
+						s.loop(ctx, j)
+					}(j)
+				}
+			}
+			s.mu.Unlock()
+
+			select {
+			case <-ctx.Done():
+				wg.Wait()
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cancel()
+			<-done
+			s.wg.Wait()
+		})
+	}
+}
+
+// loop triggers a job whenever it is due, until the job is removed or the
+// context ends
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	s.mu.Lock()
+	loc := s.location
+	last := j.state.LastRun
+	s.mu.Unlock()
+
+	now := time.Now().In(loc)
+	next := j.schedule.Next(now)
+	if j.catchUp && !last.IsZero() {
+		if missed := j.schedule.Next(last.In(loc)); !missed.IsZero() && !missed.After(now) {
+			next = now
+		}
+	}
+
+	for !next.IsZero() {
+		s.mu.Lock()
+		if s.jobs[j.name] != j {
+			s.mu.Unlock()
+			return
+		}
+		j.state.NextRun = next
+		s.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.trigger(ctx, j)
+		next = j.schedule.Next(time.Now().In(loc))
+	}
+}
+
+// trigger starts a due run of a job according to its overlap policy
+func (s *Scheduler) trigger(ctx context.Context, j *job) {
+	s.mu.Lock()
+	if s.jobs[j.name] != j {
+		s.mu.Unlock()
+		return
+	}
+	if j.running > 0 {
+		switch {
+		case j.overlap == OverlapQueue && !j.queued:
+			j.queued = true
+			s.mu.Unlock()
+			return
+		case j.overlap != OverlapAllow:
+			j.state.Skipped++
+			state := j.state
+			s.mu.Unlock()
+			logging.For(nil, "schedule").Debug("Skipped overlapping run", "job", j.name)
+			s.save(state)
+			return
+		}
+	}
+	j.running++
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	go func() {
+		defer s.wg.Done()
+		for {
+			_ = s.execute(ctx, j)
+
+			s.mu.Lock()
+			if !j.queued || ctx.Err() != nil {
+				j.queued = false
+				s.mu.Unlock()
+				return
+			}
+			j.queued = false
+			j.running++
+			s.mu.Unlock()
+		}
+	}()
+}
+
+// execute runs a job once and records the outcome. The caller has counted the
+// run as running.
+func (s *Scheduler) execute(ctx context.Context, j *job) error {
+	started := time.Now()
+	s.mu.Lock()
+	j.state.LastRun = started
+	j.state.Runs++
+	s.mu.Unlock()
+
+	runCtx := ctx
+	if j.timeout > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(ctx, j.timeout)
+		defer cancel()
+	}
+	err := runTask(runCtx, j.task)
+
+	s.mu.Lock()
+	j.running--
+	if err != nil {
+		j.state.Failures++
+		j.state.LastError = err.Error()
+	} else {
+		j.state.LastSuccess = time.Now()
+		j.state.LastError = ""
+	}
+	state := j.state
+	onFailure := s.onFailure
+	s.mu.Unlock()
+
+	s.save(state)
+	if err != nil {
+		err = fmt.Errorf("scheduled job %s failed: %w", j.name, err)
+		if onFailure != nil {
+			onFailure(Failure{Job: j.name, StartedAt: started, Err: err, State: state})
+		} else {
+			logging.For(nil, "schedule").Warn("Scheduled job failed", "job", j.name, "error", err)
+		}
+	}
+	return err
+}
+
+// runTask runs a task, turning a panic into an error so one job cannot take
+// down the scheduler
+func runTask(ctx context.Context, task Task) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panicked: %v", p)
+		}
+	}()
+	return task(ctx)
+}
+
+// State keys of a persisted job
+const (
+	stateKind        = "kind"
+	scheduleKind     = "schedule"
+	stateLastSuccess = "last_success"
+	stateLastError   = "last_error"
+	stateRuns        = "runs"
+	stateFailures    = "failures"
+	stateSkipped     = "skipped"
+)
+
+// stateID is the ID a job's state is stored under
+func stateID(name string) string {
+	return "schedule/" + name
+}
+
+// save persists the state of a job
+func (s *Scheduler) save(state JobState) {
+	s.mu.Lock()
+	store := s.store
+	s.mu.Unlock()
+	if store == nil {
+		return
+	}
+
+	var lastSuccess string
+	if !state.LastSuccess.IsZero() {
+		lastSuccess = state.LastSuccess.Format(time.RFC3339Nano)
+	}
+	saved := &runner.WorkflowState{
+		CurrentPhase:   state.Name,
+		LastCheckpoint: state.LastRun,
+		Metadata: map[string]interface{}{
+			stateKind:        scheduleKind,
+			stateLastSuccess: lastSuccess,
+			stateLastError:   state.LastError,
+			stateRuns:        state.Runs,
+			stateFailures:    state.Failures,
+			stateSkipped:     state.Skipped,
+		},
+	}
+	if err := store.SaveState(stateID(state.Name), saved); err != nil {
+		logging.For(nil, "schedule").Warn("Failed to save job state", "job", state.Name, "error", err)
+	}
+}
+
+// restore loads the persisted state of a job
+func (j *job) restore(store runner.WorkflowStateStore) error {
+	saved, err := store.LoadState(stateID(j.name))
+	if err != nil {
+		return fmt.Errorf("failed to load state of job %s: %w", j.name, err)
+	}
+	state, ok := saved.(*runner.WorkflowState)
+	if !ok || state == nil || state.Metadata[stateKind] != scheduleKind {
+		return nil
+	}
+
+	j.state.LastRun = state.LastCheckpoint
+	if s, _ := state.Metadata[stateLastSuccess].(string); s != "" {
+		j.state.LastSuccess, _ = time.Parse(time.RFC3339Nano, s)
+	}
+	j.state.LastError, _ = state.Metadata[stateLastError].(string)
+	j.state.Runs = count(state.Metadata[stateRuns])
+	j.state.Failures = count(state.Metadata[stateFailures])
+	j.state.Skipped = count(state.Metadata[stateSkipped])
+	return nil
+}
+
+// count reads a counter that may have been decoded from JSON
+func count(value interface{}) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
@@ -0,0 +1,110 @@
+// Package scratchpad provides a key/value store shared by all agents of a run, so
+// cooperating agents share state without passing it through handoff input or
+// package-level variables.
+//
+// The runner puts the scratchpad of RunConfig.Scratchpad, or a new one, in the
+// run's context, where tools read and write it:
+//
+//	func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+//		pad := scratchpad.FromContext(ctx)
+//		pad.Set("phase", "optimize")
+//		...
+//	}
+//
+// Agents can use it themselves through the scratchpad_read and scratchpad_write
+// tools returned by Tools.
+package scratchpad
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Scratchpad is a key/value store that is safe for concurrent use
+type Scratchpad struct {
+	values map[string]interface{}
+	mu     sync.RWMutex
+}
+
+// New creates an empty scratchpad
+func New() *Scratchpad {
+	return &Scratchpad{values: make(map[string]interface{})}
+}
+
+// Get returns the value of a key
+func (s *Scratchpad) Get(key string) (interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.values[key]
+	return value, ok
+}
+
+// GetString returns the value of a key if it is a string, and an empty string
+// otherwise
+func (s *Scratchpad) GetString(key string) string {
+	value, _ := s.Get(key)
+	text, _ := value.(string)
+	return text
+}
+
+// Set sets the value of a key
+func (s *Scratchpad) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+}
+
+// Update sets the value of a key to the result of fn, which receives the current
+// value, atomically
+func (s *Scratchpad) Update(key string, fn func(value interface{}, ok bool) interface{}) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	value = fn(value, ok)
+	s.values[key] = value
+	return value
+}
+
+// Delete removes a key
+func (s *Scratchpad) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+}
+
+// Keys returns the keys in sorted order
+func (s *Scratchpad) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Snapshot returns a copy of the entries
+func (s *Scratchpad) Snapshot() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshot := make(map[string]interface{}, len(s.values))
+	for key, value := range s.values {
+		snapshot[key] = value
+	}
+	return snapshot
+}
+
+type scratchpadKey struct{}
+
+// WithScratchpad returns a context carrying the scratchpad
+func WithScratchpad(ctx context.Context, s *Scratchpad) context.Context {
+	return context.WithValue(ctx, scratchpadKey{}, s)
+}
+
+// FromContext returns the scratchpad of the context, or nil outside of a run
+func FromContext(ctx context.Context) *Scratchpad {
+	s, _ := ctx.Value(scratchpadKey{}).(*Scratchpad)
+	return s
+}
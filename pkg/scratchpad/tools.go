@@ -0,0 +1,81 @@
+package scratchpad
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+)
+
+const (
+	// ReadToolName is the name of the tool that reads the scratchpad
+	ReadToolName = "scratchpad_read"
+
+	// WriteToolName is the name of the tool that writes the scratchpad
+	WriteToolName = "scratchpad_write"
+)
+
+// ErrNoScratchpad is returned by the scratchpad tools outside of a run
+var ErrNoScratchpad = errors.New("no scratchpad in context")
+
+// readParams are the parameters of the scratchpad_read tool
+type readParams struct {
+	Key string `json:"key,omitempty" doc:"Key to read; all entries when empty"`
+}
+
+// writeParams are the parameters of the scratchpad_write tool
+type writeParams struct {
+	Key    string      `json:"key" doc:"Key to write"`
+	Value  interface{} `json:"value,omitempty" doc:"Value to store"`
+	Delete bool        `json:"delete,omitempty" doc:"Remove the key instead of writing it"`
+}
+
+// Tools returns the scratchpad_read and scratchpad_write tools, which read and
+// write the scratchpad of the run they are called in
+func Tools() []tool.Tool {
+	return []tool.Tool{ReadTool(), WriteTool()}
+}
+
+// ReadTool returns a tool that reads an entry, or all entries, of the run's
+// scratchpad
+func ReadTool() tool.Tool {
+	return tool.NewTypedTool(ReadToolName,
+		"Read a value from the scratchpad shared by all agents of this run, or every entry when no key is given",
+		func(ctx context.Context, params readParams) (interface{}, error) {
+			s := FromContext(ctx)
+			if s == nil {
+				return nil, ErrNoScratchpad
+			}
+			if params.Key == "" {
+				return s.Snapshot(), nil
+			}
+			value, ok := s.Get(params.Key)
+			if !ok {
+				return fmt.Sprintf("The scratchpad has no entry %q.", params.Key), nil
+			}
+			return value, nil
+		})
+}
+
+// WriteTool returns a tool that writes or deletes an entry of the run's
+// scratchpad
+func WriteTool() tool.Tool {
+	return tool.NewTypedTool(WriteToolName,
+		"Write a value to the scratchpad shared by all agents of this run, so other agents can read it",
+		func(ctx context.Context, params writeParams) (string, error) {
+			s := FromContext(ctx)
+			if s == nil {
+				return "", ErrNoScratchpad
+			}
+			if params.Key == "" {
+				return "", errors.New("key is required")
+			}
+			if params.Delete {
+				s.Delete(params.Key)
+				return fmt.Sprintf("Deleted %q.", params.Key), nil
+			}
+			s.Set(params.Key, params.Value)
+			return fmt.Sprintf("Stored %q.", params.Key), nil
+		})
+}
@@ -0,0 +1,156 @@
+// Package server exposes runners to other programs, over HTTP (see APIHandler)
+// or over JSON-RPC on standard input and output (see RPCServer).
+//
+// The admin API gives operators control over the runs in progress:
+//
+//	GET  /admin/runs               list active runs with their current agent and turn
+//	GET  /admin/runs/{id}          inspect the input and items of a run so far
+//	POST /admin/runs/{id}/cancel   cancel a run
+//	POST /admin/runs/{id}/complete force-complete a run with {"output": ...}
+//
+// Every request must pass the handler's Authenticator.
+//
+//	http.Handle("/admin/", server.NewAdminHandler(r, server.BearerToken(os.Getenv("ADMIN_TOKEN"))))
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+)
+
+// ErrUnauthorized is returned by authenticators that reject a request
+var ErrUnauthorized = errors.New("unauthorized")
+
+// Authenticator decides whether a request may use an endpoint. It returns nil to
+// allow the request.
+type Authenticator func(r *http.Request) error
+
+// BearerToken returns an authenticator accepting requests with the given token in
+// their Authorization header. An empty token rejects every request.
+func BearerToken(token string) Authenticator {
+	return func(r *http.Request) error {
+		given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			return ErrUnauthorized
+		}
+		return nil
+	}
+}
+
+// AdminHandler serves the admin API of a runner
+type AdminHandler struct {
+	runner *runner.Runner
+	auth   Authenticator
+	mux    *http.ServeMux
+}
+
+// NewAdminHandler creates an admin handler for the runs of a runner. A nil
+// authenticator rejects every request.
+func NewAdminHandler(r *runner.Runner, auth Authenticator) *AdminHandler {
+	h := &AdminHandler{runner: r, auth: auth, mux: http.NewServeMux()}
+	h.mux.HandleFunc("GET /admin/runs", h.listRuns)
+	h.mux.HandleFunc("GET /admin/runs/{id}", h.inspectRun)
+	h.mux.HandleFunc("POST /admin/runs/{id}/cancel", h.cancelRun)
+	h.mux.HandleFunc("POST /admin/runs/{id}/complete", h.completeRun)
+	return h
+}
+
+// ServeHTTP authenticates the request and dispatches it
+func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.auth == nil {
+		writeError(w, http.StatusUnauthorized, ErrUnauthorized)
+		return
+	}
+	if err := h.auth(r); err != nil {
+		writeError(w, http.StatusUnauthorized, err)
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// runItem is the JSON encoding of a run item
+type runItem struct {
+	Type string      `json:"type"`
+	Item interface{} `json:"item"`
+}
+
+// runDetail is the JSON encoding of a run snapshot
+type runDetail struct {
+	*runner.RunSnapshot
+	Items []runItem `json:"items"`
+}
+
+// listRuns lists the active runs
+func (h *AdminHandler) listRuns(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"runs": h.runner.ActiveRuns()})
+}
+
+// inspectRun returns the input and items of a run
+func (h *AdminHandler) inspectRun(w http.ResponseWriter, r *http.Request) {
+	snapshot, err := h.runner.InspectRun(r.PathValue("id"))
+	if err != nil {
+		writeRunError(w, err)
+		return
+	}
+
+	detail := runDetail{RunSnapshot: snapshot, Items: make([]runItem, 0, len(snapshot.Items))}
+	for _, item := range snapshot.Items {
+		detail.Items = append(detail.Items, runItem{Type: item.GetType(), Item: item})
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+// cancelRun cancels a run
+func (h *AdminHandler) cancelRun(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := h.runner.Cancel(id); err != nil {
+		writeRunError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"id": id, "status": "cancelling"})
+}
+
+// completeRun force-completes a run with the output in the request body
+func (h *AdminHandler) completeRun(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Output interface{} `json:"output"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+
+	id := r.PathValue("id")
+	if err := h.runner.CompleteRun(id, body.Output); err != nil {
+		writeRunError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"id": id, "status": "completing"})
+}
+
+// writeRunError writes the error of a run operation
+func writeRunError(w http.ResponseWriter, err error) {
+	if errors.Is(err, runner.ErrRunNotActive) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err)
+}
+
+// writeError writes an error response
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
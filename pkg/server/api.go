@@ -0,0 +1,438 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/server/stream"
+)
+
+// maxAPIRequestBytes limits the size of request bodies
+const maxAPIRequestBytes = 16 << 20
+
+// Middleware wraps the handler of the API, e.g. to log, rate limit or
+// authenticate requests
+type Middleware func(http.Handler) http.Handler
+
+// APIHandler serves agents as an HTTP service, making the SDK a backend for
+// existing chat frontends and other programs:
+//
+//	GET  /v1/models              list the agents, as OpenAI models
+//	POST /v1/chat/completions    run an agent on OpenAI chat messages, streamed with "stream": true
+//	POST /runs                   start a run in the background with {"agent", "input", "run_id"}
+//	GET  /runs/{id}              fetch the status and result of a run
+//	GET  /runs/{id}/events       stream the events of a run as server-sent events
+//	POST /runs/{id}/cancel       cancel a run
+//
+// The model of a chat completion names the agent to run. The agent of a run may
+// be omitted to run the first registered agent. The events of a run are replayed
+// from its start, or after the sequence number in a Last-Event-ID header, and
+// followed until the run ends; disconnecting does not stop the run. The runs API
+// is served by a RunService.
+type APIHandler struct {
+	runner     *runner.Runner
+	runs       *RunService
+	auth       Authenticator
+	middleware []Middleware
+	handler    http.Handler
+	mu         sync.Mutex
+}
+
+// NewAPIHandler creates an HTTP API for agents run by a runner. Requests are not
+// authenticated until an authenticator is set with WithAuth.
+func NewAPIHandler(r *runner.Runner, agents ...*agent.Agent) *APIHandler {
+	h := &APIHandler{runner: r, runs: NewRunService(r, agents...)}
+	h.build()
+	return h
+}
+
+// WithRunOptions sets the options of the handler's runs. Their input and run ID
+// are replaced by those of each request.
+func (h *APIHandler) WithRunOptions(opts *runner.RunOptions) *APIHandler {
+	h.runs.WithRunOptions(opts)
+	return h
+}
+
+// WithAuth sets the authenticator every request must pass
+func (h *APIHandler) WithAuth(auth Authenticator) *APIHandler {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.auth = auth
+	h.build()
+	return h
+}
+
+// WithMiddleware adds middleware around the API. The first middleware added is
+// the outermost; all of it runs before authentication.
+func (h *APIHandler) WithMiddleware(middleware ...Middleware) *APIHandler {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.middleware = append(h.middleware, middleware...)
+	h.build()
+	return h
+}
+
+// build assembles the routes, authentication and middleware of the handler
+func (h *APIHandler) build() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/models", h.listModels)
+	mux.HandleFunc("POST /v1/chat/completions", h.chatCompletions)
+	mux.HandleFunc("POST /runs", h.createRun)
+	mux.HandleFunc("GET /runs/{id}", h.getRun)
+	mux.HandleFunc("GET /runs/{id}/events", h.runEvents)
+	mux.HandleFunc("POST /runs/{id}/cancel", h.cancelRun)
+
+	var handler http.Handler = mux
+	if auth := h.auth; auth != nil {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := auth(r); err != nil {
+				writeError(w, http.StatusUnauthorized, err)
+				return
+			}
+			mux.ServeHTTP(w, r)
+		})
+	}
+	for i := len(h.middleware) - 1; i >= 0; i-- {
+		handler = h.middleware[i](handler)
+	}
+	h.handler = handler
+}
+
+// ServeHTTP dispatches a request through the middleware and authenticator
+func (h *APIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	handler := h.handler
+	h.mu.Unlock()
+	handler.ServeHTTP(w, r)
+}
+
+// chatMessage is a message of a chat completion request
+type chatMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// chatCompletionRequest is the body of a chat completion request. Other fields
+// of the OpenAI API are accepted and ignored.
+type chatCompletionRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+}
+
+// chatCompletion is the response to a chat completion request
+type chatCompletion struct {
+	ID      string       `json:"id"`
+	Object  string       `json:"object"`
+	Created int64        `json:"created"`
+	Model   string       `json:"model"`
+	Choices []chatChoice `json:"choices"`
+	Usage   *chatUsage   `json:"usage,omitempty"`
+}
+
+// chatChoice is a choice of a chat completion or chunk
+type chatChoice struct {
+	Index        int          `json:"index"`
+	Message      *chatContent `json:"message,omitempty"`
+	Delta        *chatContent `json:"delta,omitempty"`
+	FinishReason *string      `json:"finish_reason"`
+}
+
+// chatContent is the message of a choice, or the delta of a streamed choice
+type chatContent struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// chatUsage is the token usage of a chat completion
+type chatUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// chatInput converts chat messages to run input. A lone user message becomes a
+// string; conversations become a message list. System messages are dropped, as
+// the agent's instructions take their place, and so are tool messages.
+func chatInput(messages []chatMessage) (interface{}, error) {
+	var history []interface{}
+	var text, role string
+	for _, message := range messages {
+		if message.Role != "user" && message.Role != "assistant" {
+			continue
+		}
+		content, err := chatMessageText(message.Content)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s message: %w", message.Role, err)
+		}
+		history = append(history, map[string]interface{}{
+			"type":    "message",
+			"role":    message.Role,
+			"content": content,
+		})
+		text, role = content, message.Role
+	}
+	switch {
+	case len(history) == 0:
+		return nil, errors.New("messages must include a user message")
+	case len(history) == 1 && role == "user":
+		return text, nil
+	}
+	return history, nil
+}
+
+// chatMessageText returns the text of message content, a string or a list of
+// content parts
+func chatMessageText(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return "", errors.New("content must be a string or a list of content parts")
+	}
+	var b strings.Builder
+	for _, part := range parts {
+		if part.Type == "text" {
+			b.WriteString(part.Text)
+		}
+	}
+	return b.String(), nil
+}
+
+// listModels lists the agents as models
+func (h *APIHandler) listModels(w http.ResponseWriter, r *http.Request) {
+	names := h.runs.Agents()
+	models := make([]map[string]interface{}, 0, len(names))
+	for _, name := range names {
+		models = append(models, map[string]interface{}{
+			"id":       name,
+			"object":   "model",
+			"owned_by": "agent-sdk-go",
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"object": "list", "data": models})
+}
+
+// chatCompletions runs the agent named by the model on the messages of a request
+func (h *APIHandler) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	var body chatCompletionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIRequestBytes)).Decode(&body); err != nil {
+		writeChatError(w, http.StatusBadRequest, "invalid_request_error", fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	a, ok := h.runs.agents[body.Model]
+	if !ok {
+		writeChatError(w, http.StatusNotFound, "model_not_found", fmt.Errorf("unknown model %q", body.Model))
+		return
+	}
+	input, err := chatInput(body.Messages)
+	if err != nil {
+		writeChatError(w, http.StatusBadRequest, "invalid_request_error", err)
+		return
+	}
+
+	opts := h.runs.runOptions(input, "")
+	completion := chatCompletion{
+		ID:      "chatcmpl-" + strings.TrimPrefix(opts.RunID, "run-"),
+		Created: time.Now().Unix(),
+		Model:   body.Model,
+	}
+	if body.Stream {
+		h.streamChatCompletion(w, r, a, opts, completion)
+		return
+	}
+
+	res, err := h.runner.Run(r.Context(), a, opts)
+	if err != nil {
+		writeChatError(w, chatErrorStatus(err), "run_failed", err)
+		return
+	}
+	stop := "stop"
+	completion.Object = "chat.completion"
+	completion.Choices = []chatChoice{{
+		Message:      &chatContent{Role: "assistant", Content: model.ToolResultText(res.FinalOutput)},
+		FinishReason: &stop,
+	}}
+	total := res.Usage.Total
+	completion.Usage = &chatUsage{
+		PromptTokens:     total.PromptTokens,
+		CompletionTokens: total.CompletionTokens,
+		TotalTokens:      total.TotalTokens,
+	}
+	writeJSON(w, http.StatusOK, completion)
+}
+
+// streamChatCompletion streams a run as chat completion chunks, ending with
+// [DONE]. Only content is streamed; tool calls stay on the server.
+func (h *APIHandler) streamChatCompletion(w http.ResponseWriter, r *http.Request, a *agent.Agent, opts *runner.RunOptions, completion chatCompletion) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeChatError(w, http.StatusInternalServerError, "server_error", stream.ErrStreamingUnsupported)
+		return
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	streamed, err := h.runner.RunStreaming(ctx, a, opts)
+	if err != nil {
+		writeChatError(w, chatErrorStatus(err), "run_failed", err)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	completion.Object = "chat.completion.chunk"
+	send := func(body interface{}) bool {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return false
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+	chunk := func(delta chatContent, finishReason *string) bool {
+		completion.Choices = []chatChoice{{Delta: &delta, FinishReason: finishReason}}
+		return send(completion)
+	}
+
+	connected := chunk(chatContent{Role: "assistant"}, nil)
+	for event := range streamed.Stream {
+		if !connected {
+			continue
+		}
+		switch event.Type {
+		case model.StreamEventTypeContent:
+			if event.Content != "" {
+				connected = chunk(chatContent{Content: event.Content}, nil)
+			}
+		case model.StreamEventTypeError, model.StreamEventTypeApprovalRequired:
+			if event.Error != nil {
+				send(map[string]interface{}{"error": chatError{Message: event.Error.Error(), Type: "run_failed"}})
+				connected = false
+				cancel()
+			}
+		}
+	}
+	if !connected {
+		return
+	}
+	stop := "stop"
+	if chunk(chatContent{}, &stop) {
+		fmt.Fprint(w, "data: [DONE]\n\n")
+		flusher.Flush()
+	}
+}
+
+// chatError is an error in the format of the OpenAI API
+type chatError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+// writeChatError writes an error response in the format of the OpenAI API
+func writeChatError(w http.ResponseWriter, status int, errType string, err error) {
+	writeJSON(w, status, map[string]interface{}{"error": chatError{Message: err.Error(), Type: errType}})
+}
+
+// chatErrorStatus returns the status of a failed chat completion
+func chatErrorStatus(err error) int {
+	var approvalErr *runner.ApprovalRequiredError
+	if errors.As(err, &approvalErr) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// createRun starts a run in the background
+func (h *APIHandler) createRun(w http.ResponseWriter, r *http.Request) {
+	var body CreateRunRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIRequestBytes)).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	status, err := h.runs.CreateRun(r.Context(), body)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/runs/"+status.ID)
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+// getRun returns the status and result of a run
+func (h *APIHandler) getRun(w http.ResponseWriter, r *http.Request) {
+	status, err := h.runs.GetRunResult(r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// runEvents streams the events of a run as server-sent events
+func (h *APIHandler) runEvents(w http.ResponseWriter, r *http.Request) {
+	var after int64
+	if lastID := r.Header.Get("Last-Event-ID"); lastID != "" {
+		parsed, err := strconv.ParseInt(lastID, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid Last-Event-ID: %w", err))
+			return
+		}
+		after = parsed
+	}
+	followed, err := h.runs.StreamEvents(r.Context(), r.PathValue("id"), after)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	stream.ServeSSE(w, r, followed, nil)
+}
+
+// cancelRun cancels a run
+func (h *APIHandler) cancelRun(w http.ResponseWriter, r *http.Request) {
+	status, err := h.runs.CancelRun(r.PathValue("id"))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	status.Status = "cancelling"
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+// writeServiceError writes the error of a run service operation
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrRunNotFound), errors.Is(err, ErrUnknownAgent):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, ErrRunExists), errors.Is(err, runner.ErrRunNotActive):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, ErrInputRequired):
+		writeError(w, http.StatusBadRequest, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,241 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/server/stream"
+	agentsdkv1 "github.com/pontus-devoteam/agent-sdk-go/proto/agentsdk/v1"
+)
+
+// GRPCServer serves a RunService over gRPC as the RunService of
+// proto/agentsdk/v1/runs.proto:
+//
+//	srv := grpc.NewServer()
+//	server.NewGRPCServer(server.NewRunService(runner.NewRunner(), assistant)).Register(srv)
+//
+// Streams of events end with an "end" event carrying the output of a completed
+// run. Errors of the RunService are returned with the status codes listed in the
+// proto file.
+type GRPCServer struct {
+	agentsdkv1.UnimplementedRunServiceServer
+	runs *RunService
+}
+
+// NewGRPCServer creates a gRPC server for the runs of a run service
+func NewGRPCServer(runs *RunService) *GRPCServer {
+	return &GRPCServer{runs: runs}
+}
+
+// Register registers the server's RunService with a gRPC server
+func (s *GRPCServer) Register(registrar grpc.ServiceRegistrar) {
+	agentsdkv1.RegisterRunServiceServer(registrar, s)
+}
+
+// CreateRun starts a run in the background and returns its status
+func (s *GRPCServer) CreateRun(ctx context.Context, req *agentsdkv1.CreateRunRequest) (*agentsdkv1.Run, error) {
+	var input interface{}
+	if req.GetInput() != nil {
+		input = req.GetInput().AsInterface()
+	}
+	created, err := s.runs.CreateRun(ctx, CreateRunRequest{Agent: req.GetAgent(), Input: input, RunID: req.GetRunId()})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return protoRun(created), nil
+}
+
+// StreamEvents replays the events of a run and follows it until it ends
+func (s *GRPCServer) StreamEvents(req *agentsdkv1.StreamEventsRequest, out grpc.ServerStreamingServer[agentsdkv1.Event]) error {
+	followed, err := s.runs.StreamEvents(out.Context(), req.GetRunId(), req.GetAfterSequence())
+	if err != nil {
+		return grpcError(err)
+	}
+	for event := range followed.Stream {
+		if err := out.Send(protoEvent(stream.NewEvent(event))); err != nil {
+			return err
+		}
+	}
+	if err := out.Context().Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+
+	end := stream.Event{Type: stream.EventTypeEnd}
+	if followed.IsComplete && followed.RunResult != nil {
+		end.Output = followed.FinalOutput
+	}
+	return out.Send(protoEvent(end))
+}
+
+// CancelRun cancels a run and returns its status before it stops
+func (s *GRPCServer) CancelRun(ctx context.Context, req *agentsdkv1.CancelRunRequest) (*agentsdkv1.Run, error) {
+	cancelled, err := s.runs.CancelRun(req.GetRunId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return protoRun(cancelled), nil
+}
+
+// GetRunResult returns the status of a run, with its output once it completed
+func (s *GRPCServer) GetRunResult(ctx context.Context, req *agentsdkv1.GetRunResultRequest) (*agentsdkv1.Run, error) {
+	run, err := s.runs.GetRunResult(req.GetRunId())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return protoRun(run), nil
+}
+
+// grpcError converts an error of a RunService to a gRPC status
+func grpcError(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, ErrRunNotFound):
+		code = codes.NotFound
+	case errors.Is(err, ErrUnknownAgent), errors.Is(err, ErrInputRequired):
+		code = codes.InvalidArgument
+	case errors.Is(err, ErrRunExists):
+		code = codes.AlreadyExists
+	case errors.Is(err, runner.ErrRunNotActive):
+		code = codes.FailedPrecondition
+	}
+	return status.Error(code, err.Error())
+}
+
+// protoRunStatuses are the proto statuses of runs
+var protoRunStatuses = map[string]agentsdkv1.RunStatus{
+	RunStatusRunning:          agentsdkv1.RunStatus_RUN_STATUS_RUNNING,
+	RunStatusCompleted:        agentsdkv1.RunStatus_RUN_STATUS_COMPLETED,
+	RunStatusFailed:           agentsdkv1.RunStatus_RUN_STATUS_FAILED,
+	RunStatusCancelled:        agentsdkv1.RunStatus_RUN_STATUS_CANCELLED,
+	RunStatusApprovalRequired: agentsdkv1.RunStatus_RUN_STATUS_APPROVAL_REQUIRED,
+}
+
+// protoRun converts the status of a run to its proto message
+func protoRun(run RunStatus) *agentsdkv1.Run {
+	msg := &agentsdkv1.Run{
+		Id:        run.ID,
+		Agent:     run.Agent,
+		Status:    protoRunStatuses[run.Status],
+		Output:    protoValue(run.Output),
+		Error:     run.Error,
+		CreatedAt: protoTime(run.CreatedAt),
+	}
+	if run.Usage != nil {
+		msg.Usage = &agentsdkv1.Usage{
+			PromptTokens:     int32(run.Usage.PromptTokens),
+			CompletionTokens: int32(run.Usage.CompletionTokens),
+			TotalTokens:      int32(run.Usage.TotalTokens),
+		}
+	}
+	if run.Approval != nil {
+		msg.Approval = &agentsdkv1.Approval{
+			Id:          run.Approval.ID,
+			Kind:        run.Approval.Kind,
+			Agent:       run.Approval.Agent,
+			Tool:        run.Approval.Tool,
+			TargetAgent: run.Approval.TargetAgent,
+			Parameters:  protoStruct(run.Approval.Parameters),
+		}
+	}
+	if run.FinishedAt != nil {
+		msg.FinishedAt = protoTime(*run.FinishedAt)
+	}
+	return msg
+}
+
+// protoEvent converts the JSON encoding of a stream event to its proto message
+func protoEvent(e stream.Event) *agentsdkv1.Event {
+	msg := &agentsdkv1.Event{
+		Type:      e.Type,
+		Sequence:  e.Sequence,
+		RunId:     e.RunID,
+		Agent:     e.Agent,
+		Turn:      int32(e.Turn),
+		FromAgent: e.FromAgent,
+		Content:   e.Content,
+		Output:    protoValue(e.Output),
+		Error:     e.Error,
+	}
+	if e.ToolCall != nil {
+		msg.ToolCall = &agentsdkv1.ToolCall{Id: e.ToolCall.ID, Name: e.ToolCall.Name, Parameters: protoStruct(e.ToolCall.Parameters)}
+	}
+	if e.Handoff != nil {
+		msg.Handoff = &agentsdkv1.Handoff{
+			AgentName:      e.Handoff.AgentName,
+			Type:           e.Handoff.Type,
+			TaskId:         e.Handoff.TaskID,
+			IsTaskComplete: e.Handoff.IsTaskComplete,
+		}
+	}
+	if e.Usage != nil {
+		msg.Usage = &agentsdkv1.Usage{
+			PromptTokens:     int32(e.Usage.PromptTokens),
+			CompletionTokens: int32(e.Usage.CompletionTokens),
+			TotalTokens:      int32(e.Usage.TotalTokens),
+			CachedTokens:     int32(e.Usage.CachedTokens),
+		}
+	}
+	if e.Approval != nil {
+		msg.Approval = &agentsdkv1.Approval{
+			Id:          e.Approval.ID,
+			Kind:        e.Approval.Kind,
+			Agent:       e.Approval.Agent,
+			Tool:        e.Approval.Tool,
+			TargetAgent: e.Approval.TargetAgent,
+			Parameters:  protoStruct(e.Approval.Parameters),
+		}
+	}
+	return msg
+}
+
+// protoValue converts a value to a protobuf Value through its JSON encoding, as
+// the HTTP API would send it. Values that cannot be encoded are sent as text.
+func protoValue(v interface{}) *structpb.Value {
+	if v == nil {
+		return nil
+	}
+	value := &structpb.Value{}
+	data, err := json.Marshal(v)
+	if err == nil {
+		err = value.UnmarshalJSON(data)
+	}
+	if err != nil {
+		return structpb.NewStringValue(fmt.Sprint(v))
+	}
+	return value
+}
+
+// protoStruct converts parameters to a protobuf Struct through their JSON
+// encoding, or nil if they cannot be encoded
+func protoStruct(params map[string]interface{}) *structpb.Struct {
+	if params == nil {
+		return nil
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil
+	}
+	s := &structpb.Struct{}
+	if err := s.UnmarshalJSON(data); err != nil {
+		return nil
+	}
+	return s
+}
+
+// protoTime converts a time to a protobuf Timestamp
+func protoTime(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,552 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+)
+
+// Methods of the JSON-RPC API
+const (
+	RPCMethodAgents  = "agents"
+	RPCMethodRun     = "run"
+	RPCMethodStream  = "stream"
+	RPCMethodCancel  = "cancel"
+	RPCMethodApprove = "approve"
+
+	// RPCNotificationEvent is the notification carrying the events of streamed runs
+	RPCNotificationEvent = "run/event"
+)
+
+// Statuses of RPC run results
+const (
+	RPCStatusCompleted        = "completed"
+	RPCStatusApprovalRequired = "approval_required"
+)
+
+// JSON-RPC error codes
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+
+	// rpcRunFailed is returned for runs that fail and rpcRunNotFound for run IDs
+	// that are neither active nor waiting for approval
+	rpcRunFailed   = -32000
+	rpcRunNotFound = -32001
+)
+
+// maxRPCMessageBytes limits the size of a single request
+const maxRPCMessageBytes = 16 << 20
+
+// RPCServer serves the agents of a runner over JSON-RPC 2.0, framed like the
+// Language Server Protocol: each message is a JSON body preceded by a
+// Content-Length header and a blank line. It lets editor extensions and desktop
+// apps drive agents over stdio without HTTP.
+//
+// Requests:
+//
+//	agents                                        list the agents that can be run
+//	run     {"agent", "input", "run_id"}          run to completion, returns an RPCRunResult
+//	stream  {"agent", "input", "run_id"}          start a run, returns {"run_id"} and sends run/event notifications
+//	cancel  {"run_id"}                            cancel an active run or drop a paused one
+//	approve {"run_id", "approved", "reason"}      decide the pending approval of a paused run and continue it
+//
+// The agent may be omitted to run the first registered agent. Runs paused for
+// approval, streamed or not, are continued with approve, which returns an
+// RPCRunResult like run.
+type RPCServer struct {
+	runner  *runner.Runner
+	agents  map[string]*agent.Agent
+	order   []string
+	options *runner.RunOptions
+
+	paused map[string]*runner.RunState
+	mu     sync.Mutex
+
+	out   io.Writer
+	outMu sync.Mutex
+}
+
+// NewRPCServer creates a JSON-RPC server for agents run by a runner
+func NewRPCServer(r *runner.Runner, agents ...*agent.Agent) *RPCServer {
+	s := &RPCServer{
+		runner: r,
+		agents: make(map[string]*agent.Agent, len(agents)),
+		paused: make(map[string]*runner.RunState),
+	}
+	for _, a := range agents {
+		if _, exists := s.agents[a.Name]; !exists {
+			s.order = append(s.order, a.Name)
+		}
+		s.agents[a.Name] = a
+	}
+	return s
+}
+
+// WithRunOptions sets the options of the server's runs. Their input and run ID
+// are replaced by those of each request.
+func (s *RPCServer) WithRunOptions(opts *runner.RunOptions) *RPCServer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.options = opts
+	return s
+}
+
+// RPCAgent describes an agent in the result of the agents method
+type RPCAgent struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// RPCApproval is an action waiting for approval
+type RPCApproval struct {
+	ID          string                 `json:"id"`
+	Kind        string                 `json:"kind"`
+	Agent       string                 `json:"agent"`
+	Tool        string                 `json:"tool,omitempty"`
+	TargetAgent string                 `json:"target_agent,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// RPCRunResult is the result of the run and approve methods
+type RPCRunResult struct {
+	RunID  string `json:"run_id"`
+	Status string `json:"status"`
+
+	// Agent is the agent that produced the output or requested approval
+	Agent string `json:"agent,omitempty"`
+
+	// Output is the final output of completed runs
+	Output interface{} `json:"output,omitempty"`
+
+	// Approval is the pending action of runs waiting for approval
+	Approval *RPCApproval `json:"approval,omitempty"`
+}
+
+// RPCToolCall is a tool call in a run event
+type RPCToolCall struct {
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// RPCEvent is the params of a run/event notification. Type is the type of the
+// stream event, such as "run_started", "content", "tool_call_finished",
+// "handoff", "approval_required", "done" or "error". A streamed run ends with a
+// done, error or approval_required event.
+type RPCEvent struct {
+	RunID    string             `json:"run_id"`
+	Type     string             `json:"type"`
+	Sequence int64              `json:"sequence,omitempty"`
+	Agent    string             `json:"agent,omitempty"`
+	Turn     int                `json:"turn,omitempty"`
+	Content  string             `json:"content,omitempty"`
+	ToolCall *RPCToolCall       `json:"tool_call,omitempty"`
+	Handoff  *model.HandoffCall `json:"handoff,omitempty"`
+	Approval *RPCApproval       `json:"approval,omitempty"`
+	Output   interface{}        `json:"output,omitempty"`
+	Error    string             `json:"error,omitempty"`
+}
+
+// rpcMessage is a JSON-RPC request, notification or response
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is the error of a JSON-RPC response
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface
+func (e *rpcError) Error() string {
+	return e.Message
+}
+
+// runParams are the params of the run and stream methods
+type runParams struct {
+	Agent string      `json:"agent"`
+	Input interface{} `json:"input"`
+	RunID string      `json:"run_id"`
+}
+
+// runIDParams are the params of the cancel method
+type runIDParams struct {
+	RunID string `json:"run_id"`
+}
+
+// approveParams are the params of the approve method
+type approveParams struct {
+	RunID    string `json:"run_id"`
+	Approved bool   `json:"approved"`
+	Reason   string `json:"reason"`
+}
+
+// ServeStdio serves requests read from standard input, writing responses to
+// standard output
+func (s *RPCServer) ServeStdio(ctx context.Context) error {
+	return s.Serve(ctx, os.Stdin, os.Stdout)
+}
+
+// Serve serves the requests read from in until it ends or ctx is done. Requests
+// are handled concurrently; Serve returns once the requests in progress have been
+// answered.
+func (s *RPCServer) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	s.outMu.Lock()
+	s.out = out
+	s.outMu.Unlock()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	reader := bufio.NewReader(in)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		body, err := readRPCMessage(reader)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var msg rpcMessage
+		if err := json.Unmarshal(body, &msg); err != nil {
+			s.reply(nil, nil, &rpcError{Code: rpcParseError, Message: fmt.Sprintf("invalid JSON: %v", err)})
+			continue
+		}
+		if msg.JSONRPC != "2.0" || msg.Method == "" {
+			s.reply(msg.ID, nil, &rpcError{Code: rpcInvalidRequest, Message: "invalid JSON-RPC 2.0 request"})
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if msg.Method == RPCMethodStream {
+				s.stream(ctx, msg)
+				return
+			}
+			res, err := s.handle(ctx, msg)
+			if msg.ID == nil {
+				return
+			}
+			var rpcErr *rpcError
+			if err != nil && !errors.As(err, &rpcErr) {
+				rpcErr = &rpcError{Code: rpcRunFailed, Message: err.Error()}
+			}
+			s.reply(msg.ID, res, rpcErr)
+		}()
+	}
+}
+
+// handle dispatches a request to its method
+func (s *RPCServer) handle(ctx context.Context, msg rpcMessage) (interface{}, error) {
+	switch msg.Method {
+	case RPCMethodAgents:
+		agents := make([]RPCAgent, 0, len(s.order))
+		for _, name := range s.order {
+			agents = append(agents, RPCAgent{Name: name, Description: s.agents[name].Description})
+		}
+		return map[string]interface{}{"agents": agents}, nil
+
+	case RPCMethodRun:
+		var params runParams
+		if err := decodeParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		a, opts, err := s.prepareRun(params)
+		if err != nil {
+			return nil, err
+		}
+		res, err := s.runner.Run(ctx, a, opts)
+		return s.runResult(opts.RunID, res, err)
+
+	case RPCMethodCancel:
+		var params runIDParams
+		if err := decodeParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		_, paused := s.paused[params.RunID]
+		delete(s.paused, params.RunID)
+		s.mu.Unlock()
+		if err := s.runner.Cancel(params.RunID); err != nil && !paused {
+			if errors.Is(err, runner.ErrRunNotActive) {
+				return nil, &rpcError{Code: rpcRunNotFound, Message: err.Error()}
+			}
+			return nil, err
+		}
+		return map[string]string{"run_id": params.RunID, "status": "cancelling"}, nil
+
+	case RPCMethodApprove:
+		var params approveParams
+		if err := decodeParams(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		state, ok := s.paused[params.RunID]
+		delete(s.paused, params.RunID)
+		s.mu.Unlock()
+		if !ok {
+			return nil, &rpcError{Code: rpcRunNotFound, Message: fmt.Sprintf("run %s is not waiting for approval", params.RunID)}
+		}
+
+		decision := runner.Reject(params.Reason)
+		if params.Approved {
+			decision = runner.Approve()
+		}
+		res, err := s.runner.Resume(ctx, state, decision)
+		return s.runResult(params.RunID, res, err)
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: fmt.Sprintf("method %s not found", msg.Method)}
+}
+
+// stream starts a streamed run, answers the request with its run ID and forwards
+// its events
+func (s *RPCServer) stream(ctx context.Context, msg rpcMessage) {
+	fail := func(err error) {
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &rpcError{Code: rpcRunFailed, Message: err.Error()}
+		}
+		if msg.ID != nil {
+			s.reply(msg.ID, nil, rpcErr)
+		}
+	}
+
+	var params runParams
+	if err := decodeParams(msg.Params, &params); err != nil {
+		fail(err)
+		return
+	}
+	a, opts, err := s.prepareRun(params)
+	if err != nil {
+		fail(err)
+		return
+	}
+	streamed, err := s.runner.RunStreaming(ctx, a, opts)
+	if err != nil {
+		fail(err)
+		return
+	}
+	if msg.ID != nil {
+		s.reply(msg.ID, map[string]string{"run_id": opts.RunID}, nil)
+	}
+	s.forwardEvents(opts.RunID, streamed)
+}
+
+// prepareRun resolves the agent and options of a run request
+func (s *RPCServer) prepareRun(params runParams) (*agent.Agent, *runner.RunOptions, error) {
+	name := params.Agent
+	if name == "" && len(s.order) > 0 {
+		name = s.order[0]
+	}
+	a, ok := s.agents[name]
+	if !ok {
+		return nil, nil, &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("unknown agent %q", params.Agent)}
+	}
+
+	opts := &runner.RunOptions{}
+	s.mu.Lock()
+	if s.options != nil {
+		*opts = *s.options
+	}
+	s.mu.Unlock()
+	opts.Input = params.Input
+	opts.RunID = params.RunID
+	if opts.RunID == "" {
+		opts.RunID = newRPCRunID()
+	}
+	return a, opts, nil
+}
+
+// runResult converts the outcome of a run, keeping the state of runs paused for
+// approval
+func (s *RPCServer) runResult(runID string, res *result.RunResult, err error) (*RPCRunResult, error) {
+	var approvalErr *runner.ApprovalRequiredError
+	if errors.As(err, &approvalErr) {
+		s.mu.Lock()
+		s.paused[runID] = approvalErr.State
+		s.mu.Unlock()
+		return &RPCRunResult{
+			RunID:    runID,
+			Status:   RPCStatusApprovalRequired,
+			Agent:    approvalErr.Request.AgentName,
+			Approval: rpcApproval(approvalErr.Request),
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out := &RPCRunResult{RunID: runID, Status: RPCStatusCompleted, Output: res.FinalOutput}
+	if res.LastAgent != nil {
+		out.Agent = res.LastAgent.Name
+	}
+	return out, nil
+}
+
+// forwardEvents sends the events of a streamed run as notifications. The done
+// event is sent once the stream has ended, with the final output of the run.
+func (s *RPCServer) forwardEvents(runID string, streamed *result.StreamedRunResult) {
+	ended := false
+	for event := range streamed.Stream {
+		notification := RPCEvent{RunID: runID, Type: event.Type, Sequence: event.Sequence, Agent: event.Agent, Turn: event.Turn, Content: event.Content}
+		switch event.Type {
+		case model.StreamEventTypeDone:
+			continue
+		case model.StreamEventTypeToolCall, model.StreamEventTypeToolCallStarted, model.StreamEventTypeToolCallFinished:
+			if event.ToolCall != nil {
+				notification.ToolCall = &RPCToolCall{ID: event.ToolCall.ID, Name: event.ToolCall.Name, Parameters: event.ToolCall.Parameters}
+			}
+			notification.Output = event.Output
+		case model.StreamEventTypeRunCompleted:
+			notification.Output = event.Output
+		case model.StreamEventTypeHandoff:
+			notification.Handoff = event.HandoffCall
+		case model.StreamEventTypeApprovalRequired:
+			ended = true
+			var approvalErr *runner.ApprovalRequiredError
+			if errors.As(event.Error, &approvalErr) {
+				s.mu.Lock()
+				s.paused[runID] = approvalErr.State
+				s.mu.Unlock()
+				notification.Approval = rpcApproval(approvalErr.Request)
+			}
+		default:
+			if event.Type == model.StreamEventTypeError {
+				ended = true
+			}
+			if event.Error != nil {
+				notification.Error = event.Error.Error()
+			}
+		}
+		s.notify(RPCNotificationEvent, notification)
+	}
+
+	switch {
+	case streamed.IsComplete:
+		s.notify(RPCNotificationEvent, RPCEvent{RunID: runID, Type: model.StreamEventTypeDone, Output: streamed.FinalOutput})
+	case !ended:
+		s.notify(RPCNotificationEvent, RPCEvent{RunID: runID, Type: model.StreamEventTypeError, Error: "run ended without a final output"})
+	}
+}
+
+// rpcApproval converts an approval request
+func rpcApproval(request *runner.ApprovalRequest) *RPCApproval {
+	return &RPCApproval{
+		ID:          request.ID,
+		Kind:        request.Kind,
+		Agent:       request.AgentName,
+		Tool:        request.ToolName,
+		TargetAgent: request.TargetAgent,
+		Parameters:  request.Parameters,
+	}
+}
+
+// reply writes the response to a request
+func (s *RPCServer) reply(id json.RawMessage, res interface{}, rpcErr *rpcError) {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	msg := rpcMessage{JSONRPC: "2.0", ID: id, Error: rpcErr}
+	if rpcErr == nil {
+		if res == nil {
+			res = struct{}{}
+		}
+		msg.Result = res
+	}
+	s.write(msg)
+}
+
+// notify writes a notification
+func (s *RPCServer) notify(method string, params interface{}) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+	s.write(rpcMessage{JSONRPC: "2.0", Method: method, Params: data})
+}
+
+// write writes a message with its Content-Length header
+func (s *RPCServer) write(msg rpcMessage) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		body, _ = json.Marshal(rpcMessage{JSONRPC: "2.0", ID: msg.ID, Error: &rpcError{Code: rpcRunFailed, Message: fmt.Sprintf("failed to encode response: %v", err)}})
+	}
+
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// readRPCMessage reads the body of the next message
+func readRPCMessage(reader *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(reader).ReadMIMEHeader()
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(header) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read message header: %w", err)
+	}
+
+	length, err := strconv.Atoi(strings.TrimSpace(header.Get("Content-Length")))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	if length > maxRPCMessageBytes {
+		return nil, fmt.Errorf("message of %d bytes exceeds the limit of %d", length, maxRPCMessageBytes)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil, fmt.Errorf("failed to read message body: %w", err)
+	}
+	return body, nil
+}
+
+// decodeParams decodes the params of a request
+func decodeParams(raw json.RawMessage, params interface{}) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(raw, params); err != nil {
+		return &rpcError{Code: rpcInvalidParams, Message: fmt.Sprintf("invalid params: %v", err)}
+	}
+	return nil
+}
+
+// newRPCRunID generates the ID of a run started without one
+func newRPCRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("rpc-%d", time.Now().UnixNano())
+	}
+	return fmt.Sprintf("rpc-%x", b)
+}
@@ -0,0 +1,385 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+)
+
+// Statuses of runs created through a RunService
+const (
+	RunStatusRunning          = "running"
+	RunStatusCompleted        = "completed"
+	RunStatusFailed           = "failed"
+	RunStatusCancelled        = "cancelled"
+	RunStatusApprovalRequired = "approval_required"
+)
+
+// maxFinishedRuns is the number of finished runs a RunService keeps, oldest
+// first out
+const maxFinishedRuns = 1000
+
+var (
+	// ErrRunNotFound is returned for run IDs a RunService does not know
+	ErrRunNotFound = errors.New("run not found")
+
+	// ErrRunExists is returned when creating a run with the ID of another run
+	ErrRunExists = errors.New("run already exists")
+
+	// ErrUnknownAgent is returned when creating a run of an agent that is not
+	// registered
+	ErrUnknownAgent = errors.New("unknown agent")
+
+	// ErrInputRequired is returned when creating a run without input
+	ErrInputRequired = errors.New("input is required")
+)
+
+// RunService runs agents in the background and keeps their events and results
+// for the clients that ask for them. It backs the runs API of APIHandler and the
+// gRPC service of GRPCServer, defined in proto/agentsdk/v1/runs.proto:
+//
+//	CreateRun     start a run, returning its status
+//	StreamEvents  replay the events of a run and follow it until it ends
+//	CancelRun     cancel a run
+//	GetRunResult  fetch the status and result of a run
+//
+// Errors are ErrRunNotFound, ErrRunExists, ErrUnknownAgent, ErrInputRequired and
+// runner.ErrRunNotActive for runs that have already finished.
+type RunService struct {
+	runner  *runner.Runner
+	agents  map[string]*agent.Agent
+	order   []string
+	options *runner.RunOptions
+
+	runs     map[string]*serviceRun
+	finished []string
+	mu       sync.Mutex
+}
+
+// NewRunService creates a run service for agents run by a runner
+func NewRunService(r *runner.Runner, agents ...*agent.Agent) *RunService {
+	s := &RunService{
+		runner: r,
+		agents: make(map[string]*agent.Agent, len(agents)),
+		runs:   make(map[string]*serviceRun),
+	}
+	for _, a := range agents {
+		if _, exists := s.agents[a.Name]; !exists {
+			s.order = append(s.order, a.Name)
+		}
+		s.agents[a.Name] = a
+	}
+	return s
+}
+
+// WithRunOptions sets the options of the service's runs. Their input and run ID
+// are replaced by those of each request.
+func (s *RunService) WithRunOptions(opts *runner.RunOptions) *RunService {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.options = opts
+	return s
+}
+
+// Agents returns the names of the agents that can be run, in registration order
+func (s *RunService) Agents() []string {
+	return append([]string(nil), s.order...)
+}
+
+// agent returns the agent with a name, or the first agent for an empty name
+func (s *RunService) agent(name string) (*agent.Agent, bool) {
+	if name == "" && len(s.order) > 0 {
+		name = s.order[0]
+	}
+	a, ok := s.agents[name]
+	return a, ok
+}
+
+// runOptions returns the options of a run on an input. The run gets its own copy
+// of the run config, which the runner fills in with its defaults.
+func (s *RunService) runOptions(input interface{}, runID string) *runner.RunOptions {
+	opts := &runner.RunOptions{}
+	s.mu.Lock()
+	if s.options != nil {
+		*opts = *s.options
+	}
+	s.mu.Unlock()
+	if opts.RunConfig != nil {
+		config := *opts.RunConfig
+		opts.RunConfig = &config
+	}
+	opts.Input = input
+	opts.RunID = runID
+	if opts.RunID == "" {
+		opts.RunID = newServiceRunID()
+	}
+	return opts
+}
+
+// CreateRunRequest starts a run of an agent. The agent may be omitted to run the
+// first registered agent, and the run ID to generate one.
+type CreateRunRequest struct {
+	Agent string      `json:"agent"`
+	Input interface{} `json:"input"`
+	RunID string      `json:"run_id"`
+}
+
+// RunUsage is the token usage of a run
+type RunUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// RunStatus is the status and result of a run created through a RunService
+type RunStatus struct {
+	ID     string `json:"id"`
+	Agent  string `json:"agent"`
+	Status string `json:"status"`
+
+	// Output is the final output of a completed run
+	Output interface{} `json:"output,omitempty"`
+
+	// Usage is the token usage of the run so far
+	Usage *RunUsage `json:"usage,omitempty"`
+
+	// Approval is the pending action of a run waiting for approval. Such runs end
+	// here; continue them with the runner's Resume.
+	Approval *RPCApproval `json:"approval,omitempty"`
+	Error    string       `json:"error,omitempty"`
+
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// CreateRun starts a run in the background. The run keeps the values of ctx but
+// outlives it; stop it with CancelRun.
+func (s *RunService) CreateRun(ctx context.Context, req CreateRunRequest) (RunStatus, error) {
+	a, ok := s.agent(req.Agent)
+	if !ok {
+		return RunStatus{}, fmt.Errorf("%w %q", ErrUnknownAgent, req.Agent)
+	}
+	if req.Input == nil {
+		return RunStatus{}, ErrInputRequired
+	}
+
+	opts := s.runOptions(req.Input, req.RunID)
+	run := &serviceRun{
+		status:  RunStatus{ID: opts.RunID, Agent: a.Name, Status: RunStatusRunning, CreatedAt: time.Now()},
+		updated: make(chan struct{}),
+	}
+	s.mu.Lock()
+	if _, exists := s.runs[opts.RunID]; exists {
+		s.mu.Unlock()
+		return RunStatus{}, fmt.Errorf("%w: %s", ErrRunExists, opts.RunID)
+	}
+	s.runs[opts.RunID] = run
+	s.mu.Unlock()
+
+	streamed, err := s.runner.RunStreaming(context.WithoutCancel(ctx), a, opts)
+	if err != nil {
+		s.mu.Lock()
+		delete(s.runs, opts.RunID)
+		s.mu.Unlock()
+		return RunStatus{}, err
+	}
+	go func() {
+		for event := range streamed.Stream {
+			run.record(event)
+		}
+		run.finish(streamed)
+		s.retire(opts.RunID)
+	}()
+	return run.snapshot(), nil
+}
+
+// GetRunResult returns the status of a run, with its output once it completed
+func (s *RunService) GetRunResult(id string) (RunStatus, error) {
+	run, err := s.lookup(id)
+	if err != nil {
+		return RunStatus{}, err
+	}
+	return run.snapshot(), nil
+}
+
+// StreamEvents returns a stream replaying the events of a run after a sequence
+// number, zero for all of them, and following the run until it ends or ctx is
+// done. Ending ctx does not stop the run.
+func (s *RunService) StreamEvents(ctx context.Context, id string, afterSequence int64) (*result.StreamedRunResult, error) {
+	run, err := s.lookup(id)
+	if err != nil {
+		return nil, err
+	}
+	return run.follow(ctx, afterSequence), nil
+}
+
+// CancelRun cancels a run, returning its status before it stops
+func (s *RunService) CancelRun(id string) (RunStatus, error) {
+	run, err := s.lookup(id)
+	if err != nil {
+		return RunStatus{}, err
+	}
+	if err := s.runner.Cancel(id); err != nil {
+		return RunStatus{}, err
+	}
+	return run.snapshot(), nil
+}
+
+// lookup returns a run by ID
+func (s *RunService) lookup(id string) (*serviceRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRunNotFound, id)
+	}
+	return run, nil
+}
+
+// retire records a finished run, forgetting the oldest finished runs beyond
+// maxFinishedRuns
+func (s *RunService) retire(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.finished = append(s.finished, id)
+	for len(s.finished) > maxFinishedRuns {
+		delete(s.runs, s.finished[0])
+		s.finished = s.finished[1:]
+	}
+}
+
+// serviceRun is a run started by a RunService, recording its events for the
+// clients that follow it
+type serviceRun struct {
+	status RunStatus
+	events []model.StreamEvent
+	result *result.RunResult
+
+	// updated is closed and replaced whenever an event is recorded or the run ends
+	updated chan struct{}
+	done    bool
+	mu      sync.Mutex
+}
+
+// record adds an event of the run
+func (run *serviceRun) record(event model.StreamEvent) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	run.events = append(run.events, event)
+	if event.Usage != nil {
+		usage := run.status.Usage
+		if usage == nil {
+			usage = &RunUsage{}
+			run.status.Usage = usage
+		}
+		usage.PromptTokens += event.Usage.PromptTokens
+		usage.CompletionTokens += event.Usage.CompletionTokens
+		usage.TotalTokens += event.Usage.TotalTokens
+	}
+
+	var approvalErr *runner.ApprovalRequiredError
+	switch {
+	case errors.As(event.Error, &approvalErr):
+		run.status.Status = RunStatusApprovalRequired
+		run.status.Approval = rpcApproval(approvalErr.Request)
+	case errors.Is(event.Error, runner.ErrRunCancelled), errors.Is(event.Error, context.Canceled):
+		run.status.Status = RunStatusCancelled
+		run.status.Error = event.Error.Error()
+	case event.Error != nil:
+		run.status.Status = RunStatusFailed
+		run.status.Error = event.Error.Error()
+	}
+	run.notify()
+}
+
+// finish marks the end of the run
+func (run *serviceRun) finish(streamed *result.StreamedRunResult) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	now := time.Now()
+	run.status.FinishedAt = &now
+	run.result = streamed.RunResult
+	if run.status.Status == RunStatusRunning {
+		run.status.Status = RunStatusFailed
+		if streamed.IsComplete && streamed.RunResult != nil {
+			run.status.Status = RunStatusCompleted
+			run.status.Output = streamed.FinalOutput
+		}
+	}
+	run.done = true
+	run.notify()
+}
+
+// notify wakes up the clients following the run. The lock must be held.
+func (run *serviceRun) notify() {
+	close(run.updated)
+	run.updated = make(chan struct{})
+}
+
+// snapshot returns the status of the run
+func (run *serviceRun) snapshot() RunStatus {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	status := run.status
+	if status.Usage != nil {
+		usage := *status.Usage
+		status.Usage = &usage
+	}
+	return status
+}
+
+// follow returns a stream replaying the events of the run after a sequence
+// number and following it until it ends or ctx is done
+func (run *serviceRun) follow(ctx context.Context, after int64) *result.StreamedRunResult {
+	events := make(chan model.StreamEvent)
+	followed := &result.StreamedRunResult{Stream: events}
+	go func() {
+		defer close(events)
+		for next := 0; ; {
+			run.mu.Lock()
+			pending, done, updated := run.events[next:], run.done, run.updated
+			completed, res := run.status.Status == RunStatusCompleted, run.result
+			run.mu.Unlock()
+
+			for _, event := range pending {
+				if event.Sequence > 0 && event.Sequence <= after {
+					continue
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+			next += len(pending)
+			if done {
+				followed.RunResult = res
+				followed.IsComplete = completed
+				return
+			}
+			select {
+			case <-updated:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return followed
+}
+
+// newServiceRunID creates a random run ID
+func newServiceRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("run-%d", time.Now().UnixNano())
+	}
+	return fmt.Sprintf("run-%x", b)
+}
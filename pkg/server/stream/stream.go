@@ -0,0 +1,343 @@
+// Package stream serves streamed runs to browsers and other clients, as
+// server-sent events or over a WebSocket. Every stream event is sent as a JSON
+// encoded Event, followed by an "end" event once the run's stream closes.
+// Heartbeats keep idle connections open, and a client that disconnects cancels
+// its run.
+//
+//	http.Handle("/chat", stream.SSEHandler(func(ctx context.Context, req *http.Request) (*result.StreamedRunResult, error) {
+//		return r.RunStreaming(ctx, assistant, &runner.RunOptions{Input: req.URL.Query().Get("q")})
+//	}))
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/websocket"
+)
+
+// EventTypeEnd is the type of the last event of a stream, sent once the run's
+// stream has closed. Its Output is the final output of a completed run.
+const EventTypeEnd = "end"
+
+// DefaultHeartbeat is the interval of heartbeats on idle connections
+const DefaultHeartbeat = 15 * time.Second
+
+// ErrStreamingUnsupported is returned when a response writer cannot flush
+// server-sent events
+var ErrStreamingUnsupported = errors.New("streaming is not supported by the response writer")
+
+// Event is the JSON encoding of a stream event
+type Event struct {
+	Type      string                `json:"type"`
+	Sequence  int64                 `json:"sequence,omitempty"`
+	RunID     string                `json:"run_id,omitempty"`
+	Agent     string                `json:"agent,omitempty"`
+	Turn      int                   `json:"turn,omitempty"`
+	FromAgent string                `json:"from_agent,omitempty"`
+	Content   string                `json:"content,omitempty"`
+	ToolCall  *ToolCall             `json:"tool_call,omitempty"`
+	Handoff   *model.HandoffCall    `json:"handoff,omitempty"`
+	Usage     *Usage                `json:"usage,omitempty"`
+	Artifact  *model.ArtifactUpdate `json:"artifact,omitempty"`
+	Approval  *Approval             `json:"approval,omitempty"`
+	Output    interface{}           `json:"output,omitempty"`
+	Error     string                `json:"error,omitempty"`
+}
+
+// ToolCall is a tool call in an event
+type ToolCall struct {
+	ID         string                 `json:"id,omitempty"`
+	Name       string                 `json:"name"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// Usage is the token usage of a model response in an event
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+	CachedTokens     int `json:"cached_tokens,omitempty"`
+}
+
+// Approval is the action a paused run waits on, in approval_required events
+type Approval struct {
+	ID          string                 `json:"id"`
+	Kind        string                 `json:"kind"`
+	Agent       string                 `json:"agent"`
+	Tool        string                 `json:"tool,omitempty"`
+	TargetAgent string                 `json:"target_agent,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// NewEvent converts a stream event to its JSON encoding
+func NewEvent(event model.StreamEvent) Event {
+	e := Event{
+		Type:      event.Type,
+		Sequence:  event.Sequence,
+		RunID:     event.RunID,
+		Agent:     event.Agent,
+		Turn:      event.Turn,
+		FromAgent: event.FromAgent,
+		Content:   event.Content,
+		Handoff:   event.HandoffCall,
+		Artifact:  event.Artifact,
+		Output:    event.Output,
+	}
+	if event.ToolCall != nil {
+		e.ToolCall = &ToolCall{ID: event.ToolCall.ID, Name: event.ToolCall.Name, Parameters: event.ToolCall.Parameters}
+	}
+	if event.Usage != nil {
+		e.Usage = &Usage{
+			PromptTokens:     event.Usage.PromptTokens,
+			CompletionTokens: event.Usage.CompletionTokens,
+			TotalTokens:      event.Usage.TotalTokens,
+			CachedTokens:     event.Usage.CachedTokens,
+		}
+	}
+
+	var approvalErr *runner.ApprovalRequiredError
+	switch {
+	case errors.As(event.Error, &approvalErr):
+		request := approvalErr.Request
+		e.Approval = &Approval{
+			ID:          request.ID,
+			Kind:        request.Kind,
+			Agent:       request.AgentName,
+			Tool:        request.ToolName,
+			TargetAgent: request.TargetAgent,
+			Parameters:  request.Parameters,
+		}
+	case event.Error != nil:
+		e.Error = event.Error.Error()
+	}
+	return e
+}
+
+// endEvent returns the last event of the stream of a run
+func endEvent(streamed *result.StreamedRunResult) Event {
+	e := Event{Type: EventTypeEnd}
+	if streamed.IsComplete && streamed.RunResult != nil {
+		e.Output = streamed.FinalOutput
+	}
+	return e
+}
+
+// encodeEvent encodes an event as JSON. Output that cannot be encoded is sent as text.
+func encodeEvent(e Event) ([]byte, error) {
+	data, err := json.Marshal(e)
+	if err == nil || e.Output == nil {
+		return data, err
+	}
+	e.Output = fmt.Sprint(e.Output)
+	return json.Marshal(e)
+}
+
+// Option configures how a run is streamed
+type Option func(*config)
+
+// config is the configuration of a stream
+type config struct {
+	heartbeat time.Duration
+}
+
+// WithHeartbeat sets the interval of heartbeats, which are sent while no events
+// are. Server-sent events get a comment line and WebSockets a ping. Zero or less
+// turns heartbeats off.
+func WithHeartbeat(interval time.Duration) Option {
+	return func(c *config) {
+		c.heartbeat = interval
+	}
+}
+
+// newConfig applies options to the default configuration
+func newConfig(opts []Option) *config {
+	c := &config{heartbeat: DefaultHeartbeat}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// StartFunc starts the streamed run of a request. The run must stop when ctx
+// ends, which happens when the client disconnects.
+type StartFunc func(ctx context.Context, r *http.Request) (*result.StreamedRunResult, error)
+
+// SSEHandler returns a handler that starts a run for every request and streams
+// it as server-sent events
+func SSEHandler(start StartFunc, opts ...Option) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		streamed, err := start(ctx, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ServeSSE(w, r, streamed, cancel, opts...)
+	})
+}
+
+// WebSocketHandler returns a handler that upgrades every request to a WebSocket,
+// starts a run and streams it as text messages
+func WebSocketHandler(start StartFunc, opts ...Option) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+
+		streamed, err := start(ctx, r)
+		if err != nil {
+			if data, encodeErr := encodeEvent(Event{Type: model.StreamEventTypeError, Error: err.Error()}); encodeErr == nil {
+				conn.WriteMessage(websocket.TextMessage, data)
+			}
+			conn.CloseWithCode(websocket.CloseInternalError, "failed to start run")
+			return
+		}
+		streamWebSocket(ctx, conn, streamed, cancel, newConfig(opts))
+	})
+}
+
+// ServeSSE streams a run as server-sent events until its stream closes. Each
+// event is sent with its sequence number as ID and its type as event name. If
+// the client disconnects, cancel is called to stop the run; a nil cancel leaves
+// the run going. ServeSSE returns the error that ended the stream early.
+func ServeSSE(w http.ResponseWriter, r *http.Request, streamed *result.StreamedRunResult, cancel context.CancelFunc, opts ...Option) error {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		abandon(streamed, cancel)
+		http.Error(w, ErrStreamingUnsupported.Error(), http.StatusInternalServerError)
+		return ErrStreamingUnsupported
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	send := func(e Event) error {
+		data, err := encodeEvent(e)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s event: %w", e.Type, err)
+		}
+		if e.Sequence > 0 {
+			if _, err := fmt.Fprintf(w, "id: %d\n", e.Sequence); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	heartbeat := func() error {
+		if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	return pump(r.Context(), streamed, cancel, newConfig(opts), send, heartbeat)
+}
+
+// ServeWebSocket upgrades a request to a WebSocket and streams a run over it as
+// JSON text messages until its stream closes. If the client closes the
+// connection, cancel is called to stop the run; a nil cancel leaves the run
+// going. Messages from the client are ignored.
+func ServeWebSocket(w http.ResponseWriter, r *http.Request, streamed *result.StreamedRunResult, cancel context.CancelFunc, opts ...Option) error {
+	conn, err := websocket.Upgrade(w, r, nil)
+	if err != nil {
+		abandon(streamed, cancel)
+		return err
+	}
+	defer conn.Close()
+	return streamWebSocket(r.Context(), conn, streamed, cancel, newConfig(opts))
+}
+
+// streamWebSocket streams a run over an open WebSocket
+func streamWebSocket(ctx context.Context, conn *websocket.Conn, streamed *result.StreamedRunResult, cancel context.CancelFunc, c *config) error {
+	// The connection ends when the client closes it or stops answering
+	ctx, disconnected := context.WithCancel(ctx)
+	defer disconnected()
+	go func() {
+		defer disconnected()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(e Event) error {
+		data, err := encodeEvent(e)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s event: %w", e.Type, err)
+		}
+		return conn.WriteMessage(websocket.TextMessage, data)
+	}
+	heartbeat := func() error {
+		return conn.Ping(nil)
+	}
+	return pump(ctx, streamed, cancel, c, send, heartbeat)
+}
+
+// pump sends the events of a run until its stream closes, with heartbeats in
+// between. When the connection fails or ctx ends first, the run is abandoned.
+func pump(ctx context.Context, streamed *result.StreamedRunResult, cancel context.CancelFunc, c *config, send func(Event) error, heartbeat func() error) error {
+	var ticks <-chan time.Time
+	if c.heartbeat > 0 {
+		ticker := time.NewTicker(c.heartbeat)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	for {
+		select {
+		case event, ok := <-streamed.Stream:
+			if !ok {
+				return send(endEvent(streamed))
+			}
+			if err := send(NewEvent(event)); err != nil {
+				abandon(streamed, cancel)
+				return err
+			}
+		case <-ticks:
+			if err := heartbeat(); err != nil {
+				abandon(streamed, cancel)
+				return err
+			}
+		case <-ctx.Done():
+			abandon(streamed, cancel)
+			return ctx.Err()
+		}
+	}
+}
+
+// abandon stops a run nobody listens to anymore and drains its stream, so the
+// run is not blocked on sending events
+func abandon(streamed *result.StreamedRunResult, cancel context.CancelFunc) {
+	if cancel != nil {
+		cancel()
+	}
+	go func() {
+		for range streamed.Stream {
+		}
+	}()
+}
@@ -0,0 +1,335 @@
+// Package audio provides transcribe_audio and text_to_speech tools backed by any
+// provider that implements model.AudioProvider, such as the OpenAI provider. Audio
+// is exchanged through an artifact store, so the model only handles artifact IDs.
+//
+//	store := artifact.NewMemoryStore()
+//	transcribe := audio.NewTranscriptionTool(provider, store)
+//	speak := audio.NewSpeechTool(provider, store).WithVoice("nova")
+//	assistant := agent.NewAgent("Voice").WithTools(transcribe, speak)
+package audio
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/artifact"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+)
+
+// TranscriptionTool is the transcribe_audio tool
+type TranscriptionTool struct {
+	name        string
+	description string
+	provider    model.AudioProvider
+	store       artifact.Store
+	model       string
+	language    string
+	mu          sync.RWMutex
+}
+
+// NewTranscriptionTool creates a transcribe_audio tool that transcribes audio
+// artifacts from the store
+func NewTranscriptionTool(provider model.AudioProvider, store artifact.Store) *TranscriptionTool {
+	return &TranscriptionTool{
+		name:        "transcribe_audio",
+		description: "Transcribe a stored audio recording to text. Takes the artifact ID of the recording.",
+		provider:    provider,
+		store:       store,
+	}
+}
+
+// WithName sets the name of the tool
+func (t *TranscriptionTool) WithName(name string) *TranscriptionTool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.name = name
+	return t
+}
+
+// WithDescription sets the description of the tool
+func (t *TranscriptionTool) WithDescription(description string) *TranscriptionTool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.description = description
+	return t
+}
+
+// WithModel sets the transcription model
+func (t *TranscriptionTool) WithModel(modelName string) *TranscriptionTool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.model = modelName
+	return t
+}
+
+// WithLanguage sets the language used when the model does not pass one
+func (t *TranscriptionTool) WithLanguage(language string) *TranscriptionTool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.language = language
+	return t
+}
+
+// GetName returns the name of the tool
+func (t *TranscriptionTool) GetName() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.name
+}
+
+// GetDescription returns the description of the tool
+func (t *TranscriptionTool) GetDescription() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.description
+}
+
+// GetParametersSchema returns the JSON schema for the tool parameters
+func (t *TranscriptionTool) GetParametersSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"artifact_id": map[string]interface{}{
+				"type":        "string",
+				"description": "The artifact ID of the audio recording",
+			},
+			"language": map[string]interface{}{
+				"type":        "string",
+				"description": "The ISO-639-1 language of the recording, if known",
+			},
+		},
+		"required": []string{"artifact_id"},
+	}
+}
+
+// Execute transcribes the recording
+func (t *TranscriptionTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	artifactID, _ := params["artifact_id"].(string)
+	if artifactID == "" {
+		return nil, fmt.Errorf("artifact_id parameter is required")
+	}
+
+	t.mu.RLock()
+	request := &model.TranscriptionRequest{Model: t.model, Language: t.language}
+	t.mu.RUnlock()
+	if language, ok := params["language"].(string); ok && language != "" {
+		request.Language = language
+	}
+
+	stored, data, err := t.store.Get(ctx, artifactID)
+	if err != nil {
+		return nil, err
+	}
+	request.Audio = data
+	request.Filename = filename(stored)
+
+	transcription, err := t.provider.Transcribe(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("transcription failed: %w", err)
+	}
+	return transcription, nil
+}
+
+// SpeechResponse is the result of the text_to_speech tool
+type SpeechResponse struct {
+	ArtifactID string `json:"artifact_id"`
+	MediaType  string `json:"media_type"`
+	Size       int    `json:"size"`
+}
+
+// SpeechTool is the text_to_speech tool
+type SpeechTool struct {
+	name         string
+	description  string
+	provider     model.AudioProvider
+	store        artifact.Store
+	model        string
+	voice        string
+	voices       []string
+	format       string
+	instructions string
+	mu           sync.RWMutex
+}
+
+// NewSpeechTool creates a text_to_speech tool that stores the synthesized audio
+// in the store
+func NewSpeechTool(provider model.AudioProvider, store artifact.Store) *SpeechTool {
+	return &SpeechTool{
+		name:        "text_to_speech",
+		description: "Convert text to spoken audio. Returns the artifact ID of the recording.",
+		provider:    provider,
+		store:       store,
+	}
+}
+
+// WithName sets the name of the tool
+func (t *SpeechTool) WithName(name string) *SpeechTool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.name = name
+	return t
+}
+
+// WithDescription sets the description of the tool
+func (t *SpeechTool) WithDescription(description string) *SpeechTool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.description = description
+	return t
+}
+
+// WithModel sets the speech model
+func (t *SpeechTool) WithModel(modelName string) *SpeechTool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.model = modelName
+	return t
+}
+
+// WithVoice sets the voice used when the model does not choose one
+func (t *SpeechTool) WithVoice(voice string) *SpeechTool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.voice = voice
+	return t
+}
+
+// WithVoices lets the model choose between the given voices
+func (t *SpeechTool) WithVoices(voices ...string) *SpeechTool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.voices = voices
+	return t
+}
+
+// WithFormat sets the audio format, such as mp3 or wav
+func (t *SpeechTool) WithFormat(format string) *SpeechTool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.format = format
+	return t
+}
+
+// WithInstructions sets the tone and style of the voice
+func (t *SpeechTool) WithInstructions(instructions string) *SpeechTool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.instructions = instructions
+	return t
+}
+
+// GetName returns the name of the tool
+func (t *SpeechTool) GetName() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.name
+}
+
+// GetDescription returns the description of the tool
+func (t *SpeechTool) GetDescription() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.description
+}
+
+// GetParametersSchema returns the JSON schema for the tool parameters
+func (t *SpeechTool) GetParametersSchema() map[string]interface{} {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	properties := map[string]interface{}{
+		"text": map[string]interface{}{
+			"type":        "string",
+			"description": "The text to speak",
+		},
+	}
+	if len(t.voices) > 0 {
+		voices := make([]interface{}, len(t.voices))
+		for i, voice := range t.voices {
+			voices[i] = voice
+		}
+		properties["voice"] = map[string]interface{}{
+			"type":        "string",
+			"description": "The voice to speak with",
+			"enum":        voices,
+		}
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   []string{"text"},
+	}
+}
+
+// Execute synthesizes the text and stores the audio
+func (t *SpeechTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	text, _ := params["text"].(string)
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("text parameter is required")
+	}
+
+	t.mu.RLock()
+	request := &model.SpeechRequest{
+		Text:         text,
+		Model:        t.model,
+		Voice:        t.voice,
+		Format:       t.format,
+		Instructions: t.instructions,
+	}
+	voices := t.voices
+	t.mu.RUnlock()
+
+	if voice, ok := params["voice"].(string); ok && voice != "" && len(voices) > 0 {
+		if !contains(voices, voice) {
+			return nil, fmt.Errorf("invalid voice %q, expected one of %s", voice, strings.Join(voices, ", "))
+		}
+		request.Voice = voice
+	}
+
+	speech, err := t.provider.Synthesize(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("speech synthesis failed: %w", err)
+	}
+
+	stored, err := t.store.Put(ctx, artifact.Artifact{
+		Name:      "speech",
+		MediaType: speech.MediaType,
+		Metadata:  map[string]string{"voice": request.Voice},
+	}, speech.Audio)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store speech: %w", err)
+	}
+
+	return &SpeechResponse{ArtifactID: stored.ID, MediaType: stored.MediaType, Size: stored.Size}, nil
+}
+
+// filename returns a file name with an extension matching the media type of an
+// artifact, which transcription APIs use to detect the format
+func filename(stored artifact.Artifact) string {
+	if strings.Contains(stored.Name, ".") {
+		return stored.Name
+	}
+	base := stored.Name
+	if base == "" {
+		base = stored.ID
+	}
+
+	format := "wav"
+	if stored.MediaType != "" {
+		format = model.ContentPart{Type: model.ContentTypeAudio, MediaType: stored.MediaType}.AudioFormat()
+	}
+	return base + "." + format
+}
+
+// contains reports whether values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,312 @@
+// Package exec provides tools that run commands and shell scripts for agents,
+// constrained by an allowlist, a working directory, a timeout and an output limit.
+// Commands can optionally run inside a Docker container.
+//
+//	executor := exec.New("./workspace").
+//		WithAllowedCommands("go", "npm", "tsc").
+//		WithTimeout(2 * time.Minute)
+//	validator := agent.NewAgent("Validator").WithTools(executor.Tools()...)
+//
+// An executor created with NewWorkspaceExecutor runs commands in the workspace of
+// the run each tool call belongs to (see package workspace).
+package exec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/workspace"
+)
+
+const (
+	// DefaultTimeout is how long a command may run by default
+	DefaultTimeout = 30 * time.Second
+
+	// DefaultMaxOutputBytes is how much of stdout and stderr is returned by default
+	DefaultMaxOutputBytes = 64 << 10
+)
+
+// ErrCommandNotAllowed is returned for commands missing from the allowlist
+var ErrCommandNotAllowed = errors.New("command is not allowed")
+
+// CommandParams are the parameters of run_command
+type CommandParams struct {
+	Command string   `json:"command" doc:"Program to run, without a path"`
+	Args    []string `json:"args,omitempty" doc:"Arguments passed to the program"`
+}
+
+// ScriptParams are the parameters of run_script
+type ScriptParams struct {
+	Script string `json:"script" doc:"Shell script to run"`
+}
+
+// Result is the outcome of a command
+type Result struct {
+	ExitCode   int    `json:"exit_code"`
+	Stdout     string `json:"stdout"`
+	Stderr     string `json:"stderr"`
+	Truncated  bool   `json:"truncated,omitempty"`
+	TimedOut   bool   `json:"timed_out,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Executor runs commands for agents
+type Executor struct {
+	dir            string
+	allowed        map[string]bool
+	shell          string
+	timeout        time.Duration
+	maxOutputBytes int
+	env            []string
+	dockerImage    string
+	dockerArgs     []string
+	inWorkspace    bool
+	mu             sync.RWMutex
+}
+
+// New creates an executor running commands in the given directory. No command is
+// allowed until WithAllowedCommands is called.
+func New(dir string) *Executor {
+	return &Executor{
+		dir:            dir,
+		allowed:        make(map[string]bool),
+		timeout:        DefaultTimeout,
+		maxOutputBytes: DefaultMaxOutputBytes,
+		env:            []string{"PATH=" + os.Getenv("PATH")},
+	}
+}
+
+// NewWorkspaceExecutor creates an executor running commands in the workspace of
+// the run each tool call belongs to. Calls outside of a run with a workspace fail
+// with workspace.ErrNoWorkspace.
+func NewWorkspaceExecutor() *Executor {
+	e := New("")
+	e.inWorkspace = true
+	return e
+}
+
+// WithAllowedCommands adds programs that run_command may run
+func (e *Executor) WithAllowedCommands(commands ...string) *Executor {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, command := range commands {
+		e.allowed[command] = true
+	}
+	return e
+}
+
+// WithShell enables the run_script tool, running scripts with the given shell,
+// such as "sh" or "bash". Scripts can run any program, so enable them only
+// together with Docker isolation or in a disposable environment.
+func (e *Executor) WithShell(shell string) *Executor {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shell = shell
+	return e
+}
+
+// WithTimeout sets how long a command may run
+func (e *Executor) WithTimeout(timeout time.Duration) *Executor {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.timeout = timeout
+	return e
+}
+
+// WithMaxOutputBytes sets how much of stdout and stderr is returned
+func (e *Executor) WithMaxOutputBytes(n int) *Executor {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.maxOutputBytes = n
+	return e
+}
+
+// WithEnv adds KEY=value environment variables. Commands only see PATH and the
+// variables added here.
+func (e *Executor) WithEnv(env ...string) *Executor {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.env = append(e.env, env...)
+	return e
+}
+
+// WithDocker runs every command in a new container of the image, with the working
+// directory mounted at /workspace and networking disabled. Extra docker run
+// arguments, such as resource limits, can be passed with args.
+func (e *Executor) WithDocker(image string, args ...string) *Executor {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.dockerImage = image
+	e.dockerArgs = args
+	return e
+}
+
+// Tools returns run_command, and run_script if a shell is configured
+func (e *Executor) Tools() []tool.Tool {
+	tools := []tool.Tool{e.CommandTool()}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.shell != "" {
+		tools = append(tools, e.ScriptTool())
+	}
+	return tools
+}
+
+// CommandTool returns the run_command tool
+func (e *Executor) CommandTool() tool.Tool {
+	e.mu.RLock()
+	allowed := make([]string, 0, len(e.allowed))
+	for command := range e.allowed {
+		allowed = append(allowed, command)
+	}
+	e.mu.RUnlock()
+
+	description := "Run a program in the workspace and return its exit code and output."
+	if len(allowed) > 0 {
+		sort.Strings(allowed)
+		description += " Allowed programs: " + strings.Join(allowed, ", ") + "."
+	}
+	return tool.NewTypedTool("run_command", description, e.RunCommand)
+}
+
+// ScriptTool returns the run_script tool
+func (e *Executor) ScriptTool() tool.Tool {
+	return tool.NewTypedTool("run_script", "Run a shell script in the workspace and return its exit code and output.", e.RunScript)
+}
+
+// RunCommand runs an allowlisted program
+func (e *Executor) RunCommand(ctx context.Context, params CommandParams) (*Result, error) {
+	e.mu.RLock()
+	allowed := e.allowed[params.Command]
+	e.mu.RUnlock()
+
+	if params.Command == "" {
+		return nil, errors.New("command is required")
+	}
+	if strings.ContainsRune(params.Command, '/') || strings.ContainsRune(params.Command, filepath.Separator) || !allowed {
+		return nil, fmt.Errorf("%s: %w", params.Command, ErrCommandNotAllowed)
+	}
+	return e.run(ctx, params.Command, params.Args)
+}
+
+// RunScript runs a shell script
+func (e *Executor) RunScript(ctx context.Context, params ScriptParams) (*Result, error) {
+	e.mu.RLock()
+	shell := e.shell
+	e.mu.RUnlock()
+
+	if shell == "" {
+		return nil, errors.New("scripts are not enabled")
+	}
+	if strings.TrimSpace(params.Script) == "" {
+		return nil, errors.New("script is required")
+	}
+	return e.run(ctx, shell, []string{"-c", params.Script})
+}
+
+// run executes a program with the executor's limits
+func (e *Executor) run(ctx context.Context, program string, args []string) (*Result, error) {
+	e.mu.RLock()
+	dir, timeout, maxOutput := e.dir, e.timeout, e.maxOutputBytes
+	env := append([]string(nil), e.env...)
+	image, dockerArgs := e.dockerImage, append([]string(nil), e.dockerArgs...)
+	inWorkspace := e.inWorkspace
+	e.mu.RUnlock()
+
+	if inWorkspace {
+		ws := workspace.FromContext(ctx)
+		if ws == nil {
+			return nil, workspace.ErrNoWorkspace
+		}
+		dir = ws.Path()
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve working directory: %w", err)
+	}
+
+	if image != "" {
+		dockerCmd := []string{"run", "--rm", "--network", "none", "-v", absDir + ":/workspace", "-w", "/workspace"}
+		for _, kv := range env[1:] {
+			dockerCmd = append(dockerCmd, "-e", kv)
+		}
+		dockerCmd = append(dockerCmd, dockerArgs...)
+		dockerCmd = append(dockerCmd, image, program)
+		program, args = "docker", append(dockerCmd, args...)
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	stdout := &limitedBuffer{limit: maxOutput}
+	stderr := &limitedBuffer{limit: maxOutput}
+	cmd := osexec.CommandContext(ctx, program, args...)
+	cmd.Dir = absDir
+	cmd.Env = env
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// Don't wait for children that keep the output pipes open after a kill
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	err = cmd.Run()
+	result := &Result{
+		Stdout:     stdout.String(),
+		Stderr:     stderr.String(),
+		Truncated:  stdout.truncated || stderr.truncated,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+
+	var exitErr *osexec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		result.TimedOut = true
+		result.ExitCode = -1
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		return nil, fmt.Errorf("failed to run %s: %w", program, err)
+	}
+
+	logging.For(nil, "exec").Debug("Ran command", "program", program, "args", args, "exit_code", result.ExitCode, "duration_ms", result.DurationMS)
+	return result, nil
+}
+
+// limitedBuffer keeps the first limit bytes written to it
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+// Write stores as much of p as fits and reports the whole write as successful
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buf.Len(); remaining < len(p) {
+		b.truncated = true
+		if remaining > 0 {
+			b.buf.Write(p[:remaining])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// String returns the stored output
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,283 @@
+// Package fs provides read_file, write_file, list_dir, glob and patch tools that
+// operate inside a sandbox directory.
+//
+// Every path the model passes is resolved against the sandbox root, and paths
+// that escape it, directly or through symbolic links, are rejected:
+//
+//	sandbox, err := fs.NewSandbox("./workspace")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	coder := agent.NewAgent("Coder").WithTools(sandbox.Tools()...)
+//
+// A sandbox created with NewWorkspaceSandbox is rooted at the workspace of the run
+// each tool call belongs to (see package workspace).
+package fs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/workspace"
+)
+
+const (
+	// DefaultMaxReadBytes is the largest file read_file returns by default
+	DefaultMaxReadBytes = 1 << 20
+
+	// DefaultMaxWriteBytes is the largest content write_file and patch write by default
+	DefaultMaxWriteBytes = 1 << 20
+
+	// DefaultMaxEntries is the largest number of entries list_dir and glob return by default
+	DefaultMaxEntries = 1000
+)
+
+// ErrOutsideRoot is returned for paths that resolve outside the sandbox root
+var ErrOutsideRoot = errors.New("path is outside the sandbox root")
+
+// Sandbox confines the filesystem tools to a root directory
+type Sandbox struct {
+	root          string
+	maxReadBytes  int64
+	maxWriteBytes int64
+	maxEntries    int
+	dryRun        bool
+	mu            sync.RWMutex
+
+	// inWorkspace roots the sandbox at the workspace of each call's run, and
+	// workspace is that workspace in the sandbox of a call
+	inWorkspace bool
+	workspace   *workspace.Workspace
+}
+
+// NewSandbox creates a sandbox rooted at an existing directory
+func NewSandbox(root string) (*Sandbox, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve sandbox root: %w", err)
+	}
+	abs, err = filepath.EvalSymlinks(abs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve sandbox root: %w", err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve sandbox root: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("sandbox root %s is not a directory", root)
+	}
+
+	return &Sandbox{
+		root:          abs,
+		maxReadBytes:  DefaultMaxReadBytes,
+		maxWriteBytes: DefaultMaxWriteBytes,
+		maxEntries:    DefaultMaxEntries,
+	}, nil
+}
+
+// NewWorkspaceSandbox creates a sandbox rooted at the workspace of the run each
+// tool call belongs to. Calls outside of a run with a workspace fail with
+// workspace.ErrNoWorkspace, and writes that would exceed the workspace's quota
+// fail with workspace.ErrQuotaExceeded.
+func NewWorkspaceSandbox() *Sandbox {
+	return &Sandbox{
+		maxReadBytes:  DefaultMaxReadBytes,
+		maxWriteBytes: DefaultMaxWriteBytes,
+		maxEntries:    DefaultMaxEntries,
+		inWorkspace:   true,
+	}
+}
+
+// WithMaxReadBytes sets the largest file read_file returns
+func (s *Sandbox) WithMaxReadBytes(n int64) *Sandbox {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxReadBytes = n
+	return s
+}
+
+// WithMaxWriteBytes sets the largest content write_file and patch write
+func (s *Sandbox) WithMaxWriteBytes(n int64) *Sandbox {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxWriteBytes = n
+	return s
+}
+
+// WithMaxEntries sets the largest number of entries list_dir and glob return
+func (s *Sandbox) WithMaxEntries(n int) *Sandbox {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxEntries = n
+	return s
+}
+
+// WithDryRun makes write_file and patch report what they would change without
+// touching the filesystem
+func (s *Sandbox) WithDryRun(dryRun bool) *Sandbox {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dryRun = dryRun
+	return s
+}
+
+// Root returns the absolute path of the sandbox root, which is empty for workspace
+// sandboxes
+func (s *Sandbox) Root() string {
+	return s.root
+}
+
+// Tools returns all filesystem tools of the sandbox
+func (s *Sandbox) Tools() []tool.Tool {
+	return []tool.Tool{
+		s.ReadFileTool(),
+		s.WriteFileTool(),
+		s.ListDirTool(),
+		s.GlobTool(),
+		s.PatchTool(),
+	}
+}
+
+// forCall returns the sandbox a tool call operates in
+func (s *Sandbox) forCall(ctx context.Context) (*Sandbox, error) {
+	if !s.inWorkspace {
+		return s, nil
+	}
+	ws := workspace.FromContext(ctx)
+	if ws == nil {
+		return nil, workspace.ErrNoWorkspace
+	}
+	maxRead, maxWrite, maxEntries, dryRun := s.limits()
+	return &Sandbox{
+		root:          ws.Path(),
+		maxReadBytes:  maxRead,
+		maxWriteBytes: maxWrite,
+		maxEntries:    maxEntries,
+		dryRun:        dryRun,
+		workspace:     ws,
+	}, nil
+}
+
+// bind returns a tool function running in the sandbox of each call
+func bind[P, R any](s *Sandbox, fn func(*Sandbox, context.Context, P) (R, error)) func(context.Context, P) (R, error) {
+	return func(ctx context.Context, params P) (R, error) {
+		sandbox, err := s.forCall(ctx)
+		if err != nil {
+			var zero R
+			return zero, err
+		}
+		return fn(sandbox, ctx, params)
+	}
+}
+
+// checkQuota returns an error if growing the files of the sandbox by the given
+// number of bytes would exceed the quota of its workspace
+func (s *Sandbox) checkQuota(growth int64) error {
+	if s.workspace == nil || growth <= 0 {
+		return nil
+	}
+	return s.workspace.CheckQuota(growth)
+}
+
+// limits returns the configured limits
+func (s *Sandbox) limits() (maxRead, maxWrite int64, maxEntries int, dryRun bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.maxReadBytes, s.maxWriteBytes, s.maxEntries, s.dryRun
+}
+
+// resolve maps a path from the model to an absolute path inside the root. The
+// path may not exist yet; its closest existing ancestor is checked for symbolic
+// links leading out of the root.
+func (s *Sandbox) resolve(path string) (string, error) {
+	if path == "" {
+		path = "."
+	}
+
+	var abs string
+	if filepath.IsAbs(path) {
+		abs = filepath.Clean(path)
+	} else {
+		abs = filepath.Join(s.root, path)
+	}
+	if !s.contains(abs) {
+		return "", fmt.Errorf("%s: %w", path, ErrOutsideRoot)
+	}
+
+	// Follow symbolic links in the part of the path that exists
+	existing := abs
+	var missing []string
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			existing = resolved
+			break
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+		missing = append([]string{filepath.Base(existing)}, missing...)
+		existing = filepath.Dir(existing)
+	}
+
+	resolved := filepath.Join(append([]string{existing}, missing...)...)
+	if !s.contains(resolved) {
+		return "", fmt.Errorf("%s: %w", path, ErrOutsideRoot)
+	}
+	return resolved, nil
+}
+
+// contains reports whether an absolute path is the root or inside it
+func (s *Sandbox) contains(abs string) bool {
+	rel, err := filepath.Rel(s.root, abs)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// relative returns the path of an absolute path relative to the root, with forward slashes
+func (s *Sandbox) relative(abs string) string {
+	rel, err := filepath.Rel(s.root, abs)
+	if err != nil {
+		return abs
+	}
+	return filepath.ToSlash(rel)
+}
+
+// writeFile replaces the content of a file atomically
+func writeFile(path string, content []byte) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,384 @@
+package fs
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+)
+
+// ReadFileParams are the parameters of read_file
+type ReadFileParams struct {
+	Path      string `json:"path" doc:"Path of the file, relative to the workspace root"`
+	StartLine int    `json:"start_line,omitempty" doc:"First line to return, starting at 1"`
+	EndLine   int    `json:"end_line,omitempty" doc:"Last line to return, inclusive"`
+}
+
+// ReadFileResult is the result of read_file
+type ReadFileResult struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	Size    int64  `json:"size"`
+}
+
+// WriteFileParams are the parameters of write_file
+type WriteFileParams struct {
+	Path       string `json:"path" doc:"Path of the file, relative to the workspace root"`
+	Content    string `json:"content" doc:"The new content of the file"`
+	CreateDirs bool   `json:"create_dirs,omitempty" doc:"Create missing parent directories"`
+}
+
+// WriteFileResult is the result of write_file
+type WriteFileResult struct {
+	Path    string `json:"path"`
+	Bytes   int    `json:"bytes"`
+	Created bool   `json:"created"`
+	DryRun  bool   `json:"dry_run,omitempty"`
+}
+
+// ListDirParams are the parameters of list_dir
+type ListDirParams struct {
+	Path string `json:"path,omitempty" doc:"Directory to list, relative to the workspace root. Defaults to the root."`
+}
+
+// Entry is a directory entry
+type Entry struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Size int64  `json:"size,omitempty"`
+}
+
+// ListDirResult is the result of list_dir
+type ListDirResult struct {
+	Path      string  `json:"path"`
+	Entries   []Entry `json:"entries"`
+	Truncated bool    `json:"truncated,omitempty"`
+}
+
+// GlobParams are the parameters of glob
+type GlobParams struct {
+	Pattern string `json:"pattern" doc:"Glob pattern relative to the workspace root, such as src/**/*.go"`
+}
+
+// GlobResult is the result of glob
+type GlobResult struct {
+	Pattern   string   `json:"pattern"`
+	Matches   []string `json:"matches"`
+	Truncated bool     `json:"truncated,omitempty"`
+}
+
+// Edit replaces text in a file
+type Edit struct {
+	OldText string `json:"old_text" doc:"Exact text to replace; must occur exactly once in the file"`
+	NewText string `json:"new_text" doc:"Replacement text"`
+}
+
+// PatchParams are the parameters of patch
+type PatchParams struct {
+	Path  string `json:"path" doc:"Path of the file, relative to the workspace root"`
+	Edits []Edit `json:"edits" doc:"Edits applied in order"`
+}
+
+// PatchResult is the result of patch
+type PatchResult struct {
+	Path    string `json:"path"`
+	Applied int    `json:"applied"`
+	Bytes   int    `json:"bytes"`
+	DryRun  bool   `json:"dry_run,omitempty"`
+}
+
+// ReadFileTool returns the read_file tool
+func (s *Sandbox) ReadFileTool() tool.Tool {
+	return tool.NewTypedTool("read_file", "Read a text file from the workspace, optionally a range of lines.", bind(s, (*Sandbox).readFile))
+}
+
+// WriteFileTool returns the write_file tool
+func (s *Sandbox) WriteFileTool() tool.Tool {
+	return tool.NewTypedTool("write_file", "Create or overwrite a file in the workspace.", bind(s, (*Sandbox).writeFile))
+}
+
+// ListDirTool returns the list_dir tool
+func (s *Sandbox) ListDirTool() tool.Tool {
+	return tool.NewTypedTool("list_dir", "List the files and directories in a workspace directory.", bind(s, (*Sandbox).listDir))
+}
+
+// GlobTool returns the glob tool
+func (s *Sandbox) GlobTool() tool.Tool {
+	return tool.NewTypedTool("glob", "Find workspace files matching a glob pattern. ** matches any number of directories.", bind(s, (*Sandbox).glob))
+}
+
+// PatchTool returns the patch tool
+func (s *Sandbox) PatchTool() tool.Tool {
+	return tool.NewTypedTool("patch", "Edit a file in the workspace by replacing exact text fragments.", bind(s, (*Sandbox).patch))
+}
+
+// readFile implements read_file
+func (s *Sandbox) readFile(ctx context.Context, params ReadFileParams) (*ReadFileResult, error) {
+	maxRead, _, _, _ := s.limits()
+
+	abs, err := s.resolve(params.Path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", params.Path, err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", params.Path)
+	}
+
+	result := &ReadFileResult{Path: s.relative(abs), Size: info.Size()}
+	if params.StartLine <= 0 && params.EndLine <= 0 {
+		if info.Size() > maxRead {
+			return nil, fmt.Errorf("%s is %d bytes, more than the %d byte limit; read a range of lines instead", params.Path, info.Size(), maxRead)
+		}
+		data, err := os.ReadFile(abs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", params.Path, err)
+		}
+		result.Content = string(data)
+		return result, nil
+	}
+
+	file, err := os.Open(abs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", params.Path, err)
+	}
+	defer file.Close()
+
+	start := params.StartLine
+	if start <= 0 {
+		start = 1
+	}
+
+	var content strings.Builder
+	reader := bufio.NewReader(file)
+	for line := 1; params.EndLine <= 0 || line <= params.EndLine; line++ {
+		text, err := reader.ReadString('\n')
+		if line >= start {
+			if int64(content.Len()+len(text)) > maxRead {
+				return nil, fmt.Errorf("lines %d-%d of %s exceed the %d byte limit; read fewer lines", start, line, params.Path, maxRead)
+			}
+			content.WriteString(text)
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	result.Content = content.String()
+	return result, nil
+}
+
+// writeFile implements write_file
+func (s *Sandbox) writeFile(ctx context.Context, params WriteFileParams) (*WriteFileResult, error) {
+	_, maxWrite, _, dryRun := s.limits()
+
+	if int64(len(params.Content)) > maxWrite {
+		return nil, fmt.Errorf("content is %d bytes, more than the %d byte limit", len(params.Content), maxWrite)
+	}
+
+	abs, err := s.resolve(params.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(abs)
+	created := os.IsNotExist(err)
+	if err == nil && info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", params.Path)
+	}
+
+	result := &WriteFileResult{Path: s.relative(abs), Bytes: len(params.Content), Created: created, DryRun: dryRun}
+	if dryRun {
+		return result, nil
+	}
+	growth := int64(len(params.Content))
+	if !created && info != nil {
+		growth -= info.Size()
+	}
+	if err := s.checkQuota(growth); err != nil {
+		return nil, err
+	}
+
+	if params.CreateDirs {
+		if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create directories for %s: %w", params.Path, err)
+		}
+	}
+	if err := writeFile(abs, []byte(params.Content)); err != nil {
+		return nil, fmt.Errorf("%s: %w", params.Path, err)
+	}
+	return result, nil
+}
+
+// listDir implements list_dir
+func (s *Sandbox) listDir(ctx context.Context, params ListDirParams) (*ListDirResult, error) {
+	_, _, maxEntries, _ := s.limits()
+
+	abs, err := s.resolve(params.Path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(abs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", params.Path, err)
+	}
+
+	result := &ListDirResult{Path: s.relative(abs), Entries: make([]Entry, 0, len(entries))}
+	for _, entry := range entries {
+		if len(result.Entries) >= maxEntries {
+			result.Truncated = true
+			break
+		}
+
+		e := Entry{Name: entry.Name(), Type: "file"}
+		switch {
+		case entry.Type()&fs.ModeSymlink != 0:
+			e.Type = "symlink"
+		case entry.IsDir():
+			e.Type = "dir"
+		default:
+			if info, err := entry.Info(); err == nil {
+				e.Size = info.Size()
+			}
+		}
+		result.Entries = append(result.Entries, e)
+	}
+	return result, nil
+}
+
+// glob implements glob
+func (s *Sandbox) glob(ctx context.Context, params GlobParams) (*GlobResult, error) {
+	_, _, maxEntries, _ := s.limits()
+
+	pattern := strings.TrimPrefix(filepath.ToSlash(params.Pattern), "./")
+	if pattern == "" {
+		return nil, errors.New("pattern is required")
+	}
+	if path.IsAbs(pattern) || pattern == ".." || strings.HasPrefix(pattern, "../") {
+		return nil, fmt.Errorf("%s: %w", params.Pattern, ErrOutsideRoot)
+	}
+	if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %s: %w", params.Pattern, err)
+	}
+	segments := strings.Split(pattern, "/")
+
+	result := &GlobResult{Pattern: params.Pattern, Matches: make([]string, 0)}
+	errStop := errors.New("stop")
+	err := filepath.WalkDir(s.root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if p == s.root {
+			return nil
+		}
+
+		rel := s.relative(p)
+		if matchSegments(segments, strings.Split(rel, "/")) {
+			if len(result.Matches) >= maxEntries {
+				result.Truncated = true
+				return errStop
+			}
+			result.Matches = append(result.Matches, rel)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errStop) {
+		return nil, err
+	}
+
+	sort.Strings(result.Matches)
+	return result, nil
+}
+
+// matchSegments matches path segments against pattern segments, where a "**"
+// segment matches any number of path segments
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
+
+// patch implements patch
+func (s *Sandbox) patch(ctx context.Context, params PatchParams) (*PatchResult, error) {
+	maxRead, maxWrite, _, dryRun := s.limits()
+
+	if len(params.Edits) == 0 {
+		return nil, errors.New("at least one edit is required")
+	}
+
+	abs, err := s.resolve(params.Path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", params.Path, err)
+	}
+	if info.Size() > maxRead {
+		return nil, fmt.Errorf("%s is %d bytes, more than the %d byte limit", params.Path, info.Size(), maxRead)
+	}
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", params.Path, err)
+	}
+
+	content := string(data)
+	for i, edit := range params.Edits {
+		if edit.OldText == "" {
+			return nil, fmt.Errorf("edit %d: old_text is empty", i+1)
+		}
+		switch n := strings.Count(content, edit.OldText); n {
+		case 0:
+			return nil, fmt.Errorf("edit %d: old_text not found in %s", i+1, params.Path)
+		case 1:
+			content = strings.Replace(content, edit.OldText, edit.NewText, 1)
+		default:
+			return nil, fmt.Errorf("edit %d: old_text occurs %d times in %s; include more context", i+1, n, params.Path)
+		}
+	}
+
+	if int64(len(content)) > maxWrite {
+		return nil, fmt.Errorf("patched file is %d bytes, more than the %d byte limit", len(content), maxWrite)
+	}
+
+	result := &PatchResult{Path: s.relative(abs), Applied: len(params.Edits), Bytes: len(content), DryRun: dryRun}
+	if dryRun {
+		return result, nil
+	}
+	if err := s.checkQuota(int64(len(content) - len(data))); err != nil {
+		return nil, err
+	}
+	if err := writeFile(abs, []byte(content)); err != nil {
+		return nil, fmt.Errorf("%s: %w", params.Path, err)
+	}
+	return result, nil
+}
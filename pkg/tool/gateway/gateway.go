@@ -0,0 +1,345 @@
+// Package gateway provides a shared outbound HTTP gateway for tools that call
+// external APIs. It limits the request rate to each host, stops calling hosts
+// that keep failing with a circuit breaker and retries failed requests within a
+// retry budget, so that one misbehaving API cannot cascade failures through a
+// workflow whose tools all call it at once.
+//
+// Tools share host state by sending their requests through the same gateway:
+//
+//	gw := gateway.New().
+//		WithHostPolicy("api.example.com", gateway.HostPolicy{RequestsPerMinute: 120})
+//	search := websearch.New(websearch.NewTavily(key).WithHTTPClient(gw.Client()))
+//	fetch := httpfetch.New().WithGateway(gw)
+//
+// Requests to a host whose breaker is open fail immediately with ErrCircuitOpen.
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/ratelimit"
+)
+
+// ErrCircuitOpen is returned for requests to a host whose circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// BreakerState is the state of the circuit breaker of a host
+type BreakerState string
+
+const (
+	// BreakerClosed lets requests through
+	BreakerClosed BreakerState = "closed"
+
+	// BreakerOpen rejects requests until the open timeout has passed
+	BreakerOpen BreakerState = "open"
+
+	// BreakerHalfOpen lets one probe request through to decide whether the host
+	// has recovered
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// HostPolicy limits the requests to a host
+type HostPolicy struct {
+	// RequestsPerMinute is the most requests sent to the host per minute, with
+	// retries included. Zero means unlimited.
+	RequestsPerMinute int
+
+	// FailureThreshold is the number of consecutive failures that opens the
+	// circuit breaker. Server errors and transport errors are failures.
+	FailureThreshold int
+
+	// OpenTimeout is how long the breaker stays open before a probe request is let
+	// through
+	OpenTimeout time.Duration
+
+	// MaxRetries is the most times one request is retried. A negative value
+	// disables retries.
+	MaxRetries int
+
+	// RetryBackoff is the delay before the first retry. It doubles with each
+	// retry, unless the host asks for a longer delay with Retry-After.
+	RetryBackoff time.Duration
+
+	// RetryBudget is the share of the requests of the last budget window that
+	// may be retries, such as 0.2 for one retry per five requests
+	RetryBudget float64
+
+	// MinRetries is the number of retries per budget window allowed regardless
+	// of the budget, so that hosts with few requests can still be retried
+	MinRetries int
+}
+
+// BudgetWindow is the window the retry budget of a host is computed over
+const BudgetWindow = 10 * time.Second
+
+// DefaultPolicy returns the policy of hosts without their own
+func DefaultPolicy() HostPolicy {
+	return HostPolicy{
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+		MaxRetries:       2,
+		RetryBackoff:     200 * time.Millisecond,
+		RetryBudget:      0.2,
+		MinRetries:       10,
+	}
+}
+
+// HostStatus is a snapshot of the state of a host
+type HostStatus struct {
+	// Host is the name of the host
+	Host string
+
+	// State is the state of the host's circuit breaker
+	State BreakerState
+
+	// ConsecutiveFailures is the number of failures since the last success
+	ConsecutiveFailures int
+
+	// OpenUntil is when an open breaker lets a probe request through
+	OpenUntil time.Time
+
+	// Requests and Retries are the requests and retries of the budget window
+	Requests int
+	Retries  int
+
+	// RateLimit is the state of the host's rate limiter
+	RateLimit ratelimit.Status
+}
+
+// Gateway sends the HTTP requests of tools, applying the policy of each host
+type Gateway struct {
+	transport http.RoundTripper
+	defaults  HostPolicy
+	policies  map[string]HostPolicy
+	hosts     map[string]*host
+	mu        sync.Mutex
+}
+
+// New creates a gateway sending requests with http.DefaultTransport and applying
+// DefaultPolicy to every host
+func New() *Gateway {
+	return &Gateway{
+		transport: http.DefaultTransport,
+		defaults:  DefaultPolicy(),
+		policies:  make(map[string]HostPolicy),
+		hosts:     make(map[string]*host),
+	}
+}
+
+// WithTransport sets the transport that sends the requests
+func (g *Gateway) WithTransport(transport http.RoundTripper) *Gateway {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.transport = transport
+	return g
+}
+
+// WithDefaultPolicy sets the policy of hosts without their own. Zero fields take
+// the values of DefaultPolicy.
+func (g *Gateway) WithDefaultPolicy(policy HostPolicy) *Gateway {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.defaults = withDefaults(policy)
+	g.hosts = make(map[string]*host)
+	return g
+}
+
+// WithHostPolicy sets the policy of a host, given by name without a port. Zero
+// fields take the values of DefaultPolicy.
+func (g *Gateway) WithHostPolicy(hostname string, policy HostPolicy) *Gateway {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.policies[hostname] = withDefaults(policy)
+	delete(g.hosts, hostname)
+	return g
+}
+
+// Client returns an HTTP client sending its requests through the gateway
+func (g *Gateway) Client() *http.Client {
+	return &http.Client{Transport: g}
+}
+
+// Wrap returns a transport that applies the gateway's host policies and state to
+// requests sent with base, for tools that need their own transport
+func (g *Gateway) Wrap(base http.RoundTripper) http.RoundTripper {
+	return &transport{gateway: g, base: base}
+}
+
+// RoundTrip sends a request with the gateway's transport
+func (g *Gateway) RoundTrip(req *http.Request) (*http.Response, error) {
+	g.mu.Lock()
+	base := g.transport
+	g.mu.Unlock()
+	return g.roundTrip(req, base)
+}
+
+// Status returns the state of a host
+func (g *Gateway) Status(hostname string) HostStatus {
+	return g.host(hostname).status(time.Now())
+}
+
+// host returns the state of a host, creating it on first use
+func (g *Gateway) host(hostname string) *host {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	h, ok := g.hosts[hostname]
+	if !ok {
+		policy, ok := g.policies[hostname]
+		if !ok {
+			policy = g.defaults
+		}
+		h = newHost(hostname, policy)
+		g.hosts[hostname] = h
+	}
+	return h
+}
+
+// roundTrip sends a request with base, retrying it while the policy and the
+// retry budget of its host allow
+func (g *Gateway) roundTrip(req *http.Request, base http.RoundTripper) (*http.Response, error) {
+	h := g.host(req.URL.Hostname())
+	ctx := req.Context()
+	replayable := canReplay(req)
+
+	for attempt := 0; ; attempt++ {
+		if err := h.acquire(time.Now()); err != nil {
+			return nil, err
+		}
+		if err := h.limiter.Wait(ctx, nil); err != nil {
+			h.release()
+			return nil, fmt.Errorf("rate limit wait for %s: %w", h.name, err)
+		}
+		h.recordAttempt(time.Now(), attempt > 0)
+
+		attemptReq := req
+		if attempt > 0 {
+			var err error
+			if attemptReq, err = rewind(req); err != nil {
+				h.release()
+				return nil, err
+			}
+		}
+		resp, err := base.RoundTrip(attemptReq)
+
+		switch {
+		case err != nil && ctx.Err() != nil:
+			// The caller gave up; that says nothing about the host
+			h.release()
+			return nil, err
+		case err != nil || resp.StatusCode >= 500:
+			h.recordFailure(time.Now())
+		default:
+			h.recordSuccess()
+		}
+
+		var delay time.Duration
+		if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+			if quota, ok := ratelimit.ParseHeaders(resp.Header, time.Now()); ok {
+				h.limiter.Observe(quota)
+				delay = time.Until(quota.ResumeAt())
+			}
+		}
+
+		if !retryable(resp, err) || !replayable || attempt >= h.policy.MaxRetries || !h.allowRetry(time.Now()) {
+			return resp, err
+		}
+		if backoff := h.policy.RetryBackoff << attempt; backoff > delay {
+			delay = backoff
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		logging.For(nil, "gateway").Debug("Retrying request", "host", h.name, "attempt", attempt+1, "delay_ms", delay.Milliseconds(), "error", err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// transport sends requests with its own base transport and the state of a gateway
+type transport struct {
+	gateway *Gateway
+	base    http.RoundTripper
+}
+
+// RoundTrip sends a request through the gateway
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.gateway.roundTrip(req, t.base)
+}
+
+// retryable reports whether a failed request may succeed when sent again
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// canReplay reports whether a request can safely be sent again: it must be
+// idempotent or carry an idempotency key, and its body must be rewindable
+func canReplay(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != ""
+}
+
+// rewind returns a copy of a request with a fresh body
+func rewind(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewind request body: %w", err)
+		}
+		clone.Body = body
+	}
+	return clone, nil
+}
+
+// withDefaults fills the zero fields of a policy from DefaultPolicy
+func withDefaults(policy HostPolicy) HostPolicy {
+	defaults := DefaultPolicy()
+	if policy.FailureThreshold <= 0 {
+		policy.FailureThreshold = defaults.FailureThreshold
+	}
+	if policy.OpenTimeout <= 0 {
+		policy.OpenTimeout = defaults.OpenTimeout
+	}
+	switch {
+	case policy.MaxRetries < 0:
+		policy.MaxRetries = 0
+	case policy.MaxRetries == 0:
+		policy.MaxRetries = defaults.MaxRetries
+	}
+	if policy.RetryBackoff <= 0 {
+		policy.RetryBackoff = defaults.RetryBackoff
+	}
+	if policy.RetryBudget <= 0 {
+		policy.RetryBudget = defaults.RetryBudget
+	}
+	if policy.MinRetries <= 0 {
+		policy.MinRetries = defaults.MinRetries
+	}
+	return policy
+}
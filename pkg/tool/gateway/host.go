@@ -0,0 +1,151 @@
+package gateway
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/ratelimit"
+)
+
+// host is the shared state of the requests to one host
+type host struct {
+	name    string
+	policy  HostPolicy
+	limiter *ratelimit.SlidingWindow
+
+	state     BreakerState
+	failures  int
+	openUntil time.Time
+	probing   bool
+
+	// requests and retries are the start times of the attempts of the budget window
+	requests []time.Time
+	retries  []time.Time
+
+	mu sync.Mutex
+}
+
+// newHost creates the state of a host
+func newHost(name string, policy HostPolicy) *host {
+	return &host{
+		name:    name,
+		policy:  policy,
+		limiter: ratelimit.NewSlidingWindow(policy.RequestsPerMinute, 0),
+		state:   BreakerClosed,
+	}
+}
+
+// acquire checks the circuit breaker before an attempt. An open breaker whose
+// timeout has passed lets one probe attempt through.
+func (h *host) acquire(now time.Time) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.state == BreakerOpen && !now.Before(h.openUntil) {
+		h.state = BreakerHalfOpen
+	}
+	switch {
+	case h.state == BreakerOpen:
+		return fmt.Errorf("%s: %w until %s", h.name, ErrCircuitOpen, h.openUntil.Format(time.RFC3339))
+	case h.state == BreakerHalfOpen && h.probing:
+		return fmt.Errorf("%s: %w while a probe request is in flight", h.name, ErrCircuitOpen)
+	case h.state == BreakerHalfOpen:
+		h.probing = true
+	}
+	return nil
+}
+
+// release ends an attempt that was not sent or whose caller gave up, without
+// judging the host
+func (h *host) release() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.probing = false
+}
+
+// recordSuccess closes the breaker
+func (h *host) recordSuccess() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.state != BreakerClosed {
+		logging.For(nil, "gateway").Info("Circuit breaker closed", "host", h.name)
+	}
+	h.state = BreakerClosed
+	h.failures = 0
+	h.probing = false
+}
+
+// recordFailure counts a failure, opening the breaker at the threshold or when a
+// probe fails
+func (h *host) recordFailure(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.failures++
+	if h.state == BreakerHalfOpen || h.failures >= h.policy.FailureThreshold {
+		if h.state != BreakerOpen {
+			logging.For(nil, "gateway").Warn("Circuit breaker opened", "host", h.name, "failures", h.failures, "open_timeout", h.policy.OpenTimeout)
+		}
+		h.state = BreakerOpen
+		h.openUntil = now.Add(h.policy.OpenTimeout)
+	}
+	h.probing = false
+}
+
+// recordAttempt counts an attempt towards the retry budget
+func (h *host) recordAttempt(now time.Time, retry bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.prune(now)
+	if retry {
+		h.retries = append(h.retries, now)
+	} else {
+		h.requests = append(h.requests, now)
+	}
+}
+
+// allowRetry reports whether the retry budget has room for another retry
+func (h *host) allowRetry(now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.prune(now)
+	retries := len(h.retries)
+	return retries < h.policy.MinRetries || float64(retries) < h.policy.RetryBudget*float64(len(h.requests))
+}
+
+// prune forgets attempts older than the budget window. The caller must hold h.mu.
+func (h *host) prune(now time.Time) {
+	cutoff := now.Add(-BudgetWindow)
+	h.requests = dropBefore(h.requests, cutoff)
+	h.retries = dropBefore(h.retries, cutoff)
+}
+
+// dropBefore removes the times before cutoff from a sorted slice
+func dropBefore(times []time.Time, cutoff time.Time) []time.Time {
+	i := 0
+	for i < len(times) && times[i].Before(cutoff) {
+		i++
+	}
+	return times[i:]
+}
+
+// status returns a snapshot of the host
+func (h *host) status(now time.Time) HostStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.prune(now)
+	state := h.state
+	if state == BreakerOpen && !now.Before(h.openUntil) {
+		state = BreakerHalfOpen
+	}
+	return HostStatus{
+		Host:                h.name,
+		State:               state,
+		ConsecutiveFailures: h.failures,
+		OpenUntil:           h.openUntil,
+		Requests:            len(h.requests),
+		Retries:             len(h.retries),
+		RateLimit:           h.limiter.Status(),
+	}
+}
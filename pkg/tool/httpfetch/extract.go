@@ -0,0 +1,69 @@
+package httpfetch
+
+import (
+	"encoding/json"
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// htmlSkipped matches elements whose content is not readable text
+	htmlSkipped = regexp.MustCompile(`(?is)<(script|style|noscript|svg|head)\b.*?</(script|style|noscript|svg|head)>`)
+
+	// htmlBlock matches tags that end a line of text
+	htmlBlock = regexp.MustCompile(`(?i)<(br|/p|/div|/li|/tr|/h[1-6]|/section|/article|/header|/footer)\b[^>]*>`)
+
+	// htmlTag matches any remaining tag or comment
+	htmlTag = regexp.MustCompile(`(?s)<!--.*?-->|<[^>]+>`)
+
+	// spaces matches runs of horizontal whitespace
+	spaces = regexp.MustCompile(`[ \t\r\f\v]+`)
+
+	// blankLines matches runs of empty lines
+	blankLines = regexp.MustCompile(`\n\s*\n+`)
+)
+
+// extractBody fills the JSON or Text field of the response from its body
+func extractBody(resp *Response, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+
+	contentType := strings.ToLower(resp.ContentType)
+	if strings.Contains(contentType, "json") || (contentType == "" && json.Valid(data)) {
+		var decoded interface{}
+		if err := json.Unmarshal(data, &decoded); err == nil {
+			resp.JSON = decoded
+			return
+		}
+	}
+
+	if strings.Contains(contentType, "html") {
+		resp.Text = htmlToText(string(data))
+		return
+	}
+
+	if !utf8.Valid(data) {
+		resp.Text = "(binary content omitted)"
+		return
+	}
+	resp.Text = string(data)
+}
+
+// htmlToText reduces an HTML document to its readable text
+func htmlToText(document string) string {
+	text := htmlSkipped.ReplaceAllString(document, " ")
+	text = htmlBlock.ReplaceAllString(text, "\n")
+	text = htmlTag.ReplaceAllString(text, " ")
+	text = html.UnescapeString(text)
+	text = spaces.ReplaceAllString(text, " ")
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	text = blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text)
+}
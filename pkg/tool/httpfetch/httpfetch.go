@@ -0,0 +1,468 @@
+// Package httpfetch provides a generic http_request tool so agents can call REST
+// APIs without a custom tool per endpoint. Requests are limited to allowed domains,
+// responses are capped in size, and JSON and text bodies are extracted for the model.
+//
+//	fetch := httpfetch.New().
+//		WithAllowedDomains("api.github.com", "*.example.com").
+//		WithHeader("Authorization", "Bearer "+os.Getenv("API_TOKEN"))
+//	integrator := agent.NewAgent("Integrator").WithTools(fetch)
+package httpfetch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool/gateway"
+)
+
+const (
+	// DefaultMaxResponseBytes is how much of a response body is read by default
+	DefaultMaxResponseBytes = 1 << 20
+
+	// DefaultMaxRedirects is how many redirects are followed by default
+	DefaultMaxRedirects = 5
+
+	// DefaultTimeout is the default timeout of a request
+	DefaultTimeout = 30 * time.Second
+)
+
+var (
+	// ErrDomainNotAllowed is returned for URLs outside the allowed domains
+	ErrDomainNotAllowed = errors.New("domain is not allowed")
+
+	// ErrPrivateAddress is returned for hosts resolving to loopback, private or
+	// link-local addresses while private networks are blocked
+	ErrPrivateAddress = errors.New("address is in a private network")
+
+	// ErrMethodNotAllowed is returned for HTTP methods the tool does not allow
+	ErrMethodNotAllowed = errors.New("method is not allowed")
+)
+
+// defaultMethods are the methods allowed by default
+var defaultMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// Response is the result of the http_request tool
+type Response struct {
+	StatusCode  int         `json:"status_code"`
+	URL         string      `json:"url"`
+	ContentType string      `json:"content_type,omitempty"`
+	JSON        interface{} `json:"json,omitempty"`
+	Text        string      `json:"text,omitempty"`
+	Truncated   bool        `json:"truncated,omitempty"`
+}
+
+// Tool is the http_request tool
+type Tool struct {
+	name             string
+	description      string
+	allowed          []string
+	denied           []string
+	methods          map[string]bool
+	headers          http.Header
+	maxResponseBytes int64
+	maxRedirects     int
+	allowPrivate     bool
+	timeout          time.Duration
+	transport        http.RoundTripper
+	gateway          *gateway.Gateway
+	mu               sync.RWMutex
+}
+
+// New creates an http_request tool. Any public domain is allowed until
+// WithAllowedDomains is called, and private network addresses are always blocked
+// unless WithPrivateNetworks is used.
+func New() *Tool {
+	methods := make(map[string]bool, len(defaultMethods))
+	for _, method := range defaultMethods {
+		methods[method] = true
+	}
+	return &Tool{
+		name:             "http_request",
+		description:      "Send an HTTP request and return the status code and the response body, decoded if it is JSON.",
+		methods:          methods,
+		headers:          make(http.Header),
+		maxResponseBytes: DefaultMaxResponseBytes,
+		maxRedirects:     DefaultMaxRedirects,
+		timeout:          DefaultTimeout,
+	}
+}
+
+// WithName sets the name of the tool
+func (t *Tool) WithName(name string) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.name = name
+	return t
+}
+
+// WithDescription sets the description of the tool
+func (t *Tool) WithDescription(description string) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.description = description
+	return t
+}
+
+// WithAllowedDomains restricts requests to the given domains. "example.com" matches
+// only that host; "*.example.com" matches its subdomains.
+func (t *Tool) WithAllowedDomains(domains ...string) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.allowed = append(t.allowed, normalizeDomains(domains)...)
+	return t
+}
+
+// WithDeniedDomains blocks the given domains, even if they are allowed
+func (t *Tool) WithDeniedDomains(domains ...string) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.denied = append(t.denied, normalizeDomains(domains)...)
+	return t
+}
+
+// WithMethods sets the HTTP methods the tool may use
+func (t *Tool) WithMethods(methods ...string) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.methods = make(map[string]bool, len(methods))
+	for _, method := range methods {
+		t.methods[strings.ToUpper(method)] = true
+	}
+	return t
+}
+
+// WithHeader adds a header to every request, such as an authorization token the
+// model never sees
+func (t *Tool) WithHeader(key, value string) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.headers.Add(key, value)
+	return t
+}
+
+// WithMaxResponseBytes sets how much of a response body is read
+func (t *Tool) WithMaxResponseBytes(n int64) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.maxResponseBytes = n
+	return t
+}
+
+// WithMaxRedirects sets how many redirects are followed. Zero returns redirect
+// responses to the model instead of following them.
+func (t *Tool) WithMaxRedirects(n int) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.maxRedirects = n
+	return t
+}
+
+// WithPrivateNetworks allows requests to loopback, private and link-local addresses
+func (t *Tool) WithPrivateNetworks(allow bool) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.allowPrivate = allow
+	return t
+}
+
+// WithTimeout sets the timeout of a request
+func (t *Tool) WithTimeout(timeout time.Duration) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.timeout = timeout
+	return t
+}
+
+// WithTransport sets the transport used to send requests. The private network check
+// is only applied by the default transport.
+func (t *Tool) WithTransport(transport http.RoundTripper) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.transport = transport
+	return t
+}
+
+// WithGateway sends requests through an outbound gateway, so that they share its
+// per-host rate limits, circuit breakers and retry budgets with other tools. The
+// private network check still applies.
+func (t *Tool) WithGateway(gw *gateway.Gateway) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.gateway = gw
+	return t
+}
+
+// GetName returns the name of the tool
+func (t *Tool) GetName() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.name
+}
+
+// GetDescription returns the description of the tool
+func (t *Tool) GetDescription() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if len(t.allowed) == 0 {
+		return t.description
+	}
+	return fmt.Sprintf("%s Allowed domains: %s.", t.description, strings.Join(t.allowed, ", "))
+}
+
+// GetParametersSchema returns the JSON schema for the tool parameters
+func (t *Tool) GetParametersSchema() map[string]interface{} {
+	t.mu.RLock()
+	methods := make([]interface{}, 0, len(t.methods))
+	for _, method := range defaultMethods {
+		if t.methods[method] {
+			methods = append(methods, method)
+		}
+	}
+	t.mu.RUnlock()
+
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"method": map[string]interface{}{
+				"type":        "string",
+				"description": "HTTP method, GET by default",
+				"enum":        methods,
+			},
+			"url": map[string]interface{}{
+				"type":        "string",
+				"description": "Absolute http or https URL",
+			},
+			"headers": map[string]interface{}{
+				"type":                 "object",
+				"description":          "Request headers",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+			},
+			"body": map[string]interface{}{
+				"description": "Request body. Objects and arrays are sent as JSON.",
+			},
+		},
+		"required": []string{"url"},
+	}
+}
+
+// Execute sends the request
+func (t *Tool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	rawURL, _ := params["url"].(string)
+	if strings.TrimSpace(rawURL) == "" {
+		return nil, fmt.Errorf("url parameter is required")
+	}
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	if err := t.checkURL(target); err != nil {
+		return nil, err
+	}
+
+	method := http.MethodGet
+	if m, ok := params["method"].(string); ok && m != "" {
+		method = strings.ToUpper(m)
+	}
+	t.mu.RLock()
+	methodAllowed := t.methods[method]
+	t.mu.RUnlock()
+	if !methodAllowed {
+		return nil, fmt.Errorf("%s: %w", method, ErrMethodNotAllowed)
+	}
+
+	body, contentType, err := encodeBody(params["body"])
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if headers, ok := params["headers"].(map[string]interface{}); ok {
+		for key, value := range headers {
+			req.Header.Set(key, fmt.Sprint(value))
+		}
+	}
+
+	t.mu.RLock()
+	for key, values := range t.headers {
+		req.Header.Del(key)
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	maxBytes := t.maxResponseBytes
+	t.mu.RUnlock()
+
+	resp, err := t.client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	result := &Response{
+		StatusCode:  resp.StatusCode,
+		URL:         resp.Request.URL.String(),
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if int64(len(data)) > maxBytes {
+		data = data[:maxBytes]
+		result.Truncated = true
+	}
+	extractBody(result, data)
+
+	logging.For(nil, "httpfetch").Debug("Fetched URL", "method", method, "url", result.URL, "status", resp.StatusCode, "bytes", len(data))
+	return result, nil
+}
+
+// client builds an HTTP client enforcing the redirect and network policies
+func (t *Tool) client() *http.Client {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	transport := t.transport
+	if transport == nil {
+		transport = t.defaultTransport()
+	}
+	if t.gateway != nil {
+		transport = t.gateway.Wrap(transport)
+	}
+	maxRedirects := t.maxRedirects
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   t.timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return http.ErrUseLastResponse
+			}
+			return t.checkURL(req.URL)
+		},
+	}
+}
+
+// defaultTransport returns a transport that refuses to connect to private
+// addresses unless they are allowed. The check runs on the resolved address, so
+// DNS names pointing into a private network are blocked as well.
+func (t *Tool) defaultTransport() http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if t.allowPrivate {
+		return transport
+	}
+
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip != nil && isPrivate(ip) {
+				return fmt.Errorf("%s: %w", host, ErrPrivateAddress)
+			}
+			return nil
+		},
+	}
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+	return transport
+}
+
+// checkURL checks the scheme and domain of a URL against the tool's policy
+func (t *Tool) checkURL(target *url.URL) error {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return fmt.Errorf("unsupported url scheme %q", target.Scheme)
+	}
+	host := strings.TrimSuffix(strings.ToLower(target.Hostname()), ".")
+	if host == "" {
+		return fmt.Errorf("url has no host")
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	for _, domain := range t.denied {
+		if matchDomain(domain, host) {
+			return fmt.Errorf("%s: %w", host, ErrDomainNotAllowed)
+		}
+	}
+	if len(t.allowed) == 0 {
+		return nil
+	}
+	for _, domain := range t.allowed {
+		if matchDomain(domain, host) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: %w", host, ErrDomainNotAllowed)
+}
+
+// matchDomain reports whether a host matches a domain pattern
+func matchDomain(pattern, host string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
+
+// normalizeDomains lower-cases domain patterns and removes trailing dots
+func normalizeDomains(domains []string) []string {
+	normalized := make([]string, 0, len(domains))
+	for _, domain := range domains {
+		domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if domain != "" {
+			normalized = append(normalized, domain)
+		}
+	}
+	return normalized
+}
+
+// isPrivate reports whether an IP address is not publicly routable
+func isPrivate(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsInterfaceLocalMulticast()
+}
+
+// encodeBody encodes the body parameter, sending strings as they are and other
+// values as JSON
+func encodeBody(body interface{}) (io.Reader, string, error) {
+	switch b := body.(type) {
+	case nil:
+		return nil, "", nil
+	case string:
+		if b == "" {
+			return nil, "", nil
+		}
+		if json.Valid([]byte(b)) {
+			return strings.NewReader(b), "application/json", nil
+		}
+		return strings.NewReader(b), "text/plain; charset=utf-8", nil
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to encode body: %w", err)
+		}
+		return bytes.NewReader(data), "application/json", nil
+	}
+}
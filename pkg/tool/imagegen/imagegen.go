@@ -0,0 +1,283 @@
+// Package imagegen provides a generate_image tool backed by any provider that
+// implements model.ImageGenerator, such as the OpenAI provider.
+//
+//	images := imagegen.New(openai.NewProvider(os.Getenv("OPENAI_API_KEY"))).
+//		WithModel("dall-e-3").
+//		WithSize("1024x1024")
+//	designer := agent.NewAgent("Designer").WithTools(images)
+package imagegen
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+)
+
+const (
+	// DefaultMaxImages is the number of images a single call may generate by default
+	DefaultMaxImages = 1
+)
+
+// SaveFunc stores a generated image that was returned as data and returns a URL
+// the model can refer to instead of the data
+type SaveFunc func(ctx context.Context, image model.GeneratedImage) (string, error)
+
+// Response is the result of the generate_image tool
+type Response struct {
+	Prompt string                 `json:"prompt"`
+	Images []model.GeneratedImage `json:"images"`
+}
+
+// Tool is the generate_image tool
+type Tool struct {
+	name        string
+	description string
+	generator   model.ImageGenerator
+	model       string
+	size        string
+	sizes       []string
+	quality     string
+	style       string
+	format      string
+	maxImages   int
+	save        SaveFunc
+	mu          sync.RWMutex
+}
+
+// New creates a generate_image tool that generates images with the given generator.
+// Images are returned as URLs unless WithFormat asks for base64 data.
+func New(generator model.ImageGenerator) *Tool {
+	return &Tool{
+		name:        "generate_image",
+		description: "Generate an image from a text description. Returns the URL or data of each image.",
+		generator:   generator,
+		format:      model.ImageFormatURL,
+		maxImages:   DefaultMaxImages,
+	}
+}
+
+// FromProvider creates a generate_image tool for a provider, failing if the
+// provider cannot generate images
+func FromProvider(provider model.Provider) (*Tool, error) {
+	generator, ok := provider.(model.ImageGenerator)
+	if !ok {
+		return nil, fmt.Errorf("provider %T does not support image generation", provider)
+	}
+	return New(generator), nil
+}
+
+// WithName sets the name of the tool
+func (t *Tool) WithName(name string) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.name = name
+	return t
+}
+
+// WithDescription sets the description of the tool
+func (t *Tool) WithDescription(description string) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.description = description
+	return t
+}
+
+// WithModel sets the image model
+func (t *Tool) WithModel(modelName string) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.model = modelName
+	return t
+}
+
+// WithSize sets the size used when the model does not choose one
+func (t *Tool) WithSize(size string) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.size = size
+	return t
+}
+
+// WithSizes lets the model choose between the given sizes
+func (t *Tool) WithSizes(sizes ...string) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sizes = sizes
+	return t
+}
+
+// WithQuality sets the quality level of the images
+func (t *Tool) WithQuality(quality string) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.quality = quality
+	return t
+}
+
+// WithStyle sets the style of the images
+func (t *Tool) WithStyle(style string) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.style = style
+	return t
+}
+
+// WithFormat sets whether images are returned as model.ImageFormatURL or
+// model.ImageFormatBase64. Base64 data is sent back to the model as part of the
+// tool result, so pair it with WithSaveFunc to keep it out of the conversation.
+func (t *Tool) WithFormat(format string) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.format = format
+	return t
+}
+
+// WithMaxImages sets how many images a single call may generate
+func (t *Tool) WithMaxImages(maxImages int) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if maxImages < 1 {
+		maxImages = 1
+	}
+	t.maxImages = maxImages
+	return t
+}
+
+// WithSaveFunc stores images returned as data, replacing the data in the tool
+// result with the URL returned by the function
+func (t *Tool) WithSaveFunc(save SaveFunc) *Tool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.save = save
+	return t
+}
+
+// GetName returns the name of the tool
+func (t *Tool) GetName() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.name
+}
+
+// GetDescription returns the description of the tool
+func (t *Tool) GetDescription() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.description
+}
+
+// GetParametersSchema returns the JSON schema for the tool parameters
+func (t *Tool) GetParametersSchema() map[string]interface{} {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	properties := map[string]interface{}{
+		"prompt": map[string]interface{}{
+			"type":        "string",
+			"description": "A detailed description of the image to generate",
+		},
+	}
+	if len(t.sizes) > 0 {
+		sizes := make([]interface{}, len(t.sizes))
+		for i, size := range t.sizes {
+			sizes[i] = size
+		}
+		properties["size"] = map[string]interface{}{
+			"type":        "string",
+			"description": "The size of the image",
+			"enum":        sizes,
+		}
+	}
+	if t.maxImages > 1 {
+		properties["n"] = map[string]interface{}{
+			"type":        "integer",
+			"description": fmt.Sprintf("Number of images to generate, at most %d", t.maxImages),
+		}
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   []string{"prompt"},
+	}
+}
+
+// Execute generates the images
+func (t *Tool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	prompt, _ := params["prompt"].(string)
+	if strings.TrimSpace(prompt) == "" {
+		return nil, fmt.Errorf("prompt parameter is required")
+	}
+
+	t.mu.RLock()
+	request := &model.ImageRequest{
+		Prompt:  prompt,
+		Model:   t.model,
+		N:       1,
+		Size:    t.size,
+		Quality: t.quality,
+		Style:   t.style,
+		Format:  t.format,
+	}
+	sizes := t.sizes
+	maxImages := t.maxImages
+	save := t.save
+	t.mu.RUnlock()
+
+	if size, ok := params["size"].(string); ok && size != "" && len(sizes) > 0 {
+		if !contains(sizes, size) {
+			return nil, fmt.Errorf("invalid size %q, expected one of %s", size, strings.Join(sizes, ", "))
+		}
+		request.Size = size
+	}
+	switch n := params["n"].(type) {
+	case float64:
+		request.N = clampImages(int(n), maxImages)
+	case int:
+		request.N = clampImages(n, maxImages)
+	}
+
+	response, err := t.generator.GenerateImage(ctx, request)
+	if err != nil {
+		return nil, fmt.Errorf("image generation failed: %w", err)
+	}
+
+	images := make([]model.GeneratedImage, 0, len(response.Images))
+	for _, image := range response.Images {
+		if save != nil && len(image.Data) > 0 {
+			url, err := save(ctx, image)
+			if err != nil {
+				return nil, fmt.Errorf("failed to save image: %w", err)
+			}
+			image.URL = url
+			image.Data = nil
+		}
+		images = append(images, image)
+	}
+
+	return &Response{Prompt: prompt, Images: images}, nil
+}
+
+// clampImages keeps an image count between 1 and maxImages
+func clampImages(n, maxImages int) int {
+	if n < 1 {
+		return 1
+	}
+	if n > maxImages {
+		return maxImages
+	}
+	return n
+}
+
+// contains reports whether values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,564 @@
+// Package k8sjob runs designated tools as Kubernetes Jobs, so that expensive
+// steps such as builds and data jobs run in the cluster instead of inside the
+// agent process.
+//
+// Each tool declares the image, command, resources and timeout of its job. When
+// the tool is called the executor creates a Job whose container gets the call's
+// parameters as JSON in the TOOL_PARAMS environment variable, follows the logs of
+// its pod and returns the job's completion artifact as the tool's result. The
+// logs are passed to tool.EmitOutput, so streaming runs send them as tool_output
+// events while the job runs.
+//
+//	executor, err := k8sjob.NewInClusterExecutor()
+//	build := executor.Tool(k8sjob.Job{
+//		Name:        "build_project",
+//		Description: "Build the project and run its tests",
+//		Image:       "registry.example.com/builder:latest",
+//		Requests:    map[string]string{"cpu": "2", "memory": "4Gi"},
+//		Timeout:     20 * time.Minute,
+//	})
+//
+// The completion artifact is the container's termination message, which the job
+// writes to /dev/termination-log. It is decoded as JSON if it is valid JSON. Since
+// termination messages are limited to 4 KiB, a job with a larger result can put it
+// in the executor's artifact store and write {"artifact_id": "<id>"} instead.
+package k8sjob
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/artifact"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+)
+
+const (
+	// DefaultTimeout is how long a job may run by default
+	DefaultTimeout = 10 * time.Minute
+
+	// DefaultPollInterval is how often the executor checks the state of a job
+	DefaultPollInterval = 2 * time.Second
+
+	// ParamsEnv is the environment variable holding the tool call's parameters
+	ParamsEnv = "TOOL_PARAMS"
+
+	// ContainerName is the name of the container running the tool
+	ContainerName = "tool"
+
+	// LabelTool is the label holding the name of the tool a job runs
+	LabelTool = "agent-sdk-go/tool"
+
+	// serviceAccountDir holds the credentials of pods
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+)
+
+var (
+	// ErrJobFailed is returned when a job does not complete successfully
+	ErrJobFailed = errors.New("kubernetes job failed")
+
+	// ErrNotInCluster is returned by NewInClusterExecutor outside of a pod
+	ErrNotInCluster = errors.New("not running in a kubernetes cluster")
+)
+
+// Job declares the Kubernetes Job that runs a tool
+type Job struct {
+	// Name is the name of the tool
+	Name string
+
+	// Description tells the model what the tool does
+	Description string
+
+	// Parameters is the JSON schema of the tool's parameters. A tool without
+	// parameters accepts any object.
+	Parameters map[string]interface{}
+
+	// Image is the container image of the job
+	Image string
+
+	// Command and Args override the entrypoint and arguments of the image
+	Command []string
+	Args    []string
+
+	// Env sets environment variables of the container
+	Env map[string]string
+
+	// Requests and Limits are the resources of the container, such as
+	// {"cpu": "500m", "memory": "1Gi"}
+	Requests map[string]string
+	Limits   map[string]string
+
+	// Timeout is how long the job may run, DefaultTimeout if zero
+	Timeout time.Duration
+}
+
+// Result is the outcome of a job
+type Result struct {
+	// Job is the name of the Kubernetes Job
+	Job string `json:"job"`
+
+	// Output is the completion artifact of the job
+	Output interface{} `json:"output"`
+
+	// Logs is the output of the job's container
+	Logs string `json:"logs,omitempty"`
+}
+
+// Executor runs tools as Kubernetes Jobs through the Kubernetes API
+type Executor struct {
+	baseURL      string
+	namespace    string
+	token        string
+	client       *http.Client
+	pollInterval time.Duration
+	artifacts    artifact.Store
+	mu           sync.RWMutex
+}
+
+// NewExecutor creates an executor creating jobs in a namespace of the cluster
+// whose API server is at baseURL
+func NewExecutor(baseURL, namespace string) *Executor {
+	return &Executor{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		namespace:    namespace,
+		client:       http.DefaultClient,
+		pollInterval: DefaultPollInterval,
+	}
+}
+
+// NewInClusterExecutor creates an executor for the cluster the process runs in,
+// using the service account of its pod and creating jobs in the pod's namespace
+func NewInClusterExecutor() (*Executor, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, ErrNotInCluster
+	}
+	token, err := os.ReadFile(serviceAccountDir + "/token")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+	namespace, err := os.ReadFile(serviceAccountDir + "/namespace")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account namespace: %w", err)
+	}
+	client, err := clusterClient(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, err
+	}
+
+	e := NewExecutor("https://"+net.JoinHostPort(host, port), strings.TrimSpace(string(namespace)))
+	return e.WithToken(strings.TrimSpace(string(token))).WithHTTPClient(client), nil
+}
+
+// WithToken sets the bearer token used to authenticate with the API server
+func (e *Executor) WithToken(token string) *Executor {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.token = token
+	return e
+}
+
+// WithHTTPClient sets the client used to talk to the API server
+func (e *Executor) WithHTTPClient(client *http.Client) *Executor {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.client = client
+	return e
+}
+
+// WithPollInterval sets how often the executor checks the state of a job
+func (e *Executor) WithPollInterval(interval time.Duration) *Executor {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pollInterval = interval
+	return e
+}
+
+// WithArtifactStore sets the store that jobs with large results put their
+// completion artifacts in
+func (e *Executor) WithArtifactStore(store artifact.Store) *Executor {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.artifacts = store
+	return e
+}
+
+// Tool returns a tool that runs the job, returning its completion artifact
+func (e *Executor) Tool(job Job) tool.Tool {
+	schema := job.Parameters
+	if schema == nil {
+		schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	return tool.NewFunctionTool(job.Name, job.Description, func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		res, err := e.Run(ctx, job, params)
+		if err != nil {
+			return nil, err
+		}
+		return res.Output, nil
+	}).WithSchema(schema)
+}
+
+// Run runs a job with the given parameters and waits for it to complete. The job
+// is deleted afterwards, whether it succeeded or not.
+func (e *Executor) Run(ctx context.Context, job Job, params map[string]interface{}) (*Result, error) {
+	if job.Image == "" {
+		return nil, fmt.Errorf("job %s has no image", job.Name)
+	}
+	timeout := job.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	// The job's own deadline ends it in the cluster; the extra minute gives the
+	// executor time to see it fail
+	ctx, cancel := context.WithTimeout(ctx, timeout+time.Minute)
+	defer cancel()
+
+	manifest, err := jobManifest(job, params, timeout)
+	if err != nil {
+		return nil, err
+	}
+	var created k8sObject
+	if err := e.do(ctx, http.MethodPost, e.jobsPath(""), manifest, &created); err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+	name := created.Metadata.Name
+	log := logging.For(nil, "k8sjob")
+	log.Debug("Created job", "tool", job.Name, "job", name)
+	defer e.deleteJob(name)
+
+	logs, err := e.followLogs(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.waitForJob(ctx, name); err != nil {
+		return nil, fmt.Errorf("job %s: %w\n%s", name, err, tail(logs))
+	}
+
+	pod, err := e.jobPod(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	output, err := e.completionArtifact(ctx, pod.terminationMessage())
+	if err != nil {
+		return nil, fmt.Errorf("job %s: %w", name, err)
+	}
+	log.Debug("Job completed", "tool", job.Name, "job", name)
+	return &Result{Job: name, Output: output, Logs: logs}, nil
+}
+
+// jobManifest returns the Kubernetes Job running a tool call
+func jobManifest(job Job, params map[string]interface{}, timeout time.Duration) (map[string]interface{}, error) {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode parameters: %w", err)
+	}
+
+	env := []map[string]string{{"name": ParamsEnv, "value": string(paramsJSON)}}
+	for name, value := range job.Env {
+		env = append(env, map[string]string{"name": name, "value": value})
+	}
+	container := map[string]interface{}{
+		"name":                     ContainerName,
+		"image":                    job.Image,
+		"env":                      env,
+		"terminationMessagePolicy": "FallbackToLogsOnError",
+		"resources":                map[string]interface{}{"requests": job.Requests, "limits": job.Limits},
+	}
+	if len(job.Command) > 0 {
+		container["command"] = job.Command
+	}
+	if len(job.Args) > 0 {
+		container["args"] = job.Args
+	}
+
+	labels := map[string]string{LabelTool: jobName(job.Name)}
+	return map[string]interface{}{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata":   map[string]interface{}{"generateName": jobName(job.Name) + "-", "labels": labels},
+		"spec": map[string]interface{}{
+			"backoffLimit":          0,
+			"activeDeadlineSeconds": int64(timeout.Seconds()),
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{"labels": labels},
+				"spec": map[string]interface{}{
+					"restartPolicy": "Never",
+					"containers":    []interface{}{container},
+				},
+			},
+		},
+	}, nil
+}
+
+// jobName turns a tool name into a valid Kubernetes name prefix
+func jobName(toolName string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		default:
+			return '-'
+		}
+	}, toolName)
+	if len(name) > 40 {
+		name = name[:40]
+	}
+	if name = strings.Trim(name, "-"); name == "" {
+		name = "tool"
+	}
+	return name
+}
+
+// followLogs waits for the pod of a job to start and passes its logs to
+// tool.EmitOutput until the container exits. It returns the logs.
+func (e *Executor) followLogs(ctx context.Context, name string) (string, error) {
+	var pod *k8sObject
+	err := e.poll(ctx, func() (bool, error) {
+		var err error
+		pod, err = e.jobPod(ctx, name)
+		if err != nil || pod == nil {
+			return false, err
+		}
+		return pod.Status.Phase != "" && pod.Status.Phase != "Pending", nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("job %s did not start: %w", name, err)
+	}
+
+	query := url.Values{"follow": {"true"}, "container": {ContainerName}}
+	body, err := e.stream(ctx, e.podsPath(pod.Metadata.Name)+"/log?"+query.Encode())
+	if err != nil {
+		return "", fmt.Errorf("failed to follow logs of job %s: %w", name, err)
+	}
+	defer body.Close()
+
+	var logs strings.Builder
+	reader := bufio.NewReader(body)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			logs.WriteString(line)
+			tool.EmitOutput(ctx, line)
+		}
+		if err == io.EOF {
+			return logs.String(), nil
+		}
+		if err != nil {
+			return logs.String(), fmt.Errorf("failed to follow logs of job %s: %w", name, err)
+		}
+	}
+}
+
+// waitForJob waits until a job succeeds, or returns ErrJobFailed if it fails
+func (e *Executor) waitForJob(ctx context.Context, name string) error {
+	return e.poll(ctx, func() (bool, error) {
+		var job k8sObject
+		if err := e.do(ctx, http.MethodGet, e.jobsPath(name), nil, &job); err != nil {
+			return false, err
+		}
+		if job.Status.Succeeded > 0 {
+			return true, nil
+		}
+		for _, condition := range job.Status.Conditions {
+			if condition.Type == "Failed" && condition.Status == "True" {
+				return false, fmt.Errorf("%w: %s %s", ErrJobFailed, condition.Reason, condition.Message)
+			}
+		}
+		if job.Status.Failed > 0 {
+			return false, ErrJobFailed
+		}
+		return false, nil
+	})
+}
+
+// jobPod returns the pod of a job, or nil if it has not been created yet
+func (e *Executor) jobPod(ctx context.Context, name string) (*k8sObject, error) {
+	query := url.Values{"labelSelector": {"job-name=" + name}}
+	var pods struct {
+		Items []k8sObject `json:"items"`
+	}
+	if err := e.do(ctx, http.MethodGet, e.podsPath("")+"?"+query.Encode(), nil, &pods); err != nil {
+		return nil, fmt.Errorf("failed to find pod of job %s: %w", name, err)
+	}
+	if len(pods.Items) == 0 {
+		return nil, nil
+	}
+	return &pods.Items[len(pods.Items)-1], nil
+}
+
+// completionArtifact decodes the termination message of a job's container,
+// loading the artifact it refers to from the artifact store
+func (e *Executor) completionArtifact(ctx context.Context, message string) (interface{}, error) {
+	var output interface{}
+	if err := json.Unmarshal([]byte(message), &output); err != nil {
+		return message, nil
+	}
+
+	ref, ok := output.(map[string]interface{})
+	id, isRef := ref["artifact_id"].(string)
+	if !ok || !isRef || len(ref) != 1 {
+		return output, nil
+	}
+	e.mu.RLock()
+	store := e.artifacts
+	e.mu.RUnlock()
+	if store == nil {
+		return output, nil
+	}
+
+	stored, data, err := store.Get(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load completion artifact: %w", err)
+	}
+	if stored.MediaType == "application/json" {
+		if err := json.Unmarshal(data, &output); err != nil {
+			return nil, fmt.Errorf("failed to decode completion artifact: %w", err)
+		}
+		return output, nil
+	}
+	return string(data), nil
+}
+
+// deleteJob deletes a job and its pods. It runs after the tool call's context
+// may have ended, so it gets its own deadline.
+func (e *Executor) deleteJob(name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := e.do(ctx, http.MethodDelete, e.jobsPath(name)+"?propagationPolicy=Background", nil, nil); err != nil {
+		logging.For(nil, "k8sjob").Warn("Failed to delete job", "job", name, "error", err)
+	}
+}
+
+// poll calls check every poll interval until it returns true or an error
+func (e *Executor) poll(ctx context.Context, check func() (bool, error)) error {
+	e.mu.RLock()
+	interval := e.pollInterval
+	e.mu.RUnlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		done, err := check()
+		if done || err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// jobsPath returns the API path of the namespace's jobs, or of one job
+func (e *Executor) jobsPath(name string) string {
+	path := "/apis/batch/v1/namespaces/" + url.PathEscape(e.namespace) + "/jobs"
+	if name != "" {
+		path += "/" + url.PathEscape(name)
+	}
+	return path
+}
+
+// podsPath returns the API path of the namespace's pods, or of one pod
+func (e *Executor) podsPath(name string) string {
+	path := "/api/v1/namespaces/" + url.PathEscape(e.namespace) + "/pods"
+	if name != "" {
+		path += "/" + url.PathEscape(name)
+	}
+	return path
+}
+
+// do sends a request to the API server, decoding the response into out
+func (e *Executor) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	resp, err := e.send(ctx, method, path, reader)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// stream sends a GET request to the API server and returns the response body
+func (e *Executor) stream(ctx context.Context, path string) (io.ReadCloser, error) {
+	resp, err := e.send(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// send sends an authenticated request, returning an error for failed responses
+func (e *Executor) send(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	e.mu.RLock()
+	baseURL, token, client := e.baseURL, e.token, e.client
+	e.mu.RUnlock()
+
+	req, err := http.NewRequestWithContext(ctx, method, baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		var status struct {
+			Message string `json:"message"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if json.Unmarshal(data, &status) != nil || status.Message == "" {
+			status.Message = strings.TrimSpace(string(data))
+		}
+		return nil, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, status.Message)
+	}
+	return resp, nil
+}
+
+// tail returns the last lines of logs, for error messages
+func tail(logs string) string {
+	lines := strings.Split(strings.TrimRight(logs, "\n"), "\n")
+	if len(lines) > 20 {
+		lines = lines[len(lines)-20:]
+	}
+	return strings.Join(lines, "\n")
+}
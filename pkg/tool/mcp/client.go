@@ -0,0 +1,302 @@
+// Package mcp connects to Model Context Protocol servers and exposes their tools
+// as tool.Tool instances that agents can call.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// ProtocolVersion is the MCP protocol version announced during initialization
+const ProtocolVersion = "2024-11-05"
+
+// ErrClosed is returned for requests made after the connection to the server was closed
+var ErrClosed = errors.New("mcp: connection closed")
+
+// Transport carries JSON-RPC messages between the client and an MCP server
+type Transport interface {
+	// Start opens the connection to the server
+	Start(ctx context.Context) error
+
+	// Send writes a single JSON-RPC message to the server
+	Send(ctx context.Context, message []byte) error
+
+	// Messages returns the channel of messages received from the server. The
+	// channel is closed when the connection ends.
+	Messages() <-chan []byte
+
+	// Close shuts down the connection
+	Close() error
+}
+
+// RPCError is an error returned by the server in a JSON-RPC response
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Error implements the error interface
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("mcp: rpc error %d: %s", e.Code, e.Message)
+}
+
+// message is a JSON-RPC 2.0 request, notification or response
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  interface{}      `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *RPCError        `json:"error,omitempty"`
+}
+
+// incoming is a message received from the server
+type incoming struct {
+	ID     *json.RawMessage `json:"id,omitempty"`
+	Method string           `json:"method,omitempty"`
+	Result json.RawMessage  `json:"result,omitempty"`
+	Error  *RPCError        `json:"error,omitempty"`
+}
+
+// ServerInfo describes the server as reported during initialization
+type ServerInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// ToolInfo describes a tool offered by the server
+type ToolInfo struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"inputSchema,omitempty"`
+}
+
+// Content is a single content block of a tool result
+type Content struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"`
+	MimeType string `json:"mimeType,omitempty"`
+}
+
+// CallToolResult is the result of a tools/call request
+type CallToolResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Client is a JSON-RPC client for a single MCP server
+type Client struct {
+	transport  Transport
+	nextID     int64
+	serverInfo ServerInfo
+
+	mu      sync.Mutex
+	pending map[string]chan *incoming
+	closed  bool
+	done    chan struct{}
+}
+
+// NewClient creates a new client that talks to a server over the given transport
+func NewClient(transport Transport) *Client {
+	return &Client{
+		transport: transport,
+		pending:   make(map[string]chan *incoming),
+		done:      make(chan struct{}),
+	}
+}
+
+// Connect starts the transport and performs the MCP initialization handshake
+func (c *Client) Connect(ctx context.Context) error {
+	if err := c.transport.Start(ctx); err != nil {
+		return fmt.Errorf("failed to start transport: %w", err)
+	}
+	go c.readLoop()
+
+	params := map[string]interface{}{
+		"protocolVersion": ProtocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo": map[string]interface{}{
+			"name":    "agent-sdk-go",
+			"version": "1.0.0",
+		},
+	}
+	var initResult struct {
+		ServerInfo ServerInfo `json:"serverInfo"`
+	}
+	if err := c.call(ctx, "initialize", params, &initResult); err != nil {
+		return fmt.Errorf("failed to initialize: %w", err)
+	}
+	c.serverInfo = initResult.ServerInfo
+
+	if err := c.notify(ctx, "notifications/initialized", nil); err != nil {
+		return fmt.Errorf("failed to send initialized notification: %w", err)
+	}
+	return nil
+}
+
+// ServerInfo returns the server information reported during initialization
+func (c *Client) ServerInfo() ServerInfo {
+	return c.serverInfo
+}
+
+// ListTools returns all tools offered by the server, following pagination cursors
+func (c *Client) ListTools(ctx context.Context) ([]ToolInfo, error) {
+	var tools []ToolInfo
+	cursor := ""
+	for {
+		var params map[string]interface{}
+		if cursor != "" {
+			params = map[string]interface{}{"cursor": cursor}
+		}
+
+		var page struct {
+			Tools      []ToolInfo `json:"tools"`
+			NextCursor string     `json:"nextCursor,omitempty"`
+		}
+		if err := c.call(ctx, "tools/list", params, &page); err != nil {
+			return nil, fmt.Errorf("failed to list tools: %w", err)
+		}
+		tools = append(tools, page.Tools...)
+
+		if page.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool invokes a tool on the server
+func (c *Client) CallTool(ctx context.Context, name string, arguments map[string]interface{}) (*CallToolResult, error) {
+	if arguments == nil {
+		arguments = map[string]interface{}{}
+	}
+	params := map[string]interface{}{
+		"name":      name,
+		"arguments": arguments,
+	}
+
+	var result CallToolResult
+	if err := c.call(ctx, "tools/call", params, &result); err != nil {
+		return nil, fmt.Errorf("failed to call tool %s: %w", name, err)
+	}
+	return &result, nil
+}
+
+// Close closes the connection to the server
+func (c *Client) Close() error {
+	c.shutdown()
+	return c.transport.Close()
+}
+
+// call sends a request and decodes the result into out
+func (c *Client) call(ctx context.Context, method string, params interface{}, out interface{}) error {
+	id := atomic.AddInt64(&c.nextID, 1)
+	rawID := json.RawMessage(fmt.Sprintf("%d", id))
+	key := string(rawID)
+
+	ch := make(chan *incoming, 1)
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	c.pending[key] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, key)
+		c.mu.Unlock()
+	}()
+
+	if err := c.send(ctx, &message{JSONRPC: "2.0", ID: &rawID, Method: method, Params: params}); err != nil {
+		return err
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if out == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, out); err != nil {
+			return fmt.Errorf("failed to decode %s result: %w", method, err)
+		}
+		return nil
+	case <-c.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// notify sends a notification, which has no response
+func (c *Client) notify(ctx context.Context, method string, params interface{}) error {
+	return c.send(ctx, &message{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// send encodes and writes a message to the transport
+func (c *Client) send(ctx context.Context, msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	if err := c.transport.Send(ctx, data); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return nil
+}
+
+// readLoop dispatches messages from the server until the transport closes
+func (c *Client) readLoop() {
+	defer c.shutdown()
+
+	for data := range c.transport.Messages() {
+		var msg incoming
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+
+		switch {
+		case msg.Method != "" && msg.ID != nil:
+			c.handleServerRequest(&msg)
+		case msg.Method == "" && msg.ID != nil:
+			c.mu.Lock()
+			ch, ok := c.pending[string(*msg.ID)]
+			c.mu.Unlock()
+			if ok {
+				ch <- &msg
+			}
+		}
+		// Notifications from the server are ignored
+	}
+}
+
+// handleServerRequest answers requests initiated by the server
+func (c *Client) handleServerRequest(msg *incoming) {
+	reply := &message{JSONRPC: "2.0", ID: msg.ID}
+	if msg.Method == "ping" {
+		reply.Result = json.RawMessage("{}")
+	} else {
+		reply.Error = &RPCError{Code: -32601, Message: "method not found: " + msg.Method}
+	}
+	_ = c.send(context.Background(), reply)
+}
+
+// shutdown marks the client as closed and releases pending requests
+func (c *Client) shutdown() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.closed {
+		c.closed = true
+		close(c.done)
+	}
+}
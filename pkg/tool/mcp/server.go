@@ -0,0 +1,134 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+)
+
+// Server is a connection to an MCP server whose tools can be registered with an agent
+type Server struct {
+	client    *Client
+	connected bool
+	mu        sync.Mutex
+}
+
+// NewServer creates a server that communicates over the given transport
+func NewServer(transport Transport) *Server {
+	return &Server{client: NewClient(transport)}
+}
+
+// NewStdioServer creates a server that runs the given command and talks to it over stdio
+func NewStdioServer(command string, args ...string) *Server {
+	return NewServer(NewStdioTransport(command, args...))
+}
+
+// NewSSEServer creates a server that talks to the given SSE endpoint over HTTP
+func NewSSEServer(url string) *Server {
+	return NewServer(NewSSETransport(url))
+}
+
+// Connect connects to the server if it is not connected yet
+func (s *Server) Connect(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.connected {
+		return nil
+	}
+	if err := s.client.Connect(ctx); err != nil {
+		return err
+	}
+	s.connected = true
+	return nil
+}
+
+// Client returns the underlying JSON-RPC client
+func (s *Server) Client() *Client {
+	return s.client
+}
+
+// ListTools connects to the server if needed and returns its tools as tool.Tool instances
+func (s *Server) ListTools(ctx context.Context) ([]tool.Tool, error) {
+	if err := s.Connect(ctx); err != nil {
+		return nil, err
+	}
+
+	infos, err := s.client.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tools := make([]tool.Tool, 0, len(infos))
+	for _, info := range infos {
+		tools = append(tools, &Tool{info: info, client: s.client})
+	}
+	return tools, nil
+}
+
+// Close closes the connection to the server
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.connected = false
+	return s.client.Close()
+}
+
+// Tool is a tool offered by an MCP server
+type Tool struct {
+	info   ToolInfo
+	client *Client
+}
+
+// GetName returns the name of the tool
+func (t *Tool) GetName() string {
+	return t.info.Name
+}
+
+// GetDescription returns the description of the tool
+func (t *Tool) GetDescription() string {
+	return t.info.Description
+}
+
+// GetParametersSchema returns the input schema advertised by the server
+func (t *Tool) GetParametersSchema() map[string]interface{} {
+	if t.info.InputSchema == nil {
+		return map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{},
+		}
+	}
+	return t.info.InputSchema
+}
+
+// Execute calls the tool on the server and returns its text content
+func (t *Tool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	result, err := t.client.CallTool(ctx, t.info.Name, params)
+	if err != nil {
+		return nil, err
+	}
+
+	text := result.Text()
+	if result.IsError {
+		return nil, fmt.Errorf("tool %s failed: %s", t.info.Name, text)
+	}
+	if text == "" && len(result.Content) > 0 {
+		return result.Content, nil
+	}
+	return text, nil
+}
+
+// Text joins the text content blocks of the result
+func (r *CallToolResult) Text() string {
+	var parts []string
+	for _, content := range r.Content {
+		if content.Type == "text" {
+			parts = append(parts, content.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
@@ -0,0 +1,223 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tracing"
+)
+
+// SSETransport connects to an MCP server over HTTP. Messages from the server
+// arrive on a server-sent event stream, and messages to the server are POSTed
+// to the endpoint announced in the stream's "endpoint" event.
+type SSETransport struct {
+	URL        string
+	Headers    map[string]string
+	HTTPClient *http.Client
+
+	endpoint string
+	body     io.ReadCloser
+	messages chan []byte
+	cancel   context.CancelFunc
+	mu       sync.Mutex
+}
+
+// NewSSETransport creates a transport for the given SSE endpoint URL
+func NewSSETransport(url string) *SSETransport {
+	return &SSETransport{
+		URL:        url,
+		Headers:    make(map[string]string),
+		HTTPClient: http.DefaultClient,
+		messages:   make(chan []byte, 16),
+	}
+}
+
+// WithHeader sets a header sent with every HTTP request, e.g. for authentication
+func (t *SSETransport) WithHeader(key, value string) *SSETransport {
+	t.Headers[key] = value
+	return t
+}
+
+// WithHTTPClient sets the HTTP client used for requests
+func (t *SSETransport) WithHTTPClient(client *http.Client) *SSETransport {
+	t.HTTPClient = client
+	return t
+}
+
+// Start opens the event stream and waits for the server to announce its message endpoint
+func (t *SSETransport) Start(ctx context.Context) error {
+	streamCtx, cancel := context.WithCancel(context.Background())
+
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, t.URL, nil)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	t.setHeaders(req)
+
+	resp, err := t.HTTPClient.Do(req)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("failed to connect to %s: %w", t.URL, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return fmt.Errorf("failed to connect to %s: status %d", t.URL, resp.StatusCode)
+	}
+
+	t.mu.Lock()
+	t.body = resp.Body
+	t.cancel = cancel
+	t.mu.Unlock()
+
+	endpointCh := make(chan string, 1)
+	go t.readLoop(resp.Body, endpointCh)
+
+	select {
+	case endpoint, ok := <-endpointCh:
+		if !ok {
+			t.Close()
+			return fmt.Errorf("event stream closed before the endpoint was announced")
+		}
+		resolved, err := t.resolveEndpoint(endpoint)
+		if err != nil {
+			t.Close()
+			return err
+		}
+		t.mu.Lock()
+		t.endpoint = resolved
+		t.mu.Unlock()
+		return nil
+	case <-ctx.Done():
+		t.Close()
+		return ctx.Err()
+	}
+}
+
+// Send POSTs a message to the server's message endpoint
+func (t *SSETransport) Send(ctx context.Context, message []byte) error {
+	t.mu.Lock()
+	endpoint := t.endpoint
+	t.mu.Unlock()
+	if endpoint == "" {
+		return ErrClosed
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(message))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	t.setHeaders(req)
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := t.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Messages returns the messages received on the event stream
+func (t *SSETransport) Messages() <-chan []byte {
+	return t.messages
+}
+
+// Close closes the event stream
+func (t *SSETransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.endpoint = ""
+	if t.cancel != nil {
+		t.cancel()
+		t.cancel = nil
+	}
+	if t.body != nil {
+		err := t.body.Close()
+		t.body = nil
+		return err
+	}
+	return nil
+}
+
+// setHeaders applies the configured headers to a request
+func (t *SSETransport) setHeaders(req *http.Request) {
+	for key, value := range t.Headers {
+		req.Header.Set(key, value)
+	}
+}
+
+// resolveEndpoint resolves the announced endpoint against the stream URL
+func (t *SSETransport) resolveEndpoint(endpoint string) (string, error) {
+	base, err := url.Parse(t.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %s: %w", t.URL, err)
+	}
+	ref, err := url.Parse(strings.TrimSpace(endpoint))
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint %s: %w", endpoint, err)
+	}
+	return base.ResolveReference(ref).String(), nil
+}
+
+// readLoop parses server-sent events until the stream closes
+func (t *SSETransport) readLoop(body io.Reader, endpointCh chan<- string) {
+	defer close(t.messages)
+	endpointSent := false
+	defer func() {
+		if !endpointSent {
+			close(endpointCh)
+		}
+	}()
+
+	reader := bufio.NewReader(body)
+	event := ""
+	var data []string
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "" && err == nil:
+			// A blank line dispatches the event
+			if len(data) > 0 {
+				payload := strings.Join(data, "\n")
+				switch event {
+				case "endpoint":
+					if !endpointSent {
+						endpointCh <- payload
+						endpointSent = true
+					}
+				case "", "message":
+					t.messages <- []byte(payload)
+				}
+			}
+			event = ""
+			data = nil
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+)
+
+// StdioTransport runs an MCP server as a subprocess and exchanges
+// newline-delimited JSON-RPC messages over its stdin and stdout
+type StdioTransport struct {
+	Command string
+	Args    []string
+	Env     []string
+	Dir     string
+
+	cmd        *exec.Cmd
+	stdin      io.WriteCloser
+	messages   chan []byte
+	stderrDone chan struct{}
+	logger     logging.Logger
+	writeMu    sync.Mutex
+}
+
+// NewStdioTransport creates a transport for the given command
+func NewStdioTransport(command string, args ...string) *StdioTransport {
+	return &StdioTransport{
+		Command:  command,
+		Args:     args,
+		messages: make(chan []byte, 16),
+	}
+}
+
+// WithEnv adds environment variables in KEY=value form to the subprocess
+func (t *StdioTransport) WithEnv(env ...string) *StdioTransport {
+	t.Env = append(t.Env, env...)
+	return t
+}
+
+// WithLogger sets the logger that receives the stderr output of the subprocess
+// as debug messages. Without one, it is logged when DEBUG or MCP_DEBUG is 1.
+func (t *StdioTransport) WithLogger(logger logging.Logger) *StdioTransport {
+	t.logger = logger
+	return t
+}
+
+// WithDir sets the working directory of the subprocess
+func (t *StdioTransport) WithDir(dir string) *StdioTransport {
+	t.Dir = dir
+	return t
+}
+
+// Start launches the subprocess
+func (t *StdioTransport) Start(ctx context.Context) error {
+	cmd := exec.Command(t.Command, t.Args...)
+	cmd.Dir = t.Dir
+	if len(t.Env) > 0 {
+		cmd.Env = append(os.Environ(), t.Env...)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stdout: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stderr: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", t.Command, err)
+	}
+
+	t.cmd = cmd
+	t.stdin = stdin
+	t.stderrDone = make(chan struct{})
+	go t.readLoop(stdout)
+	go t.logStderr(stderr)
+	return nil
+}
+
+// Send writes a message followed by a newline to the subprocess
+func (t *StdioTransport) Send(ctx context.Context, message []byte) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+
+	if t.stdin == nil {
+		return ErrClosed
+	}
+	if _, err := t.stdin.Write(append(message, '\n')); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Messages returns the messages written by the subprocess
+func (t *StdioTransport) Messages() <-chan []byte {
+	return t.messages
+}
+
+// Close closes stdin and waits for the subprocess to exit, killing it if necessary
+func (t *StdioTransport) Close() error {
+	t.writeMu.Lock()
+	stdin := t.stdin
+	t.stdin = nil
+	t.writeMu.Unlock()
+
+	if stdin == nil || t.cmd == nil {
+		return nil
+	}
+	_ = stdin.Close()
+	if t.cmd.Process != nil {
+		_ = t.cmd.Process.Kill()
+	}
+	_ = t.cmd.Wait()
+	<-t.stderrDone
+	return nil
+}
+
+// readLoop reads one message per line until stdout closes
+func (t *StdioTransport) readLoop(stdout io.Reader) {
+	defer close(t.messages)
+
+	reader := bufio.NewReader(stdout)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			t.messages <- line
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// logStderr logs the stderr output of the subprocess line by line until it closes
+func (t *StdioTransport) logStderr(stderr io.Reader) {
+	defer close(t.stderrDone)
+
+	log := logging.For(t.logger, "mcp")
+	reader := bufio.NewReader(stderr)
+	for {
+		line, err := reader.ReadString('\n')
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			log.Debug("MCP server stderr", "command", t.Command, "line", line)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/ratelimit"
+)
+
+// Middleware wraps a tool with an execution policy
+type Middleware func(Tool) Tool
+
+// Wrap applies middleware to a tool. The first middleware is the outermost, so
+// Wrap(t, WithTimeout(d), WithRetry(3, 0)) applies the timeout to all attempts
+// together, while Wrap(t, WithRetry(3, 0), WithTimeout(d)) applies it to each.
+func Wrap(t Tool, middleware ...Middleware) Tool {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		t = middleware[i](t)
+	}
+	return t
+}
+
+// ExecutionInfo describes what tool middleware did during an execution
+type ExecutionInfo struct {
+	// Attempts is the number of times the tool was executed. It is zero when the
+	// result came from the cache.
+	Attempts int
+
+	// CacheHit indicates that the result came from the cache
+	CacheHit bool
+
+	// TimedOut indicates that an execution exceeded its timeout
+	TimedOut bool
+
+	// RateLimitWait is how long the execution waited for rate limit capacity
+	RateLimitWait time.Duration
+}
+
+// executionRecorder collects middleware outcomes for one execution
+type executionRecorder struct {
+	info     ExecutionInfo
+	recorded bool
+	mu       sync.Mutex
+}
+
+type executionRecorderKey struct{}
+
+// TrackExecution returns a context that records middleware outcomes, and a function
+// returning them. The function returns nil when no middleware ran.
+func TrackExecution(ctx context.Context) (context.Context, func() *ExecutionInfo) {
+	recorder := &executionRecorder{}
+	ctx = context.WithValue(ctx, executionRecorderKey{}, recorder)
+	return ctx, func() *ExecutionInfo {
+		recorder.mu.Lock()
+		defer recorder.mu.Unlock()
+		if !recorder.recorded {
+			return nil
+		}
+		info := recorder.info
+		return &info
+	}
+}
+
+// recordExecution updates the execution info in the context, if it is tracked
+func recordExecution(ctx context.Context, update func(info *ExecutionInfo)) {
+	recorder, ok := ctx.Value(executionRecorderKey{}).(*executionRecorder)
+	if !ok {
+		return
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	recorder.recorded = true
+	update(&recorder.info)
+}
+
+// middlewareTool is a tool whose execution is replaced by middleware
+type middlewareTool struct {
+	Tool
+	execute func(ctx context.Context, params map[string]interface{}) (interface{}, error)
+}
+
+// Execute runs the middleware
+func (t *middlewareTool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	return t.execute(ctx, params)
+}
+
+// WithTimeout limits how long a tool execution can take. The tool's context is
+// cancelled at the deadline, and the execution returns an error wrapping
+// context.DeadlineExceeded even if the tool ignores its context.
+func WithTimeout(timeout time.Duration) Middleware {
+	return func(next Tool) Tool {
+		return &middlewareTool{
+			Tool: next,
+			execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				ctx, cancel := context.WithTimeout(ctx, timeout)
+				defer cancel()
+
+				type outcome struct {
+					result interface{}
+					err    error
+				}
+				done := make(chan outcome, 1)
+				go func() {
+					result, err := next.Execute(ctx, params)
+					done <- outcome{result: result, err: err}
+				}()
+
+				select {
+				case o := <-done:
+					if errors.Is(o.err, context.DeadlineExceeded) && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+						recordExecution(ctx, func(info *ExecutionInfo) { info.TimedOut = true })
+					}
+					return o.result, o.err
+				case <-ctx.Done():
+					if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+						recordExecution(ctx, func(info *ExecutionInfo) { info.TimedOut = true })
+						return nil, fmt.Errorf("tool %s timed out after %v: %w", next.GetName(), timeout, ctx.Err())
+					}
+					return nil, ctx.Err()
+				}
+			},
+		}
+	}
+}
+
+// WithRetry executes a tool up to maxAttempts times until it succeeds. The wait
+// between attempts starts at backoff and doubles after each failure. Invalid
+// arguments and context cancellation are not retried.
+func WithRetry(maxAttempts int, backoff time.Duration) Middleware {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return func(next Tool) Tool {
+		return &middlewareTool{
+			Tool: next,
+			execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				wait := backoff
+				var result interface{}
+				var err error
+				for attempt := 1; attempt <= maxAttempts; attempt++ {
+					recordExecution(ctx, func(info *ExecutionInfo) { info.Attempts++ })
+
+					result, err = next.Execute(ctx, params)
+					if err == nil || attempt == maxAttempts || !retryable(ctx, err) {
+						return result, err
+					}
+
+					if wait > 0 {
+						timer := time.NewTimer(wait)
+						select {
+						case <-ctx.Done():
+							timer.Stop()
+							return nil, ctx.Err()
+						case <-timer.C:
+						}
+						wait *= 2
+					}
+				}
+				return result, err
+			},
+		}
+	}
+}
+
+// retryable reports whether a failed execution should be retried
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var argErr *ArgumentError
+	return !errors.As(err, &argErr)
+}
+
+// cacheEntry is a cached tool result
+type cacheEntry struct {
+	result  interface{}
+	expires time.Time
+}
+
+// WithCache caches successful results by their parameters for the given time. A
+// ttl of zero caches results until the process exits. The cache belongs to the
+// returned middleware, so tools wrapped with the same middleware share it.
+func WithCache(ttl time.Duration) Middleware {
+	var mu sync.Mutex
+	entries := make(map[string]cacheEntry)
+
+	return func(next Tool) Tool {
+		return &middlewareTool{
+			Tool: next,
+			execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				encoded, err := json.Marshal(params)
+				if err != nil {
+					// Parameters that can't be encoded can't be cached
+					return next.Execute(ctx, params)
+				}
+				key := next.GetName() + ":" + string(encoded)
+
+				mu.Lock()
+				entry, ok := entries[key]
+				if ok && !entry.expires.IsZero() && time.Now().After(entry.expires) {
+					delete(entries, key)
+					ok = false
+				}
+				mu.Unlock()
+
+				if ok {
+					recordExecution(ctx, func(info *ExecutionInfo) { info.CacheHit = true })
+					return entry.result, nil
+				}
+
+				result, err := next.Execute(ctx, params)
+				if err != nil {
+					return result, err
+				}
+
+				entry = cacheEntry{result: result}
+				if ttl > 0 {
+					entry.expires = time.Now().Add(ttl)
+				}
+				mu.Lock()
+				entries[key] = entry
+				mu.Unlock()
+
+				return result, nil
+			},
+		}
+	}
+}
+
+// WithRateLimit allows at most limit executions per period. Executions over the
+// limit wait for capacity. The limit belongs to the returned middleware, so tools
+// wrapped with the same middleware share it.
+func WithRateLimit(limit int, per time.Duration) Middleware {
+	limiter := ratelimit.NewSlidingWindow(limit, 0).WithWindow(per)
+
+	return func(next Tool) Tool {
+		return &middlewareTool{
+			Tool: next,
+			execute: func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+				start := time.Now()
+				throttled := false
+				if err := limiter.Wait(ctx, func(ratelimit.Status) { throttled = true }); err != nil {
+					return nil, fmt.Errorf("tool %s rate limit wait: %w", next.GetName(), err)
+				}
+				if throttled {
+					waited := time.Since(start)
+					recordExecution(ctx, func(info *ExecutionInfo) { info.RateLimitWait += waited })
+				}
+				return next.Execute(ctx, params)
+			},
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package tool
+
+import "context"
+
+// outputListenerKey is the context key of the function that receives the
+// progress output of a tool
+type outputListenerKey struct{}
+
+// WithOutputListener returns a context whose tool output is passed to fn. The
+// streaming runner sets one for each tool call and turns the output into
+// tool_output events.
+func WithOutputListener(ctx context.Context, fn func(chunk string)) context.Context {
+	return context.WithValue(ctx, outputListenerKey{}, fn)
+}
+
+// EmitOutput passes output a tool produces while it runs to the listener of the
+// context, if there is one. Tools use it to report progress before they return
+// their result.
+func EmitOutput(ctx context.Context, chunk string) {
+	if fn, ok := ctx.Value(outputListenerKey{}).(func(chunk string)); ok && chunk != "" {
+		fn(chunk)
+	}
+}
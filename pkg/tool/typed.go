@@ -0,0 +1,244 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// TypedTool is a tool whose parameters and result are Go types. The parameter
+// schema is generated from the Params type, using json tags for field names and
+// doc tags for descriptions.
+type TypedTool[Params any, Result any] struct {
+	name        string
+	description string
+	function    func(ctx context.Context, params Params) (Result, error)
+	schema      map[string]interface{}
+	wrapped     bool
+}
+
+// NewTypedTool creates a tool from a function taking a Params value. Incoming
+// arguments are validated against the generated schema and unmarshaled into
+// Params before the function is invoked. Params that is not a struct is exposed
+// to the model as a single "value" parameter.
+func NewTypedTool[Params any, Result any](name, description string, fn func(ctx context.Context, params Params) (Result, error)) *TypedTool[Params, Result] {
+	paramsType := reflect.TypeOf((*Params)(nil)).Elem()
+	for paramsType.Kind() == reflect.Ptr {
+		paramsType = paramsType.Elem()
+	}
+
+	t := &TypedTool[Params, Result]{
+		name:        name,
+		description: description,
+		function:    fn,
+	}
+
+	if paramsType.Kind() == reflect.Struct {
+		t.schema = getTypeSchema(paramsType)
+	} else {
+		t.wrapped = true
+		t.schema = map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"value": getTypeSchema(paramsType),
+			},
+			"required": []string{"value"},
+		}
+	}
+
+	return t
+}
+
+// GetName returns the name of the tool
+func (t *TypedTool[Params, Result]) GetName() string {
+	return t.name
+}
+
+// GetDescription returns the description of the tool
+func (t *TypedTool[Params, Result]) GetDescription() string {
+	return t.description
+}
+
+// GetParametersSchema returns the JSON schema for the tool parameters
+func (t *TypedTool[Params, Result]) GetParametersSchema() map[string]interface{} {
+	return t.schema
+}
+
+// Execute validates the parameters, unmarshals them into Params and calls the function
+func (t *TypedTool[Params, Result]) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+
+	if problems := validateValue("", params, t.schema); len(problems) > 0 {
+		return nil, &ArgumentError{Tool: t.name, Problems: problems}
+	}
+
+	var arg interface{} = params
+	if t.wrapped {
+		arg = params["value"]
+	}
+
+	data, err := json.Marshal(arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode arguments for tool %s: %w", t.name, err)
+	}
+	var typed Params
+	if err := json.Unmarshal(data, &typed); err != nil {
+		return nil, &ArgumentError{Tool: t.name, Problems: []string{err.Error()}}
+	}
+
+	return t.function(ctx, typed)
+}
+
+// WithDescription updates the description of the tool
+func (t *TypedTool[Params, Result]) WithDescription(description string) *TypedTool[Params, Result] {
+	t.description = description
+	return t
+}
+
+// WithName updates the name of the tool
+func (t *TypedTool[Params, Result]) WithName(name string) *TypedTool[Params, Result] {
+	t.name = name
+	return t
+}
+
+// TypeSchema returns the JSON schema of a Go type, generated the way TypedTool
+// generates the schema of its parameters
+func TypeSchema(t reflect.Type) map[string]interface{} {
+	return getTypeSchema(t)
+}
+
+// ArgumentError is returned when tool arguments do not match the tool's schema
+type ArgumentError struct {
+	Tool     string
+	Problems []string
+}
+
+// Error implements the error interface
+func (e *ArgumentError) Error() string {
+	return fmt.Sprintf("invalid arguments for tool %s: %s", e.Tool, strings.Join(e.Problems, "; "))
+}
+
+// ValidateValue checks a decoded JSON value against a JSON schema and returns the
+// problems found, or nil if the value matches. Problems name fields by their path
+// below name, such as "result.items[0].id".
+func ValidateValue(name string, value interface{}, schema map[string]interface{}) []string {
+	if value == nil {
+		return []string{name + " is missing"}
+	}
+	return validateValue(name, value, schema)
+}
+
+// validateValue checks a decoded JSON value against a schema and returns the problems found
+func validateValue(path string, value interface{}, schema map[string]interface{}) []string {
+	name := path
+	if name == "" {
+		name = "arguments"
+	}
+
+	// A nil value is accepted for optional fields; required fields are checked by the parent
+	if value == nil {
+		return nil
+	}
+
+	var problems []string
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s must be an object", name)}
+		}
+
+		if required, ok := schema["required"].([]string); ok {
+			for _, field := range required {
+				if _, present := obj[field]; !present {
+					problems = append(problems, fmt.Sprintf("%s is required", joinPath(path, field)))
+				}
+			}
+		}
+
+		if properties, ok := schema["properties"].(map[string]interface{}); ok {
+			keys := make([]string, 0, len(obj))
+			for key := range obj {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				if propSchema, ok := properties[key].(map[string]interface{}); ok {
+					problems = append(problems, validateValue(joinPath(path, key), obj[key], propSchema)...)
+				}
+			}
+		}
+
+		if itemSchema, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+			for key, item := range obj {
+				problems = append(problems, validateValue(joinPath(path, key), item, itemSchema)...)
+			}
+		}
+
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s must be an array", name)}
+		}
+		if itemSchema, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range items {
+				problems = append(problems, validateValue(fmt.Sprintf("%s[%d]", name, i), item, itemSchema)...)
+			}
+		}
+
+	case "string":
+		if _, ok := value.(string); !ok {
+			problems = append(problems, fmt.Sprintf("%s must be a string", name))
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			problems = append(problems, fmt.Sprintf("%s must be a boolean", name))
+		}
+
+	case "number":
+		if _, ok := toFloat(value); !ok {
+			problems = append(problems, fmt.Sprintf("%s must be a number", name))
+		}
+
+	case "integer":
+		if f, ok := toFloat(value); !ok || f != math.Trunc(f) {
+			problems = append(problems, fmt.Sprintf("%s must be an integer", name))
+		}
+	}
+
+	return problems
+}
+
+// joinPath appends a field name to a dotted path
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// toFloat converts a numeric value to float64
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int, int8, int16, int32, int64:
+		return float64(reflect.ValueOf(v).Int()), true
+	case uint, uint8, uint16, uint32, uint64:
+		return float64(reflect.ValueOf(v).Uint()), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
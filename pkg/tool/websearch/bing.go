@@ -0,0 +1,96 @@
+package websearch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// DefaultBingURL is the Bing Web Search endpoint
+const DefaultBingURL = "https://api.bing.microsoft.com/v7.0/search"
+
+// Bing searches with the Bing Web Search API
+type Bing struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewBing creates a Bing backend with a subscription key
+func NewBing(apiKey string) *Bing {
+	return &Bing{
+		apiKey:     apiKey,
+		baseURL:    DefaultBingURL,
+		httpClient: http.DefaultClient,
+	}
+}
+
+// WithBaseURL sets the search endpoint
+func (b *Bing) WithBaseURL(baseURL string) *Bing {
+	b.baseURL = baseURL
+	return b
+}
+
+// WithHTTPClient sets the HTTP client
+func (b *Bing) WithHTTPClient(client *http.Client) *Bing {
+	b.httpClient = client
+	return b
+}
+
+// Name returns the name of the search API
+func (b *Bing) Name() string {
+	return "bing"
+}
+
+// Search returns the web results for the query
+func (b *Bing) Search(ctx context.Context, query Query) ([]Result, error) {
+	values := url.Values{}
+	values.Set("q", query.Query)
+	values.Set("count", strconv.Itoa(query.MaxResults))
+	switch query.Recency {
+	case RecencyDay:
+		values.Set("freshness", "Day")
+	case RecencyWeek:
+		values.Set("freshness", "Week")
+	case RecencyMonth:
+		values.Set("freshness", "Month")
+	case RecencyYear:
+		// Bing has no yearly freshness, so pass an explicit date range
+		now := time.Now().UTC()
+		values.Set("freshness", fmt.Sprintf("%s..%s", now.AddDate(-1, 0, 0).Format("2006-01-02"), now.Format("2006-01-02")))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.baseURL+"?"+values.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Ocp-Apim-Subscription-Key", b.apiKey)
+
+	var response struct {
+		WebPages struct {
+			Value []struct {
+				Name            string `json:"name"`
+				URL             string `json:"url"`
+				Snippet         string `json:"snippet"`
+				DatePublished   string `json:"datePublished"`
+				DateLastCrawled string `json:"dateLastCrawled"`
+			} `json:"value"`
+		} `json:"webPages"`
+	}
+	if err := doJSON(b.httpClient, req, &response); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(response.WebPages.Value))
+	for _, page := range response.WebPages.Value {
+		published := page.DatePublished
+		if published == "" {
+			published = page.DateLastCrawled
+		}
+		results = append(results, Result{Title: page.Name, URL: page.URL, Snippet: page.Snippet, Published: published})
+	}
+	return results, nil
+}
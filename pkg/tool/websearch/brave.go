@@ -0,0 +1,88 @@
+package websearch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// DefaultBraveURL is the Brave Search web endpoint
+const DefaultBraveURL = "https://api.search.brave.com/res/v1/web/search"
+
+// Brave searches with the Brave Search API
+type Brave struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewBrave creates a Brave backend with a subscription token
+func NewBrave(apiKey string) *Brave {
+	return &Brave{
+		apiKey:     apiKey,
+		baseURL:    DefaultBraveURL,
+		httpClient: http.DefaultClient,
+	}
+}
+
+// WithBaseURL sets the search endpoint
+func (b *Brave) WithBaseURL(baseURL string) *Brave {
+	b.baseURL = baseURL
+	return b
+}
+
+// WithHTTPClient sets the HTTP client
+func (b *Brave) WithHTTPClient(client *http.Client) *Brave {
+	b.httpClient = client
+	return b
+}
+
+// Name returns the name of the search API
+func (b *Brave) Name() string {
+	return "brave"
+}
+
+// Search returns the web results for the query
+func (b *Brave) Search(ctx context.Context, query Query) ([]Result, error) {
+	values := url.Values{}
+	values.Set("q", query.Query)
+	values.Set("count", strconv.Itoa(query.MaxResults))
+	switch query.Recency {
+	case RecencyDay:
+		values.Set("freshness", "pd")
+	case RecencyWeek:
+		values.Set("freshness", "pw")
+	case RecencyMonth:
+		values.Set("freshness", "pm")
+	case RecencyYear:
+		values.Set("freshness", "py")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.baseURL+"?"+values.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Subscription-Token", b.apiKey)
+
+	var response struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+				PageAge     string `json:"page_age"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := doJSON(b.httpClient, req, &response); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(response.Web.Results))
+	for _, page := range response.Web.Results {
+		results = append(results, Result{Title: page.Title, URL: page.URL, Snippet: page.Description, Published: page.PageAge})
+	}
+	return results, nil
+}
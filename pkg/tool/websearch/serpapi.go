@@ -0,0 +1,95 @@
+package websearch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// DefaultSerpAPIURL is the SerpAPI search endpoint
+const DefaultSerpAPIURL = "https://serpapi.com/search.json"
+
+// SerpAPI searches Google through SerpAPI
+type SerpAPI struct {
+	apiKey     string
+	baseURL    string
+	engine     string
+	httpClient *http.Client
+}
+
+// NewSerpAPI creates a SerpAPI backend with an API key
+func NewSerpAPI(apiKey string) *SerpAPI {
+	return &SerpAPI{
+		apiKey:     apiKey,
+		baseURL:    DefaultSerpAPIURL,
+		engine:     "google",
+		httpClient: http.DefaultClient,
+	}
+}
+
+// WithBaseURL sets the search endpoint
+func (s *SerpAPI) WithBaseURL(baseURL string) *SerpAPI {
+	s.baseURL = baseURL
+	return s
+}
+
+// WithHTTPClient sets the HTTP client
+func (s *SerpAPI) WithHTTPClient(client *http.Client) *SerpAPI {
+	s.httpClient = client
+	return s
+}
+
+// WithEngine sets the SerpAPI search engine, google by default
+func (s *SerpAPI) WithEngine(engine string) *SerpAPI {
+	s.engine = engine
+	return s
+}
+
+// Name returns the name of the search API
+func (s *SerpAPI) Name() string {
+	return "serpapi"
+}
+
+// Search returns the organic results for the query
+func (s *SerpAPI) Search(ctx context.Context, query Query) ([]Result, error) {
+	values := url.Values{}
+	values.Set("engine", s.engine)
+	values.Set("q", query.Query)
+	values.Set("num", strconv.Itoa(query.MaxResults))
+	values.Set("api_key", s.apiKey)
+	switch query.Recency {
+	case RecencyDay:
+		values.Set("tbs", "qdr:d")
+	case RecencyWeek:
+		values.Set("tbs", "qdr:w")
+	case RecencyMonth:
+		values.Set("tbs", "qdr:m")
+	case RecencyYear:
+		values.Set("tbs", "qdr:y")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+"?"+values.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	var response struct {
+		OrganicResults []struct {
+			Title   string `json:"title"`
+			Link    string `json:"link"`
+			Snippet string `json:"snippet"`
+			Date    string `json:"date"`
+		} `json:"organic_results"`
+	}
+	if err := doJSON(s.httpClient, req, &response); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(response.OrganicResults))
+	for _, page := range response.OrganicResults {
+		results = append(results, Result{Title: page.Title, URL: page.Link, Snippet: page.Snippet, Published: page.Date})
+	}
+	return results, nil
+}
@@ -0,0 +1,95 @@
+package websearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// DefaultTavilyURL is the Tavily search endpoint
+const DefaultTavilyURL = "https://api.tavily.com/search"
+
+// Tavily searches with the Tavily Search API
+type Tavily struct {
+	apiKey     string
+	baseURL    string
+	depth      string
+	httpClient *http.Client
+}
+
+// NewTavily creates a Tavily backend with an API key
+func NewTavily(apiKey string) *Tavily {
+	return &Tavily{
+		apiKey:     apiKey,
+		baseURL:    DefaultTavilyURL,
+		depth:      "basic",
+		httpClient: http.DefaultClient,
+	}
+}
+
+// WithBaseURL sets the search endpoint
+func (t *Tavily) WithBaseURL(baseURL string) *Tavily {
+	t.baseURL = baseURL
+	return t
+}
+
+// WithHTTPClient sets the HTTP client
+func (t *Tavily) WithHTTPClient(client *http.Client) *Tavily {
+	t.httpClient = client
+	return t
+}
+
+// WithSearchDepth sets the search depth, basic or advanced
+func (t *Tavily) WithSearchDepth(depth string) *Tavily {
+	t.depth = depth
+	return t
+}
+
+// Name returns the name of the search API
+func (t *Tavily) Name() string {
+	return "tavily"
+}
+
+// Search returns the results for the query
+func (t *Tavily) Search(ctx context.Context, query Query) ([]Result, error) {
+	payload := map[string]interface{}{
+		"query":        query.Query,
+		"max_results":  query.MaxResults,
+		"search_depth": t.depth,
+	}
+	if query.Recency != RecencyAny {
+		payload["time_range"] = string(query.Recency)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.baseURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+
+	var response struct {
+		Results []struct {
+			Title         string `json:"title"`
+			URL           string `json:"url"`
+			Content       string `json:"content"`
+			PublishedDate string `json:"published_date"`
+		} `json:"results"`
+	}
+	if err := doJSON(t.httpClient, req, &response); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(response.Results))
+	for _, page := range response.Results {
+		results = append(results, Result{Title: page.Title, URL: page.URL, Snippet: page.Content, Published: page.PublishedDate})
+	}
+	return results, nil
+}
@@ -0,0 +1,209 @@
+// Package websearch provides a web_search tool backed by a pluggable search API.
+//
+// Backends are available for Bing, Brave, SerpAPI and Tavily:
+//
+//	search := websearch.New(websearch.NewBrave(os.Getenv("BRAVE_API_KEY")))
+//	researcher := agent.NewAgent("Researcher").WithTools(search)
+package websearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// DefaultMaxResults is the number of results returned when the model does not ask for a number
+	DefaultMaxResults = 5
+
+	// MaxResultsLimit is the largest number of results a single search returns
+	MaxResultsLimit = 20
+)
+
+// Recency restricts results to pages published within a period
+type Recency string
+
+// Recency values
+const (
+	RecencyAny   Recency = ""
+	RecencyDay   Recency = "day"
+	RecencyWeek  Recency = "week"
+	RecencyMonth Recency = "month"
+	RecencyYear  Recency = "year"
+)
+
+// Query is a search request
+type Query struct {
+	Query      string
+	MaxResults int
+	Recency    Recency
+}
+
+// Result is a single search result
+type Result struct {
+	Title     string `json:"title"`
+	URL       string `json:"url"`
+	Snippet   string `json:"snippet"`
+	Published string `json:"published,omitempty"`
+}
+
+// Response is the result of the web_search tool
+type Response struct {
+	Query   string   `json:"query"`
+	Results []Result `json:"results"`
+}
+
+// Backend performs searches against a search API
+type Backend interface {
+	// Name returns the name of the search API
+	Name() string
+
+	// Search returns at most query.MaxResults results for the query
+	Search(ctx context.Context, query Query) ([]Result, error)
+}
+
+// Tool is the web_search tool
+type Tool struct {
+	name        string
+	description string
+	backend     Backend
+	maxResults  int
+}
+
+// New creates a web_search tool that searches with the given backend
+func New(backend Backend) *Tool {
+	return &Tool{
+		name:        "web_search",
+		description: "Search the web. Returns the title, URL and a snippet of each result.",
+		backend:     backend,
+		maxResults:  DefaultMaxResults,
+	}
+}
+
+// WithName sets the name of the tool
+func (t *Tool) WithName(name string) *Tool {
+	t.name = name
+	return t
+}
+
+// WithDescription sets the description of the tool
+func (t *Tool) WithDescription(description string) *Tool {
+	t.description = description
+	return t
+}
+
+// WithMaxResults sets the number of results returned when the model does not ask for a number
+func (t *Tool) WithMaxResults(maxResults int) *Tool {
+	t.maxResults = clampResults(maxResults)
+	return t
+}
+
+// GetName returns the name of the tool
+func (t *Tool) GetName() string {
+	return t.name
+}
+
+// GetDescription returns the description of the tool
+func (t *Tool) GetDescription() string {
+	return t.description
+}
+
+// GetParametersSchema returns the JSON schema for the tool parameters
+func (t *Tool) GetParametersSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "The search query",
+			},
+			"max_results": map[string]interface{}{
+				"type":        "integer",
+				"description": fmt.Sprintf("Number of results to return, at most %d", MaxResultsLimit),
+			},
+			"recency": map[string]interface{}{
+				"type":        "string",
+				"description": "Only return pages published within this period",
+				"enum":        []interface{}{string(RecencyDay), string(RecencyWeek), string(RecencyMonth), string(RecencyYear)},
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+// Execute runs the search
+func (t *Tool) Execute(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+	query, _ := params["query"].(string)
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("query parameter is required")
+	}
+
+	maxResults := t.maxResults
+	switch n := params["max_results"].(type) {
+	case float64:
+		maxResults = clampResults(int(n))
+	case int:
+		maxResults = clampResults(n)
+	}
+
+	recency := RecencyAny
+	if r, ok := params["recency"].(string); ok {
+		switch Recency(r) {
+		case RecencyAny, RecencyDay, RecencyWeek, RecencyMonth, RecencyYear:
+			recency = Recency(r)
+		default:
+			return nil, fmt.Errorf("invalid recency %q, expected day, week, month or year", r)
+		}
+	}
+
+	results, err := t.backend.Search(ctx, Query{Query: query, MaxResults: maxResults, Recency: recency})
+	if err != nil {
+		return nil, fmt.Errorf("%s search failed: %w", t.backend.Name(), err)
+	}
+	if len(results) > maxResults {
+		results = results[:maxResults]
+	}
+	if results == nil {
+		results = []Result{}
+	}
+
+	return &Response{Query: query, Results: results}, nil
+}
+
+// clampResults keeps a result count between 1 and MaxResultsLimit
+func clampResults(n int) int {
+	if n < 1 {
+		return 1
+	}
+	if n > MaxResultsLimit {
+		return MaxResultsLimit
+	}
+	return n
+}
+
+// doJSON sends a request and decodes the JSON response
+func doJSON(client *http.Client, req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
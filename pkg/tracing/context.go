@@ -10,6 +10,36 @@ type contextKey string
 // tracerKey is the context key for the tracer
 const tracerKey = contextKey("tracer")
 
+// userIDKey is the context key for the end user of a run
+const userIDKey = contextKey("user_id")
+
+// correlationIDKey is the context key for the correlation ID of a run
+const correlationIDKey = contextKey("correlation_id")
+
+// WithUserID records the end user a run acts for in the context, so tracers can
+// attribute its events to the user
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+// UserID returns the end user recorded in the context, or ""
+func UserID(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDKey).(string)
+	return userID
+}
+
+// WithCorrelationID records the ID correlating a run with the request or message
+// that started it, so tracers can tie its events to the originating system
+func WithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return context.WithValue(ctx, correlationIDKey, correlationID)
+}
+
+// CorrelationID returns the correlation ID recorded in the context, or ""
+func CorrelationID(ctx context.Context) string {
+	correlationID, _ := ctx.Value(correlationIDKey).(string)
+	return correlationID
+}
+
 // WithTracer adds a tracer to the context
 func WithTracer(ctx context.Context, tracer Tracer) context.Context {
 	return context.WithValue(ctx, tracerKey, tracer)
@@ -3,6 +3,8 @@ package tracing
 import (
 	"context"
 	"time"
+
+	agentmodel "github.com/pontus-devoteam/agent-sdk-go/pkg/model"
 )
 
 // AgentStart records an agent start event
@@ -84,6 +86,11 @@ func ModelResponse(ctx context.Context, agentName string, model string, response
 		"response": response,
 	}
 
+	// Record the provider's request ID so the turn can be correlated with provider logs
+	if resp, ok := response.(*agentmodel.Response); ok && resp != nil && resp.RequestID != "" {
+		details["request_id"] = resp.RequestID
+	}
+
 	event := Event{
 		Type:      EventTypeModelResponse,
 		AgentName: agentName,
@@ -153,3 +160,45 @@ func Error(ctx context.Context, agentName string, message string, err error) {
 
 	RecordEventContext(ctx, event)
 }
+
+// SLABreach records that a workflow phase exceeded its time budget
+func SLABreach(ctx context.Context, agentName string, phase string, budget, elapsed time.Duration, action string) {
+	RecordEventContext(ctx, Event{
+		Type:      EventTypeSLABreach,
+		AgentName: agentName,
+		Timestamp: time.Now(),
+		Details: map[string]interface{}{
+			"phase":   phase,
+			"budget":  budget.String(),
+			"elapsed": elapsed.String(),
+			"action":  action,
+		},
+	})
+}
+
+// LoopDetected records that agents of a run were going in circles
+func LoopDetected(ctx context.Context, agentName string, kind string, count int, action string) {
+	RecordEventContext(ctx, Event{
+		Type:      EventTypeLoopDetected,
+		AgentName: agentName,
+		Timestamp: time.Now(),
+		Details: map[string]interface{}{
+			"kind":   kind,
+			"count":  count,
+			"action": action,
+		},
+	})
+}
+
+// DeadlineExceeded records that a run or a delegated task ran past its deadline
+func DeadlineExceeded(ctx context.Context, agentName string, kind string, message string) {
+	RecordEventContext(ctx, Event{
+		Type:      EventTypeDeadlineExceeded,
+		AgentName: agentName,
+		Timestamp: time.Now(),
+		Details: map[string]interface{}{
+			"kind":    kind,
+			"message": message,
+		},
+	})
+}
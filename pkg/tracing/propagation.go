@@ -0,0 +1,210 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// W3C trace context headers
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// spanContextKey is the context key for the current span context
+const spanContextKey = contextKey("span_context")
+
+// SpanContext identifies a span within a distributed trace, as carried by the
+// W3C traceparent header
+type SpanContext struct {
+	// TraceID is the 32 hex character trace ID
+	TraceID string
+
+	// SpanID is the 16 hex character ID of the span
+	SpanID string
+
+	// Sampled indicates whether the trace is sampled
+	Sampled bool
+
+	// TraceState is the vendor-specific tracestate header, passed through unchanged
+	TraceState string
+}
+
+// NewSpanContext starts a new sampled trace
+func NewSpanContext() SpanContext {
+	return SpanContext{
+		TraceID: randomHex(16),
+		SpanID:  randomHex(8),
+		Sampled: true,
+	}
+}
+
+// NewSpanContextWithTraceID starts a new span in the trace with the given ID
+func NewSpanContextWithTraceID(traceID string) (SpanContext, error) {
+	traceID = strings.ToLower(traceID)
+	if !isHex(traceID, 32) || isZero(traceID) {
+		return SpanContext{}, fmt.Errorf("invalid trace ID %q", traceID)
+	}
+	return SpanContext{TraceID: traceID, SpanID: randomHex(8), Sampled: true}, nil
+}
+
+// ParseTraceparent parses a traceparent header value
+func ParseTraceparent(header string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(strings.ToLower(header)), "-")
+	if len(parts) < 4 {
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q", header)
+	}
+
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	switch {
+	case !isHex(version, 2) || version == "ff":
+		return SpanContext{}, fmt.Errorf("invalid traceparent version %q", version)
+	case version == "00" && len(parts) != 4:
+		return SpanContext{}, fmt.Errorf("invalid traceparent %q", header)
+	case !isHex(traceID, 32) || isZero(traceID):
+		return SpanContext{}, fmt.Errorf("invalid trace ID %q", traceID)
+	case !isHex(spanID, 16) || isZero(spanID):
+		return SpanContext{}, fmt.Errorf("invalid span ID %q", spanID)
+	case !isHex(flags, 2):
+		return SpanContext{}, fmt.Errorf("invalid trace flags %q", flags)
+	}
+
+	flagBytes, _ := hex.DecodeString(flags)
+	return SpanContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: flagBytes[0]&0x01 == 0x01,
+	}, nil
+}
+
+// IsValid reports whether the span context has valid trace and span IDs
+func (sc SpanContext) IsValid() bool {
+	return isHex(sc.TraceID, 32) && !isZero(sc.TraceID) && isHex(sc.SpanID, 16) && !isZero(sc.SpanID)
+}
+
+// Child returns a new span in the same trace
+func (sc SpanContext) Child() SpanContext {
+	return SpanContext{
+		TraceID:    sc.TraceID,
+		SpanID:     randomHex(8),
+		Sampled:    sc.Sampled,
+		TraceState: sc.TraceState,
+	}
+}
+
+// Traceparent formats the span context as a traceparent header value
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// ContextWithSpanContext returns a context carrying the span context
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey, sc)
+}
+
+// SpanContextFromContext returns the span context carried by the context, if any
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+// Inject writes the trace context of ctx into outbound request headers. Each call
+// creates a child span, so every request appears as its own span in the trace.
+// Nothing is written when ctx carries no trace context.
+func Inject(ctx context.Context, header http.Header) {
+	sc, ok := SpanContextFromContext(ctx)
+	if !ok {
+		return
+	}
+	child := sc.Child()
+	header.Set(TraceparentHeader, child.Traceparent())
+	if child.TraceState != "" {
+		header.Set(TracestateHeader, child.TraceState)
+	}
+}
+
+// Extract reads the trace context of inbound request headers into ctx. The
+// context is returned unchanged when the headers carry no valid traceparent.
+func Extract(ctx context.Context, header http.Header) context.Context {
+	sc, err := ParseTraceparent(header.Get(TraceparentHeader))
+	if err != nil {
+		return ctx
+	}
+	sc.TraceState = header.Get(TracestateHeader)
+	return ContextWithSpanContext(ctx, sc)
+}
+
+// Middleware extracts the trace context of inbound requests so that runs started
+// by the handler join the caller's trace
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(Extract(r.Context(), r.Header)))
+	})
+}
+
+// Transport is an http.RoundTripper that injects the trace context of each
+// request's context into its headers, for use in HTTP clients of tools
+type Transport struct {
+	// Base is the underlying transport. http.DefaultTransport is used when nil.
+	Base http.RoundTripper
+}
+
+// NewTransport wraps a base transport with trace context injection
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{Base: base}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if _, ok := SpanContextFromContext(req.Context()); !ok {
+		return t.base().RoundTrip(req)
+	}
+
+	// RoundTrippers must not modify the original request
+	clone := req.Clone(req.Context())
+	Inject(req.Context(), clone.Header)
+	return t.base().RoundTrip(clone)
+}
+
+// base returns the underlying transport
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// randomHex returns n random bytes encoded as hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("tracing: failed to generate random ID: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// isHex reports whether s consists of exactly n lowercase hex characters
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// isZero reports whether a hex ID consists only of zeros
+func isZero(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
@@ -0,0 +1,173 @@
+package tracing
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/retention"
+)
+
+// TraceFilePattern matches the files written by FileTracer
+const TraceFilePattern = "trace_*.log"
+
+// redactedDetails are the event details kept when an event is redacted: names
+// and timings, but no prompts, outputs or arguments
+var redactedDetails = map[string]bool{
+	"model":     true,
+	"tool_name": true,
+	"to_agent":  true,
+	"role":      true,
+	"phase":     true,
+	"budget":    true,
+	"elapsed":   true,
+	"action":    true,
+}
+
+// FileRetention applies retention to the trace files of FileTracer in a directory.
+// Files are rewritten in place, so tracers appending to them keep working.
+type FileRetention struct {
+	dir string
+}
+
+// Ensure FileRetention implements retention.Store
+var _ retention.Store = (*FileRetention)(nil)
+
+// NewFileRetention creates a retention store for the trace files in a directory
+func NewFileRetention(dir string) *FileRetention {
+	return &FileRetention{dir: dir}
+}
+
+// Purge redacts the events recorded before cutoff. Redacted events keep their
+// type, agent, time, names and token usage.
+func (f *FileRetention) Purge(ctx context.Context, cutoff time.Time) (retention.Report, error) {
+	var report retention.Report
+	err := f.rewrite(ctx, func(event map[string]interface{}) map[string]interface{} {
+		if redacted, _ := event["redacted"].(bool); redacted {
+			return event
+		}
+		timestamp, _ := event["timestamp"].(string)
+		at, err := time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil || !at.Before(cutoff) {
+			return event
+		}
+		report.Redacted++
+		return redactEvent(event)
+	})
+	return report, err
+}
+
+// Forget deletes the events of a user's runs
+func (f *FileRetention) Forget(ctx context.Context, userID string) (retention.Report, error) {
+	var report retention.Report
+	err := f.rewrite(ctx, func(event map[string]interface{}) map[string]interface{} {
+		if event["user_id"] == userID {
+			report.Deleted++
+			return nil
+		}
+		return event
+	})
+	return report, err
+}
+
+// redactEvent returns an event without its content
+func redactEvent(event map[string]interface{}) map[string]interface{} {
+	redacted := map[string]interface{}{"redacted": true}
+	for _, key := range []string{"type", "agent_name", "timestamp"} {
+		if value, ok := event[key]; ok {
+			redacted[key] = value
+		}
+	}
+
+	details, _ := event["details"].(map[string]interface{})
+	kept := make(map[string]interface{})
+	for key, value := range details {
+		if redactedDetails[key] {
+			kept[key] = value
+		}
+	}
+	if response, ok := details["response"].(map[string]interface{}); ok {
+		for _, key := range []string{"usage", "Usage"} {
+			if usage, ok := response[key]; ok && usage != nil {
+				kept["usage"] = usage
+			}
+		}
+	}
+	if len(kept) > 0 {
+		redacted["details"] = kept
+	}
+	return redacted
+}
+
+// rewrite applies fn to every event of every trace file, dropping the events it
+// returns nil for. Files that do not change are left untouched.
+func (f *FileRetention) rewrite(ctx context.Context, fn func(map[string]interface{}) map[string]interface{}) error {
+	paths, err := filepath.Glob(filepath.Join(f.dir, TraceFilePattern))
+	if err != nil {
+		return fmt.Errorf("failed to list trace files: %w", err)
+	}
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := rewriteFile(path, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rewriteFile applies fn to the events of a trace file
+func rewriteFile(path string, fn func(map[string]interface{}) map[string]interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read trace file: %w", err)
+	}
+
+	var out bytes.Buffer
+	changed := false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var event map[string]interface{}
+		if err := json.Unmarshal(line, &event); err != nil {
+			// Keep lines that are not events as they are
+			out.Write(line)
+			out.WriteByte('\n')
+			continue
+		}
+		updated := fn(event)
+		if updated == nil {
+			changed = true
+			continue
+		}
+		encoded, err := json.Marshal(updated)
+		if err != nil {
+			return fmt.Errorf("failed to encode trace event: %w", err)
+		}
+		if !bytes.Equal(encoded, line) {
+			changed = true
+		}
+		out.Write(encoded)
+		out.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read trace file %s: %w", path, err)
+	}
+	if !changed {
+		return nil
+	}
+
+	// Truncate and write the same file, so tracers holding it open in append
+	// mode keep appending to it
+	if err := os.WriteFile(path, out.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to rewrite trace file %s: %w", path, err)
+	}
+	return nil
+}
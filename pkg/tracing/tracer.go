@@ -13,16 +13,19 @@ import (
 
 // Event types for tracing
 const (
-	EventTypeAgentStart      = "agent_start"
-	EventTypeAgentEnd        = "agent_end"
-	EventTypeToolCall        = "tool_call"
-	EventTypeToolResult      = "tool_result"
-	EventTypeModelRequest    = "model_request"
-	EventTypeModelResponse   = "model_response"
-	EventTypeHandoff         = "handoff"
-	EventTypeHandoffComplete = "handoff_complete"
-	EventTypeAgentMessage    = "agent_message"
-	EventTypeError           = "error"
+	EventTypeAgentStart       = "agent_start"
+	EventTypeAgentEnd         = "agent_end"
+	EventTypeToolCall         = "tool_call"
+	EventTypeToolResult       = "tool_result"
+	EventTypeModelRequest     = "model_request"
+	EventTypeModelResponse    = "model_response"
+	EventTypeHandoff          = "handoff"
+	EventTypeHandoffComplete  = "handoff_complete"
+	EventTypeAgentMessage     = "agent_message"
+	EventTypeError            = "error"
+	EventTypeSLABreach        = "sla_breach"
+	EventTypeLoopDetected     = "loop_detected"
+	EventTypeDeadlineExceeded = "deadline_exceeded"
 )
 
 // Event is a trace event
@@ -32,6 +35,12 @@ type Event struct {
 	Timestamp time.Time              `json:"timestamp"`
 	Details   map[string]interface{} `json:"details,omitempty"`
 	Error     error                  `json:"error,omitempty"`
+
+	// UserID is the end user of the run the event belongs to
+	UserID string `json:"user_id,omitempty"`
+
+	// CorrelationID ties the event to the request or message that started the run
+	CorrelationID string `json:"correlation_id,omitempty"`
 }
 
 // Tracer is the interface for tracing
@@ -99,6 +108,12 @@ func (t *FileTracer) RecordEvent(ctx context.Context, event Event) {
 	if event.Timestamp.IsZero() {
 		event.Timestamp = time.Now()
 	}
+	if event.UserID == "" {
+		event.UserID = UserID(ctx)
+	}
+	if event.CorrelationID == "" {
+		event.CorrelationID = CorrelationID(ctx)
+	}
 
 	// Marshal event to JSON
 	data, err := json.Marshal(event)
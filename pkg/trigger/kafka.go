@@ -0,0 +1,130 @@
+package trigger
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// KafkaMessage is a Kafka record
+type KafkaMessage struct {
+	Topic     string
+	Partition int
+	Offset    int64
+	Key       []byte
+	Value     []byte
+	Headers   map[string]string
+}
+
+// KafkaClient is the subset of a consumer group reader and a writer used by the
+// adapter, modelled on kafka-go's Reader and Writer
+type KafkaClient interface {
+	// FetchMessage waits for the next record without committing it
+	FetchMessage(ctx context.Context) (KafkaMessage, error)
+
+	// CommitMessages commits the offsets of records
+	CommitMessages(ctx context.Context, msgs ...KafkaMessage) error
+
+	// WriteMessages writes records
+	WriteMessages(ctx context.Context, msgs ...KafkaMessage) error
+}
+
+// kafkaPartition identifies a partition of a topic
+type kafkaPartition struct {
+	topic     string
+	partition int
+}
+
+// Kafka consumes from and publishes to Kafka. Kafka commits offsets rather than
+// single records, so a record is only committed once it and every record
+// before it in its partition have been handled. A record that is handed back
+// holds up the commits of its partition until the consumer restarts and
+// receives it again; configure a dead-letter topic to keep a failing record
+// from stalling its partition.
+type Kafka struct {
+	client KafkaClient
+
+	// Records of each partition that were received but not committed, by offset,
+	// and whether they were handled
+	pending map[kafkaPartition]map[int64]bool
+	mu      sync.Mutex
+}
+
+// NewKafka creates a Kafka source and publisher
+func NewKafka(client KafkaClient) *Kafka {
+	return &Kafka{client: client, pending: make(map[kafkaPartition]map[int64]bool)}
+}
+
+// Receive fetches the next record
+func (k *Kafka) Receive(ctx context.Context) ([]*Message, error) {
+	record, err := k.client.FetchMessage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Kafka record: %w", err)
+	}
+
+	p := kafkaPartition{topic: record.Topic, partition: record.Partition}
+	k.mu.Lock()
+	if k.pending[p] == nil {
+		k.pending[p] = make(map[int64]bool)
+	}
+	k.pending[p][record.Offset] = false
+	k.mu.Unlock()
+
+	id := fmt.Sprintf("%s/%d/%d", record.Topic, record.Partition, record.Offset)
+	nack := func(context.Context) error { return nil }
+	msg := NewMessage(id, record.Value, record.Headers, 0, func(ctx context.Context) error {
+		return k.commit(ctx, record)
+	}, nack)
+	return []*Message{msg}, nil
+}
+
+// commit marks a record as handled and commits the records of its partition
+// that are handled without gaps
+func (k *Kafka) commit(ctx context.Context, record KafkaMessage) error {
+	p := kafkaPartition{topic: record.Topic, partition: record.Partition}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	pending := k.pending[p]
+	pending[record.Offset] = true
+	offsets := make([]int64, 0, len(pending))
+	for offset := range pending {
+		offsets = append(offsets, offset)
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	last := int64(-1)
+	for _, offset := range offsets {
+		if !pending[offset] {
+			break
+		}
+		last = offset
+	}
+	if last < 0 {
+		return nil
+	}
+
+	if err := k.client.CommitMessages(ctx, KafkaMessage{Topic: record.Topic, Partition: record.Partition, Offset: last}); err != nil {
+		return fmt.Errorf("failed to commit Kafka offset: %w", err)
+	}
+	for _, offset := range offsets {
+		if offset > last {
+			break
+		}
+		delete(pending, offset)
+	}
+	return nil
+}
+
+// Publish writes a message to a topic
+func (k *Kafka) Publish(ctx context.Context, topic string, msg *Message) error {
+	record := KafkaMessage{Topic: topic, Value: msg.Body, Headers: msg.Headers}
+	if id := msg.Headers[DefaultCorrelationHeader]; id != "" {
+		record.Key = []byte(id)
+	}
+	if err := k.client.WriteMessages(ctx, record); err != nil {
+		return fmt.Errorf("failed to write to Kafka topic %s: %w", topic, err)
+	}
+	return nil
+}
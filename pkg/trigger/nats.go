@@ -0,0 +1,97 @@
+package trigger
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// NATSMessage is a message of a JetStream pull consumer. A thin adapter around
+// jetstream.Msg satisfies it.
+type NATSMessage interface {
+	// Subject returns the subject the message was published to
+	Subject() string
+
+	// Sequence returns the sequence number of the message in its stream
+	Sequence() uint64
+
+	// Data returns the payload
+	Data() []byte
+
+	// Headers returns the headers, one value per key
+	Headers() map[string]string
+
+	// NumDelivered returns how many times the message has been delivered
+	NumDelivered() int
+
+	// Ack acknowledges the message
+	Ack() error
+
+	// Nak asks the server to redeliver the message
+	Nak() error
+}
+
+// NATSClient is the subset of a JetStream consumer and publisher used by the
+// adapter
+type NATSClient interface {
+	// Fetch waits for up to max messages from the pull consumer
+	Fetch(ctx context.Context, max int) ([]NATSMessage, error)
+
+	// Publish publishes a message to a subject, waiting for the server to store it
+	Publish(ctx context.Context, subject string, data []byte, headers map[string]string) error
+}
+
+// natsMsgIDHeader is the header JetStream deduplicates messages by
+const natsMsgIDHeader = "Nats-Msg-Id"
+
+// NATS consumes from and publishes to NATS JetStream
+type NATS struct {
+	client    NATSClient
+	batchSize int
+	mu        sync.Mutex
+}
+
+// NewNATS creates a NATS JetStream source and publisher
+func NewNATS(client NATSClient) *NATS {
+	return &NATS{client: client, batchSize: 10}
+}
+
+// WithBatchSize sets how many messages are fetched at once
+func (n *NATS) WithBatchSize(size int) *NATS {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.batchSize = size
+	return n
+}
+
+// Receive fetches the next batch of messages
+func (n *NATS) Receive(ctx context.Context) ([]*Message, error) {
+	n.mu.Lock()
+	batchSize := n.batchSize
+	n.mu.Unlock()
+
+	fetched, err := n.client.Fetch(ctx, batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch NATS messages: %w", err)
+	}
+	msgs := make([]*Message, len(fetched))
+	for i, m := range fetched {
+		headers := m.Headers()
+		id := headers[natsMsgIDHeader]
+		if id == "" {
+			id = fmt.Sprintf("%s:%d", m.Subject(), m.Sequence())
+		}
+		msgs[i] = NewMessage(id, m.Data(), headers, m.NumDelivered(),
+			func(context.Context) error { return m.Ack() },
+			func(context.Context) error { return m.Nak() })
+	}
+	return msgs, nil
+}
+
+// Publish publishes a message to a subject
+func (n *NATS) Publish(ctx context.Context, subject string, msg *Message) error {
+	if err := n.client.Publish(ctx, subject, msg.Body, msg.Headers); err != nil {
+		return fmt.Errorf("failed to publish to NATS subject %s: %w", subject, err)
+	}
+	return nil
+}
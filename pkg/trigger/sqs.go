@@ -0,0 +1,99 @@
+package trigger
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SQSMessage is a message received from an SQS queue
+type SQSMessage struct {
+	MessageID     string
+	ReceiptHandle string
+	Body          string
+	Attributes    map[string]string
+
+	// ReceiveCount is the ApproximateReceiveCount system attribute
+	ReceiveCount int
+}
+
+// SQSClient is the subset of the SQS API used by the adapter. A thin adapter
+// around the AWS SDK's sqs.Client satisfies it.
+type SQSClient interface {
+	// ReceiveMessages long-polls a queue for up to max messages
+	ReceiveMessages(ctx context.Context, queueURL string, max int, wait time.Duration) ([]SQSMessage, error)
+
+	// DeleteMessage deletes a received message
+	DeleteMessage(ctx context.Context, queueURL, receiptHandle string) error
+
+	// ChangeMessageVisibility changes when a received message becomes visible again
+	ChangeMessageVisibility(ctx context.Context, queueURL, receiptHandle string, timeout time.Duration) error
+
+	// SendMessage sends a message with message attributes
+	SendMessage(ctx context.Context, queueURL, body string, attributes map[string]string) error
+}
+
+// SQS consumes from an SQS queue and publishes to SQS queues, which are
+// addressed by their URLs
+type SQS struct {
+	client    SQSClient
+	queueURL  string
+	batchSize int
+	waitTime  time.Duration
+	mu        sync.Mutex
+}
+
+// NewSQS creates a source for an SQS queue that can also publish to queues
+func NewSQS(client SQSClient, queueURL string) *SQS {
+	return &SQS{client: client, queueURL: queueURL, batchSize: 10, waitTime: 20 * time.Second}
+}
+
+// WithBatchSize sets how many messages are received at once, at most 10
+func (s *SQS) WithBatchSize(size int) *SQS {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batchSize = size
+	return s
+}
+
+// WithWaitTime sets how long a receive waits for messages, at most 20 seconds
+func (s *SQS) WithWaitTime(wait time.Duration) *SQS {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.waitTime = wait
+	return s
+}
+
+// Receive long-polls the queue. A message that is handed back becomes visible
+// again right away.
+func (s *SQS) Receive(ctx context.Context) ([]*Message, error) {
+	s.mu.Lock()
+	batchSize, waitTime := s.batchSize, s.waitTime
+	s.mu.Unlock()
+
+	received, err := s.client.ReceiveMessages(ctx, s.queueURL, batchSize, waitTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive SQS messages: %w", err)
+	}
+	msgs := make([]*Message, len(received))
+	for i, m := range received {
+		handle := m.ReceiptHandle
+		msgs[i] = NewMessage(m.MessageID, []byte(m.Body), m.Attributes, m.ReceiveCount,
+			func(ctx context.Context) error {
+				return s.client.DeleteMessage(ctx, s.queueURL, handle)
+			},
+			func(ctx context.Context) error {
+				return s.client.ChangeMessageVisibility(ctx, s.queueURL, handle, 0)
+			})
+	}
+	return msgs, nil
+}
+
+// Publish sends a message to the queue with the given URL
+func (s *SQS) Publish(ctx context.Context, queueURL string, msg *Message) error {
+	if err := s.client.SendMessage(ctx, queueURL, string(msg.Body), msg.Headers); err != nil {
+		return fmt.Errorf("failed to send SQS message: %w", err)
+	}
+	return nil
+}
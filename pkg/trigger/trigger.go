@@ -0,0 +1,386 @@
+// Package trigger starts agent runs from message queue events. A Consumer reads
+// messages from a Source, runs an agent on each and publishes the results,
+// acknowledging a message only once it has been handled, so every message is
+// processed at least once.
+//
+// The adapters for NATS JetStream, Kafka and SQS do not depend on a specific
+// client library; each talks to its broker through a small client interface
+// that can be satisfied by a thin adapter around the official client.
+package trigger
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tracing"
+)
+
+// Headers set on the messages a Consumer publishes
+const (
+	// DefaultCorrelationHeader is the header carrying the correlation ID of a message
+	DefaultCorrelationHeader = "correlation-id"
+
+	// TraceparentHeader carries the W3C trace context of a message
+	TraceparentHeader = "traceparent"
+
+	// SourceMessageHeader is the ID of the message a result was produced for
+	SourceMessageHeader = "source-message-id"
+
+	// AgentHeader is the name of the agent that produced a result
+	AgentHeader = "agent"
+
+	// ErrorHeader is the error of a dead-lettered message
+	ErrorHeader = "error"
+)
+
+// Message is a message read from or published to a queue
+type Message struct {
+	// ID is the broker's ID of the message
+	ID string
+
+	// Body is the payload of the message
+	Body []byte
+
+	// Headers are the headers or attributes of the message
+	Headers map[string]string
+
+	// Deliveries is how many times the message has been delivered, including
+	// this delivery, or zero if the broker does not count deliveries
+	Deliveries int
+
+	ack  func(ctx context.Context) error
+	nack func(ctx context.Context) error
+}
+
+// NewMessage creates a message received by a source. Ack removes the message
+// from the queue; nack makes it available for redelivery.
+func NewMessage(id string, body []byte, headers map[string]string, deliveries int, ack, nack func(ctx context.Context) error) *Message {
+	return &Message{ID: id, Body: body, Headers: headers, Deliveries: deliveries, ack: ack, nack: nack}
+}
+
+// Ack acknowledges that the message was handled
+func (m *Message) Ack(ctx context.Context) error {
+	if m.ack == nil {
+		return nil
+	}
+	return m.ack(ctx)
+}
+
+// Nack hands the message back to the broker for redelivery
+func (m *Message) Nack(ctx context.Context) error {
+	if m.nack == nil {
+		return nil
+	}
+	return m.nack(ctx)
+}
+
+// Source delivers messages from a queue
+type Source interface {
+	// Receive blocks until messages are available or ctx ends
+	Receive(ctx context.Context) ([]*Message, error)
+}
+
+// Publisher publishes messages to a topic, subject or queue
+type Publisher interface {
+	// Publish publishes a message
+	Publish(ctx context.Context, topic string, msg *Message) error
+}
+
+// InputFunc converts a message to the input of a run
+type InputFunc func(msg *Message) (interface{}, error)
+
+// Consumer runs an agent for each message of a source
+type Consumer struct {
+	source            Source
+	agent             *agent.Agent
+	runner            *runner.Runner
+	runOptions        *runner.RunOptions
+	input             InputFunc
+	publisher         Publisher
+	outputTopic       string
+	deadLetterTopic   string
+	maxDeliveries     int
+	retries           int
+	retryDelay        time.Duration
+	concurrency       int
+	correlationHeader string
+	onError           func(*Message, error)
+	mu                sync.Mutex
+}
+
+// NewConsumer creates a consumer running an agent on the messages of a source.
+// By default the body of each message is the input of the run.
+func NewConsumer(source Source, a *agent.Agent) *Consumer {
+	return &Consumer{
+		source:            source,
+		agent:             a,
+		runner:            runner.NewRunner(),
+		concurrency:       1,
+		correlationHeader: DefaultCorrelationHeader,
+		input: func(msg *Message) (interface{}, error) {
+			return string(msg.Body), nil
+		},
+	}
+}
+
+// WithRunner sets the runner of the agent
+func (c *Consumer) WithRunner(r *runner.Runner) *Consumer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.runner = r
+	return c
+}
+
+// WithRunOptions sets the options the agent runs with. Their input is replaced
+// by the input of the message.
+func (c *Consumer) WithRunOptions(opts *runner.RunOptions) *Consumer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.runOptions = opts
+	return c
+}
+
+// WithInputFunc sets how messages are converted to the input of a run, such as
+// by decoding a JSON event
+func (c *Consumer) WithInputFunc(fn InputFunc) *Consumer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.input = fn
+	return c
+}
+
+// WithOutput publishes the final output of each run to a topic
+func (c *Consumer) WithOutput(publisher Publisher, topic string) *Consumer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.publisher = publisher
+	c.outputTopic = topic
+	return c
+}
+
+// WithDeadLetter publishes messages that keep failing to a topic instead of
+// handing them back to the broker: once they have been delivered maxDeliveries
+// times, or on their first failure if the broker does not count deliveries. It
+// uses the publisher set with WithOutput.
+func (c *Consumer) WithDeadLetter(topic string, maxDeliveries int) *Consumer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadLetterTopic = topic
+	c.maxDeliveries = maxDeliveries
+	return c
+}
+
+// WithRetry retries a failed run within the same delivery
+func (c *Consumer) WithRetry(retries int, delay time.Duration) *Consumer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.retries = retries
+	c.retryDelay = delay
+	return c
+}
+
+// WithConcurrency sets how many messages are handled at once. The default is 1.
+func (c *Consumer) WithConcurrency(n int) *Consumer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n > 0 {
+		c.concurrency = n
+	}
+	return c
+}
+
+// WithCorrelationHeader sets the header the correlation ID of a message is read
+// from. Messages without it are correlated by their ID.
+func (c *Consumer) WithCorrelationHeader(header string) *Consumer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.correlationHeader = header
+	return c
+}
+
+// OnError sets a function called when a message cannot be handled
+func (c *Consumer) OnError(fn func(*Message, error)) *Consumer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onError = fn
+	return c
+}
+
+// Run consumes messages until ctx ends, then waits for the messages being
+// handled and returns nil. Runs still in progress are cancelled and their
+// messages handed back for redelivery.
+func (c *Consumer) Run(ctx context.Context) error {
+	c.mu.Lock()
+	slots := make(chan struct{}, c.concurrency)
+	c.mu.Unlock()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	backoff := 100 * time.Millisecond
+	for ctx.Err() == nil {
+		msgs, err := c.source.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			logging.For(nil, "trigger").Warn("Failed to receive messages", "error", err)
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+			}
+			backoff = min(backoff*2, 30*time.Second)
+			continue
+		}
+		backoff = 100 * time.Millisecond
+
+		for _, msg := range msgs {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				c.release(ctx, msg)
+				continue
+			}
+			wg.Add(1)
+			go func(msg *Message) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				_ = c.Handle(ctx, msg)
+			}(msg)
+		}
+	}
+	return nil
+}
+
+// release hands a message that was not handled back to the broker
+func (c *Consumer) release(ctx context.Context, msg *Message) {
+	if err := msg.Nack(context.WithoutCancel(ctx)); err != nil {
+		c.reportError(msg, fmt.Errorf("failed to release message: %w", err))
+	}
+}
+
+// Handle runs the agent on a message, publishes the result and acknowledges
+// the message. A message that fails is handed back for redelivery, or
+// dead-lettered once it has been delivered too often.
+func (c *Consumer) Handle(ctx context.Context, msg *Message) error {
+	c.mu.Lock()
+	r, runOptions, input := c.runner, c.runOptions, c.input
+	publisher, outputTopic := c.publisher, c.outputTopic
+	retries, retryDelay := c.retries, c.retryDelay
+	correlationID := msg.Headers[c.correlationHeader]
+	correlationHeader := c.correlationHeader
+	c.mu.Unlock()
+
+	if correlationID == "" {
+		correlationID = msg.ID
+	}
+	// The run joins the trace of the message, or starts one its result carries on
+	ctx = tracing.WithCorrelationID(ctx, correlationID)
+	sc, err := tracing.ParseTraceparent(msg.Headers[TraceparentHeader])
+	if err != nil {
+		sc = tracing.NewSpanContext()
+	}
+	ctx = tracing.ContextWithSpanContext(ctx, sc)
+
+	in, err := input(msg)
+	if err != nil {
+		return c.fail(ctx, msg, fmt.Errorf("invalid message: %w", err))
+	}
+	opts := &runner.RunOptions{}
+	if runOptions != nil {
+		*opts = *runOptions
+	}
+	opts.Input = in
+
+	var output interface{}
+	for attempt := 0; ; attempt++ {
+		res, err := r.Run(ctx, c.agent, opts)
+		if err == nil {
+			output = res.FinalOutput
+			break
+		}
+		if attempt >= retries || ctx.Err() != nil {
+			return c.fail(ctx, msg, err)
+		}
+		select {
+		case <-time.After(retryDelay):
+		case <-ctx.Done():
+			return c.fail(ctx, msg, ctx.Err())
+		}
+	}
+
+	if publisher != nil && outputTopic != "" {
+		result := &Message{
+			Body: []byte(model.ToolResultText(output)),
+			Headers: map[string]string{
+				correlationHeader:   correlationID,
+				SourceMessageHeader: msg.ID,
+				AgentHeader:         c.agent.Name,
+			},
+		}
+		result.Headers[TraceparentHeader] = sc.Traceparent()
+		if err := publisher.Publish(ctx, outputTopic, result); err != nil {
+			return c.fail(ctx, msg, fmt.Errorf("failed to publish result: %w", err))
+		}
+	}
+
+	if err := msg.Ack(context.WithoutCancel(ctx)); err != nil {
+		err = fmt.Errorf("failed to acknowledge message: %w", err)
+		c.reportError(msg, err)
+		return err
+	}
+	return nil
+}
+
+// fail dead-letters or releases a message that could not be handled
+func (c *Consumer) fail(ctx context.Context, msg *Message, cause error) error {
+	c.mu.Lock()
+	publisher, topic, maxDeliveries := c.publisher, c.deadLetterTopic, c.maxDeliveries
+	c.mu.Unlock()
+
+	err := fmt.Errorf("failed to handle message %s: %w", msg.ID, cause)
+	c.reportError(msg, err)
+
+	exhausted := msg.Deliveries == 0 || msg.Deliveries >= maxDeliveries
+	cancelled := ctx.Err() != nil
+	ctx = context.WithoutCancel(ctx)
+	if publisher != nil && topic != "" && exhausted && !cancelled {
+		headers := make(map[string]string, len(msg.Headers)+2)
+		for k, v := range msg.Headers {
+			headers[k] = v
+		}
+		headers[SourceMessageHeader] = msg.ID
+		headers[ErrorHeader] = cause.Error()
+		pubErr := publisher.Publish(ctx, topic, &Message{Body: msg.Body, Headers: headers})
+		if pubErr == nil {
+			if ackErr := msg.Ack(ctx); ackErr != nil {
+				c.reportError(msg, fmt.Errorf("failed to acknowledge dead-lettered message: %w", ackErr))
+			}
+			return err
+		}
+		c.reportError(msg, fmt.Errorf("failed to dead-letter message: %w", pubErr))
+	}
+
+	if nackErr := msg.Nack(ctx); nackErr != nil {
+		c.reportError(msg, fmt.Errorf("failed to release message: %w", nackErr))
+	}
+	return err
+}
+
+// reportError passes an error to the error handler
+func (c *Consumer) reportError(msg *Message, err error) {
+	c.mu.Lock()
+	onError := c.onError
+	c.mu.Unlock()
+
+	if onError != nil {
+		onError(msg, err)
+	} else {
+		logging.For(nil, "trigger").Warn("Failed to handle message", "message", msg.ID, "error", err)
+	}
+}
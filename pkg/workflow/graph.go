@@ -0,0 +1,697 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+)
+
+var (
+	// ErrCycle is returned for graphs whose edges form a cycle
+	ErrCycle = errors.New("workflow graph has a cycle")
+
+	// ErrEmptyGraph is returned when running a graph without steps
+	ErrEmptyGraph = errors.New("workflow graph has no steps")
+)
+
+// StepFunc is a step that runs Go code. Its output is passed on to the next
+// steps.
+type StepFunc func(ctx context.Context, state *State) (interface{}, error)
+
+// Condition decides whether a conditional edge is taken, once the step it
+// leaves has completed
+type Condition func(state *State) bool
+
+// State is what a step or condition sees of a graph run
+type State struct {
+	// Input is the input of the graph run
+	Input interface{}
+
+	// Outputs are the outputs of the steps completed so far, by step name
+	Outputs map[string]interface{}
+
+	// Step is the running step, or the completed step whose edges a condition
+	// decides on
+	Step string
+
+	// Previous is the output a step builds on: its predecessor's output, a map
+	// by step name when several predecessors led to it, or the graph input for
+	// steps without predecessors. For conditions it is the output of Step.
+	Previous interface{}
+
+	// joined reports whether Previous holds the outputs of several steps
+	joined bool
+}
+
+// Output returns the output of a completed step
+func (s *State) Output(step string) interface{} {
+	return s.Outputs[step]
+}
+
+// RetryPolicy retries a failed step
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt
+	MaxRetries int
+
+	// Delay is the wait before the first retry
+	Delay time.Duration
+
+	// BackoffFactor multiplies the delay after each retry. Values below 1 keep
+	// the delay constant.
+	BackoffFactor float64
+
+	// RetryIf decides whether an error is retried. Nil retries every error.
+	RetryIf func(err error) bool
+}
+
+// StepOption configures a step
+type StepOption func(*step)
+
+// WithRetry retries the step when it fails
+func WithRetry(policy RetryPolicy) StepOption {
+	return func(s *step) {
+		s.retry = policy
+	}
+}
+
+// WithStepInput sets the input of an agent step, in place of the output of its
+// predecessors
+func WithStepInput(fn func(state *State) interface{}) StepOption {
+	return func(s *step) {
+		s.input = fn
+	}
+}
+
+// step is a node of a graph
+type step struct {
+	name  string
+	agent *agent.Agent
+	fn    StepFunc
+	retry RetryPolicy
+	input func(state *State) interface{}
+}
+
+// edge connects two steps, taken when its condition holds
+type edge struct {
+	from, to  string
+	condition Condition
+}
+
+// Graph is a workflow whose steps run in an explicit, deterministic order
+// rather than in the order a model picks. Steps are agents or Go functions,
+// connected by edges that may be conditional:
+//
+//	g := workflow.NewGraph().
+//		Step("analyze", analyzer).
+//		Then("optimize", optimizer, workflow.WithRetry(workflow.RetryPolicy{MaxRetries: 2})).
+//		Branch(needsReview, "review", "publish").
+//		Step("review", reviewer).
+//		Then("publish", publish)
+//
+// A step runs once all the steps leading to it are done and at least one of
+// their edges to it was taken; steps none of whose edges were taken are
+// skipped. Steps with several outgoing edges fan out and run concurrently, and
+// a step joining them runs after all of them.
+type Graph struct {
+	steps  map[string]*step
+	order  []string
+	edges  []edge
+	cursor string
+	err    error
+
+	runner     *runner.Runner
+	runOptions *runner.RunOptions
+	store      runner.WorkflowStateStore
+	id         string
+}
+
+// NewGraph creates an empty workflow graph
+func NewGraph() *Graph {
+	return &Graph{steps: make(map[string]*step), runner: runner.NewRunner()}
+}
+
+// Step adds a step, an *agent.Agent, a StepFunc or a *Parallel, and makes it the step the
+// next Then, Branch or FanOut starts from
+func (g *Graph) Step(name string, node interface{}, opts ...StepOption) *Graph {
+	if g.err != nil {
+		return g
+	}
+	if _, exists := g.steps[name]; exists {
+		g.err = fmt.Errorf("step %q is declared twice", name)
+		return g
+	}
+
+	s := &step{name: name}
+	switch n := node.(type) {
+	case *agent.Agent:
+		s.agent = n
+	case StepFunc:
+		s.fn = n
+	case func(ctx context.Context, state *State) (interface{}, error):
+		s.fn = n
+	case *Parallel:
+		s.fn = n.Step()
+	default:
+		g.err = fmt.Errorf("step %q must be an *agent.Agent, a StepFunc or a *Parallel, not %T", name, node)
+		return g
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	g.steps[name] = s
+	g.order = append(g.order, name)
+	g.cursor = name
+	return g
+}
+
+// Then adds a step that runs after the current one
+func (g *Graph) Then(name string, node interface{}, opts ...StepOption) *Graph {
+	from := g.cursor
+	g.Step(name, node, opts...)
+	if from != "" {
+		g.Edge(from, name)
+	}
+	return g
+}
+
+// Edge connects two steps
+func (g *Graph) Edge(from, to string) *Graph {
+	return g.EdgeIf(from, to, nil)
+}
+
+// EdgeIf connects two steps with an edge that is taken when a condition holds
+func (g *Graph) EdgeIf(from, to string, condition Condition) *Graph {
+	g.edges = append(g.edges, edge{from: from, to: to, condition: condition})
+	return g
+}
+
+// Branch continues from the current step to one of two steps, depending on a
+// condition. An empty step name ends that branch.
+func (g *Graph) Branch(condition Condition, ifTrue, ifFalse string) *Graph {
+	if g.cursor == "" {
+		g.err = errors.New("branch before the first step")
+		return g
+	}
+	if ifTrue != "" {
+		g.EdgeIf(g.cursor, ifTrue, condition)
+	}
+	if ifFalse != "" {
+		g.EdgeIf(g.cursor, ifFalse, func(state *State) bool { return !condition(state) })
+	}
+	return g
+}
+
+// FanOut continues from the current step to several steps, which run
+// concurrently
+func (g *Graph) FanOut(to ...string) *Graph {
+	for _, name := range to {
+		g.Edge(g.cursor, name)
+	}
+	return g
+}
+
+// Join makes a step wait for several steps and continues from it. It sees
+// their outputs by step name in State.Previous.
+func (g *Graph) Join(to string, from ...string) *Graph {
+	for _, name := range from {
+		g.Edge(name, to)
+	}
+	g.cursor = to
+	return g
+}
+
+// WithRunner sets the runner of the agent steps
+func (g *Graph) WithRunner(r *runner.Runner) *Graph {
+	g.runner = r
+	return g
+}
+
+// WithRunOptions sets the options of the agent steps' runs. Their input is
+// replaced by the input of each step.
+func (g *Graph) WithRunOptions(opts *runner.RunOptions) *Graph {
+	g.runOptions = opts
+	return g
+}
+
+// WithStateStore checkpoints the graph in a state store after every step. A run
+// of a graph whose last run with the same ID failed resumes after the steps that
+// completed, without running them again.
+func (g *Graph) WithStateStore(store runner.WorkflowStateStore, workflowID string) *Graph {
+	g.store = store
+	g.id = workflowID
+	return g
+}
+
+// StepError is returned for a step that failed after its retries
+type StepError struct {
+	Step     string
+	Attempts int
+	Err      error
+}
+
+// Error implements the error interface
+func (e *StepError) Error() string {
+	return fmt.Sprintf("step %s failed after %d attempt(s): %v", e.Step, e.Attempts, e.Err)
+}
+
+// Unwrap returns the error of the last attempt
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// GraphResult is the outcome of a graph run
+type GraphResult struct {
+	// Output is the output of the last step to complete
+	Output interface{}
+
+	// Outputs are the outputs of the completed steps, by step name
+	Outputs map[string]interface{}
+
+	// Completed are the completed steps in the order they completed, including
+	// those restored from a checkpoint
+	Completed []string
+
+	// Skipped are the steps none of whose incoming edges were taken
+	Skipped []string
+
+	// RunResults are the results of the agent steps run by this call
+	RunResults map[string]*result.RunResult
+}
+
+// validate checks that the graph is complete and acyclic
+func (g *Graph) validate() error {
+	if g.err != nil {
+		return g.err
+	}
+	if len(g.order) == 0 {
+		return ErrEmptyGraph
+	}
+	for _, e := range g.edges {
+		if _, ok := g.steps[e.from]; !ok {
+			return fmt.Errorf("edge from unknown step %q", e.from)
+		}
+		if _, ok := g.steps[e.to]; !ok {
+			return fmt.Errorf("edge from %q to unknown step %q", e.from, e.to)
+		}
+	}
+
+	// Kahn's algorithm: a graph is acyclic if every step can be ordered
+	incoming := make(map[string]int, len(g.order))
+	for _, e := range g.edges {
+		incoming[e.to]++
+	}
+	var ready []string
+	for _, name := range g.order {
+		if incoming[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+	for ordered := 0; ; ordered++ {
+		if len(ready) == 0 {
+			if ordered < len(g.order) {
+				return ErrCycle
+			}
+			return nil
+		}
+		name := ready[0]
+		ready = ready[1:]
+		for _, e := range g.edges {
+			if e.from == name {
+				incoming[e.to]--
+				if incoming[e.to] == 0 {
+					ready = append(ready, e.to)
+				}
+			}
+		}
+	}
+}
+
+// Status of steps in a graph run
+const (
+	stepPending = iota
+	stepRunning
+	stepCompleted
+	stepSkipped
+)
+
+// graphRun is the progress of a graph run
+type graphRun struct {
+	graph   *Graph
+	input   interface{}
+	status  map[string]int
+	taken   map[int]bool
+	outputs map[string]interface{}
+	result  *GraphResult
+}
+
+// stepOutcome is the outcome of running a step
+type stepOutcome struct {
+	name     string
+	output   interface{}
+	run      *result.RunResult
+	attempts int
+	err      error
+}
+
+// Run runs the graph on an input until every step has completed or been
+// skipped, or a step fails
+func (g *Graph) Run(ctx context.Context, input interface{}) (*GraphResult, error) {
+	if err := g.validate(); err != nil {
+		return nil, err
+	}
+
+	run := &graphRun{
+		graph:   g,
+		input:   input,
+		status:  make(map[string]int, len(g.order)),
+		taken:   make(map[int]bool),
+		outputs: make(map[string]interface{}),
+		result:  &GraphResult{RunResults: make(map[string]*result.RunResult)},
+	}
+	if err := run.restore(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	outcomes := make(chan stepOutcome)
+	running := 0
+	for {
+		running += run.startReady(ctx, outcomes)
+		if running == 0 {
+			break
+		}
+
+		outcome := <-outcomes
+		running--
+		if outcome.err != nil {
+			cancel()
+			for ; running > 0; running-- {
+				<-outcomes
+			}
+			return run.finish(), &StepError{Step: outcome.name, Attempts: outcome.attempts, Err: outcome.err}
+		}
+		run.complete(outcome)
+		if err := run.checkpoint(false); err != nil {
+			cancel()
+			for ; running > 0; running-- {
+				<-outcomes
+			}
+			return run.finish(), err
+		}
+	}
+
+	if err := run.checkpoint(true); err != nil {
+		return run.finish(), err
+	}
+	return run.finish(), nil
+}
+
+// startReady starts the steps whose incoming edges are all decided, skipping
+// those none of whose edges were taken, and returns the number started
+func (run *graphRun) startReady(ctx context.Context, outcomes chan<- stepOutcome) int {
+	started := 0
+	for changed := true; changed; {
+		changed = false
+		for _, name := range run.graph.order {
+			if run.status[name] != stepPending {
+				continue
+			}
+			decided, from := run.predecessors(name)
+			if !decided {
+				continue
+			}
+			if from == nil {
+				run.status[name] = stepSkipped
+				run.result.Skipped = append(run.result.Skipped, name)
+				changed = true
+				continue
+			}
+
+			run.status[name] = stepRunning
+			state := run.state(name, run.previous(from))
+			state.joined = len(from) > 1
+			s := run.graph.steps[name]
+			go func() {
+				outcomes <- run.graph.runStep(ctx, s, state)
+			}()
+			started++
+		}
+	}
+	return started
+}
+
+// predecessors reports whether all edges into a step are decided and returns
+// the steps whose edges were taken. Steps without incoming edges return an
+// empty, non-nil list.
+func (run *graphRun) predecessors(name string) (bool, []string) {
+	from := []string{}
+	incoming := false
+	for i, e := range run.graph.edges {
+		if e.to != name {
+			continue
+		}
+		incoming = true
+		switch run.status[e.from] {
+		case stepCompleted:
+			if run.taken[i] {
+				from = append(from, e.from)
+			}
+		case stepSkipped:
+		default:
+			return false, nil
+		}
+	}
+	if incoming && len(from) == 0 {
+		return true, nil
+	}
+	return true, from
+}
+
+// previous returns the output a step builds on
+func (run *graphRun) previous(from []string) interface{} {
+	switch len(from) {
+	case 0:
+		return run.input
+	case 1:
+		return run.outputs[from[0]]
+	}
+	outputs := make(map[string]interface{}, len(from))
+	for _, name := range from {
+		outputs[name] = run.outputs[name]
+	}
+	return outputs
+}
+
+// state returns a snapshot of the run for a step
+func (run *graphRun) state(name string, previous interface{}) *State {
+	outputs := make(map[string]interface{}, len(run.outputs))
+	for step, output := range run.outputs {
+		outputs[step] = output
+	}
+	return &State{Input: run.input, Outputs: outputs, Step: name, Previous: previous}
+}
+
+// complete records the output of a step and decides its outgoing edges
+func (run *graphRun) complete(outcome stepOutcome) {
+	run.status[outcome.name] = stepCompleted
+	run.outputs[outcome.name] = outcome.output
+	run.result.Completed = append(run.result.Completed, outcome.name)
+	run.result.Output = outcome.output
+	if outcome.run != nil {
+		run.result.RunResults[outcome.name] = outcome.run
+	}
+	run.decideEdges(outcome.name)
+}
+
+// decideEdges decides which edges leaving a completed step are taken
+func (run *graphRun) decideEdges(name string) {
+	state := run.state(name, run.outputs[name])
+	for i, e := range run.graph.edges {
+		if e.from == name {
+			run.taken[i] = e.condition == nil || e.condition(state)
+		}
+	}
+}
+
+// finish returns the result of the run
+func (run *graphRun) finish() *GraphResult {
+	run.result.Outputs = run.outputs
+	return run.result
+}
+
+// Metadata keys of graph checkpoints. The kind tells graph checkpoints apart
+// from those of a WorkflowRunner sharing the store.
+const (
+	checkpointKind     = "kind"
+	checkpointSkipped  = "skipped"
+	checkpointFinished = "finished"
+
+	graphCheckpointKind = "graph"
+)
+
+// checkpoint saves the progress of the run in the graph's state store
+func (run *graphRun) checkpoint(finished bool) error {
+	g := run.graph
+	if g.store == nil {
+		return nil
+	}
+	outputs := make(map[string]interface{}, len(run.outputs))
+	for step, output := range run.outputs {
+		outputs[step] = output
+	}
+	var current string
+	if completed := run.result.Completed; len(completed) > 0 {
+		current = completed[len(completed)-1]
+	}
+	state := &runner.WorkflowState{
+		CurrentPhase:    current,
+		CompletedPhases: append([]string(nil), run.result.Completed...),
+		Artifacts:       outputs,
+		LastCheckpoint:  time.Now(),
+		Metadata: map[string]interface{}{
+			checkpointKind:     graphCheckpointKind,
+			checkpointSkipped:  append([]string(nil), run.result.Skipped...),
+			checkpointFinished: finished,
+		},
+	}
+	if err := g.store.SaveState(g.id, state); err != nil {
+		return fmt.Errorf("failed to checkpoint workflow graph: %w", err)
+	}
+	return nil
+}
+
+// restore resumes the run from the last checkpoint of an unfinished run
+func (run *graphRun) restore() error {
+	g := run.graph
+	if g.store == nil {
+		return nil
+	}
+	saved, err := g.store.LoadState(g.id)
+	if err != nil {
+		return fmt.Errorf("failed to load workflow graph checkpoint: %w", err)
+	}
+	state, ok := saved.(*runner.WorkflowState)
+	if !ok || state == nil || state.Metadata[checkpointKind] != graphCheckpointKind {
+		return nil
+	}
+	if finished, _ := state.Metadata[checkpointFinished].(bool); finished {
+		return nil
+	}
+
+	for _, name := range state.CompletedPhases {
+		if _, exists := g.steps[name]; !exists {
+			return fmt.Errorf("checkpoint of workflow graph %s has unknown step %q", g.id, name)
+		}
+		run.status[name] = stepCompleted
+		run.outputs[name] = state.Artifacts[name]
+		run.result.Completed = append(run.result.Completed, name)
+		run.result.Output = state.Artifacts[name]
+	}
+	for _, name := range stringList(state.Metadata[checkpointSkipped]) {
+		if _, exists := g.steps[name]; exists {
+			run.status[name] = stepSkipped
+			run.result.Skipped = append(run.result.Skipped, name)
+		}
+	}
+	for _, name := range state.CompletedPhases {
+		run.decideEdges(name)
+	}
+	return nil
+}
+
+// stringList converts a list restored from a checkpoint, which may have been
+// decoded from JSON
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// runStep runs a step with its retry policy
+func (g *Graph) runStep(ctx context.Context, s *step, state *State) stepOutcome {
+	outcome := stepOutcome{name: s.name}
+	delay := s.retry.Delay
+	for {
+		outcome.attempts++
+		outcome.output, outcome.run, outcome.err = g.attempt(ctx, s, state)
+		if outcome.err == nil || outcome.attempts > s.retry.MaxRetries || ctx.Err() != nil {
+			return outcome
+		}
+		if s.retry.RetryIf != nil && !s.retry.RetryIf(outcome.err) {
+			return outcome
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return outcome
+		}
+		if s.retry.BackoffFactor > 1 {
+			delay = time.Duration(float64(delay) * s.retry.BackoffFactor)
+		}
+	}
+}
+
+// attempt runs a step once
+func (g *Graph) attempt(ctx context.Context, s *step, state *State) (interface{}, *result.RunResult, error) {
+	if s.fn != nil {
+		output, err := s.fn(ctx, state)
+		return output, nil, err
+	}
+
+	opts := &runner.RunOptions{}
+	if g.runOptions != nil {
+		*opts = *g.runOptions
+	}
+	opts.Input = agentInput(state.Previous, state.joined)
+	if s.input != nil {
+		opts.Input = s.input(state)
+	}
+	res, err := g.runner.Run(ctx, s.agent, opts)
+	if err != nil {
+		return nil, res, err
+	}
+	return res.FinalOutput, res, nil
+}
+
+// agentInput converts the output a step builds on to the input of an agent.
+// Run input passes as is; the outputs of several steps become one section per
+// step, and other outputs become text.
+func agentInput(previous interface{}, joined bool) interface{} {
+	if outputs, ok := previous.(map[string]interface{}); ok && joined {
+		names := make([]string, 0, len(outputs))
+		for name := range outputs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		sections := make([]string, len(names))
+		for i, name := range names {
+			sections[i] = fmt.Sprintf("## %s\n\n%s", name, model.ToolResultText(outputs[name]))
+		}
+		return strings.Join(sections, "\n\n")
+	}
+	switch previous.(type) {
+	case string, []interface{}, []model.ContentPart:
+		return previous
+	}
+	return model.ToolResultText(previous)
+}
@@ -0,0 +1,200 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+)
+
+// ErrShardCount is returned when the shards of a parallel step don't match its
+// agents
+var ErrShardCount = errors.New("shard count does not match the agents")
+
+// ShardFunc splits the input of a parallel step into one input per run
+type ShardFunc func(input interface{}) ([]interface{}, error)
+
+// ReduceFunc aggregates the outputs of a parallel step in Go code. The outputs
+// are in the order of the runs.
+type ReduceFunc func(ctx context.Context, outputs []interface{}) (interface{}, error)
+
+// Parallel runs several agents concurrently, on the same input or on shards of
+// it, and aggregates their outputs. It can run on its own or as a Graph step.
+type Parallel struct {
+	agents     []*agent.Agent
+	shard      ShardFunc
+	reducer    *agent.Agent
+	reduce     ReduceFunc
+	runner     *runner.Runner
+	runOptions *runner.RunOptions
+}
+
+// ParallelResult is the result of a parallel step
+type ParallelResult struct {
+	// Output is the aggregated output
+	Output interface{}
+
+	// Outputs are the outputs of the runs, in their order, with nil for runs
+	// that failed
+	Outputs []interface{}
+
+	// RunResults are the results of the runs, in their order
+	RunResults []*result.RunResult
+
+	// ReducerResult is the result of the reducer agent, if any
+	ReducerResult *result.RunResult
+}
+
+// NewParallel creates a parallel step running the given agents
+func NewParallel(agents ...*agent.Agent) *Parallel {
+	return &Parallel{agents: agents, runner: runner.NewRunner()}
+}
+
+// WithShards splits the input into one input per agent. With a single agent,
+// the agent runs once per shard.
+func (p *Parallel) WithShards(fn ShardFunc) *Parallel {
+	p.shard = fn
+	return p
+}
+
+// WithReducer sets an agent that aggregates the outputs, given one section per
+// run
+func (p *Parallel) WithReducer(reducer *agent.Agent) *Parallel {
+	p.reducer = reducer
+	return p
+}
+
+// WithReduceFunc aggregates the outputs in Go code instead of with an agent
+func (p *Parallel) WithReduceFunc(fn ReduceFunc) *Parallel {
+	p.reduce = fn
+	return p
+}
+
+// WithRunner sets the runner of the agents
+func (p *Parallel) WithRunner(r *runner.Runner) *Parallel {
+	p.runner = r
+	return p
+}
+
+// WithRunOptions sets the options the agents run with. Their input is replaced
+// by the input of the step.
+func (p *Parallel) WithRunOptions(opts *runner.RunOptions) *Parallel {
+	p.runOptions = opts
+	return p
+}
+
+// Run runs the agents and aggregates their outputs. Without a reducer, the
+// output is one section per run. If some runs fail, the result holds the
+// outputs of the others along with a *runner.ParallelError, and no reduction
+// takes place.
+func (p *Parallel) Run(ctx context.Context, input interface{}) (*ParallelResult, error) {
+	runs, labels, err := p.plan(input)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := p.runner.RunParallel(ctx, runs)
+	res := &ParallelResult{Outputs: make([]interface{}, len(results)), RunResults: results}
+	for i, r := range results {
+		if r != nil {
+			res.Outputs[i] = r.FinalOutput
+		}
+	}
+	if err != nil {
+		return res, err
+	}
+
+	switch {
+	case p.reduce != nil:
+		res.Output, err = p.reduce(ctx, res.Outputs)
+		if err != nil {
+			return res, fmt.Errorf("reducing parallel outputs failed: %w", err)
+		}
+	case p.reducer != nil:
+		opts := p.options(sections(labels, res.Outputs))
+		res.ReducerResult, err = p.runner.Run(ctx, p.reducer, opts)
+		if err != nil {
+			return res, fmt.Errorf("reducer %s failed: %w", p.reducer.Name, err)
+		}
+		res.Output = res.ReducerResult.FinalOutput
+	default:
+		res.Output = sections(labels, res.Outputs)
+	}
+	return res, nil
+}
+
+// Step returns the parallel step as a StepFunc, for use in a Graph. Its output
+// is the aggregated output.
+func (p *Parallel) Step() StepFunc {
+	return func(ctx context.Context, state *State) (interface{}, error) {
+		res, err := p.Run(ctx, agentInput(state.Previous, state.joined))
+		if err != nil {
+			return nil, err
+		}
+		return res.Output, nil
+	}
+}
+
+// plan builds the runs and the labels of their outputs
+func (p *Parallel) plan(input interface{}) ([]runner.AgentRun, []string, error) {
+	if len(p.agents) == 0 {
+		return nil, nil, errors.New("parallel step has no agents")
+	}
+
+	inputs := make([]interface{}, len(p.agents))
+	for i := range inputs {
+		inputs[i] = input
+	}
+	agents := p.agents
+	if p.shard != nil {
+		shards, err := p.shard(input)
+		if err != nil {
+			return nil, nil, fmt.Errorf("sharding input failed: %w", err)
+		}
+		switch {
+		case len(p.agents) == 1:
+			agents = make([]*agent.Agent, len(shards))
+			for i := range agents {
+				agents[i] = p.agents[0]
+			}
+		case len(shards) != len(p.agents):
+			return nil, nil, fmt.Errorf("%w: %d shards for %d agents", ErrShardCount, len(shards), len(p.agents))
+		}
+		inputs = shards
+	}
+
+	runs := make([]runner.AgentRun, len(agents))
+	labels := make([]string, len(agents))
+	for i, a := range agents {
+		runs[i] = runner.AgentRun{Agent: a, Options: p.options(inputs[i])}
+		labels[i] = a.Name
+		if len(p.agents) < len(agents) {
+			labels[i] = fmt.Sprintf("%s (shard %d)", a.Name, i+1)
+		}
+	}
+	return runs, labels, nil
+}
+
+// options returns the run options for an input
+func (p *Parallel) options(input interface{}) *runner.RunOptions {
+	opts := &runner.RunOptions{}
+	if p.runOptions != nil {
+		*opts = *p.runOptions
+	}
+	opts.Input = input
+	return opts
+}
+
+// sections formats outputs as one section per run
+func sections(labels []string, outputs []interface{}) string {
+	parts := make([]string, len(outputs))
+	for i, output := range outputs {
+		parts[i] = fmt.Sprintf("## %s\n\n%s", labels[i], model.ToolResultText(output))
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,98 @@
+package workflow
+
+import (
+	"sync"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+)
+
+// Validator checks data at a workflow validation point
+type Validator func(data interface{}) (bool, error)
+
+// Registry maps the names used in workflow files to Go implementations
+type Registry struct {
+	tools         map[string]tool.Tool
+	validators    map[string]Validator
+	compensations map[string]runner.CompensationFunc
+	provider      model.Provider
+	stateStore    runner.WorkflowStateStore
+	mu            sync.RWMutex
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		tools:         make(map[string]tool.Tool),
+		validators:    make(map[string]Validator),
+		compensations: make(map[string]runner.CompensationFunc),
+	}
+}
+
+// WithTools registers tools under their names
+func (r *Registry) WithTools(tools ...tool.Tool) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range tools {
+		r.tools[t.GetName()] = t
+	}
+	return r
+}
+
+// WithValidator registers a validator under a name
+func (r *Registry) WithValidator(name string, validator Validator) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.validators[name] = validator
+	return r
+}
+
+// WithCompensation registers a compensation under a name, for phases that roll
+// back their side effects when the workflow fails
+func (r *Registry) WithCompensation(name string, compensation runner.CompensationFunc) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.compensations[name] = compensation
+	return r
+}
+
+// WithProvider sets the model provider used to resolve the model names of the workflow
+func (r *Registry) WithProvider(provider model.Provider) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.provider = provider
+	return r
+}
+
+// WithStateStore sets the store used by workflows that persist their state
+func (r *Registry) WithStateStore(store runner.WorkflowStateStore) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stateStore = store
+	return r
+}
+
+// Tool returns the tool registered under a name
+func (r *Registry) Tool(name string) (tool.Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.tools[name]
+	return t, ok
+}
+
+// Validator returns the validator registered under a name
+func (r *Registry) Validator(name string) (Validator, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	v, ok := r.validators[name]
+	return v, ok
+}
+
+// Compensation returns the compensation registered under a name
+func (r *Registry) Compensation(name string) (runner.CompensationFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.compensations[name]
+	return c, ok
+}
@@ -0,0 +1,266 @@
+package workflow
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+)
+
+// DefaultWatchInterval is how often a Watcher checks its source for changes by default
+const DefaultWatchInterval = 5 * time.Second
+
+// Source provides the content of a workflow definition
+type Source interface {
+	// Read returns the current workflow document
+	Read(ctx context.Context) ([]byte, error)
+}
+
+// FileSource reads a workflow definition from a file
+type FileSource string
+
+// Read returns the content of the file
+func (f FileSource) Read(ctx context.Context) ([]byte, error) {
+	data, err := os.ReadFile(string(f))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflow file: %w", err)
+	}
+	return data, nil
+}
+
+// URLSource fetches a workflow definition over HTTP, using ETags to avoid
+// downloading an unchanged document
+type URLSource struct {
+	url        string
+	httpClient *http.Client
+	header     http.Header
+
+	etag string
+	body []byte
+	mu   sync.Mutex
+}
+
+// NewURLSource creates a source for a remote workflow definition
+func NewURLSource(url string) *URLSource {
+	return &URLSource{
+		url:        url,
+		httpClient: http.DefaultClient,
+		header:     make(http.Header),
+	}
+}
+
+// WithHTTPClient sets the HTTP client
+func (s *URLSource) WithHTTPClient(client *http.Client) *URLSource {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.httpClient = client
+	return s
+}
+
+// WithHeader adds a header to every request, such as an authorization token
+func (s *URLSource) WithHeader(key, value string) *URLSource {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.header.Add(key, value)
+	return s
+}
+
+// Read returns the remote document
+func (s *URLSource) Read(ctx context.Context) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range s.header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if s.etag != "" {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch workflow: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && s.body != nil {
+		return s.body, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch workflow: status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflow: %w", err)
+	}
+	s.etag = resp.Header.Get("ETag")
+	s.body = body
+	return body, nil
+}
+
+// Watcher keeps a workflow in sync with its definition. A changed definition is
+// validated and built before it replaces the current workflow, so a broken edit
+// never affects runs; runs already in progress finish with the workflow they
+// started with.
+type Watcher struct {
+	source   Source
+	registry *Registry
+	interval time.Duration
+	onReload func(*Workflow)
+	onError  func(error)
+
+	current atomic.Pointer[Workflow]
+	digest  [sha256.Size]byte
+	mu      sync.Mutex
+}
+
+// NewWatcher loads the workflow from the source. It fails if the initial
+// definition is invalid.
+func NewWatcher(ctx context.Context, source Source, registry *Registry) (*Watcher, error) {
+	w := &Watcher{
+		source:   source,
+		registry: registry,
+		interval: DefaultWatchInterval,
+	}
+	if _, err := w.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// WithInterval sets how often Start checks the source for changes
+func (w *Watcher) WithInterval(interval time.Duration) *Watcher {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.interval = interval
+	return w
+}
+
+// OnReload sets a function called with each newly loaded workflow
+func (w *Watcher) OnReload(fn func(*Workflow)) *Watcher {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onReload = fn
+	return w
+}
+
+// OnError sets a function called when a changed definition cannot be loaded
+func (w *Watcher) OnError(fn func(error)) *Watcher {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onError = fn
+	return w
+}
+
+// Current returns the current workflow
+func (w *Watcher) Current() *Workflow {
+	return w.current.Load()
+}
+
+// Run runs the current workflow
+func (w *Watcher) Run(ctx context.Context, input interface{}) (*result.RunResult, error) {
+	return w.Current().Run(ctx, input)
+}
+
+// Reload reads the source and swaps in the workflow if the definition changed.
+// It reports whether the workflow was replaced. An invalid definition leaves the
+// current workflow in place.
+func (w *Watcher) Reload(ctx context.Context) (bool, error) {
+	data, err := w.source.Read(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	w.mu.Lock()
+	digest := sha256.Sum256(data)
+	if w.current.Load() != nil && digest == w.digest {
+		w.mu.Unlock()
+		return false, nil
+	}
+
+	workflow, err := Parse(data, w.registry)
+	if err != nil {
+		w.mu.Unlock()
+		return false, fmt.Errorf("failed to reload workflow: %w", err)
+	}
+
+	w.current.Store(workflow)
+	w.digest = digest
+	onReload := w.onReload
+	w.mu.Unlock()
+
+	logging.For(nil, "workflow").Debug("Reloaded workflow", "workflow", workflow.Definition.Name)
+	if onReload != nil {
+		onReload(workflow)
+	}
+	return true, nil
+}
+
+// Start checks the source for changes at the watcher's interval until the
+// returned function is called or the context is cancelled
+func (w *Watcher) Start(ctx context.Context) (stop func()) {
+	w.mu.Lock()
+	interval := w.interval
+	w.mu.Unlock()
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if _, err := w.Reload(ctx); err != nil && ctx.Err() == nil {
+				w.reportError(err)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cancel()
+			<-done
+		})
+	}
+}
+
+// reportError passes a reload error to the error handler
+func (w *Watcher) reportError(err error) {
+	w.mu.Lock()
+	onError := w.onError
+	w.mu.Unlock()
+
+	if onError != nil {
+		onError(err)
+	} else {
+		logging.For(nil, "workflow").Warn("Failed to reload workflow", "error", err)
+	}
+}
@@ -0,0 +1,397 @@
+// Package workflow loads multi-agent workflows from YAML or JSON files.
+//
+// A workflow file declares the agents, the tools they use by name, the handoff
+// graph between them, the phases of the workflow and its validation and retry
+// policies:
+//
+//	name: code-review
+//	entry: planner
+//	max_turns: 20
+//	model: gpt-4o
+//	agents:
+//	  - name: planner
+//	    instructions: Break the request into tasks and hand them to the coder.
+//	    handoffs: [coder]
+//	  - name: coder
+//	    instructions: Implement the task you are given.
+//	    model: gpt-4o-mini
+//	    settings:
+//	      temperature: 0.2
+//	    tools: [read_file, write_file]
+//	    handoffs: [planner]
+//	phases:
+//	  - name: planning
+//	    agent: planner
+//	  - name: implementation
+//	    agent: coder
+//	    compensate: revert_changes
+//	retry:
+//	  max_retries: 3
+//	  delay: 2s
+//	  backoff_factor: 2
+//	validation:
+//	  phase_transition:
+//	    - rule: has_plan
+//	      message: the plan is missing
+//	  post_step:
+//	    - rule: has_code_block
+//	      message: include the code in a fenced code block
+//	  max_step_retries: 2
+//
+// Tool, validator and compensation names are resolved through a Registry.
+//
+// Where the order of the steps must not be left to the agents, a Graph runs
+// agents and Go functions along explicit, possibly conditional edges.
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+)
+
+// Definition is the content of a workflow file
+type Definition struct {
+	Name        string            `yaml:"name"`
+	Description string            `yaml:"description"`
+	Entry       string            `yaml:"entry"`
+	MaxTurns    int               `yaml:"max_turns"`
+	Model       string            `yaml:"model"`
+	Agents      []AgentDefinition `yaml:"agents"`
+	Phases      []PhaseDefinition `yaml:"phases"`
+	Retry       *RetryDefinition  `yaml:"retry"`
+	Validation  *ValidationDefs   `yaml:"validation"`
+	State       *StateDefinition  `yaml:"state"`
+}
+
+// AgentDefinition declares an agent
+type AgentDefinition struct {
+	Name         string              `yaml:"name"`
+	Description  string              `yaml:"description"`
+	Instructions string              `yaml:"instructions"`
+	Model        string              `yaml:"model"`
+	Settings     *SettingsDefinition `yaml:"settings"`
+	Tools        []string            `yaml:"tools"`
+	Handoffs     []string            `yaml:"handoffs"`
+}
+
+// SettingsDefinition declares model settings
+type SettingsDefinition struct {
+	Temperature       *float64 `yaml:"temperature"`
+	TopP              *float64 `yaml:"top_p"`
+	FrequencyPenalty  *float64 `yaml:"frequency_penalty"`
+	PresencePenalty   *float64 `yaml:"presence_penalty"`
+	ToolChoice        *string  `yaml:"tool_choice"`
+	ParallelToolCalls *bool    `yaml:"parallel_tool_calls"`
+	MaxTokens         *int     `yaml:"max_tokens"`
+}
+
+// PhaseDefinition declares a workflow phase
+type PhaseDefinition struct {
+	Name       string `yaml:"name"`
+	Agent      string `yaml:"agent"`
+	Compensate string `yaml:"compensate"`
+}
+
+// RetryDefinition declares the retry policy
+type RetryDefinition struct {
+	MaxRetries      int           `yaml:"max_retries"`
+	Delay           time.Duration `yaml:"delay"`
+	BackoffFactor   float64       `yaml:"backoff_factor"`
+	RetryableErrors []string      `yaml:"retryable_errors"`
+}
+
+// ValidationDefs declares the validation rules applied at each validation point
+type ValidationDefs struct {
+	PreHandoff      []RuleDefinition `yaml:"pre_handoff"`
+	PostHandoff     []RuleDefinition `yaml:"post_handoff"`
+	PhaseTransition []RuleDefinition `yaml:"phase_transition"`
+	PostStep        []RuleDefinition `yaml:"post_step"`
+	MaxStepRetries  int              `yaml:"max_step_retries"`
+}
+
+// RuleDefinition declares a validation rule backed by a registered validator
+type RuleDefinition struct {
+	Rule     string `yaml:"rule"`
+	Message  string `yaml:"message"`
+	Severity string `yaml:"severity"`
+}
+
+// StateDefinition declares how the workflow state is persisted
+type StateDefinition struct {
+	Persist             bool          `yaml:"persist"`
+	WorkflowID          string        `yaml:"workflow_id"`
+	CheckpointFrequency time.Duration `yaml:"checkpoint_frequency"`
+	RestoreOnFailure    bool          `yaml:"restore_on_failure"`
+
+	// Retention of checkpoints; none are pruned when nothing is set
+	MaxCheckpoints       int           `yaml:"max_checkpoints"`
+	CheckpointMaxAge     time.Duration `yaml:"checkpoint_max_age"`
+	KeepPhaseCheckpoints bool          `yaml:"keep_phase_checkpoints"`
+}
+
+// Workflow is a loaded workflow, ready to run
+type Workflow struct {
+	*runner.WorkflowRunner
+
+	// Definition is the parsed workflow file
+	Definition *Definition
+
+	// Agents are the agents of the workflow by name
+	Agents map[string]*agent.Agent
+
+	// Entry is the agent runs start with
+	Entry *agent.Agent
+
+	// Config is the workflow configuration built from the file
+	Config *runner.WorkflowConfig
+}
+
+// Load reads a workflow file. YAML and JSON files are both accepted.
+func Load(path string, registry *Registry) (*Workflow, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflow file: %w", err)
+	}
+
+	w, err := Parse(data, registry)
+	if err != nil {
+		return nil, fmt.Errorf("workflow %s: %w", path, err)
+	}
+	return w, nil
+}
+
+// Parse builds a workflow from a YAML or JSON document. Unknown fields, unknown
+// tools and validators and dangling agent references are reported together.
+func Parse(data []byte, registry *Registry) (*Workflow, error) {
+	if registry == nil {
+		registry = NewRegistry()
+	}
+
+	// YAML is a superset of JSON, so one decoder handles both formats
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var def Definition
+	if err := decoder.Decode(&def); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow: %w", err)
+	}
+
+	if err := def.validate(registry); err != nil {
+		return nil, err
+	}
+	return build(&def, registry)
+}
+
+// validate checks the references of the definition
+func (d *Definition) validate(registry *Registry) error {
+	var errs []error
+
+	if len(d.Agents) == 0 {
+		errs = append(errs, errors.New("no agents defined"))
+	}
+
+	names := make(map[string]bool, len(d.Agents))
+	for i, a := range d.Agents {
+		if a.Name == "" {
+			errs = append(errs, fmt.Errorf("agent %d has no name", i+1))
+			continue
+		}
+		if names[a.Name] {
+			errs = append(errs, fmt.Errorf("agent %q is defined more than once", a.Name))
+		}
+		names[a.Name] = true
+	}
+
+	if d.Entry != "" && !names[d.Entry] {
+		errs = append(errs, fmt.Errorf("entry agent %q is not defined", d.Entry))
+	}
+
+	for _, a := range d.Agents {
+		for _, t := range a.Tools {
+			if _, ok := registry.Tool(t); !ok {
+				errs = append(errs, fmt.Errorf("agent %q uses unregistered tool %q", a.Name, t))
+			}
+		}
+		for _, h := range a.Handoffs {
+			if !names[h] {
+				errs = append(errs, fmt.Errorf("agent %q hands off to undefined agent %q", a.Name, h))
+			}
+		}
+	}
+
+	for _, p := range d.Phases {
+		if p.Name == "" {
+			errs = append(errs, errors.New("phase has no name"))
+		}
+		if !names[p.Agent] {
+			errs = append(errs, fmt.Errorf("phase %q uses undefined agent %q", p.Name, p.Agent))
+		}
+		if p.Compensate != "" {
+			if _, ok := registry.Compensation(p.Compensate); !ok {
+				errs = append(errs, fmt.Errorf("phase %q uses unregistered compensation %q", p.Name, p.Compensate))
+			}
+		}
+	}
+
+	if d.Validation != nil {
+		for _, rules := range [][]RuleDefinition{d.Validation.PreHandoff, d.Validation.PostHandoff, d.Validation.PhaseTransition, d.Validation.PostStep} {
+			for _, rule := range rules {
+				if _, ok := registry.Validator(rule.Rule); !ok {
+					errs = append(errs, fmt.Errorf("validation rule %q is not registered", rule.Rule))
+				}
+				switch runner.ValidationSeverity(rule.Severity) {
+				case "", runner.ValidationError, runner.ValidationWarning:
+				default:
+					errs = append(errs, fmt.Errorf("validation rule %q has invalid severity %q", rule.Rule, rule.Severity))
+				}
+			}
+		}
+	}
+
+	if d.State != nil && d.State.Persist && registry.stateStore == nil {
+		errs = append(errs, errors.New("state persistence requires a state store in the registry"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// build creates the agents and runner of a validated definition
+func build(def *Definition, registry *Registry) (*Workflow, error) {
+	agents := make(map[string]*agent.Agent, len(def.Agents))
+	for _, a := range def.Agents {
+		ag := agent.NewAgent(a.Name, a.Instructions)
+		ag.Description = a.Description
+
+		if modelName := a.Model; modelName != "" {
+			ag.WithModel(modelName)
+		} else if def.Model != "" {
+			ag.WithModel(def.Model)
+		}
+		if a.Settings != nil {
+			ag.WithModelSettings(a.Settings.toModelSettings())
+		}
+		for _, name := range a.Tools {
+			t, _ := registry.Tool(name)
+			ag.WithTools(t)
+		}
+		agents[a.Name] = ag
+	}
+
+	// Wire the handoff graph once every agent exists, so cycles are allowed
+	for _, a := range def.Agents {
+		for _, h := range a.Handoffs {
+			agents[a.Name].WithHandoffs(agents[h])
+		}
+	}
+
+	entry := def.Entry
+	if entry == "" {
+		entry = def.Agents[0].Name
+	}
+
+	config := &runner.WorkflowConfig{}
+	for _, p := range def.Phases {
+		compensate, _ := registry.Compensation(p.Compensate)
+		config.Phases = append(config.Phases, runner.WorkflowPhase{Name: p.Name, Agent: p.Agent, Compensate: compensate})
+	}
+	if def.Retry != nil {
+		config.RetryConfig = &runner.RetryConfig{
+			MaxRetries:         def.Retry.MaxRetries,
+			RetryDelay:         def.Retry.Delay,
+			RetryBackoffFactor: def.Retry.BackoffFactor,
+			RetryableErrors:    def.Retry.RetryableErrors,
+		}
+	}
+	if def.Validation != nil {
+		config.ValidationConfig = &runner.ValidationConfig{
+			PreHandoffValidation:      rules(def.Validation.PreHandoff, registry),
+			PostHandoffValidation:     rules(def.Validation.PostHandoff, registry),
+			PhaseTransitionValidation: rules(def.Validation.PhaseTransition, registry),
+			PostStepValidation:        rules(def.Validation.PostStep, registry),
+			MaxStepRetries:            def.Validation.MaxStepRetries,
+		}
+	}
+	if def.State != nil {
+		config.StateManagement = &runner.StateManagementConfig{
+			PersistState:        def.State.Persist,
+			StateStore:          registry.stateStore,
+			WorkflowID:          def.State.WorkflowID,
+			CheckpointFrequency: def.State.CheckpointFrequency,
+			RestoreOnFailure:    def.State.RestoreOnFailure,
+		}
+		if def.State.MaxCheckpoints > 0 || def.State.CheckpointMaxAge > 0 {
+			config.StateManagement.Retention = &runner.CheckpointRetention{
+				MaxCheckpoints:  def.State.MaxCheckpoints,
+				MaxAge:          def.State.CheckpointMaxAge,
+				KeepPhaseStarts: def.State.KeepPhaseCheckpoints,
+			}
+		}
+	}
+
+	base := runner.NewRunner()
+	if registry.provider != nil {
+		base.WithDefaultProvider(registry.provider)
+	}
+	if def.MaxTurns > 0 {
+		base.WithDefaultMaxTurns(def.MaxTurns)
+	}
+
+	return &Workflow{
+		WorkflowRunner: runner.NewWorkflowRunner(base, config),
+		Definition:     def,
+		Agents:         agents,
+		Entry:          agents[entry],
+		Config:         config,
+	}, nil
+}
+
+// rules converts rule definitions to validation rules
+func rules(defs []RuleDefinition, registry *Registry) []runner.ValidationRule {
+	var out []runner.ValidationRule
+	for _, def := range defs {
+		validator, _ := registry.Validator(def.Rule)
+		severity := runner.ValidationSeverity(def.Severity)
+		if severity == "" {
+			severity = runner.ValidationError
+		}
+		out = append(out, runner.ValidationRule{
+			Name:         def.Rule,
+			Validate:     validator,
+			ErrorMessage: def.Message,
+			Severity:     severity,
+		})
+	}
+	return out
+}
+
+// toModelSettings converts the definition to model settings
+func (s *SettingsDefinition) toModelSettings() *model.Settings {
+	return &model.Settings{
+		Temperature:       s.Temperature,
+		TopP:              s.TopP,
+		FrequencyPenalty:  s.FrequencyPenalty,
+		PresencePenalty:   s.PresencePenalty,
+		ToolChoice:        s.ToolChoice,
+		ParallelToolCalls: s.ParallelToolCalls,
+		MaxTokens:         s.MaxTokens,
+	}
+}
+
+// Run runs the workflow from its entry agent
+func (w *Workflow) Run(ctx context.Context, input interface{}) (*result.RunResult, error) {
+	return w.RunWorkflow(ctx, w.Entry, &runner.RunOptions{
+		Input:          input,
+		WorkflowConfig: w.Config,
+	})
+}
@@ -0,0 +1,259 @@
+// Package workspace provisions an isolated temporary directory for each run.
+//
+// A Manager set as RunConfig.Workspace creates a directory for every run, passes
+// it to tools through the run context and removes it when the run ends, archiving
+// its contents to an artifact store first if one is configured. The filesystem
+// and command tools work inside it when created with fs.NewWorkspaceSandbox and
+// exec.NewWorkspaceExecutor; Docker isolated executors mount it as the
+// container's /workspace volume.
+//
+//	manager := workspace.NewManager("").WithQuota(100 << 20).WithArchive(store)
+//	coder := agent.NewAgent("Coder").WithTools(fs.NewWorkspaceSandbox().Tools()...)
+//	result, err := runner.NewRunner().Run(ctx, coder, &runner.RunOptions{
+//		Input:     "Scaffold a Go module",
+//		RunConfig: &runner.RunConfig{Workspace: manager},
+//	})
+package workspace
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/artifact"
+)
+
+var (
+	// ErrNoWorkspace is returned by workspace tools called outside of a run with a
+	// workspace
+	ErrNoWorkspace = errors.New("no workspace in context")
+
+	// ErrQuotaExceeded is returned when a workspace holds more than its quota
+	ErrQuotaExceeded = errors.New("workspace quota exceeded")
+)
+
+// Workspace is the directory of a run
+type Workspace struct {
+	runID string
+	path  string
+	quota int64
+}
+
+// RunID returns the ID of the run the workspace belongs to
+func (w *Workspace) RunID() string {
+	return w.runID
+}
+
+// Path returns the absolute path of the workspace directory
+func (w *Workspace) Path() string {
+	return w.path
+}
+
+// Quota returns the most bytes the workspace may hold, or 0 if it is unlimited
+func (w *Workspace) Quota() int64 {
+	return w.quota
+}
+
+// Usage returns the number of bytes of the files in the workspace
+func (w *Workspace) Usage() (int64, error) {
+	var total int64
+	err := filepath.WalkDir(w.path, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			total += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure workspace: %w", err)
+	}
+	return total, nil
+}
+
+// CheckQuota returns ErrQuotaExceeded if the workspace would hold more than its
+// quota after adding the given number of bytes
+func (w *Workspace) CheckQuota(additional int64) error {
+	if w.quota <= 0 {
+		return nil
+	}
+	used, err := w.Usage()
+	if err != nil {
+		return err
+	}
+	if used+additional > w.quota {
+		return fmt.Errorf("%w: %d of %d bytes used", ErrQuotaExceeded, used+additional, w.quota)
+	}
+	return nil
+}
+
+type contextKey struct{}
+
+// WithWorkspace returns a context carrying a workspace
+func WithWorkspace(ctx context.Context, w *Workspace) context.Context {
+	return context.WithValue(ctx, contextKey{}, w)
+}
+
+// FromContext returns the workspace of the run a context belongs to, or nil
+func FromContext(ctx context.Context) *Workspace {
+	w, _ := ctx.Value(contextKey{}).(*Workspace)
+	return w
+}
+
+// Manager provisions and cleans up the workspaces of runs
+type Manager struct {
+	baseDir string
+	quota   int64
+	archive artifact.Store
+	mu      sync.RWMutex
+}
+
+// NewManager creates a manager placing workspaces in baseDir, or in the system's
+// temporary directory when baseDir is empty
+func NewManager(baseDir string) *Manager {
+	return &Manager{baseDir: baseDir}
+}
+
+// WithQuota limits the bytes each workspace may hold. Writes through the
+// workspace tools that would exceed it fail, and a run whose workspace exceeds it
+// fails before its next turn.
+func (m *Manager) WithQuota(bytes int64) *Manager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quota = bytes
+	return m
+}
+
+// WithArchive stores the contents of each workspace as a gzipped tar artifact
+// before it is removed
+func (m *Manager) WithArchive(store artifact.Store) *Manager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.archive = store
+	return m
+}
+
+// Acquire returns the workspace of a run, creating its directory. A run resumed
+// with the same ID gets the workspace it was paused with.
+func (m *Manager) Acquire(runID string) (*Workspace, error) {
+	if runID == "" || strings.ContainsAny(runID, `/\`) || runID == "." || runID == ".." {
+		return nil, fmt.Errorf("invalid run ID %q", runID)
+	}
+
+	m.mu.RLock()
+	baseDir, quota := m.baseDir, m.quota
+	m.mu.RUnlock()
+	if baseDir == "" {
+		baseDir = filepath.Join(os.TempDir(), "agent-workspaces")
+	}
+
+	path, err := filepath.Abs(filepath.Join(baseDir, runID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve workspace: %w", err)
+	}
+	if err := os.MkdirAll(path, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	if path, err = filepath.EvalSymlinks(path); err != nil {
+		return nil, fmt.Errorf("failed to resolve workspace: %w", err)
+	}
+	return &Workspace{runID: runID, path: path, quota: quota}, nil
+}
+
+// Release removes a workspace, archiving it first if the manager has an archive
+// store. It returns the archive, if one was stored; a workspace that cannot be
+// archived is kept on disk.
+func (m *Manager) Release(ctx context.Context, w *Workspace) (*artifact.Artifact, error) {
+	m.mu.RLock()
+	store := m.archive
+	m.mu.RUnlock()
+
+	var archived *artifact.Artifact
+	if store != nil {
+		data, err := archive(w.path)
+		if err != nil {
+			return nil, err
+		}
+		stored, err := store.Put(ctx, artifact.Artifact{
+			Name:      w.runID + ".tar.gz",
+			MediaType: "application/gzip",
+			Metadata:  map[string]string{"run_id": w.runID},
+		}, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to archive workspace: %w", err)
+		}
+		archived = &stored
+	}
+
+	if err := os.RemoveAll(w.path); err != nil {
+		return archived, fmt.Errorf("failed to remove workspace: %w", err)
+	}
+	return archived, nil
+}
+
+// archive returns the contents of a directory as a gzipped tar file
+func archive(dir string) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || path == dir {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		// Symbolic links and other special files are not archived
+		if !info.Mode().IsRegular() && !info.IsDir() {
+			return nil
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err == nil {
+		err = tw.Close()
+	}
+	if err == nil {
+		err = gz.Close()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive workspace: %w", err)
+	}
+	return buf.Bytes(), nil
+}
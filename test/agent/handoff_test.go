@@ -0,0 +1,63 @@
+package agent_test
+
+import (
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/handoff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// handoffFunctions returns the function definitions of an agent's handoff tools
+func handoffFunctions(a *agent.Agent) []map[string]interface{} {
+	var functions []map[string]interface{}
+	for _, schema := range a.ToolSchemas().Handoffs {
+		functions = append(functions, schema["function"].(map[string]interface{}))
+	}
+	return functions
+}
+
+func TestHandoffToolNamesAreSanitized(t *testing.T) {
+	designer := agent.NewAgent("Design Agent")
+	a := agent.NewAgent("Manager").WithHandoffs(designer).
+		WithBroadcastHandoffs(agent.NewBroadcastHandoff("Review Board", designer))
+
+	functions := handoffFunctions(a)
+	require.Len(t, functions, 2)
+	assert.Equal(t, "handoff_to_Design_Agent", functions[0]["name"])
+	assert.Equal(t, "handoff_to_Review_Board", functions[1]["name"])
+
+	target, ok := a.HandoffTarget("Design_Agent")
+	require.True(t, ok, "names recovered from tool names map back to the agent")
+	assert.Same(t, designer, target)
+
+	assert.Equal(t, "handoff_to_caf____menu", agent.HandoffToolName("café / menu"))
+	assert.Len(t, agent.SanitizeToolName(string(make([]byte, 100))), 64)
+}
+
+func TestDeclaredHandoffs(t *testing.T) {
+	reviewer := agent.NewAgent("Security Reviewer")
+	a := agent.New("Manager", agent.WithHandoff(
+		handoff.To(reviewer).WithToolName("transfer_to_reviewer").WithDescription("Hand off finished code for a security review."),
+	))
+	a.WithHandoff(handoff.To(reviewer).WithToolName("transfer_to_reviewer"))
+
+	require.Len(t, a.Handoffs, 1, "declaring a handoff again does not add its target twice")
+	functions := handoffFunctions(a)
+	require.Len(t, functions, 1)
+	assert.Equal(t, "transfer_to_reviewer", functions[0]["name"])
+	assert.Contains(t, functions[0]["description"], "Use this when a query requires expertise")
+
+	a.WithHandoff(handoff.To(reviewer).WithToolName("transfer_to_reviewer").WithDescription("Hand off finished code for a security review."))
+	assert.Equal(t, "Hand off finished code for a security review.", handoffFunctions(a)[0]["description"])
+
+	target, ok := a.HandoffToolTarget("transfer_to_reviewer")
+	require.True(t, ok)
+	assert.Same(t, reviewer, target)
+	_, ok = a.HandoffToolTarget("handoff_to_Security_Reviewer")
+	assert.False(t, ok, "a declared tool name replaces the default one")
+
+	clone := a.CloneWith()
+	assert.Equal(t, "transfer_to_reviewer", clone.HandoffFor(reviewer).Name())
+}
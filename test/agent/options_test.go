@@ -0,0 +1,126 @@
+package agent_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func noopTool(name string) tool.Tool {
+	return tool.NewFunctionTool(name, "Does nothing", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return "ok", nil
+	})
+}
+
+func TestNewAppliesOptions(t *testing.T) {
+	temperature := 0.2
+	writer := agent.New("Writer")
+	a := agent.New("Assistant",
+		agent.WithInstructions("Be brief."),
+		agent.WithDescription("Answers questions"),
+		agent.WithModel("gpt-4o"),
+		agent.WithModelSettings(&model.Settings{Temperature: &temperature}),
+		agent.WithTools(noopTool("search")),
+		agent.WithHandoffs(writer),
+	)
+
+	assert.Equal(t, "Assistant", a.Name)
+	assert.Equal(t, "Be brief.", a.Instructions)
+	assert.Equal(t, "Answers questions", a.Description)
+	assert.Equal(t, "gpt-4o", a.Model)
+	assert.Equal(t, 0.2, *a.ModelSettings.Temperature)
+	require.Len(t, a.Tools, 1)
+	assert.Equal(t, "search", a.Tools[0].GetName())
+	assert.Equal(t, []*agent.Agent{writer}, a.Handoffs)
+}
+
+func TestCloneIsIndependent(t *testing.T) {
+	settings := &model.Settings{}
+	original := agent.New("Assistant", agent.WithTools(noopTool("search")), agent.WithModelSettings(settings))
+
+	clone := original.CloneWith(agent.WithName("Researcher"), agent.WithTools(noopTool("fetch")))
+	clone.WithTools(noopTool("summarize"))
+	clone.ModelSettings.PromptCaching = true
+
+	assert.Equal(t, "Assistant", original.Name)
+	assert.Equal(t, "Researcher", clone.Name)
+	assert.Len(t, original.Tools, 1)
+	assert.Len(t, clone.Tools, 3)
+	assert.False(t, original.ModelSettings.PromptCaching)
+	assert.NotSame(t, original.ModelSettings, clone.ModelSettings)
+}
+
+func TestCloneCopiesModelSettingsDeeply(t *testing.T) {
+	temperature, maxTokens := 0.2, 100
+	original := agent.New("Assistant", agent.WithModelSettings(&model.Settings{
+		Temperature: &temperature,
+		MaxTokens:   &maxTokens,
+		ResponseFormat: &model.ResponseFormat{
+			Type:   model.ResponseFormatJSONSchema,
+			Schema: map[string]interface{}{"properties": map[string]interface{}{"answer": map[string]interface{}{"type": "string"}}},
+		},
+	}))
+
+	clone := original.CloneWith()
+	*clone.ModelSettings.Temperature = 0.9
+	*clone.ModelSettings.MaxTokens = 10
+	clone.ModelSettings.ResponseFormat.Schema["properties"].(map[string]interface{})["answer"] = map[string]interface{}{"type": "number"}
+
+	assert.Equal(t, 0.2, *original.ModelSettings.Temperature)
+	assert.Equal(t, 100, *original.ModelSettings.MaxTokens)
+	properties := original.ModelSettings.ResponseFormat.Schema["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "string"}, properties["answer"])
+}
+
+func TestCloneWithOverrides(t *testing.T) {
+	settings := &model.Settings{PromptCaching: true}
+	original := agent.New("Assistant", agent.WithInstructions("Help."), agent.WithTools(noopTool("search")))
+
+	clone := original.Clone(map[string]interface{}{
+		"Name":          "Researcher",
+		"Description":   "Finds sources",
+		"ModelSettings": settings,
+	})
+	clone.WithTools(noopTool("fetch"))
+
+	assert.Equal(t, "Researcher", clone.Name)
+	assert.Equal(t, "Help.", clone.Instructions)
+	assert.Equal(t, "Finds sources", clone.Description)
+	assert.Same(t, settings, clone.ModelSettings)
+	assert.Equal(t, "Assistant", original.Name)
+	assert.Len(t, original.Tools, 1)
+	assert.Nil(t, original.Clone(nil).ModelSettings)
+}
+
+func TestCloneIsSafeWithConcurrentRuns(t *testing.T) {
+	a := agent.New("Assistant", agent.WithTools(noopTool("search")))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			variant := a.CloneWith(
+				agent.WithInstructions(fmt.Sprintf("Variant %d", i)),
+				agent.WithModel(mocks.NewScriptedModel(&model.Response{Content: fmt.Sprintf("done %d", i)})),
+			)
+			res, err := runner.NewRunner().Run(context.Background(), variant, &runner.RunOptions{
+				Input:     "go",
+				RunConfig: &runner.RunConfig{ModelProvider: &mocks.MockModelProvider{}, TracingDisabled: true},
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("done %d", i), res.FinalOutput)
+		}(i)
+	}
+	wg.Wait()
+	assert.Empty(t, a.Instructions)
+}
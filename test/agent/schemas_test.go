@@ -0,0 +1,65 @@
+package agent_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingTool counts how often its schema is built
+type countingTool struct {
+	tool.Tool
+	schemaCalls int
+}
+
+func (t *countingTool) GetParametersSchema() map[string]interface{} {
+	t.schemaCalls++
+	return t.Tool.GetParametersSchema()
+}
+
+func newCountingTool(name string) *countingTool {
+	return &countingTool{Tool: tool.NewFunctionTool(name, "Does "+name, func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return nil, nil
+	})}
+}
+
+func TestToolSchemasAreCached(t *testing.T) {
+	lookup := newCountingTool("lookup")
+	a := agent.NewAgent("Assistant").WithTools(lookup).WithHandoffs(agent.NewAgent("Expert"))
+
+	first := a.ToolSchemas()
+	second := a.ToolSchemas()
+	assert.Same(t, first, second)
+	assert.Equal(t, 1, lookup.schemaCalls)
+
+	require.Len(t, first.Tools, 1)
+	assert.Equal(t, "lookup", first.Tools[0]["function"].(map[string]interface{})["name"])
+	require.Len(t, first.Handoffs, 1)
+	assert.Equal(t, "handoff_to_Expert", first.Handoffs[0]["function"].(map[string]interface{})["name"])
+}
+
+func TestToolSchemasAreRebuiltWhenToolsChange(t *testing.T) {
+	a := agent.NewAgent("Assistant").WithTools(newCountingTool("lookup"))
+	first := a.ToolSchemas()
+
+	a.WithTools(newCountingTool("search"))
+	second := a.ToolSchemas()
+	assert.NotSame(t, first, second)
+	assert.Len(t, second.Tools, 2)
+
+	// Assigning the field directly is noticed as well
+	a.Tools = []tool.Tool{newCountingTool("other")}
+	third := a.ToolSchemas()
+	require.Len(t, third.Tools, 1)
+	assert.Equal(t, "other", third.Tools[0]["function"].(map[string]interface{})["name"])
+
+	a.WithBroadcastHandoffs(agent.NewBroadcastHandoff("panel", agent.NewAgent("A"), agent.NewAgent("B")))
+	assert.Len(t, a.ToolSchemas().Handoffs, 1)
+
+	a.InvalidateToolSchemas()
+	assert.NotSame(t, third, a.ToolSchemas())
+}
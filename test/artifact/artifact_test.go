@@ -0,0 +1,50 @@
+package artifact_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/artifact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStores(t *testing.T) {
+	fileStore, err := artifact.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	stores := map[string]artifact.Store{
+		"memory": artifact.NewMemoryStore(),
+		"file":   fileStore,
+	}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			stored, err := store.Put(ctx, artifact.Artifact{Name: "logo.png", MediaType: "image/png"}, []byte("png-bytes"))
+			require.NoError(t, err)
+			assert.NotEmpty(t, stored.ID)
+			assert.Equal(t, 9, stored.Size)
+			assert.False(t, stored.CreatedAt.IsZero())
+
+			got, data, err := store.Get(ctx, stored.ID)
+			require.NoError(t, err)
+			assert.Equal(t, []byte("png-bytes"), data)
+			assert.Equal(t, stored.Name, got.Name)
+			assert.Equal(t, stored.MediaType, got.MediaType)
+
+			require.NoError(t, store.Delete(ctx, stored.ID))
+			require.NoError(t, store.Delete(ctx, stored.ID))
+			_, _, err = store.Get(ctx, stored.ID)
+			assert.True(t, errors.Is(err, artifact.ErrNotFound))
+		})
+	}
+}
+
+func TestFileStoreRejectsPathIDs(t *testing.T) {
+	store, err := artifact.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	_, err = store.Put(context.Background(), artifact.Artifact{ID: "../escape"}, []byte("x"))
+	assert.Error(t, err)
+}
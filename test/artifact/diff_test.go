@@ -0,0 +1,48 @@
+package artifact_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/artifact"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	oldText := "package main\n\nfunc add(a, b int) int {\n\treturn a - b\n}\n"
+	newText := "package main\n\n// add adds two numbers\nfunc add(a, b int) int {\n\treturn a + b\n}\n"
+
+	assert.Equal(t, `--- v1
++++ v2
+@@ -1,5 +1,6 @@
+ package main
+ 
++// add adds two numbers
+ func add(a, b int) int {
+-	return a - b
++	return a + b
+ }
+`, artifact.UnifiedDiff("v1", "v2", oldText, newText))
+}
+
+func TestUnifiedDiffSeparatesDistantChanges(t *testing.T) {
+	var oldLines, newLines []string
+	for i := 0; i < 20; i++ {
+		line := strings.Repeat("x", i+1)
+		oldLines = append(oldLines, line)
+		if i == 1 || i == 18 {
+			line += " changed"
+		}
+		newLines = append(newLines, line)
+	}
+
+	diff := artifact.UnifiedDiff("old", "new", strings.Join(oldLines, "\n"), strings.Join(newLines, "\n"))
+	assert.Equal(t, 2, strings.Count(diff, "@@ -"))
+	assert.Contains(t, diff, "@@ -1,5 +1,5 @@")
+	assert.Contains(t, diff, "@@ -16,5 +16,5 @@")
+}
+
+func TestUnifiedDiffOfEqualTexts(t *testing.T) {
+	assert.Empty(t, artifact.UnifiedDiff("a", "b", "same\n", "same\n"))
+	assert.Equal(t, "--- a\n+++ b\n@@ -0,0 +1 @@\n+new\n", artifact.UnifiedDiff("a", "b", "", "new"))
+}
@@ -0,0 +1,118 @@
+package chaos_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/chaos"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/openai"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInjectorIsReproducibleWithSeed(t *testing.T) {
+	draw := func() []bool {
+		injector := chaos.NewInjector(7).WithFault(chaos.FaultToolError, 0.3)
+		echo := tool.Wrap(tool.NewFunctionTool("echo", "Echoes", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+			return "ok", nil
+		}), chaos.ToolFaults(injector))
+
+		var failures []bool
+		for i := 0; i < 50; i++ {
+			_, err := echo.Execute(context.Background(), nil)
+			failures = append(failures, err != nil)
+		}
+		return failures
+	}
+
+	first := draw()
+	assert.Equal(t, first, draw())
+	assert.Contains(t, first, true)
+	assert.Contains(t, first, false)
+}
+
+func TestProviderInjectsRateLimits(t *testing.T) {
+	provider := &mocks.MockModelProvider{}
+	provider.On("GetModel", "test").Return(mocks.NewScriptedModel(&model.Response{Content: "hi"}), nil)
+
+	injector := chaos.NewInjector(1).WithFault(chaos.FaultRateLimit, 1)
+	m, err := chaos.WrapProvider(provider, injector).GetModel("test")
+	require.NoError(t, err)
+
+	_, err = m.GetResponse(context.Background(), &model.Request{Input: "hi"})
+	var fault *chaos.FaultError
+	require.True(t, errors.As(err, &fault))
+	assert.Equal(t, http.StatusTooManyRequests, fault.StatusCode)
+	assert.Equal(t, 1, injector.Injected()[chaos.FaultRateLimit])
+}
+
+func TestTruncatedStreamEndsWithError(t *testing.T) {
+	injector := chaos.NewInjector(1).WithFault(chaos.FaultTruncatedStream, 1)
+	m := chaos.WrapModel(mocks.NewScriptedModel(&model.Response{Content: "complete answer"}), injector)
+
+	events, err := m.StreamResponse(context.Background(), &model.Request{Input: "hi"})
+	require.NoError(t, err)
+
+	var types []string
+	for event := range events {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{model.StreamEventTypeContent, model.StreamEventTypeError}, types)
+}
+
+func TestTimeoutFaultRespectsContext(t *testing.T) {
+	injector := chaos.NewInjector(1).WithFault(chaos.FaultTimeout, 1)
+	m := chaos.WrapModel(mocks.NewScriptedModel(&model.Response{Content: "late"}), injector)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := m.GetResponse(ctx, &model.Request{Input: "hi"})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestTransportExercisesProviderRetries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"recovered"},"finish_reason":"stop"}],"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`))
+	}))
+	defer server.Close()
+
+	injector := chaos.NewInjector(11).WithFault(chaos.FaultRateLimit, 0.5)
+	provider := openai.NewProvider("test-key").
+		WithHTTPClient(&http.Client{Transport: chaos.NewTransport(nil, injector)}).
+		WithRetryConfig(10, time.Millisecond)
+	provider.SetBaseURL(server.URL)
+
+	m, err := provider.GetModel("gpt-4o")
+	require.NoError(t, err)
+
+	for i := 0; i < 5; i++ {
+		response, err := m.GetResponse(context.Background(), &model.Request{Input: "hi"})
+		require.NoError(t, err)
+		assert.Equal(t, "recovered", response.Content)
+	}
+	assert.Positive(t, injector.Injected()[chaos.FaultRateLimit])
+}
+
+func TestToolFaultsWithRetryMiddleware(t *testing.T) {
+	injector := chaos.NewInjector(5).WithFault(chaos.FaultToolError, 0.5)
+	lookup := tool.Wrap(tool.NewFunctionTool("lookup", "Looks up", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return "found", nil
+	}), tool.WithRetry(10, 0), chaos.ToolFaults(injector))
+
+	for i := 0; i < 10; i++ {
+		out, err := lookup.Execute(context.Background(), nil)
+		require.NoError(t, err)
+		assert.Equal(t, "found", out)
+	}
+	assert.Positive(t, injector.Injected()[chaos.FaultToolError])
+}
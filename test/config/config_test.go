@@ -0,0 +1,141 @@
+package config_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/config"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/guardrail"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/prompt"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const supportConfig = `
+version: 1
+provider: scripted
+model: main
+entry: triage
+agents:
+  - name: triage
+    instructions: Route the request.
+    handoffs: [billing]
+  - name: billing
+    prompt:
+      template: billing
+      variables: {company: Acme}
+    model: mini
+    settings:
+      temperature: 0.2
+    tools: [lookup_invoice]
+    output_guardrails: [short]
+    handoffs: [triage]
+runner:
+  max_turns: 7
+  max_total_tokens: 5000
+  require_approval: [lookup_invoice]
+  tracing_disabled: true
+`
+
+func newRegistry(t *testing.T, provider model.Provider) *config.Registry {
+	prompts := prompt.NewLibrary()
+	_, err := prompts.Add("billing", "You handle billing for {{.company}}.", prompt.Variable{Name: "company", Required: true})
+	require.NoError(t, err)
+
+	lookup := tool.NewFunctionTool("lookup_invoice", "Look up an invoice", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return "paid", nil
+	})
+	short := guardrail.NewOutputGuardrail("short", func(ctx context.Context, output interface{}) (*guardrail.Result, error) {
+		return &guardrail.Result{}, nil
+	})
+	return config.NewRegistry().
+		WithTools(lookup).
+		WithOutputGuardrails(short).
+		WithProvider("scripted", provider).
+		WithPrompts(prompts)
+}
+
+func TestLoadBuildsAgentsAndRunOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agents.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(supportConfig), 0o600))
+
+	c, err := config.Load(path, newRegistry(t, &mocks.MockModelProvider{}))
+	require.NoError(t, err)
+
+	triage, billing := c.Agents["triage"], c.Agents["billing"]
+	require.NotNil(t, triage)
+	require.NotNil(t, billing)
+	assert.Same(t, triage, c.Entry)
+	assert.Equal(t, "main", triage.Model)
+	assert.Equal(t, "mini", billing.Model)
+	assert.Equal(t, 0.2, *billing.ModelSettings.Temperature)
+	assert.Equal(t, "lookup_invoice", billing.Tools[0].GetName())
+	assert.Equal(t, "short", billing.OutputGuardrails[0].Name())
+	assert.Same(t, billing, triage.Handoffs[0])
+	assert.Same(t, triage, billing.Handoffs[0])
+	assert.Equal(t, "You handle billing for Acme.", billing.ResolveInstructions(context.Background(), agent.RunContext{Turn: 1}))
+
+	opts := c.RunOptions("where is my invoice?")
+	assert.Equal(t, 7, opts.MaxTurns)
+	assert.Equal(t, 5000, opts.RunConfig.MaxTotalTokens)
+	assert.True(t, opts.RunConfig.TracingDisabled)
+	assert.NotNil(t, opts.RunConfig.ApprovalPolicy)
+	assert.NotNil(t, opts.RunConfig.ModelProvider)
+}
+
+func TestRunUsesRegistryProvider(t *testing.T) {
+	scripted := mocks.NewScriptedModel(&model.Response{Content: "hello from config"})
+	provider := &mocks.MockModelProvider{}
+	provider.On("GetModel", "main").Return(scripted, nil)
+
+	c, err := config.Parse([]byte(`{"provider": "scripted", "model": "main", "agents": [{"name": "solo", "instructions": "Greet."}], "runner": {"tracing_disabled": true}}`), config.NewRegistry().WithProvider("scripted", provider))
+	require.NoError(t, err)
+
+	res, err := c.Run(context.Background(), "hi")
+	require.NoError(t, err)
+	assert.Equal(t, "hello from config", res.FinalOutput)
+}
+
+func TestInvalidDocumentsReportEveryProblem(t *testing.T) {
+	_, err := config.Parse([]byte(`
+agents:
+  - name: a
+    tools: [missing_tool]
+    handoffs: [ghost]
+    settings: {temperature: 5}
+    prompt: {template: nope}
+runner:
+  max_turns: "many"
+`), newRegistry(t, &mocks.MockModelProvider{}))
+	require.ErrorIs(t, err, config.ErrInvalidConfig)
+	assert.Contains(t, err.Error(), "config.runner.max_turns must be an integer")
+
+	_, err = config.Parse([]byte(`
+agents:
+  - name: a
+    tools: [missing_tool]
+    handoffs: [ghost]
+    settings: {temperature: 5}
+    prompt: {template: nope}
+`), newRegistry(t, &mocks.MockModelProvider{}))
+	require.ErrorIs(t, err, config.ErrInvalidConfig)
+	for _, problem := range []string{`unregistered tool "missing_tool"`, `undefined agent "ghost"`, "temperature 5", "nope"} {
+		assert.Contains(t, err.Error(), problem)
+	}
+
+	_, err = config.Parse([]byte("agents:\n  - name: a\n    instuctions: typo\n"), nil)
+	require.ErrorIs(t, err, config.ErrInvalidConfig)
+	assert.Contains(t, err.Error(), "instuctions")
+}
+
+func TestSchemaDescribesDocument(t *testing.T) {
+	schema := config.Schema()
+	assert.Equal(t, "object", schema["type"])
+	assert.Contains(t, schema["properties"], "agents")
+}
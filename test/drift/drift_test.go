@@ -0,0 +1,120 @@
+package drift_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/drift"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/guardrail"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// vectorEmbedder embeds known texts as fixed vectors
+type vectorEmbedder map[string][]float32
+
+func (e vectorEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector, ok := e[text]
+		if !ok {
+			return nil, errors.New("unknown text")
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+var embedder = vectorEmbedder{
+	"Paris":         {1, 0, 0},
+	"Paris, France": {0.95, 0.05, 0},
+	"I cannot help": {0, 0, 1},
+	"Lyon":          {0.6, 0.8, 0},
+}
+
+func TestObserveRecordsBaselineThenCompares(t *testing.T) {
+	ctx := context.Background()
+	var drifted []*drift.Measurement
+	monitor := drift.NewMonitor(embedder).WithOnDrift(func(ctx context.Context, m *drift.Measurement) {
+		drifted = append(drifted, m)
+	})
+
+	first, err := monitor.Observe(ctx, "geo", "Capital of France?", "Paris")
+	require.NoError(t, err)
+	assert.Nil(t, first.Baseline)
+	assert.False(t, first.Drifted)
+
+	// The same input with different case and spacing is the same key
+	similar, err := monitor.Observe(ctx, "geo", "  capital of   france? ", "Paris, France")
+	require.NoError(t, err)
+	require.NotNil(t, similar.Baseline)
+	assert.Equal(t, first.Key, similar.Key)
+	assert.Greater(t, similar.Similarity, 0.99)
+	assert.False(t, similar.Drifted)
+
+	changed, err := monitor.Observe(ctx, "geo", "Capital of France?", "I cannot help")
+	require.NoError(t, err)
+	assert.InDelta(t, 0, changed.Similarity, 1e-6)
+	assert.InDelta(t, 0, changed.ChangeSimilarity, 1e-6)
+	assert.True(t, changed.Drifted)
+	require.Len(t, drifted, 1)
+	assert.Equal(t, "I cannot help", drifted[0].Output)
+
+	metrics := monitor.Metrics()
+	require.Len(t, metrics, 1)
+	assert.Equal(t, 1, metrics[0].Baselines)
+	assert.Equal(t, 2, metrics[0].Comparisons)
+	assert.Equal(t, 1, metrics[0].Drifted)
+
+	// After a reset the next output becomes the baseline
+	require.NoError(t, monitor.ResetBaseline(ctx, first.Key))
+	rebased, err := monitor.Observe(ctx, "geo", "Capital of France?", "I cannot help")
+	require.NoError(t, err)
+	assert.Nil(t, rebased.Baseline)
+}
+
+func TestMonitorAsRunHooks(t *testing.T) {
+	monitor := drift.NewMonitor(embedder).WithThreshold(0.9).WithBlocking(true)
+	r := runner.NewRunner().WithHooks(monitor)
+	config := &runner.RunConfig{ModelProvider: &mocks.MockModelProvider{}, TracingDisabled: true}
+
+	run := func(output string) error {
+		m := mocks.NewScriptedModel(&model.Response{Content: output})
+		_, err := r.Run(context.Background(), agent.NewAgent("geo").WithModel(m), &runner.RunOptions{Input: "Second city of France?", RunConfig: config})
+		return err
+	}
+
+	require.NoError(t, run("Paris"))
+	require.NoError(t, run("Paris, France"))
+
+	err := run("Lyon")
+	var tripped *guardrail.GuardrailTripped
+	require.ErrorAs(t, err, &tripped)
+	assert.Equal(t, drift.GuardrailName, tripped.Guardrail)
+
+	// Embedding failures are counted but do not fail the run
+	require.NoError(t, run("unknown output"))
+	assert.Equal(t, 1, monitor.Metrics()[0].Errors)
+}
+
+func TestMetricsHandler(t *testing.T) {
+	monitor := drift.NewMonitor(embedder)
+	ctx := context.Background()
+	_, err := monitor.Observe(ctx, "geo", "q", "Paris")
+	require.NoError(t, err)
+	_, err = monitor.Observe(ctx, "geo", "q", "Lyon")
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	monitor.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	assert.Contains(t, string(body), `agent_drift_drifted_total{agent="geo"} 1`)
+	assert.Contains(t, string(body), `agent_drift_similarity_last{agent="geo"} 0.6`)
+}
@@ -0,0 +1,155 @@
+package eval_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/eval"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/ratelimit"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// answerModel answers arithmetic questions, correctly only when it is the
+// "smart" model at temperature 0 with the "precise" prompt
+type answerModel struct {
+	name     string
+	active   int32
+	peak     int32
+	failures int32
+}
+
+func (m *answerModel) GetResponse(ctx context.Context, request *model.Request) (*model.Response, error) {
+	active := atomic.AddInt32(&m.active, 1)
+	defer atomic.AddInt32(&m.active, -1)
+	for {
+		peak := atomic.LoadInt32(&m.peak)
+		if active <= peak || atomic.CompareAndSwapInt32(&m.peak, peak, active) {
+			break
+		}
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	if atomic.AddInt32(&m.failures, -1) >= 0 {
+		return nil, &ratelimit.Error{StatusCode: 429, Quota: ratelimit.Quota{RetryAfter: time.Millisecond, ReportedAt: time.Now()}, Err: errors.New("slow down")}
+	}
+
+	answer := "5"
+	if m.name == "smart" && request.Settings != nil && request.Settings.Temperature != nil && *request.Settings.Temperature == 0 &&
+		request.SystemInstructions == "Be precise" {
+		answer = "4"
+	}
+	return &model.Response{Content: answer, Usage: &model.Usage{PromptTokens: 10, CompletionTokens: 1, TotalTokens: 11}}, nil
+}
+
+func (m *answerModel) StreamResponse(ctx context.Context, request *model.Request) (<-chan model.StreamEvent, error) {
+	return nil, errors.New("not supported")
+}
+
+type answerProvider struct {
+	models map[string]*answerModel
+	mu     sync.Mutex
+}
+
+func (p *answerProvider) GetModel(name string) (model.Model, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	m, ok := p.models[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown model %s", name)
+	}
+	return m, nil
+}
+
+func newSweep(provider *answerProvider) *eval.Experiment {
+	factory := func(params eval.Params) (*agent.Agent, error) {
+		return params.Apply(agent.NewAgent("Calculator")), nil
+	}
+	grid := eval.ParamGrid{
+		Models:       []string{"smart", "simple"},
+		Temperatures: []float64{0, 0.9},
+		Prompts:      []eval.PromptVariant{{Name: "precise", Instructions: "Be precise"}, {Name: "casual", Instructions: "Be casual"}},
+	}
+	dataset := []eval.Example{
+		{ID: "a", Input: "2+2", Expected: "4"},
+		{ID: "b", Input: "What is 2+2?", Expected: "4"},
+	}
+	return eval.Sweep(factory, grid, dataset).WithRunConfig(&runner.RunConfig{ModelProvider: provider, TracingDisabled: true})
+}
+
+func newProvider() *answerProvider {
+	return &answerProvider{models: map[string]*answerModel{"smart": {name: "smart"}, "simple": {name: "simple"}}}
+}
+
+func TestSweepRunsEveryCombination(t *testing.T) {
+	results, err := newSweep(newProvider()).Run(context.Background())
+	require.NoError(t, err)
+	require.Len(t, results.Rows, 16)
+
+	summaries := results.Summaries()
+	require.Len(t, summaries, 8)
+	assert.Equal(t, "model=smart temperature=0 prompt=precise", summaries[0].Params.String())
+	assert.Equal(t, 2, summaries[0].Runs)
+	assert.Equal(t, 20, summaries[0].PromptTokens)
+
+	best := results.Best()
+	require.NotNil(t, best)
+	assert.Equal(t, "smart", best.Model)
+	assert.Equal(t, 0.0, *best.Temperature)
+	assert.Equal(t, "precise", best.Prompt)
+	assert.Equal(t, 1.0, best.MeanScore)
+	for _, s := range summaries[1:] {
+		assert.Equal(t, 0.0, s.MeanScore, s.Params.String())
+	}
+}
+
+func TestSweepLimitsConcurrencyPerModel(t *testing.T) {
+	provider := newProvider()
+	_, err := newSweep(provider).WithConcurrency(8).WithModelConcurrency("smart", 1).Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), provider.models["smart"].peak)
+	assert.Greater(t, provider.models["simple"].peak, int32(1))
+}
+
+func TestSweepRetriesRateLimitedRuns(t *testing.T) {
+	provider := newProvider()
+	provider.models["smart"].failures = 2
+
+	results, err := newSweep(provider).WithConcurrency(1).Run(context.Background())
+	require.NoError(t, err)
+	for _, row := range results.Rows {
+		assert.Empty(t, row.Error)
+	}
+	assert.Equal(t, 1.0, results.Best().MeanScore)
+
+	provider = newProvider()
+	provider.models["smart"].failures = 1
+	results, err = newSweep(provider).WithConcurrency(1).WithRateLimitRetries(0).Run(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, results.Rows[0].Error, "slow down")
+	assert.Equal(t, 1, results.Summaries()[0].Errors)
+}
+
+func TestSweepWritesCSV(t *testing.T) {
+	results, err := newSweep(newProvider()).Run(context.Background())
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, results.WriteCSV(&buf))
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 17)
+	assert.Equal(t, []string{"model", "temperature", "prompt", "example", "score", "latency_ms", "prompt_tokens", "completion_tokens", "error", "output"}, records[0])
+	assert.Equal(t, []string{"smart", "0", "precise", "a", "1"}, records[1][:5])
+	assert.Equal(t, "4", records[1][9])
+}
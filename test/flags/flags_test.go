@@ -0,0 +1,90 @@
+package flags_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/flags"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryTargetsAndRollouts(t *testing.T) {
+	provider := flags.NewInMemory().
+		Set("new-model", "gpt-4o-mini").
+		SetTarget("new-model", "acme", "gpt-4o").
+		SetRollout("beta-tool", 30, true)
+
+	ctx := context.Background()
+	assert.Equal(t, "gpt-4o", provider.StringValue(ctx, "new-model", "", flags.EvaluationContext{Key: "acme"}))
+	assert.Equal(t, "gpt-4o-mini", provider.StringValue(ctx, "new-model", "", flags.EvaluationContext{Key: "globex"}))
+	assert.Equal(t, "fallback", provider.StringValue(ctx, "unknown", "fallback", flags.EvaluationContext{}))
+	assert.False(t, provider.BoolValue(ctx, "new-model", false, flags.EvaluationContext{}), "values of the wrong type fall back to the default")
+
+	enabled := 0
+	for i := 0; i < 1000; i++ {
+		ec := flags.EvaluationContext{Key: fmt.Sprintf("tenant-%d", i)}
+		first := provider.BoolValue(ctx, "beta-tool", false, ec)
+		assert.Equal(t, first, provider.BoolValue(ctx, "beta-tool", false, ec), "rollouts are stable per key")
+		if first {
+			enabled++
+		}
+	}
+	assert.InDelta(t, 300, enabled, 60)
+}
+
+func TestContextHelpersUseDefaultsWithoutProvider(t *testing.T) {
+	ctx := flags.WithEvaluationContext(context.Background(), flags.EvaluationContext{Key: "acme"})
+	assert.Equal(t, 0.5, flags.Float(ctx, "threshold", 0.5))
+
+	ctx = flags.WithProvider(ctx, flags.NewInMemory().SetTarget("threshold", "acme", 0.9))
+	assert.Equal(t, 0.9, flags.Float(ctx, "threshold", 0.5))
+}
+
+func TestRunnerAppliesModelFlagAndGatedTools(t *testing.T) {
+	var requests []*model.Request
+	m := &mocks.MockModel{}
+	m.On("GetResponse", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		requests = append(requests, args.Get(1).(*model.Request))
+	}).Return(&model.Response{Content: "done"}, nil)
+
+	provider := &mocks.MockModelProvider{}
+	provider.On("GetModel", "gpt-4o").Return(m, nil)
+
+	search := flags.Gate(tool.NewFunctionTool("search", "Searches", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return nil, errors.New("not called")
+	}), "search-tool")
+	a := agent.NewAgent("Assistant").WithModel("gpt-4o-mini").WithTools(search)
+
+	config := &runner.RunConfig{
+		ModelProvider:   provider,
+		TracingDisabled: true,
+		ModelFlag:       "assistant-model",
+		Flags: flags.NewInMemory().
+			SetTarget("assistant-model", "acme", "gpt-4o").
+			SetTarget("search-tool", "acme", true),
+	}
+
+	ctx := flags.WithEvaluationContext(context.Background(), flags.EvaluationContext{Key: "acme"})
+	_, err := runner.NewRunner().Run(ctx, a, &runner.RunOptions{Input: "hi", RunConfig: config})
+	require.NoError(t, err)
+	require.Len(t, requests, 1)
+	assert.Len(t, requests[0].Tools, 1)
+
+	// Another tenant gets neither the new model nor the gated tool
+	provider.On("GetModel", "gpt-4o-mini").Return(m, nil)
+	ctx = flags.WithEvaluationContext(context.Background(), flags.EvaluationContext{Key: "globex"})
+	_, err = runner.NewRunner().Run(ctx, a, &runner.RunOptions{Input: "hi", RunConfig: config})
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+	assert.Empty(t, requests[1].Tools)
+	provider.AssertCalled(t, "GetModel", "gpt-4o-mini")
+}
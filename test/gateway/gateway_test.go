@@ -0,0 +1,136 @@
+package gateway_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool/gateway"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool/httpfetch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyServer fails its first failures requests with status, then succeeds
+func flakyServer(t *testing.T, failures int32, status int) (*httptest.Server, *atomic.Int32) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &hits
+}
+
+func hostname(t *testing.T, server *httptest.Server) string {
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	return u.Hostname()
+}
+
+func get(client *http.Client, url string) (int, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func TestRetriesTransientFailures(t *testing.T) {
+	server, hits := flakyServer(t, 2, http.StatusServiceUnavailable)
+	gw := gateway.New().WithDefaultPolicy(gateway.HostPolicy{RetryBackoff: time.Millisecond})
+
+	status, err := get(gw.Client(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, int32(3), hits.Load())
+
+	hostStatus := gw.Status(hostname(t, server))
+	assert.Equal(t, 1, hostStatus.Requests)
+	assert.Equal(t, 2, hostStatus.Retries)
+	assert.Equal(t, gateway.BreakerClosed, hostStatus.State)
+}
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	server, hits := flakyServer(t, 2, http.StatusInternalServerError)
+	gw := gateway.New().WithHostPolicy(hostname(t, server), gateway.HostPolicy{
+		FailureThreshold: 2,
+		OpenTimeout:      50 * time.Millisecond,
+		MaxRetries:       -1,
+	})
+	client := gw.Client()
+
+	for i := 0; i < 2; i++ {
+		status, err := get(client, server.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusInternalServerError, status)
+	}
+	_, err := get(client, server.URL)
+	assert.ErrorIs(t, err, gateway.ErrCircuitOpen)
+	assert.Equal(t, int32(2), hits.Load(), "open breakers do not call the host")
+	assert.Equal(t, gateway.BreakerOpen, gw.Status(hostname(t, server)).State)
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, gateway.BreakerHalfOpen, gw.Status(hostname(t, server)).State)
+	status, err := get(client, server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, gateway.BreakerClosed, gw.Status(hostname(t, server)).State)
+}
+
+func TestRetryBudgetLimitsRetries(t *testing.T) {
+	server, hits := flakyServer(t, 100, http.StatusBadGateway)
+	gw := gateway.New().WithDefaultPolicy(gateway.HostPolicy{
+		FailureThreshold: 100,
+		MaxRetries:       5,
+		RetryBackoff:     time.Millisecond,
+		RetryBudget:      0.01,
+		MinRetries:       1,
+	})
+
+	status, err := get(gw.Client(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, status)
+	assert.Equal(t, int32(2), hits.Load(), "only one retry fits the budget")
+}
+
+func TestNonIdempotentRequestsAreNotRetried(t *testing.T) {
+	server, hits := flakyServer(t, 1, http.StatusServiceUnavailable)
+	client := gateway.New().WithDefaultPolicy(gateway.HostPolicy{RetryBackoff: time.Millisecond}).Client()
+
+	resp, err := client.Post(server.URL, "application/json", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(1), hits.Load())
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader(`{}`))
+	require.NoError(t, err)
+	req.Header.Set("Idempotency-Key", "order-1")
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestToolsShareHostState(t *testing.T) {
+	server, _ := flakyServer(t, 100, http.StatusInternalServerError)
+	gw := gateway.New().WithDefaultPolicy(gateway.HostPolicy{FailureThreshold: 1, MaxRetries: -1})
+	fetch := httpfetch.New().WithPrivateNetworks(true).WithGateway(gw)
+
+	_, err := fetch.Execute(context.Background(), map[string]interface{}{"url": server.URL})
+	require.NoError(t, err)
+
+	// The breaker opened by the fetch tool also protects other clients of the gateway
+	_, err = get(gw.Client(), server.URL)
+	assert.ErrorIs(t, err, gateway.ErrCircuitOpen)
+}
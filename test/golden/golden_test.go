@@ -0,0 +1,128 @@
+package golden_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/openai"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/testutil"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type weatherParams struct {
+	City string `json:"city" doc:"City to look up"`
+}
+
+func weatherRequest() *model.Request {
+	weather := tool.NewTypedTool("get_weather", "Gets the weather for a city", func(ctx context.Context, params weatherParams) (string, error) {
+		return "sunny", nil
+	})
+	return &model.Request{
+		SystemInstructions: "You are a weather assistant.",
+		Input:              "What is the weather in Stockholm?",
+		Tools:              []interface{}{weather},
+	}
+}
+
+func apiKey() string {
+	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
+		return key
+	}
+	return "test-key"
+}
+
+// TestOpenAIToolCallGolden replays a recorded chat completion with a tool. A change
+// to the request the provider builds, such as the tool schema, fails the test.
+// Record it again with AGENT_SDK_RECORD=1 and a real OPENAI_API_KEY.
+func TestOpenAIToolCallGolden(t *testing.T) {
+	recorder := testutil.Start(t, "testdata/openai_tool_call.json")
+	provider := openai.NewProvider(apiKey()).WithHTTPClient(recorder.Client())
+
+	m, err := provider.GetModel("gpt-4o-mini")
+	require.NoError(t, err)
+
+	res, err := m.GetResponse(context.Background(), weatherRequest())
+	require.NoError(t, err)
+	require.Len(t, res.ToolCalls, 1)
+	assert.Equal(t, "get_weather", res.ToolCalls[0].Name)
+	assert.Equal(t, "Stockholm", res.ToolCalls[0].Parameters["city"])
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func fakeAPI(body string) roundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}, "Set-Cookie": {"session=secret"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	}
+}
+
+func TestRecorderScrubsSecrets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recorder, err := testutil.NewRecorder(path, testutil.ModeRecord)
+	require.NoError(t, err)
+	recorder.WithTransport(fakeAPI(`{"ok":true}`))
+
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/v1/models?key=secret&page=2", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer sk-secret")
+
+	resp, err := recorder.Client().Do(req)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, `{"ok":true}`, string(body))
+	assert.Equal(t, "session=secret", resp.Header.Get("Set-Cookie"))
+	require.NoError(t, recorder.Stop())
+
+	saved, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(saved), "secret")
+	assert.Contains(t, string(saved), testutil.Redacted)
+}
+
+func TestReplayDetectsRequestChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recorder, err := testutil.NewRecorder(path, testutil.ModeRecord)
+	require.NoError(t, err)
+	recorder.WithTransport(fakeAPI(`{"ok":true}`))
+
+	send := func(client *http.Client, body string) error {
+		resp, err := client.Post("https://example.com/v1/chat", "application/json", strings.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+	require.NoError(t, send(recorder.Client(), `{"model":"m","messages":[]}`))
+	require.NoError(t, recorder.Stop())
+
+	replay, err := testutil.NewRecorder(path, testutil.ModeReplay)
+	require.NoError(t, err)
+	assert.Error(t, replay.Stop(), "unused interactions should be reported")
+
+	// Key order and whitespace do not matter, but content does
+	require.NoError(t, send(replay.Client(), `{ "messages": [], "model": "m" }`))
+	assert.NoError(t, replay.Stop())
+
+	replay, err = testutil.NewRecorder(path, testutil.ModeReplay)
+	require.NoError(t, err)
+	err = send(replay.Client(), `{"model":"other","messages":[]}`)
+	assert.True(t, errors.Is(err, testutil.ErrNoInteraction))
+}
@@ -0,0 +1,124 @@
+package guardrail_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/guardrail"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPIIGuardrail(t *testing.T) {
+	ctx := context.Background()
+	g := guardrail.NewPIIGuardrail()
+
+	tests := []struct {
+		name    string
+		text    string
+		tripped bool
+	}{
+		{"clean", "What is the weather today?", false},
+		{"phone", "Call me on (555) 123-4567", true},
+		{"email", "Contact me at jane.doe@example.com", true},
+		{"ssn", "My SSN is 123-45-6789", true},
+		{"valid card", "Card 4111 1111 1111 1111", true},
+		{"invalid card", "Order 1234 5678 9012 3456", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := g.CheckInput(ctx, tt.text)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.tripped, res.TripwireTriggered)
+		})
+	}
+}
+
+func TestRegexAndMaxLengthGuardrails(t *testing.T) {
+	ctx := context.Background()
+
+	regex, err := guardrail.NewRegexGuardrail("secrets", `(?i)api[_-]?key`)
+	assert.NoError(t, err)
+	res, _ := regex.CheckOutput(ctx, "here is the API_KEY")
+	assert.True(t, res.TripwireTriggered)
+	res, _ = regex.CheckOutput(ctx, "nothing to see")
+	assert.False(t, res.TripwireTriggered)
+
+	_, err = guardrail.NewRegexGuardrail("broken", `(`)
+	assert.Error(t, err)
+
+	maxLength := guardrail.NewMaxLengthGuardrail(5)
+	res, _ = maxLength.CheckInput(ctx, "hello")
+	assert.False(t, res.TripwireTriggered)
+	res, _ = maxLength.CheckInput(ctx, []interface{}{map[string]interface{}{"role": "user", "content": "hello world"}})
+	assert.True(t, res.TripwireTriggered)
+}
+
+func TestRunnerAbortsOnInputGuardrail(t *testing.T) {
+	m := mocks.NewScriptedModel(&model.Response{Content: "should not be called"})
+	a := agent.NewAgent("Assistant").WithModel(m)
+
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{
+		Input: "my email is john@example.com",
+		RunConfig: &runner.RunConfig{
+			ModelProvider:   &mocks.MockModelProvider{},
+			TracingDisabled: true,
+			InputGuardrails: []runner.InputGuardrail{guardrail.NewPIIGuardrail(guardrail.PIIEmail)},
+		},
+	})
+
+	var tripped *guardrail.GuardrailTripped
+	assert.True(t, errors.As(err, &tripped))
+	assert.Equal(t, guardrail.StageInput, tripped.Stage)
+	assert.Equal(t, "pii", tripped.Guardrail)
+	assert.Equal(t, 0, m.RequestCount())
+}
+
+func TestRunnerAbortsOnOutputGuardrail(t *testing.T) {
+	m := mocks.NewScriptedModel(&model.Response{Content: "this answer is far too long"})
+	a := agent.NewAgent("Assistant").
+		WithModel(m).
+		WithOutputGuardrails(guardrail.NewMaxLengthGuardrail(10))
+
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{
+		Input:     "hi",
+		RunConfig: &runner.RunConfig{ModelProvider: &mocks.MockModelProvider{}, TracingDisabled: true},
+	})
+
+	var tripped *guardrail.GuardrailTripped
+	assert.True(t, errors.As(err, &tripped))
+	assert.Equal(t, guardrail.StageOutput, tripped.Stage)
+}
+
+func TestStreamingEmitsGuardrailTrippedEvent(t *testing.T) {
+	m := mocks.NewScriptedModel(&model.Response{Content: "unused"})
+	a := agent.NewAgent("Assistant").WithModel(m)
+
+	blockAll := guardrail.NewInputGuardrail("block_all", func(ctx context.Context, input interface{}) (*guardrail.Result, error) {
+		return guardrail.Trip("blocked"), nil
+	})
+
+	res, err := runner.NewRunner().RunStreaming(context.Background(), a, &runner.RunOptions{
+		Input: "hi",
+		RunConfig: &runner.RunConfig{
+			ModelProvider:   &mocks.MockModelProvider{},
+			TracingDisabled: true,
+			InputGuardrails: []runner.InputGuardrail{blockAll},
+		},
+	})
+	assert.NoError(t, err)
+
+	var events []model.StreamEvent
+	for event := range res.Stream {
+		events = append(events, event)
+	}
+	assert.Len(t, events, 2)
+	assert.Equal(t, model.StreamEventTypeRunStarted, events[0].Type)
+	assert.Equal(t, model.StreamEventTypeGuardrailTripped, events[1].Type)
+	assert.Equal(t, "blocked", events[1].Content)
+}
@@ -0,0 +1,253 @@
+// Package harness runs the agent graphs of the examples against scripted models,
+// so that changes to the runner cannot silently break the documented workflows.
+//
+// An example builds its agents with a model.Provider; its test scripts what the
+// model of each agent answers, runs the graph and asserts on the handoffs, tool
+// calls and final output:
+//
+//	orchestrator := newResearchWorkflow(&mocks.MockModelProvider{})
+//	run := harness.Execute(t, runner.NewRunner(), orchestrator, harness.Script{
+//		"Orchestrator":  {harness.Handoff("ResearchAgent", "Research the topic"), harness.Answer("Summary")},
+//		"ResearchAgent": {harness.Return("Findings")},
+//	}, &runner.RunOptions{Input: "Research quantum computing"})
+//	assert.Equal(t, []string{"ResearchAgent", "Orchestrator"}, run.Handoffs())
+package harness
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+)
+
+// Script maps the name of each agent to the responses its model returns, in order
+type Script map[string][]*model.Response
+
+// Run is the outcome of running a scripted agent graph
+type Run struct {
+	// Result is the result of the run
+	Result *result.RunResult
+
+	// Err is the error the run failed with
+	Err error
+
+	// Content is the text streamed by a streaming run
+	Content string
+
+	// Models are the scripted models of the agents, by agent name
+	Models map[string]*mocks.ScriptedModel
+}
+
+// Agents returns the agents reachable from root through handoffs and broadcast
+// handoffs, root first
+func Agents(root *agent.Agent) []*agent.Agent {
+	var agents []*agent.Agent
+	seen := make(map[*agent.Agent]bool)
+	var visit func(a *agent.Agent)
+	visit = func(a *agent.Agent) {
+		if a == nil || seen[a] {
+			return
+		}
+		seen[a] = true
+		agents = append(agents, a)
+		for _, h := range a.Handoffs {
+			visit(h)
+		}
+		for _, b := range a.BroadcastHandoffs {
+			for _, executor := range b.Executors {
+				visit(executor)
+			}
+			visit(b.Reconciler)
+		}
+	}
+	visit(root)
+	return agents
+}
+
+// Use gives every agent reachable from root a scripted model in place of the model
+// of its provider. Agents with the same name share a model. Agents the script
+// leaves out get a model without responses, so that a run reaching them fails. The test fails if the script names an agent
+// that is not in the graph, such as one that was renamed.
+func Use(t testing.TB, root *agent.Agent, script Script) map[string]*mocks.ScriptedModel {
+	t.Helper()
+
+	models := make(map[string]*mocks.ScriptedModel)
+	for _, a := range Agents(root) {
+		if _, ok := models[a.Name]; !ok {
+			models[a.Name] = mocks.NewScriptedModel(script[a.Name]...)
+		}
+		a.WithModel(models[a.Name])
+	}
+	for name := range script {
+		if _, ok := models[name]; !ok {
+			t.Fatalf("harness: the script names %q, which is not in the agent graph", name)
+		}
+	}
+	return models
+}
+
+// Execute scripts the agent graph of root and runs it to completion. The run's
+// error is returned in the Run rather than failing the test, so that tests can
+// assert on failures.
+func Execute(t testing.TB, r *runner.Runner, root *agent.Agent, script Script, opts *runner.RunOptions) *Run {
+	t.Helper()
+
+	run := &Run{Models: Use(t, root, script)}
+	run.Result, run.Err = r.Run(context.Background(), root, prepare(opts))
+	return run
+}
+
+// Stream scripts the agent graph of root and runs it with streaming, collecting
+// the streamed content until the stream closes
+func Stream(t testing.TB, r *runner.Runner, root *agent.Agent, script Script, opts *runner.RunOptions) *Run {
+	t.Helper()
+
+	run := &Run{Models: Use(t, root, script)}
+	streamed, err := r.RunStreaming(context.Background(), root, prepare(opts))
+	if err != nil {
+		run.Err = err
+		return run
+	}
+
+	var content strings.Builder
+	for event := range streamed.Stream {
+		switch event.Type {
+		case model.StreamEventTypeContent:
+			content.WriteString(event.Content)
+		case model.StreamEventTypeError:
+			run.Err = event.Error
+		}
+	}
+	run.Content = content.String()
+	run.Result = streamed.RunResult
+	return run
+}
+
+// prepare returns the options of a scripted run, which needs no real provider
+// and records no traces
+func prepare(opts *runner.RunOptions) *runner.RunOptions {
+	if opts == nil {
+		opts = &runner.RunOptions{}
+	}
+	if opts.RunConfig == nil {
+		opts.RunConfig = &runner.RunConfig{}
+	}
+	if opts.RunConfig.ModelProvider == nil {
+		opts.RunConfig.ModelProvider = &mocks.MockModelProvider{}
+	}
+	opts.RunConfig.TracingDisabled = true
+	return opts
+}
+
+// Handoffs returns the names of the agents handed off to, in order, including
+// returns to delegating agents
+func (run *Run) Handoffs() []string {
+	var names []string
+	if run.Result == nil {
+		return names
+	}
+	for _, item := range run.Result.NewItems {
+		if handoff, ok := item.(*result.HandoffItem); ok {
+			names = append(names, handoff.AgentName)
+		}
+	}
+	return names
+}
+
+// ToolCalls returns the names of the tools called, in order
+func (run *Run) ToolCalls() []string {
+	var names []string
+	if run.Result == nil {
+		return names
+	}
+	for _, item := range run.Result.NewItems {
+		if call, ok := item.(*result.ToolCallItem); ok {
+			names = append(names, call.Name)
+		}
+	}
+	return names
+}
+
+// ToolResults returns the results of the calls of a tool, in order
+func (run *Run) ToolResults(name string) []interface{} {
+	var results []interface{}
+	if run.Result == nil {
+		return results
+	}
+	for _, item := range run.Result.NewItems {
+		if r, ok := item.(*result.ToolResultItem); ok && r.Name == name {
+			results = append(results, r.Result)
+		}
+	}
+	return results
+}
+
+// Output returns the final output of the run as text
+func (run *Run) Output() string {
+	if run.Result == nil || run.Result.FinalOutput == nil {
+		return ""
+	}
+	return fmt.Sprint(run.Result.FinalOutput)
+}
+
+// LastAgent returns the name of the agent that produced the final output
+func (run *Run) LastAgent() string {
+	if run.Result == nil || run.Result.LastAgent == nil {
+		return ""
+	}
+	return run.Result.LastAgent.Name
+}
+
+// Unused returns the number of scripted responses each agent did not get to
+// return, for agents with any left. A workflow that stops early leaves some.
+func (run *Run) Unused() map[string]int {
+	unused := make(map[string]int)
+	for name, m := range run.Models {
+		if left := len(m.Responses); left > 0 {
+			unused[name] = left
+		}
+	}
+	return unused
+}
+
+// Answer is a response with final content
+func Answer(content string) *model.Response {
+	return &model.Response{Content: content}
+}
+
+// CallTool is a response calling a tool
+func CallTool(name string, params map[string]interface{}) *model.Response {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	return &model.Response{ToolCalls: []model.ToolCall{{
+		ID:         "call_" + name,
+		Name:       name,
+		Parameters: params,
+	}}}
+}
+
+// Handoff is a response handing off to an agent. An agent that is handed a task
+// returns to the agent that handed it off with Return.
+func Handoff(agentName, input string) *model.Response {
+	return &model.Response{HandoffCall: &model.HandoffCall{
+		AgentName:  agentName,
+		Parameters: map[string]interface{}{"input": input},
+	}}
+}
+
+// Return is a response completing a delegated task and returning its result to
+// the delegating agent
+func Return(output string) *model.Response {
+	return &model.Response{HandoffCall: &model.HandoffCall{
+		AgentName:      "return_to_delegator",
+		Parameters:     map[string]interface{}{"input": output},
+		IsTaskComplete: true,
+	}}
+}
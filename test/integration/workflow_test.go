@@ -22,6 +22,9 @@ func TestWorkflowWithMultipleAgents(t *testing.T) {
 		t.Skip("Skipping integration test in short mode")
 	}
 
+	// The runner writes its traces to the working directory
+	t.Chdir(t.TempDir())
+
 	// Create a simple in-memory state store
 	stateStore := mocks.NewInMemoryStateStore()
 
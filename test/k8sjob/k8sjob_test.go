@@ -0,0 +1,172 @@
+package k8sjob_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/artifact"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool/k8sjob"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCluster serves the parts of the Kubernetes API the executor uses, running
+// one job that prints logs and ends with a termination message
+type fakeCluster struct {
+	logs    string
+	message string
+	failed  bool
+
+	mu      sync.Mutex
+	created map[string]interface{}
+	deleted []string
+	done    bool
+}
+
+func (c *fakeCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/apis/batch/v1/namespaces/builds/jobs":
+		_ = json.NewDecoder(r.Body).Decode(&c.created)
+		writeJSON(w, map[string]interface{}{"metadata": map[string]string{"name": "build-x1"}})
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v1/namespaces/builds/pods":
+		if r.URL.Query().Get("labelSelector") != "job-name=build-x1" {
+			writeJSON(w, map[string]interface{}{"items": []interface{}{}})
+			return
+		}
+		status := map[string]interface{}{"phase": "Running"}
+		if c.done {
+			status = map[string]interface{}{
+				"phase": "Succeeded",
+				"containerStatuses": []interface{}{map[string]interface{}{
+					"name":  "tool",
+					"state": map[string]interface{}{"terminated": map[string]interface{}{"exitCode": 0, "message": c.message}},
+				}},
+			}
+		}
+		writeJSON(w, map[string]interface{}{"items": []interface{}{map[string]interface{}{
+			"metadata": map[string]string{"name": "build-x1-pod"},
+			"status":   status,
+		}}})
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v1/namespaces/builds/pods/build-x1-pod/log":
+		fmt.Fprint(w, c.logs)
+		c.done = true
+	case r.Method == http.MethodGet && r.URL.Path == "/apis/batch/v1/namespaces/builds/jobs/build-x1":
+		status := map[string]interface{}{"succeeded": 1}
+		if c.failed {
+			status = map[string]interface{}{"failed": 1, "conditions": []interface{}{
+				map[string]string{"type": "Failed", "status": "True", "reason": "BackoffLimitExceeded", "message": "Job has reached the specified backoff limit"},
+			}}
+		}
+		writeJSON(w, map[string]interface{}{"status": status})
+	case r.Method == http.MethodDelete && r.URL.Path == "/apis/batch/v1/namespaces/builds/jobs/build-x1":
+		c.deleted = append(c.deleted, r.URL.Query().Get("propagationPolicy"))
+		writeJSON(w, map[string]interface{}{})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func newExecutor(t *testing.T, cluster *fakeCluster) *k8sjob.Executor {
+	server := httptest.NewServer(cluster)
+	t.Cleanup(server.Close)
+	return k8sjob.NewExecutor(server.URL, "builds").WithToken("secret").WithPollInterval(time.Millisecond)
+}
+
+var buildJob = k8sjob.Job{
+	Name:        "build_project",
+	Description: "Build the project",
+	Image:       "builder:1.0",
+	Args:        []string{"make", "all"},
+	Requests:    map[string]string{"cpu": "2"},
+	Timeout:     5 * time.Minute,
+}
+
+func TestRunCreatesJobAndReturnsCompletionArtifact(t *testing.T) {
+	cluster := &fakeCluster{logs: "compiling\nlinking\n", message: `{"binary":"app","tests":12}`}
+	executor := newExecutor(t, cluster)
+
+	res, err := executor.Run(context.Background(), buildJob, map[string]interface{}{"target": "all"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "build-x1", res.Job)
+	assert.Equal(t, map[string]interface{}{"binary": "app", "tests": float64(12)}, res.Output)
+	assert.Equal(t, "compiling\nlinking\n", res.Logs)
+	assert.Equal(t, []string{"Background"}, cluster.deleted)
+
+	spec := cluster.created["spec"].(map[string]interface{})
+	assert.Equal(t, float64(300), spec["activeDeadlineSeconds"])
+	container := spec["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "builder:1.0", container["image"])
+	assert.Equal(t, []interface{}{"make", "all"}, container["args"])
+	assert.Equal(t, map[string]interface{}{"cpu": "2"}, container["resources"].(map[string]interface{})["requests"])
+	assert.Contains(t, container["env"], map[string]interface{}{"name": k8sjob.ParamsEnv, "value": `{"target":"all"}`})
+}
+
+func TestFailedJobReturnsErrorAndIsDeleted(t *testing.T) {
+	cluster := &fakeCluster{logs: "error: undefined symbol\n", failed: true}
+	executor := newExecutor(t, cluster)
+
+	_, err := executor.Run(context.Background(), buildJob, nil)
+	assert.ErrorIs(t, err, k8sjob.ErrJobFailed)
+	assert.Contains(t, err.Error(), "undefined symbol")
+	assert.Equal(t, []string{"Background"}, cluster.deleted)
+}
+
+func TestCompletionArtifactFromStore(t *testing.T) {
+	store := artifact.NewMemoryStore()
+	stored, err := store.Put(context.Background(), artifact.Artifact{Name: "report.json", MediaType: "application/json"}, []byte(`{"rows":1000000}`))
+	require.NoError(t, err)
+
+	cluster := &fakeCluster{message: fmt.Sprintf(`{"artifact_id":%q}`, stored.ID)}
+	executor := newExecutor(t, cluster).WithArtifactStore(store)
+
+	res, err := executor.Run(context.Background(), buildJob, nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"rows": float64(1000000)}, res.Output)
+}
+
+func TestStreamingRunSendsJobLogsAsToolOutput(t *testing.T) {
+	cluster := &fakeCluster{logs: "step 1\nstep 2\n", message: "built"}
+	builder := agent.New("Builder",
+		agent.WithTools(newExecutor(t, cluster).Tool(buildJob)),
+		agent.WithModel(mocks.NewScriptedModel(
+			&model.Response{ToolCalls: []model.ToolCall{{ID: "call-1", Name: "build_project", Parameters: map[string]interface{}{}}}},
+			&model.Response{Content: "The build succeeded"},
+		)),
+	)
+
+	streamed, err := runner.NewRunner().RunStreaming(context.Background(), builder, &runner.RunOptions{
+		Input:     "build it",
+		RunConfig: &runner.RunConfig{ModelProvider: &mocks.MockModelProvider{}, TracingDisabled: true},
+	})
+	require.NoError(t, err)
+
+	var output []string
+	for event := range streamed.Stream {
+		if event.Type == model.StreamEventTypeToolOutput {
+			require.NotNil(t, event.ToolCall)
+			assert.Equal(t, "build_project", event.ToolCall.Name)
+			output = append(output, event.Content)
+		}
+	}
+	assert.Equal(t, []string{"step 1\n", "step 2\n"}, output)
+	assert.Equal(t, "The build succeeded", streamed.RunResult.FinalOutput)
+}
@@ -0,0 +1,295 @@
+package memory_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/memory/postgres"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPostgresMigrationsAreOrderedAndPrefixed(t *testing.T) {
+	store := postgres.NewStore(nil).WithTablePrefix("chat_")
+
+	migrations, err := store.Migrations()
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, migration := range migrations {
+		assert.Equal(t, i+1, migration.Version, "migrations must be numbered without gaps")
+		assert.NotEmpty(t, migration.Name)
+		assert.NotContains(t, migration.SQL, "{{prefix}}")
+	}
+
+	assert.Contains(t, migrations[0].SQL, "CREATE TABLE IF NOT EXISTS chat_sessions")
+	assert.Equal(t, "chat_runs", store.Table("runs"))
+}
+
+func TestPostgresTaskTablesMatchTaskStore(t *testing.T) {
+	migrations, err := postgres.NewStore(nil).Migrations()
+	require.NoError(t, err)
+
+	var schema string
+	for _, migration := range migrations {
+		schema += migration.SQL
+	}
+	assert.Contains(t, schema, "CREATE TABLE IF NOT EXISTS agent_tasks")
+	assert.Contains(t, schema, "CREATE TABLE IF NOT EXISTS agent_delegation_chains")
+}
+
+// queries returns the text of the statements an SQLDB received, in order
+func queries(db *mocks.SQLDB) []string {
+	var texts []string
+	for _, stmt := range db.Statements("") {
+		texts = append(texts, stmt.Query)
+	}
+	return texts
+}
+
+// affected answers a statement with the number of rows it changed
+func affected(n int64) mocks.SQLHandler {
+	return func(args []driver.Value) (*mocks.SQLResult, error) {
+		return &mocks.SQLResult{RowsAffected: n}, nil
+	}
+}
+
+func TestPostgresMigrateAppliesPendingMigrations(t *testing.T) {
+	db := mocks.NewSQLDB().Handle("SELECT COALESCE(MAX(version), 0) FROM agent_schema_migrations", func(args []driver.Value) (*mocks.SQLResult, error) {
+		return &mocks.SQLResult{Rows: [][]driver.Value{{int64(2)}}}, nil
+	})
+	store := postgres.NewStore(db.DB)
+	require.NoError(t, store.Migrate(context.Background()))
+
+	statements := queries(db)
+	assert.Equal(t, "BEGIN", statements[0])
+	assert.Equal(t, "SELECT pg_advisory_xact_lock($1)", statements[1])
+	assert.Equal(t, "COMMIT", statements[len(statements)-1])
+
+	// Only the migrations after the stored version are applied and recorded
+	recorded := db.Statements("INSERT INTO agent_schema_migrations")
+	require.Len(t, recorded, 2)
+	assert.Equal(t, []driver.Value{int64(3), "tasks"}, recorded[0].Args)
+	assert.Equal(t, []driver.Value{int64(4), "retention"}, recorded[1].Args)
+	assert.Empty(t, db.Statements("CREATE TABLE IF NOT EXISTS agent_sessions"))
+	assert.NotEmpty(t, db.Statements("CREATE TABLE IF NOT EXISTS agent_tasks"))
+}
+
+func TestPostgresSaveRunStoresItemsAndUsage(t *testing.T) {
+	db := mocks.NewSQLDB()
+	store := postgres.NewStore(db.DB)
+	startedAt := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	err := store.SaveRun(context.Background(), &postgres.RunRecord{
+		RunID: "run-1", SessionID: "s-1", AgentName: "Support", Model: "gpt-4o", StartedAt: startedAt,
+	}, &result.RunResult{
+		Input: "reset my password",
+		NewItems: []result.RunItem{
+			&result.MessageItem{Role: "assistant", Content: "Which account?"},
+			&result.MessageItem{Role: "assistant", Content: "Done."},
+		},
+		RawResponses: []model.Response{
+			{Usage: &model.Usage{PromptTokens: 10, CompletionTokens: 5}},
+			{Content: "no usage reported"},
+		},
+		FinalOutput: "Done.",
+	})
+	require.NoError(t, err)
+
+	statements := queries(db)
+	require.Len(t, statements, 7)
+	assert.Equal(t, "BEGIN", statements[0])
+	assert.Equal(t, "COMMIT", statements[6])
+
+	run := db.Statements("INSERT INTO agent_runs")[0]
+	assert.Equal(t, []driver.Value{"run-1", "s-1", "Support", "gpt-4o", postgres.RunStatusCompleted,
+		`"reset my password"`, `"Done."`, "", startedAt, nil, ""}, run.Args)
+
+	// Stored items replace the previous ones, in order
+	assert.Equal(t, []driver.Value{"run-1"}, db.Statements("DELETE FROM agent_run_items")[0].Args)
+	items := db.Statements("INSERT INTO agent_run_items")
+	require.Len(t, items, 2)
+	assert.Equal(t, []driver.Value{"run-1", int64(0), "message", "Which account?"}, items[0].Args[:4])
+	assert.Equal(t, []driver.Value{"run-1", int64(1), "message", "Done."}, items[1].Args[:4])
+
+	// Only responses reporting usage get a usage row, with the total filled in
+	usage := db.Statements("INSERT INTO agent_usage")
+	require.Len(t, usage, 1)
+	assert.Equal(t, []driver.Value{"run-1", "gpt-4o", int64(10), int64(5), int64(15), float64(0)}, usage[0].Args[:6])
+}
+
+func TestPostgresSaveRunRollsBackOnError(t *testing.T) {
+	db := mocks.NewSQLDB().Handle("INSERT INTO agent_run_items", func(args []driver.Value) (*mocks.SQLResult, error) {
+		return nil, errors.New("disk full")
+	})
+	err := postgres.NewStore(db.DB).SaveRun(context.Background(), &postgres.RunRecord{RunID: "run-1"}, &result.RunResult{
+		NewItems: []result.RunItem{&result.MessageItem{Role: "assistant", Content: "hi"}},
+	})
+	assert.ErrorContains(t, err, "failed to save item 0 of run run-1: disk full")
+
+	statements := queries(db)
+	assert.Equal(t, "ROLLBACK", statements[len(statements)-1])
+	assert.Empty(t, db.Statements("COMMIT"))
+}
+
+func TestPostgresGetAndListRuns(t *testing.T) {
+	startedAt := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	finishedAt := startedAt.Add(time.Minute)
+	row := func(runID string, finished interface{}) []driver.Value {
+		return []driver.Value{runID, "s-1", "user-42", "Support", "gpt-4o", "completed",
+			[]byte(`"reset my password"`), []byte(`{"answer":"Done."}`), "", startedAt, finished}
+	}
+	db := mocks.NewSQLDB().
+		Handle("WHERE run_id = $1", func(args []driver.Value) (*mocks.SQLResult, error) {
+			if args[0] != "run-1" {
+				return &mocks.SQLResult{}, nil
+			}
+			return &mocks.SQLResult{Rows: [][]driver.Value{row("run-1", finishedAt)}}, nil
+		}).
+		Handle("FROM agent_runs", func(args []driver.Value) (*mocks.SQLResult, error) {
+			return &mocks.SQLResult{Rows: [][]driver.Value{row("run-2", nil), row("run-1", finishedAt)}}, nil
+		})
+	store := postgres.NewStore(db.DB)
+	ctx := context.Background()
+
+	run, err := store.GetRun(ctx, "run-1")
+	require.NoError(t, err)
+	assert.Equal(t, &postgres.RunRecord{
+		RunID: "run-1", SessionID: "s-1", UserID: "user-42", AgentName: "Support", Model: "gpt-4o", Status: "completed",
+		Input: "reset my password", FinalOutput: map[string]interface{}{"answer": "Done."},
+		StartedAt: startedAt, FinishedAt: finishedAt,
+	}, run)
+
+	_, err = store.GetRun(ctx, "run-9")
+	assert.ErrorIs(t, err, postgres.ErrRunNotFound)
+
+	since := startedAt.Add(-time.Hour)
+	runs, err := store.ListRuns(ctx, postgres.RunFilter{SessionID: "s-1", Status: "completed", Since: since, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "run-2", runs[0].RunID)
+	assert.True(t, runs[0].FinishedAt.IsZero())
+	assert.Equal(t, "run-1", runs[1].RunID)
+
+	list := db.Statements("ORDER BY started_at DESC")
+	require.Len(t, list, 1)
+	assert.Contains(t, list[0].Query, "WHERE session_id = $1 AND status = $2 AND started_at >= $3 ORDER BY started_at DESC LIMIT $4")
+	assert.Equal(t, []driver.Value{"s-1", "completed", since, int64(10)}, list[0].Args)
+}
+
+func TestPostgresUsageByModel(t *testing.T) {
+	db := mocks.NewSQLDB().Handle("FROM agent_usage", func(args []driver.Value) (*mocks.SQLResult, error) {
+		return &mocks.SQLResult{Rows: [][]driver.Value{
+			{"gpt-4o", int64(3), int64(1200), int64(300), int64(1500), 0.42},
+			{"gpt-4o-mini", int64(5), int64(900), int64(100), int64(1000), 0.01},
+		}}, nil
+	})
+	since := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	summaries, err := postgres.NewStore(db.DB).UsageByModel(context.Background(), since)
+	require.NoError(t, err)
+	assert.Equal(t, []postgres.UsageSummary{
+		{Model: "gpt-4o", Runs: 3, PromptTokens: 1200, CompletionTokens: 300, TotalTokens: 1500, CostUSD: 0.42},
+		{Model: "gpt-4o-mini", Runs: 5, PromptTokens: 900, CompletionTokens: 100, TotalTokens: 1000, CostUSD: 0.01},
+	}, summaries)
+	assert.Equal(t, []driver.Value{since}, db.Statements("FROM agent_usage")[0].Args)
+}
+
+func TestPostgresSessionItems(t *testing.T) {
+	db := mocks.NewSQLDB().Handle("SELECT item FROM", func(args []driver.Value) (*mocks.SQLResult, error) {
+		return &mocks.SQLResult{Rows: [][]driver.Value{
+			{[]byte(`{"role":"user","content":"hi"}`)},
+			{[]byte(`{"role":"assistant","content":"hello"}`)},
+		}}, nil
+	})
+	session := postgres.NewStore(db.DB).Session("s-1").WithUserID("user-42")
+	ctx := context.Background()
+
+	require.NoError(t, session.AddItems(ctx, []interface{}{
+		map[string]interface{}{"role": "user", "content": "hi"},
+		map[string]interface{}{"role": "assistant", "content": "hello"},
+	}))
+	statements := queries(db)
+	require.Len(t, statements, 5)
+	assert.Equal(t, "BEGIN", statements[0])
+	assert.Contains(t, statements[1], "INSERT INTO agent_sessions")
+	assert.Equal(t, "COMMIT", statements[4])
+	assert.Equal(t, []driver.Value{"s-1", "user-42"}, db.Statements("INSERT INTO agent_sessions")[0].Args[:2])
+	appended := db.Statements("INSERT INTO agent_session_items")
+	require.Len(t, appended, 2)
+	assert.Equal(t, []driver.Value{"s-1", "user", "hi", `{"content":"hi","role":"user"}`}, appended[0].Args[:4])
+	assert.Equal(t, []driver.Value{"s-1", "assistant", "hello"}, appended[1].Args[:3])
+
+	// Items come back in the order of the rows, which the query sorts by insertion
+	items, err := session.GetItems(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"role": "user", "content": "hi"},
+		map[string]interface{}{"role": "assistant", "content": "hello"},
+	}, items)
+	read := db.Statements("SELECT item FROM")
+	assert.Contains(t, read[0].Query, "WHERE session_id = $1 ORDER BY id")
+	assert.Equal(t, []driver.Value{"s-1"}, read[0].Args)
+
+	// A limit keeps the most recent items, still oldest first
+	_, err = session.GetItems(ctx, 2)
+	require.NoError(t, err)
+	read = db.Statements("SELECT item FROM")
+	assert.Contains(t, read[1].Query, "ORDER BY id DESC LIMIT $2")
+	assert.Contains(t, read[1].Query, ") recent ORDER BY id")
+	assert.Equal(t, []driver.Value{"s-1", int64(2)}, read[1].Args)
+
+	// Popping an empty session returns nothing
+	item, err := session.PopItem(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, item)
+	assert.Contains(t, db.Statements("RETURNING item")[0].Query, "ORDER BY id DESC LIMIT 1")
+
+	require.NoError(t, session.Clear(ctx))
+	assert.Equal(t, []driver.Value{"s-1"}, db.Statements("DELETE FROM agent_sessions WHERE session_id = $1")[0].Args)
+}
+
+func TestPostgresPurgeKeepsRunMetrics(t *testing.T) {
+	db := mocks.NewSQLDB().
+		Handle("DELETE FROM agent_session_items WHERE created_at < $1", affected(7)).
+		Handle("DELETE FROM agent_sessions s WHERE updated_at < $1", affected(2)).
+		Handle("DELETE FROM agent_run_items", affected(12)).
+		Handle("UPDATE agent_runs SET input = NULL", affected(3))
+	cutoff := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	report, err := postgres.NewStore(db.DB).Purge(context.Background(), cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, 9, report.Deleted, "session items and emptied sessions are deleted")
+	assert.Equal(t, 3, report.Redacted, "runs are anonymized rather than deleted")
+
+	for _, stmt := range db.Statements("$1") {
+		assert.Equal(t, []driver.Value{cutoff}, stmt.Args)
+	}
+	assert.Contains(t, db.Statements("DELETE FROM agent_run_items")[0].Query, "WHERE started_at < $1")
+	assert.Empty(t, db.Statements("agent_usage"), "usage rows are kept")
+	assert.Len(t, db.Statements("COMMIT"), 1)
+}
+
+func TestPostgresForgetUser(t *testing.T) {
+	db := mocks.NewSQLDB().
+		Handle("DELETE FROM agent_sessions WHERE user_id = $1", affected(2)).
+		Handle("UPDATE agent_runs", affected(4))
+	store := postgres.NewStore(db.DB)
+
+	_, err := store.Forget(context.Background(), "")
+	assert.Error(t, err)
+	assert.Empty(t, queries(db))
+
+	report, err := store.Forget(context.Background(), "user-42")
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Deleted)
+	assert.Equal(t, 4, report.Redacted)
+	assert.Contains(t, db.Statements("UPDATE agent_runs")[0].Query, "WHERE user_id = $1")
+	assert.Equal(t, []driver.Value{"user-42"}, db.Statements("DELETE FROM agent_run_items")[0].Args)
+}
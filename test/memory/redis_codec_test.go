@@ -0,0 +1,55 @@
+package memory_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/memory/redis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMsgpackCodecRoundTrip(t *testing.T) {
+	codec := redis.MsgpackCodec{}
+	item := map[string]interface{}{
+		"role":    "user",
+		"content": strings.Repeat("long text ", 40),
+		"tokens":  int64(-70000),
+		"score":   0.25,
+		"flags":   []interface{}{true, false, nil},
+		"audio":   []byte{0, 1, 2},
+		"nested":  map[string]interface{}{"count": int64(300)},
+	}
+
+	data, err := codec.Marshal(item)
+	require.NoError(t, err)
+	decoded, err := codec.Unmarshal(data)
+	require.NoError(t, err)
+	assert.Equal(t, item, decoded)
+
+	jsonData, err := redis.JSONCodec{}.Marshal(item)
+	require.NoError(t, err)
+	assert.Less(t, len(data), len(jsonData))
+}
+
+func TestMsgpackCodecEncodesStructsThroughJSON(t *testing.T) {
+	type message struct {
+		Role    string `json:"role"`
+		Content string `json:"content,omitempty"`
+		Turn    int    `json:"turn"`
+	}
+
+	data, err := redis.MsgpackCodec{}.Marshal(message{Role: "assistant", Turn: 3})
+	require.NoError(t, err)
+	decoded, err := redis.MsgpackCodec{}.Unmarshal(data)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"role": "assistant", "turn": int64(3)}, decoded)
+}
+
+func TestMsgpackCodecRejectsTruncatedData(t *testing.T) {
+	data, err := redis.MsgpackCodec{}.Marshal([]interface{}{"a", "b", "c"})
+	require.NoError(t, err)
+
+	_, err = redis.MsgpackCodec{}.Unmarshal(data[:len(data)-1])
+	assert.Error(t, err)
+}
@@ -0,0 +1,107 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/memory/redis"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/ratelimit"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingRedisClient fails every command
+type failingRedisClient struct{}
+
+func (failingRedisClient) Get(ctx context.Context, key string) (string, error) {
+	return "", errors.New("connection refused")
+}
+
+func (failingRedisClient) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func (failingRedisClient) Del(ctx context.Context, keys ...string) error {
+	return errors.New("connection refused")
+}
+
+func (failingRedisClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestRedisCounter(t *testing.T) {
+	ctx := context.Background()
+	client := mocks.NewRedisClient()
+	counter := redis.NewCounter(client)
+
+	value, err := counter.Get(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), value, "missing counters are zero")
+
+	value, err = counter.Add(ctx, "requests", 3, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), value)
+	value, err = counter.Add(ctx, "requests", -1, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), value)
+
+	value, err = counter.Get(ctx, "requests")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), value)
+	assert.Equal(t, []string{"agentsdk:ratelimit:requests"}, client.Keys())
+	assert.Equal(t, time.Minute, client.TTL("agentsdk:ratelimit:requests"), "the expiry is set when the counter is created")
+
+	require.NoError(t, counter.Delete(ctx, "requests", "tokens"))
+	assert.Empty(t, client.Keys())
+}
+
+func TestRedisCounterKeyPrefix(t *testing.T) {
+	client := mocks.NewRedisClient()
+	_, err := redis.NewCounter(client).WithKeyPrefix("svc:").Add(context.Background(), "requests", 1, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"svc:ratelimit:requests"}, client.Keys())
+}
+
+func TestRedisCounterErrors(t *testing.T) {
+	ctx := context.Background()
+	counter := redis.NewCounter(failingRedisClient{})
+
+	_, err := counter.Add(ctx, "requests", 1, time.Minute)
+	assert.ErrorContains(t, err, "failed to add to counter requests: connection refused")
+	_, err = counter.Get(ctx, "requests")
+	assert.ErrorContains(t, err, "failed to read counter requests: connection refused")
+	assert.ErrorContains(t, counter.Delete(ctx, "requests"), "connection refused")
+
+	client := mocks.NewRedisClient()
+	require.NoError(t, client.Set(ctx, "agentsdk:ratelimit:requests", "many", 0))
+	_, err = redis.NewCounter(client).Get(ctx, "requests")
+	assert.ErrorContains(t, err, "invalid value of counter requests")
+}
+
+func TestRedisCounterSharesLimits(t *testing.T) {
+	ctx := context.Background()
+	client := mocks.NewRedisClient()
+
+	// Limiters in two processes, each with its own counter on the same Redis
+	first := ratelimit.NewCounterLimiter(redis.NewCounter(client), "org", 2, 100).WithWindow(time.Hour)
+	second := ratelimit.NewCounterLimiter(redis.NewCounter(client), "org", 2, 100).WithWindow(time.Hour)
+
+	require.NoError(t, first.Wait(ctx, nil))
+	require.NoError(t, second.Wait(ctx, nil))
+	first.AddTokens(40)
+
+	status := second.Status()
+	assert.True(t, status.Throttled)
+	assert.Equal(t, 0, status.RemainingRequests)
+	assert.Equal(t, 60, status.RemainingTokens)
+
+	waitCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	throttled := 0
+	err := first.Wait(waitCtx, func(ratelimit.Status) { throttled++ })
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, throttled)
+}
@@ -0,0 +1,165 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/memory/redis"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type plan struct {
+	Step string `json:"step"`
+}
+
+func TestRedisStoreOptimisticLocking(t *testing.T) {
+	ctx := context.Background()
+	store := redis.NewStore(mocks.NewRedisClient())
+
+	version, err := store.Save(ctx, "plan", plan{Step: "draft"}, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), version)
+
+	version, err = store.Save(ctx, "plan", plan{Step: "review"}, version)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), version)
+
+	// A writer that last saw version 1 is rejected and the record is unchanged
+	_, err = store.Save(ctx, "plan", plan{Step: "stale"}, 1)
+	assert.ErrorIs(t, err, redis.ErrVersionConflict)
+
+	var current plan
+	version, err = store.Load(ctx, "plan", &current)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), version)
+	assert.Equal(t, "review", current.Step)
+
+	// Retrying with the reloaded version succeeds, as does an unconditional write
+	version, err = store.Save(ctx, "plan", plan{Step: "merge"}, version)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), version)
+	version, err = store.Save(ctx, "plan", plan{Step: "done"}, redis.AnyVersion)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), version)
+}
+
+func TestRedisStoreMissingRecords(t *testing.T) {
+	ctx := context.Background()
+	store := redis.NewStore(mocks.NewRedisClient())
+
+	_, err := store.Load(ctx, "missing", &plan{})
+	assert.ErrorIs(t, err, redis.ErrNotFound)
+
+	err = store.LoadCheckpoint(ctx, "missing", "1", &plan{})
+	assert.ErrorIs(t, err, redis.ErrNotFound)
+
+	checkpoints, err := store.ListCheckpoints(ctx, "missing")
+	require.NoError(t, err)
+	assert.Empty(t, checkpoints)
+}
+
+func TestRedisStoreTTL(t *testing.T) {
+	ctx := context.Background()
+	client := mocks.NewRedisClient()
+	store := redis.NewStore(client).WithKeyPrefix("test:").WithTTL(90 * time.Second)
+
+	_, err := store.SaveCheckpoint(ctx, "plan", "draft", plan{Step: "draft"}, redis.AnyVersion)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"test:state:{plan}",
+		"test:state:{plan}:checkpoint:draft",
+		"test:state:{plan}:checkpoints",
+	}, client.Keys())
+	for _, key := range client.Keys() {
+		assert.Equal(t, 90*time.Second, client.TTL(key), key)
+	}
+}
+
+func TestRedisStoreCheckpoints(t *testing.T) {
+	ctx := context.Background()
+	store := redis.NewStore(mocks.NewRedisClient())
+
+	_, err := store.SaveCheckpoint(ctx, "plan", "draft", plan{Step: "draft"}, redis.AnyVersion)
+	require.NoError(t, err)
+	_, err = store.Save(ctx, "plan", plan{Step: "review"}, redis.AnyVersion)
+	require.NoError(t, err)
+	_, err = store.SaveCheckpoint(ctx, "plan", "final", plan{Step: "done"}, redis.AnyVersion)
+	require.NoError(t, err)
+
+	checkpoints, err := store.ListCheckpoints(ctx, "plan")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"draft", "2", "final"}, checkpoints)
+
+	var draft plan
+	require.NoError(t, store.LoadCheckpoint(ctx, "plan", "draft", &draft))
+	assert.Equal(t, "draft", draft.Step)
+
+	require.NoError(t, store.DeleteCheckpoint(ctx, "plan", "draft"))
+	checkpoints, err = store.ListCheckpoints(ctx, "plan")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2", "final"}, checkpoints)
+	assert.ErrorIs(t, store.LoadCheckpoint(ctx, "plan", "draft", &draft), redis.ErrNotFound)
+
+	require.NoError(t, store.Delete(ctx, "plan"))
+	_, err = store.Load(ctx, "plan", nil)
+	assert.ErrorIs(t, err, redis.ErrNotFound)
+	assert.ErrorIs(t, store.LoadCheckpoint(ctx, "plan", "final", &draft), redis.ErrNotFound)
+}
+
+func TestRedisSession(t *testing.T) {
+	ctx := context.Background()
+	client := mocks.NewRedisClient()
+	session := redis.NewSession(client, "session-1").WithMaxItems(3).WithTTL(time.Hour)
+
+	require.NoError(t, session.AddItems(ctx, []interface{}{"first", "second", "third", "fourth"}))
+
+	items, version, err := session.GetItemsWithVersion(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"second", "third", "fourth"}, items, "older items are trimmed")
+	assert.Equal(t, int64(1), version)
+	assert.Equal(t, time.Hour, client.TTL("agentsdk:session:session-1"))
+	assert.Equal(t, time.Hour, client.TTL("agentsdk:session:session-1:version"))
+
+	items, err = session.GetItems(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"fourth"}, items)
+
+	item, err := session.PopItem(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "fourth", item)
+
+	require.NoError(t, session.Clear(ctx))
+	item, err = session.PopItem(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, item)
+}
+
+func TestRedisSessionVersionConflict(t *testing.T) {
+	ctx := context.Background()
+	client := mocks.NewRedisClient()
+	first := redis.NewSession(client, "shared")
+	second := redis.NewSession(client, "shared")
+
+	_, seen, err := first.GetItemsWithVersion(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), seen)
+
+	version, err := second.AddItemsIfVersion(ctx, seen, []interface{}{"from second"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), version)
+
+	_, err = first.AddItemsIfVersion(ctx, seen, []interface{}{"from first"})
+	assert.ErrorIs(t, err, redis.ErrVersionConflict)
+
+	// After re-reading, the first writer sees the other item and can append
+	items, seen, err := first.GetItemsWithVersion(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"from second"}, items)
+	version, err = first.AddItemsIfVersion(ctx, seen, []interface{}{"from first"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), version)
+}
@@ -0,0 +1,45 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInMemorySession(t *testing.T) {
+	ctx := context.Background()
+	session := memory.NewInMemorySession("session-1")
+	assert.Equal(t, "session-1", session.ID())
+
+	// Add items
+	err := session.AddItems(ctx, []interface{}{"first", "second", "third"})
+	assert.NoError(t, err)
+
+	// Get all items
+	items, err := session.GetItems(ctx, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"first", "second", "third"}, items)
+
+	// Get the most recent items
+	items, err = session.GetItems(ctx, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"second", "third"}, items)
+
+	// Pop the most recent item
+	item, err := session.PopItem(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "third", item)
+
+	// Clear the session
+	assert.NoError(t, session.Clear(ctx))
+	items, err = session.GetItems(ctx, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, items)
+
+	// Popping from an empty session returns nil
+	item, err = session.PopItem(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, item)
+}
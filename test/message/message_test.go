@@ -0,0 +1,101 @@
+package message_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/message"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGenericItems(t *testing.T) {
+	tests := []struct {
+		name string
+		item interface{}
+		want message.Item
+	}{
+		{
+			name: "user message",
+			item: map[string]interface{}{"type": "message", "role": "user", "content": "hi"},
+			want: message.Message{Role: message.RoleUser, Content: "hi"},
+		},
+		{
+			name: "message without type",
+			item: map[string]interface{}{"role": "assistant", "content": "hello"},
+			want: message.Message{Role: message.RoleAssistant, Content: "hello"},
+		},
+		{
+			name: "assistant tool calls",
+			item: map[string]interface{}{"type": "message", "role": "assistant", "content": " ", "tool_calls": []interface{}{
+				map[string]interface{}{"id": "call_1", "type": "function", "function": map[string]interface{}{"name": "lookup", "arguments": `{"q":"go"}`}},
+			}},
+			want: message.Message{Role: message.RoleAssistant, Content: " ", ToolCalls: []message.ToolCall{
+				{ID: "call_1", Name: "lookup", Arguments: map[string]interface{}{"q": "go"}},
+			}},
+		},
+		{
+			name: "tool result",
+			item: map[string]interface{}{
+				"type":        "tool_result",
+				"tool_call":   map[string]interface{}{"id": "call_1", "name": "lookup"},
+				"tool_result": map[string]interface{}{"content": "found"},
+			},
+			want: message.ToolResult{Call: message.ToolCall{ID: "call_1", Name: "lookup"}, Content: "found"},
+		},
+		{
+			name: "OpenAI tool message",
+			item: map[string]interface{}{"role": "tool", "tool_call_id": "call_1", "content": "found"},
+			want: message.ToolResult{Call: message.ToolCall{ID: "call_1"}, Content: "found"},
+		},
+		{
+			name: "typed pointer",
+			item: &message.Message{Role: message.RoleUser, Content: "hi"},
+			want: message.Message{Role: message.RoleUser, Content: "hi"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := message.Parse(tt.item)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseRejectsUnknownItems(t *testing.T) {
+	for _, item := range []interface{}{
+		"text",
+		nil,
+		map[string]interface{}{"type": "handoff", "agent": "Writer"},
+		map[string]interface{}{"content": "no role"},
+		map[string]interface{}{"role": 42},
+	} {
+		_, ok := message.Parse(item)
+		assert.False(t, ok, "%#v", item)
+	}
+}
+
+func TestJSONKeepsGenericFormat(t *testing.T) {
+	history := []interface{}{
+		message.Message{Role: message.RoleUser, Content: "weather?"},
+		message.Message{Role: message.RoleAssistant, Content: " ", ToolCalls: []message.ToolCall{
+			{ID: "call_1", Name: "weather", Arguments: map[string]interface{}{"city": "Oslo"}},
+		}},
+		message.ToolResult{Call: message.ToolCall{ID: "call_1", Name: "weather", Arguments: map[string]interface{}{"city": "Oslo"}}, Content: "cold"},
+	}
+	data, err := json.Marshal(history)
+	require.NoError(t, err)
+
+	var decoded []interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "message", decoded[0].(map[string]interface{})["type"])
+	assert.Equal(t, "tool_result", decoded[2].(map[string]interface{})["type"])
+
+	// Decoded histories parse back to the same items
+	for i, item := range decoded {
+		parsed, ok := message.Parse(item)
+		require.True(t, ok)
+		assert.Equal(t, history[i], parsed)
+	}
+}
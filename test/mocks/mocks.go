@@ -2,8 +2,12 @@ package mocks
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"sync"
 
 	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -101,3 +105,133 @@ func (s *InMemoryStateStore) DeleteCheckpoint(workflowID string, checkpointID st
 	delete(s.states, workflowID)
 	return nil
 }
+
+// ScriptedModel is a model.Model that returns a fixed sequence of responses
+// and records every request it receives
+type ScriptedModel struct {
+	Responses []*model.Response
+	Requests  []*model.Request
+	mu        sync.Mutex
+}
+
+// NewScriptedModel creates a model that returns the given responses in order
+func NewScriptedModel(responses ...*model.Response) *ScriptedModel {
+	return &ScriptedModel{Responses: responses}
+}
+
+func (m *ScriptedModel) next(request *model.Request) (*model.Response, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Requests = append(m.Requests, request)
+	if len(m.Responses) == 0 {
+		return nil, errors.New("scripted model has no responses left")
+	}
+	resp := m.Responses[0]
+	m.Responses = m.Responses[1:]
+	return resp, nil
+}
+
+func (m *ScriptedModel) GetResponse(ctx context.Context, request *model.Request) (*model.Response, error) {
+	return m.next(request)
+}
+
+func (m *ScriptedModel) StreamResponse(ctx context.Context, request *model.Request) (<-chan model.StreamEvent, error) {
+	resp, err := m.next(request)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan model.StreamEvent, 2+len(resp.Media))
+	if resp.Content != "" {
+		ch <- model.StreamEvent{Type: model.StreamEventTypeContent, Content: resp.Content}
+	}
+	for i := range resp.Media {
+		ch <- model.StreamEvent{Type: model.StreamEventTypeMedia, Media: &resp.Media[i]}
+	}
+	ch <- model.StreamEvent{Type: model.StreamEventTypeDone, Response: resp}
+	close(ch)
+	return ch, nil
+}
+
+// RequestCount returns the number of requests the model has received
+func (m *ScriptedModel) RequestCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.Requests)
+}
+
+// Request returns the i-th request the model has received
+func (m *ScriptedModel) Request(i int) *model.Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Requests[i]
+}
+
+// InMemoryTaskStore implements runner.TaskStore in memory. Tasks are stored as
+// JSON so that loading them behaves like loading from a database.
+type InMemoryTaskStore struct {
+	tasks  map[string][]byte
+	chains map[string][]string
+	mu     sync.Mutex
+}
+
+func NewInMemoryTaskStore() *InMemoryTaskStore {
+	return &InMemoryTaskStore{
+		tasks:  make(map[string][]byte),
+		chains: make(map[string][]string),
+	}
+}
+
+func (s *InMemoryTaskStore) SaveTask(ctx context.Context, task *runner.TaskContext) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	s.tasks[task.TaskID] = data
+	return nil
+}
+
+func (s *InMemoryTaskStore) LoadTasks(ctx context.Context) ([]*runner.TaskContext, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tasks := make([]*runner.TaskContext, 0, len(s.tasks))
+	for _, data := range s.tasks {
+		task := &runner.TaskContext{}
+		if err := json.Unmarshal(data, task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+func (s *InMemoryTaskStore) DeleteTask(ctx context.Context, taskID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tasks, taskID)
+	return nil
+}
+
+func (s *InMemoryTaskStore) SaveDelegationChain(ctx context.Context, agentName string, chain []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(chain) == 0 {
+		delete(s.chains, agentName)
+		return nil
+	}
+	s.chains[agentName] = append([]string(nil), chain...)
+	return nil
+}
+
+func (s *InMemoryTaskStore) LoadDelegationChains(ctx context.Context) (map[string][]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chains := make(map[string][]string, len(s.chains))
+	for name, chain := range s.chains {
+		chains[name] = append([]string(nil), chain...)
+	}
+	return chains, nil
+}
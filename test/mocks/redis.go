@@ -0,0 +1,252 @@
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/memory/redis"
+)
+
+// RedisClient is an in-memory redis.Client. It understands the Lua scripts of
+// the redis package by the commands they run, and records the expiry set on
+// every key instead of expiring it.
+type RedisClient struct {
+	strings map[string]string
+	lists   map[string][]string
+	zsets   map[string]map[string]float64
+	ttls    map[string]time.Duration
+	mu      sync.Mutex
+}
+
+// Ensure RedisClient implements redis.Client
+var _ redis.Client = (*RedisClient)(nil)
+
+// NewRedisClient creates an empty in-memory Redis client
+func NewRedisClient() *RedisClient {
+	return &RedisClient{
+		strings: make(map[string]string),
+		lists:   make(map[string][]string),
+		zsets:   make(map[string]map[string]float64),
+		ttls:    make(map[string]time.Duration),
+	}
+}
+
+// TTL returns the expiry last set on key, or zero if it has none
+func (c *RedisClient) TTL(key string) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ttls[key]
+}
+
+// Keys returns all stored keys, sorted
+func (c *RedisClient) Keys() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var keys []string
+	for key := range c.strings {
+		keys = append(keys, key)
+	}
+	for key := range c.lists {
+		keys = append(keys, key)
+	}
+	for key := range c.zsets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *RedisClient) Get(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	value, ok := c.strings[key]
+	if !ok {
+		return "", redis.ErrNil
+	}
+	return value, nil
+}
+
+func (c *RedisClient) Set(ctx context.Context, key string, value string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.set(key, value, ttl)
+	return nil
+}
+
+func (c *RedisClient) Del(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.del(keys...)
+	return nil
+}
+
+func (c *RedisClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case strings.Contains(script, "INCRBY"):
+		return c.incr(keys, args), nil
+	case strings.Contains(script, "ZADD"):
+		return c.save(keys, args), nil
+	case strings.Contains(script, "ZREM"):
+		delete(c.zsets[keys[0]], fmt.Sprint(args[0]))
+		c.del(keys[1])
+		return int64(1), nil
+	case strings.Contains(script, "ZRANGE"):
+		return c.zrange(keys[0]), nil
+	case strings.Contains(script, "RPUSH"):
+		return c.appendItems(keys, args), nil
+	case strings.Contains(script, "LRANGE"):
+		return c.lrange(keys, args), nil
+	case strings.Contains(script, "RPOP"):
+		return c.pop(keys, args), nil
+	}
+	return nil, fmt.Errorf("mock redis: unsupported script %q", script)
+}
+
+// save emulates the record save script of redis.Store
+func (c *RedisClient) save(keys []string, args []interface{}) int64 {
+	var version int64
+	if current, ok := c.strings[keys[0]]; ok {
+		header, _, _ := strings.Cut(current, "\n")
+		version, _ = strconv.ParseInt(header, 10, 64)
+	}
+	if expected := toInt64(args[0]); expected >= 0 && expected != version {
+		return -1
+	}
+	version++
+
+	record := strconv.FormatInt(version, 10) + "\n" + fmt.Sprint(args[1])
+	ttl := time.Duration(toInt64(args[2])) * time.Millisecond
+	checkpointID := fmt.Sprint(args[3])
+	if checkpointID == "" {
+		checkpointID = strconv.FormatInt(version, 10)
+	}
+
+	c.set(keys[0], record, ttl)
+	c.set(keys[2]+checkpointID, record, ttl)
+	if c.zsets[keys[1]] == nil {
+		c.zsets[keys[1]] = make(map[string]float64)
+	}
+	c.zsets[keys[1]][checkpointID] = float64(version)
+	c.expire(keys[1], ttl)
+	return version
+}
+
+// incr emulates the counter script of redis.Counter
+func (c *RedisClient) incr(keys []string, args []interface{}) int64 {
+	value, _ := strconv.ParseInt(c.strings[keys[0]], 10, 64)
+	value += toInt64(args[0])
+	c.strings[keys[0]] = strconv.FormatInt(value, 10)
+	if _, expires := c.ttls[keys[0]]; !expires {
+		c.expire(keys[0], time.Duration(toInt64(args[1]))*time.Millisecond)
+	}
+	return value
+}
+
+// zrange returns the members of a sorted set by ascending score
+func (c *RedisClient) zrange(key string) []interface{} {
+	members := make([]string, 0, len(c.zsets[key]))
+	for member := range c.zsets[key] {
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return c.zsets[key][members[i]] < c.zsets[key][members[j]]
+	})
+
+	reply := make([]interface{}, len(members))
+	for i, member := range members {
+		reply[i] = member
+	}
+	return reply
+}
+
+// appendItems emulates the append script of redis.Session
+func (c *RedisClient) appendItems(keys []string, args []interface{}) int64 {
+	version, _ := strconv.ParseInt(c.strings[keys[1]], 10, 64)
+	if expected := toInt64(args[1]); expected >= 0 && expected != version {
+		return -1
+	}
+	for _, item := range args[3:] {
+		c.lists[keys[0]] = append(c.lists[keys[0]], fmt.Sprint(item))
+	}
+	if maxItems := int(toInt64(args[2])); maxItems > 0 && len(c.lists[keys[0]]) > maxItems {
+		c.lists[keys[0]] = c.lists[keys[0]][len(c.lists[keys[0]])-maxItems:]
+	}
+	version++
+	c.strings[keys[1]] = strconv.FormatInt(version, 10)
+
+	ttl := time.Duration(toInt64(args[0])) * time.Millisecond
+	c.expire(keys[0], ttl)
+	c.expire(keys[1], ttl)
+	return version
+}
+
+// lrange emulates the range script of redis.Session
+func (c *RedisClient) lrange(keys []string, args []interface{}) []interface{} {
+	items := c.lists[keys[0]]
+	if limit := int(toInt64(args[0])); limit > 0 && len(items) > limit {
+		items = items[len(items)-limit:]
+	}
+	ttl := time.Duration(toInt64(args[1])) * time.Millisecond
+	c.expire(keys[0], ttl)
+	c.expire(keys[1], ttl)
+
+	version, ok := c.strings[keys[1]]
+	if !ok {
+		version = "0"
+	}
+	reply := []interface{}{version}
+	for _, item := range items {
+		reply = append(reply, item)
+	}
+	return reply
+}
+
+// pop emulates the pop script of redis.Session
+func (c *RedisClient) pop(keys []string, args []interface{}) []interface{} {
+	items := c.lists[keys[0]]
+	if len(items) == 0 {
+		return []interface{}{}
+	}
+	item := items[len(items)-1]
+	c.lists[keys[0]] = items[:len(items)-1]
+
+	version, _ := strconv.ParseInt(c.strings[keys[1]], 10, 64)
+	c.strings[keys[1]] = strconv.FormatInt(version+1, 10)
+	c.expire(keys[1], time.Duration(toInt64(args[0]))*time.Millisecond)
+	return []interface{}{item}
+}
+
+func (c *RedisClient) set(key string, value string, ttl time.Duration) {
+	c.strings[key] = value
+	delete(c.ttls, key)
+	c.expire(key, ttl)
+}
+
+func (c *RedisClient) expire(key string, ttl time.Duration) {
+	if ttl > 0 {
+		c.ttls[key] = ttl
+	}
+}
+
+func (c *RedisClient) del(keys ...string) {
+	for _, key := range keys {
+		delete(c.strings, key)
+		delete(c.lists, key)
+		delete(c.zsets, key)
+		delete(c.ttls, key)
+	}
+}
+
+// toInt64 converts an Eval argument to an int64
+func toInt64(arg interface{}) int64 {
+	n, _ := strconv.ParseInt(fmt.Sprint(arg), 10, 64)
+	return n
+}
@@ -0,0 +1,195 @@
+package mocks
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// SQLResult is the answer to a statement: the rows of a query, or the number
+// of rows affected by any other statement
+type SQLResult struct {
+	Columns      []string
+	Rows         [][]driver.Value
+	RowsAffected int64
+}
+
+// SQLHandler answers a statement given its arguments
+type SQLHandler func(args []driver.Value) (*SQLResult, error)
+
+// SQLStatement is a statement received by an SQLDB
+type SQLStatement struct {
+	Query string
+	Args  []driver.Value
+}
+
+// SQLDB is a database/sql database for tests. It records every statement and
+// answers it with the first handler whose pattern the statement contains, or
+// with no rows if none does. Transactions are recorded as BEGIN, COMMIT and
+// ROLLBACK statements.
+type SQLDB struct {
+	*sql.DB
+	patterns   []string
+	handlers   []SQLHandler
+	statements []SQLStatement
+	mu         sync.Mutex
+}
+
+// NewSQLDB creates an SQLDB without handlers
+func NewSQLDB() *SQLDB {
+	db := &SQLDB{}
+	db.DB = sql.OpenDB(sqlConnector{db: db})
+	return db
+}
+
+// Handle answers the statements containing pattern with handler. Handlers run
+// one at a time, so they can keep state without locking.
+func (db *SQLDB) Handle(pattern string, handler SQLHandler) *SQLDB {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.patterns = append(db.patterns, pattern)
+	db.handlers = append(db.handlers, handler)
+	return db
+}
+
+// Statements returns the statements received so far that contain pattern
+func (db *SQLDB) Statements(pattern string) []SQLStatement {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var statements []SQLStatement
+	for _, stmt := range db.statements {
+		if strings.Contains(stmt.Query, pattern) {
+			statements = append(statements, stmt)
+		}
+	}
+	return statements
+}
+
+// run records a statement and answers it
+func (db *SQLDB) run(ctx context.Context, query string, args []driver.NamedValue) (*SQLResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.statements = append(db.statements, SQLStatement{Query: query, Args: values})
+	for i, pattern := range db.patterns {
+		if strings.Contains(query, pattern) {
+			return db.handlers[i](values)
+		}
+	}
+	return &SQLResult{}, nil
+}
+
+type sqlConnector struct {
+	db *SQLDB
+}
+
+func (c sqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return sqlConn(c), nil
+}
+
+func (c sqlConnector) Driver() driver.Driver {
+	return sqlDriver(c)
+}
+
+type sqlDriver struct {
+	db *SQLDB
+}
+
+func (d sqlDriver) Open(name string) (driver.Conn, error) {
+	return sqlConn(d), nil
+}
+
+type sqlConn struct {
+	db *SQLDB
+}
+
+func (c sqlConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("mock sql: prepared statements are not supported")
+}
+
+func (c sqlConn) Close() error {
+	return nil
+}
+
+func (c sqlConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c sqlConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if _, err := c.db.run(ctx, "BEGIN", nil); err != nil {
+		return nil, err
+	}
+	return sqlTx(c), nil
+}
+
+func (c sqlConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := c.db.run(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(res.RowsAffected), nil
+}
+
+func (c sqlConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	res, err := c.db.run(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlRows{result: res}, nil
+}
+
+type sqlTx struct {
+	db *SQLDB
+}
+
+func (t sqlTx) Commit() error {
+	_, err := t.db.run(context.Background(), "COMMIT", nil)
+	return err
+}
+
+func (t sqlTx) Rollback() error {
+	_, err := t.db.run(context.Background(), "ROLLBACK", nil)
+	return err
+}
+
+type sqlRows struct {
+	result *SQLResult
+	next   int
+}
+
+func (r *sqlRows) Columns() []string {
+	if r.result.Columns != nil || len(r.result.Rows) == 0 {
+		return r.result.Columns
+	}
+	columns := make([]string, len(r.result.Rows[0]))
+	for i := range columns {
+		columns[i] = fmt.Sprintf("column%d", i+1)
+	}
+	return columns
+}
+
+func (r *sqlRows) Close() error {
+	return nil
+}
+
+func (r *sqlRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.Rows) {
+		return io.EOF
+	}
+	copy(dest, r.result.Rows[r.next])
+	r.next++
+	return nil
+}
@@ -0,0 +1,82 @@
+package output_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/output"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mixedOutput = "Here is the function:\n\n```TS\nexport function add(a: number, b: number) {\n  return a + b;\n}\n```\n\nAnd a test:\n\n~~~js\nadd(1, 2);\n~~~\n"
+
+func TestCodeBlocks(t *testing.T) {
+	blocks := output.CodeBlocks(mixedOutput)
+	require.Len(t, blocks, 2)
+	assert.Equal(t, "typescript", blocks[0].Language)
+	assert.Equal(t, "export function add(a: number, b: number) {\n  return a + b;\n}", blocks[0].Code)
+	assert.True(t, blocks[0].Closed)
+	assert.Equal(t, "javascript", blocks[1].Language)
+	assert.Equal(t, "add(1, 2);", blocks[1].Code)
+	assert.Equal(t, "~~~js\nadd(1, 2);\n~~~", mixedOutput[blocks[1].Start:blocks[1].End])
+}
+
+func TestExtractCodeBlock(t *testing.T) {
+	ctx := context.Background()
+
+	code, err := output.ExtractCodeBlock("typescript")(ctx, mixedOutput)
+	require.NoError(t, err)
+	assert.Equal(t, "export function add(a: number, b: number) {\n  return a + b;\n}", code)
+
+	code, err = output.ExtractCodeBlock("javascript")(ctx, mixedOutput)
+	require.NoError(t, err)
+	assert.Equal(t, "add(1, 2);", code)
+
+	// Output without fences is the code itself
+	code, err = output.ExtractCodeBlock("go")(ctx, "  func main() {}\n")
+	require.NoError(t, err)
+	assert.Equal(t, "func main() {}", code)
+
+	_, err = output.ExtractCodeBlock("python")(ctx, mixedOutput)
+	assert.True(t, errors.Is(err, output.ErrNoCodeBlock))
+}
+
+func TestStripAndNormalizeFences(t *testing.T) {
+	ctx := context.Background()
+
+	stripped, err := output.StripFences()(ctx, "```json\n{\"a\": 1}\n```")
+	require.NoError(t, err)
+	assert.Equal(t, `{"a": 1}`, stripped)
+
+	// Languages are normalized and an unclosed block is closed
+	normalized, err := output.NormalizeFences()(ctx, "Result:\n````Golang\nfmt.Println(1)\n````\nDone\n```PY\nprint(1)")
+	require.NoError(t, err)
+	assert.Equal(t, "Result:\n```go\nfmt.Println(1)\n```\nDone\n```python\nprint(1)\n```", normalized)
+}
+
+func TestTrimXMLWrapper(t *testing.T) {
+	ctx := context.Background()
+
+	trimmed, err := output.TrimXMLWrapper()(ctx, "\n<answer>\n  <code>42</code>\n</answer>\n")
+	require.NoError(t, err)
+	assert.Equal(t, "42", trimmed)
+
+	// Only the given tags are removed
+	trimmed, err = output.TrimXMLWrapper("answer")(ctx, "<answer><code>42</code></answer>")
+	require.NoError(t, err)
+	assert.Equal(t, "<code>42</code>", trimmed)
+
+	// Sibling elements are not a wrapper
+	trimmed, err = output.TrimXMLWrapper()(ctx, "<a>1</a> and <a>2</a>")
+	require.NoError(t, err)
+	assert.Equal(t, "<a>1</a> and <a>2</a>", trimmed)
+}
+
+func TestApplyChainsProcessors(t *testing.T) {
+	text, err := output.Apply(context.Background(), "<result>\n```ts\nconst x = 1;\n```\n</result>",
+		output.TrimXMLWrapper("result"), output.ExtractCodeBlock("typescript"))
+	require.NoError(t, err)
+	assert.Equal(t, "const x = 1;", text)
+}
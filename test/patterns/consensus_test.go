@@ -0,0 +1,76 @@
+package patterns_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/patterns"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func answeringAgent(name, answer string) *agent.Agent {
+	return agent.NewAgent(name).WithModel(mocks.NewScriptedModel(&model.Response{Content: answer}))
+}
+
+func testOptions() *runner.RunOptions {
+	return &runner.RunOptions{
+		Input: "What is the capital of France?",
+		RunConfig: &runner.RunConfig{
+			ModelProvider:   &mocks.MockModelProvider{},
+			TracingDisabled: true,
+		},
+	}
+}
+
+func TestConsensusMajorityVote(t *testing.T) {
+	agents := []*agent.Agent{
+		answeringAgent("A", "Paris"),
+		answeringAgent("B", "paris."),
+		answeringAgent("C", "Lyon"),
+	}
+
+	res, err := patterns.Consensus(agents, patterns.MajorityVote(nil)).Run(context.Background(), runner.NewRunner(), testOptions())
+	assert.NoError(t, err)
+	assert.Equal(t, "Paris", res.Winner)
+	assert.Equal(t, []string{"A", "B"}, res.WinningAgents)
+	assert.InDelta(t, 2.0/3.0, res.Distribution["paris"], 0.001)
+	assert.InDelta(t, 1.0/3.0, res.Distribution["lyon"], 0.001)
+	assert.Len(t, res.Answers, 3)
+}
+
+func TestConsensusJudgeRanking(t *testing.T) {
+	agents := []*agent.Agent{
+		answeringAgent("A", "Lyon"),
+		answeringAgent("B", "Paris"),
+	}
+	judge := answeringAgent("Judge", "2, 1")
+
+	res, err := patterns.Consensus(agents, patterns.JudgeRanking(judge)).Run(context.Background(), runner.NewRunner(), testOptions())
+	assert.NoError(t, err)
+	assert.Equal(t, "Paris", res.Winner)
+	assert.Equal(t, []string{"B"}, res.WinningAgents)
+	assert.Equal(t, 1.0, res.Distribution["Paris"])
+	assert.Equal(t, 0.0, res.Distribution["Lyon"])
+}
+
+func TestConsensusScoreAveraging(t *testing.T) {
+	agents := []*agent.Agent{
+		answeringAgent("A", "short"),
+		answeringAgent("B", "a much longer answer"),
+	}
+	byLength := func(ctx context.Context, input interface{}, answer string) (float64, error) {
+		return float64(len(answer)), nil
+	}
+	constant := func(ctx context.Context, input interface{}, answer string) (float64, error) {
+		return 10, nil
+	}
+
+	res, err := patterns.Consensus(agents, patterns.ScoreAveraging(byLength, constant)).Run(context.Background(), runner.NewRunner(), testOptions())
+	assert.NoError(t, err)
+	assert.Equal(t, "a much longer answer", res.Winner)
+	assert.Equal(t, 7.5, res.Distribution["short"])
+}
@@ -0,0 +1,202 @@
+package policy_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/config"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/guardrail"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/policy"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const policies = `
+version: 1
+policies:
+  - name: default
+    guardrails:
+      input:
+        - type: pii
+          pii_types: [email]
+        - type: registered
+          name: company_rules
+      output:
+        - type: max_length
+          max_length: 100
+    tools:
+      deny: ["delete_*"]
+      require_approval: [deploy]
+      limits:
+        - tool: flaky
+          retries: 2
+          retry_backoff: 1ms
+    budget:
+      max_turns: 10
+      max_total_tokens: 5000
+  - name: untrusted
+    extends: default
+    tools:
+      allow: [search, flaky]
+    budget:
+      max_turns: 3
+`
+
+func newTool(name string, fn func() (interface{}, error)) tool.Tool {
+	return tool.NewFunctionTool(name, "test tool", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return fn()
+	})
+}
+
+func companyRules() *config.Registry {
+	return config.NewRegistry().WithInputGuardrails(guardrail.NewInputGuardrail("company_rules", func(ctx context.Context, input interface{}) (*guardrail.Result, error) {
+		return guardrail.Pass(), nil
+	}))
+}
+
+func toolNames(a *agent.Agent) []string {
+	names := make([]string, 0, len(a.Tools))
+	for _, t := range a.Tools {
+		names = append(names, t.GetName())
+	}
+	return names
+}
+
+func TestAgentAppliesToolRulesAndGuardrails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(policies), 0o600))
+	set, err := policy.Load(path, companyRules())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default", "untrusted"}, set.Names())
+
+	failures := 0
+	flaky := newTool("flaky", func() (interface{}, error) {
+		if failures++; failures < 3 {
+			return nil, assert.AnError
+		}
+		return "ok", nil
+	})
+	ok := func() (interface{}, error) { return "ok", nil }
+	a := agent.NewAgent("worker").WithTools(
+		newTool("search", ok), newTool("delete_user", ok), newTool("deploy", ok), flaky,
+	)
+
+	base, err := set.Agent("default", a)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"search", "deploy", "flaky"}, toolNames(base))
+	assert.Len(t, base.InputGuardrails, 2)
+	assert.Len(t, base.OutputGuardrails, 1)
+	assert.Len(t, a.Tools, 4, "the original agent is untouched")
+	assert.Empty(t, a.InputGuardrails)
+
+	untrusted, err := set.Agent("untrusted", a)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"search", "flaky"}, toolNames(untrusted))
+	assert.Len(t, untrusted.InputGuardrails, 2, "guardrails are inherited")
+
+	res, err := untrusted.Tools[1].Execute(context.Background(), map[string]interface{}{})
+	require.NoError(t, err, "the tool limit retries the flaky tool")
+	assert.Equal(t, "ok", res)
+	assert.Equal(t, 3, failures)
+
+	_, err = set.Agent("missing", a)
+	assert.ErrorIs(t, err, policy.ErrPolicyNotFound)
+}
+
+func TestRunOptionsApplyBudgetAndApprovals(t *testing.T) {
+	set, err := policy.Parse([]byte(policies), companyRules())
+	require.NoError(t, err)
+
+	opts, err := set.RunOptions("untrusted", &runner.RunOptions{
+		MaxTurns:  20,
+		RunConfig: &runner.RunConfig{MaxTotalTokens: 1000, ApprovalPolicy: runner.RequireApproval("billing")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, opts.MaxTurns, "the stricter turn limit wins")
+	assert.Equal(t, 1000, opts.RunConfig.MaxTotalTokens, "stricter existing limits are kept")
+	assert.Len(t, opts.RunConfig.InputGuardrails, 2)
+
+	approval := opts.RunConfig.ApprovalPolicy
+	ctx := context.Background()
+	assert.True(t, approval.RequiresApproval(ctx, &runner.ApprovalRequest{Kind: runner.ApprovalKindToolCall, ToolName: "deploy"}))
+	assert.True(t, approval.RequiresApproval(ctx, &runner.ApprovalRequest{Kind: runner.ApprovalKindHandoff, TargetAgent: "billing"}))
+	assert.False(t, approval.RequiresApproval(ctx, &runner.ApprovalRequest{Kind: runner.ApprovalKindToolCall, ToolName: "search"}))
+}
+
+func TestPolicyGuardrailsTripRuns(t *testing.T) {
+	set, err := policy.Parse([]byte(policies), companyRules())
+	require.NoError(t, err)
+	m := mocks.NewScriptedModel(&model.Response{Content: "unused"})
+	a, err := set.Agent("default", agent.NewAgent("worker").WithModel(m))
+	require.NoError(t, err)
+
+	_, err = runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{
+		Input:     "mail me at jane@example.com",
+		RunConfig: &runner.RunConfig{ModelProvider: &mocks.MockModelProvider{}, TracingDisabled: true},
+	})
+	var tripped *guardrail.GuardrailTripped
+	require.ErrorAs(t, err, &tripped)
+	assert.Equal(t, "pii", tripped.Guardrail)
+	assert.Equal(t, 0, m.RequestCount())
+}
+
+func TestLintReportsEveryProblem(t *testing.T) {
+	problems := policy.Lint([]byte(`
+policies:
+  - name: a
+    extends: b
+    guardrails:
+      input:
+        - type: regex
+          patterns: ["("]
+        - type: registered
+          name: checked_at_load_time
+    tools:
+      limits:
+        - tool: x
+          rate_limit: {requests: 0, per: 1m}
+  - name: c
+    extends: c
+`))
+	assert.Len(t, problems, 4)
+	for _, problem := range []string{`undefined policy "b"`, `invalid pattern "("`, "rate limit", `"c" extends itself`} {
+		assert.Contains(t, strings.Join(problems, "\n"), problem)
+	}
+
+	assert.NotEmpty(t, policy.Lint([]byte("policies:\n  - name: a\n    budgte: {}\n")))
+
+	_, err := policy.Parse([]byte("policies:\n  - name: a\n    guardrails:\n      input:\n        - {type: registered, name: nope}\n"), nil)
+	require.ErrorIs(t, err, policy.ErrInvalidPolicy)
+	assert.Contains(t, err.Error(), `"nope" is not registered`)
+}
+
+func TestConfigDocumentsReferencePolicies(t *testing.T) {
+	set, err := policy.Parse([]byte(policies), companyRules())
+	require.NoError(t, err)
+	search := newTool("search", func() (interface{}, error) { return "ok", nil })
+	remove := newTool("delete_user", func() (interface{}, error) { return "ok", nil })
+
+	c, err := config.Parse([]byte(`
+agents:
+  - name: worker
+    tools: [search, delete_user]
+    policy: default
+runner:
+  policy: untrusted
+`), companyRules().WithTools(search, remove).WithPolicies(set))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"search"}, toolNames(c.Entry))
+	assert.Equal(t, 3, c.MaxTurns)
+	assert.NotNil(t, c.RunConfig.ApprovalPolicy)
+
+	_, err = config.Parse([]byte("agents:\n  - name: a\n    policy: missing\n"), config.NewRegistry().WithPolicies(set))
+	assert.ErrorIs(t, err, config.ErrInvalidConfig)
+}
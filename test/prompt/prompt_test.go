@@ -0,0 +1,96 @@
+package prompt_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/prompt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderAppliesDefaultsAndPartials(t *testing.T) {
+	lib := prompt.NewLibrary()
+	require.NoError(t, lib.AddPartial("safety", "Never share {{.secret_kind}}."))
+	support, err := lib.Add("support",
+		`You support {{.company}} in a {{.tone}} tone. {{template "safety" .}}`,
+		prompt.Variable{Name: "company", Required: true},
+		prompt.Variable{Name: "tone", Default: "friendly"},
+		prompt.Variable{Name: "secret_kind", Default: "passwords"},
+	)
+	require.NoError(t, err)
+
+	text, err := support.Render(map[string]interface{}{"company": "Acme"})
+	require.NoError(t, err)
+	assert.Equal(t, "You support Acme in a friendly tone. Never share passwords.", text)
+
+	// Partials changed later are picked up by existing templates
+	require.NoError(t, lib.AddPartial("safety", "Stay safe."))
+	text, err = lib.Render("support", map[string]interface{}{"company": "Acme", "tone": "formal"})
+	require.NoError(t, err)
+	assert.Equal(t, "You support Acme in a formal tone. Stay safe.", text)
+}
+
+func TestMissingVariablesFail(t *testing.T) {
+	declared, err := prompt.New("greeting", "Hello {{.name}}", prompt.Variable{Name: "name", Required: true})
+	require.NoError(t, err)
+	_, err = declared.Render(nil)
+	assert.ErrorIs(t, err, prompt.ErrMissingVariable)
+	assert.Contains(t, err.Error(), "name")
+
+	undeclared, err := prompt.New("farewell", "Bye {{.name}}")
+	require.NoError(t, err)
+	_, err = undeclared.Render(map[string]interface{}{})
+	assert.ErrorIs(t, err, prompt.ErrMissingVariable)
+
+	_, err = prompt.New("broken", "{{.name")
+	assert.Error(t, err)
+}
+
+func TestLoadFSReadsFrontMatterAndPartials(t *testing.T) {
+	fsys := fstest.MapFS{
+		"prompts/_signature.tmpl": {Data: []byte("-- {{.team}}")},
+		"prompts/agents/coder.tmpl": {Data: []byte(`---
+description: Instructions of the coder
+variables:
+  - name: language
+    required: true
+  - name: team
+    default: Platform
+---
+Write idiomatic {{.language | upper}}.
+{{template "signature" .}}`)},
+		"prompts/README.md": {Data: []byte("not a template")},
+	}
+
+	lib := prompt.NewLibrary()
+	require.NoError(t, lib.LoadFS(fsys, "prompts"))
+	assert.Equal(t, []string{"agents/coder"}, lib.Names())
+
+	coder, err := lib.Get("agents/coder")
+	require.NoError(t, err)
+	assert.Equal(t, "Instructions of the coder", coder.Description())
+	assert.Equal(t, []prompt.Variable{{Name: "language", Required: true}, {Name: "team", Default: "Platform"}}, coder.Variables())
+
+	text, err := coder.Render(map[string]interface{}{"language": "go"})
+	require.NoError(t, err)
+	assert.Equal(t, "Write idiomatic GO.\n-- Platform", text)
+
+	_, err = lib.Get("agents/missing")
+	assert.ErrorIs(t, err, prompt.ErrTemplateNotFound)
+}
+
+func TestInstructionsRenderRunContext(t *testing.T) {
+	tmpl, err := prompt.New("turns", "Turn {{.run.Turn}} for {{.user}}", prompt.Variable{Name: "user", Required: true})
+	require.NoError(t, err)
+
+	_, err = tmpl.Instructions(nil)
+	assert.ErrorIs(t, err, prompt.ErrMissingVariable)
+
+	instructions, err := tmpl.Instructions(map[string]interface{}{"user": "ada"})
+	require.NoError(t, err)
+	a := agent.New("Assistant", agent.WithInstructionsFunc(instructions))
+	assert.Equal(t, "Turn 3 for ada", a.ResolveInstructions(context.Background(), agent.RunContext{Turn: 3}))
+}
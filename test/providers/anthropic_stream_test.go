@@ -0,0 +1,105 @@
+package providers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamEvents starts a server that streams the given events
+func streamEvents(t *testing.T, events ...string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range events {
+			var payload struct {
+				Type string `json:"type"`
+			}
+			require.NoError(t, json.Unmarshal([]byte(event), &payload))
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", payload.Type, event)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// collect reads a stream to its end
+func collect(t *testing.T, events <-chan model.StreamEvent) []model.StreamEvent {
+	var collected []model.StreamEvent
+	for event := range events {
+		require.NoError(t, event.Error)
+		collected = append(collected, event)
+	}
+	return collected
+}
+
+func TestAnthropicStreamsToolUseBlocks(t *testing.T) {
+	server := streamEvents(t,
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"usage":{"input_tokens":10,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Checking both."}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"weather","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":""}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\": \"Os"}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"lo\"}"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_2","name":"clock","input":{}}}`,
+		`{"type":"content_block_stop","index":2}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"output_tokens":42}}`,
+		`{"type":"message_stop"}`,
+	)
+	m := anthropicModel(t, server.URL)
+
+	stream, err := m.StreamResponse(context.Background(), &model.Request{Input: "weather and time in Oslo?"})
+	require.NoError(t, err)
+	events := collect(t, stream)
+
+	var calls []*model.ToolCall
+	for _, event := range events {
+		if event.Type == model.StreamEventTypeToolCall {
+			calls = append(calls, event.ToolCall)
+		}
+	}
+	require.Len(t, calls, 2)
+	assert.Equal(t, "toolu_1", calls[0].ID)
+	assert.Equal(t, map[string]interface{}{"city": "Oslo"}, calls[0].Parameters)
+	assert.Equal(t, "clock", calls[1].Name)
+	assert.Equal(t, map[string]interface{}{}, calls[1].Parameters)
+
+	done := events[len(events)-1]
+	require.Equal(t, model.StreamEventTypeDone, done.Type)
+	assert.True(t, done.Done)
+	assert.Equal(t, "tool_use", done.Response.StopReason)
+	assert.Equal(t, "Checking both.", done.Response.Content)
+	assert.Len(t, done.Response.ToolCalls, 2)
+	assert.Equal(t, 42, done.Response.Usage.CompletionTokens)
+}
+
+func TestAnthropicReportsStopReason(t *testing.T) {
+	server := streamEvents(t,
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"usage":{"input_tokens":10,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Once upon"}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"max_tokens","stop_sequence":null},"usage":{"output_tokens":2}}`,
+		`{"type":"message_stop"}`,
+	)
+	m := anthropicModel(t, server.URL)
+
+	stream, err := m.StreamResponse(context.Background(), &model.Request{Input: "tell a story"})
+	require.NoError(t, err)
+	events := collect(t, stream)
+	assert.Equal(t, "max_tokens", events[len(events)-1].Response.StopReason)
+
+	replies, _ := captureMessages(t, `{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"Once upon"}],"stop_reason":"max_tokens","usage":{"input_tokens":1,"output_tokens":2}}`)
+	res, err := anthropicModel(t, replies.URL).GetResponse(context.Background(), &model.Request{Input: "tell a story"})
+	require.NoError(t, err)
+	assert.Equal(t, "max_tokens", res.StopReason)
+}
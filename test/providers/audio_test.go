@@ -0,0 +1,91 @@
+package providers_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAITranscribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/audio/transcriptions", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		assert.Equal(t, "whisper-1", r.FormValue("model"))
+		assert.Equal(t, "verbose_json", r.FormValue("response_format"))
+		assert.Equal(t, "sv", r.FormValue("language"))
+
+		file, header, err := r.FormFile("file")
+		require.NoError(t, err)
+		data, _ := io.ReadAll(file)
+		assert.Equal(t, "note.mp3", header.Filename)
+		assert.Equal(t, []byte("ID3"), data)
+
+		w.Write([]byte(`{"text":"Hej hej","language":"swedish","duration":1.5}`))
+	}))
+	defer server.Close()
+
+	provider := openai.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+	var audio model.AudioProvider = provider
+
+	transcription, err := audio.Transcribe(context.Background(), &model.TranscriptionRequest{
+		Audio:    []byte("ID3"),
+		Filename: "note.mp3",
+		Language: "sv",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Hej hej", transcription.Text)
+	assert.Equal(t, "swedish", transcription.Language)
+	assert.Equal(t, 1500*time.Millisecond, transcription.Duration)
+}
+
+func TestOpenAISynthesize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/audio/speech", r.URL.Path)
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]interface{}{
+			"model":           "tts-1",
+			"input":           "Your order has shipped",
+			"voice":           "nova",
+			"response_format": "wav",
+		}, body)
+
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write([]byte("RIFF"))
+	}))
+	defer server.Close()
+
+	provider := openai.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+
+	speech, err := provider.Synthesize(context.Background(), &model.SpeechRequest{Text: "Your order has shipped", Voice: "nova", Format: "wav"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("RIFF"), speech.Audio)
+	assert.Equal(t, "audio/wav", speech.MediaType)
+}
+
+func TestOpenAIAudioErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"type":"invalid_request_error","message":"Invalid file format."}}`))
+	}))
+	defer server.Close()
+
+	provider := openai.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+
+	_, err := provider.Transcribe(context.Background(), &model.TranscriptionRequest{Audio: []byte("x")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid file format.")
+}
@@ -0,0 +1,109 @@
+package providers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/anthropic"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/openai"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// errorServer responds to every request with a status, headers and body
+func errorServer(t *testing.T, status int, header map[string]string, body string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for k, v := range header {
+			w.Header().Set(k, v)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func openAIError(t *testing.T, status int, header map[string]string, body string) error {
+	provider := openai.NewProvider("test-key").WithRetryConfig(0, time.Millisecond)
+	provider.SetBaseURL(errorServer(t, status, header, body).URL)
+	m, err := provider.GetModel("gpt-4o")
+	require.NoError(t, err)
+	_, err = m.GetResponse(context.Background(), &model.Request{Input: "hi"})
+	require.Error(t, err)
+	return err
+}
+
+func anthropicError(t *testing.T, status int, body string) error {
+	provider := anthropic.NewProvider("test-key").WithRetryConfig(0, time.Millisecond)
+	provider.SetBaseURL(errorServer(t, status, map[string]string{"request-id": "req_1"}, body).URL)
+	m, err := provider.GetModel("claude-3-5-sonnet-latest")
+	require.NoError(t, err)
+	_, err = m.GetResponse(context.Background(), &model.Request{Input: "hi"})
+	require.Error(t, err)
+	return err
+}
+
+func TestProviderErrorIsTyped(t *testing.T) {
+	err := openAIError(t, http.StatusUnauthorized, map[string]string{"x-request-id": "req_42"},
+		`{"error":{"message":"Incorrect API key","type":"invalid_request_error","code":"invalid_api_key"}}`)
+
+	var providerErr *model.ErrProvider
+	require.True(t, errors.As(err, &providerErr), "got %v", err)
+	assert.Equal(t, "openai", providerErr.Provider)
+	assert.Equal(t, http.StatusUnauthorized, providerErr.Status)
+	assert.Equal(t, "invalid_api_key", providerErr.Code)
+	assert.Equal(t, "req_42", providerErr.RequestID)
+	assert.Contains(t, err.Error(), "Incorrect API key")
+
+	var rateErr *model.ErrRateLimited
+	assert.False(t, errors.As(err, &rateErr))
+	assert.NotErrorIs(t, err, model.ErrContextWindowExceeded)
+}
+
+func TestRateLimitedErrorCarriesRetryAfter(t *testing.T) {
+	err := openAIError(t, http.StatusTooManyRequests, map[string]string{"retry-after": "20"},
+		`{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`)
+
+	var rateErr *model.ErrRateLimited
+	require.True(t, errors.As(err, &rateErr), "got %v", err)
+	assert.InDelta(t, 20*time.Second, rateErr.RetryAfter, float64(time.Second))
+	assert.True(t, ratelimit.IsRateLimited(err))
+
+	var providerErr *model.ErrProvider
+	require.True(t, errors.As(err, &providerErr))
+	assert.Equal(t, "rate_limit_exceeded", providerErr.Code)
+}
+
+func TestContextLengthExceededError(t *testing.T) {
+	errs := map[string]error{
+		"openai": openAIError(t, http.StatusBadRequest, nil,
+			`{"error":{"message":"This model's maximum context length is 128000 tokens.","type":"invalid_request_error","code":"context_length_exceeded"}}`),
+		"anthropic": anthropicError(t, http.StatusBadRequest,
+			`{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`),
+	}
+	for name, err := range errs {
+		t.Run(name, func(t *testing.T) {
+			var lengthErr *model.ErrContextLengthExceeded
+			require.True(t, errors.As(err, &lengthErr), "got %v", err)
+			assert.ErrorIs(t, err, model.ErrContextWindowExceeded)
+
+			var providerErr *model.ErrProvider
+			require.True(t, errors.As(err, &providerErr))
+			assert.Equal(t, name, providerErr.Provider)
+			assert.Equal(t, http.StatusBadRequest, providerErr.Status)
+		})
+	}
+}
+
+func TestContextWindowCheckIsContextLengthExceeded(t *testing.T) {
+	var lengthErr *model.ErrContextLengthExceeded
+	err := model.CheckContextWindow("gpt-4", &model.Request{Input: string(make([]byte, 100000))})
+	require.True(t, errors.As(err, &lengthErr))
+	assert.Equal(t, "gpt-4", lengthErr.Model)
+}
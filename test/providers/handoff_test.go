@@ -0,0 +1,73 @@
+package providers_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// DeployRequest is the typed input of a deployer
+type DeployRequest struct {
+	Service string `json:"service" doc:"Service to deploy"`
+	Version string `json:"version" doc:"Version to deploy"`
+}
+
+func TestAnthropicTypedHandoff(t *testing.T) {
+	server, body := captureBody(t, `{"id":"msg_1","type":"message","role":"assistant","content":[
+		{"type":"tool_use","id":"call_1","name":"handoff_to_Deployer","input":{"service":"api","version":"1.4.0","task_id":"t-1"}}
+	],"stop_reason":"tool_use","usage":{"input_tokens":1,"output_tokens":1}}`)
+	m := anthropicModel(t, server.URL)
+	deployer := agent.New("Deployer", agent.WithHandoffInputType(DeployRequest{}))
+
+	response, err := m.GetResponse(context.Background(), &model.Request{
+		Input:    "ship api 1.4.0",
+		Handoffs: []interface{}{agent.HandoffSchema(deployer)},
+	})
+	require.NoError(t, err)
+
+	// Claude sees the declared schema next to the arguments configuring the handoff
+	tools := (*body)["tools"].([]interface{})
+	require.Len(t, tools, 1)
+	schema := tools[0].(map[string]interface{})["input_schema"].(map[string]interface{})
+	properties := schema["properties"].(map[string]interface{})
+	assert.Contains(t, properties, "service")
+	assert.Contains(t, properties, "version")
+	assert.Contains(t, properties, "task_id")
+	assert.NotContains(t, properties, "input")
+	assert.Equal(t, []interface{}{"service", "version"}, schema["required"])
+	assert.NotContains(t, deployer.HandoffInputSchema()["properties"], "task_id", "the declared schema is not changed")
+
+	// The arguments reach the handoff as an object the target can decode
+	require.NotNil(t, response.HandoffCall)
+	assert.Equal(t, "Deployer", response.HandoffCall.AgentName)
+	assert.Equal(t, "t-1", response.HandoffCall.TaskID)
+	input := response.HandoffCall.Parameters["input"]
+	assert.Equal(t, map[string]interface{}{"service": "api", "version": "1.4.0"}, input)
+	decoded, err := deployer.DecodeHandoffInput(input)
+	require.NoError(t, err)
+	assert.Equal(t, DeployRequest{Service: "api", Version: "1.4.0"}, decoded)
+}
+
+func TestAnthropicUntypedHandoffTakesInputString(t *testing.T) {
+	server, body := captureBody(t, `{"id":"msg_1","type":"message","role":"assistant","content":[
+		{"type":"tool_use","id":"call_1","name":"handoff_to_Writer","input":{"input":"draft the release notes"}}
+	],"stop_reason":"tool_use","usage":{"input_tokens":1,"output_tokens":1}}`)
+	m := anthropicModel(t, server.URL)
+
+	response, err := m.GetResponse(context.Background(), &model.Request{
+		Input:    "write release notes",
+		Handoffs: []interface{}{agent.HandoffSchema(agent.NewAgent("Writer"))},
+	})
+	require.NoError(t, err)
+
+	schema := (*body)["tools"].([]interface{})[0].(map[string]interface{})["input_schema"].(map[string]interface{})
+	assert.Contains(t, schema["properties"], "input")
+	assert.Equal(t, []interface{}{"input"}, schema["required"])
+
+	require.NotNil(t, response.HandoffCall)
+	assert.Equal(t, "draft the release notes", response.HandoffCall.Parameters["input"])
+}
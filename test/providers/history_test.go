@@ -0,0 +1,195 @@
+package providers_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/message"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/anthropic"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAITranslatesTypedHistory(t *testing.T) {
+	server, messages := captureMessages(t, chatCompletion)
+	provider := openai.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+	m, err := provider.GetModel("gpt-4o")
+	require.NoError(t, err)
+
+	call := message.ToolCall{ID: "call_1", Name: "weather", Arguments: map[string]interface{}{"city": "Oslo"}}
+	_, err = m.GetResponse(context.Background(), &model.Request{Input: []interface{}{
+		message.Message{Role: message.RoleUser, Content: "weather in Oslo?"},
+		message.Message{Role: message.RoleAssistant, Content: " ", ToolCalls: []message.ToolCall{call}},
+		message.ToolResult{Call: call, Content: map[string]interface{}{"celsius": -3}},
+	}})
+	require.NoError(t, err)
+
+	require.Len(t, *messages, 3)
+	toolCalls := (*messages)[1]["tool_calls"].([]interface{})
+	require.Len(t, toolCalls, 1)
+	assert.Equal(t, map[string]interface{}{
+		"id":       "call_1",
+		"type":     "function",
+		"function": map[string]interface{}{"name": "weather", "arguments": `{"city":"Oslo"}`},
+	}, toolCalls[0])
+	assert.Equal(t, "tool", (*messages)[2]["role"])
+	assert.Equal(t, "call_1", (*messages)[2]["tool_call_id"])
+	assert.Equal(t, `{"celsius":-3}`, (*messages)[2]["content"])
+}
+
+// anthropicReply is a minimal Anthropic messages response
+const anthropicReply = `{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`
+
+// anthropicModel returns a Claude model sending its requests to the server
+func anthropicModel(t *testing.T, serverURL string) model.Model {
+	provider := anthropic.NewProvider("test-key")
+	provider.SetBaseURL(serverURL)
+	m, err := provider.GetModel("claude-3-haiku-20240307")
+	require.NoError(t, err)
+	return m
+}
+
+func TestAnthropicSendsToolUseAndToolResultBlocks(t *testing.T) {
+	server, messages := captureMessages(t, anthropicReply)
+	m := anthropicModel(t, server.URL)
+
+	weather := message.ToolCall{ID: "call_1", Name: "weather", Arguments: map[string]interface{}{"city": "Oslo"}}
+	clock := message.ToolCall{ID: "call_2", Name: "clock"}
+	formatter := model.FormatterFor(m)
+	_, err := m.GetResponse(context.Background(), &model.Request{Input: []interface{}{
+		message.Message{Role: message.RoleUser, Content: "weather and time in Oslo?"},
+		message.Message{Role: message.RoleAssistant, Content: "Let me check.", ToolCalls: []message.ToolCall{weather, clock}},
+		formatter.FormatToolResult(model.ToolCall{ID: "call_1", Name: "weather"}, map[string]interface{}{"celsius": -3}),
+		formatter.FormatToolResult(model.ToolCall{ID: "call_2", Name: "clock"}, "12:00"),
+	}})
+	require.NoError(t, err)
+
+	require.Len(t, *messages, 3)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"type": "text", "text": "Let me check."},
+		map[string]interface{}{"type": "tool_use", "id": "call_1", "name": "weather", "input": map[string]interface{}{"city": "Oslo"}},
+		map[string]interface{}{"type": "tool_use", "id": "call_2", "name": "clock", "input": map[string]interface{}{}},
+	}, (*messages)[1]["content"])
+
+	// The results of parallel calls share one user message
+	assert.Equal(t, "user", (*messages)[2]["role"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"type": "tool_result", "tool_use_id": "call_1", "content": []interface{}{
+			map[string]interface{}{"type": "text", "text": `{"celsius":-3}`},
+		}},
+		map[string]interface{}{"type": "tool_result", "tool_use_id": "call_2", "content": []interface{}{
+			map[string]interface{}{"type": "text", "text": "12:00"},
+		}},
+	}, (*messages)[2]["content"])
+}
+
+func TestAnthropicSendsToolResultsBeforeUserText(t *testing.T) {
+	server, messages := captureMessages(t, anthropicReply)
+	m := anthropicModel(t, server.URL)
+
+	weather := message.ToolCall{ID: "call_1", Name: "weather"}
+	clock := message.ToolCall{ID: "call_2", Name: "clock"}
+	formatter := model.FormatterFor(m)
+	_, err := m.GetResponse(context.Background(), &model.Request{Input: []interface{}{
+		message.Message{Role: message.RoleUser, Content: "weather and time in Oslo?"},
+		message.Message{Role: message.RoleAssistant, ToolCalls: []message.ToolCall{weather, clock}},
+		formatter.FormatToolResult(model.ToolCall{ID: "call_1", Name: "weather"}, "cold"),
+		formatter.FormatUserMessage("Be brief."),
+		formatter.FormatToolResult(model.ToolCall{ID: "call_2", Name: "clock"}, "12:00"),
+		formatter.FormatUserMessage("Now answer my question."),
+	}})
+	require.NoError(t, err)
+
+	require.Len(t, *messages, 3)
+	var types []interface{}
+	for _, block := range (*messages)[2]["content"].([]interface{}) {
+		types = append(types, block.(map[string]interface{})["type"])
+	}
+	assert.Equal(t, []interface{}{"tool_result", "tool_result", "text", "text"}, types)
+	content := (*messages)[2]["content"].([]interface{})
+	assert.Equal(t, "call_1", content[0].(map[string]interface{})["tool_use_id"])
+	assert.Equal(t, "call_2", content[1].(map[string]interface{})["tool_use_id"])
+	assert.Equal(t, "Be brief.", content[2].(map[string]interface{})["text"])
+	assert.Equal(t, "Now answer my question.", content[3].(map[string]interface{})["text"])
+}
+
+func TestAnthropicSendsImageToolResults(t *testing.T) {
+	server, messages := captureMessages(t, anthropicReply)
+	m := anthropicModel(t, server.URL)
+
+	call := message.ToolCall{ID: "call_1", Name: "screenshot"}
+	_, err := m.GetResponse(context.Background(), &model.Request{Input: []interface{}{
+		message.Message{Role: message.RoleUser, Content: "what is on screen?"},
+		message.Message{Role: message.RoleAssistant, Content: " ", ToolCalls: []message.ToolCall{call}},
+		model.FormatterFor(m).FormatToolResult(model.ToolCall{ID: "call_1", Name: "screenshot"}, []model.ContentPart{model.ImagePart(pngBytes, "image/png")}),
+	}})
+	require.NoError(t, err)
+
+	require.Len(t, *messages, 3)
+	result := (*messages)[2]["content"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "tool_result", result["type"])
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"type":   "image",
+		"source": map[string]interface{}{"type": "base64", "media_type": "image/png", "data": "iVBORw=="},
+	}}, result["content"])
+}
+
+func TestAnthropicSendsUnmatchedToolResultsAsText(t *testing.T) {
+	server, messages := captureMessages(t, anthropicReply)
+	m := anthropicModel(t, server.URL)
+
+	_, err := m.GetResponse(context.Background(), &model.Request{Input: []interface{}{
+		message.Message{Role: message.RoleUser, Content: "summarize"},
+		message.ToolResult{Call: message.ToolCall{Name: "search"}, Content: "three hits"},
+	}})
+	require.NoError(t, err)
+
+	require.Len(t, *messages, 1)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"type": "text", "text": "summarize"},
+		map[string]interface{}{"type": "text", "text": "Result of tool search: three hits"},
+	}, (*messages)[0]["content"])
+}
+
+func TestAnthropicHistoryTrimKeepsUserTextOfOrphanedToolResults(t *testing.T) {
+	server, messages := captureMessages(t, anthropicReply)
+	provider := anthropic.NewProvider("test-key").WithMaxHistoryMessages(3)
+	provider.SetBaseURL(server.URL)
+	m, err := provider.GetModel("claude-3-haiku-20240307")
+	require.NoError(t, err)
+
+	formatter := model.FormatterFor(m)
+	input := []interface{}{
+		message.Message{Role: message.RoleUser, Content: "weather in Oslo?"},
+		message.Message{Role: message.RoleAssistant, ToolCalls: []message.ToolCall{{ID: "call_1", Name: "weather"}}},
+		formatter.FormatToolResult(model.ToolCall{ID: "call_1", Name: "weather"}, "cold"),
+		formatter.FormatUserMessage("Answer in Celsius."),
+		message.Message{Role: message.RoleAssistant, Content: "It is -3 degrees."},
+		formatter.FormatUserMessage("Thanks!"),
+	}
+	_, err = m.GetResponse(context.Background(), &model.Request{Input: input})
+	require.NoError(t, err)
+
+	// The tool result lost its tool_use to the trim, the user text of its message stays
+	require.Len(t, *messages, 3)
+	assert.Equal(t, "user", (*messages)[0]["role"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"type": "text", "text": "Answer in Celsius."},
+	}, (*messages)[0]["content"])
+
+	// A message holding nothing but orphaned tool results is dropped
+	*messages = nil
+	_, err = m.GetResponse(context.Background(), &model.Request{Input: []interface{}{
+		message.Message{Role: message.RoleUser, Content: "weather in Oslo?"},
+		message.Message{Role: message.RoleAssistant, ToolCalls: []message.ToolCall{{ID: "call_1", Name: "weather"}}},
+		formatter.FormatToolResult(model.ToolCall{ID: "call_1", Name: "weather"}, "cold"),
+		message.Message{Role: message.RoleAssistant, Content: "It is cold."},
+		formatter.FormatUserMessage("Thanks!"),
+	}})
+	require.NoError(t, err)
+	require.Len(t, *messages, 2)
+	assert.Equal(t, "assistant", (*messages)[0]["role"])
+}
@@ -0,0 +1,195 @@
+package providers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/anthropic"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/lmstudio"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// modelsServer serves a models endpoint with the given bodies by path and raw
+// query, and counts the requests it received
+func modelsServer(t *testing.T, pages map[string]string) (*httptest.Server, *int32) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		key := r.URL.Path
+		if r.URL.RawQuery != "" {
+			key += "?" + r.URL.RawQuery
+		}
+		body, ok := pages[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+const openAIModels = `{"object":"list","data":[
+	{"id":"gpt-4o","object":"model","created":1715367049,"owned_by":"system"},
+	{"id":"gpt-4o-mini","object":"model","created":1721172741,"owned_by":"system"}
+]}`
+
+func TestOpenAIListModels(t *testing.T) {
+	server, _ := modelsServer(t, map[string]string{"/models": openAIModels})
+	provider := openai.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+
+	models, err := provider.ListModels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []model.ModelInfo{
+		{ID: "gpt-4o", OwnedBy: "system", CreatedAt: time.Unix(1715367049, 0)},
+		{ID: "gpt-4o-mini", OwnedBy: "system", CreatedAt: time.Unix(1721172741, 0)},
+	}, models)
+}
+
+func TestAnthropicListModelsFollowsPages(t *testing.T) {
+	server, requests := modelsServer(t, map[string]string{
+		"/models?limit=100": `{"data":[{"id":"claude-3-5-sonnet-20241022","display_name":"Claude 3.5 Sonnet","created_at":"2024-10-22T00:00:00Z"}],
+			"has_more":true,"last_id":"claude-3-5-sonnet-20241022"}`,
+		"/models?after_id=claude-3-5-sonnet-20241022&limit=100": `{"data":[{"id":"claude-3-haiku-20240307","display_name":"Claude 3 Haiku","created_at":"2024-03-07T00:00:00Z"}],
+			"has_more":false,"last_id":"claude-3-haiku-20240307"}`,
+	})
+	provider := anthropic.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+
+	models, err := provider.ListModels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []model.ModelInfo{
+		{ID: "claude-3-5-sonnet-20241022", DisplayName: "Claude 3.5 Sonnet", CreatedAt: time.Date(2024, 10, 22, 0, 0, 0, 0, time.UTC)},
+		{ID: "claude-3-haiku-20240307", DisplayName: "Claude 3 Haiku", CreatedAt: time.Date(2024, 3, 7, 0, 0, 0, 0, time.UTC)},
+	}, models)
+	assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+}
+
+func TestLMStudioListModels(t *testing.T) {
+	server, _ := modelsServer(t, map[string]string{
+		"/models": `{"data":[{"id":"qwen2.5-7b-instruct","owned_by":"organization_owner"}]}`,
+	})
+	provider := lmstudio.NewLMStudioProvider(server.URL)
+
+	models, err := provider.ListModels(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []model.ModelInfo{{ID: "qwen2.5-7b-instruct", OwnedBy: "organization_owner"}}, models)
+}
+
+func TestGetModelRejectsMisspelledName(t *testing.T) {
+	server, requests := modelsServer(t, map[string]string{"/models": openAIModels})
+	provider := openai.NewProvider("test-key").WithModelValidation(true)
+	provider.SetBaseURL(server.URL)
+
+	_, err := provider.GetModel("gpt-4o-minii")
+	var unknown *model.UnknownModelError
+	require.True(t, errors.As(err, &unknown), "got %v", err)
+	assert.Equal(t, "gpt-4o-minii", unknown.Name)
+	assert.Equal(t, "gpt-4o-mini", unknown.Suggestion)
+	assert.Contains(t, err.Error(), `did you mean "gpt-4o-mini"`)
+
+	_, err = provider.GetModel("gpt-4o-mini")
+	assert.NoError(t, err)
+
+	_, err = provider.GetModel("llama-3")
+	require.True(t, errors.As(err, &unknown), "got %v", err)
+	assert.Empty(t, unknown.Suggestion, "names that are not close get no suggestion")
+	assert.Equal(t, int32(1), atomic.LoadInt32(requests), "the model list is cached")
+}
+
+func TestAnthropicValidationAcceptsLatestAliases(t *testing.T) {
+	server, _ := modelsServer(t, map[string]string{
+		"/models?limit=100": `{"data":[{"id":"claude-3-5-sonnet-20241022"}],"has_more":false}`,
+	})
+	provider := anthropic.NewProvider("test-key").WithModelValidation(true)
+	provider.SetBaseURL(server.URL)
+
+	_, err := provider.GetModel("claude-3-5-sonnet-latest")
+	assert.NoError(t, err)
+
+	_, err = provider.GetModel("claude-3-5-sonet-20241022")
+	var unknown *model.UnknownModelError
+	require.True(t, errors.As(err, &unknown), "got %v", err)
+	assert.Equal(t, "claude-3-5-sonnet-20241022", unknown.Suggestion)
+}
+
+func TestLMStudioValidationSuggestsLoadedModel(t *testing.T) {
+	server, _ := modelsServer(t, map[string]string{
+		"/models": `{"data":[{"id":"qwen2.5-7b-instruct"}]}`,
+	})
+	provider := lmstudio.NewLMStudioProvider(server.URL).WithModelValidation(true)
+
+	_, err := provider.GetModel("qwen2.5-7b-instruc")
+	var unknown *model.UnknownModelError
+	require.True(t, errors.As(err, &unknown), "got %v", err)
+	assert.Equal(t, "qwen2.5-7b-instruct", unknown.Suggestion)
+}
+
+// countingLister returns a fixed model list and counts how often it was asked
+type countingLister struct {
+	ids   []string
+	calls int32
+}
+
+func (l *countingLister) ListModels(ctx context.Context) ([]model.ModelInfo, error) {
+	atomic.AddInt32(&l.calls, 1)
+	models := make([]model.ModelInfo, len(l.ids))
+	for i, id := range l.ids {
+		models[i] = model.ModelInfo{ID: id}
+	}
+	return models, nil
+}
+
+func TestModelValidatorCachesForTTL(t *testing.T) {
+	lister := &countingLister{ids: []string{"gpt-4o", "gpt-4o-mini"}}
+	validator := model.NewModelValidator(lister).WithTTL(50 * time.Millisecond)
+	ctx := context.Background()
+
+	require.NoError(t, validator.Validate(ctx, "gpt-4o"))
+	require.NoError(t, validator.Validate(ctx, "gpt-4o-mini"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&lister.calls))
+
+	// A model added after the list was cached is only seen once the TTL has passed
+	lister.ids = append(lister.ids, "o3")
+	assert.Error(t, validator.Validate(ctx, "o3"))
+	time.Sleep(60 * time.Millisecond)
+	assert.NoError(t, validator.Validate(ctx, "o3"))
+	assert.Equal(t, int32(2), atomic.LoadInt32(&lister.calls))
+}
+
+func TestModelValidatorSuggestions(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name       string
+		models     []string
+		input      string
+		suggestion string
+	}{
+		{name: "empty list", models: nil, input: "gpt-4o", suggestion: ""},
+		{name: "empty name", models: []string{"o1", "gpt-4o"}, input: "", suggestion: "o1"},
+		{name: "single edit", models: []string{"gpt-4o", "gpt-4o-mini"}, input: "gpt-4", suggestion: "gpt-4o"},
+		{name: "too far", models: []string{"gpt-4o"}, input: "claude", suggestion: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validator := model.NewModelValidator(&countingLister{ids: tt.models})
+			err := validator.Validate(ctx, tt.input)
+			var unknown *model.UnknownModelError
+			require.True(t, errors.As(err, &unknown), "got %v", err)
+			assert.Equal(t, tt.suggestion, unknown.Suggestion)
+		})
+	}
+}
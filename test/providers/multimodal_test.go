@@ -0,0 +1,180 @@
+package providers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/anthropic"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/lmstudio"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var pngBytes = []byte{0x89, 'P', 'N', 'G'}
+
+// captureMessages starts a server that records the messages of the request and
+// answers with the given body
+func captureMessages(t *testing.T, reply string) (*httptest.Server, *[]map[string]interface{}) {
+	var messages []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []map[string]interface{} `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		messages = body.Messages
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(reply))
+	}))
+	t.Cleanup(server.Close)
+	return server, &messages
+}
+
+func TestOpenAISendsContentParts(t *testing.T) {
+	server, messages := captureMessages(t, chatCompletion)
+	provider := openai.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+	m, err := provider.GetModel("gpt-4o")
+	require.NoError(t, err)
+
+	_, err = m.GetResponse(context.Background(), &model.Request{Input: []model.ContentPart{
+		model.TextPart("What is in these images?"),
+		model.ImageURLPart("https://example.com/cat.png").WithDetail("low"),
+		model.ImagePart(pngBytes, "image/png"),
+		model.FilePart("report.pdf", []byte("%PDF"), "application/pdf"),
+	}})
+	require.NoError(t, err)
+
+	require.Len(t, *messages, 1)
+	content := (*messages)[0]["content"].([]interface{})
+	require.Len(t, content, 4)
+	assert.Equal(t, map[string]interface{}{"type": "text", "text": "What is in these images?"}, content[0])
+	assert.Equal(t, map[string]interface{}{"url": "https://example.com/cat.png", "detail": "low"}, content[1].(map[string]interface{})["image_url"])
+	assert.Equal(t, "data:image/png;base64,iVBORw==", content[2].(map[string]interface{})["image_url"].(map[string]interface{})["url"])
+	assert.Equal(t, map[string]interface{}{"filename": "report.pdf", "file_data": "data:application/pdf;base64,JVBERg=="}, content[3].(map[string]interface{})["file"])
+}
+
+func TestOpenAIKeepsTextMessagesAsStrings(t *testing.T) {
+	server, messages := captureMessages(t, chatCompletion)
+	provider := openai.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+	m, err := provider.GetModel("gpt-4o")
+	require.NoError(t, err)
+
+	_, err = m.GetResponse(context.Background(), &model.Request{Input: []interface{}{
+		map[string]interface{}{"type": "message", "role": "user", "content": "plain text"},
+		model.UserMessage(model.TextPart("look"), model.ImageURLPart("https://example.com/a.jpg")),
+	}})
+	require.NoError(t, err)
+
+	require.Len(t, *messages, 2)
+	assert.Equal(t, "plain text", (*messages)[0]["content"])
+	assert.Len(t, (*messages)[1]["content"], 2)
+}
+
+func TestLMStudioSendsImageParts(t *testing.T) {
+	server, messages := captureMessages(t, chatCompletion)
+	provider := lmstudio.NewProvider()
+	provider.SetBaseURL(server.URL)
+	m, err := provider.GetModel("llava")
+	require.NoError(t, err)
+
+	_, err = m.GetResponse(context.Background(), &model.Request{Input: []model.ContentPart{
+		model.TextPart("describe"),
+		model.ImagePart(pngBytes, "image/png"),
+	}})
+	require.NoError(t, err)
+
+	require.Len(t, *messages, 1)
+	content := (*messages)[0]["content"].([]interface{})
+	require.Len(t, content, 2)
+	assert.Equal(t, "data:image/png;base64,iVBORw==", content[1].(map[string]interface{})["image_url"].(map[string]interface{})["url"])
+}
+
+func TestAnthropicSendsImageAndDocumentBlocks(t *testing.T) {
+	server, messages := captureMessages(t, `{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"a cat"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`)
+	provider := anthropic.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+	m, err := provider.GetModel("claude-3-haiku-20240307")
+	require.NoError(t, err)
+
+	res, err := m.GetResponse(context.Background(), &model.Request{Input: []interface{}{
+		model.UserMessage(
+			model.TextPart("What is this?"),
+			model.ImagePart(pngBytes, "image/png"),
+			model.ImageURLPart("https://example.com/cat.png"),
+			model.FilePart("report.pdf", []byte("%PDF"), "application/pdf"),
+		),
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "a cat", res.Content)
+
+	require.Len(t, *messages, 1)
+	blocks := (*messages)[0]["content"].([]interface{})
+	require.Len(t, blocks, 4)
+	assert.Equal(t, map[string]interface{}{"type": "text", "text": "What is this?"}, blocks[0])
+	assert.Equal(t, map[string]interface{}{
+		"type":   "image",
+		"source": map[string]interface{}{"type": "base64", "media_type": "image/png", "data": "iVBORw=="},
+	}, blocks[1])
+	assert.Equal(t, map[string]interface{}{"type": "url", "url": "https://example.com/cat.png"}, blocks[2].(map[string]interface{})["source"])
+	assert.Equal(t, "document", blocks[3].(map[string]interface{})["type"])
+}
+
+func TestContentPartsFromJSON(t *testing.T) {
+	data, err := json.Marshal(model.UserMessage(model.TextPart("hi"), model.ImagePart(pngBytes, "image/png")))
+	require.NoError(t, err)
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	parts, ok := model.ContentParts(decoded["content"])
+	require.True(t, ok)
+	assert.Equal(t, []model.ContentPart{model.TextPart("hi"), model.ImagePart(pngBytes, "image/png")}, parts)
+
+	_, ok = model.ContentParts([]interface{}{map[string]interface{}{"type": "message"}})
+	assert.False(t, ok)
+}
+
+func TestEstimateTokensCountsAttachmentsOnce(t *testing.T) {
+	large := make([]byte, 1<<20)
+	estimate := model.EstimateTokens(&model.Request{Input: []model.ContentPart{
+		model.TextPart("describe"),
+		model.ImagePart(large, "image/png"),
+	}})
+	assert.Less(t, estimate, 2000)
+}
+
+func TestOpenAISendsAudioInput(t *testing.T) {
+	server, messages := captureMessages(t, chatCompletion)
+	provider := openai.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+	m, err := provider.GetModel("gpt-4o-audio-preview")
+	require.NoError(t, err)
+
+	_, err = m.GetResponse(context.Background(), &model.Request{Input: []model.ContentPart{
+		model.TextPart("Summarize this voice note"),
+		model.AudioPart([]byte("RIFF"), "wav"),
+		model.AudioPart([]byte("ID3"), ".MP3"),
+	}})
+	require.NoError(t, err)
+
+	content := (*messages)[0]["content"].([]interface{})
+	require.Len(t, content, 3)
+	assert.Equal(t, map[string]interface{}{
+		"type":        "input_audio",
+		"input_audio": map[string]interface{}{"data": "UklGRg==", "format": "wav"},
+	}, content[1])
+	assert.Equal(t, "mp3", content[2].(map[string]interface{})["input_audio"].(map[string]interface{})["format"])
+}
+
+func TestAudioPartMediaTypes(t *testing.T) {
+	assert.Equal(t, "audio/mpeg", model.AudioPart(nil, "mp3").MediaType)
+	assert.Equal(t, "mp3", model.ContentPart{Type: model.ContentTypeAudio, MediaType: "audio/mp3"}.AudioFormat())
+	assert.Equal(t, "wav", model.ContentPart{Type: model.ContentTypeAudio, MediaType: "audio/x-wav"}.AudioFormat())
+	assert.Equal(t, "aac", model.AudioPart(nil, "aac").AudioFormat())
+}
@@ -0,0 +1,121 @@
+package providers_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/anthropic"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/openai"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var searchTool = map[string]interface{}{
+	"type": "function",
+	"function": map[string]interface{}{
+		"name":        "search",
+		"description": "Search the docs",
+		"parameters":  map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+	},
+}
+
+func TestAnthropicPromptCaching(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &body))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn",
+			"usage":{"input_tokens":20,"output_tokens":5,"cache_creation_input_tokens":0,"cache_read_input_tokens":1800}}`))
+	}))
+	defer server.Close()
+
+	provider := anthropic.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+	m, err := provider.GetModel("claude-3-5-haiku-latest")
+	require.NoError(t, err)
+
+	res, err := m.GetResponse(context.Background(), &model.Request{
+		SystemInstructions: "You are a long-winded orchestrator",
+		Input:              "hi",
+		Tools:              []interface{}{searchTool},
+		Settings:           &model.Settings{PromptCaching: true},
+	})
+	require.NoError(t, err)
+
+	system, ok := body["system"].([]interface{})
+	require.True(t, ok, "system should be sent as blocks")
+	require.Len(t, system, 1)
+	block := system[0].(map[string]interface{})
+	assert.Equal(t, "You are a long-winded orchestrator", block["text"])
+	assert.Equal(t, map[string]interface{}{"type": "ephemeral"}, block["cache_control"])
+
+	tools := body["tools"].([]interface{})
+	last := tools[len(tools)-1].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "ephemeral"}, last["cache_control"])
+
+	require.NotNil(t, res.Usage)
+	assert.Equal(t, 1820, res.Usage.PromptTokens)
+	assert.Equal(t, 1800, res.Usage.CachedTokens)
+	assert.Equal(t, 1825, res.Usage.TotalTokens)
+}
+
+func TestAnthropicWithoutPromptCachingSendsPlainSystem(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &body))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer server.Close()
+
+	provider := anthropic.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+	m, err := provider.GetModel("claude-3-5-haiku-latest")
+	require.NoError(t, err)
+
+	_, err = m.GetResponse(context.Background(), &model.Request{SystemInstructions: "Be brief", Input: "hi", Tools: []interface{}{searchTool}})
+	require.NoError(t, err)
+	assert.Equal(t, "Be brief", body["system"])
+	assert.NotContains(t, body["tools"].([]interface{})[0], "cache_control")
+}
+
+func TestOpenAIPromptCaching(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &body))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"1","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":2048,"completion_tokens":4,"total_tokens":2052,"prompt_tokens_details":{"cached_tokens":1920}}}`))
+	}))
+	defer server.Close()
+
+	provider := openai.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+	m, err := provider.GetModel("gpt-4o")
+	require.NoError(t, err)
+
+	res, err := m.GetResponse(context.Background(), &model.Request{
+		Input:    "hi",
+		Settings: &model.Settings{PromptCaching: true, PromptCacheKey: "Orchestrator"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Orchestrator", body["prompt_cache_key"])
+	assert.Equal(t, 1920, res.Usage.CachedTokens)
+
+	// Cached tokens are priced at the cached rate
+	cost, ok := runner.DefaultPricing.Cost("gpt-4o", res.Usage)
+	require.True(t, ok)
+	assert.InDelta(t, 128/1e6*2.50+1920/1e6*1.25+4/1e6*10.00, cost, 1e-12)
+}
@@ -0,0 +1,121 @@
+package providers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/anthropic"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/openai"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIRetriesAfterRetryAfterHeader(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "0.2")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"error":{"message":"Slow down","type":"requests"}}`))
+			return
+		}
+		w.Header().Set("x-ratelimit-limit-requests", "500")
+		w.Header().Set("x-ratelimit-remaining-requests", "499")
+		w.Header().Set("x-ratelimit-reset-requests", "120ms")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(chatCompletion))
+	}))
+	defer server.Close()
+
+	// A blind backoff of a minute would time the test out
+	provider := openai.NewProvider("test-key").WithRetryConfig(2, time.Minute)
+	provider.SetBaseURL(server.URL)
+	m, err := provider.GetModel("gpt-4o")
+	require.NoError(t, err)
+
+	start := time.Now()
+	res, err := m.GetResponse(context.Background(), &model.Request{Input: "hi"})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", res.Content)
+	assert.GreaterOrEqual(t, time.Since(start), 190*time.Millisecond)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	quota, ok := provider.RateLimitQuota()
+	require.True(t, ok)
+	assert.Equal(t, 500, quota.RequestsLimit)
+	assert.Equal(t, 499, quota.RequestsRemaining)
+}
+
+func TestAnthropicPacesRequestsByReportedQuota(t *testing.T) {
+	reset := time.Now().Add(300 * time.Millisecond)
+	var times []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		times = append(times, time.Now())
+		w.Header().Set("anthropic-ratelimit-requests-limit", "1")
+		w.Header().Set("anthropic-ratelimit-requests-remaining", "0")
+		w.Header().Set("anthropic-ratelimit-requests-reset", reset.UTC().Format(time.RFC3339Nano))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer server.Close()
+
+	provider := anthropic.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+	m, err := provider.GetModel("claude-3-5-haiku-latest")
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		_, err := m.GetResponse(context.Background(), &model.Request{Input: "hi"})
+		require.NoError(t, err)
+	}
+
+	// The second request waits for the quota to reset instead of being rejected
+	require.Len(t, times, 2)
+	assert.False(t, times[1].Before(reset.Truncate(time.Millisecond)))
+
+	quota, ok := provider.RateLimitQuota()
+	require.True(t, ok)
+	assert.Equal(t, 1, quota.RequestsLimit)
+	assert.Equal(t, 0, quota.RequestsRemaining)
+}
+
+func TestProvidersShareLimiter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(chatCompletion))
+	}))
+	defer server.Close()
+
+	limiter := ratelimit.NewSlidingWindow(2, 0).WithWindow(time.Hour)
+	first := openai.NewProvider("test-key").WithLimiter(limiter)
+	first.SetBaseURL(server.URL)
+	second := openai.NewProvider("test-key").WithLimiter(limiter).WithRateLimit(100, 0)
+	second.SetBaseURL(server.URL)
+
+	m, err := first.GetModel("gpt-4o")
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err := m.GetResponse(context.Background(), &model.Request{Input: "hi"})
+		require.NoError(t, err)
+	}
+
+	// The second provider is out of capacity too; its own RPM does not apply
+	status := second.RateLimitStatus()
+	assert.True(t, status.Throttled)
+	assert.Equal(t, 2, status.RPM)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	m2, err := second.GetModel("gpt-4o")
+	require.NoError(t, err)
+	_, err = m2.GetResponse(ctx, &model.Request{Input: "hi"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,107 @@
+package providers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/openai"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIRealtimeSession(t *testing.T) {
+	received := make(chan map[string]interface{}, 10)
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		assert.Equal(t, "/realtime", r.URL.Path)
+		assert.Equal(t, "gpt-4o-realtime-preview", r.URL.Query().Get("model"))
+
+		conn, err := websocket.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		send := func(event string) {
+			require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(event)))
+		}
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var event map[string]interface{}
+			require.NoError(t, json.Unmarshal(data, &event))
+			received <- event
+
+			if event["type"] == "response.create" {
+				send(`{"type":"response.audio_transcript.delta","delta":"Checking "}`)
+				send(`{"type":"response.audio.delta","delta":"AQI="}`)
+				send(`{"type":"response.function_call_arguments.done","call_id":"call_1","name":"weather","arguments":"{\"city\":\"Oslo\"}"}`)
+				send(`{"type":"response.done","response":{"status":"completed","usage":{"input_tokens":10,"output_tokens":5,"total_tokens":15}}}`)
+			}
+		}
+	}))
+	defer server.Close()
+
+	provider := openai.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+
+	session, err := provider.ConnectRealtime(context.Background(), &model.RealtimeConfig{
+		Instructions: "Be helpful",
+		Voice:        "verse",
+		Tools: []interface{}{map[string]interface{}{
+			"type":     "function",
+			"function": map[string]interface{}{"name": "weather", "description": "Gets the weather", "parameters": map[string]interface{}{"type": "object"}},
+		}},
+	})
+	require.NoError(t, err)
+	defer session.Close()
+
+	update := <-received
+	assert.Equal(t, "session.update", update["type"])
+	sessionConfig := update["session"].(map[string]interface{})
+	assert.Equal(t, "Be helpful", sessionConfig["instructions"])
+	assert.Equal(t, "verse", sessionConfig["voice"])
+	assert.Equal(t, map[string]interface{}{
+		"type":        "function",
+		"name":        "weather",
+		"description": "Gets the weather",
+		"parameters":  map[string]interface{}{"type": "object"},
+	}, sessionConfig["tools"].([]interface{})[0])
+	assert.Equal(t, "Bearer test-key", header.Get("Authorization"))
+	assert.Equal(t, "realtime=v1", header.Get("OpenAI-Beta"))
+
+	require.NoError(t, session.SendText(context.Background(), "Weather in Oslo?"))
+	require.NoError(t, session.CreateResponse(context.Background()))
+	item := (<-received)["item"].(map[string]interface{})
+	assert.Equal(t, "user", item["role"])
+	assert.Equal(t, "response.create", (<-received)["type"])
+
+	var events []model.StreamEvent
+	for event := range session.Events() {
+		events = append(events, event)
+		if event.Type == model.StreamEventTypeDone {
+			break
+		}
+	}
+	require.Len(t, events, 4)
+	assert.Equal(t, "Checking ", events[0].Content)
+	assert.Equal(t, []byte{1, 2}, events[1].Media.Data)
+	assert.Equal(t, "audio/pcm", events[1].Media.MediaType)
+	assert.Equal(t, map[string]interface{}{"city": "Oslo"}, events[2].ToolCall.Parameters)
+
+	response := events[3].Response
+	assert.Equal(t, "Checking ", response.Content)
+	require.Len(t, response.ToolCalls, 1)
+	assert.Equal(t, "call_1", response.ToolCalls[0].ID)
+	assert.Equal(t, 15, response.Usage.TotalTokens)
+
+	require.NoError(t, session.SendToolResult(context.Background(), "call_1", "sunny"))
+	output := (<-received)["item"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "function_call_output", "call_id": "call_1", "output": "sunny"}, output)
+}
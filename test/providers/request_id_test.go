@@ -0,0 +1,127 @@
+package providers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/lmstudio"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openAIStream is a minimal chat completions stream
+var openAIStream = []string{
+	`{"choices":[{"delta":{"content":"ok"},"finish_reason":"stop"}]}`,
+	`[DONE]`,
+}
+
+// anthropicStream is a minimal Anthropic messages stream
+var anthropicStream = []string{
+	`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"usage":{"input_tokens":1,"output_tokens":1}}}`,
+	`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+	`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"ok"}}`,
+	`{"type":"content_block_stop","index":0}`,
+	`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":1}}`,
+	`{"type":"message_stop"}`,
+}
+
+// openAIReply is a minimal chat completions response
+const openAIReply = `{"id":"chatcmpl-1","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`
+
+// requestIDServer answers with a request ID header, sending reply to plain
+// requests and the stream chunks to streaming ones
+func requestIDServer(t *testing.T, header, id, reply string, stream []string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Stream bool `json:"stream"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		w.Header().Set(header, id)
+		if !body.Stream {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, reply)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range stream {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProvidersReportRequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		model  func(t *testing.T, url string) model.Model
+		reply  string
+		stream []string
+	}{
+		{
+			name:   "openai",
+			header: "x-request-id",
+			model: func(t *testing.T, url string) model.Model {
+				provider := openai.NewProvider("test-key")
+				provider.SetBaseURL(url)
+				m, err := provider.GetModel("gpt-4o")
+				require.NoError(t, err)
+				return m
+			},
+			reply:  openAIReply,
+			stream: openAIStream,
+		},
+		{
+			name:   "azure openai",
+			header: "apim-request-id",
+			model: func(t *testing.T, url string) model.Model {
+				provider := openai.NewProvider("test-key")
+				provider.SetBaseURL(url)
+				m, err := provider.GetModel("gpt-4o")
+				require.NoError(t, err)
+				return m
+			},
+			reply:  openAIReply,
+			stream: openAIStream,
+		},
+		{
+			name:   "anthropic",
+			header: "request-id",
+			model:  anthropicModel,
+			reply:  anthropicReply,
+			stream: anthropicStream,
+		},
+		{
+			name:   "lmstudio",
+			header: "x-request-id",
+			model: func(t *testing.T, url string) model.Model {
+				m, err := lmstudio.NewLMStudioProvider(url).GetModel("gemma-3-4b-it")
+				require.NoError(t, err)
+				return m
+			},
+			reply:  openAIReply,
+			stream: openAIStream,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := requestIDServer(t, tt.header, "req_"+tt.header, tt.reply, tt.stream)
+			m := tt.model(t, server.URL)
+
+			response, err := m.GetResponse(context.Background(), &model.Request{Input: "hi"})
+			require.NoError(t, err)
+			assert.Equal(t, "req_"+tt.header, response.RequestID)
+
+			assert.Equal(t, "req_"+tt.header, streamDone(t, m).RequestID)
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package providers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/anthropic"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureBody starts a server that records the request body and answers with the given body
+func captureBody(t *testing.T, reply string) (*httptest.Server, *map[string]interface{}) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(reply))
+	}))
+	t.Cleanup(server.Close)
+	return server, &body
+}
+
+var weatherSchema = map[string]interface{}{
+	"type": "object",
+	"properties": map[string]interface{}{
+		"city": map[string]interface{}{"type": "string"},
+		"note": map[string]interface{}{"type": "string"},
+	},
+	"required": []string{"city"},
+}
+
+func TestOpenAISendsJSONSchemaResponseFormat(t *testing.T) {
+	server, body := captureBody(t, chatCompletion)
+	provider := openai.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+	m, err := provider.GetModel("gpt-4o")
+	require.NoError(t, err)
+
+	_, err = m.GetResponse(context.Background(), &model.Request{
+		Input:    "weather?",
+		Settings: &model.Settings{ResponseFormat: model.JSONSchemaFormat("Weather", weatherSchema, true)},
+	})
+	require.NoError(t, err)
+
+	format := (*body)["response_format"].(map[string]interface{})
+	assert.Equal(t, "json_schema", format["type"])
+	jsonSchema := format["json_schema"].(map[string]interface{})
+	assert.Equal(t, "Weather", jsonSchema["name"])
+	assert.Equal(t, true, jsonSchema["strict"])
+	schema := jsonSchema["schema"].(map[string]interface{})
+	assert.Equal(t, []interface{}{"city", "note"}, schema["required"])
+	assert.Equal(t, false, schema["additionalProperties"])
+}
+
+func TestOpenAISendsJSONObjectResponseFormat(t *testing.T) {
+	server, body := captureBody(t, chatCompletion)
+	provider := openai.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+	m, err := provider.GetModel("gpt-4o")
+	require.NoError(t, err)
+
+	_, err = m.GetResponse(context.Background(), &model.Request{
+		Input:    "weather?",
+		Settings: &model.Settings{ResponseFormat: model.JSONObjectFormat()},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"type": "json_object"}, (*body)["response_format"])
+}
+
+func TestOpenAISupportsJSONSchema(t *testing.T) {
+	provider := openai.NewProvider("test-key")
+	for name, want := range map[string]bool{
+		"gpt-4o":        true,
+		"gpt-4o-mini":   true,
+		"gpt-4":         false,
+		"gpt-4-turbo":   false,
+		"gpt-3.5-turbo": false,
+	} {
+		m, err := provider.GetModel(name)
+		require.NoError(t, err)
+		assert.Equal(t, want, m.(model.SchemaOutputModel).SupportsJSONSchema(), name)
+	}
+}
+
+func TestAnthropicRequestsSchemaOutputThroughTool(t *testing.T) {
+	server, body := captureBody(t, `{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"tool_use","id":"tu_1","name":"structured_output","input":{"city":"Paris","note":null}}],"stop_reason":"tool_use","usage":{"input_tokens":1,"output_tokens":1}}`)
+	provider := anthropic.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+	m, err := provider.GetModel("claude-3-haiku-20240307")
+	require.NoError(t, err)
+	assert.True(t, m.(model.SchemaOutputModel).SupportsJSONSchema())
+
+	res, err := m.GetResponse(context.Background(), &model.Request{
+		Input:    "weather?",
+		Settings: &model.Settings{ResponseFormat: model.JSONSchemaFormat("Weather", weatherSchema, true)},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"city":"Paris","note":null}`, res.Content)
+	assert.Empty(t, res.ToolCalls)
+
+	tools := (*body)["tools"].([]interface{})
+	require.Len(t, tools, 1)
+	assert.Equal(t, anthropic.OutputToolName, tools[0].(map[string]interface{})["name"])
+	assert.Equal(t, map[string]interface{}{"type": "tool", "name": anthropic.OutputToolName}, (*body)["tool_choice"])
+}
+
+func TestAnthropicJSONObjectInstruction(t *testing.T) {
+	server, body := captureBody(t, `{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"{}"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`)
+	provider := anthropic.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+	m, err := provider.GetModel("claude-3-haiku-20240307")
+	require.NoError(t, err)
+
+	_, err = m.GetResponse(context.Background(), &model.Request{
+		SystemInstructions: "You are helpful.",
+		Input:              "weather?",
+		Settings:           &model.Settings{ResponseFormat: model.JSONObjectFormat()},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, (*body)["system"], "You are helpful.")
+	assert.Contains(t, (*body)["system"], "valid JSON object")
+	assert.Nil(t, (*body)["tools"])
+}
+
+func TestStrictJSONSchema(t *testing.T) {
+	strict, ok := model.StrictJSONSchema(weatherSchema)
+	require.True(t, ok)
+	properties := strict["properties"].(map[string]interface{})
+	assert.Equal(t, "string", properties["city"].(map[string]interface{})["type"])
+	assert.Equal(t, []interface{}{"string", "null"}, properties["note"].(map[string]interface{})["type"])
+	assert.Equal(t, []string{"city", "note"}, strict["required"])
+
+	// The original schema is left alone
+	assert.Equal(t, "string", weatherSchema["properties"].(map[string]interface{})["note"].(map[string]interface{})["type"])
+
+	_, ok = model.StrictJSONSchema(map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": map[string]interface{}{"type": "string"},
+	})
+	assert.False(t, ok)
+
+	format := model.JSONSchemaFormat("Labels", map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": map[string]interface{}{"type": "string"},
+	}, true)
+	assert.False(t, format.Strict)
+}
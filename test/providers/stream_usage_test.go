@@ -0,0 +1,108 @@
+package providers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/openai"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStreamingServer returns a server streaming "Hello" followed by a usage chunk,
+// and the bodies of the requests it received
+func newStreamingServer(t *testing.T) (*httptest.Server, *[]map[string]interface{}) {
+	var bodies []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(data, &body))
+		bodies = append(bodies, body)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"Hel\"}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[{\"delta\":{\"content\":\"lo\"},\"finish_reason\":\"stop\"}]}\n\n")
+		if options, ok := body["stream_options"].(map[string]interface{}); ok && options["include_usage"] == true {
+			fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":12,\"completion_tokens\":3,\"total_tokens\":15,\"prompt_tokens_details\":{\"cached_tokens\":8}}}\n\n")
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+	return server, &bodies
+}
+
+// streamDone streams a response and returns the response of its done event
+func streamDone(t *testing.T, m model.Model) *model.Response {
+	stream, err := m.StreamResponse(context.Background(), &model.Request{Input: "hi"})
+	require.NoError(t, err)
+
+	var done *model.Response
+	for event := range stream {
+		require.NoError(t, event.Error)
+		if event.Type == model.StreamEventTypeDone {
+			done = event.Response
+		}
+	}
+	require.NotNil(t, done, "the stream ended without a done event")
+	return done
+}
+
+func TestOpenAIStreamReportsUsage(t *testing.T) {
+	server, bodies := newStreamingServer(t)
+	provider := openai.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+	m, err := provider.GetModel("gpt-4o")
+	require.NoError(t, err)
+
+	done := streamDone(t, m)
+
+	require.Len(t, *bodies, 1)
+	assert.Equal(t, map[string]interface{}{"include_usage": true}, (*bodies)[0]["stream_options"])
+	assert.Equal(t, "Hello", done.Content)
+	require.NotNil(t, done.Usage)
+	assert.Equal(t, model.Usage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15, CachedTokens: 8}, *done.Usage)
+}
+
+func TestOpenAIStreamWithoutUsage(t *testing.T) {
+	server, bodies := newStreamingServer(t)
+	provider := openai.NewProvider("test-key").WithStreamUsage(false)
+	provider.SetBaseURL(server.URL)
+	m, err := provider.GetModel("gpt-4o")
+	require.NoError(t, err)
+
+	done := streamDone(t, m)
+
+	assert.NotContains(t, (*bodies)[0], "stream_options")
+	assert.Equal(t, "Hello", done.Content)
+	assert.Nil(t, done.Usage)
+}
+
+func TestStreamedRunResultHasOpenAIUsage(t *testing.T) {
+	server, _ := newStreamingServer(t)
+	provider := openai.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+
+	a := agent.NewAgent("Assistant").WithModel("gpt-4o")
+	streamed, err := runner.NewRunner().RunStreaming(context.Background(), a, &runner.RunOptions{
+		Input:     "hi",
+		RunConfig: &runner.RunConfig{ModelProvider: provider, TracingDisabled: true},
+	})
+	require.NoError(t, err)
+	for event := range streamed.Stream {
+		require.NoError(t, event.Error)
+	}
+
+	assert.Equal(t, "Hello", streamed.RunResult.FinalOutput)
+	assert.Equal(t, 15, streamed.RunResult.Usage.Total.TotalTokens)
+	assert.Equal(t, 15, streamed.RunResult.Usage.ByModel["gpt-4o"].Usage.TotalTokens)
+	assert.Greater(t, streamed.RunResult.Usage.CostUSD, 0.0)
+}
@@ -0,0 +1,110 @@
+package providers_test
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/lmstudio"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/openai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const chatCompletion = `{"id":"1","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],"usage":{"total_tokens":3}}`
+
+func TestGzipStreamingUpload(t *testing.T) {
+	largeInput := strings.Repeat("lorem ipsum ", 50000)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		assert.Equal(t, []string{"chunked"}, r.TransferEncoding)
+
+		zr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		var body struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		require.NoError(t, json.NewDecoder(zr).Decode(&body))
+		assert.Equal(t, "gpt-4o", body.Model)
+		assert.Equal(t, largeInput, body.Messages[len(body.Messages)-1].Content)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(chatCompletion))
+	}))
+	defer server.Close()
+
+	provider := openai.NewProvider("test-key").WithGzipRequests(true).WithStreamingUpload(true)
+	provider.SetBaseURL(server.URL)
+	m, err := provider.GetModel("gpt-4o")
+	require.NoError(t, err)
+
+	res, err := m.GetResponse(context.Background(), &model.Request{Input: largeInput})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", res.Content)
+}
+
+func TestContextWindowCheckFailsBeforeSending(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(chatCompletion))
+	}))
+	defer server.Close()
+
+	provider := openai.NewProvider("test-key").WithContextWindowCheck(true)
+	provider.SetBaseURL(server.URL)
+	m, err := provider.GetModel("gpt-4")
+	require.NoError(t, err)
+
+	_, err = m.GetResponse(context.Background(), &model.Request{Input: strings.Repeat("word ", 20000)})
+	var windowErr *model.ContextWindowError
+	require.True(t, errors.As(err, &windowErr), "got %v", err)
+	assert.True(t, errors.Is(err, model.ErrContextWindowExceeded))
+	assert.Equal(t, 8192, windowErr.ContextWindow)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&requests))
+
+	_, err = m.GetResponse(context.Background(), &model.Request{Input: "short"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestContextWindowForLocalModels(t *testing.T) {
+	_, known := model.ContextWindow("qwen-local-7b")
+	assert.False(t, known)
+
+	model.RegisterContextWindow("qwen-local", 1000)
+	window, known := model.ContextWindow("qwen-local-7b")
+	assert.True(t, known)
+	assert.Equal(t, 1000, window)
+
+	provider := lmstudio.NewProvider().WithContextWindowCheck(true)
+	m, err := provider.GetModel("qwen-local-7b")
+	require.NoError(t, err)
+
+	_, err = m.StreamResponse(context.Background(), &model.Request{Input: strings.Repeat("x", 8000)})
+	assert.ErrorIs(t, err, model.ErrContextWindowExceeded)
+}
+
+func TestEstimateTokensReservesMaxTokens(t *testing.T) {
+	request := &model.Request{SystemInstructions: strings.Repeat("a", 400), Input: strings.Repeat("b", 400)}
+	estimate := model.EstimateTokens(request)
+	assert.InDelta(t, 200, estimate, 20)
+
+	model.RegisterContextWindow("tiny-model", estimate+10)
+	assert.NoError(t, model.CheckContextWindow("tiny-model", request))
+
+	maxTokens := 50
+	request.Settings = &model.Settings{MaxTokens: &maxTokens}
+	assert.ErrorIs(t, model.CheckContextWindow("tiny-model", request), model.ErrContextWindowExceeded)
+}
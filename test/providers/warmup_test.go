@@ -0,0 +1,95 @@
+package providers_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/lmstudio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pingServer answers chat completions with a one-token reply and records the
+// request bodies it received
+type pingServer struct {
+	*httptest.Server
+	bodies []map[string]interface{}
+	mu     sync.Mutex
+}
+
+func newPingServer(t *testing.T) *pingServer {
+	s := &pingServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		s.mu.Lock()
+		s.bodies = append(s.bodies, body)
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"1","choices":[{"index":0,"message":{"role":"assistant","content":"pong"},"finish_reason":"length"}]}`)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// pings returns the number of requests received so far
+func (s *pingServer) pings() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.bodies)
+}
+
+func TestWarmUpSendsOneTokenRequest(t *testing.T) {
+	server := newPingServer(t)
+	provider := lmstudio.NewLMStudioProvider(server.URL)
+
+	require.NoError(t, provider.WarmUp(context.Background(), "gemma-3-4b-it"))
+
+	require.Equal(t, 1, server.pings())
+	assert.Equal(t, "gemma-3-4b-it", server.bodies[0]["model"])
+	assert.Equal(t, float64(1), server.bodies[0]["max_tokens"])
+
+	var _ model.WarmUpProvider = provider
+}
+
+func TestKeepAlivePingsAtInterval(t *testing.T) {
+	server := newPingServer(t)
+	provider := lmstudio.NewLMStudioProvider(server.URL)
+
+	stop := provider.StartKeepAlive(context.Background(), "gemma-3-4b-it", 20*time.Millisecond)
+	assert.Eventually(t, func() bool { return server.pings() >= 3 }, time.Second, 5*time.Millisecond)
+
+	stop()
+	stopped := server.pings()
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, stopped, server.pings(), "no ping is sent after stop returns")
+
+	// Stopping twice is harmless
+	stop()
+}
+
+func TestKeepAliveStopsWithContext(t *testing.T) {
+	server := newPingServer(t)
+	provider := lmstudio.NewLMStudioProvider(server.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := provider.StartKeepAlive(ctx, "gemma-3-4b-it", 20*time.Millisecond)
+	assert.Eventually(t, func() bool { return server.pings() >= 2 }, time.Second, 5*time.Millisecond)
+
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	cancelled := server.pings()
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, cancelled, server.pings(), "no ping is sent after the context is cancelled")
+
+	// The goroutine has already ended, so stop returns immediately
+	stop()
+}
@@ -0,0 +1,109 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOpenAIHeaders(t *testing.T) {
+	now := time.Now()
+	header := http.Header{}
+	header.Set("x-ratelimit-limit-requests", "500")
+	header.Set("x-ratelimit-remaining-requests", "0")
+	header.Set("x-ratelimit-reset-requests", "6m0s")
+	header.Set("x-ratelimit-limit-tokens", "30000")
+	header.Set("x-ratelimit-remaining-tokens", "29000")
+	header.Set("x-ratelimit-reset-tokens", "20ms")
+
+	quota, ok := ratelimit.ParseHeaders(header, now)
+	require.True(t, ok)
+	assert.Equal(t, 500, quota.RequestsLimit)
+	assert.Equal(t, 0, quota.RequestsRemaining)
+	assert.Equal(t, now.Add(6*time.Minute), quota.RequestsReset)
+	assert.Equal(t, 30000, quota.TokensLimit)
+	assert.Equal(t, 29000, quota.TokensRemaining)
+	assert.Equal(t, now.Add(20*time.Millisecond), quota.TokensReset)
+
+	// The exhausted requests limit decides when requests resume
+	assert.Equal(t, quota.RequestsReset, quota.ResumeAt())
+}
+
+func TestParseAnthropicHeaders(t *testing.T) {
+	now := time.Now()
+	reset := now.Add(30 * time.Second).UTC().Truncate(time.Second)
+	header := http.Header{}
+	header.Set("anthropic-ratelimit-requests-limit", "50")
+	header.Set("anthropic-ratelimit-requests-remaining", "49")
+	header.Set("anthropic-ratelimit-requests-reset", reset.Format(time.RFC3339))
+	header.Set("anthropic-ratelimit-tokens-remaining", "0")
+	header.Set("anthropic-ratelimit-tokens-reset", reset.Format(time.RFC3339))
+	header.Set("Retry-After", "12")
+
+	quota, ok := ratelimit.ParseHeaders(header, now)
+	require.True(t, ok)
+	assert.Equal(t, 50, quota.RequestsLimit)
+	assert.Equal(t, 49, quota.RequestsRemaining)
+	assert.True(t, reset.Equal(quota.RequestsReset))
+	assert.Equal(t, -1, quota.TokensLimit)
+	assert.Equal(t, 12*time.Second, quota.RetryAfter)
+	assert.True(t, reset.Equal(quota.ResumeAt()))
+}
+
+func TestParseRetryAfterDate(t *testing.T) {
+	now := time.Now().UTC().Truncate(time.Second)
+	header := http.Header{}
+	header.Set("Retry-After", now.Add(5*time.Second).Format(http.TimeFormat))
+
+	quota, ok := ratelimit.ParseHeaders(header, now)
+	require.True(t, ok)
+	assert.Equal(t, 5*time.Second, quota.RetryAfter)
+
+	_, ok = ratelimit.ParseHeaders(http.Header{}, now)
+	assert.False(t, ok)
+}
+
+func TestRetryAfterFromError(t *testing.T) {
+	header := http.Header{}
+	header.Set("retry-after-ms", "1500")
+	err := ratelimit.NewError(&http.Response{StatusCode: http.StatusTooManyRequests, Header: header}, errors.New("API error: 429 Too Many Requests"))
+
+	assert.True(t, ratelimit.IsRateLimited(err))
+	assert.Equal(t, "API error: 429 Too Many Requests", err.Error())
+	delay, ok := ratelimit.RetryAfter(err)
+	require.True(t, ok)
+	assert.InDelta(t, 1500*time.Millisecond, delay, float64(100*time.Millisecond))
+
+	_, ok = ratelimit.RetryAfter(errors.New("other"))
+	assert.False(t, ok)
+}
+
+func TestSlidingWindowWaitsForReportedReset(t *testing.T) {
+	limiter := ratelimit.NewSlidingWindow(0, 0)
+	limiter.Observe(ratelimit.Quota{
+		RequestsLimit:     10,
+		RequestsRemaining: 0,
+		RequestsReset:     time.Now().Add(100 * time.Millisecond),
+		TokensRemaining:   -1,
+		ReportedAt:        time.Now(),
+	})
+
+	status := limiter.Status()
+	assert.True(t, status.Throttled)
+
+	throttled := 0
+	start := time.Now()
+	require.NoError(t, limiter.Wait(context.Background(), func(ratelimit.Status) { throttled++ }))
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+	assert.Equal(t, 1, throttled)
+
+	quota, ok := limiter.Quota()
+	require.True(t, ok)
+	assert.Equal(t, 10, quota.RequestsLimit)
+}
@@ -0,0 +1,63 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterLimitersShareCounts(t *testing.T) {
+	counter := ratelimit.NewMemoryCounter()
+	window := 200 * time.Millisecond
+	first := ratelimit.NewCounterLimiter(counter, "org", 2, 100).WithWindow(window)
+	second := ratelimit.NewCounterLimiter(counter, "org", 2, 100).WithWindow(window)
+	ctx := context.Background()
+
+	// Wait for the start of a window so that the requests fall into one
+	time.Sleep(time.Until(time.Now().Truncate(window).Add(window)))
+
+	require.NoError(t, first.Wait(ctx, nil))
+	require.NoError(t, second.Wait(ctx, nil))
+	first.AddTokens(40)
+
+	status := second.Status()
+	assert.True(t, status.Throttled)
+	assert.Equal(t, 0, status.RemainingRequests)
+	assert.Equal(t, 60, status.RemainingTokens)
+
+	// The next request waits for the next window
+	throttled := 0
+	require.NoError(t, second.Wait(ctx, func(ratelimit.Status) { throttled++ }))
+	assert.Equal(t, 1, throttled)
+	assert.Equal(t, 1, first.Status().RemainingRequests)
+}
+
+func TestCounterLimiterWaitsForTokens(t *testing.T) {
+	limiter := ratelimit.NewCounterLimiter(ratelimit.NewMemoryCounter(), "tokens", 0, 100).WithWindow(time.Hour)
+	limiter.AddTokens(100)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.Wait(ctx, nil), context.DeadlineExceeded)
+
+	limiter.Reset()
+	require.NoError(t, limiter.Wait(context.Background(), nil))
+}
+
+func TestMemoryCounterExpires(t *testing.T) {
+	counter := ratelimit.NewMemoryCounter()
+	ctx := context.Background()
+
+	value, err := counter.Add(ctx, "key", 3, 30*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), value)
+
+	time.Sleep(40 * time.Millisecond)
+	value, err = counter.Get(ctx, "key")
+	require.NoError(t, err)
+	assert.Zero(t, value)
+}
@@ -0,0 +1,66 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlidingWindowReportsRemainingCapacity(t *testing.T) {
+	limiter := ratelimit.NewSlidingWindow(3, 1000)
+	ctx := context.Background()
+
+	require.NoError(t, limiter.Wait(ctx, nil))
+	limiter.AddTokens(400)
+
+	status := limiter.Status()
+	assert.Equal(t, 2, status.RemainingRequests)
+	assert.Equal(t, 600, status.RemainingTokens)
+	assert.False(t, status.Throttled)
+	assert.Zero(t, status.WaitDuration())
+}
+
+func TestSlidingWindowThrottlesUntilCapacityFrees(t *testing.T) {
+	window := 150 * time.Millisecond
+	limiter := ratelimit.NewSlidingWindow(2, 0).WithWindow(window)
+	ctx := context.Background()
+
+	start := time.Now()
+	require.NoError(t, limiter.Wait(ctx, nil))
+	require.NoError(t, limiter.Wait(ctx, nil))
+
+	status := limiter.Status()
+	assert.True(t, status.Throttled)
+	assert.Equal(t, 0, status.RemainingRequests)
+	assert.Equal(t, -1, status.RemainingTokens)
+
+	var throttled []ratelimit.Status
+	require.NoError(t, limiter.Wait(ctx, func(s ratelimit.Status) {
+		throttled = append(throttled, s)
+	}))
+	assert.GreaterOrEqual(t, time.Since(start), window)
+	require.NotEmpty(t, throttled)
+	assert.True(t, throttled[0].Throttled)
+}
+
+func TestSlidingWindowTokenLimit(t *testing.T) {
+	limiter := ratelimit.NewSlidingWindow(0, 100).WithWindow(time.Hour)
+	limiter.AddTokens(60)
+	limiter.AddTokens(50)
+
+	status := limiter.Status()
+	assert.True(t, status.Throttled)
+	assert.Equal(t, 0, status.RemainingTokens)
+
+	// A cancelled context stops the wait
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.Wait(ctx, nil), context.DeadlineExceeded)
+
+	limiter.Reset()
+	assert.False(t, limiter.Status().Throttled)
+}
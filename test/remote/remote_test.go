@@ -0,0 +1,109 @@
+package remote_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/remote"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/server"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRunConfig() *runner.RunConfig {
+	return &runner.RunConfig{ModelProvider: &mocks.MockModelProvider{}, TracingDisabled: true}
+}
+
+// serveAgent serves an agent over HTTP like a separate deployment would
+func serveAgent(t *testing.T, a *agent.Agent, auth server.Authenticator) *httptest.Server {
+	h := server.NewAPIHandler(runner.NewRunner(), a).WithRunOptions(&runner.RunOptions{RunConfig: testRunConfig()})
+	if auth != nil {
+		h.WithAuth(auth)
+	}
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRemoteAgentAsHandoffTarget(t *testing.T) {
+	coderModel := mocks.NewScriptedModel(&model.Response{Content: "func main() {}", Usage: &model.Usage{TotalTokens: 9}})
+	srv := serveAgent(t, agent.NewAgent("CoderAgent").WithModel(coderModel), server.BearerToken("secret"))
+
+	coder := remote.NewAgent("CoderAgent", srv.URL, remote.WithBearerToken("secret"))
+	manager := agent.NewAgent("Manager").WithModel(mocks.NewScriptedModel(&model.Response{HandoffCall: &model.HandoffCall{
+		AgentName:  "CoderAgent",
+		Parameters: map[string]any{"input": "write a main function"},
+	}}))
+	manager.WithHandoffs(coder)
+
+	res, err := runner.NewRunner().Run(context.Background(), manager, &runner.RunOptions{Input: "I need a program", RunConfig: testRunConfig()})
+	require.NoError(t, err)
+
+	assert.Equal(t, "func main() {}", res.FinalOutput)
+	assert.Equal(t, "CoderAgent", res.LastAgent.Name)
+	require.Equal(t, 1, coderModel.RequestCount())
+	assert.Contains(t, fmt.Sprint(coderModel.Requests[0].Input), "write a main function")
+	assert.Equal(t, 9, res.Usage.ByAgent["CoderAgent"].Usage.TotalTokens, "the remote usage counts towards the run")
+}
+
+func TestRemoteAgentStreams(t *testing.T) {
+	srv := serveAgent(t, agent.NewAgent("CoderAgent").WithModel(mocks.NewScriptedModel(&model.Response{Content: "func main() {}"})), nil)
+	coder := remote.NewAgent("Coder", srv.URL, remote.WithRemoteName("CoderAgent"))
+
+	streamed, err := runner.NewRunner().RunStreaming(context.Background(), coder, &runner.RunOptions{Input: "write a main function", RunConfig: testRunConfig()})
+	require.NoError(t, err)
+	var content string
+	for event := range streamed.Stream {
+		require.NotEqual(t, model.StreamEventTypeError, event.Type, "%v", event.Error)
+		if event.Type == model.StreamEventTypeContent {
+			content += event.Content
+		}
+	}
+	assert.Equal(t, "func main() {}", content)
+	assert.Equal(t, "func main() {}", streamed.RunResult.FinalOutput)
+}
+
+func TestRemoteAgentErrors(t *testing.T) {
+	srv := serveAgent(t, agent.NewAgent("CoderAgent").WithModel(mocks.NewScriptedModel()), server.BearerToken("secret"))
+	request := &model.Request{Input: "hi"}
+
+	_, err := remote.NewRemoteAgent(srv.URL, "CoderAgent").GetResponse(context.Background(), request)
+	var providerErr *model.ErrProvider
+	require.True(t, errors.As(err, &providerErr))
+	assert.Equal(t, http.StatusUnauthorized, providerErr.Status)
+	assert.Equal(t, "remote", providerErr.Provider)
+
+	_, err = remote.NewRemoteAgent(srv.URL, "Nobody", remote.WithBearerToken("secret")).GetResponse(context.Background(), request)
+	require.True(t, errors.As(err, &providerErr))
+	assert.Equal(t, http.StatusNotFound, providerErr.Status)
+	assert.Equal(t, "model_not_found", providerErr.Code)
+
+	editorErr := errors.New("no credentials")
+	_, err = remote.NewRemoteAgent(srv.URL, "CoderAgent", remote.WithRequestEditor(func(*http.Request) error { return editorErr })).
+		GetResponse(context.Background(), request)
+	assert.ErrorIs(t, err, editorErr)
+}
+
+func TestRemoteAgentTimeout(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	start := time.Now()
+	_, err := remote.NewRemoteAgent(slow.URL, "CoderAgent", remote.WithTimeout(50*time.Millisecond)).
+		GetResponse(context.Background(), &model.Request{Input: "hi"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
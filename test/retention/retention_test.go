@@ -0,0 +1,206 @@
+package retention_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/memory"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/retention"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tracing"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runFor runs an agent for a user and records its events in the store
+func runFor(t *testing.T, store runner.EventStore, runID, userID string) {
+	a := agent.NewAgent("Assistant").WithModel(mocks.NewScriptedModel(&model.Response{
+		Content: "Your card ending in 4242 is blocked",
+		Usage:   &model.Usage{PromptTokens: 40, CompletionTokens: 10, TotalTokens: 50},
+	}))
+	_, err := runner.NewRunner().WithEventStore(store).Run(context.Background(), a, &runner.RunOptions{
+		Input:     "Why is my card blocked?",
+		RunID:     runID,
+		UserID:    userID,
+		RunConfig: &runner.RunConfig{ModelProvider: &mocks.MockModelProvider{}, TracingDisabled: true},
+	})
+	require.NoError(t, err)
+}
+
+// assertRedacted checks that a run's events kept their usage but lost their content
+func assertRedacted(t *testing.T, store runner.EventStore, runID string) {
+	events, err := store.LoadEvents(context.Background(), runID)
+	require.NoError(t, err)
+	require.NotEmpty(t, events)
+	for _, event := range events {
+		assert.Nil(t, event.Input)
+		assert.Nil(t, event.Output)
+		assert.Empty(t, event.UserID)
+	}
+	view := runner.ProjectRun(events)
+	assert.Equal(t, runner.RunStatusCompleted, view.Status)
+	assert.Nil(t, view.FinalOutput)
+	assert.Equal(t, 50, runner.ProjectUsage(events).Total.TotalTokens)
+}
+
+func TestEventStoresForgetUsers(t *testing.T) {
+	fileStore, err := runner.NewFileEventStore(t.TempDir())
+	require.NoError(t, err)
+
+	for name, store := range map[string]interface {
+		runner.EventStore
+		retention.Store
+	}{"memory": runner.NewMemoryEventStore(), "file": fileStore} {
+		t.Run(name, func(t *testing.T) {
+			runFor(t, store, "run-alice", "alice")
+			runFor(t, store, "run-bob", "bob")
+
+			report, err := store.Forget(context.Background(), "alice")
+			require.NoError(t, err)
+			assert.Greater(t, report.Redacted, 0)
+			assertRedacted(t, store, "run-alice")
+
+			events, err := store.LoadEvents(context.Background(), "run-bob")
+			require.NoError(t, err)
+			assert.Equal(t, "bob", events[0].UserID)
+			assert.Equal(t, "Why is my card blocked?", events[0].Input)
+
+			report, err = store.Forget(context.Background(), "alice")
+			require.NoError(t, err)
+			assert.Zero(t, report.Redacted, "forgetting twice finds nothing left")
+		})
+	}
+}
+
+func TestManagerPurgesExpiredContent(t *testing.T) {
+	store := runner.NewMemoryEventStore()
+	runFor(t, store, "run-1", "alice")
+
+	manager := retention.New(24*time.Hour).WithStore("events", store)
+	reports, err := manager.Purge(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, reports[0].Redacted, "fresh events are kept")
+
+	manager.WithClock(func() time.Time { return time.Now().Add(48 * time.Hour) })
+	reports, err = manager.Purge(context.Background())
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	assert.Equal(t, "events", reports[0].Store)
+	assert.Greater(t, reports[0].Redacted, 0)
+	assertRedacted(t, store, "run-1")
+}
+
+// failingStore is a store whose operations fail
+type failingStore struct{}
+
+func (failingStore) Purge(ctx context.Context, cutoff time.Time) (retention.Report, error) {
+	return retention.Report{}, errors.New("store offline")
+}
+
+func (failingStore) Forget(ctx context.Context, userID string) (retention.Report, error) {
+	return retention.Report{}, errors.New("store offline")
+}
+
+func TestManagerForgetsAcrossStores(t *testing.T) {
+	events := runner.NewMemoryEventStore()
+	runFor(t, events, "run-1", "alice")
+
+	session := memory.NewInMemorySession("alice-chat")
+	require.NoError(t, session.AddItems(context.Background(), []interface{}{"my card number is 4242"}))
+	sessions := retention.Sessions(func(ctx context.Context, userID string) ([]memory.Session, error) {
+		return []memory.Session{session}, nil
+	})
+
+	manager := retention.New(24*time.Hour).
+		WithStore("events", events).
+		WithStore("offline", failingStore{}).
+		WithStore("sessions", sessions)
+
+	reports, err := manager.Forget(context.Background(), "alice")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "forget offline")
+	require.Len(t, reports, 3)
+	assert.Greater(t, reports[0].Redacted, 0, "a failing store does not stop the others")
+	assert.Equal(t, 1, reports[2].Deleted)
+
+	items, err := session.GetItems(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	_, err = manager.Forget(context.Background(), "")
+	assert.Error(t, err)
+}
+
+// writeTrace writes trace events to a trace file
+func writeTrace(t *testing.T, path string, events ...tracing.Event) {
+	var lines []string
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		require.NoError(t, err)
+		lines = append(lines, string(data))
+	}
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600))
+}
+
+// readTrace reads the events of a trace file
+func readTrace(t *testing.T, path string) []map[string]interface{} {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var events []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var event map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestTraceFileRetention(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "trace_Assistant.log")
+	old := time.Now().Add(-72 * time.Hour)
+	writeTrace(t, path,
+		tracing.Event{Type: "model_response", AgentName: "Assistant", Timestamp: old, UserID: "alice", Details: map[string]interface{}{
+			"model":    "gpt-4o",
+			"response": map[string]interface{}{"Content": "Your card ending in 4242", "Usage": map[string]interface{}{"TotalTokens": 50}},
+		}},
+		tracing.Event{Type: "agent_start", AgentName: "Assistant", Timestamp: time.Now(), UserID: "bob", Details: map[string]interface{}{"input": "hello"}},
+		tracing.Event{Type: "agent_start", AgentName: "Assistant", Timestamp: time.Now(), UserID: "alice", Details: map[string]interface{}{"input": "my card"}},
+	)
+	traces := tracing.NewFileRetention(dir)
+
+	report, err := traces.Purge(context.Background(), time.Now().Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Redacted)
+	events := readTrace(t, path)
+	require.Len(t, events, 3)
+	assert.Equal(t, map[string]interface{}{
+		"model": "gpt-4o",
+		"usage": map[string]interface{}{"TotalTokens": float64(50)},
+	}, events[0]["details"])
+	assert.NotContains(t, events[0], "user_id")
+	assert.Equal(t, "hello", events[1]["details"].(map[string]interface{})["input"])
+
+	report, err = traces.Purge(context.Background(), time.Now().Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, report.Redacted, "redacted events are not redacted again")
+
+	report, err = traces.Forget(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Deleted)
+	events = readTrace(t, path)
+	require.Len(t, events, 2)
+	assert.Equal(t, "bob", events[1]["user_id"])
+}
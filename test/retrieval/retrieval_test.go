@@ -0,0 +1,148 @@
+package retrieval_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/retrieval"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wordEmbedder embeds texts as counts of a fixed vocabulary
+type wordEmbedder struct {
+	vocabulary []string
+}
+
+func (e wordEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vector := make([]float32, len(e.vocabulary))
+		for _, word := range strings.Fields(strings.ToLower(text)) {
+			for j, known := range e.vocabulary {
+				if strings.Trim(word, ".,?") == known {
+					vector[j]++
+				}
+			}
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+func newTestIndex(t *testing.T) *retrieval.Index {
+	t.Helper()
+	embedder := wordEmbedder{vocabulary: []string{"refund", "shipping", "password", "days", "reset"}}
+	index := retrieval.NewIndex(embedder, retrieval.NewMemoryStore())
+	require.NoError(t, index.Add(context.Background(),
+		retrieval.Document{ID: "refunds", Content: "A refund is issued within 14 days.", Metadata: map[string]interface{}{"team": "billing"}},
+		retrieval.Document{ID: "shipping", Content: "Shipping takes 3 days.", Metadata: map[string]interface{}{"team": "logistics"}},
+		retrieval.Document{ID: "passwords", Content: "Reset your password from the login page.", Metadata: map[string]interface{}{"team": "accounts"}},
+	))
+	return index
+}
+
+func TestIndexSearchRanksBySimilarity(t *testing.T) {
+	index := newTestIndex(t)
+
+	results, err := index.Search(context.Background(), "how do I reset my password?", 2, nil)
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "passwords", results[0].ID)
+	assert.InDelta(t, 1.0, results[0].Score, 0.001)
+
+	results, err = index.Search(context.Background(), "how many days", 5, retrieval.Filter{"team": "logistics"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "shipping", results[0].ID)
+
+	require.NoError(t, index.Delete(context.Background(), "passwords"))
+	assert.Equal(t, 2, index.Store().(*retrieval.MemoryStore).Len())
+}
+
+func TestSearchTool(t *testing.T) {
+	search := retrieval.NewSearchTool(newTestIndex(t).WithMinScore(0.1))
+	assert.Equal(t, "search_knowledge_base", search.GetName())
+
+	out, err := search.Execute(context.Background(), map[string]interface{}{"query": "refund", "top_k": float64(3)})
+	require.NoError(t, err)
+	response := out.(*retrieval.SearchResponse)
+	require.Len(t, response.Documents, 1)
+	assert.Equal(t, "refunds", response.Documents[0].ID)
+	assert.Equal(t, "A refund is issued within 14 days.", response.Documents[0].Content)
+
+	_, err = search.Execute(context.Background(), map[string]interface{}{})
+	assert.Error(t, err)
+}
+
+func TestMemoryStoreRejectsDimensionMismatch(t *testing.T) {
+	store := retrieval.NewMemoryStore()
+	require.NoError(t, store.Upsert(context.Background(), []retrieval.Record{{Document: retrieval.Document{ID: "a"}, Vector: []float32{1, 0}}}))
+
+	err := store.Upsert(context.Background(), []retrieval.Record{{Document: retrieval.Document{ID: "b"}, Vector: []float32{1, 0, 0}}})
+	assert.ErrorIs(t, err, retrieval.ErrDimensionMismatch)
+}
+
+func TestOpenAIEmbedder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+
+		var body struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, retrieval.DefaultOpenAIEmbeddingModel, body.Model)
+		assert.Equal(t, []string{"a", "b"}, body.Input)
+
+		// Return the embeddings out of order, as the API allows
+		w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer server.Close()
+
+	embedder := retrieval.NewOpenAIEmbedder("test-key").WithBaseURL(server.URL)
+	vectors, err := embedder.Embed(context.Background(), []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, [][]float32{{1, 0}, {0, 1}}, vectors)
+}
+
+func TestQdrantStore(t *testing.T) {
+	var upserted map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/collections/docs/points":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&upserted))
+			w.Write([]byte(`{"result":{"status":"completed"}}`))
+		case "/collections/docs/points/search":
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, float64(2), body["limit"])
+			assert.NotNil(t, body["filter"])
+			w.Write([]byte(`{"result":[{"id":"x","score":0.9,"payload":{"_id":"refunds","content":"Refunds take 14 days","team":"billing"}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	store := retrieval.NewQdrantStore(server.URL, "docs")
+	require.NoError(t, store.Upsert(context.Background(), []retrieval.Record{{
+		Document: retrieval.Document{ID: "refunds", Content: "Refunds take 14 days"},
+		Vector:   []float32{1, 0},
+	}}))
+	points := upserted["points"].([]interface{})
+	require.Len(t, points, 1)
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, points[0].(map[string]interface{})["id"])
+
+	results, err := store.Query(context.Background(), []float32{1, 0}, 2, retrieval.Filter{"team": "billing"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "refunds", results[0].ID)
+	assert.Equal(t, "billing", results[0].Metadata["team"])
+	assert.Equal(t, 0.9, results[0].Score)
+}
@@ -0,0 +1,133 @@
+package runner_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hangingModel calls the lookup tool on the first turn and then waits for the
+// context of the run to end
+type hangingModel struct {
+	waiting chan struct{}
+}
+
+func (m *hangingModel) GetResponse(ctx context.Context, request *model.Request) (*model.Response, error) {
+	if _, ok := request.Input.(string); ok {
+		return toolCallResponse(nil), nil
+	}
+	close(m.waiting)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (m *hangingModel) StreamResponse(ctx context.Context, request *model.Request) (<-chan model.StreamEvent, error) {
+	response, err := m.GetResponse(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan model.StreamEvent, 1)
+	ch <- model.StreamEvent{Type: model.StreamEventTypeDone, Response: response}
+	close(ch)
+	return ch, nil
+}
+
+// startHangingRun starts a run that hangs in its second turn
+func startHangingRun(t *testing.T, r *runner.Runner, id string) <-chan error {
+	m := &hangingModel{waiting: make(chan struct{})}
+	a := agent.NewAgent("worker").WithModel(m).WithTools(newLookupTool())
+
+	done := make(chan error, 1)
+	go func() {
+		res, err := r.Run(context.Background(), a, &runner.RunOptions{Input: "go", RunID: id, RunConfig: newTestRunConfig()})
+		if err == nil {
+			assert.Equal(t, "stopped by operator", res.FinalOutput)
+		}
+		done <- err
+	}()
+
+	select {
+	case <-m.waiting:
+	case <-time.After(2 * time.Second):
+		t.Fatal("run did not reach its second turn")
+	}
+	return done
+}
+
+func TestInspectActiveRun(t *testing.T) {
+	r := runner.NewRunner()
+	done := startHangingRun(t, r, "run-1")
+
+	runs := r.ActiveRuns()
+	require.Len(t, runs, 1)
+	assert.Equal(t, "run-1", runs[0].ID)
+	assert.Equal(t, "worker", runs[0].CurrentAgent)
+	assert.Equal(t, 2, runs[0].Turn)
+	assert.False(t, runs[0].Streaming)
+
+	snapshot, err := r.InspectRun("run-1")
+	require.NoError(t, err)
+	assert.Equal(t, "go", snapshot.Input)
+	require.NotEmpty(t, snapshot.Items)
+	assert.Equal(t, "lookup", snapshot.Items[0].(*result.ToolCallItem).Name)
+
+	require.NoError(t, r.CancelRun("run-1"))
+	assert.ErrorIs(t, <-done, runner.ErrRunCancelled)
+	assert.Empty(t, r.ActiveRuns())
+
+	_, err = r.InspectRun("run-1")
+	assert.ErrorIs(t, err, runner.ErrRunNotActive)
+	assert.ErrorIs(t, r.CancelRun("run-1"), runner.ErrRunNotActive)
+}
+
+func TestCompleteActiveRun(t *testing.T) {
+	r := runner.NewRunner()
+	done := startHangingRun(t, r, "run-2")
+
+	require.NoError(t, r.CompleteRun("run-2", "stopped by operator"))
+	assert.NoError(t, <-done)
+	assert.Empty(t, r.ActiveRuns())
+}
+
+func TestDuplicateRunID(t *testing.T) {
+	r := runner.NewRunner()
+	done := startHangingRun(t, r, "run-3")
+
+	a := agent.NewAgent("other").WithModel(&hangingModel{waiting: make(chan struct{})})
+	_, err := r.Run(context.Background(), a, &runner.RunOptions{Input: "go", RunID: "run-3", RunConfig: newTestRunConfig()})
+	assert.ErrorContains(t, err, "already active")
+
+	require.NoError(t, r.CancelRun("run-3"))
+	<-done
+}
+
+func TestCancelStreamingRun(t *testing.T) {
+	r := runner.NewRunner()
+	m := &hangingModel{waiting: make(chan struct{})}
+	a := agent.NewAgent("worker").WithModel(m).WithTools(newLookupTool())
+
+	stream, err := r.RunStreaming(context.Background(), a, &runner.RunOptions{Input: "go", RunID: "stream-1", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+	<-m.waiting
+
+	runs := r.ActiveRuns()
+	require.Len(t, runs, 1)
+	assert.True(t, runs[0].Streaming)
+
+	require.NoError(t, r.CancelRun("stream-1"))
+	var lastErr error
+	for event := range stream.Stream {
+		if event.Type == model.StreamEventTypeError {
+			lastErr = event.Error
+		}
+	}
+	assert.ErrorIs(t, lastErr, runner.ErrRunCancelled)
+	assert.Empty(t, r.ActiveRuns())
+}
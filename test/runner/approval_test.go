@@ -0,0 +1,111 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDeleteAgent(m model.Model, deleted *int) *agent.Agent {
+	deleteTool := tool.NewFunctionTool("delete_file", "Deletes a file", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		*deleted++
+		return "deleted", nil
+	})
+	return agent.NewAgent("Janitor").WithModel(m).WithTools(deleteTool)
+}
+
+func deleteCallResponse() *model.Response {
+	return &model.Response{
+		ToolCalls: []model.ToolCall{{ID: "call_1", Name: "delete_file", Parameters: map[string]interface{}{"path": "/tmp/x"}}},
+	}
+}
+
+func newApprovalRunConfig() *runner.RunConfig {
+	config := newTestRunConfig()
+	config.ApprovalPolicy = runner.RequireApproval("delete_file")
+	return config
+}
+
+func TestToolCallPausesForApproval(t *testing.T) {
+	deleted := 0
+	m := mocks.NewScriptedModel(deleteCallResponse(), &model.Response{Content: "All clean"})
+	a := newDeleteAgent(m, &deleted)
+
+	r := runner.NewRunner()
+	_, err := r.Run(context.Background(), a, &runner.RunOptions{Input: "clean up", RunConfig: newApprovalRunConfig()})
+
+	var approvalErr *runner.ApprovalRequiredError
+	require.True(t, errors.As(err, &approvalErr), "expected an ApprovalRequiredError, got %v", err)
+	assert.Equal(t, runner.ApprovalKindToolCall, approvalErr.Request.Kind)
+	assert.Equal(t, "delete_file", approvalErr.Request.ToolName)
+	assert.Equal(t, "Janitor", approvalErr.Request.AgentName)
+	assert.Equal(t, 0, deleted)
+
+	res, err := r.Resume(context.Background(), approvalErr.State, runner.Approve())
+	require.NoError(t, err)
+	assert.Equal(t, "All clean", res.FinalOutput)
+	assert.Equal(t, 1, deleted)
+	assert.Equal(t, 2, m.RequestCount())
+}
+
+func TestRejectedToolCallIsReportedToModel(t *testing.T) {
+	deleted := 0
+	m := mocks.NewScriptedModel(deleteCallResponse(), &model.Response{Content: "Okay, I won't"})
+	a := newDeleteAgent(m, &deleted)
+
+	r := runner.NewRunner()
+	_, err := r.Run(context.Background(), a, &runner.RunOptions{Input: "clean up", RunConfig: newApprovalRunConfig()})
+
+	var approvalErr *runner.ApprovalRequiredError
+	require.True(t, errors.As(err, &approvalErr))
+
+	res, err := r.Resume(context.Background(), approvalErr.State, runner.Reject("keep the file"))
+	require.NoError(t, err)
+	assert.Equal(t, "Okay, I won't", res.FinalOutput)
+	assert.Equal(t, 0, deleted)
+
+	var toolResult *result.ToolResultItem
+	for _, item := range res.NewItems {
+		if item, ok := item.(*result.ToolResultItem); ok {
+			toolResult = item
+		}
+	}
+	require.NotNil(t, toolResult)
+	assert.Contains(t, fmt.Sprint(toolResult.Result), "keep the file")
+}
+
+func TestStreamingRunEmitsApprovalRequired(t *testing.T) {
+	deleted := 0
+	m := mocks.NewScriptedModel(deleteCallResponse(), &model.Response{Content: "All clean"})
+	a := newDeleteAgent(m, &deleted)
+
+	r := runner.NewRunner()
+	stream, err := r.RunStreaming(context.Background(), a, &runner.RunOptions{Input: "clean up", RunConfig: newApprovalRunConfig()})
+	require.NoError(t, err)
+
+	var approvalErr *runner.ApprovalRequiredError
+	for event := range stream.Stream {
+		if event.Type == model.StreamEventTypeApprovalRequired {
+			require.NotNil(t, event.ToolCall)
+			assert.Equal(t, "delete_file", event.ToolCall.Name)
+			require.True(t, errors.As(event.Error, &approvalErr))
+		}
+	}
+	require.NotNil(t, approvalErr)
+	assert.Equal(t, 0, deleted)
+
+	res, err := r.Resume(context.Background(), approvalErr.State, runner.Approve())
+	require.NoError(t, err)
+	assert.Equal(t, "All clean", res.FinalOutput)
+	assert.Equal(t, 1, deleted)
+}
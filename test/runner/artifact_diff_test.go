@@ -0,0 +1,98 @@
+package runner_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// requestReview is a coder response delegating a review of its code
+func requestReview(code string) *model.Response {
+	return &model.Response{HandoffCall: &model.HandoffCall{
+		AgentName:  "Reviewer",
+		Parameters: map[string]any{"input": map[string]interface{}{"request": "Review this", "code": code}},
+	}}
+}
+
+func TestReviewerSeesChangesSinceLastReview(t *testing.T) {
+	coderModel := mocks.NewScriptedModel(
+		requestReview("func add(a, b int) int {\n\treturn a - b\n}\n"),
+		requestReview("func add(a, b int) int {\n\treturn a + b\n}\n"),
+		&model.Response{Content: "done"},
+	)
+	reviewerModel := mocks.NewScriptedModel(returnResult("Use + instead of -"), returnResult("LGTM"))
+
+	coder := agent.NewAgent("Coder").WithModel(coderModel)
+	reviewer := agent.NewAgent("Reviewer").WithModel(reviewerModel)
+	coder.WithHandoffs(reviewer)
+	reviewer.WithHandoffs(coder)
+
+	config := newTestRunConfig()
+	config.ReviewDiffs = true
+	_, err := runner.NewRunner().Run(context.Background(), coder, &runner.RunOptions{
+		Input: "Write add", MaxTurns: 10, RunConfig: config,
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, 2, reviewerModel.RequestCount())
+	assert.NotContains(t, fmt.Sprint(reviewerModel.Requests[0].Input), "changes_since_last_review")
+	second := fmt.Sprint(reviewerModel.Requests[1].Input)
+	assert.Contains(t, second, "changes_since_last_review")
+	assert.Contains(t, second, "-\treturn a - b\n+\treturn a + b")
+}
+
+func TestReviewDiffsAreOptIn(t *testing.T) {
+	coderModel := mocks.NewScriptedModel(
+		requestReview("v1\n"),
+		requestReview("v2\n"),
+		&model.Response{Content: "done"},
+	)
+	reviewerModel := mocks.NewScriptedModel(returnResult("ok"), returnResult("ok"))
+
+	coder := agent.NewAgent("Coder").WithModel(coderModel)
+	reviewer := agent.NewAgent("Reviewer").WithModel(reviewerModel)
+	coder.WithHandoffs(reviewer)
+	reviewer.WithHandoffs(coder)
+
+	_, err := runner.NewRunner().Run(context.Background(), coder, &runner.RunOptions{
+		Input: "Write it", MaxTurns: 10, RunConfig: newTestRunConfig(),
+	})
+	require.NoError(t, err)
+	assert.NotContains(t, fmt.Sprint(reviewerModel.Requests[1].Input), "changes_since_last_review")
+}
+
+func TestDiffArtifactTool(t *testing.T) {
+	task := runner.NewTaskContext("task-1", "Coder", "Reviewer")
+	task.SetArtifact("a\nb\n", "code")
+	task.SetArtifact("a\nb\n", "code")
+	task.SetArtifact("a\nc\n", "code")
+	task.SetArtifact("a\nc\nd\n", "code")
+	assert.Equal(t, 3, task.ArtifactVersionCount())
+
+	store := mocks.NewInMemoryTaskStore()
+	require.NoError(t, store.SaveTask(context.Background(), task))
+	r := runner.NewRunner().WithTaskStore(store)
+
+	diffTool := r.DiffArtifactTool()
+	assert.Equal(t, runner.DiffArtifactToolName, diffTool.GetName())
+
+	latest, err := diffTool.Execute(context.Background(), map[string]interface{}{"task_id": "task-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "--- task-1@v2\n+++ task-1@v3\n@@ -1,2 +1,3 @@\n a\n c\n+d\n", latest)
+
+	first, err := diffTool.Execute(context.Background(), map[string]interface{}{"task_id": "task-1", "from_version": 1, "to_version": 2})
+	require.NoError(t, err)
+	assert.Contains(t, first, "-b\n+c\n")
+
+	_, err = diffTool.Execute(context.Background(), map[string]interface{}{"task_id": "task-1", "from_version": 4})
+	assert.Error(t, err)
+	_, err = r.DiffArtifact("missing", 0, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,64 @@
+package runner_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactHistory(t *testing.T) {
+	task := runner.NewTaskContext("task-1", "Orchestrator", "Coder")
+
+	first, added := task.UpdateArtifact("v1", runner.ArtifactTypeCode, "Coder")
+	require.True(t, added)
+	_, added = task.UpdateArtifact("v1", runner.ArtifactTypeCode, "Reviewer")
+	assert.False(t, added, "an unchanged artifact is not a new version")
+	second, added := task.UpdateArtifact("v2", runner.ArtifactTypeCode, "Coder")
+	require.True(t, added)
+
+	history := task.ArtifactHistory()
+	require.Len(t, history, 2)
+	assert.Equal(t, first, history[0])
+	assert.Equal(t, 2, second.Version)
+	assert.Equal(t, "Coder", second.Author)
+	assert.Len(t, second.Hash, 64)
+	assert.NotEqual(t, first.Hash, second.Hash)
+	assert.False(t, second.Timestamp.Before(first.Timestamp))
+	assert.Equal(t, "v2", task.GetArtifact())
+
+	// The history is a copy
+	history[0].Author = "someone else"
+	assert.Equal(t, "Coder", task.ArtifactHistory()[0].Author)
+}
+
+func TestStreamedRunEmitsArtifactVersions(t *testing.T) {
+	orchestratorModel := mocks.NewScriptedModel(
+		&model.Response{HandoffCall: &model.HandoffCall{AgentName: "Writer", Parameters: map[string]any{"input": "Write the helper"}}},
+		&model.Response{Content: "done"},
+	)
+	writerModel := mocks.NewScriptedModel(returnResult("Here it is:\n```go\nfunc helper() {}\n```\n"))
+
+	stream, err := runner.NewRunner().RunStreaming(context.Background(), newValidatedOrchestration(orchestratorModel, writerModel), &runner.RunOptions{
+		Input: "Add a helper", MaxTurns: 5, RunConfig: newTestRunConfig(),
+	})
+	require.NoError(t, err)
+
+	var updates []*model.ArtifactUpdate
+	for event := range stream.Stream {
+		require.NoError(t, event.Error)
+		if event.Type == model.StreamEventTypeArtifact {
+			updates = append(updates, event.Artifact)
+		}
+	}
+
+	require.Len(t, updates, 1)
+	assert.Equal(t, "Writer", updates[0].Author)
+	assert.Equal(t, runner.ArtifactTypeCode, updates[0].ArtifactType)
+	assert.Equal(t, "func helper() {}\n", updates[0].Artifact)
+	assert.Equal(t, 1, updates[0].Version)
+}
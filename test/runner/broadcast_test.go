@@ -0,0 +1,176 @@
+package runner_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBroadcastExecutor(name, answer string) *agent.Agent {
+	return agent.NewAgent(name).WithModel(mocks.NewScriptedModel(&model.Response{Content: answer}))
+}
+
+func TestBroadcastHandoffReconcilesResults(t *testing.T) {
+	checkerA := newBroadcastExecutor("CheckerA", "true")
+	checkerB := newBroadcastExecutor("CheckerB", "true")
+	checkerC := newBroadcastExecutor("CheckerC", "false")
+
+	var reconciledFrom []agent.BroadcastResult
+	broadcast := agent.NewBroadcastHandoff("fact_check", checkerA, checkerB, checkerC).
+		WithReconcileFunc(func(ctx context.Context, input interface{}, results []agent.BroadcastResult) (interface{}, error) {
+			reconciledFrom = results
+			votes := 0
+			for _, res := range results {
+				if res.Output == "true" {
+					votes++
+				}
+			}
+			if votes*2 > len(results) {
+				return "verdict: true", nil
+			}
+			return "verdict: false", nil
+		})
+
+	delegatorModel := mocks.NewScriptedModel(
+		&model.Response{HandoffCall: &model.HandoffCall{
+			AgentName:  "fact_check",
+			Parameters: map[string]interface{}{"input": "Is the sky blue?"},
+		}},
+		&model.Response{Content: "The claim holds."},
+	)
+	delegator := agent.NewAgent("Delegator").WithModel(delegatorModel).WithBroadcastHandoffs(broadcast)
+
+	store := mocks.NewInMemoryTaskStore()
+	r := runner.NewRunner().WithTaskStore(store)
+	res, err := r.Run(context.Background(), delegator, &runner.RunOptions{
+		Input:     "Check the claim",
+		RunConfig: newTestRunConfig(),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "The claim holds.", res.FinalOutput)
+
+	// Every executor ran and the reconciler saw all results in executor order
+	assert.Len(t, reconciledFrom, 3)
+	assert.Equal(t, "CheckerA", reconciledFrom[0].AgentName)
+	assert.Equal(t, "false", reconciledFrom[2].Output)
+
+	// The delegator resumed with the reconciled output
+	assert.Equal(t, 2, delegatorModel.RequestCount())
+	assert.Equal(t, "verdict: true", delegatorModel.Requests[1].Input)
+
+	// The broadcast tool was offered to the model
+	handoffs := delegatorModel.Requests[0].Handoffs
+	assert.Len(t, handoffs, 1)
+	function := handoffs[0].(map[string]interface{})["function"].(map[string]interface{})
+	assert.Equal(t, "handoff_to_fact_check", function["name"])
+
+	// All subtasks completed and the parent task recorded the transitions
+	assert.Len(t, storedTasksByStatus(t, store, runner.TaskStatusCompleted), 4)
+
+	handoffCount := 0
+	for _, item := range res.NewItems {
+		if _, ok := item.(*result.HandoffItem); ok {
+			handoffCount++
+		}
+	}
+	assert.Equal(t, 4, handoffCount)
+}
+
+func TestBroadcastHandoffWithoutReconcilerSummarizes(t *testing.T) {
+	broadcast := agent.NewBroadcastHandoff("poll",
+		newBroadcastExecutor("First", "answer one"),
+		newBroadcastExecutor("Second", "answer two"),
+	)
+
+	delegatorModel := mocks.NewScriptedModel(
+		&model.Response{HandoffCall: &model.HandoffCall{
+			AgentName:  "poll",
+			Parameters: map[string]interface{}{"input": "question"},
+		}},
+		&model.Response{Content: "done"},
+	)
+	delegator := agent.NewAgent("Delegator").WithModel(delegatorModel).WithBroadcastHandoffs(broadcast)
+
+	_, err := runner.NewRunner().Run(context.Background(), delegator, &runner.RunOptions{
+		Input:     "ask",
+		RunConfig: newTestRunConfig(),
+	})
+	assert.NoError(t, err)
+
+	summary, ok := delegatorModel.Requests[1].Input.(string)
+	assert.True(t, ok)
+	assert.True(t, strings.Contains(summary, "[First]\nanswer one"))
+	assert.True(t, strings.Contains(summary, "[Second]\nanswer two"))
+}
+
+func TestBroadcastExecutorsShareParentRunSlot(t *testing.T) {
+	var called sync.WaitGroup
+	called.Add(1)
+	release := make(chan struct{})
+	broadcast := agent.NewBroadcastHandoff("poll",
+		newBroadcastExecutor("First", "answer one"),
+		agent.NewAgent("Second").WithModel(&gatedModel{
+			ScriptedModel: mocks.NewScriptedModel(&model.Response{Content: "answer two"}),
+			arrived:       &called,
+			release:       release,
+		}),
+	).WithReconciler(newBroadcastExecutor("Reconciler", "both agree"))
+
+	delegatorModel := mocks.NewScriptedModel(
+		&model.Response{HandoffCall: &model.HandoffCall{
+			AgentName:  "poll",
+			Parameters: map[string]interface{}{"input": "question"},
+		}},
+		&model.Response{Content: "done"},
+	)
+	delegator := agent.NewAgent("Delegator").WithModel(delegatorModel).WithBroadcastHandoffs(broadcast)
+
+	r := runner.NewRunner().WithMaxConcurrentRuns(1)
+	done := make(chan error, 1)
+	go func() {
+		res, err := r.Run(context.Background(), delegator, &runner.RunOptions{
+			Input:     "ask",
+			RunConfig: newTestRunConfig(),
+		})
+		if err == nil {
+			assert.Equal(t, "done", res.FinalOutput)
+		}
+		done <- err
+	}()
+
+	// While the executors run, the broadcast still holds the only slot
+	arrived := make(chan struct{})
+	go func() {
+		called.Wait()
+		close(arrived)
+	}()
+	select {
+	case <-arrived:
+	case err := <-done:
+		t.Fatalf("the broadcast ended before its executors ran: %v", err)
+	}
+	_, err := r.Run(context.Background(), answeringAgent("no room"), &runner.RunOptions{Input: "?", RunConfig: newTestRunConfig()})
+	assert.ErrorIs(t, err, runner.ErrTooManyRuns)
+
+	close(release)
+	require.NoError(t, <-done)
+	assert.Equal(t, "both agree", delegatorModel.Requests[1].Input)
+	assert.Empty(t, r.ActiveRuns())
+}
+
+// newTestRunConfig returns a run config for agents that carry their own model instance
+func newTestRunConfig() *runner.RunConfig {
+	return &runner.RunConfig{
+		ModelProvider:   &mocks.MockModelProvider{},
+		TracingDisabled: true,
+	}
+}
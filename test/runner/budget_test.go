@@ -0,0 +1,87 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newLookupTool() tool.Tool {
+	return tool.NewFunctionTool("lookup", "Looks something up", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return "found", nil
+	})
+}
+
+func toolCallResponse(usage *model.Usage) *model.Response {
+	return &model.Response{
+		ToolCalls: []model.ToolCall{{ID: "call_1", Name: "lookup", Parameters: map[string]interface{}{}}},
+		Usage:     usage,
+	}
+}
+
+func TestTokenBudgetAbortsRun(t *testing.T) {
+	m := mocks.NewScriptedModel(
+		toolCallResponse(&model.Usage{PromptTokens: 500, CompletionTokens: 100, TotalTokens: 600}),
+		toolCallResponse(&model.Usage{PromptTokens: 500, CompletionTokens: 100, TotalTokens: 600}),
+		&model.Response{Content: "never reached"},
+	)
+	a := agent.NewAgent("Assistant").WithModel(m).WithTools(newLookupTool())
+
+	config := newTestRunConfig()
+	config.MaxTotalTokens = 1000
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "hi", RunConfig: config})
+
+	var budgetErr *runner.BudgetExceededError
+	require.True(t, errors.As(err, &budgetErr), "expected a BudgetExceededError, got %v", err)
+	assert.Equal(t, runner.BudgetLimitTokens, budgetErr.Limit)
+	assert.Equal(t, 1200, budgetErr.TotalTokens)
+	assert.Equal(t, 2, m.RequestCount())
+}
+
+func TestCostBudgetHookCanContinueRun(t *testing.T) {
+	m := mocks.NewScriptedModel(
+		toolCallResponse(&model.Usage{PromptTokens: 1000000, CompletionTokens: 0}),
+		&model.Response{Content: "done"},
+	)
+	provider := &mocks.MockModelProvider{}
+	provider.On("GetModel", "gpt-4o-2024-08-06").Return(m, nil)
+
+	a := agent.NewAgent("Assistant").WithModel("gpt-4o-2024-08-06").WithTools(newLookupTool())
+
+	var exceeded []*runner.BudgetExceededError
+	res, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{
+		Input: "hi",
+		RunConfig: &runner.RunConfig{
+			ModelProvider:   provider,
+			TracingDisabled: true,
+			MaxCostUSD:      1.0,
+			OnBudgetExceeded: func(ctx context.Context, err *runner.BudgetExceededError) error {
+				exceeded = append(exceeded, err)
+				return nil
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "done", res.FinalOutput)
+
+	require.Len(t, exceeded, 1)
+	assert.Equal(t, runner.BudgetLimitCost, exceeded[0].Limit)
+	assert.InDelta(t, 2.50, exceeded[0].CostUSD, 1e-9)
+}
+
+func TestPricingTableUsesLongestPrefix(t *testing.T) {
+	pricing, ok := runner.DefaultPricing.Lookup("gpt-4o-mini-2024-07-18")
+	require.True(t, ok)
+	assert.Equal(t, runner.DefaultPricing["gpt-4o-mini"], pricing)
+
+	_, ok = runner.DefaultPricing.Lookup("my-local-model")
+	assert.False(t, ok)
+}
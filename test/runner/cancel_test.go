@@ -0,0 +1,124 @@
+package runner_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stuckTool is a tool that ignores its context and blocks until released
+func stuckTool(started chan<- struct{}, release <-chan struct{}) tool.Tool {
+	return tool.NewFunctionTool("deploy", "Deploys the release", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		close(started)
+		<-release
+		return "deployed", nil
+	})
+}
+
+type runOutcome struct {
+	result *result.RunResult
+	err    error
+}
+
+func TestCancelReturnsPartialResult(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	defer close(release)
+
+	store := runner.NewMemoryEventStore()
+	r := runner.NewRunner().WithEventStore(store)
+	m := mocks.NewScriptedModel(&model.Response{
+		ToolCalls: []model.ToolCall{
+			{ID: "call_1", Name: "deploy", Parameters: map[string]interface{}{}},
+			{ID: "call_2", Name: "deploy", Parameters: map[string]interface{}{}},
+		},
+	})
+	a := agent.NewAgent("Deployer").WithModel(m).WithTools(stuckTool(started, release))
+
+	done := make(chan runOutcome, 1)
+	go func() {
+		res, err := r.Run(context.Background(), a, &runner.RunOptions{Input: "ship it", RunID: "release-1", RunConfig: newTestRunConfig()})
+		done <- runOutcome{res, err}
+	}()
+	<-started
+	require.NoError(t, r.Cancel("release-1"))
+
+	var outcome runOutcome
+	select {
+	case outcome = <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the run did not stop while its tool was blocked")
+	}
+	assert.ErrorIs(t, outcome.err, runner.ErrRunCancelled)
+	require.NotNil(t, outcome.result)
+	assert.True(t, outcome.result.Cancelled)
+	assert.Equal(t, "Deployer", outcome.result.LastAgent.Name)
+
+	var results []*result.ToolResultItem
+	for _, item := range outcome.result.NewItems {
+		if r, ok := item.(*result.ToolResultItem); ok {
+			results = append(results, r)
+		}
+	}
+	require.Len(t, results, 1, "the second tool call is not started")
+	assert.ErrorIs(t, results[0].Error, runner.ErrRunCancelled)
+
+	events, err := store.LoadEvents(context.Background(), "release-1")
+	require.NoError(t, err)
+	assert.Equal(t, runner.EventRunCancelled, events[len(events)-1].Type)
+	assert.Equal(t, runner.RunStatusCancelled, runner.ProjectRun(events).Status)
+}
+
+// silentStreamModel opens a stream that never sends an event
+type silentStreamModel struct {
+	opened chan struct{}
+}
+
+func (m *silentStreamModel) GetResponse(ctx context.Context, request *model.Request) (*model.Response, error) {
+	return nil, nil
+}
+
+func (m *silentStreamModel) StreamResponse(ctx context.Context, request *model.Request) (<-chan model.StreamEvent, error) {
+	close(m.opened)
+	return make(chan model.StreamEvent), nil
+}
+
+func TestCancelStreamingRunEndsStalledStream(t *testing.T) {
+	r := runner.NewRunner()
+	m := &silentStreamModel{opened: make(chan struct{})}
+	stream, err := r.RunStreaming(context.Background(), agent.NewAgent("Writer").WithModel(m), &runner.RunOptions{Input: "go", RunID: "stream-2", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+	<-m.opened
+	require.NoError(t, r.Cancel("stream-2"))
+
+	var last model.StreamEvent
+	for event := range stream.Stream {
+		last = event
+	}
+	assert.Equal(t, model.StreamEventTypeRunCancelled, last.Type)
+	assert.ErrorIs(t, last.Error, runner.ErrRunCancelled)
+	assert.True(t, stream.RunResult.Cancelled)
+}
+
+func TestShutdownCancelsRunsStillActive(t *testing.T) {
+	r := runner.NewRunner()
+	done := startHangingRun(t, r, "run-shutdown")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, r.Shutdown(ctx), context.DeadlineExceeded)
+	assert.ErrorIs(t, <-done, runner.ErrRunCancelled)
+	assert.Empty(t, r.ActiveRuns())
+
+	_, err := r.Run(context.Background(), agent.NewAgent("late"), &runner.RunOptions{Input: "go", RunConfig: newTestRunConfig()})
+	assert.ErrorIs(t, err, runner.ErrRunnerShutdown)
+	assert.NoError(t, r.Shutdown(context.Background()), "nothing is left to wait for")
+}
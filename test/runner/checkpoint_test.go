@@ -0,0 +1,101 @@
+package runner_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runDesignReview runs a workflow in which a designer hands off to a reviewer
+func runDesignReview(t *testing.T, store runner.WorkflowStateStore, retention *runner.CheckpointRetention) *runner.WorkflowRunner {
+	t.Helper()
+	designer := agent.NewAgent("Designer").WithModel(mocks.NewScriptedModel(
+		&model.Response{HandoffCall: &model.HandoffCall{AgentName: "Reviewer", Parameters: map[string]any{"input": "Review the design"}}},
+	))
+	reviewer := agent.NewAgent("Reviewer").WithModel(mocks.NewScriptedModel(&model.Response{Content: "Looks good"}))
+	designer.WithHandoffs(reviewer)
+
+	config := &runner.WorkflowConfig{
+		Phases: []runner.WorkflowPhase{
+			{Name: "design", Agent: "Designer"},
+			{Name: "review", Agent: "Reviewer"},
+		},
+		StateManagement: &runner.StateManagementConfig{
+			PersistState: true,
+			StateStore:   store,
+			WorkflowID:   "cache-design",
+			Retention:    retention,
+		},
+	}
+	wr := runner.NewWorkflowRunner(runner.NewRunner(), config)
+	_, err := wr.RunWorkflow(context.Background(), designer, &runner.RunOptions{
+		Input:          "Design a cache",
+		MaxTurns:       5,
+		RunConfig:      newTestRunConfig(),
+		WorkflowConfig: config,
+	})
+	require.NoError(t, err)
+	return wr
+}
+
+func TestWorkflowCreatesCheckpointsPerPhaseAndTurn(t *testing.T) {
+	store := runner.NewMemoryStateStore()
+	wr := runDesignReview(t, store, nil)
+
+	ids, err := wr.ListCheckpoints("cache-design")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1-design-start", "2-review-start", "3-review-turn2", "4-review-end"}, ids)
+
+	review, err := store.LoadCheckpoint("cache-design", "2-review-start")
+	require.NoError(t, err)
+	assert.Equal(t, "review", review.CurrentPhase)
+	assert.Equal(t, []string{"design"}, review.CompletedPhases)
+}
+
+func TestRestoreFromCheckpoint(t *testing.T) {
+	store := runner.NewMemoryStateStore()
+	wr := runDesignReview(t, store, nil)
+
+	state, err := wr.RestoreFromCheckpoint("cache-design", "1-design-start")
+	require.NoError(t, err)
+	assert.Equal(t, "design", state.CurrentPhase)
+	assert.Empty(t, state.CompletedPhases)
+
+	current, err := store.LoadState("cache-design")
+	require.NoError(t, err)
+	assert.Equal(t, "design", current.(*runner.WorkflowState).CurrentPhase, "the next run resumes from the checkpoint")
+
+	// New checkpoints are numbered after the existing ones
+	runDesignReview(t, store, nil)
+	ids, err := store.ListCheckpoints("cache-design")
+	require.NoError(t, err)
+	assert.Contains(t, ids, "5-review-start")
+	assert.Contains(t, ids, "1-design-start", "earlier checkpoints are kept")
+
+	_, err = wr.RestoreFromCheckpoint("cache-design", "99-design-start")
+	assert.ErrorIs(t, err, runner.ErrCheckpointNotFound)
+}
+
+func TestCheckpointRetention(t *testing.T) {
+	store := runner.NewMemoryStateStore()
+	runDesignReview(t, store, &runner.CheckpointRetention{MaxCheckpoints: 3, KeepPhaseStarts: true})
+	ids, err := store.ListCheckpoints("cache-design")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1-design-start", "2-review-start", "4-review-end"}, ids)
+
+	aged := runner.NewMemoryStateStore()
+	old := &runner.WorkflowState{CheckpointID: "old", LastCheckpoint: time.Now().Add(-time.Hour)}
+	require.NoError(t, aged.SaveState("cache-design", old))
+	runDesignReview(t, aged, &runner.CheckpointRetention{MaxAge: time.Minute})
+	ids, err = aged.ListCheckpoints("cache-design")
+	require.NoError(t, err)
+	assert.NotContains(t, ids, "old")
+	assert.Len(t, ids, 4)
+}
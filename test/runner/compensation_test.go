@@ -0,0 +1,99 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runReleaseWorkflow runs a workflow whose deploy phase fails after the plan and
+// ticket phases completed
+func runReleaseWorkflow(t *testing.T, store runner.WorkflowStateStore, plan, ticket runner.CompensationFunc) error {
+	t.Helper()
+	deployer := agent.NewAgent("Deployer").WithModel(mocks.NewScriptedModel())
+	ticketer := agent.NewAgent("Ticketer").WithModel(mocks.NewScriptedModel(
+		&model.Response{HandoffCall: &model.HandoffCall{AgentName: "Deployer", Parameters: map[string]any{"input": "Deploy TICKET-1"}}},
+	))
+	planner := agent.NewAgent("Planner").WithModel(mocks.NewScriptedModel(
+		&model.Response{HandoffCall: &model.HandoffCall{AgentName: "Ticketer", Parameters: map[string]any{"input": "Open a ticket"}}},
+	))
+	planner.WithHandoffs(ticketer)
+	ticketer.WithHandoffs(deployer)
+
+	config := &runner.WorkflowConfig{
+		Phases: []runner.WorkflowPhase{
+			{Name: "plan", Agent: "Planner", Compensate: plan},
+			{Name: "ticket", Agent: "Ticketer", Compensate: ticket},
+			{Name: "deploy", Agent: "Deployer"},
+		},
+		StateManagement: &runner.StateManagementConfig{
+			PersistState: true,
+			StateStore:   store,
+			WorkflowID:   "release",
+		},
+	}
+	_, err := runner.NewWorkflowRunner(runner.NewRunner(), config).RunWorkflow(context.Background(), planner, &runner.RunOptions{
+		Input:          "Release 1.2",
+		MaxTurns:       5,
+		RunConfig:      newTestRunConfig(),
+		WorkflowConfig: config,
+	})
+	return err
+}
+
+func TestWorkflowCompensatesCompletedPhasesInReverseOrder(t *testing.T) {
+	var order []string
+	var causes []error
+	compensation := func(phase string) runner.CompensationFunc {
+		return func(ctx context.Context, state *runner.WorkflowState, cause error) error {
+			order = append(order, phase)
+			causes = append(causes, cause)
+			return nil
+		}
+	}
+
+	store := runner.NewMemoryStateStore()
+	err := runReleaseWorkflow(t, store, compensation("plan"), compensation("ticket"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no responses left", "the workflow still fails with its own error")
+	assert.Equal(t, []string{"ticket", "plan"}, order)
+	for _, cause := range causes {
+		assert.Equal(t, err, cause)
+	}
+
+	saved, err := store.LoadState("release")
+	require.NoError(t, err)
+	state := saved.(*runner.WorkflowState)
+	require.Len(t, state.Compensations, 2)
+	assert.Equal(t, "ticket", state.Compensations[0].Phase)
+	assert.Empty(t, state.Compensations[0].Error)
+	assert.Contains(t, state.CheckpointID, "compensation")
+}
+
+func TestWorkflowReportsFailedCompensations(t *testing.T) {
+	var planCompensated bool
+	store := runner.NewMemoryStateStore()
+	err := runReleaseWorkflow(t, store,
+		func(ctx context.Context, state *runner.WorkflowState, cause error) error {
+			planCompensated = true
+			return nil
+		},
+		func(ctx context.Context, state *runner.WorkflowState, cause error) error {
+			return errors.New("ticket tracker unavailable")
+		})
+
+	var compErr *runner.CompensationError
+	require.ErrorAs(t, err, &compErr)
+	require.Len(t, compErr.Failed, 1)
+	assert.Equal(t, "ticket", compErr.Failed[0].Phase)
+	assert.Equal(t, "ticket tracker unavailable", compErr.Failed[0].Error)
+	assert.Contains(t, compErr.Unwrap().Error(), "no responses left")
+	assert.True(t, planCompensated, "a failed compensation does not stop the others")
+}
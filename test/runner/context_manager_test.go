@@ -0,0 +1,110 @@
+package runner_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/message"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// longHistory returns a history of user and assistant messages of about 100
+// tokens each
+func longHistory(n int) []interface{} {
+	formatter := model.DefaultMessageFormatter{}
+	history := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		text := fmt.Sprintf("message %d %s", i, strings.Repeat("x", 400))
+		if i%2 == 0 {
+			history = append(history, formatter.FormatUserMessage(text))
+		} else {
+			history = append(history, formatter.FormatAssistantMessage(&model.Response{Content: text}))
+		}
+	}
+	return history
+}
+
+// messageContent returns the content of a history item
+func messageContent(item interface{}) string {
+	msg, _ := item.(message.Message)
+	return msg.Text()
+}
+
+func TestContextManagerSummarizesOlderTurns(t *testing.T) {
+	summarizer := mocks.NewScriptedModel(&model.Response{Content: "The user asked for a report.", Usage: &model.Usage{TotalTokens: 50}})
+	main := mocks.NewScriptedModel(&model.Response{Content: "done"})
+
+	config := newTestRunConfig()
+	config.ContextManager = runner.NewContextManager().
+		WithContextWindow(1000).
+		WithKeepRecent(3).
+		WithSummaryModel(summarizer)
+
+	history := longHistory(20)
+	a := agent.NewAgent("Writer").WithModel(main)
+	res, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: history, RunConfig: config})
+	require.NoError(t, err)
+	assert.Equal(t, "done", res.FinalOutput)
+
+	require.Equal(t, 1, summarizer.RequestCount())
+	assert.Contains(t, summarizer.Requests[0].Input, "message 0 ")
+	assert.NotContains(t, summarizer.Requests[0].Input, "message 19 ")
+
+	input := main.Requests[0].Input.([]interface{})
+	require.Len(t, input, 4)
+	assert.Contains(t, messageContent(input[0]), "The user asked for a report.")
+	assert.Equal(t, history[17:], input[1:])
+}
+
+func TestContextManagerDropsOlderTurnsWithoutSummaryModel(t *testing.T) {
+	main := mocks.NewScriptedModel(&model.Response{Content: "done"})
+	config := newTestRunConfig()
+	config.ContextManager = runner.NewContextManager().WithContextWindow(1000).WithKeepRecent(2)
+
+	history := longHistory(20)
+	a := agent.NewAgent("Writer").WithModel(main)
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: history, RunConfig: config})
+	require.NoError(t, err)
+
+	input := main.Requests[0].Input.([]interface{})
+	assert.Equal(t, []interface{}{history[0], history[18], history[19]}, input)
+}
+
+func TestContextManagerKeepsToolResultsWithTheirCalls(t *testing.T) {
+	main := mocks.NewScriptedModel(&model.Response{Content: "done"})
+	config := newTestRunConfig()
+	config.ContextManager = runner.NewContextManager().WithContextWindow(1000).WithKeepRecent(1)
+
+	formatter := model.DefaultMessageFormatter{}
+	call := model.ToolCall{ID: "call-1", Name: "lookup", Parameters: map[string]interface{}{}}
+	history := append(longHistory(20),
+		formatter.FormatAssistantMessage(&model.Response{ToolCalls: []model.ToolCall{call}}),
+		formatter.FormatToolResult(call, "found it"),
+	)
+
+	a := agent.NewAgent("Writer").WithModel(main)
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: history, RunConfig: config})
+	require.NoError(t, err)
+
+	input := main.Requests[0].Input.([]interface{})
+	assert.Equal(t, []interface{}{history[0], history[20], history[21]}, input)
+}
+
+func TestContextManagerLeavesShortHistoryAlone(t *testing.T) {
+	main := mocks.NewScriptedModel(&model.Response{Content: "done"})
+	config := newTestRunConfig()
+	config.ContextManager = runner.NewContextManager().WithContextWindow(100000)
+
+	history := longHistory(4)
+	a := agent.NewAgent("Writer").WithModel(main)
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: history, RunConfig: config})
+	require.NoError(t, err)
+	assert.Equal(t, history, main.Requests[0].Input)
+}
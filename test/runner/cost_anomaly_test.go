@@ -0,0 +1,121 @@
+package runner_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runWithMonitor runs an agent through the scripted responses with a cost monitor
+func runWithMonitor(t *testing.T, monitor *runner.CostMonitor, responses ...*model.Response) {
+	t.Helper()
+	a := agent.NewAgent("Assistant").WithModel(mocks.NewScriptedModel(responses...)).WithTools(newLookupTool())
+	config := newTestRunConfig()
+	config.CostMonitor = monitor
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "hi", RunConfig: config})
+	require.NoError(t, err)
+}
+
+func tokenUsage(tokens int) *model.Usage {
+	return &model.Usage{TotalTokens: tokens}
+}
+
+func TestCostMonitorAlertsOnRunaway(t *testing.T) {
+	var anomalies []*runner.CostAnomaly
+	monitor := runner.NewCostMonitor().WithMinSamples(3).WithAlertHandler(func(ctx context.Context, anomaly *runner.CostAnomaly) {
+		anomalies = append(anomalies, anomaly)
+	})
+
+	// Learn the baseline from normal runs
+	for i := 0; i < 3; i++ {
+		runWithMonitor(t, monitor, &model.Response{Content: "done", Usage: tokenUsage(100)})
+	}
+	assert.Empty(t, anomalies)
+
+	// A run stuck in a tool loop exceeds three times the baseline and is reported once
+	runWithMonitor(t, monitor,
+		toolCallResponse(tokenUsage(200)),
+		toolCallResponse(tokenUsage(200)),
+		toolCallResponse(tokenUsage(200)),
+		&model.Response{Content: "done", Usage: tokenUsage(200)},
+	)
+	require.Len(t, anomalies, 1)
+	assert.Equal(t, runner.CostAnomalyRun, anomalies[0].Kind)
+	assert.Equal(t, "Assistant", anomalies[0].Agent)
+	assert.NotEmpty(t, anomalies[0].RunID)
+	assert.Equal(t, 400, anomalies[0].Tokens)
+	assert.InDelta(t, 100, anomalies[0].BaselineTokens, 0.001)
+	assert.InDelta(t, 4, anomalies[0].Multiple, 0.001)
+
+	// The anomalous run is not part of the baseline
+	stats := monitor.Stats()
+	require.Len(t, stats, 1)
+	assert.Equal(t, 3, stats[0].Runs)
+	assert.InDelta(t, 100, stats[0].RunTokens, 0.001)
+	assert.Equal(t, 1, stats[0].RunAnomalies)
+}
+
+func TestCostMonitorAlertsOnWindow(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var anomalies []*runner.CostAnomaly
+	monitor := runner.NewCostMonitor().
+		WithMinSamples(2).
+		WithRunMultiple(100).
+		WithClock(func() time.Time { return now }).
+		WithAlertHandler(func(ctx context.Context, anomaly *runner.CostAnomaly) {
+			anomalies = append(anomalies, anomaly)
+		})
+
+	// One run an hour
+	runWithMonitor(t, monitor, &model.Response{Content: "done", Usage: tokenUsage(100)})
+	now = now.Add(time.Hour)
+	runWithMonitor(t, monitor, &model.Response{Content: "done", Usage: tokenUsage(100)})
+	now = now.Add(time.Hour)
+
+	// Many runs in one hour exceed the window baseline
+	for i := 0; i < 5; i++ {
+		runWithMonitor(t, monitor, &model.Response{Content: "done", Usage: tokenUsage(100)})
+	}
+	require.Len(t, anomalies, 1)
+	assert.Equal(t, runner.CostAnomalyWindow, anomalies[0].Kind)
+	assert.Equal(t, 400, anomalies[0].Tokens)
+	assert.Equal(t, now, anomalies[0].WindowStart)
+
+	stats := monitor.Stats()
+	require.Len(t, stats, 1)
+	assert.Equal(t, 2, stats[0].Windows)
+	assert.Equal(t, 1, stats[0].WindowAnomalies)
+}
+
+func TestWebhookAlertPostsAnomaly(t *testing.T) {
+	var mu sync.Mutex
+	var received runner.CostAnomaly
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer server.Close()
+
+	alert := runner.WebhookAlert(server.URL, nil)
+	alert(context.Background(), &runner.CostAnomaly{Kind: runner.CostAnomalyRun, Agent: "Assistant", Tokens: 900, Multiple: 9})
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "Assistant", received.Agent)
+	assert.Equal(t, 900, received.Tokens)
+	assert.Equal(t, 9.0, received.Multiple)
+}
@@ -0,0 +1,184 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSlowTool returns a tool that takes a second unless its context ends first
+func newSlowTool() tool.Tool {
+	return tool.NewFunctionTool("lookup", "Looks something up", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(time.Second):
+			return "found", nil
+		}
+	})
+}
+
+// workResponses returns n responses calling the lookup tool
+func workResponses(n int) []*model.Response {
+	responses := make([]*model.Response, n)
+	for i := range responses {
+		responses[i] = toolCallResponse(nil)
+	}
+	return responses
+}
+
+func TestRunStopsAtMaxWallClock(t *testing.T) {
+	m := mocks.NewScriptedModel(workResponses(5)...)
+	a := agent.NewAgent("Assistant").WithModel(m).WithTools(newSlowTool())
+
+	start := time.Now()
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{
+		Input:        "look it up",
+		MaxWallClock: 50 * time.Millisecond,
+		RunConfig:    newTestRunConfig(),
+	})
+	require.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+
+	var deadlineErr *runner.DeadlineExceededError
+	require.True(t, errors.As(err, &deadlineErr), "got %v", err)
+	assert.Equal(t, runner.DeadlineRun, deadlineErr.Kind)
+	assert.Equal(t, 50*time.Millisecond, deadlineErr.MaxWallClock)
+	assert.ErrorIs(t, err, runner.ErrDeadlineExceeded)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "did not finish within 50ms")
+}
+
+func TestRunPastItsDeadlineDoesNotStart(t *testing.T) {
+	m := mocks.NewScriptedModel(&model.Response{Content: "too late"})
+	a := agent.NewAgent("Assistant").WithModel(m)
+
+	store := runner.NewMemoryEventStore()
+	r := runner.NewRunner().WithEventStore(store)
+	_, err := r.Run(context.Background(), a, &runner.RunOptions{
+		Input:     "hello",
+		RunID:     "late-run",
+		Deadline:  time.Now().Add(-time.Minute),
+		RunConfig: newTestRunConfig(),
+	})
+
+	assert.ErrorIs(t, err, runner.ErrDeadlineExceeded)
+	assert.Equal(t, 0, m.RequestCount())
+
+	events, err := r.RunEvents(context.Background(), "late-run")
+	require.NoError(t, err)
+	types := eventTypes(events)
+	assert.Contains(t, types, runner.EventDeadlineExceeded)
+	assert.Equal(t, runner.EventRunFailed, types[len(types)-1])
+}
+
+func TestEarlierDeadlineWins(t *testing.T) {
+	a := agent.NewAgent("Assistant").WithModel(mocks.NewScriptedModel(workResponses(5)...)).WithTools(newSlowTool())
+
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{
+		Input:        "look it up",
+		Deadline:     time.Now().Add(30 * time.Millisecond),
+		MaxWallClock: time.Hour,
+		RunConfig:    newTestRunConfig(),
+	})
+
+	var deadlineErr *runner.DeadlineExceededError
+	require.True(t, errors.As(err, &deadlineErr), "got %v", err)
+	assert.Zero(t, deadlineErr.MaxWallClock)
+	assert.Contains(t, err.Error(), "did not finish by")
+}
+
+func TestRunawayDelegateReturnsToDelegator(t *testing.T) {
+	managerModel := mocks.NewScriptedModel(delegateTo("Worker", "look it up"), &model.Response{Content: "done without the worker"})
+	workerModel := mocks.NewScriptedModel(workResponses(10)...)
+	manager := agent.NewAgent("Manager").WithModel(managerModel)
+	worker := agent.NewAgent("Worker").WithModel(workerModel).WithTools(newLookupTool()).WithMaxTurnsPerDelegation(3)
+	manager.WithHandoffs(worker)
+	worker.WithHandoffs(manager)
+
+	store := runner.NewMemoryEventStore()
+	tasks := mocks.NewInMemoryTaskStore()
+	r := runner.NewRunner().WithEventStore(store).WithTaskStore(tasks)
+	res, err := r.Run(context.Background(), manager, &runner.RunOptions{Input: "Find it", MaxTurns: 10, RunID: "runaway", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+
+	assert.Equal(t, "done without the worker", res.FinalOutput)
+	assert.Equal(t, 3, workerModel.RequestCount())
+	assert.Contains(t, fmt.Sprint(managerModel.Requests[1].Input), "Worker did not complete the task within 3 turns")
+	assert.Len(t, storedTasksByStatus(t, tasks, runner.TaskStatusFailed), 1)
+
+	events, err := r.RunEvents(context.Background(), "runaway")
+	require.NoError(t, err)
+	var deadlines []runner.RunEvent
+	for _, event := range events {
+		if event.Type == runner.EventDeadlineExceeded {
+			deadlines = append(deadlines, event)
+		}
+	}
+	require.Len(t, deadlines, 1)
+	assert.Equal(t, "Worker", deadlines[0].Agent)
+	assert.Equal(t, runner.DeadlineDelegation, deadlines[0].Reason)
+}
+
+func TestRunawayDelegateWithoutWayBackFailsRun(t *testing.T) {
+	manager := agent.NewAgent("Manager").WithModel(mocks.NewScriptedModel(delegateTo("Worker", "look it up")))
+	worker := agent.NewAgent("Worker").WithModel(mocks.NewScriptedModel(workResponses(10)...)).
+		WithTools(newLookupTool()).WithMaxTurnsPerDelegation(2)
+	manager.WithHandoffs(worker)
+
+	_, err := runner.NewRunner().Run(context.Background(), manager, &runner.RunOptions{Input: "Find it", MaxTurns: 10, RunConfig: newTestRunConfig()})
+
+	var deadlineErr *runner.DeadlineExceededError
+	require.True(t, errors.As(err, &deadlineErr), "got %v", err)
+	assert.Equal(t, runner.DeadlineDelegation, deadlineErr.Kind)
+	assert.Equal(t, "Worker", deadlineErr.Agent)
+	assert.Equal(t, 2, deadlineErr.MaxTurns)
+	assert.NotErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestStreamingRunawayDelegateReturnsToDelegator(t *testing.T) {
+	managerModel := mocks.NewScriptedModel(delegateTo("Worker", "look it up"), &model.Response{Content: "done without the worker"})
+	workerModel := mocks.NewScriptedModel(workResponses(10)...)
+	manager := agent.NewAgent("Manager").WithModel(managerModel)
+	worker := agent.NewAgent("Worker").WithModel(workerModel).WithTools(newLookupTool()).WithMaxTurnsPerDelegation(2)
+	manager.WithHandoffs(worker)
+	worker.WithHandoffs(manager)
+
+	streamed, err := runner.NewRunner().RunStreaming(context.Background(), manager, &runner.RunOptions{Input: "Find it", MaxTurns: 10, RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+	for event := range streamed.Stream {
+		require.NotEqual(t, model.StreamEventTypeError, event.Type, "%v", event.Error)
+	}
+
+	assert.Equal(t, "done without the worker", streamed.RunResult.FinalOutput)
+	assert.Equal(t, 2, workerModel.RequestCount())
+}
+
+func TestStreamingRunStopsAtMaxWallClock(t *testing.T) {
+	a := agent.NewAgent("Assistant").WithModel(mocks.NewScriptedModel(workResponses(5)...)).WithTools(newSlowTool())
+
+	streamed, err := runner.NewRunner().RunStreaming(context.Background(), a, &runner.RunOptions{
+		Input:        "look it up",
+		MaxWallClock: 50 * time.Millisecond,
+		RunConfig:    newTestRunConfig(),
+	})
+	require.NoError(t, err)
+
+	var streamErr error
+	for event := range streamed.Stream {
+		if event.Type == model.StreamEventTypeError {
+			streamErr = event.Error
+		}
+	}
+	assert.ErrorIs(t, streamErr, runner.ErrDeadlineExceeded)
+}
@@ -0,0 +1,83 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandoffToUnknownAgentIsTyped(t *testing.T) {
+	a := agent.NewAgent("Router").WithModel(mocks.NewScriptedModel(
+		&model.Response{HandoffCall: &model.HandoffCall{AgentName: "Ghost", Parameters: map[string]any{"input": "boo"}}},
+	))
+
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "route", RunConfig: newTestRunConfig()})
+	assert.ErrorIs(t, err, runner.ErrHandoffTargetNotFound)
+	assert.ErrorContains(t, err, "Ghost")
+}
+
+func TestStreamedRunExceedingMaxTurnsIsTyped(t *testing.T) {
+	a := agent.NewAgent("Looper").WithTools(newLookupTool()).WithModel(mocks.NewScriptedModel(
+		toolCallResponse(nil), toolCallResponse(nil), toolCallResponse(nil),
+	))
+
+	stream, err := runner.NewRunner().RunStreaming(context.Background(), a, &runner.RunOptions{Input: "go", MaxTurns: 2, RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+	var lastErr error
+	for event := range stream.Stream {
+		if event.Type == model.StreamEventTypeError {
+			lastErr = event.Error
+		}
+	}
+	assert.ErrorIs(t, lastErr, runner.ErrMaxTurnsExceeded)
+}
+
+func TestRunExceedingMaxTurnsReturnsPartialResult(t *testing.T) {
+	a := agent.NewAgent("Looper").WithTools(newLookupTool()).WithModel(mocks.NewScriptedModel(
+		toolCallResponse(nil), toolCallResponse(nil), toolCallResponse(nil),
+	))
+
+	// Sync runs return what they have so far rather than failing
+	res, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "go", MaxTurns: 2, RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+	assert.Nil(t, res.FinalOutput)
+	assert.Len(t, res.RawResponses, 2)
+}
+
+func TestCallToUnknownToolIsTyped(t *testing.T) {
+	a := agent.NewAgent("Caller").WithModel(mocks.NewScriptedModel(
+		&model.Response{ToolCalls: []model.ToolCall{{ID: "call-1", Name: "missing", Parameters: map[string]interface{}{}}}},
+		&model.Response{Content: "done"},
+	))
+
+	res, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "go", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+	assert.Equal(t, "done", res.FinalOutput)
+
+	var toolResult *result.ToolResultItem
+	for _, item := range res.NewItems {
+		if r, ok := item.(*result.ToolResultItem); ok {
+			toolResult = r
+		}
+	}
+	require.NotNil(t, toolResult)
+	assert.ErrorIs(t, toolResult.Error, runner.ErrToolNotFound)
+}
+
+func TestProviderErrorsAreAliased(t *testing.T) {
+	var err error = &model.ErrRateLimited{Err: &model.ErrProvider{Provider: "openai", Status: 429}}
+
+	var rateErr *runner.ErrRateLimited
+	assert.True(t, errors.As(err, &rateErr))
+	var providerErr *runner.ErrProvider
+	require.True(t, errors.As(err, &providerErr))
+	assert.Equal(t, 429, providerErr.Status)
+}
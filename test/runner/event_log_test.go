@@ -0,0 +1,104 @@
+package runner_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventTypes returns the types of events in order
+func eventTypes(events []runner.RunEvent) []string {
+	types := make([]string, len(events))
+	for i, event := range events {
+		types[i] = event.Type
+	}
+	return types
+}
+
+func TestRunIsRecordedAsEventLog(t *testing.T) {
+	store := runner.NewMemoryEventStore()
+	r := runner.NewRunner().WithEventStore(store)
+
+	a := agent.NewAgent("Assistant").WithTools(newLookupTool()).WithModel(mocks.NewScriptedModel(
+		toolCallResponse(&model.Usage{PromptTokens: 80, CompletionTokens: 20, TotalTokens: 100}),
+		&model.Response{Content: "done", Usage: &model.Usage{PromptTokens: 100, CompletionTokens: 10, TotalTokens: 110}},
+	))
+	_, err := r.Run(context.Background(), a, &runner.RunOptions{Input: "look it up", RunID: "run-1", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+
+	events, err := r.RunEvents(context.Background(), "run-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		runner.EventRunStarted,
+		runner.EventTurnStarted, runner.EventModelResponse, runner.EventToolCall, runner.EventToolResult,
+		runner.EventTurnStarted, runner.EventModelResponse,
+		runner.EventRunCompleted,
+	}, eventTypes(events))
+	for i, event := range events {
+		assert.Equal(t, int64(i+1), event.Sequence)
+		assert.Equal(t, "run-1", event.RunID)
+	}
+
+	view := runner.ProjectRun(events)
+	assert.Equal(t, runner.RunStatusCompleted, view.Status)
+	assert.Equal(t, "done", view.FinalOutput)
+	assert.Equal(t, "look it up", view.Input)
+	assert.Equal(t, 2, view.Turn)
+	assert.Equal(t, 1, view.ToolCalls)
+
+	usage := runner.ProjectUsage(events)
+	assert.Equal(t, 2, usage.ModelCalls)
+	assert.Equal(t, 210, usage.Total.TotalTokens)
+	assert.Equal(t, 180, usage.ByAgent["Assistant"].PromptTokens)
+}
+
+func TestTaskBoardIsProjectedFromEvents(t *testing.T) {
+	store := runner.NewMemoryEventStore()
+	r := runner.NewRunner().WithEventStore(store)
+
+	orchestrator := newValidatedOrchestration(
+		mocks.NewScriptedModel(
+			&model.Response{HandoffCall: &model.HandoffCall{AgentName: "Writer", Parameters: map[string]any{"input": "Write the summary"}}},
+			&model.Response{Content: "done"},
+		),
+		mocks.NewScriptedModel(returnResult("The summary")),
+	)
+	_, err := r.Run(context.Background(), orchestrator, &runner.RunOptions{Input: "summarize", RunID: "run-2", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+
+	events, err := r.RunEvents(context.Background(), "run-2")
+	require.NoError(t, err)
+
+	board := runner.ProjectTaskBoard(events)
+	require.Len(t, board, 1)
+	assert.Equal(t, "Orchestrator", board[0].Delegator)
+	assert.Equal(t, "Writer", board[0].Executor)
+	assert.Equal(t, runner.TaskStatusCompleted, board[0].Status)
+	assert.Len(t, board.ByStatus(runner.TaskStatusCompleted), 1)
+	assert.Equal(t, 2, runner.ProjectRun(events).Handoffs)
+}
+
+func TestFailedRunIsRecorded(t *testing.T) {
+	dir := t.TempDir()
+	store, err := runner.NewFileEventStore(dir)
+	require.NoError(t, err)
+	r := runner.NewRunner().WithEventStore(store)
+
+	a := agent.NewAgent("Router").WithModel(mocks.NewScriptedModel(
+		&model.Response{HandoffCall: &model.HandoffCall{AgentName: "Ghost", Parameters: map[string]any{"input": "boo"}}},
+	))
+	_, err = r.Run(context.Background(), a, &runner.RunOptions{Input: "route", RunID: "run-3", RunConfig: newTestRunConfig()})
+	require.Error(t, err)
+
+	events, err := store.LoadEvents(context.Background(), "run-3")
+	require.NoError(t, err)
+	view := runner.ProjectRun(events)
+	assert.Equal(t, runner.RunStatusFailed, view.Status)
+	assert.Contains(t, view.Error, "Ghost")
+}
@@ -0,0 +1,106 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fallbackRecorder records the fallbacks reported to the run hooks
+type fallbackRecorder struct {
+	runner.DefaultRunHooks
+	fallbacks []result.ModelFallback
+}
+
+func (h *fallbackRecorder) OnModelFallback(ctx context.Context, agent runner.AgentType, fallback result.ModelFallback) error {
+	h.fallbacks = append(h.fallbacks, fallback)
+	return nil
+}
+
+// failingModel returns a model whose calls fail with err
+func failingModel(err error) *mocks.MockModel {
+	m := &mocks.MockModel{}
+	m.On("GetResponse", mock.Anything, mock.Anything).Return(nil, err)
+	m.On("StreamResponse", mock.Anything, mock.Anything).Return(nil, err)
+	return m
+}
+
+func TestRateLimitedModelFallsBack(t *testing.T) {
+	rateErr := &model.ErrRateLimited{Err: &model.ErrProvider{Provider: "openai", Status: 429}}
+	backup := mocks.NewScriptedModel(&model.Response{Content: "from backup"})
+
+	provider := &mocks.MockModelProvider{}
+	provider.On("GetModel", "backup").Return(backup, nil)
+
+	hooks := &fallbackRecorder{}
+	config := newTestRunConfig()
+	config.ModelProvider = provider
+	config.ModelFallbacks = []interface{}{"backup"}
+
+	a := agent.NewAgent("Writer").WithModel(failingModel(rateErr))
+	res, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "write", RunConfig: config, Hooks: hooks})
+	require.NoError(t, err)
+	assert.Equal(t, "from backup", res.FinalOutput)
+
+	require.Len(t, res.ModelFallbacks, 1)
+	assert.Equal(t, "Writer", res.ModelFallbacks[0].Agent)
+	assert.Equal(t, "backup", res.ModelFallbacks[0].To)
+	assert.ErrorIs(t, res.ModelFallbacks[0].Error, rateErr)
+	assert.Equal(t, res.ModelFallbacks, hooks.fallbacks)
+}
+
+func TestFallbacksAreTriedInOrder(t *testing.T) {
+	overloaded := &model.ErrProvider{Provider: "anthropic", Status: 529}
+	last := mocks.NewScriptedModel(&model.Response{Content: "from last"})
+
+	config := newTestRunConfig()
+	config.ModelFallbacks = []interface{}{failingModel(overloaded), last}
+
+	a := agent.NewAgent("Writer").WithModel(failingModel(&model.ErrContextLengthExceeded{Model: "small"}))
+	res, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "write", RunConfig: config})
+	require.NoError(t, err)
+	assert.Equal(t, "from last", res.FinalOutput)
+	require.Len(t, res.ModelFallbacks, 2)
+	assert.ErrorIs(t, res.ModelFallbacks[1].Error, overloaded)
+}
+
+func TestOtherErrorsDoNotFallBack(t *testing.T) {
+	backup := mocks.NewScriptedModel(&model.Response{Content: "from backup"})
+	config := newTestRunConfig()
+	config.ModelFallbacks = []interface{}{backup}
+
+	badRequest := &model.ErrProvider{Provider: "openai", Status: 400, Message: "bad request"}
+	a := agent.NewAgent("Writer").WithModel(failingModel(badRequest))
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "write", RunConfig: config})
+	assert.ErrorIs(t, err, badRequest)
+	assert.Equal(t, 0, backup.RequestCount())
+
+	config.FallbackOn = func(err error) bool { return errors.Is(err, badRequest) }
+	res, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "write", RunConfig: config})
+	require.NoError(t, err)
+	assert.Equal(t, "from backup", res.FinalOutput)
+}
+
+func TestStreamedRunFallsBack(t *testing.T) {
+	backup := mocks.NewScriptedModel(&model.Response{Content: "streamed backup"})
+	config := newTestRunConfig()
+	config.ModelFallbacks = []interface{}{backup}
+
+	a := agent.NewAgent("Writer").WithModel(failingModel(&model.ErrProvider{Provider: "openai", Status: 503}))
+	stream, err := runner.NewRunner().RunStreaming(context.Background(), a, &runner.RunOptions{Input: "write", RunConfig: config})
+	require.NoError(t, err)
+	for event := range stream.Stream {
+		require.NotEqual(t, model.StreamEventTypeError, event.Type, "unexpected error: %v", event.Error)
+	}
+	assert.Equal(t, "streamed backup", stream.FinalOutput)
+	assert.Len(t, stream.ModelFallbacks, 1)
+}
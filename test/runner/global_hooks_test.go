@@ -0,0 +1,68 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingHooks appends the name and event of every hook call to a shared log
+type recordingHooks struct {
+	runner.DefaultRunHooks
+	name     string
+	log      *[]string
+	startErr error
+}
+
+func (h *recordingHooks) OnRunStart(ctx context.Context, a *agent.Agent, input interface{}) error {
+	*h.log = append(*h.log, h.name+":start")
+	return h.startErr
+}
+
+func (h *recordingHooks) OnRunEnd(ctx context.Context, res *result.RunResult) error {
+	*h.log = append(*h.log, h.name+":end")
+	return nil
+}
+
+func TestRunnerHooksApplyToEveryRun(t *testing.T) {
+	var log []string
+	r := runner.NewRunner().WithHooks(&recordingHooks{name: "global", log: &log})
+
+	for i := 0; i < 2; i++ {
+		a := agent.NewAgent("Assistant").WithModel(mocks.NewScriptedModel(&model.Response{Content: "ok"}))
+		_, err := r.Run(context.Background(), a, &runner.RunOptions{
+			Input:     "hi",
+			RunConfig: newTestRunConfig(),
+			Hooks:     &recordingHooks{name: "run", log: &log},
+		})
+		require.NoError(t, err)
+	}
+
+	// Runner hooks run before the per-run hooks and are not duplicated across runs
+	assert.Equal(t, []string{
+		"global:start", "run:start", "global:end", "run:end",
+		"global:start", "run:start", "global:end", "run:end",
+	}, log)
+}
+
+func TestRunnerHookErrorStopsRun(t *testing.T) {
+	var log []string
+	r := runner.NewRunner().WithHooks(&recordingHooks{name: "global", log: &log, startErr: errors.New("denied")})
+
+	a := agent.NewAgent("Assistant").WithModel(mocks.NewScriptedModel(&model.Response{Content: "ok"}))
+	_, err := r.Run(context.Background(), a, &runner.RunOptions{
+		Input:     "hi",
+		RunConfig: newTestRunConfig(),
+		Hooks:     &recordingHooks{name: "run", log: &log},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"global:start"}, log)
+}
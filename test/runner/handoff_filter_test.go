@@ -0,0 +1,146 @@
+package runner_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/handoff"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// delegateTo is a response handing a task off to an agent
+func delegateTo(target string, input interface{}) *model.Response {
+	return &model.Response{HandoffCall: &model.HandoffCall{
+		AgentName:  target,
+		Parameters: map[string]any{"input": input},
+	}}
+}
+
+// runDelegation runs a manager that delegates one task to a worker and returns
+// the input the worker received
+func runDelegation(t *testing.T, task interface{}, configure func(manager *agent.Agent, config *runner.RunConfig)) interface{} {
+	managerModel := mocks.NewScriptedModel(delegateTo("Worker", task), &model.Response{Content: "done"})
+	workerModel := mocks.NewScriptedModel(returnResult("finished"))
+
+	manager := agent.NewAgent("Manager").WithModel(managerModel)
+	worker := agent.NewAgent("Worker").WithModel(workerModel)
+	manager.WithHandoffs(worker)
+	worker.WithHandoffs(manager)
+
+	config := newTestRunConfig()
+	if configure != nil {
+		configure(manager, config)
+	}
+	_, err := runner.NewRunner().Run(context.Background(), manager, &runner.RunOptions{
+		Input: "Customer 42 reports that invoices are missing", MaxTurns: 10, RunConfig: config,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, workerModel.RequestCount())
+	return workerModel.Requests[0].Input
+}
+
+func TestHandoffsPassOnlyTheTaskByDefault(t *testing.T) {
+	input := runDelegation(t, "Find the missing invoices", nil)
+	assert.Equal(t, "Find the missing invoices", input)
+}
+
+func TestHandoffFilterForwardsHistory(t *testing.T) {
+	input := runDelegation(t, "Find the missing invoices", func(manager *agent.Agent, config *runner.RunConfig) {
+		manager.WithHandoffFilter("Worker", agent.FullHistory(0))
+	})
+	items, ok := input.([]interface{})
+	require.True(t, ok, "history is passed as a list of messages")
+	require.Len(t, items, 2)
+	assert.Contains(t, fmt.Sprint(items[0]), "Customer 42")
+	assert.Contains(t, fmt.Sprint(items[1]), "Find the missing invoices")
+}
+
+func TestHandoffFilterSummarizesHistory(t *testing.T) {
+	summarizer := mocks.NewScriptedModel(&model.Response{Content: "The customer is number 42."})
+	input := runDelegation(t, "Find the missing invoices", func(manager *agent.Agent, config *runner.RunConfig) {
+		manager.WithHandoffFilter("Worker", agent.SummarizedHistory(runner.ModelHistorySummarizer(summarizer)))
+	})
+	text := fmt.Sprint(input)
+	assert.Contains(t, text, "The customer is number 42.")
+	assert.NotContains(t, text, "invoices are missing", "the raw history stays with the manager")
+	assert.Contains(t, fmt.Sprint(summarizer.Requests[0].Input), "Customer 42")
+}
+
+func TestHandoffFilterShapesTaskAndMetadata(t *testing.T) {
+	input := runDelegation(t, map[string]interface{}{"request": "Find invoices"}, func(manager *agent.Agent, config *runner.RunConfig) {
+		manager.WithHandoffFilter("Worker", agent.ChainHandoffFilters(
+			agent.CleanSlate(),
+			agent.AddHandoffMetadata(map[string]interface{}{"tenant": "acme"}),
+			func(hc agent.HandoffContext) agent.HandoffContext {
+				assert.Equal(t, "Manager", hc.From)
+				assert.Equal(t, "Worker", hc.To)
+				hc.Input.(map[string]interface{})["priority"] = "high"
+				return hc
+			},
+		))
+	})
+	assert.Equal(t, map[string]interface{}{
+		"request":  "Find invoices",
+		"priority": "high",
+		"metadata": map[string]interface{}{"tenant": "acme"},
+	}, input)
+
+	input = runDelegation(t, "Find invoices", func(manager *agent.Agent, config *runner.RunConfig) {
+		manager.WithHandoffFilter("Worker", agent.AddHandoffMetadata(map[string]interface{}{"tenant": "acme"}))
+	})
+	assert.Equal(t, "Find invoices\n\nContext:\ntenant: acme\n", input)
+}
+
+func TestRunHandoffInputFilterAppliesLast(t *testing.T) {
+	input := runDelegation(t, "Find invoices", func(manager *agent.Agent, config *runner.RunConfig) {
+		config.HandoffInputFilter = func(input interface{}) (interface{}, error) {
+			return strings.ToUpper(input.(string)), nil
+		}
+	})
+	assert.Equal(t, "FIND INVOICES", input)
+}
+
+func TestClonesKeepHandoffFilters(t *testing.T) {
+	a := agent.New("Manager", agent.WithHandoffFilter("Worker", agent.CleanSlate()))
+	clone := a.CloneWith()
+	_, ok := clone.HandoffFilterFor("Worker")
+	assert.True(t, ok)
+
+	clone.WithHandoffFilter("Other", agent.FullHistory(2))
+	_, ok = a.HandoffFilterFor("Other")
+	assert.False(t, ok, "filters added to the clone leave the original untouched")
+}
+
+func TestDeclaredHandoffToolCallsReachTarget(t *testing.T) {
+	managerModel := mocks.NewScriptedModel(
+		&model.Response{ToolCalls: []model.ToolCall{{ID: "call_1", Name: "transfer_to_reviewer", Parameters: map[string]interface{}{"input": "Check the login fix"}}}},
+		delegateTo("Design_Agent", "Sketch the login page"),
+		&model.Response{Content: "done"},
+	)
+	reviewerModel := mocks.NewScriptedModel(returnResult("approved"))
+	designerModel := mocks.NewScriptedModel(returnResult("sketched"))
+
+	reviewer := agent.NewAgent("Security Reviewer").WithModel(reviewerModel)
+	designer := agent.NewAgent("Design Agent").WithModel(designerModel)
+	manager := agent.NewAgent("Manager").WithModel(managerModel).
+		WithHandoff(handoff.To(reviewer).WithToolName("transfer_to_reviewer")).
+		WithHandoffs(designer)
+	reviewer.WithHandoffs(manager)
+	designer.WithHandoffs(manager)
+
+	_, err := runner.NewRunner().Run(context.Background(), manager, &runner.RunOptions{
+		Input: "Ship the login page", MaxTurns: 10, RunConfig: newTestRunConfig(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, reviewerModel.RequestCount())
+	assert.Equal(t, "Check the login fix", reviewerModel.Requests[0].Input)
+	require.Equal(t, 1, designerModel.RequestCount(), "sanitized names round-trip to agents with spaces")
+	assert.Equal(t, "Sketch the login page", designerModel.Requests[0].Input)
+}
@@ -0,0 +1,102 @@
+package runner_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ReviewRequest is the typed input of a reviewer
+type ReviewRequest struct {
+	Title string   `json:"title" doc:"Title of the change"`
+	Files []string `json:"files" doc:"Files to review"`
+	Notes string   `json:"notes,omitempty"`
+}
+
+func TestTypedHandoffInputSchema(t *testing.T) {
+	reviewer := agent.New("Reviewer", agent.WithHandoffInputType(ReviewRequest{}))
+	manager := agent.NewAgent("Manager").WithHandoffs(reviewer)
+
+	function := manager.ToolSchemas().Handoffs[0]["function"].(map[string]interface{})
+	assert.Equal(t, "handoff_to_Reviewer", function["name"])
+	parameters := function["parameters"].(map[string]interface{})
+	properties := parameters["properties"].(map[string]interface{})
+	assert.Contains(t, properties, "title")
+	assert.Contains(t, properties, "files")
+	assert.NotContains(t, properties, "input")
+	assert.Equal(t, []string{"title", "files"}, parameters["required"])
+	assert.Equal(t, parameters, reviewer.HandoffInputSchema())
+}
+
+func TestTypedHandoffInputReachesTarget(t *testing.T) {
+	var seen ReviewRequest
+	inspect := tool.NewFunctionTool("inspect", "Inspects the change", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		request, ok := runner.HandoffInput[ReviewRequest](ctx)
+		require.True(t, ok)
+		seen = request
+		return "looks good", nil
+	})
+
+	managerModel := mocks.NewScriptedModel(
+		delegateTo("Reviewer", map[string]interface{}{"title": "Fix login", "files": []interface{}{"auth.go"}, "task_id": "t-1"}),
+		&model.Response{Content: "done"},
+	)
+	reviewerModel := mocks.NewScriptedModel(
+		&model.Response{ToolCalls: []model.ToolCall{{ID: "call_1", Name: "inspect", Parameters: map[string]interface{}{}}}},
+		returnResult("approved"),
+	)
+
+	manager := agent.NewAgent("Manager").WithModel(managerModel)
+	reviewer := agent.New("Reviewer", agent.WithHandoffInputType(&ReviewRequest{})).WithModel(reviewerModel).WithTools(inspect)
+	manager.WithHandoffs(reviewer)
+	reviewer.WithHandoffs(manager)
+
+	_, err := runner.NewRunner().Run(context.Background(), manager, &runner.RunOptions{
+		Input: "Review the login fix", MaxTurns: 10, RunConfig: newTestRunConfig(),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, ReviewRequest{Title: "Fix login", Files: []string{"auth.go"}}, seen)
+	input := fmt.Sprint(reviewerModel.Requests[0].Input)
+	assert.Contains(t, input, `"title": "Fix login"`)
+	assert.NotContains(t, input, "task_id", "fields outside the type are dropped")
+}
+
+func TestInvalidTypedHandoffInputGoesBackToDelegator(t *testing.T) {
+	managerModel := mocks.NewScriptedModel(
+		delegateTo("Reviewer", map[string]interface{}{"files": "auth.go"}),
+		delegateTo("Reviewer", `{"title": "Fix login", "files": ["auth.go"]}`),
+		&model.Response{Content: "done"},
+	)
+	reviewerModel := mocks.NewScriptedModel(returnResult("approved"))
+
+	manager := agent.NewAgent("Manager").WithModel(managerModel)
+	reviewer := agent.NewAgent("Reviewer").WithHandoffInputType(ReviewRequest{}).WithModel(reviewerModel)
+	manager.WithHandoffs(reviewer)
+	reviewer.WithHandoffs(manager)
+
+	_, err := runner.NewRunner().Run(context.Background(), manager, &runner.RunOptions{
+		Input: "Review the login fix", MaxTurns: 10, RunConfig: newTestRunConfig(),
+	})
+	require.NoError(t, err)
+
+	feedback := fmt.Sprint(managerModel.Requests[1].Input)
+	assert.Contains(t, feedback, "input.title is required")
+	assert.Contains(t, feedback, "input.files must be an array")
+	require.Equal(t, 1, reviewerModel.RequestCount(), "the reviewer only gets valid input")
+	assert.Contains(t, fmt.Sprint(reviewerModel.Requests[0].Input), `"title": "Fix login"`)
+}
+
+func TestHandoffInputFromToolArguments(t *testing.T) {
+	assert.Equal(t, "summarize", model.HandoffInput(map[string]interface{}{"input": "summarize", "task_id": "t-1"}))
+	assert.Equal(t, map[string]interface{}{"title": "Fix login"},
+		model.HandoffInput(map[string]interface{}{"title": "Fix login", "task_id": "t-1", "is_task_complete": false}))
+}
@@ -0,0 +1,59 @@
+package runner_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstructionsFuncIsCalledEachTurn(t *testing.T) {
+	var runs []agent.RunContext
+	scripted := mocks.NewScriptedModel(
+		toolCallResponse(tokenUsage(10)),
+		&model.Response{Content: "done"},
+	)
+	a := agent.NewAgent("Assistant", "static").WithTools(newLookupTool()).WithModel(scripted)
+	a.SetInstructionsFunc(func(ctx context.Context, run agent.RunContext) string {
+		runs = append(runs, run)
+		return fmt.Sprintf("Turn %d of %s for %v", run.Turn, run.RunID, run.Input)
+	})
+
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "look it up", RunID: "run-7", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+
+	require.Len(t, runs, 2)
+	assert.Equal(t, agent.RunContext{RunID: "run-7", Turn: 2, Input: "look it up"}, runs[1])
+	require.Len(t, scripted.Requests, 2)
+	assert.Equal(t, "Turn 1 of run-7 for look it up", scripted.Requests[0].SystemInstructions)
+	assert.Equal(t, "Turn 2 of run-7 for look it up", scripted.Requests[1].SystemInstructions)
+}
+
+func TestStreamingUsesInstructionsFunc(t *testing.T) {
+	scripted := mocks.NewScriptedModel(&model.Response{Content: "done"})
+	a := agent.New("Assistant",
+		agent.WithModel(scripted),
+		agent.WithInstructionsFunc(func(ctx context.Context, run agent.RunContext) string {
+			return "Run " + runner.RunIDFromContext(ctx)
+		}),
+	)
+
+	streamed, err := runner.NewRunner().RunStreaming(context.Background(), a, &runner.RunOptions{Input: "hi", RunID: "run-8", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+	for range streamed.Stream {
+	}
+
+	require.Len(t, scripted.Requests, 1)
+	assert.Equal(t, "Run run-8", scripted.Requests[0].SystemInstructions)
+}
+
+func TestStaticInstructionsWithoutFunc(t *testing.T) {
+	a := agent.NewAgent("Assistant", "Be brief.")
+	assert.Equal(t, "Be brief.", a.ResolveInstructions(context.Background(), agent.RunContext{Turn: 1}))
+}
@@ -0,0 +1,188 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// searchCall is a response calling the search tool with a query
+func searchCall(query string) *model.Response {
+	return &model.Response{ToolCalls: []model.ToolCall{{ID: "call_" + query, Name: "search", Parameters: map[string]interface{}{"query": query}}}}
+}
+
+// newCountingSearchTool returns a search tool and the number of times it ran
+func newCountingSearchTool() (tool.Tool, *int) {
+	calls := 0
+	return tool.NewFunctionTool("search", "Searches", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		calls++
+		return "no results", nil
+	}), &calls
+}
+
+// handoffTo is a response handing off to an agent
+func handoffTo(target string) *model.Response {
+	return &model.Response{HandoffCall: &model.HandoffCall{AgentName: target, Parameters: map[string]any{"input": "over to you"}}}
+}
+
+func TestRepeatedToolCallIsNudged(t *testing.T) {
+	search, calls := newCountingSearchTool()
+	m := mocks.NewScriptedModel(
+		searchCall("go"), searchCall("golang"), searchCall("go"), searchCall("go"),
+		&model.Response{Content: "nothing found"},
+	)
+	a := agent.NewAgent("Researcher").WithModel(m).WithTools(search)
+
+	config := newTestRunConfig()
+	config.LoopDetection = &runner.LoopDetection{}
+	res, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "Find it", MaxTurns: 10, RunConfig: config})
+	require.NoError(t, err)
+
+	assert.Equal(t, "nothing found", res.FinalOutput)
+	assert.Equal(t, 3, *calls, "the third identical call is not made")
+	nudge := fmt.Sprint(m.Requests[4].Input)
+	assert.Contains(t, nudge, "You are going in circles")
+	assert.Contains(t, nudge, "Researcher called search with the same arguments 3 times")
+}
+
+func TestRepeatedToolCallAbortsRun(t *testing.T) {
+	search, calls := newCountingSearchTool()
+	m := mocks.NewScriptedModel(searchCall("go"), searchCall("go"), &model.Response{Content: "never reached"})
+	a := agent.NewAgent("Researcher").WithModel(m).WithTools(search)
+
+	config := newTestRunConfig()
+	config.LoopDetection = &runner.LoopDetection{MaxRepeatedToolCalls: 2, Action: runner.LoopActionAbort}
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "Find it", MaxTurns: 10, RunConfig: config})
+	require.Error(t, err)
+
+	assert.ErrorIs(t, err, runner.ErrLoopDetected)
+	var loopErr *runner.LoopDetectedError
+	require.True(t, errors.As(err, &loopErr))
+	assert.Equal(t, runner.LoopRepeatedToolCall, loopErr.Loop.Kind)
+	assert.Equal(t, "search", loopErr.Loop.Tool)
+	assert.Equal(t, map[string]interface{}{"query": "go"}, loopErr.Loop.Parameters)
+	assert.Equal(t, 2, loopErr.Loop.Count)
+	assert.Equal(t, 1, *calls)
+}
+
+func TestPingPongHandoffsAbortRun(t *testing.T) {
+	writerModel := mocks.NewScriptedModel(handoffTo("Editor"), handoffTo("Editor"), handoffTo("Editor"))
+	editorModel := mocks.NewScriptedModel(handoffTo("Writer"), handoffTo("Writer"), handoffTo("Writer"))
+	writer := agent.NewAgent("Writer").WithModel(writerModel)
+	editor := agent.NewAgent("Editor").WithModel(editorModel)
+	writer.WithHandoffs(editor)
+	editor.WithHandoffs(writer)
+
+	config := newTestRunConfig()
+	config.LoopDetection = &runner.LoopDetection{MaxHandoffCycles: 2, Action: runner.LoopActionAbort}
+	_, err := runner.NewRunner().Run(context.Background(), writer, &runner.RunOptions{Input: "Write a story", MaxTurns: 20, RunConfig: config})
+
+	var loopErr *runner.LoopDetectedError
+	require.True(t, errors.As(err, &loopErr), "got %v", err)
+	assert.Equal(t, runner.LoopPingPong, loopErr.Loop.Kind)
+	assert.Equal(t, []string{"Writer", "Editor"}, loopErr.Loop.Agents)
+	assert.Equal(t, 2, loopErr.Loop.Count)
+	assert.Equal(t, 2, writerModel.RequestCount(), "the run stops at the second round trip")
+}
+
+func TestPingPongNudgeReachesReceivingAgent(t *testing.T) {
+	writerModel := mocks.NewScriptedModel(handoffTo("Editor"), handoffTo("Editor"), &model.Response{Content: "The story"})
+	editorModel := mocks.NewScriptedModel(handoffTo("Writer"), handoffTo("Writer"))
+	writer := agent.NewAgent("Writer").WithModel(writerModel)
+	editor := agent.NewAgent("Editor").WithModel(editorModel)
+	writer.WithHandoffs(editor)
+	editor.WithHandoffs(writer)
+
+	config := newTestRunConfig()
+	config.LoopDetection = &runner.LoopDetection{MaxHandoffCycles: 2, Nudge: "Stop handing it back and finish the story."}
+	res, err := runner.NewRunner().Run(context.Background(), writer, &runner.RunOptions{Input: "Write a story", MaxTurns: 20, RunConfig: config})
+	require.NoError(t, err)
+
+	assert.Equal(t, "The story", res.FinalOutput)
+	assert.NotContains(t, fmt.Sprint(writerModel.Requests[1].Input), "Stop handing it back")
+	assert.Contains(t, fmt.Sprint(writerModel.Requests[2].Input), "Stop handing it back and finish the story.")
+}
+
+func TestDistinctAgentsAreNotAPingPong(t *testing.T) {
+	managerModel := mocks.NewScriptedModel(handoffTo("Writer"), handoffTo("Editor"), handoffTo("Writer"), handoffTo("Editor"), &model.Response{Content: "done"})
+	manager := agent.NewAgent("Manager").WithModel(managerModel)
+	writer := agent.NewAgent("Writer").WithModel(mocks.NewScriptedModel(handoffTo("Manager"), handoffTo("Manager")))
+	editor := agent.NewAgent("Editor").WithModel(mocks.NewScriptedModel(handoffTo("Manager"), handoffTo("Manager")))
+	manager.WithHandoffs(writer, editor)
+	writer.WithHandoffs(manager)
+	editor.WithHandoffs(manager)
+
+	config := newTestRunConfig()
+	config.LoopDetection = &runner.LoopDetection{MaxHandoffCycles: 2, Action: runner.LoopActionAbort}
+	res, err := runner.NewRunner().Run(context.Background(), manager, &runner.RunOptions{Input: "Publish", MaxTurns: 20, RunConfig: config})
+	require.NoError(t, err)
+	assert.Equal(t, "done", res.FinalOutput)
+}
+
+func TestLoopHookDecides(t *testing.T) {
+	search, calls := newCountingSearchTool()
+	m := mocks.NewScriptedModel(searchCall("go"), searchCall("go"), searchCall("go"), &model.Response{Content: "done"})
+	a := agent.NewAgent("Researcher").WithModel(m).WithTools(search)
+
+	var loops []*runner.Loop
+	config := newTestRunConfig()
+	config.LoopDetection = &runner.LoopDetection{
+		MaxRepeatedToolCalls: 2,
+		Action:               runner.LoopActionHook,
+		OnLoop: func(ctx context.Context, loop *runner.Loop) error {
+			loops = append(loops, loop)
+			if loop.Count > 2 {
+				return errors.New("too many searches")
+			}
+			return nil
+		},
+	}
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "Find it", MaxTurns: 10, RunConfig: config})
+	require.Error(t, err)
+
+	assert.Contains(t, err.Error(), "too many searches")
+	require.Len(t, loops, 2)
+	assert.Equal(t, 2, *calls, "the hook let the second call through")
+}
+
+func TestLoopNudgesEscalateToAbort(t *testing.T) {
+	search, _ := newCountingSearchTool()
+	m := mocks.NewScriptedModel(searchCall("go"), searchCall("go"), searchCall("go"), &model.Response{Content: "never reached"})
+	a := agent.NewAgent("Researcher").WithModel(m).WithTools(search)
+
+	config := newTestRunConfig()
+	config.LoopDetection = &runner.LoopDetection{MaxRepeatedToolCalls: 2, MaxNudges: 1}
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "Find it", MaxTurns: 10, RunConfig: config})
+
+	assert.ErrorIs(t, err, runner.ErrLoopDetected)
+	assert.Equal(t, 3, m.RequestCount())
+}
+
+func TestStreamingLoopDetection(t *testing.T) {
+	search, calls := newCountingSearchTool()
+	m := mocks.NewScriptedModel(searchCall("go"), searchCall("go"), &model.Response{Content: "never reached"})
+	a := agent.NewAgent("Researcher").WithModel(m).WithTools(search)
+
+	config := newTestRunConfig()
+	config.LoopDetection = &runner.LoopDetection{MaxRepeatedToolCalls: 2, Action: runner.LoopActionAbort}
+	streamed, err := runner.NewRunner().RunStreaming(context.Background(), a, &runner.RunOptions{Input: "Find it", MaxTurns: 10, RunConfig: config})
+	require.NoError(t, err)
+
+	var streamErr error
+	for event := range streamed.Stream {
+		if event.Type == model.StreamEventTypeError {
+			streamErr = event.Error
+		}
+	}
+	assert.ErrorIs(t, streamErr, runner.ErrLoopDetected)
+	assert.Equal(t, 1, *calls)
+}
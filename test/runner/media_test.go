@@ -0,0 +1,109 @@
+package runner_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/artifact"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func imageResponse() *model.Response {
+	return &model.Response{
+		Content: "Here is your logo",
+		Media: []model.MediaPart{
+			{Type: model.ContentTypeImage, MediaType: "image/png", Data: []byte("png-bytes")},
+			{Type: model.ContentTypeImage, URL: "https://images.example.com/2.png"},
+		},
+	}
+}
+
+func TestMediaIsStoredAsArtifacts(t *testing.T) {
+	store := artifact.NewMemoryStore()
+	config := newTestRunConfig()
+	config.ArtifactStore = store
+
+	a := agent.NewAgent("Designer").WithModel(mocks.NewScriptedModel(imageResponse()))
+	res, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "draw a logo", RunConfig: config})
+	require.NoError(t, err)
+	assert.Equal(t, "Here is your logo", res.FinalOutput)
+
+	var media []model.MediaPart
+	for _, item := range res.NewItems {
+		if mediaItem, ok := item.(*result.MediaItem); ok {
+			assert.Equal(t, "Designer", mediaItem.AgentName)
+			media = append(media, mediaItem.Media)
+		}
+	}
+	require.Len(t, media, 2)
+	assert.Nil(t, media[0].Data)
+	assert.NotEmpty(t, media[0].ArtifactID)
+	assert.Empty(t, media[1].ArtifactID)
+	assert.Equal(t, "https://images.example.com/2.png", media[1].URL)
+	assert.Equal(t, media, res.RawResponses[0].Media)
+
+	stored, data, err := store.Get(context.Background(), media[0].ArtifactID)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("png-bytes"), data)
+	assert.Equal(t, "image/png", stored.MediaType)
+	assert.Equal(t, "Designer", stored.Metadata["agent"])
+}
+
+func TestMediaOnlyResponseEndsRun(t *testing.T) {
+	m := mocks.NewScriptedModel(&model.Response{Media: []model.MediaPart{{Type: model.ContentTypeImage, URL: "https://images.example.com/1.png"}}})
+	a := agent.NewAgent("Designer").WithModel(m)
+
+	res, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "draw", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+	assert.Equal(t, 1, m.RequestCount())
+	assert.Len(t, res.NewItems, 1)
+}
+
+func TestStreamingForwardsStoredMedia(t *testing.T) {
+	store := artifact.NewMemoryStore()
+	config := newTestRunConfig()
+	config.ArtifactStore = store
+
+	a := agent.NewAgent("Designer").WithModel(mocks.NewScriptedModel(imageResponse()))
+	stream, err := runner.NewRunner().RunStreaming(context.Background(), a, &runner.RunOptions{Input: "draw a logo", RunConfig: config})
+	require.NoError(t, err)
+
+	var events []model.MediaPart
+	for event := range stream.Stream {
+		require.NoError(t, event.Error)
+		if event.Type == model.StreamEventTypeMedia {
+			events = append(events, *event.Media)
+		}
+	}
+	require.Len(t, events, 2)
+	assert.NotEmpty(t, events[0].ArtifactID)
+	assert.Nil(t, events[0].Data)
+
+	var items int
+	for _, item := range stream.RunResult.NewItems {
+		if mediaItem, ok := item.(*result.MediaItem); ok {
+			assert.Equal(t, events[items], mediaItem.Media)
+			items++
+		}
+	}
+	assert.Equal(t, 2, items)
+}
+
+func TestMediaItemsSurviveRunStateEncoding(t *testing.T) {
+	state := &runner.RunState{
+		Items: []result.RunItem{&result.MediaItem{AgentName: "Designer", Media: model.MediaPart{Type: model.ContentTypeImage, ArtifactID: "artifact-1"}}},
+	}
+	data, err := json.Marshal(state)
+	require.NoError(t, err)
+
+	var decoded runner.RunState
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, state.Items, decoded.Items)
+}
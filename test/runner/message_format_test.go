@@ -0,0 +1,76 @@
+package runner_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/message"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/anthropic"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// formattingModel is a scripted model with its own history format
+type formattingModel struct {
+	*mocks.ScriptedModel
+}
+
+func (m *formattingModel) FormatUserMessage(content interface{}) interface{} {
+	return map[string]interface{}{"kind": "user", "text": content}
+}
+
+func (m *formattingModel) FormatAssistantMessage(response *model.Response) interface{} {
+	return map[string]interface{}{"kind": "assistant", "calls": len(response.ToolCalls), "id": response.ToolCalls[0].ID}
+}
+
+func (m *formattingModel) FormatToolResult(call model.ToolCall, result interface{}) interface{} {
+	return map[string]interface{}{"kind": "tool", "id": call.ID, "result": result}
+}
+
+func TestRunnerUsesModelMessageFormatter(t *testing.T) {
+	m := &formattingModel{mocks.NewScriptedModel(toolCallResponse(nil), &model.Response{Content: "done"})}
+	a := agent.NewAgent("worker").WithModel(m).WithTools(newLookupTool())
+
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "look it up", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+
+	require.Len(t, m.Requests, 2)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"kind": "user", "text": "look it up"},
+		map[string]interface{}{"kind": "assistant", "calls": 1, "id": "call_1"},
+		map[string]interface{}{"kind": "tool", "id": "call_1", "result": "found"},
+	}, m.Requests[1].Input)
+}
+
+func TestGeneratedToolCallIDsMatch(t *testing.T) {
+	m := mocks.NewScriptedModel(&model.Response{
+		ToolCalls: []model.ToolCall{{Name: "lookup", Parameters: map[string]interface{}{}}},
+	}, &model.Response{Content: "done"})
+	a := agent.NewAgent("worker").WithModel(m).WithTools(newLookupTool())
+
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "look it up", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+
+	history := m.Requests[1].Input.([]interface{})
+	require.Len(t, history, 3)
+	assistant := history[1].(message.Message)
+	require.Len(t, assistant.ToolCalls, 1)
+	id := assistant.ToolCalls[0].ID
+	assert.NotEmpty(t, id)
+	assert.Equal(t, id, history[2].(message.ToolResult).Call.ID)
+}
+
+func TestAnthropicFormatsToolResultsAsText(t *testing.T) {
+	m, err := anthropic.NewProvider("test-key").GetModel("claude-3-haiku-20240307")
+	require.NoError(t, err)
+
+	formatted := model.FormatterFor(m).FormatToolResult(
+		model.ToolCall{ID: "toolu_1", Name: "lookup"},
+		map[string]interface{}{"temperature": 21},
+	).(message.ToolResult)
+	assert.Equal(t, `{"temperature":21}`, formatted.Content)
+}
@@ -0,0 +1,39 @@
+package runner_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/message"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentPartsSurviveToolTurns(t *testing.T) {
+	m := mocks.NewScriptedModel(
+		toolCallResponse(nil),
+		&model.Response{Content: "a red square"},
+	)
+	a := agent.NewAgent("Vision").WithModel(m).WithTools(newLookupTool())
+
+	parts := []model.ContentPart{
+		model.TextPart("What is in this image?"),
+		model.ImagePart([]byte{0x89, 'P', 'N', 'G'}, "image/png"),
+	}
+	res, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: parts, RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+	assert.Equal(t, "a red square", res.FinalOutput)
+
+	require.Equal(t, 2, m.RequestCount())
+	assert.Equal(t, parts, m.Requests[0].Input)
+
+	followUp, ok := m.Requests[1].Input.([]interface{})
+	require.True(t, ok, "expected a list input, got %T", m.Requests[1].Input)
+	assert.Equal(t, message.Message{Role: message.RoleUser, Content: parts}, followUp[0])
+
+	assert.Equal(t, model.UserMessage(parts...), res.ToInputList()[0])
+}
@@ -0,0 +1,81 @@
+package runner_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamMultiplexerPreservesPerSourceOrder(t *testing.T) {
+	mux := runner.NewStreamMultiplexer(context.Background())
+
+	sources := map[string]chan model.StreamEvent{
+		"a": make(chan model.StreamEvent),
+		"b": make(chan model.StreamEvent),
+	}
+	for label, ch := range sources {
+		require.NoError(t, mux.Add(label, ch))
+	}
+	assert.Error(t, mux.Add("a", make(chan model.StreamEvent)), "labels must be unique")
+	mux.Close()
+
+	for label, ch := range sources {
+		go func(label string, ch chan model.StreamEvent) {
+			for i := 1; i <= 20; i++ {
+				ch <- model.StreamEvent{Type: model.StreamEventTypeContent, Content: fmt.Sprintf("%s-%d", label, i)}
+			}
+			close(ch)
+		}(label, ch)
+	}
+
+	var lastSeq uint64
+	lastSource := map[string]uint64{}
+	closed := map[string]bool{}
+	for ev := range mux.Events() {
+		assert.Equal(t, lastSeq+1, ev.Sequence)
+		lastSeq = ev.Sequence
+
+		assert.False(t, closed[ev.Label], "no events after a source closed")
+		assert.Equal(t, lastSource[ev.Label]+1, ev.SourceSequence)
+		lastSource[ev.Label] = ev.SourceSequence
+
+		if ev.Closed {
+			closed[ev.Label] = true
+			continue
+		}
+		assert.Equal(t, fmt.Sprintf("%s-%d", ev.Label, ev.SourceSequence), ev.Event.Content)
+	}
+
+	assert.Equal(t, uint64(42), lastSeq)
+	assert.True(t, closed["a"])
+	assert.True(t, closed["b"])
+}
+
+func TestRunStreamingMultiplexedLabelsAgents(t *testing.T) {
+	researcher := agent.NewAgent("Researcher").WithModel(mocks.NewScriptedModel(&model.Response{Content: "findings"}))
+	writer := agent.NewAgent("Writer").WithModel(mocks.NewScriptedModel(&model.Response{Content: "draft"}))
+
+	r := runner.NewRunner()
+	mux, err := r.RunStreamingMultiplexed(context.Background(), &runner.RunOptions{
+		Input:     "Topic",
+		RunConfig: newTestRunConfig(),
+	}, researcher, writer)
+	require.NoError(t, err)
+
+	content := map[string]string{}
+	for ev := range mux.Events() {
+		if ev.Event.Type == model.StreamEventTypeContent {
+			content[ev.Label] += ev.Event.Content
+		}
+	}
+
+	assert.Equal(t, "findings", content["Researcher"])
+	assert.Equal(t, "draft", content["Writer"])
+}
@@ -0,0 +1,43 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/output"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputProcessorsCleanFinalOutput(t *testing.T) {
+	m := mocks.NewScriptedModel(&model.Response{Content: "Sure!\n```typescript\nexport const x = 1;\n```"})
+	a := agent.NewAgent("Coder").WithModel(m).WithOutputProcessors(output.ExtractCodeBlock("ts"))
+
+	res, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "write it", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+	assert.Equal(t, "export const x = 1;", res.FinalOutput)
+}
+
+func TestOutputProcessorsInStreamingRun(t *testing.T) {
+	m := mocks.NewScriptedModel(&model.Response{Content: "<answer>42</answer>"})
+	a := agent.NewAgent("Assistant").WithModel(m).WithOutputProcessors(output.TrimXMLWrapper("answer"))
+
+	streamed, err := runner.NewRunner().RunStreaming(context.Background(), a, &runner.RunOptions{Input: "hi", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+	for range streamed.Stream {
+	}
+	assert.Equal(t, "42", streamed.RunResult.FinalOutput)
+}
+
+func TestOutputProcessorErrorFailsRun(t *testing.T) {
+	m := mocks.NewScriptedModel(&model.Response{Content: "```python\nprint(1)\n```"})
+	a := agent.NewAgent("Coder").WithModel(m).WithOutputProcessors(output.ExtractCodeBlock("go"))
+
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "write it", RunConfig: newTestRunConfig()})
+	assert.True(t, errors.Is(err, output.ErrNoCodeBlock))
+}
@@ -0,0 +1,96 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// concurrencyModel echoes its input and records how many calls overlap
+type concurrencyModel struct {
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+func (m *concurrencyModel) GetResponse(ctx context.Context, request *model.Request) (*model.Response, error) {
+	m.mu.Lock()
+	m.running++
+	m.peak = max(m.peak, m.running)
+	m.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	m.mu.Lock()
+	m.running--
+	m.mu.Unlock()
+	return &model.Response{Content: "echo: " + model.ToolResultText(request.Input)}, nil
+}
+
+func (m *concurrencyModel) StreamResponse(ctx context.Context, request *model.Request) (<-chan model.StreamEvent, error) {
+	return nil, errors.New("not supported")
+}
+
+// Peak returns the largest number of calls that overlapped
+func (m *concurrencyModel) Peak() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.peak
+}
+
+func TestRunParallelReturnsResultsInOrder(t *testing.T) {
+	m := &concurrencyModel{}
+	runs := make([]runner.AgentRun, 3)
+	for i, input := range []string{"a", "b", "c"} {
+		runs[i] = runner.AgentRun{
+			Agent:   agent.NewAgent("Worker").WithModel(m),
+			Options: &runner.RunOptions{Input: input, RunConfig: newTestRunConfig()},
+		}
+	}
+
+	results, err := runner.NewRunner().RunParallel(context.Background(), runs)
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, "echo: a", results[0].FinalOutput)
+	assert.Equal(t, "echo: b", results[1].FinalOutput)
+	assert.Equal(t, "echo: c", results[2].FinalOutput)
+	assert.Equal(t, 3, m.Peak(), "runs overlap")
+}
+
+func TestRunParallelLimitsConcurrency(t *testing.T) {
+	m := &concurrencyModel{}
+	opts := &runner.RunOptions{Input: "x", RunConfig: newTestRunConfig()}
+	runs := make([]runner.AgentRun, 4)
+	for i := range runs {
+		runs[i] = runner.AgentRun{Agent: agent.NewAgent("Worker").WithModel(m), Options: opts}
+	}
+
+	results, err := runner.NewRunner().WithMaxParallelRuns(2).RunParallel(context.Background(), runs)
+	require.NoError(t, err)
+	assert.Len(t, results, 4)
+	assert.Equal(t, 2, m.Peak())
+	assert.Equal(t, "x", opts.Input, "shared options are not modified")
+}
+
+func TestRunParallelReportsFailedRuns(t *testing.T) {
+	runs := []runner.AgentRun{
+		{Agent: agent.NewAgent("Good").WithModel(mocks.NewScriptedModel(&model.Response{Content: "done"})), Options: &runner.RunOptions{Input: "go", RunConfig: newTestRunConfig()}},
+		{Agent: agent.NewAgent("Broken").WithModel(mocks.NewScriptedModel()), Options: &runner.RunOptions{Input: "go", RunConfig: newTestRunConfig()}},
+	}
+
+	results, err := runner.NewRunner().RunParallel(context.Background(), runs)
+	var parallelErr *runner.ParallelError
+	require.ErrorAs(t, err, &parallelErr)
+	assert.NoError(t, parallelErr.Errors[0])
+	assert.ErrorContains(t, parallelErr.Errors[1], "run of Broken failed")
+	require.NotNil(t, results[0])
+	assert.Equal(t, "done", results[0].FinalOutput)
+	assert.Nil(t, results[1])
+}
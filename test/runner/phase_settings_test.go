@@ -0,0 +1,64 @@
+package runner_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func float(v float64) *float64 { return &v }
+
+func TestWorkflowAppliesPhaseModelSettings(t *testing.T) {
+	designerModel := mocks.NewScriptedModel(
+		&model.Response{HandoffCall: &model.HandoffCall{AgentName: "Reviewer", Parameters: map[string]any{"input": "Review the design"}}},
+	)
+	reviewerModel := mocks.NewScriptedModel(&model.Response{Content: "Looks good"})
+
+	designer := agent.NewAgent("Designer").WithModel(designerModel)
+	designer.ModelSettings = &model.Settings{Temperature: float(0.5), MaxTokens: new(int)}
+	reviewer := agent.NewAgent("Reviewer").WithModel(reviewerModel)
+	designer.WithHandoffs(reviewer)
+
+	none := "none"
+	config := &runner.WorkflowConfig{
+		Phases: []runner.WorkflowPhase{
+			{Name: "design", Agent: "Designer", ModelSettings: &model.Settings{Temperature: float(0.8)}},
+			{
+				Name:          "review",
+				Agent:         "Reviewer",
+				ModelSettings: &model.Settings{Temperature: float(0.2)},
+				AgentSettings: map[string]*model.Settings{"Reviewer": {ToolChoice: &none}},
+			},
+		},
+	}
+
+	wr := runner.NewWorkflowRunner(runner.NewRunner(), config)
+	res, err := wr.RunWorkflow(context.Background(), designer, &runner.RunOptions{
+		Input:          "Design a cache",
+		MaxTurns:       3,
+		RunConfig:      newTestRunConfig(),
+		WorkflowConfig: config,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Looks good", res.FinalOutput)
+
+	design := designerModel.Requests[0].Settings
+	require.NotNil(t, design)
+	assert.Equal(t, 0.8, *design.Temperature)
+	assert.NotNil(t, design.MaxTokens, "settings the phase does not set are kept")
+
+	review := reviewerModel.Requests[0].Settings
+	require.NotNil(t, review)
+	assert.Equal(t, 0.2, *review.Temperature)
+	require.NotNil(t, review.ToolChoice)
+	assert.Equal(t, "none", *review.ToolChoice)
+
+	// The agent's own settings are not modified
+	assert.Equal(t, 0.5, *designer.ModelSettings.Temperature)
+}
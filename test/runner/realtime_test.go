@@ -0,0 +1,216 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/guardrail"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRealtimeSession plays one scripted response for each response request
+type fakeRealtimeSession struct {
+	mu          sync.Mutex
+	responses   [][]model.StreamEvent
+	texts       []string
+	audio       [][]byte
+	toolResults map[string]string
+	events      chan model.StreamEvent
+	closeOnce   sync.Once
+}
+
+func (s *fakeRealtimeSession) SendText(ctx context.Context, text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.texts = append(s.texts, text)
+	return nil
+}
+
+func (s *fakeRealtimeSession) SendAudio(ctx context.Context, audio []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.audio = append(s.audio, audio)
+	return nil
+}
+
+func (s *fakeRealtimeSession) CommitAudio(ctx context.Context) error {
+	return nil
+}
+
+func (s *fakeRealtimeSession) SendToolResult(ctx context.Context, callID string, output string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.toolResults[callID] = output
+	return nil
+}
+
+func (s *fakeRealtimeSession) CreateResponse(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.responses) == 0 {
+		return errors.New("no scripted response")
+	}
+	for _, event := range s.responses[0] {
+		s.events <- event
+	}
+	s.responses = s.responses[1:]
+	return nil
+}
+
+func (s *fakeRealtimeSession) Events() <-chan model.StreamEvent {
+	return s.events
+}
+
+func (s *fakeRealtimeSession) Close() error {
+	s.closeOnce.Do(func() { close(s.events) })
+	return nil
+}
+
+// fakeRealtimeProvider returns the same session for every connection
+type fakeRealtimeProvider struct {
+	mocks.MockModelProvider
+	session *fakeRealtimeSession
+	config  *model.RealtimeConfig
+}
+
+func (p *fakeRealtimeProvider) ConnectRealtime(ctx context.Context, config *model.RealtimeConfig) (model.RealtimeSession, error) {
+	p.config = config
+	return p.session, nil
+}
+
+func newFakeRealtime(responses ...[]model.StreamEvent) (*fakeRealtimeProvider, *runner.RunConfig) {
+	provider := &fakeRealtimeProvider{session: &fakeRealtimeSession{
+		responses:   responses,
+		toolResults: map[string]string{},
+		events:      make(chan model.StreamEvent, 100),
+	}}
+	return provider, &runner.RunConfig{ModelProvider: provider, TracingDisabled: true}
+}
+
+func textResponse(text string) []model.StreamEvent {
+	return []model.StreamEvent{
+		{Type: model.StreamEventTypeContent, Content: text},
+		{Type: model.StreamEventTypeAudio, Media: &model.MediaPart{Type: model.ContentTypeAudio, MediaType: "audio/pcm", Data: []byte{1, 2}}},
+		{Type: model.StreamEventTypeDone, Done: true, Response: &model.Response{Content: text}},
+	}
+}
+
+// collectUntilDone reads events until the given number of final responses
+func collectUntilDone(t *testing.T, run *runner.RealtimeRun, finals int) []model.StreamEvent {
+	var events []model.StreamEvent
+	for event := range run.Events() {
+		events = append(events, event)
+		if event.Type == model.StreamEventTypeDone && len(event.Response.ToolCalls) == 0 {
+			finals--
+			if finals == 0 {
+				return events
+			}
+		}
+	}
+	t.Fatalf("events ended before %d final responses", finals)
+	return nil
+}
+
+func TestRunRealtimeStreamsResponses(t *testing.T) {
+	provider, config := newFakeRealtime(textResponse("Hello there"), textResponse("Goodbye"))
+	a := agent.NewAgent("Voice").WithModel("gpt-4o-realtime-preview").WithTools(newLookupTool())
+	a.SetSystemInstructions("Be brief")
+	config.Realtime = &model.RealtimeConfig{Voice: "verse"}
+
+	run, err := runner.NewRunner().RunRealtime(context.Background(), a, &runner.RunOptions{Input: "hi", RunConfig: config})
+	require.NoError(t, err)
+
+	events := collectUntilDone(t, run, 1)
+	require.Len(t, events, 3)
+	assert.Equal(t, "Hello there", events[0].Content)
+	assert.Equal(t, model.StreamEventTypeAudio, events[1].Type)
+
+	require.NoError(t, run.SendText(context.Background(), "bye"))
+	collectUntilDone(t, run, 1)
+	require.NoError(t, run.Close())
+
+	res := run.Result()
+	assert.Equal(t, "Goodbye", res.FinalOutput)
+	assert.Len(t, res.RawResponses, 2)
+	assert.Equal(t, []result.RunItem{
+		&result.MessageItem{Role: "user", Content: "hi"},
+		&result.MessageItem{Role: "assistant", Content: "Hello there"},
+		&result.MessageItem{Role: "user", Content: "bye"},
+		&result.MessageItem{Role: "assistant", Content: "Goodbye"},
+	}, res.NewItems)
+
+	assert.Equal(t, []string{"hi", "bye"}, provider.session.texts)
+	assert.Equal(t, "gpt-4o-realtime-preview", provider.config.Model)
+	assert.Equal(t, "Be brief", provider.config.Instructions)
+	assert.Equal(t, "verse", provider.config.Voice)
+	assert.Len(t, provider.config.Tools, 1)
+}
+
+func TestRunRealtimeExecutesToolCalls(t *testing.T) {
+	toolCall := model.ToolCall{ID: "call_7", Name: "lookup", Parameters: map[string]interface{}{}}
+	provider, config := newFakeRealtime(
+		[]model.StreamEvent{
+			{Type: model.StreamEventTypeToolCall, ToolCall: &toolCall},
+			{Type: model.StreamEventTypeDone, Done: true, Response: &model.Response{ToolCalls: []model.ToolCall{toolCall}}},
+		},
+		textResponse("It was found"),
+	)
+	a := agent.NewAgent("Voice").WithTools(newLookupTool())
+
+	run, err := runner.NewRunner().RunRealtime(context.Background(), a, &runner.RunOptions{Input: "look it up", RunConfig: config})
+	require.NoError(t, err)
+
+	events := collectUntilDone(t, run, 1)
+	assert.Equal(t, model.StreamEventTypeToolCall, events[0].Type)
+	assert.Equal(t, "It was found", events[len(events)-1].Response.Content)
+	run.Close()
+
+	assert.Equal(t, map[string]string{"call_7": "found"}, provider.session.toolResults)
+	res := run.Result()
+	assert.Contains(t, res.NewItems, &result.ToolResultItem{Name: "lookup", Result: "found"})
+}
+
+func TestRunRealtimeChecksSpeechWithInputGuardrails(t *testing.T) {
+	provider, config := newFakeRealtime()
+	config.InputGuardrails = []runner.InputGuardrail{
+		guardrail.NewInputGuardrail("no-secrets", func(ctx context.Context, input interface{}) (*guardrail.Result, error) {
+			if strings.Contains(input.(string), "password") {
+				return guardrail.Trip("secret detected"), nil
+			}
+			return guardrail.Pass(), nil
+		}),
+	}
+	a := agent.NewAgent("Voice")
+
+	run, err := runner.NewRunner().RunRealtime(context.Background(), a, &runner.RunOptions{RunConfig: config})
+	require.NoError(t, err)
+
+	err = run.SendText(context.Background(), "my password is hunter2")
+	var tripped *guardrail.GuardrailTripped
+	require.ErrorAs(t, err, &tripped)
+	assert.Empty(t, provider.session.texts)
+
+	// Transcribed speech ends the conversation when a guardrail trips
+	provider.session.events <- model.StreamEvent{Type: model.StreamEventTypeTranscript, Content: "the password is hunter2"}
+
+	var last model.StreamEvent
+	for event := range run.Events() {
+		last = event
+	}
+	assert.Equal(t, model.StreamEventTypeGuardrailTripped, last.Type)
+	assert.Len(t, run.Result().InputGuardrailResults, 2)
+}
+
+func TestRunRealtimeRequiresRealtimeProvider(t *testing.T) {
+	_, err := runner.NewRunner().RunRealtime(context.Background(), agent.NewAgent("Voice"), &runner.RunOptions{RunConfig: newTestRunConfig()})
+	assert.ErrorContains(t, err, "does not support realtime sessions")
+}
@@ -0,0 +1,98 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCountingAgent returns an agent whose lookup tool counts its executions
+func newCountingAgent(m model.Model, executions *int32) *agent.Agent {
+	lookup := tool.NewFunctionTool("lookup", "Looks something up", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		atomic.AddInt32(executions, 1)
+		return "found 42", nil
+	})
+	return agent.NewAgent("Assistant").WithModel(m).WithTools(lookup)
+}
+
+func TestReplayReproducesRecordedRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+
+	var executions int32
+	recorded := mocks.NewScriptedModel(toolCallResponse(nil), &model.Response{Content: "The answer is 42"})
+	res, err := runner.NewRunner().WithRecorder(path).Run(context.Background(), newCountingAgent(recorded, &executions), &runner.RunOptions{
+		Input: "What is the answer?", RunConfig: newTestRunConfig(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "The answer is 42", res.FinalOutput)
+	assert.Equal(t, int32(1), executions)
+
+	recording, err := runner.LoadRecording(path)
+	require.NoError(t, err)
+	require.Len(t, recording.Calls, 3)
+	assert.Equal(t, runner.RecordedModelCall, recording.Calls[0].Kind)
+	assert.Equal(t, runner.RecordedToolCall, recording.Calls[1].Kind)
+	assert.Equal(t, "found 42", recording.Calls[1].Result)
+
+	// The replay neither calls the model nor executes the tool
+	empty := mocks.NewScriptedModel()
+	replayed, err := runner.NewRunner().WithReplay(path).Run(context.Background(), newCountingAgent(empty, &executions), &runner.RunOptions{
+		Input: "What is the answer?", RunConfig: newTestRunConfig(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "The answer is 42", replayed.FinalOutput)
+	assert.Equal(t, 0, empty.RequestCount())
+	assert.Equal(t, int32(1), executions)
+}
+
+func TestReplayStreamingRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stream.json")
+
+	var executions int32
+	recorded := mocks.NewScriptedModel(toolCallResponse(nil), &model.Response{Content: "streamed"})
+	streamed, err := runner.NewRunner().WithRecorder(path).RunStreaming(context.Background(), newCountingAgent(recorded, &executions), &runner.RunOptions{
+		Input: "hi", RunConfig: newTestRunConfig(),
+	})
+	require.NoError(t, err)
+	for range streamed.Stream {
+	}
+	require.Equal(t, "streamed", streamed.RunResult.FinalOutput)
+
+	replayed, err := runner.NewRunner().WithReplay(path).RunStreaming(context.Background(), newCountingAgent(mocks.NewScriptedModel(), &executions), &runner.RunOptions{
+		Input: "hi", RunConfig: newTestRunConfig(),
+	})
+	require.NoError(t, err)
+	for range replayed.Stream {
+	}
+	assert.Equal(t, "streamed", replayed.RunResult.FinalOutput)
+	assert.Equal(t, int32(1), executions)
+}
+
+func TestReplayFailsWhenRunDiverges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "short.json")
+	_, err := runner.NewRunner().WithRecorder(path).Run(context.Background(),
+		agent.NewAgent("Assistant").WithModel(mocks.NewScriptedModel(&model.Response{Content: "hi"})),
+		&runner.RunOptions{Input: "hi", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+
+	// A different agent made no calls in the recording
+	_, err = runner.NewRunner().WithReplay(path).Run(context.Background(),
+		agent.NewAgent("Other").WithModel(mocks.NewScriptedModel()),
+		&runner.RunOptions{Input: "hi", RunConfig: newTestRunConfig()})
+	assert.True(t, errors.Is(err, runner.ErrReplayExhausted))
+
+	_, err = runner.NewRunner().WithReplay(filepath.Join(t.TempDir(), "missing.json")).Run(context.Background(),
+		agent.NewAgent("Assistant").WithModel(mocks.NewScriptedModel()),
+		&runner.RunOptions{Input: "hi", RunConfig: newTestRunConfig()})
+	assert.Error(t, err)
+}
@@ -0,0 +1,78 @@
+package runner_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/memory/redis"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisStateStoreVersionConflict(t *testing.T) {
+	client := mocks.NewRedisClient()
+	first := runner.NewRedisStateStore(client)
+	second := runner.NewRedisStateStore(client)
+
+	require.NoError(t, first.SaveState("deploy", &runner.WorkflowState{CurrentPhase: "build"}))
+	_, err := second.LoadState("deploy")
+	require.NoError(t, err)
+	require.NoError(t, second.SaveState("deploy", &runner.WorkflowState{CurrentPhase: "test"}))
+
+	// The first replica has not seen the second write, and retrying does not
+	// overwrite it either
+	err = first.SaveState("deploy", &runner.WorkflowState{CurrentPhase: "release"})
+	assert.ErrorIs(t, err, redis.ErrVersionConflict)
+	err = first.SaveState("deploy", &runner.WorkflowState{CurrentPhase: "release"})
+	assert.ErrorIs(t, err, redis.ErrVersionConflict)
+
+	state, err := first.LoadState("deploy")
+	require.NoError(t, err)
+	assert.Equal(t, "test", state.(*runner.WorkflowState).CurrentPhase)
+	require.NoError(t, first.SaveState("deploy", &runner.WorkflowState{CurrentPhase: "release"}))
+}
+
+func TestRedisStateStoreCheckpointsAndTTL(t *testing.T) {
+	client := mocks.NewRedisClient()
+	store := runner.NewRedisStateStore(client).WithTTL(time.Hour)
+
+	state, err := store.LoadState("deploy")
+	require.NoError(t, err)
+	assert.Nil(t, state)
+
+	require.NoError(t, store.SaveState("deploy", &runner.WorkflowState{CheckpointID: "1-build-start", CurrentPhase: "build"}))
+	require.NoError(t, store.SaveState("deploy", &runner.WorkflowState{CurrentPhase: "test"}))
+
+	ids, err := store.ListCheckpoints("deploy")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1-build-start", "2"}, ids)
+
+	build, err := store.LoadCheckpoint("deploy", "1-build-start")
+	require.NoError(t, err)
+	assert.Equal(t, "build", build.CurrentPhase)
+	assert.Equal(t, time.Hour, client.TTL("agentsdk:state:{deploy}:checkpoint:1-build-start"))
+
+	missing, err := store.LoadCheckpoint("deploy", "99")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func TestWorkflowResumesFromRedisStateStore(t *testing.T) {
+	client := mocks.NewRedisClient()
+	runDesignReview(t, runner.NewRedisStateStore(client), nil)
+
+	// Another replica picks up the saved state and numbers its checkpoints after it
+	store := runner.NewRedisStateStore(client)
+	runDesignReview(t, store, nil)
+
+	ids, err := store.ListCheckpoints("cache-design")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1-design-start", "2-review-start", "3-review-turn2", "4-review-end",
+		"5-design-start", "6-review-start", "7-review-turn2", "8-review-end"}, ids)
+
+	state, err := store.LoadState("cache-design")
+	require.NoError(t, err)
+	assert.Equal(t, 8, state.(*runner.WorkflowState).CheckpointSequence)
+}
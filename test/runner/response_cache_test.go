@@ -0,0 +1,126 @@
+package runner_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func zeroTemperature() *model.Settings {
+	temperature := 0.0
+	return &model.Settings{Temperature: &temperature}
+}
+
+func TestCachingModelServesRepeatedRequests(t *testing.T) {
+	scripted := mocks.NewScriptedModel(
+		&model.Response{Content: "Paris", Usage: tokenUsage(10)},
+		&model.Response{Content: "Lyon", Usage: tokenUsage(10)},
+	)
+	cached := model.NewCachingModel(scripted, model.NewMemoryResponseCache())
+	request := &model.Request{Input: "Capital of France?", Settings: zeroTemperature()}
+
+	first, err := cached.GetResponse(context.Background(), request)
+	require.NoError(t, err)
+	second, err := cached.GetResponse(context.Background(), &model.Request{Input: "Capital of France?", Settings: zeroTemperature()})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Paris", first.Content)
+	assert.Equal(t, "Paris", second.Content)
+	assert.Nil(t, second.Usage, "a cached response costs nothing")
+	assert.Equal(t, 1, scripted.RequestCount())
+	assert.Equal(t, model.CacheStats{Hits: 1, Misses: 1}, cached.Stats())
+
+	// Invalidating the request calls the model again
+	require.NoError(t, cached.Invalidate(context.Background(), request))
+	third, err := cached.GetResponse(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "Lyon", third.Content)
+}
+
+func TestCachingModelSkipsNonDeterministicRequests(t *testing.T) {
+	scripted := mocks.NewScriptedModel(&model.Response{Content: "a"}, &model.Response{Content: "b"})
+	cached := model.NewCachingModel(scripted, model.NewMemoryResponseCache())
+
+	for _, want := range []string{"a", "b"} {
+		res, err := cached.GetResponse(context.Background(), &model.Request{Input: "pick one"})
+		require.NoError(t, err)
+		assert.Equal(t, want, res.Content)
+	}
+	assert.Equal(t, 2, cached.Stats().Skipped)
+}
+
+func TestCachingModelRefreshAndTTL(t *testing.T) {
+	scripted := mocks.NewScriptedModel(&model.Response{Content: "v1"}, &model.Response{Content: "v2"}, &model.Response{Content: "v3"})
+	cached := model.NewCachingModel(scripted, model.NewMemoryResponseCache()).WithAnyTemperature().WithTTL(50 * time.Millisecond)
+	request := &model.Request{Input: "version?"}
+
+	res, err := cached.GetResponse(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "v1", res.Content)
+
+	res, err = cached.GetResponse(model.WithCacheRefresh(context.Background()), request)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", res.Content)
+
+	res, err = cached.GetResponse(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "v2", res.Content)
+
+	time.Sleep(60 * time.Millisecond)
+	res, err = cached.GetResponse(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "v3", res.Content)
+}
+
+func TestFileResponseCacheSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	request := &model.Request{Input: "hi", Settings: zeroTemperature()}
+
+	cache, err := model.NewFileResponseCache(dir)
+	require.NoError(t, err)
+	_, err = model.NewCachingModel(mocks.NewScriptedModel(&model.Response{Content: "hello"}), cache).GetResponse(context.Background(), request)
+	require.NoError(t, err)
+
+	// A new process with an empty script still answers from the files
+	reopened, err := model.NewFileResponseCache(dir)
+	require.NoError(t, err)
+	res, err := model.NewCachingModel(mocks.NewScriptedModel(), reopened).GetResponse(context.Background(), request)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", res.Content)
+
+	require.NoError(t, reopened.Clear(context.Background()))
+	_, ok, err := reopened.Get(context.Background(), "missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestStreamingRunReportsCacheHit(t *testing.T) {
+	cached := model.NewCachingModel(mocks.NewScriptedModel(&model.Response{Content: "cached answer"}), model.NewMemoryResponseCache()).
+		WithAnyTemperature()
+	a := agent.NewAgent("Assistant").WithModel(cached)
+
+	run := func() ([]string, interface{}) {
+		streamed, err := runner.NewRunner().RunStreaming(context.Background(), a, &runner.RunOptions{Input: "hi", RunConfig: newTestRunConfig()})
+		require.NoError(t, err)
+		var types []string
+		for event := range streamed.Stream {
+			types = append(types, event.Type)
+		}
+		return types, streamed.RunResult.FinalOutput
+	}
+
+	types, output := run()
+	assert.NotContains(t, types, model.StreamEventTypeCacheHit)
+	assert.Equal(t, "cached answer", output)
+
+	types, output = run()
+	assert.Contains(t, types, model.StreamEventTypeCacheHit)
+	assert.Equal(t, "cached answer", output)
+}
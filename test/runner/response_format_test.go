@@ -0,0 +1,72 @@
+package runner_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type Forecast struct {
+	City    string `json:"city"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// schemaModel is a scripted model that supports schema output
+type schemaModel struct {
+	*mocks.ScriptedModel
+	supported bool
+}
+
+func (m *schemaModel) SupportsJSONSchema() bool {
+	return m.supported
+}
+
+func TestOutputTypeRequestsStrictSchema(t *testing.T) {
+	m := &schemaModel{
+		ScriptedModel: mocks.NewScriptedModel(&model.Response{Content: `{"city":"Oslo","summary":null}`}),
+		supported:     true,
+	}
+	a := agent.NewAgent("forecaster").WithModel(m).WithOutputType(Forecast{})
+
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "forecast", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+
+	require.Len(t, m.Requests, 1)
+	format := m.Requests[0].Settings.ResponseFormat
+	require.NotNil(t, format)
+	assert.Equal(t, model.ResponseFormatJSONSchema, format.Type)
+	assert.Equal(t, "Forecast", format.Name)
+	assert.True(t, format.Strict)
+	assert.Equal(t, []string{"city", "summary"}, format.Schema["required"])
+
+	// The agent's own settings are not modified
+	assert.Nil(t, a.ModelSettings)
+}
+
+func TestOutputTypeWithoutSchemaSupport(t *testing.T) {
+	m := &schemaModel{ScriptedModel: mocks.NewScriptedModel(&model.Response{Content: `{"city":"Oslo"}`})}
+	a := agent.NewAgent("forecaster").WithModel(m).WithOutputType(Forecast{})
+
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "forecast", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+	assert.Nil(t, m.Requests[0].Settings.ResponseFormat)
+}
+
+func TestExplicitResponseFormatWins(t *testing.T) {
+	m := &schemaModel{
+		ScriptedModel: mocks.NewScriptedModel(&model.Response{Content: `{"city":"Oslo"}`}),
+		supported:     true,
+	}
+	a := agent.NewAgent("forecaster").WithModel(m).WithOutputType(Forecast{}).
+		WithModelSettings(&model.Settings{ResponseFormat: model.JSONObjectFormat()})
+
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "forecast", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+	assert.Equal(t, model.ResponseFormatJSONObject, m.Requests[0].Settings.ResponseFormat.Type)
+}
@@ -0,0 +1,112 @@
+package runner_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/guardrail"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var titleSchema = map[string]interface{}{
+	"type":       "object",
+	"properties": map[string]interface{}{"title": map[string]interface{}{"type": "string"}},
+	"required":   []string{"title"},
+}
+
+// returnResult is a writer response returning a result to the delegator
+func returnResult(result string) *model.Response {
+	return &model.Response{HandoffCall: &model.HandoffCall{
+		AgentName:      "return_to_delegator",
+		Parameters:     map[string]any{"input": result},
+		IsTaskComplete: true,
+	}}
+}
+
+// newValidatedOrchestration returns an orchestrator delegating to a writer with the given script
+func newValidatedOrchestration(orchestratorModel, writerModel *mocks.ScriptedModel) *agent.Agent {
+	orchestrator := agent.NewAgent("Orchestrator").WithModel(orchestratorModel)
+	writer := agent.NewAgent("Writer").WithModel(writerModel)
+	orchestrator.WithHandoffs(writer)
+	writer.WithHandoffs(orchestrator)
+	return orchestrator
+}
+
+func TestRejectedResultIsDelegatedAgain(t *testing.T) {
+	orchestratorModel := mocks.NewScriptedModel(
+		&model.Response{HandoffCall: &model.HandoffCall{AgentName: "Writer", Parameters: map[string]any{"input": "Return a JSON title"}}},
+		&model.Response{Content: "done"},
+	)
+	writerModel := mocks.NewScriptedModel(returnResult("Here is a title"), returnResult(`{"title":"Q3 report"}`))
+
+	config := newTestRunConfig()
+	config.ResultValidation = []*runner.ResultValidation{runner.ValidateResults("Writer", runner.SchemaValidator(titleSchema))}
+
+	res, err := runner.NewRunner().Run(context.Background(), newValidatedOrchestration(orchestratorModel, writerModel), &runner.RunOptions{
+		Input: "Title the report", MaxTurns: 5, RunConfig: config,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "done", res.FinalOutput)
+
+	// The writer sees its rejected result with the feedback, and the orchestrator only the valid one
+	require.Equal(t, 2, writerModel.RequestCount())
+	retry := fmt.Sprint(writerModel.Requests[1].Input)
+	assert.Contains(t, retry, "Return a JSON title")
+	assert.Contains(t, retry, "Here is a title")
+	assert.Contains(t, retry, "the result must be valid JSON")
+	assert.Contains(t, fmt.Sprint(orchestratorModel.Requests[1].Input), `{"title":"Q3 report"}`)
+}
+
+func TestResultRejectedOnEveryAttemptFailsTask(t *testing.T) {
+	orchestratorModel := mocks.NewScriptedModel(
+		&model.Response{HandoffCall: &model.HandoffCall{AgentName: "Writer", Parameters: map[string]any{"input": "Write a title"}}},
+		&model.Response{Content: "gave up"},
+	)
+	writerModel := mocks.NewScriptedModel(returnResult("TODO"), returnResult("TODO later"))
+
+	noTodos, err := guardrail.NewRegexGuardrail("no-todos", "TODO")
+	require.NoError(t, err)
+	config := newTestRunConfig()
+	config.ResultValidation = []*runner.ResultValidation{
+		runner.ValidateResults("", runner.GuardrailValidator(noTodos)).WithMaxAttempts(2),
+	}
+
+	store := mocks.NewInMemoryTaskStore()
+	r := runner.NewRunner().WithTaskStore(store)
+	_, err = r.Run(context.Background(), newValidatedOrchestration(orchestratorModel, writerModel), &runner.RunOptions{
+		Input: "Title the report", MaxTurns: 5, RunConfig: config,
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, writerModel.RequestCount())
+	assert.Contains(t, fmt.Sprint(orchestratorModel.Requests[1].Input), "rejected after 2 attempts")
+	assert.Len(t, storedTasksByStatus(t, store, runner.TaskStatusFailed), 1)
+}
+
+func TestJudgeValidator(t *testing.T) {
+	judge := agent.NewAgent("Judge").WithModel(mocks.NewScriptedModel(
+		&model.Response{Content: "FAIL: the title is too long"},
+		&model.Response{Content: "PASS"},
+	))
+	orchestratorModel := mocks.NewScriptedModel(
+		&model.Response{HandoffCall: &model.HandoffCall{AgentName: "Writer", Parameters: map[string]any{"input": "Write a short title"}}},
+		&model.Response{Content: "done"},
+	)
+	writerModel := mocks.NewScriptedModel(returnResult("A very long title about the third quarter"), returnResult("Q3"))
+
+	config := newTestRunConfig()
+	config.ResultValidation = []*runner.ResultValidation{runner.ValidateResults("Writer", runner.JudgeValidator(judge))}
+
+	_, err := runner.NewRunner().Run(context.Background(), newValidatedOrchestration(orchestratorModel, writerModel), &runner.RunOptions{
+		Input: "Title the report", MaxTurns: 5, RunConfig: config,
+	})
+	require.NoError(t, err)
+	assert.Contains(t, fmt.Sprint(writerModel.Requests[1].Input), "the title is too long")
+	assert.Contains(t, fmt.Sprint(orchestratorModel.Requests[1].Input), "Q3")
+}
@@ -0,0 +1,105 @@
+package runner_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func answeringAgent(answer string) *agent.Agent {
+	return agent.NewAgent("Answerer").WithModel(mocks.NewScriptedModel(&model.Response{Content: answer}))
+}
+
+func TestRunResultCarriesRunID(t *testing.T) {
+	store := runner.NewMemoryEventStore()
+	r := runner.NewRunner().WithEventStore(store)
+
+	res, err := r.Run(context.Background(), answeringAgent("42"), &runner.RunOptions{Input: "?", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+	assert.Regexp(t, "^run-", res.RunID)
+
+	status, err := r.GetRunStatus(res.RunID)
+	require.NoError(t, err)
+	assert.Equal(t, runner.RunStatusCompleted, status.Status)
+	assert.Equal(t, "Answerer", status.StartingAgent)
+	assert.False(t, status.EndedAt.IsZero())
+
+	stream, err := r.RunStreaming(context.Background(), answeringAgent("43"), &runner.RunOptions{Input: "?", RunID: "stream-3", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+	for range stream.Stream {
+	}
+	assert.Equal(t, "stream-3", stream.RunID)
+	status, err = r.GetRunStatus("stream-3")
+	require.NoError(t, err)
+	assert.Equal(t, runner.RunStatusCompleted, status.Status)
+
+	// Another runner sharing the event store finds the run there
+	status, err = runner.NewRunner().WithEventStore(store).GetRunStatus(res.RunID)
+	require.NoError(t, err)
+	assert.Equal(t, runner.RunStatusCompleted, status.Status)
+	assert.Equal(t, "Answerer", status.StartingAgent)
+}
+
+func TestStreamedRunIDIsSetBeforeStreaming(t *testing.T) {
+	r := runner.NewRunner()
+	stream, err := r.RunStreaming(context.Background(), answeringAgent("44"), &runner.RunOptions{Input: "?", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+
+	// Read while the run is streaming; go test -race catches a late write
+	runID := stream.RunID
+	assert.Regexp(t, "^run-", runID)
+
+	var started string
+	for event := range stream.Stream {
+		if event.Type == model.StreamEventTypeRunStarted {
+			started = event.RunID
+		}
+	}
+	assert.Equal(t, runID, started)
+	assert.Equal(t, runID, stream.RunID)
+	status, err := r.GetRunStatus(runID)
+	require.NoError(t, err)
+	assert.Equal(t, runner.RunStatusCompleted, status.Status)
+}
+
+func TestMaxConcurrentRuns(t *testing.T) {
+	r := runner.NewRunner().WithMaxConcurrentRuns(1)
+	done := startHangingRun(t, r, "run-limited")
+
+	status, err := r.GetRunStatus("run-limited")
+	require.NoError(t, err)
+	assert.Equal(t, runner.RunStatusRunning, status.Status)
+	assert.Equal(t, 2, status.Turn)
+
+	_, err = r.Run(context.Background(), answeringAgent("no room"), &runner.RunOptions{Input: "?", RunConfig: newTestRunConfig()})
+	assert.ErrorIs(t, err, runner.ErrTooManyRuns)
+
+	require.NoError(t, r.Cancel("run-limited"))
+	<-done
+	status, err = r.GetRunStatus("run-limited")
+	require.NoError(t, err)
+	assert.Equal(t, runner.RunStatusCancelled, status.Status)
+	assert.Contains(t, status.Error, "run cancelled")
+
+	_, err = r.Run(context.Background(), answeringAgent("room again"), &runner.RunOptions{Input: "?", RunConfig: newTestRunConfig()})
+	assert.NoError(t, err)
+}
+
+func TestRunHistoryForgetsOldestRuns(t *testing.T) {
+	r := runner.NewRunner().WithRunHistory(1)
+	for _, id := range []string{"first", "second"} {
+		_, err := r.Run(context.Background(), answeringAgent(id), &runner.RunOptions{Input: "?", RunID: id, RunConfig: newTestRunConfig()})
+		require.NoError(t, err)
+	}
+
+	_, err := r.GetRunStatus("first")
+	assert.ErrorIs(t, err, runner.ErrRunNotFound)
+	_, err = r.GetRunStatus("second")
+	assert.NoError(t, err)
+}
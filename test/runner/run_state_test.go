@@ -0,0 +1,101 @@
+package runner_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pauseAfterFirstTurn(ctx context.Context, state *runner.RunState) error {
+	return runner.ErrPauseRun
+}
+
+func TestCheckpointPausesRunAndStateResumesElsewhere(t *testing.T) {
+	first := mocks.NewScriptedModel(toolCallResponse(&model.Usage{TotalTokens: 50}))
+	a := agent.NewAgent("Assistant").WithModel(first).WithTools(newLookupTool())
+
+	config := newTestRunConfig()
+	config.Checkpoint = pauseAfterFirstTurn
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "look it up", RunConfig: config})
+
+	var pausedErr *runner.RunPausedError
+	require.True(t, errors.As(err, &pausedErr), "expected a RunPausedError, got %v", err)
+	assert.True(t, errors.Is(err, runner.ErrPauseRun))
+	assert.Equal(t, 2, pausedErr.State.Turn)
+	assert.Equal(t, 50, pausedErr.State.TotalTokens)
+
+	data, err := json.Marshal(pausedErr.State)
+	require.NoError(t, err)
+
+	// Another process decodes the state and continues with a fresh agent graph
+	var state runner.RunState
+	require.NoError(t, json.Unmarshal(data, &state))
+	assert.Equal(t, 2, state.Turn)
+	require.Len(t, state.Items, 2)
+	assert.IsType(t, &result.ToolCallItem{}, state.Items[0])
+	assert.IsType(t, &result.ToolResultItem{}, state.Items[1])
+
+	second := mocks.NewScriptedModel(&model.Response{Content: "found it"})
+	restored := agent.NewAgent("Assistant").WithModel(second).WithTools(newLookupTool())
+	require.NoError(t, state.Bind(restored))
+
+	res, err := runner.ResumeFromState(context.Background(), &state, &runner.RunOptions{RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+	assert.Equal(t, "found it", res.FinalOutput)
+	assert.Equal(t, "look it up", res.Input)
+	assert.Len(t, res.NewItems, 2)
+	assert.Equal(t, 1, second.RequestCount())
+}
+
+func TestBindResolvesCurrentAgentThroughHandoffs(t *testing.T) {
+	specialist := agent.NewAgent("Specialist")
+	triage := agent.NewAgent("Triage").WithHandoffs(specialist)
+
+	var state runner.RunState
+	require.NoError(t, json.Unmarshal([]byte(`{"starting_agent":"Triage","current_agent":"Specialist","turn":3}`), &state))
+	require.NoError(t, state.Bind(triage))
+	assert.Same(t, triage, state.StartingAgent)
+	assert.Same(t, specialist, state.CurrentAgent)
+
+	var unknown runner.RunState
+	require.NoError(t, json.Unmarshal([]byte(`{"starting_agent":"Triage","current_agent":"Billing"}`), &unknown))
+	assert.Error(t, unknown.Bind(triage))
+}
+
+func TestDecodedApprovalStateResumes(t *testing.T) {
+	deleted := 0
+	_, err := runner.NewRunner().Run(context.Background(), newDeleteAgent(mocks.NewScriptedModel(deleteCallResponse()), &deleted),
+		&runner.RunOptions{Input: "clean up", RunConfig: newApprovalRunConfig()})
+
+	var approvalErr *runner.ApprovalRequiredError
+	require.True(t, errors.As(err, &approvalErr))
+
+	data, err := json.Marshal(approvalErr.State)
+	require.NoError(t, err)
+
+	var state runner.RunState
+	require.NoError(t, json.Unmarshal(data, &state))
+	require.NotNil(t, state.Pending)
+	assert.Equal(t, "delete_file", state.Pending.ToolName)
+
+	m := mocks.NewScriptedModel(&model.Response{Content: "All clean"})
+	require.NoError(t, state.Bind(newDeleteAgent(m, &deleted)))
+
+	_, err = runner.ResumeFromState(context.Background(), &state, &runner.RunOptions{RunConfig: newApprovalRunConfig()})
+	assert.Error(t, err, "a pending approval must be decided first")
+
+	require.NoError(t, state.Decide(runner.Approve()))
+	res, err := runner.ResumeFromState(context.Background(), &state, &runner.RunOptions{RunConfig: newApprovalRunConfig()})
+	require.NoError(t, err)
+	assert.Equal(t, "All clean", res.FinalOutput)
+	assert.Equal(t, 1, deleted)
+}
@@ -0,0 +1,41 @@
+package runner_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// schemaCountingTool counts how often its schema is built
+type schemaCountingTool struct {
+	tool.Tool
+	schemaCalls int
+}
+
+func (t *schemaCountingTool) GetParametersSchema() map[string]interface{} {
+	t.schemaCalls++
+	return t.Tool.GetParametersSchema()
+}
+
+func TestRunBuildsToolSchemasOnce(t *testing.T) {
+	lookup := &schemaCountingTool{Tool: newLookupTool()}
+	m := mocks.NewScriptedModel(toolCallResponse(nil), toolCallResponse(nil), &model.Response{Content: "done"})
+	a := agent.NewAgent("Assistant").WithModel(m).WithTools(lookup)
+
+	res, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "go", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+	assert.Equal(t, "done", res.FinalOutput)
+	assert.Equal(t, 3, m.RequestCount())
+	assert.Equal(t, 1, lookup.schemaCalls)
+
+	for _, request := range m.Requests {
+		require.Len(t, request.Tools, 1)
+	}
+}
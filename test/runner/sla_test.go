@@ -0,0 +1,133 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSlowAgent returns an agent that spends its first turn on a slow tool call
+func newSlowAgent(name string, responses ...*model.Response) (*agent.Agent, *mocks.ScriptedModel) {
+	slow := tool.NewFunctionTool("lookup", "Looks something up slowly", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		time.Sleep(20 * time.Millisecond)
+		return "found", nil
+	})
+	m := mocks.NewScriptedModel(append([]*model.Response{toolCallResponse(nil)}, responses...)...)
+	return agent.NewAgent(name).WithModel(m).WithTools(slow), m
+}
+
+func runWorkflow(t *testing.T, a *agent.Agent, config *runner.WorkflowConfig) (string, error) {
+	t.Helper()
+	wr := runner.NewWorkflowRunner(runner.NewRunner(), config)
+	res, err := wr.RunWorkflow(context.Background(), a, &runner.RunOptions{
+		Input:          "Write the report",
+		MaxTurns:       5,
+		RunConfig:      newTestRunConfig(),
+		WorkflowConfig: config,
+	})
+	if err != nil {
+		return "", err
+	}
+	return res.FinalOutput.(string), nil
+}
+
+func TestSLABreachSwitchesToFasterModel(t *testing.T) {
+	drafter, slowModel := newSlowAgent("Drafter")
+	fastModel := mocks.NewScriptedModel(&model.Response{Content: "Quick draft"})
+
+	var breaches []*runner.SLABreach
+	config := &runner.WorkflowConfig{
+		Phases: []runner.WorkflowPhase{{
+			Name:   "draft",
+			Agent:  "Drafter",
+			Budget: 5 * time.Millisecond,
+			Escalation: &runner.SLAEscalation{
+				Model: fastModel,
+				OnBreach: func(ctx context.Context, breach *runner.SLABreach) error {
+					breaches = append(breaches, breach)
+					return nil
+				},
+			},
+		}},
+	}
+
+	output, err := runWorkflow(t, drafter, config)
+	require.NoError(t, err)
+	assert.Equal(t, "Quick draft", output)
+	assert.Equal(t, 1, slowModel.RequestCount())
+
+	require.Len(t, breaches, 1)
+	assert.Equal(t, "draft", breaches[0].Phase)
+	assert.Equal(t, runner.SLAActionModel, breaches[0].Action)
+	assert.Greater(t, breaches[0].Elapsed, breaches[0].Budget)
+}
+
+func TestSLABreachSkipsToFallbackPhase(t *testing.T) {
+	drafter, _ := newSlowAgent("Drafter")
+	summarizer := agent.NewAgent("Summarizer").WithModel(mocks.NewScriptedModel(&model.Response{Content: "Short summary"}))
+	drafter.WithHandoffs(summarizer)
+
+	store := mocks.NewInMemoryStateStore()
+	config := &runner.WorkflowConfig{
+		StateManagement: &runner.StateManagementConfig{PersistState: true, StateStore: store},
+		Phases: []runner.WorkflowPhase{
+			{Name: "draft", Agent: "Drafter", Budget: 5 * time.Millisecond, Escalation: &runner.SLAEscalation{FallbackPhase: "summary"}},
+			{Name: "summary", Agent: "Summarizer"},
+		},
+	}
+
+	output, err := runWorkflow(t, drafter, config)
+	require.NoError(t, err)
+	assert.Equal(t, "Short summary", output)
+
+	saved, err := store.LoadState("default")
+	require.NoError(t, err)
+	state := saved.(*runner.WorkflowState)
+	assert.Equal(t, "summary", state.CurrentPhase)
+	assert.Equal(t, []string{"draft"}, state.CompletedPhases)
+	require.Len(t, state.SLABreaches, 1)
+	assert.Equal(t, runner.SLAActionFallback, state.SLABreaches[0].Action)
+}
+
+func TestSLABreachNotificationCanStopWorkflow(t *testing.T) {
+	drafter, _ := newSlowAgent("Drafter")
+	stop := errors.New("paging on-call")
+	config := &runner.WorkflowConfig{
+		Phases: []runner.WorkflowPhase{{
+			Name:   "draft",
+			Agent:  "Drafter",
+			Budget: 5 * time.Millisecond,
+			Escalation: &runner.SLAEscalation{OnBreach: func(ctx context.Context, breach *runner.SLABreach) error {
+				return stop
+			}},
+		}},
+	}
+
+	_, err := runWorkflow(t, drafter, config)
+	require.ErrorIs(t, err, stop)
+}
+
+func TestPhaseWithinBudgetIsNotEscalated(t *testing.T) {
+	drafter, _ := newSlowAgent("Drafter", &model.Response{Content: "Full draft"})
+	config := &runner.WorkflowConfig{
+		Phases: []runner.WorkflowPhase{{
+			Name:       "draft",
+			Agent:      "Drafter",
+			Budget:     time.Minute,
+			Escalation: &runner.SLAEscalation{Model: mocks.NewScriptedModel()},
+		}},
+	}
+
+	output, err := runWorkflow(t, drafter, config)
+	require.NoError(t, err)
+	assert.Equal(t, "Full draft", output)
+}
@@ -0,0 +1,207 @@
+package runner_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// taskTables answers the statements of an SQLTaskStore with the given table
+// prefix from in-memory tables, as a database would
+func taskTables(db *mocks.SQLDB, prefix string) {
+	tasks := make(map[string]string)
+	chains := make(map[string]string)
+	rows := func(table map[string]string, withKey bool) *mocks.SQLResult {
+		keys := make([]string, 0, len(table))
+		for key := range table {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		res := &mocks.SQLResult{}
+		for _, key := range keys {
+			if withKey {
+				res.Rows = append(res.Rows, []driver.Value{key, table[key]})
+			} else {
+				res.Rows = append(res.Rows, []driver.Value{table[key]})
+			}
+		}
+		return res
+	}
+
+	db.Handle("INSERT INTO "+prefix+"tasks", func(args []driver.Value) (*mocks.SQLResult, error) {
+		tasks[args[0].(string)] = args[4].(string)
+		return &mocks.SQLResult{RowsAffected: 1}, nil
+	})
+	db.Handle("SELECT data FROM "+prefix+"tasks", func(args []driver.Value) (*mocks.SQLResult, error) {
+		return rows(tasks, false), nil
+	})
+	db.Handle("DELETE FROM "+prefix+"tasks", func(args []driver.Value) (*mocks.SQLResult, error) {
+		delete(tasks, args[0].(string))
+		return &mocks.SQLResult{RowsAffected: 1}, nil
+	})
+	db.Handle("INSERT INTO "+prefix+"delegation_chains", func(args []driver.Value) (*mocks.SQLResult, error) {
+		chains[args[0].(string)] = args[1].(string)
+		return &mocks.SQLResult{RowsAffected: 1}, nil
+	})
+	db.Handle("SELECT agent_name, chain FROM "+prefix+"delegation_chains", func(args []driver.Value) (*mocks.SQLResult, error) {
+		return rows(chains, true), nil
+	})
+	db.Handle("DELETE FROM "+prefix+"delegation_chains", func(args []driver.Value) (*mocks.SQLResult, error) {
+		delete(chains, args[0].(string))
+		return &mocks.SQLResult{RowsAffected: 1}, nil
+	})
+}
+
+func TestSQLTaskStoreMigrate(t *testing.T) {
+	db := mocks.NewSQLDB()
+	require.NoError(t, runner.NewSQLiteTaskStore(db.DB).WithTablePrefix("wf_").Migrate(context.Background()))
+
+	assert.Len(t, db.Statements("CREATE TABLE IF NOT EXISTS wf_tasks"), 1)
+	assert.Len(t, db.Statements("CREATE INDEX IF NOT EXISTS wf_tasks_status_idx ON wf_tasks"), 1)
+	assert.Len(t, db.Statements("CREATE TABLE IF NOT EXISTS wf_delegation_chains"), 1)
+}
+
+func TestSQLTaskStorePlaceholders(t *testing.T) {
+	ctx := context.Background()
+	task := runner.NewTaskContext("task-1", "Manager", "Worker")
+
+	postgres := mocks.NewSQLDB()
+	store := runner.NewPostgresTaskStore(postgres.DB)
+	require.NoError(t, store.SaveTask(ctx, task))
+	require.NoError(t, store.DeleteTask(ctx, "task-1"))
+	require.NoError(t, store.SaveDelegationChain(ctx, "run-1/Worker", []string{"Manager"}))
+
+	saved := postgres.Statements("INSERT INTO agent_tasks")
+	require.Len(t, saved, 1)
+	assert.Contains(t, saved[0].Query, "VALUES ($1, $2, $3, $4, $5, $6)")
+	assert.Contains(t, saved[0].Query, "ON CONFLICT (task_id) DO UPDATE")
+	assert.Equal(t, []driver.Value{"task-1", "Manager", "Worker", "pending"}, saved[0].Args[:4])
+	data, err := task.ToJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, data, saved[0].Args[4].(string))
+	assert.IsType(t, time.Time{}, saved[0].Args[5])
+	assert.Contains(t, postgres.Statements("DELETE FROM agent_tasks")[0].Query, "task_id = $1")
+	assert.Contains(t, postgres.Statements("INSERT INTO agent_delegation_chains")[0].Query, "VALUES ($1, $2, $3)")
+
+	sqlite := mocks.NewSQLDB()
+	store = runner.NewSQLiteTaskStore(sqlite.DB)
+	require.NoError(t, store.SaveTask(ctx, task))
+	require.NoError(t, store.DeleteTask(ctx, "task-1"))
+	require.NoError(t, store.SaveDelegationChain(ctx, "run-1/Worker", nil))
+
+	assert.Contains(t, sqlite.Statements("INSERT INTO agent_tasks")[0].Query, "VALUES (?, ?, ?, ?, ?, ?)")
+	assert.Contains(t, sqlite.Statements("DELETE FROM agent_tasks")[0].Query, "task_id = ?")
+	assert.Contains(t, sqlite.Statements("DELETE FROM agent_delegation_chains")[0].Query, "agent_name = ?")
+	assert.NotContains(t, sqlite.Statements("")[0].Query, "$1")
+}
+
+func TestSQLTaskStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	db := mocks.NewSQLDB()
+	taskTables(db, "agent_")
+	store := runner.NewPostgresTaskStore(db.DB)
+
+	task := runner.NewTaskContext("task-1", "Manager", "Worker")
+	task.RunID = "run-1"
+	require.NoError(t, store.SaveTask(ctx, task))
+	require.NoError(t, task.Start())
+	task.AddMetadata("priority", "high")
+	require.NoError(t, store.SaveTask(ctx, task))
+	require.NoError(t, store.SaveTask(ctx, runner.NewTaskContext("task-2", "Manager", "Reviewer")))
+
+	tasks, err := store.LoadTasks(ctx)
+	require.NoError(t, err)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, "task-1", tasks[0].TaskID)
+	assert.Equal(t, "run-1", tasks[0].RunID)
+	assert.Equal(t, runner.TaskStatusInProgress, tasks[0].Status)
+	assert.Equal(t, "high", tasks[0].GetMetadata("priority"))
+	assert.Len(t, tasks[0].StatusHistory, 1)
+
+	require.NoError(t, store.DeleteTask(ctx, "task-2"))
+	tasks, err = store.LoadTasks(ctx)
+	require.NoError(t, err)
+	assert.Len(t, tasks, 1)
+
+	require.NoError(t, store.SaveDelegationChain(ctx, "run-1/Worker", []string{"Manager"}))
+	require.NoError(t, store.SaveDelegationChain(ctx, "run-1/Reviewer", []string{"Manager", "Worker"}))
+	require.NoError(t, store.SaveDelegationChain(ctx, "run-1/Worker", nil))
+
+	chains, err := store.LoadDelegationChains(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"run-1/Reviewer": {"Manager", "Worker"}}, chains)
+}
+
+func TestSQLTaskStoreErrors(t *testing.T) {
+	ctx := context.Background()
+	db := mocks.NewSQLDB().
+		Handle("INSERT INTO agent_tasks", func(args []driver.Value) (*mocks.SQLResult, error) {
+			return nil, errors.New("connection reset")
+		}).
+		Handle("SELECT data FROM agent_tasks", func(args []driver.Value) (*mocks.SQLResult, error) {
+			return &mocks.SQLResult{Rows: [][]driver.Value{{"not json"}}}, nil
+		}).
+		Handle("SELECT agent_name, chain FROM agent_delegation_chains", func(args []driver.Value) (*mocks.SQLResult, error) {
+			return &mocks.SQLResult{Rows: [][]driver.Value{{"run-1/Worker", "[1]"}}}, nil
+		})
+	store := runner.NewSQLiteTaskStore(db.DB)
+
+	err := store.SaveTask(ctx, runner.NewTaskContext("task-1", "Manager", "Worker"))
+	assert.ErrorContains(t, err, "failed to save task task-1: connection reset")
+
+	_, err = store.LoadTasks(ctx)
+	assert.ErrorContains(t, err, "failed to deserialize task")
+
+	_, err = store.LoadDelegationChains(ctx)
+	assert.ErrorContains(t, err, "failed to deserialize delegation chain of run-1/Worker")
+}
+
+func TestSQLTaskStoreSurvivesRestart(t *testing.T) {
+	db := mocks.NewSQLDB()
+	taskTables(db, "agent_")
+
+	// The worker never returns, leaving the delegation open
+	worker := agent.NewAgent("Worker").WithModel(mocks.NewScriptedModel(
+		&model.Response{Content: "still working"},
+	))
+	manager := agent.NewAgent("Manager").WithModel(mocks.NewScriptedModel(
+		&model.Response{HandoffCall: &model.HandoffCall{
+			AgentName:  "Worker",
+			Parameters: map[string]interface{}{"input": "write the report"},
+		}},
+	)).WithHandoffs(worker)
+
+	first := runner.NewRunner().WithTaskStore(runner.NewPostgresTaskStore(db.DB))
+	res, err := first.Run(context.Background(), manager, &runner.RunOptions{
+		Input:     "start",
+		RunConfig: newTestRunConfig(),
+	})
+	require.NoError(t, err)
+	inProgress := storedTasksByStatus(t, runner.NewPostgresTaskStore(db.DB), runner.TaskStatusInProgress)
+	require.Len(t, inProgress, 1)
+
+	// The process restarts: a new runner with a new store on the same database
+	// loads the task, its history and the open delegation
+	store := runner.NewPostgresTaskStore(db.DB)
+	second := runner.NewRunner().WithTaskStore(store)
+	assert.Equal(t, inProgress, second.TasksByStatus(runner.TaskStatusInProgress))
+
+	history, err := second.TaskHistory(inProgress[0])
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, runner.TaskStatusInProgress, history[0].To)
+
+	chains, err := store.LoadDelegationChains(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Manager"}, chains[res.RunID+"/Worker"])
+}
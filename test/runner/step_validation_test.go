@@ -0,0 +1,83 @@
+package runner_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runValidatedStep runs a single-agent workflow whose output is validated
+func runValidatedStep(m model.Model, validation *runner.ValidationConfig) (*result.RunResult, error) {
+	coder := agent.NewAgent("Coder").WithModel(m)
+	config := &runner.WorkflowConfig{
+		Phases:           []runner.WorkflowPhase{{Name: "implementation", Agent: "Coder"}},
+		ValidationConfig: validation,
+	}
+	return runner.NewWorkflowRunner(runner.NewRunner(), config).RunWorkflow(context.Background(), coder, &runner.RunOptions{
+		Input:          "Print hello",
+		MaxTurns:       5,
+		RunConfig:      newTestRunConfig(),
+		WorkflowConfig: config,
+	})
+}
+
+func TestPostStepValidationRepromptsAgent(t *testing.T) {
+	m := mocks.NewScriptedModel(
+		&model.Response{Content: "Just call Println."},
+		&model.Response{Content: "```go\nfmt.Println(\"hello\")\n```"},
+	)
+	res, err := runValidatedStep(m, &runner.ValidationConfig{
+		PostStepValidation: []runner.ValidationRule{runner.RequireCodeBlock()},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, res.FinalOutput, "fmt.Println")
+
+	require.Len(t, m.Requests, 2)
+	retry := fmt.Sprint(m.Requests[1].Input)
+	assert.Contains(t, retry, "Just call Println.", "the agent sees the output that was rejected")
+	assert.Contains(t, retry, "must contain a fenced code block")
+}
+
+func TestPostStepValidationFailsAfterRetries(t *testing.T) {
+	m := mocks.NewScriptedModel(
+		&model.Response{Content: `{"name": 1}`},
+		&model.Response{Content: `{"name": 2}`},
+		&model.Response{Content: `{"name": "never reached"}`},
+	)
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}},
+		"required":   []interface{}{"name"},
+	}
+	_, err := runValidatedStep(m, &runner.ValidationConfig{
+		PostStepValidation: []runner.ValidationRule{runner.RequireSchema(schema)},
+		MaxStepRetries:     1,
+	})
+
+	var stepErr *runner.StepValidationError
+	require.ErrorAs(t, err, &stepErr)
+	assert.Equal(t, "Coder", stepErr.Agent)
+	assert.Equal(t, "implementation", stepErr.Phase)
+	assert.Equal(t, 2, stepErr.Attempts)
+	assert.Contains(t, stepErr.Error(), "does not match the expected schema")
+	assert.Equal(t, 2, m.RequestCount())
+}
+
+func TestPostStepValidationWarningsDoNotRetry(t *testing.T) {
+	m := mocks.NewScriptedModel(&model.Response{Content: "no code here"})
+	rule := runner.RequireCodeBlock()
+	rule.Severity = runner.ValidationWarning
+
+	res, err := runValidatedStep(m, &runner.ValidationConfig{PostStepValidation: []runner.ValidationRule{rule}})
+	require.NoError(t, err)
+	assert.Equal(t, "no code here", res.FinalOutput)
+	assert.Equal(t, 1, m.RequestCount())
+}
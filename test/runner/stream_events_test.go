@@ -0,0 +1,126 @@
+package runner_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// collectStream streams a run and returns all of its events
+func collectStream(t *testing.T, a *agent.Agent, opts *runner.RunOptions) []model.StreamEvent {
+	streamed, err := runner.NewRunner().RunStreaming(context.Background(), a, opts)
+	require.NoError(t, err)
+
+	var events []model.StreamEvent
+	for event := range streamed.Stream {
+		require.NotEqual(t, model.StreamEventTypeError, event.Type, "%v", event.Error)
+		events = append(events, event)
+	}
+	return events
+}
+
+// streamEventTypes returns the types of stream events, leaving out content
+func streamEventTypes(events []model.StreamEvent) []string {
+	var types []string
+	for _, event := range events {
+		if event.Type != model.StreamEventTypeContent {
+			types = append(types, event.Type)
+		}
+	}
+	return types
+}
+
+// eventsOfType returns the stream events of a type
+func eventsOfType(events []model.StreamEvent, eventType string) []model.StreamEvent {
+	var matching []model.StreamEvent
+	for _, event := range events {
+		if event.Type == eventType {
+			matching = append(matching, event)
+		}
+	}
+	return matching
+}
+
+func TestStreamedRunEmitsRunLevelEvents(t *testing.T) {
+	m := mocks.NewScriptedModel(
+		toolCallResponse(&model.Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12}),
+		&model.Response{Content: "found it", Usage: &model.Usage{PromptTokens: 20, CompletionTokens: 3, TotalTokens: 23}},
+	)
+	a := agent.NewAgent("Assistant").WithModel(m).WithTools(newLookupTool())
+
+	events := collectStream(t, a, &runner.RunOptions{Input: "look it up", RunID: "streamed-run", RunConfig: newTestRunConfig()})
+
+	assert.Equal(t, []string{
+		model.StreamEventTypeRunStarted,
+		model.StreamEventTypeTurnStarted,
+		model.StreamEventTypeUsage,
+		model.StreamEventTypeToolCallStarted,
+		model.StreamEventTypeToolCallFinished,
+		model.StreamEventTypeTurnStarted,
+		model.StreamEventTypeUsage,
+		model.StreamEventTypeRunCompleted,
+	}, streamEventTypes(events))
+
+	for i, event := range events {
+		assert.Equal(t, int64(i+1), event.Sequence, "events are numbered in order")
+		assert.Equal(t, "streamed-run", event.RunID)
+		assert.Equal(t, "Assistant", event.Agent)
+	}
+
+	finished := eventsOfType(events, model.StreamEventTypeToolCallFinished)
+	require.Len(t, finished, 1)
+	assert.Equal(t, "lookup", finished[0].ToolCall.Name)
+	assert.Equal(t, "found", finished[0].Output)
+	assert.Equal(t, 1, finished[0].Turn)
+
+	usage := eventsOfType(events, model.StreamEventTypeUsage)
+	require.Len(t, usage, 2)
+	assert.Equal(t, 12, usage[0].Usage.TotalTokens)
+	assert.Equal(t, 2, usage[1].Turn)
+
+	completed := events[len(events)-1]
+	assert.Equal(t, "found it", completed.Output)
+	assert.Equal(t, 2, completed.Turn)
+}
+
+func TestStreamedRunEmitsHandoffsAndAgentSwitches(t *testing.T) {
+	manager := agent.NewAgent("Manager").WithModel(mocks.NewScriptedModel(delegateTo("Worker", "look it up"), &model.Response{Content: "done"}))
+	worker := agent.NewAgent("Worker").WithModel(mocks.NewScriptedModel(returnResult("found")))
+	manager.WithHandoffs(worker)
+	worker.WithHandoffs(manager)
+
+	events := collectStream(t, manager, &runner.RunOptions{Input: "Find it", RunConfig: newTestRunConfig()})
+
+	handoffs := eventsOfType(events, model.StreamEventTypeHandoff)
+	require.Len(t, handoffs, 2, "each handoff is streamed once, after it was processed")
+	assert.Equal(t, "Manager", handoffs[0].Agent)
+	assert.Equal(t, "Worker", handoffs[0].HandoffCall.AgentName)
+	assert.Equal(t, model.HandoffTypeDelegate, handoffs[0].HandoffCall.Type)
+	assert.NotEmpty(t, handoffs[0].HandoffCall.TaskID)
+	assert.Equal(t, "Worker", handoffs[1].Agent)
+	assert.Equal(t, "Manager", handoffs[1].HandoffCall.AgentName)
+	assert.Equal(t, model.HandoffTypeReturn, handoffs[1].HandoffCall.Type)
+	assert.True(t, handoffs[1].HandoffCall.IsTaskComplete)
+
+	switches := eventsOfType(events, model.StreamEventTypeAgentSwitched)
+	require.Len(t, switches, 2)
+	assert.Equal(t, "Manager", switches[0].FromAgent)
+	assert.Equal(t, "Worker", switches[0].Agent)
+	assert.Equal(t, "Worker", switches[1].FromAgent)
+	assert.Equal(t, "Manager", switches[1].Agent)
+
+	turns := eventsOfType(events, model.StreamEventTypeTurnStarted)
+	require.Len(t, turns, 3)
+	assert.Equal(t, []string{"Manager", "Worker", "Manager"}, []string{turns[0].Agent, turns[1].Agent, turns[2].Agent})
+
+	completed := events[len(events)-1]
+	assert.Equal(t, model.StreamEventTypeRunCompleted, completed.Type)
+	assert.Equal(t, "Manager", completed.Agent)
+	assert.Equal(t, "done", completed.Output)
+}
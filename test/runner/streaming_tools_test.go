@@ -0,0 +1,113 @@
+package runner_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventOnlyModel streams its scripted responses as content and tool call events
+// only, with an empty done event or none at all
+type eventOnlyModel struct {
+	*mocks.ScriptedModel
+	skipDone bool
+}
+
+func (m *eventOnlyModel) StreamResponse(ctx context.Context, request *model.Request) (<-chan model.StreamEvent, error) {
+	resp, err := m.GetResponse(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan model.StreamEvent, 2+len(resp.ToolCalls))
+	if resp.Content != "" {
+		ch <- model.StreamEvent{Type: model.StreamEventTypeContent, Content: resp.Content}
+	}
+	for i := range resp.ToolCalls {
+		ch <- model.StreamEvent{Type: model.StreamEventTypeToolCall, ToolCall: &resp.ToolCalls[i]}
+	}
+	if !m.skipDone {
+		ch <- model.StreamEvent{Type: model.StreamEventTypeDone}
+	}
+	close(ch)
+	return ch, nil
+}
+
+// itemTypes returns the types of run items
+func itemTypes(items []result.RunItem) []string {
+	types := make([]string, len(items))
+	for i, item := range items {
+		types[i] = item.GetType()
+	}
+	return types
+}
+
+func TestStreamingExecutesToolsLikeRun(t *testing.T) {
+	script := func() *mocks.ScriptedModel {
+		return mocks.NewScriptedModel(toolCallResponse(nil), toolCallResponse(nil), &model.Response{Content: "found it twice"})
+	}
+
+	syncModel := script()
+	syncResult, err := runner.NewRunner().Run(context.Background(), agent.NewAgent("Assistant").WithModel(syncModel).WithTools(newLookupTool()),
+		&runner.RunOptions{Input: "look it up", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+
+	streamModel := script()
+	streamed, err := runner.NewRunner().RunStreaming(context.Background(), agent.NewAgent("Assistant").WithModel(streamModel).WithTools(newLookupTool()),
+		&runner.RunOptions{Input: "look it up", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+	for event := range streamed.Stream {
+		require.NotEqual(t, model.StreamEventTypeError, event.Type, "%v", event.Error)
+	}
+
+	assert.Equal(t, syncResult.FinalOutput, streamed.RunResult.FinalOutput)
+	assert.Equal(t, itemTypes(syncResult.NewItems), itemTypes(streamed.RunResult.NewItems))
+	assert.Len(t, streamed.RunResult.RawResponses, len(syncResult.RawResponses))
+	require.Equal(t, 3, streamModel.RequestCount())
+	for i := range streamModel.Requests {
+		assert.Equal(t, syncModel.Requests[i].Input, streamModel.Requests[i].Input, "request %d", i+1)
+	}
+}
+
+func TestStreamingExecutesStreamedToolCalls(t *testing.T) {
+	m := &eventOnlyModel{ScriptedModel: mocks.NewScriptedModel(toolCallResponse(nil), &model.Response{Content: "found it"})}
+	a := agent.NewAgent("Assistant").WithModel(m).WithTools(newLookupTool())
+
+	streamed, err := runner.NewRunner().RunStreaming(context.Background(), a, &runner.RunOptions{Input: "look it up", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+	var finished []model.StreamEvent
+	for event := range streamed.Stream {
+		require.NotEqual(t, model.StreamEventTypeError, event.Type, "%v", event.Error)
+		if event.Type == model.StreamEventTypeToolCallFinished {
+			finished = append(finished, event)
+		}
+	}
+
+	require.Len(t, finished, 1)
+	assert.Equal(t, "found", finished[0].Output)
+	assert.Equal(t, "found it", streamed.RunResult.FinalOutput)
+	require.Equal(t, 2, m.RequestCount())
+	assert.Contains(t, fmt.Sprint(m.Requests[1].Input), "found", "the tool result is fed into the next turn")
+}
+
+func TestStreamWithoutDoneEventEndsWithStreamedContent(t *testing.T) {
+	m := &eventOnlyModel{ScriptedModel: mocks.NewScriptedModel(&model.Response{Content: "all done"}), skipDone: true}
+	a := agent.NewAgent("Assistant").WithModel(m)
+
+	streamed, err := runner.NewRunner().RunStreaming(context.Background(), a, &runner.RunOptions{Input: "hi", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+	for event := range streamed.Stream {
+		require.NotEqual(t, model.StreamEventTypeError, event.Type, "%v", event.Error)
+	}
+
+	assert.Equal(t, "all done", streamed.RunResult.FinalOutput)
+	assert.Equal(t, 1, m.RequestCount())
+}
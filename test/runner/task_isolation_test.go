@@ -0,0 +1,107 @@
+package runner_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedModel answers only once every gated model has been asked, so that all
+// runs are in the middle of their delegation at the same time
+type gatedModel struct {
+	*mocks.ScriptedModel
+	arrived *sync.WaitGroup
+	release <-chan struct{}
+}
+
+func (m *gatedModel) GetResponse(ctx context.Context, request *model.Request) (*model.Response, error) {
+	m.arrived.Done()
+	<-m.release
+	return m.ScriptedModel.GetResponse(ctx, request)
+}
+
+func TestConcurrentRunsKeepTheirOwnDelegations(t *testing.T) {
+	const runs = 4
+	store := mocks.NewInMemoryTaskStore()
+	r := runner.NewRunner().WithTaskStore(store)
+
+	var arrived sync.WaitGroup
+	arrived.Add(runs)
+	release := make(chan struct{})
+	go func() {
+		arrived.Wait()
+		close(release)
+	}()
+
+	// Every run delegates to its own agent named Worker, which returns to the
+	// manager of its run
+	results := make([]*result.RunResult, runs)
+	errs := make([]error, runs)
+	var wg sync.WaitGroup
+	for i := 0; i < runs; i++ {
+		name := fmt.Sprintf("Manager%d", i)
+		manager := agent.NewAgent(name).WithModel(mocks.NewScriptedModel(
+			&model.Response{HandoffCall: &model.HandoffCall{AgentName: "Worker", Parameters: map[string]any{"input": "work for " + name}}},
+			&model.Response{Content: "done by " + name},
+		))
+		worker := agent.NewAgent("Worker").WithModel(&gatedModel{
+			ScriptedModel: mocks.NewScriptedModel(returnResult("result for " + name)),
+			arrived:       &arrived,
+			release:       release,
+		})
+		manager.WithHandoffs(worker)
+		worker.WithHandoffs(manager)
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = r.Run(context.Background(), manager, &runner.RunOptions{
+				Input:     "start",
+				MaxTurns:  5,
+				RunConfig: newTestRunConfig(),
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < runs; i++ {
+		require.NoError(t, errs[i])
+		assert.Equal(t, fmt.Sprintf("done by Manager%d", i), results[i].FinalOutput)
+	}
+	assert.Len(t, storedTasksByStatus(t, store, runner.TaskStatusCompleted), runs)
+
+	// Finished runs leave no tasks behind in the runner
+	assertNoTasksHeld(t, r)
+}
+
+func TestDelegationChainsAreStoredPerRun(t *testing.T) {
+	store := mocks.NewInMemoryTaskStore()
+	r := runner.NewRunner().WithTaskStore(store)
+
+	// Two runs leave a delegation to an agent named Worker open
+	var runIDs []string
+	for _, name := range []string{"Alice", "Bob"} {
+		worker := agent.NewAgent("Worker").WithModel(mocks.NewScriptedModel(&model.Response{Content: "still working"}))
+		manager := agent.NewAgent(name).WithModel(mocks.NewScriptedModel(
+			&model.Response{HandoffCall: &model.HandoffCall{AgentName: "Worker", Parameters: map[string]any{"input": "report"}}},
+		)).WithHandoffs(worker)
+
+		res, err := r.Run(context.Background(), manager, &runner.RunOptions{Input: "start", RunConfig: newTestRunConfig()})
+		require.NoError(t, err)
+		runIDs = append(runIDs, res.RunID)
+	}
+
+	chains, err := store.LoadDelegationChains(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Alice"}, chains[runIDs[0]+"/Worker"])
+	assert.Equal(t, []string{"Bob"}, chains[runIDs[1]+"/Worker"])
+}
@@ -0,0 +1,75 @@
+package runner_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskLifecycleTransitions(t *testing.T) {
+	task := runner.NewTaskContext("task-1", "parent", "child")
+	assert.True(t, task.IsPending())
+
+	// Legal transitions
+	assert.NoError(t, task.Start())
+	assert.NoError(t, task.WaitOnSubtask("task-2"))
+	assert.True(t, task.IsWaitingOnSubtask())
+	assert.NoError(t, task.Start())
+	assert.NoError(t, task.MarkCompleted("done"))
+	assert.True(t, task.IsComplete())
+	assert.True(t, task.IsFinished())
+	assert.NotNil(t, task.CompletedAt)
+
+	// Terminal states cannot be left
+	err := task.MarkFailed(errors.New("too late"))
+	var transitionErr *runner.InvalidTransitionError
+	assert.True(t, errors.As(err, &transitionErr))
+	assert.Equal(t, runner.TaskStatusCompleted, transitionErr.From)
+	assert.Equal(t, runner.TaskStatusFailed, transitionErr.To)
+	assert.Equal(t, "done", task.GetResult())
+
+	// Every transition is recorded in order
+	expected := []runner.TaskStatus{
+		runner.TaskStatusInProgress,
+		runner.TaskStatusWaitingOnSubtask,
+		runner.TaskStatusInProgress,
+		runner.TaskStatusCompleted,
+	}
+	assert.Len(t, task.StatusHistory, len(expected))
+	for i, transition := range task.StatusHistory {
+		assert.Equal(t, expected[i], transition.To)
+		assert.False(t, transition.Timestamp.IsZero())
+	}
+	assert.Equal(t, runner.TaskStatusPending, task.StatusHistory[0].From)
+}
+
+func TestTaskCannotCompleteWhileWaitingOnSubtask(t *testing.T) {
+	task := runner.NewTaskContext("task-1", "parent", "child")
+	assert.NoError(t, task.Start())
+	assert.NoError(t, task.WaitOnSubtask("task-2"))
+
+	assert.Error(t, task.MarkCompleted("result"))
+	task.Complete("result")
+	assert.True(t, task.IsWaitingOnSubtask())
+	assert.NoError(t, task.Cancel("user aborted"))
+	assert.True(t, task.IsCancelled())
+	assert.Equal(t, "user aborted", task.StatusHistory[len(task.StatusHistory)-1].Reason)
+}
+
+func TestTasksStoredAsCompleteAreCompleted(t *testing.T) {
+	var task runner.TaskContext
+	require.NoError(t, json.Unmarshal([]byte(`{"TaskID":"task-1","Status":"complete"}`), &task))
+	assert.Equal(t, runner.TaskStatusCompleted, task.Status)
+	assert.True(t, task.IsComplete())
+}
+
+func TestTaskHistoryUnknownTask(t *testing.T) {
+	r := runner.NewRunner()
+	_, err := r.TaskHistory("missing")
+	assert.Error(t, err)
+	assert.Empty(t, r.TasksByStatus(runner.TaskStatusInProgress))
+}
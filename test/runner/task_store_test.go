@@ -0,0 +1,160 @@
+package runner_test
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// storedTasksByStatus returns the IDs of the stored tasks in the given status,
+// sorted. Runners release the tasks of finished runs, so tests look them up in
+// the task store.
+func storedTasksByStatus(t *testing.T, store runner.TaskStore, status runner.TaskStatus) []string {
+	tasks, err := store.LoadTasks(context.Background())
+	require.NoError(t, err)
+	var taskIDs []string
+	for _, task := range tasks {
+		if task.Status == status {
+			taskIDs = append(taskIDs, task.TaskID)
+		}
+	}
+	sort.Strings(taskIDs)
+	return taskIDs
+}
+
+// assertNoTasksHeld checks that a runner holds no tasks of any status
+func assertNoTasksHeld(t *testing.T, r *runner.Runner) {
+	for _, status := range []runner.TaskStatus{
+		runner.TaskStatusPending, runner.TaskStatusInProgress, runner.TaskStatusWaitingOnSubtask,
+		runner.TaskStatusCompleted, runner.TaskStatusFailed, runner.TaskStatusCancelled,
+	} {
+		assert.Empty(t, r.TasksByStatus(status), "tasks in status %s", status)
+	}
+}
+
+func TestTaskStoreSurvivesRestart(t *testing.T) {
+	store := mocks.NewInMemoryTaskStore()
+
+	// The worker never returns, leaving the delegation open
+	worker := agent.NewAgent("Worker").WithModel(mocks.NewScriptedModel(
+		&model.Response{Content: "still working"},
+	))
+	manager := agent.NewAgent("Manager").WithModel(mocks.NewScriptedModel(
+		&model.Response{HandoffCall: &model.HandoffCall{
+			AgentName:  "Worker",
+			Parameters: map[string]interface{}{"input": "write the report"},
+		}},
+	)).WithHandoffs(worker)
+
+	first := runner.NewRunner().WithTaskStore(store)
+	res, err := first.Run(context.Background(), manager, &runner.RunOptions{
+		Input:     "start",
+		RunConfig: newTestRunConfig(),
+	})
+	assert.NoError(t, err)
+
+	inProgress := storedTasksByStatus(t, store, runner.TaskStatusInProgress)
+	assert.Len(t, inProgress, 1)
+	assertNoTasksHeld(t, first)
+
+	// A new runner picks up the task and its history from the store
+	second := runner.NewRunner().WithTaskStore(store)
+	assert.Equal(t, inProgress, second.TasksByStatus(runner.TaskStatusInProgress))
+
+	history, err := second.TaskHistory(inProgress[0])
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+	assert.Equal(t, runner.TaskStatusInProgress, history[0].To)
+
+	chains, err := store.LoadDelegationChains(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Manager"}, chains[res.RunID+"/Worker"])
+}
+
+// blockingTaskStore holds its first task write until released, and records the
+// run of every write
+type blockingTaskStore struct {
+	*mocks.InMemoryTaskStore
+	saving  chan struct{}
+	release chan struct{}
+	once    sync.Once
+	runIDs  []string
+	mu      sync.Mutex
+}
+
+func (s *blockingTaskStore) SaveTask(ctx context.Context, task *runner.TaskContext) error {
+	s.once.Do(func() {
+		close(s.saving)
+		<-s.release
+	})
+	s.mu.Lock()
+	s.runIDs = append(s.runIDs, runner.RunIDFromContext(ctx))
+	s.mu.Unlock()
+	return s.InMemoryTaskStore.SaveTask(ctx, task)
+}
+
+func TestSlowTaskStoreDoesNotBlockRunner(t *testing.T) {
+	store := &blockingTaskStore{
+		InMemoryTaskStore: mocks.NewInMemoryTaskStore(),
+		saving:            make(chan struct{}),
+		release:           make(chan struct{}),
+	}
+	worker := agent.NewAgent("Worker").WithModel(mocks.NewScriptedModel(
+		&model.Response{Content: "done"},
+	))
+	manager := agent.NewAgent("Manager").WithModel(mocks.NewScriptedModel(
+		&model.Response{HandoffCall: &model.HandoffCall{
+			AgentName:  "Worker",
+			Parameters: map[string]interface{}{"input": "write the report"},
+		}},
+	)).WithHandoffs(worker)
+	r := runner.NewRunner().WithTaskStore(store)
+
+	type outcome struct {
+		runID string
+		err   error
+	}
+	finished := make(chan outcome, 1)
+	go func() {
+		res, err := r.Run(context.Background(), manager, &runner.RunOptions{Input: "start", RunConfig: newTestRunConfig()})
+		if err != nil {
+			finished <- outcome{err: err}
+			return
+		}
+		finished <- outcome{runID: res.RunID}
+	}()
+	<-store.saving
+
+	// The runner answers while the store is still writing
+	answered := make(chan struct{})
+	go func() {
+		r.TasksByStatus(runner.TaskStatusPending)
+		close(answered)
+	}()
+	select {
+	case <-answered:
+	case <-time.After(time.Second):
+		t.Fatal("the runner is blocked by a task store write")
+	}
+
+	close(store.release)
+	done := <-finished
+	require.NoError(t, done.err)
+
+	// Writes carry the context of their run
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	require.NotEmpty(t, store.runIDs)
+	for _, runID := range store.runIDs {
+		assert.Equal(t, done.runID, runID)
+	}
+}
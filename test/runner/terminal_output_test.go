@@ -0,0 +1,72 @@
+package runner_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newOrchestration returns an orchestrator that delegates to a writer, which returns
+// its draft, after which the orchestrator ends without a message
+func newOrchestration() *agent.Agent {
+	orchestratorModel := mocks.NewScriptedModel(
+		&model.Response{HandoffCall: &model.HandoffCall{AgentName: "Writer", Parameters: map[string]any{"input": "Write the summary"}}},
+		&model.Response{},
+	)
+	writerModel := mocks.NewScriptedModel(
+		&model.Response{HandoffCall: &model.HandoffCall{
+			AgentName:      "return_to_delegator",
+			Parameters:     map[string]any{"input": "The summary"},
+			IsTaskComplete: true,
+		}},
+	)
+
+	orchestrator := agent.NewAgent("Orchestrator").WithModel(orchestratorModel)
+	writer := agent.NewAgent("Writer").WithModel(writerModel)
+	orchestrator.WithHandoffs(writer)
+	writer.WithHandoffs(orchestrator)
+	return orchestrator
+}
+
+func TestTerminalOutputPromotesTaskResult(t *testing.T) {
+	config := newTestRunConfig()
+	config.TerminalOutput = &runner.TerminalOutput{Agent: "Writer"}
+
+	res, err := runner.NewRunner().Run(context.Background(), newOrchestration(), &runner.RunOptions{
+		Input:     "Summarize the report",
+		MaxTurns:  3,
+		RunConfig: config,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "The summary", res.FinalOutput)
+}
+
+func TestWithoutTerminalOutputFinalOutputStaysEmpty(t *testing.T) {
+	res, err := runner.NewRunner().Run(context.Background(), newOrchestration(), &runner.RunOptions{
+		Input:     "Summarize the report",
+		MaxTurns:  3,
+		RunConfig: newTestRunConfig(),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "", res.FinalOutput)
+}
+
+func TestTerminalOutputIgnoresTasksOfEarlierRuns(t *testing.T) {
+	r := runner.NewRunner()
+	_, err := r.Run(context.Background(), newOrchestration(), &runner.RunOptions{Input: "first", MaxTurns: 3, RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+
+	// The second run never delegates, so there is no deliverable to promote
+	config := newTestRunConfig()
+	config.TerminalOutput = &runner.TerminalOutput{Agent: "Writer"}
+	a := agent.NewAgent("Orchestrator").WithModel(mocks.NewScriptedModel(&model.Response{}))
+	res, err := r.Run(context.Background(), a, &runner.RunOptions{Input: "second", MaxTurns: 1, RunConfig: config})
+	require.NoError(t, err)
+	assert.Equal(t, "", res.FinalOutput)
+}
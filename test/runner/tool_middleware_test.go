@@ -0,0 +1,49 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolResultItemReportsMiddlewareOutcome(t *testing.T) {
+	failed := false
+	lookup := tool.NewFunctionTool("lookup", "Looks something up", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		if !failed {
+			failed = true
+			return nil, errors.New("temporary failure")
+		}
+		return "found", nil
+	})
+
+	m := mocks.NewScriptedModel(
+		toolCallResponse(nil),
+		&model.Response{Content: "done"},
+	)
+	a := agent.NewAgent("Assistant").WithModel(m).
+		WithTools(tool.Wrap(lookup, tool.WithRetry(2, time.Millisecond)))
+
+	res, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "hi", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+
+	var toolResult *result.ToolResultItem
+	for _, item := range res.NewItems {
+		if item, ok := item.(*result.ToolResultItem); ok {
+			toolResult = item
+		}
+	}
+	require.NotNil(t, toolResult)
+	assert.Equal(t, "found", toolResult.Result)
+	require.NotNil(t, toolResult.Execution)
+	assert.Equal(t, 2, toolResult.Execution.Attempts)
+}
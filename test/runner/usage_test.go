@@ -0,0 +1,136 @@
+package runner_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newUsageWorkflow returns a manager on gpt-4o delegating to a worker on
+// gpt-4o-mini, and the provider of their models
+func newUsageWorkflow() (*agent.Agent, *mocks.MockModelProvider) {
+	managerModel := mocks.NewScriptedModel(
+		&model.Response{
+			HandoffCall: &model.HandoffCall{AgentName: "Worker", Parameters: map[string]any{"input": "look it up"}},
+			Usage:       &model.Usage{PromptTokens: 1000, CompletionTokens: 100, TotalTokens: 1100},
+		},
+		&model.Response{Content: "done", Usage: &model.Usage{PromptTokens: 2000, CompletionTokens: 200, TotalTokens: 2200}},
+	)
+	workerModel := mocks.NewScriptedModel(
+		toolCallResponse(&model.Usage{PromptTokens: 500, CompletionTokens: 50}),
+		&model.Response{
+			HandoffCall: &model.HandoffCall{AgentName: "return_to_delegator", Parameters: map[string]any{"input": "found"}, IsTaskComplete: true},
+			Usage:       &model.Usage{PromptTokens: 600, CompletionTokens: 60, TotalTokens: 660},
+		},
+	)
+	provider := &mocks.MockModelProvider{}
+	provider.On("GetModel", "gpt-4o").Return(managerModel, nil)
+	provider.On("GetModel", "gpt-4o-mini").Return(workerModel, nil)
+
+	manager := agent.NewAgent("Manager").WithModel("gpt-4o")
+	worker := agent.NewAgent("Worker").WithModel("gpt-4o-mini").WithTools(newLookupTool())
+	manager.WithHandoffs(worker)
+	worker.WithHandoffs(manager)
+	return manager, provider
+}
+
+func TestRunResultAggregatesUsage(t *testing.T) {
+	manager, provider := newUsageWorkflow()
+
+	res, err := runner.NewRunner().Run(context.Background(), manager, &runner.RunOptions{
+		Input:     "Find it",
+		RunConfig: &runner.RunConfig{ModelProvider: provider, TracingDisabled: true},
+	})
+	require.NoError(t, err)
+
+	usage := res.Usage
+	assert.Equal(t, 4, usage.ModelCalls)
+	assert.Equal(t, 4100, usage.Total.PromptTokens)
+	assert.Equal(t, 410, usage.Total.CompletionTokens)
+	assert.Equal(t, 4510, usage.Total.TotalTokens, "responses without a total count their sum")
+
+	require.Contains(t, usage.ByAgent, "Manager")
+	require.Contains(t, usage.ByAgent, "Worker")
+	assert.Equal(t, 2, usage.ByAgent["Manager"].ModelCalls)
+	assert.Equal(t, 3300, usage.ByAgent["Manager"].Usage.TotalTokens)
+	assert.Equal(t, 1210, usage.ByAgent["Worker"].Usage.TotalTokens)
+	assert.Equal(t, usage.ByAgent["Manager"].Usage, usage.ByModel["gpt-4o"].Usage)
+	assert.Equal(t, usage.ByAgent["Worker"].Usage, usage.ByModel["gpt-4o-mini"].Usage)
+
+	// gpt-4o: 3000 prompt tokens at $2.50 and 300 completion tokens at $10 per million
+	assert.InDelta(t, 0.0105, usage.ByModel["gpt-4o"].CostUSD, 1e-9)
+	// gpt-4o-mini: 1100 prompt tokens at $0.15 and 110 completion tokens at $0.60 per million
+	assert.InDelta(t, 0.000231, usage.ByModel["gpt-4o-mini"].CostUSD, 1e-9)
+	assert.InDelta(t, 0.010731, usage.CostUSD, 1e-9)
+}
+
+func TestStreamedRunResultAggregatesUsage(t *testing.T) {
+	manager, provider := newUsageWorkflow()
+
+	streamed, err := runner.NewRunner().RunStreaming(context.Background(), manager, &runner.RunOptions{
+		Input:     "Find it",
+		RunConfig: &runner.RunConfig{ModelProvider: provider, TracingDisabled: true},
+	})
+	require.NoError(t, err)
+	for event := range streamed.Stream {
+		require.NotEqual(t, model.StreamEventTypeError, event.Type, "%v", event.Error)
+	}
+
+	assert.Equal(t, 4, streamed.RunResult.Usage.ModelCalls)
+	assert.Equal(t, 4510, streamed.RunResult.Usage.Total.TotalTokens)
+	assert.Equal(t, 1210, streamed.RunResult.Usage.ByAgent["Worker"].Usage.TotalTokens)
+	assert.InDelta(t, 0.010731, streamed.RunResult.Usage.CostUSD, 1e-9)
+}
+
+func TestUsageIsKeptAcrossPauses(t *testing.T) {
+	first := mocks.NewScriptedModel(toolCallResponse(&model.Usage{PromptTokens: 40, CompletionTokens: 10, TotalTokens: 50}))
+	a := agent.NewAgent("Assistant").WithModel(first).WithTools(newLookupTool())
+
+	config := newTestRunConfig()
+	config.Checkpoint = pauseAfterFirstTurn
+	_, err := runner.NewRunner().Run(context.Background(), a, &runner.RunOptions{Input: "look it up", RunConfig: config})
+	var pausedErr *runner.RunPausedError
+	require.True(t, errors.As(err, &pausedErr), "expected a RunPausedError, got %v", err)
+
+	data, err := json.Marshal(pausedErr.State)
+	require.NoError(t, err)
+	var state runner.RunState
+	require.NoError(t, json.Unmarshal(data, &state))
+
+	second := mocks.NewScriptedModel(&model.Response{Content: "found it", Usage: &model.Usage{PromptTokens: 60, CompletionTokens: 5, TotalTokens: 65}})
+	require.NoError(t, state.Bind(agent.NewAgent("Assistant").WithModel(second).WithTools(newLookupTool())))
+	res, err := runner.ResumeFromState(context.Background(), &state, &runner.RunOptions{RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, res.Usage.ModelCalls)
+	assert.Equal(t, 115, res.Usage.Total.TotalTokens)
+	assert.Equal(t, 2, res.Usage.ByAgent["Assistant"].ModelCalls)
+	assert.Equal(t, 1, state.Usage.ModelCalls, "resuming does not change the state")
+}
+
+func TestBroadcastUsageIsIncluded(t *testing.T) {
+	coordinatorModel := mocks.NewScriptedModel(
+		&model.Response{HandoffCall: &model.HandoffCall{AgentName: "reviewers", Parameters: map[string]any{"input": "review"}}},
+		&model.Response{Content: "merged", Usage: &model.Usage{TotalTokens: 10}},
+	)
+	first := agent.NewAgent("First").WithModel(mocks.NewScriptedModel(&model.Response{Content: "ok", Usage: &model.Usage{TotalTokens: 100}}))
+	second := agent.NewAgent("Second").WithModel(mocks.NewScriptedModel(&model.Response{Content: "ok", Usage: &model.Usage{TotalTokens: 200}}))
+	coordinator := agent.NewAgent("Coordinator").WithModel(coordinatorModel).
+		WithBroadcastHandoffs(agent.NewBroadcastHandoff("reviewers", first, second))
+
+	res, err := runner.NewRunner().Run(context.Background(), coordinator, &runner.RunOptions{Input: "Review", RunConfig: newTestRunConfig()})
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, res.Usage.ModelCalls)
+	assert.Equal(t, 310, res.Usage.Total.TotalTokens)
+	assert.Equal(t, 100, res.Usage.ByAgent["First"].Usage.TotalTokens)
+	assert.Equal(t, 200, res.Usage.ByAgent["Second"].Usage.TotalTokens)
+}
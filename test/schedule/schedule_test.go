@@ -0,0 +1,159 @@
+package schedule_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/schedule"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron(t *testing.T) {
+	// Thursday
+	from := time.Date(2026, time.January, 1, 10, 7, 30, 0, time.UTC)
+	cases := []struct {
+		expr string
+		next time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, time.January, 1, 10, 15, 0, 0, time.UTC)},
+		{"0 9 * * MON-FRI", time.Date(2026, time.January, 2, 9, 0, 0, 0, time.UTC)},
+		{"30 8 1 * *", time.Date(2026, time.February, 1, 8, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, time.January, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 12 15 * SAT", time.Date(2026, time.January, 3, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"5,10 10 * * *", time.Date(2026, time.January, 1, 10, 10, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", from.Add(90 * time.Second)},
+	}
+	for _, c := range cases {
+		s, err := schedule.ParseCron(c.expr)
+		require.NoError(t, err, c.expr)
+		assert.Equal(t, c.next, s.Next(from), c.expr)
+	}
+
+	for _, expr := range []string{"* * * *", "60 * * * *", "5-1 * * * *", "*/0 * * * *", "@every soon"} {
+		_, err := schedule.ParseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestSchedulerRunsAgentJobs(t *testing.T) {
+	reporterModel := mocks.NewScriptedModel(&model.Response{Content: "report 1"}, &model.Response{Content: "report 2"})
+	s := schedule.NewScheduler().WithRunOptions(&runner.RunOptions{
+		RunConfig: &runner.RunConfig{ModelProvider: &mocks.MockModelProvider{}, TracingDisabled: true},
+	})
+	require.NoError(t, s.Add("report", schedule.Every(20*time.Millisecond), agent.NewAgent("Reporter").WithModel(reporterModel),
+		schedule.WithInput("summarize the error rate")))
+
+	stop := s.Start(context.Background())
+	require.Eventually(t, func() bool {
+		state, _ := s.State("report")
+		return state.Runs >= 2 && !state.LastSuccess.IsZero()
+	}, time.Second, 5*time.Millisecond)
+	stop()
+
+	state, ok := s.State("report")
+	require.True(t, ok)
+	assert.Empty(t, state.LastError)
+	require.GreaterOrEqual(t, reporterModel.RequestCount(), 1)
+	assert.Equal(t, "summarize the error rate", reporterModel.Requests[0].Input)
+}
+
+func TestSchedulerOverlapPolicies(t *testing.T) {
+	for _, c := range []struct {
+		policy  schedule.OverlapPolicy
+		maxRuns int32
+	}{
+		{schedule.OverlapSkip, 1},
+		{schedule.OverlapQueue, 2},
+	} {
+		release := make(chan struct{})
+		var runs, concurrent, peak int32
+		task := schedule.Task(func(ctx context.Context) error {
+			atomic.AddInt32(&runs, 1)
+			n := atomic.AddInt32(&concurrent, 1)
+			defer atomic.AddInt32(&concurrent, -1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			<-release
+			return nil
+		})
+
+		s := schedule.NewScheduler()
+		require.NoError(t, s.Add("slow", schedule.Every(10*time.Millisecond), task, schedule.WithOverlap(c.policy)))
+		stop := s.Start(context.Background())
+		require.Eventually(t, func() bool {
+			state, _ := s.State("slow")
+			return state.Skipped >= 2 || (c.policy == schedule.OverlapQueue && state.Runs == 1 && time.Since(state.LastRun) > 50*time.Millisecond)
+		}, time.Second, 5*time.Millisecond)
+		assert.ErrorIs(t, s.RunNow(context.Background(), "slow"), schedule.ErrJobRunning)
+
+		close(release)
+		time.Sleep(5 * time.Millisecond)
+		stop()
+		assert.Equal(t, int32(1), atomic.LoadInt32(&peak), "runs never overlap")
+		assert.GreaterOrEqual(t, atomic.LoadInt32(&runs), c.maxRuns)
+	}
+}
+
+func TestSchedulerFailureHookAndPersistence(t *testing.T) {
+	store := mocks.NewInMemoryStateStore()
+	var mu sync.Mutex
+	var failures []schedule.Failure
+	s := schedule.NewScheduler().WithStateStore(store).OnFailure(func(f schedule.Failure) {
+		mu.Lock()
+		defer mu.Unlock()
+		failures = append(failures, f)
+	})
+	check := schedule.Task(func(ctx context.Context) error { return errors.New("endpoint down") })
+	require.NoError(t, s.Add("health", schedule.Every(time.Hour), check))
+
+	err := s.RunNow(context.Background(), "health")
+	assert.ErrorContains(t, err, "endpoint down")
+	require.Len(t, failures, 1)
+	assert.Equal(t, "health", failures[0].Job)
+	assert.Equal(t, 1, failures[0].State.Failures)
+
+	// A new scheduler picks up the history and catches up on the missed run
+	var caughtUp atomic.Bool
+	restarted := schedule.NewScheduler().WithStateStore(store)
+	require.NoError(t, restarted.Add("health", schedule.Every(time.Millisecond), schedule.Task(func(ctx context.Context) error {
+		caughtUp.Store(true)
+		return nil
+	}), schedule.WithCatchUp()))
+	state, _ := restarted.State("health")
+	assert.Equal(t, 1, state.Runs)
+	assert.Equal(t, "endpoint down", state.LastError)
+
+	stop := restarted.Start(context.Background())
+	require.Eventually(t, caughtUp.Load, time.Second, 5*time.Millisecond)
+	stop()
+	state, _ = restarted.State("health")
+	assert.Empty(t, state.LastError)
+	assert.False(t, state.LastSuccess.IsZero())
+}
+
+func TestSchedulerRegistration(t *testing.T) {
+	s := schedule.NewScheduler()
+	noop := schedule.Task(func(ctx context.Context) error { return nil })
+	require.NoError(t, s.Add("a", schedule.Every(time.Hour), noop))
+	assert.ErrorIs(t, s.Add("a", schedule.Every(time.Hour), noop), schedule.ErrJobExists)
+	assert.ErrorContains(t, s.Add("b", schedule.Every(time.Hour), "not a job"), "must be an *agent.Agent")
+	assert.ErrorIs(t, s.RunNow(context.Background(), "missing"), schedule.ErrJobNotFound)
+
+	require.NoError(t, s.Remove("a"))
+	assert.Empty(t, s.States())
+}
@@ -0,0 +1,97 @@
+package scratchpad_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/scratchpad"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func toolCall(id, name string, params map[string]interface{}) *model.Response {
+	return &model.Response{ToolCalls: []model.ToolCall{{ID: id, Name: name, Parameters: params}}}
+}
+
+func runConfig() *runner.RunConfig {
+	return &runner.RunConfig{ModelProvider: &mocks.MockModelProvider{}, TracingDisabled: true}
+}
+
+func TestScratchpadOperations(t *testing.T) {
+	pad := scratchpad.New()
+	pad.Set("phase", "analyze")
+	pad.Set("count", 1)
+	pad.Update("count", func(value interface{}, ok bool) interface{} { return value.(int) + 1 })
+
+	assert.Equal(t, "analyze", pad.GetString("phase"))
+	count, ok := pad.Get("count")
+	require.True(t, ok)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, []string{"count", "phase"}, pad.Keys())
+
+	pad.Delete("phase")
+	assert.Equal(t, map[string]interface{}{"count": 2}, pad.Snapshot())
+}
+
+func TestAgentsShareScratchpadThroughTools(t *testing.T) {
+	pad := scratchpad.New()
+	config := runConfig()
+	config.Scratchpad = pad
+
+	reviewer := agent.NewAgent("Reviewer").WithTools(scratchpad.Tools()...).WithModel(mocks.NewScriptedModel(
+		toolCall("read-1", scratchpad.ReadToolName, map[string]interface{}{"key": "phase"}),
+		&model.Response{Content: "reviewed"},
+	))
+	planner := agent.NewAgent("Planner").WithTools(scratchpad.Tools()...).WithHandoffs(reviewer).WithModel(mocks.NewScriptedModel(
+		toolCall("write-1", scratchpad.WriteToolName, map[string]interface{}{"key": "phase", "value": "optimize"}),
+		&model.Response{HandoffCall: &model.HandoffCall{AgentName: "Reviewer", Parameters: map[string]interface{}{"input": "review"}}},
+		&model.Response{Content: "done"},
+	))
+
+	res, err := runner.NewRunner().Run(context.Background(), planner, &runner.RunOptions{Input: "plan", RunConfig: config})
+	require.NoError(t, err)
+	assert.Equal(t, "optimize", pad.GetString("phase"))
+
+	var read []interface{}
+	for _, item := range res.NewItems {
+		if toolResult, ok := item.(*result.ToolResultItem); ok && toolResult.Name == scratchpad.ReadToolName {
+			read = append(read, toolResult.Result)
+		}
+	}
+	assert.Equal(t, []interface{}{"optimize"}, read)
+}
+
+func TestRunsGetTheirOwnScratchpad(t *testing.T) {
+	var seen []*scratchpad.Scratchpad
+	record := tool.NewFunctionTool("record", "Record the scratchpad", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		pad := scratchpad.FromContext(ctx)
+		seen = append(seen, pad)
+		pad.Set("runs", len(pad.Keys())+1)
+		return "ok", nil
+	})
+
+	r := runner.NewRunner()
+	for i := 0; i < 2; i++ {
+		a := agent.NewAgent("Recorder").WithTools(record).WithModel(mocks.NewScriptedModel(
+			toolCall("record-1", "record", map[string]interface{}{}),
+			&model.Response{Content: "done"},
+		))
+		_, err := r.Run(context.Background(), a, &runner.RunOptions{Input: "go", RunConfig: runConfig()})
+		require.NoError(t, err)
+	}
+
+	require.Len(t, seen, 2)
+	require.NotNil(t, seen[0])
+	assert.NotSame(t, seen[0], seen[1])
+}
+
+func TestToolsFailOutsideRun(t *testing.T) {
+	_, err := scratchpad.ReadTool().Execute(context.Background(), map[string]interface{}{})
+	assert.ErrorIs(t, err, scratchpad.ErrNoScratchpad)
+}
@@ -0,0 +1,122 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/server"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// waitingModel waits for the context of the run to end
+type waitingModel struct {
+	waiting chan struct{}
+}
+
+func (m *waitingModel) GetResponse(ctx context.Context, request *model.Request) (*model.Response, error) {
+	close(m.waiting)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (m *waitingModel) StreamResponse(ctx context.Context, request *model.Request) (<-chan model.StreamEvent, error) {
+	return nil, ctx.Err()
+}
+
+func startRun(t *testing.T, r *runner.Runner, id string) <-chan error {
+	m := &waitingModel{waiting: make(chan struct{})}
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.Run(context.Background(), agent.NewAgent("worker").WithModel(m), &runner.RunOptions{
+			Input:     "go",
+			RunID:     id,
+			RunConfig: &runner.RunConfig{ModelProvider: &mocks.MockModelProvider{}, TracingDisabled: true},
+		})
+		done <- err
+	}()
+	select {
+	case <-m.waiting:
+	case <-time.After(2 * time.Second):
+		t.Fatal("run did not start")
+	}
+	return done
+}
+
+func request(t *testing.T, handler http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAdminRequiresAuthentication(t *testing.T) {
+	handler := server.NewAdminHandler(runner.NewRunner(), server.BearerToken("secret"))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/runs", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/runs", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = request(t, server.NewAdminHandler(runner.NewRunner(), nil), http.MethodGet, "/admin/runs", "")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestAdminListInspectAndCancel(t *testing.T) {
+	r := runner.NewRunner()
+	handler := server.NewAdminHandler(r, server.BearerToken("secret"))
+	done := startRun(t, r, "run-1")
+
+	rec := request(t, handler, http.MethodGet, "/admin/runs", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list struct {
+		Runs []runner.ActiveRun `json:"runs"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Runs, 1)
+	assert.Equal(t, "run-1", list.Runs[0].ID)
+	assert.Equal(t, "worker", list.Runs[0].CurrentAgent)
+	assert.Equal(t, 1, list.Runs[0].Turn)
+
+	rec = request(t, handler, http.MethodGet, "/admin/runs/run-1", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var detail map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &detail))
+	assert.Equal(t, "go", detail["input"])
+	assert.Equal(t, []interface{}{}, detail["items"])
+
+	rec = request(t, handler, http.MethodPost, "/admin/runs/run-1/cancel", "")
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.ErrorIs(t, <-done, runner.ErrRunCancelled)
+
+	rec = request(t, handler, http.MethodGet, "/admin/runs/run-1", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestAdminCompleteRun(t *testing.T) {
+	r := runner.NewRunner()
+	handler := server.NewAdminHandler(r, server.BearerToken("secret"))
+	done := startRun(t, r, "run-2")
+
+	rec := request(t, handler, http.MethodPost, "/admin/runs/run-2/complete", "not json")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = request(t, handler, http.MethodPost, "/admin/runs/run-2/complete", `{"output":"done by hand"}`)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,209 @@
+package server_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/server"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/server/stream"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newAPIServer(t *testing.T, agents ...*agent.Agent) (*httptest.Server, *server.APIHandler) {
+	h := server.NewAPIHandler(runner.NewRunner(), agents...).WithRunOptions(&runner.RunOptions{RunConfig: rpcRunConfig()})
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return srv, h
+}
+
+func postJSON(t *testing.T, url, body string) *http.Response {
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func decodeBody(t *testing.T, resp *http.Response) map[string]interface{} {
+	var body map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	return body
+}
+
+func TestChatCompletions(t *testing.T) {
+	m := mocks.NewScriptedModel(&model.Response{Content: "hello there", Usage: &model.Usage{PromptTokens: 5, CompletionTokens: 2, TotalTokens: 7}})
+	srv, _ := newAPIServer(t, agent.NewAgent("Assistant").WithModel(m))
+
+	resp := postJSON(t, srv.URL+"/v1/chat/completions", `{"model": "Assistant", "messages": [
+		{"role": "system", "content": "ignored"},
+		{"role": "user", "content": "hi"},
+		{"role": "assistant", "content": "hello"},
+		{"role": "user", "content": [{"type": "text", "text": "how are you?"}]}
+	]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body := decodeBody(t, resp)
+
+	assert.Equal(t, "chat.completion", body["object"])
+	assert.Equal(t, "Assistant", body["model"])
+	choice := body["choices"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "stop", choice["finish_reason"])
+	assert.Equal(t, map[string]interface{}{"role": "assistant", "content": "hello there"}, choice["message"])
+	assert.Equal(t, float64(7), body["usage"].(map[string]interface{})["total_tokens"])
+
+	history := fmt.Sprint(m.Requests[0].Input)
+	assert.Contains(t, history, "how are you?")
+	assert.NotContains(t, history, "ignored", "system messages give way to the agent's instructions")
+}
+
+func TestChatCompletionsUnknownModel(t *testing.T) {
+	srv, _ := newAPIServer(t, agent.NewAgent("Assistant").WithModel(mocks.NewScriptedModel()))
+
+	resp := postJSON(t, srv.URL+"/v1/chat/completions", `{"model": "gpt-4o", "messages": [{"role": "user", "content": "hi"}]}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	body := decodeBody(t, resp)
+	assert.Equal(t, "model_not_found", body["error"].(map[string]interface{})["type"])
+}
+
+func TestChatCompletionsStream(t *testing.T) {
+	srv, _ := newAPIServer(t, agent.NewAgent("Assistant").WithModel(mocks.NewScriptedModel(&model.Response{Content: "hello there"})))
+
+	resp := postJSON(t, srv.URL+"/v1/chat/completions", `{"model": "Assistant", "stream": true, "messages": [{"role": "user", "content": "hi"}]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	var chunks []map[string]interface{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			break
+		}
+		var chunk map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(data), &chunk))
+		chunks = append(chunks, chunk)
+	}
+
+	require.Len(t, chunks, 3)
+	var content strings.Builder
+	for _, chunk := range chunks {
+		assert.Equal(t, "chat.completion.chunk", chunk["object"])
+		choice := chunk["choices"].([]interface{})[0].(map[string]interface{})
+		if text, ok := choice["delta"].(map[string]interface{})["content"].(string); ok {
+			content.WriteString(text)
+		}
+	}
+	assert.Equal(t, "hello there", content.String())
+	first := chunks[0]["choices"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "assistant", first["delta"].(map[string]interface{})["role"])
+	last := chunks[2]["choices"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "stop", last["finish_reason"])
+}
+
+func TestListModels(t *testing.T) {
+	srv, _ := newAPIServer(t, agent.NewAgent("Assistant"), agent.NewAgent("Researcher"))
+
+	resp, err := http.Get(srv.URL + "/v1/models")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body := decodeBody(t, resp)
+
+	models := body["data"].([]interface{})
+	require.Len(t, models, 2)
+	assert.Equal(t, "Assistant", models[0].(map[string]interface{})["id"])
+	assert.Equal(t, "Researcher", models[1].(map[string]interface{})["id"])
+}
+
+func TestRunsAPICreatesRunAndStreamsEvents(t *testing.T) {
+	srv, _ := newAPIServer(t, agent.NewAgent("Assistant").WithModel(mocks.NewScriptedModel(&model.Response{Content: "hello there"})))
+
+	resp := postJSON(t, srv.URL+"/runs", `{"input": "hi", "run_id": "run-1"}`)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, "/runs/run-1", resp.Header.Get("Location"))
+	created := decodeBody(t, resp)
+	assert.Equal(t, "run-1", created["id"])
+	assert.Equal(t, "Assistant", created["agent"])
+
+	events, err := http.Get(srv.URL + "/runs/run-1/events")
+	require.NoError(t, err)
+	defer events.Body.Close()
+	streamed, _ := readSSE(t, bufio.NewReader(events.Body))
+	assert.Equal(t, model.StreamEventTypeRunStarted, streamed[0].Name, "events are replayed from the start")
+	assert.Equal(t, stream.EventTypeEnd, streamed[len(streamed)-1].Name)
+	assert.Equal(t, "hello there", streamed[len(streamed)-1].Data.Output)
+
+	status, err := http.Get(srv.URL + "/runs/run-1")
+	require.NoError(t, err)
+	defer status.Body.Close()
+	body := decodeBody(t, status)
+	assert.Equal(t, server.RunStatusCompleted, body["status"])
+	assert.Equal(t, "hello there", body["output"])
+	assert.NotEmpty(t, body["finished_at"])
+
+	// Clients that reconnect only get the events they missed
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/runs/run-1/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", streamed[len(streamed)-3].ID)
+	resumed, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resumed.Body.Close()
+	missed, _ := readSSE(t, bufio.NewReader(resumed.Body))
+	assert.Len(t, missed, 2)
+}
+
+func TestRunsAPICancel(t *testing.T) {
+	m := newStallingModel()
+	srv, _ := newAPIServer(t, agent.NewAgent("Assistant").WithModel(m))
+
+	resp := postJSON(t, srv.URL+"/runs", `{"input": "hi", "run_id": "run-1"}`)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	<-m.started
+
+	cancel := postJSON(t, srv.URL+"/runs/run-1/cancel", ``)
+	assert.Equal(t, http.StatusAccepted, cancel.StatusCode)
+
+	assert.Eventually(t, func() bool {
+		status, err := http.Get(srv.URL + "/runs/run-1")
+		require.NoError(t, err)
+		defer status.Body.Close()
+		return decodeBody(t, status)["status"] == server.RunStatusCancelled
+	}, 2*time.Second, 10*time.Millisecond)
+
+	missing, err := http.Get(srv.URL + "/runs/run-2")
+	require.NoError(t, err)
+	defer missing.Body.Close()
+	assert.Equal(t, http.StatusNotFound, missing.StatusCode)
+}
+
+func TestAPIAuthAndMiddleware(t *testing.T) {
+	var seen []string
+	h := server.NewAPIHandler(runner.NewRunner(), agent.NewAgent("Assistant")).
+		WithAuth(server.BearerToken("secret")).
+		WithMiddleware(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = append(seen, r.URL.Path)
+				next.ServeHTTP(w, r)
+			})
+		})
+
+	authorized := request(t, h, http.MethodGet, "/v1/models", "")
+	assert.Equal(t, http.StatusOK, authorized.Code, "the shared request helper sends the secret")
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, []string{"/v1/models", "/v1/models"}, seen, "middleware runs before authentication")
+}
@@ -0,0 +1,180 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/server"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/server/stream"
+	agentsdkv1 "github.com/pontus-devoteam/agent-sdk-go/proto/agentsdk/v1"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// startGRPC serves a run service over an in-memory gRPC connection and returns
+// a client of it
+func startGRPC(t *testing.T, runs *server.RunService) agentsdkv1.RunServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	server.NewGRPCServer(runs).Register(srv)
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return agentsdkv1.NewRunServiceClient(conn)
+}
+
+// receiveAll reads the events of a stream until it ends
+func receiveAll(t *testing.T, events agentsdkv1.RunService_StreamEventsClient) []*agentsdkv1.Event {
+	t.Helper()
+	var received []*agentsdkv1.Event
+	for {
+		event, err := events.Recv()
+		if errors.Is(err, io.EOF) {
+			return received
+		}
+		require.NoError(t, err)
+		received = append(received, event)
+	}
+}
+
+func TestGRPCRun(t *testing.T) {
+	client := startGRPC(t, newRunService(agent.NewAgent("Assistant").WithModel(mocks.NewScriptedModel(
+		&model.Response{Content: "hello", Usage: &model.Usage{PromptTokens: 3, CompletionTokens: 1, TotalTokens: 4}},
+	))))
+	ctx := context.Background()
+
+	created, err := client.CreateRun(ctx, &agentsdkv1.CreateRunRequest{Input: structpb.NewStringValue("hi"), RunId: "run-1"})
+	require.NoError(t, err)
+	assert.Equal(t, "run-1", created.Id)
+	assert.Equal(t, "Assistant", created.Agent)
+	assert.NotNil(t, created.CreatedAt)
+
+	events, err := client.StreamEvents(ctx, &agentsdkv1.StreamEventsRequest{RunId: "run-1"})
+	require.NoError(t, err)
+	received := receiveAll(t, events)
+	require.GreaterOrEqual(t, len(received), 3)
+	first, end := received[0], received[len(received)-1]
+	assert.Equal(t, model.StreamEventTypeRunStarted, first.Type)
+	assert.Equal(t, "run-1", first.RunId)
+	assert.Equal(t, "Assistant", first.Agent)
+	assert.Equal(t, int64(1), first.Sequence)
+	assert.Equal(t, model.StreamEventTypeRunCompleted, received[len(received)-2].Type)
+	assert.Equal(t, stream.EventTypeEnd, end.Type)
+	assert.Equal(t, "hello", end.Output.GetStringValue())
+
+	run, err := client.GetRunResult(ctx, &agentsdkv1.GetRunResultRequest{RunId: "run-1"})
+	require.NoError(t, err)
+	assert.Equal(t, agentsdkv1.RunStatus_RUN_STATUS_COMPLETED, run.Status)
+	assert.Equal(t, "hello", run.Output.GetStringValue())
+	assert.Equal(t, int32(4), run.Usage.TotalTokens)
+	assert.NotNil(t, run.FinishedAt)
+
+	// Reconnecting clients only get the events after the last one they saw
+	events, err = client.StreamEvents(ctx, &agentsdkv1.StreamEventsRequest{RunId: "run-1", AfterSequence: received[len(received)-3].Sequence})
+	require.NoError(t, err)
+	replayed := receiveAll(t, events)
+	require.Len(t, replayed, 2)
+	assert.Equal(t, model.StreamEventTypeRunCompleted, replayed[0].Type)
+	assert.Equal(t, stream.EventTypeEnd, replayed[1].Type)
+}
+
+func TestGRPCRunTakesMessages(t *testing.T) {
+	m := mocks.NewScriptedModel(&model.Response{Content: "hello"})
+	client := startGRPC(t, newRunService(agent.NewAgent("Assistant").WithModel(m)))
+
+	input, err := structpb.NewValue([]interface{}{
+		map[string]interface{}{"role": "user", "content": "hi"},
+	})
+	require.NoError(t, err)
+	created, err := client.CreateRun(context.Background(), &agentsdkv1.CreateRunRequest{Input: input})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.Id)
+
+	events, err := client.StreamEvents(context.Background(), &agentsdkv1.StreamEventsRequest{RunId: created.Id})
+	require.NoError(t, err)
+	received := receiveAll(t, events)
+	assert.Equal(t, "hello", received[len(received)-1].Output.GetStringValue())
+}
+
+func TestGRPCCancelRun(t *testing.T) {
+	m := newStallingModel()
+	client := startGRPC(t, newRunService(agent.NewAgent("Assistant").WithModel(m)))
+	ctx := context.Background()
+
+	_, err := client.CreateRun(ctx, &agentsdkv1.CreateRunRequest{Input: structpb.NewStringValue("hi"), RunId: "run-1"})
+	require.NoError(t, err)
+	<-m.started
+	events, err := client.StreamEvents(ctx, &agentsdkv1.StreamEventsRequest{RunId: "run-1"})
+	require.NoError(t, err)
+
+	cancelled, err := client.CancelRun(ctx, &agentsdkv1.CancelRunRequest{RunId: "run-1"})
+	require.NoError(t, err)
+	assert.Equal(t, agentsdkv1.RunStatus_RUN_STATUS_RUNNING, cancelled.Status, "the status before the run stops")
+
+	received := receiveAll(t, events)
+	end := received[len(received)-1]
+	assert.Equal(t, stream.EventTypeEnd, end.Type)
+	assert.Nil(t, end.Output)
+
+	run, err := client.GetRunResult(ctx, &agentsdkv1.GetRunResultRequest{RunId: "run-1"})
+	require.NoError(t, err)
+	assert.Equal(t, agentsdkv1.RunStatus_RUN_STATUS_CANCELLED, run.Status)
+	assert.NotEmpty(t, run.Error)
+}
+
+func TestGRPCErrorCodes(t *testing.T) {
+	m := newStallingModel()
+	client := startGRPC(t, newRunService(agent.NewAgent("Assistant").WithModel(m)))
+	ctx := context.Background()
+	code := func(err error) codes.Code {
+		return status.Code(err)
+	}
+
+	_, err := client.CreateRun(ctx, &agentsdkv1.CreateRunRequest{Agent: "Nobody", Input: structpb.NewStringValue("hi")})
+	assert.Equal(t, codes.InvalidArgument, code(err))
+	_, err = client.CreateRun(ctx, &agentsdkv1.CreateRunRequest{})
+	assert.Equal(t, codes.InvalidArgument, code(err))
+	_, err = client.GetRunResult(ctx, &agentsdkv1.GetRunResultRequest{RunId: "missing"})
+	assert.Equal(t, codes.NotFound, code(err))
+	_, err = client.CancelRun(ctx, &agentsdkv1.CancelRunRequest{RunId: "missing"})
+	assert.Equal(t, codes.NotFound, code(err))
+	events, err := client.StreamEvents(ctx, &agentsdkv1.StreamEventsRequest{RunId: "missing"})
+	require.NoError(t, err)
+	_, err = events.Recv()
+	assert.Equal(t, codes.NotFound, code(err))
+
+	_, err = client.CreateRun(ctx, &agentsdkv1.CreateRunRequest{Input: structpb.NewStringValue("hi"), RunId: "run-1"})
+	require.NoError(t, err)
+	<-m.started
+	_, err = client.CreateRun(ctx, &agentsdkv1.CreateRunRequest{Input: structpb.NewStringValue("hi"), RunId: "run-1"})
+	assert.Equal(t, codes.AlreadyExists, code(err))
+
+	events, err = client.StreamEvents(ctx, &agentsdkv1.StreamEventsRequest{RunId: "run-1"})
+	require.NoError(t, err)
+	_, err = client.CancelRun(ctx, &agentsdkv1.CancelRunRequest{RunId: "run-1"})
+	require.NoError(t, err)
+	receiveAll(t, events)
+	_, err = client.CancelRun(ctx, &agentsdkv1.CancelRunRequest{RunId: "run-1"})
+	assert.Equal(t, codes.FailedPrecondition, code(err), "finished runs cannot be cancelled")
+}
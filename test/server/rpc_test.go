@@ -0,0 +1,170 @@
+package server_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/server"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rpcMessage is a message read from the server
+type rpcMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// rpcClient talks to an RPC server over pipes
+type rpcClient struct {
+	t      *testing.T
+	in     *io.PipeWriter
+	out    *bufio.Reader
+	served chan error
+}
+
+func startRPC(t *testing.T, s *server.RPCServer) *rpcClient {
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	c := &rpcClient{t: t, in: inWriter, out: bufio.NewReader(outReader), served: make(chan error, 1)}
+	go func() {
+		c.served <- s.Serve(context.Background(), inReader, outWriter)
+		outWriter.Close()
+	}()
+	t.Cleanup(func() { inWriter.Close() })
+	return c
+}
+
+func (c *rpcClient) send(id int, method string, params interface{}) {
+	body, err := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params})
+	require.NoError(c.t, err)
+	_, err = fmt.Fprintf(c.in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	require.NoError(c.t, err)
+}
+
+func (c *rpcClient) read() rpcMessage {
+	header, err := textproto.NewReader(c.out).ReadMIMEHeader()
+	require.NoError(c.t, err)
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	require.NoError(c.t, err)
+	body := make([]byte, length)
+	_, err = io.ReadFull(c.out, body)
+	require.NoError(c.t, err)
+
+	var msg rpcMessage
+	require.NoError(c.t, json.Unmarshal(body, &msg))
+	return msg
+}
+
+func rpcRunConfig() *runner.RunConfig {
+	return &runner.RunConfig{ModelProvider: &mocks.MockModelProvider{}, TracingDisabled: true}
+}
+
+func TestRPCRun(t *testing.T) {
+	a := agent.New("Assistant", agent.WithModel(mocks.NewScriptedModel(&model.Response{Content: "hello"})))
+	c := startRPC(t, server.NewRPCServer(runner.NewRunner(), a).WithRunOptions(&runner.RunOptions{RunConfig: rpcRunConfig()}))
+
+	c.send(1, server.RPCMethodRun, map[string]interface{}{"input": "hi", "run_id": "run-1"})
+	msg := c.read()
+	require.Nil(t, msg.Error)
+	require.NotNil(t, msg.ID)
+	assert.Equal(t, 1, *msg.ID)
+
+	var res server.RPCRunResult
+	require.NoError(t, json.Unmarshal(msg.Result, &res))
+	assert.Equal(t, server.RPCRunResult{RunID: "run-1", Status: server.RPCStatusCompleted, Agent: "Assistant", Output: "hello"}, res)
+
+	c.send(2, "unknown", nil)
+	msg = c.read()
+	require.NotNil(t, msg.Error)
+	assert.Equal(t, -32601, msg.Error.Code)
+
+	c.in.Close()
+	assert.NoError(t, <-c.served)
+}
+
+func TestRPCStreamSendsEvents(t *testing.T) {
+	a := agent.New("Assistant", agent.WithModel(mocks.NewScriptedModel(&model.Response{Content: "streamed"})))
+	c := startRPC(t, server.NewRPCServer(runner.NewRunner(), a).WithRunOptions(&runner.RunOptions{RunConfig: rpcRunConfig()}))
+
+	c.send(1, server.RPCMethodStream, map[string]interface{}{"agent": "Assistant", "input": "hi", "run_id": "run-2"})
+	msg := c.read()
+	require.Nil(t, msg.Error)
+	assert.JSONEq(t, `{"run_id":"run-2"}`, string(msg.Result))
+
+	var events []server.RPCEvent
+	for {
+		msg := c.read()
+		require.Equal(t, server.RPCNotificationEvent, msg.Method)
+		var event server.RPCEvent
+		require.NoError(t, json.Unmarshal(msg.Params, &event))
+		events = append(events, event)
+		if event.Type == model.StreamEventTypeDone || event.Type == model.StreamEventTypeError {
+			break
+		}
+	}
+	assert.Equal(t, model.StreamEventTypeRunStarted, events[0].Type)
+	assert.Equal(t, "Assistant", events[0].Agent)
+	var content []server.RPCEvent
+	for i, event := range events[:len(events)-1] {
+		assert.Equal(t, int64(i+1), event.Sequence)
+		if event.Type == model.StreamEventTypeContent {
+			content = append(content, event)
+		}
+	}
+	require.Len(t, content, 1)
+	assert.Equal(t, "streamed", content[0].Content)
+	assert.Equal(t, 1, content[0].Turn)
+	last := events[len(events)-1]
+	assert.Equal(t, model.StreamEventTypeDone, last.Type)
+	assert.Equal(t, "streamed", last.Output)
+}
+
+func TestRPCApproveContinuesPausedRun(t *testing.T) {
+	deploy := tool.NewFunctionTool("deploy", "Deploy the service", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return "deployed", nil
+	})
+	a := agent.New("Operator", agent.WithTools(deploy), agent.WithModel(mocks.NewScriptedModel(
+		&model.Response{ToolCalls: []model.ToolCall{{ID: "call-1", Name: "deploy", Parameters: map[string]interface{}{}}}},
+		&model.Response{Content: "shipped"},
+	)))
+	config := rpcRunConfig()
+	config.ApprovalPolicy = runner.RequireApproval("deploy")
+	c := startRPC(t, server.NewRPCServer(runner.NewRunner(), a).WithRunOptions(&runner.RunOptions{RunConfig: config}))
+
+	c.send(1, server.RPCMethodRun, map[string]interface{}{"input": "ship it", "run_id": "run-3"})
+	var res server.RPCRunResult
+	require.NoError(t, json.Unmarshal(c.read().Result, &res))
+	assert.Equal(t, server.RPCStatusApprovalRequired, res.Status)
+	require.NotNil(t, res.Approval)
+	assert.Equal(t, "deploy", res.Approval.Tool)
+
+	c.send(2, server.RPCMethodApprove, map[string]interface{}{"run_id": "run-3", "approved": true})
+	msg := c.read()
+	require.Nil(t, msg.Error)
+	require.NoError(t, json.Unmarshal(msg.Result, &res))
+	assert.Equal(t, server.RPCStatusCompleted, res.Status)
+	assert.Equal(t, "shipped", res.Output)
+
+	c.send(3, server.RPCMethodApprove, map[string]interface{}{"run_id": "run-3", "approved": true})
+	msg = c.read()
+	require.NotNil(t, msg.Error)
+	assert.Contains(t, msg.Error.Message, "not waiting for approval")
+}
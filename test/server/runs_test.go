@@ -0,0 +1,124 @@
+package server_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/server"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRunService(agents ...*agent.Agent) *server.RunService {
+	return server.NewRunService(runner.NewRunner(), agents...).WithRunOptions(&runner.RunOptions{RunConfig: rpcRunConfig()})
+}
+
+func TestRunServiceRunsInBackground(t *testing.T) {
+	s := newRunService(agent.NewAgent("Assistant").WithModel(mocks.NewScriptedModel(&model.Response{Content: "hello", Usage: &model.Usage{TotalTokens: 4}})))
+
+	created, err := s.CreateRun(context.Background(), server.CreateRunRequest{Input: "hi"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, created.ID)
+	assert.Equal(t, "Assistant", created.Agent)
+
+	followed, err := s.StreamEvents(context.Background(), created.ID, 0)
+	require.NoError(t, err)
+	var types []string
+	for event := range followed.Stream {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, model.StreamEventTypeRunStarted, types[0])
+	assert.Equal(t, model.StreamEventTypeRunCompleted, types[len(types)-1])
+	assert.True(t, followed.IsComplete)
+	assert.Equal(t, "hello", followed.FinalOutput)
+
+	status, err := s.GetRunResult(created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, server.RunStatusCompleted, status.Status)
+	assert.Equal(t, "hello", status.Output)
+	assert.Equal(t, 4, status.Usage.TotalTokens)
+
+	replayed, err := s.StreamEvents(context.Background(), created.ID, int64(len(types)-1))
+	require.NoError(t, err)
+	var last []string
+	for event := range replayed.Stream {
+		last = append(last, event.Type)
+	}
+	assert.Equal(t, []string{model.StreamEventTypeRunCompleted}, last)
+
+	_, err = s.CancelRun(created.ID)
+	assert.ErrorIs(t, err, runner.ErrRunNotActive, "finished runs cannot be cancelled")
+}
+
+func TestRunServiceRunsConcurrently(t *testing.T) {
+	const runs = 8
+	provider := &mocks.MockModelProvider{}
+	provider.On("GetModel", "test-model").Return(mocks.NewScriptedModel(), nil).Maybe()
+	config := &runner.RunConfig{TracingDisabled: true}
+
+	// The runner fills in its default provider on the run config of every run
+	responses := make([]*model.Response, runs)
+	for i := range responses {
+		responses[i] = &model.Response{Content: "hello"}
+	}
+	a := agent.NewAgent("Assistant").WithModel(mocks.NewScriptedModel(responses...))
+	s := server.NewRunService(runner.NewRunner().WithDefaultProvider(provider), a).
+		WithRunOptions(&runner.RunOptions{RunConfig: config})
+
+	var wg sync.WaitGroup
+	ids := make([]string, runs)
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			created, err := s.CreateRun(context.Background(), server.CreateRunRequest{Input: "hi"})
+			assert.NoError(t, err)
+			ids[i] = created.ID
+		}(i)
+	}
+	wg.Wait()
+
+	for _, id := range ids {
+		followed, err := s.StreamEvents(context.Background(), id, 0)
+		require.NoError(t, err)
+		for range followed.Stream {
+		}
+		assert.Equal(t, "hello", followed.FinalOutput)
+	}
+	assert.Nil(t, config.ModelProvider, "the shared run config is not modified")
+}
+
+func TestRunServiceErrors(t *testing.T) {
+	m := newStallingModel()
+	s := newRunService(agent.NewAgent("Assistant").WithModel(m))
+
+	_, err := s.CreateRun(context.Background(), server.CreateRunRequest{Agent: "Nobody", Input: "hi"})
+	assert.ErrorIs(t, err, server.ErrUnknownAgent)
+	_, err = s.CreateRun(context.Background(), server.CreateRunRequest{})
+	assert.ErrorIs(t, err, server.ErrInputRequired)
+	_, err = s.GetRunResult("missing")
+	assert.ErrorIs(t, err, server.ErrRunNotFound)
+
+	_, err = s.CreateRun(context.Background(), server.CreateRunRequest{Input: "hi", RunID: "run-1"})
+	require.NoError(t, err)
+	<-m.started
+	_, err = s.CreateRun(context.Background(), server.CreateRunRequest{Input: "hi", RunID: "run-1"})
+	assert.ErrorIs(t, err, server.ErrRunExists)
+
+	followed, err := s.StreamEvents(context.Background(), "run-1", 0)
+	require.NoError(t, err)
+	_, err = s.CancelRun("run-1")
+	require.NoError(t, err)
+	for range followed.Stream {
+	}
+	assert.False(t, followed.IsComplete)
+
+	status, err := s.GetRunResult("run-1")
+	require.NoError(t, err)
+	assert.Equal(t, server.RunStatusCancelled, status.Status)
+}
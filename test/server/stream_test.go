@@ -0,0 +1,222 @@
+package server_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/server/stream"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/websocket"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stallingModel streams nothing until the context of the run ends
+type stallingModel struct {
+	started   chan struct{}
+	cancelled chan struct{}
+}
+
+func newStallingModel() *stallingModel {
+	return &stallingModel{started: make(chan struct{}), cancelled: make(chan struct{})}
+}
+
+func (m *stallingModel) GetResponse(ctx context.Context, request *model.Request) (*model.Response, error) {
+	return nil, ctx.Err()
+}
+
+func (m *stallingModel) StreamResponse(ctx context.Context, request *model.Request) (<-chan model.StreamEvent, error) {
+	events := make(chan model.StreamEvent)
+	go func() {
+		defer close(events)
+		close(m.started)
+		<-ctx.Done()
+		close(m.cancelled)
+	}()
+	return events, nil
+}
+
+// startAgent returns a start function streaming a run of an agent on the input
+// in the request's q parameter
+func startAgent(a *agent.Agent) stream.StartFunc {
+	return func(ctx context.Context, r *http.Request) (*result.StreamedRunResult, error) {
+		return runner.NewRunner().RunStreaming(ctx, a, &runner.RunOptions{Input: r.URL.Query().Get("q"), RunConfig: rpcRunConfig()})
+	}
+}
+
+// sseEvent is an event read from a server-sent event stream
+type sseEvent struct {
+	ID   string
+	Name string
+	Data stream.Event
+}
+
+// readSSE reads server-sent events until the end event, returning the events
+// and the comment lines
+func readSSE(t *testing.T, body *bufio.Reader) ([]sseEvent, []string) {
+	var (
+		events   []sseEvent
+		comments []string
+		current  sseEvent
+	)
+	for {
+		line, err := body.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if current.Name == "" {
+				continue
+			}
+			events = append(events, current)
+			if current.Name == stream.EventTypeEnd {
+				return events, comments
+			}
+			current = sseEvent{}
+		case strings.HasPrefix(line, ":"):
+			comments = append(comments, line)
+		case strings.HasPrefix(line, "id: "):
+			current.ID = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			current.Name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &current.Data))
+		}
+	}
+}
+
+func TestSSEHandlerStreamsRun(t *testing.T) {
+	a := agent.NewAgent("Assistant").WithModel(mocks.NewScriptedModel(&model.Response{Content: "hello there", Usage: &model.Usage{TotalTokens: 7}}))
+	srv := httptest.NewServer(stream.SSEHandler(startAgent(a)))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?q=hi")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	events, _ := readSSE(t, bufio.NewReader(resp.Body))
+	var names []string
+	for _, event := range events {
+		names = append(names, event.Name)
+		assert.Equal(t, event.Name, event.Data.Type)
+	}
+	assert.Equal(t, []string{
+		model.StreamEventTypeRunStarted,
+		model.StreamEventTypeTurnStarted,
+		model.StreamEventTypeContent,
+		model.StreamEventTypeUsage,
+		model.StreamEventTypeRunCompleted,
+		stream.EventTypeEnd,
+	}, names)
+
+	assert.Equal(t, "1", events[0].ID)
+	assert.Equal(t, "Assistant", events[0].Data.Agent)
+	assert.Equal(t, "hello there", events[2].Data.Content)
+	assert.Equal(t, 7, events[3].Data.Usage.TotalTokens)
+	assert.Empty(t, events[5].ID, "the end event is not part of the run's stream")
+	assert.Equal(t, "hello there", events[5].Data.Output)
+}
+
+func TestSSEDisconnectCancelsRun(t *testing.T) {
+	m := newStallingModel()
+	srv := httptest.NewServer(stream.SSEHandler(startAgent(agent.NewAgent("Assistant").WithModel(m))))
+	defer srv.Close()
+
+	ctx, disconnect := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?q=hi", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	<-m.started
+	disconnect()
+
+	select {
+	case <-m.cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the run was not cancelled after the client disconnected")
+	}
+}
+
+func TestSSEHeartbeats(t *testing.T) {
+	m := newStallingModel()
+	a := agent.NewAgent("Assistant").WithModel(m)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 100*time.Millisecond)
+		defer cancel()
+		streamed, err := startAgent(a)(ctx, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		stream.ServeSSE(w, r, streamed, cancel, stream.WithHeartbeat(10*time.Millisecond))
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?q=hi")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	events, comments := readSSE(t, bufio.NewReader(resp.Body))
+	assert.NotEmpty(t, comments)
+	assert.Equal(t, ": heartbeat", comments[0])
+	assert.Equal(t, model.StreamEventTypeError, events[len(events)-2].Name, "the run ends with its deadline")
+	assert.Nil(t, events[len(events)-1].Data.Output)
+}
+
+func TestWebSocketHandlerStreamsRun(t *testing.T) {
+	a := agent.NewAgent("Assistant").WithModel(mocks.NewScriptedModel(&model.Response{Content: "hello there"}))
+	srv := httptest.NewServer(stream.WebSocketHandler(startAgent(a)))
+	defer srv.Close()
+
+	conn, _, err := websocket.Dial(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http")+"?q=hi", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var events []stream.Event
+	for {
+		messageType, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, websocket.TextMessage, messageType)
+		var event stream.Event
+		require.NoError(t, json.Unmarshal(data, &event))
+		events = append(events, event)
+		if event.Type == stream.EventTypeEnd {
+			break
+		}
+	}
+
+	assert.Equal(t, model.StreamEventTypeRunStarted, events[0].Type)
+	assert.Equal(t, int64(1), events[0].Sequence)
+	assert.Equal(t, "hello there", events[len(events)-1].Output)
+}
+
+func TestWebSocketCloseCancelsRun(t *testing.T) {
+	m := newStallingModel()
+	srv := httptest.NewServer(stream.WebSocketHandler(startAgent(agent.NewAgent("Assistant").WithModel(m))))
+	defer srv.Close()
+
+	conn, _, err := websocket.Dial(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http")+"?q=hi", nil)
+	require.NoError(t, err)
+
+	<-m.started
+	require.NoError(t, conn.Close())
+
+	select {
+	case <-m.cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the run was not cancelled after the client closed the connection")
+	}
+}
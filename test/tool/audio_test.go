@@ -0,0 +1,73 @@
+package tool_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/artifact"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool/audio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAudio records audio requests and answers with fixed results
+type fakeAudio struct {
+	transcriptions []*model.TranscriptionRequest
+	speeches       []*model.SpeechRequest
+}
+
+func (f *fakeAudio) Transcribe(ctx context.Context, request *model.TranscriptionRequest) (*model.Transcription, error) {
+	f.transcriptions = append(f.transcriptions, request)
+	return &model.Transcription{Text: "call me back tomorrow"}, nil
+}
+
+func (f *fakeAudio) Synthesize(ctx context.Context, request *model.SpeechRequest) (*model.Speech, error) {
+	f.speeches = append(f.speeches, request)
+	return &model.Speech{Audio: []byte("ID3" + request.Text), MediaType: "audio/mpeg"}, nil
+}
+
+func TestTranscriptionToolReadsArtifacts(t *testing.T) {
+	ctx := context.Background()
+	provider := &fakeAudio{}
+	store := artifact.NewMemoryStore()
+	note, err := store.Put(ctx, artifact.Artifact{Name: "voicemail", MediaType: "audio/mpeg"}, []byte("ID3"))
+	require.NoError(t, err)
+
+	transcribe := audio.NewTranscriptionTool(provider, store).WithLanguage("en")
+	assert.Equal(t, "transcribe_audio", transcribe.GetName())
+
+	out, err := transcribe.Execute(ctx, map[string]interface{}{"artifact_id": note.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "call me back tomorrow", out.(*model.Transcription).Text)
+
+	require.Len(t, provider.transcriptions, 1)
+	assert.Equal(t, []byte("ID3"), provider.transcriptions[0].Audio)
+	assert.Equal(t, "voicemail.mp3", provider.transcriptions[0].Filename)
+	assert.Equal(t, "en", provider.transcriptions[0].Language)
+
+	_, err = transcribe.Execute(ctx, map[string]interface{}{"artifact_id": "missing"})
+	assert.ErrorIs(t, err, artifact.ErrNotFound)
+}
+
+func TestSpeechToolStoresAudio(t *testing.T) {
+	ctx := context.Background()
+	provider := &fakeAudio{}
+	store := artifact.NewMemoryStore()
+
+	speak := audio.NewSpeechTool(provider, store).WithVoice("alloy").WithVoices("alloy", "nova")
+	assert.Contains(t, speak.GetParametersSchema()["properties"], "voice")
+
+	out, err := speak.Execute(ctx, map[string]interface{}{"text": "hello", "voice": "nova"})
+	require.NoError(t, err)
+
+	response := out.(*audio.SpeechResponse)
+	assert.Equal(t, "audio/mpeg", response.MediaType)
+	_, data, err := store.Get(ctx, response.ArtifactID)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("ID3hello"), data)
+	assert.Equal(t, "nova", provider.speeches[0].Voice)
+
+	_, err = speak.Execute(ctx, map[string]interface{}{"text": "hello", "voice": "shimmer"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,75 @@
+package tool_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool/exec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunCommandRejectsUnlistedCommands(t *testing.T) {
+	executor := exec.New(t.TempDir()).WithAllowedCommands("echo")
+
+	_, err := executor.RunCommand(context.Background(), exec.CommandParams{Command: "rm", Args: []string{"-rf", "/"}})
+	assert.True(t, errors.Is(err, exec.ErrCommandNotAllowed))
+
+	_, err = executor.RunCommand(context.Background(), exec.CommandParams{Command: "/bin/echo"})
+	assert.True(t, errors.Is(err, exec.ErrCommandNotAllowed))
+}
+
+func TestRunCommandInWorkingDirectory(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "marker.txt"), []byte("ok"), 0o644))
+	executor := exec.New(dir).WithAllowedCommands("ls", "cat")
+
+	out, err := executor.RunCommand(context.Background(), exec.CommandParams{Command: "ls"})
+	require.NoError(t, err)
+	assert.Equal(t, 0, out.ExitCode)
+	assert.Contains(t, out.Stdout, "marker.txt")
+
+	out, err = executor.RunCommand(context.Background(), exec.CommandParams{Command: "cat", Args: []string{"missing.txt"}})
+	require.NoError(t, err)
+	assert.NotEqual(t, 0, out.ExitCode)
+	assert.NotEmpty(t, out.Stderr)
+}
+
+func TestRunCommandTimeoutAndTruncation(t *testing.T) {
+	executor := exec.New(t.TempDir()).
+		WithAllowedCommands("sleep", "yes").
+		WithTimeout(200 * time.Millisecond).
+		WithMaxOutputBytes(16)
+
+	out, err := executor.RunCommand(context.Background(), exec.CommandParams{Command: "sleep", Args: []string{"5"}})
+	require.NoError(t, err)
+	assert.True(t, out.TimedOut)
+	assert.Less(t, out.DurationMS, int64(3000))
+
+	out, err = executor.RunCommand(context.Background(), exec.CommandParams{Command: "yes"})
+	require.NoError(t, err)
+	assert.True(t, out.Truncated)
+	assert.Len(t, out.Stdout, 16)
+}
+
+func TestRunScriptRequiresShell(t *testing.T) {
+	executor := exec.New(t.TempDir())
+	assert.Len(t, executor.Tools(), 1)
+
+	_, err := executor.RunScript(context.Background(), exec.ScriptParams{Script: "echo hi"})
+	assert.Error(t, err)
+
+	executor.WithShell("sh").WithEnv("GREETING=hello")
+	require.Len(t, executor.Tools(), 2)
+
+	out, err := executor.Tools()[1].Execute(context.Background(), map[string]interface{}{"script": "echo $GREETING; exit 3"})
+	require.NoError(t, err)
+	result := out.(*exec.Result)
+	assert.Equal(t, "hello", strings.TrimSpace(result.Stdout))
+	assert.Equal(t, 3, result.ExitCode)
+}
@@ -0,0 +1,144 @@
+package tool_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool/fs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSandbox(t *testing.T) (*fs.Sandbox, string) {
+	t.Helper()
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "src", "pkg"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "src", "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "src", "pkg", "util.go"), []byte("package pkg\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "README.md"), []byte("# demo\n"), 0o644))
+
+	sandbox, err := fs.NewSandbox(root)
+	require.NoError(t, err)
+	return sandbox, root
+}
+
+func findTool(t *testing.T, sandbox *fs.Sandbox, name string) tool.Tool {
+	t.Helper()
+	for _, tl := range sandbox.Tools() {
+		if tl.GetName() == name {
+			return tl
+		}
+	}
+	t.Fatalf("tool %s not found", name)
+	return nil
+}
+
+func TestFSReadFileAndLineRange(t *testing.T) {
+	sandbox, _ := newSandbox(t)
+	read := findTool(t, sandbox, "read_file")
+
+	out, err := read.Execute(context.Background(), map[string]interface{}{"path": "src/main.go"})
+	require.NoError(t, err)
+	assert.Equal(t, "package main\n\nfunc main() {}\n", out.(*fs.ReadFileResult).Content)
+
+	out, err = read.Execute(context.Background(), map[string]interface{}{"path": "src/main.go", "start_line": float64(3), "end_line": float64(3)})
+	require.NoError(t, err)
+	assert.Equal(t, "func main() {}\n", out.(*fs.ReadFileResult).Content)
+}
+
+func TestFSRejectsPathsOutsideRoot(t *testing.T) {
+	sandbox, root := newSandbox(t)
+	read := findTool(t, sandbox, "read_file")
+
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	require.NoError(t, os.WriteFile(outside, []byte("secret"), 0o600))
+	require.NoError(t, os.Symlink(filepath.Dir(outside), filepath.Join(root, "escape")))
+
+	for _, path := range []string{"../secret.txt", outside, "escape/secret.txt", "src/../../secret.txt"} {
+		_, err := read.Execute(context.Background(), map[string]interface{}{"path": path})
+		assert.True(t, errors.Is(err, fs.ErrOutsideRoot), "path %s: expected ErrOutsideRoot, got %v", path, err)
+	}
+
+	write := findTool(t, sandbox, "write_file")
+	_, err := write.Execute(context.Background(), map[string]interface{}{"path": "escape/new.txt", "content": "x"})
+	assert.True(t, errors.Is(err, fs.ErrOutsideRoot))
+}
+
+func TestFSSizeLimits(t *testing.T) {
+	sandbox, _ := newSandbox(t)
+	sandbox.WithMaxReadBytes(10).WithMaxWriteBytes(4)
+
+	_, err := findTool(t, sandbox, "read_file").Execute(context.Background(), map[string]interface{}{"path": "src/main.go"})
+	assert.ErrorContains(t, err, "byte limit")
+
+	_, err = findTool(t, sandbox, "write_file").Execute(context.Background(), map[string]interface{}{"path": "a.txt", "content": "too long"})
+	assert.ErrorContains(t, err, "byte limit")
+}
+
+func TestFSWriteAndPatch(t *testing.T) {
+	sandbox, root := newSandbox(t)
+
+	out, err := findTool(t, sandbox, "write_file").Execute(context.Background(), map[string]interface{}{
+		"path": "docs/guide.md", "content": "hello world\n", "create_dirs": true,
+	})
+	require.NoError(t, err)
+	assert.True(t, out.(*fs.WriteFileResult).Created)
+
+	patch := findTool(t, sandbox, "patch")
+	_, err = patch.Execute(context.Background(), map[string]interface{}{
+		"path":  "docs/guide.md",
+		"edits": []interface{}{map[string]interface{}{"old_text": "world", "new_text": "sandbox"}},
+	})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(root, "docs", "guide.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello sandbox\n", string(data))
+
+	_, err = patch.Execute(context.Background(), map[string]interface{}{
+		"path":  "docs/guide.md",
+		"edits": []interface{}{map[string]interface{}{"old_text": "missing", "new_text": "x"}},
+	})
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestFSDryRunLeavesFilesUntouched(t *testing.T) {
+	sandbox, root := newSandbox(t)
+	sandbox.WithDryRun(true)
+
+	out, err := findTool(t, sandbox, "write_file").Execute(context.Background(), map[string]interface{}{"path": "README.md", "content": "changed"})
+	require.NoError(t, err)
+	assert.True(t, out.(*fs.WriteFileResult).DryRun)
+
+	_, err = findTool(t, sandbox, "patch").Execute(context.Background(), map[string]interface{}{
+		"path":  "README.md",
+		"edits": []interface{}{map[string]interface{}{"old_text": "demo", "new_text": "changed"}},
+	})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(root, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# demo\n", string(data))
+}
+
+func TestFSListDirAndGlob(t *testing.T) {
+	sandbox, _ := newSandbox(t)
+
+	out, err := findTool(t, sandbox, "list_dir").Execute(context.Background(), map[string]interface{}{"path": "src"})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []fs.Entry{
+		{Name: "main.go", Type: "file", Size: 29},
+		{Name: "pkg", Type: "dir"},
+	}, out.(*fs.ListDirResult).Entries)
+
+	out, err = findTool(t, sandbox, "glob").Execute(context.Background(), map[string]interface{}{"pattern": "src/**/*.go"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"src/main.go", "src/pkg/util.go"}, out.(*fs.GlobResult).Matches)
+
+	_, err = findTool(t, sandbox, "glob").Execute(context.Background(), map[string]interface{}{"pattern": "../*"})
+	assert.True(t, errors.Is(err, fs.ErrOutsideRoot))
+}
@@ -0,0 +1,132 @@
+package tool_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool/httpfetch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFetchServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/items", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"method": r.Method,
+			"token":  r.Header.Get("Authorization"),
+			"body":   string(body),
+		})
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, `<html><head><title>x</title><script>var a = 1;</script></head><body><h1>Hello &amp; welcome</h1><p>Second line</p></body></html>`)
+	})
+	mux.HandleFunc("/big", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, strings.Repeat("a", 1000))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/items", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPRequestSendsJSON(t *testing.T) {
+	server := newFetchServer(t)
+	fetch := httpfetch.New().WithPrivateNetworks(true).WithHeader("Authorization", "Bearer secret")
+
+	out, err := fetch.Execute(context.Background(), map[string]interface{}{
+		"method":  "post",
+		"url":     server.URL + "/items",
+		"body":    map[string]interface{}{"name": "widget"},
+		"headers": map[string]interface{}{"Authorization": "Bearer forged"},
+	})
+	require.NoError(t, err)
+
+	resp := out.(*httpfetch.Response)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	decoded := resp.JSON.(map[string]interface{})
+	assert.Equal(t, "POST", decoded["method"])
+	assert.Equal(t, "Bearer secret", decoded["token"])
+	assert.JSONEq(t, `{"name":"widget"}`, decoded["body"].(string))
+}
+
+func TestHTTPRequestExtractsTextAndTruncates(t *testing.T) {
+	server := newFetchServer(t)
+	out, err := httpfetch.New().WithPrivateNetworks(true).Execute(context.Background(), map[string]interface{}{"url": server.URL + "/page"})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello & welcome\nSecond line", out.(*httpfetch.Response).Text)
+
+	out, err = httpfetch.New().WithPrivateNetworks(true).WithMaxResponseBytes(100).Execute(context.Background(), map[string]interface{}{"url": server.URL + "/big"})
+	require.NoError(t, err)
+	resp := out.(*httpfetch.Response)
+	assert.True(t, resp.Truncated)
+	assert.Len(t, resp.Text, 100)
+}
+
+func TestHTTPRequestDomainPolicy(t *testing.T) {
+	fetch := httpfetch.New().
+		WithAllowedDomains("api.example.com", "*.example.org").
+		WithDeniedDomains("internal.example.org")
+
+	for _, target := range []string{"https://evil.com/", "https://example.org/", "https://internal.example.org/", "https://internal.example.org./", "https://api.example.com.evil.com/"} {
+		_, err := fetch.Execute(context.Background(), map[string]interface{}{"url": target})
+		assert.True(t, errors.Is(err, httpfetch.ErrDomainNotAllowed), target)
+	}
+
+	_, err := fetch.Execute(context.Background(), map[string]interface{}{"url": "file:///etc/passwd"})
+	assert.Error(t, err)
+
+	_, err = httpfetch.New().WithMethods("GET").Execute(context.Background(), map[string]interface{}{"method": "DELETE", "url": "https://api.example.com/"})
+	assert.True(t, errors.Is(err, httpfetch.ErrMethodNotAllowed))
+}
+
+// roundTripFunc answers requests without a network
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestHTTPRequestMatchesFullyQualifiedHosts(t *testing.T) {
+	ok := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok")), Header: http.Header{}, Request: req}, nil
+	})
+
+	_, err := httpfetch.New().WithTransport(ok).WithDeniedDomains("evil.com").
+		Execute(context.Background(), map[string]interface{}{"url": "https://evil.com./"})
+	assert.True(t, errors.Is(err, httpfetch.ErrDomainNotAllowed), "got %v", err)
+
+	out, err := httpfetch.New().WithTransport(ok).WithAllowedDomains("api.github.com").
+		Execute(context.Background(), map[string]interface{}{"url": "https://api.github.com./repos"})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", out.(*httpfetch.Response).Text)
+}
+
+func TestHTTPRequestBlocksPrivateNetworks(t *testing.T) {
+	server := newFetchServer(t)
+
+	_, err := httpfetch.New().Execute(context.Background(), map[string]interface{}{"url": server.URL + "/items"})
+	assert.True(t, errors.Is(err, httpfetch.ErrPrivateAddress), "got %v", err)
+}
+
+func TestHTTPRequestRedirectPolicy(t *testing.T) {
+	server := newFetchServer(t)
+
+	out, err := httpfetch.New().WithPrivateNetworks(true).Execute(context.Background(), map[string]interface{}{"url": server.URL + "/redirect"})
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/items", out.(*httpfetch.Response).URL)
+
+	out, err = httpfetch.New().WithPrivateNetworks(true).WithMaxRedirects(0).Execute(context.Background(), map[string]interface{}{"url": server.URL + "/redirect"})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusFound, out.(*httpfetch.Response).StatusCode)
+}
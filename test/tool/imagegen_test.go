@@ -0,0 +1,96 @@
+package tool_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/lmstudio"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model/providers/openai"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool/imagegen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateImageWithOpenAI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/images/generations", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "dall-e-3", body["model"])
+		assert.Equal(t, "a lighthouse at dawn", body["prompt"])
+		assert.Equal(t, "1792x1024", body["size"])
+		assert.Equal(t, "url", body["response_format"])
+
+		_, _ = w.Write([]byte(`{"created":1,"data":[{"url":"https://images.example.com/1.png","revised_prompt":"A lighthouse at dawn, watercolor"}]}`))
+	}))
+	defer server.Close()
+
+	provider := openai.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+
+	images, err := imagegen.FromProvider(provider)
+	require.NoError(t, err)
+	images.WithSizes("1024x1024", "1792x1024")
+	assert.Equal(t, "generate_image", images.GetName())
+	assert.Contains(t, images.GetParametersSchema()["properties"], "size")
+
+	out, err := images.Execute(context.Background(), map[string]interface{}{
+		"prompt": "a lighthouse at dawn",
+		"size":   "1792x1024",
+	})
+	require.NoError(t, err)
+
+	response := out.(*imagegen.Response)
+	require.Len(t, response.Images, 1)
+	assert.Equal(t, "https://images.example.com/1.png", response.Images[0].URL)
+	assert.Equal(t, "A lighthouse at dawn, watercolor", response.Images[0].RevisedPrompt)
+
+	_, err = images.Execute(context.Background(), map[string]interface{}{"prompt": "x", "size": "10x10"})
+	assert.Error(t, err)
+}
+
+func TestGenerateImageSavesBase64Data(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.NotContains(t, body, "response_format")
+		assert.Equal(t, float64(2), body["n"])
+
+		_, _ = w.Write([]byte(`{"data":[{"b64_json":"iVBORw=="},{"b64_json":"iVBORw=="}],"output_format":"png","usage":{"input_tokens":10,"output_tokens":20,"total_tokens":30}}`))
+	}))
+	defer server.Close()
+
+	provider := openai.NewProvider("test-key")
+	provider.SetBaseURL(server.URL)
+
+	var saved [][]byte
+	images := imagegen.New(provider).
+		WithModel("gpt-image-1").
+		WithFormat(model.ImageFormatBase64).
+		WithMaxImages(2).
+		WithSaveFunc(func(ctx context.Context, image model.GeneratedImage) (string, error) {
+			assert.Equal(t, "image/png", image.MediaType)
+			saved = append(saved, image.Data)
+			return "file:///tmp/image.png", nil
+		})
+
+	out, err := images.Execute(context.Background(), map[string]interface{}{"prompt": "a logo", "n": float64(5)})
+	require.NoError(t, err)
+
+	response := out.(*imagegen.Response)
+	require.Len(t, response.Images, 2)
+	assert.Equal(t, "file:///tmp/image.png", response.Images[0].URL)
+	assert.Nil(t, response.Images[0].Data)
+	assert.Equal(t, [][]byte{{0x89, 'P', 'N', 'G'}, {0x89, 'P', 'N', 'G'}}, saved)
+}
+
+func TestImageGenerationRequiresCapability(t *testing.T) {
+	_, err := imagegen.FromProvider(lmstudio.NewProvider())
+	assert.Error(t, err)
+}
@@ -0,0 +1,203 @@
+package tool_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/logging"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMCPServer is a minimal MCP server speaking the HTTP+SSE transport
+type fakeMCPServer struct {
+	events chan string
+	mu     sync.Mutex
+	calls  []string
+}
+
+func newFakeMCPServer() (*fakeMCPServer, *httptest.Server) {
+	f := &fakeMCPServer{events: make(chan string, 16)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sse", f.handleStream)
+	mux.HandleFunc("/messages", f.handleMessage)
+	return f, httptest.NewServer(mux)
+}
+
+func (f *fakeMCPServer) handleStream(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher := w.(http.Flusher)
+	fmt.Fprint(w, "event: endpoint\ndata: /messages?session=1\n\n")
+	flusher.Flush()
+
+	for {
+		select {
+		case event := <-f.events:
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", event)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (f *fakeMCPServer) handleMessage(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var req struct {
+		ID     json.RawMessage        `json:"id"`
+		Method string                 `json:"method"`
+		Params map[string]interface{} `json:"params"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+
+	f.mu.Lock()
+	f.calls = append(f.calls, req.Method)
+	f.mu.Unlock()
+
+	var result interface{}
+	switch req.Method {
+	case "initialize":
+		result = map[string]interface{}{
+			"protocolVersion": mcp.ProtocolVersion,
+			"serverInfo":      map[string]interface{}{"name": "fake", "version": "0.1"},
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+		}
+	case "tools/list":
+		result = map[string]interface{}{
+			"tools": []interface{}{
+				map[string]interface{}{
+					"name":        "echo",
+					"description": "Echoes the message",
+					"inputSchema": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"message": map[string]interface{}{"type": "string"},
+						},
+						"required": []string{"message"},
+					},
+				},
+			},
+		}
+	case "tools/call":
+		args, _ := req.Params["arguments"].(map[string]interface{})
+		if args["message"] == "fail" {
+			result = map[string]interface{}{
+				"content": []interface{}{map[string]interface{}{"type": "text", "text": "boom"}},
+				"isError": true,
+			}
+		} else {
+			result = map[string]interface{}{
+				"content": []interface{}{map[string]interface{}{"type": "text", "text": fmt.Sprintf("echo: %v", args["message"])}},
+			}
+		}
+	default:
+		// Notifications have no response
+		return
+	}
+
+	resp, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	f.events <- string(resp)
+}
+
+func (f *fakeMCPServer) methods() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+func TestMCPServerOverSSE(t *testing.T) {
+	fake, httpServer := newFakeMCPServer()
+	defer httpServer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	server := mcp.NewSSEServer(httpServer.URL + "/sse")
+	defer server.Close()
+
+	tools, err := server.ListTools(ctx)
+	require.NoError(t, err)
+	require.Len(t, tools, 1)
+
+	echo := tools[0]
+	assert.Equal(t, "echo", echo.GetName())
+	assert.Equal(t, "Echoes the message", echo.GetDescription())
+	assert.Equal(t, []interface{}{"message"}, echo.GetParametersSchema()["required"])
+	assert.Equal(t, "fake", server.Client().ServerInfo().Name)
+
+	out, err := echo.Execute(ctx, map[string]interface{}{"message": "hi"})
+	require.NoError(t, err)
+	assert.Equal(t, "echo: hi", out)
+
+	_, err = echo.Execute(ctx, map[string]interface{}{"message": "fail"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "boom")
+
+	assert.Equal(t, []string{"initialize", "notifications/initialized", "tools/list", "tools/call", "tools/call"}, fake.methods())
+}
+
+func TestAgentWithMCPServer(t *testing.T) {
+	_, httpServer := newFakeMCPServer()
+	defer httpServer.Close()
+
+	server := mcp.NewSSEServer(httpServer.URL + "/sse")
+	defer server.Close()
+
+	a := agent.NewAgent("assistant").WithMCPServer(server)
+	assert.Empty(t, a.Tools)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, a.LoadMCPTools(ctx))
+	require.Len(t, a.Tools, 1)
+	assert.Equal(t, "echo", a.Tools[0].GetName())
+
+	// Loading again does not register the tools twice
+	require.NoError(t, a.LoadMCPTools(ctx))
+	assert.Len(t, a.Tools, 1)
+}
+
+func TestStdioTransportLogsStderr(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	transport := mcp.NewStdioTransport("sh", "-c", `echo "starting with api_key=abc123" >&2; echo "second line" >&2; echo ready; cat >/dev/null`).
+		WithLogger(logger)
+	require.NoError(t, transport.Start(context.Background()))
+
+	select {
+	case message := <-transport.Messages():
+		assert.Equal(t, "ready", string(message))
+	case <-time.After(5 * time.Second):
+		t.Fatal("the subprocess did not start")
+	}
+	// Close waits until stderr has been read to the end
+	require.NoError(t, transport.Close())
+
+	var lines []string
+	for _, record := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal(record, &entry))
+		assert.Equal(t, "DEBUG", entry["level"])
+		assert.Equal(t, "mcp", entry[logging.ComponentKey])
+		assert.Equal(t, "sh", entry["command"])
+		lines = append(lines, entry["line"].(string))
+	}
+	assert.Equal(t, []string{"starting with api_key=[REDACTED]", "second line"}, lines)
+}
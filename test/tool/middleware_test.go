@@ -0,0 +1,105 @@
+package tool_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingTool fails its first failures executions and counts every execution
+func countingTool(failures int32, delay time.Duration) (tool.Tool, *int32) {
+	var calls int32
+	t := tool.NewFunctionTool("flaky", "Fails a few times", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		n := atomic.AddInt32(&calls, 1)
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		if n <= failures {
+			return nil, errors.New("temporary failure")
+		}
+		return "ok", nil
+	})
+	return t, &calls
+}
+
+func TestWithRetryRecordsAttempts(t *testing.T) {
+	flaky, calls := countingTool(2, 0)
+	wrapped := tool.Wrap(flaky, tool.WithRetry(3, time.Millisecond))
+
+	ctx, info := tool.TrackExecution(context.Background())
+	result, err := wrapped.Execute(ctx, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result)
+	assert.Equal(t, int32(3), atomic.LoadInt32(calls))
+	require.NotNil(t, info())
+	assert.Equal(t, 3, info().Attempts)
+}
+
+func TestWithRetryGivesUp(t *testing.T) {
+	flaky, calls := countingTool(5, 0)
+	wrapped := tool.Wrap(flaky, tool.WithRetry(2, 0))
+
+	_, err := wrapped.Execute(context.Background(), map[string]interface{}{})
+	assert.EqualError(t, err, "temporary failure")
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
+
+func TestWithTimeout(t *testing.T) {
+	slow, _ := countingTool(0, 200*time.Millisecond)
+	wrapped := tool.Wrap(slow, tool.WithTimeout(20*time.Millisecond))
+
+	ctx, info := tool.TrackExecution(context.Background())
+	_, err := wrapped.Execute(ctx, map[string]interface{}{})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	require.NotNil(t, info())
+	assert.True(t, info().TimedOut)
+}
+
+func TestWithCache(t *testing.T) {
+	counter, calls := countingTool(0, 0)
+	wrapped := tool.Wrap(counter, tool.WithCache(time.Minute))
+
+	_, err := wrapped.Execute(context.Background(), map[string]interface{}{"q": "a"})
+	require.NoError(t, err)
+
+	ctx, info := tool.TrackExecution(context.Background())
+	result, err := wrapped.Execute(ctx, map[string]interface{}{"q": "a"})
+	require.NoError(t, err)
+	assert.Equal(t, "ok", result)
+	require.NotNil(t, info())
+	assert.True(t, info().CacheHit)
+
+	_, err = wrapped.Execute(context.Background(), map[string]interface{}{"q": "b"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(calls))
+}
+
+func TestWithRateLimit(t *testing.T) {
+	counter, _ := countingTool(0, 0)
+	wrapped := tool.Wrap(counter, tool.WithRateLimit(1, 50*time.Millisecond))
+
+	_, err := wrapped.Execute(context.Background(), map[string]interface{}{})
+	require.NoError(t, err)
+
+	ctx, info := tool.TrackExecution(context.Background())
+	_, err = wrapped.Execute(ctx, map[string]interface{}{})
+	require.NoError(t, err)
+	require.NotNil(t, info())
+	assert.Greater(t, info().RateLimitWait, 10*time.Millisecond)
+}
+
+func TestTrackExecutionWithoutMiddleware(t *testing.T) {
+	counter, _ := countingTool(0, 0)
+
+	ctx, info := tool.TrackExecution(context.Background())
+	_, err := counter.Execute(ctx, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.Nil(t, info())
+}
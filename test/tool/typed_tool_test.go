@@ -0,0 +1,84 @@
+package tool_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type weatherParams struct {
+	City  string   `json:"city" doc:"The city to look up"`
+	Days  int      `json:"days,omitempty" doc:"Number of forecast days"`
+	Units []string `json:"units,omitempty"`
+}
+
+type weatherResult struct {
+	City string
+	Days int
+}
+
+func newWeatherTool() *tool.TypedTool[weatherParams, weatherResult] {
+	return tool.NewTypedTool("weather", "Get the weather", func(ctx context.Context, params weatherParams) (weatherResult, error) {
+		return weatherResult{City: params.City, Days: params.Days}, nil
+	})
+}
+
+func TestTypedToolSchema(t *testing.T) {
+	weather := newWeatherTool()
+
+	schema := weather.GetParametersSchema()
+	assert.Equal(t, "object", schema["type"])
+	assert.Equal(t, []string{"city"}, schema["required"])
+
+	properties := schema["properties"].(map[string]interface{})
+	city := properties["city"].(map[string]interface{})
+	assert.Equal(t, "string", city["type"])
+	assert.Equal(t, "The city to look up", city["description"])
+	assert.Equal(t, "integer", properties["days"].(map[string]interface{})["type"])
+	assert.Equal(t, "array", properties["units"].(map[string]interface{})["type"])
+}
+
+func TestTypedToolExecute(t *testing.T) {
+	weather := newWeatherTool()
+
+	result, err := weather.Execute(context.Background(), map[string]interface{}{
+		"city": "Stockholm",
+		"days": float64(3),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, weatherResult{City: "Stockholm", Days: 3}, result)
+}
+
+func TestTypedToolValidation(t *testing.T) {
+	weather := newWeatherTool()
+
+	_, err := weather.Execute(context.Background(), map[string]interface{}{
+		"days":  1.5,
+		"units": []interface{}{"metric", 7},
+	})
+	require.Error(t, err)
+
+	var argErr *tool.ArgumentError
+	require.True(t, errors.As(err, &argErr))
+	assert.Equal(t, "weather", argErr.Tool)
+	assert.Contains(t, argErr.Problems, "city is required")
+	assert.Contains(t, argErr.Problems, "days must be an integer")
+	assert.Contains(t, argErr.Problems, "units[1] must be a string")
+}
+
+func TestTypedToolScalarParams(t *testing.T) {
+	double := tool.NewTypedTool("double", "Double a number", func(ctx context.Context, n int) (int, error) {
+		return n * 2, nil
+	})
+
+	schema := double.GetParametersSchema()
+	assert.Equal(t, []string{"value"}, schema["required"])
+
+	result, err := double.Execute(context.Background(), map[string]interface{}{"value": float64(21)})
+	require.NoError(t, err)
+	assert.Equal(t, 42, result)
+}
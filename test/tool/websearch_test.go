@@ -0,0 +1,92 @@
+package tool_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool/websearch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebSearchWithBrave(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.Header.Get("X-Subscription-Token"))
+		assert.Equal(t, "go generics", r.URL.Query().Get("q"))
+		assert.Equal(t, "2", r.URL.Query().Get("count"))
+		assert.Equal(t, "pw", r.URL.Query().Get("freshness"))
+		_, _ = w.Write([]byte(`{"web":{"results":[
+			{"title":"Generics","url":"https://go.dev/doc/tutorial/generics","description":"Tutorial","page_age":"2024-01-01"},
+			{"title":"Spec","url":"https://go.dev/ref/spec","description":"Language spec"},
+			{"title":"Extra","url":"https://example.com","description":"Beyond the limit"}
+		]}}`))
+	}))
+	defer server.Close()
+
+	search := websearch.New(websearch.NewBrave("secret").WithBaseURL(server.URL))
+	assert.Equal(t, "web_search", search.GetName())
+
+	out, err := search.Execute(context.Background(), map[string]interface{}{
+		"query":       "go generics",
+		"max_results": float64(2),
+		"recency":     "week",
+	})
+	require.NoError(t, err)
+
+	response := out.(*websearch.Response)
+	assert.Equal(t, "go generics", response.Query)
+	require.Len(t, response.Results, 2)
+	assert.Equal(t, websearch.Result{
+		Title:     "Generics",
+		URL:       "https://go.dev/doc/tutorial/generics",
+		Snippet:   "Tutorial",
+		Published: "2024-01-01",
+	}, response.Results[0])
+}
+
+func TestWebSearchWithTavily(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "agents", body["query"])
+		assert.Equal(t, float64(websearch.DefaultMaxResults), body["max_results"])
+		assert.NotContains(t, body, "time_range")
+
+		_, _ = w.Write([]byte(`{"results":[{"title":"Agents","url":"https://example.com/agents","content":"About agents"}]}`))
+	}))
+	defer server.Close()
+
+	search := websearch.New(websearch.NewTavily("secret").WithBaseURL(server.URL))
+	out, err := search.Execute(context.Background(), map[string]interface{}{"query": "agents"})
+	require.NoError(t, err)
+	assert.Equal(t, "About agents", out.(*websearch.Response).Results[0].Snippet)
+}
+
+func TestWebSearchReportsBackendErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid key", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	search := websearch.New(websearch.NewSerpAPI("bad").WithBaseURL(server.URL))
+	_, err := search.Execute(context.Background(), map[string]interface{}{"query": "anything"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "serpapi search failed")
+	assert.Contains(t, err.Error(), "401")
+}
+
+func TestWebSearchValidatesParameters(t *testing.T) {
+	search := websearch.New(websearch.NewBing("key"))
+
+	_, err := search.Execute(context.Background(), map[string]interface{}{"query": "  "})
+	assert.Error(t, err)
+
+	_, err = search.Execute(context.Background(), map[string]interface{}{"query": "news", "recency": "decade"})
+	assert.Error(t, err)
+}
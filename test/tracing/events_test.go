@@ -0,0 +1,37 @@
+package tracing_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// eventRecorder is a tracer keeping the events it receives
+type eventRecorder struct {
+	events []tracing.Event
+}
+
+func (r *eventRecorder) RecordEvent(ctx context.Context, event tracing.Event) {
+	r.events = append(r.events, event)
+}
+
+func (r *eventRecorder) Flush() error { return nil }
+
+func (r *eventRecorder) Close() error { return nil }
+
+func TestModelResponseEventIncludesRequestID(t *testing.T) {
+	recorder := &eventRecorder{}
+	ctx := tracing.WithTracer(context.Background(), recorder)
+
+	tracing.ModelResponse(ctx, "Assistant", "gpt-4o", &model.Response{Content: "ok", RequestID: "req_42"}, nil)
+	tracing.ModelResponse(ctx, "Assistant", "gpt-4o", &model.Response{Content: "ok"}, nil)
+
+	require.Len(t, recorder.events, 2)
+	assert.Equal(t, tracing.EventTypeModelResponse, recorder.events[0].Type)
+	assert.Equal(t, "req_42", recorder.events[0].Details["request_id"])
+	assert.NotContains(t, recorder.events[1].Details, "request_id", "responses without an ID add no detail")
+}
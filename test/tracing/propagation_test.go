@@ -0,0 +1,105 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tracing"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const parentTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceparent(t *testing.T) {
+	sc, err := tracing.ParseTraceparent(parentTraceparent)
+	require.NoError(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID)
+	assert.True(t, sc.Sampled)
+	assert.Equal(t, parentTraceparent, sc.Traceparent())
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+	} {
+		_, err := tracing.ParseTraceparent(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestMiddlewareAndTransportPropagateTrace(t *testing.T) {
+	var downstream string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		downstream = r.Header.Get(tracing.TraceparentHeader)
+	}))
+	defer backend.Close()
+
+	client := &http.Client{Transport: tracing.NewTransport(nil)}
+	frontend := httptest.NewServer(tracing.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, _ := http.NewRequestWithContext(r.Context(), http.MethodGet, backend.URL, nil)
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+	})))
+	defer frontend.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, frontend.URL, nil)
+	req.Header.Set(tracing.TraceparentHeader, parentTraceparent)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// The outbound call joins the inbound trace as a new span
+	sc, err := tracing.ParseTraceparent(downstream)
+	require.NoError(t, err)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID)
+	assert.NotEqual(t, "00f067aa0ba902b7", sc.SpanID)
+}
+
+// contextModel records the span context that reaches the model
+type contextModel struct {
+	spans []tracing.SpanContext
+}
+
+func (m *contextModel) GetResponse(ctx context.Context, request *model.Request) (*model.Response, error) {
+	sc, _ := tracing.SpanContextFromContext(ctx)
+	m.spans = append(m.spans, sc)
+	return &model.Response{Content: "done"}, nil
+}
+
+func (m *contextModel) StreamResponse(ctx context.Context, request *model.Request) (<-chan model.StreamEvent, error) {
+	return nil, nil
+}
+
+func TestRunJoinsCallerTrace(t *testing.T) {
+	m := &contextModel{}
+	a := agent.NewAgent("Assistant").WithModel(m)
+
+	parent, err := tracing.ParseTraceparent(parentTraceparent)
+	require.NoError(t, err)
+	ctx := tracing.ContextWithSpanContext(context.Background(), parent)
+
+	_, err = runner.NewRunner().Run(ctx, a, &runner.RunOptions{
+		Input: "hi",
+		RunConfig: &runner.RunConfig{
+			ModelProvider:   &mocks.MockModelProvider{},
+			TracingDisabled: true,
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, m.spans, 1)
+	assert.Equal(t, parent.TraceID, m.spans[0].TraceID)
+	assert.NotEqual(t, parent.SpanID, m.spans[0].SpanID)
+}
@@ -0,0 +1,174 @@
+package trigger_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/trigger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKafka serves records in order and records commits and writes
+type fakeKafka struct {
+	records   []trigger.KafkaMessage
+	committed []int64
+	written   []trigger.KafkaMessage
+}
+
+func (k *fakeKafka) FetchMessage(ctx context.Context) (trigger.KafkaMessage, error) {
+	record := k.records[0]
+	k.records = k.records[1:]
+	return record, nil
+}
+
+func (k *fakeKafka) CommitMessages(ctx context.Context, msgs ...trigger.KafkaMessage) error {
+	for _, msg := range msgs {
+		k.committed = append(k.committed, msg.Offset)
+	}
+	return nil
+}
+
+func (k *fakeKafka) WriteMessages(ctx context.Context, msgs ...trigger.KafkaMessage) error {
+	k.written = append(k.written, msgs...)
+	return nil
+}
+
+func TestKafkaCommitsHandledOffsetsInOrder(t *testing.T) {
+	client := &fakeKafka{}
+	for offset := int64(0); offset < 4; offset++ {
+		client.records = append(client.records, trigger.KafkaMessage{Topic: "tickets", Partition: 0, Offset: offset, Value: []byte("t")})
+	}
+	k := trigger.NewKafka(client)
+	ctx := context.Background()
+
+	var msgs []*trigger.Message
+	for range 4 {
+		received, err := k.Receive(ctx)
+		require.NoError(t, err)
+		msgs = append(msgs, received...)
+	}
+	assert.Equal(t, "tickets/0/2", msgs[2].ID)
+
+	require.NoError(t, msgs[1].Ack(ctx))
+	assert.Empty(t, client.committed, "offset 1 waits for offset 0")
+	require.NoError(t, msgs[0].Ack(ctx))
+	assert.Equal(t, []int64{1}, client.committed)
+
+	require.NoError(t, msgs[2].Nack(ctx))
+	require.NoError(t, msgs[3].Ack(ctx))
+	assert.Equal(t, []int64{1}, client.committed, "a handed back record holds up later commits")
+
+	require.NoError(t, k.Publish(ctx, "results", &trigger.Message{Body: []byte("done"), Headers: map[string]string{"correlation-id": "c1"}}))
+	require.Len(t, client.written, 1)
+	assert.Equal(t, "results", client.written[0].Topic)
+	assert.Equal(t, []byte("c1"), client.written[0].Key)
+}
+
+// fakeSQS serves one message and records the calls made for it
+type fakeSQS struct {
+	messages   []trigger.SQSMessage
+	deleted    []string
+	visibility map[string]time.Duration
+	sent       map[string]string
+}
+
+func (s *fakeSQS) ReceiveMessages(ctx context.Context, queueURL string, max int, wait time.Duration) ([]trigger.SQSMessage, error) {
+	return s.messages, nil
+}
+
+func (s *fakeSQS) DeleteMessage(ctx context.Context, queueURL, receiptHandle string) error {
+	s.deleted = append(s.deleted, receiptHandle)
+	return nil
+}
+
+func (s *fakeSQS) ChangeMessageVisibility(ctx context.Context, queueURL, receiptHandle string, timeout time.Duration) error {
+	s.visibility[receiptHandle] = timeout
+	return nil
+}
+
+func (s *fakeSQS) SendMessage(ctx context.Context, queueURL, body string, attributes map[string]string) error {
+	s.sent[queueURL] = body
+	return nil
+}
+
+func TestSQSAcknowledgement(t *testing.T) {
+	client := &fakeSQS{
+		messages: []trigger.SQSMessage{
+			{MessageID: "a", ReceiptHandle: "rh-a", Body: "first", ReceiveCount: 1},
+			{MessageID: "b", ReceiptHandle: "rh-b", Body: "second", ReceiveCount: 4, Attributes: map[string]string{"correlation-id": "c2"}},
+		},
+		visibility: make(map[string]time.Duration),
+		sent:       make(map[string]string),
+	}
+	s := trigger.NewSQS(client, "https://sqs.example/queue")
+	ctx := context.Background()
+
+	msgs, err := s.Receive(ctx)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, 4, msgs[1].Deliveries)
+	assert.Equal(t, "c2", msgs[1].Headers["correlation-id"])
+
+	require.NoError(t, msgs[0].Ack(ctx))
+	require.NoError(t, msgs[1].Nack(ctx))
+	assert.Equal(t, []string{"rh-a"}, client.deleted)
+	assert.Equal(t, map[string]time.Duration{"rh-b": 0}, client.visibility, "a handed back message is visible again right away")
+
+	require.NoError(t, s.Publish(ctx, "https://sqs.example/results", &trigger.Message{Body: []byte("done")}))
+	assert.Equal(t, "done", client.sent["https://sqs.example/results"])
+}
+
+// fakeNATSMessage is a JetStream message recording its acknowledgement
+type fakeNATSMessage struct {
+	seq     uint64
+	headers map[string]string
+	acked   bool
+	naked   bool
+}
+
+func (m *fakeNATSMessage) Subject() string            { return "tickets.created" }
+func (m *fakeNATSMessage) Sequence() uint64           { return m.seq }
+func (m *fakeNATSMessage) Data() []byte               { return []byte("ticket") }
+func (m *fakeNATSMessage) Headers() map[string]string { return m.headers }
+func (m *fakeNATSMessage) NumDelivered() int          { return 2 }
+func (m *fakeNATSMessage) Ack() error                 { m.acked = true; return nil }
+func (m *fakeNATSMessage) Nak() error                 { m.naked = true; return nil }
+
+type fakeNATS struct {
+	messages  []trigger.NATSMessage
+	published map[string][]byte
+}
+
+func (n *fakeNATS) Fetch(ctx context.Context, max int) ([]trigger.NATSMessage, error) {
+	return n.messages, nil
+}
+
+func (n *fakeNATS) Publish(ctx context.Context, subject string, data []byte, headers map[string]string) error {
+	n.published[subject] = data
+	return nil
+}
+
+func TestNATSAcknowledgement(t *testing.T) {
+	withID := &fakeNATSMessage{seq: 7, headers: map[string]string{"Nats-Msg-Id": "ticket-7"}}
+	withoutID := &fakeNATSMessage{seq: 8}
+	client := &fakeNATS{messages: []trigger.NATSMessage{withID, withoutID}, published: make(map[string][]byte)}
+	n := trigger.NewNATS(client)
+	ctx := context.Background()
+
+	msgs, err := n.Receive(ctx)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, "ticket-7", msgs[0].ID)
+	assert.Equal(t, "tickets.created:8", msgs[1].ID)
+	assert.Equal(t, 2, msgs[0].Deliveries)
+
+	require.NoError(t, msgs[0].Ack(ctx))
+	require.NoError(t, msgs[1].Nack(ctx))
+	assert.True(t, withID.acked)
+	assert.True(t, withoutID.naked)
+
+	require.NoError(t, n.Publish(ctx, "tickets.triaged", &trigger.Message{Body: []byte("high")}))
+	assert.Equal(t, []byte("high"), client.published["tickets.triaged"])
+}
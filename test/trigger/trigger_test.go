@@ -0,0 +1,190 @@
+package trigger_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tracing"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/trigger"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRunOptions() *runner.RunOptions {
+	return &runner.RunOptions{RunConfig: &runner.RunConfig{ModelProvider: &mocks.MockModelProvider{}, TracingDisabled: true}}
+}
+
+// queue is an in-memory source and publisher recording acknowledgements
+type queue struct {
+	pending   chan *trigger.Message
+	mu        sync.Mutex
+	acked     []string
+	nacked    []string
+	published map[string][]*trigger.Message
+}
+
+func newQueue() *queue {
+	return &queue{pending: make(chan *trigger.Message, 10), published: make(map[string][]*trigger.Message)}
+}
+
+func (q *queue) send(id, body string, headers map[string]string, deliveries int) {
+	q.pending <- trigger.NewMessage(id, []byte(body), headers, deliveries,
+		func(context.Context) error {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			q.acked = append(q.acked, id)
+			return nil
+		},
+		func(context.Context) error {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			q.nacked = append(q.nacked, id)
+			return nil
+		})
+}
+
+func (q *queue) Receive(ctx context.Context) ([]*trigger.Message, error) {
+	select {
+	case msg := <-q.pending:
+		return []*trigger.Message{msg}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (q *queue) Publish(ctx context.Context, topic string, msg *trigger.Message) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.published[topic] = append(q.published[topic], msg)
+	return nil
+}
+
+func (q *queue) snapshot() (acked, nacked []string, published map[string][]*trigger.Message) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]string(nil), q.acked...), append([]string(nil), q.nacked...), q.published
+}
+
+func TestConsumerRunsAgentAndPublishesResult(t *testing.T) {
+	q := newQueue()
+	triageModel := mocks.NewScriptedModel(&model.Response{Content: "priority: high"})
+	consumer := trigger.NewConsumer(q, agent.NewAgent("Triage").WithModel(triageModel)).
+		WithRunOptions(testRunOptions()).
+		WithOutput(q, "triage-results")
+
+	traceparent := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+	q.send("m1", "checkout is down", map[string]string{"correlation-id": "ticket-42", "traceparent": traceparent}, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- consumer.Run(ctx) }()
+	require.Eventually(t, func() bool {
+		acked, _, _ := q.snapshot()
+		return len(acked) == 1
+	}, time.Second, 5*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	assert.Equal(t, "checkout is down", triageModel.Requests[0].Input)
+	_, _, published := q.snapshot()
+	require.Len(t, published["triage-results"], 1)
+	result := published["triage-results"][0]
+	assert.Equal(t, "priority: high", string(result.Body))
+	assert.Equal(t, "ticket-42", result.Headers[trigger.DefaultCorrelationHeader])
+	assert.Equal(t, "m1", result.Headers[trigger.SourceMessageHeader])
+	assert.Equal(t, "Triage", result.Headers[trigger.AgentHeader])
+	assert.Contains(t, result.Headers[trigger.TraceparentHeader], "0af7651916cd43dd8448eb211c80319c", "the result stays in the trace of the message")
+}
+
+func TestConsumerPropagatesCorrelationID(t *testing.T) {
+	var correlationID string
+	a := agent.NewAgent("Triage").WithModel(mocks.NewScriptedModel(&model.Response{Content: "ok"}))
+	a.WithHooks(&correlationHooks{seen: &correlationID})
+
+	q := newQueue()
+	q.send("m1", "hello", nil, 1)
+	msg := <-q.pending
+	require.NoError(t, trigger.NewConsumer(q, a).WithRunOptions(testRunOptions()).Handle(context.Background(), msg))
+	assert.Equal(t, "m1", correlationID, "messages without a correlation header are correlated by their ID")
+}
+
+// correlationHooks records the correlation ID the run sees
+type correlationHooks struct {
+	agent.DefaultAgentHooks
+	seen *string
+}
+
+func (h *correlationHooks) OnAgentStart(ctx context.Context, a *agent.Agent, input interface{}) error {
+	*h.seen = tracing.CorrelationID(ctx)
+	return nil
+}
+
+func TestConsumerRedeliversAndDeadLetters(t *testing.T) {
+	q := newQueue()
+	var errs []error
+	consumer := trigger.NewConsumer(q, agent.NewAgent("Broken").WithModel(mocks.NewScriptedModel())).
+		WithRunOptions(testRunOptions()).
+		WithOutput(q, "results").
+		WithDeadLetter("dead-letters", 3).
+		OnError(func(msg *trigger.Message, err error) { errs = append(errs, err) })
+
+	q.send("m1", "first try", nil, 1)
+	err := consumer.Handle(context.Background(), <-q.pending)
+	require.Error(t, err)
+	acked, nacked, published := q.snapshot()
+	assert.Empty(t, acked)
+	assert.Equal(t, []string{"m1"}, nacked, "a failed message is handed back for redelivery")
+	assert.Empty(t, published["dead-letters"])
+
+	q.send("m1", "last try", map[string]string{"correlation-id": "c1"}, 3)
+	require.Error(t, consumer.Handle(context.Background(), <-q.pending))
+	acked, nacked, published = q.snapshot()
+	assert.Equal(t, []string{"m1"}, acked)
+	assert.Len(t, nacked, 1)
+	require.Len(t, published["dead-letters"], 1)
+	dead := published["dead-letters"][0]
+	assert.Equal(t, "last try", string(dead.Body))
+	assert.Equal(t, "c1", dead.Headers["correlation-id"])
+	assert.Contains(t, dead.Headers[trigger.ErrorHeader], "no responses left")
+	assert.Len(t, errs, 2)
+}
+
+func TestConsumerRetriesWithinDelivery(t *testing.T) {
+	q := newQueue()
+	m := &failingOnceModel{ScriptedModel: mocks.NewScriptedModel(&model.Response{Content: "recovered"})}
+
+	q.send("m1", "hi", nil, 1)
+	err := trigger.NewConsumer(q, agent.NewAgent("Flaky").WithModel(m)).
+		WithRunOptions(testRunOptions()).
+		WithRetry(1, time.Millisecond).
+		Handle(context.Background(), <-q.pending)
+	require.NoError(t, err)
+	acked, nacked, _ := q.snapshot()
+	assert.Equal(t, []string{"m1"}, acked)
+	assert.Empty(t, nacked)
+}
+
+// failingOnceModel fails its first request
+type failingOnceModel struct {
+	*mocks.ScriptedModel
+	mu     sync.Mutex
+	failed bool
+}
+
+func (m *failingOnceModel) GetResponse(ctx context.Context, request *model.Request) (*model.Response, error) {
+	m.mu.Lock()
+	failed := m.failed
+	m.failed = true
+	m.mu.Unlock()
+	if !failed {
+		return nil, errors.New("rate limited")
+	}
+	return m.ScriptedModel.GetResponse(ctx, request)
+}
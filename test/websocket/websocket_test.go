@@ -0,0 +1,103 @@
+package websocket_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echoServer echoes every message back to the client
+func echoServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDialEchoesMessages(t *testing.T) {
+	server := echoServer(t)
+	conn, response, err := websocket.Dial(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http")+"/echo", nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, http.StatusSwitchingProtocols, response.StatusCode)
+
+	// Cover the short, 16-bit and 64-bit payload lengths
+	for _, payload := range [][]byte{[]byte("hello"), bytes.Repeat([]byte("a"), 1000), bytes.Repeat([]byte("b"), 70000)} {
+		require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, payload))
+		messageType, data, err := conn.ReadMessage()
+		require.NoError(t, err)
+		assert.Equal(t, websocket.BinaryMessage, messageType)
+		assert.Equal(t, payload, data)
+	}
+
+	require.NoError(t, conn.Ping([]byte("ping")))
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("after ping")))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	assert.Equal(t, "after ping", string(data))
+}
+
+func TestReadMessageEnforcesLimit(t *testing.T) {
+	server := echoServer(t)
+	conn, _, err := websocket.Dial(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	conn.SetMaxMessageBytes(10)
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("far too long a message")))
+	_, _, err = conn.ReadMessage()
+	assert.ErrorContains(t, err, "too big")
+}
+
+func TestDialRejectsPlainHTTP(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	_, response, err := websocket.Dial(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, response.StatusCode)
+}
+
+func TestCloseIsReportedToPeer(t *testing.T) {
+	closed := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		_, _, err = conn.ReadMessage()
+		closed <- err
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.Dial(context.Background(), "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	require.NoError(t, conn.CloseWithCode(websocket.CloseGoingAway, "bye"))
+
+	var closeErr *websocket.CloseError
+	require.ErrorAs(t, <-closed, &closeErr)
+	assert.Equal(t, websocket.CloseGoingAway, closeErr.Code)
+	assert.Equal(t, "bye", closeErr.Reason)
+}
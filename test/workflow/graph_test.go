@@ -0,0 +1,185 @@
+package workflow_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/workflow"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// echo returns a step that outputs its name followed by what it builds on
+func echo(name string) workflow.StepFunc {
+	return func(ctx context.Context, state *workflow.State) (interface{}, error) {
+		return fmt.Sprintf("%s(%v)", name, state.Previous), nil
+	}
+}
+
+func graphRunOptions() *runner.RunOptions {
+	return &runner.RunOptions{RunConfig: &runner.RunConfig{ModelProvider: &mocks.MockModelProvider{}, TracingDisabled: true}}
+}
+
+func TestGraphRunsAgentsAndFunctionsInOrder(t *testing.T) {
+	analyzerModel := mocks.NewScriptedModel(&model.Response{Content: "slow loop in main.go"})
+	analyzer := agent.NewAgent("Analyzer").WithModel(analyzerModel)
+
+	res, err := workflow.NewGraph().
+		WithRunOptions(graphRunOptions()).
+		Step("analyze", analyzer).
+		Then("optimize", echo("optimize")).
+		Then("publish", echo("publish")).
+		Run(context.Background(), "review main.go")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"analyze", "optimize", "publish"}, res.Completed)
+	assert.Equal(t, "publish(optimize(slow loop in main.go))", res.Output)
+	assert.Equal(t, "review main.go", analyzerModel.Requests[0].Input, "the entry step gets the graph input")
+	require.Contains(t, res.RunResults, "analyze")
+	assert.Equal(t, "Analyzer", res.RunResults["analyze"].LastAgent.Name)
+}
+
+func TestGraphBranchSkipsUntakenSteps(t *testing.T) {
+	needsReview := func(state *workflow.State) bool { return state.Previous == "risky" }
+	build := func(input string) *workflow.Graph {
+		return workflow.NewGraph().
+			Step("classify", workflow.StepFunc(func(ctx context.Context, state *workflow.State) (interface{}, error) {
+				return input, nil
+			})).
+			Branch(needsReview, "review", "publish").
+			Step("review", echo("review")).
+			Then("publish", echo("publish"))
+	}
+
+	risky, err := build("risky").Run(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"classify", "review", "publish"}, risky.Completed)
+	assert.Empty(t, risky.Skipped)
+	assert.Equal(t, "publish(review(risky))", risky.Output)
+
+	safe, err := build("safe").Run(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"classify", "publish"}, safe.Completed)
+	assert.Equal(t, []string{"review"}, safe.Skipped)
+	assert.Equal(t, "publish(safe)", safe.Output)
+}
+
+func TestGraphFansOutAndJoins(t *testing.T) {
+	var mu sync.Mutex
+	var running, peak int
+	parallel := func(name string) workflow.StepFunc {
+		return func(ctx context.Context, state *workflow.State) (interface{}, error) {
+			mu.Lock()
+			running++
+			peak = max(peak, running)
+			mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return name + " done", nil
+		}
+	}
+
+	summarizerModel := mocks.NewScriptedModel(&model.Response{Content: "all good"})
+	res, err := workflow.NewGraph().
+		WithRunOptions(graphRunOptions()).
+		Step("fetch", echo("fetch")).
+		FanOut("lint", "test").
+		Step("lint", parallel("lint")).
+		Step("test", parallel("test")).
+		Step("summarize", agent.NewAgent("Summarizer").WithModel(summarizerModel)).
+		Join("summarize", "lint", "test").
+		Run(context.Background(), "repo")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, peak, "fanned out steps run concurrently")
+	assert.Equal(t, "summarize", res.Completed[3])
+	assert.Equal(t, "## lint\n\nlint done\n\n## test\n\ntest done", summarizerModel.Requests[0].Input)
+	assert.Equal(t, "all good", res.Output)
+}
+
+func TestGraphRetriesSteps(t *testing.T) {
+	var attempts int32
+	flaky := workflow.StepFunc(func(ctx context.Context, state *workflow.State) (interface{}, error) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			return nil, errors.New("temporarily unavailable")
+		}
+		return "ok", nil
+	})
+
+	res, err := workflow.NewGraph().
+		Step("flaky", flaky, workflow.WithRetry(workflow.RetryPolicy{MaxRetries: 2, Delay: time.Millisecond, BackoffFactor: 2})).
+		Run(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", res.Output)
+	assert.Equal(t, int32(3), attempts)
+
+	atomic.StoreInt32(&attempts, 0)
+	_, err = workflow.NewGraph().
+		Step("flaky", flaky, workflow.WithRetry(workflow.RetryPolicy{MaxRetries: 1})).
+		Run(context.Background(), nil)
+	var stepErr *workflow.StepError
+	require.ErrorAs(t, err, &stepErr)
+	assert.Equal(t, "flaky", stepErr.Step)
+	assert.Equal(t, 2, stepErr.Attempts)
+}
+
+func TestGraphResumesFromCheckpoint(t *testing.T) {
+	store := mocks.NewInMemoryStateStore()
+	var analyzed, published int32
+	fail := true
+	build := func() *workflow.Graph {
+		return workflow.NewGraph().
+			WithStateStore(store, "release").
+			Step("analyze", workflow.StepFunc(func(ctx context.Context, state *workflow.State) (interface{}, error) {
+				atomic.AddInt32(&analyzed, 1)
+				return "analysis", nil
+			})).
+			Then("publish", workflow.StepFunc(func(ctx context.Context, state *workflow.State) (interface{}, error) {
+				atomic.AddInt32(&published, 1)
+				if fail {
+					return nil, errors.New("registry down")
+				}
+				return "published " + state.Previous.(string), nil
+			}))
+	}
+
+	partial, err := build().Run(context.Background(), nil)
+	require.Error(t, err)
+	assert.Equal(t, []string{"analyze"}, partial.Completed)
+
+	fail = false
+	res, err := build().Run(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "published analysis", res.Output)
+	assert.Equal(t, int32(1), analyzed, "completed steps are not run again")
+	assert.Equal(t, int32(2), published)
+
+	_, err = build().Run(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), analyzed, "a finished graph starts over")
+}
+
+func TestGraphValidation(t *testing.T) {
+	_, err := workflow.NewGraph().Run(context.Background(), nil)
+	assert.ErrorIs(t, err, workflow.ErrEmptyGraph)
+
+	_, err = workflow.NewGraph().Step("a", echo("a")).Then("b", echo("b")).Edge("b", "a").Run(context.Background(), nil)
+	assert.ErrorIs(t, err, workflow.ErrCycle)
+
+	_, err = workflow.NewGraph().Step("a", echo("a")).Edge("a", "missing").Run(context.Background(), nil)
+	assert.ErrorContains(t, err, `unknown step "missing"`)
+
+	_, err = workflow.NewGraph().Step("a", "not a step").Run(context.Background(), nil)
+	assert.ErrorContains(t, err, "must be an *agent.Agent, a StepFunc or a *Parallel")
+}
@@ -0,0 +1,97 @@
+package workflow_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/workflow"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func reviewer(name, answer string) *agent.Agent {
+	return agent.NewAgent(name).WithModel(mocks.NewScriptedModel(&model.Response{Content: answer}))
+}
+
+func TestParallelAggregatesWithReducer(t *testing.T) {
+	reducerModel := mocks.NewScriptedModel(&model.Response{Content: "ship it"})
+	res, err := workflow.NewParallel(reviewer("Security", "no issues"), reviewer("Style", "fine")).
+		WithReducer(agent.NewAgent("Lead").WithModel(reducerModel)).
+		WithRunOptions(graphRunOptions()).
+		Run(context.Background(), "review the diff")
+	require.NoError(t, err)
+
+	assert.Equal(t, []interface{}{"no issues", "fine"}, res.Outputs)
+	assert.Equal(t, "## Security\n\nno issues\n\n## Style\n\nfine", reducerModel.Request(0).Input)
+	assert.Equal(t, "ship it", res.Output)
+	require.NotNil(t, res.ReducerResult)
+	assert.Equal(t, "Lead", res.ReducerResult.LastAgent.Name)
+}
+
+func TestParallelShardsInput(t *testing.T) {
+	summarizerModel := mocks.NewScriptedModel(
+		&model.Response{Content: "summary 1"},
+		&model.Response{Content: "summary 2"},
+	)
+	split := func(input interface{}) ([]interface{}, error) {
+		var shards []interface{}
+		for _, part := range strings.Split(input.(string), "|") {
+			shards = append(shards, part)
+		}
+		return shards, nil
+	}
+
+	res, err := workflow.NewParallel(agent.NewAgent("Summarizer").WithModel(summarizerModel)).
+		WithShards(split).
+		WithReduceFunc(func(ctx context.Context, outputs []interface{}) (interface{}, error) {
+			return len(outputs), nil
+		}).
+		WithRunOptions(graphRunOptions()).
+		Run(context.Background(), "chapter one|chapter two")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, res.Output)
+	require.Equal(t, 2, summarizerModel.RequestCount())
+	inputs := []interface{}{summarizerModel.Request(0).Input, summarizerModel.Request(1).Input}
+	assert.ElementsMatch(t, []interface{}{"chapter one", "chapter two"}, inputs)
+
+	_, err = workflow.NewParallel(reviewer("A", "a"), reviewer("B", "b")).
+		WithShards(split).
+		Run(context.Background(), "only one shard")
+	assert.ErrorIs(t, err, workflow.ErrShardCount)
+}
+
+func TestParallelAsGraphStep(t *testing.T) {
+	res, err := workflow.NewGraph().
+		WithRunOptions(graphRunOptions()).
+		Step("prepare", echo("prepare")).
+		Then("review", workflow.NewParallel(reviewer("Security", "ok"), reviewer("Style", "nit")).WithRunOptions(graphRunOptions())).
+		Then("publish", workflow.StepFunc(func(ctx context.Context, state *workflow.State) (interface{}, error) {
+			return fmt.Sprintf("published after:\n%v", state.Previous), nil
+		})).
+		Run(context.Background(), "diff")
+	require.NoError(t, err)
+	assert.Equal(t, "published after:\n## Security\n\nok\n\n## Style\n\nnit", res.Output)
+}
+
+func TestParallelFailureSkipsReduction(t *testing.T) {
+	reduced := false
+	res, err := workflow.NewParallel(reviewer("Good", "ok"), agent.NewAgent("Broken").WithModel(mocks.NewScriptedModel())).
+		WithReduceFunc(func(ctx context.Context, outputs []interface{}) (interface{}, error) {
+			reduced = true
+			return nil, nil
+		}).
+		WithRunOptions(graphRunOptions()).
+		Run(context.Background(), "go")
+	var parallelErr *runner.ParallelError
+	require.True(t, errors.As(err, &parallelErr))
+	assert.False(t, reduced)
+	assert.Equal(t, []interface{}{"ok", nil}, res.Outputs)
+}
@@ -0,0 +1,101 @@
+package workflow_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/workflow"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeWorkflow(t *testing.T, path, instructions string) {
+	t.Helper()
+	doc := "agents:\n  - name: assistant\n    instructions: " + instructions + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(doc), 0o600))
+}
+
+func TestWatcherSwapsInChangedDefinition(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "workflow.yaml")
+	writeWorkflow(t, path, "Be brief.")
+
+	w, err := workflow.NewWatcher(context.Background(), workflow.FileSource(path), newRegistry())
+	require.NoError(t, err)
+	before := w.Current()
+	assert.Equal(t, "Be brief.", before.Entry.Instructions)
+
+	changed, err := w.Reload(context.Background())
+	require.NoError(t, err)
+	assert.False(t, changed, "an unchanged file must not rebuild the workflow")
+	assert.Same(t, before, w.Current())
+
+	writeWorkflow(t, path, "Be thorough.")
+	changed, err = w.Reload(context.Background())
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "Be thorough.", w.Current().Entry.Instructions)
+	assert.Equal(t, "Be brief.", before.Entry.Instructions, "workflows already handed out are not modified")
+}
+
+func TestWatcherKeepsWorkflowWhenDefinitionIsInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "workflow.yaml")
+	writeWorkflow(t, path, "Be brief.")
+
+	w, err := workflow.NewWatcher(context.Background(), workflow.FileSource(path), newRegistry())
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte("agents:\n  - name: assistant\n    tools: [missing]\n"), 0o600))
+	changed, err := w.Reload(context.Background())
+	require.Error(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, "Be brief.", w.Current().Entry.Instructions)
+}
+
+func TestWatcherPollsSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "workflow.yaml")
+	writeWorkflow(t, path, "Be brief.")
+
+	reloaded := make(chan *workflow.Workflow, 1)
+	w, err := workflow.NewWatcher(context.Background(), workflow.FileSource(path), newRegistry())
+	require.NoError(t, err)
+	w.WithInterval(10 * time.Millisecond).OnReload(func(wf *workflow.Workflow) { reloaded <- wf })
+
+	stop := w.Start(context.Background())
+	defer stop()
+
+	writeWorkflow(t, path, "Be thorough.")
+	select {
+	case wf := <-reloaded:
+		assert.Equal(t, "Be thorough.", wf.Entry.Instructions)
+	case <-time.After(2 * time.Second):
+		t.Fatal("workflow was not reloaded")
+	}
+}
+
+func TestURLSourceUsesETags(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte("agents:\n  - name: remote\n"))
+	}))
+	defer server.Close()
+
+	w, err := workflow.NewWatcher(context.Background(), workflow.NewURLSource(server.URL), newRegistry())
+	require.NoError(t, err)
+	assert.Equal(t, "remote", w.Current().Entry.Name)
+
+	changed, err := w.Reload(context.Background())
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, 2, requests)
+}
@@ -0,0 +1,153 @@
+package workflow_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/workflow"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reviewWorkflow = `
+name: review
+entry: planner
+max_turns: 5
+model: test-model
+agents:
+  - name: planner
+    instructions: Plan the work.
+    handoffs: [coder]
+  - name: coder
+    instructions: Write the code.
+    settings:
+      temperature: 0.2
+    tools: [lookup]
+    handoffs: [planner]
+phases:
+  - name: planning
+    agent: planner
+  - name: implementation
+    agent: coder
+retry:
+  max_retries: 2
+  delay: 1s
+validation:
+  phase_transition:
+    - rule: always_ok
+      message: never fails
+`
+
+func newRegistry() *workflow.Registry {
+	lookup := tool.NewFunctionTool("lookup", "Looks something up", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		return "found", nil
+	})
+	return workflow.NewRegistry().
+		WithTools(lookup).
+		WithValidator("always_ok", func(data interface{}) (bool, error) { return true, nil })
+}
+
+func TestLoadYAMLWorkflow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "review.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(reviewWorkflow), 0o600))
+
+	w, err := workflow.Load(path, newRegistry())
+	require.NoError(t, err)
+
+	assert.Equal(t, "review", w.Definition.Name)
+	assert.Equal(t, "planner", w.Entry.Name)
+	require.Len(t, w.Agents, 2)
+
+	coder := w.Agents["coder"]
+	assert.Equal(t, "test-model", coder.Model)
+	require.Len(t, coder.Tools, 1)
+	assert.Equal(t, "lookup", coder.Tools[0].GetName())
+	require.NotNil(t, coder.ModelSettings)
+	assert.Equal(t, 0.2, *coder.ModelSettings.Temperature)
+	require.Len(t, coder.Handoffs, 1)
+	assert.Same(t, w.Agents["planner"], coder.Handoffs[0])
+
+	require.Len(t, w.Config.Phases, 2)
+	require.NotNil(t, w.Config.RetryConfig)
+	assert.Equal(t, 2, w.Config.RetryConfig.MaxRetries)
+	require.Len(t, w.Config.ValidationConfig.PhaseTransitionValidation, 1)
+}
+
+func TestParseJSONWorkflow(t *testing.T) {
+	w, err := workflow.Parse([]byte(`{
+		"name": "single",
+		"agents": [{"name": "solo", "instructions": "Answer the question.", "tools": ["lookup"]}]
+	}`), newRegistry())
+	require.NoError(t, err)
+	assert.Equal(t, "solo", w.Entry.Name)
+}
+
+func TestParseReportsAllProblems(t *testing.T) {
+	_, err := workflow.Parse([]byte(`
+entry: missing
+agents:
+  - name: a
+    tools: [unknown_tool]
+    handoffs: [b]
+phases:
+  - name: p
+    agent: c
+    compensate: unknown_undo
+validation:
+  pre_handoff:
+    - rule: unknown_rule
+      severity: fatal
+`), newRegistry())
+	require.Error(t, err)
+	for _, want := range []string{
+		`entry agent "missing"`,
+		`unregistered tool "unknown_tool"`,
+		`undefined agent "b"`,
+		`undefined agent "c"`,
+		`unregistered compensation "unknown_undo"`,
+		`rule "unknown_rule" is not registered`,
+		`invalid severity "fatal"`,
+	} {
+		assert.Contains(t, err.Error(), want)
+	}
+}
+
+func TestParseRejectsUnknownFields(t *testing.T) {
+	_, err := workflow.Parse([]byte("agents:\n  - name: a\n    instuctions: typo\n"), newRegistry())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "instuctions")
+}
+
+func TestRunTracksPhasesAcrossHandoffs(t *testing.T) {
+	// The runner writes its traces to the working directory
+	t.Chdir(t.TempDir())
+
+	m := mocks.NewScriptedModel(
+		&model.Response{HandoffCall: &model.HandoffCall{AgentName: "coder"}},
+		&model.Response{Content: "done"},
+	)
+	provider := &mocks.MockModelProvider{}
+	provider.On("GetModel", "test-model").Return(m, nil)
+
+	var transitions int
+	registry := newRegistry().
+		WithProvider(provider).
+		WithValidator("always_ok", func(data interface{}) (bool, error) {
+			transitions++
+			return true, nil
+		})
+
+	w, err := workflow.Parse([]byte(reviewWorkflow), registry)
+	require.NoError(t, err)
+
+	res, err := w.Run(context.Background(), "build it")
+	require.NoError(t, err)
+	assert.Equal(t, "done", res.FinalOutput)
+	assert.Equal(t, "coder", res.LastAgent.Name)
+	assert.Equal(t, 2, transitions)
+}
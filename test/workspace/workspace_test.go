@@ -0,0 +1,125 @@
+package workspace_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/agent"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/artifact"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/model"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/result"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/runner"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/tool/fs"
+	"github.com/pontus-devoteam/agent-sdk-go/pkg/workspace"
+	"github.com/pontus-devoteam/agent-sdk-go/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFileCall(path, content string) *model.Response {
+	return &model.Response{ToolCalls: []model.ToolCall{{
+		ID:         "write-1",
+		Name:       "write_file",
+		Parameters: map[string]interface{}{"path": path, "content": content},
+	}}}
+}
+
+func runConfig(manager *workspace.Manager) *runner.RunConfig {
+	return &runner.RunConfig{ModelProvider: &mocks.MockModelProvider{}, TracingDisabled: true, Workspace: manager}
+}
+
+// untar returns the files of a gzipped tar archive
+func untar(t *testing.T, data []byte) map[string]string {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	files := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files
+		}
+		require.NoError(t, err)
+		if header.Typeflag == tar.TypeReg {
+			content, err := io.ReadAll(tr)
+			require.NoError(t, err)
+			files[header.Name] = string(content)
+		}
+	}
+}
+
+func TestRunGetsWorkspaceThatIsArchivedAndRemoved(t *testing.T) {
+	base := t.TempDir()
+	store := artifact.NewMemoryStore()
+	manager := workspace.NewManager(base).WithArchive(store)
+
+	var seen string
+	where := tool.NewFunctionTool("where", "Report the workspace", func(ctx context.Context, params map[string]interface{}) (interface{}, error) {
+		seen = workspace.FromContext(ctx).Path()
+		return seen, nil
+	})
+	coder := agent.NewAgent("Coder").WithTools(append(fs.NewWorkspaceSandbox().Tools(), where)...).WithModel(mocks.NewScriptedModel(
+		writeFileCall("main.go", "package main\n"),
+		&model.Response{ToolCalls: []model.ToolCall{{ID: "where-1", Name: "where", Parameters: map[string]interface{}{}}}},
+		&model.Response{Content: "done"},
+	))
+
+	res, err := runner.NewRunner().Run(context.Background(), coder, &runner.RunOptions{Input: "scaffold", RunID: "run-1", RunConfig: runConfig(manager)})
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Join(base, "run-1"), seen)
+	assert.NoDirExists(t, seen)
+
+	require.NotEmpty(t, res.WorkspaceArchive)
+	archived, data, err := store.Get(context.Background(), res.WorkspaceArchive)
+	require.NoError(t, err)
+	assert.Equal(t, "run-1", archived.Metadata["run_id"])
+	assert.Equal(t, map[string]string{"main.go": "package main\n"}, untar(t, data))
+}
+
+func TestWritesBeyondQuotaFail(t *testing.T) {
+	manager := workspace.NewManager(t.TempDir()).WithQuota(10)
+	coder := agent.NewAgent("Coder").WithTools(fs.NewWorkspaceSandbox().Tools()...).WithModel(mocks.NewScriptedModel(
+		writeFileCall("big.txt", strings.Repeat("x", 64)),
+		&model.Response{Content: "done"},
+	))
+
+	res, err := runner.NewRunner().Run(context.Background(), coder, &runner.RunOptions{Input: "write", RunConfig: runConfig(manager)})
+	require.NoError(t, err)
+
+	var writeErr error
+	for _, item := range res.NewItems {
+		if toolResult, ok := item.(*result.ToolResultItem); ok && toolResult.Name == "write_file" {
+			writeErr = toolResult.Error
+		}
+	}
+	assert.ErrorIs(t, writeErr, workspace.ErrQuotaExceeded)
+}
+
+func TestAcquireReusesWorkspaceOfResumedRun(t *testing.T) {
+	manager := workspace.NewManager(t.TempDir())
+	first, err := manager.Acquire("run-2")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(first.Path(), "notes.txt"), []byte("kept"), 0o600))
+
+	second, err := manager.Acquire("run-2")
+	require.NoError(t, err)
+	assert.Equal(t, first.Path(), second.Path())
+	assert.FileExists(t, filepath.Join(second.Path(), "notes.txt"))
+
+	_, err = manager.Acquire("../escape")
+	assert.Error(t, err)
+}
+
+func TestWorkspaceToolsFailOutsideRun(t *testing.T) {
+	_, err := fs.NewWorkspaceSandbox().ReadFileTool().Execute(context.Background(), map[string]interface{}{"path": "x"})
+	assert.ErrorIs(t, err, workspace.ErrNoWorkspace)
+}